@@ -0,0 +1,230 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/store/archive"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+var logger *log.Entry = util.GetLoggerForModule("backup")
+
+const (
+	// ManifestFileName is the file describing a backup. It is written last, so a backup without
+	// it is incomplete.
+	ManifestFileName = "manifest.json"
+
+	// DatabaseDirName is the directory of a backup holding the copy of the database directory of
+	// the node.
+	DatabaseDirName = "db"
+
+	archiveDirName = "archive"
+)
+
+// ErrBackupNotSupported is returned for the databases which cannot be backed up while in use.
+var ErrBackupNotSupported = errors.New("Online backup is not supported by the database backend")
+
+//
+// Manifest describes a backup. The head is the last finalized block when the backup started, the
+// state of the backup is at the head or later.
+//
+type Manifest struct {
+	ChainID    string            `json:"chain_id"`
+	Backend    string            `json:"backend"`
+	HeadHash   common.Hash       `json:"head_hash"`
+	HeadHeight common.JSONUint64 `json:"head_height"`
+	Timestamp  common.JSONUint64 `json:"timestamp"`
+}
+
+// Create writes a backup of the database and of the block archive, if not nil, to the directory
+// while the node is running. The directory must not exist. The head is the last finalized block
+// of the node, read before the backup starts.
+func Create(db database.Database, blockArchive *archive.Archive, head *core.ExtendedBlock,
+	backend string, dir string) (*Manifest, error) {
+	backupable, ok := db.(database.Backupable)
+	if !ok {
+		return nil, ErrBackupNotSupported
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("Backup directory %v already exists", dir)
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	manifest, err := create(backupable, blockArchive, head, backend, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	logger.WithFields(log.Fields{
+		"dir":        dir,
+		"headHeight": head.Height,
+		"elapsed":    time.Since(start).String(),
+	}).Info("Database backed up")
+	return manifest, nil
+}
+
+func create(db database.Backupable, blockArchive *archive.Archive, head *core.ExtendedBlock,
+	backend string, dir string) (*Manifest, error) {
+	dbDir := filepath.Join(dir, DatabaseDirName)
+	err := db.Backup(dbDir)
+	if err != nil {
+		return nil, err
+	}
+	// The archive is copied after the database, so that it contains the bodies removed from the
+	// copy of the database
+	if blockArchive != nil {
+		err = blockArchive.Backup(filepath.Join(dbDir, archiveDirName))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifest := &Manifest{
+		ChainID:    head.ChainID,
+		Backend:    backend,
+		HeadHash:   head.Hash(),
+		HeadHeight: common.JSONUint64(head.Height),
+		Timestamp:  common.JSONUint64(time.Now().Unix()),
+	}
+	raw, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, ManifestFileName), raw, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// LoadManifest reads the manifest of the backup in the directory.
+func LoadManifest(dir string) (*Manifest, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("No backup manifest in %v, the backup is missing or incomplete", dir)
+		}
+		return nil, err
+	}
+	manifest := &Manifest{}
+	err = json.Unmarshal(raw, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Restore copies the database of the backup to the database directory of the node, which must not
+// exist. The node must be stopped, and the restored database is checked with Verify before the
+// node is started on it.
+func Restore(backupDir string, dbDir string) (*Manifest, error) {
+	manifest, err := LoadManifest(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dbDir); err == nil {
+		return nil, fmt.Errorf("Database directory %v already exists", dbDir)
+	}
+	err = copyDir(filepath.Join(backupDir, DatabaseDirName), dbDir)
+	if err != nil {
+		os.RemoveAll(dbDir)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Verify checks the restored database against the head of the backup. The head needs to be a
+// finalized block of the chain, and the state of the last finalized block needs to be complete
+// enough to be loaded. It returns the last finalized block of the database.
+func Verify(db database.Database, blockArchive *archive.Archive, root *core.Block,
+	manifest *Manifest) (*core.ExtendedBlock, error) {
+	if manifest.ChainID != root.ChainID {
+		return nil, fmt.Errorf("Chain ID mismatch, backup: %v, snapshot: %v", manifest.ChainID, root.ChainID)
+	}
+
+	store := kvstore.NewKVStore(database.BlockDatabase(db))
+	chain := blockchain.NewChain(root.ChainID, store, root)
+	if blockArchive != nil {
+		chain.SetBlockArchive(blockArchive)
+	}
+
+	head, err := chain.FindBlockHeader(manifest.HeadHash)
+	if err != nil {
+		return nil, fmt.Errorf("Head block %v of the backup not found: %v", manifest.HeadHash.Hex(), err)
+	}
+	if !head.Status.IsFinalized() {
+		return nil, fmt.Errorf("Head block %v of the backup is not finalized", manifest.HeadHash.Hex())
+	}
+
+	lastFinalizedHash := consensus.NewState(store, chain).GetSummary().LastFinalizedBlock
+	lastFinalized, err := chain.FindBlock(lastFinalizedHash)
+	if err == blockchain.ErrBlockBodyPruned {
+		return nil, fmt.Errorf("Body of the last finalized block %v not found", lastFinalizedHash.Hex())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Last finalized block %v not found: %v", lastFinalizedHash.Hex(), err)
+	}
+	if lastFinalized.Height < head.Height {
+		return nil, fmt.Errorf("Last finalized height %v is below the backup head height %v",
+			lastFinalized.Height, head.Height)
+	}
+	if state.NewStoreView(lastFinalized.Height, lastFinalized.StateHash, db) == nil {
+		return nil, fmt.Errorf("State %v of the last finalized block not found", lastFinalized.StateHash.Hex())
+	}
+	return lastFinalized, nil
+}
+
+// copyDir recursively copies the directory
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+	return out.Sync()
+}
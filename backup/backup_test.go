@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+func createTestBlock(chainID string, parent *core.Block, stateHash common.Hash) *core.Block {
+	block := core.NewBlock()
+	block.ChainID = chainID
+	block.StateHash = stateHash
+	if parent != nil {
+		block.Parent = parent.Hash()
+		block.Height = parent.Height + 1
+		block.Epoch = parent.Epoch + 1
+	}
+	return block
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "backup")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	db, err := backend.NewLDBDatabase(filepath.Join(dir, "node", "main"), filepath.Join(dir, "node", "ref"), 0, 0)
+	require.Nil(err)
+	defer db.Close()
+
+	sv := state.NewStoreView(0, common.Hash{}, db)
+	sv.Set(common.Bytes("key"), common.Bytes("value"))
+	stateHash := sv.Save()
+
+	chainID := "testchain"
+	root := createTestBlock(chainID, nil, stateHash)
+	store := kvstore.NewKVStore(db)
+	chain := blockchain.NewChain(chainID, store, root)
+	b1, err := chain.AddBlock(createTestBlock(chainID, root, stateHash))
+	require.Nil(err)
+	b2, err := chain.AddBlock(createTestBlock(chainID, b1.Block, common.HexToHash("a2")))
+	require.Nil(err)
+	chain.FinalizePreviousBlocks(b1.Hash())
+	b1, err = chain.FindBlock(b1.Hash())
+	require.Nil(err)
+	require.Nil(consensus.NewState(store, chain).SetLastFinalizedBlock(b1))
+
+	backupDir := filepath.Join(dir, "backup")
+	manifest, err := Create(db, nil, b1, "leveldb", backupDir)
+	require.Nil(err)
+	assert.Equal(b1.Hash(), manifest.HeadHash)
+	_, err = Create(db, nil, b1, "leveldb", backupDir)
+	assert.NotNil(err) // An existing backup is never overwritten
+
+	_, err = Create(backend.NewMemDatabase(), nil, b1, "memdb", filepath.Join(dir, "memdb"))
+	assert.Equal(ErrBackupNotSupported, err)
+
+	restoredDir := filepath.Join(dir, "restored")
+	loaded, err := Restore(backupDir, restoredDir)
+	require.Nil(err)
+	assert.Equal(*manifest, *loaded)
+	restored, err := backend.NewLDBDatabase(filepath.Join(restoredDir, "main"), filepath.Join(restoredDir, "ref"), 0, 0)
+	require.Nil(err)
+	defer restored.Close()
+
+	lastFinalized, err := Verify(restored, nil, root, manifest)
+	require.Nil(err)
+	assert.Equal(b1.Hash(), lastFinalized.Hash())
+
+	// The head of the backup must be a finalized block of the restored chain
+	unfinalized := *manifest
+	unfinalized.HeadHash = b2.Hash()
+	_, err = Verify(restored, nil, root, &unfinalized)
+	assert.NotNil(err)
+	unknown := *manifest
+	unknown.HeadHash = common.HexToHash("a3")
+	_, err = Verify(restored, nil, root, &unknown)
+	assert.NotNil(err)
+}
@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"encoding/binary"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store"
+)
+
+// addressTxCountKey constructs the DB key for the number of indexed transactions of the given address.
+func addressTxCountKey(address common.Address) common.Bytes {
+	return append(common.Bytes("addr/"), address[:]...)
+}
+
+// addressTxKey constructs the DB key for the n-th indexed transaction of the given address.
+func addressTxKey(address common.Address, n uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, n)
+	return append(addressTxCountKey(address), buf...)
+}
+
+// AddressTxEntry locates a transaction involving an address.
+type AddressTxEntry struct {
+	TxHash      common.Hash
+	BlockHash   common.Hash
+	BlockHeight uint64
+	Index       uint64
+}
+
+// AddTxsToAddressIndex adds the transactions in given block to the index of each address
+// involved. Blocks must be added in increasing height order. Transactions already indexed
+// are skipped, so a block can be safely added again.
+func (ch *Chain) AddTxsToAddressIndex(block *core.ExtendedBlock) {
+	for idx, rawTx := range block.Txs {
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			continue
+		}
+		entry := AddressTxEntry{
+			TxHash:      crypto.HashAtHeight(block.Height, rawTx),
+			BlockHash:   block.Hash(),
+			BlockHeight: block.Height,
+			Index:       uint64(idx),
+		}
+		seen := make(map[common.Address]bool)
+		for _, address := range types.GetTxAddresses(tx) {
+			if seen[address] || address.IsEmpty() {
+				continue
+			}
+			seen[address] = true
+			ch.addAddressTx(address, entry)
+		}
+	}
+}
+
+func (ch *Chain) addAddressTx(address common.Address, entry AddressTxEntry) {
+	count := ch.GetAddressTxCount(address)
+	if count > 0 {
+		last := AddressTxEntry{}
+		err := ch.store.Get(addressTxKey(address, count-1), &last)
+		if err != nil {
+			logger.Panic(err)
+		}
+		if last.BlockHeight > entry.BlockHeight ||
+			(last.BlockHeight == entry.BlockHeight && last.Index >= entry.Index) {
+			return
+		}
+	}
+	err := ch.store.Put(addressTxKey(address, count), entry)
+	if err != nil {
+		logger.Panic(err)
+	}
+	err = ch.store.Put(addressTxCountKey(address), count+1)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// GetAddressTxCount returns the number of indexed transactions involving the given address.
+func (ch *Chain) GetAddressTxCount(address common.Address) uint64 {
+	var count uint64
+	err := ch.store.Get(addressTxCountKey(address), &count)
+	if err != nil {
+		if err == store.ErrKeyNotFound {
+			return 0
+		}
+		logger.Panic(err)
+	}
+	return count
+}
+
+// FindTxsByAddress returns up to limit indexed transactions involving the given address,
+// starting from the given position in the order they were added.
+func (ch *Chain) FindTxsByAddress(address common.Address, start uint64, limit uint64) []AddressTxEntry {
+	entries := []AddressTxEntry{}
+	count := ch.GetAddressTxCount(address)
+	for n := start; n < count && uint64(len(entries)) < limit; n++ {
+		entry := AddressTxEntry{}
+		err := ch.store.Get(addressTxKey(address, n), &entry)
+		if err != nil {
+			logger.Panic(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func newTestSendTx(from, to common.Address) common.Bytes {
+	tx := &types.SendTx{
+		Fee:     types.NewCoins(0, 1000000000000),
+		Inputs:  []types.TxInput{{Address: from, Coins: types.NewCoins(10, 1000000000000), Sequence: 1}},
+		Outputs: []types.TxOutput{{Address: to, Coins: types.NewCoins(10, 0)}},
+	}
+	raw, _ := types.TxToBytes(tx)
+	return raw
+}
+
+func TestAddressIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	alice := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	bob := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	carol := common.HexToAddress("0x3333333333333333333333333333333333333333")
+
+	core.ResetTestBlocks()
+	chain := CreateTestChain()
+
+	block1 := core.CreateTestBlock("b1", "")
+	block1.Height = 10
+	block1.Txs = []common.Bytes{newTestSendTx(alice, bob), newTestSendTx(bob, carol)}
+	block1.UpdateHash()
+	eb1, _ := chain.AddBlock(block1)
+
+	block2 := core.CreateTestBlock("b2", "")
+	block2.Height = 11
+	block2.Txs = []common.Bytes{newTestSendTx(carol, alice)}
+	block2.UpdateHash()
+	eb2, _ := chain.AddBlock(block2)
+
+	chain.AddTxsToAddressIndex(eb1)
+	chain.AddTxsToAddressIndex(eb2)
+
+	assert.Equal(uint64(2), chain.GetAddressTxCount(alice))
+	assert.Equal(uint64(2), chain.GetAddressTxCount(bob))
+	assert.Equal(uint64(2), chain.GetAddressTxCount(carol))
+
+	entries := chain.FindTxsByAddress(alice, 0, 10)
+	assert.Equal(2, len(entries))
+	assert.Equal(crypto.HashAtHeight(10, block1.Txs[0]), entries[0].TxHash)
+	assert.Equal(uint64(10), entries[0].BlockHeight)
+	assert.Equal(crypto.HashAtHeight(11, block2.Txs[0]), entries[1].TxHash)
+	assert.Equal(eb2.Hash(), entries[1].BlockHash)
+
+	entries = chain.FindTxsByAddress(bob, 1, 10)
+	assert.Equal(1, len(entries))
+	assert.Equal(uint64(1), entries[0].Index)
+
+	// Adding a block again does not duplicate the entries.
+	chain.AddTxsToAddressIndex(eb1)
+	chain.AddTxsToAddressIndex(eb2)
+	assert.Equal(uint64(2), chain.GetAddressTxCount(alice))
+	assert.Equal(uint64(0), chain.GetAddressTxCount(common.HexToAddress("0x4444444444444444444444444444444444444444")))
+}
@@ -9,13 +9,22 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/event"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store"
+	"github.com/thetatoken/theta/store/archive"
 )
 
 const maxDistance = 200
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "blockchain"})
+// ErrBlockBodyPruned is returned when the block is known but its body has been pruned. The
+// header of the block can still be retrieved with FindBlockHeader.
+var ErrBlockBodyPruned = errors.New("Block body has been pruned")
+
+var logger *log.Entry = util.GetLoggerForModule("blockchain")
 
 // Chain represents the blockchain and also is the interface to underlying store.
 type Chain struct {
@@ -24,6 +33,14 @@ type Chain struct {
 	ChainID string
 	root    common.Hash
 
+	eventBus *event.Bus
+
+	// Number of finalized heights whose block bodies are kept, 0 keeps all the bodies
+	blockBodyRetention uint64
+
+	// Memory-mapped archive the bodies of the finalized blocks are moved to, nil keeps them in the store
+	archive *archive.Archive
+
 	mu *sync.RWMutex
 }
 
@@ -34,7 +51,7 @@ func NewChain(chainID string, store store.Store, root *core.Block) *Chain {
 		store:   store,
 		mu:      &sync.RWMutex{},
 	}
-	rootBlock, err := chain.FindBlock(root.Hash())
+	rootBlock, err := chain.FindBlockHeader(root.Hash())
 	if err != nil {
 		logger.WithFields(log.Fields{"Hash": root.Hash().Hex()}).Info("Root block is not found in chain. Adding block.")
 		rootBlock, err = chain.AddSnapshotRoot(root)
@@ -47,9 +64,9 @@ func NewChain(chainID string, store store.Store, root *core.Block) *Chain {
 	return chain
 }
 
-// Root returns the root block
+// Root returns the root block, without its body
 func (ch *Chain) Root() *core.ExtendedBlock {
-	ret, _ := ch.FindBlock(ch.root)
+	ret, _ := ch.FindBlockHeader(ch.root)
 	return ret
 }
 
@@ -58,9 +75,35 @@ func (ch *Chain) AddSnapshotRoot(block *core.Block) (*core.ExtendedBlock, error)
 	return ch.addBlock(block, true)
 }
 
+// SetEventBus sets the bus the added blocks are published to.
+func (ch *Chain) SetEventBus(eventBus *event.Bus) {
+	ch.eventBus = eventBus
+}
+
+// SetBlockBodyRetention sets the number of finalized heights whose block bodies are kept. The
+// bodies of the blocks finalized further back are pruned, while their headers are retained.
+// 0 keeps all the bodies.
+func (ch *Chain) SetBlockBodyRetention(retention uint64) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.blockBodyRetention = retention
+}
+
+// SetBlockArchive sets the archive the bodies of the finalized blocks are moved to. The bodies
+// are then read from the memory mapping of the archive instead of the KV store.
+func (ch *Chain) SetBlockArchive(a *archive.Archive) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.archive = a
+}
+
 // AddBlock adds a block to the chain and underlying store
 func (ch *Chain) AddBlock(block *core.Block) (*core.ExtendedBlock, error) {
-	return ch.addBlock(block, false)
+	extendedBlock, err := ch.addBlock(block, false)
+	if err == nil {
+		ch.eventBus.Publish(event.BlockAdded{Block: extendedBlock})
+	}
+	return extendedBlock, err
 }
 
 func (ch *Chain) addBlock(block *core.Block, isSnapshotRoot bool) (*core.ExtendedBlock, error) {
@@ -71,16 +114,16 @@ func (ch *Chain) addBlock(block *core.Block, isSnapshotRoot bool) (*core.Extende
 		return nil, errors.Errorf("ChainID mismatch: block.ChainID(%s) != %s", block.ChainID, ch.ChainID)
 	}
 
-	val := &core.ExtendedBlock{}
 	hash := block.Hash()
-	err := ch.store.Get(hash[:], val)
+	val, err := ch.findBlockHeader(hash)
 	if err == nil {
 		// Block has already been added.
 		return val, fmt.Errorf("Block has already been added: %X", hash[:])
 	}
 
+	batch := ch.store.NewBatch()
 	if !block.Parent.IsEmpty() && !isSnapshotRoot {
-		parentBlock, err := ch.findBlock(block.Parent)
+		parentBlock, err := ch.findBlockHeader(block.Parent)
 		if err == store.ErrKeyNotFound {
 			// Parent block is not known yet, abandon block.
 			return nil, errors.Errorf("Unknown parent block: %v", block.Parent.Hex())
@@ -91,7 +134,7 @@ func (ch *Chain) addBlock(block *core.Block, isSnapshotRoot bool) (*core.Extende
 
 		parentBlock.Children = append(parentBlock.Children, hash)
 
-		err = ch.saveBlock(parentBlock)
+		err = writeBlock(batch, parentBlock)
 		if err != nil {
 			log.Panic(err)
 		}
@@ -99,13 +142,27 @@ func (ch *Chain) addBlock(block *core.Block, isSnapshotRoot bool) (*core.Extende
 
 	extendedBlock := &core.ExtendedBlock{Block: block}
 
-	err = ch.saveBlock(extendedBlock)
+	err = writeBlock(batch, extendedBlock)
 	if err != nil {
 		logger.Panic(err)
 	}
+	if len(block.Txs) == 0 {
+		// Record the empty body, so that the block is not taken for one whose body was pruned
+		err = batch.Put(blockBodyKey(hash), *block.Body())
+		if err != nil {
+			logger.Panic(err)
+		}
+	}
 
-	ch.AddBlockByHeightIndex(extendedBlock.Height, extendedBlock.Hash())
-	ch.AddTxsToIndex(extendedBlock, false)
+	ch.addBlockByHeightIndex(batch, extendedBlock.Height, extendedBlock.Hash())
+	ch.addTxsToIndex(batch, extendedBlock, false)
+
+	// The block, its parent and the indexes are written at once, so that a crash cannot leave
+	// the block without its index entries or unreachable from its parent.
+	err = batch.Write()
+	if err != nil {
+		logger.Panic(err)
+	}
 
 	return extendedBlock, nil
 }
@@ -124,6 +181,10 @@ type BlockByHeightIndexEntry struct {
 }
 
 func (ch *Chain) AddBlockByHeightIndex(height uint64, block common.Hash) {
+	ch.addBlockByHeightIndex(ch.store, height, block)
+}
+
+func (ch *Chain) addBlockByHeightIndex(w store.Writer, height uint64, block common.Hash) {
 	key := blockByHeightIndexKey(height)
 	blockByHeightIndexEntry := BlockByHeightIndexEntry{
 		Blocks: []common.Hash{},
@@ -140,13 +201,14 @@ func (ch *Chain) AddBlockByHeightIndex(height uint64, block common.Hash) {
 
 	blockByHeightIndexEntry.Blocks = append(blockByHeightIndexEntry.Blocks, block)
 
-	err := ch.store.Put(key, blockByHeightIndexEntry)
+	err := w.Put(key, blockByHeightIndexEntry)
 	if err != nil {
 		logger.Panic(err)
 	}
 }
 
-// FindBlocksByHeight tries to retrieve blocks by height.
+// FindBlocksByHeight tries to retrieve blocks by height. The blocks whose bodies have been
+// pruned are skipped, see FindBlockHeadersByHeight.
 func (ch *Chain) FindBlocksByHeight(height uint64) []*core.ExtendedBlock {
 	ch.mu.RLock()
 	defer ch.mu.RUnlock()
@@ -155,33 +217,77 @@ func (ch *Chain) FindBlocksByHeight(height uint64) []*core.ExtendedBlock {
 
 // findBlocksByHeight is the non-locking version of FindBlockByHeight.
 func (ch *Chain) findBlocksByHeight(height uint64) []*core.ExtendedBlock {
-	key := blockByHeightIndexKey(height)
-	blockByHeightIndexEntry := BlockByHeightIndexEntry{
-		Blocks: []common.Hash{},
-	}
-	ch.store.Get(key, &blockByHeightIndexEntry)
-
 	ret := []*core.ExtendedBlock{}
-	for _, hash := range blockByHeightIndexEntry.Blocks {
+	for _, hash := range loadBlockHashesByHeight(ch.store, height) {
 		block, err := ch.findBlock(hash)
 		if err == nil {
 			ret = append(ret, block)
 		}
+	}
+	return ret
+}
+
+// FindBlockHeadersByHeight retrieves the blocks at the given height without their bodies,
+// including the blocks whose bodies have been pruned.
+func (ch *Chain) FindBlockHeadersByHeight(height uint64) []*core.ExtendedBlock {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return LoadBlockHeadersByHeight(ch.store, height)
+}
 
+// LoadBlockHeadersByHeight returns the blocks at the given height from the block store, without
+// their bodies. It is meant for the components which have access to the block store but not to
+// the Chain.
+func LoadBlockHeadersByHeight(store store.Store, height uint64) []*core.ExtendedBlock {
+	ret := []*core.ExtendedBlock{}
+	for _, hash := range loadBlockHashesByHeight(store, height) {
+		block, err := loadBlockHeader(store, hash)
+		if err == nil {
+			ret = append(ret, block)
+		}
 	}
 	return ret
 }
 
+func loadBlockHashesByHeight(db store.Store, height uint64) []common.Hash {
+	key := blockByHeightIndexKey(height)
+	blockByHeightIndexEntry := BlockByHeightIndexEntry{
+		Blocks: []common.Hash{},
+	}
+	db.Get(key, &blockByHeightIndexEntry)
+	return blockByHeightIndexEntry.Blocks
+}
+
 func (ch *Chain) MarkBlockValid(hash common.Hash) *core.ExtendedBlock {
+	return ch.MarkBlockValidWithReceipts(hash, false, nil)
+}
+
+// MarkBlockValidWithReceipts marks the block as valid, and whether it updates the validators,
+// in the same write as its receipts, so that a valid block is never missing them. The status
+// updates only rewrite the header record, so the returned block does not contain the body.
+func (ch *Chain) MarkBlockValidWithReceipts(hash common.Hash, hasValidatorUpdate bool, receipts []*types.Receipt) *core.ExtendedBlock {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
-	block, err := ch.findBlock(hash)
+	block, err := ch.findBlockHeader(hash)
 	if err != nil {
 		logger.Panic(err)
 	}
 	block.Status = core.BlockStatusValid
-	err = ch.saveBlock(block)
+	if hasValidatorUpdate {
+		block.HasValidatorUpdate = true
+	}
+
+	batch := ch.store.NewBatch()
+	err = addBlockReceipts(batch, hash, receipts)
+	if err != nil {
+		logger.Panic(err)
+	}
+	err = writeBlock(batch, block)
+	if err != nil {
+		logger.Panic(err)
+	}
+	err = batch.Write()
 	if err != nil {
 		logger.Panic(err)
 	}
@@ -192,7 +298,7 @@ func (ch *Chain) MarkBlockInvalid(hash common.Hash) *core.ExtendedBlock {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
-	block, err := ch.findBlock(hash)
+	block, err := ch.findBlockHeader(hash)
 	if err != nil {
 		logger.Panic(err)
 	}
@@ -208,7 +314,7 @@ func (ch *Chain) MarkBlockHasValidatorUpdate(hash common.Hash) *core.ExtendedBlo
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
-	block, err := ch.findBlock(hash)
+	block, err := ch.findBlockHeader(hash)
 	if err != nil {
 		logger.Panic(err)
 	}
@@ -224,7 +330,7 @@ func (ch *Chain) CommitBlock(hash common.Hash) {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
-	block, err := ch.findBlock(hash)
+	block, err := ch.findBlockHeader(hash)
 	if err != nil {
 		logger.Panic(err)
 	}
@@ -240,10 +346,12 @@ func (ch *Chain) FinalizePreviousBlocks(hash common.Hash) {
 	defer ch.mu.Unlock()
 
 	status := core.BlockStatusDirectlyFinalized
+	finalizedHeights := []uint64{}
+	finalizedBlocks := []*core.ExtendedBlock{}
 	for !hash.IsEmpty() {
-		block, err := ch.findBlock(hash)
+		block, err := ch.findBlockHeader(hash)
 		if err != nil || block.Status.IsFinalized() {
-			return
+			break
 		}
 		block.Status = status
 		status = core.BlockStatusIndirectlyFinalized // Only the first block is marked as directly finalized
@@ -251,22 +359,116 @@ func (ch *Chain) FinalizePreviousBlocks(hash common.Hash) {
 		if err != nil {
 			logger.Panic(err)
 		}
+		finalizedHeights = append(finalizedHeights, block.Height)
+		finalizedBlocks = append(finalizedBlocks, block)
 		hash = block.Parent
 	}
+
+	if ch.archive != nil {
+		// The archive is append-only, so the blocks are archived from the lowest height
+		for i := len(finalizedBlocks) - 1; i >= 0; i-- {
+			err := ch.archiveBlockBody(finalizedBlocks[i])
+			if err != nil {
+				logger.Warnf("Failed to archive the body of block %v: %v", finalizedBlocks[i].Hash().Hex(), err)
+			}
+		}
+	}
+
+	if ch.blockBodyRetention == 0 {
+		return
+	}
+	for _, height := range finalizedHeights {
+		if height > ch.blockBodyRetention {
+			ch.pruneBlockBodiesAtHeight(height - ch.blockBodyRetention)
+		}
+	}
 }
 
 func (ch *Chain) IsOrphan(block *core.Block) bool {
-	_, err := ch.FindBlock(block.Parent)
+	_, err := ch.FindBlockHeader(block.Parent)
 	return err != nil
 }
 
 // saveBlock updates a previously stored block.
 func (ch *Chain) saveBlock(block *core.ExtendedBlock) error {
+	return writeBlock(ch.store, block)
+}
+
+// blockBodyKey constructs the DB key of the body of the block. The header and the status of the
+// block are stored under the block hash.
+func blockBodyKey(hash common.Hash) common.Bytes {
+	return append(common.Bytes("bb/"), hash[:]...)
+}
+
+// writeBlock writes the header record of the block, and its body unless the block was loaded
+// without it.
+func writeBlock(w store.Writer, block *core.ExtendedBlock) error {
 	hash := block.Hash()
-	return ch.store.Put(hash[:], *block)
+	header := *block
+	header.Block = &core.Block{BlockHeader: block.BlockHeader}
+	err := w.Put(hash[:], header)
+	if err != nil {
+		return err
+	}
+	if len(block.Txs) == 0 {
+		return nil
+	}
+	return w.Put(blockBodyKey(hash), *block.Body())
+}
+
+// loadBlockHeader loads the header record of the block. The records written before the bodies
+// were stored separately also contain the transactions.
+func loadBlockHeader(db store.Store, hash common.Hash) (*core.ExtendedBlock, error) {
+	var block core.ExtendedBlock
+	err := db.Get(hash[:], &block)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// loadBlockBody fills the transactions of the block from its body record in the store, if the
+// header record does not contain them. It returns ErrBlockBodyPruned if the body record has
+// been removed.
+func loadBlockBody(db store.Store, block *core.ExtendedBlock) error {
+	if len(block.Txs) > 0 {
+		return nil
+	}
+	var body core.BlockBody
+	err := db.Get(blockBodyKey(block.Hash()), &body)
+	if err == store.ErrKeyNotFound {
+		if block.TxHash == core.EmptyRootHash {
+			return nil // Empty block written before the bodies were stored separately
+		}
+		return ErrBlockBodyPruned
+	}
+	if err != nil {
+		return err
+	}
+	block.Txs = body.Txs
+	return nil
+}
+
+// loadArchivedBlockBody fills the transactions of the finalized block from the block archive.
+// It returns archive.ErrNotFound if the body has not been archived.
+func (ch *Chain) loadArchivedBlockBody(block *core.ExtendedBlock) error {
+	if ch.archive == nil || !block.Status.IsFinalized() {
+		return archive.ErrNotFound
+	}
+	return ch.archive.View(block.Height, func(record []byte) error {
+		var body core.BlockBody
+		err := rlp.DecodeBytes(record, &body) // copies the transactions out of the mapping
+		if err != nil {
+			return err
+		}
+		block.Txs = body.Txs
+		return nil
+	})
 }
 
-// FindBlock tries to retrieve a block by hash.
+// FindBlock tries to retrieve a block by hash, with its body. It returns ErrBlockBodyPruned if
+// the body of the block has been pruned, the callers which only need the header and the status
+// of the block should use FindBlockHeader instead.
 func (ch *Chain) FindBlock(hash common.Hash) (*core.ExtendedBlock, error) {
 	ch.mu.RLock()
 	defer ch.mu.RUnlock()
@@ -275,12 +477,147 @@ func (ch *Chain) FindBlock(hash common.Hash) (*core.ExtendedBlock, error) {
 
 // findBlock is the non-locking version of FindBlock.
 func (ch *Chain) findBlock(hash common.Hash) (*core.ExtendedBlock, error) {
-	var block core.ExtendedBlock
-	err := ch.store.Get(hash[:], &block)
+	block, err := loadBlockHeader(ch.store, hash)
 	if err != nil {
 		return nil, err
 	}
-	return &block, nil
+	err = ch.loadBlockBody(block)
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// loadBlockBody fills the transactions of the block from the block archive or the store. It
+// returns ErrBlockBodyPruned if the body has been pruned.
+func (ch *Chain) loadBlockBody(block *core.ExtendedBlock) error {
+	if len(block.Txs) > 0 {
+		return nil
+	}
+	err := ch.loadArchivedBlockBody(block)
+	if err == archive.ErrNotFound {
+		err = loadBlockBody(ch.store, block)
+	}
+	return err
+}
+
+// FindBlockHeader tries to retrieve a block by hash without loading its body. It is cheaper than
+// FindBlock for the callers which only need the header and the status of the block.
+func (ch *Chain) FindBlockHeader(hash common.Hash) (*core.ExtendedBlock, error) {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.findBlockHeader(hash)
+}
+
+// findBlockHeader is the non-locking version of FindBlockHeader.
+func (ch *Chain) findBlockHeader(hash common.Hash) (*core.ExtendedBlock, error) {
+	return loadBlockHeader(ch.store, hash)
+}
+
+// HasBlockBody returns whether the body of the block is available, i.e. the block is known and
+// its body has not been pruned.
+func (ch *Chain) HasBlockBody(hash common.Hash) bool {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	block, err := ch.findBlockHeader(hash)
+	if err != nil {
+		return false
+	}
+	return ch.hasBlockBody(block)
+}
+
+func (ch *Chain) hasBlockBody(block *core.ExtendedBlock) bool {
+	if len(block.Txs) > 0 {
+		return true
+	}
+	var body core.BlockBody
+	if ch.store.Get(blockBodyKey(block.Hash()), &body) == nil {
+		return true
+	}
+	if ch.archive != nil && block.Status.IsFinalized() && ch.archive.Has(block.Height) {
+		return true
+	}
+	return block.TxHash == core.EmptyRootHash // Empty block written before the bodies were stored separately
+}
+
+// PruneBlockBody removes the body of a finalized block from the store, while its header is
+// retained.
+func (ch *Chain) PruneBlockBody(hash common.Hash) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.pruneBlockBody(hash)
+}
+
+func (ch *Chain) pruneBlockBody(hash common.Hash) error {
+	block, err := ch.findBlockHeader(hash)
+	if err != nil {
+		return err
+	}
+	if !block.Status.IsFinalized() {
+		return errors.Errorf("Cannot prune the body of block %v which is not finalized", hash.Hex())
+	}
+	return ch.removeBlockBody(block)
+}
+
+// removeBlockBody removes the body of the block from the store
+func (ch *Chain) removeBlockBody(block *core.ExtendedBlock) error {
+	batch := ch.store.NewBatch()
+	if len(block.Txs) > 0 {
+		// Record written with the transactions
+		block.Txs = nil
+		err := writeBlock(batch, block)
+		if err != nil {
+			return err
+		}
+	}
+	err := batch.Delete(blockBodyKey(block.Hash()))
+	if err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// archiveBlockBody moves the body of the finalized block from the store to the block archive.
+// The bodies of the heights already archived, e.g. of a block finalized in a snapshot, are left
+// in the store.
+func (ch *Chain) archiveBlockBody(block *core.ExtendedBlock) error {
+	if block.Height < ch.archive.NextHeight() {
+		return nil
+	}
+	err := loadBlockBody(ch.store, block)
+	if err == ErrBlockBodyPruned {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(block.Txs) == 0 {
+		return nil // Empty body
+	}
+	record, err := rlp.EncodeToBytes(*block.Body())
+	if err != nil {
+		return err
+	}
+	err = ch.archive.Append(block.Height, record)
+	if err != nil {
+		return err
+	}
+	return ch.removeBlockBody(block)
+}
+
+// pruneBlockBodiesAtHeight prunes the bodies of the finalized blocks at the height
+func (ch *Chain) pruneBlockBodiesAtHeight(height uint64) {
+	for _, hash := range loadBlockHashesByHeight(ch.store, height) {
+		block, err := ch.findBlockHeader(hash)
+		if err != nil || !block.Status.IsFinalized() {
+			continue
+		}
+		err = ch.pruneBlockBody(hash)
+		if err != nil {
+			logger.Warnf("Failed to prune the body of block %v: %v", hash.Hex(), err)
+		}
+	}
 }
 
 // IsDescendant determines whether one block is the ascendant of another block.
@@ -290,7 +627,7 @@ func (ch *Chain) IsDescendant(ascendantHash common.Hash, descendantHash common.H
 		if hash == ascendantHash {
 			return true
 		}
-		currBlock, err := ch.FindBlock(hash)
+		currBlock, err := ch.FindBlockHeader(hash)
 		if err != nil {
 			return false
 		}
@@ -1,11 +1,17 @@
 package blockchain
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/event"
+	"github.com/thetatoken/theta/store/archive"
 )
 
 func TestBlockchain(t *testing.T) {
@@ -117,3 +123,230 @@ func TestBlockIndex(t *testing.T) {
 	assert.Equal(core.GetTestBlock("a2").Hash(), blocks[0].Hash())
 	assert.Equal(core.GetTestBlock("b2").Hash(), blocks[1].Hash())
 }
+
+func TestRemoveDescendants(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	ch := CreateTestChainByBlocks([]string{
+		"a1", "a0",
+		"a2", "a1",
+		"a3", "a2",
+		"b2", "a1",
+		"b3", "b2",
+		"c1", "a0",
+	})
+
+	removed, err := ch.RemoveDescendants(core.GetTestBlock("a1").Hash())
+	require.Nil(err)
+	assert.Equal(4, removed)
+
+	a1, err := ch.FindBlock(core.GetTestBlock("a1").Hash())
+	require.Nil(err)
+	assert.Equal(0, len(a1.Children))
+	for _, name := range []string{"a2", "a3", "b2", "b3"} {
+		_, err = ch.FindBlock(core.GetTestBlock(name).Hash())
+		assert.NotNil(err, name)
+	}
+	for _, name := range []string{"a0", "c1"} {
+		_, err = ch.FindBlock(core.GetTestBlock(name).Hash())
+		assert.Nil(err, name)
+	}
+	assert.Equal(2, len(ch.FindBlocksByHeight(1)))
+	assert.Equal(0, len(ch.FindBlocksByHeight(2)))
+	assert.Equal(0, len(ch.FindBlocksByHeight(3)))
+
+	// The removed blocks can be added again
+	_, err = ch.AddBlock(core.GetTestBlock("a2"))
+	assert.Nil(err)
+	a1, err = ch.FindBlock(core.GetTestBlock("a1").Hash())
+	require.Nil(err)
+	assert.Equal(1, len(a1.Children))
+}
+
+func TestChainEventBus(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	chain := CreateTestChain()
+	bus := event.NewBus()
+	chain.SetEventBus(bus)
+	sub := bus.Subscribe(10, event.TopicBlockAdded)
+
+	a1 := core.CreateTestBlock("a1", "a0")
+	_, err := chain.AddBlock(a1)
+	assert.Nil(err)
+	assert.Equal(1, len(sub.Events()))
+	assert.Equal(a1.Hash(), (<-sub.Events()).(event.BlockAdded).Block.Hash())
+
+	// The blocks already added are not published again
+	_, err = chain.AddBlock(a1)
+	assert.NotNil(err)
+	assert.Equal(0, len(sub.Events()))
+}
+
+func createTestBlockWithTxs(chain *Chain, parent *core.ExtendedBlock, epoch uint64, txs ...string) *core.Block {
+	block := core.NewBlock()
+	block.ChainID = chain.ChainID
+	block.Parent = parent.Hash()
+	block.Height = parent.Height + 1
+	block.Epoch = epoch
+	for _, tx := range txs {
+		block.AddTxs([]common.Bytes{common.Bytes(tx)})
+	}
+	return block
+}
+
+func TestBlockBodyStorage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	chain := CreateTestChain()
+	block := createTestBlockWithTxs(chain, chain.Root(), 100, "tx1", "tx2")
+	_, err := chain.AddBlock(block)
+	require.Nil(err)
+	hash := block.Hash()
+
+	// The header record does not contain the transactions
+	header, err := chain.FindBlockHeader(hash)
+	require.Nil(err)
+	assert.Equal(block.TxHash, header.TxHash)
+	assert.Empty(header.Txs)
+	eb, err := chain.FindBlock(hash)
+	require.Nil(err)
+	assert.Equal(block.Txs, eb.Txs)
+	assert.True(chain.HasBlockBody(hash))
+	assert.True(chain.HasBlockBody(chain.Root().Hash()))
+
+	// Status updates keep the body
+	chain.CommitBlock(hash)
+	eb, err = chain.FindBlock(hash)
+	require.Nil(err)
+	assert.Equal(block.Txs, eb.Txs)
+
+	// Only the bodies of the finalized blocks are pruned
+	assert.NotNil(chain.PruneBlockBody(hash))
+	chain.FinalizePreviousBlocks(hash)
+	assert.Nil(chain.PruneBlockBody(hash))
+	assert.False(chain.HasBlockBody(hash))
+	_, err = chain.FindBlock(hash)
+	assert.Equal(ErrBlockBodyPruned, err)
+	eb, err = chain.FindBlockHeader(hash)
+	require.Nil(err)
+	assert.Equal(block.TxHash, eb.TxHash)
+	assert.True(eb.Status.IsFinalized())
+	assert.Empty(chain.FindBlocksByHeight(block.Height))
+	assert.Equal(1, len(chain.FindBlockHeadersByHeight(block.Height)))
+
+	// A status update of a pruned block does not restore its body
+	chain.MarkBlockHasValidatorUpdate(hash)
+	assert.False(chain.HasBlockBody(hash))
+	_, err = chain.FindBlock(hash)
+	assert.Equal(ErrBlockBodyPruned, err)
+
+	// Nor does adding the block again
+	_, err = chain.AddBlock(block)
+	assert.NotNil(err)
+	assert.False(chain.HasBlockBody(hash))
+}
+
+func TestBlockBodyStorageLegacyRecord(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	// Block written with its transactions in the header record
+	chain := CreateTestChain()
+	block := createTestBlockWithTxs(chain, chain.Root(), 100, "tx1")
+	hash := block.Hash()
+	require.Nil(chain.store.Put(hash[:], core.ExtendedBlock{Block: block, Status: core.BlockStatusDirectlyFinalized}))
+
+	eb, err := chain.FindBlock(hash)
+	require.Nil(err)
+	assert.Equal(block.Txs, eb.Txs)
+	assert.True(chain.HasBlockBody(hash))
+
+	assert.Nil(chain.PruneBlockBody(hash))
+	assert.False(chain.HasBlockBody(hash))
+	_, err = chain.FindBlock(hash)
+	assert.Equal(ErrBlockBodyPruned, err)
+	_, err = chain.FindBlockHeader(hash)
+	assert.Nil(err)
+}
+
+func TestBlockBodyRetention(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	chain := CreateTestChain()
+	chain.SetBlockBodyRetention(2)
+	hashes := []common.Hash{}
+	parent := chain.Root()
+	for i := 0; i < 4; i++ {
+		eb, err := chain.AddBlock(createTestBlockWithTxs(chain, parent, uint64(100+i), fmt.Sprintf("tx%v", i)))
+		require.Nil(err)
+		hashes = append(hashes, eb.Hash())
+		parent = eb
+	}
+
+	// The bodies of the blocks finalized more than 2 heights back are pruned
+	chain.FinalizePreviousBlocks(hashes[3])
+	assert.False(chain.HasBlockBody(hashes[0]))
+	assert.False(chain.HasBlockBody(hashes[1]))
+	assert.True(chain.HasBlockBody(hashes[2]))
+	assert.True(chain.HasBlockBody(hashes[3]))
+	for _, hash := range hashes {
+		_, err := chain.FindBlockHeader(hash)
+		assert.Nil(err)
+	}
+}
+
+func TestBlockArchive(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	dir, err := ioutil.TempDir("", "archive")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	blockArchive, err := archive.Open(dir)
+	require.Nil(err)
+	defer blockArchive.Close()
+
+	chain := CreateTestChain()
+	chain.SetBlockArchive(blockArchive)
+	blocks := []*core.ExtendedBlock{}
+	parent := chain.Root()
+	for i := 0; i < 3; i++ {
+		eb, err := chain.AddBlock(createTestBlockWithTxs(chain, parent, uint64(100+i), fmt.Sprintf("tx%v", i)))
+		require.Nil(err)
+		blocks = append(blocks, eb)
+		parent = eb
+	}
+
+	// The bodies of the finalized blocks are moved to the archive
+	chain.FinalizePreviousBlocks(blocks[1].Hash())
+	for i, block := range blocks {
+		assert.Equal(i < 2, blockArchive.Has(block.Height))
+		assert.True(chain.HasBlockBody(block.Hash()))
+
+		found, err := chain.FindBlock(block.Hash())
+		require.Nil(err)
+		assert.Equal([]common.Bytes{common.Bytes(fmt.Sprintf("tx%v", i))}, found.Txs)
+		found = chain.FindBlocksByHeight(block.Height)[0]
+		assert.Equal([]common.Bytes{common.Bytes(fmt.Sprintf("tx%v", i))}, found.Txs)
+	}
+
+	// The archived bodies are no longer kept in the store
+	var body core.BlockBody
+	assert.NotNil(chain.store.Get(blockBodyKey(blocks[0].Hash()), &body))
+
+	// The archived bodies above the block are removed on rollback
+	_, err = chain.RemoveDescendants(blocks[0].Hash())
+	require.Nil(err)
+	assert.True(blockArchive.Has(blocks[0].Height))
+	assert.False(blockArchive.Has(blocks[1].Height))
+}
@@ -0,0 +1,236 @@
+package blockchain
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+// ForkBlock is a block in the fork tree, together with the votes it received.
+type ForkBlock struct {
+	Hash        common.Hash       `json:"hash"`
+	Parent      common.Hash       `json:"parent"`
+	Height      common.JSONUint64 `json:"height"`
+	Epoch       common.JSONUint64 `json:"epoch"`
+	Proposer    common.Address    `json:"proposer"`
+	Status      core.BlockStatus  `json:"status"`
+	Voters      []common.Address  `json:"voters"`
+	VoteWeight  *common.JSONBig   `json:"vote_weight"`  // Total stake of the validators voted for the block
+	HasMajority bool              `json:"has_majority"` // Whether the votes form a commit certificate
+	Children    []*ForkBlock      `json:"children"`
+}
+
+// Branch summarizes the path from a fork point to a leaf of the block tree.
+type Branch struct {
+	Leaf            common.Hash       `json:"leaf"`
+	LeafHeight      common.JSONUint64 `json:"leaf_height"`
+	ForkPoint       common.Hash       `json:"fork_point"` // Last block shared with the other branches
+	ForkHeight      common.JSONUint64 `json:"fork_height"`
+	Length          common.JSONUint64 `json:"length"`      // Number of blocks after the fork point
+	VoteWeight      *common.JSONBig   `json:"vote_weight"` // Max vote weight of the blocks after the fork point
+	HighestCC       common.Hash       `json:"highest_cc"`  // Highest block with a commit certificate, empty if none
+	FinalizedHeight common.JSONUint64 `json:"finalized_height"`
+	Finalized       bool              `json:"finalized"` // Whether the leaf is finalized
+}
+
+type branchFrame struct {
+	hash  common.Hash
+	depth int
+}
+
+//
+// BranchIterator enumerates the branches of the block tree in depth first order, each as the
+// path from the start block to a leaf. Blocks are loaded as the iteration proceeds, so that
+// the blocks added by a reorg during the iteration are picked up if their parent has not been
+// visited yet.
+//
+type BranchIterator struct {
+	chain    *Chain
+	maxDepth int
+	valMgr   core.ValidatorManager
+
+	path  []*ForkBlock
+	stack []branchFrame
+}
+
+// NewBranchIterator creates an iterator over the branches starting from the given block. The
+// branches are truncated after maxDepth blocks. The vote weights are computed with the
+// validator sets of valMgr, and are left empty if valMgr is nil.
+func (ch *Chain) NewBranchIterator(from common.Hash, maxDepth int, valMgr core.ValidatorManager) *BranchIterator {
+	return &BranchIterator{
+		chain:    ch,
+		maxDepth: maxDepth,
+		valMgr:   valMgr,
+		stack:    []branchFrame{{hash: from, depth: 0}},
+	}
+}
+
+// Next returns the path from the start block to the next leaf, or false if all the branches
+// have been enumerated.
+func (it *BranchIterator) Next() ([]*ForkBlock, bool) {
+	for len(it.stack) > 0 {
+		frame := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+
+		block, err := it.chain.FindBlockHeader(frame.hash)
+		if err != nil {
+			logger.Warnf("Failed to load block %v while iterating branches: %v", frame.hash.Hex(), err)
+			continue
+		}
+		it.path = append(it.path[:frame.depth], it.chain.newForkBlock(block, it.valMgr))
+
+		if len(block.Children) == 0 || frame.depth+1 >= it.maxDepth {
+			path := make([]*ForkBlock, len(it.path))
+			copy(path, it.path)
+			return path, true
+		}
+		for i := len(block.Children) - 1; i >= 0; i-- {
+			it.stack = append(it.stack, branchFrame{hash: block.Children[i], depth: frame.depth + 1})
+		}
+	}
+	return nil, false
+}
+
+// GetForkTree returns the tree of the blocks descending from the given block, up to maxDepth
+// levels.
+func (ch *Chain) GetForkTree(from common.Hash, maxDepth int, valMgr core.ValidatorManager) (*ForkBlock, error) {
+	block, err := ch.FindBlockHeader(from)
+	if err != nil {
+		return nil, err
+	}
+	root := ch.newForkBlock(block, valMgr)
+	if maxDepth > 1 {
+		for _, child := range block.Children {
+			subtree, err := ch.GetForkTree(child, maxDepth-1, valMgr)
+			if err != nil {
+				logger.Warnf("Failed to load block %v while building fork tree: %v", child.Hex(), err)
+				continue
+			}
+			root.Children = append(root.Children, subtree)
+		}
+	}
+	return root, nil
+}
+
+// GetBranches summarizes all the known branches descending from the given block, up to
+// maxDepth blocks.
+func (ch *Chain) GetBranches(from common.Hash, maxDepth int, valMgr core.ValidatorManager) []Branch {
+	paths := [][]*ForkBlock{}
+	it := ch.NewBranchIterator(from, maxDepth, valMgr)
+	for path, ok := it.Next(); ok; path, ok = it.Next() {
+		paths = append(paths, path)
+	}
+	return summarizeBranches(paths)
+}
+
+// Branches summarizes the branches of the tree rooted at the block
+func (fb *ForkBlock) Branches() []Branch {
+	paths := [][]*ForkBlock{}
+	var collect func(node *ForkBlock, path []*ForkBlock)
+	collect = func(node *ForkBlock, path []*ForkBlock) {
+		path = append(path, node)
+		if len(node.Children) == 0 {
+			paths = append(paths, append([]*ForkBlock{}, path...))
+			return
+		}
+		for _, child := range node.Children {
+			collect(child, path)
+		}
+	}
+	collect(fb, []*ForkBlock{})
+	return summarizeBranches(paths)
+}
+
+// summarizeBranches summarizes the paths from the same start block to the leaves
+func summarizeBranches(paths [][]*ForkBlock) []Branch {
+	// Number of branches passing through each block, to locate the fork points
+	numBranches := make(map[common.Hash]int)
+	for _, path := range paths {
+		for _, block := range path {
+			numBranches[block.Hash]++
+		}
+	}
+
+	branches := []Branch{}
+	for _, path := range paths {
+		forkIdx := 0
+		for i := len(path) - 2; i >= 0; i-- {
+			if numBranches[path[i].Hash] > 1 {
+				forkIdx = i
+				break
+			}
+		}
+		leaf := path[len(path)-1]
+		branch := Branch{
+			Leaf:       leaf.Hash,
+			LeafHeight: leaf.Height,
+			ForkPoint:  path[forkIdx].Hash,
+			ForkHeight: path[forkIdx].Height,
+			Length:     common.JSONUint64(len(path) - 1 - forkIdx),
+			Finalized:  leaf.Status.IsFinalized(),
+		}
+		var maxWeight *big.Int
+		for i, block := range path {
+			if block.Status.IsFinalized() {
+				branch.FinalizedHeight = block.Height
+			}
+			if block.HasMajority {
+				branch.HighestCC = block.Hash
+			}
+			if i > forkIdx && block.VoteWeight != nil {
+				if weight := block.VoteWeight.ToInt(); maxWeight == nil || weight.Cmp(maxWeight) > 0 {
+					maxWeight = weight
+				}
+			}
+		}
+		branch.VoteWeight = (*common.JSONBig)(maxWeight)
+		branches = append(branches, branch)
+	}
+	return branches
+}
+
+func (ch *Chain) newForkBlock(block *core.ExtendedBlock, valMgr core.ValidatorManager) *ForkBlock {
+	forkBlock := &ForkBlock{
+		Hash:     block.Hash(),
+		Parent:   block.Parent,
+		Height:   common.JSONUint64(block.Height),
+		Epoch:    common.JSONUint64(block.Epoch),
+		Proposer: block.Proposer,
+		Status:   block.Status,
+		Voters:   []common.Address{},
+		Children: []*ForkBlock{},
+	}
+
+	votes := ch.FindVotesByHash(forkBlock.Hash).UniqueVoter()
+	for _, vote := range votes.Votes() {
+		forkBlock.Voters = append(forkBlock.Voters, vote.ID)
+	}
+
+	if validators := getValidatorSet(valMgr, forkBlock.Hash); validators != nil {
+		weight := new(big.Int)
+		for _, vote := range votes.Votes() {
+			if validator, err := validators.GetValidator(vote.ID); err == nil {
+				weight.Add(weight, validator.Stake)
+			}
+		}
+		forkBlock.VoteWeight = (*common.JSONBig)(weight)
+		forkBlock.HasMajority = validators.HasMajority(votes)
+	}
+	return forkBlock
+}
+
+// getValidatorSet returns nil if valMgr is nil, or the validator set of the block cannot be
+// determined, e.g. the validator candidate pools of its ancestors are missing.
+func getValidatorSet(valMgr core.ValidatorManager, blockHash common.Hash) (validators *core.ValidatorSet) {
+	if valMgr == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Debugf("Failed to get the validator set of block %v: %v", blockHash.Hex(), r)
+			validators = nil
+		}
+	}()
+	return valMgr.GetValidatorSet(blockHash)
+}
@@ -0,0 +1,153 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+type testValidatorManager struct {
+	valSet *core.ValidatorSet
+}
+
+func (m *testValidatorManager) SetConsensusEngine(consensus core.ConsensusEngine) {}
+
+func (m *testValidatorManager) GetProposer(blockHash common.Hash, epoch uint64) core.Validator {
+	return m.valSet.Validators()[0]
+}
+
+func (m *testValidatorManager) GetNextProposer(blockHash common.Hash, epoch uint64) core.Validator {
+	return m.valSet.Validators()[0]
+}
+
+func (m *testValidatorManager) GetValidatorSet(blockHash common.Hash) *core.ValidatorSet {
+	return m.valSet
+}
+
+func (m *testValidatorManager) GetNextValidatorSet(blockHash common.Hash) *core.ValidatorSet {
+	return m.valSet
+}
+
+func hashesOf(path []*ForkBlock) []common.Hash {
+	hashes := []common.Hash{}
+	for _, block := range path {
+		hashes = append(hashes, block.Hash)
+	}
+	return hashes
+}
+
+func TestBranchIterator(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	ch := CreateTestChainByBlocks([]string{
+		"a1", "a0",
+		"a2", "a1",
+		"b2", "a1",
+		"c1", "a0"})
+	a0 := core.GetTestBlock("a0").Hash()
+	a1 := core.GetTestBlock("a1").Hash()
+	a2 := core.GetTestBlock("a2").Hash()
+	b2 := core.GetTestBlock("b2").Hash()
+	c1 := core.GetTestBlock("c1").Hash()
+
+	paths := [][]common.Hash{}
+	it := ch.NewBranchIterator(a0, 100, nil)
+	for path, ok := it.Next(); ok; path, ok = it.Next() {
+		paths = append(paths, hashesOf(path))
+	}
+	assert.Equal([][]common.Hash{{a0, a1, a2}, {a0, a1, b2}, {a0, c1}}, paths)
+
+	// Branches are truncated at the max depth
+	paths = [][]common.Hash{}
+	it = ch.NewBranchIterator(a0, 2, nil)
+	for path, ok := it.Next(); ok; path, ok = it.Next() {
+		paths = append(paths, hashesOf(path))
+	}
+	assert.Equal([][]common.Hash{{a0, a1}, {a0, c1}}, paths)
+
+	// Blocks added during the iteration are picked up if their parent is not visited yet
+	it = ch.NewBranchIterator(a0, 100, nil)
+	path, ok := it.Next()
+	assert.True(ok)
+	assert.Equal([]common.Hash{a0, a1, a2}, hashesOf(path))
+	d2 := core.CreateTestBlock("d2", "c1")
+	_, err := ch.AddBlock(d2)
+	assert.Nil(err)
+	path, _ = it.Next()
+	assert.Equal([]common.Hash{a0, a1, b2}, hashesOf(path))
+	path, _ = it.Next()
+	assert.Equal([]common.Hash{a0, c1, d2.Hash()}, hashesOf(path))
+	_, ok = it.Next()
+	assert.False(ok)
+}
+
+func TestForkTreeAndBranches(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	ch := CreateTestChainByBlocks([]string{
+		"a1", "a0",
+		"a2", "a1",
+		"b2", "a1",
+		"c1", "a0"})
+	a0 := core.GetTestBlock("a0").Hash()
+	a1 := core.GetTestBlock("a1").Hash()
+	a2 := core.GetTestBlock("a2").Hash()
+	b2 := core.GetTestBlock("b2").Hash()
+	c1 := core.GetTestBlock("c1").Hash()
+
+	val1 := core.NewValidator("0x111", big.NewInt(100))
+	val2 := core.NewValidator("0x222", big.NewInt(100))
+	valSet := core.NewValidatorSet()
+	valSet.AddValidator(val1)
+	valSet.AddValidator(val2)
+	valMgr := &testValidatorManager{valSet: valSet}
+
+	ch.AddVoteToIndex(core.Vote{Block: a2, ID: val1.Address})
+	ch.AddVoteToIndex(core.Vote{Block: a2, ID: val2.Address})
+	ch.AddVoteToIndex(core.Vote{Block: b2, ID: val1.Address})
+
+	tree, err := ch.GetForkTree(a0, 100, valMgr)
+	assert.Nil(err)
+	assert.Equal(a0, tree.Hash)
+	assert.Equal(2, len(tree.Children))
+	assert.Equal(a1, tree.Children[0].Hash)
+	assert.Equal(c1, tree.Children[1].Hash)
+	a2Node := tree.Children[0].Children[0]
+	assert.Equal(a2, a2Node.Hash)
+	assert.Equal(2, len(a2Node.Voters))
+	assert.Equal(int64(200), a2Node.VoteWeight.ToInt().Int64())
+	assert.True(a2Node.HasMajority)
+	b2Node := tree.Children[0].Children[1]
+	assert.Equal(int64(100), b2Node.VoteWeight.ToInt().Int64())
+	assert.False(b2Node.HasMajority)
+
+	branches := tree.Branches()
+	assert.Equal(branches, ch.GetBranches(a0, 100, valMgr))
+	assert.Equal(3, len(branches))
+
+	assert.Equal(a2, branches[0].Leaf)
+	assert.Equal(a1, branches[0].ForkPoint)
+	assert.Equal(common.JSONUint64(1), branches[0].Length)
+	assert.Equal(int64(200), branches[0].VoteWeight.ToInt().Int64())
+	assert.Equal(a2, branches[0].HighestCC)
+
+	assert.Equal(b2, branches[1].Leaf)
+	assert.Equal(a1, branches[1].ForkPoint)
+	assert.Equal(int64(100), branches[1].VoteWeight.ToInt().Int64())
+	assert.True(branches[1].HighestCC.IsEmpty())
+
+	assert.Equal(c1, branches[2].Leaf)
+	assert.Equal(a0, branches[2].ForkPoint)
+	assert.Equal(int64(0), branches[2].VoteWeight.ToInt().Int64())
+
+	// The root block is finalized
+	for _, branch := range branches {
+		assert.Equal(tree.Height, branch.FinalizedHeight)
+		assert.False(branch.Finalized)
+	}
+}
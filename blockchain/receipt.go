@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store"
+)
+
+// receiptsKey constructs the DB key for the receipts of the given block.
+func receiptsKey(blockHash common.Hash) common.Bytes {
+	return append(common.Bytes("rcpt/"), blockHash[:]...)
+}
+
+// AddBlockReceipts stores the smart contract transaction receipts of the given block.
+func (ch *Chain) AddBlockReceipts(blockHash common.Hash, receipts []*types.Receipt) {
+	err := addBlockReceipts(ch.store, blockHash, receipts)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+func addBlockReceipts(w store.Writer, blockHash common.Hash, receipts []*types.Receipt) error {
+	if len(receipts) == 0 {
+		return nil
+	}
+	return w.Put(receiptsKey(blockHash), receipts)
+}
+
+// FindBlockReceipts returns the smart contract transaction receipts of the given block. Log
+// fields derived from the block are populated.
+func (ch *Chain) FindBlockReceipts(blockHash common.Hash) []*types.Receipt {
+	receipts := []*types.Receipt{}
+	err := ch.store.Get(receiptsKey(blockHash), &receipts)
+	if err != nil {
+		if err == store.ErrKeyNotFound {
+			return []*types.Receipt{}
+		}
+		logger.Panic(err)
+	}
+
+	block, err := ch.FindBlock(blockHash)
+	if err != nil {
+		return receipts
+	}
+	txIndices := make(map[common.Hash]uint)
+	for idx, tx := range block.Txs {
+		txIndices[crypto.HashAtHeight(block.Height, tx)] = uint(idx)
+	}
+	logIndex := uint(0)
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			l.BlockNumber = block.Height
+			l.BlockHash = blockHash
+			l.TxHash = receipt.TxHash
+			l.TxIndex = txIndices[receipt.TxHash]
+			l.Index = logIndex
+			logIndex++
+		}
+	}
+	return receipts
+}
+
+// FindTxReceipt returns the receipt of the given smart contract transaction in the given block.
+func (ch *Chain) FindTxReceipt(blockHash common.Hash, txHash common.Hash) (*types.Receipt, bool) {
+	for _, receipt := range ch.FindBlockReceipts(blockHash) {
+		if receipt.TxHash == txHash {
+			return receipt, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,87 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func TestBlockReceipts(t *testing.T) {
+	assert := assert.New(t)
+
+	tx1 := common.Bytes("tx1")
+	tx2 := common.Bytes("tx2")
+	block1 := core.CreateTestBlock("b1", "")
+	block1.Height = 10
+	block1.Txs = []common.Bytes{tx1, tx2}
+	block1.UpdateHash()
+
+	chain := CreateTestChain()
+	chain.AddBlock(block1)
+
+	assert.Equal(0, len(chain.FindBlockReceipts(block1.Hash())))
+
+	contractAddr := common.HexToAddress("0x1234")
+	receipts := []*types.Receipt{
+		{
+			TxHash:  crypto.Keccak256Hash(tx2),
+			GasUsed: 21000,
+			Logs: []*types.Log{
+				{Address: contractAddr, Topics: []common.Hash{common.HexToHash("a1")}},
+				{Address: contractAddr, Data: []byte("data")},
+			},
+		},
+	}
+	chain.AddBlockReceipts(block1.Hash(), receipts)
+
+	receipt, found := chain.FindTxReceipt(block1.Hash(), crypto.Keccak256Hash(tx2))
+	assert.True(found)
+	assert.Equal(uint64(21000), receipt.GasUsed)
+	assert.True(receipt.Succeeded())
+	assert.Equal(2, len(receipt.Logs))
+	for i, l := range receipt.Logs {
+		assert.Equal(contractAddr, l.Address)
+		assert.Equal(uint64(10), l.BlockNumber)
+		assert.Equal(block1.Hash(), l.BlockHash)
+		assert.Equal(crypto.Keccak256Hash(tx2), l.TxHash)
+		assert.Equal(uint(1), l.TxIndex)
+		assert.Equal(uint(i), l.Index)
+	}
+	assert.Equal([]byte("data"), receipt.Logs[1].Data)
+
+	_, found = chain.FindTxReceipt(block1.Hash(), crypto.Keccak256Hash(tx1))
+	assert.False(found)
+}
+
+func TestMarkBlockValidWithReceipts(t *testing.T) {
+	assert := assert.New(t)
+
+	tx := common.Bytes("tx")
+	block := core.CreateTestBlock("b1", "")
+	block.Txs = []common.Bytes{tx}
+	block.UpdateHash()
+
+	chain := CreateTestChain()
+	chain.AddBlock(block)
+
+	receipts := []*types.Receipt{{TxHash: crypto.Keccak256Hash(tx), GasUsed: 21000}}
+	eb := chain.MarkBlockValidWithReceipts(block.Hash(), true, receipts)
+	assert.Equal(core.BlockStatusValid, eb.Status)
+	assert.True(eb.HasValidatorUpdate)
+
+	eb, err := chain.FindBlock(block.Hash())
+	assert.Nil(err)
+	assert.Equal(core.BlockStatusValid, eb.Status)
+	assert.True(eb.HasValidatorUpdate)
+	receipt, found := chain.FindTxReceipt(block.Hash(), crypto.Keccak256Hash(tx))
+	assert.True(found)
+	assert.Equal(uint64(21000), receipt.GasUsed)
+
+	// Marking the block valid again does not clear the validator update flag
+	eb = chain.MarkBlockValid(block.Hash())
+	assert.True(eb.HasValidatorUpdate)
+}
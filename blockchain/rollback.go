@@ -0,0 +1,149 @@
+package blockchain
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store"
+)
+
+// RemoveDescendants removes the descendants of the block from the chain, together with their
+// receipts and their entries in the height, tx and address indexes, e.g. to roll the chain back
+// to the block. The removed blocks are downloaded and validated again once the node restarts.
+// It returns the number of blocks removed.
+func (ch *Chain) RemoveDescendants(hash common.Hash) (int, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	block, err := ch.findBlockHeader(hash)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := ch.store.NewBatch()
+	removedByHeight := make(map[uint64]map[common.Hash]bool)
+	addresses := make(map[common.Address]bool)
+	queue := append([]common.Hash{}, block.Children...)
+	for len(queue) > 0 {
+		child, err := ch.findBlockHeader(queue[0])
+		queue = queue[1:]
+		if err != nil {
+			continue
+		}
+		// The index entries of the transactions of a pruned block are left behind
+		if err := ch.loadBlockBody(child); err != nil && err != ErrBlockBodyPruned {
+			return 0, err
+		}
+		queue = append(queue, child.Children...)
+
+		childHash := child.Hash()
+		if removedByHeight[child.Height] == nil {
+			removedByHeight[child.Height] = make(map[common.Hash]bool)
+		}
+		removedByHeight[child.Height][childHash] = true
+		ch.removeTxsFromIndex(batch, child)
+		for _, rawTx := range child.Txs {
+			if tx, err := types.TxFromBytes(rawTx); err == nil {
+				for _, address := range types.GetTxAddresses(tx) {
+					addresses[address] = true
+				}
+			}
+		}
+		if err := batch.Delete(receiptsKey(childHash)); err != nil {
+			return 0, err
+		}
+		if err := batch.Delete(blockBodyKey(childHash)); err != nil {
+			return 0, err
+		}
+		if err := batch.Delete(childHash[:]); err != nil {
+			return 0, err
+		}
+	}
+
+	removed := 0
+	for height, hashes := range removedByHeight {
+		removed += len(hashes)
+		if err := ch.removeFromHeightIndex(batch, height, hashes); err != nil {
+			return 0, err
+		}
+	}
+	for address := range addresses {
+		if err := ch.truncateAddressIndex(batch, address, block.Height); err != nil {
+			return 0, err
+		}
+	}
+
+	block.Children = []common.Hash{}
+	if err := writeBlock(batch, block); err != nil {
+		return 0, err
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	if ch.archive != nil {
+		if err := ch.archive.Truncate(block.Height + 1); err != nil {
+			return 0, err
+		}
+	}
+	return removed, nil
+}
+
+func (ch *Chain) removeFromHeightIndex(w store.Writer, height uint64, hashes map[common.Hash]bool) error {
+	key := blockByHeightIndexKey(height)
+	entry := BlockByHeightIndexEntry{Blocks: []common.Hash{}}
+	ch.store.Get(key, &entry)
+
+	remaining := []common.Hash{}
+	for _, hash := range entry.Blocks {
+		if !hashes[hash] {
+			remaining = append(remaining, hash)
+		}
+	}
+	if len(remaining) == 0 {
+		return w.Delete(key)
+	}
+	return w.Put(key, BlockByHeightIndexEntry{Blocks: remaining})
+}
+
+// removeTxsFromIndex removes the tx index entries pointing to the block.
+func (ch *Chain) removeTxsFromIndex(w store.Writer, block *core.ExtendedBlock) {
+	blockHash := block.Hash()
+	for _, tx := range block.Txs {
+		key := txIndexKey(crypto.HashAtHeight(block.Height, tx))
+		entry := TxIndexEntry{}
+		if err := ch.store.Get(key, &entry); err != nil || entry.BlockHash != blockHash {
+			continue
+		}
+		if err := w.Delete(key); err != nil {
+			logger.Panic(err)
+		}
+	}
+}
+
+// truncateAddressIndex removes the transactions of the address above the height, which are the
+// last ones indexed since the blocks are indexed in increasing height order.
+func (ch *Chain) truncateAddressIndex(w store.Writer, address common.Address, height uint64) error {
+	count := ch.GetAddressTxCount(address)
+	newCount := count
+	for newCount > 0 {
+		entry := AddressTxEntry{}
+		if err := ch.store.Get(addressTxKey(address, newCount-1), &entry); err != nil {
+			return err
+		}
+		if entry.BlockHeight <= height {
+			break
+		}
+		newCount--
+		if err := w.Delete(addressTxKey(address, newCount)); err != nil {
+			return err
+		}
+	}
+	if newCount == count {
+		return nil
+	}
+	if newCount == 0 {
+		return w.Delete(addressTxCountKey(address))
+	}
+	return w.Put(addressTxCountKey(address), newCount)
+}
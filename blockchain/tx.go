@@ -21,24 +21,35 @@ type TxIndexEntry struct {
 
 // AddTxsToIndex adds transactions in given block to index.
 func (ch *Chain) AddTxsToIndex(block *core.ExtendedBlock, force bool) {
+	ch.addTxsToIndex(ch.store, block, force)
+}
+
+func (ch *Chain) addTxsToIndex(w store.Writer, block *core.ExtendedBlock, force bool) {
+	// The writes may be batched, so the keys written by this call are tracked to keep the
+	// first occurrence of a transaction indexed.
+	added := make(map[common.Hash]bool)
 	for idx, tx := range block.Txs {
 		txIndexEntry := TxIndexEntry{
 			BlockHash:   block.Hash(),
 			BlockHeight: block.Height,
 			Index:       uint64(idx),
 		}
-		txHash := crypto.Keccak256Hash(tx)
+		txHash := crypto.HashAtHeight(block.Height, tx)
 		key := txIndexKey(txHash)
 
 		if !force {
 			// Check if TX with given hash exists in DB.
+			if added[txHash] {
+				continue
+			}
 			err := ch.store.Get(key, &TxIndexEntry{})
 			if err != store.ErrKeyNotFound {
 				continue
 			}
 		}
+		added[txHash] = true
 
-		err := ch.store.Put(key, txIndexEntry)
+		err := w.Put(key, txIndexEntry)
 		if err != nil {
 			logger.Panic(err)
 		}
@@ -57,7 +68,7 @@ func (ch *Chain) FindTxByHash(hash common.Hash) (tx common.Bytes, block *core.Ex
 	}
 	block, err = ch.FindBlock(txIndexEntry.BlockHash)
 	if err != nil {
-		if err == store.ErrKeyNotFound {
+		if err == store.ErrKeyNotFound || err == ErrBlockBodyPruned {
 			return nil, nil, false
 		}
 		logger.Panic(err)
@@ -0,0 +1,204 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/store"
+)
+
+// uptimeHeightKey is the DB key for the height of the last block added to the uptime index.
+var uptimeHeightKey = common.Bytes("uptime/height")
+
+// uptimeEpochCountKey constructs the DB key for the number of epochs recorded for the given validator.
+func uptimeEpochCountKey(validator common.Address) common.Bytes {
+	return append(common.Bytes("uptime/ep/"), validator[:]...)
+}
+
+// uptimeEpochKey constructs the DB key for the n-th epoch recorded for the given validator.
+func uptimeEpochKey(validator common.Address, n uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, n)
+	return append(uptimeEpochCountKey(validator), buf...)
+}
+
+// missedBlockCountKey constructs the DB key for the number of blocks missed by the given validator.
+func missedBlockCountKey(validator common.Address) common.Bytes {
+	return append(common.Bytes("uptime/mb/"), validator[:]...)
+}
+
+// missedBlockKey constructs the DB key for the n-th block missed by the given validator.
+func missedBlockKey(validator common.Address, n uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, n)
+	return append(missedBlockCountKey(validator), buf...)
+}
+
+// ValidatorEpochStats records the participation of a validator in the finalized blocks of an
+// epoch. A block is counted as voted if this node has received the vote of the validator on it.
+type ValidatorEpochStats struct {
+	Epoch    uint64
+	Proposed uint64
+	Voted    uint64
+	Missed   uint64
+}
+
+// MissedBlockEntry locates a finalized block the validator did not vote on.
+type MissedBlockEntry struct {
+	BlockHash   common.Hash
+	BlockHeight uint64
+	Epoch       uint64
+}
+
+// AddBlockToUptimeIndex records the proposer of the given finalized block, and which of the
+// given validators of the block have voted on it. Blocks must be added in increasing height
+// order. Blocks not above the last added height are skipped, so a block can be safely added again.
+func (ch *Chain) AddBlockToUptimeIndex(block *core.ExtendedBlock, validators *core.ValidatorSet) {
+	if block.Height <= ch.GetUptimeIndexHeight() {
+		return
+	}
+
+	ch.updateEpochStats(block.Proposer, block.Epoch, func(stats *ValidatorEpochStats) {
+		stats.Proposed++
+	})
+
+	votes := ch.FindVotesByHash(block.Hash())
+	voted := make(map[common.Address]bool)
+	for _, vote := range votes.Votes() {
+		if vote.Block == block.Hash() {
+			voted[vote.ID] = true
+		}
+	}
+	for _, validator := range validators.Validators() {
+		id := validator.ID()
+		if voted[id] {
+			ch.updateEpochStats(id, block.Epoch, func(stats *ValidatorEpochStats) {
+				stats.Voted++
+			})
+			continue
+		}
+		ch.updateEpochStats(id, block.Epoch, func(stats *ValidatorEpochStats) {
+			stats.Missed++
+		})
+		ch.addMissedBlock(id, MissedBlockEntry{
+			BlockHash:   block.Hash(),
+			BlockHeight: block.Height,
+			Epoch:       block.Epoch,
+		})
+	}
+
+	err := ch.store.Put(uptimeHeightKey, block.Height)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// updateEpochStats applies the update to the stats of the validator for the epoch. Since the
+// blocks are added in increasing height order, the epoch is either the last one recorded for
+// the validator, or a new one appended after it.
+func (ch *Chain) updateEpochStats(validator common.Address, epoch uint64, update func(stats *ValidatorEpochStats)) {
+	count := ch.getCount(uptimeEpochCountKey(validator))
+	stats := &ValidatorEpochStats{Epoch: epoch}
+	n := count
+	if count > 0 {
+		last := &ValidatorEpochStats{}
+		err := ch.store.Get(uptimeEpochKey(validator, count-1), last)
+		if err != nil {
+			logger.Panic(err)
+		}
+		if last.Epoch >= epoch {
+			stats = last
+			n = count - 1
+		}
+	}
+	update(stats)
+	err := ch.store.Put(uptimeEpochKey(validator, n), stats)
+	if err != nil {
+		logger.Panic(err)
+	}
+	if n == count {
+		err = ch.store.Put(uptimeEpochCountKey(validator), count+1)
+		if err != nil {
+			logger.Panic(err)
+		}
+	}
+}
+
+func (ch *Chain) addMissedBlock(validator common.Address, entry MissedBlockEntry) {
+	count := ch.GetMissedBlockCount(validator)
+	err := ch.store.Put(missedBlockKey(validator, count), entry)
+	if err != nil {
+		logger.Panic(err)
+	}
+	err = ch.store.Put(missedBlockCountKey(validator), count+1)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+func (ch *Chain) getCount(key common.Bytes) uint64 {
+	var count uint64
+	err := ch.store.Get(key, &count)
+	if err != nil {
+		if err == store.ErrKeyNotFound {
+			return 0
+		}
+		logger.Panic(err)
+	}
+	return count
+}
+
+// GetUptimeIndexHeight returns the height of the last block added to the uptime index.
+func (ch *Chain) GetUptimeIndexHeight() uint64 {
+	return ch.getCount(uptimeHeightKey)
+}
+
+// GetValidatorUptime returns the stats of the validator for the recorded epochs from fromEpoch
+// to toEpoch, inclusive, in increasing epoch order.
+func (ch *Chain) GetValidatorUptime(validator common.Address, fromEpoch uint64, toEpoch uint64) []ValidatorEpochStats {
+	count := ch.getCount(uptimeEpochCountKey(validator))
+	getStats := func(n uint64) ValidatorEpochStats {
+		stats := ValidatorEpochStats{}
+		err := ch.store.Get(uptimeEpochKey(validator, n), &stats)
+		if err != nil {
+			logger.Panic(err)
+		}
+		return stats
+	}
+
+	ret := []ValidatorEpochStats{}
+	start := uint64(sort.Search(int(count), func(i int) bool {
+		return getStats(uint64(i)).Epoch >= fromEpoch
+	}))
+	for n := start; n < count; n++ {
+		stats := getStats(n)
+		if stats.Epoch > toEpoch {
+			break
+		}
+		ret = append(ret, stats)
+	}
+	return ret
+}
+
+// GetMissedBlockCount returns the number of finalized blocks the validator did not vote on.
+func (ch *Chain) GetMissedBlockCount(validator common.Address) uint64 {
+	return ch.getCount(missedBlockCountKey(validator))
+}
+
+// FindMissedBlocks returns up to limit finalized blocks the validator did not vote on, starting
+// from the given position in increasing height order.
+func (ch *Chain) FindMissedBlocks(validator common.Address, start uint64, limit uint64) []MissedBlockEntry {
+	entries := []MissedBlockEntry{}
+	count := ch.GetMissedBlockCount(validator)
+	for n := start; n < count && uint64(len(entries)) < limit; n++ {
+		entry := MissedBlockEntry{}
+		err := ch.store.Get(missedBlockKey(validator, n), &entry)
+		if err != nil {
+			logger.Panic(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
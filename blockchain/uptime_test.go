@@ -0,0 +1,63 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+func TestUptimeIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	alice := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	bob := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	validators := core.NewValidatorSet()
+	validators.AddValidator(core.NewValidator(alice.Hex(), big.NewInt(1)))
+	validators.AddValidator(core.NewValidator(bob.Hex(), big.NewInt(1)))
+
+	core.ResetTestBlocks()
+	chain := CreateTestChain()
+
+	addBlock := func(name string, height uint64, epoch uint64, proposer common.Address, voters ...common.Address) *core.ExtendedBlock {
+		block := core.CreateTestBlock(name, "")
+		block.Height = height
+		block.Epoch = epoch
+		block.Proposer = proposer
+		block.UpdateHash()
+		eb, _ := chain.AddBlock(block)
+		for _, voter := range voters {
+			chain.AddVoteToIndex(core.Vote{Block: eb.Hash(), Height: height, Epoch: epoch, ID: voter})
+		}
+		return eb
+	}
+
+	eb1 := addBlock("b1", 10, 5, alice, alice, bob)
+	eb2 := addBlock("b2", 11, 5, bob, bob)
+	eb3 := addBlock("b3", 12, 7, alice, alice)
+
+	chain.AddBlockToUptimeIndex(eb1, validators)
+	chain.AddBlockToUptimeIndex(eb2, validators)
+	chain.AddBlockToUptimeIndex(eb3, validators)
+	chain.AddBlockToUptimeIndex(eb3, validators) // Added again, skipped
+	assert.Equal(uint64(12), chain.GetUptimeIndexHeight())
+
+	stats := chain.GetValidatorUptime(alice, 0, 100)
+	assert.Equal([]ValidatorEpochStats{
+		{Epoch: 5, Proposed: 1, Voted: 1, Missed: 1},
+		{Epoch: 7, Proposed: 1, Voted: 1, Missed: 0},
+	}, stats)
+	stats = chain.GetValidatorUptime(bob, 6, 7)
+	assert.Equal([]ValidatorEpochStats{{Epoch: 7, Proposed: 0, Voted: 0, Missed: 1}}, stats)
+	assert.Equal(0, len(chain.GetValidatorUptime(bob, 8, 100)))
+
+	assert.Equal(uint64(1), chain.GetMissedBlockCount(alice))
+	entries := chain.FindMissedBlocks(alice, 0, 10)
+	assert.Equal([]MissedBlockEntry{{BlockHash: eb2.Hash(), BlockHeight: 11, Epoch: 5}}, entries)
+	assert.Equal(uint64(1), chain.GetMissedBlockCount(bob))
+	assert.Equal(eb3.Hash(), chain.FindMissedBlocks(bob, 0, 10)[0].BlockHash)
+	assert.Equal(0, len(chain.FindMissedBlocks(bob, 1, 10)))
+}
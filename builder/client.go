@@ -0,0 +1,73 @@
+package builder
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+	"github.com/ybbus/jsonrpc"
+)
+
+// GetPayloadArgs is the request sent to the builder endpoint.
+type GetPayloadArgs struct {
+	ChainID    string            `json:"chain_id"`
+	ParentHash common.Hash       `json:"parent_hash"`
+	Height     common.JSONUint64 `json:"height"`
+	Proposer   common.Address    `json:"proposer"`
+}
+
+// GetPayloadResult is the response of the builder endpoint.
+type GetPayloadResult struct {
+	Payload common.Bytes `json:"payload"` // RLP encoded Payload
+}
+
+// Client requests block payloads from an external builder through its RPC endpoint.
+type Client struct {
+	endpoint string
+	client   *jsonrpc.RPCClient
+}
+
+// NewClient creates an instance of Client. If authToken is not empty, it is sent as a bearer
+// token with each request. Requests time out after the given duration.
+func NewClient(endpoint string, authToken string, timeout time.Duration) *Client {
+	client := jsonrpc.NewRPCClient(endpoint)
+	client.SetHTTPClient(&http.Client{Timeout: timeout})
+	if authToken != "" {
+		client.SetCustomHeader("Authorization", "Bearer "+authToken)
+	}
+	return &Client{
+		endpoint: endpoint,
+		client:   client,
+	}
+}
+
+// Endpoint returns the URL of the builder endpoint.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// GetPayload requests the payload of the block to be proposed on top of the given parent.
+// The payload is not validated.
+func (c *Client) GetPayload(args GetPayloadArgs) (*Payload, error) {
+	res, err := c.client.Call("builder.GetPayload", args)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	result := &GetPayloadResult{}
+	if err := res.GetObject(result); err != nil {
+		return nil, err
+	}
+	if len(result.Payload) == 0 {
+		return nil, errors.New("Empty builder payload")
+	}
+	payload := &Payload{}
+	if err := rlp.DecodeBytes(result.Payload, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
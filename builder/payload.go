@@ -0,0 +1,73 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// Payload is the body of a block proposal built by an external builder. It only carries the
+// regular transactions: the proposer adds the special transactions (coinbase, slash), executes
+// the block to compute the state root, and signs it.
+type Payload struct {
+	ChainID    string
+	ParentHash common.Hash
+	Height     uint64
+	Proposer   common.Address
+	Txs        []common.Bytes
+	Builder    common.Address
+	Signature  *crypto.Signature
+}
+
+// SignBytes returns raw bytes to be signed by the builder.
+func (p *Payload) SignBytes() common.Bytes {
+	r := Payload{
+		ChainID:    p.ChainID,
+		ParentHash: p.ParentHash,
+		Height:     p.Height,
+		Proposer:   p.Proposer,
+		Txs:        p.Txs,
+		Builder:    p.Builder,
+	}
+	raw, _ := rlp.EncodeToBytes(r)
+	return raw
+}
+
+// Sign signs the payload with the builder key.
+func (p *Payload) Sign(privKey *crypto.PrivateKey) error {
+	p.Builder = privKey.PublicKey().Address()
+	sig, err := privKey.Sign(p.SignBytes())
+	if err != nil {
+		return err
+	}
+	p.Signature = sig
+	return nil
+}
+
+// Validate checks that the payload is signed by the expected builder and built on top of the
+// given parent for the given proposer.
+func (p *Payload) Validate(builder common.Address, chainID string, parent common.Hash,
+	height uint64, proposer common.Address, maxNumTxs int) error {
+	if p.Builder != builder {
+		return fmt.Errorf("Unexpected builder: %v, expected: %v", p.Builder.Hex(), builder.Hex())
+	}
+	if !p.Signature.Verify(p.SignBytes(), builder) {
+		return fmt.Errorf("Invalid builder signature")
+	}
+	if p.ChainID != chainID {
+		return fmt.Errorf("Chain ID mismatch: %v, expected: %v", p.ChainID, chainID)
+	}
+	if p.ParentHash != parent || p.Height != height {
+		return fmt.Errorf("Payload built on block %v at height %v, expected %v at height %v",
+			p.ParentHash.Hex(), p.Height, parent.Hex(), height)
+	}
+	if p.Proposer != proposer {
+		return fmt.Errorf("Payload built for proposer %v, expected: %v", p.Proposer.Hex(), proposer.Hex())
+	}
+	if len(p.Txs) > maxNumTxs {
+		return fmt.Errorf("Too many transactions in payload: %v, max: %v", len(p.Txs), maxNumTxs)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func newTestPayload(t *testing.T, privKey *crypto.PrivateKey) *Payload {
+	payload := &Payload{
+		ChainID:    "testchain",
+		ParentHash: common.BytesToHash([]byte("parent")),
+		Height:     10,
+		Proposer:   common.HexToAddress("A1"),
+		Txs:        []common.Bytes{common.Bytes("tx1"), common.Bytes("tx2")},
+	}
+	if err := payload.Sign(privKey); err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestPayloadValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	builderAddr := privKey.PublicKey().Address()
+	parent := common.BytesToHash([]byte("parent"))
+	proposer := common.HexToAddress("A1")
+
+	payload := newTestPayload(t, privKey)
+	assert.Nil(payload.Validate(builderAddr, "testchain", parent, 10, proposer, 100))
+
+	// Payload from another builder
+	otherKey, _, _ := crypto.GenerateKeyPair()
+	assert.NotNil(payload.Validate(otherKey.PublicKey().Address(), "testchain", parent, 10, proposer, 100))
+
+	// Stale or foreign payload
+	assert.NotNil(payload.Validate(builderAddr, "otherchain", parent, 10, proposer, 100))
+	assert.NotNil(payload.Validate(builderAddr, "testchain", parent, 11, proposer, 100))
+	assert.NotNil(payload.Validate(builderAddr, "testchain", parent, 10, common.HexToAddress("B1"), 100))
+	assert.NotNil(payload.Validate(builderAddr, "testchain", parent, 10, proposer, 1))
+
+	// Tampered payload
+	payload.Txs = append(payload.Txs, common.Bytes("tx3"))
+	assert.NotNil(payload.Validate(builderAddr, "testchain", parent, 10, proposer, 100))
+}
+
+func TestClientGetPayload(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	payload := newTestPayload(t, privKey)
+	raw, err := rlp.EncodeToBytes(payload)
+	assert.Nil(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		req := struct {
+			ID     int              `json:"id"`
+			Method string           `json:"method"`
+			Params []GetPayloadArgs `json:"params"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "builder.GetPayload" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  GetPayloadResult{Payload: raw},
+		})
+	}))
+	defer server.Close()
+
+	args := GetPayloadArgs{ChainID: "testchain", Height: 10}
+
+	client := NewClient(server.URL, "secret", time.Second)
+	received, err := client.GetPayload(args)
+	assert.Nil(err)
+	assert.Equal(payload.Txs, received.Txs)
+	assert.Equal(payload.Builder, received.Builder)
+	assert.True(received.Signature.Verify(received.SignBytes(), privKey.PublicKey().Address()))
+
+	client = NewClient(server.URL, "wrong", time.Second)
+	_, err = client.GetPayload(args)
+	assert.NotNil(err)
+}
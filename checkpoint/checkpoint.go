@@ -0,0 +1,210 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+	"github.com/thetatoken/theta/store"
+)
+
+var logger *log.Entry = util.GetLoggerForModule("checkpoint")
+
+// DBCheckpointSlotPrefix is the namespace the checkpoints are written to. Two slots are used
+// alternately so that a write interrupted by an abrupt shutdown never clobbers the last good
+// checkpoint.
+const DBCheckpointSlotPrefix = "ckpt/slot/"
+
+const numSlots = 2
+
+var ErrNoValidCheckpoint = errors.New("No valid checkpoint found")
+
+// VoteSource provides the last vote cast by the local node.
+type VoteSource interface {
+	GetLastVote() core.Vote
+	GetTip(includePendingBlockingLeaf bool) *core.ExtendedBlock
+	GetLastFinalizedBlock() *core.ExtendedBlock
+}
+
+// TxSource provides the pending transactions to be checkpointed.
+type TxSource interface {
+	GetCandidateTxs(maxNumTxs int) []common.Bytes
+}
+
+// Record is the small critical state persisted on every checkpoint.
+type Record struct {
+	Sequence      uint64
+	Timestamp     uint64
+	LastVote      core.Vote
+	MempoolDigest common.Hash
+	MempoolTxs    []common.Bytes
+	SyncTip       common.Hash
+	SyncTipHeight uint64
+	LastFinalized common.Hash
+	Checksum      common.Hash
+}
+
+func (r *Record) calculateChecksum() common.Hash {
+	tmp := *r
+	tmp.Checksum = common.Hash{}
+	raw, _ := rlp.EncodeToBytes(tmp)
+	return crypto.Keccak256Hash(raw)
+}
+
+// IsValid returns whether the checksum matches the record content.
+func (r *Record) IsValid() bool {
+	return r.Checksum == r.calculateChecksum()
+}
+
+// CalculateMempoolDigest returns the digest of the given pending transactions.
+func CalculateMempoolDigest(txs []common.Bytes) common.Hash {
+	hashes := make([][]byte, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, crypto.Keccak256(tx))
+	}
+	return crypto.Keccak256Hash(hashes...)
+}
+
+// Checkpointer continuously persists small critical state so that recovery does not
+// depend on a clean shutdown path executing fully.
+type Checkpointer struct {
+	logger *log.Entry
+
+	db        store.Store
+	votes     VoteSource
+	txs       TxSource
+	interval  time.Duration
+	maxNumTxs int
+
+	sequence uint64
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewCheckpointer creates an instance of Checkpointer.
+func NewCheckpointer(db store.Store, votes VoteSource, txs TxSource) *Checkpointer {
+	c := &Checkpointer{
+		db:        db,
+		votes:     votes,
+		txs:       txs,
+		interval:  time.Duration(viper.GetInt(common.CfgCheckpointInterval)) * time.Millisecond,
+		maxNumTxs: viper.GetInt(common.CfgCheckpointMaxMempoolTxs),
+		wg:        &sync.WaitGroup{},
+	}
+
+	logger = util.GetLoggerForModule("checkpoint")
+	c.logger = logger
+
+	if last, err := c.Load(); err == nil {
+		c.sequence = last.Sequence
+	}
+
+	return c
+}
+
+// Start starts the checkpointing loop.
+func (c *Checkpointer) Start(ctx context.Context) {
+	cc, cancel := context.WithCancel(ctx)
+	c.ctx = cc
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.mainLoop()
+}
+
+// Stop notifies the checkpointing loop to stop without blocking.
+func (c *Checkpointer) Stop() {
+	c.cancel()
+}
+
+// Wait blocks until the checkpointing loop stops.
+func (c *Checkpointer) Wait() {
+	c.wg.Wait()
+}
+
+func (c *Checkpointer) mainLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			// Best effort: the shutdown path might not get this far.
+			c.Checkpoint()
+			return
+		case <-ticker.C:
+			c.Checkpoint()
+		}
+	}
+}
+
+// Checkpoint captures the current critical state and writes it to the next slot.
+func (c *Checkpointer) Checkpoint() error {
+	record := &Record{
+		Sequence:  c.sequence + 1,
+		Timestamp: uint64(time.Now().Unix()),
+		LastVote:  c.votes.GetLastVote(),
+	}
+	if c.txs != nil {
+		record.MempoolTxs = c.txs.GetCandidateTxs(c.maxNumTxs)
+	}
+	record.MempoolDigest = CalculateMempoolDigest(record.MempoolTxs)
+
+	tip := c.votes.GetTip(true)
+	record.SyncTip = tip.Hash()
+	record.SyncTipHeight = tip.Height
+	record.LastFinalized = c.votes.GetLastFinalizedBlock().Hash()
+	record.Checksum = record.calculateChecksum()
+
+	if err := c.db.Put(slotKey(record.Sequence), record); err != nil {
+		c.logger.WithFields(log.Fields{"err": err, "sequence": record.Sequence}).Error("Failed to write checkpoint")
+		return err
+	}
+	c.sequence = record.Sequence
+	return nil
+}
+
+// Load returns the latest valid checkpoint.
+func (c *Checkpointer) Load() (*Record, error) {
+	return LoadLatest(c.db)
+}
+
+// LoadLatest returns the latest valid checkpoint in the given store. Slots that cannot be decoded
+// or fail checksum verification are skipped.
+func LoadLatest(db store.Store) (*Record, error) {
+	var latest *Record
+	for i := uint64(0); i < numSlots; i++ {
+		record := &Record{}
+		if err := db.Get(slotKey(i), record); err != nil {
+			continue
+		}
+		if !record.IsValid() {
+			logger.WithFields(log.Fields{"slot": i, "sequence": record.Sequence}).Warn("Ignoring corrupted checkpoint")
+			continue
+		}
+		if latest == nil || record.Sequence > latest.Sequence {
+			latest = record
+		}
+	}
+	if latest == nil {
+		return nil, ErrNoValidCheckpoint
+	}
+	return latest, nil
+}
+
+func slotKey(sequence uint64) common.Bytes {
+	return common.Bytes(DBCheckpointSlotPrefix + string('0'+byte(sequence%numSlots)))
+}
@@ -0,0 +1,109 @@
+package checkpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+type testVoteSource struct {
+	vote core.Vote
+	tip  *core.ExtendedBlock
+	lfb  *core.ExtendedBlock
+}
+
+func (s *testVoteSource) GetLastVote() core.Vote {
+	return s.vote
+}
+
+func (s *testVoteSource) GetTip(includePendingBlockingLeaf bool) *core.ExtendedBlock {
+	return s.tip
+}
+
+func (s *testVoteSource) GetLastFinalizedBlock() *core.ExtendedBlock {
+	return s.lfb
+}
+
+type testTxSource struct {
+	txs []common.Bytes
+}
+
+func (s *testTxSource) GetCandidateTxs(maxNumTxs int) []common.Bytes {
+	if maxNumTxs > 0 && len(s.txs) > maxNumTxs {
+		return s.txs[:maxNumTxs]
+	}
+	return s.txs
+}
+
+func newTestVoteSource() *testVoteSource {
+	core.ResetTestBlocks()
+	core.CreateTestBlock("A0", "")
+	lfb := core.CreateTestBlock("A1", "A0")
+	tip := core.CreateTestBlock("A2", "A1")
+	return &testVoteSource{
+		tip: &core.ExtendedBlock{Block: tip},
+		lfb: &core.ExtendedBlock{Block: lfb},
+	}
+}
+
+func TestCheckpointBasic(t *testing.T) {
+	assert := assert.New(t)
+
+	db := kvstore.NewKVStore(backend.NewMemDatabase())
+	votes := newTestVoteSource()
+	txs := &testTxSource{txs: []common.Bytes{common.Bytes("tx1"), common.Bytes("tx2")}}
+
+	_, err := LoadLatest(db)
+	assert.Equal(ErrNoValidCheckpoint, err)
+
+	c := NewCheckpointer(db, votes, txs)
+	votes.vote = core.Vote{Height: 10}
+	assert.Nil(c.Checkpoint())
+	votes.vote = core.Vote{Height: 11}
+	txs.txs = append(txs.txs, common.Bytes("tx3"))
+	assert.Nil(c.Checkpoint())
+
+	record, err := LoadLatest(db)
+	assert.Nil(err)
+	assert.Equal(uint64(2), record.Sequence)
+	assert.Equal(uint64(11), record.LastVote.Height)
+	assert.Equal(3, len(record.MempoolTxs))
+	assert.Equal(CalculateMempoolDigest(txs.txs), record.MempoolDigest)
+	assert.Equal(votes.tip.Hash(), record.SyncTip)
+	assert.Equal(votes.lfb.Hash(), record.LastFinalized)
+
+	// A new checkpointer continues from the last sequence.
+	c2 := NewCheckpointer(db, votes, txs)
+	assert.Nil(c2.Checkpoint())
+	record, err = LoadLatest(db)
+	assert.Nil(err)
+	assert.Equal(uint64(3), record.Sequence)
+}
+
+func TestCheckpointCorruptedSlot(t *testing.T) {
+	assert := assert.New(t)
+
+	db := kvstore.NewKVStore(backend.NewMemDatabase())
+	votes := newTestVoteSource()
+	c := NewCheckpointer(db, votes, nil)
+
+	votes.vote = core.Vote{Height: 10}
+	assert.Nil(c.Checkpoint())
+	votes.vote = core.Vote{Height: 11}
+	assert.Nil(c.Checkpoint())
+
+	// Simulate a torn write to the latest slot.
+	record, err := LoadLatest(db)
+	assert.Nil(err)
+	record.LastVote.Height = 100
+	assert.Nil(db.Put(slotKey(record.Sequence), record))
+
+	record, err = LoadLatest(db)
+	assert.Nil(err)
+	assert.Equal(uint64(1), record.Sequence)
+	assert.Equal(uint64(10), record.LastVote.Height)
+}
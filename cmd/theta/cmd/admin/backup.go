@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/rpc"
+)
+
+// backupCmd represents the backup command.
+// Example:
+//		theta admin backup /backup/theta-20200101
+var backupCmd = &cobra.Command{
+	Use:   "backup <dir>",
+	Short: "Back up the database of the running node",
+	Long: `Write a consistent copy of the database of the running node to a new directory, from a
+snapshot of the database backend. The node keeps running during the backup. The backup is
+restored with theta restore.`,
+	Example: `theta admin backup /backup/theta-20200101`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// The directory is created by the node, whose working directory may differ
+		dir, err := filepath.Abs(args[0])
+		if err != nil {
+			exitWithError("Invalid backup directory: %v\n", err)
+		}
+		call("BackupDatabase", rpc.BackupDatabaseArgs{Dir: dir}, "back up database")
+	},
+}
@@ -0,0 +1,19 @@
+package admin
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/rpc"
+)
+
+// checkpointCmd represents the checkpoint command.
+// Example:
+//		theta admin checkpoint
+var checkpointCmd = &cobra.Command{
+	Use:     "checkpoint",
+	Short:   "Write a checkpoint of the critical node state now",
+	Long:    `Write a checkpoint of the last vote, the pending transactions and the sync cursor now, e.g. before a planned restart.`,
+	Example: `theta admin checkpoint`,
+	Run: func(cmd *cobra.Command, args []string) {
+		call("ExportCheckpoint", rpc.ExportCheckpointArgs{}, "export checkpoint")
+	},
+}
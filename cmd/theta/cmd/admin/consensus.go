@@ -0,0 +1,18 @@
+package admin
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/rpc"
+)
+
+// consensusCmd represents the consensus command.
+// Example:
+//		theta admin consensus
+var consensusCmd = &cobra.Command{
+	Use:     "consensus",
+	Short:   "Dump the consensus state",
+	Example: `theta admin consensus`,
+	Run: func(cmd *cobra.Command, args []string) {
+		call("DumpConsensusState", rpc.DumpConsensusStateArgs{}, "dump consensus state")
+	},
+}
@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/rpc"
+)
+
+// diskCmd represents the disk command.
+// Example:
+//		theta admin disk
+var diskCmd = &cobra.Command{
+	Use:     "disk",
+	Short:   "Show the disk usage of the database columns",
+	Example: `theta admin disk`,
+	Run: func(cmd *cobra.Command, args []string) {
+		call("GetDiskUsage", rpc.GetDiskUsageArgs{}, "get disk usage")
+	},
+}
+
+// compactCmd represents the compact command.
+// Example:
+//		theta admin compact
+var compactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Compact the database of the running node",
+	Long: `Compact the database of the running node within a minute, regardless of the compaction
+schedule. The node keeps running during the compaction, its progress is shown by theta admin disk.`,
+	Example: `theta admin compact`,
+	Run: func(cmd *cobra.Command, args []string) {
+		call("CompactDatabase", rpc.CompactDatabaseArgs{}, "compact database")
+	},
+}
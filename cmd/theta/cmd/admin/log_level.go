@@ -0,0 +1,21 @@
+package admin
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/rpc"
+)
+
+// logLevelCmd represents the log_level command.
+// Example:
+//		theta admin log_level consensus debug
+var logLevelCmd = &cobra.Command{
+	Use:   "log_level <module> <level>",
+	Short: "Change the log level of a module",
+	Long: `Change the log level of a module without restarting the node. Module * sets the level of the
+modules without a level of their own. Levels: panic, fatal, error, warn, info and debug.`,
+	Example: `theta admin log_level consensus debug`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		call("SetLogLevel", rpc.SetLogLevelArgs{Module: args[0], Level: args[1]}, "set log level")
+	},
+}
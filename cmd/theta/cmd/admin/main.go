@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+var endpointFlag string
+
+// AdminCmd represents the admin command
+var AdminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Administrate a running node",
+	Long: `Administrate a running node through its admin RPC service, which must be enabled with
+admin.enabled. By default the address of the service is read from the node config.`,
+}
+
+func init() {
+	AdminCmd.PersistentFlags().StringVar(&endpointFlag, "endpoint", "", "Admin RPC endpoint, defaults to the admin.listenAddress of the node config")
+
+	AdminCmd.AddCommand(peersCmd)
+	AdminCmd.AddCommand(banCmd)
+	AdminCmd.AddCommand(unbanCmd)
+	AdminCmd.AddCommand(mempoolCmd)
+	AdminCmd.AddCommand(flushMempoolCmd)
+	AdminCmd.AddCommand(checkpointCmd)
+	AdminCmd.AddCommand(logLevelCmd)
+	AdminCmd.AddCommand(reloadConfigCmd)
+	AdminCmd.AddCommand(consensusCmd)
+	AdminCmd.AddCommand(backupCmd)
+	AdminCmd.AddCommand(diskCmd)
+	AdminCmd.AddCommand(compactCmd)
+}
+
+// call calls the admin RPC method and prints its result.
+func call(method string, args interface{}, action string) {
+	endpoint := endpointFlag
+	if endpoint == "" {
+		endpoint = "http://" + viper.GetString(common.CfgAdminListenAddress) + "/rpc"
+	}
+	client := rpcc.NewRPCClient(endpoint)
+
+	res, err := client.Call("admin."+method, args)
+	if err != nil {
+		exitWithError("Failed to %v: %v\n", action, err)
+	}
+	if res.Error != nil {
+		exitWithError("Failed to %v: %v\n", action, res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		exitWithError("Failed to parse server response: %v\n%v\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func exitWithError(msg string, args ...interface{}) {
+	fmt.Printf(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/rpc"
+)
+
+var limitFlag int
+
+// mempoolCmd represents the mempool command.
+// Example:
+//		theta admin mempool --limit=20
+var mempoolCmd = &cobra.Command{
+	Use:     "mempool",
+	Short:   "Inspect the pending transactions",
+	Example: `theta admin mempool --limit=20`,
+	Run: func(cmd *cobra.Command, args []string) {
+		call("InspectMempool", rpc.InspectMempoolArgs{Limit: limitFlag}, "inspect mempool")
+	},
+}
+
+// flushMempoolCmd represents the flush_mempool command.
+// Example:
+//		theta admin flush_mempool
+var flushMempoolCmd = &cobra.Command{
+	Use:     "flush_mempool",
+	Short:   "Drop all the pending transactions",
+	Example: `theta admin flush_mempool`,
+	Run: func(cmd *cobra.Command, args []string) {
+		call("FlushMempool", rpc.FlushMempoolArgs{}, "flush mempool")
+	},
+}
+
+func init() {
+	mempoolCmd.Flags().IntVar(&limitFlag, "limit", 100, "Max number of transactions to list")
+}
@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/rpc"
+)
+
+var durationFlag int64
+
+// peersCmd represents the peers command.
+// Example:
+//		theta admin peers
+var peersCmd = &cobra.Command{
+	Use:     "peers",
+	Short:   "List the connected and the banned peers",
+	Example: `theta admin peers`,
+	Run: func(cmd *cobra.Command, args []string) {
+		call("ListPeers", rpc.ListPeersArgs{}, "list peers")
+	},
+}
+
+// banCmd represents the ban command.
+// Example:
+//		theta admin ban 0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --duration=3600
+var banCmd = &cobra.Command{
+	Use:     "ban <peer_id>",
+	Short:   "Disconnect a peer and reject its connections for a while",
+	Example: `theta admin ban 0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --duration=3600`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		call("BanPeer", rpc.BanPeerArgs{ID: args[0], Duration: durationFlag}, "ban peer")
+	},
+}
+
+// unbanCmd represents the unban command.
+// Example:
+//		theta admin unban 0x2E833968E5bB786Ae419c4d13189fB081Cc43bab
+var unbanCmd = &cobra.Command{
+	Use:     "unban <peer_id>",
+	Short:   "Lift the ban of a peer",
+	Example: `theta admin unban 0x2E833968E5bB786Ae419c4d13189fB081Cc43bab`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		call("UnbanPeer", rpc.UnbanPeerArgs{ID: args[0]}, "unban peer")
+	},
+}
+
+func init() {
+	banCmd.Flags().Int64Var(&durationFlag, "duration", 24*3600, "Ban duration in seconds")
+}
@@ -0,0 +1,22 @@
+package admin
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/rpc"
+)
+
+// reloadConfigCmd represents the reload_config command.
+// Example:
+//		theta admin reload_config
+var reloadConfigCmd = &cobra.Command{
+	Use:   "reload_config",
+	Short: "Reload the node config",
+	Long: `Re-read the config file of the node and apply the settings that can be changed at runtime:
+log.levels, p2p.maxNumPeers, p2p.sufficientNumPeers, p2p.sendRate, p2p.recvRate, rpc.enabled and
+rpc.maxBatchSize. Sending SIGHUP to the node has the same effect.`,
+	Example: `theta admin reload_config`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		call("ReloadConfig", rpc.ReloadConfigArgs{}, "reload config")
+	},
+}
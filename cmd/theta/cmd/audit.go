@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/snapshot"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+var auditHeightFlag uint64
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check the invariants of the ledger state.",
+	Long: `Check the invariants of the ledger state of a finalized block, by default the last finalized
+block. The node must be stopped during the audit.
+
+The audit checks that the balances and the reserved funds of all the accounts are non-negative,
+that the TFuel held by the accounts covers the TFuel supply accounting, and that the validator
+and guardian candidate pools and the unbonding queue are consistent. The TFuel minted for the
+coins arriving over the bridge is not part of the supply accounting, and is reported as
+unaccounted. The command exits with an error if any invariant is violated.
+
+The same checks run after each block is applied when ledger.checkInvariants is enabled, which
+also checks that the block conserves the TFuel.`,
+	Example: `theta audit --config=../privatenet/node
+theta audit --config=../privatenet/node --height=1000`,
+	Run: runAudit,
+}
+
+func init() {
+	auditCmd.Flags().Uint64Var(&auditHeightFlag, "height", 0, "Height of the finalized block to audit, 0 for the last finalized block")
+	RootCmd.AddCommand(auditCmd)
+}
+
+func runAudit(cmd *cobra.Command, args []string) {
+	db := openDatabase()
+	defer db.Close()
+	migrateDatabase(db)
+	blockArchive := openBlockArchive()
+	if blockArchive != nil {
+		defer blockArchive.Close()
+	}
+
+	if len(snapshotPath) == 0 {
+		snapshotPath = path.Join(cfgPath, "snapshot")
+	}
+	snapshotBlockHeader, err := snapshot.ValidateSnapshot(snapshotPath)
+	if err != nil {
+		log.Fatalf("Snapshot validation failed, err: %v", err)
+	}
+	root := &core.Block{BlockHeader: snapshotBlockHeader}
+	setForkSchedule(root.ChainID)
+
+	store := kvstore.NewKVStore(database.BlockDatabase(db))
+	chain := blockchain.NewChain(root.ChainID, store, root)
+	if blockArchive != nil {
+		chain.SetBlockArchive(blockArchive)
+	}
+	block := consensus.NewState(store, chain).GetLastFinalizedBlock()
+	if auditHeightFlag != 0 && auditHeightFlag != block.Height {
+		if auditHeightFlag > block.Height {
+			log.Fatalf("Block height %v is not finalized yet, last finalized height: %v", auditHeightFlag, block.Height)
+		}
+		block = nil
+		for _, b := range chain.FindBlocksByHeight(auditHeightFlag) {
+			if b.Status.IsFinalized() {
+				block = b
+				break
+			}
+		}
+		if block == nil {
+			log.Fatalf("Failed to find the finalized block at height %v", auditHeightFlag)
+		}
+	}
+
+	view := state.NewStoreView(block.Height, block.StateHash, db)
+	if view == nil {
+		log.Fatalf("State of block %v at height %v is not available", block.Hash().Hex(), block.Height)
+	}
+	report := ledger.CheckInvariants(view)
+
+	log.Infof("Audited the state of block %v at height %v: %v accounts, %v TFuelWei",
+		block.Hash().Hex(), block.Height, report.NumAccounts, report.TotalTFuel)
+	if report.Supply != nil {
+		log.Infof("TFuel supply: %v TFuelWei, %v minted and %v burned since the supply accounting, %v unaccounted",
+			report.Supply.Total, report.Supply.Minted, report.Supply.Burned, report.UnaccountedTFuel())
+	}
+	for _, violation := range report.Violations {
+		log.Errorf("Invariant violated: %v", violation)
+	}
+	if !report.OK() {
+		log.Fatalf("%v invariants violated at height %v", len(report.Violations), block.Height)
+	}
+	log.Infof("All the invariants hold")
+}
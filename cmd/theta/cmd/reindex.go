@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/reindex"
+	"github.com/thetatoken/theta/snapshot"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+var indexesFlag string
+var restartFlag bool
+var replayFlag bool
+
+// reindexCmd represents the reindex command
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild derived indexes from the stored blocks.",
+	Long: `Rebuild derived indexes from the stored blocks. The node must be stopped while reindexing.
+An interrupted run resumes from where it stopped.
+
+Supported indexes:
+  tx       transaction hash to block position
+  logs     smart contract receipts and logs, requires the historical state
+  address  transactions involving each address
+
+With --replay, all the blocks are applied again from the root block to rebuild the ledger
+state and the receipts together with the tx and address indexes, e.g. to recover from a
+corrupted database without downloading the chain again. The state index can also be chosen
+explicitly with --indexes=state.`,
+	Example: `theta reindex --config=../privatenet/node --indexes=tx,logs,address
+theta reindex --config=../privatenet/node --replay`,
+	Run: runReindex,
+}
+
+func init() {
+	reindexCmd.Flags().StringVar(&indexesFlag, "indexes", strings.Join(reindex.AllIndexes, ","), "Comma separated list of indexes to rebuild")
+	reindexCmd.Flags().BoolVar(&restartFlag, "restart", false, "Discard the saved progress and rebuild from the root block")
+	reindexCmd.Flags().BoolVar(&replayFlag, "replay", false, "Apply all the blocks from the root block to rebuild the state, receipts and indexes")
+	RootCmd.AddCommand(reindexCmd)
+}
+
+func runReindex(cmd *cobra.Command, args []string) {
+	f := func(c rune) bool {
+		return c == ','
+	}
+	indexes := []string{}
+	for _, index := range strings.FieldsFunc(indexesFlag, f) {
+		indexes = append(indexes, strings.TrimSpace(index))
+	}
+	if replayFlag {
+		indexes = reindex.ReplayIndexes
+	}
+
+	db := openDatabase()
+	defer db.Close()
+	migrateDatabase(db)
+	blockArchive := openBlockArchive()
+	if blockArchive != nil {
+		defer blockArchive.Close()
+	}
+
+	if len(snapshotPath) == 0 {
+		snapshotPath = path.Join(cfgPath, "snapshot")
+	}
+	snapshotBlockHeader, err := snapshot.ValidateSnapshot(snapshotPath)
+	if err != nil {
+		log.Fatalf("Snapshot validation failed, err: %v", err)
+	}
+	root := &core.Block{BlockHeader: snapshotBlockHeader}
+	setForkSchedule(root.ChainID)
+
+	store := kvstore.NewKVStore(database.BlockDatabase(db))
+	chain := blockchain.NewChain(root.ChainID, store, root)
+	if blockArchive != nil {
+		chain.SetBlockArchive(blockArchive)
+	}
+	lastFinalized := consensus.NewState(store, chain).GetLastFinalizedBlock()
+
+	reindexer, err := reindex.NewReindexer(db, store, chain, indexes)
+	if err != nil {
+		log.Fatalf("Failed to create reindexer: %v", err)
+	}
+
+	for _, index := range indexes {
+		if index != reindex.IndexState {
+			continue
+		}
+		// The state is rebuilt on top of the state of the root block
+		if err := reindexer.CheckRootState(); err != nil {
+			log.Infof("Importing the root state from the snapshot: %v", err)
+			if _, err := snapshot.ImportSnapshot(snapshotPath, db); err != nil {
+				log.Fatalf("Failed to import snapshot: %v", err)
+			}
+		}
+	}
+
+	// Stop after the block being processed on interrupt, the progress is saved.
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		log.Info("Interrupted, saving progress")
+		cancel()
+	}()
+
+	if err := reindexer.Run(ctx, lastFinalized.Height, restartFlag); err != nil {
+		log.Fatalf("Reindexing failed: %v", err)
+	}
+}
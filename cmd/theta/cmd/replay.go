@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/replay"
+	"github.com/thetatoken/theta/snapshot"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+var replayChainFlag string
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay recorded blocks to check the consensus validation and the ledger execution.",
+	Long: `Replay recorded blocks, e.g. exported from a mainnet node, on top of the state of a snapshot
+to check that the consensus validation and the ledger execution of this build agree with the
+recorded chain. It is meant to detect regressions before a release.
+
+The blocks are read from the chain backups given with --chain, in increasing height order, and
+must follow the snapshot block without gaps. Each block is validated the way the consensus engine
+does, except for the proposer of its epoch which depends on the live state of the node, and is
+applied to the state of its parent. The replay stops at the first block whose state root, receipts,
+validator updates or votes do not match the recorded ones, and the command exits with an error.
+
+The replay runs in a temporary database, the database of the node is not used.`,
+	Example: `theta replay --config=../mainnet/node --snapshot=theta_snapshot-1000 --chain=theta_chain-1000-2000,theta_chain-2001-3000`,
+	Run:     runReplay,
+}
+
+func init() {
+	replayCmd.Flags().StringVar(&replayChainFlag, "chain", "", "Comma separated list of chain backups to replay, in increasing height order")
+	RootCmd.AddCommand(replayCmd)
+}
+
+func runReplay(cmd *cobra.Command, args []string) {
+	if len(replayChainFlag) == 0 {
+		log.Fatalf("No chain backup to replay")
+	}
+	if len(snapshotPath) == 0 {
+		snapshotPath = path.Join(cfgPath, "snapshot")
+	}
+
+	tmpdbRoot, err := ioutil.TempDir("", "replaydb")
+	if err != nil {
+		log.Fatalf("Failed to create temporary db for the replay: %v", err)
+	}
+	defer os.RemoveAll(tmpdbRoot)
+	db, err := backend.NewLDBDatabase(path.Join(tmpdbRoot, "main"), path.Join(tmpdbRoot, "ref"), 256, 0)
+	if err != nil {
+		log.Fatalf("Failed to open temporary db for the replay: %v", err)
+	}
+	defer db.Close()
+
+	root, err := snapshot.ImportSnapshot(snapshotPath, db)
+	if err != nil {
+		log.Fatalf("Failed to import snapshot: %v", err)
+	}
+	setForkSchedule(root.ChainID)
+
+	var blocks, last *core.BackupBlock
+	for _, chainPath := range strings.Split(replayChainFlag, ",") {
+		chainPath = strings.TrimSpace(chainPath)
+		if len(chainPath) == 0 {
+			continue
+		}
+		backupBlocks, err := snapshot.ImportChainBackup(chainPath)
+		if err != nil {
+			log.Fatalf("Failed to import chain backup %v: %v", chainPath, err)
+		}
+		if last == nil {
+			blocks = backupBlocks
+		} else {
+			last.Next = backupBlocks
+		}
+		for last = backupBlocks; last.Next != nil; last = last.Next {
+		}
+	}
+
+	// Stop after the block being replayed on interrupt
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		log.Info("Interrupted, stopping the replay")
+		cancel()
+	}()
+
+	replayer := replay.NewReplayer(db, root)
+	report, err := replayer.Run(ctx, blocks)
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+
+	log.Infof("Replayed %v blocks with %v transactions from height %v to %v in %v",
+		report.NumBlocks, report.NumTxs, report.FromHeight, report.ToHeight, report.Elapsed)
+	if report.Divergence != nil {
+		log.Fatalf("Replay diverged from the recorded chain: %v", report.Divergence)
+	}
+	log.Infof("All the blocks match the recorded chain")
+}
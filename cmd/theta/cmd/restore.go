@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/backup"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/snapshot"
+)
+
+var restoreBackupFlag string
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the database of the node from a backup.",
+	Long: `Restore the database of the node from a backup written by theta admin backup. The node
+must be stopped during the restore.
+
+The current database is moved aside rather than deleted. The restored database is verified
+against the head of the backup, i.e. the head must be a finalized block of the restored chain
+and the state of the last finalized block must be available. The current database is put back
+if the verification fails.`,
+	Example: `theta restore --config=../privatenet/node --backup=/backup/theta-20200101`,
+	Run:     runRestore,
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreBackupFlag, "backup", "", "Directory of the backup to restore")
+	RootCmd.AddCommand(restoreCmd)
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	if restoreBackupFlag == "" {
+		log.Fatalf("The backup directory must be set with --backup")
+	}
+	manifest, err := backup.LoadManifest(restoreBackupFlag)
+	if err != nil {
+		log.Fatalf("Failed to load the backup: %v", err)
+	}
+	if backendName := viper.GetString(common.CfgStorageBackend); manifest.Backend != backendName {
+		log.Fatalf("The backup was taken from the %v backend, the node uses %v", manifest.Backend, backendName)
+	}
+
+	if len(snapshotPath) == 0 {
+		snapshotPath = path.Join(cfgPath, "snapshot")
+	}
+	snapshotBlockHeader, err := snapshot.ValidateSnapshot(snapshotPath)
+	if err != nil {
+		log.Fatalf("Snapshot validation failed, err: %v", err)
+	}
+	root := &core.Block{BlockHeader: snapshotBlockHeader}
+	setForkSchedule(root.ChainID)
+
+	dbDir := path.Join(cfgPath, "db")
+	movedDBDir := ""
+	if _, err := os.Stat(dbDir); err == nil {
+		// Fails if the node is running, since the database is locked
+		openDatabase().Close()
+
+		movedDBDir = fmt.Sprintf("%v.%v", dbDir, time.Now().Unix())
+		if err := os.Rename(dbDir, movedDBDir); err != nil {
+			log.Fatalf("Failed to move the current database aside: %v", err)
+		}
+		log.Infof("Moved the current database to %v", movedDBDir)
+	}
+	revert := func(reason string, err error) {
+		os.RemoveAll(dbDir)
+		if movedDBDir != "" {
+			if err := os.Rename(movedDBDir, dbDir); err != nil {
+				log.Errorf("Failed to put the current database back from %v: %v", movedDBDir, err)
+			}
+		}
+		log.Fatalf("%v: %v", reason, err)
+	}
+
+	if _, err := backup.Restore(restoreBackupFlag, dbDir); err != nil {
+		revert("Failed to restore the backup", err)
+	}
+	if _, err := os.Stat(path.Join(dbDir, "archive")); err == nil && !viper.GetBool(common.CfgStorageBlockArchive) {
+		revert("Failed to restore the backup", fmt.Errorf("The backup contains a block archive, %v must be enabled",
+			common.CfgStorageBlockArchive))
+	}
+
+	lastFinalized, err := verifyRestoredDatabase(root, manifest)
+	if err != nil {
+		revert("Restored database failed the verification", err)
+	}
+	log.Infof("Restored the backup, backup head: %v at height %v, last finalized block: %v at height %v",
+		manifest.HeadHash.Hex(), manifest.HeadHeight, lastFinalized.Hash().Hex(), lastFinalized.Height)
+}
+
+func verifyRestoredDatabase(root *core.Block, manifest *backup.Manifest) (*core.ExtendedBlock, error) {
+	db := openDatabase()
+	defer db.Close()
+	blockArchive := openBlockArchive()
+	if blockArchive != nil {
+		defer blockArchive.Close()
+	}
+	return backup.Verify(db, blockArchive, root, manifest)
+}
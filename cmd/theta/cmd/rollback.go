@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/reindex"
+	"github.com/thetatoken/theta/snapshot"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+var rollbackBlocksFlag uint64
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll the node back a number of finalized blocks.",
+	Long: `Roll the node back a number of finalized blocks, e.g. to recover from a consensus bug or a
+corrupted state. The node must be stopped during the rollback.
+
+The ledger state of the new last finalized block is rebuilt if it is missing, by replaying the
+blocks from the nearest available state, or from the snapshot if no state is available. The
+blocks above it are removed together with their receipts and index entries, and are downloaded
+and validated again once the node restarts.`,
+	Example: `theta rollback --config=../privatenet/node --blocks=100`,
+	Run:     runRollback,
+}
+
+func init() {
+	rollbackCmd.Flags().Uint64Var(&rollbackBlocksFlag, "blocks", 0, "Number of finalized blocks to roll back")
+	RootCmd.AddCommand(rollbackCmd)
+}
+
+func runRollback(cmd *cobra.Command, args []string) {
+	if rollbackBlocksFlag == 0 {
+		log.Fatalf("The number of blocks to roll back must be set with --blocks")
+	}
+
+	db := openDatabase()
+	defer db.Close()
+	migrateDatabase(db)
+	blockArchive := openBlockArchive()
+	if blockArchive != nil {
+		defer blockArchive.Close()
+	}
+
+	if len(snapshotPath) == 0 {
+		snapshotPath = path.Join(cfgPath, "snapshot")
+	}
+	snapshotBlockHeader, err := snapshot.ValidateSnapshot(snapshotPath)
+	if err != nil {
+		log.Fatalf("Snapshot validation failed, err: %v", err)
+	}
+	root := &core.Block{BlockHeader: snapshotBlockHeader}
+	setForkSchedule(root.ChainID)
+
+	store := kvstore.NewKVStore(database.BlockDatabase(db))
+	chain := blockchain.NewChain(root.ChainID, store, root)
+	if blockArchive != nil {
+		chain.SetBlockArchive(blockArchive)
+	}
+	state := consensus.NewState(store, chain)
+
+	reindexer, err := reindex.NewReindexer(db, store, chain, []string{reindex.IndexState})
+	if err != nil {
+		log.Fatalf("Failed to create reindexer: %v", err)
+	}
+	// The state is rebuilt on top of the state of the root block if no later state is available
+	if err := reindexer.CheckRootState(); err != nil {
+		log.Infof("Importing the root state from the snapshot: %v", err)
+		if _, err := snapshot.ImportSnapshot(snapshotPath, db); err != nil {
+			log.Fatalf("Failed to import snapshot: %v", err)
+		}
+	}
+
+	// Nothing is removed until the state is rebuilt, so the rollback can be run again.
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		log.Info("Interrupted, stopping rollback")
+		cancel()
+	}()
+
+	block, err := reindexer.Rollback(ctx, state, rollbackBlocksFlag)
+	if err != nil {
+		log.Fatalf("Rollback failed: %v", err)
+	}
+	log.Infof("Rolled back to block %v at height %v", block.Hash().Hex(), block.Height)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/signer"
+)
+
+// signerCmd represents the signer command
+var signerCmd = &cobra.Command{
+	Use:   "signer",
+	Short: "Start the signing service holding the validator key.",
+	Long: `Start the signing service holding the validator key. Validator nodes configured with
+signer.remote request their vote and block signatures from the service instead of
+keeping the validator key on the node.`,
+	Run: runSigner,
+}
+
+func init() {
+	RootCmd.AddCommand(signerCmd)
+}
+
+func runSigner(cmd *cobra.Command, args []string) {
+	privKey, err := loadOrCreateKey()
+	if err != nil {
+		log.Fatalf("Failed to load or create key: %v", err)
+	}
+
+	f := func(c rune) bool {
+		return c == ','
+	}
+	allowedNodes := []common.Address{}
+	for _, addr := range strings.FieldsFunc(viper.GetString(common.CfgSignerAllowedNodes), f) {
+		allowedNodes = append(allowedNodes, common.HexToAddress(strings.TrimSpace(addr)))
+	}
+	if len(allowedNodes) == 0 {
+		log.Fatalf("No node is allowed to connect, please set %v", common.CfgSignerAllowedNodes)
+	}
+
+	stateFilePath := path.Join(cfgPath, "signer_state.json")
+	server, err := signer.NewServer(privKey, viper.GetString(common.CfgSignerListenAddress), allowedNodes, stateFilePath)
+	if err != nil {
+		log.Fatalf("Failed to create the signing service: %v", err)
+	}
+	if err := server.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start the signing service: %v", err)
+	}
+
+	server.Wait()
+}
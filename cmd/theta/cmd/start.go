@@ -3,20 +3,32 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"path"
 	"strings"
+	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/tracing"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/node"
 	"github.com/thetatoken/theta/p2p/messenger"
+	"github.com/thetatoken/theta/rpc"
+	"github.com/thetatoken/theta/signer"
 	"github.com/thetatoken/theta/snapshot"
+	"github.com/thetatoken/theta/store/archive"
+	"github.com/thetatoken/theta/store/database"
 	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/migration"
+	"github.com/thetatoken/theta/version"
 	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
 )
 
@@ -32,6 +44,10 @@ func init() {
 }
 
 func runStart(cmd *cobra.Command, args []string) {
+	if err := util.InitLogging(); err != nil {
+		log.Fatalf("Failed to initialize logging: %v", err)
+	}
+
 	port := viper.GetInt(common.CfgP2PPort)
 
 	// Parse seeds and filter out empty item.
@@ -39,41 +55,243 @@ func runStart(cmd *cobra.Command, args []string) {
 		return c == ','
 	}
 	peerSeeds := strings.FieldsFunc(viper.GetString(common.CfgP2PSeeds), f)
+	dnsSeeds := strings.FieldsFunc(viper.GetString(common.CfgP2PDNSSeeds), f)
+	persistentPeers := strings.FieldsFunc(viper.GetString(common.CfgP2PPersistentPeers), f)
 	privKey, err := loadOrCreateKey()
 	if err != nil {
 		log.Fatalf("Failed to load or create key: %v", err)
 	}
 
-	network := newMessenger(privKey, peerSeeds, port)
-	mainDBPath := path.Join(cfgPath, "db", "main")
-	refDBPath := path.Join(cfgPath, "db", "ref")
-	db, err := backend.NewLDBDatabase(mainDBPath, refDBPath, 256, 0)
-	if err != nil {
-		log.Fatalf("Failed to connect to the db. main: %v, ref: %v, err: %v",
-			mainDBPath, refDBPath, err)
-	}
+	network := newMessenger(privKey, peerSeeds, dnsSeeds, persistentPeers, port)
+	db := openDatabase()
+	migrateDatabase(db)
+	blockArchive := openBlockArchive()
 
 	if len(snapshotPath) == 0 {
 		snapshotPath = path.Join(cfgPath, "snapshot")
 	}
+	if err := downloadSnapshotIfMissing(snapshotPath); err != nil {
+		log.Fatalf("Failed to download snapshot: %v", err)
+	}
 	snapshotBlockHeader, err := snapshot.ValidateSnapshot(snapshotPath)
 	if err != nil {
 		log.Fatalf("Snapshot validation failed, err: %v", err)
 	}
 	root := &core.Block{BlockHeader: snapshotBlockHeader}
 
+	setForkSchedule(root.ChainID)
+
 	params := &node.Params{
 		ChainID:      root.ChainID,
 		PrivateKey:   privKey,
+		Signer:       newSigner(privKey),
 		Root:         root,
 		Network:      network,
 		DB:           db,
+		BlockArchive: blockArchive,
 		SnapshotPath: snapshotPath,
+		DataPath:     cfgPath,
+		Subchains:    openSubchains(),
+	}
+	if err := tracing.Init(context.Background()); err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
 	}
+
 	n := node.NewNode(params)
 	n.Start(context.Background())
 
+	go reloadConfigOnSighup(n)
+	go stopOnInterrupt(n)
+
 	n.Wait()
+
+	// Flush the spans of the blocks processed before the shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		log.Warnf("Failed to flush the tracing spans: %v", err)
+	}
+}
+
+// setForkSchedule schedules the upgrades of the chain configured in the genesis section.
+func setForkSchedule(chainID string) {
+	forks := viper.GetString(common.CfgGenesisForks)
+	if len(forks) == 0 {
+		return
+	}
+	schedule, err := core.ParseForkSchedule(forks)
+	if err != nil {
+		log.Fatalf("Invalid fork schedule: %v", err)
+	}
+	core.SetForkSchedule(chainID, schedule)
+}
+
+// stopOnInterrupt stops the node on SIGINT or SIGTERM, letting it complete the work in
+// progress. A second signal exits immediately.
+func stopOnInterrupt(n *node.Node) {
+	interrupt := make(chan os.Signal, 2)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-interrupt
+	log.WithFields(log.Fields{"signal": sig}).Info("Shutting down, signal again to exit immediately")
+	n.Stop()
+
+	<-interrupt
+	log.Warn("Exiting without waiting for the shutdown to complete")
+	os.Exit(1)
+}
+
+// reloadConfigOnSighup reloads the runtime configurable settings of the node on each SIGHUP.
+func reloadConfigOnSighup(n *node.Node) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		if _, err := n.ReloadConfig(); err != nil {
+			log.Errorf("Failed to reload config: %v", err)
+		}
+	}
+}
+
+// openDatabase opens the database of the configured backend.
+func openDatabase() database.Database {
+	return openDatabaseAt(path.Join(cfgPath, "db"))
+}
+
+// openDatabaseAt opens the database of the configured backend in the directory.
+func openDatabaseAt(dbRoot string) database.Database {
+	switch backendName := viper.GetString(common.CfgStorageBackend); backendName {
+	case "leveldb":
+		mainDBPath := path.Join(dbRoot, "main")
+		refDBPath := path.Join(dbRoot, "ref")
+		db, err := backend.NewLDBDatabase(mainDBPath, refDBPath, 256, 0)
+		if err != nil {
+			log.Fatalf("Failed to connect to the db. main: %v, ref: %v, err: %v",
+				mainDBPath, refDBPath, err)
+		}
+		return db
+	case "rocksdb":
+		dbPath := path.Join(dbRoot, "rocksdb")
+		db, err := backend.NewRocksDatabase(dbPath, backend.RocksDBOptions{
+			BlockCacheSize:    viper.GetInt(common.CfgStorageRocksDBBlockCacheSize),
+			WriteBufferSize:   viper.GetInt(common.CfgStorageRocksDBWriteBufferSize),
+			MaxOpenFiles:      viper.GetInt(common.CfgStorageRocksDBMaxOpenFiles),
+			MaxBackgroundJobs: viper.GetInt(common.CfgStorageRocksDBMaxBackgroundJobs),
+			Compression:       viper.GetString(common.CfgStorageRocksDBCompression),
+		})
+		if err != nil {
+			log.Fatalf("Failed to connect to the db: %v, err: %v", dbPath, err)
+		}
+		return db
+	default:
+		log.Fatalf("Unknown storage backend: %v", backendName)
+		return nil
+	}
+}
+
+// openSubchains opens the snapshots and the databases of the configured subchains.
+func openSubchains() []*node.SubchainParams {
+	f := func(c rune) bool {
+		return c == ','
+	}
+	subchains := []*node.SubchainParams{}
+	for _, chainID := range strings.FieldsFunc(viper.GetString(common.CfgSubchainIDs), f) {
+		chainID = strings.TrimSpace(chainID)
+		subchainPath := path.Join(cfgPath, "subchains", chainID)
+		snapshotPath := path.Join(subchainPath, "snapshot")
+		snapshotBlockHeader, err := snapshot.ValidateSnapshot(snapshotPath)
+		if err != nil {
+			log.Fatalf("Snapshot validation of subchain %v failed, err: %v", chainID, err)
+		}
+		if snapshotBlockHeader.ChainID != chainID {
+			log.Fatalf("Snapshot of subchain %v is for chain %v", chainID, snapshotBlockHeader.ChainID)
+		}
+
+		db := openDatabaseAt(path.Join(subchainPath, "db"))
+		migrateDatabase(db)
+		subchains = append(subchains, &node.SubchainParams{
+			ChainID:      chainID,
+			Root:         &core.Block{BlockHeader: snapshotBlockHeader},
+			DB:           db,
+			SnapshotPath: snapshotPath,
+		})
+	}
+	return subchains
+}
+
+// migrateDatabase brings the database to the latest schema version. A migration interrupted by a
+// signal resumes on the next start.
+func migrateDatabase(db database.Database) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := migration.NewRunner(db, migration.Migrations).Run(ctx); err != nil {
+		db.Close()
+		log.Fatalf("Failed to migrate the database: %v", err)
+	}
+}
+
+// openBlockArchive opens the archive of the finalized block bodies if enabled, otherwise nil is
+// returned.
+func openBlockArchive() *archive.Archive {
+	if !viper.GetBool(common.CfgStorageBlockArchive) {
+		return nil
+	}
+	archivePath := path.Join(cfgPath, "db", "archive")
+	blockArchive, err := archive.Open(archivePath)
+	if err != nil {
+		log.Fatalf("Failed to open the block archive: %v, err: %v", archivePath, err)
+	}
+	return blockArchive
+}
+
+// newSigner connects to the remote signing service if configured. Otherwise nil is returned,
+// and the node signs with its own key.
+func newSigner(privKey *crypto.PrivateKey) core.Signer {
+	if !viper.GetBool(common.CfgSignerRemote) {
+		return nil
+	}
+	remoteSigner, err := signer.NewRemoteSigner(viper.GetString(common.CfgSignerEndpoint), privKey,
+		common.HexToAddress(viper.GetString(common.CfgSignerAddress)),
+		time.Duration(viper.GetInt(common.CfgSignerTimeout))*time.Millisecond)
+	if err != nil {
+		log.Fatalf("Failed to connect to the remote signer: %v", err)
+	}
+	return remoteSigner
+}
+
+// downloadSnapshotIfMissing fetches the snapshot from the configured providers when there is
+// no local snapshot file.
+func downloadSnapshotIfMissing(snapshotPath string) error {
+	if _, err := os.Stat(snapshotPath); !os.IsNotExist(err) {
+		return nil
+	}
+	f := func(c rune) bool {
+		return c == ','
+	}
+	urls := strings.FieldsFunc(viper.GetString(common.CfgSnapshotProviders), f)
+	if len(urls) == 0 {
+		return nil
+	}
+	providers := []snapshot.Provider{}
+	for _, url := range urls {
+		providers = append(providers, rpc.NewSnapshotProvider(strings.TrimSpace(url)))
+	}
+	manifest, err := snapshot.Download(providers, viper.GetInt(common.CfgSnapshotMinProviders), snapshotPath)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"manifest": manifest, "path": snapshotPath}).Info("Snapshot downloaded")
+	return nil
 }
 
 func loadOrCreateKey() (*crypto.PrivateKey, error) {
@@ -156,14 +374,18 @@ func loadOrCreateKey() (*crypto.PrivateKey, error) {
 	return nodePrivKey, nil
 }
 
-func newMessenger(privKey *crypto.PrivateKey, seedPeerNetAddresses []string, port int) *messenger.Messenger {
+func newMessenger(privKey *crypto.PrivateKey, seedPeerNetAddresses []string, dnsSeeds []string,
+	persistentPeers []string, port int) *messenger.Messenger {
 	log.WithFields(log.Fields{
 		"pubKey":  fmt.Sprintf("%v", privKey.PublicKey().ToBytes()),
 		"address": fmt.Sprintf("%v", privKey.PublicKey().Address()),
 	}).Info("Using key")
 	msgrConfig := messenger.GetDefaultMessengerConfig()
 	msgrConfig.SetAddressBookFilePath(path.Join(cfgPath, "addrbook.json"))
-	messenger, err := messenger.CreateMessenger(privKey.PublicKey(), seedPeerNetAddresses, port, msgrConfig)
+	msgrConfig.SetNodeVersion(version.GitHash)
+	msgrConfig.SetDNSSeeds(dnsSeeds)
+	msgrConfig.SetPersistentPeers(persistentPeers)
+	messenger, err := messenger.CreateMessenger(privKey, seedPeerNetAddresses, port, msgrConfig)
 	if err != nil {
 		log.WithFields(log.Fields{"err": err}).Fatal("Failed to create PeerDiscoveryManager instance")
 	}
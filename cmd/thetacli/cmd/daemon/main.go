@@ -0,0 +1,14 @@
+package daemon
+
+import "github.com/spf13/cobra"
+
+// DaemonCmd represents the daemon command
+var DaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the wallet daemon",
+	Long:  `Run the wallet daemon, which serves the keys of the wallet through RPC.`,
+}
+
+func init() {
+	DaemonCmd.AddCommand(startCmd)
+}
@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/cmd/thetacli/rpc"
+	sw "github.com/thetatoken/theta/wallet/softwallet"
+)
+
+// startCmd starts the wallet daemon.
+// Example:
+//		thetacli daemon start
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the wallet daemon",
+	Long: `Start the wallet daemon. The keys stay encrypted on disk with the passwords of their owners.
+A key unlocked through the UnlockKey call is kept in memory until LockKey is called or its timeout
+expires, and can sign through the Sign call in the meantime.`,
+	Example: `thetacli daemon start`,
+	Run:     runStart,
+}
+
+func runStart(cmd *cobra.Command, args []string) {
+	cfgPath := cmd.Flag("config").Value.String()
+	wallet, err := sw.NewSoftWallet(path.Join(cfgPath, "keys"), sw.KeystoreTypeEncrypted)
+	if err != nil {
+		utils.Error("Failed to open wallet: %v\n", err)
+	}
+
+	unlockTimeout := time.Duration(viper.GetInt(utils.CfgDaemonUnlockTimeout)) * time.Second
+	server := rpc.NewThetaCliRPCServer(wallet, viper.GetString(utils.CfgDaemonListenAddress), unlockTimeout)
+	if err := server.Start(context.Background()); err != nil {
+		utils.Error("Failed to start the wallet daemon: %v\n", err)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		server.Stop()
+	}()
+
+	server.Wait()
+}
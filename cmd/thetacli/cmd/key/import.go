@@ -0,0 +1,102 @@
+package key
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/wallet"
+	sw "github.com/thetatoken/theta/wallet/softwallet"
+	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
+	wtypes "github.com/thetatoken/theta/wallet/types"
+)
+
+// importCmd imports a key from an Ethereum keystore file or a raw hex private key
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a key from an Ethereum keystore file or a raw hex private key",
+	Long: `Import a key from an Ethereum keystore file or a raw hex private key. The address derived
+from the key is displayed for confirmation before the key is stored.`,
+	Example: `thetacli key import --keystore=./UTC--2019-01-01T00-00-00.000000000Z--26d813157f7503a9057fb2db6eb2f83a35c4fdd7
+thetacli key import --hex`,
+	Run: doImportCmd,
+}
+
+func doImportCmd(cmd *cobra.Command, args []string) {
+	if (keystoreFileFlag == "") == !hexKeyFlag {
+		utils.Error("Please specify exactly one of --keystore and --hex\n")
+	}
+
+	var key *ks.Key
+	var err error
+	if keystoreFileFlag != "" {
+		keyjson, err := ioutil.ReadFile(keystoreFileFlag)
+		if err != nil {
+			utils.Error("Failed to read keystore file: %v\n", err)
+		}
+		password, err := utils.GetPassword("Please enter the password of the keystore file: ")
+		if err != nil {
+			utils.Error("Failed to get password: %v\n", err)
+		}
+		key, err = ks.ImportEthereumKeyJSON(keyjson, password)
+		if err != nil {
+			utils.Error("Failed to decrypt keystore file: %v\n", err)
+		}
+	} else {
+		// Read the private key from the prompt so that it does not end up in the shell history
+		hexKey, err := utils.GetPassword("Please enter the hex private key: ")
+		if err != nil {
+			utils.Error("Failed to get private key: %v\n", err)
+		}
+		key, err = ks.ImportHexKey(hexKey)
+		if err != nil {
+			utils.Error("Failed to parse private key: %v\n", err)
+		}
+	}
+
+	fmt.Printf("The key corresponds to address: %v\n", key.Address.Hex())
+	fmt.Println("Is this the address you expect? Please enter 'no' to stop or 'yes' to proceed: ")
+	confirmation, err := utils.GetConfirmation()
+	if err != nil {
+		utils.Error("Failed to get confirmation: %v\n", err)
+	}
+	if strings.ToLower(confirmation) != "yes" {
+		return
+	}
+
+	cfgPath := cmd.Flag("config").Value.String()
+	wallet, err := wallet.OpenWallet(cfgPath, wtypes.WalletTypeSoft, true)
+	if err != nil {
+		utils.Error("Failed to open wallet: %v\n", err)
+	}
+	softWallet, ok := wallet.(*sw.SoftWallet)
+	if !ok {
+		utils.Error("Key import is only supported for the software wallet\n")
+	}
+
+	password, err := utils.GetPassword("Please enter the password for the imported key: ")
+	if err != nil {
+		utils.Error("Failed to get password: %v\n", err)
+	}
+	password2, err := utils.GetPassword("Please enter the password again: ")
+	if err != nil {
+		utils.Error("Failed to get password: %v\n", err)
+	}
+	if password != password2 {
+		utils.Error("Passwords do not match, abort\n")
+	}
+
+	address, err := softWallet.ImportKey(key, password)
+	if err != nil {
+		utils.Error("Failed to import key: %v\n", err)
+	}
+
+	fmt.Printf("Successfully imported key: %v\n", address.Hex())
+}
+
+func init() {
+	importCmd.Flags().StringVar(&keystoreFileFlag, "keystore", "", "Path to the Ethereum keystore file")
+	importCmd.Flags().BoolVar(&hexKeyFlag, "hex", false, "Import a raw hex private key entered at the prompt")
+}
@@ -4,6 +4,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Common flags used in Key sub commands.
+var (
+	keystoreFileFlag string
+	hexKeyFlag       bool
+)
+
 // KeyCmd represents the key command
 var KeyCmd = &cobra.Command{
 	Use:   "key",
@@ -13,6 +19,7 @@ var KeyCmd = &cobra.Command{
 
 func init() {
 	KeyCmd.AddCommand(newCmd)
+	KeyCmd.AddCommand(importCmd)
 	KeyCmd.AddCommand(listCmd)
 	KeyCmd.AddCommand(deleteCmd)
 	KeyCmd.AddCommand(passwordCmd)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/rpc"
 
 	"github.com/spf13/cobra"
@@ -20,6 +21,7 @@ var (
 // accountCmd represents the account command.
 // Example:
 //		thetacli query account --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab
+//		thetacli query account --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --height=1000
 var accountCmd = &cobra.Command{
 	Use:     "account",
 	Short:   "Get account status",
@@ -32,7 +34,7 @@ func doAccountCmd(cmd *cobra.Command, args []string) {
 	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
 
 	res, err := client.Call("theta.GetAccount", rpc.GetAccountArgs{
-		Address: addressFlag, Preview: previewFlag})
+		Address: addressFlag, Preview: previewFlag, Height: common.JSONUint64(heightFlag)})
 	if err != nil {
 		utils.Error("Failed to get account details: %v\n", err)
 	}
@@ -49,5 +51,6 @@ func doAccountCmd(cmd *cobra.Command, args []string) {
 func init() {
 	accountCmd.Flags().StringVar(&addressFlag, "address", "", "Address of the account")
 	accountCmd.Flags().BoolVar(&previewFlag, "preview", false, "Preview account balance from the screened view")
+	accountCmd.Flags().Uint64Var(&heightFlag, "height", uint64(0), "Height of the finalized block to query the account at, 0 for the last finalized block")
 	accountCmd.MarkFlagRequired("address")
 }
@@ -0,0 +1,40 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// chainParamsCmd represents the chain_params command.
+// Example:
+//		thetacli query chain_params
+var chainParamsCmd = &cobra.Command{
+	Use:     "chain_params",
+	Short:   "Get the block limits and fee minimums of the chain",
+	Example: `thetacli query chain_params`,
+	Run:     doChainParamsCmd,
+}
+
+func doChainParamsCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetChainParams", rpc.GetChainParamsArgs{})
+	if err != nil {
+		utils.Error("Failed to get chain params: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get chain params: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
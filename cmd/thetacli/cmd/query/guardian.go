@@ -0,0 +1,40 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// guardianCmd represents the guardian command.
+// Example:
+//		thetacli query guardian
+var guardianCmd = &cobra.Command{
+	Use:     "guardian",
+	Short:   "Get the guardian key of the node, to be registered with a stake deposit",
+	Example: `thetacli query guardian`,
+	Run:     doGuardianCmd,
+}
+
+func doGuardianCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetGuardianInfo", rpc.GetGuardianInfoArgs{})
+	if err != nil {
+		utils.Error("Failed to get guardian info: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get guardian info: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
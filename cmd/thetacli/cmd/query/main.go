@@ -14,4 +14,9 @@ func init() {
 	QueryCmd.AddCommand(accountCmd)
 	QueryCmd.AddCommand(splitRuleCmd)
 	QueryCmd.AddCommand(vcpCmd)
+	QueryCmd.AddCommand(sequenceCmd)
+	QueryCmd.AddCommand(chainParamsCmd)
+	QueryCmd.AddCommand(guardianCmd)
+	QueryCmd.AddCommand(stakeCmd)
+	QueryCmd.AddCommand(validatorsCmd)
 }
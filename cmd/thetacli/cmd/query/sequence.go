@@ -0,0 +1,52 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+var (
+	modeFlag string
+)
+
+// sequenceCmd represents the sequence command.
+// Example:
+//		thetacli query sequence --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --mode=pending
+var sequenceCmd = &cobra.Command{
+	Use:     "sequence",
+	Short:   "Get account sequence",
+	Long:    `Get account sequence. With --mode=pending, transactions pending in the mempool are taken into account.`,
+	Example: `thetacli query sequence --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --mode=pending`,
+	Run:     doSequenceCmd,
+}
+
+func doSequenceCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetSequence", rpc.GetSequenceArgs{
+		Address: addressFlag, Mode: rpc.SequenceMode(modeFlag)})
+	if err != nil {
+		utils.Error("Failed to get account sequence: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get account sequence: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	sequenceCmd.Flags().StringVar(&addressFlag, "address", "", "Address of the account")
+	sequenceCmd.Flags().StringVar(&modeFlag, "mode", string(rpc.SequenceModeCommitted), "Sequence mode: committed or pending")
+	sequenceCmd.MarkFlagRequired("address")
+}
@@ -0,0 +1,48 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// stakeCmd represents the stake command.
+// Example:
+//		thetacli query stake --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --height=1000
+var stakeCmd = &cobra.Command{
+	Use:     "stake",
+	Short:   "Get the stakes deposited by an address",
+	Example: `thetacli query stake --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --height=1000`,
+	Run:     doStakeCmd,
+}
+
+func doStakeCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetStake", rpc.GetStakeArgs{
+		Address: addressFlag, Height: common.JSONUint64(heightFlag)})
+	if err != nil {
+		utils.Error("Failed to get stakes: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get stakes: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	stakeCmd.Flags().StringVar(&addressFlag, "address", "", "Address of the stake source")
+	stakeCmd.Flags().Uint64Var(&heightFlag, "height", uint64(0), "Height of the finalized block, 0 for the last finalized block")
+	stakeCmd.MarkFlagRequired("address")
+}
@@ -0,0 +1,45 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// validatorsCmd represents the validators command.
+// Example:
+//		thetacli query validators --height=1000
+var validatorsCmd = &cobra.Command{
+	Use:     "validators",
+	Short:   "Get the validator set",
+	Example: `thetacli query validators --height=1000`,
+	Run:     doValidatorsCmd,
+}
+
+func doValidatorsCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetValidatorSet", rpc.GetValidatorSetArgs{Height: common.JSONUint64(heightFlag)})
+	if err != nil {
+		utils.Error("Failed to get validator set: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get validator set: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	validatorsCmd.Flags().Uint64Var(&heightFlag, "height", uint64(0), "Height of the finalized block, 0 for the last finalized block")
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/call"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/daemon"
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/key"
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/query"
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/tx"
@@ -46,6 +47,7 @@ func init() {
 	RootCmd.AddCommand(call.CallCmd)
 	RootCmd.AddCommand(snapshot.SnapshotCmd)
 	RootCmd.AddCommand(backup.BackupCmd)
+	RootCmd.AddCommand(daemon.DaemonCmd)
 }
 
 // initConfig reads in config file and ENV variables if set.
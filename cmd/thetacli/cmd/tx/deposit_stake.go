@@ -4,12 +4,15 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/crypto/bls"
 	"github.com/thetatoken/theta/ledger/types"
 	"github.com/thetatoken/theta/rpc"
 
@@ -57,14 +60,31 @@ func doDepositStakeCmd(cmd *cobra.Command, args []string) {
 		Address: common.HexToAddress(holderFlag),
 	}
 
-	depositStakeTx := &types.DepositStakeTx{
-		Fee: types.Coins{
-			ThetaWei: new(big.Int).SetUint64(0),
-			TFuelWei: fee,
-		},
-		Source:  source,
-		Holder:  holder,
-		Purpose: purposeFlag,
+	var depositStakeTx signableTx
+	if purposeFlag == core.StakeForGuardian {
+		blsPubkey, blsPop, holderSig := parseGuardianKey(guardianKeyFlag)
+		depositStakeTx = &types.DepositStakeTxV2{
+			Fee: types.Coins{
+				ThetaWei: new(big.Int).SetUint64(0),
+				TFuelWei: fee,
+			},
+			Source:    source,
+			Holder:    holder,
+			Purpose:   purposeFlag,
+			BlsPubkey: blsPubkey,
+			BlsPop:    blsPop,
+			HolderSig: holderSig,
+		}
+	} else {
+		depositStakeTx = &types.DepositStakeTx{
+			Fee: types.Coins{
+				ThetaWei: new(big.Int).SetUint64(0),
+				TFuelWei: fee,
+			},
+			Source:  source,
+			Holder:  holder,
+			Purpose: purposeFlag,
+		}
 	}
 
 	sig, err := wallet.Sign(sourceAddress, depositStakeTx.SignBytes(chainIDFlag))
@@ -91,6 +111,26 @@ func doDepositStakeCmd(cmd *cobra.Command, args []string) {
 	fmt.Printf("Successfully broadcasted transaction.\n")
 }
 
+type signableTx interface {
+	types.Tx
+	SetSignature(addr common.Address, sig *crypto.Signature) bool
+}
+
+// parseGuardianKey parses the guardian key summary returned by the GetGuardianInfo RPC
+func parseGuardianKey(summary string) (common.Bytes, common.Bytes, *crypto.Signature) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(summary, "0x"))
+	if err != nil || len(raw) <= bls.PublicKeyLength+bls.SignatureLength {
+		utils.Error("Invalid guardian key, please use the summary returned by \"thetacli query guardian\" on the guardian node\n")
+	}
+	blsPubkey := raw[:bls.PublicKeyLength]
+	blsPop := raw[bls.PublicKeyLength : bls.PublicKeyLength+bls.SignatureLength]
+	holderSig, err := crypto.SignatureFromBytes(raw[bls.PublicKeyLength+bls.SignatureLength:])
+	if err != nil {
+		utils.Error("Invalid holder signature in the guardian key: %v\n", err)
+	}
+	return blsPubkey, blsPop, holderSig
+}
+
 func init() {
 	depositStakeCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
 	depositStakeCmd.Flags().StringVar(&sourceFlag, "source", "", "Source of the stake")
@@ -100,6 +140,7 @@ func init() {
 	depositStakeCmd.Flags().StringVar(&stakeInThetaFlag, "stake", "5000000", "Theta amount to stake")
 	depositStakeCmd.Flags().Uint8Var(&purposeFlag, "purpose", 0, "Purpose of staking")
 	depositStakeCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
+	depositStakeCmd.Flags().StringVar(&guardianKeyFlag, "guardian_key", "", "Guardian key summary, required when staking for a guardian")
 
 	depositStakeCmd.MarkFlagRequired("chain")
 	depositStakeCmd.MarkFlagRequired("source")
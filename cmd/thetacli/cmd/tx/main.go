@@ -31,6 +31,8 @@ var (
 	purposeFlag                  uint8
 	sourceFlag                   string
 	holderFlag                   string
+	guardianKeyFlag              string
+	batchFlag                    string
 )
 
 // TxCmd represents the Tx command
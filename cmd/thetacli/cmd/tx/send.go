@@ -1,15 +1,15 @@
 package tx
 
 import (
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"math/big"
+	"io/ioutil"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/txbuilder"
 	"github.com/thetatoken/theta/ledger/types"
 	"github.com/thetatoken/theta/rpc"
 
@@ -19,67 +19,98 @@ import (
 // sendCmd represents the send command
 // Example:
 //		thetacli tx send --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --theta=10 --tfuel=900000 --seq=1
+//		thetacli tx send --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --batch=payout.json --seq=1
 var sendCmd = &cobra.Command{
-	Use:     "send",
-	Short:   "Send tokens",
-	Example: `thetacli tx send --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --theta=10 --tfuel=900000 --seq=1`,
-	Run:     doSendCmd,
+	Use:   "send",
+	Short: "Send tokens",
+	Long: `Send tokens to one address, or to many addresses in one transaction with --batch. The batch
+file is a JSON list of recipients, e.g. [{"address": "0x9F12...3Ec6", "theta": "10", "tfuel": "900000"}].
+The fee of a batch grows with the number of recipients, at a fraction of the fee of a transaction each.`,
+	Example: `thetacli tx send --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --theta=10 --tfuel=900000 --seq=1
+thetacli tx send --chain="privatenet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --batch=payout.json --seq=1`,
+	Run: doSendCmd,
+}
+
+// batchRecipient is a recipient listed in the batch file.
+type batchRecipient struct {
+	Address string `json:"address"`
+	Theta   string `json:"theta"`
+	TFuel   string `json:"tfuel"`
+}
+
+// parseOutputs returns the outputs of the transaction, read from the batch file if set.
+func parseOutputs() ([]types.TxOutput, error) {
+	recipients := []batchRecipient{{Address: toFlag, Theta: thetaAmountFlag, TFuel: tfuelAmountFlag}}
+	if batchFlag != "" {
+		raw, err := ioutil.ReadFile(batchFlag)
+		if err != nil {
+			return nil, err
+		}
+		recipients = []batchRecipient{}
+		if err := json.Unmarshal(raw, &recipients); err != nil {
+			return nil, fmt.Errorf("Failed to parse batch file: %v", err)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("No recipient")
+	}
+
+	outputs := []types.TxOutput{}
+	for _, recipient := range recipients {
+		if !common.IsHexAddress(recipient.Address) {
+			return nil, fmt.Errorf("Invalid recipient address: %v", recipient.Address)
+		}
+		theta, ok := types.ParseCoinAmount(defaultAmount(recipient.Theta))
+		if !ok {
+			return nil, fmt.Errorf("Failed to parse theta amount of %v", recipient.Address)
+		}
+		tfuel, ok := types.ParseCoinAmount(defaultAmount(recipient.TFuel))
+		if !ok {
+			return nil, fmt.Errorf("Failed to parse tfuel amount of %v", recipient.Address)
+		}
+		outputs = append(outputs, types.TxOutput{
+			Address: common.HexToAddress(recipient.Address),
+			Coins:   types.Coins{ThetaWei: theta, TFuelWei: tfuel},
+		})
+	}
+	return outputs, nil
+}
+
+func defaultAmount(amount string) string {
+	if amount == "" {
+		return "0"
+	}
+	return amount
 }
 
 func doSendCmd(cmd *cobra.Command, args []string) {
+	if (toFlag == "") == (batchFlag == "") {
+		utils.Error("Either --to or --batch must be set\n")
+	}
+	outputs, err := parseOutputs()
+	if err != nil {
+		utils.Error("%v\n", err)
+	}
+
 	wallet, fromAddress, err := walletUnlock(cmd, fromFlag)
 	if err != nil {
 		return
 	}
 	defer wallet.Lock(fromAddress)
 
-	theta, ok := types.ParseCoinAmount(thetaAmountFlag)
-	if !ok {
-		utils.Error("Failed to parse theta amount")
-	}
-	tfuel, ok := types.ParseCoinAmount(tfuelAmountFlag)
-	if !ok {
-		utils.Error("Failed to parse tfuel amount")
-	}
 	fee, ok := types.ParseCoinAmount(feeFlag)
 	if !ok {
 		utils.Error("Failed to parse fee")
 	}
-	inputs := []types.TxInput{{
-		Address: fromAddress,
-		Coins: types.Coins{
-			TFuelWei: new(big.Int).Add(tfuel, fee),
-			ThetaWei: theta,
-		},
-		Sequence: uint64(seqFlag),
-	}}
-	outputs := []types.TxOutput{{
-		Address: common.HexToAddress(toFlag),
-		Coins: types.Coins{
-			TFuelWei: tfuel,
-			ThetaWei: theta,
-		},
-	}}
-	sendTx := &types.SendTx{
-		Fee: types.Coins{
-			ThetaWei: new(big.Int).SetUint64(0),
-			TFuelWei: fee,
-		},
-		Inputs:  inputs,
-		Outputs: outputs,
-	}
-
-	sig, err := wallet.Sign(fromAddress, sendTx.SignBytes(chainIDFlag))
-	if err != nil {
+	sendTx := txbuilder.NewBuilder(chainIDFlag).WithFee(fee).SendToMany(fromAddress, seqFlag, outputs)
+	if err := txbuilder.Sign(chainIDFlag, sendTx, &walletSigner{wallet: wallet, address: fromAddress}); err != nil {
 		utils.Error("Failed to sign transaction: %v\n", err)
 	}
-	sendTx.SetSignature(fromAddress, sig)
 
-	raw, err := types.TxToBytes(sendTx)
+	signedTx, err := txbuilder.Encode(sendTx)
 	if err != nil {
 		utils.Error("Failed to encode transaction: %v\n", err)
 	}
-	signedTx := hex.EncodeToString(raw)
 
 	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
 
@@ -109,11 +140,11 @@ func init() {
 	sendCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
 	sendCmd.Flags().StringVar(&thetaAmountFlag, "theta", "0", "Theta amount")
 	sendCmd.Flags().StringVar(&tfuelAmountFlag, "tfuel", "0", "TFuel amount")
-	sendCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeTFuelWei), "Fee")
+	sendCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeTFuelWei), "Fee, or fee for the first recipient of a batch")
 	sendCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
+	sendCmd.Flags().StringVar(&batchFlag, "batch", "", "JSON file listing the recipients to send to in one transaction")
 
 	sendCmd.MarkFlagRequired("chain")
 	sendCmd.MarkFlagRequired("from")
-	sendCmd.MarkFlagRequired("to")
 	sendCmd.MarkFlagRequired("seq")
 }
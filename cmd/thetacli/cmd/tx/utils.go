@@ -8,6 +8,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/txbuilder"
 	"github.com/thetatoken/theta/wallet"
 	wtypes "github.com/thetatoken/theta/wallet/types"
 )
@@ -90,3 +92,19 @@ func getWalletType(cmd *cobra.Command) (walletType wtypes.WalletType) {
 	}
 	return walletType
 }
+
+// walletSigner signs transactions with an unlocked key of the wallet.
+type walletSigner struct {
+	wallet  wtypes.Wallet
+	address common.Address
+}
+
+var _ txbuilder.Signer = (*walletSigner)(nil)
+
+func (s *walletSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *walletSigner) Sign(data common.Bytes) (*crypto.Signature, error) {
+	return s.wallet.Sign(s.address, data)
+}
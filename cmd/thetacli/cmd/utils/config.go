@@ -5,9 +5,16 @@ import "github.com/spf13/viper"
 const (
 	CfgRemoteRPCEndpoint = "remoteRPCEndpoint"
 	CfgDebug             = "debug"
+
+	// CfgDaemonListenAddress sets the address the wallet daemon listens on.
+	CfgDaemonListenAddress = "daemon.listenAddress"
+	// CfgDaemonUnlockTimeout sets the default time in seconds a key unlocked through the daemon stays unlocked.
+	CfgDaemonUnlockTimeout = "daemon.unlockTimeout"
 )
 
 func init() {
 	viper.SetDefault(CfgRemoteRPCEndpoint, "http://localhost:16888/rpc")
 	viper.SetDefault(CfgDebug, false)
+	viper.SetDefault(CfgDaemonListenAddress, "127.0.0.1:16890")
+	viper.SetDefault(CfgDaemonUnlockTimeout, 300)
 }
@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+)
+
+// ------------------------------- NewKey -----------------------------------
+
+type NewKeyArgs struct {
+	Password string `json:"password"`
+}
+
+type NewKeyResult struct {
+	Address common.Address `json:"address"`
+}
+
+// NewKey creates a key encrypted with the password. The new key is left locked.
+func (t *ThetaCliRPCService) NewKey(args *NewKeyArgs, result *NewKeyResult) (err error) {
+	if args.Password == "" {
+		return errors.New("Password must be specified")
+	}
+	result.Address, err = t.wallet.NewKey(args.Password)
+	if err != nil {
+		return err
+	}
+	return t.wallet.Lock(result.Address)
+}
+
+// ------------------------------- ListKeys -----------------------------------
+
+type ListKeysArgs struct{}
+
+type ListKeysResult struct {
+	Addresses []common.Address `json:"addresses"`
+}
+
+func (t *ThetaCliRPCService) ListKeys(args *ListKeysArgs, result *ListKeysResult) (err error) {
+	result.Addresses, err = t.wallet.List()
+	return err
+}
+
+// ------------------------------- UnlockKey -----------------------------------
+
+type UnlockKeyArgs struct {
+	Address  string `json:"address"`
+	Password string `json:"password"`
+	Timeout  int64  `json:"timeout"` // in seconds, 0 for the default timeout of the daemon
+}
+
+type UnlockKeyResult struct {
+	Unlocked bool      `json:"unlocked"`
+	Until    time.Time `json:"until"`
+}
+
+// UnlockKey decrypts the key, which stays unlocked until the timeout expires or LockKey is called.
+func (t *ThetaCliRPCService) UnlockKey(args *UnlockKeyArgs, result *UnlockKeyResult) (err error) {
+	if args.Timeout < 0 {
+		return errors.New("Timeout must not be negative")
+	}
+	timeout := t.unlockTimeout
+	if args.Timeout > 0 {
+		timeout = time.Duration(args.Timeout) * time.Second
+	}
+	if err := t.wallet.UnlockWithTimeout(common.HexToAddress(args.Address), args.Password, timeout); err != nil {
+		return err
+	}
+	result.Unlocked = true
+	result.Until = time.Now().Add(timeout)
+	return nil
+}
+
+// ------------------------------- LockKey -----------------------------------
+
+type LockKeyArgs struct {
+	Address string `json:"address"`
+}
+
+type LockKeyResult struct {
+	Locked bool `json:"locked"`
+}
+
+func (t *ThetaCliRPCService) LockKey(args *LockKeyArgs, result *LockKeyResult) (err error) {
+	if err := t.wallet.Lock(common.HexToAddress(args.Address)); err != nil {
+		return err
+	}
+	result.Locked = true
+	return nil
+}
+
+// ------------------------------- IsKeyUnlocked -----------------------------------
+
+type IsKeyUnlockedArgs struct {
+	Address string `json:"address"`
+}
+
+type IsKeyUnlockedResult struct {
+	Unlocked bool `json:"unlocked"`
+}
+
+func (t *ThetaCliRPCService) IsKeyUnlocked(args *IsKeyUnlockedArgs, result *IsKeyUnlockedResult) (err error) {
+	result.Unlocked = t.wallet.IsUnlocked(common.HexToAddress(args.Address))
+	return nil
+}
+
+// ------------------------------- Sign -----------------------------------
+
+type SignArgs struct {
+	Address string `json:"address"`
+	Data    string `json:"data"` // hex encoded bytes to sign, e.g. the sign bytes of a transaction
+}
+
+type SignResult struct {
+	Signature *crypto.Signature `json:"signature"`
+}
+
+// Sign signs the data with an unlocked key.
+func (t *ThetaCliRPCService) Sign(args *SignArgs, result *SignResult) (err error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(args.Data, "0x"))
+	if err != nil {
+		return err
+	}
+	result.Signature, err = t.wallet.Sign(common.HexToAddress(args.Address), data)
+	return err
+}
@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+	sw "github.com/thetatoken/theta/wallet/softwallet"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "thetacli"})
+
+// ThetaCliRPCService serves the key management calls of the wallet daemon. The private keys
+// stay encrypted on disk, and are only kept in memory while unlocked.
+type ThetaCliRPCService struct {
+	wallet        *sw.SoftWallet
+	unlockTimeout time.Duration // Default time a key stays unlocked
+}
+
+// ThetaCliRPCServer is an instance of the wallet daemon RPC service.
+type ThetaCliRPCServer struct {
+	*ThetaCliRPCService
+
+	server  *http.Server
+	address string
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewThetaCliRPCServer creates a new instance of ThetaCliRPCServer.
+func NewThetaCliRPCServer(wallet *sw.SoftWallet, address string, unlockTimeout time.Duration) *ThetaCliRPCServer {
+	t := &ThetaCliRPCServer{
+		ThetaCliRPCService: &ThetaCliRPCService{
+			wallet:        wallet,
+			unlockTimeout: unlockTimeout,
+		},
+		address: address,
+		wg:      &sync.WaitGroup{},
+	}
+
+	s := rpc.NewServer()
+	s.RegisterName("thetacli", t.ThetaCliRPCService)
+
+	router := mux.NewRouter()
+	router.Handle("/rpc", jsonrpc2.HTTPHandler(s))
+	t.server = &http.Server{Handler: router}
+
+	return t
+}
+
+// Start creates the main goroutine.
+func (t *ThetaCliRPCServer) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	t.ctx = c
+	t.cancel = cancel
+
+	l, err := net.Listen("tcp", t.address)
+	if err != nil {
+		return err
+	}
+	logger.WithFields(log.Fields{"address": t.address}).Info("Wallet daemon started")
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		if err := t.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			logger.WithFields(log.Fields{"error": err}).Error("Wallet daemon stopped")
+		}
+	}()
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		<-t.ctx.Done()
+		t.server.Shutdown(context.Background())
+	}()
+
+	return nil
+}
+
+// Stop notifies all goroutines to stop without blocking.
+func (t *ThetaCliRPCServer) Stop() {
+	t.cancel()
+}
+
+// Wait blocks until all goroutines stop.
+func (t *ThetaCliRPCServer) Wait() {
+	t.wg.Wait()
+}
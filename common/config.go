@@ -7,42 +7,322 @@ import (
 const (
 	// CfgGenesisHash defines the hash of the genesis block
 	CfgGenesisHash = "genesis.hash"
+	// CfgGenesisForks schedules the upgrades of the chain as comma separated upgrade=height pairs,
+	// e.g. "feeMarket=1000". The upgrades which are not scheduled stay dormant.
+	CfgGenesisForks = "genesis.forks"
 
-	// CfgConsensusMaxEpochLength defines the maxium length of an epoch.
+	// CfgSubchainIDs sets the comma separated IDs of the additional chains hosted by the node, e.g. a
+	// subchain along with the mainnet. The snapshot and the database of each chain are in the
+	// subchains/<chainID> directory of the config. The upgrades of the subchains are not scheduled.
+	CfgSubchainIDs = "subchain.chainIDs"
+
+	// CfgConsensusMaxEpochLength defines the maxium length of an epoch. With adaptive epoch length,
+	// it is the length until the first epochs are observed.
 	CfgConsensusMaxEpochLength = "consensus.maxEpochLength"
+	// CfgConsensusAdaptiveEpochLength sets whether to adapt the epoch length to the observed epoch durations.
+	CfgConsensusAdaptiveEpochLength = "consensus.adaptiveEpochLength"
+	// CfgConsensusEpochTimeoutFloor sets the min adaptive epoch length in seconds.
+	CfgConsensusEpochTimeoutFloor = "consensus.epochTimeoutFloor"
+	// CfgConsensusEpochTimeoutCeiling sets the max adaptive epoch length in seconds, after back-offs on missed epochs.
+	CfgConsensusEpochTimeoutCeiling = "consensus.epochTimeoutCeiling"
 	// CfgConsensusMinProposalWait defines the minimal interval between proposals.
 	CfgConsensusMinProposalWait = "consensus.minProposalWait"
+	// CfgConsensusPipelineProposal sets whether the proposer of the next epoch prepares the transactions
+	// of its block while the current block is voted on.
+	CfgConsensusPipelineProposal = "consensus.pipelineProposal"
 	// CfgConsensusMessageQueueSize defines the capacity of consensus message queue.
 	CfgConsensusMessageQueueSize = "consensus.messageQueueSize"
 	// CfgConsensusMaxNumValidators defines the max number validators allowed
 	CfgConsensusMaxNumValidators = "consensus.maxNumValidators"
 
+	// CfgGuardianRoundLength sets the interval in seconds between two gossips of the guardian votes.
+	CfgGuardianRoundLength = "guardian.roundLength"
+
+	// CfgMempoolMaxBlockGas sets the gas budget of the transactions reaped for a block, 0 means uncapped.
+	CfgMempoolMaxBlockGas = "mempool.maxBlockGas"
+	// CfgMempoolMaxBlockBytes sets the size budget in bytes of the transactions reaped for a block, 0 means uncapped.
+	CfgMempoolMaxBlockBytes = "mempool.maxBlockBytes"
+	// CfgMempoolReplaceFeeBump sets the min fee increase in percent to replace a pending transaction.
+	CfgMempoolReplaceFeeBump = "mempool.replaceFeeBump"
+	// CfgMempoolMaxFutureTxs limits the number of future sequence transactions held until their gap is filled.
+	CfgMempoolMaxFutureTxs = "mempool.maxFutureTxs"
+	// CfgMempoolMaxFutureTxsPerAccount limits the number of future sequence transactions held for an account.
+	CfgMempoolMaxFutureTxsPerAccount = "mempool.maxFutureTxsPerAccount"
+	// CfgMempoolMaxPendingTxsPerAccount limits the number of pending transactions of an account, 0 means uncapped.
+	CfgMempoolMaxPendingTxsPerAccount = "mempool.maxPendingTxsPerAccount"
+	// CfgMempoolMaxPendingBytesPerAccount limits the total size in bytes of the pending transactions of an account, 0 means uncapped.
+	CfgMempoolMaxPendingBytesPerAccount = "mempool.maxPendingBytesPerAccount"
+	// CfgMempoolFreePendingTxsPerAccount sets the number of pending transactions an account can have before its new transactions need to pay more, 0 means unlimited.
+	CfgMempoolFreePendingTxsPerAccount = "mempool.freePendingTxsPerAccount"
+	// CfgMempoolPendingFeeBump sets the min fee increase in percent for each pending transaction of an account beyond the free ones.
+	CfgMempoolPendingFeeBump = "mempool.pendingFeeBump"
+	// CfgMempoolReconcileInterval sets the interval in seconds between two reconciliations of the mempool with the peers, 0 disables it.
+	CfgMempoolReconcileInterval = "mempool.reconcileInterval"
+
+	// CfgBuilderEnabled sets whether to request block proposals from an external builder.
+	CfgBuilderEnabled = "builder.enabled"
+	// CfgBuilderEndpoint sets the RPC endpoint of the external builder.
+	CfgBuilderEndpoint = "builder.endpoint"
+	// CfgBuilderAddress sets the address of the key the builder signs its payloads with.
+	CfgBuilderAddress = "builder.address"
+	// CfgBuilderAuthToken sets the bearer token sent to the builder endpoint.
+	CfgBuilderAuthToken = "builder.authToken"
+	// CfgBuilderTimeout sets the time in milliseconds to wait for the builder before building the block locally.
+	CfgBuilderTimeout = "builder.timeout"
+
+	// CfgSignerRemote sets whether to request the validator signatures from a remote signing service.
+	CfgSignerRemote = "signer.remote"
+	// CfgSignerEndpoint sets the address of the remote signing service.
+	CfgSignerEndpoint = "signer.endpoint"
+	// CfgSignerAddress sets the address of the validator key held by the remote signing service.
+	CfgSignerAddress = "signer.address"
+	// CfgSignerTimeout sets the time in milliseconds to wait for the remote signing service.
+	CfgSignerTimeout = "signer.timeout"
+	// CfgSignerListenAddress sets the address the signing service listens on.
+	CfgSignerListenAddress = "signer.listenAddress"
+	// CfgSignerAllowedNodes sets the comma separated addresses of the node keys allowed to connect to the signing service.
+	CfgSignerAllowedNodes = "signer.allowedNodes"
+
+	// CfgStorageBackend sets the database backend of the node, leveldb or rocksdb. RocksDB requires
+	// a binary built with the rocksdb tag.
+	CfgStorageBackend = "storage.backend"
+	// CfgStorageStateCacheSize sets the memory budget in MB of the cache of the state trie nodes, 0 disables it.
+	CfgStorageStateCacheSize = "storage.stateCacheSize"
+	// CfgStorageBlockBodyRetention sets the number of finalized heights whose block bodies are kept, the older
+	// blocks keep their headers only. 0 keeps all the bodies.
+	CfgStorageBlockBodyRetention = "storage.blockBodyRetention"
+	// CfgStorageBlockArchive moves the bodies of the finalized blocks from the database to memory-mapped
+	// flat files, which speeds up the sequential reads of the explorers and the reindexing.
+	CfgStorageBlockArchive = "storage.blockArchive"
+	// CfgStorageCompactionInterval sets the interval in hours between two scheduled compactions of the
+	// database, 0 disables them.
+	CfgStorageCompactionInterval = "storage.compaction.interval"
+	// CfgStorageCompactionWindow restricts the scheduled compactions to a window of the local time of day,
+	// in the HH:MM-HH:MM format, e.g. 01:00-05:00. Empty allows any time.
+	CfgStorageCompactionWindow = "storage.compaction.window"
+	// CfgStorageCompactionMaxPendingTxs defers the scheduled compactions while the mempool holds more
+	// transactions.
+	CfgStorageCompactionMaxPendingTxs = "storage.compaction.maxPendingTxs"
+	// CfgStorageRocksDBBlockCacheSize sets the size in MB of the RocksDB block cache.
+	CfgStorageRocksDBBlockCacheSize = "storage.rocksdb.blockCacheSize"
+	// CfgStorageRocksDBWriteBufferSize sets the size in MB of the RocksDB memtable of each column family.
+	CfgStorageRocksDBWriteBufferSize = "storage.rocksdb.writeBufferSize"
+	// CfgStorageRocksDBMaxOpenFiles limits the number of files kept open by RocksDB, -1 means unlimited.
+	CfgStorageRocksDBMaxOpenFiles = "storage.rocksdb.maxOpenFiles"
+	// CfgStorageRocksDBMaxBackgroundJobs limits the number of concurrent RocksDB flushes and compactions.
+	CfgStorageRocksDBMaxBackgroundJobs = "storage.rocksdb.maxBackgroundJobs"
+	// CfgStorageRocksDBCompression sets the RocksDB compression: none, snappy, lz4 or zstd.
+	CfgStorageRocksDBCompression = "storage.rocksdb.compression"
+
 	// CfgSyncMessageQueueSize defines the capacity of Sync Manager message queue.
 	CfgSyncMessageQueueSize = "sync.messageQueueSize"
+	// CfgSyncBlockRequestTimeout sets the time in milliseconds to wait for a block requested directly
+	// from the peer which referenced it in a vote or a HCC, before retrying with another peer.
+	CfgSyncBlockRequestTimeout = "sync.blockRequestTimeout"
+	// CfgSyncBlockRequestRetries sets the number of retries of a direct block request before the
+	// block is left to the periodic sync.
+	CfgSyncBlockRequestRetries = "sync.blockRequestRetries"
+	// CfgSyncMaxOrphanBlocks limits the number of blocks received before their parents which are
+	// kept until the parents arrive.
+	CfgSyncMaxOrphanBlocks = "sync.maxOrphanBlocks"
+	// CfgSyncOrphanBlockMaxAge sets the time in seconds a block is kept waiting for its parent.
+	CfgSyncOrphanBlockMaxAge = "sync.orphanBlockMaxAge"
 
 	// CfgP2PName sets the ID of local node in P2P network.
 	CfgP2PName = "p2p.name"
 	// CfgP2PPort sets the port used by P2P network.
 	CfgP2PPort = "p2p.port"
+	// CfgP2PListenAddress sets the IP the P2P network listens on. Empty listens on all the IPv4
+	// and IPv6 interfaces.
+	CfgP2PListenAddress = "p2p.listenAddress"
 	// CfgP2PSeeds sets the boostrap peers.
 	CfgP2PSeeds = "p2p.seeds"
+	// CfgP2PPersistentPeers sets the peers the node always keeps connected, e.g. the validator
+	// behind a sentry node. They are redialed when disconnected, and never evicted.
+	CfgP2PPersistentPeers = "p2p.persistentPeers"
+	// CfgP2PPrivatePeering decides whether the node only peers with its persistent peers, e.g. a
+	// validator behind sentry nodes. The node neither discovers other peers nor lets its peers
+	// share its address.
+	CfgP2PPrivatePeering = "p2p.privatePeering"
 	// CfgP2PMessageQueueSize sets the message queue size for network interface.
 	CfgP2PMessageQueueSize = "p2p.messageQueueSize"
+	// CfgP2PDNSSeeds sets the domain names whose TXT and A/AAAA records list the boostrap peers,
+	// in the "domain[:port]" form. The records are re-resolved once their TTL expires.
+	CfgP2PDNSSeeds = "p2p.dnsSeeds"
 	// CfgP2PSeedPeerOnlyOutbound decides whether only the seed peers can be outbound peers.
 	CfgP2PSeedPeerOnlyOutbound = "p2p.seedPeerOnlyOutbound"
+	// CfgP2PCompressionEnabled decides whether to advertise the support of message compression to peers.
+	CfgP2PCompressionEnabled = "p2p.compressionEnabled"
+	// CfgP2PFlowControlWindow sets the bytes each channel of a peer may send before waiting for
+	// the node to process them. 0 disables the flow control.
+	CfgP2PFlowControlWindow = "p2p.flowControlWindow"
+	// CfgP2PCrawlerMode decides whether the node runs as a crawler, which continuously walks the
+	// network instead of keeping connections, and serves the reachable peers to bootstrapping nodes.
+	CfgP2PCrawlerMode = "p2p.crawlerMode"
+	// CfgP2PCrawlInterval sets the interval in seconds between two crawl rounds.
+	CfgP2PCrawlInterval = "p2p.crawlInterval"
+	// CfgP2PPexEnabled decides whether to periodically exchange address book entries with peers.
+	CfgP2PPexEnabled = "p2p.pexEnabled"
+	// CfgP2PPexInterval sets the interval in seconds between two address book exchanges.
+	CfgP2PPexInterval = "p2p.pexInterval"
+	// CfgP2PMaxNumPeers sets the max number of peers the node connects to. Can be reloaded at runtime.
+	CfgP2PMaxNumPeers = "p2p.maxNumPeers"
+	// CfgP2PSufficientNumPeers sets the number of peers below which the node looks for more peers. Can be reloaded at runtime.
+	CfgP2PSufficientNumPeers = "p2p.sufficientNumPeers"
+	// CfgP2PMaxNumInboundPeers sets the max number of inbound peers. Once reached, a new inbound peer
+	// replaces an evictable one, or is rejected. 0 means no limit.
+	CfgP2PMaxNumInboundPeers = "p2p.maxNumInboundPeers"
+	// CfgP2PMaxNumOutboundPeers sets the max number of outbound peers, the seed peers excluded. 0 means no limit.
+	CfgP2PMaxNumOutboundPeers = "p2p.maxNumOutboundPeers"
+	// CfgP2PSendRate limits the bytes per second sent to each peer. Can be reloaded at runtime.
+	CfgP2PSendRate = "p2p.sendRate"
+	// CfgP2PRecvRate limits the bytes per second received from each peer. Can be reloaded at runtime.
+	CfgP2PRecvRate = "p2p.recvRate"
+
+	// CfgDispatcherQueueSize sets the capacity of each of the outbound message queues of the
+	// dispatcher, one per priority.
+	CfgDispatcherQueueSize = "dispatcher.queueSize"
+	// CfgDispatcherNumWorkers sets the number of workers sending the queued messages to the peers.
+	CfgDispatcherNumWorkers = "dispatcher.numWorkers"
 
-	// CfgRPCEnabled sets whether to run RPC service.
+	// CfgRPCEnabled sets whether to run RPC service. Can be reloaded at runtime.
 	CfgRPCEnabled = "rpc.enabled"
 	// CfgRPCPort sets the port of RPC service.
 	CfgRPCPort = "rpc.port"
 	// CfgRPCMaxConnections limits concurrent connections accepted by RPC server.
 	CfgRPCMaxConnections = "rpc.maxConnections"
+	// CfgRPCMaxBatchSize limits the number of calls in a JSON-RPC batch request. Can be reloaded at runtime.
+	CfgRPCMaxBatchSize = "rpc.maxBatchSize"
+	// CfgRPCIdleTimeout sets the time in seconds an idle keep-alive connection is kept open.
+	CfgRPCIdleTimeout = "rpc.idleTimeout"
+	// CfgRPCTLSCertFile sets the TLS certificate file. If empty, HTTP/2 is served over cleartext (h2c).
+	CfgRPCTLSCertFile = "rpc.tlsCertFile"
+	// CfgRPCTLSKeyFile sets the TLS private key file.
+	CfgRPCTLSKeyFile = "rpc.tlsKeyFile"
+	// CfgRPCTLSClientCAFile sets the CA certificate file the client certificates are verified against.
+	// A client with a verified certificate is allowed the admin methods. Requires TLS.
+	CfgRPCTLSClientCAFile = "rpc.tlsClientCAFile"
+	// CfgRPCAuthTokens sets the bearer tokens that authenticate the clients for the public methods.
+	CfgRPCAuthTokens = "rpc.authTokens"
+	// CfgRPCAdminTokens sets the bearer tokens that authenticate the clients for all the methods.
+	CfgRPCAdminTokens = "rpc.adminTokens"
+	// CfgRPCRequireAuth sets whether the public methods require a token or a client certificate.
+	CfgRPCRequireAuth = "rpc.requireAuth"
+	// CfgRPCAdminMethods sets the methods only allowed to the admin clients.
+	CfgRPCAdminMethods = "rpc.adminMethods"
+	// CfgRPCAdminFromLocalhost sets whether the clients on the local host are allowed the admin methods.
+	CfgRPCAdminFromLocalhost = "rpc.adminFromLocalhost"
+	// CfgRPCCORSAllowedOrigins sets the origins of the browser dapps allowed to call the RPC service, "*" for all.
+	CfgRPCCORSAllowedOrigins = "rpc.corsAllowedOrigins"
+	// CfgRPCMaxRequestBodySize limits the size in bytes of a request body, 0 for no limit.
+	CfgRPCMaxRequestBodySize = "rpc.maxRequestBodySize"
+	// CfgRPCRequestTimeout sets the time in seconds a request is handled within, 0 for no timeout.
+	CfgRPCRequestTimeout = "rpc.requestTimeout"
+	// CfgRPCTrustedProxies sets the addresses or CIDR ranges of the reverse proxies whose X-Forwarded-For
+	// header is trusted. A reverse proxy on the local host needs to be trusted, otherwise the requests it
+	// forwards are considered local.
+	CfgRPCTrustedProxies = "rpc.trustedProxies"
+
+	// CfgAdminEnabled sets whether to run the admin RPC service used by theta admin.
+	CfgAdminEnabled = "admin.enabled"
+	// CfgAdminListenAddress sets the address of the admin RPC service, which should only be reachable from the local host.
+	CfgAdminListenAddress = "admin.listenAddress"
+
+	// CfgSnapshotServePath sets the snapshot file served to peers bootstrapping from this node.
+	CfgSnapshotServePath = "snapshot.servePath"
+	// CfgSnapshotProviders sets the RPC endpoints of the nodes to download snapshot from.
+	CfgSnapshotProviders = "snapshot.providers"
+	// CfgSnapshotMinProviders sets the min number of providers that must agree on the snapshot.
+	CfgSnapshotMinProviders = "snapshot.minProviders"
+
+	// CfgCheckpointEnabled sets whether to periodically checkpoint critical node state.
+	CfgCheckpointEnabled = "checkpoint.enabled"
+	// CfgCheckpointInterval sets the checkpoint interval in milliseconds.
+	CfgCheckpointInterval = "checkpoint.interval"
+	// CfgCheckpointMaxMempoolTxs limits the number of pending transactions included in a checkpoint.
+	CfgCheckpointMaxMempoolTxs = "checkpoint.maxMempoolTxs"
+
+	// CfgStateSyncChunkSize limits the number of trie entries served in one state chunk.
+	CfgStateSyncChunkSize = "stateSync.chunkSize"
+	// CfgStateSyncRequestTimeout sets the timeout in seconds for a state chunk request.
+	CfgStateSyncRequestTimeout = "stateSync.requestTimeout"
+
+	// CfgStatsEnabled sets whether to collect chain statistics.
+	CfgStatsEnabled = "stats.enabled"
+	// CfgStatsInterval sets the interval in milliseconds to process newly finalized blocks.
+	CfgStatsInterval = "stats.interval"
+	// CfgStatsBackfillBlocks limits the number of past blocks processed on first start.
+	CfgStatsBackfillBlocks = "stats.backfillBlocks"
+
+	// CfgIndexAddress sets whether to index the transactions of each address.
+	CfgIndexAddress = "index.address"
+	// CfgIndexValidatorUptime sets whether to record the proposal and vote participation of the validators.
+	CfgIndexValidatorUptime = "index.validatorUptime"
+
+	// CfgLedgerCheckInvariants sets whether to check the invariants of the ledger state after each block
+	// is applied, e.g. the TFuel supply conservation. It traverses the whole account state twice per
+	// block, and is meant for debugging.
+	CfgLedgerCheckInvariants = "ledger.checkInvariants"
+
+	// CfgShutdownTimeout sets the max time in seconds for the sub components to complete the work
+	// in progress on shutdown, after which the node exits without closing the database.
+	CfgShutdownTimeout = "shutdown.timeout"
+
+	// CfgWatchdogEnabled sets whether to watch the finalized height and try to recover when it
+	// stops advancing.
+	CfgWatchdogEnabled = "watchdog.enabled"
+	// CfgWatchdogStallTimeout sets the time in seconds without a newly finalized block after which
+	// the chain is considered stalled.
+	CfgWatchdogStallTimeout = "watchdog.stallTimeout"
+	// CfgWatchdogWebhookURL sets the URL the stall alerts are posted to, no alerts are sent if empty.
+	CfgWatchdogWebhookURL = "watchdog.webhookURL"
+
+	// CfgNotifyWebhookURL sets the URL the operator notifications are posted to as JSON.
+	CfgNotifyWebhookURL = "notify.webhookURL"
+	// CfgNotifySlackWebhookURL sets the Slack incoming webhook the operator notifications are
+	// posted to.
+	CfgNotifySlackWebhookURL = "notify.slackWebhookURL"
+	// CfgNotifyPagerDutyRoutingKey sets the PagerDuty integration key the operator notifications
+	// are sent with, no PagerDuty events are sent if empty.
+	CfgNotifyPagerDutyRoutingKey = "notify.pagerDutyRoutingKey"
+	// CfgNotifyPagerDutyURL sets the PagerDuty Events API endpoint.
+	CfgNotifyPagerDutyURL = "notify.pagerDutyURL"
+	// CfgNotifyCooldown sets the min time in seconds between two notifications of the same event.
+	CfgNotifyCooldown = "notify.cooldown"
+	// CfgNotifyCheckInterval sets the interval in seconds at which the peer count and the disk
+	// space are checked.
+	CfgNotifyCheckInterval = "notify.checkInterval"
+	// CfgNotifyMinPeers sets the peer count below which the operator is notified.
+	CfgNotifyMinPeers = "notify.minPeers"
+	// CfgNotifyMinFreeDiskSpace sets the free disk space in MB below which the operator is notified.
+	CfgNotifyMinFreeDiskSpace = "notify.minFreeDiskSpace"
+
+	// CfgTracingEnabled sets whether to export the OpenTelemetry spans of the block processing.
+	CfgTracingEnabled = "tracing.enabled"
+	// CfgTracingExporter sets the span exporter, "otlp" or "stdout".
+	CfgTracingExporter = "tracing.exporter"
+	// CfgTracingEndpoint sets the host:port of the OTLP/HTTP collector.
+	CfgTracingEndpoint = "tracing.endpoint"
+	// CfgTracingSampleRate sets the fraction of the blocks traced, between 0 and 1.
+	CfgTracingSampleRate = "tracing.sampleRate"
 
-	// CfgLogLevels sets the log level.
+	// CfgLogLevels sets the log level. Can be reloaded at runtime.
 	CfgLogLevels = "log.levels"
 	// CfgLogPrintSelfID determines whether to print node's ID in log (Useful in simulation when
 	// there are more than one node running).
 	CfgLogPrintSelfID = "log.printSelfID"
+	// CfgLogFormat sets the log format, "text" or "json". Can be reloaded at runtime.
+	CfgLogFormat = "log.format"
+	// CfgLogFile sets the file the logs are written to, logs go to stderr if empty. Can be
+	// reloaded at runtime.
+	CfgLogFile = "log.file"
+	// CfgLogMaxSize sets the size in MB at which the log file is rotated, 0 to disable.
+	CfgLogMaxSize = "log.maxSize"
+	// CfgLogRotateInterval sets the interval in hours at which the log file is rotated, 0 to
+	// disable.
+	CfgLogRotateInterval = "log.rotateInterval"
+	// CfgLogMaxBackups sets the number of rotated log files to keep, 0 to keep all of them.
+	CfgLogMaxBackups = "log.maxBackups"
 )
 
 // InitialConfig is the default configuartion produced by init command.
@@ -53,25 +333,156 @@ p2p:
 `
 
 func init() {
+	viper.SetDefault(CfgGenesisForks, "")
+	viper.SetDefault(CfgSubchainIDs, "")
+
 	viper.SetDefault(CfgConsensusMaxEpochLength, 10)
+	viper.SetDefault(CfgConsensusAdaptiveEpochLength, true)
+	viper.SetDefault(CfgConsensusEpochTimeoutFloor, 8)
+	viper.SetDefault(CfgConsensusEpochTimeoutCeiling, 60)
 	viper.SetDefault(CfgConsensusMinProposalWait, 6)
+	viper.SetDefault(CfgConsensusPipelineProposal, true)
 	viper.SetDefault(CfgConsensusMessageQueueSize, 512)
 	viper.SetDefault(CfgConsensusMaxNumValidators, 7)
 
+	viper.SetDefault(CfgGuardianRoundLength, 2)
+
+	viper.SetDefault(CfgMempoolMaxBlockGas, 100000000)
+	viper.SetDefault(CfgMempoolMaxBlockBytes, 8*1024*1024)
+	viper.SetDefault(CfgMempoolReplaceFeeBump, 10)
+	viper.SetDefault(CfgMempoolMaxFutureTxs, 4096)
+	viper.SetDefault(CfgMempoolMaxFutureTxsPerAccount, 64)
+	viper.SetDefault(CfgMempoolMaxPendingTxsPerAccount, 256)
+	viper.SetDefault(CfgMempoolMaxPendingBytesPerAccount, 1024*1024)
+	viper.SetDefault(CfgMempoolFreePendingTxsPerAccount, 64)
+	viper.SetDefault(CfgMempoolPendingFeeBump, 10)
+	viper.SetDefault(CfgMempoolReconcileInterval, 30)
+
+	viper.SetDefault(CfgBuilderEnabled, false)
+	viper.SetDefault(CfgBuilderEndpoint, "")
+	viper.SetDefault(CfgBuilderAddress, "")
+	viper.SetDefault(CfgBuilderAuthToken, "")
+	viper.SetDefault(CfgBuilderTimeout, 1000)
+
+	viper.SetDefault(CfgSignerRemote, false)
+	viper.SetDefault(CfgSignerEndpoint, "")
+	viper.SetDefault(CfgSignerAddress, "")
+	viper.SetDefault(CfgSignerTimeout, 3000)
+	viper.SetDefault(CfgSignerListenAddress, "127.0.0.1:16900")
+	viper.SetDefault(CfgSignerAllowedNodes, "")
+
+	viper.SetDefault(CfgStorageBackend, "leveldb")
+	viper.SetDefault(CfgStorageStateCacheSize, 256)
+	viper.SetDefault(CfgStorageBlockBodyRetention, 0)
+	viper.SetDefault(CfgStorageBlockArchive, false)
+	viper.SetDefault(CfgStorageCompactionInterval, 0)
+	viper.SetDefault(CfgStorageCompactionWindow, "")
+	viper.SetDefault(CfgStorageCompactionMaxPendingTxs, 100)
+	viper.SetDefault(CfgStorageRocksDBBlockCacheSize, 512)
+	viper.SetDefault(CfgStorageRocksDBWriteBufferSize, 64)
+	viper.SetDefault(CfgStorageRocksDBMaxOpenFiles, 1024)
+	viper.SetDefault(CfgStorageRocksDBMaxBackgroundJobs, 4)
+	viper.SetDefault(CfgStorageRocksDBCompression, "lz4")
+
 	viper.SetDefault(CfgSyncMessageQueueSize, 512)
+	viper.SetDefault(CfgSyncBlockRequestTimeout, 2000)
+	viper.SetDefault(CfgSyncBlockRequestRetries, 3)
+	viper.SetDefault(CfgSyncMaxOrphanBlocks, 1024)
+	viper.SetDefault(CfgSyncOrphanBlockMaxAge, 600)
+
+	viper.SetDefault(CfgDispatcherQueueSize, 2048)
+	viper.SetDefault(CfgDispatcherNumWorkers, 8)
 
 	viper.SetDefault(CfgRPCEnabled, false)
 	viper.SetDefault(CfgP2PMessageQueueSize, 512)
 	viper.SetDefault(CfgP2PName, "Anonymous")
 	viper.SetDefault(CfgP2PPort, 50001)
+	viper.SetDefault(CfgP2PListenAddress, "")
 	viper.SetDefault(CfgP2PSeeds, "")
+	viper.SetDefault(CfgP2PDNSSeeds, "")
+	viper.SetDefault(CfgP2PPersistentPeers, "")
+	viper.SetDefault(CfgP2PPrivatePeering, false)
 	viper.SetDefault(CfgP2PSeedPeerOnlyOutbound, false)
+	viper.SetDefault(CfgP2PCompressionEnabled, true)
+	viper.SetDefault(CfgP2PFlowControlWindow, 256*1024) // 256KB
+	viper.SetDefault(CfgP2PCrawlerMode, false)
+	viper.SetDefault(CfgP2PCrawlInterval, 30)
+	viper.SetDefault(CfgP2PPexEnabled, true)
+	viper.SetDefault(CfgP2PPexInterval, 60)
+	viper.SetDefault(CfgP2PMaxNumPeers, 128)
+	viper.SetDefault(CfgP2PSufficientNumPeers, 32)
+	viper.SetDefault(CfgP2PMaxNumInboundPeers, 96)
+	viper.SetDefault(CfgP2PMaxNumOutboundPeers, 32)
+	viper.SetDefault(CfgP2PSendRate, 512000) // 500KB/s
+	viper.SetDefault(CfgP2PRecvRate, 512000) // 500KB/s
 
 	viper.SetDefault(CfgRPCPort, "16888")
 	viper.SetDefault(CfgRPCMaxConnections, 200)
+	viper.SetDefault(CfgRPCMaxBatchSize, 100)
+	viper.SetDefault(CfgRPCIdleTimeout, 120)
+	viper.SetDefault(CfgRPCTLSCertFile, "")
+	viper.SetDefault(CfgRPCTLSKeyFile, "")
+	viper.SetDefault(CfgRPCTLSClientCAFile, "")
+	viper.SetDefault(CfgRPCAuthTokens, []string{})
+	viper.SetDefault(CfgRPCAdminTokens, []string{})
+	viper.SetDefault(CfgRPCRequireAuth, false)
+	viper.SetDefault(CfgRPCAdminMethods, []string{"theta.GenBackup", "theta.GenSnapshot", "theta.GetConsensusState"})
+	viper.SetDefault(CfgRPCAdminFromLocalhost, true)
+	viper.SetDefault(CfgRPCCORSAllowedOrigins, []string{})
+	viper.SetDefault(CfgRPCMaxRequestBodySize, 10*1024*1024)
+	viper.SetDefault(CfgRPCRequestTimeout, 60)
+	viper.SetDefault(CfgRPCTrustedProxies, []string{})
+
+	viper.SetDefault(CfgAdminEnabled, false)
+	viper.SetDefault(CfgAdminListenAddress, "127.0.0.1:16889")
+
+	viper.SetDefault(CfgSnapshotServePath, "")
+	viper.SetDefault(CfgSnapshotProviders, "")
+	viper.SetDefault(CfgSnapshotMinProviders, 3)
+
+	viper.SetDefault(CfgCheckpointEnabled, true)
+	viper.SetDefault(CfgCheckpointInterval, 1000)
+	viper.SetDefault(CfgCheckpointMaxMempoolTxs, 1024)
+
+	viper.SetDefault(CfgStateSyncChunkSize, 512)
+	viper.SetDefault(CfgStateSyncRequestTimeout, 10)
+
+	viper.SetDefault(CfgStatsEnabled, true)
+	viper.SetDefault(CfgStatsInterval, 1000)
+	viper.SetDefault(CfgStatsBackfillBlocks, 10000)
+
+	viper.SetDefault(CfgIndexAddress, false)
+	viper.SetDefault(CfgIndexValidatorUptime, false)
+
+	viper.SetDefault(CfgLedgerCheckInvariants, false)
+
+	viper.SetDefault(CfgShutdownTimeout, 30)
+
+	viper.SetDefault(CfgWatchdogEnabled, true)
+	viper.SetDefault(CfgWatchdogStallTimeout, 300)
+	viper.SetDefault(CfgWatchdogWebhookURL, "")
+
+	viper.SetDefault(CfgNotifyWebhookURL, "")
+	viper.SetDefault(CfgNotifySlackWebhookURL, "")
+	viper.SetDefault(CfgNotifyPagerDutyRoutingKey, "")
+	viper.SetDefault(CfgNotifyPagerDutyURL, "https://events.pagerduty.com/v2/enqueue")
+	viper.SetDefault(CfgNotifyCooldown, 600)
+	viper.SetDefault(CfgNotifyCheckInterval, 60)
+	viper.SetDefault(CfgNotifyMinPeers, 3)
+	viper.SetDefault(CfgNotifyMinFreeDiskSpace, 1024)
+
+	viper.SetDefault(CfgTracingEnabled, false)
+	viper.SetDefault(CfgTracingExporter, "otlp")
+	viper.SetDefault(CfgTracingEndpoint, "localhost:4318")
+	viper.SetDefault(CfgTracingSampleRate, 1.0)
 
 	viper.SetDefault(CfgLogLevels, "*:debug")
 	viper.SetDefault(CfgLogPrintSelfID, false)
+	viper.SetDefault(CfgLogFormat, "text")
+	viper.SetDefault(CfgLogFile, "")
+	viper.SetDefault(CfgLogMaxSize, 100)
+	viper.SetDefault(CfgLogRotateInterval, 24)
+	viper.SetDefault(CfgLogMaxBackups, 10)
 }
 
 // WriteInitialConfig writes initial config file to file system.
@@ -1,5 +1,7 @@
 package result
 
+import "fmt"
+
 type ErrorCode int
 
 const (
@@ -13,6 +15,14 @@ const (
 	CodeEmptyPubKeyWithSequence1 ErrorCode = 100004
 	CodeUnauthorizedTx           ErrorCode = 100005
 	CodeInvalidFee               ErrorCode = 100006
+	CodeFutureSequence           ErrorCode = 100007
+	CodeTxDecodingFailed         ErrorCode = 100008
+	CodeUnknownTxType            ErrorCode = 100009
+	CodeAccountNotFound          ErrorCode = 100010
+	CodeDuplicatedAddress        ErrorCode = 100011
+	CodeInvalidInputOutput       ErrorCode = 100012
+	CodeTooManyAccounts          ErrorCode = 100013
+	CodeInputOutputMismatch      ErrorCode = 100014
 
 	// ReserveFund Errors
 	CodeReserveFundCheckFailed   ErrorCode = 101001
@@ -27,16 +37,133 @@ const (
 
 	// SplitRule Errors
 	CodeUnauthorizedToUpdateSplitRule ErrorCode = 104001
+	CodeInvalidSplitRule              ErrorCode = 104002
 
 	// SmartContract Errors
 	CodeEVMError               ErrorCode = 105001
 	CodeInvalidValueToTransfer ErrorCode = 105002
 	CodeInvalidGasPrice        ErrorCode = 105003
 	CodeFeeLimitTooHigh        ErrorCode = 105004
+	CodeGasLimitTooHigh        ErrorCode = 105005
 
 	// Stake Deposit/Withdrawal Errors
 	CodeInvalidStakePurpose     ErrorCode = 106001
 	CodeInvalidStake            ErrorCode = 106002
 	CodeInsufficientStake       ErrorCode = 106003
 	CodeNotEnoughBalanceToStake ErrorCode = 106004
+	CodeInvalidBlsKey           ErrorCode = 106005
+
+	// Mempool Errors
+	CodeTxAlreadySeen          ErrorCode = 107001
+	CodeReplacementUnderpriced ErrorCode = 107002
+	CodeExceedsBlockBudget     ErrorCode = 107003
+	CodeFutureTxQueueFull      ErrorCode = 107004
+	CodeSenderPendingLimit     ErrorCode = 107005
+	CodeSenderFeeTooLow        ErrorCode = 107006
+
+	// Governance Errors
+	CodeInvalidChainParams     ErrorCode = 108001
+	CodeInsufficientApprovals  ErrorCode = 108002
+	CodeUnauthorizedGovernance ErrorCode = 108003
+	CodeProposalNotFound       ErrorCode = 108004
+	CodeInvalidProposalStatus  ErrorCode = 108005
+	CodeInvalidProposalHeight  ErrorCode = 108006
+
+	// Bridge Errors
+	CodeUnknownBridgeChain        ErrorCode = 109001
+	CodeInvalidBridgeMessage      ErrorCode = 109002
+	CodeInvalidBridgeProof        ErrorCode = 109003
+	CodeBridgeMessageProcessed    ErrorCode = 109004
+	CodeInvalidBridgeValidatorSet ErrorCode = 109005
+
+	// Sponsored Transaction Errors
+	CodeInvalidSponsoredTx ErrorCode = 110001
+
+	// Time Lock Errors
+	CodeInvalidTimeLock      ErrorCode = 111001
+	CodeTimeLockNotFound     ErrorCode = 111002
+	CodeTimeLockNotReleased  ErrorCode = 111003
+	CodeUnauthorizedTimeLock ErrorCode = 111004
+
+	// Delegation Errors
+	CodeInvalidDelegation ErrorCode = 112001
 )
+
+// errorCodeNames are the stable names of the error codes, which clients can program against.
+// Names must not be changed once released.
+var errorCodeNames = map[ErrorCode]string{
+	CodeOK: "OK",
+
+	CodeGenericError:             "GenericError",
+	CodeInvalidSignature:         "InvalidSignature",
+	CodeInvalidSequence:          "InvalidSequence",
+	CodeInsufficientFund:         "InsufficientFund",
+	CodeEmptyPubKeyWithSequence1: "EmptyPubKeyWithSequence1",
+	CodeUnauthorizedTx:           "UnauthorizedTx",
+	CodeInvalidFee:               "InvalidFee",
+	CodeFutureSequence:           "FutureSequence",
+	CodeTxDecodingFailed:         "TxDecodingFailed",
+	CodeUnknownTxType:            "UnknownTxType",
+	CodeAccountNotFound:          "AccountNotFound",
+	CodeDuplicatedAddress:        "DuplicatedAddress",
+	CodeInvalidInputOutput:       "InvalidInputOutput",
+	CodeTooManyAccounts:          "TooManyAccounts",
+	CodeInputOutputMismatch:      "InputOutputMismatch",
+
+	CodeReserveFundCheckFailed:   "ReserveFundCheckFailed",
+	CodeReservedFundNotSpecified: "ReservedFundNotSpecified",
+	CodeInvalidFundToReserve:     "InvalidFundToReserve",
+
+	CodeReleaseFundCheckFailed: "ReleaseFundCheckFailed",
+
+	CodeCheckTransferReservedFundFailed: "CheckTransferReservedFundFailed",
+
+	CodeUnauthorizedToUpdateSplitRule: "UnauthorizedToUpdateSplitRule",
+	CodeInvalidSplitRule:              "InvalidSplitRule",
+
+	CodeEVMError:               "EVMError",
+	CodeInvalidValueToTransfer: "InvalidValueToTransfer",
+	CodeInvalidGasPrice:        "InvalidGasPrice",
+	CodeFeeLimitTooHigh:        "FeeLimitTooHigh",
+	CodeGasLimitTooHigh:        "GasLimitTooHigh",
+
+	CodeInvalidStakePurpose:     "InvalidStakePurpose",
+	CodeInvalidStake:            "InvalidStake",
+	CodeInsufficientStake:       "InsufficientStake",
+	CodeNotEnoughBalanceToStake: "NotEnoughBalanceToStake",
+	CodeInvalidBlsKey:           "InvalidBlsKey",
+
+	CodeTxAlreadySeen:          "TxAlreadySeen",
+	CodeReplacementUnderpriced: "ReplacementUnderpriced",
+	CodeExceedsBlockBudget:     "ExceedsBlockBudget",
+	CodeFutureTxQueueFull:      "FutureTxQueueFull",
+	CodeSenderPendingLimit:     "SenderPendingLimit",
+	CodeSenderFeeTooLow:        "SenderFeeTooLow",
+
+	CodeInvalidChainParams:     "InvalidChainParams",
+	CodeInsufficientApprovals:  "InsufficientApprovals",
+	CodeUnauthorizedGovernance: "UnauthorizedGovernance",
+	CodeProposalNotFound:       "ProposalNotFound",
+	CodeInvalidProposalStatus:  "InvalidProposalStatus",
+	CodeInvalidProposalHeight:  "InvalidProposalHeight",
+
+	CodeUnknownBridgeChain:        "UnknownBridgeChain",
+	CodeInvalidBridgeMessage:      "InvalidBridgeMessage",
+	CodeInvalidBridgeProof:        "InvalidBridgeProof",
+	CodeBridgeMessageProcessed:    "BridgeMessageProcessed",
+	CodeInvalidBridgeValidatorSet: "InvalidBridgeValidatorSet",
+	CodeInvalidSponsoredTx:        "InvalidSponsoredTx",
+	CodeInvalidTimeLock:           "InvalidTimeLock",
+	CodeTimeLockNotFound:          "TimeLockNotFound",
+	CodeTimeLockNotReleased:       "TimeLockNotReleased",
+	CodeUnauthorizedTimeLock:      "UnauthorizedTimeLock",
+	CodeInvalidDelegation:         "InvalidDelegation",
+}
+
+// String returns the stable name of the error code.
+func (code ErrorCode) String() string {
+	if name, ok := errorCodeNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorCode(%d)", int(code))
+}
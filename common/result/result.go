@@ -23,7 +23,16 @@ func (res Result) IsError() bool {
 
 // String returns the string representation of the result
 func (res Result) String() string {
-	return fmt.Sprintf("Result{code:%v, message:%v}", res.Code, res.Message)
+	return fmt.Sprintf("Result{code:%d (%v), message:%v}", res.Code, res.Code, res.Message)
+}
+
+// ToError converts the result into an error carrying the error code. It returns nil if the
+// execution succeeded.
+func (res Result) ToError() error {
+	if res.IsOK() {
+		return nil
+	}
+	return &CodedError{Code: res.Code, Message: res.Message}
 }
 
 // WithErrorCode attach the error code to the result
@@ -67,3 +76,21 @@ func Error(msgFormat string, a ...interface{}) Result {
 		Info:    make(Info),
 	}
 }
+
+// -------------- Errors -------------- //
+
+// CodedError is an error with an error code, e.g. returned by the RPC layer to the clients.
+type CodedError struct {
+	Code    ErrorCode
+	Message string
+}
+
+// Error implements the error interface.
+func (e *CodedError) Error() string {
+	return e.Message
+}
+
+// ErrorCode returns the error code.
+func (e *CodedError) ErrorCode() ErrorCode {
+	return e.Code
+}
@@ -0,0 +1,240 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/thetatoken/theta/common"
+)
+
+const (
+	// ExporterOTLP exports the spans to an OpenTelemetry collector over OTLP/HTTP
+	ExporterOTLP = "otlp"
+	// ExporterStdout prints the spans to stdout, for debugging
+	ExporterStdout = "stdout"
+
+	// maxTracedBlocks limits the number of blocks traced at a time. The trace of the oldest block
+	// is ended when a new block is traced beyond the limit, e.g. if the block never completes.
+	maxTracedBlocks = 1024
+
+	tracerName = "github.com/thetatoken/theta"
+)
+
+var (
+	mu       sync.Mutex
+	enabled  bool
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer = noop.NewTracerProvider().Tracer(tracerName)
+
+	blockTraces = make(map[common.Hash]*blockTrace)
+	blockOrder  = []common.Hash{} // The traced blocks, from the oldest to the newest
+)
+
+// Init sets up the span exporter configured in the tracing section. Tracing is a no-op unless
+// it is enabled.
+func Init(ctx context.Context) error {
+	if !viper.GetBool(common.CfgTracingEnabled) {
+		return nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := viper.GetString(common.CfgTracingExporter); name {
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpoint(viper.GetString(common.CfgTracingEndpoint)),
+			otlptracehttp.WithInsecure())
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return fmt.Errorf("Unknown tracing exporter: %v", name)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to create the %v tracing exporter: %v", viper.GetString(common.CfgTracingExporter), err)
+	}
+
+	sampleRate := viper.GetFloat64(common.CfgTracingSampleRate)
+	setProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "theta"))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))),
+	))
+	return nil
+}
+
+func setProvider(p *sdktrace.TracerProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	otel.SetTracerProvider(p)
+	provider = p
+	tracer = p.Tracer(tracerName)
+	enabled = true
+}
+
+// Shutdown ends the traces in progress and flushes the spans to the exporter.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	p := provider
+	for _, hash := range blockOrder {
+		blockTraces[hash].end()
+	}
+	blockTraces = make(map[common.Hash]*blockTrace)
+	blockOrder = []common.Hash{}
+	enabled = false
+	provider = nil
+	tracer = noop.NewTracerProvider().Tracer(tracerName)
+	mu.Unlock()
+
+	if p == nil {
+		return nil
+	}
+	return p.Shutdown(ctx)
+}
+
+// Enabled returns true if the spans are exported.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// StartSpan starts a span as a child of the span in the context, if any.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	mu.Lock()
+	t := tracer
+	mu.Unlock()
+	return t.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordSpan records a span of the given interval as a child of the span in the context, for the
+// work timed by a component which does not trace itself.
+func RecordSpan(ctx context.Context, name string, start, end time.Time, attrs ...attribute.KeyValue) {
+	mu.Lock()
+	t := tracer
+	mu.Unlock()
+	_, span := t.Start(ctx, name, trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+	span.End(trace.WithTimestamp(end))
+}
+
+//
+// blockTrace is the trace of the processing of a block, from its receipt from a peer to its
+// commit. A block is handed from a component to the next through channels and queues rather
+// than with a context, so the spans of a block are linked by the block hash instead. The time a
+// block waits between two components is recorded as a stage span, which ends when the next
+// component picks up the block.
+//
+type blockTrace struct {
+	ctx        context.Context
+	root       trace.Span
+	stage      string
+	stageStart time.Time
+}
+
+// endStage records the pending stage span, if any.
+func (bt *blockTrace) endStage(now time.Time) {
+	if bt.stage == "" {
+		return
+	}
+	_, span := tracer.Start(bt.ctx, bt.stage, trace.WithTimestamp(bt.stageStart))
+	span.End(trace.WithTimestamp(now))
+	bt.stage = ""
+}
+
+func (bt *blockTrace) end() {
+	now := time.Now()
+	bt.endStage(now)
+	bt.root.End(trace.WithTimestamp(now))
+}
+
+// StartBlockTrace starts the trace of the block received at the given time, with the given stage
+// pending. It is a no-op if the block is already traced or if tracing is disabled.
+func StartBlockTrace(hash common.Hash, receivedAt time.Time, stage string, attrs ...attribute.KeyValue) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !enabled {
+		return
+	}
+	if _, ok := blockTraces[hash]; ok {
+		return
+	}
+	if len(blockOrder) >= maxTracedBlocks {
+		oldest := blockOrder[0]
+		blockTraces[oldest].end()
+		delete(blockTraces, oldest)
+		blockOrder = blockOrder[1:]
+	}
+
+	attrs = append(attrs, attribute.String("block.hash", hash.Hex()))
+	ctx, root := tracer.Start(context.Background(), "block",
+		trace.WithTimestamp(receivedAt), trace.WithAttributes(attrs...))
+	blockTraces[hash] = &blockTrace{
+		ctx:        ctx,
+		root:       root,
+		stage:      stage,
+		stageStart: receivedAt,
+	}
+	blockOrder = append(blockOrder, hash)
+}
+
+// BlockStage records the end of the pending stage of the block, and starts the given stage.
+func BlockStage(hash common.Hash, stage string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	bt, ok := blockTraces[hash]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	bt.endStage(now)
+	bt.stage = stage
+	bt.stageStart = now
+}
+
+// StartBlockSpan records the end of the pending stage of the block, and starts a span of the
+// block as a child of its trace. The span is a no-op if the block is not traced.
+func StartBlockSpan(hash common.Hash, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	bt, ok := blockTraces[hash]
+	if !ok {
+		return context.Background(), trace.SpanFromContext(context.Background())
+	}
+	bt.endStage(time.Now())
+	return tracer.Start(bt.ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndBlockTrace ends the trace of the block.
+func EndBlockTrace(hash common.Hash, attrs ...attribute.KeyValue) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	bt, ok := blockTraces[hash]
+	if !ok {
+		return
+	}
+	bt.root.SetAttributes(attrs...)
+	bt.end()
+	delete(blockTraces, hash)
+	for i, h := range blockOrder {
+		if h == hash {
+			blockOrder = append(blockOrder[:i], blockOrder[i+1:]...)
+			break
+		}
+	}
+}
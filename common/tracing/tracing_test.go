@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/thetatoken/theta/common"
+)
+
+func enableForTest() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	setProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+func TestBlockTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	recorder := enableForTest()
+	defer Shutdown(context.Background())
+
+	hash := common.BytesToHash([]byte("block"))
+	receivedAt := time.Now().Add(-time.Second)
+	StartBlockTrace(hash, receivedAt, "p2p.receive")
+	StartBlockTrace(hash, time.Now(), "p2p.receive") // Already traced
+	BlockStage(hash, "consensus.queue")
+
+	ctx, span := StartBlockSpan(hash, "ledger.apply")
+	RecordSpan(ctx, "store.commit", time.Now(), time.Now())
+	span.End()
+	EndBlockTrace(hash)
+
+	spans := recorder.Ended()
+	assert.Equal([]string{"p2p.receive", "consensus.queue", "store.commit", "ledger.apply", "block"}, spanNames(spans))
+
+	root := spans[4]
+	assert.Equal(receivedAt, root.StartTime())
+	assert.Equal(receivedAt, spans[0].StartTime())
+	for _, span := range spans[:2] {
+		assert.Equal(root.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	assert.Equal(spans[3].SpanContext().SpanID(), spans[2].Parent().SpanID())
+	assert.Equal(root.SpanContext().SpanID(), spans[3].Parent().SpanID())
+
+	// The spans of the untraced blocks are no-ops
+	_, span = StartBlockSpan(hash, "ledger.apply")
+	span.End()
+	assert.Equal(5, len(recorder.Ended()))
+}
+
+func TestBlockTraceLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	recorder := enableForTest()
+	defer Shutdown(context.Background())
+
+	for i := 0; i <= maxTracedBlocks; i++ {
+		StartBlockTrace(common.BigToHash(big.NewInt(int64(i+1))), time.Now(), "")
+	}
+	// The oldest block trace is ended to make room for the newest one
+	assert.Equal(1, len(recorder.Ended()))
+	assert.Equal(maxTracedBlocks, len(blockOrder))
+
+	assert.Nil(Shutdown(context.Background()))
+	assert.Equal(maxTracedBlocks+1, len(recorder.Ended()))
+	assert.False(Enabled())
+
+	// Tracing is disabled after the shutdown
+	StartBlockTrace(common.BytesToHash([]byte("block")), time.Now(), "")
+	assert.Equal(0, len(blockOrder))
+}
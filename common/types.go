@@ -388,4 +388,29 @@ const (
 
 	// ChannelIDPing indicates the channel for Ping/Pong messages between peers
 	ChannelIDPing
+
+	// ChannelIDState indicates the channel for streaming state trie chunks
+	ChannelIDState
+
+	// ChannelIDPEX indicates the channel for exchanging address book entries between peers
+	ChannelIDPEX
+
+	// ChannelIDGuardian indicates the channel for the aggregated votes of the guardians
+	ChannelIDGuardian
+
+	// ChannelIDTxGossip indicates the channel for announcing transaction hashes and requesting the unknown transactions
+	ChannelIDTxGossip
+
+	// ChannelIDFlowControl indicates the channel for the flow-control window updates between peers
+	ChannelIDFlowControl
+
+	// ChannelIDTxReconcile indicates the channel for exchanging the sketches of the mempool transaction hashes
+	ChannelIDTxReconcile
+
+	// ChannelIDBlockBody indicates the channel for the block bodies, requested separately from the headers
+	ChannelIDBlockBody
+
+	// ChannelIDChainMux indicates the channel for the messages of the additional chains hosted by the nodes,
+	// each carrying the chain ID and the channel of the chain it is sent on
+	ChannelIDChainMux
 )
@@ -2,7 +2,11 @@ package util
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -11,6 +15,20 @@ import (
 
 var logLevels map[string]string
 
+var (
+	loggersMu     sync.Mutex
+	moduleLoggers = make(map[string][]*log.Logger) // Loggers created for each module, updated on log level changes
+
+	logFormatter log.Formatter = newTextFormatter()
+	logOutput    io.Writer     = os.Stderr
+	logFile      *RotatingFile // The rotating log file, nil if the logs go to stderr
+)
+
+const (
+	textFormat = "text"
+	jsonFormat = "json"
+)
+
 const (
 	panicLevel = "panic"
 	fatalLevel = "fatal"
@@ -41,24 +59,171 @@ func parseLogLevelConfig(config string) map[string]string {
 
 // GetLoggerForModule returns the logger for given module.
 func GetLoggerForModule(module string) *log.Entry {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
 	if logLevels == nil {
 		logLevels = parseLogLevelConfig(viper.GetString(common.CfgLogLevels))
 		log.Infof("Log settings: %v, %v", logLevels, viper.GetString(common.CfgLogLevels))
 	}
+	log.SetFormatter(logFormatter)
+
+	logger := log.New()
+	logger.Formatter = logFormatter
+	logger.Out = logOutput
+
+	setLevel(logger, moduleLevel(module))
+	moduleLoggers[module] = append(moduleLoggers[module], logger)
+
+	return logger.WithFields(log.Fields{"prefix": module})
+}
+
+// SetLogLevel changes the log level of the module at runtime. Module "*" changes the level of
+// the modules without a level of their own.
+func SetLogLevel(module string, level string) error {
+	if !isValidLevel(level) {
+		return fmt.Errorf("Invalid log level: %v", level)
+	}
+
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	if logLevels == nil {
+		logLevels = parseLogLevelConfig(viper.GetString(common.CfgLogLevels))
+	}
+	logLevels[module] = level
+	updateLoggers()
+	return nil
+}
+
+// ReloadLogLevels replaces the log levels of all modules with the ones of the config, which has
+// the format of the log.levels setting, e.g. "*:info,consensus:debug".
+func ReloadLogLevels(config string) error {
+	for _, moduleAndLevel := range strings.Split(config, ",") {
+		tokens := strings.Split(moduleAndLevel, ":")
+		if len(tokens) != 2 {
+			return fmt.Errorf("Failed to parse module log level: \"%v\"", moduleAndLevel)
+		}
+		if level := strings.TrimSpace(tokens[1]); !isValidLevel(level) {
+			return fmt.Errorf("Invalid log level: %v", level)
+		}
+	}
+
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	logLevels = parseLogLevelConfig(config)
+	updateLoggers()
+	return nil
+}
+
+// InitLogging applies the log settings of the config to the loggers created so far and to the
+// ones created afterwards. The loggers of the packages are created before the config is read, so
+// it must be called once the config is loaded.
+func InitLogging() error {
+	if err := ReloadLogLevels(viper.GetString(common.CfgLogLevels)); err != nil {
+		return err
+	}
+	return ReloadLogOutput()
+}
+
+// ReloadLogOutput applies the log format and the log file settings of the config. The log file
+// is reopened, so that a change of its path or of its rotation settings takes effect.
+func ReloadLogOutput() error {
+	var formatter log.Formatter
+	switch format := viper.GetString(common.CfgLogFormat); format {
+	case textFormat, "":
+		formatter = newTextFormatter()
+	case jsonFormat:
+		formatter = &log.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	default:
+		return fmt.Errorf("Invalid log format: %v", format)
+	}
+
+	var output io.Writer = os.Stderr
+	var file *RotatingFile
+	if path := viper.GetString(common.CfgLogFile); path != "" {
+		var err error
+		file, err = NewRotatingFile(path,
+			viper.GetInt64(common.CfgLogMaxSize)*1024*1024,
+			time.Duration(viper.GetInt64(common.CfgLogRotateInterval))*time.Hour,
+			viper.GetInt(common.CfgLogMaxBackups))
+		if err != nil {
+			return fmt.Errorf("Failed to open log file %v: %v", path, err)
+		}
+		output = file
+	}
+
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	previousFile := logFile
+	logFormatter = formatter
+	logOutput = output
+	logFile = file
+
+	log.SetFormatter(formatter)
+	log.SetOutput(output)
+	for _, loggers := range moduleLoggers {
+		for _, logger := range loggers {
+			logger.Formatter = formatter
+			logger.Out = output
+		}
+	}
+
+	if previousFile != nil {
+		previousFile.Close()
+	}
+	return nil
+}
+
+func newTextFormatter() *TextFormatter {
 	customFormatter := new(TextFormatter)
 	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
-	log.SetFormatter(customFormatter)
 	customFormatter.FullTimestamp = true
 	customFormatter.ForceFormatting = true
+	return customFormatter
+}
 
-	logger := log.New()
-	logger.Formatter = customFormatter
+// GetLogLevels returns the current log level of each configured module.
+func GetLogLevels() map[string]string {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+
+	levels := make(map[string]string)
+	for module, level := range logLevels {
+		levels[module] = level
+	}
+	return levels
+}
+
+func isValidLevel(level string) bool {
+	switch level {
+	case panicLevel, fatalLevel, errorLevel, warnLevel, infoLevel, debugLevel:
+		return true
+	}
+	return false
+}
+
+// updateLoggers applies the current log levels to the loggers created so far. Caller must hold
+// loggersMu.
+func updateLoggers() {
+	for module, loggers := range moduleLoggers {
+		for _, logger := range loggers {
+			setLevel(logger, moduleLevel(module))
+		}
+	}
+}
 
+func moduleLevel(module string) string {
 	level, ok := logLevels[module]
 	if !ok {
 		level = logLevels["*"]
 	}
+	return level
+}
 
+func setLevel(logger *log.Logger, level string) {
 	if level == panicLevel {
 		logger.SetLevel(log.PanicLevel)
 	} else if level == fatalLevel {
@@ -72,6 +237,4 @@ func GetLoggerForModule(module string) *log.Entry {
 	} else if level == debugLevel {
 		logger.SetLevel(log.DebugLevel)
 	}
-
-	return logger.WithFields(log.Fields{"prefix": module})
 }
@@ -3,10 +3,16 @@
 package util
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
 )
 
 func TestParseLogLevelConfig(t *testing.T) {
@@ -35,3 +41,79 @@ func TestGetLoggerForModule(t *testing.T) {
 	assert.Equal(log.InfoLevel, GetLoggerForModule("consensus").Logger.Level)
 	assert.Equal(log.ErrorLevel, GetLoggerForModule("sync").Logger.Level)
 }
+
+func TestSetLogLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	logLevels = parseLogLevelConfig("*:error,p2p:debug")
+	p2pLogger := GetLoggerForModule("p2p")
+	syncLogger := GetLoggerForModule("sync")
+
+	assert.Nil(SetLogLevel("p2p", "info"))
+	assert.Equal(log.InfoLevel, p2pLogger.Logger.Level)
+	assert.Equal(log.ErrorLevel, syncLogger.Logger.Level)
+
+	// The default level applies to the modules without a level of their own.
+	assert.Nil(SetLogLevel("*", "debug"))
+	assert.Equal(log.InfoLevel, p2pLogger.Logger.Level)
+	assert.Equal(log.DebugLevel, syncLogger.Logger.Level)
+	assert.Equal("debug", GetLogLevels()["*"])
+
+	assert.NotNil(SetLogLevel("p2p", "verbose"))
+	assert.Equal(log.InfoLevel, p2pLogger.Logger.Level)
+}
+
+func TestReloadLogLevels(t *testing.T) {
+	assert := assert.New(t)
+
+	logLevels = parseLogLevelConfig("*:error,p2p:debug")
+	p2pLogger := GetLoggerForModule("p2p")
+	syncLogger := GetLoggerForModule("sync")
+
+	assert.Nil(ReloadLogLevels("*:info,sync:debug"))
+	assert.Equal(log.InfoLevel, p2pLogger.Logger.Level)
+	assert.Equal(log.DebugLevel, syncLogger.Logger.Level)
+
+	// Invalid configs leave the levels unchanged.
+	assert.NotNil(ReloadLogLevels("*:info,sync"))
+	assert.NotNil(ReloadLogLevels("*:verbose"))
+	assert.Equal(log.InfoLevel, p2pLogger.Logger.Level)
+	assert.Equal(log.DebugLevel, syncLogger.Logger.Level)
+}
+
+func TestReloadLogOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "log")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "theta.log")
+
+	logLevels = parseLogLevelConfig("*:info")
+	logger := GetLoggerForModule("ledger")
+
+	viper.Set(common.CfgLogFormat, "json")
+	viper.Set(common.CfgLogFile, path)
+	defer viper.Set(common.CfgLogFormat, "text")
+	defer viper.Set(common.CfgLogFile, "")
+	assert.Nil(ReloadLogOutput())
+
+	logger.WithFields(log.Fields{"height": 100}).Info("Block finalized")
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	var entry map[string]interface{}
+	assert.Nil(json.Unmarshal(content, &entry))
+	assert.Equal("Block finalized", entry["msg"])
+	assert.Equal("info", entry["level"])
+	assert.Equal("ledger", entry["prefix"])
+	assert.Equal(float64(100), entry["height"])
+
+	// Invalid formats leave the output unchanged
+	viper.Set(common.CfgLogFormat, "xml")
+	assert.NotNil(ReloadLogOutput())
+
+	viper.Set(common.CfgLogFormat, "text")
+	viper.Set(common.CfgLogFile, "")
+	assert.Nil(ReloadLogOutput())
+	assert.Equal(os.Stderr, logger.Logger.Out)
+}
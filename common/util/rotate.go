@@ -0,0 +1,183 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedFileTimeFormat = "20060102-150405"
+
+//
+// RotatingFile is a log file rotated once it reaches the max size, or once it has been written
+// to for the rotation interval. The rotated files are renamed with the time of the rotation as
+// suffix, and only the most recent ones are kept.
+//
+type RotatingFile struct {
+	mu sync.Mutex
+
+	path       string
+	maxSize    int64         // 0 disables the size based rotation
+	interval   time.Duration // 0 disables the time based rotation
+	maxBackups int           // 0 keeps all the rotated files
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	now func() time.Time
+}
+
+// NewRotatingFile opens the log file at the path for appending, creating it if needed.
+func NewRotatingFile(path string, maxSize int64, interval time.Duration, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		interval:   interval,
+		maxBackups: maxBackups,
+		now:        time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Write implements io.Writer. The file is rotated before the write if the write would exceed the
+// max size, or if the rotation interval has elapsed.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.shouldRotate(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+func (rf *RotatingFile) shouldRotate(writeSize int64) bool {
+	if rf.size == 0 {
+		return false
+	}
+	if rf.maxSize > 0 && rf.size+writeSize > rf.maxSize {
+		return true
+	}
+	return rf.interval > 0 && rf.now().Sub(rf.openedAt) >= rf.interval
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	rf.openedAt = rf.now()
+	if info.Size() > 0 {
+		// Carry on the rotation interval of the existing file
+		rf.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// rotate renames the current file and opens a new one. Caller must hold mu.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+
+	timestamp := rf.now().Format(rotatedFileTimeFormat)
+	rotatedPath := rf.path + "." + timestamp
+	if seq := rf.nextBackupSeq(timestamp); seq > 0 {
+		rotatedPath = fmt.Sprintf("%v.%v", rotatedPath, seq)
+	}
+	if err := os.Rename(rf.path, rotatedPath); err != nil {
+		return err
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.removeOldBackups()
+	return nil
+}
+
+// removeOldBackups removes the oldest rotated files beyond maxBackups.
+func (rf *RotatingFile) removeOldBackups() {
+	if rf.maxBackups <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(rf.path + ".*")
+	if err != nil || len(backups) <= rf.maxBackups {
+		return
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		ti, ni := rf.backupOrder(backups[i])
+		tj, nj := rf.backupOrder(backups[j])
+		if ti != tj {
+			return ti < tj
+		}
+		return ni < nj
+	})
+	for _, backup := range backups[:len(backups)-rf.maxBackups] {
+		os.Remove(backup)
+	}
+}
+
+// backupOrder returns the rotation time and the sequence number of the rotated file, which order
+// the rotated files from the oldest to the newest.
+func (rf *RotatingFile) backupOrder(backup string) (string, int) {
+	suffix := strings.TrimPrefix(backup, rf.path+".")
+	tokens := strings.SplitN(suffix, ".", 2)
+	seq := 0
+	if len(tokens) == 2 {
+		seq, _ = strconv.Atoi(tokens[1])
+	}
+	return tokens[0], seq
+}
+
+// nextBackupSeq returns the sequence number of the next file rotated at the time, so that the
+// files rotated within the same second do not overwrite each other.
+func (rf *RotatingFile) nextBackupSeq(timestamp string) int {
+	backups, _ := filepath.Glob(rf.path + "." + timestamp + "*")
+	next := 0
+	for _, backup := range backups {
+		if ts, seq := rf.backupOrder(backup); ts == timestamp && seq >= next {
+			next = seq + 1
+		}
+	}
+	return next
+}
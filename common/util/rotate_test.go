@@ -0,0 +1,85 @@
+// +build unit
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingFileBySize(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(dir, "theta.log")
+	rf, err := NewRotatingFile(path, 10, 0, 2)
+	assert.Nil(err)
+	rf.now = func() time.Time { return now }
+	defer rf.Close()
+
+	_, err = rf.Write([]byte("12345678"))
+	assert.Nil(err)
+	backups, _ := filepath.Glob(path + ".*")
+	assert.Equal(0, len(backups))
+
+	// Exceeds the max size, rotated before the write
+	for i := 0; i < 4; i++ {
+		_, err = rf.Write([]byte("12345678"))
+		assert.Nil(err)
+	}
+	backups, _ = filepath.Glob(path + ".*")
+	assert.Equal(2, len(backups))
+	_, err = os.Stat(path + ".20190301-000000")
+	assert.True(os.IsNotExist(err)) // The oldest backups are removed
+	_, err = os.Stat(path + ".20190301-000000.3")
+	assert.Nil(err)
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	assert.Equal("12345678", string(content))
+}
+
+func TestRotatingFileByTime(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	path := filepath.Join(dir, "theta.log")
+	rf, err := NewRotatingFile(path, 0, time.Hour, 0)
+	assert.Nil(err)
+	rf.now = func() time.Time { return now }
+	defer rf.Close()
+
+	_, err = rf.Write([]byte("first"))
+	assert.Nil(err)
+	now = now.Add(30 * time.Minute)
+	_, err = rf.Write([]byte("second"))
+	assert.Nil(err)
+	backups, _ := filepath.Glob(path + ".*")
+	assert.Equal(0, len(backups))
+
+	now = now.Add(time.Hour)
+	_, err = rf.Write([]byte("third"))
+	assert.Nil(err)
+	backups, _ = filepath.Glob(path + ".*")
+	assert.Equal(1, len(backups))
+
+	rotated, err := ioutil.ReadFile(backups[0])
+	assert.Nil(err)
+	assert.Equal("firstsecond", string(rotated))
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	assert.Equal("third", string(content))
+}
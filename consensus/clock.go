@@ -0,0 +1,241 @@
+package consensus
+
+import (
+	"sync"
+	"time"
+)
+
+//
+// Clock provides the time to the epoch and proposal timers of the consensus engine and to the
+// rounds of the guardian engine. The engines use the system clock by default, tests and
+// simulations can use a FakeClock to trigger the timeouts deterministically.
+//
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer delivers the time on its channel once the duration has elapsed, like time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker delivers the time on its channel at each period, like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// NewSystemClock returns the clock backed by the time package.
+func NewSystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{timer: time.NewTimer(d)}
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+//
+// FakeClock is a Clock whose time only moves when advanced explicitly. The timers and tickers
+// due are fired in the order of their deadlines, each seeing the time of its deadline. Like the
+// system ones, their channels hold a single pending tick, the ticks not received in time are
+// dropped.
+//
+type FakeClock struct {
+	mu     *sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer // active timers and tickers, in creation order
+}
+
+// NewFakeClock creates a fake clock starting at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	mu := &sync.Mutex{}
+	return &FakeClock{
+		mu:   mu,
+		cond: sync.NewCond(mu),
+		now:  now,
+	}
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer firing once the clock is advanced by the duration.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	return c.newTimer(d, 0)
+}
+
+// NewTicker creates a ticker firing each time the clock is advanced by the period. It panics
+// if the period is not positive, like time.NewTicker.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return fakeTicker{c.newTimer(d, d)}
+}
+
+func (c *FakeClock) newTimer(d time.Duration, period time.Duration) *fakeTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{
+		clock:    c,
+		c:        make(chan time.Time, 1),
+		deadline: c.now.Add(d),
+		period:   period,
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by the duration, and fires the timers and tickers due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		next := c.nextDue(end)
+		if next < 0 {
+			break
+		}
+		t := c.timers[next]
+		c.now = t.deadline
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			t.deadline = t.deadline.Add(t.period)
+		} else {
+			c.remove(t)
+		}
+	}
+	c.now = end
+	c.cond.Broadcast()
+}
+
+// AdvanceToNext moves the clock forward to the deadline of the next timer or ticker, fires it
+// and returns the duration the clock moved by. It returns 0 if there is no active timer.
+func (c *FakeClock) AdvanceToNext() time.Duration {
+	c.mu.Lock()
+	if len(c.timers) == 0 {
+		c.mu.Unlock()
+		return 0
+	}
+	d := time.Duration(0)
+	if next := c.nextDue(time.Time{}); next >= 0 {
+		d = c.timers[next].deadline.Sub(c.now)
+	}
+	c.mu.Unlock()
+
+	c.Advance(d)
+	return d
+}
+
+// WaitForTimers blocks until at least n timers and tickers are active, e.g. until the engines
+// have set up their timers after the previous ones fired.
+func (c *FakeClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// nextDue returns the index of the active timer with the earliest deadline not after end, or
+// -1 if none is due. A zero end matches any deadline.
+func (c *FakeClock) nextDue(end time.Time) int {
+	next := -1
+	for i, t := range c.timers {
+		if !end.IsZero() && t.deadline.After(end) {
+			continue
+		}
+		if next < 0 || t.deadline.Before(c.timers[next].deadline) {
+			next = i
+		}
+	}
+	return next
+}
+
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	for i, active := range c.timers {
+		if active == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+	period   time.Duration // 0 for a timer
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
+
+type fakeTicker struct {
+	timer *fakeTimer
+}
+
+func (t fakeTicker) C() <-chan time.Time {
+	return t.timer.c
+}
+
+func (t fakeTicker) Stop() {
+	t.timer.Stop()
+}
@@ -0,0 +1,85 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fired(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeClockTimers(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	t1 := clock.NewTimer(2 * time.Second)
+	t2 := clock.NewTimer(5 * time.Second)
+	t3 := clock.NewTimer(5 * time.Second)
+	clock.WaitForTimers(3)
+
+	clock.Advance(time.Second)
+	_, ok := fired(t1.C())
+	assert.False(ok)
+	assert.Equal(start.Add(time.Second), clock.Now())
+
+	// The timers see the time of their deadline
+	assert.True(t3.Stop())
+	clock.Advance(10 * time.Second)
+	at, ok := fired(t1.C())
+	assert.True(ok)
+	assert.Equal(start.Add(2*time.Second), at)
+	at, ok = fired(t2.C())
+	assert.True(ok)
+	assert.Equal(start.Add(5*time.Second), at)
+	_, ok = fired(t3.C())
+	assert.False(ok)
+	assert.Equal(start.Add(11*time.Second), clock.Now())
+
+	// Fired and stopped timers can not be stopped again
+	assert.False(t1.Stop())
+	assert.False(t3.Stop())
+	assert.Equal(time.Duration(0), clock.AdvanceToNext())
+}
+
+func TestFakeClockTickers(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	ticker := clock.NewTicker(3 * time.Second)
+	timer := clock.NewTimer(4 * time.Second)
+
+	assert.Equal(3*time.Second, clock.AdvanceToNext())
+	at, ok := fired(ticker.C())
+	assert.True(ok)
+	assert.Equal(start.Add(3*time.Second), at)
+	assert.Equal(time.Second, clock.AdvanceToNext())
+	_, ok = fired(timer.C())
+	assert.True(ok)
+
+	// The ticks not received in time are dropped
+	clock.Advance(10 * time.Second)
+	at, ok = fired(ticker.C())
+	assert.True(ok)
+	assert.Equal(start.Add(6*time.Second), at)
+	_, ok = fired(ticker.C())
+	assert.False(ok)
+	assert.Equal(time.Second, clock.AdvanceToNext())
+	at, ok = fired(ticker.C())
+	assert.True(ok)
+	assert.Equal(start.Add(15*time.Second), at)
+
+	ticker.Stop()
+	clock.Advance(time.Minute)
+	_, ok = fired(ticker.C())
+	assert.False(ok)
+}
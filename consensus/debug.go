@@ -0,0 +1,77 @@
+package consensus
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+//
+// DebugState is a snapshot of the consensus engine, for diagnosing the stalls of a node
+//
+type DebugState struct {
+	Epoch              uint64
+	HighestCCBlock     *core.ExtendedBlock
+	LastFinalizedBlock *core.ExtendedBlock
+	LastVote           core.Vote
+	LastProposal       core.Proposal
+
+	// Proposer of the current epoch, and whether it is the local node and due to propose
+	Proposer      core.Validator
+	ShouldPropose bool
+
+	// Leaves of the block tree above the highest CC block, which the next blocks may extend
+	TipCandidates []*core.ExtendedBlock
+
+	// Votes received on the blocks above the highest CC block, which are not committed yet
+	PendingVotes map[common.Hash]*core.VoteSet
+
+	EpochVotes *core.VoteSet
+}
+
+// GetDebugState returns a snapshot of the consensus state. The snapshot is not atomic, since the
+// engine keeps running while it is taken.
+func (e *ConsensusEngine) GetDebugState() *DebugState {
+	epoch := e.GetEpoch()
+	lfb := e.state.GetLastFinalizedBlock()
+	hcc := e.state.GetHighestCCBlock()
+	s := &DebugState{
+		Epoch:              epoch,
+		HighestCCBlock:     hcc,
+		LastFinalizedBlock: lfb,
+		LastVote:           e.state.GetLastVote(),
+		LastProposal:       e.state.GetLastProposal(),
+		Proposer:           e.validatorManager.GetNextProposer(lfb.Hash(), epoch),
+		ShouldPropose:      e.shouldPropose(epoch),
+		TipCandidates:      []*core.ExtendedBlock{},
+		PendingVotes:       make(map[common.Hash]*core.VoteSet),
+	}
+	if epochVotes, err := e.state.GetEpochVotes(); err == nil {
+		s.EpochVotes = epochVotes
+	} else {
+		s.EpochVotes = core.NewVoteSet()
+	}
+
+	stack := []*core.ExtendedBlock{hcc}
+	for len(stack) > 0 {
+		curr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if curr.Hash() != hcc.Hash() {
+			if votes := e.chain.FindVotesByHash(curr.Hash()); !votes.IsEmpty() {
+				s.PendingVotes[curr.Hash()] = votes
+			}
+		}
+		if len(curr.Children) == 0 {
+			s.TipCandidates = append(s.TipCandidates, curr)
+			continue
+		}
+		for _, childHash := range curr.Children {
+			child, err := e.chain.FindBlockHeader(childHash)
+			if err != nil {
+				continue
+			}
+			stack = append(stack, child)
+		}
+	}
+	return s
+}
@@ -13,16 +13,23 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/builder"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/common/tracing"
 	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/event"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/notify"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "consensus"})
+var logger *log.Entry = util.GetLoggerForModule("consensus")
 
 var _ core.ConsensusEngine = (*ConsensusEngine)(nil)
 
@@ -30,15 +37,25 @@ var _ core.ConsensusEngine = (*ConsensusEngine)(nil)
 type ConsensusEngine struct {
 	logger *log.Entry
 
-	privateKey *crypto.PrivateKey
+	signer core.Signer
 
 	chain            *blockchain.Chain
 	dispatcher       *dispatcher.Dispatcher
 	validatorManager core.ValidatorManager
 	ledger           core.Ledger
+	guardian         *GuardianEngine
+
+	// External block builder, nil if blocks are built locally
+	builder        *builder.Client
+	builderAddress common.Address
+
+	// Transactions of the next proposal, prepared while the current block is voted on
+	pipelineProposal bool
+	preparedTxs      *preparedBlockTxs
 
 	incoming        chan interface{}
 	finalizedBlocks chan *core.Block
+	eventBus        *event.Bus
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -47,8 +64,11 @@ type ConsensusEngine struct {
 	stopped bool
 
 	mu            *sync.Mutex
-	epochTimer    *time.Timer
-	proposalTimer *time.Timer
+	clock         Clock
+	epochTimer    Timer
+	proposalTimer Timer
+	epochTimeout  *EpochTimeout
+	epochStart    time.Time
 
 	state *State
 
@@ -56,27 +76,44 @@ type ConsensusEngine struct {
 }
 
 // NewConsensusEngine creates a instance of ConsensusEngine.
-func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *blockchain.Chain, dispatcher *dispatcher.Dispatcher, validatorManager core.ValidatorManager) *ConsensusEngine {
+func NewConsensusEngine(signer core.Signer, db store.Store, chain *blockchain.Chain, dispatcher *dispatcher.Dispatcher, validatorManager core.ValidatorManager) *ConsensusEngine {
 	e := &ConsensusEngine{
 		chain:      chain,
 		dispatcher: dispatcher,
 
-		privateKey: privateKey,
+		signer: signer,
 
 		incoming:        make(chan interface{}, viper.GetInt(common.CfgConsensusMessageQueueSize)),
 		finalizedBlocks: make(chan *core.Block, viper.GetInt(common.CfgConsensusMessageQueueSize)),
 
 		wg: &sync.WaitGroup{},
 
-		mu:    &sync.Mutex{},
-		state: NewState(db, chain),
+		mu:           &sync.Mutex{},
+		clock:        NewSystemClock(),
+		epochTimeout: NewEpochTimeout(),
+		state:        NewState(db, chain),
 
 		validatorManager: validatorManager,
+
+		pipelineProposal: viper.GetBool(common.CfgConsensusPipelineProposal),
 	}
 
 	logger = util.GetLoggerForModule("consensus")
 	e.logger = logger
 
+	e.guardian = NewGuardianEngine(e)
+
+	if viper.GetBool(common.CfgBuilderEnabled) {
+		e.builder = builder.NewClient(viper.GetString(common.CfgBuilderEndpoint),
+			viper.GetString(common.CfgBuilderAuthToken),
+			time.Duration(viper.GetInt(common.CfgBuilderTimeout))*time.Millisecond)
+		e.builderAddress = common.HexToAddress(viper.GetString(common.CfgBuilderAddress))
+		e.logger.WithFields(log.Fields{
+			"endpoint": e.builder.Endpoint(),
+			"address":  e.builderAddress.Hex(),
+		}).Info("Using external block builder")
+	}
+
 	e.logger.WithFields(log.Fields{"state": e.state}).Info("Starting state")
 
 	e.rand = rand.New(rand.NewSource(time.Now().Unix()))
@@ -84,10 +121,32 @@ func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *bl
 	return e
 }
 
+// SetEventBus sets the bus the finalized blocks, their transactions and the validator set
+// updates are published to.
+func (e *ConsensusEngine) SetEventBus(eventBus *event.Bus) {
+	e.eventBus = eventBus
+}
+
 func (e *ConsensusEngine) SetLedger(ledger core.Ledger) {
 	e.ledger = ledger
 }
 
+// SetClock sets the clock of the epoch and proposal timers and of the guardian rounds, e.g. a
+// FakeClock to control the timeouts in tests. It must be called before Start.
+func (e *ConsensusEngine) SetClock(clock Clock) {
+	e.clock = clock
+}
+
+// SetGuardianKey sets the node key the guardian BLS key is derived from
+func (e *ConsensusEngine) SetGuardianKey(nodeKey *crypto.PrivateKey) {
+	e.guardian.SetKey(nodeKey)
+}
+
+// GetGuardianEngine returns the guardian engine
+func (e *ConsensusEngine) GetGuardianEngine() *GuardianEngine {
+	return e.guardian
+}
+
 // GetLedger returns the ledger instance attached to the consensus engine
 func (e *ConsensusEngine) GetLedger() core.Ledger {
 	return e.ledger
@@ -95,12 +154,12 @@ func (e *ConsensusEngine) GetLedger() core.Ledger {
 
 // ID returns the identifier of current node.
 func (e *ConsensusEngine) ID() string {
-	return e.privateKey.PublicKey().Address().Hex()
+	return e.signer.PublicKey().Address().Hex()
 }
 
-// PrivateKey returns the private key
-func (e *ConsensusEngine) PrivateKey() *crypto.PrivateKey {
-	return e.privateKey
+// Signer returns the signer of the validator
+func (e *ConsensusEngine) Signer() core.Signer {
+	return e.signer
 }
 
 // Chain return a pointer to the underlying chain store.
@@ -113,6 +172,16 @@ func (e *ConsensusEngine) GetEpoch() uint64 {
 	return e.state.GetEpoch()
 }
 
+// GetEpochVotes returns the latest epoch votes of the validators
+func (e *ConsensusEngine) GetEpochVotes() (*core.VoteSet, error) {
+	return e.state.GetEpochVotes()
+}
+
+// GetEpochTimeout returns the epoch timeout of the engine
+func (e *ConsensusEngine) GetEpochTimeout() *EpochTimeout {
+	return e.epochTimeout
+}
+
 // GetValidatorManager returns a pointer to the valiator manager.
 func (e *ConsensusEngine) GetValidatorManager() core.ValidatorManager {
 	return e.validatorManager
@@ -131,22 +200,36 @@ func (e *ConsensusEngine) Start(ctx context.Context) {
 			"CfgConsensusMinProposalWait": viper.GetInt(common.CfgConsensusMinProposalWait),
 		}).Fatal("Invalid configuration: max epoch length must be larger than minimal proposal wait")
 	}
+	if viper.GetBool(common.CfgConsensusAdaptiveEpochLength) {
+		if viper.GetInt(common.CfgConsensusEpochTimeoutFloor) <= viper.GetInt(common.CfgConsensusMinProposalWait) ||
+			viper.GetInt(common.CfgConsensusEpochTimeoutCeiling) < viper.GetInt(common.CfgConsensusEpochTimeoutFloor) {
+			log.WithFields(log.Fields{
+				"CfgConsensusEpochTimeoutFloor":   viper.GetInt(common.CfgConsensusEpochTimeoutFloor),
+				"CfgConsensusEpochTimeoutCeiling": viper.GetInt(common.CfgConsensusEpochTimeoutCeiling),
+				"CfgConsensusMinProposalWait":     viper.GetInt(common.CfgConsensusMinProposalWait),
+			}).Fatal("Invalid configuration: epoch timeout floor must be larger than minimal proposal wait and not larger than the ceiling")
+		}
+	}
 
 	// Set ledger state pointer to intial state.
 	lastCC := e.state.GetHighestCCBlock()
 	e.ledger.ResetState(lastCC.Height, lastCC.StateHash)
 
+	e.guardian.Start(e.ctx)
+
 	e.wg.Add(1)
 	go e.mainLoop()
 }
 
 // Stop notifies all goroutines to stop without blocking.
 func (e *ConsensusEngine) Stop() {
+	e.guardian.Stop()
 	e.cancel()
 }
 
 // Wait blocks until all goroutines stop.
 func (e *ConsensusEngine) Wait() {
+	e.guardian.Wait()
 	e.wg.Wait()
 }
 
@@ -164,13 +247,19 @@ func (e *ConsensusEngine) mainLoop() {
 			case msg := <-e.incoming:
 				endEpoch := e.processMessage(msg)
 				if endEpoch {
+					e.epochTimeout.ObserveEpoch(e.clock.Now().Sub(e.epochStart))
 					break Epoch
 				}
-			case <-e.epochTimer.C:
-				e.logger.WithFields(log.Fields{"e.epoch": e.GetEpoch()}).Debug("Epoch timeout. Repeating epoch")
+			case <-e.epochTimer.C():
+				e.epochTimeout.ObserveTimeout()
+				e.logger.WithFields(log.Fields{
+					"e.epoch":      e.GetEpoch(),
+					"missedEpochs": e.epochTimeout.MissedEpochs(),
+				}).Debug("Epoch timeout. Repeating epoch")
+				e.checkMissedProposal()
 				e.vote()
 				break Epoch
-			case <-e.proposalTimer.C:
+			case <-e.proposalTimer.C():
 				e.propose()
 			}
 		}
@@ -182,15 +271,16 @@ func (e *ConsensusEngine) enterEpoch() {
 	if e.epochTimer != nil {
 		e.epochTimer.Stop()
 	}
-	e.epochTimer = time.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMaxEpochLength)) * time.Second)
+	e.epochStart = e.clock.Now()
+	e.epochTimer = e.clock.NewTimer(e.epochTimeout.Timeout())
 
 	if e.proposalTimer != nil {
 		e.proposalTimer.Stop()
 	}
 	if e.shouldPropose(e.GetEpoch()) {
-		e.proposalTimer = time.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMinProposalWait)) * time.Second)
+		e.proposalTimer = e.clock.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMinProposalWait)) * time.Second)
 	} else {
-		e.proposalTimer = time.NewTimer(math.MaxInt64)
+		e.proposalTimer = e.clock.NewTimer(math.MaxInt64)
 		e.proposalTimer.Stop()
 	}
 }
@@ -216,6 +306,9 @@ func (e *ConsensusEngine) processMessage(msg interface{}) (endEpoch bool) {
 	case *core.Block:
 		e.logger.WithFields(log.Fields{"block": m}).Debug("Received block")
 		e.handleBlock(m)
+	case *core.AggregatedVotes:
+		e.logger.WithFields(log.Fields{"votes": m}).Debug("Received guardian votes")
+		e.guardian.AddVote(m)
 	default:
 		log.Errorf("Unknown message type: %v", m)
 		panic(fmt.Sprintf("Unknown message type: %v", m))
@@ -226,98 +319,20 @@ func (e *ConsensusEngine) processMessage(msg interface{}) (endEpoch bool) {
 
 func (e *ConsensusEngine) validateBlock(block *core.Block, parent *core.ExtendedBlock) bool {
 	validators := e.validatorManager.GetValidatorSet(block.Hash())
-
-	if parent.Height+1 != block.Height {
-		e.logger.WithFields(log.Fields{
-			"parent":        block.Parent.Hex(),
-			"parent.Height": parent.Height,
-			"block":         block.Hash().Hex(),
-			"block.Height":  block.Height,
-		}).Warn("Block.Height != parent.Height + 1")
-		return false
-	}
-
-	if parent.Epoch >= block.Epoch {
-		e.logger.WithFields(log.Fields{
-			"parent":       block.Parent.Hex(),
-			"parent.Epoch": parent.Epoch,
-			"block":        block.Hash().Hex(),
-			"block.Epoch":  block.Epoch,
-		}).Warn("Block.Epoch <= parent.Epoch")
-		return false
-	}
-
-	if !parent.Status.IsValid() {
+	if err := ValidateBlock(e.chain, validators, block, parent); err != nil {
 		e.logger.WithFields(log.Fields{
-			"parent": block.Parent.Hex(),
 			"block":  block.Hash().Hex(),
-		}).Warn("Block is referring to invalid parent block")
-		return false
-	}
-
-	if !e.chain.IsDescendant(block.HCC.BlockHash, block.Hash()) {
-		e.logger.WithFields(log.Fields{
-			"block.HCC": block.HCC.BlockHash.Hex(),
-			"block":     block.Hash().Hex(),
-		}).Warn("HCC must be ancestor")
-		return false
-	}
-
-	if !block.HCC.IsValid(validators) {
-		e.logger.WithFields(log.Fields{
-			"parent":    block.Parent.Hex(),
-			"block":     block.Hash().Hex(),
-			"block.HCC": block.HCC.String(),
-		}).Warn("Invalid HCC")
+			"parent": block.Parent.Hex(),
+			"err":    err,
+		}).Warn("Block is invalid")
 		return false
 	}
-
-	// Blocks with validator changes must be followed by two direct confirmation blocks.
-	if parent.HasValidatorUpdate {
-		if block.HCC.BlockHash != block.Parent {
-			e.logger.WithFields(log.Fields{
-				"parent":    block.Parent.Hex(),
-				"block":     block.Hash().Hex(),
-				"block.HCC": block.HCC.BlockHash.Hex(),
-			}).Warn("block.HCC must equal to parent when parent contains validator changes.")
-			return false
-		}
-	}
-	if !parent.Parent.IsEmpty() {
-		grandParent, err := e.chain.FindBlock(parent.Parent)
-		if err != nil {
-			e.logger.WithFields(log.Fields{
-				"error":         err,
-				"parent":        parent.Hash().Hex(),
-				"block":         block.Hash().Hex(),
-				"parent.Parent": parent.Parent.Hex(),
-			}).Warn("Failed to find grand parent block")
-			return false
-		}
-		if grandParent.HasValidatorUpdate {
-			if block.HCC.BlockHash != block.Parent {
-				e.logger.WithFields(log.Fields{
-					"parent":    block.Parent.Hex(),
-					"block":     block.Hash().Hex(),
-					"block.HCC": block.HCC.BlockHash.Hex(),
-				}).Warn("block.HCC must equal to block.Parent when block.Parent.Parent contains validator changes.")
-				return false
-			}
-			if !block.HCC.IsProven(validators) {
-				e.logger.WithFields(log.Fields{
-					"parent":    block.Parent.Hex(),
-					"block":     block.Hash().Hex(),
-					"block.HCC": block.HCC,
-				}).Warn("block.HCC must contain valid voteset when block.Parent.Parent contains validator changes.")
-				return false
-			}
-		}
-	}
-
-	if res := block.Validate(); res.IsError() {
+	if err := ValidateVRFProof(e.ledger, block, parent); err != nil {
 		e.logger.WithFields(log.Fields{
-			"err": res.String(),
-		}).Warn("Block is invalid")
+			"block":    block.Hash().Hex(),
+			"proposer": block.Proposer.Hex(),
+			"err":      err,
+		}).Warn("Invalid VRF proof")
 		return false
 	}
 	if !e.shouldProposeByID(block.Epoch, block.Proposer.Hex()) {
@@ -331,7 +346,13 @@ func (e *ConsensusEngine) validateBlock(block *core.Block, parent *core.Extended
 }
 
 func (e *ConsensusEngine) handleBlock(block *core.Block) {
-	parent, err := e.chain.FindBlock(block.Parent)
+	// The trace of a block received from a peer is started by the sync manager, the one of a
+	// block proposed by the node starts here
+	hash := block.Hash()
+	tracing.StartBlockTrace(hash, time.Now(), "", attribute.Int64("block.height", int64(block.Height)))
+	defer tracing.EndBlockTrace(hash)
+
+	parent, err := e.chain.FindBlockHeader(block.Parent)
 	if err != nil {
 		// Should not happen.
 		e.logger.WithFields(log.Fields{
@@ -342,7 +363,10 @@ func (e *ConsensusEngine) handleBlock(block *core.Block) {
 		return
 	}
 
-	if !e.validateBlock(block, parent) {
+	_, validateSpan := tracing.StartBlockSpan(hash, "consensus.validate")
+	valid := e.validateBlock(block, parent)
+	validateSpan.End()
+	if !valid {
 		e.chain.MarkBlockInvalid(block.Hash())
 		e.logger.WithFields(log.Fields{
 			"block.Hash": block.Hash().Hex(),
@@ -350,19 +374,23 @@ func (e *ConsensusEngine) handleBlock(block *core.Block) {
 		return
 	}
 
-	for _, vote := range block.HCC.Votes.Votes() {
-		e.handleVoteInBlock(vote)
-	}
+	e.handleVotesInBlock(block.HCC.Votes.Votes())
 
+	applyCtx, applySpan := tracing.StartBlockSpan(hash, "ledger.apply", attribute.Int("block.txs", len(block.Txs)))
 	result := e.ledger.ResetState(parent.Height, parent.StateHash)
 	if result.IsError() {
+		applySpan.End()
 		e.logger.WithFields(log.Fields{
 			"error":            result.Message,
 			"parent.StateHash": parent.StateHash,
 		}).Error("Failed to reset state to parent.StateHash")
 		return
 	}
-	result = e.ledger.ApplyBlockTxs(block.Txs, block.StateHash)
+	result = e.ledger.ApplyBlockTxs(block.Txs, block.Timestamp, block.StateHash)
+	if commitStart, ok := result.Info["commitStart"].(time.Time); ok {
+		tracing.RecordSpan(applyCtx, "store.commit", commitStart, result.Info["commitEnd"].(time.Time))
+	}
+	applySpan.End()
 	if result.IsError() {
 		e.logger.WithFields(log.Fields{
 			"error":           result.String(),
@@ -373,14 +401,25 @@ func (e *ConsensusEngine) handleBlock(block *core.Block) {
 		return
 	}
 
-	if hasValidatorUpdate, ok := result.Info["hasValidatorUpdate"]; ok {
-		hasValidatorUpdateBool := hasValidatorUpdate.(bool)
-		if hasValidatorUpdateBool {
-			e.chain.MarkBlockHasValidatorUpdate(block.Hash())
-		}
+	if err := ValidateAppliedBlock(block, result); err != nil {
+		e.chain.MarkBlockInvalid(block.Hash())
+		e.logger.WithFields(log.Fields{
+			"block.Hash": block.Hash().Hex(),
+			"err":        err,
+		}).Warn("Block does not match the result of its transactions")
+		return
 	}
-
-	e.chain.MarkBlockValid(block.Hash())
+	hasValidatorUpdate := false
+	if v, ok := result.Info["hasValidatorUpdate"]; ok {
+		hasValidatorUpdate = v.(bool)
+	}
+	receipts := []*types.Receipt{}
+	if r, ok := result.Info["receipts"]; ok {
+		receipts = r.([]*types.Receipt)
+	}
+	_, saveSpan := tracing.StartBlockSpan(hash, "chain.save")
+	e.chain.MarkBlockValidWithReceipts(block.Hash(), hasValidatorUpdate, receipts)
+	saveSpan.End()
 
 	// Check and process CC.
 	e.checkCC(block.Hash())
@@ -397,11 +436,13 @@ func (e *ConsensusEngine) handleBlock(block *core.Block) {
 		return
 	}
 
+	e.prepareNextProposal(block)
+
 	e.vote()
 }
 
 func (e *ConsensusEngine) shouldVote(block common.Hash) bool {
-	return e.shouldVoteByID(e.privateKey.PublicKey().Address(), block)
+	return e.shouldVoteByID(e.signer.PublicKey().Address(), block)
 }
 
 func (e *ConsensusEngine) shouldVoteByID(id common.Address, block common.Hash) bool {
@@ -418,6 +459,7 @@ func (e *ConsensusEngine) vote() {
 	}
 
 	var vote core.Vote
+	var err error
 	lastVote := e.state.GetLastVote()
 	shouldRepeatVote := false
 	if lastVote.Height != 0 && lastVote.Height >= tip.Height {
@@ -437,14 +479,22 @@ func (e *ConsensusEngine) vote() {
 	}
 
 	if shouldRepeatVote {
-		block, err := e.chain.FindBlock(lastVote.Block)
+		block, err := e.chain.FindBlockHeader(lastVote.Block)
 		if err != nil {
 			log.Panic(err)
 		}
 		// Recreating vote so that it has updated epoch and signature.
-		vote = e.createVote(block.Block)
+		vote, err = e.createVote(block.Block)
+		if err != nil {
+			e.logger.WithFields(log.Fields{"error": err}).Error("Failed to sign vote")
+			return
+		}
 	} else {
-		vote = e.createVote(tip.Block)
+		vote, err = e.createVote(tip.Block)
+		if err != nil {
+			e.logger.WithFields(log.Fields{"error": err}).Error("Failed to sign vote")
+			return
+		}
 		e.state.SetLastVote(vote)
 	}
 	e.logger.WithFields(log.Fields{
@@ -467,33 +517,70 @@ func (e *ConsensusEngine) broadcastVote(vote core.Vote) {
 	e.dispatcher.SendData([]string{}, voteMsg)
 }
 
-func (e *ConsensusEngine) createVote(block *core.Block) core.Vote {
+func (e *ConsensusEngine) createVote(block *core.Block) (core.Vote, error) {
 	vote := core.Vote{
 		Block:  block.Hash(),
 		Height: block.Height,
-		ID:     e.privateKey.PublicKey().Address(),
+		ID:     e.signer.PublicKey().Address(),
 		Epoch:  e.GetEpoch(),
 	}
-	sig, err := e.privateKey.Sign(vote.SignBytes())
+	// Before the first fork the votes keep the legacy encoding and sign bytes, so that the nodes
+	// not aware of the signature domain can still decode and verify them.
+	if core.ForkNumber(e.chain.ChainID, block.Height) > 0 {
+		vote.ChainID = e.chain.ChainID
+	}
+	sig, err := e.signer.SignVote(vote)
 	if err != nil {
-		e.logger.WithFields(log.Fields{"error": err}).Panic("Failed to sign vote")
+		return core.Vote{}, err
 	}
 	vote.SetSignature(sig)
-	return vote
+	return vote, nil
 }
 
 func (e *ConsensusEngine) validateVote(vote core.Vote) bool {
-	if res := vote.Validate(); res.IsError() {
-		e.logger.WithFields(log.Fields{
-			"err": res.String(),
-		}).Warn("Ignoring invalid vote")
-		return false
+	return e.validateVotes([]core.Vote{vote})[0]
+}
+
+// validateVotes returns whether each of the votes is valid. The signatures are verified in one
+// batched pass.
+func (e *ConsensusEngine) validateVotes(votes []core.Vote) []bool {
+	valid := make([]bool, len(votes))
+	toVerify := make([]core.Vote, 0, len(votes))
+	indexes := make([]int, 0, len(votes))
+	for i, vote := range votes {
+		// The votes without chain ID are only accepted before the first hard fork
+		if vote.ChainID != e.chain.ChainID &&
+			(vote.ChainID != "" || core.ForkNumber(e.chain.ChainID, vote.Height) > 0) {
+			e.logger.WithFields(log.Fields{
+				"vote.ChainID": vote.ChainID,
+				"chainID":      e.chain.ChainID,
+			}).Warn("Ignoring vote from another chain")
+			continue
+		}
+		toVerify = append(toVerify, vote)
+		indexes = append(indexes, i)
 	}
-	return true
+
+	for j, res := range core.ValidateVotes(toVerify) {
+		if res.IsError() {
+			e.logger.WithFields(log.Fields{
+				"err": res.String(),
+			}).Warn("Ignoring invalid vote")
+			continue
+		}
+		valid[indexes[j]] = true
+	}
+	return valid
 }
 
-func (e *ConsensusEngine) handleVoteInBlock(vote core.Vote) (endEpoch bool) {
-	return e.handleVote(vote)
+// handleVotesInBlock handles the votes of the block HCC, whose signatures are verified in one
+// batched pass rather than one vote after the other.
+func (e *ConsensusEngine) handleVotesInBlock(votes []core.Vote) {
+	for i, valid := range e.validateVotes(votes) {
+		if valid {
+			e.handleValidVote(votes[i])
+		}
+	}
 }
 
 func (e *ConsensusEngine) handleStandaloneVote(vote core.Vote) (endEpoch bool) {
@@ -507,7 +594,12 @@ func (e *ConsensusEngine) handleVote(vote core.Vote) (endEpoch bool) {
 	if !e.validateVote(vote) {
 		return
 	}
+	return e.handleValidVote(vote)
+}
 
+// handleValidVote saves the vote, which has been validated, and moves to the next epoch once a
+// majority has voted in the current one.
+func (e *ConsensusEngine) handleValidVote(vote core.Vote) (endEpoch bool) {
 	// Save vote.
 	err := e.state.AddVote(&vote)
 	if err != nil {
@@ -554,7 +646,7 @@ func (e *ConsensusEngine) checkCC(hash common.Hash) {
 	if hash.IsEmpty() {
 		return
 	}
-	block, err := e.Chain().FindBlock(hash)
+	block, err := e.Chain().FindBlockHeader(hash)
 	if err != nil {
 		e.logger.WithFields(log.Fields{"block": hash.Hex()}).Warn("checkCC: Block hash in vote is not found")
 		return
@@ -606,7 +698,7 @@ func (e *ConsensusEngine) GetTip(includePendingBlockingLeaf bool) *core.Extended
 		}
 
 		for _, childHash := range curr.Children {
-			child, err := e.chain.FindBlock(childHash)
+			child, err := e.chain.FindBlockHeader(childHash)
 			if err != nil {
 				e.logger.WithFields(log.Fields{
 					"err":       err,
@@ -634,6 +726,23 @@ func (e *ConsensusEngine) GetLastFinalizedBlock() *core.ExtendedBlock {
 	return e.state.GetLastFinalizedBlock()
 }
 
+// GetLastVote returns the last vote cast by the local node.
+func (e *ConsensusEngine) GetLastVote() core.Vote {
+	return e.state.GetLastVote()
+}
+
+// RestoreLastVote restores the last vote from a checkpoint. Only votes higher than the
+// persisted one are taken so that voting height stays monotonically increasing after an
+// abrupt shutdown.
+func (e *ConsensusEngine) RestoreLastVote(vote core.Vote) bool {
+	if vote.Height <= e.state.GetLastVote().Height {
+		return false
+	}
+	e.logger.WithFields(log.Fields{"vote": vote}).Info("Restoring last vote from checkpoint")
+	e.state.SetLastVote(vote)
+	return true
+}
+
 func (e *ConsensusEngine) processCCBlock(ccBlock *core.ExtendedBlock) {
 	if ccBlock.Height <= e.state.GetHighestCCBlock().Height {
 		return
@@ -643,7 +752,7 @@ func (e *ConsensusEngine) processCCBlock(ccBlock *core.ExtendedBlock) {
 	e.state.SetHighestCCBlock(ccBlock)
 	e.chain.CommitBlock(ccBlock.Hash())
 
-	parent, err := e.Chain().FindBlock(ccBlock.Parent)
+	parent, err := e.Chain().FindBlockHeader(ccBlock.Parent)
 	if err != nil {
 		e.logger.WithFields(log.Fields{"err": err, "hash": ccBlock.Parent}).Error("Failed to load block")
 		return
@@ -653,6 +762,42 @@ func (e *ConsensusEngine) processCCBlock(ccBlock *core.ExtendedBlock) {
 	}
 }
 
+// newlyFinalizedBlocks returns the blocks finalized after prevFinalized up to block, in
+// increasing height order.
+func (e *ConsensusEngine) newlyFinalizedBlocks(prevFinalized *core.ExtendedBlock, block *core.ExtendedBlock) []*core.ExtendedBlock {
+	blocks := []*core.ExtendedBlock{}
+	for curr := block; curr != nil && curr.Height > prevFinalized.Height; {
+		blocks = append(blocks, curr)
+		parent, err := e.chain.FindBlock(curr.Parent)
+		if err != nil {
+			e.logger.WithFields(log.Fields{"err": err, "hash": curr.Parent.Hex()}).Error("Failed to load block")
+			break
+		}
+		curr = parent
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks
+}
+
+// addToAddressIndex indexes the transactions of the blocks finalized after prevFinalized up to
+// block, in increasing height order.
+func (e *ConsensusEngine) addToAddressIndex(prevFinalized *core.ExtendedBlock, block *core.ExtendedBlock) {
+	for _, finalized := range e.newlyFinalizedBlocks(prevFinalized, block) {
+		e.chain.AddTxsToAddressIndex(finalized)
+	}
+}
+
+// addToUptimeIndex records the validator participation in the blocks finalized after
+// prevFinalized up to block, in increasing height order.
+func (e *ConsensusEngine) addToUptimeIndex(prevFinalized *core.ExtendedBlock, block *core.ExtendedBlock) {
+	for _, finalized := range e.newlyFinalizedBlocks(prevFinalized, block) {
+		validators := e.validatorManager.GetValidatorSet(finalized.Hash())
+		e.chain.AddBlockToUptimeIndex(finalized, validators)
+	}
+}
+
 func (e *ConsensusEngine) finalizeBlock(block *core.ExtendedBlock) {
 	if e.stopped {
 		return
@@ -665,6 +810,7 @@ func (e *ConsensusEngine) finalizeBlock(block *core.ExtendedBlock) {
 
 	e.logger.WithFields(log.Fields{"block.Hash": block.Hash().Hex()}).Info("Finalizing block")
 
+	prevFinalized := e.state.GetLastFinalizedBlock()
 	e.state.SetLastFinalizedBlock(block)
 	e.ledger.FinalizeState(block.Height, block.StateHash)
 
@@ -675,10 +821,63 @@ func (e *ConsensusEngine) finalizeBlock(block *core.ExtendedBlock) {
 	// duplicate TX in fork.
 	e.chain.AddTxsToIndex(block, true)
 
+	if viper.GetBool(common.CfgIndexAddress) {
+		e.addToAddressIndex(prevFinalized, block)
+	}
+
+	if viper.GetBool(common.CfgIndexValidatorUptime) {
+		e.addToUptimeIndex(prevFinalized, block)
+	}
+
+	if core.IsCheckpointHeight(block.Height) {
+		e.guardian.StartNewCheckpoint(block)
+	}
+
 	select {
 	case e.finalizedBlocks <- block.Block:
 	default:
 	}
+
+	e.publishFinalizedBlocks(prevFinalized, block)
+}
+
+// publishFinalizedBlocks publishes the blocks finalized after the previously finalized block, up
+// to and including the block, in increasing height order.
+func (e *ConsensusEngine) publishFinalizedBlocks(prevFinalized *core.ExtendedBlock, block *core.ExtendedBlock) {
+	if e.eventBus == nil {
+		return
+	}
+	blocks := []*core.ExtendedBlock{block}
+	for curr := block; curr.Height > prevFinalized.Height+1; {
+		parent, err := e.chain.FindBlock(curr.Parent)
+		if err != nil {
+			break
+		}
+		blocks = append(blocks, parent)
+		curr = parent
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		e.publishFinalizedBlock(blocks[i])
+	}
+}
+
+// publishFinalizedBlock publishes the finalized block, its transactions, and its validator set
+// update if any, to the event bus.
+func (e *ConsensusEngine) publishFinalizedBlock(block *core.ExtendedBlock) {
+	e.eventBus.Publish(event.BlockFinalized{Block: block})
+	for idx, tx := range block.Txs {
+		e.eventBus.Publish(event.TxConfirmed{
+			TxHash: crypto.HashAtHeight(block.Height, tx),
+			Index:  idx,
+			Block:  block,
+		})
+	}
+	if block.HasValidatorUpdate {
+		e.eventBus.Publish(event.ValidatorSetChanged{
+			Block:      block,
+			Validators: e.validatorManager.GetNextValidatorSet(block.Hash()),
+		})
+	}
 }
 
 func (e *ConsensusEngine) randHex() []byte {
@@ -716,6 +915,29 @@ func (e *ConsensusEngine) shouldPropose(epoch uint64) bool {
 	return true
 }
 
+// checkMissedProposal notifies the operator if the epoch times out without a proposal from the
+// local validator while it is the proposer of the epoch.
+func (e *ConsensusEngine) checkMissedProposal() {
+	epoch := e.GetEpoch()
+	lastProposal := e.state.GetLastProposal()
+	if lastProposal.Block != nil && lastProposal.Block.Epoch == epoch {
+		return
+	}
+	if !e.shouldPropose(epoch) {
+		return
+	}
+	notify.Notify(notify.Event{
+		Type:     notify.EventMissedProposal,
+		Severity: notify.SeverityWarning,
+		Summary:  fmt.Sprintf("Validator %v missed its proposal in epoch %v", e.ID(), epoch),
+		Details: map[string]interface{}{
+			"validator": e.ID(),
+			"epoch":     epoch,
+			"tip":       e.GetTipToExtend().Hash().Hex(),
+		},
+	})
+}
+
 func (e *ConsensusEngine) shouldProposeByID(epoch uint64, id string) bool {
 	extBlk := e.state.GetLastFinalizedBlock()
 	proposer := e.validatorManager.GetNextProposer(extBlk.Hash(), epoch)
@@ -742,24 +964,45 @@ func (e *ConsensusEngine) createProposal() (core.Proposal, error) {
 	block.Epoch = e.GetEpoch()
 	block.Parent = tip.Hash()
 	block.Height = tip.Height + 1
-	block.Proposer = e.privateKey.PublicKey().Address()
+	block.Proposer = e.signer.PublicKey().Address()
 	block.Timestamp = big.NewInt(time.Now().Unix())
 	block.HCC.BlockHash = e.state.GetHighestCCBlock().Hash()
 	block.HCC.Votes = e.chain.FindVotesByHash(block.HCC.BlockHash).UniqueVoter()
+	if core.RulesAt(block.ChainID, block.Height).IsActive(core.UpgradeCompactHCC) {
+		// The validator set of the block, which the validators look up from its HCC
+		block.HCC.Compact(e.validatorManager.GetNextValidatorSet(block.HCC.BlockHash))
+	}
 
 	// Add Txs.
-	newRoot, txs, result := e.ledger.ProposeBlockTxs()
+	newRoot, txs, result := e.proposeBlockTxs(tip, block)
 	if result.IsError() {
 		err := fmt.Errorf("Failed to collect Txs for block proposal: %v", result.String())
 		return core.Proposal{}, err
 	}
 	block.AddTxs(txs)
 	block.StateHash = newRoot
+	if bloom, ok := result.Info["bloom"]; ok {
+		block.Bloom = bloom.(core.Bloom)
+	}
+	if baseFee, ok := result.Info["baseFee"]; ok {
+		block.BaseFee = baseFee.(*big.Int)
+	}
+	if nextValidatorSetHash, ok := result.Info["nextValidatorSetHash"]; ok {
+		block.NextValidatorSetHash = nextValidatorSetHash.(common.Hash)
+	}
+
+	if core.RulesAt(block.ChainID, block.Height).IsActive(core.UpgradeVRFProposer) {
+		vrfProof, err := e.guardian.ProveVRF(tip, block)
+		if err != nil {
+			return core.Proposal{}, errors.Wrap(err, "Failed to prove the VRF seed")
+		}
+		block.VRFProof = vrfProof
+	}
 
 	// Sign block.
-	sig, err := e.privateKey.Sign(block.SignBytes())
+	sig, err := e.signer.SignBlock(block.BlockHeader)
 	if err != nil {
-		e.logger.WithFields(log.Fields{"error": err}).Panic("Failed to sign vote")
+		return core.Proposal{}, errors.Wrap(err, "Failed to sign block proposal")
 	}
 	block.SetSignature(sig)
 
@@ -778,8 +1021,11 @@ func (e *ConsensusEngine) createProposal() (core.Proposal, error) {
 			e.logger.WithFields(log.Fields{"error": err}).Warn("Failed to load epoch votes")
 		}
 	}
-	proposal.Votes = lastCCVotes.Merge(epochVotes).UniqueVoterAndBlock()
-	selfVote := e.createVote(block)
+	proposal.Votes = lastCCVotes.Merge(epochVotes)
+	selfVote, err := e.createVote(block)
+	if err != nil {
+		return core.Proposal{}, errors.Wrap(err, "Failed to sign vote for the proposed block")
+	}
 	proposal.Votes.AddVote(selfVote)
 
 	_, err = e.chain.AddBlock(block)
@@ -792,6 +1038,65 @@ func (e *ConsensusEngine) createProposal() (core.Proposal, error) {
 	return proposal, nil
 }
 
+// proposeBlockTxs collects and executes the transactions of the block to propose. If an external
+// builder is configured, the transactions of its payload are used. The block is built locally
+// if the builder does not respond in time or provides an invalid payload, using the transactions
+// prepared while the tip was voted on if any.
+func (e *ConsensusEngine) proposeBlockTxs(tip *core.ExtendedBlock, block *core.Block) (common.Hash, []common.Bytes, result.Result) {
+	if e.builder != nil {
+		newRoot, txs, res, err := e.proposeBuilderTxs(tip, block)
+		if err == nil {
+			e.logger.WithFields(log.Fields{
+				"block.Height": block.Height,
+				"numTxs":       len(txs),
+			}).Info("Using block payload from external builder")
+			return newRoot, txs, res
+		}
+		e.logger.WithFields(log.Fields{
+			"error":        err,
+			"block.Height": block.Height,
+		}).Warn("Failed to use block payload from external builder, building block locally")
+	}
+	if txs, ok := e.takePreparedTxs(tip.Hash()); ok {
+		e.logger.WithFields(log.Fields{
+			"block.Height": block.Height,
+			"numTxs":       len(txs),
+		}).Debug("Using prepared transactions")
+		return e.ledger.ProposePreparedBlockTxs(block.Timestamp, txs)
+	}
+	return e.ledger.ProposeBlockTxs(block.Timestamp)
+}
+
+func (e *ConsensusEngine) proposeBuilderTxs(tip *core.ExtendedBlock, block *core.Block) (common.Hash, []common.Bytes, result.Result, error) {
+	payload, err := e.builder.GetPayload(builder.GetPayloadArgs{
+		ChainID:    block.ChainID,
+		ParentHash: block.Parent,
+		Height:     common.JSONUint64(block.Height),
+		Proposer:   block.Proposer,
+	})
+	if err != nil {
+		return common.Hash{}, nil, result.Result{}, errors.Wrap(err, "Failed to get payload")
+	}
+	err = payload.Validate(e.builderAddress, block.ChainID, block.Parent, block.Height,
+		block.Proposer, core.MaxNumRegularTxsPerBlock)
+	if err != nil {
+		return common.Hash{}, nil, result.Result{}, errors.Wrap(err, "Invalid payload")
+	}
+
+	newRoot, txs, res := e.ledger.ProposeBlockTxsFromPayload(block.Timestamp, payload.Txs)
+	if res.IsError() {
+		// Discard the partially executed payload
+		if resetRes := e.ledger.ResetState(tip.Height, tip.StateHash); resetRes.IsError() {
+			e.logger.WithFields(log.Fields{
+				"error":         resetRes.Message,
+				"tip.StateHash": tip.StateHash.Hex(),
+			}).Panic("Failed to reset state to tip.StateHash")
+		}
+		return common.Hash{}, nil, res, fmt.Errorf("Failed to execute payload: %v", res.Message)
+	}
+	return newRoot, txs, res, nil
+}
+
 func (e *ConsensusEngine) propose() {
 	var proposal core.Proposal
 	var err error
@@ -11,7 +11,10 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/event"
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rlp"
+	"github.com/thetatoken/theta/signer"
 	"github.com/thetatoken/theta/store/database/backend"
 	"github.com/thetatoken/theta/store/kvstore"
 )
@@ -35,7 +38,7 @@ func (m MockValidatorManager) GetValidatorSet(_ common.Hash) *core.ValidatorSet
 }
 
 func (m MockValidatorManager) GetNextValidatorSet(a common.Hash) *core.ValidatorSet {
-	return m.GetNextValidatorSet(a)
+	return m.GetValidatorSet(a)
 }
 
 func (m MockValidatorManager) SetConsensusEngine(consensus core.ConsensusEngine) {}
@@ -552,3 +555,133 @@ func TestTipSelection(t *testing.T) {
 	tip = ce.GetTipToExtend()
 	assert.Equal(a2.Hash(), tip.Hash(), "should not select blocks with validator update that are higher than local HCC")
 }
+
+func TestPublishFinalizedBlocks(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	validatorManager := MockValidatorManager{PrivKey: privKey}
+
+	core.ResetTestBlocks()
+
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	root := core.CreateTestBlock("root", "")
+	chain := blockchain.NewChain("testchain", store, root)
+
+	ce := NewConsensusEngine(nil, store, chain, nil, validatorManager)
+	bus := event.NewBus()
+	ce.SetEventBus(bus)
+	sub := bus.Subscribe(10)
+
+	a1 := core.CreateTestBlock("a1", "root")
+	a1.Txs = []common.Bytes{common.Bytes("tx1"), common.Bytes("tx2")}
+	chain.AddBlock(a1)
+	a2 := core.CreateTestBlock("a2", "a1")
+	chain.AddBlock(a2)
+	eb2 := chain.MarkBlockHasValidatorUpdate(a2.Hash())
+
+	// The ancestors finalized along with a block are published first
+	ce.publishFinalizedBlocks(chain.Root(), eb2)
+	topics := []event.Topic{}
+	for len(sub.Events()) > 0 {
+		topics = append(topics, (<-sub.Events()).Topic())
+	}
+	assert.Equal([]event.Topic{
+		event.TopicBlockFinalized, event.TopicTxConfirmed, event.TopicTxConfirmed,
+		event.TopicBlockFinalized, event.TopicValidatorSetChanged,
+	}, topics)
+}
+
+func TestGetDebugState(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	validatorManager := MockValidatorManager{PrivKey: privKey}
+
+	core.ResetTestBlocks()
+
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	root := core.CreateTestBlock("root", "")
+	chain := blockchain.NewChain("testchain", store, root)
+
+	ce := NewConsensusEngine(signer.NewLocalSigner(privKey), store, chain, nil, validatorManager)
+
+	a1 := core.CreateTestBlock("a1", "root")
+	chain.AddBlock(a1)
+	a2 := core.CreateTestBlock("a2", "a1")
+	chain.AddBlock(a2)
+	b1 := core.CreateTestBlock("b1", "root")
+	chain.AddBlock(b1)
+	chain.AddVoteToIndex(core.Vote{Block: a1.Hash(), Height: a1.Height, ID: privKey.PublicKey().Address()})
+
+	s := ce.GetDebugState()
+	assert.Equal(root.Hash(), s.HighestCCBlock.Hash())
+	assert.Equal(root.Hash(), s.LastFinalizedBlock.Hash())
+	assert.Equal(privKey.PublicKey().Address(), s.Proposer.ID())
+	assert.Equal(s.Epoch > 0, s.ShouldPropose)
+
+	tips := []common.Hash{}
+	for _, block := range s.TipCandidates {
+		tips = append(tips, block.Hash())
+	}
+	assert.ElementsMatch([]common.Hash{a2.Hash(), b1.Hash()}, tips)
+
+	assert.Equal(1, len(s.PendingVotes))
+	assert.Equal(1, s.PendingVotes[a1.Hash()].Size())
+}
+
+// legacyVote is the encoding of the votes before the signature domain was introduced.
+type legacyVote struct {
+	Block     common.Hash
+	Height    uint64
+	Epoch     uint64
+	ID        common.Address
+	Signature *crypto.Signature
+}
+
+func TestCreateVoteWireFormat(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	validatorManager := MockValidatorManager{PrivKey: privKey}
+
+	core.ResetTestBlocks()
+
+	chainID := "test_create_vote"
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	root := core.CreateTestBlock("root", "")
+	root.ChainID = chainID
+	chain := blockchain.NewChain(chainID, store, root)
+	ce := NewConsensusEngine(signer.NewLocalSigner(privKey), store, chain, nil, validatorManager)
+
+	block := core.CreateTestBlock("a1", "root")
+	block.ChainID = chainID
+	block.Height = 10
+
+	// Before the first fork the votes are encoded and signed as by the nodes predating the
+	// signature domain.
+	vote, err := ce.createVote(block)
+	require.Nil(err)
+	assert.Equal("", vote.ChainID)
+	legacy := legacyVote{Block: vote.Block, Height: vote.Height, Epoch: vote.Epoch, ID: vote.ID, Signature: vote.Signature}
+	expected, err := rlp.EncodeToBytes(legacy)
+	require.Nil(err)
+	encoded, err := rlp.EncodeToBytes(vote)
+	require.Nil(err)
+	assert.Equal(expected, encoded)
+	signBytes, err := rlp.EncodeToBytes(legacyVote{Block: vote.Block, Epoch: vote.Epoch, ID: vote.ID})
+	require.Nil(err)
+	assert.True(vote.Signature.Verify(signBytes, vote.ID))
+	assert.True(ce.validateVote(vote))
+
+	// After it, the votes are bound to the signature domain of the chain.
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeCompactHCC: 5}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+	vote, err = ce.createVote(block)
+	require.Nil(err)
+	assert.Equal(chainID, vote.ChainID)
+	assert.False(vote.Signature.Verify(signBytes, vote.ID))
+	assert.True(vote.Validate().IsOK())
+	assert.True(ce.validateVote(vote))
+}
@@ -0,0 +1,108 @@
+package consensus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+)
+
+// EpochTimeout computes how long the engine waits for an epoch to end before repeating it.
+// In adaptive mode, the timeout follows the recent durations of the epochs ended by a majority
+// of votes, i.e. the proposal wait plus the proposal and vote round trips, the same way TCP
+// estimates its retransmission timeout. Each epoch missed in a row doubles the timeout, so that
+// the validators resynchronize after a partition without having to wait for long timeouts
+// once the network is back to normal.
+type EpochTimeout struct {
+	mu *sync.Mutex
+
+	adaptive bool
+	base     time.Duration // Timeout before any epoch is observed, or if not adaptive
+	floor    time.Duration
+	ceiling  time.Duration
+
+	smoothed  time.Duration // Smoothed epoch duration
+	variation time.Duration // Smoothed mean deviation of the epoch durations
+	samples   uint64
+	missed    uint // Number of epochs in a row that ended by timeout
+}
+
+// NewEpochTimeout creates an instance of EpochTimeout from the consensus configuration.
+func NewEpochTimeout() *EpochTimeout {
+	return newEpochTimeout(viper.GetBool(common.CfgConsensusAdaptiveEpochLength),
+		time.Duration(viper.GetInt(common.CfgConsensusMaxEpochLength))*time.Second,
+		time.Duration(viper.GetInt(common.CfgConsensusEpochTimeoutFloor))*time.Second,
+		time.Duration(viper.GetInt(common.CfgConsensusEpochTimeoutCeiling))*time.Second)
+}
+
+func newEpochTimeout(adaptive bool, base, floor, ceiling time.Duration) *EpochTimeout {
+	return &EpochTimeout{
+		mu:       &sync.Mutex{},
+		adaptive: adaptive,
+		base:     base,
+		floor:    floor,
+		ceiling:  ceiling,
+	}
+}
+
+// Timeout returns the timeout of the next epoch.
+func (t *EpochTimeout) Timeout() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.adaptive {
+		return t.base
+	}
+
+	timeout := t.base
+	if t.samples > 0 {
+		timeout = t.smoothed + 4*t.variation
+	}
+	if timeout < t.floor {
+		timeout = t.floor
+	}
+	for i := uint(0); i < t.missed && timeout < t.ceiling; i++ {
+		timeout *= 2
+	}
+	if timeout > t.ceiling {
+		timeout = t.ceiling
+	}
+	return timeout
+}
+
+// ObserveEpoch records the duration of an epoch ended by a majority of votes.
+func (t *EpochTimeout) ObserveEpoch(duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.missed = 0
+	if t.samples == 0 {
+		t.smoothed = duration
+		t.variation = duration / 2
+	} else {
+		delta := t.smoothed - duration
+		if delta < 0 {
+			delta = -delta
+		}
+		t.variation = (3*t.variation + delta) / 4
+		t.smoothed = (7*t.smoothed + duration) / 8
+	}
+	t.samples++
+}
+
+// ObserveTimeout records an epoch ended by timeout.
+func (t *EpochTimeout) ObserveTimeout() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.missed++
+}
+
+// MissedEpochs returns the number of epochs in a row that ended by timeout.
+func (t *EpochTimeout) MissedEpochs() uint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.missed
+}
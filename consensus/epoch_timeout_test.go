@@ -0,0 +1,59 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEpochTimeoutAdaptsToEpochDurations(t *testing.T) {
+	assert := assert.New(t)
+
+	et := newEpochTimeout(true, 10*time.Second, 2*time.Second, 60*time.Second)
+	assert.Equal(10*time.Second, et.Timeout())
+
+	for i := 0; i < 50; i++ {
+		et.ObserveEpoch(1500 * time.Millisecond)
+	}
+	// Stable epoch durations shrink the timeout down to the floor.
+	assert.Equal(2*time.Second, et.Timeout())
+
+	for i := 0; i < 50; i++ {
+		et.ObserveEpoch(5 * time.Second)
+	}
+	timeout := et.Timeout()
+	assert.True(timeout >= 5*time.Second)
+	assert.True(timeout < 10*time.Second)
+}
+
+func TestEpochTimeoutBacksOffOnMissedEpochs(t *testing.T) {
+	assert := assert.New(t)
+
+	et := newEpochTimeout(true, 4*time.Second, 2*time.Second, 30*time.Second)
+	et.ObserveTimeout()
+	assert.Equal(8*time.Second, et.Timeout())
+	et.ObserveTimeout()
+	assert.Equal(16*time.Second, et.Timeout())
+	et.ObserveTimeout()
+	assert.Equal(30*time.Second, et.Timeout())
+	for i := 0; i < 100; i++ {
+		et.ObserveTimeout()
+	}
+	assert.Equal(30*time.Second, et.Timeout())
+	assert.Equal(uint(103), et.MissedEpochs())
+
+	// An epoch ended by a majority of votes resets the back-off.
+	et.ObserveEpoch(3 * time.Second)
+	assert.Equal(uint(0), et.MissedEpochs())
+	assert.True(et.Timeout() <= 9*time.Second)
+}
+
+func TestEpochTimeoutNotAdaptive(t *testing.T) {
+	assert := assert.New(t)
+
+	et := newEpochTimeout(false, 10*time.Second, 2*time.Second, 60*time.Second)
+	et.ObserveEpoch(time.Second)
+	et.ObserveTimeout()
+	assert.Equal(10*time.Second, et.Timeout())
+}
@@ -0,0 +1,93 @@
+package consensus
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+// GetFinalityCertificate exports a certificate proving the finality of the given block. A
+// block directly finalized by the votes on itself and on one of its children is certified by
+// these votes. Otherwise the certificate is the one of its closest directly finalized
+// descendant, with the block in the header chain.
+func (e *ConsensusEngine) GetFinalityCertificate(hash common.Hash) (*core.FinalityCertificate, error) {
+	block, err := e.chain.FindBlockHeader(hash)
+	if err != nil {
+		return nil, err
+	}
+	if !block.Status.IsFinalized() {
+		return nil, fmt.Errorf("Block %v is not finalized", hash.Hex())
+	}
+
+	certified := block
+	var child *core.ExtendedBlock
+	var blockVotes, childVotes *core.VoteSet
+	for {
+		blockVotes = e.chain.FindVotesByHash(certified.Hash()).UniqueVoter()
+		if e.validatorManager.GetValidatorSet(certified.Hash()).HasMajority(blockVotes) {
+			child, childVotes = e.findCommittedChild(certified)
+			if child != nil {
+				break
+			}
+		}
+		next := e.findFinalizedChild(certified)
+		if next == nil {
+			return nil, fmt.Errorf("No votes finalizing block %v found", hash.Hex())
+		}
+		certified = next
+	}
+
+	checkpointHeight := block.Height - block.Height%core.CheckpointInterval
+	headerChain := []*core.BlockHeader{}
+	for curr := certified; curr.Height > checkpointHeight; {
+		parent, err := e.chain.FindBlockHeader(curr.Parent)
+		if err != nil {
+			return nil, err
+		}
+		headerChain = append(headerChain, parent.BlockHeader)
+		curr = parent
+	}
+	for i, j := 0, len(headerChain)-1; i < j; i, j = i+1, j-1 {
+		headerChain[i], headerChain[j] = headerChain[j], headerChain[i]
+	}
+
+	return &core.FinalityCertificate{
+		ChainID:         e.chain.ChainID,
+		Block:           certified.BlockHeader,
+		Child:           child.BlockHeader,
+		BlockVotes:      blockVotes.Votes(),
+		ChildVotes:      childVotes.Votes(),
+		BlockValidators: e.validatorManager.GetValidatorSet(certified.Hash()).Validators(),
+		ChildValidators: e.validatorManager.GetValidatorSet(child.Hash()).Validators(),
+		HeaderChain:     headerChain,
+	}, nil
+}
+
+// findCommittedChild returns a child of the block voted for by a validator supermajority,
+// along with the votes.
+func (e *ConsensusEngine) findCommittedChild(block *core.ExtendedBlock) (*core.ExtendedBlock, *core.VoteSet) {
+	for _, hash := range block.Children {
+		child, err := e.chain.FindBlockHeader(hash)
+		if err != nil {
+			continue
+		}
+		votes := e.chain.FindVotesByHash(hash).UniqueVoter()
+		if e.validatorManager.GetValidatorSet(hash).HasMajority(votes) {
+			return child, votes
+		}
+	}
+	return nil, nil
+}
+
+// findFinalizedChild returns the finalized child of the block, or nil if the block is the last
+// finalized block.
+func (e *ConsensusEngine) findFinalizedChild(block *core.ExtendedBlock) *core.ExtendedBlock {
+	for _, hash := range block.Children {
+		child, err := e.chain.FindBlockHeader(hash)
+		if err == nil && child.Status.IsFinalized() {
+			return child
+		}
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/crypto/bls"
+	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// GuardianKeyInfo is what a DepositStakeTxV2 needs to register the node as a guardian
+type GuardianKeyInfo struct {
+	Holder    common.Address
+	BlsPubkey common.Bytes
+	BlsPop    common.Bytes
+	HolderSig *crypto.Signature
+}
+
+// Summary concatenates the BLS key, its proof of possession and the holder signature, so that
+// they can be passed around as a single hex string.
+func (info *GuardianKeyInfo) Summary() common.Bytes {
+	summary := append(common.Bytes{}, info.BlsPubkey...)
+	summary = append(summary, info.BlsPop...)
+	return append(summary, info.HolderSig.ToBytes()...)
+}
+
+// GuardianEngine collects the BLS signatures of the guardians over the finalized checkpoints.
+// Each guardian merges the aggregated votes it receives into its own, and gossips the result
+// every round, so that the votes propagate along aggregation trees rooted at every guardian.
+type GuardianEngine struct {
+	logger *log.Entry
+
+	engine    *ConsensusEngine
+	nodeKey   *crypto.PrivateKey
+	blsSigner bls.Signer // nil if the node does not sign as a guardian
+
+	incoming    chan *core.AggregatedVotes
+	checkpoints chan *core.ExtendedBlock
+
+	// Voting state of the current checkpoint
+	mu            *sync.Mutex
+	block         *core.ExtendedBlock
+	gcp           *core.GuardianCandidatePool
+	currVote      *core.AggregatedVotes
+	lastFinalized common.Hash
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewGuardianEngine creates a instance of GuardianEngine.
+func NewGuardianEngine(engine *ConsensusEngine) *GuardianEngine {
+	return &GuardianEngine{
+		logger: util.GetLoggerForModule("guardian"),
+
+		engine: engine,
+
+		incoming:    make(chan *core.AggregatedVotes, viper.GetInt(common.CfgConsensusMessageQueueSize)),
+		checkpoints: make(chan *core.ExtendedBlock, 1),
+
+		mu: &sync.Mutex{},
+		wg: &sync.WaitGroup{},
+	}
+}
+
+// SetKey derives the BLS key of the guardian from the node key, so that it does not need to be
+// stored separately.
+func (g *GuardianEngine) SetKey(nodeKey *crypto.PrivateKey) {
+	g.SetSigner(nodeKey, bls.GenKeyFromSeed(nodeKey.ToBytes()))
+}
+
+// SetSigner sets the BLS signer of the guardian, e.g. to sign with a key held outside of the
+// node. The node key signs the registration of the BLS key.
+func (g *GuardianEngine) SetSigner(nodeKey *crypto.PrivateKey, blsSigner bls.Signer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.nodeKey = nodeKey
+	g.blsSigner = blsSigner
+}
+
+// GetKeyInfo returns the BLS key of the node together with the proofs needed to register it.
+func (g *GuardianEngine) GetKeyInfo() (*GuardianKeyInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.blsSigner == nil {
+		return nil, errors.New("Guardian key not available, the node key is not held locally")
+	}
+
+	holder := g.nodeKey.PublicKey().Address()
+	blsPubkey := g.blsSigner.PublicKey().ToBytes()
+	blsPop := g.blsSigner.PopProve().ToBytes()
+	holderSig, err := g.nodeKey.Sign(types.GuardianKeySignBytes(holder, blsPubkey, blsPop))
+	if err != nil {
+		return nil, err
+	}
+	return &GuardianKeyInfo{
+		Holder:    holder,
+		BlsPubkey: blsPubkey,
+		BlsPop:    blsPop,
+		HolderSig: holderSig,
+	}, nil
+}
+
+// Start starts the main loop.
+func (g *GuardianEngine) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	g.ctx = c
+	g.cancel = cancel
+
+	g.wg.Add(1)
+	go g.mainLoop()
+}
+
+// Stop notifies the main loop to stop without blocking.
+func (g *GuardianEngine) Stop() {
+	g.cancel()
+}
+
+// Wait blocks until the main loop stops.
+func (g *GuardianEngine) Wait() {
+	g.wg.Wait()
+}
+
+// StartNewCheckpoint starts the voting on the newly finalized checkpoint. Voting on the previous
+// checkpoint stops, since finalizing the new checkpoint also finalizes the previous one.
+func (g *GuardianEngine) StartNewCheckpoint(block *core.ExtendedBlock) {
+	select {
+	case <-g.checkpoints: // drop the checkpoint not picked up yet
+	default:
+	}
+	g.checkpoints <- block
+}
+
+// AddVote queues the aggregated votes received from a peer, dropping them if the queue is full.
+func (g *GuardianEngine) AddVote(vote *core.AggregatedVotes) {
+	select {
+	case g.incoming <- vote:
+	default:
+		g.logger.WithFields(log.Fields{"vote": vote}).Debug("Guardian vote queue full, dropping vote")
+	}
+}
+
+// GetVoteToBroadcast returns the aggregated votes of the current checkpoint.
+func (g *GuardianEngine) GetVoteToBroadcast() *core.AggregatedVotes {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.currVote
+}
+
+// GetLastFinalizedCheckpoint returns the hash of the last checkpoint signed by guardians
+// holding more than 2/3 of the guardian stake.
+func (g *GuardianEngine) GetLastFinalizedCheckpoint() common.Hash {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastFinalized
+}
+
+func (g *GuardianEngine) mainLoop() {
+	defer g.wg.Done()
+
+	roundLength := time.Duration(viper.GetInt(common.CfgGuardianRoundLength)) * time.Second
+	ticker := g.engine.clock.NewTicker(roundLength)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case block := <-g.checkpoints:
+			g.handleCheckpoint(block)
+		case vote := <-g.incoming:
+			g.handleVote(vote)
+		case <-ticker.C():
+			g.broadcastVote()
+		}
+	}
+}
+
+func (g *GuardianEngine) handleCheckpoint(block *core.ExtendedBlock) {
+	gcp, err := g.engine.GetLedger().GetGuardianCandidatePool(block.Hash())
+	if err != nil {
+		g.logger.WithFields(log.Fields{
+			"block": block.Hash().Hex(),
+			"error": err,
+		}).Error("Failed to load guardian candidate pool")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.block = block
+	g.gcp = gcp
+	g.currVote = nil
+	if gcp == nil || gcp.Len() == 0 {
+		return
+	}
+
+	vote := core.NewAggregatedVotes(block.Hash(), gcp)
+	if signerIdx := g.registeredSignerIndex(gcp); signerIdx >= 0 {
+		vote.Sign(g.blsSigner, signerIdx)
+	}
+	g.currVote = vote
+
+	g.logger.WithFields(log.Fields{
+		"block":     block.Hash().Hex(),
+		"height":    block.Height,
+		"guardians": gcp.Len(),
+		"vote":      vote,
+	}).Debug("Started voting on checkpoint")
+
+	g.checkMajority()
+}
+
+func (g *GuardianEngine) handleVote(vote *core.AggregatedVotes) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.currVote == nil || vote.Block != g.currVote.Block {
+		g.logger.WithFields(log.Fields{"vote": vote}).Debug("Ignoring guardian vote for another checkpoint")
+		return
+	}
+
+	// Cheap checks first, since the signature verification is expensive
+	merged, err := g.currVote.Merge(vote)
+	if err != nil {
+		g.logger.WithFields(log.Fields{"vote": vote, "error": err}).Debug("Ignoring incompatible guardian vote")
+		return
+	}
+	if merged == nil {
+		return // no new signer
+	}
+
+	if err := vote.Validate(g.gcp); err != nil {
+		g.logger.WithFields(log.Fields{"vote": vote, "error": err}).Warn("Ignoring invalid guardian vote")
+		return
+	}
+
+	g.currVote = merged
+	g.checkMajority()
+}
+
+// checkMajority records the checkpoint as finalized by the guardians once the current votes
+// hold a majority of the guardian stake.
+func (g *GuardianEngine) checkMajority() {
+	if g.lastFinalized == g.currVote.Block || !g.currVote.HasMajority(g.gcp) {
+		return
+	}
+	g.lastFinalized = g.currVote.Block
+
+	g.logger.WithFields(log.Fields{
+		"block":   g.block.Hash().Hex(),
+		"height":  g.block.Height,
+		"signers": g.currVote.NumSigners(),
+	}).Info("Checkpoint finalized by guardians")
+}
+
+func (g *GuardianEngine) broadcastVote() {
+	vote := g.GetVoteToBroadcast()
+	if vote == nil || vote.NumSigners() == 0 {
+		return
+	}
+
+	payload, err := rlp.EncodeToBytes(vote)
+	if err != nil {
+		g.logger.WithFields(log.Fields{"vote": vote}).Error("Failed to encode guardian vote")
+		return
+	}
+	voteMsg := dispatcher.DataResponse{
+		ChannelID: common.ChannelIDGuardian,
+		Payload:   payload,
+	}
+	g.engine.dispatcher.SendData([]string{}, voteMsg)
+}
+
+// registeredSignerIndex returns the index of the node in the guardian candidate pool if its BLS
+// key is the one registered, -1 otherwise.
+func (g *GuardianEngine) registeredSignerIndex(gcp *core.GuardianCandidatePool) int {
+	if g.blsSigner == nil {
+		return -1
+	}
+	signerIdx := gcp.Index(g.engine.signer.PublicKey().Address())
+	if signerIdx < 0 || !gcp.SortedGuardians[signerIdx].Pubkey.Equals(g.blsSigner.PublicKey()) {
+		return -1
+	}
+	return signerIdx
+}
+
+// ProveVRF returns the VRF proof of the block proposed by the node on top of the parent, or nil
+// if the node has no guardian key registered as of the parent block.
+func (g *GuardianEngine) ProveVRF(parent *core.ExtendedBlock, block *core.Block) (common.Bytes, error) {
+	gcp, err := g.engine.GetLedger().GetGuardianCandidatePool(parent.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if gcp == nil || g.registeredSignerIndex(gcp) < 0 {
+		return nil, nil
+	}
+	return g.blsSigner.Sign(VRFInput(block.ChainID, block.Height, BlockSeed(parent.BlockHeader))).ToBytes(), nil
+}
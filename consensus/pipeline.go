@@ -0,0 +1,66 @@
+package consensus
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+// preparedBlockTxs holds the regular transactions prepared for the block extending the parent.
+type preparedBlockTxs struct {
+	parent common.Hash
+	txs    []common.Bytes
+	done   chan struct{} // closed once txs is set
+}
+
+// prepareNextProposal starts to assemble the transactions of the block extending the given
+// block in the background, if the node proposes in the next epoch. This way the transactions
+// are selected from the mempool while the given block is voted on, instead of when proposing.
+func (e *ConsensusEngine) prepareNextProposal(block *core.Block) {
+	if !e.pipelineProposal || e.builder != nil {
+		return
+	}
+	if !e.shouldProposeByID(e.GetEpoch()+1, e.ID()) {
+		return
+	}
+	if e.preparedTxs != nil && e.preparedTxs.parent == block.Hash() {
+		return
+	}
+
+	prepared := &preparedBlockTxs{
+		parent: block.Hash(),
+		done:   make(chan struct{}),
+	}
+	e.preparedTxs = prepared
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer close(prepared.done)
+
+		prepared.txs = e.ledger.PrepareBlockTxs()
+
+		e.logger.WithFields(log.Fields{
+			"parent": prepared.parent.Hex(),
+			"numTxs": len(prepared.txs),
+		}).Debug("Prepared transactions for next proposal")
+	}()
+}
+
+// takePreparedTxs returns the transactions prepared for the block extending the given parent,
+// waiting for the preparation to complete if needed. The second return value is false if the
+// transactions were prepared for another parent, e.g. if a different block became the tip.
+func (e *ConsensusEngine) takePreparedTxs(parent common.Hash) ([]common.Bytes, bool) {
+	prepared := e.preparedTxs
+	e.preparedTxs = nil
+	if prepared == nil || prepared.parent != parent {
+		return nil, false
+	}
+
+	select {
+	case <-prepared.done:
+		return prepared.txs, true
+	case <-e.ctx.Done():
+		return nil, false
+	}
+}
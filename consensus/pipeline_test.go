@@ -0,0 +1,61 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+func TestTakePreparedTxs(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	validatorManager := MockValidatorManager{PrivKey: privKey}
+
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	root := core.CreateTestBlock("a0", "")
+	root.ChainID = "testchain"
+	chain := blockchain.NewChain("testchain", store, root)
+
+	ce := NewConsensusEngine(nil, store, chain, nil, validatorManager)
+	ce.ctx, ce.cancel = context.WithCancel(context.Background())
+	defer ce.cancel()
+
+	// Nothing prepared
+	_, ok := ce.takePreparedTxs(root.Hash())
+	assert.False(ok)
+
+	// Prepared for another parent
+	ce.preparedTxs = &preparedBlockTxs{
+		parent: common.BytesToHash([]byte("a1")),
+		done:   make(chan struct{}),
+	}
+	_, ok = ce.takePreparedTxs(root.Hash())
+	assert.False(ok)
+	assert.Nil(ce.preparedTxs)
+
+	// Prepared for the parent, waits for the preparation to complete
+	prepared := &preparedBlockTxs{
+		parent: root.Hash(),
+		done:   make(chan struct{}),
+	}
+	ce.preparedTxs = prepared
+	go func() {
+		prepared.txs = []common.Bytes{common.Bytes("tx1"), common.Bytes("tx2")}
+		close(prepared.done)
+	}()
+	txs, ok := ce.takePreparedTxs(root.Hash())
+	assert.True(ok)
+	assert.Equal([]common.Bytes{common.Bytes("tx1"), common.Bytes("tx2")}, txs)
+
+	// The prepared transactions are used only once
+	_, ok = ce.takePreparedTxs(root.Hash())
+	assert.False(ok)
+}
@@ -0,0 +1,238 @@
+package simulation
+
+import (
+	"math/big"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/dispatcher"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// Fault is a misbehavior of a byzantine node
+type Fault int
+
+const (
+	// FaultEquivocate sends two conflicting proposals for the same epoch, each to half of the peers
+	FaultEquivocate Fault = iota
+	// FaultWithholdVotes does not send the votes
+	FaultWithholdVotes
+	// FaultInvalidHCC sends proposals whose HCC is not an ancestor of the block
+	FaultInvalidHCC
+	// FaultConflictingVotes also votes for all the other blocks at the height of the voted block
+	FaultConflictingVotes
+)
+
+func (f Fault) String() string {
+	switch f {
+	case FaultEquivocate:
+		return "Equivocate"
+	case FaultWithholdVotes:
+		return "WithholdVotes"
+	case FaultInvalidHCC:
+		return "InvalidHCC"
+	case FaultConflictingVotes:
+		return "ConflictingVotes"
+	default:
+		return "Unknown"
+	}
+}
+
+// FaultWindow enables a fault during the epochs in [StartEpoch, EndEpoch]
+type FaultWindow struct {
+	Fault      Fault
+	StartEpoch uint64
+	EndEpoch   uint64
+}
+
+// FaultSchedule lists the faults of a byzantine node. The windows can overlap.
+type FaultSchedule []FaultWindow
+
+// IsActive returns true if the fault is enabled at the epoch
+func (s FaultSchedule) IsActive(fault Fault, epoch uint64) bool {
+	for _, w := range s {
+		if w.Fault == fault && w.StartEpoch <= epoch && epoch <= w.EndEpoch {
+			return true
+		}
+	}
+	return false
+}
+
+// forgeKey identifies a forged proposal by the original block and the fault
+type forgeKey struct {
+	block common.Hash
+	fault Fault
+}
+
+// Byzantine wraps the consensus engine of a node and rewrites the messages it sends according
+// to the fault schedule. The engine itself runs unmodified, so the faulty messages are signed
+// with the node key and go through the real validation logic of the honest nodes.
+type Byzantine struct {
+	logger *log.Entry
+
+	node     *Node
+	schedule FaultSchedule
+
+	mu       *sync.Mutex
+	forged   map[forgeKey]*core.Proposal // so that all the peers get the same forged proposal
+	injected map[Fault]int
+}
+
+// SetByzantine turns the i-th node into a byzantine node. It must be called before Start.
+func (s *Simulation) SetByzantine(i int, schedule FaultSchedule) *Byzantine {
+	node := s.Nodes[i]
+	b := &Byzantine{
+		logger:   logger.WithFields(log.Fields{"byzantine": node.ID}),
+		node:     node,
+		schedule: schedule,
+		mu:       &sync.Mutex{},
+		forged:   make(map[forgeKey]*core.Proposal),
+		injected: make(map[Fault]int),
+	}
+	node.endpoint.interceptor = b.intercept
+	return b
+}
+
+// Injected returns how many faulty messages of the given kind have been sent
+func (b *Byzantine) Injected(fault Fault) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.injected[fault]
+}
+
+// intercept returns the messages to send to the peer in place of the given message.
+func (b *Byzantine) intercept(peerID string, message p2ptypes.Message) []p2ptypes.Message {
+	data, ok := message.Content.(dispatcher.DataResponse)
+	if !ok {
+		return []p2ptypes.Message{message}
+	}
+	epoch := b.node.Consensus.GetEpoch()
+
+	switch data.ChannelID {
+	case common.ChannelIDVote:
+		if b.schedule.IsActive(FaultWithholdVotes, epoch) {
+			b.record(FaultWithholdVotes)
+			return []p2ptypes.Message{}
+		}
+		if b.schedule.IsActive(FaultConflictingVotes, epoch) {
+			return append([]p2ptypes.Message{message}, b.conflictingVotes(data)...)
+		}
+	case common.ChannelIDProposal:
+		if b.schedule.IsActive(FaultInvalidHCC, epoch) {
+			if forged := b.forgeProposal(data, FaultInvalidHCC); forged != nil {
+				return []p2ptypes.Message{*forged}
+			}
+		} else if b.schedule.IsActive(FaultEquivocate, epoch) && isSecondHalf(peerID) {
+			if forged := b.forgeProposal(data, FaultEquivocate); forged != nil {
+				return []p2ptypes.Message{*forged}
+			}
+		}
+	}
+	return []p2ptypes.Message{message}
+}
+
+// conflictingVotes signs votes for the other blocks at the height of the voted block.
+func (b *Byzantine) conflictingVotes(data dispatcher.DataResponse) []p2ptypes.Message {
+	vote := core.Vote{}
+	if err := rlp.DecodeBytes(data.Payload, &vote); err != nil {
+		b.logger.WithFields(log.Fields{"error": err}).Error("Failed to decode vote")
+		return nil
+	}
+
+	ret := []p2ptypes.Message{}
+	for _, block := range b.node.Chain.FindBlockHeadersByHeight(vote.Height) {
+		if block.Hash() == vote.Block {
+			continue
+		}
+		conflicting := core.Vote{
+			Block:  block.Hash(),
+			Height: block.Height,
+			Epoch:  vote.Epoch,
+			ID:     vote.ID,
+		}
+		sig, err := b.node.PrivateKey.Sign(conflicting.SignBytes())
+		if err != nil {
+			continue
+		}
+		conflicting.SetSignature(sig)
+		if msg := b.encode(common.ChannelIDVote, conflicting); msg != nil {
+			ret = append(ret, *msg)
+			b.record(FaultConflictingVotes)
+		}
+	}
+	return ret
+}
+
+// forgeProposal returns a re-signed copy of the proposal, either with a different timestamp so
+// that it conflicts with the original, or with an HCC which is not an ancestor of the block.
+func (b *Byzantine) forgeProposal(data dispatcher.DataResponse, fault Fault) *p2ptypes.Message {
+	proposal := &core.Proposal{}
+	if err := rlp.DecodeBytes(data.Payload, proposal); err != nil || proposal.Block == nil {
+		b.logger.WithFields(log.Fields{"error": err}).Error("Failed to decode proposal")
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	original := proposal.Block.Hash()
+	key := forgeKey{block: original, fault: fault}
+	forged, ok := b.forged[key]
+	if !ok {
+		block := proposal.Block
+		switch fault {
+		case FaultEquivocate:
+			block.Timestamp = new(big.Int).Add(block.Timestamp, big.NewInt(1))
+		case FaultInvalidHCC:
+			block.HCC = core.CommitCertificate{BlockHash: crypto.Keccak256Hash(original.Bytes())}
+		}
+		sig, err := b.node.PrivateKey.Sign(block.SignBytes())
+		if err != nil {
+			return nil
+		}
+		block.SetSignature(sig)
+		block.UpdateHash()
+
+		forged = proposal
+		b.forged[key] = forged
+		b.injected[fault]++
+
+		b.logger.WithFields(log.Fields{
+			"fault":    fault,
+			"original": original.Hex(),
+			"forged":   block.Hash().Hex(),
+		}).Info("Forged proposal")
+	}
+
+	return b.encode(common.ChannelIDProposal, forged)
+}
+
+func (b *Byzantine) encode(channelID common.ChannelIDEnum, content interface{}) *p2ptypes.Message {
+	payload, err := rlp.EncodeToBytes(content)
+	if err != nil {
+		b.logger.WithFields(log.Fields{"error": err}).Error("Failed to encode message")
+		return nil
+	}
+	return &p2ptypes.Message{
+		ChannelID: channelID,
+		Content: dispatcher.DataResponse{
+			ChannelID: channelID,
+			Payload:   payload,
+		},
+	}
+}
+
+func (b *Byzantine) record(fault Fault) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.injected[fault]++
+}
+
+// isSecondHalf splits the peers into two halves, the same way for every proposal.
+func isSecondHalf(peerID string) bool {
+	return crypto.Keccak256([]byte(peerID))[0]&1 == 1
+}
@@ -0,0 +1,103 @@
+package simulation
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/dispatcher"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func encodeMessage(assert *assert.Assertions, channelID common.ChannelIDEnum, content interface{}) p2ptypes.Message {
+	payload, err := rlp.EncodeToBytes(content)
+	assert.Nil(err)
+	return p2ptypes.Message{
+		ChannelID: channelID,
+		Content:   dispatcher.DataResponse{ChannelID: channelID, Payload: payload},
+	}
+}
+
+func decodeProposal(assert *assert.Assertions, message p2ptypes.Message) *core.Proposal {
+	proposal := &core.Proposal{}
+	assert.Nil(rlp.DecodeBytes(message.Content.(dispatcher.DataResponse).Payload, proposal))
+	return proposal
+}
+
+func newTestProposal(assert *assert.Assertions, node *Node) *core.Proposal {
+	block := core.NewBlock()
+	block.ChainID = ChainID
+	block.Epoch = 1
+	block.Height = node.Chain.Root().Height + 1
+	block.Parent = node.Chain.Root().Hash()
+	block.HCC.BlockHash = block.Parent
+	block.Proposer = node.PrivateKey.PublicKey().Address()
+	block.Timestamp = big.NewInt(time.Now().Unix())
+	sig, err := node.PrivateKey.Sign(block.SignBytes())
+	assert.Nil(err)
+	block.SetSignature(sig)
+	return &core.Proposal{Block: block, ProposerID: block.Proposer}
+}
+
+func TestFaultSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	schedule := FaultSchedule{
+		{Fault: FaultWithholdVotes, StartEpoch: 2, EndEpoch: 4},
+		{Fault: FaultEquivocate, StartEpoch: 3, EndEpoch: 3},
+	}
+	assert.False(schedule.IsActive(FaultWithholdVotes, 1))
+	assert.True(schedule.IsActive(FaultWithholdVotes, 2))
+	assert.True(schedule.IsActive(FaultWithholdVotes, 4))
+	assert.False(schedule.IsActive(FaultWithholdVotes, 5))
+	assert.True(schedule.IsActive(FaultEquivocate, 3))
+	assert.False(schedule.IsActive(FaultInvalidHCC, 3))
+}
+
+func TestByzantineFaults(t *testing.T) {
+	assert := assert.New(t)
+
+	sim := NewSimulation(4, NetworkConfig{Seed: 1})
+	node := sim.Nodes[0]
+	proposal := newTestProposal(assert, node)
+	original := proposal.Block.Hash()
+
+	// Withheld votes are not sent
+	b := sim.SetByzantine(0, FaultSchedule{{Fault: FaultWithholdVotes, StartEpoch: 0, EndEpoch: 10}})
+	vote := core.Vote{Block: original, Height: 1, ID: proposal.ProposerID}
+	assert.Equal(0, len(b.intercept(sim.Nodes[1].ID, encodeMessage(assert, common.ChannelIDVote, vote))))
+	assert.Equal(1, b.Injected(FaultWithholdVotes))
+
+	// Proposals with invalid HCC are properly signed, but the HCC is not an ancestor
+	b = sim.SetByzantine(0, FaultSchedule{{Fault: FaultInvalidHCC, StartEpoch: 0, EndEpoch: 10}})
+	sent := b.intercept(sim.Nodes[1].ID, encodeMessage(assert, common.ChannelIDProposal, proposal))
+	assert.Equal(1, len(sent))
+	forged := decodeProposal(assert, sent[0])
+	assert.NotEqual(original, forged.Block.Hash())
+	assert.False(node.Chain.IsDescendant(forged.Block.HCC.BlockHash, forged.Block.Hash()))
+	assert.True(forged.Block.Signature.Verify(forged.Block.SignBytes(), node.PrivateKey.PublicKey().Address()))
+
+	// Equivocating proposals differ between the two halves of the peers, and are the same
+	// within each half
+	b = sim.SetByzantine(0, FaultSchedule{{Fault: FaultEquivocate, StartEpoch: 0, EndEpoch: 10}})
+	blocks := make(map[bool]common.Hash)
+	for _, peer := range sim.Nodes[1:] {
+		sent := b.intercept(peer.ID, encodeMessage(assert, common.ChannelIDProposal, proposal))
+		assert.Equal(1, len(sent))
+		hash := decodeProposal(assert, sent[0]).Block.Hash()
+		if prev, ok := blocks[isSecondHalf(peer.ID)]; ok {
+			assert.Equal(prev, hash)
+		}
+		blocks[isSecondHalf(peer.ID)] = hash
+		assert.Equal(!isSecondHalf(peer.ID), hash == original)
+	}
+
+	// Without active fault, the messages are sent unmodified
+	b = sim.SetByzantine(0, FaultSchedule{{Fault: FaultEquivocate, StartEpoch: 5, EndEpoch: 10}})
+	message := encodeMessage(assert, common.ChannelIDProposal, proposal)
+	assert.Equal([]p2ptypes.Message{message}, b.intercept(sim.Nodes[1].ID, message))
+}
@@ -0,0 +1,436 @@
+package simulation
+
+import (
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/p2p"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+// NetworkConfig sets the behavior of the simulated network
+type NetworkConfig struct {
+	Seed       int64         // Seed of the scheduler, the same seed reproduces the same message fates
+	MinLatency time.Duration // Minimal delivery latency
+	MaxLatency time.Duration // Maximal delivery latency
+	DropRate   float64       // Probability that a message is dropped, in [0, 1]
+}
+
+// NetworkStats counts the messages handled by the simulated network
+type NetworkStats struct {
+	Sent        uint64
+	Delivered   uint64
+	Dropped     uint64
+	Partitioned uint64
+}
+
+// delivery is a message scheduled for delivery
+type delivery struct {
+	at       time.Time
+	seq      uint64
+	to       *Endpoint
+	envelope p2ptypes.Message
+}
+
+// deliveryQueue is a min-heap of the deliveries ordered by delivery time, ties broken by the
+// order in which they were scheduled
+type deliveryQueue []*delivery
+
+func (q deliveryQueue) Len() int { return len(q) }
+func (q deliveryQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q deliveryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *deliveryQueue) Push(x interface{}) { *q = append(*q, x.(*delivery)) }
+func (q *deliveryQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	d := old[n-1]
+	*q = old[:n-1]
+	return d
+}
+
+// linkKey identifies the stream of messages of a channel between two endpoints
+type linkKey struct {
+	from      string
+	to        string
+	channelID common.ChannelIDEnum
+}
+
+// Network is a simulated network connecting in-process endpoints. The fate of each message,
+// i.e. whether it is dropped and its latency, is drawn from a random source seeded with the
+// network seed, the link and the position of the message in the stream of the link. The
+// fates therefore do not depend on the goroutine scheduling, and a run can be replayed by
+// reusing the seed.
+type Network struct {
+	mu        *sync.Mutex
+	config    NetworkConfig
+	endpoints map[string]*Endpoint
+	partition map[string]int // group of each endpoint, nil if the network is not partitioned
+	linkSeqs  map[linkKey]uint64
+	queue     deliveryQueue
+	seq       uint64
+	stats     NetworkStats
+	wakeup    chan struct{}
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewNetwork creates a new instance of Network.
+func NewNetwork(config NetworkConfig) *Network {
+	if config.MaxLatency < config.MinLatency {
+		config.MaxLatency = config.MinLatency
+	}
+	return &Network{
+		mu:        &sync.Mutex{},
+		config:    config,
+		endpoints: make(map[string]*Endpoint),
+		linkSeqs:  make(map[linkKey]uint64),
+		queue:     deliveryQueue{},
+		wakeup:    make(chan struct{}, 1),
+		wg:        &sync.WaitGroup{},
+	}
+}
+
+// AddEndpoint adds an endpoint with the given ID to the network.
+func (n *Network) AddEndpoint(id string) *Endpoint {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	endpoint := &Endpoint{
+		id:       id,
+		network:  n,
+		incoming: make(chan p2ptypes.Message, viper.GetInt(common.CfgP2PMessageQueueSize)),
+		wg:       &sync.WaitGroup{},
+	}
+	n.endpoints[id] = endpoint
+	return endpoint
+}
+
+// Start starts the delivery of the scheduled messages.
+func (n *Network) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	n.ctx = c
+	n.cancel = cancel
+
+	n.wg.Add(1)
+	go n.mainLoop()
+}
+
+// Stop notifies the delivery loop to stop without blocking.
+func (n *Network) Stop() {
+	n.cancel()
+}
+
+// Wait blocks until the delivery loop stops.
+func (n *Network) Wait() {
+	n.wg.Wait()
+}
+
+// SetDropRate changes the probability that a message is dropped.
+func (n *Network) SetDropRate(dropRate float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.config.DropRate = dropRate
+}
+
+// SetLatency changes the range of the delivery latency.
+func (n *Network) SetLatency(min, max time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if max < min {
+		max = min
+	}
+	n.config.MinLatency = min
+	n.config.MaxLatency = max
+}
+
+// Partition splits the network into the given groups of endpoints. Messages are only sent
+// within a group, and endpoints not listed in any group are isolated.
+func (n *Network) Partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.partition = make(map[string]int)
+	for i, group := range groups {
+		for _, id := range group {
+			n.partition[id] = i
+		}
+	}
+}
+
+// Heal removes the partition. Messages already dropped by the partition are not resent.
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.partition = nil
+}
+
+// Stats returns the message counters of the network.
+func (n *Network) Stats() NetworkStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stats
+}
+
+// send schedules the delivery of the message from one endpoint to another.
+func (n *Network) send(from string, to string, message p2ptypes.Message) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	endpoint, ok := n.endpoints[to]
+	if !ok || from == to {
+		return
+	}
+	n.stats.Sent++
+
+	if !n.connected(from, to) {
+		n.stats.Partitioned++
+		return
+	}
+
+	drop, latency := n.fate(from, to, message.ChannelID)
+	if drop {
+		n.stats.Dropped++
+		return
+	}
+
+	message.PeerID = from
+	n.seq++
+	heap.Push(&n.queue, &delivery{
+		at:       time.Now().Add(latency),
+		seq:      n.seq,
+		to:       endpoint,
+		envelope: message,
+	})
+
+	select {
+	case n.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// connected returns true if the partition allows messages between the endpoints.
+func (n *Network) connected(from string, to string) bool {
+	if n.partition == nil {
+		return true
+	}
+	fromGroup, ok := n.partition[from]
+	if !ok {
+		return false
+	}
+	toGroup, ok := n.partition[to]
+	return ok && fromGroup == toGroup
+}
+
+// fate draws whether the next message of the link is dropped, and its latency otherwise.
+func (n *Network) fate(from string, to string, channelID common.ChannelIDEnum) (drop bool, latency time.Duration) {
+	key := linkKey{from: from, to: to, channelID: channelID}
+	linkSeq := n.linkSeqs[key]
+	n.linkSeqs[key] = linkSeq + 1
+
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[0:8], uint64(n.config.Seed))
+	binary.BigEndian.PutUint64(buf[8:16], linkSeq)
+	h := crypto.Keccak256(buf[:], []byte(from), []byte(to), []byte{byte(channelID)})
+	rnd := rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(h[:8]))))
+
+	if rnd.Float64() < n.config.DropRate {
+		return true, 0
+	}
+	latency = n.config.MinLatency
+	if spread := n.config.MaxLatency - n.config.MinLatency; spread > 0 {
+		latency += time.Duration(rnd.Int63n(int64(spread) + 1))
+	}
+	return false, latency
+}
+
+func (n *Network) mainLoop() {
+	defer n.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		wait := n.deliverDue()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-n.wakeup:
+		case <-timer.C:
+		}
+	}
+}
+
+// deliverDue delivers the messages whose delivery time has come, and returns how long to wait
+// for the next one.
+func (n *Network) deliverDue() time.Duration {
+	n.mu.Lock()
+	due := []*delivery{}
+	now := time.Now()
+	for n.queue.Len() > 0 && !n.queue[0].at.After(now) {
+		due = append(due, heap.Pop(&n.queue).(*delivery))
+	}
+	wait := time.Hour
+	if n.queue.Len() > 0 {
+		wait = n.queue[0].at.Sub(now)
+	}
+	n.mu.Unlock()
+
+	delivered, overflown := uint64(0), uint64(0)
+	for _, d := range due {
+		select {
+		case d.to.incoming <- d.envelope:
+			delivered++
+		default:
+			overflown++ // the endpoint is stopped or too slow
+		}
+	}
+
+	n.mu.Lock()
+	n.stats.Delivered += delivered
+	n.stats.Dropped += overflown
+	n.mu.Unlock()
+
+	return wait
+}
+
+//
+// ------- Endpoint ------- //
+//
+
+var _ p2p.Network = (*Endpoint)(nil)
+
+// Endpoint is the implementation of the p2p.Network interface for a node of the simulated
+// network.
+type Endpoint struct {
+	id       string
+	network  *Network
+	handlers []p2p.MessageHandler
+	incoming chan p2ptypes.Message
+
+	// interceptor rewrites the messages sent to each peer, nil if the endpoint is honest
+	interceptor func(peerID string, message p2ptypes.Message) []p2ptypes.Message
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Start implements the p2p.Network interface. It starts handling the delivered messages.
+func (e *Endpoint) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	e.ctx = c
+	e.cancel = cancel
+
+	e.wg.Add(1)
+	go e.mainLoop()
+	return nil
+}
+
+// Stop implements the p2p.Network interface.
+func (e *Endpoint) Stop() {
+	e.cancel()
+}
+
+// Wait implements the p2p.Network interface.
+func (e *Endpoint) Wait() {
+	e.wg.Wait()
+}
+
+// Broadcast implements the p2p.Network interface.
+func (e *Endpoint) Broadcast(message p2ptypes.Message) chan bool {
+	e.network.mu.Lock()
+	peerIDs := make([]string, 0, len(e.network.endpoints))
+	for id := range e.network.endpoints {
+		peerIDs = append(peerIDs, id)
+	}
+	e.network.mu.Unlock()
+
+	// Visit the peers in a fixed order, so that the deliveries are scheduled deterministically
+	sort.Strings(peerIDs)
+
+	successes := make(chan bool, len(peerIDs))
+	for _, id := range peerIDs {
+		if id != e.id {
+			e.sendTo(id, message)
+			successes <- true
+		}
+	}
+	return successes
+}
+
+// Send implements the p2p.Network interface.
+func (e *Endpoint) Send(peerID string, message p2ptypes.Message) bool {
+	e.sendTo(peerID, message)
+	return true
+}
+
+func (e *Endpoint) sendTo(peerID string, message p2ptypes.Message) {
+	if e.interceptor == nil {
+		e.network.send(e.id, peerID, message)
+		return
+	}
+	for _, m := range e.interceptor(peerID, message) {
+		e.network.send(e.id, peerID, m)
+	}
+}
+
+// RegisterMessageHandler implements the p2p.Network interface.
+func (e *Endpoint) RegisterMessageHandler(handler p2p.MessageHandler) {
+	e.handlers = append(e.handlers, handler)
+}
+
+// ID implements the p2p.Network interface.
+func (e *Endpoint) ID() string {
+	return e.id
+}
+
+func (e *Endpoint) mainLoop() {
+	defer e.wg.Done()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case message := <-e.incoming:
+			e.handleMessage(message)
+		}
+	}
+}
+
+// handleMessage passes the message to the handlers of its channel, as the messenger does.
+func (e *Endpoint) handleMessage(message p2ptypes.Message) {
+	for _, handler := range e.handlers {
+		for _, channelID := range handler.GetChannelIDs() {
+			if channelID == message.ChannelID {
+				handler.HandleMessage(message)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+// recorder is a message handler recording the messages it receives
+type recorder struct {
+	received chan p2ptypes.Message
+}
+
+func newRecorder() *recorder {
+	return &recorder{received: make(chan p2ptypes.Message, 100)}
+}
+
+func (r *recorder) GetChannelIDs() []common.ChannelIDEnum {
+	return []common.ChannelIDEnum{common.ChannelIDVote}
+}
+
+func (r *recorder) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
+	return p2ptypes.Message{PeerID: peerID, ChannelID: channelID, Content: rawMessageBytes}, nil
+}
+
+func (r *recorder) EncodeMessage(message interface{}) (common.Bytes, error) {
+	return nil, nil
+}
+
+func (r *recorder) HandleMessage(message p2ptypes.Message) error {
+	r.received <- message
+	return nil
+}
+
+func sendMessages(network *Network, from string, to string, num int) {
+	for i := 0; i < num; i++ {
+		network.send(from, to, p2ptypes.Message{ChannelID: common.ChannelIDVote, Content: i})
+	}
+}
+
+func TestNetworkFatesAreReproducible(t *testing.T) {
+	assert := assert.New(t)
+
+	fates := func(seed int64) []bool {
+		network := NewNetwork(NetworkConfig{Seed: seed, DropRate: 0.5})
+		network.AddEndpoint("a")
+		network.AddEndpoint("b")
+		ret := []bool{}
+		for i := 0; i < 50; i++ {
+			drop, _ := network.fate("a", "b", common.ChannelIDVote)
+			ret = append(ret, drop)
+		}
+		return ret
+	}
+
+	assert.Equal(fates(1), fates(1))
+	assert.NotEqual(fates(1), fates(2))
+
+	// The fates of a link do not depend on the traffic of the other links
+	network := NewNetwork(NetworkConfig{Seed: 1, DropRate: 0.5})
+	expected := fates(1)
+	for i := 0; i < 50; i++ {
+		network.fate("b", "a", common.ChannelIDVote)
+		drop, _ := network.fate("a", "b", common.ChannelIDVote)
+		assert.Equal(expected[i], drop)
+	}
+}
+
+func TestNetworkDelivery(t *testing.T) {
+	assert := assert.New(t)
+
+	network := NewNetwork(NetworkConfig{Seed: 1, MinLatency: time.Millisecond, MaxLatency: 5 * time.Millisecond})
+	a := network.AddEndpoint("a")
+	b := network.AddEndpoint("b")
+	c := network.AddEndpoint("c")
+	recorders := []*recorder{newRecorder(), newRecorder(), newRecorder()}
+	for i, endpoint := range []*Endpoint{a, b, c} {
+		endpoint.RegisterMessageHandler(recorders[i])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	network.Start(ctx)
+	for _, endpoint := range []*Endpoint{a, b, c} {
+		endpoint.Start(ctx)
+	}
+
+	// Broadcast reaches all the other endpoints
+	a.Broadcast(p2ptypes.Message{ChannelID: common.ChannelIDVote, Content: "hello"})
+	for _, r := range recorders[1:] {
+		select {
+		case msg := <-r.received:
+			assert.Equal("a", msg.PeerID)
+			assert.Equal("hello", msg.Content)
+		case <-time.After(time.Second):
+			assert.Fail("Message not delivered")
+		}
+	}
+
+	// Messages do not cross the partition
+	network.Partition([]string{"a", "b"}, []string{"c"})
+	a.Send("c", p2ptypes.Message{ChannelID: common.ChannelIDVote, Content: "lost"})
+	a.Send("b", p2ptypes.Message{ChannelID: common.ChannelIDVote, Content: "kept"})
+	select {
+	case msg := <-recorders[1].received:
+		assert.Equal("kept", msg.Content)
+	case <-time.After(time.Second):
+		assert.Fail("Message not delivered")
+	}
+
+	network.Heal()
+	a.Send("c", p2ptypes.Message{ChannelID: common.ChannelIDVote, Content: "healed"})
+	select {
+	case msg := <-recorders[2].received:
+		assert.Equal("healed", msg.Content)
+	case <-time.After(time.Second):
+		assert.Fail("Message not delivered")
+	}
+
+	// All messages dropped
+	network.SetDropRate(1)
+	sendMessages(network, "a", "b", 10)
+
+	stats := network.Stats()
+	assert.Equal(uint64(15), stats.Sent)
+	assert.Equal(uint64(4), stats.Delivered)
+	assert.Equal(uint64(1), stats.Partitioned)
+	assert.Equal(uint64(10), stats.Dropped)
+
+	cancel()
+	network.Wait()
+}
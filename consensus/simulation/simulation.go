@@ -0,0 +1,328 @@
+// Package simulation runs several consensus engines in the same process, connected by a
+// simulated network with controllable latency, partitions and message drops, so that liveness
+// and safety regressions can be reproduced from the network seed.
+package simulation
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/netsync"
+	"github.com/thetatoken/theta/signer"
+	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "simulation"})
+
+// ChainID is the chain ID of the simulated chain
+const ChainID = "simchain"
+
+//
+// ------- Node ------- //
+//
+
+// Node is a validator of the simulation
+type Node struct {
+	ID          string
+	PrivateKey  *crypto.PrivateKey
+	Chain       *blockchain.Chain
+	Consensus   *consensus.ConsensusEngine
+	SyncManager *netsync.SyncManager
+	Dispatcher  *dispatcher.Dispatcher
+
+	endpoint  *Endpoint
+	mu        *sync.Mutex
+	finalized []*core.Block
+}
+
+func newNode(privKey *crypto.PrivateKey, root *core.Block, vcp *core.ValidatorCandidatePool, network *Network) *Node {
+	id := privKey.PublicKey().Address().Hex()
+	endpoint := network.AddEndpoint(id)
+
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	chain := blockchain.NewChain(ChainID, store, root)
+	validatorManager := consensus.NewRotatingValidatorManager()
+	disp := dispatcher.NewDispatcher(endpoint)
+	engine := consensus.NewConsensusEngine(signer.NewLocalSigner(privKey), store, chain, disp, validatorManager)
+	syncMgr := netsync.NewSyncManager(chain, engine, endpoint, disp, engine)
+	validatorManager.SetConsensusEngine(engine)
+	engine.SetLedger(newSimLedger(vcp))
+
+	return &Node{
+		ID:          id,
+		PrivateKey:  privKey,
+		Chain:       chain,
+		Consensus:   engine,
+		SyncManager: syncMgr,
+		Dispatcher:  disp,
+		endpoint:    endpoint,
+		mu:          &sync.Mutex{},
+		finalized:   []*core.Block{},
+	}
+}
+
+// FinalizedBlocks returns the blocks finalized by the node so far, in the order of finalization.
+func (n *Node) FinalizedBlocks() []*core.Block {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*core.Block{}, n.finalized...)
+}
+
+func (n *Node) collectFinalizedBlocks(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case block := <-n.Consensus.FinalizedBlocks():
+			n.mu.Lock()
+			n.finalized = append(n.finalized, block)
+			n.mu.Unlock()
+		}
+	}
+}
+
+//
+// ------- Simulation ------- //
+//
+
+// Simulation runs the consensus engines of the validators over the simulated network. The
+// validators all have the same stake, and their keys are derived from the network seed.
+type Simulation struct {
+	Network *Network
+	Nodes   []*Node
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewSimulation creates a simulation of numNodes validators.
+func NewSimulation(numNodes int, config NetworkConfig) *Simulation {
+	network := NewNetwork(config)
+
+	privKeys := make([]*crypto.PrivateKey, numNodes)
+	vcp := &core.ValidatorCandidatePool{}
+	for i := 0; i < numNodes; i++ {
+		privKeys[i] = deriveKey(config.Seed, i)
+		holder := privKeys[i].PublicKey().Address()
+		if err := vcp.DepositStake(holder, holder, core.MinValidatorStakeDeposit); err != nil {
+			logger.Panic(err)
+		}
+	}
+
+	root := core.NewBlock()
+	root.ChainID = ChainID
+	root.Epoch = 0
+
+	nodes := make([]*Node, numNodes)
+	for i, privKey := range privKeys {
+		nodes[i] = newNode(privKey, root, vcp, network)
+	}
+
+	return &Simulation{
+		Network: network,
+		Nodes:   nodes,
+		wg:      &sync.WaitGroup{},
+	}
+}
+
+// deriveKey derives the private key of the i-th validator from the seed.
+func deriveKey(seed int64, i int) *crypto.PrivateKey {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[0:8], uint64(seed))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(i))
+	for counter := byte(0); ; counter++ {
+		privKey, err := crypto.PrivateKeyFromBytes(crypto.Keccak256(buf[:], []byte{counter}))
+		if err == nil {
+			return privKey
+		}
+	}
+}
+
+// NodeIDs returns the IDs of the nodes with the given indexes, e.g. to partition the network.
+func (s *Simulation) NodeIDs(indexes ...int) []string {
+	ids := make([]string, len(indexes))
+	for i, idx := range indexes {
+		ids[i] = s.Nodes[idx].ID
+	}
+	return ids
+}
+
+// SetClock sets the clock of the consensus engines of all the nodes, e.g. a shared
+// consensus.FakeClock to trigger the epoch timeouts deterministically. It must be called
+// before Start. The latency of the network still follows the system clock.
+func (s *Simulation) SetClock(clock consensus.Clock) {
+	for _, node := range s.Nodes {
+		node.Consensus.SetClock(clock)
+	}
+}
+
+// Start starts the network and the nodes.
+func (s *Simulation) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	s.ctx = c
+	s.cancel = cancel
+
+	s.Network.Start(s.ctx)
+	for _, node := range s.Nodes {
+		node.Consensus.Start(s.ctx)
+		node.SyncManager.Start(s.ctx)
+		node.Dispatcher.Start(s.ctx)
+
+		s.wg.Add(1)
+		go node.collectFinalizedBlocks(s.ctx, s.wg)
+	}
+}
+
+// Stop notifies the network and the nodes to stop without blocking.
+func (s *Simulation) Stop() {
+	s.cancel()
+}
+
+// Wait blocks until the network and the nodes stop.
+func (s *Simulation) Wait() {
+	for _, node := range s.Nodes {
+		node.Consensus.Wait()
+		node.SyncManager.Wait()
+		node.Dispatcher.Wait()
+	}
+	s.Network.Wait()
+	s.wg.Wait()
+}
+
+// RunFor lets the simulation run for the given duration.
+func (s *Simulation) RunFor(duration time.Duration) {
+	select {
+	case <-s.ctx.Done():
+	case <-time.After(duration):
+	}
+}
+
+// CheckLiveness returns an error if a node has finalized less than minFinalized blocks.
+func (s *Simulation) CheckLiveness(minFinalized int) error {
+	for _, node := range s.Nodes {
+		if num := len(node.FinalizedBlocks()); num < minFinalized {
+			return fmt.Errorf("Node %v only finalized %v blocks, expected at least %v", node.ID, num, minFinalized)
+		}
+	}
+	return nil
+}
+
+// CheckSafety returns an error if two nodes finalized conflicting blocks. The finalized chain
+// of each node is rebuilt from its last finalized block, and every block finalized by any node
+// must be on it, up to its height.
+func (s *Simulation) CheckSafety() error {
+	for _, node := range s.Nodes {
+		finalized := node.FinalizedBlocks()
+		if len(finalized) == 0 {
+			continue
+		}
+
+		chainByHeight := make(map[uint64]common.Hash)
+		last := finalized[len(finalized)-1]
+		for hash := last.Hash(); ; {
+			block, err := node.Chain.FindBlock(hash)
+			if err != nil {
+				return fmt.Errorf("Node %v misses finalized block %v: %v", node.ID, hash.Hex(), err)
+			}
+			chainByHeight[block.Height] = hash
+			if block.Height == node.Chain.Root().Height {
+				break
+			}
+			hash = block.Parent
+		}
+
+		for _, other := range s.Nodes {
+			for _, block := range other.FinalizedBlocks() {
+				if block.Height > last.Height {
+					break
+				}
+				if chainByHeight[block.Height] != block.Hash() {
+					return fmt.Errorf("Conflicting blocks finalized at height %v: %v by node %v, %v by node %v",
+						block.Height, chainByHeight[block.Height].Hex(), node.ID, block.Hash().Hex(), other.ID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//
+// ------- simLedger ------- //
+//
+
+var _ core.Ledger = (*simLedger)(nil)
+
+// simLedger is a ledger without transactions, whose validator candidate pool never changes,
+// so that the simulation exercises the consensus only.
+type simLedger struct {
+	vcp *core.ValidatorCandidatePool
+	gcp *core.GuardianCandidatePool
+}
+
+func newSimLedger(vcp *core.ValidatorCandidatePool) *simLedger {
+	return &simLedger{
+		vcp: vcp,
+		gcp: core.NewGuardianCandidatePool(),
+	}
+}
+
+func (l *simLedger) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	return nil, result.Error("Transactions are not supported by the simulation")
+}
+
+func (l *simLedger) NewScreenBatch() core.ScreenBatch {
+	return nil
+}
+
+func (l *simLedger) ProposeBlockTxs(timestamp *big.Int) (common.Hash, []common.Bytes, result.Result) {
+	return common.Hash{}, []common.Bytes{}, result.OK
+}
+
+func (l *simLedger) ProposeBlockTxsFromPayload(timestamp *big.Int, regularRawTxs []common.Bytes) (common.Hash, []common.Bytes, result.Result) {
+	return common.Hash{}, []common.Bytes{}, result.OK
+}
+
+func (l *simLedger) PrepareBlockTxs() []common.Bytes {
+	return []common.Bytes{}
+}
+
+func (l *simLedger) ProposePreparedBlockTxs(timestamp *big.Int, regularRawTxs []common.Bytes) (common.Hash, []common.Bytes, result.Result) {
+	return common.Hash{}, []common.Bytes{}, result.OK
+}
+
+func (l *simLedger) ApplyBlockTxs(blockRawTxs []common.Bytes, timestamp *big.Int, expectedStateRoot common.Hash) result.Result {
+	return result.OK
+}
+
+func (l *simLedger) ResetState(height uint64, rootHash common.Hash) result.Result {
+	return result.OK
+}
+
+func (l *simLedger) FinalizeState(height uint64, rootHash common.Hash) result.Result {
+	return result.OK
+}
+
+func (l *simLedger) GetFinalizedValidatorCandidatePool(blockHash common.Hash, isNext bool) (*core.ValidatorCandidatePool, error) {
+	return l.vcp, nil
+}
+
+func (l *simLedger) GetGuardianCandidatePool(blockHash common.Hash) (*core.GuardianCandidatePool, error) {
+	return l.gcp, nil
+}
@@ -0,0 +1,134 @@
+// +build integration
+
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/consensus"
+)
+
+func setTestEpochLength() {
+	viper.Set(common.CfgConsensusMaxEpochLength, 2)
+	viper.Set(common.CfgConsensusMinProposalWait, 1)
+	viper.Set(common.CfgConsensusEpochTimeoutFloor, 2)
+	viper.Set(common.CfgConsensusEpochTimeoutCeiling, 8)
+}
+
+func TestSimulationLossyNetwork(t *testing.T) {
+	assert := assert.New(t)
+	setTestEpochLength()
+
+	sim := NewSimulation(4, NetworkConfig{
+		Seed:       42,
+		MinLatency: 10 * time.Millisecond,
+		MaxLatency: 200 * time.Millisecond,
+		DropRate:   0.1,
+	})
+	sim.Start(context.Background())
+	sim.RunFor(10 * time.Second)
+	sim.Stop()
+	sim.Wait()
+
+	assert.Nil(sim.CheckSafety())
+	assert.Nil(sim.CheckLiveness(1))
+	assert.True(sim.Network.Stats().Dropped > 0)
+}
+
+func TestSimulationPartition(t *testing.T) {
+	assert := assert.New(t)
+	setTestEpochLength()
+
+	sim := NewSimulation(4, NetworkConfig{
+		Seed:       7,
+		MinLatency: 10 * time.Millisecond,
+		MaxLatency: 50 * time.Millisecond,
+	})
+
+	// Neither half holds more than 2/3 of the stake, so no block can be finalized
+	sim.Network.Partition(sim.NodeIDs(0, 1), sim.NodeIDs(2, 3))
+	sim.Start(context.Background())
+	sim.RunFor(5 * time.Second)
+	assert.Nil(sim.CheckSafety())
+	for _, node := range sim.Nodes {
+		assert.Equal(0, len(node.FinalizedBlocks()))
+	}
+
+	// The chain makes progress again once the partition heals
+	sim.Network.Heal()
+	sim.RunFor(10 * time.Second)
+	sim.Stop()
+	sim.Wait()
+
+	assert.Nil(sim.CheckSafety())
+	assert.Nil(sim.CheckLiveness(1))
+}
+
+func TestSimulationByzantine(t *testing.T) {
+	assert := assert.New(t)
+	setTestEpochLength()
+
+	sim := NewSimulation(4, NetworkConfig{
+		Seed:       3,
+		MinLatency: 10 * time.Millisecond,
+		MaxLatency: 50 * time.Millisecond,
+	})
+
+	// A single byzantine node holds less than 1/3 of the stake
+	byzantine := sim.SetByzantine(0, FaultSchedule{
+		{Fault: FaultEquivocate, StartEpoch: 0, EndEpoch: 5},
+		{Fault: FaultConflictingVotes, StartEpoch: 0, EndEpoch: 10},
+		{Fault: FaultInvalidHCC, StartEpoch: 6, EndEpoch: 10},
+		{Fault: FaultWithholdVotes, StartEpoch: 11, EndEpoch: 1000},
+	})
+	sim.Start(context.Background())
+	sim.RunFor(15 * time.Second)
+	sim.Stop()
+	sim.Wait()
+
+	assert.Nil(sim.CheckSafety())
+	assert.Nil(sim.CheckLiveness(1))
+	assert.True(byzantine.Injected(FaultConflictingVotes)+byzantine.Injected(FaultWithholdVotes) > 0)
+}
+
+func TestSimulationEpochTimeoutBackOff(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	setTestEpochLength()
+
+	sim := NewSimulation(4, NetworkConfig{Seed: 5})
+	clock := consensus.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	// No validator holds a majority on its own, so every epoch times out
+	sim.Network.Partition()
+	start := time.Now()
+	sim.Start(context.Background())
+
+	// The epoch timer and the guardian ticker of each node
+	numTimers := 2 * len(sim.Nodes)
+	for i, timeout := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		clock.WaitForTimers(numTimers)
+		for _, node := range sim.Nodes {
+			require.Equal(uint(i), node.Consensus.GetEpochTimeout().MissedEpochs())
+			require.Equal(timeout, node.Consensus.GetEpochTimeout().Timeout())
+		}
+		clock.Advance(timeout)
+	}
+	clock.WaitForTimers(numTimers)
+	sim.Stop()
+	sim.Wait()
+
+	for _, node := range sim.Nodes {
+		assert.Equal(uint(4), node.Consensus.GetEpochTimeout().MissedEpochs())
+		assert.Equal(0, len(node.FinalizedBlocks()))
+	}
+	// The 22 seconds of timeouts do not take place in real time
+	assert.True(time.Since(start) < 5*time.Second)
+}
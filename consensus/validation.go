@@ -0,0 +1,108 @@
+package consensus
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// ValidateBlock checks the block against its parent and the validator set of the block. These are
+// the checks of the consensus engine which do not depend on its live state, so that the tools
+// replaying recorded blocks validate them the same way. The engine also checks that the block is
+// proposed by the proposer of its epoch.
+func ValidateBlock(chain *blockchain.Chain, validators *core.ValidatorSet, block *core.Block, parent *core.ExtendedBlock) error {
+	if parent.Height+1 != block.Height {
+		return fmt.Errorf("Block height %v != parent height %v + 1", block.Height, parent.Height)
+	}
+	if parent.Epoch >= block.Epoch {
+		return fmt.Errorf("Block epoch %v <= parent epoch %v", block.Epoch, parent.Epoch)
+	}
+	if !parent.Status.IsValid() {
+		return fmt.Errorf("Block is referring to invalid parent block %v", block.Parent.Hex())
+	}
+	if !chain.IsDescendant(block.HCC.BlockHash, block.Hash()) {
+		return fmt.Errorf("HCC %v must be ancestor", block.HCC.BlockHash.Hex())
+	}
+	if err := block.HCC.ResolveVotes(validators); err != nil {
+		return fmt.Errorf("Invalid HCC voters: %v", err)
+	}
+	if !block.HCC.IsValid(validators) {
+		return fmt.Errorf("Invalid HCC: %v", block.HCC.String())
+	}
+
+	// Blocks with validator changes must be followed by two direct confirmation blocks.
+	if parent.HasValidatorUpdate && block.HCC.BlockHash != block.Parent {
+		return fmt.Errorf("HCC %v must equal to parent when parent contains validator changes", block.HCC.BlockHash.Hex())
+	}
+	if !parent.Parent.IsEmpty() {
+		grandParent, err := chain.FindBlockHeader(parent.Parent)
+		if err != nil {
+			return fmt.Errorf("Failed to find grand parent block %v: %v", parent.Parent.Hex(), err)
+		}
+		if grandParent.HasValidatorUpdate {
+			if block.HCC.BlockHash != block.Parent {
+				return fmt.Errorf("HCC %v must equal to parent when grand parent contains validator changes", block.HCC.BlockHash.Hex())
+			}
+			if !block.HCC.IsProven(validators) {
+				return fmt.Errorf("HCC %v must contain valid voteset when grand parent contains validator changes", block.HCC)
+			}
+		}
+	}
+
+	if res := block.Validate(); res.IsError() {
+		return fmt.Errorf("Block is invalid: %v", res.String())
+	}
+	return validateBlockRules(block, core.RulesAt(chain.ChainID, block.Height))
+}
+
+// validateBlockRules checks the block against the validation rules of the upgrades active at
+// the block height.
+func validateBlockRules(block *core.Block, rules *core.Rules) error {
+	if rules.IsActive(core.UpgradeFeeMarket) != (block.BaseFee != nil) {
+		return fmt.Errorf("Block base fee %v must be set if and only if the fee market is active, fee market: %v",
+			block.BaseFee, rules.IsActive(core.UpgradeFeeMarket))
+	}
+	if rules.IsActive(core.UpgradeCompactHCC) != block.HCC.IsCompact() {
+		return fmt.Errorf("Block HCC must be compact if and only if the compact HCC is active, compact HCC: %v",
+			rules.IsActive(core.UpgradeCompactHCC))
+	}
+	return nil
+}
+
+// ValidateAppliedBlock checks the result of applying the transactions of the block, as returned
+// by Ledger.ApplyBlockTxs, against the log bloom, the base fee and the next validator set hash
+// committed to by the block.
+func ValidateAppliedBlock(block *core.Block, res result.Result) error {
+	receipts := []*types.Receipt{}
+	if r, ok := res.Info["receipts"]; ok {
+		receipts = r.([]*types.Receipt)
+	}
+	if bloom := types.CreateBloom(receipts); bloom != block.Bloom {
+		return fmt.Errorf("Log bloom mismatch, block: %v, computed: %v", block.Bloom.Big().Text(16), bloom.Big().Text(16))
+	}
+	if baseFee, ok := res.Info["baseFee"]; ok && !baseFeeMatches(block.BaseFee, baseFee.(*big.Int)) {
+		return fmt.Errorf("Base fee mismatch, block: %v, computed: %v", block.BaseFee, baseFee)
+	}
+	nextValidatorSetHash := common.Hash{}
+	if h, ok := res.Info["nextValidatorSetHash"]; ok {
+		nextValidatorSetHash = h.(common.Hash)
+	}
+	if nextValidatorSetHash != block.NextValidatorSetHash {
+		return fmt.Errorf("Next validator set hash mismatch, block: %v, computed: %v",
+			block.NextValidatorSetHash.Hex(), nextValidatorSetHash.Hex())
+	}
+	return nil
+}
+
+// baseFeeMatches checks the base fee in the block header against the one computed by the ledger
+func baseFeeMatches(headerBaseFee, baseFee *big.Int) bool {
+	if headerBaseFee == nil || baseFee == nil {
+		return headerBaseFee == nil && baseFee == nil
+	}
+	return headerBaseFee.Cmp(baseFee) == 0
+}
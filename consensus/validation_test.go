@@ -0,0 +1,59 @@
+package consensus
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func TestValidateAppliedBlock(t *testing.T) {
+	assert := assert.New(t)
+
+	receipts := []*types.Receipt{{Logs: []*types.Log{{Address: common.HexToAddress("0x1111111111111111111111111111111111111111")}}}}
+	nextValidatorSetHash := common.HexToHash("0x01")
+	block := core.NewBlock()
+	block.Bloom = types.CreateBloom(receipts)
+	block.BaseFee = big.NewInt(100)
+	block.NextValidatorSetHash = nextValidatorSetHash
+
+	res := result.OKWith(result.Info{
+		"receipts":             receipts,
+		"baseFee":              big.NewInt(100),
+		"nextValidatorSetHash": nextValidatorSetHash,
+	})
+	assert.Nil(ValidateAppliedBlock(block, res))
+
+	// Each recorded field must match the result of the transactions
+	mismatches := map[string]result.Info{
+		"Log bloom mismatch":               {"baseFee": big.NewInt(100), "nextValidatorSetHash": nextValidatorSetHash},
+		"Base fee mismatch":                {"receipts": receipts, "baseFee": big.NewInt(99), "nextValidatorSetHash": nextValidatorSetHash},
+		"Next validator set hash mismatch": {"receipts": receipts, "baseFee": big.NewInt(100)},
+	}
+	for prefix, info := range mismatches {
+		err := ValidateAppliedBlock(block, result.OKWith(info))
+		if assert.NotNil(err, prefix) {
+			assert.True(strings.HasPrefix(err.Error(), prefix), "%v does not start with %v", err, prefix)
+		}
+	}
+}
+
+func TestValidateBlockRulesCompactHCC(t *testing.T) {
+	assert := assert.New(t)
+
+	schedule := core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeCompactHCC: 100})
+	block := core.NewBlock()
+	block.HCC.BlockHash = common.HexToHash("0x01")
+	assert.Nil(validateBlockRules(block, schedule.Rules(99)))
+	assert.NotNil(validateBlockRules(block, schedule.Rules(100)))
+
+	// The votes of the HCC are encoded as a bitmap of the validators once the upgrade is active
+	block.HCC.Compact(core.NewValidatorSet())
+	assert.NotNil(validateBlockRules(block, schedule.Rules(99)))
+	assert.Nil(validateBlockRules(block, schedule.Rules(100)))
+}
@@ -69,7 +69,8 @@ func (m *FixedValidatorManager) GetNextValidatorSet(blockHash common.Hash) *core
 var _ core.ValidatorManager = &RotatingValidatorManager{}
 
 // RotatingValidatorManager is an implementation of ValidatorManager interface that selects a random validator as
-// the proposer using validator's stake as weight.
+// the proposer using validator's stake as weight. Once the VRF proposer selection is active, the randomness comes
+// from the seed of the block, chained through the VRF proofs of the proposers, instead of the epoch alone.
 type RotatingValidatorManager struct {
 	consensus core.ConsensusEngine
 }
@@ -87,12 +88,39 @@ func (m *RotatingValidatorManager) SetConsensusEngine(consensus core.ConsensusEn
 
 // GetProposer implements ValidatorManager interface.
 func (m *RotatingValidatorManager) GetProposer(blockHash common.Hash, epoch uint64) core.Validator {
-	return m.getProposerFromValidators(m.GetValidatorSet(blockHash), epoch)
+	valSet := m.GetValidatorSet(blockHash)
+	if proposer, ok := m.getVRFProposer(blockHash, valSet, epoch); ok {
+		return proposer
+	}
+	return m.getProposerFromValidators(valSet, epoch)
 }
 
 // GetNextProposer implements ValidatorManager interface.
 func (m *RotatingValidatorManager) GetNextProposer(blockHash common.Hash, epoch uint64) core.Validator {
-	return m.getProposerFromValidators(m.GetNextValidatorSet(blockHash), epoch)
+	valSet := m.GetNextValidatorSet(blockHash)
+	if proposer, ok := m.getVRFProposer(blockHash, valSet, epoch); ok {
+		return proposer
+	}
+	return m.getProposerFromValidators(valSet, epoch)
+}
+
+// getVRFProposer selects the proposer from the seed of the block if the VRF proposer selection
+// is active for the blocks following it. Only the validators able to extend the seed chain are
+// eligible.
+func (m *RotatingValidatorManager) getVRFProposer(blockHash common.Hash, valSet *core.ValidatorSet, epoch uint64) (core.Validator, bool) {
+	provider, ok := m.consensus.(chainProvider)
+	if !ok {
+		return core.Validator{}, false
+	}
+	block, err := provider.Chain().FindBlockHeader(blockHash)
+	if err != nil {
+		return core.Validator{}, false
+	}
+	if !core.RulesAt(block.ChainID, block.Height+1).IsActive(core.UpgradeVRFProposer) {
+		return core.Validator{}, false
+	}
+	eligible := vrfEligibleValidators(m.consensus.GetLedger(), blockHash, valSet)
+	return selectProposerBySeed(eligible, BlockSeed(block.BlockHeader), epoch), true
 }
 
 func (m *RotatingValidatorManager) getProposerFromValidators(valSet *core.ValidatorSet, epoch uint64) core.Validator {
@@ -0,0 +1,144 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/crypto/bls"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// vrfDomain separates the VRF inputs from the other messages signed with the guardian keys.
+const vrfDomain = "theta/proposer-vrf"
+
+// VRFInput returns the message the proposer of the block at the given height signs with its
+// guardian key to produce the VRF proof of the block, chained from the seed of the parent block.
+func VRFInput(chainID string, height uint64, parentSeed common.Hash) common.Bytes {
+	input, err := rlp.EncodeToBytes([]interface{}{vrfDomain, chainID, height, parentSeed})
+	if err != nil {
+		panic(err)
+	}
+	return input
+}
+
+// BlockSeed returns the seed of the block the proposers are selected from. It is the hash of the
+// VRF proof of the block, which its proposer can not choose, or the hash of the block if the
+// block has no proof.
+func BlockSeed(header *core.BlockHeader) common.Hash {
+	if len(header.VRFProof) == 0 {
+		return header.Hash()
+	}
+	return crypto.Keccak256Hash(header.VRFProof)
+}
+
+// ValidateVRFProof checks the VRF proof of the block. Once the VRF proposer selection is active,
+// the proposers with a guardian key registered as of the parent block must prove the seed of the
+// parent block with it. The blocks of the other proposers, and all the blocks before the upgrade,
+// must not carry a proof.
+func ValidateVRFProof(ledger core.Ledger, block *core.Block, parent *core.ExtendedBlock) error {
+	if !core.RulesAt(block.ChainID, block.Height).IsActive(core.UpgradeVRFProposer) {
+		if len(block.VRFProof) != 0 {
+			return fmt.Errorf("VRF proof is not allowed before the VRF proposer selection")
+		}
+		return nil
+	}
+
+	pubkey, err := vrfKey(ledger, parent.Hash(), block.Proposer)
+	if err != nil {
+		return err
+	}
+	if pubkey == nil {
+		if len(block.VRFProof) != 0 {
+			return fmt.Errorf("VRF proof is not allowed for proposer %v without guardian key", block.Proposer.Hex())
+		}
+		return nil
+	}
+	if len(block.VRFProof) == 0 {
+		return fmt.Errorf("Missing VRF proof of proposer %v", block.Proposer.Hex())
+	}
+	proof, err := bls.SignatureFromBytes(block.VRFProof)
+	if err != nil {
+		return fmt.Errorf("Invalid VRF proof: %v", err)
+	}
+	// The seed is the hash of the proof, which must have a single encoding
+	if !bytes.Equal(proof.ToBytes(), block.VRFProof) {
+		return fmt.Errorf("VRF proof is not canonically encoded")
+	}
+	if !proof.Verify(pubkey, VRFInput(block.ChainID, block.Height, BlockSeed(parent.BlockHeader))) {
+		return fmt.Errorf("VRF proof does not match the seed of parent %v", parent.Hash().Hex())
+	}
+	return nil
+}
+
+// vrfKey returns the guardian key registered by the validator as of the given block, or nil if
+// the validator is not a guardian.
+func vrfKey(ledger core.Ledger, blockHash common.Hash, validator common.Address) (*bls.PublicKey, error) {
+	gcp, err := ledger.GetGuardianCandidatePool(blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load the guardian candidate pool of block %v: %v", blockHash.Hex(), err)
+	}
+	if gcp == nil {
+		return nil, nil
+	}
+	idx := gcp.Index(validator)
+	if idx < 0 {
+		return nil, nil
+	}
+	return gcp.SortedGuardians[idx].Pubkey, nil
+}
+
+// vrfEligibleValidators returns the validators of the set with a guardian key registered as of
+// the given block, which are the ones able to prove the seed chain. All the validators are
+// eligible if none of them has a key.
+func vrfEligibleValidators(ledger core.Ledger, blockHash common.Hash, valSet *core.ValidatorSet) *core.ValidatorSet {
+	eligible := core.NewValidatorSet()
+	for _, v := range valSet.Validators() {
+		pubkey, err := vrfKey(ledger, blockHash, v.ID())
+		if err != nil {
+			panic(err)
+		}
+		if pubkey != nil {
+			eligible.AddValidator(v)
+		}
+	}
+	if eligible.Size() == 0 {
+		return valSet
+	}
+	return eligible
+}
+
+// selectProposerBySeed selects the proposer of the epoch among the validators, with a
+// probability proportional to their stake, from the seed of a block.
+func selectProposerBySeed(valSet *core.ValidatorSet, seed common.Hash, epoch uint64) core.Validator {
+	if valSet.Size() == 0 {
+		panic("No validators have been added")
+	}
+
+	epochBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(epochBytes, epoch)
+	r := new(big.Int).SetBytes(crypto.Keccak256(seed[:], epochBytes))
+	r.Mod(r, valSet.TotalStake())
+
+	curr := new(big.Int)
+	for _, v := range valSet.Validators() {
+		curr.Add(curr, v.Stake)
+		if r.Cmp(curr) < 0 {
+			return v
+		}
+	}
+
+	// Should not reach here.
+	panic("Failed to select a validator from the seed")
+}
+
+// chainProvider is implemented by the consensus engines giving access to their chain, which the
+// VRF proposer selection needs to read the seed of the blocks.
+type chainProvider interface {
+	Chain() *blockchain.Chain
+}
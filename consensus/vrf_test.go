@@ -0,0 +1,105 @@
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto/bls"
+)
+
+// gcpLedger is a ledger serving a fixed guardian candidate pool.
+type gcpLedger struct {
+	core.Ledger
+	gcp *core.GuardianCandidatePool
+}
+
+func (l *gcpLedger) GetGuardianCandidatePool(blockHash common.Hash) (*core.GuardianCandidatePool, error) {
+	return l.gcp, nil
+}
+
+func TestSelectProposerBySeed(t *testing.T) {
+	assert := assert.New(t)
+
+	valSet := core.NewValidatorSet()
+	valSet.AddValidator(core.NewValidator("0x1111111111111111111111111111111111111111", big.NewInt(100)))
+	valSet.AddValidator(core.NewValidator("0x2222222222222222222222222222222222222222", big.NewInt(300)))
+	seed := common.HexToHash("0x0123")
+
+	// The selection is deterministic, and proportional to the stake
+	counts := map[common.Address]int{}
+	for epoch := uint64(0); epoch < 4000; epoch++ {
+		proposer := selectProposerBySeed(valSet, seed, epoch)
+		assert.Equal(proposer, selectProposerBySeed(valSet, seed, epoch))
+		counts[proposer.ID()]++
+	}
+	low := counts[common.HexToAddress("0x1111111111111111111111111111111111111111")]
+	high := counts[common.HexToAddress("0x2222222222222222222222222222222222222222")]
+	assert.Equal(4000, low+high)
+	assert.InDelta(1000, low, 150)
+
+	// Another seed selects other proposers
+	differ := false
+	for epoch := uint64(0); epoch < 20; epoch++ {
+		if selectProposerBySeed(valSet, seed, epoch) != selectProposerBySeed(valSet, common.HexToHash("0x0456"), epoch) {
+			differ = true
+		}
+	}
+	assert.True(differ)
+}
+
+func TestValidateVRFProof(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID := "test_vrf_proof"
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeVRFProposer: 10}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	key, err := bls.RandKey()
+	require.Nil(err)
+	otherKey, err := bls.RandKey()
+	require.Nil(err)
+	proposer := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	ledger := &gcpLedger{gcp: &core.GuardianCandidatePool{SortedGuardians: []*core.Guardian{
+		{StakeHolder: &core.StakeHolder{Holder: proposer}, Pubkey: key.PublicKey()},
+	}}}
+
+	parent := &core.ExtendedBlock{Block: core.NewBlock()}
+	parent.ChainID = chainID
+	parent.Height = 9
+	block := core.NewBlock()
+	block.ChainID = chainID
+	block.Height = 10
+	block.Proposer = proposer
+
+	input := VRFInput(chainID, block.Height, BlockSeed(parent.BlockHeader))
+	block.VRFProof = key.Sign(input).ToBytes()
+	assert.Nil(ValidateVRFProof(ledger, block, parent))
+	assert.NotEqual(block.Hash(), BlockSeed(block.BlockHeader))
+
+	// The proof must be signed with the registered key, over the seed of the parent
+	block.VRFProof = otherKey.Sign(input).ToBytes()
+	assert.NotNil(ValidateVRFProof(ledger, block, parent))
+	block.VRFProof = key.Sign(VRFInput(chainID, block.Height, common.HexToHash("0x01"))).ToBytes()
+	assert.NotNil(ValidateVRFProof(ledger, block, parent))
+	block.VRFProof = nil
+	assert.NotNil(ValidateVRFProof(ledger, block, parent))
+
+	// The proposers without guardian key do not prove the seed
+	block.Proposer = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	assert.Nil(ValidateVRFProof(ledger, block, parent))
+	block.VRFProof = key.Sign(input).ToBytes()
+	assert.NotNil(ValidateVRFProof(ledger, block, parent))
+
+	// No proof before the upgrade
+	block.Proposer = proposer
+	block.Height = 9
+	parent.Height = 8
+	assert.NotNil(ValidateVRFProof(ledger, block, parent))
+	block.VRFProof = nil
+	assert.Nil(ValidateVRFProof(ledger, block, parent))
+}
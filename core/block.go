@@ -4,11 +4,9 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
-	"math/big"
 
 	"github.com/thetatoken/theta/common"
-	"github.com/thetatoken/theta/common/result"
-	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/core/verifier"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store/trie"
 )
@@ -41,6 +39,11 @@ func (b *Block) String() string {
 	return fmt.Sprintf("Block{Header: %v, Txs: %v}", b.BlockHeader, txs)
 }
 
+// Body returns the body of the block.
+func (b *Block) Body() *BlockBody {
+	return &BlockBody{Txs: b.Txs}
+}
+
 // AddTxs adds transactions to the block and update transaction root hash.
 func (b *Block) AddTxs(txs []common.Bytes) {
 	b.Txs = append(b.Txs, txs...)
@@ -65,102 +68,22 @@ func calculateRootHash(items []common.Bytes) common.Hash {
 }
 
 // BlockHeader contains the essential information of a block.
-type BlockHeader struct {
-	ChainID     string
-	Epoch       uint64
-	Height      uint64
-	Parent      common.Hash
-	HCC         CommitCertificate
-	TxHash      common.Hash
-	ReceiptHash common.Hash
-	Bloom       Bloom
-	StateHash   common.Hash
-	Timestamp   *big.Int
-	Proposer    common.Address
-	Signature   *crypto.Signature
-
-	hash common.Hash // Cache of calculated hash.
-}
-
-// Hash of header.
-func (h *BlockHeader) Hash() common.Hash {
-	if h == nil {
-		return common.Hash{}
-	}
-	if h.hash.IsEmpty() {
-		h.hash = h.calculateHash()
-	}
-	return h.hash
-}
-
-// UpdateHash recalculate hash of header.
-func (h *BlockHeader) UpdateHash() common.Hash {
-	if h == nil {
-		return common.Hash{}
-	}
-	h.hash = h.calculateHash()
-	return h.hash
-}
-
-func (h *BlockHeader) calculateHash() common.Hash {
-	raw, _ := rlp.EncodeToBytes(h)
-	return crypto.Keccak256Hash(raw)
-}
-
-func (h *BlockHeader) CalculateHash() common.Hash {
-	return h.calculateHash()
-}
-
-func (h *BlockHeader) String() string {
-	return fmt.Sprintf("{ChainID: %v, Epoch: %d, Hash: %v. Parent: %v, HCC: %v, Height: %v, TxHash: %v, StateHash: %v, Timestamp: %v, Proposer: %s}",
-		h.ChainID, h.Epoch, h.Hash().Hex(), h.Parent.Hex(), h.HCC, h.Height, h.TxHash.Hex(), h.StateHash.Hex(), h.Timestamp, h.Proposer)
-}
-
-// SignBytes returns raw bytes to be signed.
-func (h *BlockHeader) SignBytes() common.Bytes {
-	r := BlockHeader{
-		ChainID:     h.ChainID,
-		Epoch:       h.Epoch,
-		Height:      h.Height,
-		Parent:      h.Parent,
-		HCC:         h.HCC,
-		TxHash:      h.TxHash,
-		ReceiptHash: h.ReceiptHash,
-		Bloom:       h.Bloom,
-		StateHash:   h.StateHash,
-		Timestamp:   h.Timestamp,
-		Proposer:    h.Proposer,
-	}
-	raw, _ := rlp.EncodeToBytes(r)
-	return raw
-}
+type BlockHeader = verifier.BlockHeader
 
-// SetSignature sets given signature in header.
-func (h *BlockHeader) SetSignature(sig *crypto.Signature) {
-	h.Signature = sig
+// BlockBody contains the transactions of a block. It is stored and exchanged separately from
+// the header, so that the headers can be synced and kept without the bodies.
+type BlockBody struct {
+	Txs []common.Bytes `json:"transactions"`
 }
 
-// Validate checks the header is legitimate.
-func (h *BlockHeader) Validate() result.Result {
-	if h.Parent.IsEmpty() {
-		return result.Error("Parent is empty")
-	}
-	if h.HCC.BlockHash.IsEmpty() {
-		return result.Error("HCC is empty")
-	}
-	if h.Timestamp == nil {
-		return result.Error("Timestamp is missing")
-	}
-	if h.Proposer.IsEmpty() {
-		return result.Error("Proposer is not specified")
-	}
-	if h.Signature == nil || h.Signature.IsEmpty() {
-		return result.Error("Block is not signed")
-	}
-	if !h.Signature.Verify(h.SignBytes(), h.Proposer) {
-		return result.Error("Signature verification failed")
+// AssembleBlock creates the block from its header and its body, which needs to match the
+// transaction root hash of the header.
+func AssembleBlock(header *BlockHeader, body *BlockBody) (*Block, error) {
+	if txHash := calculateRootHash(body.Txs); txHash != header.TxHash {
+		return nil, fmt.Errorf("Block body does not match the header, tx hash: %v, expected: %v",
+			txHash.Hex(), header.TxHash.Hex())
 	}
-	return result.OK
+	return &Block{BlockHeader: header, Txs: body.Txs}, nil
 }
 
 type BlockStatus byte
@@ -36,3 +36,20 @@ func TestCreateTestBlock(t *testing.T) {
 
 	assert.Equal(b11.Hash(), b12.Hash())
 }
+
+func TestAssembleBlock(t *testing.T) {
+	assert := assert.New(t)
+
+	block := NewBlock()
+	block.Epoch = 1
+	block.AddTxs([]common.Bytes{common.Bytes("tx1"), common.Bytes("tx2")})
+
+	assembled, err := AssembleBlock(block.BlockHeader, block.Body())
+	assert.Nil(err)
+	assert.Equal(block.Hash(), assembled.Hash())
+	assert.Equal(block.Txs, assembled.Txs)
+
+	// The body of another block is rejected
+	_, err = AssembleBlock(block.BlockHeader, &BlockBody{Txs: []common.Bytes{common.Bytes("tx1")}})
+	assert.NotNil(err)
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+//
+// BridgeBatch commits to the outbound messages of a block with the Merkle root of their hashes.
+// The validators of the source chain co-sign the batches of the finalized blocks, and the
+// destination chain accepts the messages of a batch signed by more than 2/3 of their stake.
+//
+type BridgeBatch struct {
+	SourceChainID string      `json:"source_chain_id"`
+	Height        uint64      `json:"height"`
+	Root          common.Hash `json:"root"`
+	NumMessages   uint64      `json:"num_messages"`
+}
+
+// SignBytes returns the bytes the validators co-sign. They are prefixed to never collide with
+// the sign bytes of a transaction or a vote.
+func (b *BridgeBatch) SignBytes() common.Bytes {
+	raw, err := rlp.EncodeToBytes([]interface{}{"ThetaBridgeBatch", b})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to encode bridge batch: %v", err))
+	}
+	return raw
+}
+
+func (b *BridgeBatch) String() string {
+	return fmt.Sprintf("BridgeBatch{%v, height: %v, root: %v, messages: %v}",
+		b.SourceChainID, b.Height, b.Root.Hex(), b.NumMessages)
+}
+
+// BridgeSignature is the co-signature of a validator on a bridge batch
+type BridgeSignature struct {
+	Address   common.Address    `json:"address"`
+	Signature *crypto.Signature `json:"signature"`
+}
+
+// VerifyBridgeSignatures checks the batch is co-signed by the validators holding more than 2/3
+// of the stake of the validator set.
+func VerifyBridgeSignatures(batch *BridgeBatch, sigs []BridgeSignature, validators *ValidatorSet) error {
+	signBytes := batch.SignBytes()
+	signed := make(map[common.Address]bool)
+	votes := []Vote{}
+	for _, sig := range sigs {
+		if signed[sig.Address] {
+			return fmt.Errorf("Duplicated signature from %v", sig.Address.Hex())
+		}
+		if sig.Signature == nil || !sig.Signature.Verify(signBytes, sig.Address) {
+			return fmt.Errorf("Invalid signature from %v", sig.Address.Hex())
+		}
+		signed[sig.Address] = true
+		votes = append(votes, Vote{ID: sig.Address})
+	}
+	if !validators.HasMajorityVotes(votes) {
+		return errors.New("The bridge batch is not signed by a validator supermajority")
+	}
+	return nil
+}
+
+// ------------------------------- Merkle Tree -----------------------------------
+
+// BridgeMerkleRoot returns the root of the binary Merkle tree of the leaves. A level with an
+// odd number of nodes pairs its last node with itself. The root of an empty tree is empty.
+func BridgeMerkleRoot(leaves []common.Hash) common.Hash {
+	if len(leaves) == 0 {
+		return common.Hash{}
+	}
+	level := leaves
+	for len(level) > 1 {
+		level = nextBridgeMerkleLevel(level)
+	}
+	return level[0]
+}
+
+// BridgeMerkleProof returns the sibling hashes on the path from the leaf at the index to the
+// root, starting from the bottom.
+func BridgeMerkleProof(leaves []common.Hash, index uint64) ([]common.Hash, error) {
+	if index >= uint64(len(leaves)) {
+		return nil, fmt.Errorf("Leaf index %v out of range, there are %v leaves", index, len(leaves))
+	}
+	proof := []common.Hash{}
+	level := leaves
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling >= uint64(len(level)) {
+			sibling = index
+		}
+		proof = append(proof, level[sibling])
+		level = nextBridgeMerkleLevel(level)
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyBridgeMerkleProof checks the leaf is at the index of the Merkle tree with the root.
+func VerifyBridgeMerkleProof(leaf common.Hash, index uint64, proof []common.Hash, root common.Hash) bool {
+	hash := leaf
+	for _, sibling := range proof {
+		if index%2 == 0 {
+			hash = crypto.Keccak256Hash(hash[:], sibling[:])
+		} else {
+			hash = crypto.Keccak256Hash(sibling[:], hash[:])
+		}
+		index /= 2
+	}
+	return index == 0 && hash == root
+}
+
+func nextBridgeMerkleLevel(level []common.Hash) []common.Hash {
+	next := make([]common.Hash, (len(level)+1)/2)
+	for i := range next {
+		left := level[2*i]
+		right := left
+		if 2*i+1 < len(level) {
+			right = level[2*i+1]
+		}
+		next[i] = crypto.Keccak256Hash(left[:], right[:])
+	}
+	return next
+}
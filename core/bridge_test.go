@@ -0,0 +1,75 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+)
+
+func TestBridgeMerkleProof(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(common.Hash{}, BridgeMerkleRoot(nil))
+
+	for n := 1; n <= 7; n++ {
+		leaves := []common.Hash{}
+		for i := 0; i < n; i++ {
+			leaves = append(leaves, crypto.Keccak256Hash([]byte{byte(i)}))
+		}
+		root := BridgeMerkleRoot(leaves)
+		for i := 0; i < n; i++ {
+			proof, err := BridgeMerkleProof(leaves, uint64(i))
+			assert.Nil(err)
+			assert.True(VerifyBridgeMerkleProof(leaves[i], uint64(i), proof, root), "%v leaves, index %v", n, i)
+			if n > 1 {
+				other := leaves[(i+1)%n]
+				assert.False(VerifyBridgeMerkleProof(other, uint64(i), proof, root), "%v leaves, index %v", n, i)
+			}
+		}
+		_, err := BridgeMerkleProof(leaves, uint64(n))
+		assert.NotNil(err)
+	}
+}
+
+func TestVerifyBridgeSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	var privKeys []*crypto.PrivateKey
+	validators := NewValidatorSet()
+	for i := 0; i < 3; i++ {
+		privKey, _, _ := crypto.GenerateKeyPair()
+		privKeys = append(privKeys, privKey)
+		validators.AddValidator(NewValidator(privKey.PublicKey().Address().Hex(), big.NewInt(100)))
+	}
+
+	batch := &BridgeBatch{
+		SourceChainID: "testchain_bridge",
+		Height:        20,
+		Root:          common.HexToHash("a1"),
+		NumMessages:   2,
+	}
+	cosign := func(keys ...*crypto.PrivateKey) []BridgeSignature {
+		sigs := []BridgeSignature{}
+		for _, key := range keys {
+			sig, _ := key.Sign(batch.SignBytes())
+			sigs = append(sigs, BridgeSignature{Address: key.PublicKey().Address(), Signature: sig})
+		}
+		return sigs
+	}
+
+	assert.Nil(VerifyBridgeSignatures(batch, cosign(privKeys...), validators))
+
+	// 2/3 of the stake is not a supermajority
+	assert.NotNil(VerifyBridgeSignatures(batch, cosign(privKeys[0], privKeys[1]), validators))
+
+	// A signature cannot be counted twice
+	assert.NotNil(VerifyBridgeSignatures(batch, cosign(privKeys[0], privKeys[1], privKeys[1]), validators))
+
+	// The signatures are bound to the batch
+	sigs := cosign(privKeys...)
+	batch.Height = 21
+	assert.NotNil(VerifyBridgeSignatures(batch, sigs, validators))
+}
@@ -0,0 +1,8 @@
+package core
+
+import (
+	"github.com/thetatoken/theta/core/verifier"
+)
+
+// FinalityCertificate proves a block is finalized. See verifier.FinalityCertificate.
+type FinalityCertificate = verifier.FinalityCertificate
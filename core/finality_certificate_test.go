@@ -0,0 +1,122 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+)
+
+func TestFinalityCertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "testchain_finality"
+	var privKeys []*crypto.PrivateKey
+	validators := NewValidatorSet()
+	for i := 0; i < 4; i++ {
+		privKey, _, _ := crypto.GenerateKeyPair()
+		privKeys = append(privKeys, privKey)
+		validators.AddValidator(NewValidator(privKey.PublicKey().Address().Hex(), big.NewInt(100)))
+	}
+
+	newHeader := func(parent common.Hash, height uint64) *BlockHeader {
+		header := &BlockHeader{
+			ChainID:   chainID,
+			Epoch:     height,
+			Height:    height,
+			Parent:    parent,
+			HCC:       CommitCertificate{BlockHash: parent},
+			Timestamp: big.NewInt(int64(height)),
+			Proposer:  DefaultSigner.PublicKey().Address(),
+		}
+		header.Signature, _ = DefaultSigner.Sign(header.SignBytes())
+		return header
+	}
+	newVotes := func(header *BlockHeader, numVoters int) []Vote {
+		votes := []Vote{}
+		for _, privKey := range privKeys[:numVoters] {
+			vote := Vote{
+				Block:   header.Hash(),
+				Height:  header.Height,
+				Epoch:   header.Epoch + 1,
+				ID:      privKey.PublicKey().Address(),
+				ChainID: chainID,
+			}
+			vote.Signature, _ = privKey.Sign(vote.SignBytes())
+			votes = append(votes, vote)
+		}
+		return votes
+	}
+
+	checkpoint := newHeader(common.HexToHash("a1"), 100)
+	middle := newHeader(checkpoint.Hash(), 101)
+	block := newHeader(middle.Hash(), 102)
+	child := newHeader(block.Hash(), 103)
+	newCertificate := func() *FinalityCertificate {
+		return &FinalityCertificate{
+			ChainID:         chainID,
+			Block:           block,
+			Child:           child,
+			BlockVotes:      newVotes(block, 3),
+			ChildVotes:      newVotes(child, 3),
+			BlockValidators: validators.Validators(),
+			ChildValidators: validators.Validators(),
+			HeaderChain:     []*BlockHeader{checkpoint, middle},
+		}
+	}
+
+	fc := newCertificate()
+	assert.Nil(fc.Verify(chainID, fc.BlockValidatorSet(), fc.ChildValidatorSet()))
+	assert.Equal(checkpoint, fc.Checkpoint())
+	assert.True(fc.Finalizes(block.Hash()))
+	assert.True(fc.Finalizes(middle.Hash()))
+	assert.False(fc.Finalizes(child.Hash()))
+
+	// Not on another chain
+	assert.NotNil(fc.Verify("otherchain", validators, validators))
+
+	// Not with the votes of 2/4 of the stake
+	fc = newCertificate()
+	fc.ChildVotes = newVotes(child, 2)
+	assert.NotNil(fc.Verify(chainID, validators, validators))
+
+	// Not by repeating a vote
+	fc = newCertificate()
+	fc.BlockVotes = append(newVotes(block, 2), newVotes(block, 1)...)
+	assert.NotNil(fc.Verify(chainID, validators, validators))
+
+	// Not with the votes for another block
+	fc = newCertificate()
+	fc.ChildVotes = newVotes(block, 3)
+	assert.NotNil(fc.Verify(chainID, validators, validators))
+
+	// Not with the votes of another validator set
+	otherValidators := NewValidatorSet()
+	for i := 0; i < 4; i++ {
+		_, pubKey, _ := crypto.GenerateKeyPair()
+		otherValidators.AddValidator(NewValidator(pubKey.Address().Hex(), big.NewInt(100)))
+	}
+	fc = newCertificate()
+	assert.NotNil(fc.Verify(chainID, otherValidators, otherValidators))
+
+	// Not if the header chain is broken or does not start at a checkpoint
+	fc = newCertificate()
+	fc.HeaderChain = []*BlockHeader{checkpoint}
+	assert.NotNil(fc.Verify(chainID, validators, validators))
+	fc = newCertificate()
+	fc.HeaderChain = []*BlockHeader{middle}
+	assert.NotNil(fc.Verify(chainID, validators, validators))
+
+	// A checkpoint needs no header chain
+	fc = &FinalityCertificate{
+		ChainID:    chainID,
+		Block:      checkpoint,
+		Child:      middle,
+		BlockVotes: newVotes(checkpoint, 3),
+		ChildVotes: newVotes(middle, 3),
+	}
+	assert.Nil(fc.Verify(chainID, validators, validators))
+	assert.Equal(checkpoint, fc.Checkpoint())
+}
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/thetatoken/theta/core/verifier"
+)
+
+// Upgrade names a protocol change activated by a hard fork, e.g. a new validation rule, a new
+// transaction type or a new consensus parameter. The code of an upgrade ships dormant, and is
+// only enabled from the block height the upgrade is scheduled at for the chain.
+type Upgrade string
+
+const (
+	// UpgradeFeeMarket enables the base fee, which adjusts the min transaction fees to the
+	// block fullness.
+	UpgradeFeeMarket Upgrade = "feeMarket"
+
+	// UpgradeOnChainGovernance enables the governance proposals, which the stake holders vote on
+	// to change the chain parameters at a future height.
+	UpgradeOnChainGovernance Upgrade = "onChainGovernance"
+
+	// UpgradeBridge enables the bridge transactions, which send coins to and receive coins from
+	// the counterpart chains.
+	UpgradeBridge Upgrade = "bridge"
+
+	// UpgradeSponsoredFees enables the sponsored transactions, whose fee is paid by a third-party
+	// sponsor instead of their sender.
+	UpgradeSponsoredFees Upgrade = "sponsoredFees"
+
+	// UpgradeBatchSendFee charges the SendTx paying several outputs a min fee growing with the
+	// number of outputs, at a discount to paying each output in a separate transaction.
+	UpgradeBatchSendFee Upgrade = "batchSendFee"
+
+	// UpgradeTimeLock enables the time-locked transfers, whose coins are released to the
+	// recipient at a height or a time, and records the chain time in the ledger state.
+	UpgradeTimeLock Upgrade = "timeLock"
+
+	// UpgradeSupplyAccounting records the TFuel minted and burned by each block, and the total
+	// TFuel supply, in the ledger state.
+	UpgradeSupplyAccounting Upgrade = "supplyAccounting"
+
+	// UpgradeRewardDistribution distributes the block reward and the transaction fees of each
+	// block to the proposer, and to the validator and guardian stakers proportional to stake.
+	UpgradeRewardDistribution Upgrade = "rewardDistribution"
+
+	// UpgradeDelegation enables the delegation of stake to the validator candidates, which counts
+	// for their voting power and is rewarded per delegation.
+	UpgradeDelegation Upgrade = "delegation"
+
+	// UpgradeUnbondingQueue moves the withdrawn stakes and the undelegated stakes to the unbonding
+	// queue, where they wait for the unbonding period of the chain params before they are returned.
+	UpgradeUnbondingQueue Upgrade = "unbondingQueue"
+
+	// UpgradeCanonicalTxOrder requires the regular transactions of a block to be in the canonical
+	// order, by fee priority, then by sender and sequence, then by hash.
+	UpgradeCanonicalTxOrder Upgrade = "canonicalTxOrder"
+
+	// UpgradeValidatorSetHash commits the hash of the next validator set to the header of each
+	// block updating the validator set, so that the light clients can track the validator set.
+	UpgradeValidatorSetHash Upgrade = "validatorSetHash"
+
+	// UpgradeCompactHCC encodes the votes of the HCC in the block headers as a bitmap of the
+	// validators plus their signatures, instead of the full votes.
+	UpgradeCompactHCC Upgrade = "compactHCC"
+
+	// UpgradeVRFProposer selects the proposer of each epoch from a seed chained through the VRF
+	// proofs of the blocks, so that the proposers can not be predicted beyond the last finalized
+	// block.
+	UpgradeVRFProposer Upgrade = "vrfProposer"
+
+	// UpgradeChainParams enables the governance transactions updating the chain params, and
+	// enforces the block limits of the chain params on the regular transactions of each block.
+	UpgradeChainParams Upgrade = "chainParams"
+
+	// UpgradeGuardianStake enables the DepositStakeV2 transactions, which register the BLS key
+	// the guardian signs its votes with along with its stake.
+	UpgradeGuardianStake Upgrade = "guardianStake"
+
+	// UpgradeSmartContracts enables the smart contract transactions, executed by the EVM, and
+	// stores the receipts of their execution per block.
+	UpgradeSmartContracts Upgrade = "smartContracts"
+)
+
+// KnownUpgrades lists the upgrades implemented by the node.
+var KnownUpgrades = []Upgrade{
+	UpgradeFeeMarket,
+	UpgradeOnChainGovernance,
+	UpgradeBridge,
+	UpgradeSponsoredFees,
+	UpgradeBatchSendFee,
+	UpgradeTimeLock,
+	UpgradeSupplyAccounting,
+	UpgradeRewardDistribution,
+	UpgradeDelegation,
+	UpgradeUnbondingQueue,
+	UpgradeCanonicalTxOrder,
+	UpgradeValidatorSetHash,
+	UpgradeCompactHCC,
+	UpgradeVRFProposer,
+	UpgradeChainParams,
+	UpgradeGuardianStake,
+	UpgradeSmartContracts,
+}
+
+//
+// ForkSchedule holds the activation heights of the upgrades of a chain
+//
+type ForkSchedule struct {
+	heights     map[Upgrade]uint64
+	forkHeights []uint64 // distinct activation heights in increasing order
+}
+
+// NewForkSchedule creates a fork schedule activating each upgrade at the given height.
+func NewForkSchedule(heights map[Upgrade]uint64) *ForkSchedule {
+	fs := &ForkSchedule{
+		heights: make(map[Upgrade]uint64),
+	}
+	distinct := make(map[uint64]bool)
+	for upgrade, height := range heights {
+		fs.heights[upgrade] = height
+		if !distinct[height] {
+			distinct[height] = true
+			fs.forkHeights = append(fs.forkHeights, height)
+		}
+	}
+	sort.Slice(fs.forkHeights, func(i, j int) bool {
+		return fs.forkHeights[i] < fs.forkHeights[j]
+	})
+	return fs
+}
+
+// ParseForkSchedule parses a comma separated list of upgrade=height pairs, e.g. "feeMarket=1000".
+// Only the upgrades known to the node can be scheduled.
+func ParseForkSchedule(str string) (*ForkSchedule, error) {
+	heights := make(map[Upgrade]uint64)
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid fork %v, expecting upgrade=height", item)
+		}
+		upgrade := Upgrade(strings.TrimSpace(parts[0]))
+		if !isKnownUpgrade(upgrade) {
+			return nil, fmt.Errorf("Unknown upgrade: %v", upgrade)
+		}
+		if _, ok := heights[upgrade]; ok {
+			return nil, fmt.Errorf("Upgrade %v is scheduled more than once", upgrade)
+		}
+		height, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid height for upgrade %v: %v", upgrade, parts[1])
+		}
+		heights[upgrade] = height
+	}
+	return NewForkSchedule(heights), nil
+}
+
+func isKnownUpgrade(upgrade Upgrade) bool {
+	for _, known := range KnownUpgrades {
+		if upgrade == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ActivationHeight returns the height the upgrade is activated at, and false if the upgrade is
+// not scheduled.
+func (fs *ForkSchedule) ActivationHeight(upgrade Upgrade) (uint64, bool) {
+	height, ok := fs.heights[upgrade]
+	return height, ok
+}
+
+// ForkNumber returns the number of hard forks activated at or below the height. The upgrades
+// scheduled at the same height belong to the same hard fork.
+func (fs *ForkSchedule) ForkNumber(height uint64) uint64 {
+	return uint64(sort.Search(len(fs.forkHeights), func(i int) bool {
+		return fs.forkHeights[i] > height
+	}))
+}
+
+// Rules returns the set of the upgrades active at the height.
+func (fs *ForkSchedule) Rules(height uint64) *Rules {
+	rules := &Rules{
+		Height:     height,
+		ForkNumber: fs.ForkNumber(height),
+		active:     make(map[Upgrade]bool),
+	}
+	for upgrade, activationHeight := range fs.heights {
+		if activationHeight <= height {
+			rules.active[upgrade] = true
+		}
+	}
+	return rules
+}
+
+//
+// Rules is the set of the upgrades active at a block height, which the block validation and
+// the transaction execution consult to enable the dormant code paths
+//
+type Rules struct {
+	Height     uint64
+	ForkNumber uint64
+
+	active map[Upgrade]bool
+}
+
+// IsActive returns whether the upgrade is active.
+func (r *Rules) IsActive(upgrade Upgrade) bool {
+	return r.active[upgrade]
+}
+
+var (
+	forkSchedulesMu sync.RWMutex
+	forkSchedules   = map[string]*ForkSchedule{
+		MainnetChainID: NewForkSchedule(nil),
+	}
+)
+
+// SetForkSchedule sets the fork schedule of the chain.
+func SetForkSchedule(chainID string, schedule *ForkSchedule) {
+	forkSchedulesMu.Lock()
+	defer forkSchedulesMu.Unlock()
+	forkSchedules[chainID] = schedule
+	verifier.SetForkHeights(chainID, schedule.forkHeights)
+}
+
+// GetForkSchedule returns the fork schedule of the chain, which is empty if no upgrade was
+// scheduled for the chain.
+func GetForkSchedule(chainID string) *ForkSchedule {
+	forkSchedulesMu.RLock()
+	defer forkSchedulesMu.RUnlock()
+	if schedule, ok := forkSchedules[chainID]; ok {
+		return schedule
+	}
+	return NewForkSchedule(nil)
+}
+
+// RulesAt returns the set of the upgrades of the chain active at the height.
+func RulesAt(chainID string, height uint64) *Rules {
+	return GetForkSchedule(chainID).Rules(height)
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForkSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	schedule := NewForkSchedule(map[Upgrade]uint64{
+		"upgradeA": 100,
+		"upgradeB": 100,
+		"upgradeC": 300,
+	})
+
+	height, ok := schedule.ActivationHeight("upgradeC")
+	assert.True(ok)
+	assert.Equal(uint64(300), height)
+	_, ok = schedule.ActivationHeight(UpgradeFeeMarket)
+	assert.False(ok)
+
+	// The upgrades activated at the same height belong to the same hard fork
+	assert.Equal(uint64(0), schedule.ForkNumber(99))
+	assert.Equal(uint64(1), schedule.ForkNumber(100))
+	assert.Equal(uint64(1), schedule.ForkNumber(299))
+	assert.Equal(uint64(2), schedule.ForkNumber(300))
+
+	rules := schedule.Rules(99)
+	assert.False(rules.IsActive("upgradeA"))
+	rules = schedule.Rules(150)
+	assert.Equal(uint64(1), rules.ForkNumber)
+	assert.True(rules.IsActive("upgradeA"))
+	assert.True(rules.IsActive("upgradeB"))
+	assert.False(rules.IsActive("upgradeC"))
+	assert.False(rules.IsActive(UpgradeFeeMarket))
+
+	// The chains without a schedule have all the upgrades dormant
+	assert.False(RulesAt("testchain_no_schedule", 1000000).IsActive(UpgradeFeeMarket))
+}
+
+func TestParseForkSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	schedule, err := ParseForkSchedule(" feeMarket = 1000 ,")
+	assert.Nil(err)
+	height, ok := schedule.ActivationHeight(UpgradeFeeMarket)
+	assert.True(ok)
+	assert.Equal(uint64(1000), height)
+
+	schedule, err = ParseForkSchedule("")
+	assert.Nil(err)
+	assert.Equal(uint64(0), schedule.ForkNumber(1000000))
+
+	_, err = ParseForkSchedule("unknownUpgrade=1000")
+	assert.NotNil(err)
+	_, err = ParseForkSchedule("feeMarket")
+	assert.NotNil(err)
+	_, err = ParseForkSchedule("feeMarket=abc")
+	assert.NotNil(err)
+	_, err = ParseForkSchedule("feeMarket=1000,feeMarket=2000")
+	assert.NotNil(err)
+}
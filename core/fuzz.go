@@ -0,0 +1,99 @@
+// +build gofuzz
+
+package core
+
+import (
+	"github.com/thetatoken/theta/rlp"
+)
+
+// The entry points for the go-fuzz tool on the decoding of the consensus messages received from
+// the peers, e.g.
+//
+//	go-fuzz-build -func FuzzBlock github.com/thetatoken/theta/core
+//	go-fuzz -bin core-fuzz.zip -workdir fuzz/block
+//
+// They return 1 for the inputs which decode, so that go-fuzz prioritizes them, and 0 otherwise.
+// The decoded messages are encoded again, and must decode to the same message.
+
+// FuzzBlock fuzzes the decoding of the blocks.
+func FuzzBlock(data []byte) int {
+	block := NewBlock()
+	if err := rlp.DecodeBytes(data, block); err != nil {
+		return 0
+	}
+	block.Validate()
+	block.Body()
+
+	decoded := NewBlock()
+	roundTrip(block, decoded)
+	if block.Hash() != decoded.Hash() {
+		panic("Block hash changed after re-encoding")
+	}
+	return 1
+}
+
+// FuzzBlockHeader fuzzes the decoding of the block headers.
+func FuzzBlockHeader(data []byte) int {
+	header := &BlockHeader{}
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return 0
+	}
+	header.Validate()
+
+	decoded := &BlockHeader{}
+	roundTrip(header, decoded)
+	if header.Hash() != decoded.Hash() {
+		panic("Block header hash changed after re-encoding")
+	}
+	return 1
+}
+
+// FuzzVote fuzzes the decoding of the votes, and the verification of their signatures.
+func FuzzVote(data []byte) int {
+	vote := Vote{}
+	if err := rlp.DecodeBytes(data, &vote); err != nil {
+		return 0
+	}
+	vote.Validate()
+
+	decoded := Vote{}
+	roundTrip(&vote, &decoded)
+	if vote.Block != decoded.Block || vote.Height != decoded.Height || vote.Epoch != decoded.Epoch || vote.ID != decoded.ID {
+		panic("Vote changed after re-encoding")
+	}
+	return 1
+}
+
+// FuzzProposal fuzzes the decoding of the proposals, which carry a block and a vote set.
+func FuzzProposal(data []byte) int {
+	proposal := &Proposal{}
+	if err := rlp.DecodeBytes(data, proposal); err != nil {
+		return 0
+	}
+	if proposal.Block != nil {
+		proposal.Block.Validate()
+	}
+	if proposal.Votes != nil {
+		proposal.Votes.Validate()
+		proposal.Votes.UniqueVoter()
+	}
+
+	decoded := &Proposal{}
+	roundTrip(proposal, decoded)
+	if (proposal.Votes == nil) != (decoded.Votes == nil) ||
+		(proposal.Votes != nil && proposal.Votes.Size() != decoded.Votes.Size()) {
+		panic("Proposal votes changed after re-encoding")
+	}
+	return 1
+}
+
+// roundTrip encodes the decoded message and decodes it into the given value.
+func roundTrip(message interface{}, decoded interface{}) {
+	encoded, err := rlp.EncodeToBytes(message)
+	if err != nil {
+		panic(err)
+	}
+	if err := rlp.DecodeBytes(encoded, decoded); err != nil {
+		panic(err)
+	}
+}
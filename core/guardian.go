@@ -0,0 +1,338 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core/verifier"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/crypto/bls"
+	"github.com/thetatoken/theta/rlp"
+)
+
+const (
+	// CheckpointInterval is the number of blocks between two checkpoints finalized by the guardians
+	CheckpointInterval uint64 = verifier.CheckpointInterval
+)
+
+var (
+	MinGuardianStakeDeposit *big.Int
+)
+
+func init() {
+	// Each guardian stake deposit needs to be at least 1,000 Theta
+	MinGuardianStakeDeposit = new(big.Int).Mul(new(big.Int).SetUint64(1000), new(big.Int).SetUint64(1000000000000000000))
+}
+
+// IsCheckpointHeight returns true if the block at the given height is a checkpoint
+func IsCheckpointHeight(height uint64) bool {
+	return verifier.IsCheckpointHeight(height)
+}
+
+//
+// ------- Guardian ------- //
+//
+
+// Guardian is a stake holder which has registered a BLS public key to sign the checkpoints
+type Guardian struct {
+	*StakeHolder
+	Pubkey *bls.PublicKey
+}
+
+func (g *Guardian) String() string {
+	return fmt.Sprintf("{holder: %v, pubkey: %v, stakes :%v}", g.Holder, g.Pubkey.ToBytes(), g.Stakes)
+}
+
+//
+// ------- GuardianCandidatePool ------- //
+//
+
+// GuardianCandidatePool holds the guardians sorted by holder address, so that the position of a
+// guardian in the pool is stable and can be used to index the aggregated votes.
+type GuardianCandidatePool struct {
+	SortedGuardians []*Guardian
+}
+
+// NewGuardianCandidatePool creates a new instance of GuardianCandidatePool
+func NewGuardianCandidatePool() *GuardianCandidatePool {
+	return &GuardianCandidatePool{
+		SortedGuardians: []*Guardian{},
+	}
+}
+
+// Len returns the number of guardians in the pool
+func (gcp *GuardianCandidatePool) Len() int {
+	return len(gcp.SortedGuardians)
+}
+
+// Hash returns the hash of the pool, which the aggregated votes commit to
+func (gcp *GuardianCandidatePool) Hash() common.Hash {
+	raw, err := rlp.EncodeToBytes(gcp)
+	if err != nil {
+		logger.Panic(err)
+	}
+	return crypto.Keccak256Hash(raw)
+}
+
+// Index returns the position of the guardian in the pool, or -1 if not found
+func (gcp *GuardianCandidatePool) Index(holder common.Address) int {
+	idx := gcp.search(holder)
+	if idx < len(gcp.SortedGuardians) && gcp.SortedGuardians[idx].Holder == holder {
+		return idx
+	}
+	return -1
+}
+
+// search returns the position the holder is at or would be inserted at
+func (gcp *GuardianCandidatePool) search(holder common.Address) int {
+	return sort.Search(len(gcp.SortedGuardians), func(i int) bool {
+		return bytes.Compare(gcp.SortedGuardians[i].Holder.Bytes(), holder.Bytes()) >= 0
+	})
+}
+
+// TotalStake returns the total stake of the guardians in the pool
+func (gcp *GuardianCandidatePool) TotalStake() *big.Int {
+	ret := new(big.Int).SetUint64(0)
+	for _, g := range gcp.SortedGuardians {
+		ret = new(big.Int).Add(ret, g.TotalStake())
+	}
+	return ret
+}
+
+// DepositStake deposits stake to the guardian, registering it with the given public key if needed
+func (gcp *GuardianCandidatePool) DepositStake(source common.Address, holder common.Address, amount *big.Int, pubkey *bls.PublicKey) (err error) {
+	if amount.Cmp(MinGuardianStakeDeposit) < 0 {
+		return fmt.Errorf("Insufficient stake: %v", amount)
+	}
+	if pubkey == nil {
+		return fmt.Errorf("Guardian BLS public key is required")
+	}
+
+	idx := gcp.search(holder)
+	if idx < len(gcp.SortedGuardians) && gcp.SortedGuardians[idx].Holder == holder {
+		guardian := gcp.SortedGuardians[idx]
+		if !guardian.Pubkey.Equals(pubkey) {
+			return fmt.Errorf("BLS public key mismatch for guardian: %v", holder)
+		}
+		return guardian.depositStake(source, amount)
+	}
+
+	newGuardian := &Guardian{
+		StakeHolder: newStakeHolder(holder, []*Stake{newStake(source, amount)}),
+		Pubkey:      pubkey,
+	}
+	gcp.SortedGuardians = append(gcp.SortedGuardians, nil)
+	copy(gcp.SortedGuardians[idx+1:], gcp.SortedGuardians[idx:])
+	gcp.SortedGuardians[idx] = newGuardian
+
+	return nil
+}
+
+// WithdrawStake withdraws the stake deposited by the source to the guardian
+func (gcp *GuardianCandidatePool) WithdrawStake(source common.Address, holder common.Address, currentHeight uint64) error {
+	idx := gcp.Index(holder)
+	if idx < 0 {
+		return fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	return gcp.SortedGuardians[idx].withdrawStake(source, currentHeight)
+}
+
+// UnbondStake removes the stake deposited by the source to the guardian from the pool, so it can
+// be held in the unbonding queue until it is returned. The guardian is removed from the pool once
+// it has no stake left.
+func (gcp *GuardianCandidatePool) UnbondStake(source common.Address, holder common.Address) (*Stake, error) {
+	idx := gcp.Index(holder)
+	if idx < 0 {
+		return nil, fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	guardian := gcp.SortedGuardians[idx]
+	stake, err := guardian.unbondStake(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(guardian.Stakes) == 0 {
+		gcp.SortedGuardians = append(gcp.SortedGuardians[:idx], gcp.SortedGuardians[idx+1:]...)
+	}
+	return stake, nil
+}
+
+// ReturnStakes returns the withdrawn stakes whose locking period has passed
+func (gcp *GuardianCandidatePool) ReturnStakes(currentHeight uint64) []*Stake {
+	returnedStakes := []*Stake{}
+
+	// need to iterate in the reverse order, since we may delete elemements
+	// from the slice while iterating through it
+	for gidx := len(gcp.SortedGuardians) - 1; gidx >= 0; gidx-- {
+		guardian := gcp.SortedGuardians[gidx]
+		for sidx := len(guardian.Stakes) - 1; sidx >= 0; sidx-- {
+			stake := guardian.Stakes[sidx]
+			if (stake.Withdrawn) && (currentHeight >= stake.ReturnHeight) {
+				logger.Printf("Guardian stake to be returned: source = %v, amount = %v", stake.Source, stake.Amount)
+				source := stake.Source
+				returnedStake, err := guardian.returnStake(source, currentHeight)
+				if err != nil {
+					logger.Errorf("Failed to return stake: %v, error: %v", source, err)
+					continue
+				}
+				returnedStakes = append(returnedStakes, returnedStake)
+			}
+		}
+
+		if len(guardian.Stakes) == 0 {
+			gcp.SortedGuardians = append(gcp.SortedGuardians[:gidx], gcp.SortedGuardians[gidx+1:]...)
+		}
+	}
+
+	return returnedStakes
+}
+
+//
+// ------- AggregatedVotes ------- //
+//
+
+// AggregatedVotes is the aggregation of the guardian signatures over a checkpoint. Multiplies[i]
+// is the number of times the signature of the i-th guardian of the pool has been aggregated,
+// since the aggregation trees built by different guardians can overlap.
+type AggregatedVotes struct {
+	Block      common.Hash // Hash of the checkpoint block
+	Gcp        common.Hash // Hash of the guardian candidate pool
+	Multiplies []uint32    // Multiplies of each guardian's signature
+	Signature  *bls.Signature
+}
+
+// NewAggregatedVotes creates an empty aggregation for the checkpoint
+func NewAggregatedVotes(block common.Hash, gcp *GuardianCandidatePool) *AggregatedVotes {
+	return &AggregatedVotes{
+		Block:      block,
+		Gcp:        gcp.Hash(),
+		Multiplies: make([]uint32, gcp.Len()),
+		Signature:  bls.NewAggregateSignature(),
+	}
+}
+
+func (a *AggregatedVotes) String() string {
+	return fmt.Sprintf("AggregatedVotes{Block: %s, Gcp: %s, Signers: %v/%v}",
+		a.Block.Hex(), a.Gcp.Hex(), a.NumSigners(), len(a.Multiplies))
+}
+
+// SignBytes returns raw bytes to be signed.
+func (a *AggregatedVotes) SignBytes() common.Bytes {
+	tmp := &AggregatedVotes{
+		Block: a.Block,
+		Gcp:   a.Gcp,
+	}
+	raw, _ := rlp.EncodeToBytes(tmp)
+	return raw
+}
+
+// Sign adds the signature of the i-th guardian of the pool
+func (a *AggregatedVotes) Sign(signer bls.Signer, signerIdx int) bool {
+	if signerIdx < 0 || signerIdx >= len(a.Multiplies) || a.Multiplies[signerIdx] > 0 {
+		return false
+	}
+	a.Multiplies[signerIdx] = 1
+	a.Signature.Aggregate(signer.Sign(a.SignBytes()))
+	return true
+}
+
+// NumSigners returns the number of distinct guardians in the aggregation
+func (a *AggregatedVotes) NumSigners() int {
+	count := 0
+	for _, m := range a.Multiplies {
+		if m > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Merge returns the aggregation of both votes, or nil if the other votes contain no new signer
+func (a *AggregatedVotes) Merge(b *AggregatedVotes) (*AggregatedVotes, error) {
+	if a.Block != b.Block || a.Gcp != b.Gcp || len(a.Multiplies) != len(b.Multiplies) {
+		return nil, errors.New("Cannot merge incompatible aggregated votes")
+	}
+
+	hasNewSigner := false
+	multiplies := make([]uint32, len(a.Multiplies))
+	for i := range a.Multiplies {
+		if a.Multiplies[i] == 0 && b.Multiplies[i] > 0 {
+			hasNewSigner = true
+		}
+		multiplies[i] = a.Multiplies[i] + b.Multiplies[i]
+		if multiplies[i] < a.Multiplies[i] {
+			return nil, errors.New("Signature multiplies overflow")
+		}
+	}
+	if !hasNewSigner {
+		return nil, nil
+	}
+
+	return &AggregatedVotes{
+		Block:      a.Block,
+		Gcp:        a.Gcp,
+		Multiplies: multiplies,
+		Signature:  a.Signature.Copy().Aggregate(b.Signature),
+	}, nil
+}
+
+// Validate checks the aggregated signature against the public keys of the signers
+func (a *AggregatedVotes) Validate(gcp *GuardianCandidatePool) error {
+	if len(a.Multiplies) != gcp.Len() {
+		return errors.New("Aggregated votes length mismatch with the guardian candidate pool")
+	}
+	if a.Gcp != gcp.Hash() {
+		return errors.New("Aggregated votes signed over another guardian candidate pool")
+	}
+	if a.Signature == nil {
+		return errors.New("Aggregated votes not signed")
+	}
+
+	var aggPubkey *bls.PublicKey
+	for i, m := range a.Multiplies {
+		if m == 0 {
+			continue
+		}
+		pubkey := gcp.SortedGuardians[i].Pubkey.Copy()
+		if m > 1 {
+			pubkey = pubkey.ScalarMult(m)
+		}
+		if aggPubkey == nil {
+			aggPubkey = pubkey
+		} else {
+			aggPubkey.Aggregate(pubkey)
+		}
+	}
+	if aggPubkey == nil {
+		return errors.New("Aggregated votes have no signer")
+	}
+	if !a.Signature.Verify(aggPubkey, a.SignBytes()) {
+		return errors.New("Aggregated signature verification failed")
+	}
+	return nil
+}
+
+// HasMajority checks whether the signers hold more than 2/3 of the guardian stake
+func (a *AggregatedVotes) HasMajority(gcp *GuardianCandidatePool) bool {
+	if len(a.Multiplies) != gcp.Len() {
+		return false
+	}
+
+	votedStake := new(big.Int).SetUint64(0)
+	for i, m := range a.Multiplies {
+		if m > 0 {
+			votedStake = new(big.Int).Add(votedStake, gcp.SortedGuardians[i].TotalStake())
+		}
+	}
+
+	three := new(big.Int).SetUint64(3)
+	two := new(big.Int).SetUint64(2)
+	lhs := new(big.Int)
+	rhs := new(big.Int)
+
+	//return votedStake*3 > gcp.TotalStake()*2
+	return lhs.Mul(votedStake, three).Cmp(rhs.Mul(gcp.TotalStake(), two)) > 0
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto/bls"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func newTestGuardianPool(assert *assert.Assertions, n int) (*GuardianCandidatePool, []*bls.SecretKey) {
+	gcp := NewGuardianCandidatePool()
+	keys := make(map[common.Address]*bls.SecretKey)
+	for i := 0; i < n; i++ {
+		key, err := bls.RandKey()
+		assert.Nil(err)
+		holder := common.BigToAddress(big.NewInt(int64(n - i)))
+		assert.Nil(gcp.DepositStake(holder, holder, MinGuardianStakeDeposit, key.PublicKey()))
+		keys[holder] = key
+	}
+
+	// Order the keys by the guardian positions in the pool
+	sorted := make([]*bls.SecretKey, n)
+	for i, g := range gcp.SortedGuardians {
+		sorted[i] = keys[g.Holder]
+	}
+	return gcp, sorted
+}
+
+func TestGuardianCandidatePool(t *testing.T) {
+	assert := assert.New(t)
+
+	gcp := NewGuardianCandidatePool()
+	key, err := bls.RandKey()
+	assert.Nil(err)
+
+	holder1 := common.HexToAddress("0x222")
+	holder2 := common.HexToAddress("0x111")
+	source := common.HexToAddress("0xabc")
+
+	assert.NotNil(gcp.DepositStake(source, holder1, big.NewInt(1), key.PublicKey())) // below the minimal deposit
+	assert.NotNil(gcp.DepositStake(source, holder1, MinGuardianStakeDeposit, nil))
+	assert.Nil(gcp.DepositStake(source, holder1, MinGuardianStakeDeposit, key.PublicKey()))
+	assert.Nil(gcp.DepositStake(source, holder1, MinGuardianStakeDeposit, key.PublicKey()))
+
+	key2, err := bls.RandKey()
+	assert.Nil(err)
+	assert.NotNil(gcp.DepositStake(source, holder1, MinGuardianStakeDeposit, key2.PublicKey())) // pubkey mismatch
+	assert.Nil(gcp.DepositStake(source, holder2, MinGuardianStakeDeposit, key2.PublicKey()))
+
+	// Guardians are sorted by holder address
+	assert.Equal(2, gcp.Len())
+	assert.Equal(0, gcp.Index(holder2))
+	assert.Equal(1, gcp.Index(holder1))
+	assert.Equal(-1, gcp.Index(source))
+
+	total := new(big.Int).Mul(MinGuardianStakeDeposit, big.NewInt(3))
+	assert.Equal(0, gcp.TotalStake().Cmp(total))
+
+	assert.NotNil(gcp.WithdrawStake(source, source, 100))
+	assert.Nil(gcp.WithdrawStake(source, holder2, 100))
+	assert.Equal(0, len(gcp.ReturnStakes(100)))
+	returned := gcp.ReturnStakes(100 + ReturnLockingPeriod)
+	assert.Equal(1, len(returned))
+	assert.Equal(1, gcp.Len())
+	assert.Equal(0, gcp.Index(holder1))
+
+	raw, err := rlp.EncodeToBytes(gcp)
+	assert.Nil(err)
+	decoded := &GuardianCandidatePool{}
+	assert.Nil(rlp.DecodeBytes(raw, decoded))
+	assert.Equal(gcp.Hash(), decoded.Hash())
+}
+
+func TestAggregatedVotes(t *testing.T) {
+	assert := assert.New(t)
+
+	gcp, keys := newTestGuardianPool(assert, 4)
+	block := common.HexToHash("0x1234")
+
+	// Two overlapping aggregation trees
+	votes1 := NewAggregatedVotes(block, gcp)
+	assert.True(votes1.Sign(keys[0], 0))
+	assert.False(votes1.Sign(keys[0], 0))
+	assert.True(votes1.Sign(keys[1], 1))
+	assert.Nil(votes1.Validate(gcp))
+	assert.False(votes1.HasMajority(gcp))
+
+	votes2 := NewAggregatedVotes(block, gcp)
+	assert.True(votes2.Sign(keys[1], 1))
+	assert.True(votes2.Sign(keys[2], 2))
+	assert.Nil(votes2.Validate(gcp))
+
+	merged, err := votes1.Merge(votes2)
+	assert.Nil(err)
+	assert.NotNil(merged)
+	assert.Equal([]uint32{1, 2, 1, 0}, merged.Multiplies)
+	assert.Nil(merged.Validate(gcp))
+	assert.True(merged.HasMajority(gcp))
+
+	// Nothing new to merge
+	noop, err := merged.Merge(votes1)
+	assert.Nil(err)
+	assert.Nil(noop)
+
+	// Tampered multiplies fail the verification
+	merged.Multiplies[1] = 1
+	assert.NotNil(merged.Validate(gcp))
+
+	// Votes for another checkpoint cannot be merged
+	other := NewAggregatedVotes(common.HexToHash("0x5678"), gcp)
+	assert.True(other.Sign(keys[3], 3))
+	_, err = votes1.Merge(other)
+	assert.NotNil(err)
+
+	raw, err := rlp.EncodeToBytes(votes2)
+	assert.Nil(err)
+	decoded := &AggregatedVotes{}
+	assert.Nil(rlp.DecodeBytes(raw, decoded))
+	assert.Nil(decoded.Validate(gcp))
+}
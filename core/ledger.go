@@ -20,18 +20,39 @@ const (
 //
 type TxInfo struct {
 	EffectiveGasPrice *big.Int
+	Fee               *big.Int // Max fee in TFuelWei the transaction pays
+	Gas               uint64   // Max gas the transaction consumes
 	Address           common.Address
 	Sequence          uint64
 }
 
+//
+// ScreenBatch screens a batch of transactions on a copy-on-write overlay of the screened view.
+// A screened transaction affects the screening of the later transactions in the batch only
+// once accepted, and the accepted transactions affect the screened view only once the batch
+// is committed. A transaction replacing a pooled one, which the screened view already has the
+// effects of, is screened with ScreenReplacementTx instead.
+//
+type ScreenBatch interface {
+	ScreenTx(rawTx common.Bytes) (priority *TxInfo, res result.Result)
+	ScreenReplacementTx(rawTx common.Bytes, earlierRawTxs []common.Bytes) (priority *TxInfo, res result.Result)
+	Accept()
+	Commit()
+}
+
 //
 // Ledger defines the interface of the ledger
 //
 type Ledger interface {
 	ScreenTx(rawTx common.Bytes) (priority *TxInfo, res result.Result)
-	ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result)
-	ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoot common.Hash) result.Result
+	NewScreenBatch() ScreenBatch
+	ProposeBlockTxs(timestamp *big.Int) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result)
+	ProposeBlockTxsFromPayload(timestamp *big.Int, regularRawTxs []common.Bytes) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result)
+	PrepareBlockTxs() []common.Bytes
+	ProposePreparedBlockTxs(timestamp *big.Int, regularRawTxs []common.Bytes) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result)
+	ApplyBlockTxs(blockRawTxs []common.Bytes, timestamp *big.Int, expectedStateRoot common.Hash) result.Result
 	ResetState(height uint64, rootHash common.Hash) result.Result
 	FinalizeState(height uint64, rootHash common.Hash) result.Result
 	GetFinalizedValidatorCandidatePool(blockHash common.Hash, isNext bool) (*ValidatorCandidatePool, error)
+	GetGuardianCandidatePool(blockHash common.Hash) (*GuardianCandidatePool, error)
 }
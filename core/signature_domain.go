@@ -0,0 +1,17 @@
+package core
+
+import (
+	"github.com/thetatoken/theta/core/verifier"
+)
+
+// ForkNumber returns the number of hard forks of the chain activated at or below the height.
+func ForkNumber(chainID string, height uint64) uint64 {
+	return verifier.ForkNumber(chainID, height)
+}
+
+// SignatureDomain returns the domain of the signatures made for the chain at the height, which
+// separates them from the signatures of the other chains and of the other forks of the chain.
+// See verifier.SignatureDomain.
+func SignatureDomain(chainID string, height uint64) string {
+	return verifier.SignatureDomain(chainID, height)
+}
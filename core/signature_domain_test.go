@@ -0,0 +1,95 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+)
+
+func TestSignatureDomain(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "testchain_signature_domain"
+	assert.Equal(uint64(0), ForkNumber(chainID, 100))
+	assert.Equal(chainID, SignatureDomain(chainID, 100))
+
+	SetForkSchedule(chainID, NewForkSchedule(map[Upgrade]uint64{"upgradeA": 200, "upgradeB": 100}))
+	defer SetForkSchedule(chainID, NewForkSchedule(nil))
+
+	assert.Equal(uint64(0), ForkNumber(chainID, 99))
+	assert.Equal(uint64(1), ForkNumber(chainID, 100))
+	assert.Equal(uint64(1), ForkNumber(chainID, 199))
+	assert.Equal(uint64(2), ForkNumber(chainID, 1000))
+	assert.Equal(chainID, SignatureDomain(chainID, 99))
+	assert.Equal(chainID+"/fork1", SignatureDomain(chainID, 100))
+	assert.Equal(chainID+"/fork2", SignatureDomain(chainID, 200))
+
+	// The domains of the other chains are not affected
+	assert.Equal("otherchain", SignatureDomain("otherchain", 1000))
+}
+
+func TestVoteSignatureDomain(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	sign := func(v Vote) Vote {
+		v.Signature, _ = privKey.Sign(v.SignBytes())
+		return v
+	}
+
+	chainID := "testchain_vote_domain"
+	vote := Vote{
+		Block:   common.HexToHash("a1"),
+		Height:  150,
+		Epoch:   3,
+		ID:      privKey.PublicKey().Address(),
+		ChainID: chainID,
+	}
+	preForkVote := sign(vote)
+	assert.True(preForkVote.Validate().IsOK())
+
+	// The vote cannot be replayed on another chain
+	otherChainVote := preForkVote
+	otherChainVote.ChainID = "otherchain"
+	assert.True(otherChainVote.Validate().IsError())
+
+	// The votes signed before the fork are invalid after it, and vice versa
+	SetForkSchedule(chainID, NewForkSchedule(map[Upgrade]uint64{"upgradeA": 100}))
+	defer SetForkSchedule(chainID, NewForkSchedule(nil))
+	assert.True(preForkVote.Validate().IsError())
+	postForkVote := sign(vote)
+	assert.True(postForkVote.Validate().IsOK())
+
+	// Moving the vote before the fork height breaks the signature too
+	postForkVote.Height = 50
+	assert.True(postForkVote.Validate().IsError())
+}
+
+func TestBlockSignatureDomain(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "testchain_block_domain"
+	header := &BlockHeader{
+		ChainID:   chainID,
+		Epoch:     5,
+		Height:    150,
+		Parent:    common.HexToHash("a1"),
+		HCC:       CommitCertificate{BlockHash: common.HexToHash("a1")},
+		Timestamp: big.NewInt(1),
+		Proposer:  DefaultSigner.PublicKey().Address(),
+	}
+	legacySignBytes := header.SignBytes()
+	header.Signature, _ = DefaultSigner.Sign(legacySignBytes)
+	assert.True(header.Validate().IsOK())
+
+	SetForkSchedule(chainID, NewForkSchedule(map[Upgrade]uint64{"upgradeA": 100}))
+	defer SetForkSchedule(chainID, NewForkSchedule(nil))
+	assert.NotEqual(legacySignBytes, header.SignBytes())
+	assert.True(header.Validate().IsError())
+
+	header.Signature, _ = DefaultSigner.Sign(header.SignBytes())
+	assert.True(header.Validate().IsOK())
+}
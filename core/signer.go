@@ -0,0 +1,26 @@
+package core
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+)
+
+// Signer signs the votes, the block proposals, the proposer transactions and the bridge
+// batches on behalf of the validator. The validator key can be held locally, or by a remote signing service.
+type Signer interface {
+	// PublicKey returns the public key of the validator.
+	PublicKey() *crypto.PublicKey
+
+	// SignVote returns the signature of the vote.
+	SignVote(vote Vote) (*crypto.Signature, error)
+
+	// SignBlock returns the signature of the block header.
+	SignBlock(header *BlockHeader) (*crypto.Signature, error)
+
+	// SignTx returns the signature of the RLP encoded transaction. Only the coinbase and
+	// slash transactions issued by the proposer need to be signed by the validator.
+	SignTx(chainID string, rawTx common.Bytes) (*crypto.Signature, error)
+
+	// SignBridgeBatch returns the co-signature of the batch of the outbound bridge messages.
+	SignBridgeBatch(batch *BridgeBatch) (*crypto.Signature, error)
+}
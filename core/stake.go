@@ -52,9 +52,14 @@ func (s *Stake) String() string {
 // ------- StakeHolder ------- //
 //
 
+// StakeHolder holds the stakes deposited to a validator or a guardian candidate. A validator
+// candidate may also hold the stakes delegated by the token holders, which count for the holder
+// only while it has stake of its own. Unlike the deposited stakes, only the delegator can
+// undelegate a delegated stake.
 type StakeHolder struct {
-	Holder common.Address
-	Stakes []*Stake
+	Holder      common.Address
+	Stakes      []*Stake
+	Delegations []*Stake `rlp:"optional"`
 }
 
 func newStakeHolder(holder common.Address, stakes []*Stake) *StakeHolder {
@@ -64,9 +69,29 @@ func newStakeHolder(holder common.Address, stakes []*Stake) *StakeHolder {
 	}
 }
 
+// TotalStake returns the stake of the holder that is not withdrawn, including the delegated
+// stake if the holder has stake of its own.
 func (sh *StakeHolder) TotalStake() *big.Int {
+	totalAmount := sh.SelfStake()
+	if totalAmount.Sign() > 0 {
+		totalAmount.Add(totalAmount, sh.DelegatedStake())
+	}
+	return totalAmount
+}
+
+// SelfStake returns the deposited stake of the holder that is not withdrawn.
+func (sh *StakeHolder) SelfStake() *big.Int {
+	return sumStakes(sh.Stakes)
+}
+
+// DelegatedStake returns the delegated stake of the holder that is not undelegated.
+func (sh *StakeHolder) DelegatedStake() *big.Int {
+	return sumStakes(sh.Delegations)
+}
+
+func sumStakes(stakes []*Stake) *big.Int {
 	totalAmount := new(big.Int).SetUint64(0)
-	for _, stake := range sh.Stakes {
+	for _, stake := range stakes {
 		if !stake.Withdrawn {
 			totalAmount = new(big.Int).Add(totalAmount, stake.Amount)
 		}
@@ -129,6 +154,99 @@ func (sh *StakeHolder) returnStake(source common.Address, currentHeight uint64)
 	return nil, fmt.Errorf("Cannot return, no matched stake source address found: %v", source)
 }
 
+func (sh *StakeHolder) delegate(delegator common.Address, amount *big.Int) error {
+	if amount.Cmp(Zero) < 0 {
+		return fmt.Errorf("Invalid delegation: %v", amount)
+	}
+
+	for _, delegation := range sh.Delegations {
+		if delegation.Source == delegator {
+			if delegation.Withdrawn {
+				return fmt.Errorf("Cannot delegate during the undelegation locking period for: %v", delegator)
+			}
+
+			delegation.Amount = new(big.Int).Add(delegation.Amount, amount)
+			return nil
+		}
+	}
+
+	sh.Delegations = append(sh.Delegations, newStake(delegator, amount))
+	return nil
+}
+
+func (sh *StakeHolder) undelegate(delegator common.Address, currentHeight uint64) error {
+	for _, delegation := range sh.Delegations {
+		if delegation.Source == delegator {
+			if delegation.Withdrawn {
+				return fmt.Errorf("Already undelegated, cannot undelegate again for delegator: %v", delegator)
+			}
+			delegation.Withdrawn = true
+			delegation.ReturnHeight = currentHeight + ReturnLockingPeriod
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Cannot undelegate, no matched delegator address found: %v", delegator)
+}
+
+// unbondStake removes the stake deposited by the source that is not withdrawn, and returns it.
+func (sh *StakeHolder) unbondStake(source common.Address) (*Stake, error) {
+	stakes, stake, err := removeStake(sh.Stakes, source)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot withdraw, %v", err)
+	}
+	sh.Stakes = stakes
+	return stake, nil
+}
+
+// unbondDelegation removes the stake delegated by the delegator that is not undelegated, and
+// returns it.
+func (sh *StakeHolder) unbondDelegation(delegator common.Address) (*Stake, error) {
+	delegations, delegation, err := removeStake(sh.Delegations, delegator)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot undelegate, %v", err)
+	}
+	if len(delegations) == 0 {
+		delegations = nil
+	}
+	sh.Delegations = delegations
+	return delegation, nil
+}
+
+func removeStake(stakes []*Stake, source common.Address) ([]*Stake, *Stake, error) {
+	for idx, stake := range stakes {
+		if stake.Source == source {
+			if stake.Withdrawn {
+				return nil, nil, fmt.Errorf("stake already withdrawn for source: %v", source)
+			}
+			return append(stakes[:idx:idx], stakes[idx+1:]...), stake, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("no matched stake source address found: %v", source)
+}
+
+// returnDelegations removes the undelegated stakes whose return height has been reached, and
+// returns them.
+func (sh *StakeHolder) returnDelegations(currentHeight uint64) []*Stake {
+	returned := []*Stake{}
+	remaining := []*Stake{}
+	for _, delegation := range sh.Delegations {
+		if delegation.Withdrawn && currentHeight >= delegation.ReturnHeight {
+			returned = append(returned, delegation)
+		} else {
+			remaining = append(remaining, delegation)
+		}
+	}
+	if len(remaining) == 0 {
+		remaining = nil
+	}
+	sh.Delegations = remaining
+	return returned
+}
+
 func (sh *StakeHolder) String() string {
+	if len(sh.Delegations) > 0 {
+		return fmt.Sprintf("{holder: %v, stakes :%v, delegations: %v}", sh.Holder, sh.Stakes, sh.Delegations)
+	}
 	return fmt.Sprintf("{holder: %v, stakes :%v}", sh.Holder, sh.Stakes)
 }
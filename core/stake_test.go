@@ -146,3 +146,42 @@ func TestStakeReturn(t *testing.T) {
 	assert.Nil(returnedStake) // sourceAddr3 never deposited any stake, so cannot return
 	assert.NotNil(err)
 }
+
+func TestDelegation(t *testing.T) {
+	assert := assert.New(t)
+
+	validator := common.HexToAddress("0xabc")
+	source := common.HexToAddress("0x111")
+	delegator := common.HexToAddress("0x222")
+	delegation := new(big.Int).Mul(MinDelegation, big.NewInt(2))
+
+	vcp := &ValidatorCandidatePool{}
+	assert.NotNil(vcp.Delegate(delegator, validator, delegation)) // not a candidate
+	assert.Nil(vcp.DepositStake(source, validator, MinValidatorStakeDeposit))
+	assert.NotNil(vcp.Delegate(delegator, validator, new(big.Int).Sub(MinDelegation, big.NewInt(1))))
+	assert.Nil(vcp.Delegate(delegator, validator, delegation))
+
+	// The delegation counts for the validator while it has stake of its own
+	candidate := vcp.SortedCandidates[0]
+	assert.Equal(new(big.Int).Add(MinValidatorStakeDeposit, delegation), candidate.TotalStake())
+	assert.Equal(delegation, candidate.DelegatedStake())
+	assert.Nil(vcp.WithdrawStake(source, validator, 100))
+	assert.Equal(0, candidate.TotalStake().Sign())
+
+	// Only the delegator can undelegate, and the stake is returned after the locking period
+	assert.NotNil(vcp.Undelegate(source, validator, 200))
+	assert.Nil(vcp.Undelegate(delegator, validator, 200))
+	assert.NotNil(vcp.Undelegate(delegator, validator, 200))
+	assert.NotNil(vcp.Delegate(delegator, validator, delegation))
+
+	returned := vcp.ReturnStakes(100 + ReturnLockingPeriod)
+	assert.Equal(1, len(returned))
+	assert.Equal(source, returned[0].Source)
+	assert.Equal(1, len(vcp.SortedCandidates)) // kept for the delegation
+
+	returned = vcp.ReturnStakes(200 + ReturnLockingPeriod)
+	assert.Equal(1, len(returned))
+	assert.Equal(delegator, returned[0].Source)
+	assert.Equal(delegation, returned[0].Amount)
+	assert.Equal(0, len(vcp.SortedCandidates))
+}
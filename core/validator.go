@@ -1,8 +1,6 @@
 package core
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
 	"math/big"
 	"sort"
@@ -10,158 +8,35 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/core/verifier"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "core"})
+var logger *log.Entry = util.GetLoggerForModule("core")
 
 var (
 	// ErrValidatorNotFound for ID is not found in validator set.
-	ErrValidatorNotFound = errors.New("ValidatorNotFound")
+	ErrValidatorNotFound = verifier.ErrValidatorNotFound
 )
 
 // Validator contains the public information of a validator.
-type Validator struct {
-	Address common.Address
-	Stake   *big.Int
-}
+type Validator = verifier.Validator
 
 // NewValidator creates a new validator instance.
 func NewValidator(addressStr string, stake *big.Int) Validator {
-	address := common.HexToAddress(addressStr)
-	return Validator{address, stake}
-}
-
-// ID returns the ID of the validator, which is the string representation of its address.
-func (v Validator) ID() common.Address {
-	return v.Address
-}
-
-// Equals checks whether the validator is the same as another validator
-func (v Validator) Equals(x Validator) bool {
-	if v.Address != x.Address {
-		return false
-	}
-	if v.Stake.Cmp(x.Stake) != 0 {
-		return false
-	}
-	return true
-}
-
-// String represents the string representation of the validator
-func (v Validator) String() string {
-	return fmt.Sprintf("{ID: %v, Stake: %v}", v.ID(), v.Stake)
+	return verifier.NewValidator(addressStr, stake)
 }
 
 // ValidatorSet represents a set of validators.
-type ValidatorSet struct {
-	validators []Validator
-}
+type ValidatorSet = verifier.ValidatorSet
 
 // NewValidatorSet returns a new instance of ValidatorSet.
 func NewValidatorSet() *ValidatorSet {
-	return &ValidatorSet{
-		validators: []Validator{},
-	}
-}
-
-// SetValidators sets validators
-func (s *ValidatorSet) SetValidators(validators []Validator) {
-	s.validators = validators
-}
-
-// Copy creates a copy of this validator set.
-func (s *ValidatorSet) Copy() *ValidatorSet {
-	ret := NewValidatorSet()
-	for _, v := range s.Validators() {
-		ret.AddValidator(v)
-	}
-	return ret
-}
-
-// Size returns the number of the validators in the validator set.
-func (s *ValidatorSet) Size() int {
-	return len(s.validators)
-}
-
-// Equals checks whether the validator set is the same as another validator set
-func (s *ValidatorSet) Equals(t *ValidatorSet) bool {
-	numVals := len(s.validators)
-	if numVals != len(t.validators) {
-		return false
-	}
-	for i := 0; i < numVals; i++ {
-		if !s.validators[i].Equals(t.validators[i]) {
-			return false
-		}
-	}
-	return true
-}
-
-// String represents the string representation of the validator set
-func (s *ValidatorSet) String() string {
-	return fmt.Sprintf("{Validators: %v}", s.validators)
+	return verifier.NewValidatorSet()
 }
 
 // ByID implements sort.Interface for ValidatorSet based on ID.
-type ByID []Validator
-
-func (b ByID) Len() int           { return len(b) }
-func (b ByID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
-func (b ByID) Less(i, j int) bool { return bytes.Compare(b[i].ID().Bytes(), b[j].ID().Bytes()) < 0 }
-
-// GetValidator returns a validator if a matching ID is found.
-func (s *ValidatorSet) GetValidator(id common.Address) (Validator, error) {
-	for _, v := range s.validators {
-		if v.ID() == id {
-			return v, nil
-		}
-	}
-	return Validator{}, ErrValidatorNotFound
-}
-
-// AddValidator adds a validator to the validator set.
-func (s *ValidatorSet) AddValidator(validator Validator) {
-	s.validators = append(s.validators, validator)
-	sort.Sort(ByID(s.validators))
-}
-
-// TotalStake returns the total stake of the validators in the set.
-func (s *ValidatorSet) TotalStake() *big.Int {
-	ret := new(big.Int).SetUint64(0)
-	for _, v := range s.validators {
-		ret = new(big.Int).Add(ret, v.Stake)
-	}
-	return ret
-}
-
-// HasMajorityVotes checks whether a vote set has reach majority.
-func (s *ValidatorSet) HasMajorityVotes(votes []Vote) bool {
-	votedStake := new(big.Int).SetUint64(0)
-	for _, vote := range votes {
-		validator, err := s.GetValidator(vote.ID)
-		if err == nil {
-			votedStake = new(big.Int).Add(votedStake, validator.Stake)
-		}
-	}
-
-	three := new(big.Int).SetUint64(3)
-	two := new(big.Int).SetUint64(2)
-	lhs := new(big.Int)
-	rhs := new(big.Int)
-
-	//return votedStake*3 > s.TotalStake()*2
-	return lhs.Mul(votedStake, three).Cmp(rhs.Mul(s.TotalStake(), two)) > 0
-}
-
-// HasMajority checks whether a vote set has reach majority.
-func (s *ValidatorSet) HasMajority(votes *VoteSet) bool {
-	return s.HasMajorityVotes(votes.Votes())
-}
-
-// Validators returns a slice of validators.
-func (s *ValidatorSet) Validators() []Validator {
-	return s.validators
-}
+type ByID = verifier.ByID
 
 //
 // ------- ValidatorCandidatePool ------- //
@@ -169,11 +44,15 @@ func (s *ValidatorSet) Validators() []Validator {
 
 var (
 	MinValidatorStakeDeposit *big.Int
+	MinDelegation            *big.Int
 )
 
 func init() {
 	// Each stake deposit needs to be at least 5,000,000 Theta
 	MinValidatorStakeDeposit = new(big.Int).Mul(new(big.Int).SetUint64(5000000), new(big.Int).SetUint64(1000000000000000000))
+
+	// Each delegation needs to be at least 1,000 Theta
+	MinDelegation = new(big.Int).Mul(new(big.Int).SetUint64(1000), new(big.Int).SetUint64(1000000000000000000))
 }
 
 type ValidatorCandidatePool struct {
@@ -241,6 +120,104 @@ func (vcp *ValidatorCandidatePool) WithdrawStake(source common.Address, holder c
 	return nil
 }
 
+// Delegate delegates the amount of stake from the delegator to the holder, which must be a
+// candidate with stake of its own.
+func (vcp *ValidatorCandidatePool) Delegate(delegator common.Address, holder common.Address, amount *big.Int) error {
+	if amount.Cmp(MinDelegation) < 0 {
+		return fmt.Errorf("Insufficient delegation: %v", amount)
+	}
+
+	candidate := vcp.getCandidate(holder)
+	if candidate == nil || candidate.SelfStake().Sign() == 0 {
+		return fmt.Errorf("Cannot delegate to %v, which is not a validator candidate", holder)
+	}
+	err := candidate.delegate(delegator, amount)
+	if err != nil {
+		return err
+	}
+
+	vcp.sort()
+	return nil
+}
+
+// Undelegate undelegates the stake delegated by the delegator to the holder. The stake is returned
+// to the delegator after the locking period.
+func (vcp *ValidatorCandidatePool) Undelegate(delegator common.Address, holder common.Address, currentHeight uint64) error {
+	candidate := vcp.getCandidate(holder)
+	if candidate == nil {
+		return fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	err := candidate.undelegate(delegator, currentHeight)
+	if err != nil {
+		return err
+	}
+
+	vcp.sort()
+	return nil
+}
+
+// UnbondStake removes the stake deposited by the source to the holder from the pool, so it can be
+// held in the unbonding queue until it is returned. The holder is removed from the pool once it
+// has no stake left.
+func (vcp *ValidatorCandidatePool) UnbondStake(source common.Address, holder common.Address) (*Stake, error) {
+	candidate := vcp.getCandidate(holder)
+	if candidate == nil {
+		return nil, fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	stake, err := candidate.unbondStake(source)
+	if err != nil {
+		return nil, err
+	}
+
+	vcp.removeIfEmpty(candidate)
+	vcp.sort()
+	return stake, nil
+}
+
+// UnbondDelegation removes the stake delegated by the delegator to the holder from the pool, so
+// it can be held in the unbonding queue until it is returned.
+func (vcp *ValidatorCandidatePool) UnbondDelegation(delegator common.Address, holder common.Address) (*Stake, error) {
+	candidate := vcp.getCandidate(holder)
+	if candidate == nil {
+		return nil, fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	delegation, err := candidate.unbondDelegation(delegator)
+	if err != nil {
+		return nil, err
+	}
+
+	vcp.removeIfEmpty(candidate)
+	vcp.sort()
+	return delegation, nil
+}
+
+func (vcp *ValidatorCandidatePool) removeIfEmpty(candidate *StakeHolder) {
+	if len(candidate.Stakes) > 0 || len(candidate.Delegations) > 0 {
+		return
+	}
+	for idx, c := range vcp.SortedCandidates {
+		if c == candidate {
+			vcp.SortedCandidates = append(vcp.SortedCandidates[:idx], vcp.SortedCandidates[idx+1:]...)
+			return
+		}
+	}
+}
+
+func (vcp *ValidatorCandidatePool) getCandidate(holder common.Address) *StakeHolder {
+	for _, candidate := range vcp.SortedCandidates {
+		if candidate.Holder == holder {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func (vcp *ValidatorCandidatePool) sort() {
+	sort.Slice(vcp.SortedCandidates[:], func(i, j int) bool { // descending order
+		return vcp.SortedCandidates[i].TotalStake().Cmp(vcp.SortedCandidates[j].TotalStake()) >= 0
+	})
+}
+
 func (vcp *ValidatorCandidatePool) ReturnStakes(currentHeight uint64) []*Stake {
 	returnedStakes := []*Stake{}
 
@@ -262,8 +239,9 @@ func (vcp *ValidatorCandidatePool) ReturnStakes(currentHeight uint64) []*Stake {
 				returnedStakes = append(returnedStakes, returnedStake)
 			}
 		}
+		returnedStakes = append(returnedStakes, candidate.returnDelegations(currentHeight)...)
 
-		if len(candidate.Stakes) == 0 { // the candidate's stake becomes zero, no need to keep track of the candiate anymore
+		if len(candidate.Stakes) == 0 && len(candidate.Delegations) == 0 { // the candidate's stake becomes zero, no need to keep track of the candiate anymore
 			vcp.SortedCandidates = append(vcp.SortedCandidates[:cidx], vcp.SortedCandidates[cidx+1:]...)
 		}
 	}
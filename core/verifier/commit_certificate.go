@@ -0,0 +1,192 @@
+package verifier
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// commitCertificateEncodingVersion prefixes the compact encoding of the commit certificates,
+// which tells it apart from the legacy encoding starting with the vote set.
+const commitCertificateEncodingVersion uint = 1
+
+//
+// compactCommitCertificate is the compact encoding of a commit certificate. The voters are a
+// bitmap over the validators of the block carrying the certificate ordered by ID, and the epochs
+// and signatures of their votes follow the same order. The votes share the block hash, height
+// and chain ID instead of repeating them, and the voter IDs are not stored at all.
+//
+type compactCommitCertificate struct {
+	Version    uint
+	BlockHash  common.Hash
+	Height     uint64
+	ChainID    string
+	Voters     common.Bytes
+	Epochs     []uint64
+	Signatures []*crypto.Signature
+}
+
+// legacyCommitCertificate is the encoding of the commit certificates before the compact one, with
+// the full vote set.
+type legacyCommitCertificate struct {
+	Votes     *VoteSet `rlp:"nil"`
+	BlockHash common.Hash
+}
+
+// IsCompact returns whether the certificate uses the compact encoding.
+func (cc CommitCertificate) IsCompact() bool {
+	return cc.compact != nil
+}
+
+// Compact switches the certificate to the compact encoding, with the voters as a bitmap over the
+// given validators, which must be the validator set of the block carrying the certificate. The
+// votes of the other voters or on another block are dropped, since they do not count towards the
+// majority anyway.
+func (cc *CommitCertificate) Compact(validators *ValidatorSet) {
+	sorted := sortedValidators(validators)
+	compact := &compactCommitCertificate{
+		Version:    commitCertificateEncodingVersion,
+		BlockHash:  cc.BlockHash,
+		Voters:     make(common.Bytes, (len(sorted)+7)/8),
+		Epochs:     []uint64{},
+		Signatures: []*crypto.Signature{},
+	}
+	votes := NewVoteSet()
+	if cc.Votes != nil {
+		votes = cc.Votes.UniqueVoter()
+	}
+	first := true
+	for i, validator := range sorted {
+		vote, ok := votes.LatestVote(validator.ID())
+		if !ok || vote.Block != cc.BlockHash {
+			continue
+		}
+		if first {
+			compact.Height = vote.Height
+			compact.ChainID = vote.ChainID
+			first = false
+		} else if vote.Height != compact.Height || vote.ChainID != compact.ChainID {
+			continue
+		}
+		compact.Voters[i/8] |= 1 << uint(i%8)
+		compact.Epochs = append(compact.Epochs, vote.Epoch)
+		compact.Signatures = append(compact.Signatures, vote.Signature)
+	}
+
+	cc.compact = compact
+	cc.Votes, _ = compact.votes(sorted)
+}
+
+// ResolveVotes sets the votes of a certificate decoded from the compact encoding, using the
+// validator set of the block carrying the certificate. It does nothing if the votes are already
+// known, e.g. for the legacy encoding.
+func (cc *CommitCertificate) ResolveVotes(validators *ValidatorSet) error {
+	if cc.compact == nil || cc.Votes != nil {
+		return nil
+	}
+	votes, err := cc.compact.votes(sortedValidators(validators))
+	if err != nil {
+		return err
+	}
+	cc.Votes = votes
+	return nil
+}
+
+// votes returns the votes of the certificate, with the voters of the bitmap taken from the
+// validators ordered by ID.
+func (c *compactCommitCertificate) votes(sorted []Validator) (*VoteSet, error) {
+	if len(c.Voters) != (len(sorted)+7)/8 {
+		return nil, fmt.Errorf("Voter bitmap of %v bytes does not match the %v validators", len(c.Voters), len(sorted))
+	}
+	if len(c.Epochs) != len(c.Signatures) {
+		return nil, fmt.Errorf("%v epochs do not match the %v signatures", len(c.Epochs), len(c.Signatures))
+	}
+	votes := NewVoteSet()
+	for i := 0; i < len(c.Voters)*8; i++ {
+		if c.Voters[i/8]&(1<<uint(i%8)) == 0 {
+			continue
+		}
+		if i >= len(sorted) {
+			return nil, fmt.Errorf("Voter %v is not a validator", i)
+		}
+		idx := votes.Size()
+		if idx >= len(c.Signatures) {
+			return nil, fmt.Errorf("Voter bitmap has more voters than the %v signatures", len(c.Signatures))
+		}
+		votes.AddVote(Vote{
+			Block:     c.BlockHash,
+			Height:    c.Height,
+			Epoch:     c.Epochs[idx],
+			ID:        sorted[i].ID(),
+			Signature: c.Signatures[idx],
+			ChainID:   c.ChainID,
+		})
+	}
+	if votes.Size() != len(c.Signatures) {
+		return nil, fmt.Errorf("Voter bitmap has %v voters for %v signatures", votes.Size(), len(c.Signatures))
+	}
+	return votes, nil
+}
+
+func sortedValidators(validators *ValidatorSet) []Validator {
+	sorted := make([]Validator, len(validators.Validators()))
+	copy(sorted, validators.Validators())
+	sort.Sort(ByID(sorted))
+	return sorted
+}
+
+var _ rlp.Encoder = CommitCertificate{}
+
+// EncodeRLP implements RLP Encoder interface. The certificate keeps the encoding it was created
+// or decoded with, so that the hash of the block header does not change.
+func (cc CommitCertificate) EncodeRLP(w io.Writer) error {
+	if cc.compact != nil {
+		compact := *cc.compact
+		compact.BlockHash = cc.BlockHash
+		return rlp.Encode(w, &compact)
+	}
+	return rlp.Encode(w, legacyCommitCertificate{Votes: cc.Votes, BlockHash: cc.BlockHash})
+}
+
+var _ rlp.Decoder = (*CommitCertificate)(nil)
+
+// DecodeRLP implements RLP Decoder interface. Both the compact and the legacy encodings are
+// accepted. The votes of a compact certificate are left nil until resolved.
+func (cc *CommitCertificate) DecodeRLP(stream *rlp.Stream) error {
+	raw, err := stream.Raw()
+	if err != nil {
+		return err
+	}
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return err
+	}
+	kind, _, _, err := rlp.Split(content)
+	if err != nil {
+		return err
+	}
+
+	if kind == rlp.List {
+		// Legacy encoding, starting with the vote set
+		legacy := legacyCommitCertificate{}
+		if err := rlp.DecodeBytes(raw, &legacy); err != nil {
+			return err
+		}
+		*cc = CommitCertificate{Votes: legacy.Votes, BlockHash: legacy.BlockHash}
+		return nil
+	}
+
+	compact := &compactCommitCertificate{}
+	if err := rlp.DecodeBytes(raw, compact); err != nil {
+		return err
+	}
+	if compact.Version != commitCertificateEncodingVersion {
+		return fmt.Errorf("Unsupported commit certificate encoding version: %v", compact.Version)
+	}
+	*cc = CommitCertificate{BlockHash: compact.BlockHash, compact: compact}
+	return nil
+}
@@ -0,0 +1,64 @@
+package verifier
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+var (
+	forkHeightsMu sync.RWMutex
+	forkHeights   = map[string][]uint64{}
+)
+
+// SetForkHeights sets the heights the hard forks of the chain are activated at, which determine
+// the signature domain of the blocks and the votes. A node sets them from its fork schedule. An
+// external verifier needs to set the same heights as the nodes of the chain, otherwise the
+// signatures made after the first hard fork do not verify.
+func SetForkHeights(chainID string, heights []uint64) {
+	sorted := append([]uint64{}, heights...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	forkHeightsMu.Lock()
+	defer forkHeightsMu.Unlock()
+	forkHeights[chainID] = sorted
+}
+
+// ForkNumber returns the number of hard forks of the chain activated at or below the height.
+func ForkNumber(chainID string, height uint64) uint64 {
+	forkHeightsMu.RLock()
+	defer forkHeightsMu.RUnlock()
+	heights := forkHeights[chainID]
+	return uint64(sort.Search(len(heights), func(i int) bool {
+		return heights[i] > height
+	}))
+}
+
+// SignatureDomain returns the domain of the signatures made for the chain at the height, which
+// separates them from the signatures of the other chains and of the other forks of the chain.
+// The transactions are signed with the domain in place of the chain ID. Before the first hard
+// fork, the domain is the chain ID itself, so the signatures made before the domain separation
+// was introduced stay valid.
+func SignatureDomain(chainID string, height uint64) string {
+	forkNumber := ForkNumber(chainID, height)
+	if forkNumber == 0 {
+		return chainID
+	}
+	return fmt.Sprintf("%s/fork%d", chainID, forkNumber)
+}
+
+// domainSignBytes binds the sign bytes of a block or a vote to the signature domain of the chain
+// at the height. Like SignatureDomain, it leaves the sign bytes unchanged before the first fork.
+func domainSignBytes(chainID string, height uint64, signBytes common.Bytes) common.Bytes {
+	forkNumber := ForkNumber(chainID, height)
+	if forkNumber == 0 {
+		return signBytes
+	}
+	raw, _ := rlp.EncodeToBytes([]interface{}{SignatureDomain(chainID, height), signBytes})
+	return raw
+}
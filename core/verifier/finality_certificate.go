@@ -0,0 +1,165 @@
+package verifier
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+)
+
+const (
+	// CheckpointInterval is the number of blocks between two checkpoints finalized by the guardians
+	CheckpointInterval uint64 = 100
+)
+
+// IsCheckpointHeight returns true if the block at the given height is a checkpoint
+func IsCheckpointHeight(height uint64) bool {
+	return height%CheckpointInterval == 0
+}
+
+//
+// FinalityCertificate proves a block is finalized. A block is finalized once both the block and
+// one of its children are committed, i.e. voted for by validators holding more than 2/3 of the
+// stake. The certificate also links the block to a checkpoint below it with the chain of the
+// block headers in between, so that a verifier trusting the checkpoint can check the block
+// descends from it. The blocks of the header chain are the ancestors of the finalized block,
+// so they are finalized too.
+//
+// The certificate is self-contained and can be verified offline with Verify. The validator sets
+// are included for convenience, but they are only as trustworthy as the node that exported the
+// certificate. A verifier should compare them to validator sets obtained from a trusted source.
+//
+type FinalityCertificate struct {
+	ChainID         string         `json:"chain_id"`
+	Block           *BlockHeader   `json:"block"`            // the finalized block
+	Child           *BlockHeader   `json:"child"`            // the committed child of the block
+	BlockVotes      []Vote         `json:"block_votes"`      // the votes committing the block
+	ChildVotes      []Vote         `json:"child_votes"`      // the votes committing the child
+	BlockValidators []Validator    `json:"block_validators"` // the validators voting on the block
+	ChildValidators []Validator    `json:"child_validators"` // the validators voting on the child
+	HeaderChain     []*BlockHeader `json:"header_chain"`     // a checkpoint up to the parent of the block, empty if the block is a checkpoint
+}
+
+// BlockValidatorSet returns the validator set voting on the block, as claimed by the certificate.
+func (fc *FinalityCertificate) BlockValidatorSet() *ValidatorSet {
+	return newValidatorSetOf(fc.BlockValidators)
+}
+
+// ChildValidatorSet returns the validator set voting on the child, as claimed by the certificate.
+func (fc *FinalityCertificate) ChildValidatorSet() *ValidatorSet {
+	return newValidatorSetOf(fc.ChildValidators)
+}
+
+func newValidatorSetOf(validators []Validator) *ValidatorSet {
+	vs := NewValidatorSet()
+	for _, v := range validators {
+		vs.AddValidator(v)
+	}
+	return vs
+}
+
+// Checkpoint returns the header of the checkpoint the block descends from.
+func (fc *FinalityCertificate) Checkpoint() *BlockHeader {
+	if len(fc.HeaderChain) == 0 {
+		return fc.Block
+	}
+	return fc.HeaderChain[0]
+}
+
+// Finalizes returns true if the certificate proves the finality of the block with the given hash.
+func (fc *FinalityCertificate) Finalizes(hash common.Hash) bool {
+	if fc.Block != nil && fc.Block.Hash() == hash {
+		return true
+	}
+	for _, header := range fc.HeaderChain {
+		if header != nil && header.Hash() == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks the certificate proves the finality of the block on the chain, with the votes
+// of the given validator sets on the block and on its child.
+func (fc *FinalityCertificate) Verify(chainID string, blockValidators *ValidatorSet, childValidators *ValidatorSet) error {
+	if fc.Block == nil || fc.Child == nil {
+		return errors.New("Block header missing")
+	}
+	if fc.ChainID != chainID || fc.Block.ChainID != chainID || fc.Child.ChainID != chainID {
+		return fmt.Errorf("Certificate is not for chain %v", chainID)
+	}
+
+	blockHash := fc.Block.Hash()
+	if res := fc.Block.Validate(); res.IsError() {
+		return fmt.Errorf("Invalid block %v: %v", blockHash.Hex(), res.Message)
+	}
+	if res := fc.Child.Validate(); res.IsError() {
+		return fmt.Errorf("Invalid child %v: %v", fc.Child.Hash().Hex(), res.Message)
+	}
+	if fc.Child.Parent != blockHash || fc.Child.Height != fc.Block.Height+1 {
+		return fmt.Errorf("Block %v is not the parent of the child", blockHash.Hex())
+	}
+
+	if err := verifyCommitVotes(chainID, fc.Block, fc.BlockVotes, blockValidators); err != nil {
+		return err
+	}
+	if err := verifyCommitVotes(chainID, fc.Child, fc.ChildVotes, childValidators); err != nil {
+		return err
+	}
+
+	return fc.verifyHeaderChain()
+}
+
+// verifyHeaderChain checks the header chain links the block to a checkpoint below it.
+func (fc *FinalityCertificate) verifyHeaderChain() error {
+	if len(fc.HeaderChain) == 0 {
+		if !IsCheckpointHeight(fc.Block.Height) {
+			return errors.New("Header chain to the checkpoint missing")
+		}
+		return nil
+	}
+	for _, header := range fc.HeaderChain {
+		if header == nil {
+			return errors.New("Block header missing")
+		}
+	}
+	if !IsCheckpointHeight(fc.HeaderChain[0].Height) {
+		return fmt.Errorf("Header chain starts at height %v, which is not a checkpoint", fc.HeaderChain[0].Height)
+	}
+	headers := append(append([]*BlockHeader{}, fc.HeaderChain...), fc.Block)
+	for i := 1; i < len(headers); i++ {
+		if headers[i].Parent != headers[i-1].Hash() || headers[i].Height != headers[i-1].Height+1 {
+			return fmt.Errorf("Header chain broken at height %v", headers[i].Height)
+		}
+	}
+	return nil
+}
+
+// verifyCommitVotes checks the votes on the block are signed by validators holding more than
+// 2/3 of the stake.
+func verifyCommitVotes(chainID string, header *BlockHeader, votes []Vote, validators *ValidatorSet) error {
+	blockHash := header.Hash()
+	voted := make(map[common.Address]bool)
+	for _, vote := range votes {
+		if vote.Block != blockHash {
+			return fmt.Errorf("Vote from %v is not for block %v", vote.ID.Hex(), blockHash.Hex())
+		}
+		// The votes without chain ID are only accepted before the first hard fork
+		if vote.ChainID != chainID && (vote.ChainID != "" || ForkNumber(chainID, vote.Height) > 0) {
+			return fmt.Errorf("Vote from %v is not for chain %v", vote.ID.Hex(), chainID)
+		}
+		if voted[vote.ID] {
+			return fmt.Errorf("Duplicated vote from %v", vote.ID.Hex())
+		}
+		voted[vote.ID] = true
+	}
+	for i, res := range ValidateVotes(votes) {
+		if res.IsError() {
+			return fmt.Errorf("Invalid vote from %v: %v", votes[i].ID.Hex(), res.Message)
+		}
+	}
+	if !validators.HasMajorityVotes(votes) {
+		return fmt.Errorf("Block %v is not voted for by a validator supermajority", blockHash.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package verifier
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// BlockHeader contains the essential information of a block.
+type BlockHeader struct {
+	ChainID     string
+	Epoch       uint64
+	Height      uint64
+	Parent      common.Hash
+	HCC         CommitCertificate
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	Bloom       Bloom
+	StateHash   common.Hash
+	Timestamp   *big.Int
+	Proposer    common.Address
+	Signature   *crypto.Signature
+	BaseFee     *big.Int `rlp:"optional"` // Min gas price of the block transactions, nil before the fee market
+
+	// NextValidatorSetHash is the hash of the validator set of the blocks following a block
+	// updating the validator set, empty for the other blocks.
+	NextValidatorSetHash common.Hash `rlp:"optional"`
+
+	// VRFProof is the signature of the seed of the parent block with the guardian key of the
+	// proposer, which chains the randomness the proposers are selected from, empty before the
+	// VRF proposer selection and for the proposers without a guardian key.
+	VRFProof common.Bytes `rlp:"optional"`
+
+	hash common.Hash // Cache of calculated hash.
+}
+
+// Hash of header.
+func (h *BlockHeader) Hash() common.Hash {
+	if h == nil {
+		return common.Hash{}
+	}
+	if h.hash.IsEmpty() {
+		h.hash = h.calculateHash()
+	}
+	return h.hash
+}
+
+// UpdateHash recalculate hash of header.
+func (h *BlockHeader) UpdateHash() common.Hash {
+	if h == nil {
+		return common.Hash{}
+	}
+	h.hash = h.calculateHash()
+	return h.hash
+}
+
+func (h *BlockHeader) calculateHash() common.Hash {
+	raw, _ := rlp.EncodeToBytes(h)
+	return crypto.HashAtHeight(h.Height, raw)
+}
+
+func (h *BlockHeader) CalculateHash() common.Hash {
+	return h.calculateHash()
+}
+
+func (h *BlockHeader) String() string {
+	return fmt.Sprintf("{ChainID: %v, Epoch: %d, Hash: %v. Parent: %v, HCC: %v, Height: %v, TxHash: %v, StateHash: %v, Timestamp: %v, Proposer: %s, BaseFee: %v, NextValidatorSetHash: %v, VRFProof: %v}",
+		h.ChainID, h.Epoch, h.Hash().Hex(), h.Parent.Hex(), h.HCC, h.Height, h.TxHash.Hex(), h.StateHash.Hex(), h.Timestamp, h.Proposer, h.BaseFee, h.NextValidatorSetHash.Hex(), h.VRFProof)
+}
+
+// SignBytes returns raw bytes to be signed, bound to the signature domain of the chain at the
+// block height.
+func (h *BlockHeader) SignBytes() common.Bytes {
+	r := BlockHeader{
+		ChainID:     h.ChainID,
+		Epoch:       h.Epoch,
+		Height:      h.Height,
+		Parent:      h.Parent,
+		HCC:         h.HCC,
+		TxHash:      h.TxHash,
+		ReceiptHash: h.ReceiptHash,
+		Bloom:       h.Bloom,
+		StateHash:   h.StateHash,
+		Timestamp:   h.Timestamp,
+		Proposer:    h.Proposer,
+		BaseFee:     h.BaseFee,
+
+		NextValidatorSetHash: h.NextValidatorSetHash,
+		VRFProof:             h.VRFProof,
+	}
+	raw, _ := rlp.EncodeToBytes(r)
+	return domainSignBytes(h.ChainID, h.Height, raw)
+}
+
+// SetSignature sets given signature in header.
+func (h *BlockHeader) SetSignature(sig *crypto.Signature) {
+	h.Signature = sig
+}
+
+// Validate checks the header is legitimate.
+func (h *BlockHeader) Validate() result.Result {
+	if h.Parent.IsEmpty() {
+		return result.Error("Parent is empty")
+	}
+	if h.HCC.BlockHash.IsEmpty() {
+		return result.Error("HCC is empty")
+	}
+	if h.Timestamp == nil {
+		return result.Error("Timestamp is missing")
+	}
+	if h.Proposer.IsEmpty() {
+		return result.Error("Proposer is not specified")
+	}
+	if h.Signature == nil || h.Signature.IsEmpty() {
+		return result.Error("Block is not signed")
+	}
+	if !h.Signature.Verify(h.SignBytes(), h.Proposer) {
+		return result.Error("Signature verification failed")
+	}
+	return result.OK
+}
@@ -0,0 +1,59 @@
+// Package verifier verifies the block headers, their highest committed certificates (HCC), the
+// votes of the validators, and the finality certificates of the chain. It only depends on the
+// common, crypto and rlp packages, so that external Go programs, e.g. bridges and light clients,
+// can verify the chain without importing the node and its storage backends. The core package
+// aliases its types, so the node verifies the chain with the same code.
+//
+// The signatures of the blocks and the votes are bound to the hard forks of the chain, so an
+// external verifier needs to set the fork heights of the chain with SetForkHeights first.
+package verifier
+
+import (
+	"errors"
+	"fmt"
+)
+
+// VerifyHeader checks the header is a legitimate header of the chain signed by its proposer,
+// and its HCC, if it carries votes, is voted for by the validators holding more than 2/3 of the
+// stake of the validator set of the block. It does not check the header against its parent.
+func VerifyHeader(chainID string, header *BlockHeader, validators *ValidatorSet) error {
+	if header == nil {
+		return errors.New("Block header missing")
+	}
+	if header.ChainID != chainID {
+		return fmt.Errorf("Block is not for chain %v", chainID)
+	}
+	if res := header.Validate(); res.IsError() {
+		return fmt.Errorf("Invalid block %v: %v", header.Hash().Hex(), res.Message)
+	}
+	if !header.HCC.IsValid(validators) {
+		return fmt.Errorf("Invalid HCC of block %v", header.Hash().Hex())
+	}
+	return nil
+}
+
+// VerifyCommit checks the block is committed, i.e. the votes on the block are signed by the
+// validators holding more than 2/3 of the stake of the validator set of the block.
+func VerifyCommit(chainID string, header *BlockHeader, votes []Vote, validators *ValidatorSet) error {
+	if header == nil {
+		return errors.New("Block header missing")
+	}
+	return verifyCommitVotes(chainID, header, votes, validators)
+}
+
+// VerifyNextValidatorSet checks the validator set is the one of the blocks following the block
+// of the header, i.e. the header updates the validator set and commits to its hash. Combined with
+// VerifyCommit, a light client tracks the validator set transitions without the ledger state.
+func VerifyNextValidatorSet(header *BlockHeader, nextValidators *ValidatorSet) error {
+	if header == nil {
+		return errors.New("Block header missing")
+	}
+	if header.NextValidatorSetHash.IsEmpty() {
+		return fmt.Errorf("Block %v does not update the validator set", header.Hash().Hex())
+	}
+	if nextValidators == nil || nextValidators.Hash() != header.NextValidatorSetHash {
+		return fmt.Errorf("Validator set does not match the next validator set hash %v of block %v",
+			header.NextValidatorSetHash.Hex(), header.Hash().Hex())
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package verifier
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func TestVerifyHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "testchain_verifier"
+	SetForkHeights(chainID, []uint64{50})
+	defer SetForkHeights(chainID, nil)
+
+	proposerKey, _, _ := crypto.GenerateKeyPair()
+	var privKeys []*crypto.PrivateKey
+	validators := NewValidatorSet()
+	for i := 0; i < 4; i++ {
+		privKey, _, _ := crypto.GenerateKeyPair()
+		privKeys = append(privKeys, privKey)
+		validators.AddValidator(NewValidator(privKey.PublicKey().Address().Hex(), big.NewInt(100)))
+	}
+
+	newVotes := func(block common.Hash, height uint64, numVoters int) []Vote {
+		votes := []Vote{}
+		for _, privKey := range privKeys[:numVoters] {
+			vote := Vote{
+				Block:   block,
+				Height:  height,
+				Epoch:   height + 1,
+				ID:      privKey.PublicKey().Address(),
+				ChainID: chainID,
+			}
+			vote.Signature, _ = privKey.Sign(vote.SignBytes())
+			votes = append(votes, vote)
+		}
+		return votes
+	}
+	newHeader := func(parent common.Hash, height uint64, hccVotes []Vote) *BlockHeader {
+		header := &BlockHeader{
+			ChainID:   chainID,
+			Epoch:     height,
+			Height:    height,
+			Parent:    parent,
+			HCC:       CommitCertificate{BlockHash: parent},
+			Timestamp: big.NewInt(int64(height)),
+			Proposer:  proposerKey.PublicKey().Address(),
+		}
+		if hccVotes != nil {
+			header.HCC.Votes = NewVoteSet()
+			for _, vote := range hccVotes {
+				header.HCC.Votes.AddVote(vote)
+			}
+		}
+		header.Signature, _ = proposerKey.Sign(header.SignBytes())
+		return header
+	}
+
+	parent := common.HexToHash("a1")
+	header := newHeader(parent, 100, newVotes(parent, 99, 3))
+	assert.Nil(VerifyHeader(chainID, header, validators))
+	assert.NotNil(VerifyHeader("otherchain", header, validators))
+	assert.NotNil(VerifyHeader(chainID, nil, validators))
+
+	// The HCC needs the votes of more than 2/3 of the stake
+	assert.NotNil(VerifyHeader(chainID, newHeader(parent, 100, newVotes(parent, 99, 2)), validators))
+	assert.Nil(VerifyHeader(chainID, newHeader(parent, 100, nil), validators))
+
+	// The header signature is bound to the fork of the chain at the block height
+	SetForkHeights(chainID, []uint64{50, 100})
+	assert.NotNil(VerifyHeader(chainID, header, validators))
+	SetForkHeights(chainID, []uint64{50})
+
+	assert.Nil(VerifyCommit(chainID, header, newVotes(header.Hash(), 100, 3), validators))
+	assert.NotNil(VerifyCommit(chainID, header, newVotes(header.Hash(), 100, 2), validators))
+	assert.NotNil(VerifyCommit(chainID, header, newVotes(parent, 100, 3), validators))
+}
+
+func TestForkNumber(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "testchain_verifier_forks"
+	assert.Equal(uint64(0), ForkNumber(chainID, 1000))
+
+	SetForkHeights(chainID, []uint64{200, 100})
+	defer SetForkHeights(chainID, nil)
+	assert.Equal(uint64(0), ForkNumber(chainID, 99))
+	assert.Equal(uint64(1), ForkNumber(chainID, 100))
+	assert.Equal(uint64(2), ForkNumber(chainID, 200))
+	assert.Equal(chainID+"/fork2", SignatureDomain(chainID, 200))
+}
+
+func TestVerifyNextValidatorSet(t *testing.T) {
+	assert := assert.New(t)
+
+	v1 := NewValidator("0x1", big.NewInt(100))
+	v2 := NewValidator("0x2", big.NewInt(200))
+	validators := NewValidatorSet()
+	validators.SetValidators([]Validator{v2, v1})
+	sorted := NewValidatorSet()
+	sorted.AddValidator(v1)
+	sorted.AddValidator(v2)
+
+	// The hash does not depend on the order of the validators
+	assert.Equal(sorted.Hash(), validators.Hash())
+	assert.NotEqual(sorted.Hash(), NewValidatorSet().Hash())
+
+	header := &BlockHeader{ChainID: "testchain_verifier", Height: 10}
+	assert.NotNil(VerifyNextValidatorSet(header, validators))
+
+	header.NextValidatorSetHash = validators.Hash()
+	assert.Nil(VerifyNextValidatorSet(header, sorted))
+	other := NewValidatorSet()
+	other.AddValidator(NewValidator("0x1", big.NewInt(300)))
+	assert.NotNil(VerifyNextValidatorSet(header, other))
+	assert.NotNil(VerifyNextValidatorSet(header, nil))
+}
+
+func TestCompactCommitCertificate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID := "testchain_compact_hcc"
+	block := common.HexToHash("a1")
+	var privKeys []*crypto.PrivateKey
+	validators := NewValidatorSet()
+	for i := 0; i < 20; i++ {
+		privKey, _, _ := crypto.GenerateKeyPair()
+		privKeys = append(privKeys, privKey)
+		validators.AddValidator(NewValidator(privKey.PublicKey().Address().Hex(), big.NewInt(100)))
+	}
+	outsider, _, _ := crypto.GenerateKeyPair()
+
+	votes := NewVoteSet()
+	for i, privKey := range append(privKeys[:15:15], outsider) {
+		vote := Vote{Block: block, Height: 99, Epoch: 100 + uint64(i%2), ID: privKey.PublicKey().Address(), ChainID: chainID}
+		vote.Signature, _ = privKey.Sign(vote.SignBytes())
+		votes.AddVote(vote)
+	}
+	legacy := CommitCertificate{Votes: votes, BlockHash: block}
+	legacyBytes, err := rlp.EncodeToBytes(legacy)
+	require.Nil(err)
+
+	// The legacy encoding is unchanged, so are the hashes of the existing blocks
+	expected, err := rlp.EncodeToBytes(legacyCommitCertificate{Votes: votes, BlockHash: block})
+	require.Nil(err)
+	assert.Equal(expected, legacyBytes)
+	decoded := CommitCertificate{}
+	require.Nil(rlp.DecodeBytes(legacyBytes, &decoded))
+	assert.False(decoded.IsCompact())
+	assert.Equal(16, decoded.Votes.Size())
+
+	// The compact certificate drops the votes of the outsider and keeps the signatures
+	compact := legacy.Copy()
+	compact.Compact(validators)
+	assert.True(compact.IsCompact())
+	assert.Equal(15, compact.Votes.Size())
+	assert.True(compact.IsProven(validators))
+	compactBytes, err := rlp.EncodeToBytes(compact)
+	require.Nil(err)
+	assert.True(4*len(compactBytes) < 3*len(legacyBytes), "compact %v bytes, legacy %v bytes", len(compactBytes), len(legacyBytes))
+
+	decoded = CommitCertificate{}
+	require.Nil(rlp.DecodeBytes(compactBytes, &decoded))
+	assert.True(decoded.IsCompact())
+	assert.Nil(decoded.Votes)
+	assert.Equal(block, decoded.BlockHash)
+	reencoded, err := rlp.EncodeToBytes(decoded)
+	require.Nil(err)
+	assert.Equal(compactBytes, reencoded)
+
+	// The voters are resolved against the validator set of the block
+	assert.True(decoded.IsProven(validators))
+	assert.True(decoded.IsValid(validators))
+	require.Nil(decoded.ResolveVotes(validators))
+	assert.Equal(compact.Votes.Votes(), decoded.Votes.Votes())
+	assert.True(decoded.Votes.Validate().IsOK())
+
+	// A different validator set does not resolve to valid votes
+	others := NewValidatorSet()
+	for _, v := range validators.Validators()[1:] {
+		others.AddValidator(v)
+	}
+	others.AddValidator(NewValidator(outsider.PublicKey().Address().Hex(), big.NewInt(100)))
+	decoded = CommitCertificate{}
+	require.Nil(rlp.DecodeBytes(compactBytes, &decoded))
+	require.Nil(decoded.ResolveVotes(others))
+	assert.False(decoded.Votes.Validate().IsOK())
+	small := NewValidatorSet()
+	small.AddValidator(validators.Validators()[0])
+	decoded = CommitCertificate{}
+	require.Nil(rlp.DecodeBytes(compactBytes, &decoded))
+	assert.NotNil(decoded.ResolveVotes(small))
+	assert.False(decoded.IsValid(small))
+}
+
+func TestVoteSetEncodingChainID(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID := "testchain_legacy_votes"
+	votes := NewVoteSet()
+	for i := 0; i < 4; i++ {
+		privKey, _, _ := crypto.GenerateKeyPair()
+		vote := Vote{Block: common.HexToHash("a1"), Height: 99, Epoch: 100, ID: privKey.PublicKey().Address(), ChainID: chainID}
+		if i == 3 {
+			// Votes signed before the chain ID was part of the sign bytes
+			vote.ChainID = ""
+		}
+		vote.Signature, _ = privKey.Sign(vote.SignBytes())
+		votes.AddVote(vote)
+	}
+	require.True(votes.Validate().IsOK())
+
+	// The chain ID of the votes survives the round trip, so the signatures still verify
+	raw, err := rlp.EncodeToBytes(votes)
+	require.Nil(err)
+	decoded := NewVoteSet()
+	require.Nil(rlp.DecodeBytes(raw, decoded))
+	assert.Equal(votes.Votes(), decoded.Votes())
+	assert.True(decoded.Validate().IsOK())
+}
@@ -0,0 +1,361 @@
+package verifier
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// CommitCertificate represents a commit made a majority of validators. A certificate decoded
+// from the compact encoding carries its votes only once resolved against the validator set, see
+// ResolveVotes.
+type CommitCertificate struct {
+	Votes     *VoteSet `rlp:"nil"`
+	BlockHash common.Hash
+
+	compact *compactCommitCertificate // nil for the legacy encoding
+}
+
+// Copy creates a copy of this commit certificate.
+func (cc CommitCertificate) Copy() CommitCertificate {
+	ret := CommitCertificate{
+		BlockHash: cc.BlockHash,
+		compact:   cc.compact,
+	}
+	if cc.Votes != nil {
+		ret.Votes = cc.Votes.Copy()
+	}
+	return ret
+}
+
+func (cc CommitCertificate) String() string {
+	if cc.Votes == nil && cc.compact != nil {
+		return fmt.Sprintf("CC{BlockHash: %v, Voters: %x}", cc.BlockHash.Hex(), cc.compact.Voters)
+	}
+	return fmt.Sprintf("CC{BlockHash: %v, Votes: %v}", cc.BlockHash.Hex(), cc.Votes)
+}
+
+// IsValid checks if a CommitCertificate is in valid format. Note that we allow
+// CommitCertificate with nil voteset in block header.
+func (cc CommitCertificate) IsValid(validators *ValidatorSet) bool {
+	if err := cc.ResolveVotes(validators); err != nil {
+		return false
+	}
+	if cc.Votes == nil || cc.Votes.IsEmpty() {
+		return true
+	}
+	return cc.IsProven(validators)
+}
+
+// IsProven checks if a CommitCertificate contains supporting voteset.
+func (cc CommitCertificate) IsProven(validators *ValidatorSet) bool {
+	if err := cc.ResolveVotes(validators); err != nil {
+		return false
+	}
+	if cc.Votes == nil || cc.Votes.IsEmpty() {
+		return false
+	}
+
+	filtered := cc.Votes.UniqueVoter()
+	if filtered.Size() != cc.Votes.Size() {
+		return false
+	}
+
+	for _, vote := range filtered.Votes() {
+		if vote.Block != cc.BlockHash {
+			return false
+		}
+	}
+
+	return validators.HasMajority(filtered)
+}
+
+// Vote represents a vote on a block by a validaor.
+type Vote struct {
+	Block     common.Hash    // Hash of the tip as seen by the voter.
+	Height    uint64         // Height of the tip
+	Epoch     uint64         // Voter's current epoch. It doesn't need to equal the epoch in the block above.
+	ID        common.Address // Voter's address.
+	Signature *crypto.Signature
+	ChainID   string `rlp:"optional"` // Chain the vote is cast on, empty for the votes cast before it was added
+}
+
+func (v Vote) String() string {
+	return fmt.Sprintf("Vote{ID: %s, block: %s,  Epoch: %v}", v.ID, v.Block.Hex(), v.Epoch)
+}
+
+// SignBytes returns raw bytes to be signed. The votes carrying the chain ID are bound to the
+// signature domain of the chain at the vote height.
+func (v Vote) SignBytes() common.Bytes {
+	vv := Vote{
+		Block: v.Block,
+		Epoch: v.Epoch,
+		ID:    v.ID,
+	}
+	if len(v.ChainID) == 0 {
+		raw, _ := rlp.EncodeToBytes(vv)
+		return raw
+	}
+	vv.Height = v.Height
+	vv.ChainID = v.ChainID
+	raw, _ := rlp.EncodeToBytes(vv)
+	return domainSignBytes(v.ChainID, v.Height, raw)
+}
+
+// SetSignature sets given signature in vote.
+func (v *Vote) SetSignature(sig *crypto.Signature) {
+	v.Signature = sig
+}
+
+// Validate checks the vote is legitimate.
+func (v Vote) Validate() result.Result {
+	if v.ID.IsEmpty() {
+		return result.Error("Voter is not specified")
+	}
+	if v.Signature == nil || v.Signature.IsEmpty() {
+		return result.Error("Vote is not signed")
+	}
+	if !v.Signature.Verify(v.SignBytes(), v.ID) {
+		return result.Error("Signature verification failed")
+	}
+	return result.OK
+}
+
+// minParallelVotes is the number of votes below which ValidateVotes verifies the signatures in
+// the calling goroutine, since starting the workers would cost more than it saves.
+const minParallelVotes = 8
+
+// ValidateVotes validates the votes in one pass, e.g. the votes of a HCC or of a finality
+// certificate, and returns the result of each vote at the same index. An ECDSA signature can only
+// be verified by recovering its signer, so the signatures are not aggregated but verified in
+// parallel, which is what dominates the CPU usage of catching up with the chain.
+func ValidateVotes(votes []Vote) []result.Result {
+	results := make([]result.Result, len(votes))
+	numWorkers := runtime.GOMAXPROCS(0)
+	if max := len(votes) / minParallelVotes; numWorkers > max {
+		numWorkers = max
+	}
+	if numWorkers <= 1 {
+		for i, vote := range votes {
+			results[i] = vote.Validate()
+		}
+		return results
+	}
+
+	next := int64(-1)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(votes) {
+					return
+				}
+				results[i] = votes[i].Validate()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// voteKey identifies the votes of a validator on a block.
+type voteKey struct {
+	ID    common.Address
+	Block common.Hash
+}
+
+// VoteSet represents a set of votes on a proposal. It holds at most one vote per validator per
+// block, the one from the highest epoch, and tracks the highest-epoch vote of each validator.
+type VoteSet struct {
+	votes   map[voteKey]Vote           // Voter ID and block to vote
+	latest  map[common.Address]voteKey // Voter ID to the key of its highest-epoch vote
+	decoded []Vote                     // Votes as decoded from the legacy encoding, nil once changed
+}
+
+// NewVoteSet creates an instance of VoteSet.
+func NewVoteSet() *VoteSet {
+	return &VoteSet{
+		votes:  make(map[voteKey]Vote),
+		latest: make(map[common.Address]voteKey),
+	}
+}
+
+// Copy creates a copy of this vote set.
+func (s *VoteSet) Copy() *VoteSet {
+	ret := NewVoteSet()
+	for key, vote := range s.votes {
+		ret.votes[key] = vote
+	}
+	for id, key := range s.latest {
+		ret.latest[id] = key
+	}
+	ret.decoded = s.decoded
+	return ret
+}
+
+// AddVote adds a vote to vote set. A vote replaces the vote of the same validator on the same
+// block only if it is from a higher epoch, so duplicate votes are ignored. Returns whether the
+// vote set has changed.
+func (s *VoteSet) AddVote(vote Vote) bool {
+	key := voteKey{ID: vote.ID, Block: vote.Block}
+	if prev, ok := s.votes[key]; ok && prev.Epoch >= vote.Epoch {
+		return false
+	}
+	s.votes[key] = vote
+	s.decoded = nil
+
+	if latestKey, ok := s.latest[vote.ID]; ok {
+		latest := s.votes[latestKey]
+		if latest.Epoch > vote.Epoch {
+			return true
+		}
+		// Ties between blocks are broken by block hash so that the merged vote sets converge
+		// regardless of the order the votes are added.
+		if latest.Epoch == vote.Epoch && bytes.Compare(latest.Block[:], vote.Block[:]) <= 0 {
+			return true
+		}
+	}
+	s.latest[vote.ID] = key
+	return true
+}
+
+// AddVotes adds all the votes of another vote set, e.g. received from a peer, to this vote set.
+// Returns the number of votes that changed this vote set.
+func (s *VoteSet) AddVotes(another *VoteSet) int {
+	if another == nil {
+		return 0
+	}
+	added := 0
+	for _, vote := range another.votes {
+		if s.AddVote(vote) {
+			added++
+		}
+	}
+	return added
+}
+
+// LatestVote returns the highest-epoch vote of the validator.
+func (s *VoteSet) LatestVote(id common.Address) (Vote, bool) {
+	key, ok := s.latest[id]
+	if !ok {
+		return Vote{}, false
+	}
+	return s.votes[key], true
+}
+
+// Size returns the number of votes in the vote set.
+func (s *VoteSet) Size() int {
+	return len(s.votes)
+}
+
+// IsEmpty returns wether the vote set is empty.
+func (s *VoteSet) IsEmpty() bool {
+	return s.Size() == 0
+}
+
+// Votes return a slice of votes in the vote set.
+func (s *VoteSet) Votes() []Vote {
+	ret := make([]Vote, 0, len(s.votes))
+	for _, v := range s.votes {
+		ret = append(ret, v)
+	}
+	sort.Sort(VoteByID(ret))
+	return ret
+}
+
+// Validate checks the vote set is legitimate. A vote set decoded from the legacy encoding is
+// re-encoded with all the votes it was decoded with, duplicates and stale votes included, so all
+// of them are checked.
+func (s *VoteSet) Validate() result.Result {
+	votes := s.decoded
+	if votes == nil {
+		votes = s.Votes()
+	}
+	for i, res := range ValidateVotes(votes) {
+		if res.IsError() {
+			return result.Error("Contains invalid vote: %s", votes[i].String())
+		}
+	}
+	return result.OK
+}
+
+func (s *VoteSet) String() string {
+	return fmt.Sprintf("%v", s.Votes())
+}
+
+var _ rlp.Encoder = (*VoteSet)(nil)
+
+// EncodeRLP implements RLP Encoder interface. A vote set decoded from the legacy encoding is
+// re-encoded with the votes it was decoded with until it changes, so that the hash of the block
+// header carrying it does not change.
+func (s *VoteSet) EncodeRLP(w io.Writer) error {
+	if s == nil {
+		return rlp.Encode(w, []Vote{})
+	}
+	if s.decoded != nil {
+		return rlp.Encode(w, s.decoded)
+	}
+	return rlp.Encode(w, s.Votes())
+}
+
+var _ rlp.Decoder = (*VoteSet)(nil)
+
+// DecodeRLP implements RLP Decoder interface.
+func (s *VoteSet) DecodeRLP(stream *rlp.Stream) error {
+	votes := []Vote{}
+	if err := stream.Decode(&votes); err != nil {
+		return err
+	}
+	*s = *NewVoteSet()
+	for _, vote := range votes {
+		s.AddVote(vote)
+	}
+	s.decoded = votes
+	return nil
+}
+
+// Merge combines two vote sets.
+func (s *VoteSet) Merge(another *VoteSet) *VoteSet {
+	ret := s.Copy()
+	ret.AddVotes(another)
+	return ret
+}
+
+// UniqueVoterAndBlock consolidate vote set by removing votes from the same voter to same block
+// in older epoches. Since AddVote already does so, it simply returns a copy of the vote set.
+func (s *VoteSet) UniqueVoterAndBlock() *VoteSet {
+	return s.Copy()
+}
+
+// UniqueVoter consolidate vote set by removing votes from the same voter in older epoches.
+func (s *VoteSet) UniqueVoter() *VoteSet {
+	ret := NewVoteSet()
+	for _, key := range s.latest {
+		ret.AddVote(s.votes[key])
+	}
+	return ret
+}
+
+// VoteByID implements sort.Interface for []Vote based on Voter's ID.
+type VoteByID []Vote
+
+func (a VoteByID) Len() int      { return len(a) }
+func (a VoteByID) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a VoteByID) Less(i, j int) bool {
+	if c := bytes.Compare(a[i].ID.Bytes(), a[j].ID.Bytes()); c != 0 {
+		return c < 0
+	}
+	return bytes.Compare(a[i].Block[:], a[j].Block[:]) < 0
+}
@@ -128,7 +128,7 @@ func TestDedup(t *testing.T) {
 	})
 
 	votes := votes1.Merge(votes2)
-	assert.Equal(6, len(votes.Votes()))
+	assert.Equal(5, len(votes.Votes()))
 
 	res := votes.UniqueVoterAndBlock()
 	assert.Equal(5, len(res.Votes()))
@@ -140,6 +140,172 @@ func TestDedup(t *testing.T) {
 	assert.Equal(uint64(5), v.Epoch)
 }
 
+func TestVoteSetLatestVote(t *testing.T) {
+	assert := assert.New(t)
+
+	id := common.HexToAddress("A1")
+	b1 := CreateTestBlock("B1", "").Hash()
+	b2 := CreateTestBlock("B2", "").Hash()
+
+	votes := NewVoteSet()
+	assert.True(votes.AddVote(Vote{Block: b1, ID: id, Epoch: 3}))
+	assert.False(votes.AddVote(Vote{Block: b1, ID: id, Epoch: 3}))
+	assert.False(votes.AddVote(Vote{Block: b1, ID: id, Epoch: 2}))
+	assert.True(votes.AddVote(Vote{Block: b2, ID: id, Epoch: 1}))
+	assert.Equal(2, votes.Size())
+
+	latest, ok := votes.LatestVote(id)
+	assert.True(ok)
+	assert.Equal(b1, latest.Block)
+	assert.Equal(uint64(3), latest.Epoch)
+
+	assert.True(votes.AddVote(Vote{Block: b2, ID: id, Epoch: 4}))
+	latest, _ = votes.LatestVote(id)
+	assert.Equal(b2, latest.Block)
+	assert.Equal(2, votes.Size())
+
+	_, ok = votes.LatestVote(common.HexToAddress("A2"))
+	assert.False(ok)
+}
+
+func TestVoteSetMergeFromPeers(t *testing.T) {
+	assert := assert.New(t)
+
+	b1 := CreateTestBlock("B1", "").Hash()
+	b2 := CreateTestBlock("B2", "").Hash()
+	v1 := Vote{Block: b1, ID: common.HexToAddress("A1"), Epoch: 5}
+	v2 := Vote{Block: b2, ID: common.HexToAddress("A1"), Epoch: 5}
+	v3 := Vote{Block: b1, ID: common.HexToAddress("A2"), Epoch: 5}
+
+	peer1 := NewVoteSet()
+	peer1.AddVote(v1)
+	peer1.AddVote(v3)
+	peer2 := NewVoteSet()
+	peer2.AddVote(v2)
+	peer2.AddVote(v3)
+
+	// The merged vote sets are the same regardless of the merge order.
+	merged1 := NewVoteSet()
+	assert.Equal(2, merged1.AddVotes(peer1))
+	assert.Equal(1, merged1.AddVotes(peer2))
+	merged2 := peer2.Merge(peer1)
+	assert.Equal(merged1.Votes(), merged2.Votes())
+	assert.Equal(merged1.UniqueVoter().Votes(), merged2.UniqueVoter().Votes())
+	assert.Equal(2, merged1.UniqueVoter().Size())
+
+	// Merging does not modify the merged vote sets.
+	assert.Equal(2, peer1.Size())
+	assert.Equal(2, peer2.Size())
+}
+
+func TestVoteSetLegacyEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	block := CreateTestBlock("B1", "").Hash()
+	votes := NewVoteSet()
+	legacy := []Vote{}
+	for i := 0; i < 10; i++ {
+		vote := Vote{Block: block, Height: 10, ID: common.BigToAddress(big.NewInt(int64(i + 1))), Epoch: 20}
+		votes.AddVote(vote)
+		legacy = append(legacy, vote)
+	}
+	// Duplicates are not encoded.
+	legacy = append(legacy, legacy[0])
+
+	// Vote sets are encoded as the legacy list of votes.
+	encoded, err := rlp.EncodeToBytes(votes)
+	assert.Nil(err)
+	expected, err := rlp.EncodeToBytes(votes.Votes())
+	assert.Nil(err)
+	assert.Equal(expected, encoded)
+
+	legacyBytes, err := rlp.EncodeToBytes(legacy)
+	assert.Nil(err)
+	decoded := NewVoteSet()
+	assert.Nil(rlp.DecodeBytes(legacyBytes, decoded))
+	decodedBytes, err := rlp.EncodeToBytes(decoded.Votes())
+	assert.Nil(err)
+	assert.Equal(expected, decodedBytes)
+
+	// A legacy vote set is re-encoded as decoded, duplicates and stale votes included, so that
+	// the hash of the header carrying it does not change.
+	stale := legacy[1]
+	stale.Epoch = 19
+	staleBytes, err := rlp.EncodeToBytes(append(legacy, stale))
+	assert.Nil(err)
+	decodedVotes := NewVoteSet()
+	assert.Nil(rlp.DecodeBytes(staleBytes, decodedVotes))
+	assert.Equal(votes.Size(), decodedVotes.Size())
+	reencoded, err := rlp.EncodeToBytes(decodedVotes)
+	assert.Nil(err)
+	assert.Equal(staleBytes, reencoded)
+
+	header := CreateTestBlock("B2", "").BlockHeader
+	header.HCC = CommitCertificate{Votes: decodedVotes, BlockHash: block}
+	headerBytes, err := rlp.EncodeToBytes(header)
+	assert.Nil(err)
+	decodedHeader := &BlockHeader{}
+	assert.Nil(rlp.DecodeBytes(headerBytes, decodedHeader))
+	assert.Equal(header.UpdateHash(), decodedHeader.Hash())
+	reencoded, err = rlp.EncodeToBytes(decodedHeader)
+	assert.Nil(err)
+	assert.Equal(headerBytes, reencoded)
+
+	// The vote set is encoded from its votes once it changes.
+	decodedVotes.AddVote(Vote{Block: block, Height: 10, ID: common.BigToAddress(big.NewInt(100)), Epoch: 20})
+	reencoded, err = rlp.EncodeToBytes(decodedVotes)
+	assert.Nil(err)
+	expected, err = rlp.EncodeToBytes(decodedVotes.Votes())
+	assert.Nil(err)
+	assert.Equal(expected, reencoded)
+
+	// Empty and nil vote sets are encoded alike in headers.
+	cc := CommitCertificate{Votes: NewVoteSet(), BlockHash: block}
+	b, err := rlp.EncodeToBytes(cc)
+	assert.Nil(err)
+	nilBytes, err := rlp.EncodeToBytes(CommitCertificate{BlockHash: block})
+	assert.Nil(err)
+	assert.Equal(nilBytes, b)
+	decodedCC := CommitCertificate{}
+	assert.Nil(rlp.DecodeBytes(b, &decodedCC))
+	assert.True(decodedCC.Votes.IsEmpty())
+}
+
+func TestVoteSetValidateDecodedVotes(t *testing.T) {
+	assert := assert.New(t)
+
+	block := CreateTestBlock("B1", "").Hash()
+	privKey, _, _ := crypto.GenerateKeyPair()
+	vote := Vote{Block: block, Height: 10, ID: privKey.PublicKey().Address(), Epoch: 20}
+	vote.Signature, _ = privKey.Sign(vote.SignBytes())
+
+	// The stale votes of a decoded vote set are hashed along with the header, so their signatures
+	// are checked even though the vote set only holds the latest vote.
+	stale := vote
+	stale.Epoch = 19
+	staleBytes, err := rlp.EncodeToBytes([]Vote{vote, stale})
+	assert.Nil(err)
+	decoded := NewVoteSet()
+	assert.Nil(rlp.DecodeBytes(staleBytes, decoded))
+	assert.Equal([]Vote{vote}, decoded.Votes())
+	assert.True(decoded.Validate().IsError())
+
+	stale.Signature, _ = privKey.Sign(stale.SignBytes())
+	staleBytes, err = rlp.EncodeToBytes([]Vote{vote, stale})
+	assert.Nil(err)
+	assert.Nil(rlp.DecodeBytes(staleBytes, decoded))
+	assert.True(decoded.Validate().IsOK())
+
+	// So are the duplicates.
+	forged := vote
+	forged.Signature, _ = privKey.Sign(stale.SignBytes())
+	duplicateBytes, err := rlp.EncodeToBytes([]Vote{vote, forged})
+	assert.Nil(err)
+	assert.Nil(rlp.DecodeBytes(duplicateBytes, decoded))
+	assert.Equal(1, decoded.Size())
+	assert.True(decoded.Validate().IsError())
+}
+
 func TestCommitCertificate(t *testing.T) {
 	assert := assert.New(t)
 
@@ -224,3 +390,42 @@ func TestCommitCertificate(t *testing.T) {
 	assert.False(cc.IsValid(vs))
 	assert.False(cc.IsProven(vs))
 }
+
+func createSignedTestVotes(n int) []Vote {
+	block := CreateTestBlock("B1", "").Hash()
+	votes := []Vote{}
+	for i := 0; i < n; i++ {
+		privKey, _, _ := crypto.GenerateKeyPair()
+		vote := Vote{Block: block, Height: 10, ID: privKey.PublicKey().Address(), Epoch: 20}
+		sig, _ := privKey.Sign(vote.SignBytes())
+		vote.SetSignature(sig)
+		votes = append(votes, vote)
+	}
+	return votes
+}
+
+func TestValidateVotes(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, n := range []int{3, 50} {
+		votes := createSignedTestVotes(n)
+		votes[1].ID = common.HexToAddress("A1")
+		votes[2].Signature = nil
+
+		results := ValidateVotes(votes)
+		assert.Equal(n, len(results))
+		for i, res := range results {
+			assert.Equal(i != 1 && i != 2, res.IsOK(), "vote %v of %v", i, n)
+			assert.Equal(votes[i].Validate().IsOK(), res.IsOK())
+		}
+	}
+	assert.Equal(0, len(ValidateVotes([]Vote{})))
+}
+
+func BenchmarkValidateVotes(b *testing.B) {
+	votes := createSignedTestVotes(100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ValidateVotes(votes)
+	}
+}
@@ -0,0 +1,332 @@
+// Package bls implements BLS signatures over the BLS12-381 curve, following the minimal signature
+// size variant of the IETF BLS signature scheme with proofs of possession. Signatures live in G1
+// and public keys in G2, so that signatures are short and can be aggregated by simple point
+// addition. Public keys must come with a proof of possession (PoP) before they are aggregated, to
+// prevent rogue key attacks. The curve arithmetic, the hashing to the curve (RFC 9380) and the
+// compressed point encoding are provided by blst.
+package bls
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	blst "github.com/supranational/blst/bindings/go"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/hexutil"
+	"github.com/thetatoken/theta/rlp"
+)
+
+const (
+	// PublicKeyLength is the length of a marshalled public key, i.e. a compressed G2 point
+	PublicKeyLength = blst.BLST_P2_COMPRESS_BYTES
+
+	// SignatureLength is the length of a marshalled signature, i.e. a compressed G1 point
+	SignatureLength = blst.BLST_P1_COMPRESS_BYTES
+
+	// SeedLength is the minimal length of the seed a secret key is derived from
+	SeedLength = 32
+)
+
+var (
+	// Domain separation tags of the IETF ciphersuites, so that a signature can never be replayed
+	// as a proof of possession
+	dstSignature = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+	dstPop       = []byte("BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+
+	errInvalidPublicKey = errors.New("bls: invalid public key")
+	errInvalidSignature = errors.New("bls: invalid signature")
+)
+
+//
+// ------- SecretKey ------- //
+//
+
+// SecretKey is a BLS secret key
+type SecretKey struct {
+	sk *blst.SecretKey
+}
+
+// RandKey generates a random secret key
+func RandKey() (*SecretKey, error) {
+	return randKey(rand.Reader)
+}
+
+func randKey(r io.Reader) (*SecretKey, error) {
+	seed := make([]byte, SeedLength)
+	if _, err := io.ReadFull(r, seed); err != nil {
+		return nil, err
+	}
+	return GenKeyFromSeed(seed), nil
+}
+
+// GenKeyFromSeed deterministically derives a secret key from the given seed, e.g. the node
+// private key, so that the BLS key does not need to be stored separately. The key is derived with
+// the IETF KeyGen, which needs a seed of at least SeedLength bytes.
+func GenKeyFromSeed(seed []byte) *SecretKey {
+	sk := blst.KeyGen(seed)
+	if sk == nil {
+		panic("bls: the seed must be at least 32 bytes long")
+	}
+	return &SecretKey{sk: sk}
+}
+
+// PublicKey returns the public key of the secret key
+func (sk *SecretKey) PublicKey() *PublicKey {
+	return &PublicKey{p: new(blst.P2Affine).From(sk.sk)}
+}
+
+// Sign signs the message
+func (sk *SecretKey) Sign(msg []byte) *Signature {
+	return &Signature{p: new(blst.P1Affine).Sign(sk.sk, msg, dstSignature)}
+}
+
+// PopProve creates the proof of possession of the secret key
+func (sk *SecretKey) PopProve() *Signature {
+	return &Signature{p: new(blst.P1Affine).Sign(sk.sk, sk.PublicKey().ToBytes(), dstPop)}
+}
+
+//
+// ------- Signer ------- //
+//
+
+// Signer signs with a BLS secret key. SecretKey signs locally, and other implementations can
+// keep the key elsewhere, e.g. in a remote signing service, without changing the callers.
+type Signer interface {
+	PublicKey() *PublicKey
+	Sign(msg []byte) *Signature
+	PopProve() *Signature
+}
+
+var _ Signer = (*SecretKey)(nil)
+
+//
+// ------- PublicKey ------- //
+//
+
+// PublicKey is a BLS public key, or an aggregation of public keys
+type PublicKey struct {
+	p *blst.P2Affine
+}
+
+var _ rlp.Encoder = (*PublicKey)(nil)
+var _ rlp.Decoder = (*PublicKey)(nil)
+
+// PublicKeyFromBytes parses a marshalled public key
+func PublicKeyFromBytes(data []byte) (*PublicKey, error) {
+	if len(data) != PublicKeyLength {
+		return nil, errInvalidPublicKey
+	}
+	p := new(blst.P2Affine).Uncompress(data)
+	if p == nil {
+		return nil, errInvalidPublicKey
+	}
+	pk := &PublicKey{p: p}
+	if !pk.isValid() {
+		return nil, errInvalidPublicKey
+	}
+	return pk, nil
+}
+
+// isValid checks the key is in the prime order subgroup of G2, and is not the identity
+func (pk *PublicKey) isValid() bool {
+	return pk.p.KeyValidate()
+}
+
+// isInfinity checks whether the key is the identity of G2, e.g. an aggregate of opposite keys
+func (pk *PublicKey) isInfinity() bool {
+	return pk.p.Equals(new(blst.P2Affine))
+}
+
+// ToBytes marshals the public key
+func (pk *PublicKey) ToBytes() common.Bytes {
+	return pk.p.Compress()
+}
+
+// Copy returns a copy of the public key
+func (pk *PublicKey) Copy() *PublicKey {
+	p := *pk.p
+	return &PublicKey{p: &p}
+}
+
+// Aggregate adds the other public key to this one
+func (pk *PublicKey) Aggregate(other *PublicKey) *PublicKey {
+	sum := new(blst.P2)
+	sum.FromAffine(pk.p)
+	pk.p = sum.AddAssign(other.p).ToAffine()
+	return pk
+}
+
+// ScalarMult returns the public key multiplied by k, which verifies a signature aggregated k times
+func (pk *PublicKey) ScalarMult(k uint32) *PublicKey {
+	var scalar [4]byte
+	binary.LittleEndian.PutUint32(scalar[:], k)
+	p := new(blst.P2)
+	p.FromAffine(pk.p)
+	return &PublicKey{p: p.MultAssign(scalar[:]).ToAffine()}
+}
+
+// Equals returns true if both public keys are the same
+func (pk *PublicKey) Equals(other *PublicKey) bool {
+	if pk == nil || other == nil {
+		return pk == other
+	}
+	return pk.p.Equals(other.p)
+}
+
+// PopVerify verifies the proof of possession of the secret key of the public key
+func (pk *PublicKey) PopVerify(pop *Signature) bool {
+	if pop == nil || pop.isInfinity() {
+		return false
+	}
+	return pop.p.Verify(false, pk.p, true, pk.ToBytes(), dstPop)
+}
+
+// EncodeRLP implements RLP Encoder interface.
+func (pk *PublicKey) EncodeRLP(w io.Writer) error {
+	if pk == nil {
+		return rlp.Encode(w, []byte{})
+	}
+	return rlp.Encode(w, pk.ToBytes())
+}
+
+// DecodeRLP implements RLP Decoder interface.
+func (pk *PublicKey) DecodeRLP(stream *rlp.Stream) error {
+	var b []byte
+	if err := stream.Decode(&b); err != nil {
+		return err
+	}
+	parsed, err := PublicKeyFromBytes(b)
+	if err != nil {
+		return err
+	}
+	*pk = *parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (pk *PublicKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hexutil.Bytes(pk.ToBytes()))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (pk *PublicKey) UnmarshalJSON(data []byte) error {
+	raw := &hexutil.Bytes{}
+	if err := raw.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	parsed, err := PublicKeyFromBytes(*raw)
+	if err != nil {
+		return err
+	}
+	*pk = *parsed
+	return nil
+}
+
+//
+// ------- Signature ------- //
+//
+
+// Signature is a BLS signature, or an aggregation of signatures
+type Signature struct {
+	p *blst.P1Affine
+}
+
+var _ rlp.Encoder = (*Signature)(nil)
+var _ rlp.Decoder = (*Signature)(nil)
+
+// NewAggregateSignature returns an empty aggregate signature, i.e. the identity of G1
+func NewAggregateSignature() *Signature {
+	return &Signature{p: new(blst.P1Affine)}
+}
+
+// SignatureFromBytes parses a marshalled signature. The identity is accepted, as the encoding of
+// an empty aggregate signature, but never verifies.
+func SignatureFromBytes(data []byte) (*Signature, error) {
+	if len(data) != SignatureLength {
+		return nil, errInvalidSignature
+	}
+	p := new(blst.P1Affine).Uncompress(data)
+	if p == nil || !p.SigValidate(false) {
+		return nil, errInvalidSignature
+	}
+	return &Signature{p: p}, nil
+}
+
+// isInfinity checks whether the signature is the identity of G1
+func (sig *Signature) isInfinity() bool {
+	return sig.p.Equals(new(blst.P1Affine))
+}
+
+// ToBytes marshals the signature
+func (sig *Signature) ToBytes() common.Bytes {
+	return sig.p.Compress()
+}
+
+// Copy returns a copy of the signature
+func (sig *Signature) Copy() *Signature {
+	p := *sig.p
+	return &Signature{p: &p}
+}
+
+// Aggregate adds the other signature to this one
+func (sig *Signature) Aggregate(other *Signature) *Signature {
+	sum := new(blst.P1)
+	sum.FromAffine(sig.p)
+	sig.p = sum.AddAssign(other.p).ToAffine()
+	return sig
+}
+
+// Verify verifies the signature, or the aggregate signature of the same message, against the
+// public key, or the aggregate of the public keys of the signers. The points have been checked to
+// be in their subgroup when they were parsed.
+func (sig *Signature) Verify(pk *PublicKey, msg []byte) bool {
+	if pk == nil || pk.isInfinity() || sig.isInfinity() {
+		return false
+	}
+	return sig.p.Verify(false, pk.p, false, msg, dstSignature)
+}
+
+// EncodeRLP implements RLP Encoder interface.
+func (sig *Signature) EncodeRLP(w io.Writer) error {
+	if sig == nil {
+		return rlp.Encode(w, []byte{})
+	}
+	return rlp.Encode(w, sig.ToBytes())
+}
+
+// DecodeRLP implements RLP Decoder interface.
+func (sig *Signature) DecodeRLP(stream *rlp.Stream) error {
+	var b []byte
+	if err := stream.Decode(&b); err != nil {
+		return err
+	}
+	parsed, err := SignatureFromBytes(b)
+	if err != nil {
+		return err
+	}
+	*sig = *parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (sig *Signature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hexutil.Bytes(sig.ToBytes()))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (sig *Signature) UnmarshalJSON(data []byte) error {
+	raw := &hexutil.Bytes{}
+	if err := raw.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	parsed, err := SignatureFromBytes(*raw)
+	if err != nil {
+		return err
+	}
+	*sig = *parsed
+	return nil
+}
@@ -0,0 +1,189 @@
+package bls
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	blst "github.com/supranational/blst/bindings/go"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func TestSignVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	sk, err := RandKey()
+	assert.Nil(err)
+	pk := sk.PublicKey()
+
+	msg := common.Bytes("checkpoint")
+	sig := sk.Sign(msg)
+	assert.True(sig.Verify(pk, msg))
+	assert.False(sig.Verify(pk, common.Bytes("another checkpoint")))
+
+	sk2, err := RandKey()
+	assert.Nil(err)
+	assert.False(sig.Verify(sk2.PublicKey(), msg))
+
+	// An empty signature never verifies
+	assert.False(NewAggregateSignature().Verify(pk, msg))
+}
+
+func TestGenKeyFromSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	seed := common.Bytes("node private key of 32 bytes....")
+	sk1 := GenKeyFromSeed(seed)
+	sk2 := GenKeyFromSeed(seed)
+	assert.True(sk1.PublicKey().Equals(sk2.PublicKey()))
+
+	sk3 := GenKeyFromSeed(common.Bytes("another node private key of 32 bytes"))
+	assert.False(sk1.PublicKey().Equals(sk3.PublicKey()))
+
+	// The IETF KeyGen needs enough key material
+	assert.Panics(func() { GenKeyFromSeed(common.Bytes("node private key")) })
+}
+
+func TestAggregate(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := common.Bytes("checkpoint")
+	aggPk := (*PublicKey)(nil)
+	aggSig := NewAggregateSignature()
+	for i := 0; i < 5; i++ {
+		sk, err := RandKey()
+		assert.Nil(err)
+		if aggPk == nil {
+			aggPk = sk.PublicKey()
+		} else {
+			aggPk.Aggregate(sk.PublicKey())
+		}
+		aggSig.Aggregate(sk.Sign(msg))
+	}
+	assert.True(aggSig.Verify(aggPk, msg))
+
+	// A signer counted twice needs to be signed for twice
+	sk, err := RandKey()
+	assert.Nil(err)
+	sig := sk.Sign(msg)
+	doublePk := sk.PublicKey().Aggregate(sk.PublicKey())
+	assert.False(sig.Copy().Verify(doublePk, msg))
+	assert.True(sig.Copy().Aggregate(sig).Verify(doublePk, msg))
+
+	triplePk := sk.PublicKey().ScalarMult(3)
+	assert.False(sig.Copy().Aggregate(sig).Verify(triplePk, msg))
+	assert.True(sig.Copy().Aggregate(sig).Aggregate(sig).Verify(triplePk, msg))
+}
+
+func TestPop(t *testing.T) {
+	assert := assert.New(t)
+
+	sk, err := RandKey()
+	assert.Nil(err)
+	pk := sk.PublicKey()
+	pop := sk.PopProve()
+	assert.True(pk.PopVerify(pop))
+
+	// A signature over the public key is not a proof of possession
+	assert.False(pk.PopVerify(sk.Sign(pk.ToBytes())))
+
+	sk2, err := RandKey()
+	assert.Nil(err)
+	assert.False(sk2.PublicKey().PopVerify(pop))
+	assert.False(pk.PopVerify(nil))
+}
+
+// wrappedSigner is a Signer wrapping the secret key, as a remote signer would
+type wrappedSigner struct {
+	sk *SecretKey
+}
+
+func (s *wrappedSigner) PublicKey() *PublicKey      { return s.sk.PublicKey() }
+func (s *wrappedSigner) Sign(msg []byte) *Signature { return s.sk.Sign(msg) }
+func (s *wrappedSigner) PopProve() *Signature       { return s.sk.PopProve() }
+
+func TestSigner(t *testing.T) {
+	assert := assert.New(t)
+
+	sk, err := RandKey()
+	assert.Nil(err)
+	msg := common.Bytes("checkpoint")
+
+	for _, signer := range []Signer{sk, &wrappedSigner{sk: sk}} {
+		pk := signer.PublicKey()
+		assert.True(signer.Sign(msg).Verify(pk, msg))
+		assert.True(pk.PopVerify(signer.PopProve()))
+	}
+}
+
+func TestInvalidPublicKey(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := PublicKeyFromBytes(make([]byte, PublicKeyLength))
+	assert.NotNil(err)
+
+	_, err = PublicKeyFromBytes(make([]byte, 10))
+	assert.NotNil(err)
+
+	_, err = SignatureFromBytes(make([]byte, 10))
+	assert.NotNil(err)
+
+	// The identity is not a valid public key, but encodes an empty aggregate signature
+	identity := append([]byte{0xc0}, make([]byte, PublicKeyLength-1)...)
+	_, err = PublicKeyFromBytes(identity)
+	assert.NotNil(err)
+	sig, err := SignatureFromBytes(identity[:SignatureLength])
+	assert.Nil(err)
+	assert.Equal(NewAggregateSignature().ToBytes(), sig.ToBytes())
+}
+
+func TestPointEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	// The public key of the secret key 1 is the generator of G2, in the compressed ZCash encoding
+	one := make([]byte, 32)
+	one[31] = 1
+	sk := &SecretKey{sk: new(blst.SecretKey).Deserialize(one)}
+	assert.Equal("93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e"+
+		"024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8",
+		hex.EncodeToString(sk.PublicKey().ToBytes()))
+
+	sig := sk.Sign(common.Bytes("checkpoint"))
+	assert.Equal(SignatureLength, len(sig.ToBytes()))
+	assert.True(sig.Verify(sk.PublicKey(), common.Bytes("checkpoint")))
+}
+
+func TestSerialization(t *testing.T) {
+	assert := assert.New(t)
+
+	sk, err := RandKey()
+	assert.Nil(err)
+	pk := sk.PublicKey()
+	sig := sk.Sign(common.Bytes("checkpoint"))
+
+	raw, err := rlp.EncodeToBytes(pk)
+	assert.Nil(err)
+	pk2 := &PublicKey{}
+	assert.Nil(rlp.DecodeBytes(raw, pk2))
+	assert.True(pk.Equals(pk2))
+
+	raw, err = rlp.EncodeToBytes(sig)
+	assert.Nil(err)
+	sig2 := &Signature{}
+	assert.Nil(rlp.DecodeBytes(raw, sig2))
+	assert.Equal(sig.ToBytes(), sig2.ToBytes())
+
+	raw, err = json.Marshal(pk)
+	assert.Nil(err)
+	pk3 := &PublicKey{}
+	assert.Nil(json.Unmarshal(raw, pk3))
+	assert.True(pk.Equals(pk3))
+
+	raw, err = json.Marshal(sig)
+	assert.Nil(err)
+	sig3 := &Signature{}
+	assert.Nil(json.Unmarshal(raw, sig3))
+	assert.Equal(sig.ToBytes(), sig3.ToBytes())
+}
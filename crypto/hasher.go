@@ -0,0 +1,136 @@
+package crypto
+
+import (
+	"fmt"
+	"hash"
+	"sort"
+	"sync"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto/sha3"
+)
+
+//
+// ----------------------------- Hasher APIs ----------------------------- //
+//
+
+// HashAlgorithm identifies a hash function. The values are persisted as part of the protocol
+// and must never be reused.
+type HashAlgorithm byte
+
+const (
+	HashAlgorithmKeccak256 HashAlgorithm = iota
+)
+
+// Hasher is a versioned hash function used for block hashes, tx hashes and the state trie.
+type Hasher interface {
+	Algorithm() HashAlgorithm
+	New() hash.Hash
+	Hash(data ...[]byte) common.Hash
+}
+
+// HashUpgrade activates the hash algorithm at the given block height.
+type HashUpgrade struct {
+	Height    uint64
+	Algorithm HashAlgorithm
+}
+
+var (
+	hasherMu       sync.RWMutex
+	hashers        = map[HashAlgorithm]Hasher{}
+	hashSchedule   []HashUpgrade
+	defaultHashing = []HashUpgrade{{Height: 0, Algorithm: HashAlgorithmKeccak256}}
+)
+
+func init() {
+	RegisterHasher(keccak256Hasher{})
+	hashSchedule = defaultHashing
+}
+
+// RegisterHasher makes a hash algorithm available to the hash schedule.
+func RegisterHasher(h Hasher) {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+
+	hashers[h.Algorithm()] = h
+}
+
+// GetHasher returns the hasher of the given algorithm.
+func GetHasher(algorithm HashAlgorithm) (Hasher, error) {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+
+	h, ok := hashers[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("Hash algorithm %v is not registered", algorithm)
+	}
+	return h, nil
+}
+
+// SetHashSchedule sets the hash algorithm upgrade heights. The schedule must start at height 0
+// and only refer to registered algorithms.
+func SetHashSchedule(schedule []HashUpgrade) error {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+
+	sorted := make([]HashUpgrade, len(schedule))
+	copy(sorted, schedule)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Height < sorted[j].Height })
+
+	if len(sorted) == 0 || sorted[0].Height != 0 {
+		return fmt.Errorf("Hash schedule must start at height 0")
+	}
+	for i, upgrade := range sorted {
+		if _, ok := hashers[upgrade.Algorithm]; !ok {
+			return fmt.Errorf("Hash algorithm %v is not registered", upgrade.Algorithm)
+		}
+		if i > 0 && upgrade.Height == sorted[i-1].Height {
+			return fmt.Errorf("Duplicate hash upgrade at height %v", upgrade.Height)
+		}
+	}
+	hashSchedule = sorted
+	return nil
+}
+
+// ResetHashSchedule restores the default schedule, i.e. Keccak256 at all heights.
+func ResetHashSchedule() {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+
+	hashSchedule = defaultHashing
+}
+
+// HasherAtHeight returns the hasher in effect at the given block height.
+func HasherAtHeight(height uint64) Hasher {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+
+	algorithm := hashSchedule[0].Algorithm
+	for _, upgrade := range hashSchedule {
+		if upgrade.Height > height {
+			break
+		}
+		algorithm = upgrade.Algorithm
+	}
+	return hashers[algorithm]
+}
+
+// HashAtHeight hashes the input data with the hasher in effect at the given block height.
+func HashAtHeight(height uint64, data ...[]byte) common.Hash {
+	return HasherAtHeight(height).Hash(data...)
+}
+
+// keccak256Hasher is the default hasher.
+type keccak256Hasher struct{}
+
+func (keccak256Hasher) Algorithm() HashAlgorithm {
+	return HashAlgorithmKeccak256
+}
+
+func (keccak256Hasher) New() hash.Hash {
+	return sha3.NewKeccak256()
+}
+
+func (keccak256Hasher) Hash(data ...[]byte) common.Hash {
+	return keccak256Hash(data...)
+}
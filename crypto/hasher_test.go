@@ -0,0 +1,66 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+)
+
+const hashAlgorithmTestSha256 HashAlgorithm = 0xff
+
+type testSha256Hasher struct{}
+
+func (testSha256Hasher) Algorithm() HashAlgorithm {
+	return hashAlgorithmTestSha256
+}
+
+func (testSha256Hasher) New() hash.Hash {
+	return sha256.New()
+}
+
+func (h testSha256Hasher) Hash(data ...[]byte) common.Hash {
+	d := h.New()
+	for _, b := range data {
+		d.Write(b)
+	}
+	return common.BytesToHash(d.Sum(nil))
+}
+
+func TestHasherDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	data := common.Bytes("Hello world!")
+	for _, height := range []uint64{0, 1, 1000000} {
+		assert.Equal(HashAlgorithmKeccak256, HasherAtHeight(height).Algorithm())
+		assert.Equal(Keccak256Hash(data), HashAtHeight(height, data))
+	}
+}
+
+func TestHasherSchedule(t *testing.T) {
+	assert := assert.New(t)
+	defer ResetHashSchedule()
+
+	// Unregistered algorithm.
+	err := SetHashSchedule([]HashUpgrade{{0, HashAlgorithmKeccak256}, {100, hashAlgorithmTestSha256}})
+	assert.NotNil(err)
+
+	RegisterHasher(testSha256Hasher{})
+
+	// Schedule must start at height 0.
+	err = SetHashSchedule([]HashUpgrade{{100, hashAlgorithmTestSha256}})
+	assert.NotNil(err)
+
+	err = SetHashSchedule([]HashUpgrade{{100, hashAlgorithmTestSha256}, {0, HashAlgorithmKeccak256}})
+	assert.Nil(err)
+
+	data := common.Bytes("Hello world!")
+	assert.Equal(Keccak256Hash(data), HashAtHeight(99, data))
+	assert.Equal(common.Hash(sha256.Sum256(data)), HashAtHeight(100, data))
+	assert.Equal(hashAlgorithmTestSha256, HasherAtHeight(101).Algorithm())
+
+	ResetHashSchedule()
+	assert.Equal(Keccak256Hash(data), HashAtHeight(100, data))
+}
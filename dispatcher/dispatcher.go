@@ -3,18 +3,91 @@ package dispatcher
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/metrics"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/p2p"
 	p2ptypes "github.com/thetatoken/theta/p2p/types"
 )
 
+var logger *log.Entry = util.GetLoggerForModule("dispatcher")
+
+// Priority is the priority of an outbound message. The messages of a higher priority are sent
+// before the queued messages of a lower priority.
+type Priority int
+
+const (
+	// PriorityConsensus is the priority of the proposals, votes and commit certificates
+	PriorityConsensus Priority = iota
+	// PrioritySync is the priority of the blocks, headers and other data requested by the peers
+	PrioritySync
+	// PriorityTxGossip is the priority of the transactions, which are dropped under load
+	PriorityTxGossip
+
+	// NumPriorities is the number of priorities, i.e. the number of outbound message queues
+	NumPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityConsensus:
+		return "consensus"
+	case PrioritySync:
+		return "sync"
+	case PriorityTxGossip:
+		return "txgossip"
+	default:
+		return "unknown"
+	}
+}
+
+// ChannelPriority returns the priority of the messages of the channel.
+func ChannelPriority(channelID common.ChannelIDEnum) Priority {
+	switch channelID {
+	case common.ChannelIDProposal, common.ChannelIDVote, common.ChannelIDCC, common.ChannelIDGuardian:
+		return PriorityConsensus
+	case common.ChannelIDTransaction, common.ChannelIDTxGossip, common.ChannelIDTxReconcile:
+		return PriorityTxGossip
+	default:
+		return PrioritySync
+	}
+}
+
+var (
+	queueDepthGauges [NumPriorities]metrics.Gauge
+	droppedMeter     = metrics.NewRegisteredMeter("dispatcher/dropped", nil)
+	deferredMeter    = metrics.NewRegisteredMeter("dispatcher/deferred", nil)
+)
+
+func init() {
+	for p := Priority(0); p < NumPriorities; p++ {
+		queueDepthGauges[p] = metrics.NewRegisteredGauge("dispatcher/queue/"+p.String(), nil)
+	}
+}
+
+type outboundMessage struct {
+	peerIDs []string // Broadcast if empty
+	message p2ptypes.Message
+}
+
 //
-// Dispatcher dispatches messages to approporiate destinations
+// Dispatcher dispatches messages to approporiate destinations. The outbound messages are queued
+// in bounded queues, one per priority, and sent by a fixed number of workers. When its queue is
+// full, a transaction is dropped, while the sender of a consensus or sync message waits for room
+// in the queue.
 //
 type Dispatcher struct {
 	p2pnet p2p.Network
 
+	queues     [NumPriorities]chan outboundMessage
+	numWorkers int
+	started    int32
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	quit    chan struct{}
@@ -25,10 +98,19 @@ type Dispatcher struct {
 
 // NewDispatcher returns the pointer to the Dispatcher singleton
 func NewDispatcher(p2pnet p2p.Network) *Dispatcher {
-	return &Dispatcher{
-		p2pnet: p2pnet,
-		wg:     &sync.WaitGroup{},
+	dp := &Dispatcher{
+		p2pnet:     p2pnet,
+		numWorkers: viper.GetInt(common.CfgDispatcherNumWorkers),
+		wg:         &sync.WaitGroup{},
+	}
+	if dp.numWorkers <= 0 {
+		dp.numWorkers = 1
 	}
+	queueSize := viper.GetInt(common.CfgDispatcherQueueSize)
+	for p := range dp.queues {
+		dp.queues[p] = make(chan outboundMessage, queueSize)
+	}
+	return dp
 }
 
 // Start is called when the dispatcher starts
@@ -38,7 +120,16 @@ func (dp *Dispatcher) Start(ctx context.Context) error {
 	dp.cancel = cancel
 
 	err := dp.p2pnet.Start(c)
-	return err
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < dp.numWorkers; i++ {
+		dp.wg.Add(1)
+		go dp.worker()
+	}
+	atomic.StoreInt32(&dp.started, 1)
+	return nil
 }
 
 // Stop is called when the dispatcher stops
@@ -52,6 +143,15 @@ func (dp *Dispatcher) Wait() {
 	dp.wg.Wait()
 }
 
+// QueueDepths returns the number of messages waiting in the queue of each priority.
+func (dp *Dispatcher) QueueDepths() map[Priority]int {
+	depths := make(map[Priority]int)
+	for p, queue := range dp.queues {
+		depths[Priority(p)] = len(queue)
+	}
+	return depths
+}
+
 // GetInventory sends out the InventoryRequest
 func (dp *Dispatcher) GetInventory(peerIDs []string, invreq InventoryRequest) {
 	dp.send(peerIDs, invreq.ChannelID, invreq)
@@ -77,6 +177,40 @@ func (dp *Dispatcher) send(peerIDs []string, channelID common.ChannelIDEnum, con
 		ChannelID: channelID,
 		Content:   content,
 	}
+
+	if atomic.LoadInt32(&dp.started) == 0 {
+		// No workers to drain the queues, e.g. in tests which do not start the dispatcher
+		dp.sendNow(peerIDs, message)
+		return
+	}
+
+	priority := ChannelPriority(channelID)
+	queue := dp.queues[priority]
+	msg := outboundMessage{peerIDs: peerIDs, message: message}
+	select {
+	case queue <- msg:
+		queueDepthGauges[priority].Update(int64(len(queue)))
+		return
+	default:
+	}
+
+	if priority == PriorityTxGossip {
+		droppedMeter.Mark(1)
+		logger.Debugf("Outbound queue full, dropped message on channel %v", channelID)
+		return
+	}
+
+	// Apply backpressure on the sender until the workers catch up
+	deferredMeter.Mark(1)
+	select {
+	case queue <- msg:
+		queueDepthGauges[priority].Update(int64(len(queue)))
+	case <-dp.ctx.Done():
+	}
+}
+
+// sendNow sends the message without queuing it.
+func (dp *Dispatcher) sendNow(peerIDs []string, message p2ptypes.Message) {
 	if len(peerIDs) == 0 {
 		dp.p2pnet.Broadcast(message)
 	} else {
@@ -87,3 +221,49 @@ func (dp *Dispatcher) send(peerIDs []string, channelID common.ChannelIDEnum, con
 		}
 	}
 }
+
+func (dp *Dispatcher) worker() {
+	defer dp.wg.Done()
+
+	for {
+		msg, ok := dp.next()
+		if !ok {
+			return
+		}
+		dp.deliver(msg)
+	}
+}
+
+// next returns the oldest message of the highest priority non-empty queue, waiting for one if all
+// the queues are empty. It returns false once the dispatcher is stopped.
+func (dp *Dispatcher) next() (outboundMessage, bool) {
+	for p, queue := range dp.queues {
+		select {
+		case msg := <-queue:
+			queueDepthGauges[p].Update(int64(len(queue)))
+			return msg, true
+		default:
+		}
+	}
+
+	select {
+	case msg := <-dp.queues[PriorityConsensus]:
+		return msg, true
+	case msg := <-dp.queues[PrioritySync]:
+		return msg, true
+	case msg := <-dp.queues[PriorityTxGossip]:
+		return msg, true
+	case <-dp.ctx.Done():
+		return outboundMessage{}, false
+	}
+}
+
+func (dp *Dispatcher) deliver(msg outboundMessage) {
+	if len(msg.peerIDs) == 0 {
+		dp.p2pnet.Broadcast(msg.message)
+		return
+	}
+	for _, peerID := range msg.peerIDs {
+		dp.p2pnet.Send(peerID, msg.message)
+	}
+}
@@ -0,0 +1,147 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+type mockNetwork struct {
+	mu   sync.Mutex
+	sent []p2ptypes.Message
+}
+
+func (net *mockNetwork) Start(ctx context.Context) error { return nil }
+func (net *mockNetwork) Wait()                           {}
+func (net *mockNetwork) Stop()                           {}
+func (net *mockNetwork) ID() string                      { return "mock" }
+
+func (net *mockNetwork) RegisterMessageHandler(messageHandler p2p.MessageHandler) {}
+
+func (net *mockNetwork) Broadcast(message p2ptypes.Message) chan bool {
+	net.Send("", message)
+	return make(chan bool)
+}
+
+func (net *mockNetwork) Send(peerID string, message p2ptypes.Message) bool {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.sent = append(net.sent, message)
+	return true
+}
+
+func (net *mockNetwork) numSent() int {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	return len(net.sent)
+}
+
+// newQueuingDispatcher returns a dispatcher which queues the messages without workers to drain
+// the queues.
+func newQueuingDispatcher(queueSize int) (*Dispatcher, context.CancelFunc) {
+	viper.Set(common.CfgDispatcherQueueSize, queueSize)
+	defer viper.Set(common.CfgDispatcherQueueSize, 2048)
+
+	dp := NewDispatcher(&mockNetwork{})
+	dp.ctx, dp.cancel = context.WithCancel(context.Background())
+	dp.started = 1
+	return dp, dp.cancel
+}
+
+func TestChannelPriority(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(PriorityConsensus, ChannelPriority(common.ChannelIDVote))
+	assert.Equal(PriorityConsensus, ChannelPriority(common.ChannelIDProposal))
+	assert.Equal(PrioritySync, ChannelPriority(common.ChannelIDBlock))
+	assert.Equal(PrioritySync, ChannelPriority(common.ChannelIDHeader))
+	assert.Equal(PriorityTxGossip, ChannelPriority(common.ChannelIDTransaction))
+}
+
+func TestDispatcherPriorities(t *testing.T) {
+	assert := assert.New(t)
+
+	dp, cancel := newQueuingDispatcher(4)
+	dp.SendData([]string{"peer1"}, DataResponse{ChannelID: common.ChannelIDTransaction})
+	dp.SendData([]string{"peer1"}, DataResponse{ChannelID: common.ChannelIDBlock})
+	dp.SendData(nil, DataResponse{ChannelID: common.ChannelIDVote})
+	assert.Equal(map[Priority]int{PriorityConsensus: 1, PrioritySync: 1, PriorityTxGossip: 1}, dp.QueueDepths())
+
+	expected := []common.ChannelIDEnum{common.ChannelIDVote, common.ChannelIDBlock, common.ChannelIDTransaction}
+	for _, channelID := range expected {
+		msg, ok := dp.next()
+		assert.True(ok)
+		assert.Equal(channelID, msg.message.ChannelID)
+	}
+
+	cancel()
+	_, ok := dp.next()
+	assert.False(ok)
+}
+
+func TestDispatcherBackpressure(t *testing.T) {
+	assert := assert.New(t)
+
+	dp, cancel := newQueuingDispatcher(2)
+	defer cancel()
+
+	// The transactions beyond the queue size are dropped
+	for i := 0; i < 5; i++ {
+		dp.SendData(nil, DataResponse{ChannelID: common.ChannelIDTransaction})
+	}
+	assert.Equal(2, dp.QueueDepths()[PriorityTxGossip])
+
+	// The sender of a block waits for room in the queue
+	dp.SendData(nil, DataResponse{ChannelID: common.ChannelIDBlock})
+	dp.SendData(nil, DataResponse{ChannelID: common.ChannelIDBlock})
+	done := make(chan struct{})
+	go func() {
+		dp.SendData(nil, DataResponse{ChannelID: common.ChannelIDBlock})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("the sender should wait for room in the queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, ok := dp.next()
+	assert.True(ok)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the sender should resume once the queue has room")
+	}
+	assert.Equal(2, dp.QueueDepths()[PrioritySync])
+}
+
+func TestDispatcherWorkers(t *testing.T) {
+	assert := assert.New(t)
+
+	net := &mockNetwork{}
+	dp := NewDispatcher(net)
+
+	// Sent right away before the dispatcher starts
+	dp.SendData(nil, DataResponse{ChannelID: common.ChannelIDBlock})
+	assert.Equal(1, net.numSent())
+
+	assert.Nil(dp.Start(context.Background()))
+	dp.SendData([]string{"peer1", "peer2"}, DataResponse{ChannelID: common.ChannelIDVote})
+	dp.SendData(nil, DataResponse{ChannelID: common.ChannelIDTransaction})
+
+	for i := 0; i < 100 && net.numSent() < 4; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(4, net.numSent())
+
+	dp.Stop()
+	dp.Wait()
+}
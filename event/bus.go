@@ -0,0 +1,126 @@
+package event
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBufferSize is the number of the events a subscription buffers by default.
+const DefaultBufferSize = 256
+
+//
+// Bus delivers the events published by the subsystems, e.g. the chain, the consensus engine
+// and the p2p network, to the subscribers, e.g. indexers and websocket feeds, so that they do
+// not need hooks in the publishing subsystems. Publishing never blocks: the events are dropped
+// for a subscriber whose buffer is full, which keeps a slow subscriber from stalling consensus.
+//
+// The methods of a nil Bus are no-ops, so a subsystem without a bus publishes nothing.
+//
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]bool
+}
+
+// NewBus creates an event bus.
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[*Subscription]bool),
+	}
+}
+
+// Subscribe subscribes to the events of the topics, or of all the topics if none is given. Up
+// to bufferSize events are buffered, DefaultBufferSize if not positive.
+func (b *Bus) Subscribe(bufferSize int, topics ...Topic) *Subscription {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	sub := &Subscription{
+		bus:    b,
+		topics: make(map[Topic]bool),
+		ch:     make(chan Event, bufferSize),
+	}
+	for _, topic := range topics {
+		sub.topics[topic] = true
+	}
+	if b == nil {
+		return sub
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = true
+	return sub
+}
+
+// Publish delivers the event to the subscribers of its topic.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		sub.deliver(event)
+	}
+}
+
+// NumSubscriptions returns the number of the active subscriptions.
+func (b *Bus) NumSubscriptions() int {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+func (b *Bus) unsubscribe(sub *Subscription) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[sub] {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+//
+// Subscription receives the events of its topics in the order they were published.
+//
+type Subscription struct {
+	bus     *Bus
+	topics  map[Topic]bool // all the topics if empty
+	ch      chan Event
+	dropped uint64 // Accessed atomically
+}
+
+// Events returns the channel the events are received from. It is closed on Unsubscribe.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Dropped returns the number of the events dropped since the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Unsubscribe stops the delivery of the events, and closes the event channel.
+func (s *Subscription) Unsubscribe() {
+	s.bus.unsubscribe(s)
+}
+
+func (s *Subscription) deliver(event Event) {
+	if len(s.topics) > 0 && !s.topics[event.Topic()] {
+		return
+	}
+	select {
+	case s.ch <- event:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
@@ -0,0 +1,63 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/core"
+)
+
+func TestBusTopics(t *testing.T) {
+	assert := assert.New(t)
+
+	bus := NewBus()
+	all := bus.Subscribe(10)
+	peers := bus.Subscribe(10, TopicPeerConnected)
+	assert.Equal(2, bus.NumSubscriptions())
+
+	block := &core.ExtendedBlock{Block: core.NewBlock()}
+	bus.Publish(BlockAdded{Block: block})
+	bus.Publish(PeerConnected{PeerID: "peer1"})
+	bus.Publish(BlockFinalized{Block: block})
+
+	assert.Equal(3, len(all.Events()))
+	assert.Equal(TopicBlockAdded, (<-all.Events()).Topic())
+	assert.Equal(TopicPeerConnected, (<-all.Events()).Topic())
+	assert.Equal(TopicBlockFinalized, (<-all.Events()).Topic())
+
+	assert.Equal(1, len(peers.Events()))
+	e := (<-peers.Events()).(PeerConnected)
+	assert.Equal("peer1", e.PeerID)
+
+	// The channel is closed on unsubscribe
+	peers.Unsubscribe()
+	_, ok := <-peers.Events()
+	assert.False(ok)
+	assert.Equal(1, bus.NumSubscriptions())
+	peers.Unsubscribe()
+	bus.Publish(PeerConnected{PeerID: "peer2"})
+	assert.Equal(1, len(all.Events()))
+}
+
+func TestBusSlowSubscriber(t *testing.T) {
+	assert := assert.New(t)
+
+	bus := NewBus()
+	sub := bus.Subscribe(2)
+	for i := 0; i < 5; i++ {
+		bus.Publish(PeerConnected{PeerID: "peer"})
+	}
+	assert.Equal(2, len(sub.Events()))
+	assert.Equal(uint64(3), sub.Dropped())
+}
+
+func TestNilBus(t *testing.T) {
+	assert := assert.New(t)
+
+	var bus *Bus
+	bus.Publish(PeerConnected{PeerID: "peer"})
+	sub := bus.Subscribe(0)
+	sub.Unsubscribe()
+	assert.Equal(0, bus.NumSubscriptions())
+	assert.Equal(0, len(sub.Events()))
+}
@@ -0,0 +1,91 @@
+package event
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+// Topic identifies the kind of the events, which the subscribers select the events by.
+type Topic string
+
+const (
+	// TopicBlockAdded is the topic of the blocks added to the chain
+	TopicBlockAdded Topic = "block_added"
+	// TopicBlockFinalized is the topic of the finalized blocks
+	TopicBlockFinalized Topic = "block_finalized"
+	// TopicTxConfirmed is the topic of the transactions included in the finalized blocks
+	TopicTxConfirmed Topic = "tx_confirmed"
+	// TopicPeerConnected is the topic of the peers the node connects with
+	TopicPeerConnected Topic = "peer_connected"
+	// TopicValidatorSetChanged is the topic of the validator set updates of the finalized blocks
+	TopicValidatorSetChanged Topic = "validator_set_changed"
+)
+
+// Event is an event published on the bus.
+type Event interface {
+	Topic() Topic
+}
+
+// BlockAdded is published when a block is added to the chain, before it is validated.
+type BlockAdded struct {
+	Block *core.ExtendedBlock
+}
+
+func (e BlockAdded) Topic() Topic { return TopicBlockAdded }
+
+func (e BlockAdded) String() string {
+	return fmt.Sprintf("BlockAdded{hash: %v, height: %v}", e.Block.Hash().Hex(), e.Block.Height)
+}
+
+// BlockFinalized is published when a block is finalized. The blocks are published in increasing
+// height order, including the ancestors finalized along with a block.
+type BlockFinalized struct {
+	Block *core.ExtendedBlock
+}
+
+func (e BlockFinalized) Topic() Topic { return TopicBlockFinalized }
+
+func (e BlockFinalized) String() string {
+	return fmt.Sprintf("BlockFinalized{hash: %v, height: %v}", e.Block.Hash().Hex(), e.Block.Height)
+}
+
+// TxConfirmed is published for each transaction of a finalized block, after the block itself.
+type TxConfirmed struct {
+	TxHash common.Hash
+	Index  int // Index of the transaction in the block
+	Block  *core.ExtendedBlock
+}
+
+func (e TxConfirmed) Topic() Topic { return TopicTxConfirmed }
+
+func (e TxConfirmed) String() string {
+	return fmt.Sprintf("TxConfirmed{hash: %v, block: %v, index: %v}", e.TxHash.Hex(), e.Block.Hash().Hex(), e.Index)
+}
+
+// PeerConnected is published when a peer is connected and started.
+type PeerConnected struct {
+	PeerID   string
+	Address  string
+	Outbound bool
+}
+
+func (e PeerConnected) Topic() Topic { return TopicPeerConnected }
+
+func (e PeerConnected) String() string {
+	return fmt.Sprintf("PeerConnected{id: %v, address: %v, outbound: %v}", e.PeerID, e.Address, e.Outbound)
+}
+
+// ValidatorSetChanged is published when a block updating the validator set is finalized. The
+// validators are the ones of the blocks following the block.
+type ValidatorSetChanged struct {
+	Block      *core.ExtendedBlock
+	Validators *core.ValidatorSet
+}
+
+func (e ValidatorSetChanged) Topic() Topic { return TopicValidatorSetChanged }
+
+func (e ValidatorSetChanged) String() string {
+	return fmt.Sprintf("ValidatorSetChanged{block: %v, validators: %v}", e.Block.Hash().Hex(), e.Validators)
+}
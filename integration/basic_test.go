@@ -4,6 +4,9 @@ package integration
 
 import (
 	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -14,9 +17,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
-	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/node"
+	"github.com/thetatoken/theta/p2p"
 	p2psim "github.com/thetatoken/theta/p2p/simulation"
 )
 
@@ -26,16 +29,57 @@ func TestConsensusBaseCase(t *testing.T) {
 	viper.Set(common.CfgLogPrintSelfID, true)
 
 	simnet := p2psim.NewSimnet()
+	nodes := createTestNodes(assert, func(id string) p2p.Network {
+		return simnet.AddEndpoint(id)
+	})
 
-	nodes := []*node.Node{}
+	testConsensus(assert, simnet, nodes, 5*time.Second, 1)
+}
+
+func TestConsensusWithPartition(t *testing.T) {
+	assert := assert.New(t)
 
-	validators := consensus.NewTestValidatorSet([]string{
-		"042CA7FFB62122A220C72AA7CD87C252B21D72273275682386A099F0983C135659FF93E2E8756011074706E18113AA6529CD5833DD6463266980C6973895153C7C",
-		"048E8D53FD435265AD074597CC3E202F8E935CFB57925BB51316252027CB08767FB8099226414732543C4B5CBAA64B4EE8F173BA559258A0B5F633A0D11509E78B",
-		"0479188733862EBB3FE98A92315556D5214D908941CDC8D6C8700EEEAE5F90A6177A37E23B33B81B9FAC3A98EE2382AB24B1C92384FC151D07E36AC7209702D353",
-		"0455BDC5CF697F9519DF40E837BEE3E246C8D47C1B58CD1892FD3B0F780D2C09E718FF50A5929B86B8B88C7031164BDE553E285103F1B4DF668B44AFC907264C1C",
+	viper.Set(common.CfgLogPrintSelfID, true)
+
+	memnet := p2psim.NewMemNetwork(1)
+	memnet.SetDefaultLink(p2psim.LinkConfig{
+		Latency:  20 * time.Millisecond,
+		Jitter:   10 * time.Millisecond,
+		DropRate: 0.01,
+	})
+	ids := []string{}
+	nodes := createTestNodes(assert, func(id string) p2p.Network {
+		ids = append(ids, id)
+		return memnet.AddEndpoint(id)
 	})
 
+	// The last validator is cut off for a while. The others hold more than 2/3 of the stake and
+	// keep finalizing blocks, which the last validator catches up with once the network heals.
+	go func() {
+		time.Sleep(2 * time.Second)
+		memnet.Partition(ids[:3], ids[3:])
+		time.Sleep(4 * time.Second)
+		memnet.Heal()
+	}()
+
+	testConsensus(assert, nil, nodes, 12*time.Second, 1)
+}
+
+// createTestNodes creates the nodes of the test validators, connected through the networks
+// returned by newNetwork for their IDs.
+func createTestNodes(assert *assert.Assertions, newNetwork func(id string) p2p.Network) []*node.Node {
+	nodes := []*node.Node{}
+
+	// Short epochs, so that the nodes finalize blocks within the simulation
+	viper.Set(common.CfgConsensusMaxEpochLength, 2)
+	viper.Set(common.CfgConsensusMinProposalWait, 1)
+	viper.Set(common.CfgConsensusEpochTimeoutFloor, 2)
+	viper.Set(common.CfgConsensusEpochTimeoutCeiling, 8)
+
+	snapshotDir, err := ioutil.TempDir("", "integration")
+	assert.Nil(err)
+	defer os.RemoveAll(snapshotDir) // The snapshot is only read when the nodes are created
+
 	privKeys := []string{
 		"A249A82C42A282E87B2DDEF63404D9DFCF6EA501DCAF5D447761765BD74F666D",
 		"93A90EA508331DFDF27FB79757D4250B4E84954927BA0073CD67454AC432C737",
@@ -43,25 +87,50 @@ func TestConsensusBaseCase(t *testing.T) {
 		"83F0BB8655139CEF4657F90DB64A7BB57847038A9BD0CCD87C9B0828E9CBF76D",
 	}
 
-	for i, v := range validators.Validators() {
-		privateKeyBytes, _ := hex.DecodeString(privKeys[i])
-		privateKey, _ := crypto.PrivateKeyFromBytes(privateKeyBytes)
+	// The validators of the genesis are the holders of the private keys
+	privateKeys := []*crypto.PrivateKey{}
+	spec := &node.GenesisSpec{
+		ChainID:   "testchain",
+		Timestamp: common.JSONUint64(1552000000),
+	}
+	for _, privKey := range privKeys {
+		privateKeyBytes, _ := hex.DecodeString(privKey)
+		privateKey, err := crypto.PrivateKeyFromBytes(privateKeyBytes)
+		assert.Nil(err)
+		privateKeys = append(privateKeys, privateKey)
+
+		address := privateKey.PublicKey().Address()
+		stake := (*common.JSONBig)(core.MinValidatorStakeDeposit)
+		spec.Accounts = append(spec.Accounts, node.GenesisAccount{
+			Address:  address,
+			ThetaWei: stake,
+			TFuelWei: stake,
+		})
+		spec.Stakes = append(spec.Stakes, node.GenesisStake{
+			Source: address,
+			Holder: address,
+			Amount: stake,
+		})
+	}
+
+	for _, privateKey := range privateKeys {
+		id := privateKey.PublicKey().Address().Hex()
 		db := backend.NewMemDatabase()
-		chainID := "testchain"
-		root := core.NewBlock()
-		root.ChainID = chainID
-		root.Epoch = 0
+		sv, root, err := node.GenerateGenesis(spec, db)
+		assert.Nil(err)
+		viper.Set(common.CfgGenesisHash, root.Hash().Hex())
+		snapshotPath := filepath.Join(snapshotDir, id)
+		assert.Nil(node.WriteGenesisSnapshot(sv, root, snapshotPath))
 
 		params := &node.Params{
-			PrivateKey: privateKey,
-			DB:         db,
-			ChainID:    chainID,
-			Root:       root,
-			Validators: validators,
-			Network:    simnet.AddEndpoint(v.ID().Hex()),
+			PrivateKey:   privateKey,
+			DB:           db,
+			ChainID:      spec.ChainID,
+			Root:         root,
+			Network:      newNetwork(id),
+			SnapshotPath: snapshotPath,
 		}
 		nodes = append(nodes, node.NewNode(params))
 	}
-
-	testConsensus(assert, simnet, nodes, 5*time.Second, 1)
+	return nodes
 }
@@ -13,6 +13,8 @@ import (
 	p2psim "github.com/thetatoken/theta/p2p/simulation"
 )
 
+// testConsensus runs the nodes for the duration, and checks that they all finalized blocks without
+// conflicts. The simnet is nil if the nodes are connected through another network.
 func testConsensus(assert *assert.Assertions, simnet *p2psim.Simnet, nodes []*node.Node, duration time.Duration, minFinalized int) {
 	l := &sync.Mutex{}
 	wg := sync.WaitGroup{}
@@ -24,7 +26,9 @@ func testConsensus(assert *assert.Assertions, simnet *p2psim.Simnet, nodes []*no
 	for _, node := range nodes {
 		finalizedBlocksByNode[node.Consensus.ID()] = []common.Hash{}
 	}
-	simnet.Start(ctx)
+	if simnet != nil {
+		simnet.Start(ctx) // The endpoints of the other networks are started by the nodes
+	}
 	for _, node := range nodes {
 		node.Start(ctx)
 		wg.Add(1)
@@ -1,15 +1,12 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"math/big"
-	"os"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -17,6 +14,7 @@ import (
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/node"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store/database/backend"
 	"github.com/thetatoken/theta/store/trie"
@@ -24,44 +22,61 @@ import (
 
 var logger *log.Entry = log.WithFields(log.Fields{"prefix": "genesis"})
 
-const (
-	GenBlockHashMode int = iota
-	GenGenesisFileMode
-)
-
 type StakeDeposit struct {
 	Source string `json:"source"`
 	Holder string `json:"holder"`
 	Amount string `json:"amount"`
 }
 
+//
+// The genesis is generated from a genesis spec, or from an ERC20 balance snapshot and the initial
+// stake deposits, which are first converted into a genesis spec. The same inputs always produce
+// the same genesis block hash.
 //
 // Example:
 // pushd $THETA_HOME/integration/privatenet/node
-// generate_genesis -chainID=privatenet -erc20snapshot=./data/genesis_theta_erc20_snapshot.json -stake_deposit=./data/genesis_stake_deposit.json -genesis=./genesis
+// generate_genesis -spec=./data/genesis_spec.json -genesis=./genesis
+// generate_genesis -chainID=privatenet -erc20snapshot=./data/genesis_theta_erc20_snapshot.json -stake_deposit=./data/genesis_stake_deposit.json -timestamp=1552000000 -spec_out=./data/genesis_spec.json -genesis=./genesis
 //
 func main() {
-	chainID, erc20SnapshotJSONFilePath, stakeDepositFilePath, genesisSnapshotFilePath := parseArguments()
+	args := parseArguments()
 
-	sv, metadata, err := generateGenesisSnapshot(chainID, erc20SnapshotJSONFilePath, stakeDepositFilePath)
+	var spec *node.GenesisSpec
+	var err error
+	if args.specFilePath != "" {
+		spec, err = node.LoadGenesisSpec(args.specFilePath)
+	} else {
+		spec, err = specFromERC20Snapshot(args.chainID, args.timestamp, args.erc20SnapshotJSONFilePath, args.stakeDepositFilePath)
+	}
 	if err != nil {
-		panic(fmt.Sprintf("Failed to generate genesis snapshot: %v", err))
+		panic(fmt.Sprintf("Failed to load genesis spec: %v", err))
+	}
+	if args.specOutFilePath != "" {
+		if err := spec.Save(args.specOutFilePath); err != nil {
+			panic(fmt.Sprintf("Failed to write genesis spec: %v", err))
+		}
 	}
 
-	err = sanityChecks(sv)
+	sv, genesisBlock, err := node.GenerateGenesis(spec, backend.NewMemDatabase())
 	if err != nil {
-		panic(fmt.Sprintf("Sanity checks failed: %v", err))
-	} else {
-		logger.Infof("Sanity checks all passed.")
+		panic(fmt.Sprintf("Failed to generate genesis snapshot: %v", err))
+	}
+
+	if args.checkSupply {
+		err = sanityChecks(sv)
+		if err != nil {
+			panic(fmt.Sprintf("Sanity checks failed: %v", err))
+		} else {
+			logger.Infof("Sanity checks all passed.")
+		}
 	}
 
-	err = writeGenesisSnapshot(sv, metadata, genesisSnapshotFilePath)
+	err = node.WriteGenesisSnapshot(sv, genesisBlock, args.genesisSnapshotFilePath)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to write genesis snapshot: %v", err))
 	}
 
-	genesisBlockHeader := &metadata.TailTrio.Second.Header
-	genesisBlockHash := genesisBlockHeader.Hash()
+	genesisBlockHash := genesisBlock.Hash()
 
 	fmt.Println("")
 	fmt.Printf("--------------------------------------------------------------------------\n")
@@ -70,144 +85,100 @@ func main() {
 	fmt.Println("")
 }
 
-func parseArguments() (chainID, erc20SnapshotJSONFilePath, stakeDepositFilePath, genesisSnapshotFilePath string) {
+type arguments struct {
+	chainID                   string
+	timestamp                 uint64
+	specFilePath              string
+	specOutFilePath           string
+	erc20SnapshotJSONFilePath string
+	stakeDepositFilePath      string
+	genesisSnapshotFilePath   string
+	checkSupply               bool
+}
+
+func parseArguments() *arguments {
 	chainIDPtr := flag.String("chainID", "local_chain", "the ID of the chain")
+	timestampPtr := flag.Uint64("timestamp", 0, "the Unix time of the genesis block")
+	specFilePathPtr := flag.String("spec", "", "the genesis spec, which takes precedence over the ERC20 balance snapshot and the stake deposits")
+	specOutFilePathPtr := flag.String("spec_out", "", "write the genesis spec to the file")
 	erc20SnapshotJSONFilePathPtr := flag.String("erc20snapshot", "./theta_erc20_snapshot.json", "the json file contain the ERC20 balance snapshot")
 	stakeDepositFilePathPtr := flag.String("stake_deposit", "./stake_deposit.json", "the initial stake deposits")
 	genesisSnapshotFilePathPtr := flag.String("genesis", "./genesis", "the genesis snapshot")
+	checkSupplyPtr := flag.Bool("check_supply", true, "check the total supply matches the mainnet one")
 	flag.Parse()
 
-	chainID = *chainIDPtr
-	erc20SnapshotJSONFilePath = *erc20SnapshotJSONFilePathPtr
-	stakeDepositFilePath = *stakeDepositFilePathPtr
-	genesisSnapshotFilePath = *genesisSnapshotFilePathPtr
-
-	return
-}
-
-// generateGenesisSnapshot generates the genesis snapshot.
-func generateGenesisSnapshot(chainID, erc20SnapshotJSONFilePath, stakeDepositFilePath string) (*state.StoreView, *core.SnapshotMetadata, error) {
-	metadata := &core.SnapshotMetadata{}
-	genesisHeight := core.GenesisBlockHeight
-
-	sv := loadInitialBalances(erc20SnapshotJSONFilePath)
-	performInitialStakeDeposit(stakeDepositFilePath, genesisHeight, sv)
-
-	stateHash := sv.Hash()
-
-	genesisBlock := core.NewBlock()
-	genesisBlock.ChainID = chainID
-	genesisBlock.Height = genesisHeight
-	genesisBlock.Epoch = genesisBlock.Height
-	genesisBlock.Parent = common.Hash{}
-	genesisBlock.StateHash = stateHash
-	genesisBlock.Timestamp = big.NewInt(time.Now().Unix())
-
-	metadata.TailTrio = core.SnapshotBlockTrio{
-		First:  core.SnapshotFirstBlock{},
-		Second: core.SnapshotSecondBlock{Header: *genesisBlock.BlockHeader},
-		Third:  core.SnapshotThirdBlock{},
+	return &arguments{
+		chainID:                   *chainIDPtr,
+		timestamp:                 *timestampPtr,
+		specFilePath:              *specFilePathPtr,
+		specOutFilePath:           *specOutFilePathPtr,
+		erc20SnapshotJSONFilePath: *erc20SnapshotJSONFilePathPtr,
+		stakeDepositFilePath:      *stakeDepositFilePathPtr,
+		genesisSnapshotFilePath:   *genesisSnapshotFilePathPtr,
+		checkSupply:               *checkSupplyPtr,
 	}
-
-	return sv, metadata, nil
 }
 
-func loadInitialBalances(erc20SnapshotJSONFilePath string) *state.StoreView {
+// specFromERC20Snapshot converts the ERC20 balance snapshot and the initial stake deposits into a
+// genesis spec. Each account gets 5 TFuelWei per ThetaWei.
+func specFromERC20Snapshot(chainID string, timestamp uint64, erc20SnapshotJSONFilePath, stakeDepositFilePath string) (*node.GenesisSpec, error) {
 	initTFuelToThetaRatio := new(big.Int).SetUint64(5)
-	sv := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	spec := &node.GenesisSpec{
+		ChainID:   chainID,
+		Timestamp: common.JSONUint64(timestamp),
+	}
 
-	erc20SnapshotJSONFile, err := os.Open(erc20SnapshotJSONFilePath)
+	erc20BalanceMapByteValue, err := ioutil.ReadFile(erc20SnapshotJSONFilePath)
 	if err != nil {
-		panic(fmt.Sprintf("failed to open the ERC20 balance snapshot: %v", err))
+		return nil, fmt.Errorf("failed to read the ERC20 balance snapshot: %v", err)
 	}
-	defer erc20SnapshotJSONFile.Close()
-
 	var erc20BalanceMap map[string]string
-	erc20BalanceMapByteValue, err := ioutil.ReadAll(erc20SnapshotJSONFile)
-	if err != nil {
-		panic(fmt.Sprintf("failed to read the ERC20 balance snapshot: %v", err))
+	if err := json.Unmarshal(erc20BalanceMapByteValue, &erc20BalanceMap); err != nil {
+		return nil, fmt.Errorf("failed to parse the ERC20 balance snapshot: %v", err)
 	}
-
-	json.Unmarshal(erc20BalanceMapByteValue, &erc20BalanceMap)
 	for key, val := range erc20BalanceMap {
 		if !common.IsHexAddress(key) {
-			panic(fmt.Sprintf("Invalid address: %v", key))
+			return nil, fmt.Errorf("Invalid address: %v", key)
 		}
-		address := common.HexToAddress(key)
-
 		theta, success := new(big.Int).SetString(val, 10)
 		if !success {
-			panic(fmt.Sprintf("Failed to parse ThetaWei amount: %v", val))
+			return nil, fmt.Errorf("Failed to parse ThetaWei amount: %v", val)
 		}
 		tfuel := new(big.Int).Mul(initTFuelToThetaRatio, theta)
-		acc := &types.Account{
-			Address:  address,
-			Root:     common.Hash{},
-			CodeHash: types.EmptyCodeHash,
-			Balance: types.Coins{
-				ThetaWei: theta,
-				TFuelWei: tfuel,
-			},
-		}
-		sv.SetAccount(acc.Address, acc)
-		//logger.Infof("address: %v, theta: %v, tfuel: %v", strings.ToLower(address.String()), theta, tfuel)
+		spec.Accounts = append(spec.Accounts, node.GenesisAccount{
+			Address:  common.HexToAddress(key),
+			ThetaWei: (*common.JSONBig)(theta),
+			TFuelWei: (*common.JSONBig)(tfuel),
+		})
 	}
 
-	return sv
-}
-
-func performInitialStakeDeposit(stakeDepositFilePath string, genesisHeight uint64, sv *state.StoreView) *core.ValidatorCandidatePool {
-	var stakeDeposits []StakeDeposit
-	stakeDepositFile, err := os.Open(stakeDepositFilePath)
-	stakeDepositByteValue, err := ioutil.ReadAll(stakeDepositFile)
+	stakeDepositByteValue, err := ioutil.ReadFile(stakeDepositFilePath)
 	if err != nil {
-		panic(fmt.Sprintf("failed to read initial stake deposit file: %v", err))
+		return nil, fmt.Errorf("failed to read initial stake deposit file: %v", err)
+	}
+	var stakeDeposits []StakeDeposit
+	if err := json.Unmarshal(stakeDepositByteValue, &stakeDeposits); err != nil {
+		return nil, fmt.Errorf("failed to parse initial stake deposit file: %v", err)
 	}
-
-	json.Unmarshal(stakeDepositByteValue, &stakeDeposits)
-	vcp := &core.ValidatorCandidatePool{}
 	for _, stakeDeposit := range stakeDeposits {
 		if !common.IsHexAddress(stakeDeposit.Source) {
-			panic(fmt.Sprintf("Invalid source address: %v", stakeDeposit.Source))
+			return nil, fmt.Errorf("Invalid source address: %v", stakeDeposit.Source)
 		}
 		if !common.IsHexAddress(stakeDeposit.Holder) {
-			panic(fmt.Sprintf("Invalid holder address: %v", stakeDeposit.Holder))
+			return nil, fmt.Errorf("Invalid holder address: %v", stakeDeposit.Holder)
 		}
-		sourceAddress := common.HexToAddress(stakeDeposit.Source)
-		holderAddress := common.HexToAddress(stakeDeposit.Holder)
 		stakeAmount, success := new(big.Int).SetString(stakeDeposit.Amount, 10)
 		if !success {
-			panic(fmt.Sprintf("Failed to parse Stake amount: %v", stakeDeposit.Amount))
-		}
-
-		sourceAccount := sv.GetAccount(sourceAddress)
-		if sourceAccount == nil {
-			panic(fmt.Sprintf("Failed to retrieve account for source address: %v", sourceAddress))
-		}
-		if sourceAccount.Balance.ThetaWei.Cmp(stakeAmount) < 0 {
-			panic(fmt.Sprintf("The source account %v does NOT have sufficient balance for stake deposit. ThetaWeiBalance = %v, StakeAmount = %v",
-				sourceAddress, sourceAccount.Balance.ThetaWei, stakeDeposit.Amount))
+			return nil, fmt.Errorf("Failed to parse Stake amount: %v", stakeDeposit.Amount)
 		}
-		err := vcp.DepositStake(sourceAddress, holderAddress, stakeAmount)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to deposit stake, err: %v", err))
-		}
-
-		stake := types.Coins{
-			ThetaWei: stakeAmount,
-			TFuelWei: new(big.Int).SetUint64(0),
-		}
-		sourceAccount.Balance = sourceAccount.Balance.Minus(stake)
-		sv.SetAccount(sourceAddress, sourceAccount)
+		spec.Stakes = append(spec.Stakes, node.GenesisStake{
+			Source: common.HexToAddress(stakeDeposit.Source),
+			Holder: common.HexToAddress(stakeDeposit.Holder),
+			Amount: (*common.JSONBig)(stakeAmount),
+		})
 	}
 
-	sv.UpdateValidatorCandidatePool(vcp)
-
-	hl := &types.HeightList{}
-	hl.Append(genesisHeight)
-	sv.UpdateStakeTransactionHeightList(hl)
-
-	return vcp
+	return spec, spec.Validate()
 }
 
 func proveVCP(sv *state.StoreView) (*core.VCPProof, error) {
@@ -217,42 +188,6 @@ func proveVCP(sv *state.StoreView) (*core.VCPProof, error) {
 	return vp, err
 }
 
-// writeGenesisSnapshot writes genesis snapshot to file system.
-func writeGenesisSnapshot(sv *state.StoreView, metadata *core.SnapshotMetadata, genesisSnapshotFilePath string) error {
-	file, err := os.Create(genesisSnapshotFilePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	writer := bufio.NewWriter(file)
-	err = core.WriteMetadata(writer, metadata)
-	if err != nil {
-		return err
-	}
-	writeStoreView(sv, true, writer)
-	return err
-}
-
-func writeStoreView(sv *state.StoreView, needAccountStorage bool, writer *bufio.Writer) {
-	height := core.Itobytes(sv.Height())
-	err := core.WriteRecord(writer, []byte{core.SVStart}, height)
-	if err != nil {
-		panic(err)
-	}
-	sv.GetStore().Traverse(nil, func(k, v common.Bytes) bool {
-		err = core.WriteRecord(writer, k, v)
-		if err != nil {
-			panic(err)
-		}
-		return true
-	})
-	err = core.WriteRecord(writer, []byte{core.SVEnd}, height)
-	if err != nil {
-		panic(err)
-	}
-	writer.Flush()
-}
-
 func sanityChecks(sv *state.StoreView) error {
 	thetaWeiTotal := new(big.Int).SetUint64(0)
 	tfuelWeiTotal := new(big.Int).SetUint64(0)
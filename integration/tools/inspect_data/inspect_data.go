@@ -10,6 +10,7 @@ import (
 
 	"github.com/thetatoken/theta/blockchain"
 	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store/database/backend"
 	"github.com/thetatoken/theta/store/trie"
@@ -27,6 +28,34 @@ func printUsage() {
 	fmt.Println("Usage: inspect_data -config=<path_to_config_home> -key=<key> -level=<level>")
 }
 
+func formatValue(value []byte) string {
+	account := types.Account{}
+	err := rlp.DecodeBytes(value, &account)
+	if err == nil {
+		return fmt.Sprintf("%v", account)
+	}
+
+	splitRule := types.SplitRule{}
+	err = rlp.DecodeBytes(value, &splitRule)
+	if err == nil {
+		return fmt.Sprintf("%v", splitRule)
+	}
+
+	vcp := core.ValidatorCandidatePool{}
+	err = rlp.DecodeBytes(value, &vcp)
+	if err == nil {
+		return fmt.Sprintf("%v", vcp)
+	}
+
+	hl := types.HeightList{}
+	err = rlp.DecodeBytes(value, &hl)
+	if err == nil {
+		return fmt.Sprintf("%v", hl)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
 func main() {
 	configPathPtr := flag.String("config", "", "path to ukuele config home")
 	keyPtr := flag.String("key", "", "db key")
@@ -47,6 +76,7 @@ func main() {
 	value, err := db.Get(k)
 	handleError(err)
 
+	trie.FormatValue = formatValue
 	node, err := trie.DecodeNode(k, value, 0)
 	if err == nil {
 		// fmt.Printf("%v\n", node)
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rpc"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+func call(n *node, method string, args interface{}, result interface{}) error {
+	client := rpcc.NewRPCClient(n.rpcURL())
+	res, err := client.Call(method, args)
+	if err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	return res.GetObject(result)
+}
+
+func getStatus(n *node) (*rpc.GetStatusResult, error) {
+	result := &rpc.GetStatusResult{}
+	err := call(n, "theta.GetStatus", rpc.GetStatusArgs{}, result)
+	return result, err
+}
+
+func getFinalizedBlock(n *node, height uint64) (*rpc.GetBlockResult, error) {
+	result := &rpc.GetBlockResult{}
+	err := call(n, "theta.GetBlockByHeight", rpc.GetBlockByHeightArgs{Height: common.JSONUint64(height)}, result)
+	if err != nil {
+		return nil, err
+	}
+	if result.GetBlockResultInner == nil {
+		return nil, fmt.Errorf("No finalized block at height %v on %v", height, n.name)
+	}
+	return result, nil
+}
+
+func getBalance(n *node, address common.Address) (types.Coins, error) {
+	result := struct {
+		types.AccountJSON
+	}{}
+	err := call(n, "theta.GetAccount", rpc.GetAccountArgs{Address: address.Hex()}, &result)
+	if err != nil {
+		return types.Coins{}, err
+	}
+	return result.Balance.NoNil(), nil
+}
+
+func getNextSequence(n *node, address common.Address) (uint64, error) {
+	result := &rpc.GetSequenceResult{}
+	err := call(n, "theta.GetSequence", rpc.GetSequenceArgs{Address: address.Hex(), Mode: rpc.SequenceModePending}, result)
+	return uint64(result.NextSequence), err
+}
+
+// sendTokens submits a send transaction signed with the given key.
+func sendTokens(n *node, chainID string, privKey *crypto.PrivateKey, to common.Address,
+	coins types.Coins, fee *big.Int, sequence uint64) error {
+	from := privKey.PublicKey().Address()
+	tx := &types.SendTx{
+		Fee: types.Coins{
+			ThetaWei: big.NewInt(0),
+			TFuelWei: fee,
+		},
+		Inputs: []types.TxInput{{
+			Address: from,
+			Coins: types.Coins{
+				ThetaWei: coins.ThetaWei,
+				TFuelWei: new(big.Int).Add(coins.TFuelWei, fee),
+			},
+			Sequence: sequence,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: to,
+			Coins:   coins,
+		}},
+	}
+	sig, err := privKey.Sign(tx.SignBytes(chainID))
+	if err != nil {
+		return err
+	}
+	tx.SetSignature(from, sig)
+	raw, err := types.TxToBytes(tx)
+	if err != nil {
+		return err
+	}
+
+	result := &rpc.BroadcastRawTransactionAsyncResult{}
+	err = call(n, "theta.BroadcastRawTransactionAsync", rpc.BroadcastRawTransactionAsyncArgs{TxBytes: hex.EncodeToString(raw)}, result)
+	if err != nil {
+		return err
+	}
+	if result.TxHash == "" {
+		return errors.New("Empty transaction hash")
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func printUsage() {
+	fmt.Println("Usage: theta-e2e -scenario=<path_to_scenario> [-theta=<path_to_theta_binary>] [-testnet=<path_to_node_configs>]" +
+		" [-nodes=node1,node2,node3,node4] [-workdir=<path>] [-baseport=21000] [-password=<key_password>]")
+}
+
+func main() {
+	scenarioPtr := flag.String("scenario", "", "path to the scenario script")
+	thetaBinPtr := flag.String("theta", "theta", "path to the theta binary")
+	testnetPtr := flag.String("testnet", "./integration/testnet", "directory containing the config dir of each node")
+	nodesPtr := flag.String("nodes", "node1,node2,node3,node4", "nodes of the network")
+	workDirPtr := flag.String("workdir", path.Join(os.TempDir(), "theta-e2e"), "directory the node configs, data and logs are written to")
+	basePortPtr := flag.Int("baseport", 21000, "first port used by the nodes and proxies")
+	passwordPtr := flag.String("password", "qwertyuiop", "password of the node keys")
+
+	flag.Parse()
+
+	if *scenarioPtr == "" {
+		printUsage()
+		os.Exit(1)
+	}
+
+	f, err := os.Open(*scenarioPtr)
+	if err != nil {
+		fmt.Printf("Failed to open scenario: %v\n", err)
+		os.Exit(1)
+	}
+	steps, err := parseScenario(f)
+	f.Close()
+	if err != nil {
+		fmt.Printf("Failed to parse scenario: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(*workDirPtr, 0700); err != nil {
+		fmt.Printf("Failed to create work dir: %v\n", err)
+		os.Exit(1)
+	}
+	net, err := newNetwork(*thetaBinPtr, *testnetPtr, *workDirPtr, strings.Split(*nodesPtr, ","), *basePortPtr, *passwordPtr)
+	if err != nil {
+		fmt.Printf("Failed to set up network: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Don't leave node processes behind when interrupted.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		net.shutdown()
+		os.Exit(1)
+	}()
+
+	r := &runner{
+		net:     net,
+		baseDir: filepath.Dir(*scenarioPtr),
+	}
+	err = r.run(steps)
+	net.shutdown()
+	if err != nil {
+		fmt.Printf("[theta-e2e] FAILED: %v\n", err)
+		fmt.Printf("[theta-e2e] Node logs are under %v\n", *workDirPtr)
+		os.Exit(1)
+	}
+	fmt.Println("[theta-e2e] PASSED")
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const nodeStopTimeout = 10 * time.Second
+
+// node is a Theta node running as a separate process.
+type node struct {
+	name    string
+	index   int
+	dir     string // Config dir of the node under the work dir
+	p2pPort int
+	rpcPort int
+
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+func (n *node) isRunning() bool {
+	if n.cmd == nil {
+		return false
+	}
+	select {
+	case <-n.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (n *node) rpcURL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d/rpc", n.rpcPort)
+}
+
+// network launches the nodes on localhost. The nodes only connect to each other through a proxy
+// per ordered pair of nodes, so that links can be cut to partition the network.
+type network struct {
+	thetaBin string
+	workDir  string
+	password string
+
+	nodes   []*node
+	byName  map[string]*node
+	proxies map[[2]int]*proxy // Keyed by the indexes of the dialing and the dialed nodes
+}
+
+// newNetwork sets up the config dirs of the given nodes under workDir, copied from the ones
+// under templateDir. Node k listens on basePort+100*k for P2P and basePort+100*k+1 for RPC,
+// and reaches node j through the proxy listening on basePort+100*k+10+j.
+func newNetwork(thetaBin, templateDir, workDir string, names []string, basePort int, password string) (*network, error) {
+	if len(names) > 10 {
+		return nil, fmt.Errorf("At most 10 nodes are supported, got %v", len(names))
+	}
+	net := &network{
+		thetaBin: thetaBin,
+		workDir:  workDir,
+		password: password,
+		byName:   make(map[string]*node),
+		proxies:  make(map[[2]int]*proxy),
+	}
+	for k, name := range names {
+		n := &node{
+			name:    name,
+			index:   k,
+			dir:     path.Join(workDir, name),
+			p2pPort: basePort + 100*k,
+			rpcPort: basePort + 100*k + 1,
+		}
+		net.nodes = append(net.nodes, n)
+		net.byName[name] = n
+	}
+
+	for _, n := range net.nodes {
+		if err := os.RemoveAll(n.dir); err != nil {
+			return nil, err
+		}
+		if err := copyDir(path.Join(templateDir, n.name), n.dir); err != nil {
+			return nil, fmt.Errorf("Failed to copy config of %v: %v", n.name, err)
+		}
+		// Start from a fresh chain, and don't dial the peers known from previous runs.
+		os.RemoveAll(path.Join(n.dir, "db"))
+		os.Remove(path.Join(n.dir, "addrbook.json"))
+
+		seeds := []string{}
+		for _, peer := range net.nodes {
+			if peer != n {
+				seeds = append(seeds, fmt.Sprintf("127.0.0.1:%d", net.proxyPort(basePort, n, peer)))
+			}
+		}
+		if err := net.writeConfig(n, seeds); err != nil {
+			return nil, fmt.Errorf("Failed to write config of %v: %v", n.name, err)
+		}
+	}
+
+	for _, from := range net.nodes {
+		for _, to := range net.nodes {
+			if from == to {
+				continue
+			}
+			p := newProxy(fmt.Sprintf("127.0.0.1:%d", net.proxyPort(basePort, from, to)),
+				fmt.Sprintf("127.0.0.1:%d", to.p2pPort))
+			if err := p.start(); err != nil {
+				net.shutdown()
+				return nil, fmt.Errorf("Failed to start proxy: %v", err)
+			}
+			net.proxies[[2]int{from.index, to.index}] = p
+		}
+	}
+	return net, nil
+}
+
+func (net *network) proxyPort(basePort int, from *node, to *node) int {
+	return basePort + 100*from.index + 10 + to.index
+}
+
+// writeConfig overrides the network settings of the config copied from the template.
+func (net *network) writeConfig(n *node, seeds []string) error {
+	configPath := path.Join(n.dir, "config.yaml")
+	cfg := make(map[interface{}]interface{})
+	if data, err := ioutil.ReadFile(configPath); err == nil {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return err
+		}
+	}
+	setConfigValue(cfg, "p2p", "port", n.p2pPort)
+	setConfigValue(cfg, "p2p", "seeds", strings.Join(seeds, ","))
+	setConfigValue(cfg, "p2p", "seedPeerOnlyOutbound", true) // Only dial the proxies
+	setConfigValue(cfg, "rpc", "enabled", true)
+	setConfigValue(cfg, "rpc", "port", fmt.Sprintf("%d", n.rpcPort))
+	setConfigValue(cfg, "log", "printSelfID", true)
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configPath, data, 0600)
+}
+
+func setConfigValue(cfg map[interface{}]interface{}, section string, key string, value interface{}) {
+	s, ok := cfg[section].(map[interface{}]interface{})
+	if !ok {
+		s = make(map[interface{}]interface{})
+		cfg[section] = s
+	}
+	s[key] = value
+}
+
+func (net *network) getNode(name string) (*node, error) {
+	n, ok := net.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown node: %v", name)
+	}
+	return n, nil
+}
+
+// resolveNodes returns the nodes with the given names, or all the nodes for "all".
+func (net *network) resolveNodes(names []string) ([]*node, error) {
+	if len(names) == 1 && names[0] == "all" {
+		return net.nodes, nil
+	}
+	nodes := []*node{}
+	for _, name := range names {
+		n, err := net.getNode(name)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func (net *network) runningNodes() []*node {
+	nodes := []*node{}
+	for _, n := range net.nodes {
+		if n.isRunning() {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+func (net *network) startNode(n *node) error {
+	if n.isRunning() {
+		return fmt.Errorf("Node %v is already running", n.name)
+	}
+	logFile, err := os.OpenFile(path.Join(net.workDir, n.name+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(net.thetaBin, "start", "--config="+n.dir)
+	cmd.Stdin = strings.NewReader(net.password + "\n") // The key password is read from stdin when not a TTY
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return err
+	}
+	n.cmd = cmd
+	n.exited = make(chan struct{})
+	go func(exited chan struct{}) {
+		cmd.Wait()
+		logFile.Close()
+		close(exited)
+	}(n.exited)
+	return nil
+}
+
+func (net *network) stopNode(n *node) error {
+	if !n.isRunning() {
+		return fmt.Errorf("Node %v is not running", n.name)
+	}
+	n.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-n.exited:
+	case <-time.After(nodeStopTimeout):
+		n.cmd.Process.Kill()
+		<-n.exited
+	}
+	return nil
+}
+
+// partition cuts the links between the nodes of different groups. Nodes not in any group keep
+// their links.
+func (net *network) partition(groups [][]*node) {
+	for i, group := range groups {
+		for j, other := range groups {
+			if i == j {
+				continue
+			}
+			for _, a := range group {
+				for _, b := range other {
+					net.proxies[[2]int{a.index, b.index}].setBlocked(true)
+				}
+			}
+		}
+	}
+}
+
+// heal restores all the links.
+func (net *network) heal() {
+	for _, p := range net.proxies {
+		p.setBlocked(false)
+	}
+}
+
+// shutdown stops all the running nodes and the proxies.
+func (net *network) shutdown() {
+	for _, n := range net.runningNodes() {
+		net.stopNode(n)
+	}
+	for _, p := range net.proxies {
+		p.close()
+	}
+}
+
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(srcPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, srcPath)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dstPath, 0700)
+		}
+		return copyFile(srcPath, dstPath, info.Mode())
+	})
+}
+
+func copyFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
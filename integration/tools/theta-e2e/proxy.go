@@ -0,0 +1,137 @@
+package main
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const proxyDialTimeout = 5 * time.Second
+
+// proxy forwards the P2P connections one node opens to another. While the link is blocked,
+// the established connections are dropped and new ones refused, which partitions the network
+// without touching the firewall.
+type proxy struct {
+	listenAddr string
+	targetAddr string
+	listener   net.Listener
+
+	mu      sync.Mutex
+	blocked bool
+	closed  bool
+	conns   map[net.Conn]bool
+}
+
+func newProxy(listenAddr string, targetAddr string) *proxy {
+	return &proxy{
+		listenAddr: listenAddr,
+		targetAddr: targetAddr,
+		conns:      make(map[net.Conn]bool),
+	}
+}
+
+func (p *proxy) start() error {
+	l, err := net.Listen("tcp", p.listenAddr)
+	if err != nil {
+		return err
+	}
+	p.listener = l
+	go p.acceptLoop()
+	return nil
+}
+
+func (p *proxy) acceptLoop() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return // Listener closed
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *proxy) handle(in net.Conn) {
+	if p.isBlocked() {
+		in.Close()
+		return
+	}
+	out, err := net.DialTimeout("tcp", p.targetAddr, proxyDialTimeout)
+	if err != nil {
+		in.Close()
+		return
+	}
+	if !p.track(in, out) {
+		in.Close()
+		out.Close()
+		return
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(out, in)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(in, out)
+		done <- struct{}{}
+	}()
+	<-done
+
+	in.Close()
+	out.Close()
+	p.untrack(in, out)
+}
+
+func (p *proxy) isBlocked() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.blocked || p.closed
+}
+
+func (p *proxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.blocked || p.closed {
+		return false
+	}
+	for _, conn := range conns {
+		p.conns[conn] = true
+	}
+	return true
+}
+
+func (p *proxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+// setBlocked blocks or unblocks the link. Blocking drops the established connections.
+func (p *proxy) setBlocked(blocked bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.blocked = blocked
+	if blocked {
+		p.dropConnsUnsafe()
+	}
+}
+
+func (p *proxy) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.listener != nil {
+		p.listener.Close()
+	}
+	p.dropConnsUnsafe()
+}
+
+func (p *proxy) dropConnsUnsafe() {
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = make(map[net.Conn]bool)
+}
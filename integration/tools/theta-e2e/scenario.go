@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
+)
+
+const (
+	defaultWaitTimeout = 120 * time.Second
+	pollInterval       = 1 * time.Second
+)
+
+// step is a command of the scenario script. Each non-empty line not starting with '#' is a
+// command followed by positional arguments and key=value options, e.g.
+//
+//		start all
+//		wait-height 10 timeout=60s
+//		partition node1,node2 node3,node4
+//		heal
+//		stop node4
+//		send node=node1 key=keys/sender to=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab tfuel=10 count=100
+//		assert-balance node=node2 address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab tfuel=1000wei
+//		assert-finalized
+//
+type step struct {
+	line int
+	cmd  string
+	args []string
+	opts map[string]string
+}
+
+func (s *step) String() string {
+	return fmt.Sprintf("line %d: %v", s.line, s.cmd)
+}
+
+func (s *step) opt(key string, defaultValue string) string {
+	if v, ok := s.opts[key]; ok {
+		return v
+	}
+	return defaultValue
+}
+
+func (s *step) requiredOpt(key string) (string, error) {
+	v, ok := s.opts[key]
+	if !ok || v == "" {
+		return "", fmt.Errorf("Option %v is required", key)
+	}
+	return v, nil
+}
+
+func (s *step) timeout() (time.Duration, error) {
+	return time.ParseDuration(s.opt("timeout", defaultWaitTimeout.String()))
+}
+
+// parseScenario parses the scenario script.
+func parseScenario(r io.Reader) ([]*step, error) {
+	steps := []*step{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		s := &step{
+			line: line,
+			cmd:  fields[0],
+			opts: make(map[string]string),
+		}
+		for _, field := range fields[1:] {
+			if idx := strings.Index(field, "="); idx > 0 {
+				s.opts[field[:idx]] = field[idx+1:]
+			} else {
+				s.args = append(s.args, field)
+			}
+		}
+		if _, ok := commands[s.cmd]; !ok {
+			return nil, fmt.Errorf("line %d: unknown command %v", line, s.cmd)
+		}
+		steps = append(steps, s)
+	}
+	return steps, scanner.Err()
+}
+
+// runner executes the scenario steps against the network.
+type runner struct {
+	net     *network
+	baseDir string // Directory of the scenario script, relative key paths are resolved from it
+}
+
+type command func(r *runner, s *step) error
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"start":              (*runner).start,
+		"stop":               (*runner).stop,
+		"partition":          (*runner).partition,
+		"heal":               (*runner).heal,
+		"sleep":              (*runner).sleep,
+		"wait-height":        (*runner).waitHeight,
+		"assert-no-progress": (*runner).assertNoProgress,
+		"assert-finalized":   (*runner).assertFinalized,
+		"assert-balance":     (*runner).assertBalance,
+		"send":               (*runner).send,
+	}
+}
+
+func (r *runner) run(steps []*step) error {
+	for _, s := range steps {
+		fmt.Printf("[theta-e2e] %v %v\n", s, strings.Join(s.args, " "))
+		if err := commands[s.cmd](r, s); err != nil {
+			return fmt.Errorf("%v: %v", s, err)
+		}
+	}
+	return nil
+}
+
+// targetNodes returns the nodes set by the "nodes" option, or the running nodes by default.
+func (r *runner) targetNodes(s *step) ([]*node, error) {
+	names, ok := s.opts["nodes"]
+	if !ok {
+		nodes := r.net.runningNodes()
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("No node is running")
+		}
+		return nodes, nil
+	}
+	return r.net.resolveNodes(strings.Split(names, ","))
+}
+
+func (r *runner) start(s *step) error {
+	nodes, err := r.net.resolveNodes(s.args)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if err := r.net.startNode(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) stop(s *step) error {
+	nodes, err := r.net.resolveNodes(s.args)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if err := r.net.stopNode(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) partition(s *step) error {
+	if len(s.args) < 2 {
+		return fmt.Errorf("At least two groups of nodes are required")
+	}
+	groups := [][]*node{}
+	for _, arg := range s.args {
+		group, err := r.net.resolveNodes(strings.Split(arg, ","))
+		if err != nil {
+			return err
+		}
+		groups = append(groups, group)
+	}
+	r.net.partition(groups)
+	return nil
+}
+
+func (r *runner) heal(s *step) error {
+	r.net.heal()
+	return nil
+}
+
+func (r *runner) sleep(s *step) error {
+	if len(s.args) != 1 {
+		return fmt.Errorf("Usage: sleep <duration>")
+	}
+	d, err := time.ParseDuration(s.args[0])
+	if err != nil {
+		return err
+	}
+	time.Sleep(d)
+	return nil
+}
+
+// waitHeight waits until the target nodes have finalized the given height.
+func (r *runner) waitHeight(s *step) error {
+	if len(s.args) != 1 {
+		return fmt.Errorf("Usage: wait-height <height> [nodes=<node,...>] [timeout=<duration>]")
+	}
+	height, err := strconv.ParseUint(s.args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	nodes, err := r.targetNodes(s)
+	if err != nil {
+		return err
+	}
+	timeout, err := s.timeout()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, n := range nodes {
+		for {
+			status, err := getStatus(n)
+			if err == nil && uint64(status.LatestFinalizedBlockHeight) >= height {
+				break
+			}
+			if time.Now().After(deadline) {
+				if err != nil {
+					return fmt.Errorf("Timed out waiting for %v to finalize height %v: %v", n.name, height, err)
+				}
+				return fmt.Errorf("Timed out waiting for %v to finalize height %v, finalized: %v",
+					n.name, height, status.LatestFinalizedBlockHeight)
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+	return nil
+}
+
+// assertNoProgress asserts that the target nodes finalize no block during the given duration,
+// e.g. when no partition has a majority of the stake.
+func (r *runner) assertNoProgress(s *step) error {
+	if len(s.args) != 1 {
+		return fmt.Errorf("Usage: assert-no-progress <duration> [nodes=<node,...>]")
+	}
+	d, err := time.ParseDuration(s.args[0])
+	if err != nil {
+		return err
+	}
+	nodes, err := r.targetNodes(s)
+	if err != nil {
+		return err
+	}
+	before := make(map[string]uint64)
+	for _, n := range nodes {
+		status, err := getStatus(n)
+		if err != nil {
+			return err
+		}
+		before[n.name] = uint64(status.LatestFinalizedBlockHeight)
+	}
+	time.Sleep(d)
+	for _, n := range nodes {
+		status, err := getStatus(n)
+		if err != nil {
+			return err
+		}
+		if height := uint64(status.LatestFinalizedBlockHeight); height != before[n.name] {
+			return fmt.Errorf("%v finalized blocks from height %v to %v", n.name, before[n.name], height)
+		}
+	}
+	return nil
+}
+
+// assertFinalized asserts that the target nodes finalized the same blocks, up to the lowest
+// finalized height among them, or the height given by the "height" option.
+func (r *runner) assertFinalized(s *step) error {
+	nodes, err := r.targetNodes(s)
+	if err != nil {
+		return err
+	}
+	var height uint64
+	if h, ok := s.opts["height"]; ok {
+		if height, err = strconv.ParseUint(h, 10, 64); err != nil {
+			return err
+		}
+	} else {
+		for i, n := range nodes {
+			status, err := getStatus(n)
+			if err != nil {
+				return err
+			}
+			if h := uint64(status.LatestFinalizedBlockHeight); i == 0 || h < height {
+				height = h
+			}
+		}
+	}
+	if height == 0 {
+		return fmt.Errorf("No block finalized")
+	}
+
+	var expected common.Hash
+	for i, n := range nodes {
+		block, err := getFinalizedBlock(n, height)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			expected = block.Hash
+		} else if block.Hash != expected {
+			return fmt.Errorf("Finalized block mismatch at height %v: %v has %v, %v has %v",
+				height, nodes[0].name, expected.Hex(), n.name, block.Hash.Hex())
+		}
+	}
+	fmt.Printf("[theta-e2e] %v nodes agree on block %v at height %v\n", len(nodes), expected.Hex(), height)
+	return nil
+}
+
+// assertBalance asserts the finalized balance of an account. Amounts are in Theta/TFuel units,
+// or in wei with the "wei" suffix.
+func (r *runner) assertBalance(s *step) error {
+	n, err := r.optNode(s)
+	if err != nil {
+		return err
+	}
+	addr, err := s.requiredOpt("address")
+	if err != nil {
+		return err
+	}
+	expected, err := parseCoins(s)
+	if err != nil {
+		return err
+	}
+	timeout, err := s.timeout()
+	if err != nil {
+		return err
+	}
+
+	// Transactions could still be pending, so wait for the balance to be reached.
+	deadline := time.Now().Add(timeout)
+	for {
+		balance, err := getBalance(n, common.HexToAddress(addr))
+		if err == nil && balance.IsEqual(expected) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("Balance mismatch for %v on %v: %v, expected: %v", addr, n.name, balance, expected)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// send submits a load of send transactions to a node.
+func (r *runner) send(s *step) error {
+	n, err := r.optNode(s)
+	if err != nil {
+		return err
+	}
+	keyPath, err := s.requiredOpt("key")
+	if err != nil {
+		return err
+	}
+	privKey, err := r.loadKey(keyPath)
+	if err != nil {
+		return err
+	}
+	to, err := s.requiredOpt("to")
+	if err != nil {
+		return err
+	}
+	coins, err := parseCoins(s)
+	if err != nil {
+		return err
+	}
+	fee, ok := types.ParseCoinAmount(s.opt("fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeTFuelWei)))
+	if !ok {
+		return fmt.Errorf("Invalid fee: %v", s.opts["fee"])
+	}
+	count, err := strconv.Atoi(s.opt("count", "1"))
+	if err != nil {
+		return err
+	}
+
+	block, err := getFinalizedBlock(n, 1)
+	if err != nil {
+		return fmt.Errorf("Failed to get chain ID: %v", err)
+	}
+	sequence, err := getNextSequence(n, privKey.PublicKey().Address())
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		err := sendTokens(n, block.ChainID, privKey, common.HexToAddress(to), coins, fee, sequence+uint64(i))
+		if err != nil {
+			return fmt.Errorf("Failed to send transaction %v of %v: %v", i+1, count, err)
+		}
+	}
+	return nil
+}
+
+func (r *runner) optNode(s *step) (*node, error) {
+	name, err := s.requiredOpt("node")
+	if err != nil {
+		return nil, err
+	}
+	return r.net.getNode(name)
+}
+
+// loadKey reads a raw hex private key from the given file.
+func (r *runner) loadKey(keyPath string) (*crypto.PrivateKey, error) {
+	if !filepath.IsAbs(keyPath) {
+		keyPath = filepath.Join(r.baseDir, keyPath)
+	}
+	data, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ks.ImportHexKey(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
+
+func parseCoins(s *step) (types.Coins, error) {
+	theta, ok := types.ParseCoinAmount(s.opt("theta", "0"))
+	if !ok {
+		return types.Coins{}, fmt.Errorf("Invalid theta amount: %v", s.opts["theta"])
+	}
+	tfuel, ok := types.ParseCoinAmount(s.opt("tfuel", "0"))
+	if !ok {
+		return types.Coins{}, fmt.Errorf("Invalid tfuel amount: %v", s.opts["tfuel"])
+	}
+	return types.Coins{ThetaWei: theta, TFuelWei: tfuel}, nil
+}
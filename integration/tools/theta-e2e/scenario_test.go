@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScenario(t *testing.T) {
+	assert := assert.New(t)
+
+	script := `
+# Comment
+start all
+
+partition node1,node2 node3,node4
+wait-height 10 nodes=node1,node2 timeout=30s
+`
+	steps, err := parseScenario(strings.NewReader(script))
+	assert.Nil(err)
+	assert.Equal(3, len(steps))
+
+	assert.Equal("start", steps[0].cmd)
+	assert.Equal([]string{"all"}, steps[0].args)
+	assert.Equal(3, steps[0].line)
+
+	assert.Equal("partition", steps[1].cmd)
+	assert.Equal([]string{"node1,node2", "node3,node4"}, steps[1].args)
+
+	assert.Equal("wait-height", steps[2].cmd)
+	assert.Equal([]string{"10"}, steps[2].args)
+	assert.Equal("node1,node2", steps[2].opt("nodes", ""))
+	timeout, err := steps[2].timeout()
+	assert.Nil(err)
+	assert.Equal("30s", timeout.String())
+
+	_, err = parseScenario(strings.NewReader("start all\nexplode node1\n"))
+	assert.NotNil(err)
+}
@@ -0,0 +1,57 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+//
+// blockLimits tracks how much of the block limits set by the chain params the regular
+// transactions of a block use
+//
+type blockLimits struct {
+	params *types.ChainParams
+
+	numTxs    uint64
+	gasUsed   uint64
+	bytesUsed uint64
+}
+
+func newBlockLimits(params *types.ChainParams) *blockLimits {
+	return &blockLimits{
+		params: params,
+	}
+}
+
+// isSpecialTx returns true for the transactions added by the proposer, which are not subject
+// to the block limits.
+func isSpecialTx(tx types.Tx) bool {
+	switch tx.(type) {
+	case *types.CoinbaseTx, *types.SlashTx:
+		return true
+	}
+	return false
+}
+
+// fits returns an error if the regular transaction would push the block over one of its limits.
+func (bl *blockLimits) fits(rawTx common.Bytes, gas uint64) error {
+	if bl.numTxs+1 > bl.params.MaxNumRegularTxsPerBlock {
+		return fmt.Errorf("Block exceeds the max number of regular transactions %v", bl.params.MaxNumRegularTxsPerBlock)
+	}
+	if bl.gasUsed+gas > bl.params.MaxBlockGas {
+		return fmt.Errorf("Block exceeds the gas limit %v", bl.params.MaxBlockGas)
+	}
+	if bl.bytesUsed+uint64(len(rawTx)) > bl.params.MaxBlockBytes {
+		return fmt.Errorf("Block exceeds the size limit %v bytes", bl.params.MaxBlockBytes)
+	}
+	return nil
+}
+
+// add accounts for a regular transaction included in the block.
+func (bl *blockLimits) add(rawTx common.Bytes, gas uint64) {
+	bl.numTxs++
+	bl.gasUsed += gas
+	bl.bytesUsed += uint64(len(rawTx))
+}
@@ -0,0 +1,126 @@
+package ledger
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// DryRunResult is the outcome of a transaction executed without committing the state changes
+type DryRunResult struct {
+	GasUsed uint64
+	Receipt *types.Receipt // only for the smart contract transactions
+}
+
+// DryRunTx executes the transaction against a throwaway copy of the state, 0 height meaning the
+// latest state, and the state of the finalized block at the height otherwise. The state changes
+// are discarded. If skipSanityCheck is true, the transaction is executed without checking the
+// signature, the sequence or the balance, e.g. to preview an unsigned transaction.
+func (ledger *Ledger) DryRunTx(rawTx common.Bytes, height uint64, skipSanityCheck bool) (*DryRunResult, result.Result) {
+	tx, res := parseDryRunTx(rawTx)
+	if res.IsError() {
+		return nil, res
+	}
+	view, err := ledger.dryRunView(height)
+	if err != nil {
+		return nil, result.Error("%v", err)
+	}
+	return ledger.dryRunTxOnView(tx, view, skipSanityCheck)
+}
+
+// EstimateGas returns the lowest gas limit with which the transaction executes successfully,
+// on the state selected as in DryRunTx. The gas used with the block gas limit may not be enough
+// as the gas limit, since part of the gas can be refunded, and a contract call only forwards
+// part of the remaining gas. The gas limit of a smart contract transaction is thus searched
+// between the two. The sanity checks are skipped, since the gas limit is signed.
+func (ledger *Ledger) EstimateGas(rawTx common.Bytes, height uint64) (uint64, result.Result) {
+	tx, res := parseDryRunTx(rawTx)
+	if res.IsError() {
+		return 0, res
+	}
+	view, err := ledger.dryRunView(height)
+	if err != nil {
+		return 0, result.Error("%v", err)
+	}
+
+	sctx, ok := tx.(*types.SmartContractTx)
+	if !ok {
+		dryRun, res := ledger.dryRunTxOnView(tx, view, true)
+		if res.IsError() {
+			return 0, res
+		}
+		return dryRun.GasUsed, result.OK
+	}
+
+	executeWithGasLimit := func(gasLimit uint64) (*DryRunResult, result.Result) {
+		tx := *sctx
+		tx.GasLimit = gasLimit
+		copiedView, err := view.Copy()
+		if err != nil {
+			return nil, result.Error("Failed to copy the state: %v", err)
+		}
+		dryRun, res := ledger.dryRunTxOnView(&tx, copiedView, true)
+		if res.IsOK() && dryRun.Receipt.EvmErr != "" {
+			res = result.Error("%v", dryRun.Receipt.EvmErr).WithErrorCode(result.CodeEVMError)
+		}
+		return dryRun, res
+	}
+
+	maxGas := view.GetChainParams().MaxBlockGas
+	dryRun, res := executeWithGasLimit(maxGas)
+	if res.IsError() {
+		return 0, res.WithMessage(" (with the block gas limit)")
+	}
+
+	// Invariant: the transaction succeeds with the gas limit hi, and fails with lo
+	lo, hi := dryRun.GasUsed, maxGas
+	if _, res := executeWithGasLimit(lo); res.IsOK() {
+		return lo, result.OK
+	}
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		if _, res := executeWithGasLimit(mid); res.IsOK() {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, result.OK
+}
+
+func parseDryRunTx(rawTx common.Bytes) (types.Tx, result.Result) {
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, result.Error("Failed to parse transaction: %v", err).WithErrorCode(result.CodeTxDecodingFailed)
+	}
+	if isSpecialTx(tx) {
+		return nil, result.Error("Transactions added by the block proposer cannot be dry run").
+			WithErrorCode(result.CodeUnauthorizedTx)
+	}
+	return tx, result.OK
+}
+
+// dryRunView returns a copy of the latest delivered state for height 0, and the state of the
+// finalized block at the height otherwise.
+func (ledger *Ledger) dryRunView(height uint64) (*st.StoreView, error) {
+	if height == 0 {
+		return ledger.GetDeliveredSnapshot()
+	}
+	_, view, err := ledger.finalizedStoreViewAt(height)
+	return view, err
+}
+
+func (ledger *Ledger) dryRunTxOnView(tx types.Tx, view *st.StoreView, skipSanityCheck bool) (*DryRunResult, result.Result) {
+	_, res := ledger.executor.DryRunTxOnView(tx, view, skipSanityCheck)
+	if res.IsError() {
+		return nil, res
+	}
+
+	dryRun := &DryRunResult{GasUsed: ledger.getTxGas(tx)}
+	if r, ok := res.Info["receipt"]; ok {
+		dryRun.Receipt = r.(*types.Receipt)
+		dryRun.GasUsed = dryRun.Receipt.GasUsed
+	}
+	return dryRun, result.OK
+}
@@ -0,0 +1,101 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func TestLedgerDryRunTx(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 1)
+	stateRoot := ledger.state.Delivered().Hash()
+
+	sendTxBytes := newRawSendTx(chainID, 1, true, accOut, accIns[0], false)
+	dryRun, res := ledger.DryRunTx(sendTxBytes, 0, false)
+	require.True(res.IsOK(), res.Message)
+	assert.Nil(dryRun.Receipt)
+	assert.True(dryRun.GasUsed > 0)
+
+	// The state is not modified, so the same transaction can be dry run again
+	assert.Equal(stateRoot, ledger.state.Delivered().Hash())
+	_, res = ledger.DryRunTx(sendTxBytes, 0, false)
+	assert.True(res.IsOK(), res.Message)
+
+	// Unsigned transactions are only executed without the sanity checks
+	unsignedTxBytes := newRawSendTx(chainID, 1, true, accOut, accIns[0], false)
+	tx, err := types.TxFromBytes(unsignedTxBytes)
+	require.Nil(err)
+	tx.(*types.SendTx).Inputs[0].Signature = nil
+	unsignedTxBytes, err = types.TxToBytes(tx)
+	require.Nil(err)
+	_, res = ledger.DryRunTx(unsignedTxBytes, 0, false)
+	assert.True(res.IsError())
+	_, res = ledger.DryRunTx(unsignedTxBytes, 0, true)
+	assert.True(res.IsOK(), res.Message)
+
+	coinbaseTxBytes := newRawCoinbaseTx(chainID, ledger, 1)
+	_, res = ledger.DryRunTx(coinbaseTxBytes, 0, true)
+	assert.Equal(result.CodeUnauthorizedTx, res.Code)
+
+	_, res = ledger.DryRunTx(common.Bytes("invalid"), 0, true)
+	assert.Equal(result.CodeTxDecodingFailed, res.Code)
+}
+
+func TestLedgerEstimateGas(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 1)
+
+	sendTxBytes := newRawSendTx(chainID, 1, true, accOut, accIns[0], false)
+	gasLimit, res := ledger.EstimateGas(sendTxBytes, 0)
+	require.True(res.IsOK(), res.Message)
+	dryRun, _ := ledger.DryRunTx(sendTxBytes, 0, false)
+	assert.Equal(dryRun.GasUsed, gasLimit)
+
+	// Deploys a contract storing 1 at slot 0: PUSH1 1, PUSH1 0, SSTORE, STOP
+	sctx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address:  accIns[0].Address,
+			Sequence: 1,
+		},
+		GasLimit: 1, // ignored by the estimation
+		GasPrice: new(big.Int).SetUint64(types.MinimumGasPrice),
+		Data:     common.Hex2Bytes("600160005500"),
+	}
+	sctxBytes, err := types.TxToBytes(sctx)
+	require.Nil(err)
+
+	// The smart contracts are not executed before their upgrade, even in a dry run
+	_, res = ledger.EstimateGas(sctxBytes, 0)
+	assert.Equal(result.CodeUnknownTxType, res.Code)
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeSmartContracts: 0}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	gasLimit, res = ledger.EstimateGas(sctxBytes, 0)
+	require.True(res.IsOK(), res.Message)
+
+	sctx.GasLimit = gasLimit
+	sctxBytes, _ = types.TxToBytes(sctx)
+	dryRun, res = ledger.DryRunTx(sctxBytes, 0, true)
+	require.True(res.IsOK(), res.Message)
+	assert.Equal("", dryRun.Receipt.EvmErr)
+	assert.Equal(gasLimit, dryRun.GasUsed)
+
+	sctx.GasLimit = gasLimit - 1
+	sctxBytes, _ = types.TxToBytes(sctx)
+	dryRun, res = ledger.DryRunTx(sctxBytes, 0, true)
+	require.True(res.IsOK(), res.Message)
+	assert.NotEqual("", dryRun.Receipt.EvmErr)
+}
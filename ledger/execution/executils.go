@@ -68,12 +68,14 @@ func getInputs(view *state.StoreView, ins []types.TxInput) (map[string]*types.Ac
 	for _, in := range ins {
 		// Account shouldn't be duplicated
 		if _, ok := accounts[string(in.Address[:])]; ok {
-			return nil, result.Error("getInputs - Duplicated address: %v", in.Address)
+			return nil, result.Error("getInputs - Duplicated address: %v", in.Address).
+				WithErrorCode(result.CodeDuplicatedAddress)
 		}
 
 		acc, success := getAccount(view, in.Address)
 		if success.IsError() {
-			return nil, result.Error("getInputs - Unknown address: %v", in.Address)
+			return nil, result.Error("getInputs - Unknown address: %v", in.Address).
+				WithErrorCode(result.CodeAccountNotFound)
 		}
 
 		accounts[string(in.Address[:])] = acc
@@ -93,7 +95,8 @@ func getOrMakeInput(view *state.StoreView, in types.TxInput) (*types.Account, re
 func getOrMakeInputImpl(view *state.StoreView, in types.TxInput, makeNewAccount bool) (*types.Account, result.Result) {
 	acc, success := getOrMakeAccountImpl(view, in.Address, makeNewAccount)
 	if success.IsError() {
-		return nil, result.Error("getOrMakeInputImpl - Unknown address: %v", in.Address)
+		return nil, result.Error("getOrMakeInputImpl - Unknown address: %v", in.Address).
+			WithErrorCode(result.CodeAccountNotFound)
 	}
 
 	return acc, result.OK
@@ -113,7 +116,8 @@ func getOrMakeAccountImpl(view *state.StoreView, address common.Address, makeNew
 	acc := view.GetAccount(address)
 	if acc == nil {
 		if !makeNewAccount {
-			return nil, result.Error("getOrMakeAccountImpl - Unknown address: %v", address)
+			return nil, result.Error("getOrMakeAccountImpl - Unknown address: %v", address).
+				WithErrorCode(result.CodeAccountNotFound)
 		}
 		acc = types.NewAccount(address)
 		acc.LastUpdatedBlockHeight = view.Height()
@@ -131,7 +135,8 @@ func getOrMakeOutputs(view *state.StoreView, accounts map[string]*types.Account,
 	for _, out := range outs {
 		// Account shouldn't be duplicated
 		if _, ok := accounts[string(out.Address[:])]; ok {
-			return nil, result.Error("getOrMakeOutputs - Duplicated address: %v", out.Address)
+			return nil, result.Error("getOrMakeOutputs - Duplicated address: %v", out.Address).
+				WithErrorCode(result.CodeDuplicatedAddress)
 		}
 
 		acc := getOrMakeAccount(view, out.Address)
@@ -172,6 +177,16 @@ func validateInputsAdvanced(accounts map[string]*types.Account, signBytes []byte
 func validateInputAdvanced(acc *types.Account, signBytes []byte, in types.TxInput) result.Result {
 	// Check sequence/coins
 	seq, balance := acc.Sequence, acc.Balance
+	if in.Sequence > seq+1 {
+		// The transaction could become valid once the sequence gap is filled. Only the
+		// signature can be checked for now.
+		if !in.Signature.Verify(signBytes, acc.Address) {
+			return result.Error("Signature verification failed, SignBytes: %v",
+				hex.EncodeToString(signBytes)).WithErrorCode(result.CodeInvalidSignature)
+		}
+		return result.Error("ValidateInputAdvanced: Got future sequence %v, expected %v. (acc.seq=%v)",
+			in.Sequence, seq+1, acc.Sequence).WithErrorCode(result.CodeFutureSequence)
+	}
 	if seq+1 != in.Sequence {
 		return result.Error("ValidateInputAdvanced: Got %v, expected %v. (acc.seq=%v)",
 			in.Sequence, seq+1, acc.Sequence).WithErrorCode(result.CodeInvalidSequence)
@@ -238,30 +253,58 @@ func adjustByOutputs(view *state.StoreView, accounts map[string]*types.Account,
 	}
 }
 
-func sanityCheckForGasPrice(gasPrice *big.Int) bool {
+func sanityCheckForGasPrice(view *state.StoreView, gasPrice *big.Int) bool {
 	if gasPrice == nil {
 		return false
 	}
 
-	minimumGasPrice := new(big.Int).SetUint64(types.MinimumGasPrice)
-	if gasPrice.Cmp(minimumGasPrice) < 0 {
+	if gasPrice.Cmp(minimumGasPrice(view)) < 0 {
 		return false
 	}
 
 	return true
 }
 
-func sanityCheckForFee(fee types.Coins) bool {
+func sanityCheckForFee(view *state.StoreView, fee types.Coins) bool {
 	fee = fee.NoNil()
-	minimumFee := new(big.Int).SetUint64(types.MinimumTransactionFeeTFuelWei)
-	return fee.ThetaWei.Cmp(types.Zero) == 0 && fee.TFuelWei.Cmp(minimumFee) >= 0
+	return fee.ThetaWei.Cmp(types.Zero) == 0 && fee.TFuelWei.Cmp(minimumTransactionFee(view)) >= 0
+}
+
+// minimumGasPrice returns the min gas price of a smart contract transaction, which follows the
+// base fee of the block being executed
+func minimumGasPrice(view *state.StoreView) *big.Int {
+	return types.MinimumGasPriceWithBaseFee(view.GetChainParams(), view.GetBaseFee())
 }
 
-func chargeFee(account *types.Account, fee types.Coins) bool {
+// minimumTransactionFee returns the min fee of a regular transaction, which scales with the base
+// fee of the block being executed
+func minimumTransactionFee(view *state.StoreView) *big.Int {
+	return types.MinimumTransactionFeeWithBaseFee(view.GetChainParams(), view.GetBaseFee())
+}
+
+// chargeFee deducts the fee from the account. The fee is burned.
+func chargeFee(view *state.StoreView, account *types.Account, fee types.Coins) bool {
 	if !account.Balance.IsGTE(fee) {
 		return false
 	}
 
 	account.Balance = account.Balance.Minus(fee)
+	view.AddBurnedTFuel(fee.NoNil().TFuelWei)
 	return true
 }
+
+// addToUnbondingQueue adds the stake removed from the holder to the unbonding queue. The stake is
+// returned to its source once the unbonding period of the chain params has passed.
+func addToUnbondingQueue(view *state.StoreView, stake *core.Stake, holder common.Address, purpose uint8, delegated bool) {
+	queue := view.GetUnbondingQueue()
+	queue.Add(&types.UnbondingEntry{
+		Source:       stake.Source,
+		Holder:       holder,
+		Purpose:      purpose,
+		Delegated:    delegated,
+		Amount:       stake.Amount,
+		StartHeight:  view.Height(),
+		ReturnHeight: view.Height() + view.GetChainParams().GetUnbondingPeriod(),
+	})
+	view.UpdateUnbondingQueue(queue)
+}
@@ -5,12 +5,13 @@ import (
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
 	st "github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "ledger"})
+var logger *log.Entry = util.GetLoggerForModule("ledger")
 
 //
 // TxExecutor defines the interface of the transaction executors
@@ -36,9 +37,22 @@ type Executor struct {
 	releaseFundTxExec    *ReleaseFundTxExecutor
 	servicePaymentTxExec *ServicePaymentTxExecutor
 	splitRuleTxExec      *SplitRuleTxExecutor
-	//smartContractTxExec  *SmartContractTxExecutor
-	depositStakeTxExec  *DepositStakeExecutor
-	withdrawStakeTxExec *WithdrawStakeExecutor
+	smartContractTxExec  *SmartContractTxExecutor
+	depositStakeTxExec   *DepositStakeExecutor
+	withdrawStakeTxExec  *WithdrawStakeExecutor
+	governanceTxExec     *GovernanceTxExecutor
+	proposalTxExec       *GovernanceProposalTxExecutor
+	voteTxExec           *GovernanceVoteTxExecutor
+	tallyTxExec          *GovernanceTallyTxExecutor
+	bridgeLockTxExec     *BridgeLockTxExecutor
+	bridgeMintTxExec     *BridgeMintTxExecutor
+	bridgeValsTxExec     *BridgeValidatorsTxExecutor
+	sponsoredTxExec      *SponsoredTxExecutor
+	timeLockTxExec       *TimeLockTxExecutor
+	timeLockClaimTxExec  *TimeLockClaimTxExecutor
+	timeLockRevokeTxExec *TimeLockRevokeTxExecutor
+	delegateTxExec       *DelegateTxExecutor
+	undelegateTxExec     *UndelegateTxExecutor
 
 	skipSanityCheck bool
 }
@@ -51,16 +65,29 @@ func NewExecutor(state *st.LedgerState, consensus core.ConsensusEngine, valMgr c
 		valMgr:               valMgr,
 		coinbaseTxExec:       NewCoinbaseTxExecutor(state, consensus, valMgr),
 		slashTxExec:          NewSlashTxExecutor(consensus, valMgr),
-		sendTxExec:           NewSendTxExecutor(),
+		sendTxExec:           NewSendTxExecutor(state),
 		reserveFundTxExec:    NewReserveFundTxExecutor(state),
 		releaseFundTxExec:    NewReleaseFundTxExecutor(state),
 		servicePaymentTxExec: NewServicePaymentTxExecutor(state),
 		splitRuleTxExec:      NewSplitRuleTxExecutor(state),
-		//smartContractTxExec:  NewSmartContractTxExecutor(state),
-		depositStakeTxExec:  NewDepositStakeExecutor(),
-		withdrawStakeTxExec: NewWithdrawStakeExecutor(state),
-		skipSanityCheck:     false,
+		smartContractTxExec:  NewSmartContractTxExecutor(state),
+		depositStakeTxExec:   NewDepositStakeExecutor(),
+		withdrawStakeTxExec:  NewWithdrawStakeExecutor(state),
+		governanceTxExec:     NewGovernanceTxExecutor(consensus, valMgr),
+		proposalTxExec:       NewGovernanceProposalTxExecutor(),
+		voteTxExec:           NewGovernanceVoteTxExecutor(),
+		tallyTxExec:          NewGovernanceTallyTxExecutor(),
+		bridgeLockTxExec:     NewBridgeLockTxExecutor(),
+		bridgeMintTxExec:     NewBridgeMintTxExecutor(state),
+		bridgeValsTxExec:     NewBridgeValidatorsTxExecutor(state, consensus, valMgr),
+		timeLockTxExec:       NewTimeLockTxExecutor(),
+		timeLockClaimTxExec:  NewTimeLockClaimTxExecutor(),
+		timeLockRevokeTxExec: NewTimeLockRevokeTxExecutor(),
+		delegateTxExec:       NewDelegateTxExecutor(),
+		undelegateTxExec:     NewUndelegateTxExecutor(state),
+		skipSanityCheck:      false,
 	}
+	executor.sponsoredTxExec = NewSponsoredTxExecutor(state, executor)
 
 	return executor
 }
@@ -86,11 +113,35 @@ func (exec *Executor) ScreenTx(tx types.Tx) (common.Hash, result.Result) {
 	return exec.processTx(tx, core.ScreenedView)
 }
 
+// ScreenTxOnView checks the validity of the given transaction against the given view, e.g. an
+// overlay of the screened view.
+func (exec *Executor) ScreenTxOnView(tx types.Tx, view *st.StoreView) (common.Hash, result.Result) {
+	return exec.processTxOnView(tx, view)
+}
+
+// DryRunTxOnView executes the given transaction against the given view, which the caller
+// discards afterwards. The sanity checks, e.g. of the signature, can be skipped to execute
+// unsigned transactions, but not the activation of the transaction type.
+func (exec *Executor) DryRunTxOnView(tx types.Tx, view *st.StoreView, skipSanityCheck bool) (common.Hash, result.Result) {
+	chainID := exec.state.GetChainID()
+	if res := checkTxTypeActive(tx, core.RulesAt(chainID, view.Height()+1)); res.IsError() {
+		return common.Hash{}, res
+	}
+	if !skipSanityCheck {
+		sanityCheckResult := exec.sanityCheck(chainID, view, tx)
+		if sanityCheckResult.IsError() {
+			return common.Hash{}, sanityCheckResult
+		}
+	}
+
+	return exec.process(chainID, view, tx)
+}
+
 // GetTxInfo extracts tx information used by mempool to sort Txs.
 func (exec *Executor) GetTxInfo(tx types.Tx) (*core.TxInfo, result.Result) {
 	txExecutor := exec.getTxExecutor(tx)
 	if txExecutor == nil {
-		return nil, result.Error("Unknown tx type")
+		return nil, result.Error("Unknown tx type").WithErrorCode(result.CodeUnknownTxType)
 	}
 
 	txInfo := txExecutor.getTxInfo(tx)
@@ -99,7 +150,6 @@ func (exec *Executor) GetTxInfo(tx types.Tx) (*core.TxInfo, result.Result) {
 
 // processTx contains the main logic to process the transaction. If the tx is invalid, a TMSP error will be returned.
 func (exec *Executor) processTx(tx types.Tx, viewSel core.ViewSelector) (common.Hash, result.Result) {
-	chainID := exec.state.GetChainID()
 	var view *st.StoreView
 	switch viewSel {
 	case core.DeliveredView:
@@ -110,6 +160,11 @@ func (exec *Executor) processTx(tx types.Tx, viewSel core.ViewSelector) (common.
 		view = exec.state.Screened()
 	}
 
+	return exec.processTxOnView(tx, view)
+}
+
+func (exec *Executor) processTxOnView(tx types.Tx, view *st.StoreView) (common.Hash, result.Result) {
+	chainID := exec.state.GetChainID()
 	sanityCheckResult := exec.sanityCheck(chainID, view, tx)
 	if sanityCheckResult.IsError() {
 		return common.Hash{}, sanityCheckResult
@@ -124,17 +179,57 @@ func (exec *Executor) sanityCheck(chainID string, view *st.StoreView, tx types.T
 		return result.OK
 	}
 
+	// The transactions introduced by an upgrade are rejected until the upgrade is active
+	if res := checkTxTypeActive(tx, core.RulesAt(chainID, view.Height()+1)); res.IsError() {
+		return res
+	}
+
+	// The transactions are signed for the signature domain of the block including them
+	signDomain := core.SignatureDomain(chainID, view.Height()+1)
+
 	var sanityCheckResult result.Result
 	txExecutor := exec.getTxExecutor(tx)
 	if txExecutor != nil {
-		sanityCheckResult = txExecutor.sanityCheck(chainID, view, tx)
+		sanityCheckResult = txExecutor.sanityCheck(signDomain, view, tx)
 	} else {
-		sanityCheckResult = result.Error("Unknown tx type")
+		sanityCheckResult = result.Error("Unknown tx type").WithErrorCode(result.CodeUnknownTxType)
 	}
 
 	return sanityCheckResult
 }
 
+// txTypeUpgrades maps the transaction types introduced by an upgrade to the upgrade.
+var txTypeUpgrades = map[types.TxType]core.Upgrade{
+	types.TxSmartContract:      core.UpgradeSmartContracts,
+	types.TxGovernance:         core.UpgradeChainParams,
+	types.TxDepositStakeV2:     core.UpgradeGuardianStake,
+	types.TxGovernanceProposal: core.UpgradeOnChainGovernance,
+	types.TxGovernanceVote:     core.UpgradeOnChainGovernance,
+	types.TxGovernanceTally:    core.UpgradeOnChainGovernance,
+	types.TxBridgeLock:         core.UpgradeBridge,
+	types.TxBridgeMint:         core.UpgradeBridge,
+	types.TxBridgeValidators:   core.UpgradeBridge,
+	types.TxSponsored:          core.UpgradeSponsoredFees,
+	types.TxTimeLock:           core.UpgradeTimeLock,
+	types.TxTimeLockClaim:      core.UpgradeTimeLock,
+	types.TxTimeLockRevoke:     core.UpgradeTimeLock,
+	types.TxDelegate:           core.UpgradeDelegation,
+	types.TxUndelegate:         core.UpgradeDelegation,
+}
+
+// checkTxTypeActive checks the type of the transaction is enabled by the active upgrades.
+func checkTxTypeActive(tx types.Tx, rules *core.Rules) result.Result {
+	txType, err := types.GetTxType(tx)
+	if err != nil {
+		return result.Error("Unknown tx type").WithErrorCode(result.CodeUnknownTxType)
+	}
+	if upgrade, ok := txTypeUpgrades[txType]; ok && !rules.IsActive(upgrade) {
+		return result.Error("Transaction type %v is not enabled before the %v upgrade", txType, upgrade).
+			WithErrorCode(result.CodeUnknownTxType)
+	}
+	return result.OK
+}
+
 func (exec *Executor) process(chainID string, view *st.StoreView, tx types.Tx) (common.Hash, result.Result) {
 	var processResult result.Result
 	var txHash common.Hash
@@ -142,7 +237,7 @@ func (exec *Executor) process(chainID string, view *st.StoreView, tx types.Tx) (
 	if txExecutor != nil {
 		txHash, processResult = txExecutor.process(chainID, view, tx)
 	} else {
-		processResult = result.Error("Unknown tx type")
+		processResult = result.Error("Unknown tx type").WithErrorCode(result.CodeUnknownTxType)
 	}
 
 	return txHash, processResult
@@ -165,12 +260,38 @@ func (exec *Executor) getTxExecutor(tx types.Tx) TxExecutor {
 		txExecutor = exec.servicePaymentTxExec
 	case *types.SplitRuleTx:
 		txExecutor = exec.splitRuleTxExec
-	// case *types.SmartContractTx:
-	// 	txExecutor = exec.smartContractTxExec
-	case *types.DepositStakeTx:
+	case *types.SmartContractTx:
+		txExecutor = exec.smartContractTxExec
+	case *types.DepositStakeTx, *types.DepositStakeTxV2:
 		txExecutor = exec.depositStakeTxExec
 	case *types.WithdrawStakeTx:
 		txExecutor = exec.withdrawStakeTxExec
+	case *types.GovernanceTx:
+		txExecutor = exec.governanceTxExec
+	case *types.GovernanceProposalTx:
+		txExecutor = exec.proposalTxExec
+	case *types.GovernanceVoteTx:
+		txExecutor = exec.voteTxExec
+	case *types.GovernanceTallyTx:
+		txExecutor = exec.tallyTxExec
+	case *types.BridgeLockTx:
+		txExecutor = exec.bridgeLockTxExec
+	case *types.BridgeMintTx:
+		txExecutor = exec.bridgeMintTxExec
+	case *types.BridgeValidatorsTx:
+		txExecutor = exec.bridgeValsTxExec
+	case *types.SponsoredTx:
+		txExecutor = exec.sponsoredTxExec
+	case *types.TimeLockTx:
+		txExecutor = exec.timeLockTxExec
+	case *types.TimeLockClaimTx:
+		txExecutor = exec.timeLockClaimTxExec
+	case *types.TimeLockRevokeTx:
+		txExecutor = exec.timeLockRevokeTxExec
+	case *types.DelegateTx:
+		txExecutor = exec.delegateTxExec
+	case *types.UndelegateTx:
+		txExecutor = exec.undelegateTxExec
 	default:
 		txExecutor = nil
 	}
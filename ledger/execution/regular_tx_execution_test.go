@@ -6,7 +6,10 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto/bls"
 	"github.com/thetatoken/theta/ledger/types"
 )
 
@@ -1073,3 +1076,803 @@ func TestSplitRuleTxUpdate(t *testing.T) {
 	log.Infof("currHeight = %v", currHeight)
 	log.Infof("endHeight2 = %v", endHeight2)
 }
+
+func TestGovernanceTx(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	proposer := et.accProposer // stake 999
+	val2 := et.accVal2         // stake 100
+	et.acc2State(proposer, val2, et.accIn, et.accOut)
+
+	txFee := getMinimumTxFee()
+	params := types.DefaultChainParams()
+	params.MinimumTransactionFeeTFuelWei = big.NewInt(2 * txFee)
+
+	createGovernanceTx := func(from types.PrivAccount, params *types.ChainParams, approvers ...types.PrivAccount) *types.GovernanceTx {
+		tx := &types.GovernanceTx{
+			Fee: types.NewCoins(0, txFee),
+			Proposer: types.TxInput{
+				Address:  from.Address,
+				Sequence: 1,
+			},
+			Params: *params,
+		}
+		for _, approver := range approvers {
+			tx.Approvals = append(tx.Approvals, types.GovernanceApproval{Address: approver.Address})
+		}
+		signBytes := tx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1))
+		tx.SetSignature(from.Address, from.Sign(signBytes))
+		for _, approver := range approvers {
+			tx.SetSignature(approver.Address, approver.Sign(signBytes))
+		}
+		return tx
+	}
+
+	// Only validators can propose
+	tx := createGovernanceTx(et.accIn, params, proposer)
+	res := et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	assert.Equal(result.CodeUnauthorizedGovernance, res.Code, res.Message)
+
+	// 100 out of 1099 stake is not a supermajority
+	tx = createGovernanceTx(val2, params)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	assert.Equal(result.CodeInsufficientApprovals, res.Code, res.Message)
+
+	// Approvals need to be signed by the approver
+	tx = createGovernanceTx(val2, params, proposer)
+	tx.Approvals[0].Signature = val2.Sign(tx.SignBytes(et.chainID))
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	assert.Equal(result.CodeInvalidSignature, res.Code, res.Message)
+
+	// Params that would halt the chain are rejected
+	invalidParams := types.DefaultChainParams()
+	invalidParams.MaxNumRegularTxsPerBlock = 0
+	tx = createGovernanceTx(val2, invalidParams, proposer)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	assert.Equal(result.CodeInvalidChainParams, res.Code, res.Message)
+
+	// The governance transactions are disabled before the upgrade
+	tx = createGovernanceTx(val2, params, proposer)
+	_, res = et.executor.ExecuteTx(tx)
+	assert.Equal(result.CodeUnknownTxType, res.Code, res.Message)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeChainParams: 0}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+
+	// Approved by a supermajority
+	tx = createGovernanceTx(val2, params, proposer)
+	_, res = et.executor.ExecuteTx(tx)
+	assert.True(res.IsOK(), res.Message)
+
+	view := et.state().Delivered()
+	assert.Equal(0, params.MinimumTransactionFeeTFuelWei.Cmp(view.GetChainParams().MinimumTransactionFeeTFuelWei))
+	val2Account := view.GetAccount(val2.Address)
+	assert.Equal(uint64(1), val2Account.Sequence)
+	assert.Equal(val2.Balance.Minus(types.NewCoins(0, txFee)), val2Account.Balance)
+
+	// The old minimum fee is no longer sufficient
+	sendTx := types.MakeSendTx(1, et.accOut, et.accIn)
+	types.SignSendTx(core.SignatureDomain(et.chainID, et.state().Height()+1), sendTx, et.accIn)
+	_, res = et.executor.ExecuteTx(sendTx)
+	assert.Equal(result.CodeInvalidFee, res.Code, res.Message)
+}
+
+func TestGovernanceProposalTx(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	proposer := et.accProposer // 90% of the stake
+	val2 := et.accVal2         // 10% of the stake
+	et.acc2State(proposer, val2, et.accIn, et.accOut)
+
+	vcp := &core.ValidatorCandidatePool{}
+	vcp.DepositStake(proposer.Address, proposer.Address, new(big.Int).Mul(core.MinValidatorStakeDeposit, big.NewInt(9)))
+	vcp.DepositStake(val2.Address, val2.Address, core.MinValidatorStakeDeposit)
+	et.state().Delivered().UpdateValidatorCandidatePool(vcp)
+	et.state().Commit()
+
+	txFee := getMinimumTxFee()
+	params := types.DefaultChainParams()
+	params.MinimumTransactionFeeTFuelWei = big.NewInt(2 * txFee)
+	activationHeight := et.state().Height() + types.GovernanceVotingPeriod + 100
+
+	sequences := make(map[common.Address]uint64)
+	sign := func(from types.PrivAccount, tx types.Tx, input *types.TxInput) {
+		input.Address = from.Address
+		input.Sequence = sequences[from.Address] + 1
+		signBytes := tx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1))
+		input.Signature = from.Sign(signBytes)
+	}
+	execute := func(from types.PrivAccount, tx types.Tx, input *types.TxInput) result.Result {
+		sign(from, tx, input)
+		_, res := et.executor.ExecuteTx(tx)
+		if res.IsOK() {
+			sequences[from.Address]++
+		}
+		return res
+	}
+	propose := func(from types.PrivAccount, activationHeight uint64) result.Result {
+		tx := &types.GovernanceProposalTx{
+			Fee:              types.NewCoins(0, txFee),
+			Params:           *params,
+			ActivationHeight: activationHeight,
+		}
+		return execute(from, tx, &tx.Proposer)
+	}
+	vote := func(from types.PrivAccount, id uint64, approve bool) result.Result {
+		tx := &types.GovernanceVoteTx{
+			Fee:        types.NewCoins(0, txFee),
+			ProposalID: id,
+			Approve:    approve,
+		}
+		return execute(from, tx, &tx.Voter)
+	}
+	tally := func(from types.PrivAccount, id uint64) result.Result {
+		tx := &types.GovernanceTallyTx{
+			Fee:        types.NewCoins(0, txFee),
+			ProposalID: id,
+		}
+		return execute(from, tx, &tx.Caller)
+	}
+
+	// The governance transactions are disabled before the upgrade
+	res := propose(val2, activationHeight)
+	assert.Equal(result.CodeUnknownTxType, res.Code, res.Message)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeOnChainGovernance: 0}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+
+	// Only stake holders can propose
+	res = propose(et.accIn, activationHeight)
+	assert.Equal(result.CodeUnauthorizedGovernance, res.Code, res.Message)
+
+	// The params cannot be activated before the end of the voting
+	res = propose(val2, et.state().Height()+types.GovernanceVotingPeriod)
+	assert.Equal(result.CodeInvalidProposalHeight, res.Code, res.Message)
+
+	res = propose(val2, activationHeight)
+	assert.True(res.IsOK(), res.Message)
+	proposal := et.state().Delivered().GetGovernanceProposal(1)
+	assert.NotNil(proposal)
+	assert.Equal(types.GovernanceProposalVoting, proposal.Status)
+	assert.Equal(val2.Address, proposal.Proposer)
+	assert.Equal(uint64(2), et.state().Delivered().GetNextGovernanceProposalID())
+
+	res = vote(val2, 2, true)
+	assert.Equal(result.CodeProposalNotFound, res.Code, res.Message)
+	res = vote(et.accIn, 1, true)
+	assert.Equal(result.CodeUnauthorizedGovernance, res.Code, res.Message)
+
+	// 10% of the stake approving is undecided until the end of the voting
+	res = vote(val2, 1, true)
+	assert.True(res.IsOK(), res.Message)
+	res = tally(et.accIn, 1)
+	assert.Equal(result.CodeInvalidProposalHeight, res.Code, res.Message)
+
+	// The later vote of a voter replaces the earlier one
+	res = vote(proposer, 1, false)
+	assert.True(res.IsOK(), res.Message)
+	res = vote(proposer, 1, true)
+	assert.True(res.IsOK(), res.Message)
+	assert.Equal(2, len(et.state().Delivered().GetGovernanceProposal(1).Votes))
+
+	// Settled as soon as a supermajority approved
+	res = tally(et.accIn, 1)
+	assert.True(res.IsOK(), res.Message)
+	view := et.state().Delivered()
+	assert.Equal(types.GovernanceProposalPassed, view.GetGovernanceProposal(1).Status)
+	assert.Equal([]uint64{1}, view.GetPassedGovernanceProposals().IDs)
+
+	// The params are only updated at the activation height
+	assert.Equal(0, types.DefaultChainParams().MinimumTransactionFeeTFuelWei.Cmp(view.GetChainParams().MinimumTransactionFeeTFuelWei))
+
+	res = vote(val2, 1, false)
+	assert.Equal(result.CodeInvalidProposalStatus, res.Code, res.Message)
+	res = tally(et.accIn, 1)
+	assert.Equal(result.CodeInvalidProposalStatus, res.Code, res.Message)
+}
+
+func TestBridgeTxs(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	proposer := et.accProposer // stake 999
+	val2 := et.accVal2         // stake 100
+	et.acc2State(proposer, val2, et.accIn, et.accOut)
+
+	txFee := getMinimumTxFee()
+	counterpartChainID := "counterpart_chain"
+	counterpartVals := []types.PrivAccount{types.MakeAcc("cp1"), types.MakeAcc("cp2"), types.MakeAcc("cp3")}
+	bridgeValidators := types.BridgeValidators{ChainID: counterpartChainID}
+	for _, val := range counterpartVals {
+		bridgeValidators.Validators = append(bridgeValidators.Validators,
+			core.NewValidator(val.Address.Hex(), big.NewInt(100)))
+	}
+
+	sequences := make(map[common.Address]uint64)
+	execute := func(from types.PrivAccount, tx types.Tx, input *types.TxInput) result.Result {
+		input.Address = from.Address
+		input.Sequence = sequences[from.Address] + 1
+		input.Signature = from.Sign(tx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1)))
+		_, res := et.executor.ExecuteTx(tx)
+		if res.IsOK() {
+			sequences[from.Address]++
+		}
+		return res
+	}
+	lock := func(from types.PrivAccount, destChainID string, coins types.Coins) result.Result {
+		tx := &types.BridgeLockTx{
+			Fee:         types.NewCoins(0, txFee),
+			DestChainID: destChainID,
+			Recipient:   et.accOut.Address,
+		}
+		tx.Source.Coins = coins
+		return execute(from, tx, &tx.Source)
+	}
+
+	// The bridge transactions are disabled before the upgrade
+	res := lock(et.accIn, counterpartChainID, types.NewCoins(0, 5000))
+	assert.Equal(result.CodeUnknownTxType, res.Code, res.Message)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeBridge: 0}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+
+	// The coins can only be sent to a registered counterpart chain
+	res = lock(et.accIn, counterpartChainID, types.NewCoins(0, 5000))
+	assert.Equal(result.CodeUnknownBridgeChain, res.Code, res.Message)
+
+	// The counterpart chain is registered with the approval of a validator supermajority
+	registerTx := &types.BridgeValidatorsTx{
+		Fee:        types.NewCoins(0, txFee),
+		Validators: bridgeValidators,
+	}
+	res = execute(val2, registerTx, &registerTx.Proposer)
+	assert.Equal(result.CodeInsufficientApprovals, res.Code, res.Message)
+	registerTx.Approvals = []types.GovernanceApproval{{Address: proposer.Address}}
+	registerTx.Proposer.Address = val2.Address
+	registerTx.Proposer.Sequence = sequences[val2.Address] + 1
+	signBytes := registerTx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1))
+	registerTx.SetSignature(proposer.Address, proposer.Sign(signBytes))
+	registerTx.SetSignature(val2.Address, val2.Sign(signBytes))
+	_, res = et.executor.ExecuteTx(registerTx)
+	assert.True(res.IsOK(), res.Message)
+	sequences[val2.Address]++
+	assert.NotNil(et.state().Delivered().GetBridgeValidators(counterpartChainID))
+
+	// Lock the coins into the escrow, and queue the outbound message
+	res = lock(et.accIn, counterpartChainID, types.NewCoins(0, 5000))
+	assert.True(res.IsOK(), res.Message)
+	view := et.state().Delivered()
+	height := view.Height() + 1
+	messages := view.GetBridgeOutboundMessages(height)
+	assert.Equal(1, len(messages.Messages))
+	assert.Equal(uint64(1), messages.Messages[0].Nonce)
+	assert.Equal(et.chainID, messages.Messages[0].SourceChainID)
+	assert.Equal(et.accOut.Address, messages.Messages[0].Recipient)
+	assert.Equal(uint64(2), view.GetNextBridgeNonce())
+	assert.True(types.NewCoins(0, 5000).IsEqual(view.GetAccount(types.BridgeEscrowAddress).Balance))
+	assert.True(et.accIn.Balance.Minus(types.NewCoins(0, 5000+txFee)).IsEqual(view.GetAccount(et.accIn.Address).Balance))
+
+	// An inbound message in a batch co-signed by the counterpart validators
+	inbound := []types.BridgeMessage{
+		{SourceChainID: counterpartChainID, DestChainID: et.chainID, Nonce: 7, Height: 20,
+			Recipient: et.accOut.Address, Coins: types.NewCoins(0, 3000)},
+		{SourceChainID: counterpartChainID, DestChainID: "otherchain", Nonce: 8, Height: 20,
+			Recipient: et.accOut.Address, Coins: types.NewCoins(0, 3000)},
+	}
+	batch := types.NewBridgeBatch(counterpartChainID, 20, &types.BridgeMessageList{Messages: inbound})
+	proof, err := core.BridgeMerkleProof([]common.Hash{inbound[0].Hash(), inbound[1].Hash()}, 0)
+	assert.Nil(err)
+	cosign := func(signers ...types.PrivAccount) []core.BridgeSignature {
+		sigs := []core.BridgeSignature{}
+		for _, signer := range signers {
+			sigs = append(sigs, core.BridgeSignature{Address: signer.Address, Signature: signer.Sign(batch.SignBytes())})
+		}
+		return sigs
+	}
+	mint := func(index uint64, proof []common.Hash, sigs []core.BridgeSignature) result.Result {
+		tx := &types.BridgeMintTx{
+			Fee:        types.NewCoins(0, txFee),
+			Message:    inbound[index],
+			Batch:      *batch,
+			Index:      index,
+			Proof:      proof,
+			Signatures: sigs,
+		}
+		return execute(val2, tx, &tx.Relayer)
+	}
+
+	// 2/3 of the counterpart stake is not a supermajority
+	res = mint(0, proof, cosign(counterpartVals[0], counterpartVals[1]))
+	assert.Equal(result.CodeInvalidBridgeProof, res.Code, res.Message)
+
+	// The proof needs to match the message
+	res = mint(1, proof, cosign(counterpartVals...))
+	assert.Equal(result.CodeInvalidBridgeMessage, res.Code, res.Message)
+	inbound[1].DestChainID = et.chainID
+	res = mint(1, proof, cosign(counterpartVals...))
+	assert.Equal(result.CodeInvalidBridgeProof, res.Code, res.Message)
+
+	// The coins are released from the escrow to the recipient
+	res = mint(0, proof, cosign(counterpartVals...))
+	assert.True(res.IsOK(), res.Message)
+	view = et.state().Delivered()
+	assert.True(types.NewCoins(0, 2000).IsEqual(view.GetAccount(types.BridgeEscrowAddress).Balance))
+	assert.True(et.accOut.Balance.Plus(types.NewCoins(0, 3000)).IsEqual(view.GetAccount(et.accOut.Address).Balance))
+	assert.True(view.IsBridgeMessageProcessed(counterpartChainID, 7))
+
+	// A message can only be processed once
+	res = mint(0, proof, cosign(counterpartVals...))
+	assert.Equal(result.CodeBridgeMessageProcessed, res.Code, res.Message)
+}
+
+func TestSponsoredTx(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	txFee := getMinimumTxFee()
+	user := types.MakeAccWithInitBalance("user", types.NewCoins(0, 5000))
+	sponsor := et.accOut
+	et.acc2State(user, sponsor, et.accIn)
+
+	sendTx := &types.SendTx{
+		Fee: types.NewCoins(0, txFee),
+		Inputs: []types.TxInput{{
+			Address:  user.Address,
+			Coins:    types.NewCoins(0, 5000+txFee),
+			Sequence: 1,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: et.accIn.Address,
+			Coins:   types.NewCoins(0, 5000),
+		}},
+	}
+	sponsored := func(signer types.PrivAccount, inner types.Tx) *types.SponsoredTx {
+		raw, err := types.TxToBytes(inner)
+		assert.Nil(err)
+		tx := &types.SponsoredTx{Sponsor: sponsor.Address, Tx: raw}
+		tx.Signature = signer.Sign(tx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1)))
+		return tx
+	}
+
+	// The sponsored transactions are disabled before the upgrade
+	_, res := et.executor.ScreenTx(sponsored(sponsor, sendTx))
+	assert.Equal(result.CodeUnknownTxType, res.Code, res.Message)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{
+		core.UpgradeSponsoredFees:  0,
+		core.UpgradeSmartContracts: 0,
+	}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+
+	// The user cannot pay the fee of the transfer on its own
+	sendTx.Inputs[0].Signature = user.Sign(sendTx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1)))
+	_, res = et.executor.ScreenTx(sendTx)
+	assert.Equal(result.CodeInsufficientFund, res.Code, res.Message)
+
+	// The sponsor needs to sign the wrapper
+	_, res = et.executor.ScreenTx(sponsored(user, sendTx))
+	assert.Equal(result.CodeInvalidSignature, res.Code, res.Message)
+
+	// The transactions without a fee cannot be sponsored
+	_, res = et.executor.ScreenTx(sponsored(sponsor, &types.CoinbaseTx{Proposer: types.TxInput{Address: et.accProposer.Address}}))
+	assert.Equal(result.CodeInvalidSponsoredTx, res.Code, res.Message)
+
+	// The sponsor pays the fee of the user
+	tx := sponsored(sponsor, sendTx)
+	_, res = et.executor.ExecuteTx(tx)
+	assert.True(res.IsOK(), res.Message)
+	view := et.state().Delivered()
+	assert.True(types.NewCoins(0, 0).IsEqual(view.GetAccount(user.Address).Balance))
+	assert.Equal(uint64(1), view.GetAccount(user.Address).Sequence)
+	assert.True(sponsor.Balance.Minus(types.NewCoins(0, txFee)).IsEqual(view.GetAccount(sponsor.Address).Balance))
+	assert.True(et.accIn.Balance.Plus(types.NewCoins(0, 5000)).IsEqual(view.GetAccount(et.accIn.Address).Balance))
+
+	// The sponsorship cannot be replayed
+	_, res = et.executor.ExecuteTx(tx)
+	assert.Equal(result.CodeInvalidSequence, res.Code, res.Message)
+
+	// The sponsor only pays for the gas a smart contract call used
+	gasPrice := new(big.Int).SetUint64(types.MinimumGasPrice)
+	callTx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address:  user.Address,
+			Coins:    types.NewCoins(0, 0),
+			Sequence: 2,
+		},
+		To:       types.TxOutput{Address: et.accIn.Address},
+		GasLimit: 100000,
+		GasPrice: gasPrice,
+	}
+	callTx.From.Signature = user.Sign(callTx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1)))
+	sponsorBalance := et.state().Delivered().GetAccount(sponsor.Address).Balance
+	_, res = et.executor.ExecuteTx(sponsored(sponsor, callTx))
+	assert.True(res.IsOK(), res.Message)
+	receipt := res.Info["receipt"].(*types.Receipt)
+	assert.True(receipt.GasUsed > 0 && receipt.GasUsed < callTx.GasLimit)
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+	view = et.state().Delivered()
+	assert.True(types.NewCoins(0, 0).IsEqual(view.GetAccount(user.Address).Balance))
+	assert.True(sponsorBalance.Minus(types.Coins{ThetaWei: big.NewInt(0), TFuelWei: fee}).
+		IsEqual(view.GetAccount(sponsor.Address).Balance))
+}
+
+func TestBatchSendFee(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+	et.acc2State(et.accIn)
+
+	txFee := getMinimumTxFee()
+	recipients := []types.PrivAccount{types.MakeAcc("r1"), types.MakeAcc("r2"), types.MakeAcc("r3")}
+	send := func(sequence uint64, fee int64) result.Result {
+		tx := &types.SendTx{Fee: types.NewCoins(0, fee)}
+		for _, recipient := range recipients {
+			tx.Outputs = append(tx.Outputs, types.TxOutput{Address: recipient.Address, Coins: types.NewCoins(0, 100)})
+		}
+		tx.Inputs = []types.TxInput{{
+			Address:  et.accIn.Address,
+			Coins:    types.NewCoins(0, 300+fee),
+			Sequence: sequence,
+		}}
+		tx.Inputs[0].Signature = et.accIn.Sign(tx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1)))
+		_, res := et.executor.ExecuteTx(tx)
+		return res
+	}
+
+	// The min fee covers all the outputs before the upgrade
+	res := send(1, txFee)
+	assert.True(res.IsOK(), res.Message)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeBatchSendFee: 0}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+
+	// Each output beyond the first adds a fraction of the min fee
+	res = send(2, txFee)
+	assert.Equal(result.CodeInvalidFee, res.Code, res.Message)
+	res = send(2, txFee+2*txFee/types.BatchSendOutputFeeDivisor)
+	assert.True(res.IsOK(), res.Message)
+	for _, recipient := range recipients {
+		assert.True(types.NewCoins(0, 200).IsEqual(et.state().Delivered().GetAccount(recipient.Address).Balance))
+	}
+}
+
+func TestTimeLockTxs(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	source := et.accIn
+	recipient := et.accOut
+	revoker := types.MakeAcc("revoker")
+	et.acc2State(source, recipient, revoker)
+
+	txFee := getMinimumTxFee()
+	sequences := make(map[common.Address]uint64)
+	execute := func(from types.PrivAccount, tx types.Tx, input *types.TxInput) result.Result {
+		input.Address = from.Address
+		input.Sequence = sequences[from.Address] + 1
+		input.Signature = from.Sign(tx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1)))
+		_, res := et.executor.ExecuteTx(tx)
+		if res.IsOK() {
+			sequences[from.Address]++
+		}
+		return res
+	}
+	lock := func(revoker common.Address, releaseHeight, releaseTime uint64) result.Result {
+		tx := &types.TimeLockTx{
+			Fee:           types.NewCoins(0, txFee),
+			Recipient:     recipient.Address,
+			Revoker:       revoker,
+			ReleaseHeight: releaseHeight,
+			ReleaseTime:   releaseTime,
+		}
+		tx.Source.Coins = types.NewCoins(0, 5000)
+		return execute(source, tx, &tx.Source)
+	}
+	claim := func(from types.PrivAccount, lockID uint64) result.Result {
+		tx := &types.TimeLockClaimTx{Fee: types.NewCoins(0, txFee), LockID: lockID}
+		return execute(from, tx, &tx.Recipient)
+	}
+	revoke := func(from types.PrivAccount, lockID uint64) result.Result {
+		tx := &types.TimeLockRevokeTx{Fee: types.NewCoins(0, txFee), LockID: lockID}
+		return execute(from, tx, &tx.Revoker)
+	}
+	balance := func(addr common.Address) types.Coins {
+		return et.state().Delivered().GetAccount(addr).Balance
+	}
+
+	// The time lock transactions are disabled before the upgrade
+	releaseHeight := et.state().Height() + 10
+	res := lock(common.Address{}, releaseHeight, 0)
+	assert.Equal(result.CodeUnknownTxType, res.Code, res.Message)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeTimeLock: 0}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+
+	// A time lock needs a release height or time in the future
+	res = lock(common.Address{}, 0, 0)
+	assert.Equal(result.CodeInvalidTimeLock, res.Code, res.Message)
+	res = lock(common.Address{}, et.state().Height()+1, 0)
+	assert.Equal(result.CodeInvalidTimeLock, res.Code, res.Message)
+
+	// The coins are held by the escrow until the release height
+	res = lock(common.Address{}, releaseHeight, 0)
+	assert.True(res.IsOK(), res.Message)
+	assert.Equal(uint64(1), res.Info["lock_id"])
+	assert.True(source.Balance.Minus(types.NewCoins(0, 5000+txFee)).IsEqual(balance(source.Address)))
+	assert.True(types.NewCoins(0, 5000).IsEqual(balance(types.TimeLockEscrowAddress)))
+
+	res = claim(recipient, 1)
+	assert.Equal(result.CodeTimeLockNotReleased, res.Code, res.Message)
+	res = revoke(source, 1)
+	assert.Equal(result.CodeUnauthorizedTimeLock, res.Code, res.Message)
+
+	et.fastforwardTo(releaseHeight - 1)
+	res = claim(revoker, 1)
+	assert.Equal(result.CodeUnauthorizedTimeLock, res.Code, res.Message)
+	res = claim(recipient, 1)
+	assert.True(res.IsOK(), res.Message)
+	assert.True(recipient.Balance.Plus(types.NewCoins(0, 5000-txFee)).IsEqual(balance(recipient.Address)))
+	assert.True(types.NewCoins(0, 0).IsEqual(balance(types.TimeLockEscrowAddress)))
+	assert.Nil(et.state().Delivered().GetTimeLock(1))
+
+	// The coins can only be claimed once
+	res = claim(recipient, 1)
+	assert.Equal(result.CodeTimeLockNotFound, res.Code, res.Message)
+
+	// The revoker returns the coins of a revocable time lock to the source before the release
+	et.state().Delivered().SetChainTime(1000)
+	res = lock(revoker.Address, 0, 2000)
+	assert.True(res.IsOK(), res.Message)
+	sourceBalance := balance(source.Address)
+	res = revoke(revoker, 2)
+	assert.True(res.IsOK(), res.Message)
+	assert.True(sourceBalance.Plus(types.NewCoins(0, 5000)).IsEqual(balance(source.Address)))
+	assert.Nil(et.state().Delivered().GetTimeLock(2))
+
+	// Once the chain time reaches the release time, the coins belong to the recipient
+	res = lock(revoker.Address, 0, 2000)
+	assert.True(res.IsOK(), res.Message)
+	res = claim(recipient, 3)
+	assert.Equal(result.CodeTimeLockNotReleased, res.Code, res.Message)
+	et.state().Delivered().SetChainTime(2000)
+	res = revoke(revoker, 3)
+	assert.Equal(result.CodeInvalidTimeLock, res.Code, res.Message)
+	res = claim(recipient, 3)
+	assert.True(res.IsOK(), res.Message)
+}
+
+func TestDelegationTxs(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	txFee := getMinimumTxFee()
+	delegation := new(big.Int).Mul(core.MinDelegation, big.NewInt(2))
+	delegator := types.MakeAccWithInitBalance("delegator", types.Coins{
+		ThetaWei: new(big.Int).Mul(delegation, big.NewInt(2)),
+		TFuelWei: big.NewInt(50 * txFee),
+	})
+	validator := types.MakeAcc("validator")
+	et.acc2State(delegator, validator)
+
+	sequence := uint64(0)
+	execute := func(tx types.Tx, input *types.TxInput) result.Result {
+		input.Address = delegator.Address
+		input.Sequence = sequence + 1
+		input.Signature = delegator.Sign(tx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1)))
+		_, res := et.executor.ExecuteTx(tx)
+		if res.IsOK() {
+			sequence++
+		}
+		return res
+	}
+	delegate := func(validator common.Address, amount *big.Int) result.Result {
+		tx := &types.DelegateTx{Fee: types.NewCoins(0, txFee), Validator: validator}
+		tx.Delegator.Coins = types.Coins{ThetaWei: amount, TFuelWei: big.NewInt(0)}
+		return execute(tx, &tx.Delegator)
+	}
+	undelegate := func(validator common.Address) result.Result {
+		tx := &types.UndelegateTx{Fee: types.NewCoins(0, txFee), Validator: validator}
+		return execute(tx, &tx.Delegator)
+	}
+
+	// The delegation transactions are disabled before the upgrade
+	res := delegate(validator.Address, delegation)
+	assert.Equal(result.CodeUnknownTxType, res.Code, res.Message)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeDelegation: 0}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+
+	// The stake can only be delegated to a validator candidate
+	res = delegate(validator.Address, delegation)
+	assert.Equal(result.CodeInvalidDelegation, res.Code, res.Message)
+
+	vcp := &core.ValidatorCandidatePool{}
+	assert.Nil(vcp.DepositStake(validator.Address, validator.Address, core.MinValidatorStakeDeposit))
+	et.state().Delivered().UpdateValidatorCandidatePool(vcp)
+
+	res = delegate(validator.Address, new(big.Int).Sub(core.MinDelegation, big.NewInt(1)))
+	assert.Equal(result.CodeInsufficientStake, res.Code, res.Message)
+
+	// The delegated stake counts for the validator
+	res = delegate(validator.Address, delegation)
+	assert.True(res.IsOK(), res.Message)
+	candidate := et.state().Delivered().GetValidatorCandidatePool().SortedCandidates[0]
+	assert.Equal(new(big.Int).Add(core.MinValidatorStakeDeposit, delegation), candidate.TotalStake())
+	assert.Equal(delegator.Address, candidate.Delegations[0].Source)
+	balance := et.state().Delivered().GetAccount(delegator.Address).Balance
+	assert.Equal(delegation, balance.ThetaWei)
+
+	// The stake is returned to the delegator after the locking period
+	res = undelegate(validator.Address)
+	assert.True(res.IsOK(), res.Message)
+	candidate = et.state().Delivered().GetValidatorCandidatePool().SortedCandidates[0]
+	assert.True(candidate.Delegations[0].Withdrawn)
+	assert.Equal(core.MinValidatorStakeDeposit, candidate.TotalStake())
+	res = undelegate(validator.Address)
+	assert.Equal(result.CodeInvalidDelegation, res.Code, res.Message)
+}
+
+func TestUnbondingQueue(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	txFee := getMinimumTxFee()
+	delegator := types.MakeAccWithInitBalance("delegator", types.Coins{
+		ThetaWei: new(big.Int).Mul(core.MinDelegation, big.NewInt(2)),
+		TFuelWei: big.NewInt(50 * txFee),
+	})
+	validator := types.MakeAccWithInitBalance("validator", types.NewCoins(0, 50*txFee))
+	et.acc2State(delegator, validator)
+
+	view := et.state().Delivered()
+	vcp := &core.ValidatorCandidatePool{}
+	assert.Nil(vcp.DepositStake(validator.Address, validator.Address, core.MinValidatorStakeDeposit))
+	assert.Nil(vcp.Delegate(delegator.Address, validator.Address, core.MinDelegation))
+	view.UpdateValidatorCandidatePool(vcp)
+	params := types.DefaultChainParams()
+	params.UnbondingPeriod = 100
+	view.SetChainParams(params)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{
+		core.UpgradeDelegation:     0,
+		core.UpgradeUnbondingQueue: 0,
+	}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+	domain := core.SignatureDomain(et.chainID, et.state().Height()+1)
+
+	// The undelegated stake leaves the validator for the unbonding queue
+	undelegateTx := &types.UndelegateTx{
+		Fee:       types.NewCoins(0, txFee),
+		Delegator: types.TxInput{Address: delegator.Address, Sequence: 1},
+		Validator: validator.Address,
+	}
+	undelegateTx.Delegator.Signature = delegator.Sign(undelegateTx.SignBytes(domain))
+	_, res := et.executor.ExecuteTx(undelegateTx)
+	assert.True(res.IsOK(), res.Message)
+	candidate := view.GetValidatorCandidatePool().SortedCandidates[0]
+	assert.Equal(0, len(candidate.Delegations))
+	assert.Equal(core.MinValidatorStakeDeposit, candidate.TotalStake())
+
+	// The withdrawn stake too, and the candidate without stake is removed
+	withdrawTx := &types.WithdrawStakeTx{
+		Fee:     types.NewCoins(0, txFee),
+		Source:  types.TxInput{Address: validator.Address, Sequence: 1},
+		Holder:  types.TxOutput{Address: validator.Address},
+		Purpose: core.StakeForValidator,
+	}
+	withdrawTx.SetSignature(validator.Address, validator.Sign(withdrawTx.SignBytes(domain)))
+	_, res = et.executor.ExecuteTx(withdrawTx)
+	assert.True(res.IsOK(), res.Message)
+	assert.Equal(0, len(view.GetValidatorCandidatePool().SortedCandidates))
+
+	queue := view.GetUnbondingQueue()
+	assert.Equal(2, queue.Len())
+	entry := queue.Entries[0]
+	assert.Equal(delegator.Address, entry.Source)
+	assert.Equal(validator.Address, entry.Holder)
+	assert.True(entry.Delegated)
+	assert.Equal(core.MinDelegation, entry.Amount)
+	assert.Equal(view.Height()+100, entry.ReturnHeight)
+	entry = queue.Entries[1]
+	assert.Equal(validator.Address, entry.Source)
+	assert.False(entry.Delegated)
+	assert.Equal(core.MinValidatorStakeDeposit, entry.Amount)
+
+	// A stake can only be withdrawn once
+	withdrawTx.Source.Sequence = 2
+	withdrawTx.SetSignature(validator.Address, validator.Sign(withdrawTx.SignBytes(domain)))
+	_, res = et.executor.ExecuteTx(withdrawTx)
+	assert.True(res.IsError())
+}
+
+func TestDepositStakeForGuardian(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	txFee := getMinimumTxFee()
+	stake := core.MinGuardianStakeDeposit
+	source := types.MakeAccWithInitBalance("source", types.Coins{
+		ThetaWei: new(big.Int).Mul(stake, big.NewInt(2)),
+		TFuelWei: big.NewInt(50 * txFee),
+	})
+	holder := types.MakeAcc("guardian")
+	et.acc2State(source, holder)
+
+	blsKey := bls.GenKeyFromSeed(holder.PrivKey.ToBytes())
+	createDepositTx := func(seq int, blsPop common.Bytes, holderSig bool) *types.DepositStakeTxV2 {
+		tx := &types.DepositStakeTxV2{
+			Fee: types.NewCoins(0, txFee),
+			Source: types.TxInput{
+				Address:  source.Address,
+				Coins:    types.Coins{ThetaWei: stake, TFuelWei: big.NewInt(0)},
+				Sequence: uint64(seq),
+			},
+			Holder:    types.TxOutput{Address: holder.Address},
+			Purpose:   core.StakeForGuardian,
+			BlsPubkey: blsKey.PublicKey().ToBytes(),
+			BlsPop:    blsPop,
+		}
+		if holderSig {
+			tx.HolderSig = holder.Sign(tx.HolderSignBytes())
+		}
+		tx.SetSignature(source.Address, source.Sign(tx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1))))
+		return tx
+	}
+
+	// The BLS key needs a valid proof of possession
+	tx := createDepositTx(1, blsKey.Sign(blsKey.PublicKey().ToBytes()).ToBytes(), true)
+	res := et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	assert.Equal(result.CodeInvalidBlsKey, res.Code, res.Message)
+
+	// The holder needs to approve the BLS key
+	tx = createDepositTx(1, blsKey.PopProve().ToBytes(), false)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	assert.Equal(result.CodeInvalidSignature, res.Code, res.Message)
+
+	// A DepositStakeTx carries no BLS key, so it cannot register a guardian
+	v1Tx := &types.DepositStakeTx{
+		Fee:     tx.Fee,
+		Source:  tx.Source,
+		Holder:  tx.Holder,
+		Purpose: core.StakeForGuardian,
+	}
+	v1Tx.SetSignature(source.Address, source.Sign(v1Tx.SignBytes(et.chainID)))
+	res = et.executor.getTxExecutor(v1Tx).sanityCheck(et.chainID, et.state().Delivered(), v1Tx)
+	assert.Equal(result.CodeInvalidBlsKey, res.Code, res.Message)
+
+	// The DepositStakeV2 transactions are disabled before the upgrade
+	tx = createDepositTx(1, blsKey.PopProve().ToBytes(), true)
+	_, res = et.executor.ExecuteTx(tx)
+	assert.Equal(result.CodeUnknownTxType, res.Code, res.Message)
+
+	core.SetForkSchedule(et.chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeGuardianStake: 0}))
+	defer core.SetForkSchedule(et.chainID, core.NewForkSchedule(nil))
+
+	tx = createDepositTx(1, blsKey.PopProve().ToBytes(), true)
+	_, res = et.executor.ExecuteTx(tx)
+	assert.True(res.IsOK(), res.Message)
+
+	gcp := et.state().Delivered().GetGuardianCandidatePool()
+	assert.Equal(1, gcp.Len())
+	assert.Equal(holder.Address, gcp.SortedGuardians[0].Holder)
+	assert.True(gcp.SortedGuardians[0].Pubkey.Equals(blsKey.PublicKey()))
+	assert.Equal(0, stake.Cmp(gcp.TotalStake()))
+
+	withdrawTx := &types.WithdrawStakeTx{
+		Fee: types.NewCoins(0, txFee),
+		Source: types.TxInput{
+			Address:  source.Address,
+			Sequence: 2,
+		},
+		Holder:  types.TxOutput{Address: holder.Address},
+		Purpose: core.StakeForGuardian,
+	}
+	withdrawTx.SetSignature(source.Address, source.Sign(withdrawTx.SignBytes(core.SignatureDomain(et.chainID, et.state().Height()+1))))
+	_, res = et.executor.ExecuteTx(withdrawTx)
+	assert.True(res.IsOK(), res.Message)
+
+	gcp = et.state().Delivered().GetGuardianCandidatePool()
+	assert.True(gcp.SortedGuardians[0].Stakes[0].Withdrawn)
+	assert.Equal(0, gcp.TotalStake().Sign())
+}
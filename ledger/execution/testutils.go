@@ -17,6 +17,7 @@ import (
 	st "github.com/thetatoken/theta/ledger/state"
 
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/signer"
 	"github.com/thetatoken/theta/store/database/backend"
 )
 
@@ -24,10 +25,11 @@ import (
 
 type TestConsensusEngine struct {
 	privKey *crypto.PrivateKey
+	signer  core.Signer
 }
 
 func (tce *TestConsensusEngine) ID() string                        { return tce.privKey.PublicKey().Address().Hex() }
-func (tce *TestConsensusEngine) PrivateKey() *crypto.PrivateKey    { return tce.privKey }
+func (tce *TestConsensusEngine) Signer() core.Signer               { return tce.signer }
 func (tce *TestConsensusEngine) GetTip(bool) *core.ExtendedBlock   { return nil }
 func (tce *TestConsensusEngine) GetEpoch() uint64                  { return 100 }
 func (tce *TestConsensusEngine) AddMessage(msg interface{})        {}
@@ -39,7 +41,7 @@ func (tce *TestConsensusEngine) GetLastFinalizedBlock() *core.ExtendedBlock {
 
 func NewTestConsensusEngine(seed string) *TestConsensusEngine {
 	privKey, _, _ := crypto.TEST_GenerateKeyPairWithSeed(seed)
-	return &TestConsensusEngine{privKey}
+	return &TestConsensusEngine{privKey, signer.NewLocalSigner(privKey)}
 }
 
 type TestValidatorManager struct {
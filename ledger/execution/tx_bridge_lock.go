@@ -0,0 +1,136 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*BridgeLockTxExecutor)(nil)
+
+// ------------------------------- Bridge Lock Transaction -----------------------------------
+
+// BridgeLockTxExecutor implements the TxExecutor interface
+type BridgeLockTxExecutor struct {
+}
+
+// NewBridgeLockTxExecutor creates a new instance of BridgeLockTxExecutor
+func NewBridgeLockTxExecutor() *BridgeLockTxExecutor {
+	return &BridgeLockTxExecutor{}
+}
+
+func (exec *BridgeLockTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.BridgeLockTx)
+
+	res := tx.Source.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	sourceAccount, success := getInput(view, tx.Source)
+	if success.IsError() {
+		return result.Error("Failed to get the source account: %v", tx.Source.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(sourceAccount, signBytes, tx.Source)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Source.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if view.GetBridgeValidators(tx.DestChainID) == nil {
+		return result.Error("Chain %v is not a counterpart of the bridge", tx.DestChainID).
+			WithErrorCode(result.CodeUnknownBridgeChain)
+	}
+
+	if tx.Recipient.IsEmpty() {
+		return result.Error("Recipient is not specified").WithErrorCode(result.CodeInvalidBridgeMessage)
+	}
+
+	coins := tx.Source.Coins.NoNil()
+	if !coins.IsValid() || !coins.IsPositive() {
+		return result.Error("Invalid coins to lock: %v", coins).WithErrorCode(result.CodeInvalidBridgeMessage)
+	}
+
+	minimalBalance := coins.Plus(tx.Fee)
+	if !sourceAccount.Balance.IsGTE(minimalBalance) {
+		return result.Error("Source balance is %v, but required minimal balance is %v",
+			sourceAccount.Balance, minimalBalance).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	return result.OK
+}
+
+func (exec *BridgeLockTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.BridgeLockTx)
+
+	sourceAccount, success := getInput(view, tx.Source)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the source account")
+	}
+
+	if !chargeFee(view, sourceAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	coins := tx.Source.Coins.NoNil()
+	if !sourceAccount.Balance.IsGTE(coins) {
+		return common.Hash{}, result.Error("Not enough balance to lock")
+	}
+	sourceAccount.Balance = sourceAccount.Balance.Minus(coins)
+	sourceAccount.Sequence++
+	view.SetAccount(tx.Source.Address, sourceAccount)
+
+	escrowAccount := getOrMakeAccount(view, types.BridgeEscrowAddress)
+	escrowAccount.Balance = escrowAccount.Balance.Plus(coins)
+	view.SetAccount(types.BridgeEscrowAddress, escrowAccount)
+
+	// Queue the outbound message in the batch of the block
+	height := view.Height() + 1
+	nonce := view.GetNextBridgeNonce()
+	messages := view.GetBridgeOutboundMessages(height)
+	messages.Messages = append(messages.Messages, types.BridgeMessage{
+		SourceChainID: chainID,
+		DestChainID:   tx.DestChainID,
+		Nonce:         nonce,
+		Height:        height,
+		Sender:        tx.Source.Address,
+		Recipient:     tx.Recipient,
+		Coins:         coins,
+	})
+	view.SetBridgeOutboundMessages(height, messages)
+	view.SetNextBridgeNonce(nonce + 1)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *BridgeLockTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.BridgeLockTx)
+	return &core.TxInfo{
+		Address:           tx.Source.Address,
+		Sequence:          tx.Source.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasBridgeLockTx,
+	}
+}
+
+func (exec *BridgeLockTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.BridgeLockTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasBridgeLockTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
@@ -0,0 +1,155 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*BridgeMintTxExecutor)(nil)
+
+// ------------------------------- Bridge Mint Transaction -----------------------------------
+
+// BridgeMintTxExecutor implements the TxExecutor interface
+type BridgeMintTxExecutor struct {
+	state *st.LedgerState
+}
+
+// NewBridgeMintTxExecutor creates a new instance of BridgeMintTxExecutor
+func NewBridgeMintTxExecutor(state *st.LedgerState) *BridgeMintTxExecutor {
+	return &BridgeMintTxExecutor{
+		state: state,
+	}
+}
+
+func (exec *BridgeMintTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.BridgeMintTx)
+
+	res := tx.Relayer.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	relayerAccount, success := getInput(view, tx.Relayer)
+	if success.IsError() {
+		return result.Error("Failed to get the relayer account: %v", tx.Relayer.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(relayerAccount, signBytes, tx.Relayer)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Relayer.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !relayerAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Relayer balance is %v, but required minimal balance is %v",
+			relayerAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	msg := tx.Message
+	batch := tx.Batch
+	bridgeValidators := view.GetBridgeValidators(batch.SourceChainID)
+	if bridgeValidators == nil {
+		return result.Error("Chain %v is not a counterpart of the bridge", batch.SourceChainID).
+			WithErrorCode(result.CodeUnknownBridgeChain)
+	}
+	if msg.SourceChainID != batch.SourceChainID || msg.Height != batch.Height {
+		return result.Error("Message %v is not in batch %v", msg.String(), batch.String()).
+			WithErrorCode(result.CodeInvalidBridgeMessage)
+	}
+	if msg.DestChainID != exec.state.GetChainID() {
+		return result.Error("Message %v is not sent to chain %v", msg.String(), exec.state.GetChainID()).
+			WithErrorCode(result.CodeInvalidBridgeMessage)
+	}
+	coins := msg.Coins.NoNil()
+	if !coins.IsValid() || !coins.IsPositive() {
+		return result.Error("Invalid coins to mint: %v", coins).WithErrorCode(result.CodeInvalidBridgeMessage)
+	}
+
+	if tx.Index >= batch.NumMessages ||
+		!core.VerifyBridgeMerkleProof(msg.Hash(), tx.Index, tx.Proof, batch.Root) {
+		return result.Error("Invalid Merkle proof of message %v in batch %v", msg.String(), batch.String()).
+			WithErrorCode(result.CodeInvalidBridgeProof)
+	}
+	if err := core.VerifyBridgeSignatures(&batch, tx.Signatures, bridgeValidators.ValidatorSet()); err != nil {
+		return result.Error("Invalid signatures of batch %v: %v", batch.String(), err).
+			WithErrorCode(result.CodeInvalidBridgeProof)
+	}
+
+	if view.IsBridgeMessageProcessed(msg.SourceChainID, msg.Nonce) {
+		return result.Error("Message %v from chain %v was already processed", msg.Nonce, msg.SourceChainID).
+			WithErrorCode(result.CodeBridgeMessageProcessed)
+	}
+
+	return result.OK
+}
+
+func (exec *BridgeMintTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.BridgeMintTx)
+	msg := tx.Message
+
+	if view.IsBridgeMessageProcessed(msg.SourceChainID, msg.Nonce) {
+		return common.Hash{}, result.Error("Message %v from chain %v was already processed", msg.Nonce, msg.SourceChainID)
+	}
+
+	relayerAccount, success := getInput(view, tx.Relayer)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the relayer account")
+	}
+
+	if !chargeFee(view, relayerAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	relayerAccount.Sequence++
+	view.SetAccount(tx.Relayer.Address, relayerAccount)
+
+	// The coins locked by the earlier outbound messages are released from the escrow, while the
+	// coins first arriving from the counterpart chain are minted
+	coins := msg.Coins.NoNil()
+	escrowAccount := getOrMakeAccount(view, types.BridgeEscrowAddress)
+	if escrowAccount.Balance.IsGTE(coins) {
+		escrowAccount.Balance = escrowAccount.Balance.Minus(coins)
+		view.SetAccount(types.BridgeEscrowAddress, escrowAccount)
+	} else {
+		view.AddBridgedTFuel(coins.TFuelWei)
+	}
+	recipientAccount := getOrMakeAccount(view, msg.Recipient)
+	recipientAccount.Balance = recipientAccount.Balance.Plus(coins)
+	view.SetAccount(msg.Recipient, recipientAccount)
+
+	view.MarkBridgeMessageProcessed(msg.SourceChainID, msg.Nonce)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *BridgeMintTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.BridgeMintTx)
+	return &core.TxInfo{
+		Address:           tx.Relayer.Address,
+		Sequence:          tx.Relayer.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasBridgeMintTx,
+	}
+}
+
+func (exec *BridgeMintTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.BridgeMintTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasBridgeMintTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
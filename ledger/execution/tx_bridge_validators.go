@@ -0,0 +1,146 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*BridgeValidatorsTxExecutor)(nil)
+
+// ------------------------------- Bridge Validators Transaction -----------------------------------
+
+// BridgeValidatorsTxExecutor implements the TxExecutor interface
+type BridgeValidatorsTxExecutor struct {
+	state     *st.LedgerState
+	consensus core.ConsensusEngine
+	valMgr    core.ValidatorManager
+}
+
+// NewBridgeValidatorsTxExecutor creates a new instance of BridgeValidatorsTxExecutor
+func NewBridgeValidatorsTxExecutor(state *st.LedgerState, consensus core.ConsensusEngine, valMgr core.ValidatorManager) *BridgeValidatorsTxExecutor {
+	return &BridgeValidatorsTxExecutor{
+		state:     state,
+		consensus: consensus,
+		valMgr:    valMgr,
+	}
+}
+
+func (exec *BridgeValidatorsTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.BridgeValidatorsTx)
+
+	res := tx.Proposer.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	proposerAccount, success := getInput(view, tx.Proposer)
+	if success.IsError() {
+		return result.Error("Failed to get the proposer account: %v", tx.Proposer.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(proposerAccount, signBytes, tx.Proposer)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Proposer.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !proposerAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Proposer balance is %v, but required minimal balance is %v",
+			proposerAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	res = checkBridgeValidators(exec.state.GetChainID(), &tx.Validators)
+	if res.IsError() {
+		return res
+	}
+
+	// The counterpart chains are registered with the approval of a validator supermajority
+	validatorSet := exec.valMgr.GetValidatorSet(exec.consensus.GetLastFinalizedBlock().Hash())
+	res = checkValidatorApprovals(validatorSet, tx.Proposer.Address, tx.Approvals, signBytes)
+	if res.IsError() {
+		return res
+	}
+
+	return result.OK
+}
+
+// checkBridgeValidators checks the validator set of a counterpart chain is well formed.
+func checkBridgeValidators(chainID string, bv *types.BridgeValidators) result.Result {
+	if bv.ChainID == "" || bv.ChainID == chainID {
+		return result.Error("Invalid counterpart chain: %v", bv.ChainID).
+			WithErrorCode(result.CodeInvalidBridgeValidatorSet)
+	}
+	if len(bv.Validators) == 0 {
+		return result.Error("The validator set of chain %v is empty", bv.ChainID).
+			WithErrorCode(result.CodeInvalidBridgeValidatorSet)
+	}
+	seen := make(map[common.Address]bool)
+	for _, v := range bv.Validators {
+		if seen[v.Address] {
+			return result.Error("Duplicated validator %v", v.Address.Hex()).
+				WithErrorCode(result.CodeInvalidBridgeValidatorSet)
+		}
+		seen[v.Address] = true
+		if v.Stake == nil || v.Stake.Sign() <= 0 {
+			return result.Error("Invalid stake of validator %v", v.Address.Hex()).
+				WithErrorCode(result.CodeInvalidBridgeValidatorSet)
+		}
+	}
+	return result.OK
+}
+
+func (exec *BridgeValidatorsTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.BridgeValidatorsTx)
+
+	proposerAccount, success := getInput(view, tx.Proposer)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the proposer account")
+	}
+
+	if !chargeFee(view, proposerAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	validators := tx.Validators
+	view.SetBridgeValidators(&validators)
+
+	logger.Infof("Bridge validators of chain %v updated at height %v: %v validators",
+		validators.ChainID, view.Height()+1, len(validators.Validators))
+
+	proposerAccount.Sequence++
+	view.SetAccount(tx.Proposer.Address, proposerAccount)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *BridgeValidatorsTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.BridgeValidatorsTx)
+	return &core.TxInfo{
+		Address:           tx.Proposer.Address,
+		Sequence:          tx.Proposer.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasBridgeValidatorsTx,
+	}
+}
+
+func (exec *BridgeValidatorsTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.BridgeValidatorsTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasBridgeValidatorsTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
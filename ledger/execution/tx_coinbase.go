@@ -106,9 +106,15 @@ func (exec *CoinbaseTxExecutor) process(chainID string, view *st.StoreView, tran
 		if account, exists := accounts[addr]; exists {
 			account.Balance = account.Balance.Plus(output.Coins)
 			view.SetAccount(output.Address, account)
+			view.AddMintedTFuel(output.Coins.NoNil().TFuelWei)
 		}
 	}
 
+	validators := make([]common.Address, 0, len(tx.Outputs))
+	for _, output := range tx.Outputs {
+		validators = append(validators, output.Address)
+	}
+	view.SetBlockValidators(tx.Proposer.Address, validators)
 	view.SetCoinbaseTransactionProcessed(true)
 
 	txHash := types.TxID(chainID, tx)
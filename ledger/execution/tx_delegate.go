@@ -0,0 +1,150 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*DelegateTxExecutor)(nil)
+
+// ------------------------------- Delegate Transaction -----------------------------------
+
+// DelegateTxExecutor implements the TxExecutor interface
+type DelegateTxExecutor struct {
+}
+
+// NewDelegateTxExecutor creates a new instance of DelegateTxExecutor
+func NewDelegateTxExecutor() *DelegateTxExecutor {
+	return &DelegateTxExecutor{}
+}
+
+func (exec *DelegateTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.DelegateTx)
+
+	res := tx.Delegator.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	delegatorAccount, res := getInput(view, tx.Delegator)
+	if res.IsError() {
+		return result.Error("Failed to get the delegator account: %v", tx.Delegator.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(delegatorAccount, signBytes, tx.Delegator)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Delegator.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	stake := tx.Delegator.Coins.NoNil()
+	if !stake.IsValid() || !stake.IsNonnegative() || stake.TFuelWei.Sign() != 0 {
+		return result.Error("Only Theta can be delegated").WithErrorCode(result.CodeInvalidStake)
+	}
+	if stake.ThetaWei.Cmp(core.MinDelegation) < 0 {
+		return result.Error("Insufficient amount of stake, at least %v ThetaWei is required for each delegation", core.MinDelegation).
+			WithErrorCode(result.CodeInsufficientStake)
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	if !isDelegatable(vcp, tx.Validator) {
+		return result.Error("Cannot delegate to %v, which is not a validator candidate", tx.Validator).
+			WithErrorCode(result.CodeInvalidDelegation)
+	}
+
+	minimalBalance := stake.Plus(tx.Fee)
+	if !delegatorAccount.Balance.IsGTE(minimalBalance) {
+		return result.Error("Delegate: Delegator balance is %v, but required minimal balance is %v",
+			delegatorAccount.Balance, minimalBalance).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	return result.OK
+}
+
+// isDelegatable returns whether the holder is a validator candidate with stake of its own.
+func isDelegatable(vcp *core.ValidatorCandidatePool, holder common.Address) bool {
+	if vcp == nil {
+		return false
+	}
+	for _, candidate := range vcp.SortedCandidates {
+		if candidate.Holder == holder {
+			return candidate.SelfStake().Sign() > 0
+		}
+	}
+	return false
+}
+
+func (exec *DelegateTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.DelegateTx)
+
+	delegatorAccount, res := getInput(view, tx.Delegator)
+	if res.IsError() {
+		return common.Hash{}, result.Error("Failed to get the delegator account")
+	}
+
+	if !chargeFee(view, delegatorAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	stake := tx.Delegator.Coins.NoNil()
+	if !delegatorAccount.Balance.IsGTE(stake) {
+		return common.Hash{}, result.Error("Not enough balance to delegate").WithErrorCode(result.CodeNotEnoughBalanceToStake)
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return common.Hash{}, result.Error("Cannot delegate to %v, which is not a validator candidate", tx.Validator).
+			WithErrorCode(result.CodeInvalidDelegation)
+	}
+	err := vcp.Delegate(tx.Delegator.Address, tx.Validator, stake.ThetaWei)
+	if err != nil {
+		return common.Hash{}, result.Error("Failed to delegate stake, err: %v", err).
+			WithErrorCode(result.CodeInvalidDelegation)
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+	delegatorAccount.Balance = delegatorAccount.Balance.Minus(stake)
+
+	hl := view.GetStakeTransactionHeightList()
+	if hl == nil {
+		hl = &types.HeightList{}
+	}
+	hl.Append(view.Height())
+	view.UpdateStakeTransactionHeightList(hl)
+
+	delegatorAccount.Sequence++
+	view.SetAccount(tx.Delegator.Address, delegatorAccount)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *DelegateTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.DelegateTx)
+	return &core.TxInfo{
+		Address:           tx.Delegator.Address,
+		Sequence:          tx.Delegator.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasDelegateTx,
+	}
+}
+
+func (exec *DelegateTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.DelegateTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasDelegateTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
 	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto/bls"
 	st "github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
 )
@@ -24,8 +25,26 @@ func NewDepositStakeExecutor() *DepositStakeExecutor {
 	return &DepositStakeExecutor{}
 }
 
+// toDepositStakeTxV2 converts the transaction to a DepositStakeTxV2, since a DepositStakeTx is a
+// DepositStakeTxV2 without the BLS key
+func toDepositStakeTxV2(transaction types.Tx) *types.DepositStakeTxV2 {
+	switch tx := transaction.(type) {
+	case *types.DepositStakeTx:
+		return &types.DepositStakeTxV2{
+			Fee:     tx.Fee,
+			Source:  tx.Source,
+			Holder:  tx.Holder,
+			Purpose: tx.Purpose,
+		}
+	case *types.DepositStakeTxV2:
+		return tx
+	default:
+		panic(fmt.Sprintf("Unexpected deposit stake transaction type: %T", transaction))
+	}
+}
+
 func (exec *DepositStakeExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
-	tx := transaction.(*types.DepositStakeTx)
+	tx := toDepositStakeTxV2(transaction)
 
 	res := tx.Source.ValidateBasic()
 	if res.IsError() {
@@ -34,19 +53,20 @@ func (exec *DepositStakeExecutor) sanityCheck(chainID string, view *st.StoreView
 
 	sourceAccount, success := getInput(view, tx.Source)
 	if success.IsError() {
-		return result.Error("Failed to get the source account: %v", tx.Source.Address)
+		return result.Error("Failed to get the source account: %v", tx.Source.Address).
+			WithErrorCode(result.CodeAccountNotFound)
 	}
 
-	signBytes := tx.SignBytes(chainID)
+	signBytes := transaction.SignBytes(chainID)
 	res = validateInputAdvanced(sourceAccount, signBytes, tx.Source)
 	if res.IsError() {
 		logger.Infof(fmt.Sprintf("validateSourceAdvanced failed on %v: %v", tx.Source.Address.Hex(), res))
 		return res
 	}
 
-	if !sanityCheckForFee(tx.Fee) {
+	if !sanityCheckForFee(view, tx.Fee) {
 		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
-			types.MinimumTransactionFeeTFuelWei).WithErrorCode(result.CodeInvalidFee)
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
 	}
 
 	if !(tx.Purpose == core.StakeForValidator || tx.Purpose == core.StakeForGuardian) {
@@ -66,11 +86,22 @@ func (exec *DepositStakeExecutor) sanityCheck(chainID string, view *st.StoreView
 	}
 
 	// Minimum stake deposit requirement to avoid spamming
-	if stake.ThetaWei.Cmp(core.MinValidatorStakeDeposit) < 0 {
-		return result.Error("Insufficient amount of stake, at least %v ThetaWei is required for each deposit", core.MinValidatorStakeDeposit).
+	minStakeDeposit := core.MinValidatorStakeDeposit
+	if tx.Purpose == core.StakeForGuardian {
+		minStakeDeposit = core.MinGuardianStakeDeposit
+	}
+	if stake.ThetaWei.Cmp(minStakeDeposit) < 0 {
+		return result.Error("Insufficient amount of stake, at least %v ThetaWei is required for each deposit", minStakeDeposit).
 			WithErrorCode(result.CodeInsufficientStake)
 	}
 
+	if tx.Purpose == core.StakeForGuardian {
+		res = sanityCheckForGuardianKey(tx)
+		if res.IsError() {
+			return res
+		}
+	}
+
 	minimalBalance := stake.Plus(tx.Fee)
 	if !sourceAccount.Balance.IsGTE(minimalBalance) {
 		logger.Infof(fmt.Sprintf("DepositStake: Source did not have enough balance %v", tx.Source.Address.Hex()))
@@ -81,15 +112,31 @@ func (exec *DepositStakeExecutor) sanityCheck(chainID string, view *st.StoreView
 	return result.OK
 }
 
+// sanityCheckForGuardianKey checks the guardian owns both the BLS key and the holder account
+func sanityCheckForGuardianKey(tx *types.DepositStakeTxV2) result.Result {
+	pubkey, err := bls.PublicKeyFromBytes(tx.BlsPubkey)
+	if err != nil {
+		return result.Error("Invalid BLS public key: %v", err).WithErrorCode(result.CodeInvalidBlsKey)
+	}
+	pop, err := bls.SignatureFromBytes(tx.BlsPop)
+	if err != nil || !pubkey.PopVerify(pop) {
+		return result.Error("Invalid proof of possession of the BLS key").WithErrorCode(result.CodeInvalidBlsKey)
+	}
+	if tx.HolderSig == nil || !tx.HolderSig.Verify(tx.HolderSignBytes(), tx.Holder.Address) {
+		return result.Error("Invalid holder signature over the BLS key").WithErrorCode(result.CodeInvalidSignature)
+	}
+	return result.OK
+}
+
 func (exec *DepositStakeExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
-	tx := transaction.(*types.DepositStakeTx)
+	tx := toDepositStakeTxV2(transaction)
 
 	sourceAccount, success := getInput(view, tx.Source)
 	if success.IsError() {
 		return common.Hash{}, result.Error("Failed to get the source account")
 	}
 
-	if !chargeFee(sourceAccount, tx.Fee) {
+	if !chargeFee(view, sourceAccount, tx.Fee) {
 		return common.Hash{}, result.Error("Failed to charge transaction fee")
 	}
 
@@ -111,7 +158,18 @@ func (exec *DepositStakeExecutor) process(chainID string, view *st.StoreView, tr
 		}
 		view.UpdateValidatorCandidatePool(vcp)
 	} else if tx.Purpose == core.StakeForGuardian {
-		return common.Hash{}, result.Error("Staking for guardian not supported yet")
+		pubkey, err := bls.PublicKeyFromBytes(tx.BlsPubkey)
+		if err != nil {
+			return common.Hash{}, result.Error("Invalid BLS public key: %v", err).WithErrorCode(result.CodeInvalidBlsKey)
+		}
+		sourceAccount.Balance = sourceAccount.Balance.Minus(stake)
+		stakeAmount := stake.ThetaWei
+		gcp := view.GetGuardianCandidatePool()
+		err = gcp.DepositStake(sourceAddress, holderAddress, stakeAmount, pubkey)
+		if err != nil {
+			return common.Hash{}, result.Error("Failed to deposit stake, err: %v", err)
+		}
+		view.UpdateGuardianCandidatePool(gcp)
 	} else {
 		return common.Hash{}, result.Error("Invalid staking purpose").WithErrorCode(result.CodeInvalidStakePurpose)
 	}
@@ -126,21 +184,23 @@ func (exec *DepositStakeExecutor) process(chainID string, view *st.StoreView, tr
 	sourceAccount.Sequence++
 	view.SetAccount(sourceAddress, sourceAccount)
 
-	txHash := types.TxID(chainID, tx)
+	txHash := types.TxID(chainID, transaction)
 	return txHash, result.OK
 }
 
 func (exec *DepositStakeExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
-	tx := transaction.(*types.DepositStakeTx)
+	tx := toDepositStakeTxV2(transaction)
 	return &core.TxInfo{
 		Address:           tx.Source.Address,
 		Sequence:          tx.Source.Sequence,
 		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasDepositStakeTx,
 	}
 }
 
 func (exec *DepositStakeExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
-	tx := transaction.(*types.DepositStakeTx)
+	tx := toDepositStakeTxV2(transaction)
 	fee := tx.Fee
 	gas := new(big.Int).SetUint64(types.GasDepositStakeTx)
 	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
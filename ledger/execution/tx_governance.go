@@ -0,0 +1,153 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*GovernanceTxExecutor)(nil)
+
+// ------------------------------- Governance Transaction -----------------------------------
+
+// GovernanceTxExecutor implements the TxExecutor interface
+type GovernanceTxExecutor struct {
+	consensus core.ConsensusEngine
+	valMgr    core.ValidatorManager
+}
+
+// NewGovernanceTxExecutor creates a new instance of GovernanceTxExecutor
+func NewGovernanceTxExecutor(consensus core.ConsensusEngine, valMgr core.ValidatorManager) *GovernanceTxExecutor {
+	return &GovernanceTxExecutor{
+		consensus: consensus,
+		valMgr:    valMgr,
+	}
+}
+
+func (exec *GovernanceTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.GovernanceTx)
+
+	res := tx.Proposer.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	proposerAccount, success := getInput(view, tx.Proposer)
+	if success.IsError() {
+		return result.Error("Failed to get the proposer account: %v", tx.Proposer.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(proposerAccount, signBytes, tx.Proposer)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Proposer.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !proposerAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Proposer balance is %v, but required minimal balance is %v",
+			proposerAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	if err := tx.Params.Validate(); err != nil {
+		return result.Error("Invalid chain params: %v", err).WithErrorCode(result.CodeInvalidChainParams)
+	}
+
+	// The proposer and the approving validators together need to hold more than 2/3 of the stake
+	validatorSet := exec.valMgr.GetValidatorSet(exec.consensus.GetLastFinalizedBlock().Hash())
+	res = checkValidatorApprovals(validatorSet, tx.Proposer.Address, tx.Approvals, signBytes)
+	if res.IsError() {
+		return res
+	}
+
+	return result.OK
+}
+
+func (exec *GovernanceTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.GovernanceTx)
+
+	proposerAccount, success := getInput(view, tx.Proposer)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the proposer account")
+	}
+
+	if !chargeFee(view, proposerAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	params := tx.Params
+	view.SetChainParams(&params)
+
+	logger.Infof("Chain params updated at height %v: %v", view.Height(), params.String())
+
+	proposerAccount.Sequence++
+	view.SetAccount(tx.Proposer.Address, proposerAccount)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *GovernanceTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.GovernanceTx)
+	return &core.TxInfo{
+		Address:           tx.Proposer.Address,
+		Sequence:          tx.Proposer.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasGovernanceTx,
+	}
+}
+
+func (exec *GovernanceTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.GovernanceTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasGovernanceTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
+
+// checkValidatorApprovals checks the proposer and the approving validators together hold more
+// than 2/3 of the stake of the validator set.
+func checkValidatorApprovals(validatorSet *core.ValidatorSet, proposer common.Address,
+	approvals []types.GovernanceApproval, signBytes []byte) result.Result {
+	if _, err := validatorSet.GetValidator(proposer); err != nil {
+		return result.Error("The proposer %v is not a validator", proposer.Hex()).
+			WithErrorCode(result.CodeUnauthorizedGovernance)
+	}
+
+	approved := map[common.Address]bool{proposer: true}
+	votes := []core.Vote{{ID: proposer}}
+	for _, approval := range approvals {
+		if approved[approval.Address] {
+			return result.Error("Duplicated approval from %v", approval.Address.Hex()).
+				WithErrorCode(result.CodeDuplicatedAddress)
+		}
+		if _, err := validatorSet.GetValidator(approval.Address); err != nil {
+			return result.Error("The approver %v is not a validator", approval.Address.Hex()).
+				WithErrorCode(result.CodeUnauthorizedGovernance)
+		}
+		if approval.Signature == nil || !approval.Signature.Verify(signBytes, approval.Address) {
+			return result.Error("Invalid approval signature from %v", approval.Address.Hex()).
+				WithErrorCode(result.CodeInvalidSignature)
+		}
+		approved[approval.Address] = true
+		votes = append(votes, core.Vote{ID: approval.Address})
+	}
+
+	if !validatorSet.HasMajorityVotes(votes) {
+		return result.Error("The transaction is not approved by a validator supermajority").
+			WithErrorCode(result.CodeInsufficientApprovals)
+	}
+
+	return result.OK
+}
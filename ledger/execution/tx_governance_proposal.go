@@ -0,0 +1,141 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*GovernanceProposalTxExecutor)(nil)
+
+// ------------------------------- Governance Proposal Transaction -----------------------------------
+
+// GovernanceProposalTxExecutor implements the TxExecutor interface
+type GovernanceProposalTxExecutor struct {
+}
+
+// NewGovernanceProposalTxExecutor creates a new instance of GovernanceProposalTxExecutor
+func NewGovernanceProposalTxExecutor() *GovernanceProposalTxExecutor {
+	return &GovernanceProposalTxExecutor{}
+}
+
+func (exec *GovernanceProposalTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.GovernanceProposalTx)
+
+	res := tx.Proposer.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	proposerAccount, success := getInput(view, tx.Proposer)
+	if success.IsError() {
+		return result.Error("Failed to get the proposer account: %v", tx.Proposer.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(proposerAccount, signBytes, tx.Proposer)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Proposer.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !proposerAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Proposer balance is %v, but required minimal balance is %v",
+			proposerAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	if err := tx.Params.Validate(); err != nil {
+		return result.Error("Invalid chain params: %v", err).WithErrorCode(result.CodeInvalidChainParams)
+	}
+
+	votingEndHeight := view.Height() + 1 + types.GovernanceVotingPeriod
+	if tx.ActivationHeight <= votingEndHeight {
+		return result.Error("The activation height %v needs to be after the end of the voting at height %v",
+			tx.ActivationHeight, votingEndHeight).WithErrorCode(result.CodeInvalidProposalHeight)
+	}
+
+	if !hasGovernanceStake(view, tx.Proposer.Address) {
+		return result.Error("The governance proposer %v is not a stake holder", tx.Proposer.Address.Hex()).
+			WithErrorCode(result.CodeUnauthorizedGovernance)
+	}
+
+	return result.OK
+}
+
+func (exec *GovernanceProposalTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.GovernanceProposalTx)
+
+	proposerAccount, success := getInput(view, tx.Proposer)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the proposer account")
+	}
+
+	if !chargeFee(view, proposerAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	submitHeight := view.Height() + 1
+	proposal := &types.GovernanceProposal{
+		ID:               view.GetNextGovernanceProposalID(),
+		Proposer:         tx.Proposer.Address,
+		Params:           tx.Params,
+		SubmitHeight:     submitHeight,
+		VotingEndHeight:  submitHeight + types.GovernanceVotingPeriod,
+		ActivationHeight: tx.ActivationHeight,
+		Status:           types.GovernanceProposalVoting,
+	}
+	view.SetGovernanceProposal(proposal)
+	view.SetNextGovernanceProposalID(proposal.ID + 1)
+
+	logger.Infof("Governance proposal submitted at height %v: %v", submitHeight, proposal)
+
+	proposerAccount.Sequence++
+	view.SetAccount(tx.Proposer.Address, proposerAccount)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *GovernanceProposalTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.GovernanceProposalTx)
+	return &core.TxInfo{
+		Address:           tx.Proposer.Address,
+		Sequence:          tx.Proposer.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasGovernanceProposalTx,
+	}
+}
+
+func (exec *GovernanceProposalTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.GovernanceProposalTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasGovernanceProposalTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
+
+// hasGovernanceStake returns whether the address holds stake in the validator candidate pool,
+// which the governance votes are weighted with.
+func hasGovernanceStake(view *st.StoreView, addr common.Address) bool {
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return false
+	}
+	for _, candidate := range vcp.SortedCandidates {
+		if candidate.Holder == addr {
+			return candidate.TotalStake().Sign() > 0
+		}
+	}
+	return false
+}
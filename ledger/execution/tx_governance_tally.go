@@ -0,0 +1,135 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*GovernanceTallyTxExecutor)(nil)
+
+// ------------------------------- Governance Tally Transaction -----------------------------------
+
+// GovernanceTallyTxExecutor implements the TxExecutor interface
+type GovernanceTallyTxExecutor struct {
+}
+
+// NewGovernanceTallyTxExecutor creates a new instance of GovernanceTallyTxExecutor
+func NewGovernanceTallyTxExecutor() *GovernanceTallyTxExecutor {
+	return &GovernanceTallyTxExecutor{}
+}
+
+func (exec *GovernanceTallyTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.GovernanceTallyTx)
+
+	res := tx.Caller.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	callerAccount, success := getInput(view, tx.Caller)
+	if success.IsError() {
+		return result.Error("Failed to get the caller account: %v", tx.Caller.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(callerAccount, signBytes, tx.Caller)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Caller.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !callerAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Caller balance is %v, but required minimal balance is %v",
+			callerAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	proposal := view.GetGovernanceProposal(tx.ProposalID)
+	if proposal == nil {
+		return result.Error("Governance proposal %v not found", tx.ProposalID).
+			WithErrorCode(result.CodeProposalNotFound)
+	}
+	if proposal.Status != types.GovernanceProposalVoting {
+		return result.Error("Governance proposal %v is already %v", tx.ProposalID, proposal.Status).
+			WithErrorCode(result.CodeInvalidProposalStatus)
+	}
+
+	// Before the end of the voting, the proposal can only be settled if the outcome can no
+	// longer change
+	if view.Height()+1 <= proposal.VotingEndHeight {
+		tally := types.TallyGovernanceProposal(proposal, view.GetValidatorCandidatePool())
+		if !tally.Approved() && !tally.Rejected() {
+			return result.Error("The voting on governance proposal %v is undecided until height %v",
+				tx.ProposalID, proposal.VotingEndHeight).WithErrorCode(result.CodeInvalidProposalHeight)
+		}
+	}
+
+	return result.OK
+}
+
+func (exec *GovernanceTallyTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.GovernanceTallyTx)
+
+	callerAccount, success := getInput(view, tx.Caller)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the caller account")
+	}
+
+	proposal := view.GetGovernanceProposal(tx.ProposalID)
+	if proposal == nil {
+		return common.Hash{}, result.Error("Governance proposal %v not found", tx.ProposalID)
+	}
+
+	if !chargeFee(view, callerAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	tally := types.TallyGovernanceProposal(proposal, view.GetValidatorCandidatePool())
+	if tally.Approved() {
+		proposal.Status = types.GovernanceProposalPassed
+		passed := view.GetPassedGovernanceProposals()
+		passed.Append(proposal.ID)
+		view.UpdatePassedGovernanceProposals(passed)
+	} else {
+		proposal.Status = types.GovernanceProposalRejected
+	}
+	view.SetGovernanceProposal(proposal)
+
+	logger.Infof("Governance proposal %v %v at height %v, approved stake: %v, rejected stake: %v, total stake: %v",
+		proposal.ID, proposal.Status, view.Height()+1, tally.ApprovedStake, tally.RejectedStake, tally.TotalStake)
+
+	callerAccount.Sequence++
+	view.SetAccount(tx.Caller.Address, callerAccount)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *GovernanceTallyTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.GovernanceTallyTx)
+	return &core.TxInfo{
+		Address:           tx.Caller.Address,
+		Sequence:          tx.Caller.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasGovernanceTallyTx,
+	}
+}
+
+func (exec *GovernanceTallyTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.GovernanceTallyTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasGovernanceTallyTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
@@ -0,0 +1,123 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*GovernanceVoteTxExecutor)(nil)
+
+// ------------------------------- Governance Vote Transaction -----------------------------------
+
+// GovernanceVoteTxExecutor implements the TxExecutor interface
+type GovernanceVoteTxExecutor struct {
+}
+
+// NewGovernanceVoteTxExecutor creates a new instance of GovernanceVoteTxExecutor
+func NewGovernanceVoteTxExecutor() *GovernanceVoteTxExecutor {
+	return &GovernanceVoteTxExecutor{}
+}
+
+func (exec *GovernanceVoteTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.GovernanceVoteTx)
+
+	res := tx.Voter.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	voterAccount, success := getInput(view, tx.Voter)
+	if success.IsError() {
+		return result.Error("Failed to get the voter account: %v", tx.Voter.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(voterAccount, signBytes, tx.Voter)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Voter.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !voterAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Voter balance is %v, but required minimal balance is %v",
+			voterAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	proposal := view.GetGovernanceProposal(tx.ProposalID)
+	if proposal == nil {
+		return result.Error("Governance proposal %v not found", tx.ProposalID).
+			WithErrorCode(result.CodeProposalNotFound)
+	}
+	if proposal.Status != types.GovernanceProposalVoting {
+		return result.Error("Governance proposal %v is %v, no longer open for votes", tx.ProposalID, proposal.Status).
+			WithErrorCode(result.CodeInvalidProposalStatus)
+	}
+	if view.Height()+1 > proposal.VotingEndHeight {
+		return result.Error("The voting on governance proposal %v ended at height %v", tx.ProposalID, proposal.VotingEndHeight).
+			WithErrorCode(result.CodeInvalidProposalHeight)
+	}
+
+	if !hasGovernanceStake(view, tx.Voter.Address) {
+		return result.Error("The governance voter %v is not a stake holder", tx.Voter.Address.Hex()).
+			WithErrorCode(result.CodeUnauthorizedGovernance)
+	}
+
+	return result.OK
+}
+
+func (exec *GovernanceVoteTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.GovernanceVoteTx)
+
+	voterAccount, success := getInput(view, tx.Voter)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the voter account")
+	}
+
+	proposal := view.GetGovernanceProposal(tx.ProposalID)
+	if proposal == nil {
+		return common.Hash{}, result.Error("Governance proposal %v not found", tx.ProposalID)
+	}
+
+	if !chargeFee(view, voterAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	proposal.Vote(tx.Voter.Address, tx.Approve)
+	view.SetGovernanceProposal(proposal)
+
+	voterAccount.Sequence++
+	view.SetAccount(tx.Voter.Address, voterAccount)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *GovernanceVoteTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.GovernanceVoteTx)
+	return &core.TxInfo{
+		Address:           tx.Voter.Address,
+		Sequence:          tx.Voter.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasGovernanceVoteTx,
+	}
+}
+
+func (exec *GovernanceVoteTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.GovernanceVoteTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasGovernanceVoteTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
@@ -16,11 +16,14 @@ var _ TxExecutor = (*SendTxExecutor)(nil)
 
 // SendTxExecutor implements the TxExecutor interface
 type SendTxExecutor struct {
+	state *st.LedgerState
 }
 
 // NewSendTxExecutor creates a new instance of SendTxExecutor
-func NewSendTxExecutor() *SendTxExecutor {
-	return &SendTxExecutor{}
+func NewSendTxExecutor(state *st.LedgerState) *SendTxExecutor {
+	return &SendTxExecutor{
+		state: state,
+	}
 }
 
 func (exec *SendTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
@@ -37,13 +40,14 @@ func (exec *SendTxExecutor) sanityCheck(chainID string, view *st.StoreView, tran
 	}
 
 	if len(tx.Inputs) == 0 || len(tx.Outputs) == 0 {
-		return result.Error("Invalid sendTx, Inputs and/or Outputs are empty")
+		return result.Error("Invalid sendTx, Inputs and/or Outputs are empty").
+			WithErrorCode(result.CodeInvalidInputOutput)
 	}
 
 	numAccountsAffected := uint64(len(tx.Inputs) + len(tx.Outputs))
 	if numAccountsAffected > types.MaxAccountsAffectedPerTx {
 		return result.Error("Trasaction modifying too many accounts. At most %v accounts are allowed per transaction",
-			types.MaxAccountsAffectedPerTx)
+			types.MaxAccountsAffectedPerTx).WithErrorCode(result.CodeTooManyAccounts)
 	}
 
 	// Get inputs
@@ -65,21 +69,36 @@ func (exec *SendTxExecutor) sanityCheck(chainID string, view *st.StoreView, tran
 		return res
 	}
 
-	if !sanityCheckForFee(tx.Fee) {
+	if !sanityCheckForFee(view, tx.Fee) {
 		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
-			types.MinimumTransactionFeeTFuelWei).WithErrorCode(result.CodeInvalidFee)
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+	if minFee := exec.minimumFee(view, tx); tx.Fee.TFuelWei.Cmp(minFee) < 0 {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei for %v outputs",
+			minFee, len(tx.Outputs)).WithErrorCode(result.CodeInvalidFee)
 	}
 
 	outTotal := sumOutputs(tx.Outputs)
 	outPlusFees := outTotal
 	outPlusFees = outTotal.Plus(tx.Fee)
 	if !inTotal.IsEqual(outPlusFees) {
-		return result.Error("Input total (%v) != output total + fees (%v)", inTotal, outPlusFees)
+		return result.Error("Input total (%v) != output total + fees (%v)", inTotal, outPlusFees).
+			WithErrorCode(result.CodeInputOutputMismatch)
 	}
 
 	return result.OK
 }
 
+// minimumFee returns the min fee of the transaction, which grows with the number of outputs once
+// the batch send fee upgrade is active.
+func (exec *SendTxExecutor) minimumFee(view *st.StoreView, tx *types.SendTx) *big.Int {
+	minFee := minimumTransactionFee(view)
+	if !core.RulesAt(exec.state.GetChainID(), view.Height()+1).IsActive(core.UpgradeBatchSendFee) {
+		return minFee
+	}
+	return types.MinimumSendTxFee(minFee, len(tx.Outputs))
+}
+
 func (exec *SendTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
 	tx := transaction.(*types.SendTx)
 
@@ -96,6 +115,9 @@ func (exec *SendTxExecutor) process(chainID string, view *st.StoreView, transact
 	adjustByInputs(view, accounts, tx.Inputs)
 	adjustByOutputs(view, accounts, tx.Outputs)
 
+	// The inputs exceed the outputs by the fee, which is burned
+	view.AddBurnedTFuel(tx.Fee.NoNil().TFuelWei)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
@@ -106,18 +128,24 @@ func (exec *SendTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
 		Address:           tx.Inputs[0].Address,
 		Sequence:          tx.Inputs[0].Sequence,
 		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               exec.calculateGas(tx),
 	}
 }
 
 func (exec *SendTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
 	tx := transaction.(*types.SendTx)
 	fee := tx.Fee
-	numAccountsAffected := uint64(len(tx.Inputs) + len(tx.Outputs))
-	gasUint64 := types.GasSendTxPerAccount * numAccountsAffected
-	if gasUint64 < 2*types.GasSendTxPerAccount {
-		gasUint64 = 2 * types.GasSendTxPerAccount // to prevent spamming with invalid transactions, e.g. empty inputs/outputs
-	}
-	gas := new(big.Int).SetUint64(gasUint64)
+	gas := new(big.Int).SetUint64(exec.calculateGas(tx))
 	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
 	return effectiveGasPrice
 }
+
+func (exec *SendTxExecutor) calculateGas(tx *types.SendTx) uint64 {
+	numAccountsAffected := uint64(len(tx.Inputs) + len(tx.Outputs))
+	gas := types.GasSendTxPerAccount * numAccountsAffected
+	if gas < 2*types.GasSendTxPerAccount {
+		gas = 2 * types.GasSendTxPerAccount // to prevent spamming with invalid transactions, e.g. empty inputs/outputs
+	}
+	return gas
+}
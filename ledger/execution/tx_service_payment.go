@@ -63,25 +63,26 @@ func (exec *ServicePaymentTxExecutor) sanityCheck(chainID string, view *st.Store
 	if !tx.Source.Signature.Verify(sourceSignBytes, sourceAccount.Address) {
 		errMsg := fmt.Sprintf("sanityCheckForServicePaymentTx failed on source signature, addr: %v", sourceAddress.Hex())
 		logger.Infof(errMsg)
-		return result.Error(errMsg)
+		return result.Error(errMsg).WithErrorCode(result.CodeInvalidSignature)
 	}
 
 	// Verify target
 	if targetAccount.Sequence+1 != tx.Target.Sequence {
 		return result.Error("ServicePayment: Got %v, expected %v. (acc.seq=%v)",
-			tx.Target.Sequence, targetAccount.Sequence+1, targetAccount.Sequence)
+			tx.Target.Sequence, targetAccount.Sequence+1, targetAccount.Sequence).
+			WithErrorCode(result.CodeInvalidSequence)
 	}
 
 	targetSignBytes := tx.TargetSignBytes(chainID)
 	if !tx.Target.Signature.Verify(targetSignBytes, targetAccount.Address) {
 		errMsg := fmt.Sprintf("sanityCheckForServicePaymentTx failed on target signature, addr: %v", targetAddress.Hex())
 		logger.Infof(errMsg)
-		return result.Error(errMsg)
+		return result.Error(errMsg).WithErrorCode(result.CodeInvalidSignature)
 	}
 
-	if !sanityCheckForFee(tx.Fee) {
+	if !sanityCheckForFee(view, tx.Fee) {
 		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
-			types.MinimumTransactionFeeTFuelWei).WithErrorCode(result.CodeInvalidFee)
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
 	}
 
 	transferAmount := tx.Source.Coins
@@ -131,7 +132,7 @@ func (exec *ServicePaymentTxExecutor) process(chainID string, view *st.StoreView
 	if shouldSlash {
 		view.AddSlashIntent(slashIntent)
 	}
-	if !chargeFee(targetAccount, tx.Fee) {
+	if !chargeFee(view, targetAccount, tx.Fee) {
 		return common.Hash{}, result.Error("failed to charge transaction fee")
 	}
 	targetAccount.Sequence++ // targetAccount broadcasted the transaction
@@ -200,6 +201,8 @@ func (exec *ServicePaymentTxExecutor) getTxInfo(transaction types.Tx) *core.TxIn
 		Address:           tx.Target.Address,
 		Sequence:          tx.Target.Sequence,
 		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasServicePaymentTx,
 	}
 }
 
@@ -1,6 +1,7 @@
 package execution
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/thetatoken/theta/common"
@@ -8,6 +9,7 @@ import (
 	"github.com/thetatoken/theta/core"
 	st "github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/notify"
 )
 
 var _ TxExecutor = (*SlashTxExecutor)(nil)
@@ -133,9 +135,41 @@ func (exec *SlashTxExecutor) process(chainID string, view *st.StoreView, transac
 	view.SetAccount(slashedAddress, slashedAccount)
 
 	txHash := types.TxID(chainID, tx)
+	exec.notifySlashing(txHash, tx, slashedAmount)
 	return txHash, result.OK
 }
 
+// notifySlashing notifies the operator of the slash transactions involving the local validator,
+// either as the proposer which reported the overspending or as the slashed address.
+func (exec *SlashTxExecutor) notifySlashing(txHash common.Hash, tx *types.SlashTx, slashedAmount types.Coins) {
+	localAddress := common.HexToAddress(exec.consensus.ID())
+	var severity notify.Severity
+	var summary string
+	switch localAddress {
+	case tx.SlashedAddress:
+		severity = notify.SeverityCritical
+		summary = fmt.Sprintf("Local address %v is slashed", localAddress.Hex())
+	case tx.Proposer.Address:
+		severity = notify.SeverityInfo
+		summary = fmt.Sprintf("Local validator %v slashed %v", localAddress.Hex(), tx.SlashedAddress.Hex())
+	default:
+		return
+	}
+	notify.Notify(notify.Event{
+		Type:     notify.EventSlashing,
+		Severity: severity,
+		Summary:  summary,
+		Details: map[string]interface{}{
+			"tx":              txHash.Hex(),
+			"slashedAddress":  tx.SlashedAddress.Hex(),
+			"proposer":        tx.Proposer.Address.Hex(),
+			"reserveSequence": tx.ReserveSequence,
+			"slashedAmount":   slashedAmount.String(),
+		},
+		Key: string(notify.EventSlashing) + "/" + txHash.Hex(), // The tx is processed again when the block is applied
+	})
+}
+
 func (exec *SlashTxExecutor) verifySlashProof(chainID string, slashedAccount *types.Account, overspendingProofBytes []byte) bool {
 	var overspendingProof types.OverspendingProof
 	err := types.FromBytes(overspendingProofBytes, &overspendingProof)
@@ -40,7 +40,8 @@ func (exec *SmartContractTxExecutor) sanityCheck(chainID string, view *st.StoreV
 	// Get input account
 	fromAccount, success := getInput(view, tx.From)
 	if success.IsError() {
-		return result.Error("Failed to get the from account")
+		return result.Error("Failed to get the from account").
+			WithErrorCode(result.CodeAccountNotFound)
 	}
 
 	// Validate input, advanced
@@ -57,11 +58,17 @@ func (exec *SmartContractTxExecutor) sanityCheck(chainID string, view *st.StoreV
 			WithErrorCode(result.CodeInvalidValueToTransfer)
 	}
 
-	if !sanityCheckForGasPrice(tx.GasPrice) {
-		return result.Error("Insufficient gas price. Gas price needs to be at least %v TFuelWei", types.MinimumGasPrice).
+	if !sanityCheckForGasPrice(view, tx.GasPrice) {
+		return result.Error("Insufficient gas price. Gas price needs to be at least %v TFuelWei", minimumGasPrice(view)).
 			WithErrorCode(result.CodeInvalidGasPrice)
 	}
 
+	maxBlockGas := view.GetChainParams().MaxBlockGas
+	if tx.GasLimit > maxBlockGas {
+		return result.Error("Gas limit %v exceeds the block gas limit %v", tx.GasLimit, maxBlockGas).
+			WithErrorCode(result.CodeGasLimitTooHigh)
+	}
+
 	zero := big.NewInt(0)
 	feeLimit := new(big.Int).Mul(tx.GasPrice, new(big.Int).SetUint64(tx.GasLimit))
 	if feeLimit.BitLen() > 255 || feeLimit.Cmp(zero) < 0 {
@@ -91,7 +98,7 @@ func (exec *SmartContractTxExecutor) process(chainID string, view *st.StoreView,
 	// Note: for contract deployment, vm.Execute() might transfer coins from the fromAccount to the
 	//       deployed smart contract. Thus, we should call vm.Execute() before calling getInput().
 	//       Otherwise, the fromAccount returned by getInput() will have incorrect balance.
-	_, _, gasUsed, _ := vm.Execute(tx, view)
+	evmRet, contractAddr, gasUsed, evmErr := vm.Execute(tx, view)
 
 	fromAddress := tx.From.Address
 	fromAccount, success := getInput(view, tx.From)
@@ -104,7 +111,7 @@ func (exec *SmartContractTxExecutor) process(chainID string, view *st.StoreView,
 		ThetaWei: big.NewInt(int64(0)),
 		TFuelWei: feeAmount,
 	}
-	if !chargeFee(fromAccount, fee) {
+	if !chargeFee(view, fromAccount, fee) {
 		return common.Hash{}, result.Error("failed to charge transaction fee")
 	}
 
@@ -115,7 +122,21 @@ func (exec *SmartContractTxExecutor) process(chainID string, view *st.StoreView,
 	view.SetAccount(fromAddress, fromAccount)
 
 	txHash := types.TxID(chainID, tx)
-	return txHash, result.OK
+
+	// Logs of a failed execution are discarded along with its state changes.
+	logs := view.PopLogs()
+	receipt := &types.Receipt{
+		EvmRet:          evmRet,
+		ContractAddress: contractAddr,
+		GasUsed:         gasUsed,
+	}
+	if evmErr != nil {
+		receipt.EvmErr = evmErr.Error()
+	} else {
+		receipt.Logs = logs
+	}
+
+	return txHash, result.OKWith(result.Info{"receipt": receipt})
 }
 
 func (exec *SmartContractTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
@@ -124,6 +145,8 @@ func (exec *SmartContractTxExecutor) getTxInfo(transaction types.Tx) *core.TxInf
 		Address:           tx.From.Address,
 		Sequence:          tx.From.Sequence,
 		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               new(big.Int).Mul(tx.GasPrice, new(big.Int).SetUint64(tx.GasLimit)),
+		Gas:               tx.GasLimit,
 	}
 }
 
@@ -48,37 +48,41 @@ func (exec *SplitRuleTxExecutor) sanityCheck(chainID string, view *st.StoreView,
 		return res
 	}
 
-	if !sanityCheckForFee(tx.Fee) {
+	if !sanityCheckForFee(view, tx.Fee) {
 		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
-			types.MinimumTransactionFeeTFuelWei).WithErrorCode(result.CodeInvalidFee)
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
 	}
 
 	minimalBalance := tx.Fee
 	if !initiatorAccount.Balance.IsGTE(minimalBalance) {
 		logger.Infof(fmt.Sprintf("the contract initiator did not have enough to cover the fee %X", tx.Initiator.Address))
-		return result.Error("the contract initiator account balance is %v, but required minimal balance is %v", initiatorAccount.Balance, minimalBalance)
+		return result.Error("the contract initiator account balance is %v, but required minimal balance is %v", initiatorAccount.Balance, minimalBalance).
+			WithErrorCode(result.CodeInsufficientFund)
 	}
 
 	numAccountsAffected := len(tx.Splits) + 1
 	if numAccountsAffected > types.MaxAccountsAffectedPerTx {
 		return result.Error("This allows one trasaction to modify many accounts. At most %v accounts are allowed per transaction.",
-			types.MaxAccountsAffectedPerTx)
+			types.MaxAccountsAffectedPerTx).WithErrorCode(result.CodeTooManyAccounts)
 	}
 
 	totalPercentage := uint(0)
 	for _, split := range tx.Splits {
 		percentage := split.Percentage
 		if percentage < 0 {
-			return result.Error("Percentage needs to be positive")
+			return result.Error("Percentage needs to be positive").
+				WithErrorCode(result.CodeInvalidSplitRule)
 		}
 		if percentage > 100 {
-			return result.Error("Percentage needs to be less than 100")
+			return result.Error("Percentage needs to be less than 100").
+				WithErrorCode(result.CodeInvalidSplitRule)
 		}
 		totalPercentage += percentage
 	}
 
 	if totalPercentage > 100 {
-		return result.Error("Sum of the percentages should be at most 100")
+		return result.Error("Sum of the percentages should be at most 100").
+			WithErrorCode(result.CodeInvalidSplitRule)
 	}
 
 	resourceID := tx.ResourceID
@@ -131,7 +135,7 @@ func (exec *SplitRuleTxExecutor) process(chainID string, view *st.StoreView, tra
 		return common.Hash{}, result.Error("failed to add or update split rule")
 	}
 
-	if !chargeFee(initiatorAccount, tx.Fee) {
+	if !chargeFee(view, initiatorAccount, tx.Fee) {
 		return common.Hash{}, result.Error("failed to charge transaction fee")
 	}
 
@@ -148,6 +152,8 @@ func (exec *SplitRuleTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
 		Address:           tx.Initiator.Address,
 		Sequence:          tx.Initiator.Sequence,
 		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasSplitRuleTx,
 	}
 }
 
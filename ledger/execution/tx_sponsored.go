@@ -0,0 +1,186 @@
+package execution
+
+import (
+	"encoding/hex"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*SponsoredTxExecutor)(nil)
+
+// ------------------------------- Sponsored Transaction -----------------------------------
+
+//
+// SponsoredTxExecutor implements the TxExecutor interface. The sponsor pays the fee of the
+// inner transaction: the max fee of the inner transaction is transferred from the sponsor to the
+// sender before the inner transaction is executed, and the part of it the inner transaction did
+// not consume, e.g. the unused gas of a smart contract call, is transferred back afterwards.
+//
+type SponsoredTxExecutor struct {
+	state    *st.LedgerState
+	executor *Executor
+}
+
+// NewSponsoredTxExecutor creates a new instance of SponsoredTxExecutor
+func NewSponsoredTxExecutor(state *st.LedgerState, executor *Executor) *SponsoredTxExecutor {
+	return &SponsoredTxExecutor{
+		state:    state,
+		executor: executor,
+	}
+}
+
+func (exec *SponsoredTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.SponsoredTx)
+
+	if tx.Sponsor.IsEmpty() {
+		return result.Error("Sponsor address cannot be empty").WithErrorCode(result.CodeInvalidSponsoredTx)
+	}
+	if tx.Signature == nil || tx.Signature.IsEmpty() {
+		return result.Error("Sponsor signature cannot be empty").WithErrorCode(result.CodeInvalidSignature)
+	}
+
+	inner, innerExec, res := exec.getInnerTx(tx)
+	if res.IsError() {
+		return res
+	}
+	if res := checkTxTypeActive(inner, core.RulesAt(exec.state.GetChainID(), view.Height()+1)); res.IsError() {
+		return res
+	}
+
+	sponsorAccount, res := getAccount(view, tx.Sponsor)
+	if res.IsError() {
+		return result.Error("Failed to get the sponsor account: %v", tx.Sponsor).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+	signBytes := tx.SignBytes(chainID)
+	if !tx.Signature.Verify(signBytes, tx.Sponsor) {
+		return result.Error("Signature verification failed, SignBytes: %v",
+			hex.EncodeToString(signBytes)).WithErrorCode(result.CodeInvalidSignature)
+	}
+
+	txInfo := innerExec.getTxInfo(inner)
+	fee := sponsoredFee(txInfo)
+	if !sponsorAccount.Balance.IsGTE(fee) {
+		return result.Error("Sponsor balance is %v, but required minimal balance is %v",
+			sponsorAccount.Balance, fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	// The inner transaction is checked as if the sender had received the fee
+	root := view.Snapshot()
+	defer view.RevertToSnapshot(root)
+	if res := transferFee(view, tx.Sponsor, txInfo.Address, fee); res.IsError() {
+		return res
+	}
+	return innerExec.sanityCheck(chainID, view, inner)
+}
+
+func (exec *SponsoredTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.SponsoredTx)
+
+	inner, innerExec, res := exec.getInnerTx(tx)
+	if res.IsError() {
+		return common.Hash{}, res
+	}
+
+	txInfo := innerExec.getTxInfo(inner)
+	fee := sponsoredFee(txInfo)
+	if res := transferFee(view, tx.Sponsor, txInfo.Address, fee); res.IsError() {
+		return common.Hash{}, res
+	}
+
+	_, res = innerExec.process(chainID, view, inner)
+	if res.IsError() {
+		return common.Hash{}, res
+	}
+
+	// A smart contract call only pays for the gas it used, the rest is returned to the sponsor
+	if sctx, ok := inner.(*types.SmartContractTx); ok {
+		if receipt, ok := res.Info["receipt"].(*types.Receipt); ok {
+			used := new(big.Int).Mul(sctx.GasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+			unused := types.Coins{
+				ThetaWei: big.NewInt(0),
+				TFuelWei: new(big.Int).Sub(fee.TFuelWei, used),
+			}
+			if unused.IsPositive() {
+				if res := transferFee(view, txInfo.Address, tx.Sponsor, unused); res.IsError() {
+					return common.Hash{}, res
+				}
+			}
+		}
+	}
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, res
+}
+
+// getInnerTx decodes the inner transaction of the sponsored transaction, and returns it together
+// with its executor.
+func (exec *SponsoredTxExecutor) getInnerTx(tx *types.SponsoredTx) (types.Tx, TxExecutor, result.Result) {
+	inner, err := tx.InnerTx()
+	if err != nil {
+		return nil, nil, result.Error("Failed to decode the sponsored transaction: %v", err).
+			WithErrorCode(result.CodeInvalidSponsoredTx)
+	}
+	switch inner.(type) {
+	case *types.CoinbaseTx, *types.SlashTx, *types.SponsoredTx:
+		return nil, nil, result.Error("Transaction type %T cannot be sponsored", inner).
+			WithErrorCode(result.CodeInvalidSponsoredTx)
+	}
+	innerExec := exec.executor.getTxExecutor(inner)
+	if innerExec == nil {
+		return nil, nil, result.Error("Unknown tx type").WithErrorCode(result.CodeUnknownTxType)
+	}
+	return inner, innerExec, result.OK
+}
+
+// sponsoredFee returns the max fee of the inner transaction, which the sponsor pays.
+func sponsoredFee(txInfo *core.TxInfo) types.Coins {
+	fee := txInfo.Fee
+	if fee == nil {
+		fee = big.NewInt(0)
+	}
+	return types.Coins{
+		ThetaWei: big.NewInt(0),
+		TFuelWei: new(big.Int).Set(fee),
+	}
+}
+
+// transferFee transfers the fee between the sponsor and the sender of the inner transaction.
+func transferFee(view *st.StoreView, from, to common.Address, fee types.Coins) result.Result {
+	fromAccount, res := getAccount(view, from)
+	if res.IsError() {
+		return res
+	}
+	if !fromAccount.Balance.IsGTE(fee) {
+		return result.Error("Failed to charge transaction fee").WithErrorCode(result.CodeInsufficientFund)
+	}
+	fromAccount.Balance = fromAccount.Balance.Minus(fee)
+	view.SetAccount(from, fromAccount)
+
+	toAccount, res := getAccount(view, to)
+	if res.IsError() {
+		return res
+	}
+	toAccount.Balance = toAccount.Balance.Plus(fee)
+	view.SetAccount(to, toAccount)
+	return result.OK
+}
+
+func (exec *SponsoredTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.SponsoredTx)
+	inner, innerExec, res := exec.getInnerTx(tx)
+	if res.IsError() {
+		return &core.TxInfo{
+			Address:           tx.Sponsor,
+			EffectiveGasPrice: big.NewInt(0),
+			Fee:               big.NewInt(0),
+		}
+	}
+	// Ordered with the other transactions of the sender, whose sequence the inner tx carries
+	return innerExec.getTxInfo(inner)
+}
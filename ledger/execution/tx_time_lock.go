@@ -0,0 +1,156 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*TimeLockTxExecutor)(nil)
+
+// ------------------------------- Time Lock Transaction -----------------------------------
+
+// TimeLockTxExecutor implements the TxExecutor interface
+type TimeLockTxExecutor struct {
+}
+
+// NewTimeLockTxExecutor creates a new instance of TimeLockTxExecutor
+func NewTimeLockTxExecutor() *TimeLockTxExecutor {
+	return &TimeLockTxExecutor{}
+}
+
+func (exec *TimeLockTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.TimeLockTx)
+
+	res := tx.Source.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	sourceAccount, success := getInput(view, tx.Source)
+	if success.IsError() {
+		return result.Error("Failed to get the source account: %v", tx.Source.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(sourceAccount, signBytes, tx.Source)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Source.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if tx.Recipient.IsEmpty() {
+		return result.Error("Recipient is not specified").WithErrorCode(result.CodeInvalidTimeLock)
+	}
+
+	if tx.ReleaseHeight == 0 && tx.ReleaseTime == 0 {
+		return result.Error("Neither the release height nor the release time is specified").
+			WithErrorCode(result.CodeInvalidTimeLock)
+	}
+	lock := types.TimeLock{ReleaseHeight: tx.ReleaseHeight, ReleaseTime: tx.ReleaseTime}
+	if lock.IsReleased(view.Height()+1, view.GetChainTime()) {
+		return result.Error("The release height %v and release time %v are already reached",
+			tx.ReleaseHeight, tx.ReleaseTime).WithErrorCode(result.CodeInvalidTimeLock)
+	}
+
+	coins := tx.Source.Coins.NoNil()
+	if !coins.IsValid() || !coins.IsPositive() {
+		return result.Error("Invalid coins to lock: %v", coins).WithErrorCode(result.CodeInvalidTimeLock)
+	}
+
+	minimalBalance := coins.Plus(tx.Fee)
+	if !sourceAccount.Balance.IsGTE(minimalBalance) {
+		return result.Error("Source balance is %v, but required minimal balance is %v",
+			sourceAccount.Balance, minimalBalance).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	return result.OK
+}
+
+func (exec *TimeLockTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.TimeLockTx)
+
+	sourceAccount, success := getInput(view, tx.Source)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the source account")
+	}
+
+	if !chargeFee(view, sourceAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	coins := tx.Source.Coins.NoNil()
+	if !sourceAccount.Balance.IsGTE(coins) {
+		return common.Hash{}, result.Error("Not enough balance to lock")
+	}
+	sourceAccount.Balance = sourceAccount.Balance.Minus(coins)
+	sourceAccount.Sequence++
+	view.SetAccount(tx.Source.Address, sourceAccount)
+
+	escrowAccount := getOrMakeAccount(view, types.TimeLockEscrowAddress)
+	escrowAccount.Balance = escrowAccount.Balance.Plus(coins)
+	view.SetAccount(types.TimeLockEscrowAddress, escrowAccount)
+
+	timeLock := &types.TimeLock{
+		ID:            view.GetNextTimeLockID(),
+		Source:        tx.Source.Address,
+		Recipient:     tx.Recipient,
+		Revoker:       tx.Revoker,
+		Coins:         coins,
+		LockHeight:    view.Height() + 1,
+		ReleaseHeight: tx.ReleaseHeight,
+		ReleaseTime:   tx.ReleaseTime,
+	}
+	view.SetTimeLock(timeLock)
+	view.SetNextTimeLockID(timeLock.ID + 1)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OKWith(result.Info{"lock_id": timeLock.ID})
+}
+
+func (exec *TimeLockTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.TimeLockTx)
+	return &core.TxInfo{
+		Address:           tx.Source.Address,
+		Sequence:          tx.Source.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasTimeLockTx,
+	}
+}
+
+func (exec *TimeLockTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.TimeLockTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasTimeLockTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
+
+// releaseTimeLock transfers the coins of the time lock from the escrow to the given account and
+// deletes the time lock.
+func releaseTimeLock(view *st.StoreView, timeLock *types.TimeLock, to common.Address) result.Result {
+	escrowAccount := getOrMakeAccount(view, types.TimeLockEscrowAddress)
+	if !escrowAccount.Balance.IsGTE(timeLock.Coins) {
+		return result.Error("Not enough balance in the time lock escrow")
+	}
+	escrowAccount.Balance = escrowAccount.Balance.Minus(timeLock.Coins)
+	view.SetAccount(types.TimeLockEscrowAddress, escrowAccount)
+
+	toAccount := getOrMakeAccount(view, to)
+	toAccount.Balance = toAccount.Balance.Plus(timeLock.Coins)
+	view.SetAccount(to, toAccount)
+
+	view.DeleteTimeLock(timeLock.ID)
+	return result.OK
+}
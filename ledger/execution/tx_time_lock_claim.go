@@ -0,0 +1,116 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*TimeLockClaimTxExecutor)(nil)
+
+// ------------------------------- Time Lock Claim Transaction -----------------------------------
+
+// TimeLockClaimTxExecutor implements the TxExecutor interface
+type TimeLockClaimTxExecutor struct {
+}
+
+// NewTimeLockClaimTxExecutor creates a new instance of TimeLockClaimTxExecutor
+func NewTimeLockClaimTxExecutor() *TimeLockClaimTxExecutor {
+	return &TimeLockClaimTxExecutor{}
+}
+
+func (exec *TimeLockClaimTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.TimeLockClaimTx)
+
+	res := tx.Recipient.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	recipientAccount, success := getInput(view, tx.Recipient)
+	if success.IsError() {
+		return result.Error("Failed to get the recipient account: %v", tx.Recipient.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(recipientAccount, signBytes, tx.Recipient)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Recipient.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !recipientAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Recipient balance is %v, but required minimal balance is %v",
+			recipientAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	timeLock := view.GetTimeLock(tx.LockID)
+	if timeLock == nil {
+		return result.Error("Time lock %v not found", tx.LockID).WithErrorCode(result.CodeTimeLockNotFound)
+	}
+	if timeLock.Recipient != tx.Recipient.Address {
+		return result.Error("%v is not the recipient of time lock %v", tx.Recipient.Address.Hex(), tx.LockID).
+			WithErrorCode(result.CodeUnauthorizedTimeLock)
+	}
+	if !timeLock.IsReleased(view.Height()+1, view.GetChainTime()) {
+		return result.Error("Time lock %v is not released before height %v and time %v",
+			tx.LockID, timeLock.ReleaseHeight, timeLock.ReleaseTime).WithErrorCode(result.CodeTimeLockNotReleased)
+	}
+
+	return result.OK
+}
+
+func (exec *TimeLockClaimTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.TimeLockClaimTx)
+
+	recipientAccount, success := getInput(view, tx.Recipient)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the recipient account")
+	}
+
+	if !chargeFee(view, recipientAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+	recipientAccount.Sequence++
+	view.SetAccount(tx.Recipient.Address, recipientAccount)
+
+	timeLock := view.GetTimeLock(tx.LockID)
+	if timeLock == nil {
+		return common.Hash{}, result.Error("Time lock %v not found", tx.LockID)
+	}
+	if res := releaseTimeLock(view, timeLock, timeLock.Recipient); res.IsError() {
+		return common.Hash{}, res
+	}
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *TimeLockClaimTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.TimeLockClaimTx)
+	return &core.TxInfo{
+		Address:           tx.Recipient.Address,
+		Sequence:          tx.Recipient.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasTimeLockClaimTx,
+	}
+}
+
+func (exec *TimeLockClaimTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.TimeLockClaimTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasTimeLockClaimTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
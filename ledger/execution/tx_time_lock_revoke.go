@@ -0,0 +1,116 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*TimeLockRevokeTxExecutor)(nil)
+
+// ------------------------------- Time Lock Revoke Transaction -----------------------------------
+
+// TimeLockRevokeTxExecutor implements the TxExecutor interface
+type TimeLockRevokeTxExecutor struct {
+}
+
+// NewTimeLockRevokeTxExecutor creates a new instance of TimeLockRevokeTxExecutor
+func NewTimeLockRevokeTxExecutor() *TimeLockRevokeTxExecutor {
+	return &TimeLockRevokeTxExecutor{}
+}
+
+func (exec *TimeLockRevokeTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.TimeLockRevokeTx)
+
+	res := tx.Revoker.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	revokerAccount, success := getInput(view, tx.Revoker)
+	if success.IsError() {
+		return result.Error("Failed to get the revoker account: %v", tx.Revoker.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(revokerAccount, signBytes, tx.Revoker)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Revoker.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !revokerAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Revoker balance is %v, but required minimal balance is %v",
+			revokerAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	timeLock := view.GetTimeLock(tx.LockID)
+	if timeLock == nil {
+		return result.Error("Time lock %v not found", tx.LockID).WithErrorCode(result.CodeTimeLockNotFound)
+	}
+	if !timeLock.IsRevocable() || timeLock.Revoker != tx.Revoker.Address {
+		return result.Error("%v is not the revoker of time lock %v", tx.Revoker.Address.Hex(), tx.LockID).
+			WithErrorCode(result.CodeUnauthorizedTimeLock)
+	}
+	// Once released, the coins belong to the recipient
+	if timeLock.IsReleased(view.Height()+1, view.GetChainTime()) {
+		return result.Error("Time lock %v is already released", tx.LockID).WithErrorCode(result.CodeInvalidTimeLock)
+	}
+
+	return result.OK
+}
+
+func (exec *TimeLockRevokeTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.TimeLockRevokeTx)
+
+	revokerAccount, success := getInput(view, tx.Revoker)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the revoker account")
+	}
+
+	if !chargeFee(view, revokerAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+	revokerAccount.Sequence++
+	view.SetAccount(tx.Revoker.Address, revokerAccount)
+
+	timeLock := view.GetTimeLock(tx.LockID)
+	if timeLock == nil {
+		return common.Hash{}, result.Error("Time lock %v not found", tx.LockID)
+	}
+	if res := releaseTimeLock(view, timeLock, timeLock.Source); res.IsError() {
+		return common.Hash{}, res
+	}
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *TimeLockRevokeTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.TimeLockRevokeTx)
+	return &core.TxInfo{
+		Address:           tx.Revoker.Address,
+		Sequence:          tx.Revoker.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasTimeLockRevokeTx,
+	}
+}
+
+func (exec *TimeLockRevokeTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.TimeLockRevokeTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasTimeLockRevokeTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
@@ -0,0 +1,130 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*UndelegateTxExecutor)(nil)
+
+// ------------------------------- Undelegate Transaction -----------------------------------
+
+// UndelegateTxExecutor implements the TxExecutor interface
+type UndelegateTxExecutor struct {
+	state *st.LedgerState
+}
+
+// NewUndelegateTxExecutor creates a new instance of UndelegateTxExecutor
+func NewUndelegateTxExecutor(state *st.LedgerState) *UndelegateTxExecutor {
+	return &UndelegateTxExecutor{
+		state: state,
+	}
+}
+
+func (exec *UndelegateTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.UndelegateTx)
+
+	res := tx.Delegator.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	delegatorAccount, res := getInput(view, tx.Delegator)
+	if res.IsError() {
+		return result.Error("Failed to get the delegator account: %v", tx.Delegator.Address).
+			WithErrorCode(result.CodeAccountNotFound)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(delegatorAccount, signBytes, tx.Delegator)
+	if res.IsError() {
+		logger.Infof("validateSourceAdvanced failed on %v: %v", tx.Delegator.Address.Hex(), res)
+		return res
+	}
+
+	if !sanityCheckForFee(view, tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !delegatorAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Undelegate: Delegator balance is %v, but required minimal balance is %v",
+			delegatorAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	return result.OK
+}
+
+// NOTE: UndelegateTxExecutor.process() does NOT return the stake to the delegator. Like a withdrawn
+//       stake, the stake is returned when the block height reaches the return height, and it is
+//       moved to the unbonding queue since the unbonding queue upgrade.
+func (exec *UndelegateTxExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.UndelegateTx)
+
+	delegatorAccount, res := getInput(view, tx.Delegator)
+	if res.IsError() {
+		return common.Hash{}, result.Error("Failed to get the delegator account")
+	}
+
+	if !chargeFee(view, delegatorAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return common.Hash{}, result.Error("No delegation to %v is found", tx.Validator).
+			WithErrorCode(result.CodeInvalidDelegation)
+	}
+	if core.RulesAt(exec.state.GetChainID(), view.Height()+1).IsActive(core.UpgradeUnbondingQueue) {
+		delegation, err := vcp.UnbondDelegation(tx.Delegator.Address, tx.Validator)
+		if err != nil {
+			return common.Hash{}, result.Error("Failed to undelegate stake, err: %v", err).
+				WithErrorCode(result.CodeInvalidDelegation)
+		}
+		addToUnbondingQueue(view, delegation, tx.Validator, core.StakeForValidator, true)
+	} else {
+		err := vcp.Undelegate(tx.Delegator.Address, tx.Validator, view.Height())
+		if err != nil {
+			return common.Hash{}, result.Error("Failed to undelegate stake, err: %v", err).
+				WithErrorCode(result.CodeInvalidDelegation)
+		}
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+
+	hl := view.GetStakeTransactionHeightList()
+	if hl == nil {
+		hl = &types.HeightList{}
+	}
+	hl.Append(view.Height())
+	view.UpdateStakeTransactionHeightList(hl)
+
+	delegatorAccount.Sequence++
+	view.SetAccount(tx.Delegator.Address, delegatorAccount)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *UndelegateTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.UndelegateTx)
+	return &core.TxInfo{
+		Address:           tx.Delegator.Address,
+		Sequence:          tx.Delegator.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasUndelegateTx,
+	}
+}
+
+func (exec *UndelegateTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.UndelegateTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasUndelegateTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
@@ -37,7 +37,8 @@ func (exec *WithdrawStakeExecutor) sanityCheck(chainID string, view *st.StoreVie
 
 	sourceAccount, success := getInput(view, tx.Source)
 	if success.IsError() {
-		return result.Error("Failed to get the source account: %v", tx.Source.Address)
+		return result.Error("Failed to get the source account: %v", tx.Source.Address).
+			WithErrorCode(result.CodeAccountNotFound)
 	}
 
 	signBytes := tx.SignBytes(chainID)
@@ -47,9 +48,9 @@ func (exec *WithdrawStakeExecutor) sanityCheck(chainID string, view *st.StoreVie
 		return res
 	}
 
-	if !sanityCheckForFee(tx.Fee) {
+	if !sanityCheckForFee(view, tx.Fee) {
 		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
-			types.MinimumTransactionFeeTFuelWei).WithErrorCode(result.CodeInvalidFee)
+			minimumTransactionFee(view)).WithErrorCode(result.CodeInvalidFee)
 	}
 
 	if !(tx.Purpose == core.StakeForValidator || tx.Purpose == core.StakeForGuardian) {
@@ -61,7 +62,7 @@ func (exec *WithdrawStakeExecutor) sanityCheck(chainID string, view *st.StoreVie
 	if !sourceAccount.Balance.IsGTE(minimalBalance) {
 		logger.Infof(fmt.Sprintf("WithdrawStake: Source did not have enough balance %v", tx.Source.Address.Hex()))
 		return result.Error("WithdrawStake: Source balance is %v, but required minimal balance is %v",
-			sourceAccount.Balance, minimalBalance)
+			sourceAccount.Balance, minimalBalance).WithErrorCode(result.CodeInsufficientFund)
 	}
 
 	return result.OK
@@ -69,7 +70,8 @@ func (exec *WithdrawStakeExecutor) sanityCheck(chainID string, view *st.StoreVie
 
 // NOTE: WithdrawStakeExecutor.process() does NOT return the stake to the source. Instead, it updates
 //       the ReturnHeight of the withdrawn stake. The stake will be returned to the source when
-//       the block height reaches the ReturnHeigth
+//       the block height reaches the ReturnHeigth. Since the unbonding queue upgrade, the withdrawn
+//       stake is moved to the unbonding queue instead, with the ReturnHeight set by the chain params
 func (exec *WithdrawStakeExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
 	tx := transaction.(*types.WithdrawStakeTx)
 
@@ -78,14 +80,19 @@ func (exec *WithdrawStakeExecutor) process(chainID string, view *st.StoreView, t
 		return common.Hash{}, result.Error("Failed to get the source account")
 	}
 
-	if !chargeFee(sourceAccount, tx.Fee) {
+	if !chargeFee(view, sourceAccount, tx.Fee) {
 		return common.Hash{}, result.Error("Failed to charge transaction fee")
 	}
 
 	sourceAddress := tx.Source.Address
 	holderAddress := tx.Holder.Address
 
-	if tx.Purpose == core.StakeForValidator {
+	if core.RulesAt(exec.state.GetChainID(), view.Height()+1).IsActive(core.UpgradeUnbondingQueue) {
+		res := exec.unbondStake(view, sourceAddress, holderAddress, tx.Purpose)
+		if res.IsError() {
+			return common.Hash{}, res
+		}
+	} else if tx.Purpose == core.StakeForValidator {
 		vcp := view.GetValidatorCandidatePool()
 		currentHeight := exec.state.Height()
 		err := vcp.WithdrawStake(sourceAddress, holderAddress, currentHeight)
@@ -94,7 +101,13 @@ func (exec *WithdrawStakeExecutor) process(chainID string, view *st.StoreView, t
 		}
 		view.UpdateValidatorCandidatePool(vcp)
 	} else if tx.Purpose == core.StakeForGuardian {
-		return common.Hash{}, result.Error("Withdraw stake for guardian not supported yet")
+		gcp := view.GetGuardianCandidatePool()
+		currentHeight := exec.state.Height()
+		err := gcp.WithdrawStake(sourceAddress, holderAddress, currentHeight)
+		if err != nil {
+			return common.Hash{}, result.Error("Failed to withdraw stake, err: %v", err)
+		}
+		view.UpdateGuardianCandidatePool(gcp)
 	} else {
 		return common.Hash{}, result.Error("Invalid staking purpose").WithErrorCode(result.CodeInvalidStakePurpose)
 	}
@@ -113,12 +126,43 @@ func (exec *WithdrawStakeExecutor) process(chainID string, view *st.StoreView, t
 	return txHash, result.OK
 }
 
+// unbondStake removes the stake from the candidate pool and adds it to the unbonding queue.
+func (exec *WithdrawStakeExecutor) unbondStake(view *st.StoreView, source common.Address, holder common.Address, purpose uint8) result.Result {
+	var stake *core.Stake
+	var err error
+	if purpose == core.StakeForValidator {
+		vcp := view.GetValidatorCandidatePool()
+		if vcp == nil {
+			return result.Error("Failed to withdraw stake, no validator candidate found")
+		}
+		stake, err = vcp.UnbondStake(source, holder)
+		if err != nil {
+			return result.Error("Failed to withdraw stake, err: %v", err)
+		}
+		view.UpdateValidatorCandidatePool(vcp)
+	} else if purpose == core.StakeForGuardian {
+		gcp := view.GetGuardianCandidatePool()
+		stake, err = gcp.UnbondStake(source, holder)
+		if err != nil {
+			return result.Error("Failed to withdraw stake, err: %v", err)
+		}
+		view.UpdateGuardianCandidatePool(gcp)
+	} else {
+		return result.Error("Invalid staking purpose").WithErrorCode(result.CodeInvalidStakePurpose)
+	}
+
+	addToUnbondingQueue(view, stake, holder, purpose, false)
+	return result.OK
+}
+
 func (exec *WithdrawStakeExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
 	tx := transaction.(*types.WithdrawStakeTx)
 	return &core.TxInfo{
 		Address:           tx.Source.Address,
 		Sequence:          tx.Source.Sequence,
 		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		Fee:               tx.Fee.TFuelWei,
+		Gas:               types.GasWidthdrawStakeTx,
 	}
 }
 
@@ -0,0 +1,222 @@
+package ledger
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+//
+// InvariantReport is the result of checking the invariants of the ledger state, i.e. the properties
+// every state reachable by applying valid blocks should have: the balances and the reserved funds
+// are non-negative, the TFuel held by the accounts matches the supply accounting, and the stake
+// pools and the unbonding queue are well formed.
+//
+type InvariantReport struct {
+	Height      uint64
+	StateRoot   common.Hash
+	NumAccounts int
+	TotalTFuel  *big.Int           // TFuel of all the accounts, including their reserved funds
+	Supply      *types.TFuelSupply // nil before the supply accounting upgrade
+	Violations  []error
+}
+
+// OK returns whether none of the invariants is violated.
+func (r *InvariantReport) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// UnaccountedTFuel returns the TFuel held by the accounts in excess of the supply accounting, which
+// is the TFuel minted for the coins arriving from the counterpart chains of the bridge, or nil if
+// the supply is not accounted.
+func (r *InvariantReport) UnaccountedTFuel() *big.Int {
+	if r.Supply == nil {
+		return nil
+	}
+	return new(big.Int).Sub(r.TotalTFuel, r.Supply.Total)
+}
+
+func (r *InvariantReport) violation(format string, args ...interface{}) {
+	r.Violations = append(r.Violations, fmt.Errorf(format, args...))
+}
+
+// CheckInvariants checks the invariants of the ledger state of the given view. It traverses the
+// whole account state.
+func CheckInvariants(view *st.StoreView) *InvariantReport {
+	report := &InvariantReport{
+		Height:     view.Height(),
+		StateRoot:  view.Hash(),
+		TotalTFuel: big.NewInt(0),
+		Supply:     view.GetTFuelSupply(),
+	}
+	checkAccounts(view, report)
+	checkTFuelSupply(report)
+	checkValidatorCandidatePool(view, report)
+	checkGuardianCandidatePool(view, report)
+	checkUnbondingQueue(view, report)
+	return report
+}
+
+// checkAccounts checks the balances and the reserved funds of the accounts, and sums their TFuel
+// the same way as StoreView.TotalTFuel.
+func checkAccounts(view *st.StoreView, report *InvariantReport) {
+	view.GetStore().Traverse(st.AccountKeyPrefix(), func(key, value common.Bytes) bool {
+		report.NumAccounts++
+		address := common.BytesToAddress(key[len(st.AccountKeyPrefix()):])
+		account := &types.Account{}
+		if err := types.FromBytes(value, account); err != nil {
+			report.violation("Failed to decode account %v: %v", address, err)
+			return true
+		}
+		if !account.Balance.IsNonnegative() {
+			report.violation("Negative balance of account %v: %v", address, account.Balance)
+		}
+		report.TotalTFuel.Add(report.TotalTFuel, account.Balance.NoNil().TFuelWei)
+
+		for _, reservedFund := range account.ReservedFunds {
+			if !reservedFund.InitialFund.IsNonnegative() || !reservedFund.UsedFund.IsNonnegative() ||
+				!reservedFund.Collateral.IsNonnegative() {
+				report.violation("Negative reserved fund %v of account %v: initial %v, used %v, collateral %v", reservedFund.ReserveSequence,
+					address, reservedFund.InitialFund, reservedFund.UsedFund, reservedFund.Collateral)
+			}
+			if !reservedFund.InitialFund.IsGTE(reservedFund.UsedFund) {
+				report.violation("Overspent reserved fund %v of account %v: initial %v, used %v", reservedFund.ReserveSequence,
+					address, reservedFund.InitialFund, reservedFund.UsedFund)
+			}
+			remaining := reservedFund.InitialFund.Minus(reservedFund.UsedFund).Plus(reservedFund.Collateral)
+			report.TotalTFuel.Add(report.TotalTFuel, remaining.NoNil().TFuelWei)
+		}
+		return true
+	})
+}
+
+// checkTFuelSupply checks the supply accounting against the TFuel held by the accounts. The
+// accounts may hold more, since the TFuel minted for the bridge messages is not accounted.
+func checkTFuelSupply(report *InvariantReport) {
+	supply := report.Supply
+	if supply == nil {
+		return
+	}
+	for _, amount := range []*big.Int{supply.Total, supply.Minted, supply.Burned, supply.BlockMinted, supply.BlockBurned} {
+		if amount == nil || amount.Sign() < 0 {
+			report.violation("Invalid TFuel supply: %v", supply)
+			return
+		}
+	}
+	if supply.BlockMinted.Cmp(supply.Minted) > 0 || supply.BlockBurned.Cmp(supply.Burned) > 0 {
+		report.violation("TFuel changes of the block exceed the accumulated changes: minted %v of %v, burned %v of %v",
+			supply.BlockMinted, supply.Minted, supply.BlockBurned, supply.Burned)
+	}
+	if report.TotalTFuel.Cmp(supply.Total) < 0 {
+		report.violation("Accounts hold less TFuel than the total supply: %v < %v", report.TotalTFuel, supply.Total)
+	}
+}
+
+func checkValidatorCandidatePool(view *st.StoreView, report *InvariantReport) {
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return
+	}
+	holders := make(map[common.Address]bool)
+	for idx, candidate := range vcp.SortedCandidates {
+		if holders[candidate.Holder] {
+			report.violation("Duplicate validator candidate %v", candidate.Holder)
+		}
+		holders[candidate.Holder] = true
+		if len(candidate.Stakes) == 0 && len(candidate.Delegations) == 0 {
+			report.violation("Validator candidate %v holds no stake", candidate.Holder)
+		}
+		if idx > 0 && vcp.SortedCandidates[idx-1].TotalStake().Cmp(candidate.TotalStake()) < 0 {
+			report.violation("Validator candidate %v is not sorted by stake", candidate.Holder)
+		}
+		checkStakes(view, report, "validator candidate", candidate.Holder, candidate.Stakes)
+		checkStakes(view, report, "delegation to validator candidate", candidate.Holder, candidate.Delegations)
+	}
+}
+
+func checkGuardianCandidatePool(view *st.StoreView, report *InvariantReport) {
+	gcp := view.GetGuardianCandidatePool()
+	for idx, guardian := range gcp.SortedGuardians {
+		if idx > 0 && bytes.Compare(gcp.SortedGuardians[idx-1].Holder.Bytes(), guardian.Holder.Bytes()) >= 0 {
+			report.violation("Guardian %v is duplicate or not sorted by address", guardian.Holder)
+		}
+		if guardian.Pubkey == nil {
+			report.violation("Guardian %v has no public key", guardian.Holder)
+		}
+		if len(guardian.Stakes) == 0 {
+			report.violation("Guardian %v holds no stake", guardian.Holder)
+		}
+		checkStakes(view, report, "guardian", guardian.Holder, guardian.Stakes)
+	}
+}
+
+// checkStakes checks the stakes of a holder. Each source has at most one stake, which has a return
+// height once withdrawn, and the stake can be returned to the source account.
+func checkStakes(view *st.StoreView, report *InvariantReport, kind string, holder common.Address, stakes []*core.Stake) {
+	sources := make(map[common.Address]bool)
+	for _, stake := range stakes {
+		if sources[stake.Source] {
+			report.violation("Duplicate stake of %v for %v %v", stake.Source, kind, holder)
+		}
+		sources[stake.Source] = true
+		if stake.Amount == nil || stake.Amount.Sign() < 0 {
+			report.violation("Invalid stake of %v for %v %v: %v", stake.Source, kind, holder, stake.Amount)
+		}
+		if stake.Withdrawn == (stake.ReturnHeight == core.InvalidReturnHeight) {
+			report.violation("Inconsistent withdrawal of the stake of %v for %v %v: withdrawn %v, return height %v",
+				stake.Source, kind, holder, stake.Withdrawn, stake.ReturnHeight)
+		}
+		if view.GetAccount(stake.Source) == nil {
+			report.violation("Missing source account %v of the stake for %v %v", stake.Source, kind, holder)
+		}
+	}
+}
+
+// checkUnbondingQueue checks that the unbonding queue is ordered by return height, and that its
+// entries can be returned to their source accounts.
+func checkUnbondingQueue(view *st.StoreView, report *InvariantReport) {
+	queue := view.GetUnbondingQueue()
+	for idx, entry := range queue.Entries {
+		if idx > 0 && queue.Entries[idx-1].ReturnHeight > entry.ReturnHeight {
+			report.violation("Unbonding entry is not sorted by return height: %v", entry)
+		}
+		if entry.Amount == nil || entry.Amount.Sign() < 0 {
+			report.violation("Invalid amount of unbonding entry: %v", entry)
+		}
+		if entry.ReturnHeight < entry.StartHeight {
+			report.violation("Unbonding entry returns before it starts: %v", entry)
+		}
+		if entry.Purpose != core.StakeForValidator && (entry.Purpose != core.StakeForGuardian || entry.Delegated) {
+			report.violation("Invalid purpose of unbonding entry: %v", entry)
+		}
+		if view.GetAccount(entry.Source) == nil {
+			report.violation("Missing source account of unbonding entry: %v", entry)
+		}
+	}
+}
+
+// checkBlockInvariants checks the invariants of the state after a block is applied, and that the
+// TFuel held by the accounts only changed by the TFuel minted and burned by the block. The
+// violations are logged, the block is applied regardless.
+func (ledger *Ledger) checkBlockInvariants(view *st.StoreView, totalTFuelBefore *big.Int) *InvariantReport {
+	report := CheckInvariants(view)
+
+	minted, burned := view.GetBlockTFuelChanges()
+	expected := new(big.Int).Add(totalTFuelBefore, minted)
+	expected.Sub(expected, burned)
+	expected.Add(expected, view.GetBlockBridgedTFuel())
+	if report.TotalTFuel.Cmp(expected) != 0 {
+		report.violation("TFuel is not conserved: %v before the block, %v minted, %v burned, %v bridged, %v after",
+			totalTFuelBefore, minted, burned, view.GetBlockBridgedTFuel(), report.TotalTFuel)
+	}
+
+	for _, violation := range report.Violations {
+		logger.Errorf("Ledger invariant violated by block %v: %v", view.Height()+1, violation)
+	}
+	return report
+}
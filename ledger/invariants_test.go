@@ -0,0 +1,152 @@
+package ledger
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto/bls"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func TestLedgerCheckInvariants(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	viper.Set(common.CfgLedgerCheckInvariants, true)
+	defer viper.Set(common.CfgLedgerCheckInvariants, false)
+
+	chainID, ledger, mempool := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 1)
+	height := ledger.state.Delivered().Height()
+
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeSupplyAccounting: height + 1}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	newStateRoot, blockTxs, res := ledger.ProposeBlockTxs(nil)
+	require.True(res.IsOK(), res.Message)
+	res = ledger.ApplyBlockTxs(blockTxs, nil, newStateRoot)
+	require.True(res.IsOK(), res.Message)
+
+	ledger.ResetState(height+1, newStateRoot)
+	sendTxBytes := newRawSendTx(core.SignatureDomain(chainID, height+2), 1, true, accOut, accIns[0], false)
+	require.Nil(mempool.InsertTransaction(sendTxBytes))
+	newStateRoot, blockTxs, res = ledger.ProposeBlockTxs(nil)
+	require.True(res.IsOK(), res.Message)
+	res = ledger.ApplyBlockTxs(blockTxs, nil, newStateRoot)
+	require.True(res.IsOK(), res.Message)
+
+	view := ledger.state.Delivered()
+	report := CheckInvariants(view)
+	assert.True(report.OK(), "%v", report.Violations)
+	assert.Equal(view.TotalTFuel(), report.TotalTFuel)
+	assert.Equal(0, report.UnaccountedTFuel().Sign())
+
+	// The TFuel held by the accounts only changes by the TFuel minted and burned by the block
+	totalTFuel := view.TotalTFuel()
+	view.ResetBlockChanges()
+	report = ledger.checkBlockInvariants(view, totalTFuel)
+	assert.True(report.OK(), "%v", report.Violations)
+	view.AddBurnedTFuel(big.NewInt(1))
+	report = ledger.checkBlockInvariants(view, totalTFuel)
+	require.Len(report.Violations, 1)
+	assert.Contains(report.Violations[0].Error(), "TFuel is not conserved")
+	view.AddBridgedTFuel(big.NewInt(1))
+	report = ledger.checkBlockInvariants(view, totalTFuel)
+	assert.True(report.OK(), "%v", report.Violations)
+
+	// The TFuel minted for the bridge messages is held by the accounts but not accounted
+	bridged := view.GetAccount(accOut.Address)
+	bridged.Balance = bridged.Balance.Plus(types.NewCoins(0, 10))
+	view.SetAccount(accOut.Address, bridged)
+	report = CheckInvariants(view)
+	assert.True(report.OK(), "%v", report.Violations)
+	assert.Equal(big.NewInt(10), report.UnaccountedTFuel())
+}
+
+func TestLedgerInvariantViolations(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, ledger, _ := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 2)
+	view := ledger.state.Delivered()
+	require.True(CheckInvariants(view).OK())
+
+	source := accIns[0].Address
+	holder := accIns[1].Address
+	missing := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	guardian := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	// Overspent reserved fund, a negative balance cannot even be encoded
+	account := view.GetAccount(accOut.Address)
+	account.ReservedFunds = []types.ReservedFund{{
+		Collateral:      types.NewCoins(0, 10),
+		InitialFund:     types.NewCoins(0, 5),
+		UsedFund:        types.NewCoins(0, 6),
+		ReserveSequence: 1,
+	}}
+	view.SetAccount(accOut.Address, account)
+
+	// Supply exceeding the TFuel held by the accounts
+	totalTFuel := view.TotalTFuel()
+	view.SetTFuelSupply(&types.TFuelSupply{
+		Total:       new(big.Int).Add(totalTFuel, big.NewInt(1)),
+		Minted:      big.NewInt(0),
+		Burned:      big.NewInt(0),
+		BlockMinted: big.NewInt(0),
+		BlockBurned: big.NewInt(0),
+	})
+
+	// Duplicate stake, withdrawn stake without return height, and stake of a missing source
+	vcp := &core.ValidatorCandidatePool{SortedCandidates: []*core.StakeHolder{{
+		Holder: holder,
+		Stakes: []*core.Stake{
+			{Source: source, Amount: big.NewInt(100), ReturnHeight: core.InvalidReturnHeight},
+			{Source: source, Amount: big.NewInt(100), Withdrawn: true, ReturnHeight: core.InvalidReturnHeight},
+			{Source: missing, Amount: big.NewInt(100), ReturnHeight: core.InvalidReturnHeight},
+		},
+	}}}
+	view.UpdateValidatorCandidatePool(vcp)
+
+	// Guardians not sorted by address
+	key, err := bls.RandKey()
+	require.Nil(err)
+	guardianStakes := []*core.Stake{{Source: source, Amount: big.NewInt(100), ReturnHeight: core.InvalidReturnHeight}}
+	gcp := &core.GuardianCandidatePool{SortedGuardians: []*core.Guardian{
+		{StakeHolder: &core.StakeHolder{Holder: guardian, Stakes: guardianStakes}, Pubkey: key.PublicKey()},
+		{StakeHolder: &core.StakeHolder{Holder: missing, Stakes: guardianStakes}, Pubkey: key.PublicKey()},
+	}}
+	view.UpdateGuardianCandidatePool(gcp)
+
+	// Unbonding queue out of order, with a delegated guardian stake
+	view.UpdateUnbondingQueue(&types.UnbondingQueue{Entries: []*types.UnbondingEntry{
+		{Source: source, Holder: holder, Amount: big.NewInt(100), StartHeight: 1, ReturnHeight: 20},
+		{Source: source, Holder: holder, Purpose: core.StakeForGuardian, Delegated: true, Amount: big.NewInt(100), StartHeight: 1, ReturnHeight: 10},
+	}})
+
+	report := CheckInvariants(view)
+	violations := []string{}
+	for _, violation := range report.Violations {
+		violations = append(violations, violation.Error())
+	}
+	expected := []string{
+		"Overspent reserved fund 1",
+		"Accounts hold less TFuel than the total supply",
+		"Duplicate stake of " + source.String(),
+		"Inconsistent withdrawal of the stake of " + source.String(),
+		"Missing source account " + missing.String(),
+		"Guardian " + missing.String() + " is duplicate or not sorted",
+		"Unbonding entry is not sorted by return height",
+		"Invalid purpose of unbonding entry",
+	}
+	require.Len(violations, len(expected), "%v", violations)
+	for i, prefix := range expected {
+		assert.True(strings.HasPrefix(violations[i], prefix), "%v does not start with %v", violations[i], prefix)
+	}
+}
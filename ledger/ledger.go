@@ -3,15 +3,21 @@ package ledger
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"sync"
+	"time"
 
+	"github.com/thetatoken/theta/blockchain"
 	"github.com/thetatoken/theta/store"
 	"github.com/thetatoken/theta/store/kvstore"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
 	exec "github.com/thetatoken/theta/ledger/execution"
@@ -21,7 +27,7 @@ import (
 	"github.com/thetatoken/theta/store/database"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "ledger"})
+var logger *log.Entry = util.GetLoggerForModule("ledger")
 
 var _ core.Ledger = (*Ledger)(nil)
 
@@ -85,7 +91,7 @@ func (ledger *Ledger) GetFinalizedSnapshot() (*st.StoreView, error) {
 // GetFinalizedValidatorCandidatePool returns the validator candidate pool of the latest DIRECTLY finalized block
 func (ledger *Ledger) GetFinalizedValidatorCandidatePool(blockHash common.Hash, isNext bool) (*core.ValidatorCandidatePool, error) {
 	db := ledger.state.DB()
-	store := kvstore.NewKVStore(db)
+	store := kvstore.NewKVStore(database.BlockDatabase(db))
 
 	var i int
 	if isNext {
@@ -115,47 +121,127 @@ func (ledger *Ledger) GetFinalizedValidatorCandidatePool(blockHash common.Hash,
 	return nil, fmt.Errorf("Failed to find a directly finalized ancestor block for %v", blockHash)
 }
 
-func findBlock(store store.Store, blockHash common.Hash) (*core.ExtendedBlock, error) {
-	var block core.ExtendedBlock
-	err := store.Get(blockHash[:], &block)
+// GetGuardianCandidatePool returns the guardian candidate pool as of the given block
+func (ledger *Ledger) GetGuardianCandidatePool(blockHash common.Hash) (*core.GuardianCandidatePool, error) {
+	db := ledger.state.DB()
+	store := kvstore.NewKVStore(database.BlockDatabase(db))
+
+	block, err := findBlock(store, blockHash)
 	if err != nil {
 		return nil, err
 	}
-	return &block, nil
+	if block == nil {
+		return nil, fmt.Errorf("Block is nil for hash %v", blockHash.Hex())
+	}
+
+	storeView := st.NewStoreView(block.Height, block.BlockHeader.StateHash, db)
+	return storeView.GetGuardianCandidatePool(), nil
 }
 
-// ScreenTx screens the given transaction
-func (ledger *Ledger) ScreenTx(rawTx common.Bytes) (txInfo *core.TxInfo, res result.Result) {
-	var tx types.Tx
-	tx, err := types.TxFromBytes(rawTx)
+// GetAccountProof returns the merkle proof of the account against the state root of the
+// finalized block at the given height, 0 meaning the last finalized block.
+func (ledger *Ledger) GetAccountProof(addr common.Address, height uint64) (*st.AccountProof, error) {
+	block, storeView, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := storeView.ProveAccount(addr)
 	if err != nil {
-		return nil, result.Error("Error decoding tx: %v", err)
+		return nil, err
 	}
+	proof.BlockHash = block.Hash()
+	return proof, nil
+}
 
-	if ledger.shouldSkipCheckTx(tx) {
-		return nil, result.Error("Unauthorized transaction, should skip").
-			WithErrorCode(result.CodeUnauthorizedTx)
+// GetStorageProof returns the merkle proof of the smart contract storage slot against the state
+// root of the finalized block at the given height, 0 meaning the last finalized block.
+func (ledger *Ledger) GetStorageProof(addr common.Address, key common.Hash, height uint64) (*st.StorageProof, error) {
+	block, storeView, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := storeView.ProveStorage(addr, key)
+	if err != nil {
+		return nil, err
 	}
+	proof.BlockHash = block.Hash()
+	return proof, nil
+}
 
-	ledger.mu.RLock()
-	defer ledger.mu.RUnlock()
+// finalizedStoreViewAt returns the finalized block at the given height and a read-only view of
+// its state, 0 meaning the last finalized block. The block is resolved through the block height
+// index of the chain. The states older than the pruning window are not available.
+func (ledger *Ledger) finalizedStoreViewAt(height uint64) (*core.ExtendedBlock, *st.StoreView, error) {
+	block := ledger.consensus.GetLastFinalizedBlock()
+	if height > block.Height {
+		return nil, nil, fmt.Errorf("Block height %v is not finalized yet, last finalized height: %v", height, block.Height)
+	}
 
-	_, res = ledger.executor.ScreenTx(tx)
-	if res.IsError() {
-		return nil, res
+	db := ledger.state.DB()
+	if height != 0 && height != block.Height {
+		store := kvstore.NewKVStore(database.BlockDatabase(db))
+		block = nil
+		for _, b := range blockchain.LoadBlockHeadersByHeight(store, height) {
+			if b.Status.IsFinalized() {
+				block = b
+				break
+			}
+		}
+		if block == nil {
+			return nil, nil, fmt.Errorf("Failed to find the finalized block at height %v", height)
+		}
 	}
 
-	txInfo, res = ledger.executor.GetTxInfo(tx)
-	if res.IsError() {
-		return nil, res
+	storeView := st.NewStoreView(block.Height, block.StateHash, db)
+	if storeView == nil {
+		return nil, nil, fmt.Errorf("State of block %v is not available", block.Hash().Hex())
+	}
+	return block, storeView, nil
+}
+
+func findBlock(store store.Store, blockHash common.Hash) (*core.ExtendedBlock, error) {
+	var block core.ExtendedBlock
+	err := store.Get(blockHash[:], &block)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// GetTFuelSupply returns the TFuel supply accounting of the finalized block at the given height,
+// 0 meaning the last finalized block, together with the block and the TFuel held by the escrows.
+func (ledger *Ledger) GetTFuelSupply(height uint64) (*core.ExtendedBlock, *types.TFuelSupply, *big.Int, error) {
+	block, storeView, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	supply := storeView.GetTFuelSupply()
+	if supply == nil {
+		return nil, nil, nil, fmt.Errorf("TFuel supply is not accounted at height %v", block.Height)
 	}
+	escrowed := big.NewInt(0)
+	for _, addr := range types.EscrowAddresses {
+		if account := storeView.GetAccount(addr); account != nil {
+			escrowed.Add(escrowed, account.Balance.NoNil().TFuelWei)
+		}
+	}
+	return block, supply, escrowed, nil
+}
 
+// ScreenTx screens the given transaction, and applies it to the screened view if valid
+func (ledger *Ledger) ScreenTx(rawTx common.Bytes) (txInfo *core.TxInfo, res result.Result) {
+	batch := ledger.NewScreenBatch()
+	txInfo, res = batch.ScreenTx(rawTx)
+	if res.IsOK() {
+		batch.Accept()
+		batch.Commit()
+	}
 	return txInfo, res
 }
 
 // ProposeBlockTxs collects and executes a list of transactions, which will be used to assemble the next blockl
-// It also clears these transactions from the mempool.
-func (ledger *Ledger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
+// with the given timestamp. It also clears these transactions from the mempool.
+func (ledger *Ledger) ProposeBlockTxs(timestamp *big.Int) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
 	// Must always acquire locks in following order to avoid deadlock: mempool, ledger.
 	// Otherwise, could cause deadlock since mempool.InsertTransaction() also first acquires the mempool, and then the ledger lock
 	ledger.mempool.Lock()
@@ -164,47 +250,152 @@ func (ledger *Ledger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs
 	ledger.mu.Lock()
 	defer ledger.mu.Unlock()
 
+	// Add regular transactions submitted by the clients, within the block limits of the chain params
+	params := ledger.state.Checked().GetChainParams()
+	regularRawTxs := ledger.mempool.ReapWithinLimitsUnsafe(int(params.MaxNumRegularTxsPerBlock),
+		params.MaxBlockGas, params.MaxBlockBytes)
+
+	return ledger.proposeBlockTxs(timestamp, regularRawTxs, false)
+}
+
+// PrepareBlockTxs selects the regular transactions of a block to be proposed later, without
+// removing them from the mempool. It allows the proposer to assemble the transactions of the
+// next block while the current block is still being voted on.
+func (ledger *Ledger) PrepareBlockTxs() []common.Bytes {
+	// Must always acquire locks in following order to avoid deadlock: mempool, ledger.
+	ledger.mempool.Lock()
+	defer ledger.mempool.Unlock()
+
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	params := ledger.state.Checked().GetChainParams()
+	return ledger.mempool.SelectWithinLimitsUnsafe(int(params.MaxNumRegularTxsPerBlock),
+		params.MaxBlockGas, params.MaxBlockBytes)
+}
+
+// ProposePreparedBlockTxs executes the special transactions followed by the regular transactions
+// prepared by PrepareBlockTxs. Like ProposeBlockTxs, it skips the transactions which are no
+// longer valid, e.g. included in a block after they were prepared.
+func (ledger *Ledger) ProposePreparedBlockTxs(timestamp *big.Int, regularRawTxs []common.Bytes) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	return ledger.proposeBlockTxs(timestamp, regularRawTxs, false)
+}
+
+// ProposeBlockTxsFromPayload executes the special transactions followed by the given regular
+// transactions, e.g. provided by an external block builder, which will be used to assemble the
+// next block. Unlike ProposeBlockTxs, it returns an error if any of the regular transactions fails.
+// The caller should reset the state before proposing another block in that case.
+func (ledger *Ledger) ProposeBlockTxsFromPayload(timestamp *big.Int, regularRawTxs []common.Bytes) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	return ledger.proposeBlockTxs(timestamp, regularRawTxs, true)
+}
+
+func (ledger *Ledger) proposeBlockTxs(timestamp *big.Int, regularRawTxs []common.Bytes, strict bool) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
 	view := ledger.state.Checked()
+	limits := newBlockLimits(view.GetChainParams())
+	rules := ledger.rulesAt(view)
+	baseFee := blockBaseFee(view, rules)
+	ledger.updateChainTime(view, rules, timestamp)
+	enforceLimits := rules.IsActive(core.UpgradeChainParams)
+	storeReceipts := rules.IsActive(core.UpgradeSmartContracts)
+	view.ResetBlockChanges()
 
 	// Add special transactions
 	rawTxCandidates := []common.Bytes{}
 	ledger.addSpecialTransactions(view, &rawTxCandidates)
+	numSpecialTxs := len(rawTxCandidates)
 
-	// Add regular transactions submitted by the clients
-	regularRawTxs := ledger.mempool.ReapUnsafe(core.MaxNumRegularTxsPerBlock)
+	canonicalOrder := rules.IsActive(core.UpgradeCanonicalTxOrder)
+	if canonicalOrder {
+		regularRawTxs = canonicalTxOrder(regularRawTxs, ledger.regularTxInfo)
+	}
 	for _, regularRawTx := range regularRawTxs {
 		rawTxCandidates = append(rawTxCandidates, regularRawTx)
 	}
 
 	blockRawTxs = []common.Bytes{}
-	for _, rawTxCandidate := range rawTxCandidates {
+	includedRegularRawTxs := []common.Bytes{}
+	hasValidatorUpdate := false
+	receipts := []*types.Receipt{}
+	for idx, rawTxCandidate := range rawTxCandidates {
+		isRegular := idx >= numSpecialTxs
 		tx, err := types.TxFromBytes(rawTxCandidate)
 		if err != nil {
+			if strict && isRegular {
+				return common.Hash{}, nil, result.Error("Failed to parse transaction: %v", hex.EncodeToString(rawTxCandidate))
+			}
 			continue
 		}
+		if strict && isRegular && isSpecialTx(tx) {
+			return common.Hash{}, nil, result.Error("Unexpected special transaction: %v", hex.EncodeToString(rawTxCandidate))
+		}
+		txGas := ledger.getTxGas(tx)
+		if !isSpecialTx(tx) && enforceLimits {
+			if err := limits.fits(rawTxCandidate, txGas); err != nil {
+				if strict {
+					return common.Hash{}, nil, result.Error("%v, tx = %v", err, tx)
+				}
+				logger.Debugf("Transaction skipped: %v, tx = %v", err, tx)
+				continue
+			}
+		}
 		_, res := ledger.executor.CheckTx(tx)
 		if res.IsError() {
+			if strict && isRegular {
+				return common.Hash{}, nil, result.Error("Transaction check failed: errMsg = %v, tx = %v", res.Message, tx)
+			}
 			logger.Errorf("Transaction check failed: errMsg = %v, tx = %v", res.Message, tx)
 			continue
 		}
 		blockRawTxs = append(blockRawTxs, rawTxCandidate)
+		if !isSpecialTx(tx) {
+			limits.add(rawTxCandidate, txGas)
+			includedRegularRawTxs = append(includedRegularRawTxs, rawTxCandidate)
+		}
+		if isValidatorUpdateTx(tx) {
+			hasValidatorUpdate = true
+		}
+		if r, ok := res.Info["receipt"]; ok && storeReceipts {
+			receipts = append(receipts, r.(*types.Receipt))
+		}
+	}
+
+	// Skipping a transaction normally skips the later transactions of its sender too, which keeps
+	// the included transactions in the canonical order. The block would be rejected otherwise.
+	if canonicalOrder && !isCanonicalTxOrder(includedRegularRawTxs, ledger.regularTxInfo) {
+		return common.Hash{}, nil, result.Error("The included transactions are not in the canonical order")
 	}
 
+	ledger.updateBaseFee(view, rules, limits)
+	ledger.distributeBlockRewards(view, rules)
+	ledger.updateTFuelSupply(view, rules)
 	ledger.handleDelayedStateUpdates(view)
 
 	stateRootHash = view.Hash()
 
-	return stateRootHash, blockRawTxs, result.OK
+	return stateRootHash, blockRawTxs, result.OKWith(result.Info{
+		"bloom":                types.CreateBloom(receipts),
+		"baseFee":              baseFee,
+		"nextValidatorSetHash": nextValidatorSetHash(view, rules, hasValidatorUpdate),
+	})
 }
 
-// ApplyBlockTxs applies the given block transactions. If any of the transactions failed, it returns
-// an error immediately. If all the transactions execute successfully, it then validates the state
-// root hash. If the states root hash matches the expected value, it clears the transactions from the mempool
-func (ledger *Ledger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoot common.Hash) result.Result {
+// ApplyBlockTxs applies the given transactions of the block with the given timestamp. If any of the
+// transactions failed, it returns an error immediately. If all the transactions execute successfully,
+// it then validates the state root hash. If the states root hash matches the expected value, it clears
+// the transactions from the mempool
+func (ledger *Ledger) ApplyBlockTxs(blockRawTxs []common.Bytes, timestamp *big.Int, expectedStateRoot common.Hash) result.Result {
 	// Must always acquire locks in following order to avoid deadlock: mempool, ledger.
 	// Otherwise, could cause deadlock since mempool.InsertTransaction() also first acquires the mempool, and then the ledger lock
-	ledger.mempool.Lock()
-	defer ledger.mempool.Unlock()
+	if ledger.mempool != nil {
+		ledger.mempool.Lock()
+		defer ledger.mempool.Unlock()
+	}
 
 	ledger.mu.Lock()
 	defer ledger.mu.Unlock()
@@ -214,16 +405,46 @@ func (ledger *Ledger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoo
 	currHeight := view.Height()
 	currStateRoot := view.Hash()
 
+	// The block limits in effect are the ones before any of the block transactions is executed,
+	// and they are enforced from the chain params upgrade
+	limits := newBlockLimits(view.GetChainParams())
+	rules := ledger.rulesAt(view)
+	enforceLimits := rules.IsActive(core.UpgradeChainParams)
+	storeReceipts := rules.IsActive(core.UpgradeSmartContracts)
+	baseFee := blockBaseFee(view, rules)
+	if rules.IsActive(core.UpgradeCanonicalTxOrder) {
+		if err := ledger.checkCanonicalTxOrder(blockRawTxs); err != nil {
+			return result.Error("%v", err)
+		}
+	}
+	ledger.updateChainTime(view, rules, timestamp)
+	view.ResetBlockChanges()
+
+	checkInvariants := viper.GetBool(common.CfgLedgerCheckInvariants)
+	var totalTFuel *big.Int
+	if checkInvariants {
+		totalTFuel = view.TotalTFuel()
+	}
+
 	hasValidatorUpdate := false
+	receipts := []*types.Receipt{}
 	for _, rawTx := range blockRawTxs {
 		tx, err := types.TxFromBytes(rawTx)
 		if err != nil {
 			ledger.resetState(currHeight, currStateRoot)
 			return result.Error("Failed to parse transaction: %v", hex.EncodeToString(rawTx))
 		}
-		if _, ok := tx.(*types.DepositStakeTx); ok {
-			hasValidatorUpdate = true
-		} else if _, ok := tx.(*types.WithdrawStakeTx); ok {
+		if !isSpecialTx(tx) {
+			txGas := ledger.getTxGas(tx)
+			if enforceLimits {
+				if err := limits.fits(rawTx, txGas); err != nil {
+					ledger.resetState(currHeight, currStateRoot)
+					return result.Error("%v", err)
+				}
+			}
+			limits.add(rawTx, txGas)
+		}
+		if isValidatorUpdateTx(tx) {
 			hasValidatorUpdate = true
 		}
 		_, res := ledger.executor.ExecuteTx(tx)
@@ -231,23 +452,124 @@ func (ledger *Ledger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoo
 			ledger.resetState(currHeight, currStateRoot)
 			return res
 		}
+		if r, ok := res.Info["receipt"]; ok && storeReceipts {
+			receipt := r.(*types.Receipt)
+			receipt.TxHash = crypto.HashAtHeight(currHeight+1, rawTx)
+			receipts = append(receipts, receipt)
+		}
 	}
 
+	ledger.updateBaseFee(view, rules, limits)
+	ledger.distributeBlockRewards(view, rules)
+	ledger.updateTFuelSupply(view, rules)
 	ledger.handleDelayedStateUpdates(view)
+	validatorSetHash := nextValidatorSetHash(view, rules, hasValidatorUpdate)
 
 	newStateRoot := view.Hash()
 	if newStateRoot != expectedStateRoot {
 		ledger.resetState(currHeight, currStateRoot)
-		return result.Error("State root mismatch! root: %v, exptected: %v",
+		res := result.Error("State root mismatch! root: %v, exptected: %v",
 			hex.EncodeToString(newStateRoot[:]),
 			hex.EncodeToString(expectedStateRoot[:]))
+		res.Info["stateRoot"] = newStateRoot // the state root computed, e.g. for the replay tool
+		return res
+	}
+	if checkInvariants {
+		ledger.checkBlockInvariants(view, totalTFuel)
 	}
 
+	commitStart := time.Now()
 	ledger.state.Commit() // commit to persistent storage
+	commitEnd := time.Now()
 
-	ledger.mempool.UpdateUnsafe(blockRawTxs) // clear txs from the mempool
+	if ledger.mempool != nil {
+		ledger.mempool.UpdateUnsafe(blockRawTxs) // clear txs from the mempool
+	}
 
-	return result.OKWith(result.Info{"hasValidatorUpdate": hasValidatorUpdate})
+	return result.OKWith(result.Info{
+		"hasValidatorUpdate":   hasValidatorUpdate,
+		"nextValidatorSetHash": validatorSetHash,
+		"receipts":             receipts,
+		"baseFee":              baseFee,
+		"commitStart":          commitStart, // the commit is timed for the tracing of the block
+		"commitEnd":            commitEnd,
+	})
+}
+
+// ReplayBlockTxs re-executes the transactions of a committed block on top of the state of its
+// parent and returns the receipts. Unlike ApplyBlockTxs, the transactions are not checked and
+// the resulting state is not committed. It is used to rebuild the receipts of past blocks.
+func (ledger *Ledger) ReplayBlockTxs(parentHeight uint64, parentStateRoot common.Hash,
+	blockRawTxs []common.Bytes, timestamp *big.Int, expectedStateRoot common.Hash) ([]*types.Receipt, result.Result) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	res := ledger.resetState(parentHeight, parentStateRoot)
+	if res.IsError() {
+		return nil, res
+	}
+	view := ledger.state.Delivered()
+
+	ledger.executor.SetSkipSanityCheck(true)
+	defer ledger.executor.SetSkipSanityCheck(false)
+
+	limits := newBlockLimits(view.GetChainParams())
+	rules := ledger.rulesAt(view)
+	storeReceipts := rules.IsActive(core.UpgradeSmartContracts)
+	ledger.updateChainTime(view, rules, timestamp)
+	view.ResetBlockChanges()
+	receipts := []*types.Receipt{}
+	for _, rawTx := range blockRawTxs {
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			return nil, result.Error("Failed to parse transaction: %v", hex.EncodeToString(rawTx))
+		}
+		if !isSpecialTx(tx) {
+			limits.add(rawTx, ledger.getTxGas(tx))
+		}
+		_, res := ledger.executor.ExecuteTx(tx)
+		if res.IsError() {
+			return nil, res
+		}
+		if r, ok := res.Info["receipt"]; ok && storeReceipts {
+			receipt := r.(*types.Receipt)
+			receipt.TxHash = crypto.HashAtHeight(parentHeight+1, rawTx)
+			receipts = append(receipts, receipt)
+		}
+	}
+
+	ledger.updateBaseFee(view, rules, limits)
+	ledger.distributeBlockRewards(view, rules)
+	ledger.updateTFuelSupply(view, rules)
+	ledger.handleDelayedStateUpdates(view)
+
+	newStateRoot := view.Hash()
+	if newStateRoot != expectedStateRoot {
+		return nil, result.Error("State root mismatch! root: %v, exptected: %v",
+			hex.EncodeToString(newStateRoot[:]),
+			hex.EncodeToString(expectedStateRoot[:]))
+	}
+
+	return receipts, result.OK
+}
+
+// SetSkipSanityCheck sets whether ApplyBlockTxs skips the sanity checks of the transactions.
+// It is only meant for re-applying finalized blocks, whose transactions were checked when the
+// blocks were validated, with a ledger not attached to a running consensus engine.
+func (ledger *Ledger) SetSkipSanityCheck(skip bool) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	ledger.executor.SetSkipSanityCheck(skip)
+}
+
+// getTxGas returns the gas the transaction counts against the block gas limit
+func (ledger *Ledger) getTxGas(tx types.Tx) uint64 {
+	txInfo, res := ledger.executor.GetTxInfo(tx)
+	if res.IsError() {
+		return 0 // the transaction will be rejected by the executor anyway
+	}
+	return txInfo.Gas
 }
 
 // ResetState sets the ledger state with the designated root
@@ -295,21 +617,169 @@ func (ledger *Ledger) shouldSkipCheckTx(tx types.Tx) bool {
 	}
 }
 
+// rulesAt returns the upgrades active for the block executed on top of the view.
+func (ledger *Ledger) rulesAt(view *st.StoreView) *core.Rules {
+	return core.RulesAt(ledger.state.GetChainID(), view.Height()+1)
+}
+
+// blockBaseFee returns the base fee of the block executed on top of the view, or nil before the
+// fee market upgrade.
+func blockBaseFee(view *st.StoreView, rules *core.Rules) *big.Int {
+	if !rules.IsActive(core.UpgradeFeeMarket) {
+		return nil
+	}
+	return view.GetBaseFee()
+}
+
+// isValidatorUpdateTx returns whether the transaction changes the stakes of the validator candidates,
+// which updates the validator set of the blocks following its block.
+func isValidatorUpdateTx(tx types.Tx) bool {
+	switch tx.(type) {
+	case *types.DepositStakeTx, *types.DepositStakeTxV2, *types.WithdrawStakeTx,
+		*types.DelegateTx, *types.UndelegateTx:
+		return true
+	}
+	return false
+}
+
+// nextValidatorSetHash returns the hash of the validator set selected from the validator candidate
+// pool of the view, which the header of a block updating the validator set commits to. It is empty
+// for the other blocks, and before the validator set hash upgrade.
+func nextValidatorSetHash(view *st.StoreView, rules *core.Rules, hasValidatorUpdate bool) common.Hash {
+	if !hasValidatorUpdate || !rules.IsActive(core.UpgradeValidatorSetHash) {
+		return common.Hash{}
+	}
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return common.Hash{}
+	}
+	return consensus.SelectTopStakeHoldersAsValidators(vcp).Hash()
+}
+
+// updateBaseFee stores the base fee of the next block, adjusted according to the gas used by the
+// regular transactions of the block. Before the fee market upgrade, no base fee is stored, and
+// the fee minimums are the ones of the chain params.
+func (ledger *Ledger) updateBaseFee(view *st.StoreView, rules *core.Rules, limits *blockLimits) {
+	if !rules.IsActive(core.UpgradeFeeMarket) {
+		return
+	}
+	view.SetBaseFee(types.NextBaseFee(limits.params, view.GetBaseFee(), limits.gasUsed))
+}
+
+// updateChainTime records the timestamp of the block executed on top of the view as the chain
+// time, which the time locks are released at. The chain time never decreases, even if a block has
+// an earlier timestamp than its ancestors.
+func (ledger *Ledger) updateChainTime(view *st.StoreView, rules *core.Rules, timestamp *big.Int) {
+	if !rules.IsActive(core.UpgradeTimeLock) || timestamp == nil || !timestamp.IsUint64() {
+		return
+	}
+	if blockTime := timestamp.Uint64(); blockTime > view.GetChainTime() {
+		view.SetChainTime(blockTime)
+	}
+}
+
+// updateTFuelSupply adds the TFuel minted and burned by the block to the supply accounting. The
+// total supply is computed from the balances of all the accounts at the first block of the supply
+// accounting upgrade, which traverses the whole account state once.
+func (ledger *Ledger) updateTFuelSupply(view *st.StoreView, rules *core.Rules) {
+	if !rules.IsActive(core.UpgradeSupplyAccounting) {
+		return
+	}
+	minted, burned := view.GetBlockTFuelChanges()
+	supply := view.GetTFuelSupply()
+	if supply == nil {
+		supply = &types.TFuelSupply{
+			Total:  view.TotalTFuel(),
+			Minted: big.NewInt(0),
+			Burned: big.NewInt(0),
+		}
+		logger.Infof("TFuel supply accounting started at height %v, total supply: %v", view.Height()+1, supply.Total)
+	} else {
+		supply.Total = new(big.Int).Add(supply.Total, minted)
+		supply.Total.Sub(supply.Total, burned)
+	}
+	supply.Minted = new(big.Int).Add(supply.Minted, minted)
+	supply.Burned = new(big.Int).Add(supply.Burned, burned)
+	supply.BlockMinted = minted
+	supply.BlockBurned = burned
+	view.SetTFuelSupply(supply)
+}
+
 // handleDelayedStateUpdates handles delayed state updates, e.g. stake return, where the stake
 // is returned only after X blocks of its corresponding StakeWithdraw transaction
 func (ledger *Ledger) handleDelayedStateUpdates(view *st.StoreView) {
 	ledger.handleStakeReturn(view)
+	ledger.handleGovernanceActivation(view)
 }
 
-func (ledger *Ledger) handleStakeReturn(view *st.StoreView) {
-	vcp := view.GetValidatorCandidatePool()
-	if vcp == nil {
+// handleGovernanceActivation applies the chain params of the passed governance proposals. The
+// params of a proposal are applied at the end of the block before its activation height, so
+// they are in effect for the blocks from the activation height on. A proposal tallied after its
+// activation height is applied at the end of the block it is tallied in.
+func (ledger *Ledger) handleGovernanceActivation(view *st.StoreView) {
+	nextHeight := view.Height() + 2
+
+	passed := view.GetPassedGovernanceProposals()
+	if len(passed.IDs) == 0 {
 		return
 	}
+	pending := &types.GovernanceProposalIDList{}
+	for _, id := range passed.IDs {
+		proposal := view.GetGovernanceProposal(id)
+		if proposal == nil {
+			panic(fmt.Sprintf("Failed to retrieve the passed governance proposal %v", id))
+		}
+		if proposal.ActivationHeight > nextHeight {
+			pending.Append(id)
+			continue
+		}
+		params := proposal.Params
+		view.SetChainParams(&params)
+		proposal.Status = types.GovernanceProposalActivated
+		view.SetGovernanceProposal(proposal)
+
+		logger.Infof("Chain params updated by governance proposal %v at height %v: %v",
+			proposal.ID, view.Height()+1, params.String())
+	}
+	view.UpdatePassedGovernanceProposals(pending)
+}
 
+func (ledger *Ledger) handleStakeReturn(view *st.StoreView) {
 	currentHeight := view.Height()
-	returnedStakes := vcp.ReturnStakes(currentHeight)
 
+	vcp := view.GetValidatorCandidatePool()
+	if vcp != nil {
+		ledger.returnStakes(view, vcp.ReturnStakes(currentHeight))
+		view.UpdateValidatorCandidatePool(vcp)
+	}
+
+	gcp := view.GetGuardianCandidatePool()
+	if gcp.Len() > 0 {
+		ledger.returnStakes(view, gcp.ReturnStakes(currentHeight))
+		view.UpdateGuardianCandidatePool(gcp)
+	}
+
+	queue := view.GetUnbondingQueue()
+	if queue.Len() > 0 {
+		returnedStakes := []*core.Stake{}
+		for _, entry := range queue.PopMatured(currentHeight) {
+			logger.Debugf("Unbonded stake to be returned: %v", entry)
+			returnedStakes = append(returnedStakes, &core.Stake{
+				Source:       entry.Source,
+				Amount:       entry.Amount,
+				Withdrawn:    true,
+				ReturnHeight: entry.ReturnHeight,
+			})
+		}
+		if len(returnedStakes) > 0 {
+			ledger.returnStakes(view, returnedStakes)
+			view.UpdateUnbondingQueue(queue)
+		}
+	}
+}
+
+func (ledger *Ledger) returnStakes(view *st.StoreView, returnedStakes []*core.Stake) {
+	currentHeight := view.Height()
 	for _, returnedStake := range returnedStakes {
 		if !returnedStake.Withdrawn || currentHeight < returnedStake.ReturnHeight {
 			panic(fmt.Sprintf("Cannot return stake: withdrawn = %v, returnHeight = %v, currentHeight = %v",
@@ -327,7 +797,6 @@ func (ledger *Ledger) handleStakeReturn(view *st.StoreView) {
 		sourceAccount.Balance = sourceAccount.Balance.Plus(returnedCoins)
 		view.SetAccount(sourceAddress, sourceAccount)
 	}
-	view.UpdateValidatorCandidatePool(vcp)
 }
 
 // addSpecialTransactions adds special transactions (e.g. coinbase transaction, slash transaction) to the block
@@ -423,9 +892,12 @@ func (ledger *Ledger) addSlashTxs(view *st.StoreView, proposer *core.Validator,
 
 // signTransaction signs the given transaction
 func (ledger *Ledger) signTransaction(tx types.Tx) (*crypto.Signature, error) {
-	chainID := ledger.state.GetChainID()
-	signBytes := tx.SignBytes(chainID)
-	signature, err := ledger.consensus.PrivateKey().Sign(signBytes)
+	signDomain := core.SignatureDomain(ledger.state.GetChainID(), ledger.state.Checked().Height()+1)
+	rawTx, err := types.TxToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := ledger.consensus.Signer().SignTx(signDomain, rawTx)
 	if err != nil {
 		return nil, err
 	}
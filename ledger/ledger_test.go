@@ -12,8 +12,11 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
+	mp "github.com/thetatoken/theta/mempool"
 	"github.com/thetatoken/theta/store/database/backend"
 )
 
@@ -41,6 +44,104 @@ func TestLedgerScreenTx(t *testing.T) {
 	assert.Equal(result.CodeUnauthorizedTx, res.Code, res.Message)
 }
 
+func TestLedgerScreenBatchIsolation(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 2)
+	sendTx := newRawSendTx(chainID, 1, true, accOut, accIns[0], false)
+	conflictingTx := newRawSendTx(chainID, 1, true, accIns[1], accIns[0], false)
+
+	// A screened transaction not accepted does not affect the later screening
+	batch := ledger.NewScreenBatch()
+	_, res := batch.ScreenTx(sendTx)
+	assert.True(res.IsOK(), res.Message)
+	_, res = batch.ScreenTx(conflictingTx)
+	assert.True(res.IsOK(), res.Message)
+	batch.Accept()
+
+	// Once accepted, the double spend is rejected within the batch
+	_, res = batch.ScreenTx(sendTx)
+	assert.Equal(result.CodeInvalidSequence, res.Code, res.Message)
+
+	// The batch is not visible to other batches before committed
+	_, res = ledger.NewScreenBatch().ScreenTx(sendTx)
+	assert.True(res.IsOK(), res.Message)
+
+	batch.Commit()
+	_, res = ledger.ScreenTx(sendTx)
+	assert.Equal(result.CodeInvalidSequence, res.Code, res.Message)
+	_, res = ledger.ScreenTx(newRawSendTx(chainID, 2, true, accOut, accIns[0], false))
+	assert.True(res.IsOK(), res.Message)
+
+	// Screening never affects the delivered and checked views
+	assert.Equal(uint64(0), ledger.state.Delivered().GetAccount(accIns[0].Address).Sequence)
+	assert.Equal(uint64(0), ledger.state.Checked().GetAccount(accIns[0].Address).Sequence)
+}
+
+func TestLedgerScreenBatchDroppedAfterReset(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 1)
+	sendTx := newRawSendTx(chainID, 1, true, accOut, accIns[0], false)
+
+	batch := ledger.NewScreenBatch()
+	_, res := batch.ScreenTx(sendTx)
+	assert.True(res.IsOK(), res.Message)
+	batch.Accept()
+
+	// The screened view is reset, e.g. by a new block, before the batch is committed
+	delivered := ledger.state.Delivered()
+	res = ledger.ResetState(delivered.Height(), delivered.Hash())
+	assert.True(res.IsOK(), res.Message)
+	batch.Commit()
+
+	_, res = ledger.ScreenTx(sendTx)
+	assert.True(res.IsOK(), res.Message)
+}
+
+func TestLedgerMempoolReplacement(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, mempool := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 1)
+	sendTx := func(sequence int, txFee int64) common.Bytes {
+		tx := &types.SendTx{
+			Fee:     types.NewCoins(0, txFee),
+			Inputs:  []types.TxInput{{Sequence: uint64(sequence), Address: accIns[0].Address, Coins: types.NewCoins(15, txFee)}},
+			Outputs: []types.TxOutput{{Address: accOut.Address, Coins: types.NewCoins(15, 0)}},
+		}
+		types.SignSendTx(chainID, tx, accIns[0])
+		rawTx, err := types.TxToBytes(tx)
+		require.Nil(err)
+		return rawTx
+	}
+	txFee := getMinimumTxFee()
+
+	require.Nil(mempool.InsertTransaction(sendTx(1, txFee)))
+	require.Nil(mempool.InsertTransaction(sendTx(2, txFee)))
+	require.Equal(2, mempool.Size())
+
+	// The pooled transactions are replaced by the ones paying a higher fee, which are screened on
+	// the state before them
+	assert.Equal(mp.ReplacementUnderpricedError, mempool.InsertTransaction(sendTx(2, txFee+1)))
+	assert.NotNil(mempool.InsertTransaction(sendTx(2, 100000*txFee)))
+	replacement2 := sendTx(2, 2*txFee)
+	assert.Nil(mempool.InsertTransaction(replacement2))
+	replacement1 := sendTx(1, 2*txFee)
+	assert.Nil(mempool.InsertTransaction(replacement1))
+	assert.Equal(2, mempool.Size())
+
+	// The replacements make it into the block
+	_, blockTxs, res := ledger.ProposeBlockTxs(nil)
+	require.True(res.IsOK(), res.Message)
+	assert.Contains(blockTxs, replacement1)
+	assert.Contains(blockTxs, replacement2)
+	assert.Equal(0, mempool.Size())
+}
+
 func TestLedgerProposerBlockTxs(t *testing.T) {
 	assert := assert.New(t)
 
@@ -63,7 +164,7 @@ func TestLedgerProposerBlockTxs(t *testing.T) {
 	startTime := time.Now()
 
 	// Propose block transactions
-	_, blockTxs, res := ledger.ProposeBlockTxs()
+	_, blockTxs, res := ledger.ProposeBlockTxs(nil)
 
 	endTime := time.Now()
 	elapsed := endTime.Sub(startTime)
@@ -86,7 +187,7 @@ func TestLedgerProposerBlockTxs(t *testing.T) {
 			assert.Equal(0, idx) // The first tx needs to be a coinbase transaction
 			coinbaseTx := tx.(*types.CoinbaseTx)
 			signBytes := coinbaseTx.SignBytes(chainID)
-			ledger.consensus.PrivateKey().PublicKey().VerifySignature(signBytes, coinbaseTx.Proposer.Signature)
+			ledger.consensus.Signer().PublicKey().VerifySignature(signBytes, coinbaseTx.Proposer.Signature)
 		case *types.SendTx:
 			assert.True(idx > 0)
 			currSendTx := tx.(*types.SendTx)
@@ -125,7 +226,7 @@ func TestLedgerApplyBlockTxs(t *testing.T) {
 	}
 	expectedStateRoot := common.HexToHash("0d7bff2377e3638b82b09c21b7d0636ed593d2225164cb9b67f7296432194c58")
 
-	res := ledger.ApplyBlockTxs(blockRawTxs, expectedStateRoot)
+	res := ledger.ApplyBlockTxs(blockRawTxs, nil, expectedStateRoot)
 	require.True(res.IsOK(), res.Message)
 
 	//
@@ -326,8 +427,8 @@ func TestValidatorStakeUpdate(t *testing.T) {
 	for h := uint64(0); h < heightDelta1; h++ {
 		es.state.Commit() // increment height
 	}
-	expectedStateHash, _, res := es.consensus.GetLedger().ProposeBlockTxs()
-	res = es.consensus.GetLedger().ApplyBlockTxs([]common.Bytes{}, expectedStateHash)
+	expectedStateHash, _, res := es.consensus.GetLedger().ProposeBlockTxs(nil)
+	res = es.consensus.GetLedger().ApplyBlockTxs([]common.Bytes{}, nil, expectedStateHash)
 	assert.True(res.IsOK())
 
 	srcAcc = es.state.Delivered().GetAccount(withdrawSourcePrivAcc.Address)
@@ -340,8 +441,8 @@ func TestValidatorStakeUpdate(t *testing.T) {
 	for h := uint64(0); h < heightDelta2; h++ {
 		es.state.Commit() // increment height
 	}
-	expectedStateHash, _, res = es.consensus.GetLedger().ProposeBlockTxs()
-	res = es.consensus.GetLedger().ApplyBlockTxs([]common.Bytes{}, expectedStateHash)
+	expectedStateHash, _, res = es.consensus.GetLedger().ProposeBlockTxs(nil)
+	res = es.consensus.GetLedger().ApplyBlockTxs([]common.Bytes{}, nil, expectedStateHash)
 	assert.True(res.IsOK())
 
 	srcAcc = es.state.Delivered().GetAccount(withdrawSourcePrivAcc.Address)
@@ -353,3 +454,275 @@ func TestValidatorStakeUpdate(t *testing.T) {
 	assert.True(returnedCoins.TFuelWei.Cmp(core.Zero) == 0)
 	log.Infof("Returned coins: %v", returnedCoins)
 }
+
+func TestLedgerFeeMarketUpgrade(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	prepareInitLedgerState(ledger, 1)
+	height := ledger.state.Delivered().Height()
+	stateRoot := ledger.state.Delivered().Hash()
+
+	// The fee market is dormant until scheduled
+	_, _, res := ledger.ProposeBlockTxs(nil)
+	require.True(res.IsOK(), res.Message)
+	assert.Nil(res.Info["baseFee"].(*big.Int))
+	ledger.ResetState(height, stateRoot)
+
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeFeeMarket: height + 1}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	newStateRoot, blockTxs, res := ledger.ProposeBlockTxs(nil)
+	require.True(res.IsOK(), res.Message)
+	minGasPrice := new(big.Int).SetUint64(types.MinimumGasPrice)
+	assert.Equal(minGasPrice, res.Info["baseFee"].(*big.Int))
+	assert.NotEqual(stateRoot, newStateRoot)
+
+	res = ledger.ApplyBlockTxs(blockTxs, nil, newStateRoot)
+	require.True(res.IsOK(), res.Message)
+	assert.Equal(minGasPrice, res.Info["baseFee"].(*big.Int))
+	assert.NotNil(ledger.state.Delivered().Get(st.BaseFeeKey()))
+}
+
+func TestLedgerChainTime(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	prepareInitLedgerState(ledger, 1)
+	height := ledger.state.Delivered().Height()
+	stateRoot := ledger.state.Delivered().Hash()
+
+	// The chain time is not recorded before the time lock upgrade
+	_, _, res := ledger.ProposeBlockTxs(big.NewInt(1000))
+	require.True(res.IsOK(), res.Message)
+	assert.Equal(uint64(0), ledger.state.Checked().GetChainTime())
+	ledger.ResetState(height, stateRoot)
+
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeTimeLock: height + 1}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	newStateRoot, blockTxs, res := ledger.ProposeBlockTxs(big.NewInt(1000))
+	require.True(res.IsOK(), res.Message)
+	res = ledger.ApplyBlockTxs(blockTxs, big.NewInt(1000), newStateRoot)
+	require.True(res.IsOK(), res.Message)
+	assert.Equal(uint64(1000), ledger.state.Delivered().GetChainTime())
+
+	// The chain time does not go back with an earlier block timestamp
+	ledger.ResetState(height+1, newStateRoot)
+	newStateRoot, blockTxs, res = ledger.ProposeBlockTxs(big.NewInt(900))
+	require.True(res.IsOK(), res.Message)
+	res = ledger.ApplyBlockTxs(blockTxs, big.NewInt(900), newStateRoot)
+	require.True(res.IsOK(), res.Message)
+	assert.Equal(uint64(1000), ledger.state.Delivered().GetChainTime())
+}
+
+func TestLedgerTFuelSupply(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, mempool := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 1)
+	height := ledger.state.Delivered().Height()
+
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeSupplyAccounting: height + 1}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	// The supply is initialized from the account balances in the first block of the upgrade
+	newStateRoot, blockTxs, res := ledger.ProposeBlockTxs(nil)
+	require.True(res.IsOK(), res.Message)
+	res = ledger.ApplyBlockTxs(blockTxs, nil, newStateRoot)
+	require.True(res.IsOK(), res.Message)
+	supply := ledger.state.Delivered().GetTFuelSupply()
+	require.NotNil(supply)
+	assert.Equal(ledger.state.Delivered().TotalTFuel(), supply.Total)
+	initialTotal := supply.Total
+
+	// The fee of a send transaction is burned
+	ledger.ResetState(height+1, newStateRoot)
+	sendTxBytes := newRawSendTx(core.SignatureDomain(chainID, height+2), 1, true, accOut, accIns[0], false)
+	require.Nil(mempool.InsertTransaction(sendTxBytes))
+	newStateRoot, blockTxs, res = ledger.ProposeBlockTxs(nil)
+	require.True(res.IsOK(), res.Message)
+	require.Equal(2, len(blockTxs))
+	res = ledger.ApplyBlockTxs(blockTxs, nil, newStateRoot)
+	require.True(res.IsOK(), res.Message)
+
+	fee := big.NewInt(getMinimumTxFee())
+	supply = ledger.state.Delivered().GetTFuelSupply()
+	assert.Equal(fee, supply.BlockBurned)
+	assert.Equal(fee, supply.Burned)
+	assert.Equal(new(big.Int).Sub(initialTotal, fee), supply.Total)
+	assert.Equal(ledger.state.Delivered().TotalTFuel(), supply.Total)
+}
+
+func TestLedgerGovernanceActivation(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ledger, _ := newTestLedger()
+	prepareInitLedgerState(ledger, 1)
+	view := ledger.state.Delivered()
+	blockHeight := view.Height() + 1
+
+	// Passed proposals taking effect from the next block, and from the one after
+	for id, activationHeight := range []uint64{blockHeight + 1, blockHeight + 2} {
+		params := types.DefaultChainParams()
+		params.MaxBlockGas = uint64(1000000 * (id + 1))
+		view.SetGovernanceProposal(&types.GovernanceProposal{
+			ID:               uint64(id + 1),
+			Params:           *params,
+			ActivationHeight: activationHeight,
+			Status:           types.GovernanceProposalPassed,
+		})
+	}
+	view.UpdatePassedGovernanceProposals(&types.GovernanceProposalIDList{IDs: []uint64{1, 2}})
+
+	ledger.handleGovernanceActivation(view)
+	assert.Equal(uint64(1000000), view.GetChainParams().MaxBlockGas)
+	assert.Equal(types.GovernanceProposalActivated, view.GetGovernanceProposal(1).Status)
+	assert.Equal(types.GovernanceProposalPassed, view.GetGovernanceProposal(2).Status)
+	assert.Equal([]uint64{2}, view.GetPassedGovernanceProposals().IDs)
+}
+
+func TestLedgerBlockLimitsActivation(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	params := types.DefaultChainParams()
+	params.MaxNumRegularTxsPerBlock = 1
+	ledger.state.Delivered().SetChainParams(params)
+	accOut, accIns := prepareInitLedgerState(ledger, 2)
+	height := ledger.state.Delivered().Height()
+	stateRoot := ledger.state.Delivered().Hash()
+	regularTxs := []common.Bytes{
+		newRawSendTx(chainID, 1, true, accOut, accIns[0], false),
+		newRawSendTx(chainID, 1, true, accOut, accIns[1], false),
+	}
+
+	// The block limits are not enforced before the upgrade
+	newStateRoot, blockTxs, res := ledger.ProposeBlockTxsFromPayload(nil, regularTxs)
+	require.True(res.IsOK(), res.Message)
+	ledger.ResetState(height, stateRoot)
+	res = ledger.ApplyBlockTxs(blockTxs, nil, newStateRoot)
+	require.True(res.IsOK(), res.Message)
+	ledger.ResetState(height, stateRoot)
+
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeChainParams: height + 1}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	// The transactions are signed for the fork of the upgrade
+	domain := core.SignatureDomain(chainID, height+1)
+	regularTxs = []common.Bytes{
+		newRawSendTx(domain, 1, true, accOut, accIns[0], false),
+		newRawSendTx(domain, 1, true, accOut, accIns[1], false),
+	}
+	_, _, res = ledger.ProposeBlockTxsFromPayload(nil, regularTxs)
+	assert.True(res.IsError())
+	assert.Contains(res.Message, "max number of regular transactions")
+	ledger.ResetState(height, stateRoot)
+	res = ledger.ApplyBlockTxs(regularTxs, nil, common.Hash{})
+	assert.True(res.IsError())
+	assert.Contains(res.Message, "max number of regular transactions")
+}
+
+func TestLedgerUnbondingQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ledger, _ := newTestLedger()
+	prepareInitLedgerState(ledger, 1)
+	view := ledger.state.Delivered()
+	height := view.Height()
+
+	source := types.MakeAcc("unbonding_source")
+	view.SetAccount(source.Address, &source.Account)
+	initBalance := source.Account.Balance.ThetaWei
+
+	queue := &types.UnbondingQueue{}
+	for _, returnHeight := range []uint64{height + 1, height, height - 1} {
+		queue.Add(&types.UnbondingEntry{
+			Source:       source.Address,
+			Amount:       big.NewInt(1000),
+			ReturnHeight: returnHeight,
+		})
+	}
+	view.UpdateUnbondingQueue(queue)
+
+	// Only the stakes whose return height has been reached are returned
+	ledger.handleStakeReturn(view)
+	queue = view.GetUnbondingQueue()
+	assert.Equal(1, queue.Len())
+	assert.Equal(height+1, queue.Entries[0].ReturnHeight)
+	balance := view.GetAccount(source.Address).Balance.ThetaWei
+	assert.Equal(new(big.Int).Add(initBalance, big.NewInt(2000)), balance)
+}
+
+func TestLedgerCanonicalTxOrder(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 4)
+	height := ledger.state.Delivered().Height()
+
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeCanonicalTxOrder: height + 1}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	domain := core.SignatureDomain(chainID, height+1)
+	regularRawTxs := []common.Bytes{}
+	for _, accIn := range accIns {
+		regularRawTxs = append(regularRawTxs, newRawSendTx(domain, 1, true, accOut, accIn, true))
+	}
+
+	// The proposer puts the regular transactions in the canonical order
+	newStateRoot, blockTxs, res := ledger.ProposeBlockTxsFromPayload(nil, regularRawTxs)
+	require.True(res.IsOK(), res.Message)
+	require.Equal(len(regularRawTxs)+1, len(blockTxs))
+	assert.True(isCanonicalTxOrder(blockTxs[1:], ledger.regularTxInfo))
+
+	// A block with the regular transactions in another order is rejected
+	reordered := []common.Bytes{blockTxs[0]}
+	for idx := len(blockTxs) - 1; idx > 0; idx-- {
+		reordered = append(reordered, blockTxs[idx])
+	}
+	res = ledger.ApplyBlockTxs(reordered, nil, newStateRoot)
+	assert.True(res.IsError())
+	assert.Equal(height, ledger.state.Delivered().Height())
+
+	res = ledger.ApplyBlockTxs(blockTxs, nil, newStateRoot)
+	assert.True(res.IsOK(), res.Message)
+}
+
+func TestLedgerNextValidatorSetHash(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	prepareInitLedgerState(ledger, 1)
+	view := ledger.state.Delivered()
+	height := view.Height()
+
+	vcp := &core.ValidatorCandidatePool{}
+	for _, name := range []string{"validator_a", "validator_b"} {
+		acc := types.MakeAcc(name)
+		assert.Nil(vcp.DepositStake(acc.Address, acc.Address, core.MinValidatorStakeDeposit))
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+	expected := consensus.SelectTopStakeHoldersAsValidators(vcp).Hash()
+	assert.False(expected.IsEmpty())
+
+	// No hash before the upgrade
+	assert.True(nextValidatorSetHash(view, ledger.rulesAt(view), true).IsEmpty())
+
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeValidatorSetHash: height + 1}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	// Only the blocks updating the validator set commit to its hash
+	rules := ledger.rulesAt(view)
+	assert.True(nextValidatorSetHash(view, rules, false).IsEmpty())
+	assert.Equal(expected, nextValidatorSetHash(view, rules, true))
+
+	assert.True(isValidatorUpdateTx(&types.DelegateTx{}))
+	assert.True(isValidatorUpdateTx(&types.WithdrawStakeTx{}))
+	assert.False(isValidatorUpdateTx(&types.SendTx{}))
+}
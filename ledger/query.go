@@ -0,0 +1,118 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// DepositedStake is a stake deposited by an address to a validator or guardian candidate
+type DepositedStake struct {
+	*core.Stake
+	Holder  common.Address
+	Purpose uint8 // core.StakeForValidator or core.StakeForGuardian
+}
+
+// GetAccount returns the account as of the finalized block at the given height, 0 meaning the
+// last finalized block.
+func (ledger *Ledger) GetAccount(addr common.Address, height uint64) (*types.Account, error) {
+	_, storeView, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, err
+	}
+	account := storeView.GetAccount(addr)
+	if account == nil {
+		return nil, fmt.Errorf("Account with address %s is not found at height %v", addr.Hex(), height)
+	}
+	return account, nil
+}
+
+// GetStake returns the stakes deposited by the given address to the validator and guardian
+// candidates as of the finalized block at the given height, 0 meaning the last finalized block.
+// The withdrawn stakes are included until they are returned, unless they were moved to the
+// unbonding queue.
+func (ledger *Ledger) GetStake(addr common.Address, height uint64) ([]*DepositedStake, error) {
+	_, storeView, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, err
+	}
+
+	stakes := []*DepositedStake{}
+	if vcp := storeView.GetValidatorCandidatePool(); vcp != nil {
+		for _, candidate := range vcp.SortedCandidates {
+			stakes = appendStakesFrom(stakes, addr, candidate, core.StakeForValidator)
+		}
+	}
+	if gcp := storeView.GetGuardianCandidatePool(); gcp != nil {
+		for _, guardian := range gcp.SortedGuardians {
+			stakes = appendStakesFrom(stakes, addr, guardian.StakeHolder, core.StakeForGuardian)
+		}
+	}
+	return stakes, nil
+}
+
+func appendStakesFrom(stakes []*DepositedStake, source common.Address, holder *core.StakeHolder, purpose uint8) []*DepositedStake {
+	for _, stake := range holder.Stakes {
+		if stake.Source == source {
+			stakes = append(stakes, &DepositedStake{
+				Stake:   stake,
+				Holder:  holder.Holder,
+				Purpose: purpose,
+			})
+		}
+	}
+	return stakes
+}
+
+// Delegation is a stake delegated by an address to a validator candidate
+type Delegation struct {
+	*core.Stake
+	Validator common.Address
+}
+
+// GetDelegations returns the stakes delegated by the given address to the validator candidates
+// as of the finalized block at the given height, 0 meaning the last finalized block. The
+// undelegated stakes are included until they are returned, unless they were moved to the
+// unbonding queue.
+func (ledger *Ledger) GetDelegations(addr common.Address, height uint64) ([]*Delegation, error) {
+	_, storeView, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, err
+	}
+	delegations := []*Delegation{}
+	if vcp := storeView.GetValidatorCandidatePool(); vcp != nil {
+		for _, candidate := range vcp.SortedCandidates {
+			for _, delegation := range candidate.Delegations {
+				if delegation.Source == addr {
+					delegations = append(delegations, &Delegation{
+						Stake:     delegation,
+						Validator: candidate.Holder,
+					})
+				}
+			}
+		}
+	}
+	return delegations, nil
+}
+
+// GetUnbondings returns the entries of the unbonding queue whose source or holder is the given
+// address, as of the finalized block at the given height, 0 meaning the last finalized block.
+func (ledger *Ledger) GetUnbondings(addr common.Address, height uint64) ([]*types.UnbondingEntry, error) {
+	_, storeView, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, err
+	}
+	return storeView.GetUnbondingQueue().Find(addr), nil
+}
+
+// GetValidatorSet returns the validator set of the finalized block at the given height, 0
+// meaning the last finalized block.
+func (ledger *Ledger) GetValidatorSet(height uint64) (*core.ValidatorSet, error) {
+	block, _, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, err
+	}
+	return ledger.valMgr.GetValidatorSet(block.Hash()), nil
+}
@@ -0,0 +1,64 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+func TestLedgerQueryAtHeight(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "test_chain_001"
+	db := backend.NewMemDatabase()
+
+	snapshot, srcPrivAccs, valPrivAccs := genSimSnapshot(chainID, db)
+	es := newExecSim(chainID, db, snapshot, valPrivAccs[0])
+	ledger := es.consensus.GetLedger().(*Ledger)
+	b0 := es.getTipBlock()
+
+	// Add block #1, which is not finalized
+	b1 := core.NewBlock()
+	b1.ChainID = chainID
+	b1.Height = b0.Height + 1
+	b1.Epoch = 1
+	b1.Parent = b0.Hash()
+	b1.HCC.BlockHash = b1.Parent
+	b1.StateHash = b0.StateHash
+	es.addBlock(b1)
+
+	// Account as of the last finalized block
+	account, err := ledger.GetAccount(srcPrivAccs[0].Address, 0)
+	assert.Nil(err)
+	assert.Equal(srcPrivAccs[0].Balance, account.Balance)
+
+	_, err = ledger.GetAccount(valPrivAccs[5].PrivKey.PublicKey().Address(), 0)
+	assert.Nil(err)
+
+	_, err = ledger.GetAccount(srcPrivAccs[0].Address, b1.Height)
+	assert.NotNil(err)
+
+	// Stakes deposited by the source
+	stakes, err := ledger.GetStake(srcPrivAccs[0].Address, 0)
+	assert.Nil(err)
+	assert.Equal(1, len(stakes))
+	assert.Equal(valPrivAccs[0].Address, stakes[0].Holder)
+	assert.Equal(core.StakeForValidator, stakes[0].Purpose)
+
+	stakes, err = ledger.GetStake(srcPrivAccs[5].Address, 0)
+	assert.Nil(err)
+	assert.Equal(0, len(stakes))
+
+	_, err = ledger.GetStake(srcPrivAccs[0].Address, b1.Height)
+	assert.NotNil(err)
+
+	// Validator set of the last finalized block
+	valSet, err := ledger.GetValidatorSet(0)
+	assert.Nil(err)
+	assert.Equal(4, valSet.Size())
+
+	_, err = ledger.GetValidatorSet(b1.Height)
+	assert.NotNil(err)
+}
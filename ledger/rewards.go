@@ -0,0 +1,162 @@
+package ledger
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// distributeBlockRewards pays the block reward and the transaction fees of the block to the
+// proposer, and to the stakers of the validators and the guardians proportional to their stakes.
+// The fees were burned when they were charged, so they are minted again with the block reward.
+// The rewards are recorded in the state, so they can be audited at each finalized block.
+func (ledger *Ledger) distributeBlockRewards(view *st.StoreView, rules *core.Rules) {
+	if !rules.IsActive(core.UpgradeRewardDistribution) {
+		return
+	}
+	proposer, validatorAddresses := view.GetBlockValidators()
+	if proposer.IsEmpty() {
+		return // the coinbase transaction of the block has not been processed
+	}
+
+	validators := []*core.StakeHolder{}
+	if vcp := view.GetValidatorCandidatePool(); vcp != nil {
+		isValidator := make(map[common.Address]bool)
+		for _, address := range validatorAddresses {
+			isValidator[address] = true
+		}
+		for _, candidate := range vcp.SortedCandidates {
+			if isValidator[candidate.Holder] {
+				validators = append(validators, candidate)
+			}
+		}
+	}
+	guardians := []*core.StakeHolder{}
+	for _, guardian := range view.GetGuardianCandidatePool().SortedGuardians {
+		guardians = append(guardians, guardian.StakeHolder)
+	}
+
+	_, fees := view.GetBlockTFuelChanges()
+	blockReward := new(big.Int).SetUint64(types.BlockRewardTFuelWei)
+	rewards := calculateBlockRewards(view.Height()+1, proposer, validators, guardians, blockReward, fees)
+
+	for _, reward := range rewards.Rewards {
+		account := view.GetAccount(reward.Address)
+		if account == nil {
+			account = types.NewAccount(reward.Address)
+			account.LastUpdatedBlockHeight = view.Height()
+		}
+		account.UpdateToHeight(view.Height())
+		account.Balance = account.Balance.Plus(types.Coins{ThetaWei: big.NewInt(0), TFuelWei: reward.Amount})
+		view.SetAccount(reward.Address, account)
+	}
+	view.AddMintedTFuel(rewards.Total())
+	view.SetBlockRewards(rewards)
+}
+
+// calculateBlockRewards splits the block reward and the fees among the proposer, the validator
+// stakers and the guardian stakers. The guardian share goes to the validator stakers if there is
+// no guardian stake, and the validator share goes to the proposer if there is no validator stake.
+// The remainders of the integer divisions also go to the proposer.
+func calculateBlockRewards(height uint64, proposer common.Address, validators []*core.StakeHolder,
+	guardians []*core.StakeHolder, blockReward *big.Int, fees *big.Int) *types.BlockRewards {
+	total := new(big.Int).Add(blockReward, fees)
+	proposerShare := percentOf(total, types.ProposerRewardPercent)
+	guardianShare := percentOf(total, types.GuardianRewardPercent)
+	if totalStake(guardians).Sign() == 0 {
+		guardianShare = big.NewInt(0)
+	}
+	validatorShare := new(big.Int).Sub(total, proposerShare)
+	validatorShare.Sub(validatorShare, guardianShare)
+
+	rewards := []types.Reward{}
+	rewards = append(rewards, splitByStake(validators, validatorShare, types.RewardToValidator)...)
+	rewards = append(rewards, splitByStake(guardians, guardianShare, types.RewardToGuardian)...)
+
+	proposerReward := new(big.Int).Set(total)
+	for _, reward := range rewards {
+		proposerReward.Sub(proposerReward, reward.Amount)
+	}
+	rewards = append([]types.Reward{{
+		Address: proposer,
+		Holder:  proposer,
+		Role:    types.RewardToProposer,
+		Amount:  proposerReward,
+	}}, rewards...)
+
+	return &types.BlockRewards{
+		Height:      height,
+		Proposer:    proposer,
+		BlockReward: blockReward,
+		Fees:        fees,
+		Rewards:     rewards,
+	}
+}
+
+// splitByStake splits the share among the stakes of the stake holders that are not withdrawn,
+// proportional to the stake amounts. Like the voting power, the delegations of a holder are
+// counted only if the holder has stake of its own. The stakes with zero rewards are skipped.
+func splitByStake(holders []*core.StakeHolder, share *big.Int, role uint8) []types.Reward {
+	rewards := []types.Reward{}
+	stake := totalStake(holders)
+	if stake.Sign() == 0 || share.Sign() == 0 {
+		return rewards
+	}
+	addRewards := func(holder *core.StakeHolder, stakes []*core.Stake, role uint8) {
+		for _, s := range stakes {
+			if s.Withdrawn {
+				continue
+			}
+			amount := new(big.Int).Mul(share, s.Amount)
+			amount.Div(amount, stake)
+			if amount.Sign() == 0 {
+				continue
+			}
+			rewards = append(rewards, types.Reward{
+				Address: s.Source,
+				Holder:  holder.Holder,
+				Role:    role,
+				Amount:  amount,
+			})
+		}
+	}
+	for _, holder := range holders {
+		if holder.SelfStake().Sign() == 0 {
+			continue
+		}
+		addRewards(holder, holder.Stakes, role)
+		addRewards(holder, holder.Delegations, types.RewardToDelegator)
+	}
+	return rewards
+}
+
+func totalStake(holders []*core.StakeHolder) *big.Int {
+	total := big.NewInt(0)
+	for _, holder := range holders {
+		total.Add(total, holder.TotalStake())
+	}
+	return total
+}
+
+func percentOf(amount *big.Int, percent uint64) *big.Int {
+	ret := new(big.Int).Mul(amount, new(big.Int).SetUint64(percent))
+	return ret.Div(ret, big.NewInt(100))
+}
+
+// GetBlockRewards returns the rewards paid by the finalized block at the given height, 0 meaning
+// the last finalized block.
+func (ledger *Ledger) GetBlockRewards(height uint64) (*core.ExtendedBlock, *types.BlockRewards, error) {
+	block, storeView, err := ledger.finalizedStoreViewAt(height)
+	if err != nil {
+		return nil, nil, err
+	}
+	rewards := storeView.GetBlockRewards()
+	if rewards == nil || rewards.Height != block.Height {
+		return nil, nil, fmt.Errorf("No rewards are paid by the block at height %v", block.Height)
+	}
+	return block, rewards, nil
+}
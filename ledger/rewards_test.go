@@ -0,0 +1,109 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func TestCalculateBlockRewards(t *testing.T) {
+	assert := assert.New(t)
+
+	proposer := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	val := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	guardian := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	alice := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	bob := common.HexToAddress("0x5555555555555555555555555555555555555555")
+
+	validators := []*core.StakeHolder{{
+		Holder: val,
+		Stakes: []*core.Stake{
+			{Source: alice, Amount: big.NewInt(2)},
+			{Source: bob, Amount: big.NewInt(1)},
+			{Source: proposer, Amount: big.NewInt(5), Withdrawn: true},
+		},
+	}}
+	guardians := []*core.StakeHolder{{
+		Holder: guardian,
+		Stakes: []*core.Stake{{Source: bob, Amount: big.NewInt(7)}},
+	}}
+
+	// 10% to the proposer, 30% to the guardian stakers and 60% to the validator stakers
+	rewards := calculateBlockRewards(10, proposer, validators, guardians, big.NewInt(900), big.NewInt(101))
+	assert.Equal(uint64(10), rewards.Height)
+	assert.Equal(big.NewInt(1001), rewards.Total())
+	assert.Equal([]types.Reward{
+		{Address: proposer, Holder: proposer, Role: types.RewardToProposer, Amount: big.NewInt(101)},
+		{Address: alice, Holder: val, Role: types.RewardToValidator, Amount: big.NewInt(400)},
+		{Address: bob, Holder: val, Role: types.RewardToValidator, Amount: big.NewInt(200)},
+		{Address: bob, Holder: guardian, Role: types.RewardToGuardian, Amount: big.NewInt(300)},
+	}, rewards.Rewards)
+
+	// The guardian share goes to the validator stakers without guardian stake
+	rewards = calculateBlockRewards(10, proposer, validators, nil, big.NewInt(1000), big.NewInt(0))
+	assert.Equal([]types.Reward{
+		{Address: proposer, Holder: proposer, Role: types.RewardToProposer, Amount: big.NewInt(100)},
+		{Address: alice, Holder: val, Role: types.RewardToValidator, Amount: big.NewInt(600)},
+		{Address: bob, Holder: val, Role: types.RewardToValidator, Amount: big.NewInt(300)},
+	}, rewards.Rewards)
+
+	// Everything goes to the proposer without any stake
+	rewards = calculateBlockRewards(10, proposer, nil, nil, big.NewInt(1000), big.NewInt(0))
+	assert.Equal([]types.Reward{
+		{Address: proposer, Holder: proposer, Role: types.RewardToProposer, Amount: big.NewInt(1000)},
+	}, rewards.Rewards)
+}
+
+func TestLedgerBlockRewards(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, _ := newTestLedger()
+	accOut, accIns := prepareInitLedgerState(ledger, 1)
+	view := ledger.state.Delivered()
+	validators := ledger.valMgr.GetValidatorSet(common.Hash{}).Validators()
+	proposer := ledger.consensus.Signer().PublicKey().Address()
+
+	vcp := &core.ValidatorCandidatePool{}
+	stake := core.MinValidatorStakeDeposit
+	require.Nil(vcp.DepositStake(accOut.Address, validators[0].Address, new(big.Int).Mul(stake, big.NewInt(3))))
+	require.Nil(vcp.DepositStake(accIns[0].Address, validators[1].Address, stake))
+	view.UpdateValidatorCandidatePool(vcp)
+	ledger.state.Commit()
+	height := view.Height()
+
+	core.SetForkSchedule(chainID, core.NewForkSchedule(map[core.Upgrade]uint64{core.UpgradeRewardDistribution: height + 1}))
+	defer core.SetForkSchedule(chainID, core.NewForkSchedule(nil))
+
+	balance := func(address common.Address) *big.Int {
+		return ledger.state.Delivered().GetAccount(address).Balance.TFuelWei
+	}
+	proposerBalance := balance(proposer)
+	accOutBalance := balance(accOut.Address)
+	accInBalance := balance(accIns[0].Address)
+
+	newStateRoot, blockTxs, res := ledger.ProposeBlockTxs(nil)
+	require.True(res.IsOK(), res.Message)
+	res = ledger.ApplyBlockTxs(blockTxs, nil, newStateRoot)
+	require.True(res.IsOK(), res.Message)
+
+	rewards := ledger.state.Delivered().GetBlockRewards()
+	require.NotNil(rewards)
+	assert.Equal(height+1, rewards.Height)
+	assert.Equal(proposer, rewards.Proposer)
+	assert.Equal(new(big.Int).SetUint64(types.BlockRewardTFuelWei), rewards.Total())
+
+	// Without guardian stake, 10% goes to the proposer and 90% to the validator stakers
+	total := new(big.Int).SetUint64(types.BlockRewardTFuelWei)
+	proposerReward := percentOf(total, 10)
+	accOutReward := new(big.Int).Div(new(big.Int).Mul(percentOf(total, 90), big.NewInt(3)), big.NewInt(4))
+	accInReward := new(big.Int).Div(percentOf(total, 90), big.NewInt(4))
+	assert.Equal(new(big.Int).Add(proposerBalance, proposerReward), balance(proposer))
+	assert.Equal(new(big.Int).Add(accOutBalance, accOutReward), balance(accOut.Address))
+	assert.Equal(new(big.Int).Add(accInBalance, accInReward), balance(accIns[0].Address))
+}
@@ -0,0 +1,167 @@
+package ledger
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ core.ScreenBatch = (*ScreenBatch)(nil)
+
+//
+// ScreenBatch implements the core.ScreenBatch interface. The transactions are screened on an
+// overlay of the screened view, so that a transaction screened but then rejected by the
+// mempool, e.g. for an underpriced replacement, does not affect the screening of the later
+// transactions.
+//
+type ScreenBatch struct {
+	ledger *Ledger
+
+	overlay *st.StoreView
+	version uint64 // Version of the screened view the overlay is based on
+	err     error
+
+	snapshot common.Hash // Overlay root before the last screened transaction
+	pending  bool        // Whether the last screened transaction awaits acceptance
+}
+
+// NewScreenBatch creates a batch to screen transactions on top of the current screened view
+func (ledger *Ledger) NewScreenBatch() core.ScreenBatch {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	overlay, version, err := ledger.state.ScreenedOverlay()
+	return &ScreenBatch{
+		ledger:  ledger,
+		overlay: overlay,
+		version: version,
+		err:     err,
+	}
+}
+
+// ScreenTx screens the given transaction on top of the transactions accepted earlier in the batch
+func (sb *ScreenBatch) ScreenTx(rawTx common.Bytes) (txInfo *core.TxInfo, res result.Result) {
+	if sb.err != nil {
+		return nil, result.Error("Failed to create the screened view overlay: %v", sb.err)
+	}
+
+	var tx types.Tx
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, result.Error("Error decoding tx: %v", err).
+			WithErrorCode(result.CodeTxDecodingFailed)
+	}
+
+	ledger := sb.ledger
+	if ledger.shouldSkipCheckTx(tx) {
+		return nil, result.Error("Unauthorized transaction, should skip").
+			WithErrorCode(result.CodeUnauthorizedTx)
+	}
+
+	sb.revertPending()
+
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	sb.snapshot = sb.overlay.Snapshot()
+	_, res = ledger.executor.ScreenTxOnView(tx, sb.overlay)
+	if res.Code == result.CodeFutureSequence || res.Code == result.CodeInvalidSequence {
+		// Return the tx info so that the mempool can hold the transaction until the gap is filled,
+		// or screen it as the replacement of the pooled transaction with the same sequence
+		sb.overlay.RevertToSnapshot(sb.snapshot)
+		txInfo, infoRes := ledger.executor.GetTxInfo(tx)
+		if infoRes.IsError() {
+			return nil, infoRes
+		}
+		return txInfo, res
+	}
+	if res.IsError() {
+		sb.overlay.RevertToSnapshot(sb.snapshot)
+		return nil, res
+	}
+	sb.pending = true
+
+	txInfo, res = ledger.executor.GetTxInfo(tx)
+	if res.IsError() {
+		return nil, res
+	}
+
+	return txInfo, res
+}
+
+// ScreenReplacementTx screens a transaction replacing a pooled transaction with the same sender
+// and sequence. The screened view already has the effects of the pooled transaction, so the
+// replacement is screened on the state the screened view started from instead, after the earlier
+// pooled transactions of the sender. The effects of the replacement are not kept in the batch.
+func (sb *ScreenBatch) ScreenReplacementTx(rawTx common.Bytes, earlierRawTxs []common.Bytes) (txInfo *core.TxInfo, res result.Result) {
+	var tx types.Tx
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, result.Error("Error decoding tx: %v", err).
+			WithErrorCode(result.CodeTxDecodingFailed)
+	}
+
+	ledger := sb.ledger
+	if ledger.shouldSkipCheckTx(tx) {
+		return nil, result.Error("Unauthorized transaction, should skip").
+			WithErrorCode(result.CodeUnauthorizedTx)
+	}
+
+	sb.revertPending()
+
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	view, err := ledger.state.ScreenedBaseOverlay()
+	if err != nil {
+		return nil, result.Error("Failed to create the screened base view overlay: %v", err)
+	}
+	for _, earlierRawTx := range earlierRawTxs {
+		earlierTx, err := types.TxFromBytes(earlierRawTx)
+		if err != nil {
+			continue
+		}
+		// The earlier transactions only advance the sequence and the balances of the sender,
+		// the replacement fails on its own if one of them is no longer valid
+		ledger.executor.ScreenTxOnView(earlierTx, view)
+	}
+	_, res = ledger.executor.ScreenTxOnView(tx, view)
+	if res.IsError() {
+		return nil, res
+	}
+
+	return ledger.executor.GetTxInfo(tx)
+}
+
+// Accept keeps the effects of the last screened transaction for the rest of the batch
+func (sb *ScreenBatch) Accept() {
+	sb.pending = false
+}
+
+// Commit merges the accepted transactions into the screened view. The batch is dropped if the
+// screened view has been reset since the batch was created, e.g. by a new block.
+func (sb *ScreenBatch) Commit() {
+	if sb.err != nil {
+		return
+	}
+	sb.revertPending()
+
+	ledger := sb.ledger
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	if !ledger.state.MergeScreenedOverlay(sb.overlay, sb.version) {
+		logger.Debugf("Screened view has changed, dropping screen batch")
+	}
+}
+
+// revertPending reverts the last screened transaction if it has not been accepted
+func (sb *ScreenBatch) revertPending() {
+	if !sb.pending {
+		return
+	}
+	sb.overlay.RevertToSnapshot(sb.snapshot)
+	sb.pending = false
+}
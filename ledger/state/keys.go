@@ -1,6 +1,10 @@
 package state
 
-import "github.com/thetatoken/theta/common"
+import (
+	"strconv"
+
+	"github.com/thetatoken/theta/common"
+)
 
 //
 // ------------------------- Ledger State Keys -------------------------
@@ -11,9 +15,14 @@ func ChainIDKey() common.Bytes {
 	return common.Bytes("chainid")
 }
 
+// AccountKeyPrefix returns the prefix for the account key
+func AccountKeyPrefix() common.Bytes {
+	return common.Bytes("ls/a/")
+}
+
 // AccountKey constructs the state key for the given address
 func AccountKey(addr common.Address) common.Bytes {
-	return append(common.Bytes("ls/a/"), addr[:]...)
+	return append(AccountKeyPrefix(), addr[:]...)
 }
 
 // SplitRuleKeyPrefix returns the prefix for the split rule key
@@ -37,8 +46,96 @@ func ValidatorCandidatePoolKey() common.Bytes {
 	return common.Bytes("ls/vcp")
 }
 
+// GuardianCandidatePoolKey returns the state key for the guardian candidate pool
+func GuardianCandidatePoolKey() common.Bytes {
+	return common.Bytes("ls/gcp")
+}
+
 // StakeTransactionHeightListKey returns the state key the heights of blocks
 // that contain stake related transactions (i.e. StakeDeposit, StakeWithdraw, etc)
 func StakeTransactionHeightListKey() common.Bytes {
 	return common.Bytes("ls/sthl")
 }
+
+// ChainParamsKey returns the state key for the chain parameters
+func ChainParamsKey() common.Bytes {
+	return common.Bytes("ls/cp")
+}
+
+// BaseFeeKey returns the state key for the base fee of the next block
+func BaseFeeKey() common.Bytes {
+	return common.Bytes("ls/bf")
+}
+
+// GovernanceProposalKeyPrefix returns the prefix for the governance proposal key
+func GovernanceProposalKeyPrefix() common.Bytes {
+	return common.Bytes("ls/gp/")
+}
+
+// GovernanceProposalKey constructs the state key for the governance proposal with the given ID
+func GovernanceProposalKey(id uint64) common.Bytes {
+	return append(GovernanceProposalKeyPrefix(), common.Bytes(strconv.FormatUint(id, 10))...)
+}
+
+// NextGovernanceProposalIDKey returns the state key for the ID of the next governance proposal
+func NextGovernanceProposalIDKey() common.Bytes {
+	return common.Bytes("ls/gpid")
+}
+
+// PassedGovernanceProposalsKey returns the state key for the IDs of the passed governance
+// proposals pending activation
+func PassedGovernanceProposalsKey() common.Bytes {
+	return common.Bytes("ls/gpa")
+}
+
+// BridgeOutboundMessagesKey constructs the state key for the outbound bridge messages of the
+// block at the given height
+func BridgeOutboundMessagesKey(height uint64) common.Bytes {
+	return append(common.Bytes("ls/bro/"), common.Bytes(strconv.FormatUint(height, 10))...)
+}
+
+// NextBridgeNonceKey returns the state key for the nonce of the next outbound bridge message
+func NextBridgeNonceKey() common.Bytes {
+	return common.Bytes("ls/brn")
+}
+
+// BridgeInboundMessageKey constructs the state key marking the inbound bridge message with the
+// given nonce from the source chain as processed
+func BridgeInboundMessageKey(sourceChainID string, nonce uint64) common.Bytes {
+	return common.Bytes("ls/bri/" + sourceChainID + "/" + strconv.FormatUint(nonce, 10))
+}
+
+// BridgeValidatorsKey constructs the state key for the validator set of the counterpart chain
+func BridgeValidatorsKey(chainID string) common.Bytes {
+	return common.Bytes("ls/brv/" + chainID)
+}
+
+// ChainTimeKey returns the state key for the chain time, i.e. the latest block timestamp
+func ChainTimeKey() common.Bytes {
+	return common.Bytes("ls/ct")
+}
+
+// TimeLockKey constructs the state key for the time lock with the given ID
+func TimeLockKey(id uint64) common.Bytes {
+	return append(common.Bytes("ls/tl/"), common.Bytes(strconv.FormatUint(id, 10))...)
+}
+
+// NextTimeLockIDKey returns the state key for the ID of the next time lock
+func NextTimeLockIDKey() common.Bytes {
+	return common.Bytes("ls/tlid")
+}
+
+// TFuelSupplyKey returns the state key for the TFuel supply accounting
+func TFuelSupplyKey() common.Bytes {
+	return common.Bytes("ls/tfs")
+}
+
+// BlockRewardsKey returns the state key for the rewards paid by the last block
+func BlockRewardsKey() common.Bytes {
+	return common.Bytes("ls/brw")
+}
+
+// UnbondingQueueKey returns the state key for the queue of the stakes being unbonded
+func UnbondingQueueKey() common.Bytes {
+	return common.Bytes("ls/ubq")
+}
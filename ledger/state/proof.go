@@ -0,0 +1,134 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rlp"
+	"github.com/thetatoken/theta/store/trie"
+)
+
+//
+// ------------------------- Account and Storage Proofs -------------------------
+//
+
+// AccountProof is a merkle proof of an account against the state root of a block. If the
+// account does not exist, Account is nil and the proof proves its absence.
+type AccountProof struct {
+	BlockHash   common.Hash
+	BlockHeight uint64
+	StateRoot   common.Hash
+	Address     common.Address
+	Account     *types.Account
+	Proof       trie.ProofList
+}
+
+// StorageProof is a merkle proof of a smart contract storage slot. The storage root is proven
+// by the account proof, and the slot against the storage root. A zero value means the slot is
+// empty, and the proof then proves its absence.
+type StorageProof struct {
+	AccountProof
+	Key          common.Hash
+	Value        common.Hash
+	StorageProof trie.ProofList
+}
+
+// ProveAccount returns the proof of the account against the root of the store view.
+func (sv *StoreView) ProveAccount(addr common.Address) (*AccountProof, error) {
+	proof := trie.ProofList{}
+	if err := sv.store.Prove(AccountKey(addr), 0, &proof); err != nil {
+		return nil, err
+	}
+	return &AccountProof{
+		BlockHeight: sv.height,
+		StateRoot:   sv.Hash(),
+		Address:     addr,
+		Account:     sv.GetAccount(addr),
+		Proof:       proof,
+	}, nil
+}
+
+// ProveStorage returns the proof of the storage slot of the account against the root of the
+// store view.
+func (sv *StoreView) ProveStorage(addr common.Address, key common.Hash) (*StorageProof, error) {
+	accountProof, err := sv.ProveAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	storageProof := &StorageProof{
+		AccountProof: *accountProof,
+		Key:          key,
+		StorageProof: trie.ProofList{},
+	}
+	if accountProof.Account == nil {
+		return storageProof, nil
+	}
+
+	if err := sv.getAccountStorage(accountProof.Account).Prove(key[:], 0, &storageProof.StorageProof); err != nil {
+		return nil, err
+	}
+	storageProof.Value = sv.GetState(addr, key)
+	return storageProof, nil
+}
+
+// Verify checks the proof against its state root. The state root must be checked against a
+// trusted block header by the caller.
+func (p *AccountProof) Verify() error {
+	enc, err := trie.VerifyProofList(p.StateRoot, AccountKey(p.Address), p.Proof,
+		crypto.HasherAtHeight(p.BlockHeight).Algorithm())
+	if err != nil {
+		return err
+	}
+	if len(enc) == 0 {
+		if p.Account != nil {
+			return fmt.Errorf("Account %v is proven absent", p.Address.Hex())
+		}
+		return nil
+	}
+	if p.Account == nil {
+		return fmt.Errorf("Account %v is proven present", p.Address.Hex())
+	}
+	expected, err := types.ToBytes(p.Account)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(enc, expected) {
+		return fmt.Errorf("Account %v does not match the proof", p.Address.Hex())
+	}
+	return nil
+}
+
+// Verify checks the account proof against its state root, and the storage proof against the
+// storage root of the account.
+func (p *StorageProof) Verify() error {
+	if err := p.AccountProof.Verify(); err != nil {
+		return err
+	}
+	if p.Account == nil {
+		if (p.Value != common.Hash{}) {
+			return fmt.Errorf("Storage of absent account %v is not empty", p.Address.Hex())
+		}
+		return nil
+	}
+
+	// The storage tries are hashed with Keccak256, see getAccountStorage
+	enc, err := trie.VerifyProofList(p.Account.Root, p.Key[:], p.StorageProof, crypto.HashAlgorithmKeccak256)
+	if err != nil {
+		return err
+	}
+	value := common.Hash{}
+	if len(enc) > 0 {
+		_, content, _, err := rlp.Split(enc)
+		if err != nil {
+			return err
+		}
+		value = common.BytesToHash(content)
+	}
+	if value != p.Value {
+		return fmt.Errorf("Storage slot %v of account %v does not match the proof", p.Key.Hex(), p.Address.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+func TestAccountProof(t *testing.T) {
+	assert := assert.New(t)
+
+	sv := NewStoreView(1, common.Hash{}, backend.NewMemDatabase())
+	addr1 := common.HexToAddress("0x111")
+	addr2 := common.HexToAddress("0x222")
+	acc1 := types.NewAccount(addr1)
+	acc1.Balance = types.NewCoins(100, 200)
+	sv.SetAccount(addr1, acc1)
+	sv.SetAccount(addr2, types.NewAccount(addr2))
+	sv.Save()
+
+	proof, err := sv.ProveAccount(addr1)
+	assert.Nil(err)
+	assert.Equal(sv.Hash(), proof.StateRoot)
+	assert.Equal(0, proof.Account.Balance.ThetaWei.Cmp(acc1.Balance.ThetaWei))
+	assert.Nil(proof.Verify())
+
+	// Tampered accounts are rejected
+	proof.Account.Balance = types.NewCoins(1000, 200)
+	assert.NotNil(proof.Verify())
+
+	// The absence of an account is proven
+	proof, err = sv.ProveAccount(common.HexToAddress("0x333"))
+	assert.Nil(err)
+	assert.Nil(proof.Account)
+	assert.Nil(proof.Verify())
+	proof.Account = acc1
+	assert.NotNil(proof.Verify())
+
+	// Proofs against another state root are rejected
+	proof, err = sv.ProveAccount(addr1)
+	assert.Nil(err)
+	proof.StateRoot = common.HexToHash("0x1234")
+	assert.NotNil(proof.Verify())
+}
+
+func TestStorageProof(t *testing.T) {
+	assert := assert.New(t)
+
+	sv := NewStoreView(1, common.Hash{}, backend.NewMemDatabase())
+	contract := common.HexToAddress("0x111")
+	sv.SetAccount(contract, types.NewAccount(contract))
+	key1, value1 := common.HexToHash("0x01"), common.HexToHash("0xabcd")
+	key2, value2 := common.HexToHash("0x02"), common.HexToHash("0x1234")
+	sv.SetState(contract, key1, value1)
+	sv.SetState(contract, key2, value2)
+	sv.Save()
+
+	proof, err := sv.ProveStorage(contract, key1)
+	assert.Nil(err)
+	assert.Equal(value1, proof.Value)
+	assert.Nil(proof.Verify())
+
+	proof.Value = value2
+	assert.NotNil(proof.Verify())
+
+	// Empty slots are proven empty
+	proof, err = sv.ProveStorage(contract, common.HexToHash("0x03"))
+	assert.Nil(err)
+	assert.Equal(common.Hash{}, proof.Value)
+	assert.Nil(proof.Verify())
+
+	// So is the storage of accounts without storage or absent accounts
+	noStorage := common.HexToAddress("0x222")
+	sv.SetAccount(noStorage, types.NewAccount(noStorage))
+	for _, addr := range []common.Address{noStorage, common.HexToAddress("0x333")} {
+		proof, err = sv.ProveStorage(addr, key1)
+		assert.Nil(err)
+		assert.Equal(common.Hash{}, proof.Value)
+		assert.Nil(proof.Verify())
+	}
+}
@@ -20,6 +20,9 @@ type LedgerState struct {
 	delivered *StoreView // for actually applying the transactions
 	checked   *StoreView // for block proposal check
 	screened  *StoreView // for mempool screening
+
+	screenedBase    *StoreView // the screened view before any transaction is screened
+	screenedVersion uint64     // incremented each time the screened view is replaced
 }
 
 // NewLedgerState creates a new Leger State with given store.
@@ -52,6 +55,11 @@ func (s *LedgerState) ResetState(height uint64, stateRootHash common.Hash) resul
 	if err != nil {
 		return result.Error(fmt.Sprintf("Failed to copy to the screened view: %v", err))
 	}
+	s.screenedBase, err = s.delivered.Copy()
+	if err != nil {
+		return result.Error(fmt.Sprintf("Failed to copy to the screened base view: %v", err))
+	}
+	s.screenedVersion++
 
 	return result.OK
 }
@@ -101,6 +109,34 @@ func (s *LedgerState) Screened() *StoreView {
 	return s.screened
 }
 
+// ScreenedOverlay returns a copy-on-write overlay of the screened view, together with the
+// version of the screened view it is based on.
+func (s *LedgerState) ScreenedOverlay() (*StoreView, uint64, error) {
+	overlay, err := s.screened.Copy()
+	if err != nil {
+		return nil, 0, err
+	}
+	return overlay, s.screenedVersion, nil
+}
+
+// ScreenedBaseOverlay returns a copy-on-write overlay of the state the screened view started
+// from, i.e. without the effects of the transactions screened since the last reset or commit.
+func (s *LedgerState) ScreenedBaseOverlay() (*StoreView, error) {
+	return s.screenedBase.Copy()
+}
+
+// MergeScreenedOverlay replaces the screened view with the overlay. The overlay is dropped if
+// the screened view has been replaced since the overlay was created, as it is no longer based
+// on the current screened state.
+func (s *LedgerState) MergeScreenedOverlay(overlay *StoreView, version uint64) bool {
+	if version != s.screenedVersion {
+		return false
+	}
+	s.screened = overlay
+	s.screenedVersion++
+	return true
+}
+
 // Finalized creates a fresh clone of delivered view to be used for checking transcations.
 func (s *LedgerState) Finalized() *StoreView {
 	return s.finalized
@@ -121,5 +157,10 @@ func (s *LedgerState) Commit() common.Hash {
 	if err != nil {
 		panic(fmt.Errorf("Commit: failed to copy to the screened view: %v", err))
 	}
+	s.screenedBase, err = s.delivered.Copy()
+	if err != nil {
+		panic(fmt.Errorf("Commit: failed to copy to the screened base view: %v", err))
+	}
+	s.screenedVersion++
 	return hash
 }
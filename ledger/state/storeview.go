@@ -7,6 +7,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/ledger/types"
@@ -15,7 +16,7 @@ import (
 	"github.com/thetatoken/theta/store/treestore"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "ledger"})
+var logger *log.Entry = util.GetLoggerForModule("ledger")
 
 //
 // ------------------------- StoreView -------------------------
@@ -28,20 +29,35 @@ type StoreView struct {
 	coinbaseTransactinProcessed bool
 	slashIntents                []types.SlashIntent
 	refund                      uint64 // Gas refund during smart contract execution
+
+	mintedTFuel  *big.Int // TFuel minted by the block being executed
+	burnedTFuel  *big.Int // TFuel burned by the block being executed
+	bridgedTFuel *big.Int // TFuel minted by the block being executed for the bridge messages
+
+	blockProposer   common.Address   // Proposer of the block being executed, set by the coinbase transaction
+	blockValidators []common.Address // Validators of the block being executed, set by the coinbase transaction
+
+	logs              []*types.Log        // Logs emitted by the smart contract being executed
+	numLogsAtSnapshot map[common.Hash]int // Number of logs emitted when each snapshot was taken
 }
 
 // NewStoreView creates an instance of the StoreView
 func NewStoreView(height uint64, root common.Hash, db database.Database) *StoreView {
-	store := treestore.NewTreeStore(root, db)
+	store := treestore.NewTreeStoreWithHashAlgorithm(root, db, crypto.HasherAtHeight(height).Algorithm())
 	if store == nil {
 		return nil
 	}
 
 	sv := &StoreView{
-		height:       height,
-		store:        store,
-		slashIntents: []types.SlashIntent{},
-		refund:       0,
+		height:            height,
+		store:             store,
+		slashIntents:      []types.SlashIntent{},
+		refund:            0,
+		mintedTFuel:       big.NewInt(0),
+		burnedTFuel:       big.NewInt(0),
+		bridgedTFuel:      big.NewInt(0),
+		logs:              []*types.Log{},
+		numLogsAtSnapshot: make(map[common.Hash]int),
 	}
 	return sv
 }
@@ -53,10 +69,15 @@ func (sv *StoreView) Copy() (*StoreView, error) {
 		return nil, err
 	}
 	copiedStoreView := &StoreView{
-		height:       sv.height,
-		store:        copiedStore,
-		slashIntents: []types.SlashIntent{},
-		refund:       0,
+		height:            sv.height,
+		store:             copiedStore,
+		slashIntents:      []types.SlashIntent{},
+		refund:            0,
+		mintedTFuel:       big.NewInt(0),
+		burnedTFuel:       big.NewInt(0),
+		bridgedTFuel:      big.NewInt(0),
+		logs:              []*types.Log{},
+		numLogsAtSnapshot: make(map[common.Hash]int),
 	}
 	return copiedStoreView, nil
 }
@@ -135,6 +156,60 @@ func (sv *StoreView) SetCoinbaseTransactionProcessed(processed bool) {
 	sv.coinbaseTransactinProcessed = processed
 }
 
+// AddMintedTFuel records TFuel minted by the block being executed, e.g. the block rewards.
+func (sv *StoreView) AddMintedTFuel(amount *big.Int) {
+	if amount != nil {
+		sv.mintedTFuel.Add(sv.mintedTFuel, amount)
+	}
+}
+
+// AddBurnedTFuel records TFuel burned by the block being executed, e.g. the transaction fees.
+func (sv *StoreView) AddBurnedTFuel(amount *big.Int) {
+	if amount != nil {
+		sv.burnedTFuel.Add(sv.burnedTFuel, amount)
+	}
+}
+
+// GetBlockTFuelChanges returns the TFuel minted and burned by the block being executed.
+func (sv *StoreView) GetBlockTFuelChanges() (minted *big.Int, burned *big.Int) {
+	return new(big.Int).Set(sv.mintedTFuel), new(big.Int).Set(sv.burnedTFuel)
+}
+
+// AddBridgedTFuel records TFuel minted by the block being executed for the coins arriving from the
+// counterpart chains of the bridge. Unlike the block rewards, it is not in the supply accounting.
+func (sv *StoreView) AddBridgedTFuel(amount *big.Int) {
+	if amount != nil {
+		sv.bridgedTFuel.Add(sv.bridgedTFuel, amount)
+	}
+}
+
+// GetBlockBridgedTFuel returns the TFuel minted by the block being executed for the bridge messages.
+func (sv *StoreView) GetBlockBridgedTFuel() *big.Int {
+	return new(big.Int).Set(sv.bridgedTFuel)
+}
+
+// SetBlockValidators records the proposer and the validators of the block being executed.
+func (sv *StoreView) SetBlockValidators(proposer common.Address, validators []common.Address) {
+	sv.blockProposer = proposer
+	sv.blockValidators = validators
+}
+
+// GetBlockValidators returns the proposer and the validators of the block being executed, or
+// empty ones if the coinbase transaction of the block has not been processed.
+func (sv *StoreView) GetBlockValidators() (proposer common.Address, validators []common.Address) {
+	return sv.blockProposer, sv.blockValidators
+}
+
+// ResetBlockChanges clears the TFuel minted and burned, and the proposer and the validators,
+// before a block is executed.
+func (sv *StoreView) ResetBlockChanges() {
+	sv.mintedTFuel = big.NewInt(0)
+	sv.burnedTFuel = big.NewInt(0)
+	sv.bridgedTFuel = big.NewInt(0)
+	sv.blockProposer = common.Address{}
+	sv.blockValidators = nil
+}
+
 // GetAccount returns an account.
 func (sv *StoreView) GetAccount(addr common.Address) *types.Account {
 	data := sv.Get(AccountKey(addr))
@@ -277,6 +352,82 @@ func (sv *StoreView) UpdateValidatorCandidatePool(vcp *core.ValidatorCandidatePo
 	sv.Set(ValidatorCandidatePoolKey(), vcpBytes)
 }
 
+// GetGuardianCandidatePool gets the guardian candidate pool.
+func (sv *StoreView) GetGuardianCandidatePool() *core.GuardianCandidatePool {
+	data := sv.Get(GuardianCandidatePoolKey())
+	if data == nil || len(data) == 0 {
+		return core.NewGuardianCandidatePool()
+	}
+	gcp := &core.GuardianCandidatePool{}
+	err := types.FromBytes(data, gcp)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading guardian candidate pool %X, error: %v",
+			data, err.Error()))
+	}
+	return gcp
+}
+
+// UpdateGuardianCandidatePool updates the guardian candidate pool.
+func (sv *StoreView) UpdateGuardianCandidatePool(gcp *core.GuardianCandidatePool) {
+	gcpBytes, err := types.ToBytes(gcp)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing guardian candidate pool %v, error: %v",
+			gcp, err.Error()))
+	}
+	sv.Set(GuardianCandidatePoolKey(), gcpBytes)
+}
+
+// GetChainParams gets the chain parameters, or the default ones if they were never updated.
+func (sv *StoreView) GetChainParams() *types.ChainParams {
+	data := sv.Get(ChainParamsKey())
+	if data == nil || len(data) == 0 {
+		return types.DefaultChainParams()
+	}
+	params := &types.ChainParams{}
+	err := types.FromBytes(data, params)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading chain params %X, error: %v",
+			data, err.Error()))
+	}
+	return params
+}
+
+// SetChainParams sets the chain parameters.
+func (sv *StoreView) SetChainParams(params *types.ChainParams) {
+	paramsBytes, err := types.ToBytes(params)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing chain params %v, error: %v",
+			params, err.Error()))
+	}
+	sv.Set(ChainParamsKey(), paramsBytes)
+}
+
+// GetBaseFee gets the base fee of the next block, or the minimum gas price of the chain params if
+// no block was executed since the fee market was introduced.
+func (sv *StoreView) GetBaseFee() *big.Int {
+	data := sv.Get(BaseFeeKey())
+	if data == nil || len(data) == 0 {
+		return new(big.Int).Set(sv.GetChainParams().MinimumGasPrice)
+	}
+	baseFee := new(big.Int)
+	err := types.FromBytes(data, baseFee)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading base fee %X, error: %v",
+			data, err.Error()))
+	}
+	return baseFee
+}
+
+// SetBaseFee sets the base fee of the next block.
+func (sv *StoreView) SetBaseFee(baseFee *big.Int) {
+	baseFeeBytes, err := types.ToBytes(baseFee)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing base fee %v, error: %v",
+			baseFee, err.Error()))
+	}
+	sv.Set(BaseFeeKey(), baseFeeBytes)
+}
+
 // GetStakeTransactionHeightList gets the heights of blocks that contain stake related transactions
 func (sv *StoreView) GetStakeTransactionHeightList() *types.HeightList {
 	data := sv.Get(StakeTransactionHeightListKey())
@@ -303,6 +454,346 @@ func (sv *StoreView) UpdateStakeTransactionHeightList(hl *types.HeightList) {
 	sv.Set(StakeTransactionHeightListKey(), hlBytes)
 }
 
+// GetGovernanceProposal gets the governance proposal with the given ID, or nil if it does not exist.
+func (sv *StoreView) GetGovernanceProposal(id uint64) *types.GovernanceProposal {
+	data := sv.Get(GovernanceProposalKey(id))
+	if data == nil || len(data) == 0 {
+		return nil
+	}
+	proposal := &types.GovernanceProposal{}
+	err := types.FromBytes(data, proposal)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading governance proposal %X, error: %v",
+			data, err.Error()))
+	}
+	return proposal
+}
+
+// SetGovernanceProposal sets the governance proposal.
+func (sv *StoreView) SetGovernanceProposal(proposal *types.GovernanceProposal) {
+	proposalBytes, err := types.ToBytes(proposal)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing governance proposal %v, error: %v",
+			proposal, err.Error()))
+	}
+	sv.Set(GovernanceProposalKey(proposal.ID), proposalBytes)
+}
+
+// GetNextGovernanceProposalID gets the ID of the next governance proposal. The IDs start from 1.
+func (sv *StoreView) GetNextGovernanceProposalID() uint64 {
+	data := sv.Get(NextGovernanceProposalIDKey())
+	if data == nil || len(data) == 0 {
+		return 1
+	}
+	var id uint64
+	err := types.FromBytes(data, &id)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading next governance proposal ID %X, error: %v",
+			data, err.Error()))
+	}
+	return id
+}
+
+// SetNextGovernanceProposalID sets the ID of the next governance proposal.
+func (sv *StoreView) SetNextGovernanceProposalID(id uint64) {
+	idBytes, err := types.ToBytes(id)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing next governance proposal ID %v, error: %v",
+			id, err.Error()))
+	}
+	sv.Set(NextGovernanceProposalIDKey(), idBytes)
+}
+
+// GetPassedGovernanceProposals gets the IDs of the passed governance proposals pending activation
+func (sv *StoreView) GetPassedGovernanceProposals() *types.GovernanceProposalIDList {
+	data := sv.Get(PassedGovernanceProposalsKey())
+	if data == nil || len(data) == 0 {
+		return &types.GovernanceProposalIDList{}
+	}
+	ids := &types.GovernanceProposalIDList{}
+	err := types.FromBytes(data, ids)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading passed governance proposals %X, error: %v",
+			data, err.Error()))
+	}
+	return ids
+}
+
+// UpdatePassedGovernanceProposals updates the IDs of the passed governance proposals pending activation
+func (sv *StoreView) UpdatePassedGovernanceProposals(ids *types.GovernanceProposalIDList) {
+	idsBytes, err := types.ToBytes(ids)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing passed governance proposals %v, error: %v",
+			ids, err.Error()))
+	}
+	sv.Set(PassedGovernanceProposalsKey(), idsBytes)
+}
+
+// GetBridgeOutboundMessages gets the outbound bridge messages of the block at the given height.
+func (sv *StoreView) GetBridgeOutboundMessages(height uint64) *types.BridgeMessageList {
+	data := sv.Get(BridgeOutboundMessagesKey(height))
+	if data == nil || len(data) == 0 {
+		return &types.BridgeMessageList{}
+	}
+	messages := &types.BridgeMessageList{}
+	err := types.FromBytes(data, messages)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading outbound bridge messages %X, error: %v",
+			data, err.Error()))
+	}
+	return messages
+}
+
+// SetBridgeOutboundMessages sets the outbound bridge messages of the block at the given height.
+func (sv *StoreView) SetBridgeOutboundMessages(height uint64, messages *types.BridgeMessageList) {
+	messagesBytes, err := types.ToBytes(messages)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing outbound bridge messages %v, error: %v",
+			messages, err.Error()))
+	}
+	sv.Set(BridgeOutboundMessagesKey(height), messagesBytes)
+}
+
+// GetNextBridgeNonce gets the nonce of the next outbound bridge message. The nonces start from 1.
+func (sv *StoreView) GetNextBridgeNonce() uint64 {
+	data := sv.Get(NextBridgeNonceKey())
+	if data == nil || len(data) == 0 {
+		return 1
+	}
+	var nonce uint64
+	err := types.FromBytes(data, &nonce)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading next bridge nonce %X, error: %v",
+			data, err.Error()))
+	}
+	return nonce
+}
+
+// SetNextBridgeNonce sets the nonce of the next outbound bridge message.
+func (sv *StoreView) SetNextBridgeNonce(nonce uint64) {
+	nonceBytes, err := types.ToBytes(nonce)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing next bridge nonce %v, error: %v",
+			nonce, err.Error()))
+	}
+	sv.Set(NextBridgeNonceKey(), nonceBytes)
+}
+
+// IsBridgeMessageProcessed returns whether the inbound bridge message with the given nonce from
+// the source chain was processed.
+func (sv *StoreView) IsBridgeMessageProcessed(sourceChainID string, nonce uint64) bool {
+	data := sv.Get(BridgeInboundMessageKey(sourceChainID, nonce))
+	return len(data) > 0
+}
+
+// MarkBridgeMessageProcessed marks the inbound bridge message with the given nonce from the
+// source chain as processed.
+func (sv *StoreView) MarkBridgeMessageProcessed(sourceChainID string, nonce uint64) {
+	sv.Set(BridgeInboundMessageKey(sourceChainID, nonce), common.Bytes{0x01})
+}
+
+// GetBridgeValidators gets the validator set of the counterpart chain, or nil if the chain is
+// not registered.
+func (sv *StoreView) GetBridgeValidators(chainID string) *types.BridgeValidators {
+	data := sv.Get(BridgeValidatorsKey(chainID))
+	if data == nil || len(data) == 0 {
+		return nil
+	}
+	validators := &types.BridgeValidators{}
+	err := types.FromBytes(data, validators)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading bridge validators %X, error: %v",
+			data, err.Error()))
+	}
+	return validators
+}
+
+// SetBridgeValidators sets the validator set of the counterpart chain.
+func (sv *StoreView) SetBridgeValidators(validators *types.BridgeValidators) {
+	validatorsBytes, err := types.ToBytes(validators)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing bridge validators %v, error: %v",
+			validators, err.Error()))
+	}
+	sv.Set(BridgeValidatorsKey(validators.ChainID), validatorsBytes)
+}
+
+// GetChainTime gets the chain time, i.e. the largest timestamp of the blocks executed since the
+// time lock upgrade, in unix seconds. It is 0 if no such block was executed.
+func (sv *StoreView) GetChainTime() uint64 {
+	data := sv.Get(ChainTimeKey())
+	if data == nil || len(data) == 0 {
+		return 0
+	}
+	var chainTime uint64
+	err := types.FromBytes(data, &chainTime)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading chain time %X, error: %v",
+			data, err.Error()))
+	}
+	return chainTime
+}
+
+// SetChainTime sets the chain time.
+func (sv *StoreView) SetChainTime(chainTime uint64) {
+	chainTimeBytes, err := types.ToBytes(chainTime)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing chain time %v, error: %v",
+			chainTime, err.Error()))
+	}
+	sv.Set(ChainTimeKey(), chainTimeBytes)
+}
+
+// GetTimeLock gets the time lock with the given ID, or nil if it does not exist.
+func (sv *StoreView) GetTimeLock(id uint64) *types.TimeLock {
+	data := sv.Get(TimeLockKey(id))
+	if data == nil || len(data) == 0 {
+		return nil
+	}
+	timeLock := &types.TimeLock{}
+	err := types.FromBytes(data, timeLock)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading time lock %X, error: %v",
+			data, err.Error()))
+	}
+	return timeLock
+}
+
+// SetTimeLock sets the time lock.
+func (sv *StoreView) SetTimeLock(timeLock *types.TimeLock) {
+	timeLockBytes, err := types.ToBytes(timeLock)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing time lock %v, error: %v",
+			timeLock, err.Error()))
+	}
+	sv.Set(TimeLockKey(timeLock.ID), timeLockBytes)
+}
+
+// DeleteTimeLock deletes the time lock with the given ID.
+func (sv *StoreView) DeleteTimeLock(id uint64) {
+	sv.Delete(TimeLockKey(id))
+}
+
+// GetNextTimeLockID gets the ID of the next time lock. The IDs start from 1.
+func (sv *StoreView) GetNextTimeLockID() uint64 {
+	data := sv.Get(NextTimeLockIDKey())
+	if data == nil || len(data) == 0 {
+		return 1
+	}
+	var id uint64
+	err := types.FromBytes(data, &id)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading next time lock ID %X, error: %v",
+			data, err.Error()))
+	}
+	return id
+}
+
+// SetNextTimeLockID sets the ID of the next time lock.
+func (sv *StoreView) SetNextTimeLockID(id uint64) {
+	idBytes, err := types.ToBytes(id)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing next time lock ID %v, error: %v",
+			id, err.Error()))
+	}
+	sv.Set(NextTimeLockIDKey(), idBytes)
+}
+
+// GetTFuelSupply gets the TFuel supply accounting, or nil if no block was executed since the
+// supply accounting upgrade.
+func (sv *StoreView) GetTFuelSupply() *types.TFuelSupply {
+	data := sv.Get(TFuelSupplyKey())
+	if data == nil || len(data) == 0 {
+		return nil
+	}
+	supply := &types.TFuelSupply{}
+	err := types.FromBytes(data, supply)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading TFuel supply %X, error: %v",
+			data, err.Error()))
+	}
+	return supply
+}
+
+// SetTFuelSupply sets the TFuel supply accounting.
+func (sv *StoreView) SetTFuelSupply(supply *types.TFuelSupply) {
+	supplyBytes, err := types.ToBytes(supply)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing TFuel supply %v, error: %v",
+			supply, err.Error()))
+	}
+	sv.Set(TFuelSupplyKey(), supplyBytes)
+}
+
+// GetBlockRewards gets the rewards paid by the last block, or nil if none has been paid.
+func (sv *StoreView) GetBlockRewards() *types.BlockRewards {
+	data := sv.Get(BlockRewardsKey())
+	if data == nil || len(data) == 0 {
+		return nil
+	}
+	rewards := &types.BlockRewards{}
+	err := types.FromBytes(data, rewards)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading block rewards %X, error: %v",
+			data, err.Error()))
+	}
+	return rewards
+}
+
+// SetBlockRewards sets the rewards paid by the last block.
+func (sv *StoreView) SetBlockRewards(rewards *types.BlockRewards) {
+	rewardsBytes, err := types.ToBytes(rewards)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing block rewards %v, error: %v",
+			rewards, err.Error()))
+	}
+	sv.Set(BlockRewardsKey(), rewardsBytes)
+}
+
+// GetUnbondingQueue gets the queue of the stakes being unbonded.
+func (sv *StoreView) GetUnbondingQueue() *types.UnbondingQueue {
+	queue := &types.UnbondingQueue{}
+	data := sv.Get(UnbondingQueueKey())
+	if data == nil || len(data) == 0 {
+		return queue
+	}
+	err := types.FromBytes(data, queue)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading unbonding queue %X, error: %v",
+			data, err.Error()))
+	}
+	return queue
+}
+
+// UpdateUnbondingQueue updates the queue of the stakes being unbonded.
+func (sv *StoreView) UpdateUnbondingQueue(queue *types.UnbondingQueue) {
+	queueBytes, err := types.ToBytes(queue)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing unbonding queue %v, error: %v",
+			queue, err.Error()))
+	}
+	sv.Set(UnbondingQueueKey(), queueBytes)
+}
+
+// TotalTFuel sums the TFuel of all the accounts, including their reserved funds. It traverses
+// the whole account state.
+func (sv *StoreView) TotalTFuel() *big.Int {
+	total := big.NewInt(0)
+	sv.store.Traverse(AccountKeyPrefix(), func(key, value common.Bytes) bool {
+		account := &types.Account{}
+		if err := types.FromBytes(value, account); err != nil {
+			panic(fmt.Sprintf("Error reading account %X, error: %v", value, err.Error()))
+		}
+		total.Add(total, account.Balance.NoNil().TFuelWei)
+		for _, reservedFund := range account.ReservedFunds {
+			remaining := reservedFund.InitialFund.Minus(reservedFund.UsedFund).Plus(reservedFund.Collateral)
+			total.Add(total, remaining.NoNil().TFuelWei)
+		}
+		return true
+	})
+	return total
+}
+
 func (sv *StoreView) GetStore() *treestore.TreeStore {
 	return sv.store
 }
@@ -500,11 +991,18 @@ func (sv *StoreView) RevertToSnapshot(root common.Hash) {
 	if err != nil {
 		panic(err)
 	}
+
+	// Logs emitted after the snapshot are reverted along with the state.
+	if numLogs, ok := sv.numLogsAtSnapshot[root]; ok && numLogs < len(sv.logs) {
+		sv.logs = sv.logs[:numLogs]
+	}
 }
 
 func (sv *StoreView) Snapshot() common.Hash {
 	sv.store.Trie.Commit(nil) // Needs to commit to the in-memory trie DB
-	return sv.store.Hash()
+	root := sv.store.Hash()
+	sv.numLogsAtSnapshot[root] = len(sv.logs)
+	return root
 }
 
 func (sv *StoreView) Prune() bool {
@@ -531,6 +1029,14 @@ func (sv *StoreView) Prune() bool {
 	return true
 }
 
-func (sv *StoreView) AddLog(*types.Log) {
-	// TODO
+func (sv *StoreView) AddLog(l *types.Log) {
+	sv.logs = append(sv.logs, l)
+}
+
+// PopLogs returns the logs emitted since the last call and resets the log list.
+func (sv *StoreView) PopLogs() []*types.Log {
+	logs := sv.logs
+	sv.logs = []*types.Log{}
+	sv.numLogsAtSnapshot = make(map[common.Hash]int)
+	return logs
 }
@@ -0,0 +1,135 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+//
+// orderedTx holds the sort keys of a regular transaction in the canonical block order. Since the
+// transactions of a sender need to execute in sequence order, a transaction is prioritized by the
+// lowest effective gas price among the transactions of its sender up to its sequence, which never
+// increases along the sequences of a sender.
+//
+type orderedTx struct {
+	rawTx    common.Bytes
+	price    *big.Int
+	priority *big.Int
+	sender   common.Address
+	sequence uint64
+	hash     common.Hash
+}
+
+// canonicalTxOrder returns the regular transactions in the canonical block order: by decreasing
+// priority, then by sender, then by increasing sequence, then by hash. The order depends only on
+// the transactions themselves, so every node derives the same order from the same transactions.
+// A transaction without TxInfo, e.g. of an unknown type, is ordered as a transaction of zero
+// price from the empty address.
+func canonicalTxOrder(rawTxs []common.Bytes, getTxInfo func(rawTx common.Bytes) *core.TxInfo) []common.Bytes {
+	ordered := make([]*orderedTx, len(rawTxs))
+	for idx, rawTx := range rawTxs {
+		otx := &orderedTx{
+			rawTx: rawTx,
+			price: big.NewInt(0),
+			hash:  crypto.Keccak256Hash(rawTx),
+		}
+		if txInfo := getTxInfo(rawTx); txInfo != nil {
+			if txInfo.EffectiveGasPrice != nil {
+				otx.price = txInfo.EffectiveGasPrice
+			}
+			otx.sender = txInfo.Address
+			otx.sequence = txInfo.Sequence
+		}
+		ordered[idx] = otx
+	}
+
+	// The priority of a transaction is the running min of the prices along the sequences of its sender
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return compareSenderSequence(ordered[i], ordered[j]) < 0
+	})
+	for idx, otx := range ordered {
+		otx.priority = otx.price
+		if idx > 0 && ordered[idx-1].sender == otx.sender && ordered[idx-1].priority.Cmp(otx.priority) < 0 {
+			otx.priority = ordered[idx-1].priority
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if c := ordered[i].priority.Cmp(ordered[j].priority); c != 0 {
+			return c > 0
+		}
+		return compareSenderSequence(ordered[i], ordered[j]) < 0
+	})
+
+	ret := make([]common.Bytes, len(ordered))
+	for idx, otx := range ordered {
+		ret[idx] = otx.rawTx
+	}
+	return ret
+}
+
+func compareSenderSequence(a *orderedTx, b *orderedTx) int {
+	if c := bytes.Compare(a.sender[:], b.sender[:]); c != 0 {
+		return c
+	}
+	if a.sequence != b.sequence {
+		if a.sequence < b.sequence {
+			return -1
+		}
+		return 1
+	}
+	return bytes.Compare(a.hash[:], b.hash[:])
+}
+
+// isCanonicalTxOrder returns true if the regular transactions are in the canonical block order.
+func isCanonicalTxOrder(rawTxs []common.Bytes, getTxInfo func(rawTx common.Bytes) *core.TxInfo) bool {
+	ordered := canonicalTxOrder(rawTxs, getTxInfo)
+	for idx := range rawTxs {
+		if !bytes.Equal(rawTxs[idx], ordered[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCanonicalTxOrder returns an error if the regular transactions of the block are not in the
+// canonical order. The special transactions are not ordered.
+func (ledger *Ledger) checkCanonicalTxOrder(blockRawTxs []common.Bytes) error {
+	regularRawTxs := []common.Bytes{}
+	for _, rawTx := range blockRawTxs {
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			return fmt.Errorf("Failed to parse transaction: %v", hex.EncodeToString(rawTx))
+		}
+		if !isSpecialTx(tx) {
+			regularRawTxs = append(regularRawTxs, rawTx)
+		}
+	}
+	if !isCanonicalTxOrder(regularRawTxs, ledger.regularTxInfo) {
+		return errors.New("The regular transactions of the block are not in the canonical order")
+	}
+	return nil
+}
+
+// regularTxInfo returns the TxInfo the canonical block order of the regular transaction is
+// based on, or nil if the transaction cannot be parsed.
+func (ledger *Ledger) regularTxInfo(rawTx common.Bytes) *core.TxInfo {
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil
+	}
+	txInfo, res := ledger.executor.GetTxInfo(tx)
+	if res.IsError() {
+		return nil
+	}
+	return txInfo
+}
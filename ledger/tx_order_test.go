@@ -0,0 +1,52 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+func TestCanonicalTxOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	addrA := common.HexToAddress("A1")
+	addrB := common.HexToAddress("B1")
+	addrC := common.HexToAddress("C1")
+	txInfos := map[string]*core.TxInfo{
+		"a1": {Address: addrA, Sequence: 1, EffectiveGasPrice: big.NewInt(10)},
+		"a2": {Address: addrA, Sequence: 2, EffectiveGasPrice: big.NewInt(50)},
+		"a3": {Address: addrA, Sequence: 3, EffectiveGasPrice: big.NewInt(5)},
+		"b1": {Address: addrB, Sequence: 1, EffectiveGasPrice: big.NewInt(20)},
+		"c1": {Address: addrC, Sequence: 1, EffectiveGasPrice: big.NewInt(10)},
+	}
+	getTxInfo := func(rawTx common.Bytes) *core.TxInfo {
+		return txInfos[string(rawTx)]
+	}
+	toStrings := func(rawTxs []common.Bytes) []string {
+		ret := []string{}
+		for _, rawTx := range rawTxs {
+			ret = append(ret, string(rawTx))
+		}
+		return ret
+	}
+
+	// The pricier a2 cannot precede a1, so it shares its priority, and ties are broken by sender.
+	// Unknown transactions have zero priority.
+	rawTxs := []common.Bytes{
+		common.Bytes("a3"), common.Bytes("unknown"), common.Bytes("c1"),
+		common.Bytes("a2"), common.Bytes("b1"), common.Bytes("a1"),
+	}
+	ordered := canonicalTxOrder(rawTxs, getTxInfo)
+	assert.Equal([]string{"b1", "a1", "a2", "c1", "a3", "unknown"}, toStrings(ordered))
+	assert.True(isCanonicalTxOrder(ordered, getTxInfo))
+	assert.False(isCanonicalTxOrder(rawTxs, getTxInfo))
+
+	// Dropping the last transactions of a sender keeps the order canonical
+	assert.True(isCanonicalTxOrder(ordered[:4], getTxInfo))
+	assert.True(isCanonicalTxOrder([]common.Bytes{ordered[0], ordered[3]}, getTxInfo))
+	assert.True(isCanonicalTxOrder([]common.Bytes{}, getTxInfo))
+}
@@ -0,0 +1,421 @@
+// Package txbuilder constructs, signs and serializes transactions without a running node, e.g.
+// to prepare transactions on an online machine and sign them on an offline one. It only depends
+// on the transaction types, not on the ledger state or the network.
+//
+// The coinbase and slash transactions are created by the block proposers, and are not covered.
+package txbuilder
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// Builder constructs the transactions of a chain. The transactions are returned unsigned.
+type Builder struct {
+	chainID string
+	fee     *big.Int // Fee in TFuelWei
+}
+
+// NewBuilder creates a Builder for the chain, which charges the minimum transaction fee.
+func NewBuilder(chainID string) *Builder {
+	return &Builder{
+		chainID: chainID,
+		fee:     new(big.Int).SetUint64(types.MinimumTransactionFeeTFuelWei),
+	}
+}
+
+// WithFee returns a copy of the Builder that charges the given fee in TFuelWei.
+func (b *Builder) WithFee(fee *big.Int) *Builder {
+	return &Builder{
+		chainID: b.chainID,
+		fee:     new(big.Int).Set(fee),
+	}
+}
+
+// ChainID returns the ID of the chain the transactions are built for.
+func (b *Builder) ChainID() string {
+	return b.chainID
+}
+
+func (b *Builder) feeCoins() types.Coins {
+	return types.Coins{
+		ThetaWei: big.NewInt(0),
+		TFuelWei: new(big.Int).Set(b.fee),
+	}
+}
+
+func coins(theta, tfuel *big.Int) types.Coins {
+	return types.Coins{
+		ThetaWei: new(big.Int).Set(theta),
+		TFuelWei: new(big.Int).Set(tfuel),
+	}
+}
+
+// Send builds a SendTx that transfers theta and tfuel from one address to another. The fee is
+// paid by the sender on top of the transferred amount.
+func (b *Builder) Send(from common.Address, sequence uint64, to common.Address, theta, tfuel *big.Int) *types.SendTx {
+	return b.SendToMany(from, sequence, []types.TxOutput{{
+		Address: to,
+		Coins:   coins(theta, tfuel),
+	}})
+}
+
+// SendToMany builds a SendTx that transfers theta and tfuel from one address to each of the
+// outputs in one transaction, e.g. for an exchange payout. The fee grows with the number of
+// outputs, see types.MinimumSendTxFee, and is paid by the sender on top of the transferred amount.
+func (b *Builder) SendToMany(from common.Address, sequence uint64, outputs []types.TxOutput) *types.SendTx {
+	fee := types.MinimumSendTxFee(b.fee, len(outputs))
+	total := coins(big.NewInt(0), fee)
+	for _, output := range outputs {
+		total = total.Plus(output.Coins)
+	}
+	return &types.SendTx{
+		Fee: coins(big.NewInt(0), fee),
+		Inputs: []types.TxInput{{
+			Address:  from,
+			Coins:    total,
+			Sequence: sequence,
+		}},
+		Outputs: outputs,
+	}
+}
+
+// ReserveFund builds a ReserveFundTx that reserves a fund for the service payments of the
+// resources, with the collateral slashed if the fund is overspent.
+func (b *Builder) ReserveFund(source common.Address, sequence uint64, fund, collateral *big.Int,
+	resourceIDs []string, duration uint64) *types.ReserveFundTx {
+	return &types.ReserveFundTx{
+		Fee: b.feeCoins(),
+		Source: types.TxInput{
+			Address:  source,
+			Coins:    coins(big.NewInt(0), fund),
+			Sequence: sequence,
+		},
+		Collateral:  coins(big.NewInt(0), collateral),
+		ResourceIDs: resourceIDs,
+		Duration:    duration,
+	}
+}
+
+// ReleaseFund builds a ReleaseFundTx that releases the expired fund reserved at the reserve sequence.
+func (b *Builder) ReleaseFund(source common.Address, sequence uint64, reserveSequence uint64) *types.ReleaseFundTx {
+	return &types.ReleaseFundTx{
+		Fee: b.feeCoins(),
+		Source: types.TxInput{
+			Address:  source,
+			Sequence: sequence,
+		},
+		ReserveSequence: reserveSequence,
+	}
+}
+
+// ServicePayment builds a ServicePaymentTx that pays the target from the fund the source reserved
+// at the reserve sequence. The target submits the transaction and pays the fee. The source signs
+// first with SignServicePaymentSource, then the target with SignServicePaymentTarget.
+func (b *Builder) ServicePayment(source common.Address, target common.Address, targetSequence uint64,
+	amount *big.Int, paymentSequence, reserveSequence uint64, resourceID string) *types.ServicePaymentTx {
+	return &types.ServicePaymentTx{
+		Fee: b.feeCoins(),
+		Source: types.TxInput{
+			Address: source,
+			Coins:   coins(big.NewInt(0), amount),
+		},
+		Target: types.TxInput{
+			Address:  target,
+			Sequence: targetSequence,
+		},
+		PaymentSequence: paymentSequence,
+		ReserveSequence: reserveSequence,
+		ResourceID:      resourceID,
+	}
+}
+
+// SplitRule builds a SplitRuleTx that splits the service payments of the resource among the
+// addresses of the splits for the duration in blocks.
+func (b *Builder) SplitRule(initiator common.Address, sequence uint64, resourceID string,
+	splits []types.Split, duration uint64) *types.SplitRuleTx {
+	return &types.SplitRuleTx{
+		Fee:        b.feeCoins(),
+		ResourceID: resourceID,
+		Initiator: types.TxInput{
+			Address:  initiator,
+			Sequence: sequence,
+		},
+		Splits:   splits,
+		Duration: duration,
+	}
+}
+
+// SmartContract builds a SmartContractTx that calls the contract at the to address, or deploys
+// the contract in data if to is the zero address. The fee is paid in gas.
+func (b *Builder) SmartContract(from common.Address, sequence uint64, to common.Address, value *big.Int,
+	gasLimit uint64, gasPrice *big.Int, data common.Bytes) *types.SmartContractTx {
+	return &types.SmartContractTx{
+		From: types.TxInput{
+			Address:  from,
+			Coins:    coins(big.NewInt(0), value),
+			Sequence: sequence,
+		},
+		To: types.TxOutput{
+			Address: to,
+		},
+		GasLimit: gasLimit,
+		GasPrice: new(big.Int).Set(gasPrice),
+		Data:     data,
+	}
+}
+
+// DepositStake builds a DepositStakeTx that stakes theta to the holder for the purpose, e.g.
+// core.StakeForValidator.
+func (b *Builder) DepositStake(source common.Address, sequence uint64, holder common.Address,
+	stake *big.Int, purpose uint8) *types.DepositStakeTx {
+	return &types.DepositStakeTx{
+		Fee: b.feeCoins(),
+		Source: types.TxInput{
+			Address:  source,
+			Coins:    coins(stake, big.NewInt(0)),
+			Sequence: sequence,
+		},
+		Holder: types.TxOutput{
+			Address: holder,
+		},
+		Purpose: purpose,
+	}
+}
+
+// DepositGuardianStake builds a DepositStakeTxV2 that stakes theta to a guardian, along with the
+// BLS key of the guardian and its signature binding the key to its address.
+func (b *Builder) DepositGuardianStake(source common.Address, sequence uint64, holder common.Address,
+	stake *big.Int, purpose uint8, blsPubkey, blsPop common.Bytes, holderSig *crypto.Signature) *types.DepositStakeTxV2 {
+	return &types.DepositStakeTxV2{
+		Fee: b.feeCoins(),
+		Source: types.TxInput{
+			Address:  source,
+			Coins:    coins(stake, big.NewInt(0)),
+			Sequence: sequence,
+		},
+		Holder: types.TxOutput{
+			Address: holder,
+		},
+		Purpose:   purpose,
+		BlsPubkey: blsPubkey,
+		BlsPop:    blsPop,
+		HolderSig: holderSig,
+	}
+}
+
+// WithdrawStake builds a WithdrawStakeTx that withdraws the stake of the source from the holder.
+func (b *Builder) WithdrawStake(source common.Address, sequence uint64, holder common.Address, purpose uint8) *types.WithdrawStakeTx {
+	return &types.WithdrawStakeTx{
+		Fee: b.feeCoins(),
+		Source: types.TxInput{
+			Address:  source,
+			Sequence: sequence,
+		},
+		Holder: types.TxOutput{
+			Address: holder,
+		},
+		Purpose: purpose,
+	}
+}
+
+// Delegate builds a DelegateTx that delegates theta of the delegator to the validator candidate.
+func (b *Builder) Delegate(delegator common.Address, sequence uint64, validator common.Address, stake *big.Int) *types.DelegateTx {
+	return &types.DelegateTx{
+		Fee: b.feeCoins(),
+		Delegator: types.TxInput{
+			Address:  delegator,
+			Coins:    coins(stake, big.NewInt(0)),
+			Sequence: sequence,
+		},
+		Validator: validator,
+	}
+}
+
+// Undelegate builds an UndelegateTx that undelegates the stake of the delegator from the
+// validator candidate.
+func (b *Builder) Undelegate(delegator common.Address, sequence uint64, validator common.Address) *types.UndelegateTx {
+	return &types.UndelegateTx{
+		Fee: b.feeCoins(),
+		Delegator: types.TxInput{
+			Address:  delegator,
+			Sequence: sequence,
+		},
+		Validator: validator,
+	}
+}
+
+// Governance builds a GovernanceTx that proposes the chain parameters. The proposer and each
+// approver must sign the transaction.
+func (b *Builder) Governance(proposer common.Address, sequence uint64, params types.ChainParams,
+	approvers []common.Address) *types.GovernanceTx {
+	approvals := make([]types.GovernanceApproval, len(approvers))
+	for i, approver := range approvers {
+		approvals[i] = types.GovernanceApproval{Address: approver}
+	}
+	return &types.GovernanceTx{
+		Fee: b.feeCoins(),
+		Proposer: types.TxInput{
+			Address:  proposer,
+			Sequence: sequence,
+		},
+		Params:    params,
+		Approvals: approvals,
+	}
+}
+
+// GovernanceProposal builds a GovernanceProposalTx that proposes the chain parameters to take
+// effect at the activation height.
+func (b *Builder) GovernanceProposal(proposer common.Address, sequence uint64, params types.ChainParams,
+	activationHeight uint64) *types.GovernanceProposalTx {
+	return &types.GovernanceProposalTx{
+		Fee: b.feeCoins(),
+		Proposer: types.TxInput{
+			Address:  proposer,
+			Sequence: sequence,
+		},
+		Params:           params,
+		ActivationHeight: activationHeight,
+	}
+}
+
+// GovernanceVote builds a GovernanceVoteTx that votes for or against the governance proposal.
+func (b *Builder) GovernanceVote(voter common.Address, sequence uint64, proposalID uint64, approve bool) *types.GovernanceVoteTx {
+	return &types.GovernanceVoteTx{
+		Fee: b.feeCoins(),
+		Voter: types.TxInput{
+			Address:  voter,
+			Sequence: sequence,
+		},
+		ProposalID: proposalID,
+		Approve:    approve,
+	}
+}
+
+// GovernanceTally builds a GovernanceTallyTx that settles the outcome of the governance proposal.
+func (b *Builder) GovernanceTally(caller common.Address, sequence uint64, proposalID uint64) *types.GovernanceTallyTx {
+	return &types.GovernanceTallyTx{
+		Fee: b.feeCoins(),
+		Caller: types.TxInput{
+			Address:  caller,
+			Sequence: sequence,
+		},
+		ProposalID: proposalID,
+	}
+}
+
+// BridgeLock builds a BridgeLockTx that locks the coins of the source to send them to the
+// recipient on the counterpart chain.
+func (b *Builder) BridgeLock(source common.Address, sequence uint64, destChainID string, recipient common.Address,
+	thetaWei, tfuelWei *big.Int) *types.BridgeLockTx {
+	return &types.BridgeLockTx{
+		Fee: b.feeCoins(),
+		Source: types.TxInput{
+			Address:  source,
+			Coins:    coins(thetaWei, tfuelWei),
+			Sequence: sequence,
+		},
+		DestChainID: destChainID,
+		Recipient:   recipient,
+	}
+}
+
+// BridgeMint builds a BridgeMintTx that relays the message sent from the counterpart chain,
+// along with its Merkle proof and the co-signatures of the batch by the counterpart validators.
+func (b *Builder) BridgeMint(relayer common.Address, sequence uint64, message types.BridgeMessage,
+	batch core.BridgeBatch, index uint64, proof []common.Hash, sigs []core.BridgeSignature) *types.BridgeMintTx {
+	return &types.BridgeMintTx{
+		Fee: b.feeCoins(),
+		Relayer: types.TxInput{
+			Address:  relayer,
+			Sequence: sequence,
+		},
+		Message:    message,
+		Batch:      batch,
+		Index:      index,
+		Proof:      proof,
+		Signatures: sigs,
+	}
+}
+
+// BridgeValidators builds a BridgeValidatorsTx that registers the validator set of the
+// counterpart chain. The proposer and each approver must sign the transaction.
+func (b *Builder) BridgeValidators(proposer common.Address, sequence uint64, validators types.BridgeValidators,
+	approvers []common.Address) *types.BridgeValidatorsTx {
+	approvals := make([]types.GovernanceApproval, len(approvers))
+	for i, approver := range approvers {
+		approvals[i] = types.GovernanceApproval{Address: approver}
+	}
+	return &types.BridgeValidatorsTx{
+		Fee: b.feeCoins(),
+		Proposer: types.TxInput{
+			Address:  proposer,
+			Sequence: sequence,
+		},
+		Validators: validators,
+		Approvals:  approvals,
+	}
+}
+
+// Sponsored builds a SponsoredTx whose sponsor pays the fee of the inner transaction, e.g. for a
+// dapp to subsidize the fees of its users. The inner transaction must be signed by its sender
+// before being wrapped, and the sponsor signs the wrapper.
+func (b *Builder) Sponsored(sponsor common.Address, inner types.Tx) (*types.SponsoredTx, error) {
+	raw, err := types.TxToBytes(inner)
+	if err != nil {
+		return nil, err
+	}
+	return &types.SponsoredTx{
+		Sponsor: sponsor,
+		Tx:      raw,
+	}, nil
+}
+
+// TimeLock builds a TimeLockTx that locks the coins of the source for the recipient until both
+// the release height and the release time are reached, either of which can be 0. The revoker can
+// return the coins to the source before the release, and can be empty for an irrevocable lock.
+func (b *Builder) TimeLock(source common.Address, sequence uint64, recipient, revoker common.Address,
+	releaseHeight, releaseTime uint64, thetaWei, tfuelWei *big.Int) *types.TimeLockTx {
+	return &types.TimeLockTx{
+		Fee: b.feeCoins(),
+		Source: types.TxInput{
+			Address:  source,
+			Coins:    coins(thetaWei, tfuelWei),
+			Sequence: sequence,
+		},
+		Recipient:     recipient,
+		Revoker:       revoker,
+		ReleaseHeight: releaseHeight,
+		ReleaseTime:   releaseTime,
+	}
+}
+
+// TimeLockClaim builds a TimeLockClaimTx that transfers the coins of the released time lock to
+// its recipient.
+func (b *Builder) TimeLockClaim(recipient common.Address, sequence uint64, lockID uint64) *types.TimeLockClaimTx {
+	return &types.TimeLockClaimTx{
+		Fee: b.feeCoins(),
+		Recipient: types.TxInput{
+			Address:  recipient,
+			Sequence: sequence,
+		},
+		LockID: lockID,
+	}
+}
+
+// TimeLockRevoke builds a TimeLockRevokeTx that returns the coins of the time lock to its source
+// before the release.
+func (b *Builder) TimeLockRevoke(revoker common.Address, sequence uint64, lockID uint64) *types.TimeLockRevokeTx {
+	return &types.TimeLockRevokeTx{
+		Fee: b.feeCoins(),
+		Revoker: types.TxInput{
+			Address:  revoker,
+			Sequence: sequence,
+		},
+		LockID: lockID,
+	}
+}
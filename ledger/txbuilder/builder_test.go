@@ -0,0 +1,113 @@
+package txbuilder
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+const chainID = "testchain"
+
+func newTestSigner() *KeySigner {
+	privKey, _, err := crypto.GenerateKeyPair()
+	if err != nil {
+		panic(err)
+	}
+	return NewKeySigner(privKey)
+}
+
+func TestSendTxOfflineSigning(t *testing.T) {
+	assert := assert.New(t)
+
+	alice := newTestSigner()
+	bob := common.HexToAddress("0x9F1233798E905E173560071255140b4A8aBd3Ec6")
+
+	// Built on the online machine.
+	tx := NewBuilder(chainID).Send(alice.Address(), 3, bob, big.NewInt(10), big.NewInt(20))
+	assert.True(tx.Inputs[0].ValidateBasic().IsOK())
+	fee := new(big.Int).SetUint64(types.MinimumTransactionFeeTFuelWei)
+	assert.Equal(0, new(big.Int).Add(big.NewInt(20), fee).Cmp(tx.Inputs[0].Coins.TFuelWei))
+	unsigned, err := Encode(tx)
+	assert.Nil(err)
+
+	// Signed on the offline machine.
+	decoded, err := Decode(unsigned)
+	assert.Nil(err)
+	offlineTx, ok := decoded.(*types.SendTx)
+	assert.True(ok)
+	assert.Nil(Sign(chainID, offlineTx, alice))
+	signed, err := Encode(offlineTx)
+	assert.Nil(err)
+
+	// Broadcast from the online machine.
+	decoded, err = Decode("0x" + signed)
+	assert.Nil(err)
+	signedTx := decoded.(*types.SendTx)
+	assert.True(signedTx.Inputs[0].Signature.Verify(signedTx.SignBytes(chainID), alice.Address()))
+	assert.Equal(uint64(3), signedTx.Inputs[0].Sequence)
+
+	// Only the inputs can sign.
+	assert.NotNil(Sign(chainID, signedTx, newTestSigner()))
+}
+
+func TestGovernanceTxSigners(t *testing.T) {
+	assert := assert.New(t)
+
+	proposer := newTestSigner()
+	approver1 := newTestSigner()
+	approver2 := newTestSigner()
+	tx := NewBuilder(chainID).WithFee(big.NewInt(1e15)).Governance(proposer.Address(), 1,
+		*types.DefaultChainParams(), []common.Address{approver1.Address(), approver2.Address()})
+	assert.Equal(0, big.NewInt(1e15).Cmp(tx.Fee.TFuelWei))
+
+	// Signatures are collected in any order.
+	assert.Nil(Sign(chainID, tx, approver2))
+	assert.Nil(Sign(chainID, tx, proposer, approver1))
+
+	signBytes := tx.SignBytes(chainID)
+	assert.True(tx.Proposer.Signature.Verify(signBytes, proposer.Address()))
+	assert.True(tx.Approvals[0].Signature.Verify(signBytes, approver1.Address()))
+	assert.True(tx.Approvals[1].Signature.Verify(signBytes, approver2.Address()))
+}
+
+func TestServicePaymentTxSigning(t *testing.T) {
+	assert := assert.New(t)
+
+	source := newTestSigner()
+	target := newTestSigner()
+	tx := NewBuilder(chainID).ServicePayment(source.Address(), target.Address(), 5, big.NewInt(100), 1, 2, "rid")
+
+	assert.NotNil(SignServicePaymentTarget(chainID, tx, target))
+	assert.NotNil(SignServicePaymentSource(chainID, tx, target))
+	assert.Nil(SignServicePaymentSource(chainID, tx, source))
+	assert.Nil(SignServicePaymentTarget(chainID, tx, target))
+
+	assert.True(tx.Source.Signature.Verify(tx.SourceSignBytes(chainID), source.Address()))
+	assert.True(tx.Target.Signature.Verify(tx.TargetSignBytes(chainID), target.Address()))
+}
+
+func TestSendToMany(t *testing.T) {
+	assert := assert.New(t)
+
+	alice := newTestSigner()
+	outputs := []types.TxOutput{
+		{Address: common.HexToAddress("0x9F1233798E905E173560071255140b4A8aBd3Ec6"), Coins: types.NewCoins(10, 20)},
+		{Address: common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"), Coins: types.NewCoins(0, 30)},
+		{Address: common.HexToAddress("0x70f587259738cB626A1720Af7038B8DcDb6a42a0"), Coins: types.Coins{TFuelWei: big.NewInt(40)}},
+	}
+	tx := NewBuilder(chainID).SendToMany(alice.Address(), 1, outputs)
+
+	fee := types.MinimumSendTxFee(new(big.Int).SetUint64(types.MinimumTransactionFeeTFuelWei), 3)
+	assert.Equal(0, fee.Cmp(tx.Fee.TFuelWei))
+	assert.Equal(1, len(tx.Inputs))
+	assert.Equal(3, len(tx.Outputs))
+	assert.Equal(0, big.NewInt(10).Cmp(tx.Inputs[0].Coins.ThetaWei))
+	assert.Equal(0, new(big.Int).Add(big.NewInt(90), fee).Cmp(tx.Inputs[0].Coins.TFuelWei))
+
+	assert.Nil(Sign(chainID, tx, alice))
+	assert.True(tx.Inputs[0].Signature.Verify(tx.SignBytes(chainID), alice.Address()))
+}
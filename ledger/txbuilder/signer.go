@@ -0,0 +1,110 @@
+package txbuilder
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// Signer signs transactions on behalf of an address, e.g. with a key kept on an offline machine
+// or in a hardware wallet.
+type Signer interface {
+	Address() common.Address
+	Sign(data common.Bytes) (*crypto.Signature, error)
+}
+
+// KeySigner signs with a private key held in memory.
+type KeySigner struct {
+	privKey *crypto.PrivateKey
+}
+
+var _ Signer = (*KeySigner)(nil)
+
+// NewKeySigner creates an instance of KeySigner.
+func NewKeySigner(privKey *crypto.PrivateKey) *KeySigner {
+	return &KeySigner{privKey: privKey}
+}
+
+// Address returns the address of the private key.
+func (s *KeySigner) Address() common.Address {
+	return s.privKey.PublicKey().Address()
+}
+
+// Sign signs the data with the private key.
+func (s *KeySigner) Sign(data common.Bytes) (*crypto.Signature, error) {
+	return s.privKey.Sign(data)
+}
+
+// SignableTx is a transaction signed by the addresses of its inputs.
+type SignableTx interface {
+	types.Tx
+	SetSignature(addr common.Address, sig *crypto.Signature) bool
+}
+
+// Sign signs the transaction with each signer. Signers can sign in any order, and the signatures
+// of transactions with several signers, e.g. GovernanceTx, can be collected on different machines.
+func Sign(chainID string, tx SignableTx, signers ...Signer) error {
+	for _, signer := range signers {
+		sig, err := signer.Sign(tx.SignBytes(chainID))
+		if err != nil {
+			return err
+		}
+		if !tx.SetSignature(signer.Address(), sig) {
+			return fmt.Errorf("%v is not a signer of the transaction", signer.Address().Hex())
+		}
+	}
+	return nil
+}
+
+// SignServicePaymentSource signs the service payment with the key of the source. The source
+// signs off-chain before handing the transaction over to the target.
+func SignServicePaymentSource(chainID string, tx *types.ServicePaymentTx, signer Signer) error {
+	if tx.Source.Address != signer.Address() {
+		return fmt.Errorf("%v is not the source of the service payment", signer.Address().Hex())
+	}
+	sig, err := signer.Sign(tx.SourceSignBytes(chainID))
+	if err != nil {
+		return err
+	}
+	tx.SetSourceSignature(sig)
+	return nil
+}
+
+// SignServicePaymentTarget signs the service payment with the key of the target, after the
+// source has signed.
+func SignServicePaymentTarget(chainID string, tx *types.ServicePaymentTx, signer Signer) error {
+	if tx.Target.Address != signer.Address() {
+		return fmt.Errorf("%v is not the target of the service payment", signer.Address().Hex())
+	}
+	if tx.Source.Signature == nil || tx.Source.Signature.IsEmpty() {
+		return fmt.Errorf("Service payment must be signed by the source first")
+	}
+	sig, err := signer.Sign(tx.TargetSignBytes(chainID))
+	if err != nil {
+		return err
+	}
+	tx.SetTargetSignature(sig)
+	return nil
+}
+
+// Encode serializes the transaction into the hex string accepted by the BroadcastRawTransaction RPC.
+func Encode(tx types.Tx) (string, error) {
+	raw, err := types.TxToBytes(tx)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// Decode parses a transaction serialized by Encode, e.g. to sign it on another machine.
+func Decode(encoded string) (types.Tx, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return types.TxFromBytes(raw)
+}
@@ -0,0 +1,86 @@
+package types
+
+import (
+	"math/big"
+)
+
+const (
+	// BaseFeeElasticity is the ratio between the block gas limit and the gas targeted by the base fee
+	BaseFeeElasticity uint64 = 2
+
+	// BaseFeeChangeDenominator bounds the change of the base fee between two blocks to 1/8
+	BaseFeeChangeDenominator uint64 = 8
+)
+
+// NextBaseFee returns the base fee of the block following a block with the given base fee and
+// gas used. The base fee increases when the block uses more than the target gas, i.e. half of
+// the block gas limit, and decreases otherwise, by at most 1/BaseFeeChangeDenominator. It never
+// drops below the minimum gas price of the chain params.
+func NextBaseFee(params *ChainParams, baseFee *big.Int, gasUsed uint64) *big.Int {
+	minBaseFee := params.MinimumGasPrice
+	if baseFee == nil || baseFee.Cmp(minBaseFee) < 0 {
+		baseFee = minBaseFee
+	}
+	targetGas := params.MaxBlockGas / BaseFeeElasticity
+	if targetGas == 0 || gasUsed == targetGas {
+		return new(big.Int).Set(baseFee)
+	}
+
+	var gasDelta uint64
+	if gasUsed > targetGas {
+		gasDelta = gasUsed - targetGas
+	} else {
+		gasDelta = targetGas - gasUsed
+	}
+	// delta = baseFee * gasDelta / targetGas / BaseFeeChangeDenominator
+	delta := new(big.Int).Mul(baseFee, new(big.Int).SetUint64(gasDelta))
+	delta.Div(delta, new(big.Int).SetUint64(targetGas))
+	delta.Div(delta, new(big.Int).SetUint64(BaseFeeChangeDenominator))
+
+	if gasUsed > targetGas {
+		// The base fee always increases for a busy block, even if it is zero
+		if delta.Sign() == 0 {
+			delta.SetUint64(1)
+		}
+		return delta.Add(baseFee, delta)
+	}
+	next := delta.Sub(baseFee, delta)
+	if next.Cmp(minBaseFee) < 0 {
+		next.Set(minBaseFee)
+	}
+	return next
+}
+
+// MinimumTransactionFeeWithBaseFee returns the min fee of a regular transaction under the given
+// base fee. The min fee of the chain params scales with the base fee relative to the minimum gas
+// price.
+func MinimumTransactionFeeWithBaseFee(params *ChainParams, baseFee *big.Int) *big.Int {
+	minFee := new(big.Int).Set(params.MinimumTransactionFeeTFuelWei)
+	if baseFee == nil || params.MinimumGasPrice.Sign() == 0 || baseFee.Cmp(params.MinimumGasPrice) <= 0 {
+		return minFee
+	}
+	minFee.Mul(minFee, baseFee)
+	return minFee.Div(minFee, params.MinimumGasPrice)
+}
+
+// MinimumSendTxFee returns the min fee of a SendTx paying the given number of outputs, given the
+// min fee of a regular transaction. Each output beyond the first adds 1/BatchSendOutputFeeDivisor
+// of the min fee, so that paying many recipients at once, e.g. an exchange payout, costs a
+// fraction of paying each of them in a separate transaction.
+func MinimumSendTxFee(minFee *big.Int, numOutputs int) *big.Int {
+	fee := new(big.Int).Set(minFee)
+	if numOutputs <= 1 {
+		return fee
+	}
+	extra := new(big.Int).Mul(minFee, big.NewInt(int64(numOutputs-1)))
+	extra.Div(extra, big.NewInt(BatchSendOutputFeeDivisor))
+	return fee.Add(fee, extra)
+}
+
+// MinimumGasPriceWithBaseFee returns the min gas price of a smart contract transaction under the given base fee
+func MinimumGasPriceWithBaseFee(params *ChainParams, baseFee *big.Int) *big.Int {
+	if baseFee == nil || baseFee.Cmp(params.MinimumGasPrice) < 0 {
+		return new(big.Int).Set(params.MinimumGasPrice)
+	}
+	return new(big.Int).Set(baseFee)
+}
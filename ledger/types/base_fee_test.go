@@ -0,0 +1,60 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextBaseFee(t *testing.T) {
+	assert := assert.New(t)
+
+	params := DefaultChainParams()
+	minBaseFee := new(big.Int).SetUint64(MinimumGasPrice)
+	targetGas := params.MaxBlockGas / BaseFeeElasticity
+
+	// Unchanged at the target gas
+	assert.Equal(minBaseFee, NextBaseFee(params, minBaseFee, targetGas))
+
+	// Full block, increased by 1/8
+	assert.Equal(big.NewInt(112500000), NextBaseFee(params, minBaseFee, params.MaxBlockGas))
+
+	// Empty block, decreased by 1/8 but never below the minimum gas price
+	assert.Equal(big.NewInt(175000000), NextBaseFee(params, big.NewInt(200000000), 0))
+	assert.Equal(minBaseFee, NextBaseFee(params, minBaseFee, 0))
+	assert.Equal(minBaseFee, NextBaseFee(params, nil, targetGas))
+
+	// A zero base fee still increases for a busy block
+	params.MinimumGasPrice = big.NewInt(0)
+	assert.Equal(big.NewInt(1), NextBaseFee(params, big.NewInt(0), params.MaxBlockGas))
+}
+
+func TestMinimumFeesWithBaseFee(t *testing.T) {
+	assert := assert.New(t)
+
+	params := DefaultChainParams()
+	minFee := new(big.Int).SetUint64(MinimumTransactionFeeTFuelWei)
+	minGasPrice := new(big.Int).SetUint64(MinimumGasPrice)
+
+	assert.Equal(minFee, MinimumTransactionFeeWithBaseFee(params, nil))
+	assert.Equal(minFee, MinimumTransactionFeeWithBaseFee(params, big.NewInt(1)))
+	assert.Equal(new(big.Int).Mul(minFee, big.NewInt(2)),
+		MinimumTransactionFeeWithBaseFee(params, new(big.Int).Mul(minGasPrice, big.NewInt(2))))
+
+	assert.Equal(minGasPrice, MinimumGasPriceWithBaseFee(params, nil))
+	assert.Equal(minGasPrice, MinimumGasPriceWithBaseFee(params, big.NewInt(1)))
+	assert.Equal(big.NewInt(300000000), MinimumGasPriceWithBaseFee(params, big.NewInt(300000000)))
+}
+
+func TestMinimumSendTxFee(t *testing.T) {
+	assert := assert.New(t)
+
+	minFee := new(big.Int).SetUint64(MinimumTransactionFeeTFuelWei)
+	assert.Equal(minFee, MinimumSendTxFee(minFee, 0))
+	assert.Equal(minFee, MinimumSendTxFee(minFee, 1))
+	assert.Equal(big.NewInt(1100000000000), MinimumSendTxFee(minFee, 2))
+
+	// A payout to 100 recipients costs 10.9 times the min fee instead of 100 times
+	assert.Equal(big.NewInt(10900000000000), MinimumSendTxFee(minFee, 100))
+}
@@ -0,0 +1,84 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// BridgeEscrowAddress holds the coins locked by the outbound bridge messages. No one owns its
+// private key, so the coins can only leave it when the bridge releases them to the recipients
+// of the inbound messages.
+var BridgeEscrowAddress = common.HexToAddress("0x0000000000000000000000000000000000b41d9e")
+
+//
+// BridgeMessage transfers coins locked on the source chain to a recipient on the destination
+// chain
+//
+type BridgeMessage struct {
+	SourceChainID string         `json:"source_chain_id"`
+	DestChainID   string         `json:"dest_chain_id"`
+	Nonce         uint64         `json:"nonce"`  // sequence number of the message on the source chain
+	Height        uint64         `json:"height"` // height of the block sending the message
+	Sender        common.Address `json:"sender"`
+	Recipient     common.Address `json:"recipient"`
+	Coins         Coins          `json:"coins"`
+}
+
+// Hash returns the hash of the message, which is the leaf of the message in the Merkle tree of
+// the outbound batch.
+func (m *BridgeMessage) Hash() common.Hash {
+	raw, err := rlp.EncodeToBytes(m)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to encode bridge message: %v", err))
+	}
+	return crypto.Keccak256Hash(raw)
+}
+
+func (m *BridgeMessage) String() string {
+	return fmt.Sprintf("BridgeMessage{%v -> %v, nonce: %v, height: %v, %v -> %v, coins: %v}",
+		m.SourceChainID, m.DestChainID, m.Nonce, m.Height, m.Sender.Hex(), m.Recipient.Hex(), m.Coins)
+}
+
+// BridgeMessageList is the outbound message queue of a block.
+type BridgeMessageList struct {
+	Messages []BridgeMessage
+}
+
+// Hashes returns the hashes of the messages in the queue order.
+func (l *BridgeMessageList) Hashes() []common.Hash {
+	hashes := make([]common.Hash, len(l.Messages))
+	for i := range l.Messages {
+		hashes[i] = l.Messages[i].Hash()
+	}
+	return hashes
+}
+
+// NewBridgeBatch creates the batch of the outbound messages of the block at the height.
+func NewBridgeBatch(chainID string, height uint64, messages *BridgeMessageList) *core.BridgeBatch {
+	return &core.BridgeBatch{
+		SourceChainID: chainID,
+		Height:        height,
+		Root:          core.BridgeMerkleRoot(messages.Hashes()),
+		NumMessages:   uint64(len(messages.Messages)),
+	}
+}
+
+// BridgeValidators is the validator set of a counterpart chain, which co-signs the batches of
+// the messages inbound from the chain
+type BridgeValidators struct {
+	ChainID    string
+	Validators []core.Validator
+}
+
+// ValidatorSet returns the validator set of the counterpart chain.
+func (bv *BridgeValidators) ValidatorSet() *core.ValidatorSet {
+	vs := core.NewValidatorSet()
+	for _, v := range bv.Validators {
+		vs.AddValidator(v)
+	}
+	return vs
+}
@@ -0,0 +1,123 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+const (
+	// DefaultMaxBlockGas is the default gas budget of the regular transactions in a block
+	DefaultMaxBlockGas uint64 = 100000000
+
+	// DefaultMaxBlockBytes is the default size budget in bytes of the regular transactions in a block
+	DefaultMaxBlockBytes uint64 = 8 * 1024 * 1024
+)
+
+// ChainParams holds the block limits and fee minimums of the chain. They are stored in the
+// ledger state and can only be changed by a GovernanceTx approved by a validator supermajority.
+type ChainParams struct {
+	MaxNumRegularTxsPerBlock      uint64   // Max number of regular transactions in a block
+	MaxBlockGas                   uint64   // Max total gas of the regular transactions in a block
+	MaxBlockBytes                 uint64   // Max total size of the regular transactions in a block
+	MinimumGasPrice               *big.Int // Min gas price for a smart contract transaction
+	MinimumTransactionFeeTFuelWei *big.Int // Min fee for a regular transaction
+	UnbondingPeriod               uint64   `rlp:"optional"` // Number of blocks a stake waits in the unbonding queue, 0 for the default
+}
+
+type ChainParamsJSON struct {
+	MaxNumRegularTxsPerBlock      common.JSONUint64 `json:"max_num_regular_txs_per_block"`
+	MaxBlockGas                   common.JSONUint64 `json:"max_block_gas"`
+	MaxBlockBytes                 common.JSONUint64 `json:"max_block_bytes"`
+	MinimumGasPrice               *common.JSONBig   `json:"minimum_gas_price"`
+	MinimumTransactionFeeTFuelWei *common.JSONBig   `json:"minimum_transaction_fee_tfuel_wei"`
+	UnbondingPeriod               common.JSONUint64 `json:"unbonding_period"`
+}
+
+func NewChainParamsJSON(a ChainParams) ChainParamsJSON {
+	return ChainParamsJSON{
+		MaxNumRegularTxsPerBlock:      common.JSONUint64(a.MaxNumRegularTxsPerBlock),
+		MaxBlockGas:                   common.JSONUint64(a.MaxBlockGas),
+		MaxBlockBytes:                 common.JSONUint64(a.MaxBlockBytes),
+		MinimumGasPrice:               (*common.JSONBig)(a.MinimumGasPrice),
+		MinimumTransactionFeeTFuelWei: (*common.JSONBig)(a.MinimumTransactionFeeTFuelWei),
+		UnbondingPeriod:               common.JSONUint64(a.UnbondingPeriod),
+	}
+}
+
+func (a ChainParamsJSON) ChainParams() ChainParams {
+	return ChainParams{
+		MaxNumRegularTxsPerBlock:      uint64(a.MaxNumRegularTxsPerBlock),
+		MaxBlockGas:                   uint64(a.MaxBlockGas),
+		MaxBlockBytes:                 uint64(a.MaxBlockBytes),
+		MinimumGasPrice:               a.MinimumGasPrice.ToInt(),
+		MinimumTransactionFeeTFuelWei: a.MinimumTransactionFeeTFuelWei.ToInt(),
+		UnbondingPeriod:               uint64(a.UnbondingPeriod),
+	}
+}
+
+func (a ChainParams) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewChainParamsJSON(a))
+}
+
+func (a *ChainParams) UnmarshalJSON(data []byte) error {
+	var b ChainParamsJSON
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	*a = b.ChainParams()
+	return nil
+}
+
+// DefaultChainParams returns the chain parameters in effect before any governance update
+func DefaultChainParams() *ChainParams {
+	return &ChainParams{
+		MaxNumRegularTxsPerBlock:      uint64(core.MaxNumRegularTxsPerBlock),
+		MaxBlockGas:                   DefaultMaxBlockGas,
+		MaxBlockBytes:                 DefaultMaxBlockBytes,
+		MinimumGasPrice:               new(big.Int).SetUint64(MinimumGasPrice),
+		MinimumTransactionFeeTFuelWei: new(big.Int).SetUint64(MinimumTransactionFeeTFuelWei),
+		UnbondingPeriod:               core.ReturnLockingPeriod,
+	}
+}
+
+// Validate checks the chain parameters are usable, i.e. a block can still hold transactions
+func (cp *ChainParams) Validate() error {
+	if cp.MaxNumRegularTxsPerBlock == 0 || cp.MaxNumRegularTxsPerBlock > math.MaxInt32 {
+		return errors.New("MaxNumRegularTxsPerBlock must be positive and fit in an int32")
+	}
+	if cp.MaxBlockGas == 0 {
+		return errors.New("MaxBlockGas must be positive")
+	}
+	if cp.MaxBlockBytes == 0 {
+		return errors.New("MaxBlockBytes must be positive")
+	}
+	if cp.MinimumGasPrice == nil || cp.MinimumGasPrice.Sign() < 0 {
+		return errors.New("MinimumGasPrice must be non-negative")
+	}
+	if cp.MinimumTransactionFeeTFuelWei == nil || cp.MinimumTransactionFeeTFuelWei.Sign() < 0 {
+		return errors.New("MinimumTransactionFeeTFuelWei must be non-negative")
+	}
+	return nil
+}
+
+// GetUnbondingPeriod returns the number of blocks a withdrawn or undelegated stake waits in the
+// unbonding queue before it is returned. The params stored before the unbonding period was added
+// leave it 0, which stands for core.ReturnLockingPeriod.
+func (cp *ChainParams) GetUnbondingPeriod() uint64 {
+	if cp.UnbondingPeriod == 0 {
+		return core.ReturnLockingPeriod
+	}
+	return cp.UnbondingPeriod
+}
+
+func (cp *ChainParams) String() string {
+	return fmt.Sprintf("ChainParams{max_txs: %v, max_gas: %v, max_bytes: %v, min_gas_price: %v, min_fee: %v, unbonding_period: %v}",
+		cp.MaxNumRegularTxsPerBlock, cp.MaxBlockGas, cp.MaxBlockBytes, cp.MinimumGasPrice, cp.MinimumTransactionFeeTFuelWei,
+		cp.GetUnbondingPeriod())
+}
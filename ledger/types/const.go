@@ -15,6 +15,10 @@ const (
 
 	// MaxAccountsAffectedPerTx specifies the max number of accounts one transaction is allowed to modify to avoid spamming
 	MaxAccountsAffectedPerTx = 512
+
+	// BatchSendOutputFeeDivisor sets the min fee of each output of a SendTx beyond the first to
+	// 1/BatchSendOutputFeeDivisor of the minimum transaction fee
+	BatchSendOutputFeeDivisor = 10
 )
 
 const (
@@ -45,6 +49,19 @@ const (
 	RegularTFuelGenerationRateDenominator int64 = 1e10
 )
 
+const (
+	// BlockRewardTFuelWei is the TFuel minted by each block since the reward distribution upgrade,
+	// which is distributed along with the transaction fees of the block
+	BlockRewardTFuelWei uint64 = 4.8e18
+
+	// ProposerRewardPercent is the percentage of the block rewards paid to the block proposer
+	ProposerRewardPercent uint64 = 10
+
+	// GuardianRewardPercent is the percentage of the block rewards paid to the guardian stakers. It
+	// goes to the validator stakers when there is no guardian stake
+	GuardianRewardPercent uint64 = 30
+)
+
 const (
 
 	// ServiceRewardVerificationBlockDelay gives the block delay for service certificate verification
@@ -0,0 +1,40 @@
+// +build gofuzz
+
+package types
+
+import (
+	"bytes"
+)
+
+// FuzzTx is the entry point for the go-fuzz tool on the decoding of the transactions, e.g. those
+// gossiped by the peers or submitted through the RPC API.
+//
+//	go-fuzz-build -func FuzzTx github.com/thetatoken/theta/ledger/types
+//	go-fuzz -bin types-fuzz.zip -workdir fuzz/tx
+//
+// It returns 1 for the inputs which decode, and 0 otherwise. The decoded transactions are encoded
+// again, and must decode to a transaction with the same encoding.
+func FuzzTx(data []byte) int {
+	tx, err := TxFromBytes(data)
+	if err != nil {
+		return 0
+	}
+	TxID("fuzzchain", tx)
+
+	encoded, err := TxToBytes(tx)
+	if err != nil {
+		panic(err)
+	}
+	decoded, err := TxFromBytes(encoded)
+	if err != nil {
+		panic(err)
+	}
+	reencoded, err := TxToBytes(decoded)
+	if err != nil {
+		panic(err)
+	}
+	if !bytes.Equal(encoded, reencoded) {
+		panic("Transaction changed after re-encoding")
+	}
+	return 1
+}
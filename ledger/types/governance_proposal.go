@@ -0,0 +1,164 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+const (
+	// GovernanceVotingPeriod is the number of blocks a governance proposal is open for votes
+	GovernanceVotingPeriod uint64 = 28800
+)
+
+// GovernanceProposalStatus is the stage of a governance proposal.
+type GovernanceProposalStatus uint8
+
+const (
+	GovernanceProposalVoting GovernanceProposalStatus = iota
+	GovernanceProposalPassed
+	GovernanceProposalRejected
+	GovernanceProposalActivated
+)
+
+var governanceProposalStatusNames = map[GovernanceProposalStatus]string{
+	GovernanceProposalVoting:    "voting",
+	GovernanceProposalPassed:    "passed",
+	GovernanceProposalRejected:  "rejected",
+	GovernanceProposalActivated: "activated",
+}
+
+func (s GovernanceProposalStatus) String() string {
+	if name, ok := governanceProposalStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("GovernanceProposalStatus(%d)", uint8(s))
+}
+
+// ParseGovernanceProposalStatus parses the name of a governance proposal status, e.g. "voting".
+func ParseGovernanceProposalStatus(name string) (GovernanceProposalStatus, error) {
+	for status, statusName := range governanceProposalStatusNames {
+		if statusName == name {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown governance proposal status: %v", name)
+}
+
+// GovernanceVote is the vote of a stake holder on a governance proposal
+type GovernanceVote struct {
+	Voter   common.Address `json:"voter"`
+	Approve bool           `json:"approve"`
+}
+
+//
+// GovernanceProposal is a change of the chain parameters submitted by a GovernanceProposalTx.
+// The stake holders vote on it with GovernanceVoteTxs until the voting end height, and a
+// GovernanceTallyTx settles the outcome. The parameters of a passed proposal take effect at
+// its activation height.
+//
+type GovernanceProposal struct {
+	ID               uint64
+	Proposer         common.Address
+	Params           ChainParams
+	SubmitHeight     uint64
+	VotingEndHeight  uint64
+	ActivationHeight uint64
+	Votes            []GovernanceVote
+	Status           GovernanceProposalStatus
+}
+
+// Vote records the vote of the voter, replacing the previous vote of the voter if any.
+func (gp *GovernanceProposal) Vote(voter common.Address, approve bool) {
+	for i := range gp.Votes {
+		if gp.Votes[i].Voter == voter {
+			gp.Votes[i].Approve = approve
+			return
+		}
+	}
+	gp.Votes = append(gp.Votes, GovernanceVote{Voter: voter, Approve: approve})
+}
+
+func (gp *GovernanceProposal) String() string {
+	return fmt.Sprintf("GovernanceProposal{id: %v, proposer: %v, params: %v, voting end: %v, activation: %v, votes: %v, status: %v}",
+		gp.ID, gp.Proposer, gp.Params.String(), gp.VotingEndHeight, gp.ActivationHeight, len(gp.Votes), gp.Status)
+}
+
+// GovernanceTally is the stake voting for and against a governance proposal
+type GovernanceTally struct {
+	ApprovedStake *big.Int `json:"approved_stake"`
+	RejectedStake *big.Int `json:"rejected_stake"`
+	TotalStake    *big.Int `json:"total_stake"`
+}
+
+// TallyGovernanceProposal weighs the votes on the proposal with the current stakes of the voters
+// in the validator candidate pool. The votes of the addresses without stake count for nothing.
+func TallyGovernanceProposal(gp *GovernanceProposal, vcp *core.ValidatorCandidatePool) *GovernanceTally {
+	tally := &GovernanceTally{
+		ApprovedStake: big.NewInt(0),
+		RejectedStake: big.NewInt(0),
+		TotalStake:    big.NewInt(0),
+	}
+	if vcp == nil {
+		return tally
+	}
+	stakes := make(map[common.Address]*big.Int)
+	for _, candidate := range vcp.SortedCandidates {
+		stake := candidate.TotalStake()
+		stakes[candidate.Holder] = stake
+		tally.TotalStake.Add(tally.TotalStake, stake)
+	}
+	for _, vote := range gp.Votes {
+		stake, ok := stakes[vote.Voter]
+		if !ok {
+			continue
+		}
+		if vote.Approve {
+			tally.ApprovedStake.Add(tally.ApprovedStake, stake)
+		} else {
+			tally.RejectedStake.Add(tally.RejectedStake, stake)
+		}
+	}
+	return tally
+}
+
+// Approved returns whether more than 2/3 of the total stake approved the proposal.
+func (t *GovernanceTally) Approved() bool {
+	return hasSupermajority(t.ApprovedStake, t.TotalStake)
+}
+
+// Rejected returns whether enough stake voted against the proposal that it can no longer be
+// approved.
+func (t *GovernanceTally) Rejected() bool {
+	remaining := new(big.Int).Sub(t.TotalStake, t.RejectedStake)
+	return !hasSupermajority(remaining, t.TotalStake)
+}
+
+func hasSupermajority(stake *big.Int, totalStake *big.Int) bool {
+	if totalStake.Sign() == 0 {
+		return false
+	}
+	lhs := new(big.Int).Mul(stake, big.NewInt(3))
+	rhs := new(big.Int).Mul(totalStake, big.NewInt(2))
+	return lhs.Cmp(rhs) > 0
+}
+
+// GovernanceProposalIDList holds the IDs of governance proposals
+type GovernanceProposalIDList struct {
+	IDs []uint64
+}
+
+func (l *GovernanceProposalIDList) Append(id uint64) {
+	l.IDs = append(l.IDs, id)
+}
+
+func (l *GovernanceProposalIDList) Remove(id uint64) {
+	for i, existing := range l.IDs {
+		if existing == id {
+			l.IDs = append(l.IDs[:i], l.IDs[i+1:]...)
+			return
+		}
+	}
+}
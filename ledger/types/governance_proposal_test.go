@@ -0,0 +1,83 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func TestTallyGovernanceProposal(t *testing.T) {
+	assert := assert.New(t)
+
+	addr1 := common.HexToAddress("0x1")
+	addr2 := common.HexToAddress("0x2")
+	addr3 := common.HexToAddress("0x3")
+	vcp := &core.ValidatorCandidatePool{}
+	vcp.DepositStake(addr1, addr1, new(big.Int).Mul(core.MinValidatorStakeDeposit, big.NewInt(4)))
+	vcp.DepositStake(addr2, addr2, core.MinValidatorStakeDeposit)
+	vcp.DepositStake(addr3, addr3, core.MinValidatorStakeDeposit)
+
+	proposal := &GovernanceProposal{ID: 1}
+	tally := TallyGovernanceProposal(proposal, vcp)
+	assert.Equal(new(big.Int).Mul(core.MinValidatorStakeDeposit, big.NewInt(6)), tally.TotalStake)
+	assert.False(tally.Approved())
+	assert.False(tally.Rejected())
+
+	// Exactly 2/3 is not a supermajority
+	proposal.Vote(addr1, true)
+	tally = TallyGovernanceProposal(proposal, vcp)
+	assert.False(tally.Approved())
+	assert.False(tally.Rejected())
+
+	proposal.Vote(addr3, true)
+	tally = TallyGovernanceProposal(proposal, vcp)
+	assert.True(tally.Approved())
+
+	// Changing a vote replaces it
+	proposal.Vote(addr1, false)
+	assert.Equal(2, len(proposal.Votes))
+	tally = TallyGovernanceProposal(proposal, vcp)
+	assert.False(tally.Approved())
+	assert.True(tally.Rejected())
+
+	// The votes of the addresses without stake are ignored
+	proposal = &GovernanceProposal{ID: 2}
+	proposal.Vote(common.HexToAddress("0x4"), false)
+	tally = TallyGovernanceProposal(proposal, vcp)
+	assert.Equal(0, tally.RejectedStake.Sign())
+
+	tally = TallyGovernanceProposal(proposal, nil)
+	assert.False(tally.Approved())
+}
+
+func TestGovernanceProposalRLPEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	proposal := &GovernanceProposal{
+		ID:               3,
+		Proposer:         common.HexToAddress("0x1"),
+		Params:           *DefaultChainParams(),
+		SubmitHeight:     100,
+		VotingEndHeight:  100 + GovernanceVotingPeriod,
+		ActivationHeight: 200 + GovernanceVotingPeriod,
+		Status:           GovernanceProposalPassed,
+	}
+	proposal.Vote(common.HexToAddress("0x2"), true)
+
+	raw, err := rlp.EncodeToBytes(proposal)
+	assert.Nil(err)
+	decoded := &GovernanceProposal{}
+	assert.Nil(rlp.DecodeBytes(raw, decoded))
+	assert.Equal(proposal.String(), decoded.String())
+	assert.Equal(proposal.Votes, decoded.Votes)
+
+	status, err := ParseGovernanceProposalStatus("passed")
+	assert.Nil(err)
+	assert.Equal(GovernanceProposalPassed, status)
+	_, err = ParseGovernanceProposalStatus("unknown")
+	assert.NotNil(err)
+}
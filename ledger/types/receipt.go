@@ -0,0 +1,44 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+// Receipt records the outcome of a smart contract transaction execution.
+type Receipt struct {
+	TxHash          common.Hash    `json:"transactionHash"` // Hash of the raw transaction, same as in the chain's tx index
+	Logs            []*Log         `json:"logs"`
+	EvmRet          common.Bytes   `json:"evmReturn"`
+	ContractAddress common.Address `json:"contractAddress"`
+	GasUsed         uint64         `json:"gasUsed"`
+	EvmErr          string         `json:"evmError"`
+}
+
+// Succeeded returns whether the EVM execution completed without error.
+func (r *Receipt) Succeeded() bool {
+	return r.EvmErr == ""
+}
+
+// CreateBloom creates the bloom filter of the logs in the given receipts.
+func CreateBloom(receipts []*Receipt) core.Bloom {
+	bin := new(big.Int)
+	for _, receipt := range receipts {
+		bin.Or(bin, LogsBloom(receipt.Logs))
+	}
+	return core.BytesToBloom(bin.Bytes())
+}
+
+// LogsBloom returns the bloom bits of the addresses and topics of the given logs.
+func LogsBloom(logs []*Log) *big.Int {
+	bin := new(big.Int)
+	for _, log := range logs {
+		bin.Or(bin, core.Bloom9(log.Address.Bytes()))
+		for _, b := range log.Topics {
+			bin.Or(bin, core.Bloom9(b[:]))
+		}
+	}
+	return bin
+}
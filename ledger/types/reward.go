@@ -0,0 +1,51 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+)
+
+const (
+	RewardToProposer  uint8 = 0
+	RewardToValidator uint8 = 1
+	RewardToGuardian  uint8 = 2
+	RewardToDelegator uint8 = 3
+)
+
+// Reward is the TFuel paid to an address by a block. The stakers of a validator or a guardian,
+// and the delegators of a validator, are paid for each of their stakes, with Holder set to the
+// validator or the guardian.
+type Reward struct {
+	Address common.Address
+	Holder  common.Address
+	Role    uint8
+	Amount  *big.Int
+}
+
+func (r Reward) String() string {
+	return fmt.Sprintf("Reward{address: %v, holder: %v, role: %v, amount: %v}", r.Address, r.Holder, r.Role, r.Amount)
+}
+
+//
+// BlockRewards records the rewards paid by a block since the reward distribution upgrade. The
+// block reward and the fees are paid in full, i.e. the amounts of the rewards add up to their sum.
+//
+type BlockRewards struct {
+	Height      uint64
+	Proposer    common.Address
+	BlockReward *big.Int // TFuel minted by the block
+	Fees        *big.Int // transaction fees of the block
+	Rewards     []Reward
+}
+
+// Total returns the total amount of the rewards.
+func (br *BlockRewards) Total() *big.Int {
+	return new(big.Int).Add(br.BlockReward, br.Fees)
+}
+
+func (br *BlockRewards) String() string {
+	return fmt.Sprintf("BlockRewards{height: %v, proposer: %v, block reward: %v, fees: %v, rewards: %v}",
+		br.Height, br.Proposer, br.BlockReward, br.Fees, br.Rewards)
+}
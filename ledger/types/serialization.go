@@ -33,6 +33,20 @@ const (
 	TxSmartContract
 	TxDepositStake
 	TxWithdrawStake
+	TxGovernance
+	TxDepositStakeV2
+	TxGovernanceProposal
+	TxGovernanceVote
+	TxGovernanceTally
+	TxBridgeLock
+	TxBridgeMint
+	TxBridgeValidators
+	TxSponsored
+	TxTimeLock
+	TxTimeLockClaim
+	TxTimeLockRevoke
+	TxDelegate
+	TxUndelegate
 )
 
 func TxFromBytes(raw []byte) (Tx, error) {
@@ -82,6 +96,62 @@ func TxFromBytes(raw []byte) (Tx, error) {
 		data := &WithdrawStakeTx{}
 		err = rlp.Decode(buff, data)
 		return data, err
+	} else if txType == TxGovernance {
+		data := &GovernanceTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxDepositStakeV2 {
+		data := &DepositStakeTxV2{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxGovernanceProposal {
+		data := &GovernanceProposalTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxGovernanceVote {
+		data := &GovernanceVoteTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxGovernanceTally {
+		data := &GovernanceTallyTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxBridgeLock {
+		data := &BridgeLockTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxBridgeMint {
+		data := &BridgeMintTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxBridgeValidators {
+		data := &BridgeValidatorsTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxSponsored {
+		data := &SponsoredTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxTimeLock {
+		data := &TimeLockTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxTimeLockClaim {
+		data := &TimeLockClaimTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxTimeLockRevoke {
+		data := &TimeLockRevokeTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxDelegate {
+		data := &DelegateTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxUndelegate {
+		data := &UndelegateTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
 	} else {
 		return nil, fmt.Errorf("Unknown TX type: %v", txType)
 	}
@@ -89,6 +159,23 @@ func TxFromBytes(raw []byte) (Tx, error) {
 
 func TxToBytes(t Tx) ([]byte, error) {
 	var buf bytes.Buffer
+	txType, err := GetTxType(t)
+	if err != nil {
+		return nil, err
+	}
+	err = rlp.Encode(&buf, txType)
+	if err != nil {
+		return nil, err
+	}
+	err = rlp.Encode(&buf, t)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GetTxType returns the type of the transaction, as encoded by TxToBytes.
+func GetTxType(t Tx) (TxType, error) {
 	var txType TxType
 	switch t.(type) {
 	case *CoinbaseTx:
@@ -111,16 +198,36 @@ func TxToBytes(t Tx) ([]byte, error) {
 		txType = TxDepositStake
 	case *WithdrawStakeTx:
 		txType = TxWithdrawStake
+	case *GovernanceTx:
+		txType = TxGovernance
+	case *DepositStakeTxV2:
+		txType = TxDepositStakeV2
+	case *GovernanceProposalTx:
+		txType = TxGovernanceProposal
+	case *GovernanceVoteTx:
+		txType = TxGovernanceVote
+	case *GovernanceTallyTx:
+		txType = TxGovernanceTally
+	case *BridgeLockTx:
+		txType = TxBridgeLock
+	case *BridgeMintTx:
+		txType = TxBridgeMint
+	case *BridgeValidatorsTx:
+		txType = TxBridgeValidators
+	case *SponsoredTx:
+		txType = TxSponsored
+	case *TimeLockTx:
+		txType = TxTimeLock
+	case *TimeLockClaimTx:
+		txType = TxTimeLockClaim
+	case *TimeLockRevokeTx:
+		txType = TxTimeLockRevoke
+	case *DelegateTx:
+		txType = TxDelegate
+	case *UndelegateTx:
+		txType = TxUndelegate
 	default:
-		return nil, errors.New("Unsupported message type")
-	}
-	err := rlp.Encode(&buf, txType)
-	if err != nil {
-		return nil, err
-	}
-	err = rlp.Encode(&buf, t)
-	if err != nil {
-		return nil, err
+		return txType, errors.New("Unsupported message type")
 	}
-	return buf.Bytes(), nil
+	return txType, nil
 }
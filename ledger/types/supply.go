@@ -0,0 +1,34 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+)
+
+// EscrowAddresses lists the addresses holding coins on behalf of the protocol, which are excluded
+// from the circulating supply.
+var EscrowAddresses = []common.Address{BridgeEscrowAddress, TimeLockEscrowAddress}
+
+//
+// TFuelSupply is the accounting of the TFuel supply, updated by every block since the supply
+// accounting upgrade. The total supply includes the coins held by the escrows.
+//
+type TFuelSupply struct {
+	Total       *big.Int // total supply after the block
+	Minted      *big.Int // TFuel minted since the supply accounting upgrade, e.g. the block rewards
+	Burned      *big.Int // TFuel burned since the supply accounting upgrade, e.g. the transaction fees
+	BlockMinted *big.Int // TFuel minted by the block
+	BlockBurned *big.Int // TFuel burned by the block
+}
+
+// Circulating returns the total supply minus the coins held by the escrows.
+func (s *TFuelSupply) Circulating(escrowed *big.Int) *big.Int {
+	return new(big.Int).Sub(s.Total, escrowed)
+}
+
+func (s *TFuelSupply) String() string {
+	return fmt.Sprintf("TFuelSupply{total: %v, minted: %v, burned: %v, block minted: %v, block burned: %v}",
+		s.Total, s.Minted, s.Burned, s.BlockMinted, s.BlockBurned)
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+)
+
+// TimeLockEscrowAddress holds the coins of the time locks. No one owns its private key, so the
+// coins can only leave it when a time lock is claimed by its recipient or revoked by its revoker.
+var TimeLockEscrowAddress = common.HexToAddress("0x00000000000000000000000000000000007110c4")
+
+//
+// TimeLock is a transfer of coins created by a TimeLockTx, which the recipient can claim with a
+// TimeLockClaimTx once the chain reaches both the release height and the release time, e.g. for
+// vesting schedules and escrows. Until then the revoker, if any, can return the coins to the
+// source with a TimeLockRevokeTx.
+//
+type TimeLock struct {
+	ID            uint64
+	Source        common.Address
+	Recipient     common.Address
+	Revoker       common.Address // empty if the time lock cannot be revoked
+	Coins         Coins
+	LockHeight    uint64
+	ReleaseHeight uint64 // 0 if the release does not depend on the height
+	ReleaseTime   uint64 // unix timestamp in seconds, 0 if the release does not depend on the time
+}
+
+// IsRevocable returns whether the time lock has a revoker.
+func (tl *TimeLock) IsRevocable() bool {
+	return !tl.Revoker.IsEmpty()
+}
+
+// IsReleased returns whether the coins are released at the given height and chain time.
+func (tl *TimeLock) IsReleased(height uint64, chainTime uint64) bool {
+	return height >= tl.ReleaseHeight && chainTime >= tl.ReleaseTime
+}
+
+func (tl *TimeLock) String() string {
+	return fmt.Sprintf("TimeLock{id: %v, source: %v, recipient: %v, revoker: %v, coins: %v, release height: %v, release time: %v}",
+		tl.ID, tl.Source, tl.Recipient, tl.Revoker, tl.Coins, tl.ReleaseHeight, tl.ReleaseTime)
+}
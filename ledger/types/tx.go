@@ -8,6 +8,7 @@ import (
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/rlp"
 )
@@ -24,20 +25,46 @@ Transaction Types:
  - ServicePaymentTx     Payments for service
  - SplitRuleTx          Payment split rule
  - DepositStakeTx       Deposit stake to a target address (e.g. a validator)
+ - DepositStakeTxV2     Deposit stake to a target address, with the BLS key of a guardian
  - WithdrawStakeTx      Withdraw stake from a target address (e.g. a validator)
  - SmartContractTx      Execute smart contract
+ - GovernanceTx         Update the chain parameters with the approval of a validator supermajority
+ - GovernanceProposalTx Submit a proposal to update the chain parameters at a future height
+ - GovernanceVoteTx     Vote on a governance proposal
+ - GovernanceTallyTx    Settle the outcome of a governance proposal
+ - BridgeLockTx         Lock coins to send them to a counterpart chain over the bridge
+ - BridgeMintTx         Mint the coins sent from a counterpart chain over the bridge
+ - BridgeValidatorsTx   Register the validator set of a counterpart chain of the bridge
+ - SponsoredTx          Wrap a transaction whose fee is paid by a third-party sponsor
+ - TimeLockTx           Lock coins for a recipient until a release height or time
+ - TimeLockClaimTx      Claim the coins of a released time lock
+ - TimeLockRevokeTx     Return the coins of a time lock to its source before the release
+ - DelegateTx           Delegate stake to a validator candidate
+ - UndelegateTx         Undelegate the stake delegated to a validator candidate
 */
 
 // Gas of regular transactions
 const (
-	GasSendTxPerAccount   uint64 = 5000
-	GasReserveFundTx      uint64 = 10000
-	GasReleaseFundTx      uint64 = 10000
-	GasServicePaymentTx   uint64 = 10000
-	GasSplitRuleTx        uint64 = 10000
-	GasUpdateValidatorsTx uint64 = 10000
-	GasDepositStakeTx     uint64 = 10000
-	GasWidthdrawStakeTx   uint64 = 10000
+	GasSendTxPerAccount     uint64 = 5000
+	GasReserveFundTx        uint64 = 10000
+	GasReleaseFundTx        uint64 = 10000
+	GasServicePaymentTx     uint64 = 10000
+	GasSplitRuleTx          uint64 = 10000
+	GasUpdateValidatorsTx   uint64 = 10000
+	GasDepositStakeTx       uint64 = 10000
+	GasWidthdrawStakeTx     uint64 = 10000
+	GasGovernanceTx         uint64 = 10000
+	GasGovernanceProposalTx uint64 = 10000
+	GasGovernanceVoteTx     uint64 = 10000
+	GasGovernanceTallyTx    uint64 = 10000
+	GasBridgeLockTx         uint64 = 10000
+	GasBridgeMintTx         uint64 = 20000
+	GasBridgeValidatorsTx   uint64 = 10000
+	GasTimeLockTx           uint64 = 10000
+	GasTimeLockClaimTx      uint64 = 10000
+	GasTimeLockRevokeTx     uint64 = 10000
+	GasDelegateTx           uint64 = 10000
+	GasUndelegateTx         uint64 = 10000
 )
 
 type Tx interface {
@@ -127,10 +154,10 @@ func (a *TxInput) UnmarshalJSON(data []byte) error {
 
 func (txIn TxInput) ValidateBasic() result.Result {
 	if len(txIn.Address) != 20 {
-		return result.Error("Invalid address length")
+		return result.Error("Invalid address length").WithErrorCode(result.CodeInvalidInputOutput)
 	}
 	if !txIn.Coins.IsValid() {
-		return result.Error("Invalid coins: %v", txIn.Coins)
+		return result.Error("Invalid coins: %v", txIn.Coins).WithErrorCode(result.CodeInvalidInputOutput)
 	}
 	// if txIn.Coins.IsZero() {
 	// 	return result.Error("Coins cannot be zero")
@@ -168,11 +195,11 @@ type TxOutput struct {
 
 func (txOut TxOutput) ValidateBasic() result.Result {
 	if len(txOut.Address) != 20 {
-		return result.Error("Invalid address length")
+		return result.Error("Invalid address length").WithErrorCode(result.CodeInvalidInputOutput)
 	}
 
 	if !txOut.Coins.IsValid() {
-		return result.Error("Invalid coins: %v", txOut.Coins)
+		return result.Error("Invalid coins: %v", txOut.Coins).WithErrorCode(result.CodeInvalidInputOutput)
 	}
 	// if txOut.Coins.IsZero() {
 	// 	return result.Error("Coins cannot be zero")
@@ -832,6 +859,59 @@ func (tx *DepositStakeTx) String() string {
 
 //-----------------------------------------------------------------------------
 
+// DepositStakeTxV2 extends DepositStakeTx with the BLS public key the holder uses to sign the
+// checkpoints as a guardian. The BLS fields are only required when staking for a guardian.
+type DepositStakeTxV2 struct {
+	Fee       Coins             `json:"fee"`        // Fee
+	Source    TxInput           `json:"source"`     // source staker account
+	Holder    TxOutput          `json:"holder"`     // stake holder account
+	Purpose   uint8             `json:"purpose"`    // purpose e.g. stake for validator/guardian
+	BlsPubkey common.Bytes      `json:"bls_pubkey"` // BLS public key of the guardian
+	BlsPop    common.Bytes      `json:"bls_pop"`    // proof of possession of the BLS key
+	HolderSig *crypto.Signature `json:"holder_sig"` // holder signature over the BLS key
+}
+
+func (_ *DepositStakeTxV2) AssertIsTx() {}
+
+func (tx *DepositStakeTxV2) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Source.Signature
+	tx.Source.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Source.Signature = sig
+	return signBytes
+}
+
+// HolderSignBytes returns the bytes the holder signs to bind its address to the BLS key
+func (tx *DepositStakeTxV2) HolderSignBytes() []byte {
+	return GuardianKeySignBytes(tx.Holder.Address, tx.BlsPubkey, tx.BlsPop)
+}
+
+func (tx *DepositStakeTxV2) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Source.Address == addr {
+		tx.Source.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *DepositStakeTxV2) String() string {
+	return fmt.Sprintf("DepositStakeTxV2{%v -> %v, stake: %v, purpose: %v, bls_pubkey: %v}",
+		tx.Source.Address, tx.Holder.Address, tx.Source.Coins.ThetaWei, tx.Purpose, hex.EncodeToString(tx.BlsPubkey))
+}
+
+// GuardianKeySignBytes returns the bytes a guardian signs with its account key to bind its
+// address to its BLS key
+func GuardianKeySignBytes(holder common.Address, blsPubkey common.Bytes, blsPop common.Bytes) []byte {
+	raw, _ := rlp.EncodeToBytes([]interface{}{holder, blsPubkey, blsPop})
+	return raw
+}
+
+//-----------------------------------------------------------------------------
+
 type WithdrawStakeTx struct {
 	Fee     Coins    `json:"fee"`     // Fee
 	Source  TxInput  `json:"source"`  // source staker account
@@ -866,8 +946,585 @@ func (tx *WithdrawStakeTx) String() string {
 		tx.Source.Address, tx.Holder.Address, tx.Source.Coins.ThetaWei, tx.Purpose)
 }
 
+//-----------------------------------------------------------------------------
+
+// GovernanceApproval is the approval of a validator for a GovernanceTx
+type GovernanceApproval struct {
+	Address   common.Address    `json:"address"`
+	Signature *crypto.Signature `json:"signature"`
+}
+
+type GovernanceTx struct {
+	Fee       Coins                `json:"fee"`       // Fee
+	Proposer  TxInput              `json:"proposer"`  // proposer account, pays the fee
+	Params    ChainParams          `json:"params"`    // the new chain parameters
+	Approvals []GovernanceApproval `json:"approvals"` // approvals of the validators other than the proposer
+}
+
+func (_ *GovernanceTx) AssertIsTx() {}
+
+// SignBytes returns the bytes signed by the proposer and all the approving validators
+func (tx *GovernanceTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Proposer.Signature
+	tx.Proposer.Signature = nil
+	approvalSigs := make([]*crypto.Signature, len(tx.Approvals))
+	for i := range tx.Approvals {
+		approvalSigs[i] = tx.Approvals[i].Signature
+		tx.Approvals[i].Signature = nil
+	}
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Proposer.Signature = sig
+	for i := range tx.Approvals {
+		tx.Approvals[i].Signature = approvalSigs[i]
+	}
+	return signBytes
+}
+
+func (tx *GovernanceTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Proposer.Address == addr {
+		tx.Proposer.Signature = sig
+		return true
+	}
+	for i := range tx.Approvals {
+		if tx.Approvals[i].Address == addr {
+			tx.Approvals[i].Signature = sig
+			return true
+		}
+	}
+	return false
+}
+
+func (tx *GovernanceTx) String() string {
+	return fmt.Sprintf("GovernanceTx{%v, params: %v, approvals: %v}",
+		tx.Proposer.Address, tx.Params.String(), len(tx.Approvals))
+}
+
+//-----------------------------------------------------------------------------
+
+type GovernanceProposalTx struct {
+	Fee              Coins       `json:"fee"`               // Fee
+	Proposer         TxInput     `json:"proposer"`          // proposer account, needs to be a stake holder
+	Params           ChainParams `json:"params"`            // the proposed chain parameters
+	ActivationHeight uint64      `json:"activation_height"` // height the params take effect at if the proposal passes
+}
+
+func (_ *GovernanceProposalTx) AssertIsTx() {}
+
+func (tx *GovernanceProposalTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Proposer.Signature
+	tx.Proposer.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Proposer.Signature = sig
+	return signBytes
+}
+
+func (tx *GovernanceProposalTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Proposer.Address == addr {
+		tx.Proposer.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *GovernanceProposalTx) String() string {
+	return fmt.Sprintf("GovernanceProposalTx{%v, params: %v, activation height: %v}",
+		tx.Proposer.Address, tx.Params.String(), tx.ActivationHeight)
+}
+
+//-----------------------------------------------------------------------------
+
+type GovernanceVoteTx struct {
+	Fee        Coins   `json:"fee"`         // Fee
+	Voter      TxInput `json:"voter"`       // voter account, weighted by its stake
+	ProposalID uint64  `json:"proposal_id"` // ID of the governance proposal
+	Approve    bool    `json:"approve"`     // whether the voter approves the proposal
+}
+
+func (_ *GovernanceVoteTx) AssertIsTx() {}
+
+func (tx *GovernanceVoteTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Voter.Signature
+	tx.Voter.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Voter.Signature = sig
+	return signBytes
+}
+
+func (tx *GovernanceVoteTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Voter.Address == addr {
+		tx.Voter.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *GovernanceVoteTx) String() string {
+	return fmt.Sprintf("GovernanceVoteTx{%v, proposal: %v, approve: %v}",
+		tx.Voter.Address, tx.ProposalID, tx.Approve)
+}
+
+//-----------------------------------------------------------------------------
+
+type GovernanceTallyTx struct {
+	Fee        Coins   `json:"fee"`         // Fee
+	Caller     TxInput `json:"caller"`      // caller account, pays the fee
+	ProposalID uint64  `json:"proposal_id"` // ID of the governance proposal
+}
+
+func (_ *GovernanceTallyTx) AssertIsTx() {}
+
+func (tx *GovernanceTallyTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Caller.Signature
+	tx.Caller.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Caller.Signature = sig
+	return signBytes
+}
+
+func (tx *GovernanceTallyTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Caller.Address == addr {
+		tx.Caller.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *GovernanceTallyTx) String() string {
+	return fmt.Sprintf("GovernanceTallyTx{%v, proposal: %v}", tx.Caller.Address, tx.ProposalID)
+}
+
+//-----------------------------------------------------------------------------
+
+type BridgeLockTx struct {
+	Fee         Coins          `json:"fee"`           // Fee
+	Source      TxInput        `json:"source"`        // source account, the coins of the input are locked
+	DestChainID string         `json:"dest_chain_id"` // the counterpart chain to send the coins to
+	Recipient   common.Address `json:"recipient"`     // recipient of the coins on the counterpart chain
+}
+
+func (_ *BridgeLockTx) AssertIsTx() {}
+
+func (tx *BridgeLockTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Source.Signature
+	tx.Source.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Source.Signature = sig
+	return signBytes
+}
+
+func (tx *BridgeLockTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Source.Address == addr {
+		tx.Source.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *BridgeLockTx) String() string {
+	return fmt.Sprintf("BridgeLockTx{%v -> %v on %v, coins: %v}",
+		tx.Source.Address, tx.Recipient, tx.DestChainID, tx.Source.Coins)
+}
+
+//-----------------------------------------------------------------------------
+
+type BridgeMintTx struct {
+	Fee        Coins                  `json:"fee"`        // Fee
+	Relayer    TxInput                `json:"relayer"`    // relayer account, pays the fee
+	Message    BridgeMessage          `json:"message"`    // the inbound message
+	Batch      core.BridgeBatch       `json:"batch"`      // the batch of the message on the source chain
+	Index      uint64                 `json:"index"`      // index of the message in the batch
+	Proof      []common.Hash          `json:"proof"`      // Merkle proof of the message in the batch
+	Signatures []core.BridgeSignature `json:"signatures"` // co-signatures of the validators of the source chain on the batch
+}
+
+func (_ *BridgeMintTx) AssertIsTx() {}
+
+func (tx *BridgeMintTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Relayer.Signature
+	tx.Relayer.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Relayer.Signature = sig
+	return signBytes
+}
+
+func (tx *BridgeMintTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Relayer.Address == addr {
+		tx.Relayer.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *BridgeMintTx) String() string {
+	return fmt.Sprintf("BridgeMintTx{%v, message: %v, batch: %v}",
+		tx.Relayer.Address, tx.Message.String(), tx.Batch.String())
+}
+
+//-----------------------------------------------------------------------------
+
+type BridgeValidatorsTx struct {
+	Fee        Coins                `json:"fee"`        // Fee
+	Proposer   TxInput              `json:"proposer"`   // proposer account, pays the fee
+	Validators BridgeValidators     `json:"validators"` // the validator set of the counterpart chain
+	Approvals  []GovernanceApproval `json:"approvals"`  // approvals of the validators other than the proposer
+}
+
+func (_ *BridgeValidatorsTx) AssertIsTx() {}
+
+// SignBytes returns the bytes signed by the proposer and all the approving validators
+func (tx *BridgeValidatorsTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Proposer.Signature
+	tx.Proposer.Signature = nil
+	approvalSigs := make([]*crypto.Signature, len(tx.Approvals))
+	for i := range tx.Approvals {
+		approvalSigs[i] = tx.Approvals[i].Signature
+		tx.Approvals[i].Signature = nil
+	}
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Proposer.Signature = sig
+	for i := range tx.Approvals {
+		tx.Approvals[i].Signature = approvalSigs[i]
+	}
+	return signBytes
+}
+
+func (tx *BridgeValidatorsTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Proposer.Address == addr {
+		tx.Proposer.Signature = sig
+		return true
+	}
+	for i := range tx.Approvals {
+		if tx.Approvals[i].Address == addr {
+			tx.Approvals[i].Signature = sig
+			return true
+		}
+	}
+	return false
+}
+
+func (tx *BridgeValidatorsTx) String() string {
+	return fmt.Sprintf("BridgeValidatorsTx{%v, chain: %v, validators: %v, approvals: %v}",
+		tx.Proposer.Address, tx.Validators.ChainID, len(tx.Validators.Validators), len(tx.Approvals))
+}
+
+//-----------------------------------------------------------------------------
+
+type SponsoredTx struct {
+	Sponsor   common.Address    `json:"sponsor"`   // sponsor account, pays the fee of the inner transaction
+	Tx        common.Bytes      `json:"tx"`        // the inner transaction, signed by its sender
+	Signature *crypto.Signature `json:"signature"` // signature of the sponsor
+}
+
+func (_ *SponsoredTx) AssertIsTx() {}
+
+// SignBytes returns the bytes signed by the sponsor. They cover the inner transaction together
+// with the signature of its sender, so the sponsorship cannot be replayed once the inner
+// transaction is executed.
+func (tx *SponsoredTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Signature
+	tx.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Signature = sig
+	return signBytes
+}
+
+func (tx *SponsoredTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Sponsor == addr {
+		tx.Signature = sig
+		return true
+	}
+	return false
+}
+
+// InnerTx decodes the inner transaction.
+func (tx *SponsoredTx) InnerTx() (Tx, error) {
+	return TxFromBytes(tx.Tx)
+}
+
+func (tx *SponsoredTx) String() string {
+	return fmt.Sprintf("SponsoredTx{%v, tx: %v}", tx.Sponsor, hex.EncodeToString(tx.Tx))
+}
+
+//-----------------------------------------------------------------------------
+
+type TimeLockTx struct {
+	Fee           Coins          `json:"fee"`            // Fee
+	Source        TxInput        `json:"source"`         // source account, the coins of the input are locked
+	Recipient     common.Address `json:"recipient"`      // recipient of the coins once released
+	Revoker       common.Address `json:"revoker"`        // account allowed to revoke the lock before the release, empty if irrevocable
+	ReleaseHeight uint64         `json:"release_height"` // height the coins are released at, 0 if not height-locked
+	ReleaseTime   uint64         `json:"release_time"`   // unix timestamp the coins are released at, 0 if not time-locked
+}
+
+func (_ *TimeLockTx) AssertIsTx() {}
+
+func (tx *TimeLockTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Source.Signature
+	tx.Source.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Source.Signature = sig
+	return signBytes
+}
+
+func (tx *TimeLockTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Source.Address == addr {
+		tx.Source.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *TimeLockTx) String() string {
+	return fmt.Sprintf("TimeLockTx{%v -> %v, coins: %v, revoker: %v, release height: %v, release time: %v}",
+		tx.Source.Address, tx.Recipient, tx.Source.Coins, tx.Revoker, tx.ReleaseHeight, tx.ReleaseTime)
+}
+
+//-----------------------------------------------------------------------------
+
+type TimeLockClaimTx struct {
+	Fee       Coins   `json:"fee"`       // Fee
+	Recipient TxInput `json:"recipient"` // recipient account of the time lock, pays the fee
+	LockID    uint64  `json:"lock_id"`   // ID of the time lock
+}
+
+func (_ *TimeLockClaimTx) AssertIsTx() {}
+
+func (tx *TimeLockClaimTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Recipient.Signature
+	tx.Recipient.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Recipient.Signature = sig
+	return signBytes
+}
+
+func (tx *TimeLockClaimTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Recipient.Address == addr {
+		tx.Recipient.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *TimeLockClaimTx) String() string {
+	return fmt.Sprintf("TimeLockClaimTx{%v, lock: %v}", tx.Recipient.Address, tx.LockID)
+}
+
+//-----------------------------------------------------------------------------
+
+type TimeLockRevokeTx struct {
+	Fee     Coins   `json:"fee"`     // Fee
+	Revoker TxInput `json:"revoker"` // revoker account of the time lock, pays the fee
+	LockID  uint64  `json:"lock_id"` // ID of the time lock
+}
+
+func (_ *TimeLockRevokeTx) AssertIsTx() {}
+
+func (tx *TimeLockRevokeTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Revoker.Signature
+	tx.Revoker.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Revoker.Signature = sig
+	return signBytes
+}
+
+func (tx *TimeLockRevokeTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Revoker.Address == addr {
+		tx.Revoker.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *TimeLockRevokeTx) String() string {
+	return fmt.Sprintf("TimeLockRevokeTx{%v, lock: %v}", tx.Revoker.Address, tx.LockID)
+}
+
+//-----------------------------------------------------------------------------
+
+type DelegateTx struct {
+	Fee       Coins          `json:"fee"`       // Fee
+	Delegator TxInput        `json:"delegator"` // delegator account, the Theta of the input is delegated
+	Validator common.Address `json:"validator"` // validator candidate delegated to
+}
+
+func (_ *DelegateTx) AssertIsTx() {}
+
+func (tx *DelegateTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Delegator.Signature
+	tx.Delegator.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Delegator.Signature = sig
+	return signBytes
+}
+
+func (tx *DelegateTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Delegator.Address == addr {
+		tx.Delegator.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *DelegateTx) String() string {
+	return fmt.Sprintf("DelegateTx{%v -> %v, stake: %v}",
+		tx.Delegator.Address, tx.Validator, tx.Delegator.Coins.ThetaWei)
+}
+
+//-----------------------------------------------------------------------------
+
+type UndelegateTx struct {
+	Fee       Coins          `json:"fee"`       // Fee
+	Delegator TxInput        `json:"delegator"` // delegator account, pays the fee
+	Validator common.Address `json:"validator"` // validator candidate delegated to
+}
+
+func (_ *UndelegateTx) AssertIsTx() {}
+
+func (tx *UndelegateTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Delegator.Signature
+	tx.Delegator.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Delegator.Signature = sig
+	return signBytes
+}
+
+func (tx *UndelegateTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Delegator.Address == addr {
+		tx.Delegator.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *UndelegateTx) String() string {
+	return fmt.Sprintf("UndelegateTx{%v <- %v}", tx.Delegator.Address, tx.Validator)
+}
+
 // --------------- Utils --------------- //
 
+// GetTxAddresses returns the addresses involved in the transaction. An address may appear more
+// than once.
+func GetTxAddresses(tx Tx) []common.Address {
+	switch tx := tx.(type) {
+	case *CoinbaseTx:
+		addrs := []common.Address{tx.Proposer.Address}
+		for _, out := range tx.Outputs {
+			addrs = append(addrs, out.Address)
+		}
+		return addrs
+	case *SlashTx:
+		return []common.Address{tx.Proposer.Address, tx.SlashedAddress}
+	case *SendTx:
+		addrs := []common.Address{}
+		for _, in := range tx.Inputs {
+			addrs = append(addrs, in.Address)
+		}
+		for _, out := range tx.Outputs {
+			addrs = append(addrs, out.Address)
+		}
+		return addrs
+	case *ReserveFundTx:
+		return []common.Address{tx.Source.Address}
+	case *ReleaseFundTx:
+		return []common.Address{tx.Source.Address}
+	case *ServicePaymentTx:
+		return []common.Address{tx.Source.Address, tx.Target.Address}
+	case *SplitRuleTx:
+		return []common.Address{tx.Initiator.Address}
+	case *SmartContractTx:
+		return []common.Address{tx.From.Address, tx.To.Address}
+	case *DepositStakeTx:
+		return []common.Address{tx.Source.Address, tx.Holder.Address}
+	case *DepositStakeTxV2:
+		return []common.Address{tx.Source.Address, tx.Holder.Address}
+	case *WithdrawStakeTx:
+		return []common.Address{tx.Source.Address, tx.Holder.Address}
+	case *GovernanceTx:
+		return []common.Address{tx.Proposer.Address}
+	case *GovernanceProposalTx:
+		return []common.Address{tx.Proposer.Address}
+	case *GovernanceVoteTx:
+		return []common.Address{tx.Voter.Address}
+	case *GovernanceTallyTx:
+		return []common.Address{tx.Caller.Address}
+	case *BridgeLockTx:
+		return []common.Address{tx.Source.Address, BridgeEscrowAddress}
+	case *BridgeMintTx:
+		return []common.Address{tx.Relayer.Address, tx.Message.Recipient, BridgeEscrowAddress}
+	case *BridgeValidatorsTx:
+		return []common.Address{tx.Proposer.Address}
+	case *SponsoredTx:
+		addrs := []common.Address{tx.Sponsor}
+		if inner, err := tx.InnerTx(); err == nil {
+			addrs = append(addrs, GetTxAddresses(inner)...)
+		}
+		return addrs
+	case *TimeLockTx:
+		return []common.Address{tx.Source.Address, TimeLockEscrowAddress}
+	case *TimeLockClaimTx:
+		return []common.Address{tx.Recipient.Address, TimeLockEscrowAddress}
+	case *TimeLockRevokeTx:
+		return []common.Address{tx.Revoker.Address, TimeLockEscrowAddress}
+	case *DelegateTx:
+		return []common.Address{tx.Delegator.Address, tx.Validator}
+	case *UndelegateTx:
+		return []common.Address{tx.Delegator.Address, tx.Validator}
+	}
+	return []common.Address{}
+}
+
 // Need to add the following prefix to the tx signbytes to be compatible with
 // the Ethereum tx format
 func addPrefixForSignBytes(signBytes common.Bytes) common.Bytes {
@@ -787,3 +787,106 @@ func TestSmartContractTxJSON(t *testing.T) {
 	assert.Equal(uint64(math.MaxUint64), d.GasLimit)
 	assert.Equal(0, gasPrice.Cmp(d.GasPrice))
 }
+
+func TestGovernanceTxProto(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	chainID := "test_chain_id"
+	va1PrivAcc := PrivAccountFromSecret("validator1")
+	va2PrivAcc := PrivAccountFromSecret("validator2")
+
+	params := DefaultChainParams()
+	params.MaxBlockGas = 5000000
+	tx := &GovernanceTx{
+		Fee:       NewCoins(0, int64(MinimumTransactionFeeTFuelWei)),
+		Proposer:  NewTxInput(va1PrivAcc.Address, Coins{}, 1),
+		Params:    *params,
+		Approvals: []GovernanceApproval{{Address: va2PrivAcc.Address}},
+	}
+
+	// serialize this and back
+	b, err := TxToBytes(tx)
+	require.Nil(err)
+	txs, err := TxFromBytes(b)
+	require.Nil(err)
+	tx2 := txs.(*GovernanceTx)
+
+	// the proposer and the approver sign the same bytes
+	signBytes := tx.SignBytes(chainID)
+	assert.Equal(signBytes, tx2.SignBytes(chainID))
+
+	assert.True(tx.SetSignature(va1PrivAcc.Address, va1PrivAcc.Sign(signBytes)))
+	assert.True(tx.SetSignature(va2PrivAcc.Address, va2PrivAcc.Sign(signBytes)))
+	assert.False(tx.SetSignature(getTestAddress("014FAB"), va2PrivAcc.Sign(signBytes)))
+	assert.Equal(signBytes, tx.SignBytes(chainID))
+
+	b, err = TxToBytes(tx)
+	require.Nil(err)
+	txs, err = TxFromBytes(b)
+	require.Nil(err)
+	tx2 = txs.(*GovernanceTx)
+
+	// and make sure the sigs and params are preserved
+	assert.Equal(tx.Proposer.Signature, tx2.Proposer.Signature)
+	assert.Equal(tx.Approvals[0].Signature, tx2.Approvals[0].Signature)
+	assert.True(tx2.Approvals[0].Signature.Verify(signBytes, va2PrivAcc.Address))
+	assert.Equal(uint64(5000000), tx2.Params.MaxBlockGas)
+	assert.Equal(0, params.MinimumGasPrice.Cmp(tx2.Params.MinimumGasPrice))
+}
+
+func TestChainParamsJSON(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	a := DefaultChainParams()
+	a.MinimumTransactionFeeTFuelWei, _ = new(big.Int).SetString("12312312312312312312331231231231212312312312312313213", 10)
+	s, err := json.Marshal(a)
+	require.Nil(err)
+
+	var d ChainParams
+	err = json.Unmarshal(s, &d)
+	require.Nil(err)
+	assert.Equal(a.MaxNumRegularTxsPerBlock, d.MaxNumRegularTxsPerBlock)
+	assert.Equal(0, a.MinimumTransactionFeeTFuelWei.Cmp(d.MinimumTransactionFeeTFuelWei))
+	assert.Nil(d.Validate())
+
+	d.MaxBlockBytes = 0
+	assert.NotNil(d.Validate())
+}
+
+func TestDepositStakeTxV2Proto(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	chainID := "test_chain_id"
+	sourcePrivAcc := PrivAccountFromSecret("source")
+	holderPrivAcc := PrivAccountFromSecret("guardian")
+
+	tx := &DepositStakeTxV2{
+		Fee:       NewCoins(0, int64(MinimumTransactionFeeTFuelWei)),
+		Source:    NewTxInput(sourcePrivAcc.Address, NewCoins(1000, 0), 1),
+		Holder:    TxOutput{Address: holderPrivAcc.Address},
+		Purpose:   1,
+		BlsPubkey: common.Bytes("bls_pubkey"),
+		BlsPop:    common.Bytes("bls_pop"),
+	}
+	tx.HolderSig = holderPrivAcc.Sign(tx.HolderSignBytes())
+	signBytes := tx.SignBytes(chainID)
+	assert.True(tx.SetSignature(sourcePrivAcc.Address, sourcePrivAcc.Sign(signBytes)))
+	assert.Equal(signBytes, tx.SignBytes(chainID))
+
+	b, err := TxToBytes(tx)
+	require.Nil(err)
+	txs, err := TxFromBytes(b)
+	require.Nil(err)
+	tx2 := txs.(*DepositStakeTxV2)
+
+	assert.Equal(signBytes, tx2.SignBytes(chainID))
+	assert.Equal(tx.BlsPubkey, tx2.BlsPubkey)
+	assert.Equal(tx.BlsPop, tx2.BlsPop)
+	assert.True(tx2.Source.Signature.Verify(signBytes, sourcePrivAcc.Address))
+	assert.True(tx2.HolderSig.Verify(tx2.HolderSignBytes(), holderPrivAcc.Address))
+
+	// The holder signature is bound to the BLS key
+	tx2.BlsPubkey = common.Bytes("another_bls_pubkey")
+	assert.False(tx2.HolderSig.Verify(tx2.HolderSignBytes(), holderPrivAcc.Address))
+}
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+)
+
+//
+// UnbondingEntry is a withdrawn stake, or an undelegated stake, waiting in the unbonding queue.
+// The stake no longer counts for the holder, but it is not returned to the source before the
+// return height.
+//
+type UnbondingEntry struct {
+	Source       common.Address
+	Holder       common.Address
+	Purpose      uint8 // core.StakeForValidator or core.StakeForGuardian
+	Delegated    bool  // true for a stake undelegated from a validator candidate
+	Amount       *big.Int
+	StartHeight  uint64
+	ReturnHeight uint64
+}
+
+func (e *UnbondingEntry) String() string {
+	return fmt.Sprintf("UnbondingEntry{source: %v, holder: %v, purpose: %v, delegated: %v, amount: %v, start: %v, return: %v}",
+		e.Source, e.Holder, e.Purpose, e.Delegated, e.Amount, e.StartHeight, e.ReturnHeight)
+}
+
+//
+// UnbondingQueue holds the unbonding entries in increasing return height order. The entries
+// with the same return height are kept in the order they were added.
+//
+type UnbondingQueue struct {
+	Entries []*UnbondingEntry
+}
+
+// Add inserts the entry into the queue.
+func (q *UnbondingQueue) Add(entry *UnbondingEntry) {
+	idx := sort.Search(len(q.Entries), func(i int) bool {
+		return q.Entries[i].ReturnHeight > entry.ReturnHeight
+	})
+	q.Entries = append(q.Entries, nil)
+	copy(q.Entries[idx+1:], q.Entries[idx:])
+	q.Entries[idx] = entry
+}
+
+// PopMatured removes the entries whose return height has been reached, and returns them.
+func (q *UnbondingQueue) PopMatured(currentHeight uint64) []*UnbondingEntry {
+	idx := sort.Search(len(q.Entries), func(i int) bool {
+		return q.Entries[i].ReturnHeight > currentHeight
+	})
+	matured := q.Entries[:idx:idx]
+	q.Entries = q.Entries[idx:]
+	return matured
+}
+
+// Find returns the entries whose source or holder is the given address.
+func (q *UnbondingQueue) Find(address common.Address) []*UnbondingEntry {
+	entries := []*UnbondingEntry{}
+	for _, entry := range q.Entries {
+		if entry.Source == address || entry.Holder == address {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Len returns the number of entries in the queue.
+func (q *UnbondingQueue) Len() int {
+	return len(q.Entries)
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+)
+
+func TestUnbondingQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	source := common.HexToAddress("0x1")
+	holder := common.HexToAddress("0x2")
+	queue := &UnbondingQueue{}
+	for idx, returnHeight := range []uint64{30, 10, 20, 10} {
+		queue.Add(&UnbondingEntry{
+			Source:       source,
+			Holder:       holder,
+			StartHeight:  uint64(idx),
+			ReturnHeight: returnHeight,
+		})
+	}
+
+	// The entries are ordered by return height, then by the order they were added
+	var startHeights []uint64
+	for _, entry := range queue.Entries {
+		startHeights = append(startHeights, entry.StartHeight)
+	}
+	assert.Equal([]uint64{1, 3, 2, 0}, startHeights)
+	assert.Equal(4, len(queue.Find(holder)))
+	assert.Equal(0, len(queue.Find(common.HexToAddress("0x3"))))
+
+	assert.Equal(0, len(queue.PopMatured(9)))
+	matured := queue.PopMatured(20)
+	assert.Equal(3, len(matured))
+	assert.Equal(uint64(20), matured[2].ReturnHeight)
+	assert.Equal(1, queue.Len())
+
+	// The queue survives the encoding
+	queueBytes, err := ToBytes(queue)
+	assert.Nil(err)
+	decoded := &UnbondingQueue{}
+	assert.Nil(FromBytes(queueBytes, decoded))
+	assert.Equal(uint64(30), decoded.Entries[0].ReturnHeight)
+}
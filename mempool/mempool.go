@@ -3,21 +3,27 @@ package mempool
 import (
 	"context"
 	"encoding/hex"
-	"errors"
 	"math/big"
+	"math/rand"
+	"sort"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/clist"
 	"github.com/thetatoken/theta/common/math"
 	"github.com/thetatoken/theta/common/pqueue"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
 	dp "github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/rlp"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "mempool"})
+var logger *log.Entry = util.GetLoggerForModule("mempool")
 
 type MempoolError string
 
@@ -25,7 +31,35 @@ func (m MempoolError) Error() string {
 	return string(m)
 }
 
+// ErrorCode returns the error code of the mempool error.
+func (m MempoolError) ErrorCode() result.ErrorCode {
+	switch m {
+	case DuplicateTxError:
+		return result.CodeTxAlreadySeen
+	case ReplacementUnderpricedError:
+		return result.CodeReplacementUnderpriced
+	case ExceedsBlockBudgetError:
+		return result.CodeExceedsBlockBudget
+	case FutureTxQueueFullError:
+		return result.CodeFutureTxQueueFull
+	case SenderPendingLimitError:
+		return result.CodeSenderPendingLimit
+	case SenderFeeTooLowError:
+		return result.CodeSenderFeeTooLow
+	}
+	return result.CodeGenericError
+}
+
 const DuplicateTxError = MempoolError("Transaction already seen")
+const ReplacementUnderpricedError = MempoolError("Replacement transaction underpriced")
+const ExceedsBlockBudgetError = MempoolError("Transaction exceeds the block gas or size budget")
+const FutureTxQueueFullError = MempoolError("Too many future sequence transactions queued")
+const SenderPendingLimitError = MempoolError("Too many pending transactions from the sender")
+const SenderFeeTooLowError = MempoolError("Transaction fee too low for the pending transactions of the sender")
+
+// futureTxPromotionInterval is the interval between two attempts to promote the queued future
+// sequence transactions, whose gap could have been filled by transactions committed in blocks.
+const futureTxPromotionInterval = 1 * time.Second
 
 //
 // mempoolTransaction implements the pqueue.Element interface
@@ -34,6 +68,7 @@ type mempoolTransaction struct {
 	index          int
 	rawTransaction common.Bytes
 	txInfo         *core.TxInfo
+	feePerByte     *big.Int
 }
 
 var _ pqueue.Element = (*mempoolTransaction)(nil)
@@ -55,17 +90,26 @@ func createMempoolTransaction(rawTransaction common.Bytes, txInfo *core.TxInfo)
 	return &mempoolTransaction{
 		rawTransaction: rawTransaction,
 		txInfo:         txInfo,
+		feePerByte:     calculateFeePerByte(rawTransaction, txInfo),
+	}
+}
+
+func calculateFeePerByte(rawTransaction common.Bytes, txInfo *core.TxInfo) *big.Int {
+	if txInfo.Fee == nil || len(rawTransaction) == 0 {
+		return new(big.Int).SetUint64(0)
 	}
+	return new(big.Int).Div(txInfo.Fee, big.NewInt(int64(len(rawTransaction))))
 }
 
 //
-// mempoolTransactionGroup holds a sequenece of transactions from one account. We sort transaction groups by the priority of
-// their lowest sequence transaction.
+// mempoolTransactionGroup holds a sequenece of transactions from one account. We sort transaction groups by the fee per
+// byte of their lowest sequence transaction, so that transactions from the same account are always ordered by sequence.
 //
 type mempoolTransactionGroup struct {
-	address common.Address
-	txs     *pqueue.PriorityQueue
-	index   int
+	address      common.Address
+	txs          *pqueue.PriorityQueue
+	sequenceToTx map[uint64]*mempoolTransaction
+	index        int
 }
 
 var _ pqueue.Element = (*mempoolTransactionGroup)(nil)
@@ -74,7 +118,7 @@ func (mtg *mempoolTransactionGroup) Priority() *big.Int {
 	if mtg.IsEmpty() {
 		return new(big.Int).SetInt64(-1)
 	}
-	return mtg.txs.Peek().(*mempoolTransaction).txInfo.EffectiveGasPrice
+	return mtg.txs.Peek().(*mempoolTransaction).feePerByte
 }
 
 func (mtg *mempoolTransactionGroup) SetIndex(index int) {
@@ -85,16 +129,65 @@ func (mtg *mempoolTransactionGroup) GetIndex() int {
 	return mtg.index
 }
 
-func (mtg *mempoolTransactionGroup) AddTx(rawTx common.Bytes, txInfo *core.TxInfo) {
-	mpx := createMempoolTransaction(rawTx, txInfo)
-	mtg.txs.Push(mpx)
+func (mtg *mempoolTransactionGroup) AddTx(mptx *mempoolTransaction) {
+	mtg.txs.Push(mptx)
+	mtg.sequenceToTx[mptx.txInfo.Sequence] = mptx
+}
+
+// FindTx returns the transaction with the given sequence, or nil if there is none.
+func (mtg *mempoolTransactionGroup) FindTx(sequence uint64) *mempoolTransaction {
+	return mtg.sequenceToTx[sequence]
+}
+
+// TxsBefore returns the transactions of the group with a lower sequence than the given one, in
+// increasing sequence order.
+func (mtg *mempoolTransactionGroup) TxsBefore(sequence uint64) []common.Bytes {
+	earlier := []*mempoolTransaction{}
+	for seq, mptx := range mtg.sequenceToTx {
+		if seq < sequence {
+			earlier = append(earlier, mptx)
+		}
+	}
+	sort.Slice(earlier, func(i, j int) bool {
+		return earlier[i].txInfo.Sequence < earlier[j].txInfo.Sequence
+	})
+	rawTxs := make([]common.Bytes, len(earlier))
+	for i, mptx := range earlier {
+		rawTxs[i] = mptx.rawTransaction
+	}
+	return rawTxs
+}
+
+// ReplaceTx replaces an existing transaction of the group with a new one of the same sequence.
+func (mtg *mempoolTransactionGroup) ReplaceTx(existing *mempoolTransaction, replacement *mempoolTransaction) {
+	mtg.txs.Remove(existing.GetIndex())
+	mtg.AddTx(replacement)
+}
+
+func (mtg *mempoolTransactionGroup) PeekTx() *mempoolTransaction {
+	return mtg.txs.Peek().(*mempoolTransaction)
 }
 
 func (mtg *mempoolTransactionGroup) PopTx() (common.Bytes, *core.TxInfo) {
 	mptx := mtg.txs.Pop().(*mempoolTransaction)
+	delete(mtg.sequenceToTx, mptx.txInfo.Sequence)
 	return mptx.rawTransaction, mptx.txInfo
 }
 
+// Load returns the number of transactions in the group, their total size in bytes, and the lowest
+// fee per byte among them.
+func (mtg *mempoolTransactionGroup) Load() (numTxs int, numBytes uint64, minFeePerByte *big.Int) {
+	for _, elem := range *mtg.txs.ElementList() {
+		mptx := elem.(*mempoolTransaction)
+		numTxs++
+		numBytes += uint64(len(mptx.rawTransaction))
+		if minFeePerByte == nil || mptx.feePerByte.Cmp(minFeePerByte) < 0 {
+			minFeePerByte = mptx.feePerByte
+		}
+	}
+	return numTxs, numBytes, minFeePerByte
+}
+
 func (mtg *mempoolTransactionGroup) IsEmpty() bool {
 	return mtg.txs.IsEmpty()
 }
@@ -113,17 +206,19 @@ func (mtg *mempoolTransactionGroup) RemoveTxs(committedRawTxMap map[string]bool)
 	}
 	for _, elem := range elemsTobeRemoved {
 		mtg.txs.Remove(elem.GetIndex())
+		delete(mtg.sequenceToTx, elem.(*mempoolTransaction).txInfo.Sequence)
 		numRemoved++
 	}
 	return
 }
 
-func createMempoolTransactionGroup(rawTx common.Bytes, txInfo *core.TxInfo) *mempoolTransactionGroup {
+func createMempoolTransactionGroup(mptx *mempoolTransaction) *mempoolTransactionGroup {
 	txGroup := &mempoolTransactionGroup{
-		address: txInfo.Address,
-		txs:     pqueue.CreatePriorityQueue(),
+		address:      mptx.txInfo.Address,
+		txs:          pqueue.CreatePriorityQueue(),
+		sequenceToTx: make(map[uint64]*mempoolTransaction),
 	}
-	txGroup.AddTx(rawTx, txInfo)
+	txGroup.AddTx(mptx)
 	return txGroup
 }
 
@@ -138,11 +233,33 @@ type Mempool struct {
 	dispatcher *dp.Dispatcher
 
 	newTxs           *clist.CList          // new transactions, to be gossiped to other nodes
-	candidateTxs     *pqueue.PriorityQueue // candidate transactions for new block assembly, ordered by the transaction fee (high to low)
+	newTxAvailable   chan struct{}         // signaled when a new transaction is pushed to newTxs
+	candidateTxs     *pqueue.PriorityQueue // candidate transactions for new block assembly, ordered by the fee per byte (high to low)
 	txBookeepper     transactionBookkeeper
+	gossip           *txGossip
 	addressToTxGroup map[common.Address]*mempoolTransactionGroup
 	size             int
 
+	// Transactions with a future sequence, held until the sequence gap is filled
+	futureTxs    map[common.Address]map[uint64]*mempoolTransaction
+	numFutureTxs int
+
+	maxBlockGas    uint64 // Gas budget of the reaped transactions, 0 means uncapped
+	maxBlockBytes  uint64 // Size budget of the reaped transactions, 0 means uncapped
+	replaceFeeBump uint64 // Min fee per byte increase in percent required to replace a pending transaction
+
+	maxFutureTxs           int
+	maxFutureTxsPerAccount int
+
+	// Limits on the pending transactions of an account, so that one account cannot fill the
+	// mempool with cheap transactions
+	maxPendingTxsPerAccount   int
+	maxPendingBytesPerAccount uint64
+	freePendingTxsPerAccount  int
+	pendingFeeBump            uint64
+
+	reconcileInterval time.Duration // Interval between two reconciliations with the peers, 0 disables it
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	quit    chan struct{}
@@ -157,10 +274,26 @@ func CreateMempool(dispatcher *dp.Dispatcher) *Mempool {
 		mutex:            &sync.Mutex{},
 		dispatcher:       dispatcher,
 		newTxs:           clist.New(),
+		newTxAvailable:   make(chan struct{}, 1),
 		candidateTxs:     pqueue.CreatePriorityQueue(),
 		addressToTxGroup: make(map[common.Address]*mempoolTransactionGroup),
 		txBookeepper:     createTransactionBookkeeper(defaultMaxNumTxs),
+		gossip:           createTxGossip(defaultMaxNumAnnouncedTxs),
+		maxBlockGas:      uint64(viper.GetInt64(common.CfgMempoolMaxBlockGas)),
+		maxBlockBytes:    uint64(viper.GetInt64(common.CfgMempoolMaxBlockBytes)),
+		replaceFeeBump:   uint64(viper.GetInt64(common.CfgMempoolReplaceFeeBump)),
+		futureTxs:        make(map[common.Address]map[uint64]*mempoolTransaction),
 		wg:               &sync.WaitGroup{},
+
+		maxFutureTxs:           viper.GetInt(common.CfgMempoolMaxFutureTxs),
+		maxFutureTxsPerAccount: viper.GetInt(common.CfgMempoolMaxFutureTxsPerAccount),
+
+		maxPendingTxsPerAccount:   viper.GetInt(common.CfgMempoolMaxPendingTxsPerAccount),
+		maxPendingBytesPerAccount: uint64(viper.GetInt64(common.CfgMempoolMaxPendingBytesPerAccount)),
+		freePendingTxsPerAccount:  viper.GetInt(common.CfgMempoolFreePendingTxsPerAccount),
+		pendingFeeBump:            uint64(viper.GetInt64(common.CfgMempoolPendingFeeBump)),
+
+		reconcileInterval: time.Duration(viper.GetInt(common.CfgMempoolReconcileInterval)) * time.Second,
 	}
 }
 
@@ -174,19 +307,77 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	mp.mutex.Lock()
 	defer mp.mutex.Unlock()
 
+	batch := mp.ledger.NewScreenBatch()
+	defer batch.Commit()
+
+	return mp.insertTransactionUnsafe(batch, rawTx)
+}
+
+// InsertTransactions inserts the given transactions in order, screening them in one batch. The
+// returned errors correspond to the transactions.
+func (mp *Mempool) InsertTransactions(rawTxs []common.Bytes) []error {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	batch := mp.ledger.NewScreenBatch()
+	defer batch.Commit()
+
+	errs := make([]error, len(rawTxs))
+	for i, rawTx := range rawTxs {
+		errs[i] = mp.insertTransactionUnsafe(batch, rawTx)
+	}
+	return errs
+}
+
+func (mp *Mempool) insertTransactionUnsafe(batch core.ScreenBatch, rawTx common.Bytes) error {
 	if mp.txBookeepper.hasSeen(rawTx) {
 		logger.Infof("[mempool] Transaction already seen: %v", hex.EncodeToString(rawTx))
 		return DuplicateTxError
 	}
 
-	txInfo, checkTxRes := mp.ledger.ScreenTx(rawTx)
+	txInfo, checkTxRes := batch.ScreenTx(rawTx)
+	if checkTxRes.Code == result.CodeInvalidSequence && txInfo != nil {
+		// The sequence is taken by a pooled transaction, which the new one might replace
+		if txGroup, ok := mp.addressToTxGroup[txInfo.Address]; ok && txGroup.FindTx(txInfo.Sequence) != nil {
+			txInfo, checkTxRes = batch.ScreenReplacementTx(rawTx, txGroup.TxsBefore(txInfo.Sequence))
+		}
+	}
+	if checkTxRes.Code == result.CodeFutureSequence && txInfo != nil {
+		return mp.addFutureTx(createMempoolTransaction(rawTx, txInfo))
+	}
 	if !checkTxRes.IsOK() {
 		logger.Infof("[mempool] Transaction screening failed, tx: %v, error: %v", hex.EncodeToString(rawTx), checkTxRes.Message)
-		return errors.New(checkTxRes.Message)
+		return checkTxRes.ToError()
+	}
+
+	mptx := createMempoolTransaction(rawTx, txInfo)
+	if !mp.fitsBudget(mp.maxBlockGas, mp.maxBlockBytes, 0, 0, mptx) {
+		logger.Infof("[mempool] Transaction exceeds the block budget, tx: %v, txInfo: %v", hex.EncodeToString(rawTx), txInfo)
+		return ExceedsBlockBudgetError
+	}
+
+	txGroup, ok := mp.addressToTxGroup[txInfo.Address]
+	var existing *mempoolTransaction
+	if ok {
+		existing = txGroup.FindTx(txInfo.Sequence)
+		if existing != nil && !mp.isReplacementAcceptable(existing, mptx) {
+			logger.Infof("[mempool] Replacement transaction underpriced, tx: %v, txInfo: %v", hex.EncodeToString(rawTx), txInfo)
+			return ReplacementUnderpricedError
+		}
+		if existing == nil {
+			if err := mp.checkSenderLoad(txGroup, mptx); err != nil {
+				logger.Infof("[mempool] Transaction rejected, tx: %v, txInfo: %v, error: %v", hex.EncodeToString(rawTx), txInfo, err)
+				return err
+			}
+		}
 	}
 
 	logger.Infof("[mempool] Insert tx: %v, txInfo: %v", hex.EncodeToString(rawTx), txInfo)
 
+	// The screened effects of the transaction are kept only once it is accepted, so that the
+	// rejected transactions do not affect the screening of the later ones.
+	batch.Accept()
+
 	// only record the transactions that passed the screening. This is because that
 	// an invalid transaction could becoume valid later on. For example, assume expected
 	// sequence for an account is 6. The account accidently submits txA (seq = 7), got rejected.
@@ -194,21 +385,199 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	// should not be rejected even though it has been submitted earlier.
 	mp.txBookeepper.record(rawTx)
 
-	txGroup, ok := mp.addressToTxGroup[txInfo.Address]
 	if ok {
-		txGroup.AddTx(rawTx, txInfo)
+		if existing != nil {
+			logger.Infof("[mempool] Replace tx: %v", hex.EncodeToString(existing.rawTransaction))
+			txGroup.ReplaceTx(existing, mptx)
+		} else {
+			txGroup.AddTx(mptx)
+			mp.size++
+		}
 		mp.candidateTxs.Remove(txGroup.index) // Need to re-insert txGroup into queue since its priority could change.
 	} else {
-		txGroup = createMempoolTransactionGroup(rawTx, txInfo)
+		txGroup = createMempoolTransactionGroup(mptx)
 		mp.addressToTxGroup[txInfo.Address] = txGroup
+		mp.size++
 	}
 	mp.candidateTxs.Push(txGroup)
 
 	mp.newTxs.PushBack(rawTx)
-	mp.size++
+	select {
+	case mp.newTxAvailable <- struct{}{}:
+	default:
+	}
+
+	mp.promoteFutureTx(batch, txInfo.Address, txInfo.Sequence+1)
 	return nil
 }
 
+// addFutureTx queues a transaction whose sequence is ahead of the expected one. It is neither
+// recorded nor gossiped until promoted, since it might never become valid.
+func (mp *Mempool) addFutureTx(mptx *mempoolTransaction) error {
+	address, sequence := mptx.txInfo.Address, mptx.txInfo.Sequence
+	accountTxs, ok := mp.futureTxs[address]
+	if !ok {
+		accountTxs = make(map[uint64]*mempoolTransaction)
+	}
+	if existing, ok := accountTxs[sequence]; ok {
+		if !mp.isReplacementAcceptable(existing, mptx) {
+			logger.Infof("[mempool] Replacement future transaction underpriced, tx: %v, txInfo: %v", hex.EncodeToString(mptx.rawTransaction), mptx.txInfo)
+			return ReplacementUnderpricedError
+		}
+		accountTxs[sequence] = mptx
+		return nil
+	}
+	if mp.numFutureTxs >= mp.maxFutureTxs || len(accountTxs) >= mp.maxFutureTxsPerAccount {
+		logger.Infof("[mempool] Future transaction queue full, tx: %v, txInfo: %v", hex.EncodeToString(mptx.rawTransaction), mptx.txInfo)
+		return FutureTxQueueFullError
+	}
+
+	logger.Infof("[mempool] Queue future tx: %v, txInfo: %v", hex.EncodeToString(mptx.rawTransaction), mptx.txInfo)
+
+	accountTxs[sequence] = mptx
+	mp.futureTxs[address] = accountTxs
+	mp.numFutureTxs++
+	return nil
+}
+
+// removeFutureTx removes the queued future transaction of the given address and sequence.
+func (mp *Mempool) removeFutureTx(address common.Address, sequence uint64) *mempoolTransaction {
+	accountTxs, ok := mp.futureTxs[address]
+	if !ok {
+		return nil
+	}
+	mptx, ok := accountTxs[sequence]
+	if !ok {
+		return nil
+	}
+	delete(accountTxs, sequence)
+	if len(accountTxs) == 0 {
+		delete(mp.futureTxs, address)
+	}
+	mp.numFutureTxs--
+	return mptx
+}
+
+// promoteFutureTx screens again the queued transaction of the given address and sequence, if
+// any. A successful insertion in turn promotes the transaction with the next sequence.
+func (mp *Mempool) promoteFutureTx(batch core.ScreenBatch, address common.Address, sequence uint64) {
+	mptx := mp.removeFutureTx(address, sequence)
+	if mptx == nil {
+		return
+	}
+	logger.Infof("[mempool] Promote future tx: %v, txInfo: %v", hex.EncodeToString(mptx.rawTransaction), mptx.txInfo)
+	if err := mp.insertTransactionUnsafe(batch, mptx.rawTransaction); err != nil {
+		logger.Infof("[mempool] Failed to promote future tx: %v, error: %v", hex.EncodeToString(mptx.rawTransaction), err)
+	}
+}
+
+// PromoteFutureTxs screens again the queued transactions of each account in increasing sequence
+// order, until one is still ahead of the expected sequence. The sequence gaps could have been
+// filled by transactions committed in blocks. Transactions that became invalid, e.g. because
+// their sequence has been used, are dropped.
+func (mp *Mempool) PromoteFutureTxs() {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	batch := mp.ledger.NewScreenBatch()
+	defer batch.Commit()
+
+	addresses := []common.Address{}
+	for address := range mp.futureTxs {
+		addresses = append(addresses, address)
+	}
+	for _, address := range addresses {
+		for {
+			lowest, ok := mp.lowestFutureSequence(address)
+			if !ok {
+				break
+			}
+			mp.promoteFutureTx(batch, address, lowest)
+			if _, requeued := mp.futureTxs[address][lowest]; requeued {
+				break
+			}
+		}
+	}
+}
+
+func (mp *Mempool) lowestFutureSequence(address common.Address) (lowest uint64, ok bool) {
+	for sequence := range mp.futureTxs[address] {
+		if !ok || sequence < lowest {
+			lowest = sequence
+			ok = true
+		}
+	}
+	return lowest, ok
+}
+
+// NumFutureTxs returns the number of queued future sequence transactions
+func (mp *Mempool) NumFutureTxs() int {
+	return mp.numFutureTxs
+}
+
+// isReplacementAcceptable returns true if the replacement pays a high enough fee per byte to
+// replace the existing transaction with the same sequence.
+func (mp *Mempool) isReplacementAcceptable(existing *mempoolTransaction, replacement *mempoolTransaction) bool {
+	if replacement.feePerByte.Cmp(existing.feePerByte) <= 0 {
+		return false
+	}
+	required := new(big.Int).Mul(existing.feePerByte, new(big.Int).SetUint64(100+mp.replaceFeeBump))
+	offered := new(big.Int).Mul(replacement.feePerByte, big.NewInt(100))
+	return offered.Cmp(required) >= 0
+}
+
+// checkSenderLoad checks a new transaction against the pending transactions of its sender. The
+// number and the total size of the pending transactions of an account are capped, and once the
+// account has more pending transactions than the free ones, each additional one needs to pay a
+// fee per byte pendingFeeBump percent higher than the previous one, starting from the lowest fee
+// per byte of the pending transactions.
+func (mp *Mempool) checkSenderLoad(txGroup *mempoolTransactionGroup, mptx *mempoolTransaction) error {
+	if mp.maxPendingTxsPerAccount <= 0 && mp.maxPendingBytesPerAccount == 0 && mp.freePendingTxsPerAccount <= 0 {
+		return nil
+	}
+	numTxs, numBytes, minFeePerByte := txGroup.Load()
+	if mp.maxPendingTxsPerAccount > 0 && numTxs >= mp.maxPendingTxsPerAccount {
+		return SenderPendingLimitError
+	}
+	if mp.maxPendingBytesPerAccount > 0 && numBytes+uint64(len(mptx.rawTransaction)) > mp.maxPendingBytesPerAccount {
+		return SenderPendingLimitError
+	}
+	if mp.freePendingTxsPerAccount <= 0 || numTxs < mp.freePendingTxsPerAccount {
+		return nil
+	}
+
+	required := new(big.Int).Set(minFeePerByte)
+	offered := new(big.Int).Set(mptx.feePerByte)
+	for i := mp.freePendingTxsPerAccount; i <= numTxs; i++ {
+		required.Mul(required, new(big.Int).SetUint64(100+mp.pendingFeeBump))
+		offered.Mul(offered, big.NewInt(100))
+	}
+	if offered.Cmp(required) < 0 {
+		return SenderFeeTooLowError
+	}
+	return nil
+}
+
+// fitsBudget returns true if the transaction can be added to a block which has already used the
+// given amount of gas and bytes, without exceeding the given budget.
+func (mp *Mempool) fitsBudget(maxGas uint64, maxBytes uint64, gasUsed uint64, bytesUsed uint64, mptx *mempoolTransaction) bool {
+	if maxGas > 0 && gasUsed+mptx.txInfo.Gas > maxGas {
+		return false
+	}
+	if maxBytes > 0 && bytesUsed+uint64(len(mptx.rawTransaction)) > maxBytes {
+		return false
+	}
+	return true
+}
+
+// tighterBudget returns the smaller of the two budgets, where 0 means uncapped.
+func tighterBudget(a uint64, b uint64) uint64 {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
 // Start needs to be called when the Mempool starts
 func (mp *Mempool) Start(ctx context.Context) error {
 	c, cancel := context.WithCancel(ctx)
@@ -218,6 +587,14 @@ func (mp *Mempool) Start(ctx context.Context) error {
 	mp.wg.Add(1)
 	go mp.broadcastTransactionsRoutine()
 
+	mp.wg.Add(1)
+	go mp.promoteFutureTxsRoutine()
+
+	if mp.reconcileInterval > 0 {
+		mp.wg.Add(1)
+		go mp.reconcileTransactionsRoutine()
+	}
+
 	return nil
 }
 
@@ -248,7 +625,8 @@ func (mp *Mempool) Size() int {
 
 // Reap returns a list of valid raw transactions and remove these
 // transactions from the candidate pool. maxNumTxs == 0 means
-// none, maxNumTxs < 0 means uncapped. Note that Reap does NOT remove
+// none, maxNumTxs < 0 means uncapped. The reaped transactions also
+// stay within the configured block gas and size budget. Note that Reap does NOT remove
 // the transactions from the candidateTxs list. Instead, the consensus engine needs
 // to call the Mempool.Update() function to remove the committed transactions
 // RUNTIME COMPLEXITY: k*log(n), where k is the number transactions to reap,
@@ -262,22 +640,64 @@ func (mp *Mempool) Reap(maxNumTxs int) []common.Bytes {
 
 // ReapUnsafe is the non-locking version of Reap.
 func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
+	return mp.ReapWithinLimitsUnsafe(maxNumTxs, 0, 0)
+}
+
+// ReapWithinLimitsUnsafe is the non-locking version of Reap, which also keeps the reaped
+// transactions within the given block gas and size limits, e.g. set by the chain params.
+// A limit of 0 means uncapped. The configured budget applies if it is tighter.
+func (mp *Mempool) ReapWithinLimitsUnsafe(maxNumTxs int, maxGas uint64, maxBytes uint64) []common.Bytes {
+	reaped := mp.reapWithinLimitsUnsafe(maxNumTxs, maxGas, maxBytes)
+	txs := make([]common.Bytes, 0, len(reaped))
+	for _, mptx := range reaped {
+		txs = append(txs, mptx.rawTransaction)
+	}
+	return txs
+}
+
+// SelectWithinLimitsUnsafe returns the transactions ReapWithinLimitsUnsafe would reap, but keeps
+// them in the Mempool, e.g. to prepare the transactions of a block which is proposed later.
+// Caller must call Mempool.Lock() before calling this method.
+func (mp *Mempool) SelectWithinLimitsUnsafe(maxNumTxs int, maxGas uint64, maxBytes uint64) []common.Bytes {
+	reaped := mp.reapWithinLimitsUnsafe(maxNumTxs, maxGas, maxBytes)
+	txs := make([]common.Bytes, 0, len(reaped))
+	for _, mptx := range reaped {
+		txs = append(txs, mptx.rawTransaction)
+		mp.restoreTxUnsafe(mptx)
+	}
+	return txs
+}
+
+func (mp *Mempool) reapWithinLimitsUnsafe(maxNumTxs int, maxGas uint64, maxBytes uint64) []*mempoolTransaction {
+	maxGas = tighterBudget(mp.maxBlockGas, maxGas)
+	maxBytes = tighterBudget(mp.maxBlockBytes, maxBytes)
+
 	if maxNumTxs == 0 {
-		return []common.Bytes{}
+		return []*mempoolTransaction{}
 	} else if maxNumTxs < 0 {
 		maxNumTxs = mp.Size()
 	} else {
 		maxNumTxs = math.MinInt(mp.Size(), maxNumTxs)
 	}
 
-	txs := make([]common.Bytes, 0, maxNumTxs)
-	for i := 0; i < maxNumTxs; i++ {
+	reaped := make([]*mempoolTransaction, 0, maxNumTxs)
+	skippedTxGroups := []*mempoolTransactionGroup{}
+	var gasUsed, bytesUsed uint64
+	for len(reaped) < maxNumTxs {
 		if mp.candidateTxs.IsEmpty() {
 			break
 		}
 		txGroup := mp.candidateTxs.Pop().(*mempoolTransactionGroup)
-		rawTx, txInfo := txGroup.PopTx()
-		txs = append(txs, rawTx)
+		if !mp.fitsBudget(maxGas, maxBytes, gasUsed, bytesUsed, txGroup.PeekTx()) {
+			// None of the remaining transactions of the account can be included before this one.
+			skippedTxGroups = append(skippedTxGroups, txGroup)
+			continue
+		}
+		mptx := txGroup.PeekTx()
+		txGroup.PopTx()
+		reaped = append(reaped, mptx)
+		gasUsed += mptx.txInfo.Gas
+		bytesUsed += uint64(len(mptx.rawTransaction))
 
 		if txGroup.IsEmpty() {
 			delete(mp.addressToTxGroup, txGroup.address)
@@ -286,11 +706,118 @@ func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
 		}
 
 		logger.Debugf("[mempool] Reap tx: %v, txInfo: %v",
-			hex.EncodeToString(rawTx), txInfo)
+			hex.EncodeToString(mptx.rawTransaction), mptx.txInfo)
 	}
 
-	mp.size -= len(txs)
+	for _, txGroup := range skippedTxGroups {
+		mp.candidateTxs.Push(txGroup)
+	}
+
+	mp.size -= len(reaped)
 
+	return reaped
+}
+
+// restoreTxUnsafe puts a reaped transaction back to the candidate pool.
+func (mp *Mempool) restoreTxUnsafe(mptx *mempoolTransaction) {
+	txGroup, ok := mp.addressToTxGroup[mptx.txInfo.Address]
+	if ok {
+		mp.candidateTxs.Remove(txGroup.index) // Need to re-insert txGroup into queue since its priority could change.
+		txGroup.AddTx(mptx)
+	} else {
+		txGroup = createMempoolTransactionGroup(mptx)
+		mp.addressToTxGroup[mptx.txInfo.Address] = txGroup
+	}
+	mp.candidateTxs.Push(txGroup)
+	mp.size++
+}
+
+// GetPendingSequence returns the highest sequence among the transactions from the given
+// address that are pending in the Mempool. The second return value is false if there is none.
+func (mp *Mempool) GetPendingSequence(address common.Address) (uint64, bool) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	txGroup, ok := mp.addressToTxGroup[address]
+	if !ok || txGroup.IsEmpty() {
+		return 0, false
+	}
+	var maxSequence uint64
+	for _, elem := range *txGroup.txs.ElementList() {
+		if seq := elem.(*mempoolTransaction).txInfo.Sequence; seq > maxSequence {
+			maxSequence = seq
+		}
+	}
+	return maxSequence, true
+}
+
+// PoolTx is a transaction held in the Mempool, either pending or queued with a future sequence
+type PoolTx struct {
+	RawTx  common.Bytes
+	TxInfo *core.TxInfo
+}
+
+// GetPoolTxs returns the pending transactions and the queued future sequence transactions in the
+// Mempool, grouped by sender and sorted by sequence. If sender is not nil, only the transactions
+// of the sender are returned.
+func (mp *Mempool) GetPoolTxs(sender *common.Address) (pending map[common.Address][]PoolTx, queued map[common.Address][]PoolTx) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	matches := func(address common.Address) bool {
+		return sender == nil || *sender == address
+	}
+
+	pending = make(map[common.Address][]PoolTx)
+	for address, txGroup := range mp.addressToTxGroup {
+		if !matches(address) || txGroup.IsEmpty() {
+			continue
+		}
+		txs := []PoolTx{}
+		for _, elem := range *txGroup.txs.ElementList() {
+			mptx := elem.(*mempoolTransaction)
+			txs = append(txs, PoolTx{RawTx: mptx.rawTransaction, TxInfo: mptx.txInfo})
+		}
+		pending[address] = sortPoolTxs(txs)
+	}
+
+	queued = make(map[common.Address][]PoolTx)
+	for address, accountTxs := range mp.futureTxs {
+		if !matches(address) {
+			continue
+		}
+		txs := []PoolTx{}
+		for _, mptx := range accountTxs {
+			txs = append(txs, PoolTx{RawTx: mptx.rawTransaction, TxInfo: mptx.txInfo})
+		}
+		queued[address] = sortPoolTxs(txs)
+	}
+	return pending, queued
+}
+
+func sortPoolTxs(txs []PoolTx) []PoolTx {
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].TxInfo.Sequence < txs[j].TxInfo.Sequence
+	})
+	return txs
+}
+
+// GetCandidateTxs returns up to maxNumTxs candidate transactions without removing them from
+// the Mempool. maxNumTxs <= 0 means uncapped.
+func (mp *Mempool) GetCandidateTxs(maxNumTxs int) []common.Bytes {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	txs := []common.Bytes{}
+	for _, elem := range *mp.candidateTxs.ElementList() {
+		txGroup := elem.(*mempoolTransactionGroup)
+		for _, txElem := range *txGroup.txs.ElementList() {
+			if maxNumTxs > 0 && len(txs) >= maxNumTxs {
+				return txs
+			}
+			txs = append(txs, txElem.(*mempoolTransaction).rawTransaction)
+		}
+	}
 	return txs
 }
 
@@ -338,14 +865,38 @@ func (mp *Mempool) Flush() {
 	defer mp.mutex.Unlock()
 
 	mp.txBookeepper.reset()
+	mp.gossip.reset()
 
 	for !mp.candidateTxs.IsEmpty() {
 		mp.candidateTxs.Pop()
 	}
+	mp.addressToTxGroup = make(map[common.Address]*mempoolTransactionGroup)
 	mp.size = 0
+	mp.futureTxs = make(map[common.Address]map[uint64]*mempoolTransaction)
+	mp.numFutureTxs = 0
 }
 
-// broadcastTransactionRoutine broadcasts transactions to neighoring peers
+// promoteFutureTxsRoutine periodically promotes the queued future transactions whose gap has been
+// filled by committed blocks. It can't be done in UpdateUnsafe() since the ledger lock is held.
+func (mp *Mempool) promoteFutureTxsRoutine() {
+	defer mp.wg.Done()
+
+	ticker := time.NewTicker(futureTxPromotionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mp.ctx.Done():
+			return
+		case <-ticker.C:
+			mp.PromoteFutureTxs()
+		}
+	}
+}
+
+// broadcastTransactionRoutine announces the hashes of the new transactions to neighoring peers.
+// The peers request the transactions they have not seen yet, so the full transactions are not
+// pushed to the peers which already have them.
 func (mp *Mempool) broadcastTransactionsRoutine() {
 	defer mp.wg.Done()
 
@@ -359,22 +910,69 @@ func (mp *Mempool) broadcastTransactionsRoutine() {
 		}
 
 		if next == nil {
-			next = mp.newTxs.FrontWait() // Wait until a tx is available
+			next = mp.newTxs.Front()
+		}
+		if next == nil {
+			// Wait until a tx is available, or the mempool stops
+			select {
+			case <-mp.ctx.Done():
+				mp.stopped = true
+				return
+			case <-mp.newTxAvailable:
+			}
+			continue
 		}
 
-		rawTx := next.Value.(common.Bytes)
+		// Announce the transactions available so far in one message
+		txhashes := []string{}
+		for next != nil && len(txhashes) < dp.MaxInventorySize {
+			rawTx := next.Value.(common.Bytes)
+			txhashes = append(txhashes, mp.gossip.announce(rawTx))
 
-		// Broadcast the transaction
-		data := dp.DataResponse{
-			ChannelID: common.ChannelIDTransaction,
-			Payload:   rawTx,
+			curr := next
+			next = curr.Next()
+			mp.newTxs.Remove(curr) // already announced, should remove
+		}
+
+		announcement := dp.InventoryResponse{
+			ChannelID: common.ChannelIDTxGossip,
+			Entries:   txhashes,
 		}
 
 		peerIDs := []string{} // empty peerID list means broadcasting to all neighboring peers
-		mp.dispatcher.SendData(peerIDs, data)
+		mp.dispatcher.SendInventory(peerIDs, announcement)
+	}
+}
+
+// reconcileTransactionsRoutine periodically sends the sketch of the pending transaction hashes to
+// the neighboring peers. A peer decodes the difference with its own pending transactions, to
+// recover the transactions the gossip failed to deliver to either side, e.g. during a partition.
+func (mp *Mempool) reconcileTransactionsRoutine() {
+	defer mp.wg.Done()
 
-		curr := next
-		next = curr.NextWait()
-		mp.newTxs.Remove(curr) // already broadcasted, should remove
+	ticker := time.NewTicker(mp.reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mp.ctx.Done():
+			return
+		case <-ticker.C:
+			if mp.Size() == 0 {
+				continue
+			}
+			sketch := createTxSketch(mp.GetCandidateTxs(0), defaultTxSketchCells, rand.Uint64())
+			payload, err := rlp.EncodeToBytes(sketch)
+			if err != nil {
+				logger.Errorf("Failed to encode the transaction sketch: %v", err)
+				continue
+			}
+			data := dp.DataResponse{
+				ChannelID: common.ChannelIDTxReconcile,
+				Payload:   payload,
+			}
+			peerIDs := []string{} // empty peerID list means broadcasting to all neighboring peers
+			mp.dispatcher.SendData(peerIDs, data)
+		}
 	}
 }
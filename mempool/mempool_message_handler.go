@@ -13,8 +13,10 @@ import (
 )
 
 //
-// MempoolMessageHandler handles the messages received over the
-// ChannelIDTransaction channel
+// MempoolMessageHandler handles the messages received over the ChannelIDTxGossip
+// channel, the full transactions pushed over the ChannelIDTransaction channel by
+// the peers without the transaction gossip capability, and the transaction sketches
+// received over the ChannelIDTxReconcile channel
 //
 type MempoolMessageHandler struct {
 	mempool *Mempool
@@ -31,16 +33,64 @@ func CreateMempoolMessageHandler(mempool *Mempool) *MempoolMessageHandler {
 func (mmh *MempoolMessageHandler) GetChannelIDs() []common.ChannelIDEnum {
 	return []common.ChannelIDEnum{
 		common.ChannelIDTransaction,
+		common.ChannelIDTxGossip,
+		common.ChannelIDTxReconcile,
 	}
 }
 
 // EncodeMessage implements the p2p.MessageHandler interface
 func (mmh *MempoolMessageHandler) EncodeMessage(message interface{}) (common.Bytes, error) {
+	switch content := message.(type) {
+	case dp.InventoryResponse, dp.DataRequest:
+		return encodeGossipMessage(content)
+	case dp.DataResponse:
+		if content.ChannelID == common.ChannelIDTxGossip {
+			return encodeGossipMessage(content)
+		}
+	}
 	return rlp.EncodeToBytes(message)
 }
 
+// DowngradeMessage implements the p2p.MessageDowngrader interface. The transactions announced to
+// a peer without the transaction gossip capability are pushed to it in full instead.
+func (mmh *MempoolMessageHandler) DowngradeMessage(message types.Message) []types.Message {
+	announcement, ok := message.Content.(dp.InventoryResponse)
+	if !ok || message.ChannelID != common.ChannelIDTxGossip {
+		return nil
+	}
+	messages := []types.Message{}
+	for _, txhash := range announcement.Entries {
+		rawTx, ok := mmh.mempool.gossip.getAnnouncedTx(txhash)
+		if !ok {
+			continue
+		}
+		messages = append(messages, types.Message{
+			PeerID:    message.PeerID,
+			ChannelID: common.ChannelIDTransaction,
+			Content: dp.DataResponse{
+				ChannelID: common.ChannelIDTransaction,
+				Payload:   rawTx,
+			},
+		})
+	}
+	return messages
+}
+
 // ParseMessage implements the p2p.MessageHandler interface
 func (mmh *MempoolMessageHandler) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (types.Message, error) {
+	if channelID == common.ChannelIDTxGossip {
+		content, err := decodeGossipMessage(rawMessageBytes)
+		if err != nil {
+			return types.Message{}, err
+		}
+		message := types.Message{
+			PeerID:    peerID,
+			ChannelID: channelID,
+			Content:   content,
+		}
+		return message, nil
+	}
+
 	var dataResponse dp.DataResponse
 	rlp.DecodeBytes(rawMessageBytes, &dataResponse)
 
@@ -55,12 +105,160 @@ func (mmh *MempoolMessageHandler) ParseMessage(peerID string, channelID common.C
 
 // HandleMessage implements the p2p.MessageHandler interface
 func (mmh *MempoolMessageHandler) HandleMessage(message types.Message) error {
-	if message.ChannelID != common.ChannelIDTransaction {
+	switch message.ChannelID {
+	case common.ChannelIDTransaction:
+		rawTx := message.Content.(common.Bytes)
+		logger.Infof("Received gossiped transaction: %v", hex.EncodeToString(rawTx))
+		return mmh.insertTransaction(rawTx)
+	case common.ChannelIDTxGossip:
+		return mmh.handleGossipMessage(message)
+	case common.ChannelIDTxReconcile:
+		return mmh.handleSketch(message.PeerID, message.Content.(common.Bytes))
+	default:
 		return fmt.Errorf("Invalid channel for MempoolMessageHandler: %v", message.ChannelID)
 	}
-	rawTx := message.Content.(common.Bytes)
-	logger.Infof("Received gossiped transaction: %v", hex.EncodeToString(rawTx))
+}
+
+func (mmh *MempoolMessageHandler) handleGossipMessage(message types.Message) error {
+	switch content := message.Content.(type) {
+	case dp.InventoryResponse:
+		mmh.handleAnnouncement(message.PeerID, content)
+	case dp.DataRequest:
+		mmh.handleRequest(message.PeerID, content)
+	case dp.DataResponse:
+		rawTx := content.Payload
+		logger.Infof("Received requested transaction: %v", hex.EncodeToString(rawTx))
+		mmh.mempool.gossip.markReceived(rawTx)
+		return mmh.insertTransaction(rawTx)
+	default:
+		return fmt.Errorf("Invalid transaction gossip message: %v", message.Content)
+	}
+	return nil
+}
+
+// handleAnnouncement requests the announced transactions which have not been seen yet from the announcing peer
+func (mmh *MempoolMessageHandler) handleAnnouncement(peerID string, announcement dp.InventoryResponse) {
+	unseen := []string{}
+	for _, txhash := range announcement.Entries {
+		if !mmh.mempool.txBookeepper.hasSeenHash(txhash) {
+			unseen = append(unseen, txhash)
+		}
+	}
+
+	toRequest := mmh.mempool.gossip.markRequested(unseen)
+	if len(toRequest) == 0 {
+		return
+	}
+
+	logger.Debugf("Requesting %v announced transactions from peer %v", len(toRequest), peerID)
+	request := dp.DataRequest{
+		ChannelID: common.ChannelIDTxGossip,
+		Entries:   toRequest,
+	}
+	mmh.mempool.dispatcher.GetData([]string{peerID}, request)
+}
+
+// handleRequest sends the requested transactions which are still kept to the requesting peer
+func (mmh *MempoolMessageHandler) handleRequest(peerID string, request dp.DataRequest) {
+	if len(request.Entries) > dp.MaxInventorySize {
+		logger.Debugf("Transaction request from peer %v exceeds the max inventory size", peerID)
+		return
+	}
+
+	for _, txhash := range request.Entries {
+		rawTx, ok := mmh.mempool.gossip.getAnnouncedTx(txhash)
+		if !ok {
+			continue
+		}
+		data := dp.DataResponse{
+			ChannelID: common.ChannelIDTxGossip,
+			Payload:   rawTx,
+		}
+		mmh.mempool.dispatcher.SendData([]string{peerID}, data)
+	}
+}
+
+// handleSketch reconciles the pending transactions with the sketch of the peer. The transactions
+// only the peer has are requested from it, and those only the node has are announced to it. If
+// the difference is too large to be decoded, the highest priority pending transactions are
+// announced instead.
+func (mmh *MempoolMessageHandler) handleSketch(peerID string, payload common.Bytes) error {
+	remote := &TxSketch{}
+	err := rlp.DecodeBytes(payload, remote)
+	if err != nil {
+		return err
+	}
+	err = remote.validate()
+	if err != nil {
+		return err
+	}
+
+	rawTxs := mmh.mempool.GetCandidateTxs(0)
+	local := createTxSketch(rawTxs, len(remote.Cells), remote.Salt)
+	diff, err := remote.subtract(local)
+	if err != nil {
+		return err
+	}
+	remoteOnly, localOnly, ok := remote.decodeDiff(diff)
+	if !ok {
+		logger.Debugf("Failed to decode the transaction sketch of peer %v, announcing the pending transactions", peerID)
+		if len(rawTxs) > dp.MaxInventorySize {
+			rawTxs = rawTxs[:dp.MaxInventorySize]
+		}
+		mmh.announceTxs(peerID, rawTxs, nil)
+		return nil
+	}
+
+	logger.Debugf("Reconciled the transactions with peer %v, %v missing locally, %v missing remotely",
+		peerID, len(remoteOnly), len(localOnly))
+	for start := 0; start < len(remoteOnly); start += dp.MaxInventorySize {
+		end := start + dp.MaxInventorySize
+		if end > len(remoteOnly) {
+			end = len(remoteOnly)
+		}
+		mmh.handleAnnouncement(peerID, dp.InventoryResponse{
+			ChannelID: common.ChannelIDTxGossip,
+			Entries:   remoteOnly[start:end],
+		})
+	}
+	if len(localOnly) > 0 {
+		wanted := make(map[string]bool)
+		for _, txhash := range localOnly {
+			wanted[txhash] = true
+		}
+		mmh.announceTxs(peerID, rawTxs, wanted)
+	}
+	return nil
+}
+
+// announceTxs announces the transactions whose hash is wanted to the peer, or all of them if
+// wanted is nil, up to dp.MaxInventorySize per message
+func (mmh *MempoolMessageHandler) announceTxs(peerID string, rawTxs []common.Bytes, wanted map[string]bool) {
+	txhashes := []string{}
+	for _, rawTx := range rawTxs {
+		if wanted != nil && !wanted[getTransactionHash(rawTx)] {
+			continue
+		}
+		txhashes = append(txhashes, mmh.mempool.gossip.announce(rawTx))
+		if len(txhashes) == dp.MaxInventorySize {
+			mmh.sendAnnouncement(peerID, txhashes)
+			txhashes = []string{}
+		}
+	}
+	if len(txhashes) > 0 {
+		mmh.sendAnnouncement(peerID, txhashes)
+	}
+}
+
+func (mmh *MempoolMessageHandler) sendAnnouncement(peerID string, txhashes []string) {
+	announcement := dp.InventoryResponse{
+		ChannelID: common.ChannelIDTxGossip,
+		Entries:   txhashes,
+	}
+	mmh.mempool.dispatcher.SendInventory([]string{peerID}, announcement)
+}
 
+func (mmh *MempoolMessageHandler) insertTransaction(rawTx common.Bytes) error {
 	err := mmh.mempool.InsertTransaction(rawTx)
 	if err == DuplicateTxError {
 		return nil
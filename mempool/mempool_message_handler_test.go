@@ -8,6 +8,7 @@ import (
 	"github.com/thetatoken/theta/common"
 	dp "github.com/thetatoken/theta/dispatcher"
 	p2psim "github.com/thetatoken/theta/p2p/simulation"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
 	"github.com/thetatoken/theta/rlp"
 )
 
@@ -69,3 +70,149 @@ func TestMempoolMessageHandler(t *testing.T) {
 	assert.Equal("tx1", string(reapedRawTxs[1][:]))
 	assert.Equal("tx3", string(reapedRawTxs[2][:]))
 }
+
+func TestMempoolMessageHandlerTxGossip(t *testing.T) {
+	assert := assert.New(t)
+
+	netMsgIntercepter := newTestNetworkMessageInterceptor()
+	p2psimnet := p2psim.NewSimnetWithHandler(netMsgIntercepter)
+	mempool, ctx := newTestMempool("peer0", p2psimnet)
+	peer1 := p2psimnet.AddEndpoint("peer1")
+	peer1.Start(ctx)
+	p2psimnet.Start(ctx)
+
+	mmh := CreateMempoolMessageHandler(mempool)
+
+	tx1 := createTestRawTx("tx1")
+	tx2 := createTestRawTx("tx2")
+	tx3 := createTestRawTx("tx3")
+	assert.Nil(mempool.InsertTransaction(tx1))
+
+	// The gossip messages survive the encoding
+	announcement := dp.InventoryResponse{
+		ChannelID: common.ChannelIDTxGossip,
+		Entries:   []string{getTransactionHash(tx1), getTransactionHash(tx2), getTransactionHash(tx3)},
+	}
+	contentBytes, err := mmh.EncodeMessage(announcement)
+	assert.Nil(err)
+	message, err := mmh.ParseMessage("peer1", common.ChannelIDTxGossip, contentBytes)
+	assert.Nil(err)
+	assert.Equal(announcement, message.Content)
+
+	// Only the transactions not seen yet are requested from the announcing peer
+	assert.Nil(mmh.HandleMessage(message))
+	receivedMsg := <-netMsgIntercepter.ReceivedMessages
+	request := receivedMsg.Content.(dp.DataRequest)
+	assert.Equal(common.ChannelIDTxGossip, request.ChannelID)
+	assert.Equal([]string{getTransactionHash(tx2), getTransactionHash(tx3)}, request.Entries)
+
+	// Transactions already requested are not requested again from another peer
+	message.PeerID = "peer2"
+	assert.Nil(mmh.HandleMessage(message))
+
+	for _, rawTx := range []common.Bytes{tx2, tx3} {
+		data := dp.DataResponse{
+			ChannelID: common.ChannelIDTxGossip,
+			Payload:   rawTx,
+		}
+		contentBytes, err := mmh.EncodeMessage(data)
+		assert.Nil(err)
+		message, err := mmh.ParseMessage("peer1", common.ChannelIDTxGossip, contentBytes)
+		assert.Nil(err)
+		assert.Nil(mmh.HandleMessage(message))
+	}
+	assert.Equal(3, mempool.Size())
+
+	// Announced transactions are served on request
+	mempool.gossip.announce(tx1)
+	request = dp.DataRequest{
+		ChannelID: common.ChannelIDTxGossip,
+		Entries:   []string{getTransactionHash(createTestRawTx("tx4")), getTransactionHash(tx1)},
+	}
+	assert.Nil(mmh.HandleMessage(p2ptypes.Message{PeerID: "peer1", ChannelID: common.ChannelIDTxGossip, Content: request}))
+	receivedMsg = <-netMsgIntercepter.ReceivedMessages
+	data := receivedMsg.Content.(dp.DataResponse)
+	assert.Equal(tx1, data.Payload)
+}
+
+func TestMempoolMessageHandlerDowngrade(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mmh := CreateMempoolMessageHandler(mempool)
+
+	tx1 := createTestRawTx("tx1")
+	mempool.gossip.announce(tx1)
+	announcement := dp.InventoryResponse{
+		ChannelID: common.ChannelIDTxGossip,
+		Entries:   []string{getTransactionHash(tx1), getTransactionHash(createTestRawTx("tx2"))},
+	}
+
+	// The announced transactions still kept are pushed in full to the peers without tx gossip
+	messages := mmh.DowngradeMessage(p2ptypes.Message{ChannelID: common.ChannelIDTxGossip, Content: announcement})
+	assert.Equal(1, len(messages))
+	assert.Equal(common.ChannelIDTransaction, messages[0].ChannelID)
+	contentBytes, err := mmh.EncodeMessage(messages[0].Content)
+	assert.Nil(err)
+	message, err := mmh.ParseMessage("peer1", common.ChannelIDTransaction, contentBytes)
+	assert.Nil(err)
+	assert.Equal(tx1, message.Content)
+
+	// The requests and responses of the gossip protocol are not conveyed
+	request := dp.DataRequest{ChannelID: common.ChannelIDTxGossip, Entries: announcement.Entries}
+	assert.Nil(mmh.DowngradeMessage(p2ptypes.Message{ChannelID: common.ChannelIDTxGossip, Content: request}))
+}
+
+func TestMempoolMessageHandlerReconcile(t *testing.T) {
+	assert := assert.New(t)
+
+	netMsgIntercepter := newTestNetworkMessageInterceptor()
+	p2psimnet := p2psim.NewSimnetWithHandler(netMsgIntercepter)
+	mempool, ctx := newTestMempool("peer0", p2psimnet)
+	peer1 := p2psimnet.AddEndpoint("peer1")
+	peer1.Start(ctx)
+	p2psimnet.Start(ctx)
+
+	mmh := CreateMempoolMessageHandler(mempool)
+
+	tx1 := createTestRawTx("tx1")
+	tx2 := createTestRawTx("tx2")
+	tx3 := createTestRawTx("tx3")
+	assert.Nil(mempool.InsertTransaction(tx1))
+	assert.Nil(mempool.InsertTransaction(tx2))
+
+	// The sketch of the peer survives the encoding
+	sketch := createTxSketch([]common.Bytes{tx2, tx3}, defaultTxSketchCells, 1)
+	payload, err := rlp.EncodeToBytes(sketch)
+	assert.Nil(err)
+	contentBytes, err := mmh.EncodeMessage(dp.DataResponse{ChannelID: common.ChannelIDTxReconcile, Payload: payload})
+	assert.Nil(err)
+	message, err := mmh.ParseMessage("peer1", common.ChannelIDTxReconcile, contentBytes)
+	assert.Nil(err)
+	assert.Equal(common.Bytes(payload), message.Content)
+
+	// The transaction only the peer has is requested, and the one only the node has is announced
+	assert.Nil(mmh.HandleMessage(message))
+	for i := 0; i < 2; i++ {
+		receivedMsg := <-netMsgIntercepter.ReceivedMessages
+		switch content := receivedMsg.Content.(type) {
+		case dp.DataRequest:
+			assert.Equal([]string{getTransactionHash(tx3)}, content.Entries)
+		case dp.InventoryResponse:
+			assert.Equal([]string{getTransactionHash(tx1)}, content.Entries)
+			_, ok := mempool.gossip.getAnnouncedTx(getTransactionHash(tx1))
+			assert.True(ok)
+		default:
+			assert.Fail("Unexpected message", "%v", receivedMsg.Content)
+		}
+	}
+
+	// Malformed sketches are rejected
+	message.Content = common.Bytes{0x01}
+	assert.NotNil(mmh.HandleMessage(message))
+	payload, err = rlp.EncodeToBytes(&TxSketch{Cells: make([]TxSketchCell, 4)})
+	assert.Nil(err)
+	message.Content = common.Bytes(payload)
+	assert.NotNil(mmh.HandleMessage(message))
+}
@@ -152,6 +152,32 @@ func TestMempoolBasics(t *testing.T) {
 	assert.Equal("tx3", string(reapedRawTxs[2][:])) // priority: 32
 }
 
+func TestMempoolPendingSequence(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+
+	for i := 1; i <= 5; i++ {
+		assert.Nil(mempool.InsertTransaction(createTestRawTx("tx" + strconv.Itoa(i))))
+	}
+
+	seq, ok := mempool.GetPendingSequence(common.HexToAddress("A1"))
+	assert.True(ok)
+	assert.Equal(uint64(1023), seq) // tx1 and tx4 are both from A1
+
+	seq, ok = mempool.GetPendingSequence(common.HexToAddress("B1"))
+	assert.True(ok)
+	assert.Equal(uint64(1033), seq)
+
+	_, ok = mempool.GetPendingSequence(common.HexToAddress("C1"))
+	assert.False(ok)
+
+	mempool.Reap(-1)
+	_, ok = mempool.GetPendingSequence(common.HexToAddress("A1"))
+	assert.False(ok)
+}
+
 func TestMempoolReapOrder(t *testing.T) {
 	assert := assert.New(t)
 
@@ -197,6 +223,296 @@ func TestMempoolReapOrder(t *testing.T) {
 	assert.Equal("tx3", string(reapedRawTxs[9][:]))  // gasPrice: 32, address: A3, seq: 2012
 }
 
+func TestMempoolReplaceByFee(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+
+	addrA := common.HexToAddress("A1")
+	addrB := common.HexToAddress("B1")
+	mempool.ledger.(*TestLedger).txInfos = map[string]*core.TxInfo{
+		"txA1":      {Address: addrA, Sequence: 1, Fee: big.NewInt(400)}, // fee per byte: 100
+		"txA2":      {Address: addrA, Sequence: 2, Fee: big.NewInt(4000)},
+		"txB1":      {Address: addrB, Sequence: 1, Fee: big.NewInt(800)},  // fee per byte: 200
+		"txA1_low":  {Address: addrA, Sequence: 1, Fee: big.NewInt(840)},  // fee per byte: 105
+		"txA1_high": {Address: addrA, Sequence: 1, Fee: big.NewInt(2700)}, // fee per byte: 300
+	}
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+	assert.Equal(3, mempool.Size())
+
+	// The fee bump is below the required 10%.
+	assert.Equal(ReplacementUnderpricedError, mempool.InsertTransaction(createTestRawTx("txA1_low")))
+	assert.Equal(3, mempool.Size())
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1_high")))
+	assert.Equal(3, mempool.Size())
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(3, len(reapedRawTxs))
+	assert.Equal("txA1_high", string(reapedRawTxs[0]))
+	assert.Equal("txA2", string(reapedRawTxs[1])) // fee per byte: 1000
+	assert.Equal("txB1", string(reapedRawTxs[2]))
+}
+
+func TestMempoolReapWithinBudget(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.maxBlockGas = 50000
+
+	addrA := common.HexToAddress("A1")
+	addrB := common.HexToAddress("B1")
+	addrC := common.HexToAddress("C1")
+	mempool.ledger.(*TestLedger).txInfos = map[string]*core.TxInfo{
+		"txA1":   {Address: addrA, Sequence: 1, Fee: big.NewInt(4000), Gas: 20000},
+		"txA2":   {Address: addrA, Sequence: 2, Fee: big.NewInt(4000), Gas: 10000},
+		"txB1":   {Address: addrB, Sequence: 1, Fee: big.NewInt(2000), Gas: 40000},
+		"txC1":   {Address: addrC, Sequence: 1, Fee: big.NewInt(400), Gas: 20000},
+		"txHuge": {Address: addrC, Sequence: 2, Fee: big.NewInt(400000), Gas: 60000},
+	}
+
+	assert.Equal(ExceedsBlockBudgetError, mempool.InsertTransaction(createTestRawTx("txHuge")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txC1")))
+
+	// txB1 does not fit after txA1 and txA2, the remaining budget goes to txC1.
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(3, len(reapedRawTxs))
+	assert.Equal("txA1", string(reapedRawTxs[0]))
+	assert.Equal("txA2", string(reapedRawTxs[1]))
+	assert.Equal("txC1", string(reapedRawTxs[2]))
+	assert.Equal(1, mempool.Size())
+
+	reapedRawTxs = mempool.Reap(-1)
+	assert.Equal(1, len(reapedRawTxs))
+	assert.Equal("txB1", string(reapedRawTxs[0]))
+}
+
+func TestMempoolReapWithinChainLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.maxBlockGas = 50000
+
+	addrA := common.HexToAddress("A1")
+	addrB := common.HexToAddress("B1")
+	mempool.ledger.(*TestLedger).txInfos = map[string]*core.TxInfo{
+		"txA1": {Address: addrA, Sequence: 1, Fee: big.NewInt(4000), Gas: 20000},
+		"txA2": {Address: addrA, Sequence: 2, Fee: big.NewInt(4000), Gas: 20000},
+		"txB1": {Address: addrB, Sequence: 1, Fee: big.NewInt(2000), Gas: 20000},
+	}
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+
+	// The chain gas limit is tighter than the configured budget
+	mempool.Lock()
+	reapedRawTxs := mempool.ReapWithinLimitsUnsafe(-1, 30000, 0)
+	mempool.Unlock()
+	assert.Equal(1, len(reapedRawTxs))
+	assert.Equal("txA1", string(reapedRawTxs[0]))
+
+	// The configured budget is tighter than the chain gas limit
+	mempool.Lock()
+	reapedRawTxs = mempool.ReapWithinLimitsUnsafe(-1, 1000000, 0)
+	mempool.Unlock()
+	assert.Equal(2, len(reapedRawTxs))
+	assert.Equal(0, mempool.Size())
+}
+
+func TestMempoolSelectWithinLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+
+	addrA := common.HexToAddress("A1")
+	addrB := common.HexToAddress("B1")
+	mempool.ledger.(*TestLedger).txInfos = map[string]*core.TxInfo{
+		"txA1": {Address: addrA, Sequence: 1, Fee: big.NewInt(4000), Gas: 20000},
+		"txA2": {Address: addrA, Sequence: 2, Fee: big.NewInt(4000), Gas: 20000},
+		"txB1": {Address: addrB, Sequence: 1, Fee: big.NewInt(2000), Gas: 20000},
+	}
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+
+	// The selected transactions stay in the mempool
+	mempool.Lock()
+	selectedRawTxs := mempool.SelectWithinLimitsUnsafe(-1, 50000, 0)
+	mempool.Unlock()
+	assert.Equal(2, len(selectedRawTxs))
+	assert.Equal("txA1", string(selectedRawTxs[0]))
+	assert.Equal("txA2", string(selectedRawTxs[1]))
+	assert.Equal(3, mempool.Size())
+
+	// Selecting again yields the same transactions in the same order
+	mempool.Lock()
+	selectedRawTxs = mempool.SelectWithinLimitsUnsafe(-1, 50000, 0)
+	mempool.Unlock()
+	assert.Equal(2, len(selectedRawTxs))
+	assert.Equal("txA1", string(selectedRawTxs[0]))
+	assert.Equal("txA2", string(selectedRawTxs[1]))
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(3, len(reapedRawTxs))
+	assert.Equal("txA1", string(reapedRawTxs[0]))
+	assert.Equal("txA2", string(reapedRawTxs[1]))
+	assert.Equal("txB1", string(reapedRawTxs[2]))
+	assert.Equal(0, mempool.Size())
+}
+
+func TestMempoolFutureTxs(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.maxFutureTxsPerAccount = 3
+
+	addrA := common.HexToAddress("A1")
+	addrB := common.HexToAddress("B1")
+	ledger := mempool.ledger.(*TestLedger)
+	ledger.sequences = make(map[common.Address]uint64)
+	ledger.txInfos = map[string]*core.TxInfo{
+		"txA1": {Address: addrA, Sequence: 1, Fee: big.NewInt(400)},
+		"txA2": {Address: addrA, Sequence: 2, Fee: big.NewInt(400)},
+		"txA3": {Address: addrA, Sequence: 3, Fee: big.NewInt(400)},
+		"txA5": {Address: addrA, Sequence: 5, Fee: big.NewInt(400)},
+		"txB2": {Address: addrB, Sequence: 2, Fee: big.NewInt(400)},
+		"txB3": {Address: addrB, Sequence: 3, Fee: big.NewInt(400)},
+		"txB4": {Address: addrB, Sequence: 4, Fee: big.NewInt(400)},
+		"txB5": {Address: addrB, Sequence: 5, Fee: big.NewInt(400)},
+	}
+
+	// Batch sent out of order, the transactions are queued until the gap is filled.
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA3")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Equal(0, mempool.Size())
+	assert.Equal(2, mempool.NumFutureTxs())
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Equal(3, mempool.Size())
+	assert.Equal(0, mempool.NumFutureTxs())
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(3, len(reapedRawTxs))
+	assert.Equal("txA1", string(reapedRawTxs[0]))
+	assert.Equal("txA2", string(reapedRawTxs[1]))
+	assert.Equal("txA3", string(reapedRawTxs[2]))
+
+	// The gap is filled by a transaction committed in a block.
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA5")))
+	assert.Equal(1, mempool.NumFutureTxs())
+	ledger.sequences[addrA] = 4
+	mempool.PromoteFutureTxs()
+	assert.Equal(1, mempool.Size())
+	assert.Equal(0, mempool.NumFutureTxs())
+
+	// The number of queued transactions per account is capped.
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB2")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB3")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB4")))
+	assert.Equal(FutureTxQueueFullError, mempool.InsertTransaction(createTestRawTx("txB5")))
+
+	// Queued transactions whose sequence has been used are dropped.
+	ledger.sequences[addrB] = 2
+	mempool.PromoteFutureTxs()
+	assert.Equal(3, mempool.Size())
+	assert.Equal(0, mempool.NumFutureTxs())
+}
+
+func TestMempoolSenderLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.maxPendingTxsPerAccount = 4
+	mempool.maxPendingBytesPerAccount = 20
+	mempool.freePendingTxsPerAccount = 2
+	mempool.pendingFeeBump = 50
+
+	addrA := common.HexToAddress("A1")
+	addrB := common.HexToAddress("B1")
+	ledger := mempool.ledger.(*TestLedger)
+	ledger.txInfos = map[string]*core.TxInfo{
+		"txA1":              {Address: addrA, Sequence: 1, Fee: big.NewInt(400)},
+		"txA2":              {Address: addrA, Sequence: 2, Fee: big.NewInt(400)},
+		"txA3":              {Address: addrA, Sequence: 3, Fee: big.NewInt(400)},
+		"tyA3":              {Address: addrA, Sequence: 3, Fee: big.NewInt(600)},
+		"txA4":              {Address: addrA, Sequence: 4, Fee: big.NewInt(800)},
+		"tyA4":              {Address: addrA, Sequence: 4, Fee: big.NewInt(900)},
+		"txA5":              {Address: addrA, Sequence: 5, Fee: big.NewInt(100000)},
+		"txB1":              {Address: addrB, Sequence: 1, Fee: big.NewInt(400)},
+		"txB2":              {Address: addrB, Sequence: 2, Fee: big.NewInt(400)},
+		"txB3_long_payload": {Address: addrB, Sequence: 3, Fee: big.NewInt(100000)},
+	}
+
+	// The pending transactions beyond the free ones pay an increasing fee floor
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Equal(SenderFeeTooLowError, mempool.InsertTransaction(createTestRawTx("txA3")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("tyA3")))
+	assert.Equal(SenderFeeTooLowError, mempool.InsertTransaction(createTestRawTx("txA4")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("tyA4")))
+
+	// The number of pending transactions per account is capped
+	err := mempool.InsertTransaction(createTestRawTx("txA5"))
+	assert.Equal(SenderPendingLimitError, err)
+	assert.Equal(result.CodeSenderPendingLimit, err.(MempoolError).ErrorCode())
+
+	// So is their total size, and the limits of one account do not affect the others
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB2")))
+	assert.Equal(SenderPendingLimitError, mempool.InsertTransaction(createTestRawTx("txB3_long_payload")))
+	assert.Equal(6, mempool.Size())
+}
+
+func TestMempoolGetPoolTxs(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+
+	addrA := common.HexToAddress("A1")
+	addrB := common.HexToAddress("B1")
+	ledger := mempool.ledger.(*TestLedger)
+	ledger.sequences = make(map[common.Address]uint64)
+	ledger.txInfos = map[string]*core.TxInfo{
+		"txA1": {Address: addrA, Sequence: 1, Fee: big.NewInt(400)},
+		"txA2": {Address: addrA, Sequence: 2, Fee: big.NewInt(500)},
+		"txA4": {Address: addrA, Sequence: 4, Fee: big.NewInt(600)},
+		"txB3": {Address: addrB, Sequence: 3, Fee: big.NewInt(700)},
+	}
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA4")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB3")))
+
+	pending, queued := mempool.GetPoolTxs(nil)
+	assert.Equal(1, len(pending))
+	assert.Equal(2, len(pending[addrA]))
+	assert.Equal("txA1", string(pending[addrA][0].RawTx))
+	assert.Equal("txA2", string(pending[addrA][1].RawTx))
+	assert.Equal(2, len(queued))
+	assert.Equal("txA4", string(queued[addrA][0].RawTx))
+	assert.Equal("txB3", string(queued[addrB][0].RawTx))
+
+	pending, queued = mempool.GetPoolTxs(&addrB)
+	assert.Equal(0, len(pending))
+	assert.Equal(1, len(queued))
+	assert.Equal(big.NewInt(700), queued[addrB][0].TxInfo.Fee)
+}
+
 func TestMempoolUpdate(t *testing.T) {
 	assert := assert.New(t)
 
@@ -295,6 +611,9 @@ func TestMempoolBigBatchUpdateAndReaping(t *testing.T) {
 
 	p2psimnet := p2psim.NewSimnetWithHandler(nil)
 	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.maxPendingTxsPerAccount = 0
+	mempool.maxPendingBytesPerAccount = 0
+	mempool.freePendingTxsPerAccount = 0
 
 	committedRawTxs := []common.Bytes{}
 	multiplier := 30
@@ -354,6 +673,27 @@ func TestMempoolBigBatchUpdateAndReaping(t *testing.T) {
 	assert.Equal(numInitCandidateTxs-2*core.MaxNumRegularTxsPerBlock, numFinalCandidateTxs)
 }
 
+func TestMempoolStopWithoutNewTxs(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, ctx := newTestMempool("peer0", p2psimnet)
+	mempool.Start(ctx)
+
+	stopped := make(chan struct{})
+	go func() {
+		mempool.Stop()
+		mempool.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		assert.Fail("Mempool did not stop")
+	}
+}
+
 func TestMempoolTransactionGossip(t *testing.T) {
 	assert := assert.New(t)
 
@@ -383,14 +723,30 @@ func TestMempoolTransactionGossip(t *testing.T) {
 	assert.Equal(3, mempool.Size())
 	log.Infof(">>> Client submitted tx1, tx2, tx3")
 
-	numGossippedTxs := 2 * 3 // 2 peers, each should receive 3 transactions
-	for i := 0; i < numGossippedTxs; i++ {
+	// Only the transaction hashes are announced, possibly several in one message
+	txhashes := map[string]bool{
+		getTransactionHash(tx1): true,
+		getTransactionHash(tx2): true,
+		getTransactionHash(tx3): true,
+	}
+	numAnnouncedTxs := 2 * 3 // 2 peers, each should receive 3 transaction hashes
+	for numReceived := 0; numReceived < numAnnouncedTxs; {
 		receivedMsg := <-netMsgIntercepter.ReceivedMessages
 		senderID := receivedMsg.PeerID
-		dataResponse := receivedMsg.Content.(dp.DataResponse)
-		rawTx := string(dataResponse.Payload[:])
-		log.Infof("received transaction, sender: %v, rawTx: %v", senderID, rawTx)
-		assert.True(rawTx == "tx1" || rawTx == "tx2" || rawTx == "tx3")
+		announcement := receivedMsg.Content.(dp.InventoryResponse)
+		assert.Equal(common.ChannelIDTxGossip, announcement.ChannelID)
+		for _, txhash := range announcement.Entries {
+			log.Infof("received transaction announcement, sender: %v, txhash: %v", senderID, txhash)
+			assert.True(txhashes[txhash])
+			numReceived++
+		}
+	}
+
+	// The announced transactions are served on request
+	for txhash := range txhashes {
+		rawTx, ok := mempool.gossip.getAnnouncedTx(txhash)
+		assert.True(ok)
+		assert.Equal(txhash, getTransactionHash(rawTx))
 	}
 }
 
@@ -411,9 +767,12 @@ func newTestMempool(peerID string, simnet *p2psim.Simnet) (*Mempool, context.Con
 
 type TestLedger struct {
 	counter               int
+	round                 uint64
 	effectiveGasPriceList []uint64
 	addressList           []string
 	sequenceList          []uint64
+	txInfos               map[string]*core.TxInfo   // Overrides the lists above for specific raw txs
+	sequences             map[common.Address]uint64 // If set, the last screened sequence of each address in txInfos
 }
 
 func newTestLedger() core.Ledger {
@@ -459,20 +818,74 @@ func newTestLedger() core.Ledger {
 }
 
 func (tl *TestLedger) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	if txInfo, ok := tl.txInfos[string(rawTx)]; ok {
+		if tl.sequences == nil {
+			return txInfo, result.OK
+		}
+		expected := tl.sequences[txInfo.Address] + 1
+		if txInfo.Sequence > expected {
+			return txInfo, result.Error("Future sequence").WithErrorCode(result.CodeFutureSequence)
+		}
+		if txInfo.Sequence < expected {
+			return nil, result.Error("Invalid sequence").WithErrorCode(result.CodeInvalidSequence)
+		}
+		tl.sequences[txInfo.Address] = txInfo.Sequence
+		return txInfo, result.OK
+	}
+
+	// The fee is set such that the fee per byte equals the effective gas price. Sequences are
+	// shifted for each round through the lists so that repeated txs do not replace each other.
+	gasPrice := new(big.Int).SetUint64(tl.effectiveGasPriceList[tl.counter])
 	txInfo := &core.TxInfo{
-		EffectiveGasPrice: new(big.Int).SetUint64(tl.effectiveGasPriceList[tl.counter]),
+		EffectiveGasPrice: gasPrice,
+		Fee:               new(big.Int).Mul(gasPrice, big.NewInt(int64(len(rawTx)))),
 		Address:           common.HexToAddress(tl.addressList[tl.counter]),
-		Sequence:          tl.sequenceList[tl.counter],
+		Sequence:          tl.sequenceList[tl.counter] + tl.round*10000,
 	}
 	tl.counter = (tl.counter + 1) % len(tl.effectiveGasPriceList)
+	if tl.counter == 0 {
+		tl.round++
+	}
 	return txInfo, result.OK
 }
 
-func (tl *TestLedger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
+func (tl *TestLedger) NewScreenBatch() core.ScreenBatch {
+	return &TestScreenBatch{ledger: tl}
+}
+
+type TestScreenBatch struct {
+	ledger *TestLedger
+}
+
+func (tsb *TestScreenBatch) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	return tsb.ledger.ScreenTx(rawTx)
+}
+
+func (tsb *TestScreenBatch) ScreenReplacementTx(rawTx common.Bytes, earlierRawTxs []common.Bytes) (*core.TxInfo, result.Result) {
+	return tsb.ledger.ScreenTx(rawTx)
+}
+
+func (tsb *TestScreenBatch) Accept() {}
+
+func (tsb *TestScreenBatch) Commit() {}
+
+func (tl *TestLedger) ProposeBlockTxs(timestamp *big.Int) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
 	return common.Hash{}, []common.Bytes{}, result.OK
 }
 
-func (tl *TestLedger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoot common.Hash) result.Result {
+func (tl *TestLedger) ProposeBlockTxsFromPayload(timestamp *big.Int, regularRawTxs []common.Bytes) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
+	return common.Hash{}, regularRawTxs, result.OK
+}
+
+func (tl *TestLedger) PrepareBlockTxs() []common.Bytes {
+	return []common.Bytes{}
+}
+
+func (tl *TestLedger) ProposePreparedBlockTxs(timestamp *big.Int, regularRawTxs []common.Bytes) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
+	return common.Hash{}, regularRawTxs, result.OK
+}
+
+func (tl *TestLedger) ApplyBlockTxs(blockRawTxs []common.Bytes, timestamp *big.Int, expectedStateRoot common.Hash) result.Result {
 	return result.OK
 }
 
@@ -488,6 +901,10 @@ func (tl *TestLedger) GetFinalizedValidatorCandidatePool(blockHash common.Hash,
 	return nil, nil
 }
 
+func (tl *TestLedger) GetGuardianCandidatePool(blockHash common.Hash) (*core.GuardianCandidatePool, error) {
+	return nil, nil
+}
+
 type TestNetworkMessageInterceptor struct {
 	lock             *sync.Mutex
 	ReceivedMessages chan p2ptypes.Message
@@ -503,6 +920,7 @@ func newTestNetworkMessageInterceptor() *TestNetworkMessageInterceptor {
 func (tnmi *TestNetworkMessageInterceptor) GetChannelIDs() []common.ChannelIDEnum {
 	return []common.ChannelIDEnum{
 		common.ChannelIDTransaction,
+		common.ChannelIDTxGossip,
 	}
 }
 
@@ -0,0 +1,180 @@
+package mempool
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	dp "github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/rlp"
+)
+
+const (
+	defaultMaxNumAnnouncedTxs = uint(10000) // Max number of announced transactions kept to serve the requests
+	maxNumPendingTxRequests   = 10000       // Max number of transaction requests waiting for the response
+	txRequestTimeout          = 10 * time.Second
+)
+
+// gossipMessageIDEnum identifies the type of the messages sent over the ChannelIDTxGossip channel
+type gossipMessageIDEnum uint8
+
+const (
+	gossipMessageIDAnnounce gossipMessageIDEnum = iota // dp.InventoryResponse with the hashes of new transactions
+	gossipMessageIDRequest                             // dp.DataRequest with the hashes of the wanted transactions
+	gossipMessageIDTx                                  // dp.DataResponse with a raw transaction
+)
+
+func encodeGossipMessage(message interface{}) (common.Bytes, error) {
+	var buf bytes.Buffer
+	var msgID gossipMessageIDEnum
+	switch message.(type) {
+	case dp.InventoryResponse:
+		msgID = gossipMessageIDAnnounce
+	case dp.DataRequest:
+		msgID = gossipMessageIDRequest
+	case dp.DataResponse:
+		msgID = gossipMessageIDTx
+	default:
+		return nil, errors.New("Unsupported transaction gossip message type")
+	}
+	err := rlp.Encode(&buf, msgID)
+	if err != nil {
+		return nil, err
+	}
+	err = rlp.Encode(&buf, message)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeGossipMessage(raw common.Bytes) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("Empty transaction gossip message")
+	}
+	var msgID gossipMessageIDEnum
+	err := rlp.DecodeBytes(raw[:1], &msgID)
+	if err != nil {
+		return nil, err
+	}
+	switch msgID {
+	case gossipMessageIDAnnounce:
+		data := dp.InventoryResponse{}
+		err = rlp.DecodeBytes(raw[1:], &data)
+		return data, err
+	case gossipMessageIDRequest:
+		data := dp.DataRequest{}
+		err = rlp.DecodeBytes(raw[1:], &data)
+		return data, err
+	case gossipMessageIDTx:
+		data := dp.DataResponse{}
+		err = rlp.DecodeBytes(raw[1:], &data)
+		return data, err
+	default:
+		return nil, errors.New("Unknown transaction gossip message type")
+	}
+}
+
+//
+// txGossip keeps the state of the transaction gossip protocol. New transactions are
+// announced by their hashes, and the peers request the ones they have not seen yet.
+//
+type txGossip struct {
+	mutex *sync.Mutex
+
+	announcedTxs  map[string]common.Bytes // map: transaction hash -> raw transaction
+	announcedList list.List               // FIFO list of announced transaction hashes
+	maxNumTxs     uint
+
+	requestedTxs map[string]time.Time // map: transaction hash -> time of the request
+}
+
+func createTxGossip(maxNumTxs uint) *txGossip {
+	return &txGossip{
+		mutex:        &sync.Mutex{},
+		announcedTxs: make(map[string]common.Bytes),
+		maxNumTxs:    maxNumTxs,
+		requestedTxs: make(map[string]time.Time),
+	}
+}
+
+// announce keeps the transaction to serve the requests of the peers, and returns its hash
+func (tg *txGossip) announce(rawTx common.Bytes) string {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	txhash := getTransactionHash(rawTx)
+	if _, exists := tg.announcedTxs[txhash]; exists {
+		return txhash
+	}
+
+	if uint(tg.announcedList.Len()) >= tg.maxNumTxs { // remove the oldest transactions
+		popped := tg.announcedList.Front()
+		delete(tg.announcedTxs, popped.Value.(string))
+		tg.announcedList.Remove(popped)
+	}
+
+	tg.announcedTxs[txhash] = rawTx
+	tg.announcedList.PushBack(txhash)
+
+	return txhash
+}
+
+// getAnnouncedTx returns the announced transaction with the given hash, if it is still kept
+func (tg *txGossip) getAnnouncedTx(txhash string) (common.Bytes, bool) {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	rawTx, exists := tg.announcedTxs[txhash]
+	return rawTx, exists
+}
+
+// markRequested returns the hashes that are not being requested from another peer, and
+// marks them as requested. A request without response expires after txRequestTimeout, so
+// that the transaction can be requested from the next peer announcing it.
+func (tg *txGossip) markRequested(txhashes []string) []string {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	now := time.Now()
+	if len(tg.requestedTxs) >= maxNumPendingTxRequests {
+		for txhash, requestedAt := range tg.requestedTxs {
+			if now.Sub(requestedAt) > txRequestTimeout {
+				delete(tg.requestedTxs, txhash)
+			}
+		}
+	}
+
+	toRequest := []string{}
+	for _, txhash := range txhashes {
+		if requestedAt, exists := tg.requestedTxs[txhash]; exists && now.Sub(requestedAt) <= txRequestTimeout {
+			continue
+		}
+		if len(tg.requestedTxs) >= maxNumPendingTxRequests {
+			break
+		}
+		tg.requestedTxs[txhash] = now
+		toRequest = append(toRequest, txhash)
+	}
+	return toRequest
+}
+
+// markReceived clears the pending request of the transaction
+func (tg *txGossip) markReceived(rawTx common.Bytes) {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	delete(tg.requestedTxs, getTransactionHash(rawTx))
+}
+
+func (tg *txGossip) reset() {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	tg.announcedTxs = make(map[string]common.Bytes)
+	tg.announcedList.Init()
+	tg.requestedTxs = make(map[string]time.Time)
+}
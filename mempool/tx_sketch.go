@@ -0,0 +1,178 @@
+package mempool
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+)
+
+const (
+	txSketchNumHashes     = 3
+	defaultTxSketchCells  = 3 * 64   // Number of cells of the sketches sent, decodes up to about 100 differences
+	maxTxSketchCells      = 3 * 1024 // Max number of cells of the sketches received
+	maxTxSketchDifference = 2 * maxTxSketchCells
+)
+
+//
+// TxSketchCell is a cell of a TxSketch. The key sum and the check sum XOR the hashes of the
+// transactions mapped to the cell, and of their salted digests.
+//
+type TxSketchCell struct {
+	Count    uint32
+	KeySum   common.Hash
+	CheckSum uint64
+}
+
+//
+// TxSketch is an invertible Bloom lookup table of the hashes of the pending transactions of a
+// node. A node subtracts its own sketch from the sketch of a peer, and decodes the difference to
+// find the transactions only one of them has, with a message size proportional to the size of
+// the difference rather than to the size of the mempools.
+//
+type TxSketch struct {
+	Salt  uint64 // randomizes the cells of the transactions, so that they cannot be crafted to collide
+	Cells []TxSketchCell
+}
+
+// newTxSketch creates an empty sketch. The number of cells is rounded up to a multiple of the
+// number of hashes, since each hash maps the transactions to its own range of cells.
+func newTxSketch(numCells int, salt uint64) *TxSketch {
+	if rem := numCells % txSketchNumHashes; rem != 0 {
+		numCells += txSketchNumHashes - rem
+	}
+	return &TxSketch{
+		Salt:  salt,
+		Cells: make([]TxSketchCell, numCells),
+	}
+}
+
+// createTxSketch creates the sketch of the hashes of the raw transactions
+func createTxSketch(rawTxs []common.Bytes, numCells int, salt uint64) *TxSketch {
+	sketch := newTxSketch(numCells, salt)
+	for _, rawTx := range rawTxs {
+		sketch.insert(crypto.Keccak256Hash(rawTx))
+	}
+	return sketch
+}
+
+// validate checks the size of a sketch received from a peer
+func (ts *TxSketch) validate() error {
+	numCells := len(ts.Cells)
+	if numCells == 0 || numCells%txSketchNumHashes != 0 {
+		return errors.New("Invalid number of transaction sketch cells")
+	}
+	if numCells > maxTxSketchCells {
+		return errors.New("Too many transaction sketch cells")
+	}
+	return nil
+}
+
+// insert adds the transaction hash to the sketch
+func (ts *TxSketch) insert(txhash common.Hash) {
+	indexes, checkSum := ts.locate(txhash)
+	for _, idx := range indexes {
+		cell := &ts.Cells[idx]
+		cell.Count++
+		cell.KeySum = xorHash(cell.KeySum, txhash)
+		cell.CheckSum ^= checkSum
+	}
+}
+
+// locate returns the cells of the transaction hash, one in the range of each hash, and its check sum
+func (ts *TxSketch) locate(txhash common.Hash) ([txSketchNumHashes]int, uint64) {
+	var salt [8]byte
+	binary.BigEndian.PutUint64(salt[:], ts.Salt)
+	digest := crypto.Keccak256(salt[:], txhash[:])
+
+	var indexes [txSketchNumHashes]int
+	rangeSize := uint64(len(ts.Cells) / txSketchNumHashes)
+	for i := 0; i < txSketchNumHashes; i++ {
+		indexes[i] = i*int(rangeSize) + int(binary.BigEndian.Uint64(digest[8*i:8*i+8])%rangeSize)
+	}
+	return indexes, binary.BigEndian.Uint64(digest[24:32])
+}
+
+// subtract returns the cell-wise difference between the sketch and a sketch of the same salt and
+// size. The counts of the difference are signed.
+func (ts *TxSketch) subtract(other *TxSketch) ([]txSketchDiffCell, error) {
+	if ts.Salt != other.Salt || len(ts.Cells) != len(other.Cells) {
+		return nil, errors.New("Mismatching transaction sketches")
+	}
+	diff := make([]txSketchDiffCell, len(ts.Cells))
+	for i := range ts.Cells {
+		diff[i] = txSketchDiffCell{
+			count:    int64(ts.Cells[i].Count) - int64(other.Cells[i].Count),
+			keySum:   xorHash(ts.Cells[i].KeySum, other.Cells[i].KeySum),
+			checkSum: ts.Cells[i].CheckSum ^ other.Cells[i].CheckSum,
+		}
+	}
+	return diff, nil
+}
+
+type txSketchDiffCell struct {
+	count    int64
+	keySum   common.Hash
+	checkSum uint64
+}
+
+// decodeDiff peels the difference of the sketches of two nodes A and B. It returns the
+// hashes of the transactions only A has, and of those only B has. It returns false if the
+// difference is too large for the size of the sketches to be fully decoded.
+func (ts *TxSketch) decodeDiff(diff []txSketchDiffCell) (onlyA []string, onlyB []string, ok bool) {
+	isPure := func(cell *txSketchDiffCell) bool {
+		if cell.count != 1 && cell.count != -1 {
+			return false
+		}
+		_, checkSum := ts.locate(cell.keySum)
+		return checkSum == cell.checkSum
+	}
+
+	queue := []int{}
+	for i := range diff {
+		if isPure(&diff[i]) {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 && len(onlyA)+len(onlyB) < maxTxSketchDifference {
+		idx := queue[0]
+		queue = queue[1:]
+		if !isPure(&diff[idx]) {
+			continue // already peeled through another cell
+		}
+
+		txhash, count := diff[idx].keySum, diff[idx].count
+		if count > 0 {
+			onlyA = append(onlyA, hex.EncodeToString(txhash[:]))
+		} else {
+			onlyB = append(onlyB, hex.EncodeToString(txhash[:]))
+		}
+
+		indexes, checkSum := ts.locate(txhash)
+		for _, i := range indexes {
+			cell := &diff[i]
+			cell.count -= count
+			cell.keySum = xorHash(cell.keySum, txhash)
+			cell.checkSum ^= checkSum
+			if isPure(cell) {
+				queue = append(queue, i)
+			}
+		}
+	}
+
+	for i := range diff {
+		if diff[i].count != 0 || diff[i].checkSum != 0 || diff[i].keySum != (common.Hash{}) {
+			return onlyA, onlyB, false
+		}
+	}
+	return onlyA, onlyB, true
+}
+
+func xorHash(a, b common.Hash) common.Hash {
+	for i := range a {
+		a[i] ^= b[i]
+	}
+	return a
+}
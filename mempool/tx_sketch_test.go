@@ -0,0 +1,82 @@
+package mempool
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+)
+
+func createTestRawTxs(prefix string, numTxs int) []common.Bytes {
+	rawTxs := []common.Bytes{}
+	for i := 0; i < numTxs; i++ {
+		rawTxs = append(rawTxs, createTestRawTx(prefix+strconv.Itoa(i)))
+	}
+	return rawTxs
+}
+
+func getTransactionHashes(rawTxs []common.Bytes) []string {
+	txhashes := []string{}
+	for _, rawTx := range rawTxs {
+		txhashes = append(txhashes, getTransactionHash(rawTx))
+	}
+	return txhashes
+}
+
+func TestTxSketchDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	commonTxs := createTestRawTxs("common", 1000)
+	onlyA := createTestRawTxs("a", 20)
+	onlyB := createTestRawTxs("b", 30)
+
+	salt := uint64(12345)
+	sketchA := createTxSketch(append(append([]common.Bytes{}, commonTxs...), onlyA...), defaultTxSketchCells, salt)
+	sketchB := createTxSketch(append(append([]common.Bytes{}, commonTxs...), onlyB...), defaultTxSketchCells, salt)
+	assert.Nil(sketchA.validate())
+
+	diff, err := sketchA.subtract(sketchB)
+	assert.Nil(err)
+	decodedA, decodedB, ok := sketchA.decodeDiff(diff)
+	assert.True(ok)
+	assert.ElementsMatch(getTransactionHashes(onlyA), decodedA)
+	assert.ElementsMatch(getTransactionHashes(onlyB), decodedB)
+
+	// Identical sketches have an empty difference
+	diff, err = sketchA.subtract(createTxSketch(append(append([]common.Bytes{}, onlyA...), commonTxs...), defaultTxSketchCells, salt))
+	assert.Nil(err)
+	decodedA, decodedB, ok = sketchA.decodeDiff(diff)
+	assert.True(ok)
+	assert.Empty(decodedA)
+	assert.Empty(decodedB)
+
+	// Sketches of different salts or sizes cannot be compared
+	_, err = sketchA.subtract(createTxSketch(commonTxs, defaultTxSketchCells, salt+1))
+	assert.NotNil(err)
+	_, err = sketchA.subtract(createTxSketch(commonTxs, defaultTxSketchCells+3, salt))
+	assert.NotNil(err)
+}
+
+func TestTxSketchDecodeFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	// The difference is too large for the size of the sketches
+	salt := uint64(12345)
+	sketchA := createTxSketch(createTestRawTxs("a", 300), defaultTxSketchCells, salt)
+	sketchB := createTxSketch(createTestRawTxs("b", 300), defaultTxSketchCells, salt)
+	diff, err := sketchA.subtract(sketchB)
+	assert.Nil(err)
+	_, _, ok := sketchA.decodeDiff(diff)
+	assert.False(ok)
+}
+
+func TestTxSketchValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(3, len(newTxSketch(1, 0).Cells))
+	assert.NotNil((&TxSketch{}).validate())
+	assert.NotNil((&TxSketch{Cells: make([]TxSketchCell, 4)}).validate())
+	assert.NotNil(newTxSketch(maxTxSketchCells+3, 0).validate())
+	assert.Nil(newTxSketch(maxTxSketchCells, 0).validate())
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/dispatcher"
 	"github.com/thetatoken/theta/rlp"
 )
@@ -46,6 +47,9 @@ func encodeMessage(message interface{}) (common.Bytes, error) {
 }
 
 func decodeMessage(raw common.Bytes) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("Empty message")
+	}
 	var msgID MessageIDEnum
 	err := rlp.DecodeBytes(raw[:1], &msgID)
 	if err != nil {
@@ -71,3 +75,10 @@ func decodeMessage(raw common.Bytes) (interface{}, error) {
 		return nil, fmt.Errorf("Unknown message ID: %v", msgID)
 	}
 }
+
+// BlockBodyResponse is the payload of the DataResponse over ChannelIDBlockBody. The receiver
+// matches the body with the header of the block using core.AssembleBlock.
+type BlockBodyResponse struct {
+	Hash common.Hash
+	Body core.BlockBody
+}
@@ -26,3 +26,18 @@ func TestMessageEncoding(t *testing.T) {
 	assert.Equal(1, len(dataReq2.Entries))
 	assert.Equal("A0", dataReq2.Entries[0])
 }
+
+func TestMalformedMessageDecoding(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, raw := range []common.Bytes{
+		nil,
+		{},
+		{byte(MessageIDDataResponse)},
+		{0x7f, 0xc0},
+		{byte(MessageIDDataRequest), 0xbb, 0xff, 0xff, 0xff, 0xff},
+	} {
+		_, err := decodeMessage(raw)
+		assert.NotNil(err, "raw: %v", raw)
+	}
+}
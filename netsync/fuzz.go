@@ -0,0 +1,40 @@
+// +build gofuzz
+
+package netsync
+
+import (
+	"bytes"
+)
+
+// FuzzMessage is the entry point for the go-fuzz tool on the decoding of the sync messages
+// received from the peers.
+//
+//	go-fuzz-build -func FuzzMessage github.com/thetatoken/theta/netsync
+//	go-fuzz -bin netsync-fuzz.zip -workdir fuzz/netsync
+//
+// It returns 1 for the inputs which decode, and 0 otherwise. The decoded messages are encoded
+// again, and must decode to a message with the same encoding. The payloads of the data responses
+// are fuzzed by the targets of the core package.
+func FuzzMessage(data []byte) int {
+	message, err := decodeMessage(data)
+	if err != nil {
+		return 0
+	}
+
+	encoded, err := encodeMessage(message)
+	if err != nil {
+		panic(err)
+	}
+	decoded, err := decodeMessage(encoded)
+	if err != nil {
+		panic(err)
+	}
+	reencoded, err := encodeMessage(decoded)
+	if err != nil {
+		panic(err)
+	}
+	if !bytes.Equal(encoded, reencoded) {
+		panic("Message changed after re-encoding")
+	}
+	return 1
+}
@@ -0,0 +1,120 @@
+package netsync
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+type orphanBlock struct {
+	block   *core.Block
+	addedAt time.Time
+	el      *list.Element
+}
+
+//
+// OrphanPool holds the blocks received before their parents, until the parents are added to the
+// chain. The pool is bounded both by the number of blocks and by their age: the oldest blocks are
+// evicted to make room for the new ones, and the blocks whose parents never arrive expire.
+//
+type OrphanPool struct {
+	maxSize int
+	maxAge  time.Duration
+
+	blocks   map[common.Hash]*orphanBlock
+	byParent map[common.Hash][]common.Hash
+	order    *list.List // The hashes of the blocks, from the oldest to the newest
+
+	now func() time.Time
+}
+
+// NewOrphanPool creates an orphan pool holding up to maxSize blocks for up to maxAge.
+func NewOrphanPool(maxSize int, maxAge time.Duration) *OrphanPool {
+	return &OrphanPool{
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		blocks:   make(map[common.Hash]*orphanBlock),
+		byParent: make(map[common.Hash][]common.Hash),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Len returns the number of blocks in the pool.
+func (op *OrphanPool) Len() int {
+	return len(op.blocks)
+}
+
+// Has returns true if the block is in the pool.
+func (op *OrphanPool) Has(hash common.Hash) bool {
+	_, ok := op.blocks[hash]
+	return ok
+}
+
+// Add adds the block to the pool, and returns the blocks evicted to make room for it.
+func (op *OrphanPool) Add(block *core.Block) []*core.Block {
+	hash := block.Hash()
+	if op.Has(hash) || op.maxSize <= 0 {
+		return nil
+	}
+
+	evicted := []*core.Block{}
+	for op.order.Len() >= op.maxSize {
+		evicted = append(evicted, op.remove(op.order.Front().Value.(common.Hash)))
+	}
+
+	op.blocks[hash] = &orphanBlock{
+		block:   block,
+		addedAt: op.now(),
+		el:      op.order.PushBack(hash),
+	}
+	op.byParent[block.Parent] = append(op.byParent[block.Parent], hash)
+	return evicted
+}
+
+// TakeChildren removes the children of the parent from the pool and returns them.
+func (op *OrphanPool) TakeChildren(parent common.Hash) []*core.Block {
+	hashes := op.byParent[parent]
+	children := make([]*core.Block, 0, len(hashes))
+	for _, hash := range hashes {
+		children = append(children, op.remove(hash))
+	}
+	return children
+}
+
+// Prune removes the blocks held for longer than the max age, and returns them.
+func (op *OrphanPool) Prune() []*core.Block {
+	expired := []*core.Block{}
+	now := op.now()
+	for op.order.Len() > 0 {
+		hash := op.order.Front().Value.(common.Hash)
+		if now.Sub(op.blocks[hash].addedAt) <= op.maxAge {
+			break
+		}
+		expired = append(expired, op.remove(hash))
+	}
+	return expired
+}
+
+func (op *OrphanPool) remove(hash common.Hash) *core.Block {
+	orphan := op.blocks[hash]
+	delete(op.blocks, hash)
+	op.order.Remove(orphan.el)
+
+	parent := orphan.block.Parent
+	siblings := op.byParent[parent]
+	for i, sibling := range siblings {
+		if sibling == hash {
+			siblings = append(siblings[:i], siblings[i+1:]...)
+			break
+		}
+	}
+	if len(siblings) == 0 {
+		delete(op.byParent, parent)
+	} else {
+		op.byParent[parent] = siblings
+	}
+	return orphan.block
+}
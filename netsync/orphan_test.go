@@ -0,0 +1,73 @@
+// +build unit
+
+package netsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thetatoken/theta/core"
+)
+
+func TestOrphanPool(t *testing.T) {
+	assert := assert.New(t)
+
+	f0 := core.CreateTestBlock("f0", "")
+	f1 := core.CreateTestBlock("f1", "f0")
+	f2 := core.CreateTestBlock("f2", "f1")
+	f3 := core.CreateTestBlock("f3", "f0")
+
+	pool := NewOrphanPool(2, time.Minute)
+	assert.Equal(0, len(pool.Add(f1)))
+	assert.Equal(0, len(pool.Add(f3)))
+	assert.Equal(0, len(pool.Add(f3))) // Already in the pool
+	assert.Equal(2, pool.Len())
+
+	// The oldest block is evicted to make room for the new one
+	evicted := pool.Add(f2)
+	assert.Equal(1, len(evicted))
+	assert.Equal(f1.Hash(), evicted[0].Hash())
+	assert.False(pool.Has(f1.Hash()))
+	assert.True(pool.Has(f2.Hash()))
+	assert.True(pool.Has(f3.Hash()))
+
+	children := pool.TakeChildren(f0.Hash())
+	assert.Equal(1, len(children))
+	assert.Equal(f3.Hash(), children[0].Hash())
+	assert.Equal(0, len(pool.TakeChildren(f0.Hash())))
+
+	children = pool.TakeChildren(f1.Hash())
+	assert.Equal(1, len(children))
+	assert.Equal(f2.Hash(), children[0].Hash())
+	assert.Equal(0, pool.Len())
+}
+
+func TestOrphanPoolExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	f0 := core.CreateTestBlock("f0", "")
+	f1 := core.CreateTestBlock("f1", "f0")
+	f3 := core.CreateTestBlock("f3", "f0")
+
+	now := time.Now()
+	pool := NewOrphanPool(16, time.Minute)
+	pool.now = func() time.Time { return now }
+
+	pool.Add(f1)
+	now = now.Add(30 * time.Second)
+	pool.Add(f3)
+	assert.Equal(0, len(pool.Prune()))
+
+	now = now.Add(45 * time.Second)
+	expired := pool.Prune()
+	assert.Equal(1, len(expired))
+	assert.Equal(f1.Hash(), expired[0].Hash())
+	assert.Equal(1, pool.Len())
+
+	// The remaining sibling is still processed when the parent arrives
+	children := pool.TakeChildren(f0.Hash())
+	assert.Equal(1, len(children))
+	assert.Equal(f3.Hash(), children[0].Hash())
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/blockchain"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/tracing"
 	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/dispatcher"
@@ -34,6 +35,12 @@ type PendingBlock struct {
 	peers      []string
 	lastUpdate time.Time
 	status     RequestState
+	timeout    time.Duration
+
+	// The block is requested directly from the peers which referenced it, until the retries
+	// are exhausted
+	direct   bool
+	attempts int
 }
 
 func NewPendingBlock(x common.Hash, peerIds []string) *PendingBlock {
@@ -42,11 +49,12 @@ func NewPendingBlock(x common.Hash, peerIds []string) *PendingBlock {
 		lastUpdate: time.Now(),
 		peers:      peerIds,
 		status:     RequestToSendDataReq,
+		timeout:    RequestTimeout,
 	}
 }
 
 func (pb *PendingBlock) HasTimedOut() bool {
-	return time.Since(pb.lastUpdate) > RequestTimeout
+	return time.Since(pb.lastUpdate) > pb.timeout
 }
 
 func (pb *PendingBlock) UpdateTimestamp() {
@@ -59,6 +67,11 @@ type RequestManager struct {
 	ticker *time.Ticker
 	quota  int
 
+	blockRequestTimeout time.Duration
+	blockRequestRetries int
+
+	mu *sync.Mutex
+
 	wg      *sync.WaitGroup
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -70,9 +83,9 @@ type RequestManager struct {
 
 	lastInventoryRequest time.Time
 
-	pendingBlocks         *list.List
-	pendingBlocksByHash   map[string]*list.Element
-	pendingBlocksByParent map[string][]*core.Block
+	pendingBlocks       *list.List
+	pendingBlocksByHash map[string]*list.Element
+	orphans             *OrphanPool
 
 	endHashCache      []common.Bytes
 	blockRequestCache []common.Bytes
@@ -83,6 +96,10 @@ func NewRequestManager(syncMgr *SyncManager) *RequestManager {
 		ticker: time.NewTicker(1 * time.Second),
 		quota:  RequestQuotaPerSecond,
 
+		blockRequestTimeout: time.Duration(viper.GetInt(common.CfgSyncBlockRequestTimeout)) * time.Millisecond,
+		blockRequestRetries: viper.GetInt(common.CfgSyncBlockRequestRetries),
+
+		mu: &sync.Mutex{},
 		wg: &sync.WaitGroup{},
 
 		lastInventoryRequest: time.Now(),
@@ -91,9 +108,10 @@ func NewRequestManager(syncMgr *SyncManager) *RequestManager {
 		chain:      syncMgr.chain,
 		dispatcher: syncMgr.dispatcher,
 
-		pendingBlocks:         list.New(),
-		pendingBlocksByHash:   make(map[string]*list.Element),
-		pendingBlocksByParent: make(map[string][]*core.Block),
+		pendingBlocks:       list.New(),
+		pendingBlocksByHash: make(map[string]*list.Element),
+		orphans: NewOrphanPool(viper.GetInt(common.CfgSyncMaxOrphanBlocks),
+			time.Duration(viper.GetInt(common.CfgSyncOrphanBlockMaxAge))*time.Second),
 	}
 
 	logger := util.GetLoggerForModule("request")
@@ -158,7 +176,7 @@ func (rm *RequestManager) buildInventoryRequest() dispatcher.InventoryRequest {
 			break
 		}
 
-		blocks := rm.syncMgr.chain.FindBlocksByHeight(index)
+		blocks := rm.syncMgr.chain.FindBlockHeadersByHeight(index)
 		for _, b := range blocks {
 			starts = append(starts, b.Hash().Hex())
 		}
@@ -174,25 +192,21 @@ func (rm *RequestManager) buildInventoryRequest() dispatcher.InventoryRequest {
 }
 
 func (rm *RequestManager) tryToDownload() {
-	hasUndownloadedBlocks := rm.pendingBlocks.Len() > 0 || len(rm.pendingBlocksByHash) > 0 || len(rm.pendingBlocksByParent) > 0
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.dropOrphans(rm.orphans.Prune(), "expired")
+
+	hasUndownloadedBlocks := rm.pendingBlocks.Len() > 0 || len(rm.pendingBlocksByHash) > 0 || rm.orphans.Len() > 0
 	inventoryRequestIntervalPassed := time.Since(rm.lastInventoryRequest) >= MinInventoryRequestInterval
 	if hasUndownloadedBlocks && inventoryRequestIntervalPassed {
 		rm.logger.WithFields(log.Fields{
 			"pendingBlocks":     rm.pendingBlocks.Len(),
-			"orphan blocks":     len(rm.pendingBlocksByParent),
+			"orphan blocks":     rm.orphans.Len(),
 			"current chain tip": rm.syncMgr.consensus.GetTip(true).Hash().Hex(),
 		}).Info("Fast sync in progress")
 
-		rm.lastInventoryRequest = time.Now()
-		req := rm.buildInventoryRequest()
-
-		rm.logger.WithFields(log.Fields{
-			"channelID": req.ChannelID,
-			"starts":    req.Starts,
-			"end":       req.End,
-		}).Debug("Sending inventory request")
-
-		rm.syncMgr.dispatcher.GetInventory([]string{}, req)
+		rm.sendInventoryRequest()
 	}
 
 	for curr := rm.pendingBlocks.Front(); rm.quota != 0 && curr != nil; curr = curr.Next() {
@@ -205,29 +219,119 @@ func (rm *RequestManager) tryToDownload() {
 		}
 		if pendingBlock.status == RequestToSendDataReq ||
 			(pendingBlock.status == RequestWaitingDataResp && pendingBlock.HasTimedOut()) {
-			randomPeerID := pendingBlock.peers[rand.Intn(len(pendingBlock.peers))]
-			request := dispatcher.DataRequest{
-				ChannelID: common.ChannelIDBlock,
-				Entries:   []string{pendingBlock.hash.String()},
+			if pendingBlock.direct {
+				if pendingBlock.attempts <= rm.blockRequestRetries {
+					// Retry with the next peer which referenced the block
+					peerID := pendingBlock.peers[pendingBlock.attempts%len(pendingBlock.peers)]
+					rm.sendDataRequest(pendingBlock, peerID)
+					continue
+				}
+				rm.logger.WithFields(log.Fields{
+					"block":    pendingBlock.hash.Hex(),
+					"attempts": pendingBlock.attempts,
+				}).Debug("Direct block request exhausted, falling back to periodic sync")
+				pendingBlock.direct = false
+				pendingBlock.timeout = RequestTimeout
 			}
-			rm.logger.WithFields(log.Fields{
-				"channelID":       request.ChannelID,
-				"request.Entries": request.Entries,
-				"peer":            randomPeerID,
-			}).Debug("Sending data request")
-			rm.syncMgr.dispatcher.GetData([]string{randomPeerID}, request)
-			pendingBlock.UpdateTimestamp()
-			pendingBlock.status = RequestWaitingDataResp
+			randomPeerID := pendingBlock.peers[rand.Intn(len(pendingBlock.peers))]
+			rm.sendDataRequest(pendingBlock, randomPeerID)
 			rm.quota--
 			continue
 		}
 	}
 }
 
-func (rm *RequestManager) AddHash(x common.Hash, peerIDs []string) {
-	if _, err := rm.chain.FindBlock(x); err == nil {
+// RequestInventory requests the inventory from the peers right away, instead of waiting for
+// blocks to be announced, e.g. when the node stops making progress.
+func (rm *RequestManager) RequestInventory() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.sendInventoryRequest()
+}
+
+// sendInventoryRequest requests the blocks after the local chain tip from all the peers. Caller
+// must hold mu.
+func (rm *RequestManager) sendInventoryRequest() {
+	rm.lastInventoryRequest = time.Now()
+	req := rm.buildInventoryRequest()
+
+	rm.logger.WithFields(log.Fields{
+		"channelID": req.ChannelID,
+		"starts":    req.Starts,
+		"end":       req.End,
+	}).Debug("Sending inventory request")
+
+	rm.syncMgr.dispatcher.GetInventory([]string{}, req)
+}
+
+// sendDataRequest requests the pending block from the peer. Caller must hold mu.
+func (rm *RequestManager) sendDataRequest(pendingBlock *PendingBlock, peerID string) {
+	request := dispatcher.DataRequest{
+		ChannelID: common.ChannelIDBlock,
+		Entries:   []string{pendingBlock.hash.String()},
+	}
+	rm.logger.WithFields(log.Fields{
+		"channelID":       request.ChannelID,
+		"request.Entries": request.Entries,
+		"peer":            peerID,
+		"direct":          pendingBlock.direct,
+	}).Debug("Sending data request")
+	rm.syncMgr.dispatcher.GetData([]string{peerID}, request)
+	pendingBlock.UpdateTimestamp()
+	pendingBlock.status = RequestWaitingDataResp
+	if pendingBlock.direct {
+		pendingBlock.attempts++
+	}
+}
+
+// RequestBlock requests a block missing from the local chain directly from the peer which
+// referenced it, e.g. in a vote or a HCC, instead of waiting for the next inventory round. The
+// request is retried with the other peers which referenced the block upon timeout, and the
+// block is left to the periodic sync once the retries are exhausted.
+func (rm *RequestManager) RequestBlock(x common.Hash, peerID string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if x.IsEmpty() || rm.hasBlock(x) {
+		return
+	}
+	pendingBlock := rm.addHash(x, []string{peerID})
+	if pendingBlock == nil || pendingBlock.block != nil {
 		return
 	}
+	if pendingBlock.direct || (pendingBlock.status == RequestWaitingDataResp && !pendingBlock.HasTimedOut()) {
+		// Already requested, the peer is tried if the request times out
+		return
+	}
+	pendingBlock.direct = true
+	pendingBlock.attempts = 0
+	pendingBlock.timeout = rm.blockRequestTimeout
+	rm.sendDataRequest(pendingBlock, peerID)
+}
+
+// hasBlock returns true if the block is in the local chain or waits for its parent. Caller must
+// hold mu.
+func (rm *RequestManager) hasBlock(x common.Hash) bool {
+	if _, err := rm.chain.FindBlockHeader(x); err == nil {
+		return true
+	}
+	return rm.orphans.Has(x)
+}
+
+func (rm *RequestManager) AddHash(x common.Hash, peerIDs []string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.addHash(x, peerIDs)
+}
+
+// addHash adds the block to the pending blocks, and the peers to the peers which have the block.
+// Caller must hold mu.
+func (rm *RequestManager) addHash(x common.Hash, peerIDs []string) *PendingBlock {
+	if _, err := rm.chain.FindBlockHeader(x); err == nil {
+		return nil
+	}
 
 	var pendingBlockEl *list.Element
 	var pendingBlock *PendingBlock
@@ -240,7 +344,7 @@ func (rm *RequestManager) AddHash(x common.Hash, peerIDs []string) {
 	// Add peerIDs to pendingBlock.peers
 	pendingBlock = pendingBlockEl.Value.(*PendingBlock)
 	if pendingBlock.block != nil {
-		return
+		return pendingBlock
 	}
 	for _, xid := range peerIDs {
 		found := false
@@ -254,33 +358,41 @@ func (rm *RequestManager) AddHash(x common.Hash, peerIDs []string) {
 			pendingBlock.peers = append(pendingBlock.peers, xid)
 		}
 	}
+	return pendingBlock
 }
 
 func (rm *RequestManager) AddBlock(block *core.Block) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
 	if pendingBlockEl, ok := rm.pendingBlocksByHash[block.Hash().String()]; ok {
 		pendingBlock := pendingBlockEl.Value.(*PendingBlock)
 		pendingBlock.block = block
 	}
 	parent := block.Parent
-	if _, err := rm.chain.FindBlock(parent); err == nil {
+	if _, err := rm.chain.FindBlockHeader(parent); err == nil {
 		rm.dumpReadyBlocks(block)
 		return
 	}
-	byParents, ok := rm.pendingBlocksByParent[parent.String()]
-	if !ok {
-		byParents = []*core.Block{}
-	}
-	found := false
-	for _, child := range byParents {
-		if child.Hash() == block.Hash() {
-			found = true
-			break
+	rm.dropOrphans(rm.orphans.Add(block), "evicted")
+}
+
+// dropOrphans forgets the orphan blocks dropped from the pool, so that they are downloaded again
+// by the periodic sync if they are still needed. Caller must hold mu.
+func (rm *RequestManager) dropOrphans(blocks []*core.Block, reason string) {
+	for _, block := range blocks {
+		hash := block.Hash().String()
+		rm.logger.WithFields(log.Fields{
+			"block":  hash,
+			"parent": block.Parent.Hex(),
+			"reason": reason,
+		}).Debug("Dropping orphan block")
+
+		if pendingBlockEl, ok := rm.pendingBlocksByHash[hash]; ok {
+			rm.pendingBlocks.Remove(pendingBlockEl)
+			delete(rm.pendingBlocksByHash, hash)
 		}
 	}
-	if !found {
-		byParents = append(byParents, block)
-	}
-	rm.pendingBlocksByParent[parent.String()] = byParents
 }
 
 func (rm *RequestManager) dumpReadyBlocks(block *core.Block) {
@@ -290,10 +402,7 @@ func (rm *RequestManager) dumpReadyBlocks(block *core.Block) {
 		hash := block.Hash().String()
 		queue = queue[1:]
 
-		if children, ok := rm.pendingBlocksByParent[hash]; ok {
-			queue = append(queue, children...)
-			delete(rm.pendingBlocksByParent, hash)
-		}
+		queue = append(queue, rm.orphans.TakeChildren(block.Hash())...)
 
 		if pendingBlockEl, ok := rm.pendingBlocksByHash[hash]; ok {
 			rm.pendingBlocks.Remove(pendingBlockEl)
@@ -304,6 +413,7 @@ func (rm *RequestManager) dumpReadyBlocks(block *core.Block) {
 		if err != nil {
 			rm.logger.Panic(err)
 		}
+		tracing.BlockStage(block.Hash(), "consensus.queue")
 		rm.syncMgr.PassdownMessage(block)
 	}
 }
@@ -3,20 +3,23 @@ package netsync
 import (
 	"context"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/blockchain"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/tracing"
 	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/dispatcher"
 	"github.com/thetatoken/theta/p2p"
 	p2ptypes "github.com/thetatoken/theta/p2p/types"
 	"github.com/thetatoken/theta/rlp"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "netsync"})
+var logger *log.Entry = util.GetLoggerForModule("sync")
 
 type MessageConsumer interface {
 	AddMessage(interface{})
@@ -107,6 +110,8 @@ func (sm *SyncManager) GetChannelIDs() []common.ChannelIDEnum {
 		common.ChannelIDProposal,
 		common.ChannelIDCC,
 		common.ChannelIDVote,
+		common.ChannelIDGuardian,
+		common.ChannelIDBlockBody,
 	}
 }
 
@@ -142,7 +147,7 @@ func (sm *SyncManager) processMessage(message p2ptypes.Message) {
 	case dispatcher.DataRequest:
 		sm.handleDataRequest(message.PeerID, &content)
 	case dispatcher.DataResponse:
-		sm.handleDataResponse(message.PeerID, &content)
+		sm.handleDataResponse(message.PeerID, message.ReceivedAt, &content)
 	default:
 		sm.logger.WithFields(log.Fields{
 			"message": message,
@@ -150,6 +155,20 @@ func (sm *SyncManager) processMessage(message p2ptypes.Message) {
 	}
 }
 
+// ResumeFrom hints the sync manager about a block known before restart, e.g. from a
+// checkpoint. The block is queued for download if it is missing from the local chain.
+func (sm *SyncManager) ResumeFrom(hash common.Hash) {
+	if hash.IsEmpty() {
+		return
+	}
+	sm.requestMgr.AddHash(hash, []string{})
+}
+
+// Resync requests the blocks missing from the local chain from the peers right away.
+func (sm *SyncManager) Resync() {
+	sm.requestMgr.RequestInventory()
+}
+
 // PassdownMessage passes message through to the consumer.
 func (sm *SyncManager) PassdownMessage(msg interface{}) {
 	sm.consumer.AddMessage(msg)
@@ -160,7 +179,7 @@ func (m *SyncManager) locateStart(starts []string) common.Hash {
 	var start common.Hash
 	for i := 0; i < len(starts); i++ {
 		curr := common.HexToHash(starts[i])
-		if _, err := m.chain.FindBlock(curr); err == nil {
+		if _, err := m.chain.FindBlockHeader(curr); err == nil {
 			start = curr
 			break
 		}
@@ -177,7 +196,7 @@ func (m *SyncManager) collectBlocks(start common.Hash, end common.Hash) []string
 	for len(q) > 0 && len(ret) < dispatcher.MaxInventorySize {
 		curr := q[0]
 		q = q[1:]
-		block, err := m.chain.FindBlock(curr)
+		block, err := m.chain.FindBlockHeader(curr)
 		if err != nil {
 			m.logger.WithFields(log.Fields{
 				"hash": curr.Hex(),
@@ -192,7 +211,7 @@ func (m *SyncManager) collectBlocks(start common.Hash, end common.Hash) []string
 		if block.Height < lfbHeight {
 			// Enqueue finalized child.
 			for _, child := range block.Children {
-				block, err := m.chain.FindBlock(child)
+				block, err := m.chain.FindBlockHeader(child)
 				if err != nil {
 					m.logger.WithFields(log.Fields{
 						"err":  err,
@@ -272,6 +291,13 @@ func (m *SyncManager) handleDataRequest(peerID string, data *dispatcher.DataRequ
 		for _, hashStr := range data.Entries {
 			hash := common.HexToHash(hashStr)
 			block, err := m.chain.FindBlock(hash)
+			if err == blockchain.ErrBlockBodyPruned {
+				m.logger.WithFields(log.Fields{
+					"channelID": data.ChannelID,
+					"hashStr":   hashStr,
+				}).Debug("Requested block body has been pruned")
+				continue
+			}
 			if err != nil {
 				m.logger.WithFields(log.Fields{
 					"channelID": data.ChannelID,
@@ -298,6 +324,10 @@ func (m *SyncManager) handleDataRequest(peerID string, data *dispatcher.DataRequ
 			}).Debug("Sending requested block")
 			m.dispatcher.SendData([]string{peerID}, data)
 		}
+	case common.ChannelIDHeader:
+		m.sendHeaders(peerID, data.Entries)
+	case common.ChannelIDBlockBody:
+		m.sendBlockBodies(peerID, data.Entries)
 	default:
 		m.logger.WithFields(log.Fields{
 			"channelID": data.ChannelID,
@@ -305,7 +335,64 @@ func (m *SyncManager) handleDataRequest(peerID string, data *dispatcher.DataRequ
 	}
 }
 
-func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataResponse) {
+// sendHeaders sends the headers of the requested blocks known locally in one response, in the
+// order of the request.
+func (m *SyncManager) sendHeaders(peerID string, hashStrs []string) {
+	if len(hashStrs) > dispatcher.MaxInventorySize {
+		hashStrs = hashStrs[:dispatcher.MaxInventorySize]
+	}
+	headers := []*core.BlockHeader{}
+	for _, hashStr := range hashStrs {
+		block, err := m.chain.FindBlockHeader(common.HexToHash(hashStr))
+		if err != nil {
+			continue
+		}
+		headers = append(headers, block.BlockHeader)
+	}
+	if len(headers) == 0 {
+		return
+	}
+
+	payload, err := rlp.EncodeToBytes(headers)
+	if err != nil {
+		m.logger.WithFields(log.Fields{"err": err}).Error("Failed to encode block headers")
+		return
+	}
+	m.logger.WithFields(log.Fields{
+		"peer":         peerID,
+		"len(headers)": len(headers),
+	}).Debug("Sending requested block headers")
+	m.dispatcher.SendData([]string{peerID}, dispatcher.DataResponse{
+		ChannelID: common.ChannelIDHeader,
+		Payload:   payload,
+	})
+}
+
+// sendBlockBodies sends the bodies of the requested blocks which have not been pruned, one
+// response per block.
+func (m *SyncManager) sendBlockBodies(peerID string, hashStrs []string) {
+	if len(hashStrs) > dispatcher.MaxInventorySize {
+		hashStrs = hashStrs[:dispatcher.MaxInventorySize]
+	}
+	for _, hashStr := range hashStrs {
+		hash := common.HexToHash(hashStr)
+		block, err := m.chain.FindBlock(hash)
+		if err != nil {
+			continue // Unknown block or pruned body
+		}
+		payload, err := rlp.EncodeToBytes(BlockBodyResponse{Hash: hash, Body: *block.Body()})
+		if err != nil {
+			m.logger.WithFields(log.Fields{"hashStr": hashStr, "err": err}).Error("Failed to encode block body")
+			return
+		}
+		m.dispatcher.SendData([]string{peerID}, dispatcher.DataResponse{
+			ChannelID: common.ChannelIDBlockBody,
+			Payload:   payload,
+		})
+	}
+}
+
+func (m *SyncManager) handleDataResponse(peerID string, receivedAt time.Time, data *dispatcher.DataResponse) {
 	switch data.ChannelID {
 	case common.ChannelIDBlock:
 		block := core.NewBlock()
@@ -318,7 +405,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 			}).Error("Failed to decode DataResponse payload")
 			return
 		}
-		m.handleBlock(block)
+		m.handleBlock(peerID, receivedAt, block)
 	case common.ChannelIDVote:
 		vote := core.Vote{}
 		err := rlp.DecodeBytes(data.Payload, &vote)
@@ -331,6 +418,8 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 			return
 		}
 		m.handleVote(vote)
+		// Request the voted block from the sender if it is missing
+		m.requestMgr.RequestBlock(vote.Block, peerID)
 	case common.ChannelIDProposal:
 		proposal := &core.Proposal{}
 		err := rlp.DecodeBytes(data.Payload, proposal)
@@ -342,7 +431,25 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 			}).Error("Failed to decode DataResponse payload")
 			return
 		}
-		m.handleProposal(proposal)
+		m.handleProposal(peerID, receivedAt, proposal)
+	case common.ChannelIDGuardian:
+		votes := &core.AggregatedVotes{}
+		err := rlp.DecodeBytes(data.Payload, votes)
+		if err != nil {
+			m.logger.WithFields(log.Fields{
+				"channelID": data.ChannelID,
+				"payload":   data.Payload,
+				"error":     err,
+			}).Error("Failed to decode DataResponse payload")
+			return
+		}
+		m.handleAggregatedVotes(votes)
+	case common.ChannelIDHeader, common.ChannelIDBlockBody:
+		// The headers and the bodies are only served, to the light clients and the peers syncing
+		// header first. The node itself requests the full blocks, so these are unsolicited
+		m.logger.WithFields(log.Fields{
+			"channelID": data.ChannelID,
+		}).Debug("Ignoring unrequested block parts")
 	default:
 		m.logger.WithFields(log.Fields{
 			"channelID": data.ChannelID,
@@ -350,7 +457,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 	}
 }
 
-func (sm *SyncManager) handleProposal(p *core.Proposal) {
+func (sm *SyncManager) handleProposal(peerID string, receivedAt time.Time, p *core.Proposal) {
 	sm.logger.WithFields(log.Fields{
 		"proposal": p,
 	}).Debug("Received proposal")
@@ -360,27 +467,52 @@ func (sm *SyncManager) handleProposal(p *core.Proposal) {
 			sm.handleVote(vote)
 		}
 	}
-	sm.handleBlock(p.Block)
+	sm.handleBlock(peerID, receivedAt, p.Block)
+
+	// Request the voted blocks from the proposer if they are missing
+	if p.Votes != nil {
+		for _, vote := range p.Votes.Votes() {
+			sm.requestMgr.RequestBlock(vote.Block, peerID)
+		}
+	}
 }
 
-func (sm *SyncManager) handleBlock(block *core.Block) {
+func (sm *SyncManager) handleBlock(peerID string, receivedAt time.Time, block *core.Block) {
+	hash := block.Hash()
 	sm.logger.WithFields(log.Fields{
-		"block.Hash":   block.Hash().Hex(),
+		"block.Hash":   hash.Hex(),
 		"block.Parent": block.Parent.Hex(),
 	}).Debug("Received block")
 
-	if _, err := sm.chain.FindBlock(block.Hash()); err == nil {
+	if _, err := sm.chain.FindBlockHeader(hash); err == nil {
 		return
 	}
 
+	// The block waits in the request manager until its ancestors are received
+	tracing.StartBlockTrace(hash, receivedAt, "p2p.receive",
+		attribute.String("peer", peerID), attribute.Int64("block.height", int64(block.Height)))
+	tracing.BlockStage(hash, "netsync.pending")
 	sm.requestMgr.AddBlock(block)
 
+	// Request the block certified by the HCC from the sender if it is missing
+	sm.requestMgr.RequestBlock(block.HCC.BlockHash, peerID)
+
 	sm.dispatcher.SendInventory([]string{}, dispatcher.InventoryResponse{
 		ChannelID: common.ChannelIDBlock,
 		Entries:   []string{block.Hash().Hex()},
 	})
 }
 
+func (sm *SyncManager) handleAggregatedVotes(votes *core.AggregatedVotes) {
+	sm.logger.WithFields(log.Fields{
+		"votes.Block": votes.Block.Hex(),
+		"votes.Gcp":   votes.Gcp.Hex(),
+	}).Debug("Received aggregated votes")
+
+	// The guardian engine validates and merges the votes, and gossips the merged votes itself
+	sm.PassdownMessage(votes)
+}
+
 func (sm *SyncManager) handleVote(vote core.Vote) {
 	sm.logger.WithFields(log.Fields{
 		"vote.Hash":  vote.Block.Hex(),
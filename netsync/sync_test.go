@@ -7,7 +7,6 @@ import (
 
 	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
-	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/ledger"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store/database/backend"
@@ -16,6 +15,7 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/dispatcher"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/thetatoken/theta/blockchain"
 	"github.com/thetatoken/theta/p2p/simulation"
@@ -61,6 +61,10 @@ func TestSyncManager(t *testing.T) {
 	assert := assert.New(t)
 	core.ResetTestBlocks()
 
+	// No retries of the direct block requests during the test
+	viper.Set(common.CfgSyncBlockRequestTimeout, 60000)
+	defer viper.Set(common.CfgSyncBlockRequestTimeout, 2000)
+
 	// node1's chain initially contains only A0, A1
 	initChain := blockchain.CreateTestChainByBlocks([]string{
 		"A1", "A0",
@@ -100,13 +104,23 @@ func TestSyncManager(t *testing.T) {
 		},
 	})
 
-	// node1 should broadcast InventoryResponse
+	// node1 should broadcast InventoryResponse, and request A3 certified by the HCC of A4 from node2
 	var res interface{}
-	res = <-mockMsgHandler.C
-	msg1, ok := res.(dispatcher.InventoryResponse)
-	assert.True(ok)
+	var msg1 dispatcher.InventoryResponse
+	var req dispatcher.DataRequest
+	for i := 0; i < 2; i++ {
+		res = <-mockMsgHandler.C
+		switch msg := res.(type) {
+		case dispatcher.InventoryResponse:
+			msg1 = msg
+		case dispatcher.DataRequest:
+			req = msg
+		}
+	}
 	assert.Equal(common.ChannelIDBlock, msg1.ChannelID)
-	assert.Equal(core.GetTestBlock("A4").Hash().Hex(), msg1.Entries[0])
+	assert.Equal([]string{core.GetTestBlock("A4").Hash().Hex()}, msg1.Entries)
+	assert.Equal(common.ChannelIDBlock, req.ChannelID)
+	assert.Equal([]string{core.GetTestBlock("A3").Hash().String()}, req.Entries)
 
 	res = <-mockMsgHandler.C
 	msg2, ok := res.(dispatcher.InventoryRequest)
@@ -162,6 +176,81 @@ func TestSyncManager(t *testing.T) {
 	}
 }
 
+func waitForDataRequest(t *testing.T, c chan interface{}) dispatcher.DataRequest {
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case res := <-c:
+			if req, ok := res.(dispatcher.DataRequest); ok {
+				return req
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for the data request")
+		}
+	}
+}
+
+func TestRequestBlockFromVoteSender(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	viper.Set(common.CfgSyncBlockRequestTimeout, 100)
+	defer viper.Set(common.CfgSyncBlockRequestTimeout, 2000)
+
+	// node1's chain contains A0, A1, and misses A2 voted by node2
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+	a2 := core.CreateTestBlock("A2", "A1")
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	net2 := simnet.AddEndpoint("node2")
+	mockMsgHandler := &MockMsgHandler{C: make(chan interface{}, 128)}
+	net2.RegisterMessageHandler(mockMsgHandler)
+	simnet.Start(context.Background())
+
+	valMgr := consensus.NewFixedValidatorManager()
+	db := kvstore.NewKVStore(backend.NewMemDatabase())
+	dispatch := dispatcher.NewDispatcher(net1)
+	consensus := consensus.NewConsensusEngine(nil, db, initChain, dispatch, valMgr)
+	mockMsgConsumer := NewMockMessageConsumer()
+
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, mockMsgConsumer)
+	sm.Start(context.Background())
+
+	payload, _ := rlp.EncodeToBytes(core.Vote{Block: a2.Hash(), Height: a2.Height, Epoch: a2.Epoch})
+	net2.Broadcast(types.Message{
+		ChannelID: common.ChannelIDVote,
+		Content: dispatcher.DataResponse{
+			ChannelID: common.ChannelIDVote,
+			Payload:   payload,
+		},
+	})
+
+	// node1 requests A2 from node2 right away, and again once the request times out
+	for i := 0; i < 2; i++ {
+		req := waitForDataRequest(t, mockMsgHandler.C)
+		assert.Equal(common.ChannelIDBlock, req.ChannelID)
+		assert.Equal([]string{a2.Hash().String()}, req.Entries)
+	}
+
+	payload, _ = rlp.EncodeToBytes(a2)
+	net2.Broadcast(types.Message{
+		ChannelID: common.ChannelIDBlock,
+		Content: dispatcher.DataResponse{
+			ChannelID: common.ChannelIDBlock,
+			Payload:   payload,
+		},
+	})
+
+	time.Sleep(500 * time.Millisecond)
+
+	// Sync manager should output the vote, then A2
+	assert.Equal(2, len(mockMsgConsumer.Received))
+	assert.Equal(a2.Hash(), mockMsgConsumer.Received[1].(*core.Block).Hash())
+}
+
 type MockConsensus struct {
 	chain *blockchain.Chain
 	lfb   *core.ExtendedBlock
@@ -175,7 +264,7 @@ func NewMockConsensus(chain *blockchain.Chain, lfb *core.ExtendedBlock) *MockCon
 }
 
 // ID() string
-// PrivateKey() *crypto.PrivateKey
+// Signer() Signer
 // GetTip(includePendingBlockingLeaf bool) *ExtendedBlock
 // GetEpoch() uint64
 // GetLedger() Ledger
@@ -187,7 +276,7 @@ func (c *MockConsensus) ID() string {
 	return ""
 }
 
-func (c *MockConsensus) PrivateKey() *crypto.PrivateKey {
+func (c *MockConsensus) Signer() core.Signer {
 	return nil
 }
 
@@ -252,3 +341,77 @@ func TestCollectBlocks(t *testing.T) {
 	assert.Equal(core.GetTestBlock("D4").Hash().Hex(), blocks[4])
 	assert.Equal(core.GetTestBlock("A5").Hash().Hex(), blocks[5])
 }
+
+func waitForDataResponse(t *testing.T, c chan interface{}) dispatcher.DataResponse {
+	select {
+	case res := <-c:
+		return res.(dispatcher.DataResponse)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for the data response")
+	}
+	return dispatcher.DataResponse{}
+}
+
+func TestServeBlockParts(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+		"A2", "A1",
+	})
+	a1 := core.GetTestBlock("A1")
+	a2 := core.GetTestBlock("A2")
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	net2 := simnet.AddEndpoint("node2")
+	mockMsgHandler := &MockMsgHandler{C: make(chan interface{}, 128)}
+	net2.RegisterMessageHandler(mockMsgHandler)
+	simnet.Start(context.Background())
+
+	dispatch := dispatcher.NewDispatcher(net1)
+	lfb, _ := initChain.FindBlock(a1.Hash())
+	sm := NewSyncManager(initChain, NewMockConsensus(initChain, lfb), net1, dispatch, NewMockMessageConsumer())
+
+	// The headers of the known blocks are sent in one response
+	sm.handleDataRequest("node2", &dispatcher.DataRequest{
+		ChannelID: common.ChannelIDHeader,
+		Entries:   []string{a1.Hash().Hex(), common.HexToHash("0xff").Hex(), a2.Hash().Hex()},
+	})
+	resp := waitForDataResponse(t, mockMsgHandler.C)
+	assert.Equal(common.ChannelIDHeader, resp.ChannelID)
+	headers := []*core.BlockHeader{}
+	assert.Nil(rlp.DecodeBytes(resp.Payload, &headers))
+	assert.Equal(2, len(headers))
+	assert.Equal(a1.Hash(), headers[0].Hash())
+	assert.Equal(a2.Hash(), headers[1].Hash())
+
+	// The bodies are sent with the hash of their block
+	sm.handleDataRequest("node2", &dispatcher.DataRequest{
+		ChannelID: common.ChannelIDBlockBody,
+		Entries:   []string{a1.Hash().Hex()},
+	})
+	resp = waitForDataResponse(t, mockMsgHandler.C)
+	assert.Equal(common.ChannelIDBlockBody, resp.ChannelID)
+	body := BlockBodyResponse{}
+	assert.Nil(rlp.DecodeBytes(resp.Payload, &body))
+	assert.Equal(a1.Hash(), body.Hash)
+
+	// Neither the body nor the full block is served once the body is pruned
+	initChain.FinalizePreviousBlocks(a1.Hash())
+	assert.Nil(initChain.PruneBlockBody(a1.Hash()))
+	sm.handleDataRequest("node2", &dispatcher.DataRequest{
+		ChannelID: common.ChannelIDBlockBody,
+		Entries:   []string{a1.Hash().Hex()},
+	})
+	sm.handleDataRequest("node2", &dispatcher.DataRequest{
+		ChannelID: common.ChannelIDBlock,
+		Entries:   []string{a1.Hash().Hex(), a2.Hash().Hex()},
+	})
+	resp = waitForDataResponse(t, mockMsgHandler.C)
+	assert.Equal(common.ChannelIDBlock, resp.ChannelID)
+	block := core.NewBlock()
+	assert.Nil(rlp.DecodeBytes(resp.Payload, block))
+	assert.Equal(a2.Hash(), block.Hash())
+}
@@ -0,0 +1,257 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database"
+)
+
+// GenesisAccount is an account funded at genesis
+type GenesisAccount struct {
+	Address  common.Address  `json:"address"`
+	ThetaWei *common.JSONBig `json:"theta_wei"`
+	TFuelWei *common.JSONBig `json:"tfuel_wei"`
+}
+
+// GenesisStake is a stake deposited at genesis. The stake is deducted from the Theta balance of
+// the source account, and the holders of the stakes form the genesis validator candidate pool.
+type GenesisStake struct {
+	Source common.Address  `json:"source"`
+	Holder common.Address  `json:"holder"`
+	Amount *common.JSONBig `json:"amount"`
+}
+
+//
+// GenesisSpec is the canonical description of the genesis of a chain. The genesis block and its
+// state are fully determined by the spec: the accounts and the stakes are applied in a canonical
+// order regardless of their order in the spec, and the block timestamp is taken from the spec,
+// so every node generating the genesis from the same spec gets the same genesis block hash.
+//
+type GenesisSpec struct {
+	ChainID     string             `json:"chain_id"`
+	Timestamp   common.JSONUint64  `json:"timestamp"` // Unix time of the genesis block
+	Accounts    []GenesisAccount   `json:"accounts"`
+	Stakes      []GenesisStake     `json:"stakes"`
+	ChainParams *types.ChainParams `json:"chain_params,omitempty"` // Defaults to types.DefaultChainParams()
+}
+
+// LoadGenesisSpec reads the genesis spec from the JSON file.
+func LoadGenesisSpec(filePath string) (*GenesisSpec, error) {
+	raw, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGenesisSpec(raw)
+}
+
+// ParseGenesisSpec decodes and validates the JSON genesis spec. Unknown fields are rejected, so
+// that a misspelled field does not silently change the genesis.
+func ParseGenesisSpec(raw []byte) (*GenesisSpec, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	spec := &GenesisSpec{}
+	if err := decoder.Decode(spec); err != nil {
+		return nil, fmt.Errorf("Failed to parse the genesis spec: %v", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Save writes the spec to the file in its canonical form.
+func (spec *GenesisSpec) Save(filePath string) error {
+	raw, err := json.MarshalIndent(spec.canonical(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, raw, 0600)
+}
+
+// Validate checks the spec describes a valid genesis, without generating it.
+func (spec *GenesisSpec) Validate() error {
+	if spec.ChainID == "" {
+		return errors.New("Chain ID must be specified")
+	}
+
+	balances := make(map[common.Address]*big.Int)
+	for _, acc := range spec.Accounts {
+		if _, ok := balances[acc.Address]; ok {
+			return fmt.Errorf("Duplicated genesis account %v", acc.Address.Hex())
+		}
+		if isNegative(acc.ThetaWei) || isNegative(acc.TFuelWei) {
+			return fmt.Errorf("Negative balance for genesis account %v", acc.Address.Hex())
+		}
+		balances[acc.Address] = new(big.Int).Set(toInt(acc.ThetaWei))
+	}
+
+	if len(spec.Stakes) == 0 {
+		return errors.New("At least one stake is required to form the genesis validator set")
+	}
+	staked := make(map[[2]common.Address]bool)
+	for _, stake := range spec.Stakes {
+		key := [2]common.Address{stake.Source, stake.Holder}
+		if staked[key] {
+			return fmt.Errorf("Duplicated stake from %v to %v", stake.Source.Hex(), stake.Holder.Hex())
+		}
+		staked[key] = true
+
+		amount := toInt(stake.Amount)
+		if amount.Cmp(core.MinValidatorStakeDeposit) < 0 {
+			return fmt.Errorf("Stake from %v is below the minimum validator stake: %v", stake.Source.Hex(), amount)
+		}
+		balance, ok := balances[stake.Source]
+		if !ok {
+			return fmt.Errorf("Stake source %v is not a genesis account", stake.Source.Hex())
+		}
+		if balance.Cmp(amount) < 0 {
+			return fmt.Errorf("Genesis account %v does not have sufficient Theta for its stakes", stake.Source.Hex())
+		}
+		balance.Sub(balance, amount)
+	}
+
+	if spec.ChainParams != nil {
+		if err := spec.ChainParams.Validate(); err != nil {
+			return fmt.Errorf("Invalid chain params: %v", err)
+		}
+	}
+	return nil
+}
+
+// canonical returns a copy of the spec with the accounts sorted by address, and the stakes sorted
+// by holder then source.
+func (spec *GenesisSpec) canonical() *GenesisSpec {
+	c := *spec
+	c.Accounts = append([]GenesisAccount{}, spec.Accounts...)
+	sort.Slice(c.Accounts, func(i, j int) bool {
+		return bytes.Compare(c.Accounts[i].Address[:], c.Accounts[j].Address[:]) < 0
+	})
+	c.Stakes = append([]GenesisStake{}, spec.Stakes...)
+	sort.Slice(c.Stakes, func(i, j int) bool {
+		if cmp := bytes.Compare(c.Stakes[i].Holder[:], c.Stakes[j].Holder[:]); cmp != 0 {
+			return cmp < 0
+		}
+		return bytes.Compare(c.Stakes[i].Source[:], c.Stakes[j].Source[:]) < 0
+	})
+	return &c
+}
+
+// GenerateGenesis builds the genesis state from the spec, saves it to the database, and returns it
+// along with the genesis block committing to it. The genesis block can be used as the root block
+// of the chain.
+func GenerateGenesis(spec *GenesisSpec, db database.Database) (*state.StoreView, *core.Block, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, nil, err
+	}
+	spec = spec.canonical()
+	genesisHeight := core.GenesisBlockHeight
+
+	sv := state.NewStoreView(genesisHeight, common.Hash{}, db)
+	for _, acc := range spec.Accounts {
+		sv.SetAccount(acc.Address, &types.Account{
+			Address:  acc.Address,
+			Root:     common.Hash{},
+			CodeHash: types.EmptyCodeHash,
+			Balance: types.Coins{
+				ThetaWei: new(big.Int).Set(toInt(acc.ThetaWei)),
+				TFuelWei: new(big.Int).Set(toInt(acc.TFuelWei)),
+			},
+		})
+	}
+
+	vcp := &core.ValidatorCandidatePool{}
+	for _, stake := range spec.Stakes {
+		amount := new(big.Int).Set(toInt(stake.Amount))
+		if err := vcp.DepositStake(stake.Source, stake.Holder, amount); err != nil {
+			return nil, nil, fmt.Errorf("Failed to deposit stake from %v: %v", stake.Source.Hex(), err)
+		}
+		source := sv.GetAccount(stake.Source)
+		source.Balance = source.Balance.Minus(types.Coins{
+			ThetaWei: amount,
+			TFuelWei: big.NewInt(0),
+		})
+		sv.SetAccount(stake.Source, source)
+	}
+	sv.UpdateValidatorCandidatePool(vcp)
+
+	hl := &types.HeightList{}
+	hl.Append(genesisHeight)
+	sv.UpdateStakeTransactionHeightList(hl)
+
+	if spec.ChainParams != nil {
+		sv.SetChainParams(spec.ChainParams)
+	}
+
+	stateHash := sv.Save()
+
+	genesisBlock := core.NewBlock()
+	genesisBlock.ChainID = spec.ChainID
+	genesisBlock.Height = genesisHeight
+	genesisBlock.Epoch = genesisBlock.Height
+	genesisBlock.Parent = common.Hash{}
+	genesisBlock.StateHash = stateHash
+	genesisBlock.Timestamp = new(big.Int).SetUint64(uint64(spec.Timestamp))
+
+	return sv, genesisBlock, nil
+}
+
+// WriteGenesisSnapshot writes the genesis state and block as a snapshot, from which the nodes of
+// the chain can start.
+func WriteGenesisSnapshot(sv *state.StoreView, genesisBlock *core.Block, filePath string) error {
+	metadata := &core.SnapshotMetadata{
+		TailTrio: core.SnapshotBlockTrio{
+			First:  core.SnapshotFirstBlock{},
+			Second: core.SnapshotSecondBlock{Header: *genesisBlock.BlockHeader},
+			Third:  core.SnapshotThirdBlock{},
+		},
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	if err := core.WriteMetadata(writer, metadata); err != nil {
+		return err
+	}
+
+	height := core.Itobytes(sv.Height())
+	if err := core.WriteRecord(writer, []byte{core.SVStart}, height); err != nil {
+		return err
+	}
+	sv.GetStore().Traverse(nil, func(k, v common.Bytes) bool {
+		err = core.WriteRecord(writer, k, v)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := core.WriteRecord(writer, []byte{core.SVEnd}, height); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func toInt(b *common.JSONBig) *big.Int {
+	if b == nil {
+		return big.NewInt(0)
+	}
+	return b.ToInt()
+}
+
+func isNegative(b *common.JSONBig) bool {
+	return toInt(b).Sign() < 0
+}
@@ -0,0 +1,144 @@
+package node
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+func newTestGenesisSpec() *GenesisSpec {
+	stake := (*common.JSONBig)(core.MinValidatorStakeDeposit)
+	balance := (*common.JSONBig)(new(big.Int).Mul(core.MinValidatorStakeDeposit, big.NewInt(3)))
+	alice := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	bob := common.HexToAddress("0x1F0c4c4A3bA8a8b9C0A0D5EB73F5C8b2d2Ab4c1d")
+	return &GenesisSpec{
+		ChainID:   "testchain",
+		Timestamp: common.JSONUint64(1552000000),
+		Accounts: []GenesisAccount{
+			{Address: alice, ThetaWei: balance, TFuelWei: balance},
+			{Address: bob, ThetaWei: balance, TFuelWei: balance},
+		},
+		Stakes: []GenesisStake{
+			{Source: alice, Holder: alice, Amount: stake},
+			{Source: bob, Holder: alice, Amount: stake},
+			{Source: bob, Holder: bob, Amount: stake},
+		},
+	}
+}
+
+func TestGenerateGenesisDeterministic(t *testing.T) {
+	assert := assert.New(t)
+
+	spec := newTestGenesisSpec()
+	sv, block, err := GenerateGenesis(spec, backend.NewMemDatabase())
+	assert.Nil(err)
+	assert.Equal("testchain", block.ChainID)
+	assert.Equal(core.GenesisBlockHeight, block.Height)
+	assert.Equal(uint64(1552000000), block.Timestamp.Uint64())
+	assert.Equal(sv.Hash(), block.StateHash)
+
+	// The order of the accounts and the stakes in the spec does not matter
+	reordered := newTestGenesisSpec()
+	reordered.Accounts[0], reordered.Accounts[1] = reordered.Accounts[1], reordered.Accounts[0]
+	reordered.Stakes[0], reordered.Stakes[2] = reordered.Stakes[2], reordered.Stakes[0]
+	_, block2, err := GenerateGenesis(reordered, backend.NewMemDatabase())
+	assert.Nil(err)
+	assert.Equal(block.Hash(), block2.Hash())
+
+	// The genesis state is saved to the database
+	gsv := state.NewStoreView(block.Height, block.StateHash, sv.GetDB())
+	alice := gsv.GetAccount(spec.Accounts[0].Address)
+	assert.Equal(0, alice.Balance.ThetaWei.Cmp(new(big.Int).Mul(core.MinValidatorStakeDeposit, big.NewInt(2))))
+	bob := gsv.GetAccount(spec.Accounts[1].Address)
+	assert.Equal(0, bob.Balance.ThetaWei.Cmp(core.MinValidatorStakeDeposit))
+	vcp := gsv.GetValidatorCandidatePool()
+	assert.Equal(2, len(vcp.SortedCandidates))
+	assert.Equal(spec.Accounts[0].Address, vcp.SortedCandidates[0].Holder)
+	assert.Equal(types.DefaultChainParams(), gsv.GetChainParams())
+
+	// The chain params are part of the genesis state
+	spec.ChainParams = types.DefaultChainParams()
+	spec.ChainParams.MaxBlockGas = 1000000
+	_, block3, err := GenerateGenesis(spec, backend.NewMemDatabase())
+	assert.Nil(err)
+	assert.NotEqual(block.Hash(), block3.Hash())
+}
+
+func TestGenesisSpecValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	spec := newTestGenesisSpec()
+	spec.ChainID = ""
+	assert.NotNil(spec.Validate())
+
+	spec = newTestGenesisSpec()
+	spec.Accounts = append(spec.Accounts, spec.Accounts[0])
+	assert.NotNil(spec.Validate())
+
+	spec = newTestGenesisSpec()
+	spec.Stakes = nil
+	assert.NotNil(spec.Validate())
+
+	spec = newTestGenesisSpec()
+	spec.Stakes = append(spec.Stakes, spec.Stakes[0])
+	assert.NotNil(spec.Validate())
+
+	// The stakes of the source exceed its Theta balance
+	spec = newTestGenesisSpec()
+	spec.Stakes = append(spec.Stakes, GenesisStake{
+		Source: spec.Accounts[1].Address,
+		Holder: common.HexToAddress("0x3F0c4c4A3bA8a8b9C0A0D5EB73F5C8b2d2Ab4c1d"),
+		Amount: (*common.JSONBig)(new(big.Int).Mul(core.MinValidatorStakeDeposit, big.NewInt(2))),
+	})
+	assert.NotNil(spec.Validate())
+
+	spec = newTestGenesisSpec()
+	spec.Stakes[0].Amount = (*common.JSONBig)(big.NewInt(1))
+	assert.NotNil(spec.Validate())
+
+	spec = newTestGenesisSpec()
+	spec.Stakes[0].Source = common.HexToAddress("0x3F0c4c4A3bA8a8b9C0A0D5EB73F5C8b2d2Ab4c1d")
+	assert.NotNil(spec.Validate())
+
+	spec = newTestGenesisSpec()
+	spec.ChainParams = types.DefaultChainParams()
+	spec.ChainParams.MaxBlockGas = 0
+	assert.NotNil(spec.Validate())
+
+	_, _, err := GenerateGenesis(spec, backend.NewMemDatabase())
+	assert.NotNil(err)
+}
+
+func TestGenesisSpecFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "genesis")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	spec := newTestGenesisSpec()
+	specPath := filepath.Join(dir, "genesis.json")
+	assert.Nil(spec.Save(specPath))
+	loaded, err := LoadGenesisSpec(specPath)
+	assert.Nil(err)
+
+	_, block, err := GenerateGenesis(spec, backend.NewMemDatabase())
+	assert.Nil(err)
+	sv, loadedBlock, err := GenerateGenesis(loaded, backend.NewMemDatabase())
+	assert.Nil(err)
+	assert.Equal(block.Hash(), loadedBlock.Hash())
+	assert.Nil(WriteGenesisSnapshot(sv, loadedBlock, filepath.Join(dir, "genesis")))
+
+	_, err = ParseGenesisSpec([]byte(`{"chain_id": "testchain", "unknown_field": "1"}`))
+	assert.NotNil(err)
+}
@@ -4,23 +4,35 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/checkpoint"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
 	dp "github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/event"
 	ld "github.com/thetatoken/theta/ledger"
 	mp "github.com/thetatoken/theta/mempool"
 	"github.com/thetatoken/theta/netsync"
+	"github.com/thetatoken/theta/notify"
 	"github.com/thetatoken/theta/p2p"
+	"github.com/thetatoken/theta/p2p/chainmux"
 	"github.com/thetatoken/theta/rpc"
+	sgn "github.com/thetatoken/theta/signer"
 	"github.com/thetatoken/theta/snapshot"
+	"github.com/thetatoken/theta/statesync"
+	"github.com/thetatoken/theta/stats"
 	"github.com/thetatoken/theta/store"
+	"github.com/thetatoken/theta/store/archive"
 	"github.com/thetatoken/theta/store/database"
 	"github.com/thetatoken/theta/store/kvstore"
+	"github.com/thetatoken/theta/store/maintenance"
+	"github.com/thetatoken/theta/store/trie"
 )
 
 type Node struct {
@@ -29,10 +41,29 @@ type Node struct {
 	Consensus        *consensus.ConsensusEngine
 	ValidatorManager core.ValidatorManager
 	SyncManager      *netsync.SyncManager
+	StateSyncManager *statesync.StateSyncManager
 	Dispatcher       *dp.Dispatcher
 	Ledger           core.Ledger
 	Mempool          *mp.Mempool
 	RPC              *rpc.ThetaRPCServer
+	Admin            *rpc.ThetaAdminServer
+	Checkpointer     *checkpoint.Checkpointer
+	Maintainer       *maintenance.Maintainer
+	Stats            *stats.Collector
+	Watchdog         *Watchdog
+	Notifier         *notify.Notifier
+	Monitor          *notify.Monitor
+	EventBus         *event.Bus
+
+	// Subchains are the nodes of the additional chains hosted by the node, in the order of the
+	// params. They share the network of the node, and are started and stopped along with it.
+	Subchains []*Node
+
+	network  p2p.Network
+	ledger   *ld.Ledger
+	db       database.Database
+	archive  *archive.Archive
+	reloadMu *sync.Mutex // Guards the config reload and the components it starts or stops
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -40,23 +71,83 @@ type Node struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
 	stopped bool
+
+	// Context of the sub components. It is not derived from ctx, since the sub components are
+	// stopped one stage after the other on shutdown rather than all at once.
+	runCtx    context.Context
+	runCancel context.CancelFunc
 }
 
 type Params struct {
 	ChainID      string
 	PrivateKey   *crypto.PrivateKey
+	Signer       core.Signer // Signs on behalf of the validator, defaults to PrivateKey if nil
 	Root         *core.Block
 	Network      p2p.Network
 	DB           database.Database
+	BlockArchive *archive.Archive // Archive of the finalized block bodies, kept in DB if nil
+	SnapshotPath string
+	DataPath     string // Directory whose free disk space is monitored, not monitored if empty
+
+	// Subchains are the additional chains hosted by the node, e.g. a subchain along with the
+	// mainnet. Each of them has its own store, ledger and consensus engine, and shares the
+	// network of the main chain, their messages being namespaced by their chain ID.
+	Subchains []*SubchainParams
+}
+
+// SubchainParams are the parameters of an additional chain hosted by the node. The subchain
+// signs with the keys of the main chain. It serves neither the RPC nor the admin API, and is
+// not watched by the watchdog nor the notifications of the node.
+type SubchainParams struct {
+	ChainID      string
+	Root         *core.Block
+	DB           database.Database
 	SnapshotPath string
 }
 
 func NewNode(params *Params) *Node {
-	store := kvstore.NewKVStore(params.DB)
+	if len(params.Subchains) == 0 {
+		return newNode(params, false)
+	}
+
+	mux := chainmux.NewMux(params.Network, params.ChainID)
+	node := newNode(params, false)
+	for _, subchain := range params.Subchains {
+		if subchain.ChainID == params.ChainID {
+			panic(fmt.Sprintf("Subchain %v is the main chain", subchain.ChainID))
+		}
+		node.Subchains = append(node.Subchains, newNode(&Params{
+			ChainID:      subchain.ChainID,
+			PrivateKey:   params.PrivateKey,
+			Signer:       params.Signer,
+			Root:         subchain.Root,
+			Network:      mux.Network(subchain.ChainID),
+			DB:           subchain.DB,
+			SnapshotPath: subchain.SnapshotPath,
+		}, true))
+	}
+	return node
+}
+
+// newNode creates the node of a chain. The node of a subchain only runs the components
+// processing the chain, the ones serving the operator are run by the node of the main chain.
+func newNode(params *Params, isSubchain bool) *Node {
+	store := kvstore.NewKVStore(database.BlockDatabase(params.DB))
 	chain := blockchain.NewChain(params.ChainID, store, params.Root)
+	chain.SetBlockBodyRetention(uint64(viper.GetInt64(common.CfgStorageBlockBodyRetention)))
+	if params.BlockArchive != nil {
+		chain.SetBlockArchive(params.BlockArchive)
+	}
 	validatorManager := consensus.NewRotatingValidatorManager()
 	dispatcher := dp.NewDispatcher(params.Network)
-	consensus := consensus.NewConsensusEngine(params.PrivateKey, store, chain, dispatcher, validatorManager)
+	signer := params.Signer
+	if signer == nil {
+		signer = sgn.NewLocalSigner(params.PrivateKey)
+	}
+	consensus := consensus.NewConsensusEngine(signer, store, chain, dispatcher, validatorManager)
+	if params.PrivateKey != nil {
+		consensus.SetGuardianKey(params.PrivateKey)
+	}
 
 	currentHeight := consensus.GetLastFinalizedBlock().Height
 	if currentHeight <= params.Root.Height {
@@ -67,58 +158,282 @@ func NewNode(params *Params) *Node {
 	}
 
 	syncMgr := netsync.NewSyncManager(chain, consensus, params.Network, dispatcher, consensus)
+	stateSyncMgr := statesync.NewStateSyncManager(params.DB, params.Network)
 	mempool := mp.CreateMempool(dispatcher)
-	ledger := ld.NewLedger(params.ChainID, params.DB, consensus, validatorManager, mempool)
+	stateDB := params.DB
+	if cacheSize := viper.GetInt(common.CfgStorageStateCacheSize); cacheSize > 0 {
+		stateDB = trie.NewCachedDatabase(params.DB, cacheSize*1024*1024)
+	}
+	ledger := ld.NewLedger(params.ChainID, stateDB, consensus, validatorManager, mempool)
 	validatorManager.SetConsensusEngine(consensus)
 	consensus.SetLedger(ledger)
 	mempool.SetLedger(ledger)
 	txMsgHandler := mp.CreateMempoolMessageHandler(mempool)
 	params.Network.RegisterMessageHandler(txMsgHandler)
 
+	eventBus := event.NewBus()
+	chain.SetEventBus(eventBus)
+	consensus.SetEventBus(eventBus)
+	if publisher, ok := params.Network.(p2p.EventPublisher); ok {
+		publisher.SetEventBus(eventBus)
+	}
+
 	node := &Node{
 		Store:            store,
 		Chain:            chain,
 		Consensus:        consensus,
 		ValidatorManager: validatorManager,
 		SyncManager:      syncMgr,
+		StateSyncManager: stateSyncMgr,
 		Dispatcher:       dispatcher,
 		Ledger:           ledger,
 		Mempool:          mempool,
+		EventBus:         eventBus,
+		network:          params.Network,
+		ledger:           ledger,
+		db:               params.DB,
+		archive:          params.BlockArchive,
+		reloadMu:         &sync.Mutex{},
+		wg:               &sync.WaitGroup{},
+	}
+
+	if viper.GetBool(common.CfgCheckpointEnabled) {
+		node.Checkpointer = checkpoint.NewCheckpointer(store, consensus, mempool)
+	}
+
+	if compactable, ok := params.DB.(database.Compactable); ok {
+		maintainer, err := maintenance.NewMaintainer(compactable, mempool)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create the database maintainer, err: %v", err))
+		}
+		node.Maintainer = maintainer
+	}
+
+	if isSubchain {
+		return node
+	}
+
+	if viper.GetBool(common.CfgStatsEnabled) {
+		node.Stats = stats.NewCollector(store, chain, consensus)
+	}
+
+	if viper.GetBool(common.CfgWatchdogEnabled) {
+		node.Watchdog = NewWatchdog(consensus, syncMgr, params.Network)
+	}
+
+	if notifier := notify.NewNotifierFromConfig(params.Network.ID()); notifier != nil {
+		node.Notifier = notifier
+		peers, _ := params.Network.(notify.PeerSource)
+		node.Monitor = notify.NewMonitor(notifier, peers, params.DataPath)
 	}
 
 	if viper.GetBool(common.CfgRPCEnabled) {
-		node.RPC = rpc.NewThetaRPCServer(mempool, ledger, chain, consensus)
+		node.RPC = node.newRPCServer()
+	}
+
+	if viper.GetBool(common.CfgAdminEnabled) {
+		node.Admin = rpc.NewThetaAdminServer(mempool, consensus, params.Network, node.Checkpointer)
+		node.Admin.SetConfigReloader(node)
+		node.Admin.SetDatabase(params.DB, params.BlockArchive)
+		node.Admin.SetMaintainer(node.Maintainer)
 	}
 
 	return node
 }
 
+func (n *Node) newRPCServer() *rpc.ThetaRPCServer {
+	return rpc.NewThetaRPCServer(n.Mempool, n.ledger, n.Chain, n.Consensus, n.Stats)
+}
+
 // Start starts sub components and kick off the main loop.
 func (n *Node) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
 	n.ctx = c
 	n.cancel = cancel
+	n.runCtx, n.runCancel = context.WithCancel(context.Background())
 
-	n.Consensus.Start(n.ctx)
-	n.SyncManager.Start(n.ctx)
-	n.Dispatcher.Start(n.ctx)
-	n.Mempool.Start(n.ctx)
+	// Restore critical state in case the previous shutdown was not clean.
+	record, err := checkpoint.LoadLatest(n.Store)
+	if err == nil {
+		n.Consensus.RestoreLastVote(record.LastVote)
+	}
 
-	if viper.GetBool(common.CfgRPCEnabled) {
-		n.RPC.Start(n.ctx)
+	n.Consensus.Start(n.runCtx)
+
+	if err == nil {
+		n.recoverFromCheckpoint(record)
+	}
+
+	n.SyncManager.Start(n.runCtx)
+	n.StateSyncManager.Start(n.runCtx)
+	n.Dispatcher.Start(n.runCtx)
+	n.Mempool.Start(n.runCtx)
+
+	if n.Checkpointer != nil {
+		n.Checkpointer.Start(n.runCtx)
+	}
+
+	if n.Stats != nil {
+		n.Stats.Start(n.runCtx)
 	}
+
+	if n.Maintainer != nil {
+		n.Maintainer.Start(n.runCtx)
+	}
+
+	if n.Watchdog != nil {
+		n.Watchdog.Start(n.runCtx)
+	}
+
+	if n.Notifier != nil {
+		notify.SetDefault(n.Notifier)
+		n.Notifier.Start(n.runCtx)
+		n.Monitor.Start(n.runCtx)
+	}
+
+	n.reloadMu.Lock()
+	if n.RPC != nil {
+		n.RPC.Start(n.runCtx)
+	}
+	n.reloadMu.Unlock()
+
+	if n.Admin != nil {
+		n.Admin.Start(n.runCtx)
+	}
+
+	// The subchains are started once the shared network is started by the dispatcher
+	for _, subchain := range n.Subchains {
+		subchain.Start(n.ctx)
+	}
+
+	n.wg.Add(1)
+	go n.mainLoop()
+}
+
+// recoverFromCheckpoint restores pending transactions and the sync cursor recorded in the
+// last checkpoint. Ledger state must have been reset before calling this method.
+func (n *Node) recoverFromCheckpoint(record *checkpoint.Record) {
+	numRestored := 0
+	for _, err := range n.Mempool.InsertTransactions(record.MempoolTxs) {
+		if err == nil {
+			numRestored++
+		}
+	}
+	n.SyncManager.ResumeFrom(record.SyncTip)
+
+	log.WithFields(log.Fields{
+		"sequence":      record.Sequence,
+		"mempoolTxs":    len(record.MempoolTxs),
+		"restoredTxs":   numRestored,
+		"syncTip":       record.SyncTip.Hex(),
+		"syncTipHeight": record.SyncTipHeight,
+	}).Info("Recovered from checkpoint")
 }
 
-// Stop notifies all sub components to stop without blocking.
+// Stop notifies all sub components to stop without blocking. The sub components complete the
+// work in progress before they stop, see shutdown().
 func (n *Node) Stop() {
 	n.cancel()
 }
 
-// Wait blocks until all sub components stop.
+// Wait blocks until all sub components stop and the database is closed.
 func (n *Node) Wait() {
-	n.Consensus.Wait()
-	n.SyncManager.Wait()
+	n.wg.Wait()
+}
+
+func (n *Node) mainLoop() {
+	defer n.wg.Done()
+
+	<-n.ctx.Done()
+
+	// The subchains shut down before the main chain, which stops the shared network
+	for _, subchain := range n.Subchains {
+		subchain.Stop()
+	}
+	for _, subchain := range n.Subchains {
+		subchain.Wait()
+	}
+
+	timeout := time.Duration(viper.GetInt(common.CfgShutdownTimeout)) * time.Second
+	if err := n.shutdown(timeout); err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("Node did not shut down cleanly")
+	} else {
+		log.Info("Node shut down")
+	}
+	n.stopped = true
+}
+
+// lifecycle is implemented by the sub components of the node.
+type lifecycle interface {
+	Stop()
+	Wait()
+}
+
+// shutdown stops the sub components in stages. First the ones receiving work, i.e. the RPC
+// servers and the network, so that no new work comes in. Then the ones processing blocks, which
+// complete the block in progress before stopping. Then the remaining ones, so that e.g. the last
+// checkpoint captures the final state. The database is closed once all the sub components have
+// stopped. It is left open if they fail to stop before the timeout, since closing it could
+// interrupt a write in progress.
+func (n *Node) shutdown(timeout time.Duration) error {
+	n.reloadMu.Lock()
+	defer n.reloadMu.Unlock()
+
+	intake := []lifecycle{}
 	if n.RPC != nil {
-		n.RPC.Wait()
+		intake = append(intake, n.RPC)
+	}
+	if n.Admin != nil {
+		intake = append(intake, n.Admin)
+	}
+	intake = append(intake, n.Dispatcher)
+
+	processing := []lifecycle{n.SyncManager, n.StateSyncManager, n.Consensus}
+
+	others := []lifecycle{n.Mempool}
+	if n.Checkpointer != nil {
+		others = append(others, n.Checkpointer)
+	}
+	if n.Stats != nil {
+		others = append(others, n.Stats)
+	}
+	if n.Maintainer != nil {
+		others = append(others, n.Maintainer)
+	}
+	if n.Watchdog != nil {
+		others = append(others, n.Watchdog)
+	}
+	if n.Notifier != nil {
+		notify.SetDefault(nil)
+		others = append(others, n.Monitor, n.Notifier)
+	}
+
+	deadline := time.After(timeout)
+	for i, stage := range [][]lifecycle{intake, processing, others} {
+		for _, component := range stage {
+			component.Stop()
+		}
+
+		stopped := make(chan struct{})
+		go func(stage []lifecycle) {
+			for _, component := range stage {
+				component.Wait()
+			}
+			close(stopped)
+		}(stage)
+
+		select {
+		case <-stopped:
+			log.WithFields(log.Fields{"stage": i}).Debug("Shutdown stage completed")
+		case <-deadline:
+			return fmt.Errorf("Timed out after %v waiting for shutdown stage %v", timeout, i)
+		}
+	}
+
+	n.runCancel()
+	n.db.Close()
+	if n.archive != nil {
+		n.archive.Close()
 	}
+	return nil
 }
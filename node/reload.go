@@ -0,0 +1,101 @@
+package node
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/p2p"
+)
+
+// reloadableConfigs are the settings that can be changed without restarting the node. The
+// other settings, in particular the ones of consensus, take effect after a restart.
+var reloadableConfigs = []string{
+	common.CfgLogLevels,
+	common.CfgLogFormat,
+	common.CfgLogFile,
+	common.CfgLogMaxSize,
+	common.CfgLogRotateInterval,
+	common.CfgLogMaxBackups,
+	common.CfgP2PMaxNumPeers,
+	common.CfgP2PSufficientNumPeers,
+	common.CfgP2PSendRate,
+	common.CfgP2PRecvRate,
+	common.CfgRPCEnabled,
+	common.CfgRPCMaxBatchSize,
+}
+
+// logOutputConfigs are the settings of the log format and of the log file.
+var logOutputConfigs = []string{
+	common.CfgLogFormat,
+	common.CfgLogFile,
+	common.CfgLogMaxSize,
+	common.CfgLogRotateInterval,
+	common.CfgLogMaxBackups,
+}
+
+// ReloadConfig re-reads the config file and applies the reloadable settings to the running
+// node. It returns the reloadable settings that have changed. Consensus keeps running while
+// the config is reloaded.
+func (n *Node) ReloadConfig() ([]string, error) {
+	n.reloadMu.Lock()
+	defer n.reloadMu.Unlock()
+
+	previous := make(map[string]string)
+	for _, key := range reloadableConfigs {
+		previous[key] = viper.GetString(key)
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for _, key := range reloadableConfigs {
+		if viper.GetString(key) != previous[key] {
+			changed = append(changed, key)
+		}
+	}
+
+	// Log levels are applied first, so that the rest of the reload is logged at the new levels.
+	if err := util.ReloadLogLevels(viper.GetString(common.CfgLogLevels)); err != nil {
+		return nil, err
+	}
+	if containsAny(changed, logOutputConfigs) {
+		if err := util.ReloadLogOutput(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Peer limits are read from the config by the peer discovery, so only the rates of the
+	// connected peers need to be updated.
+	if rateLimiter, ok := n.network.(p2p.RateLimiter); ok {
+		rateLimiter.SetRateLimits(viper.GetInt64(common.CfgP2PSendRate), viper.GetInt64(common.CfgP2PRecvRate))
+	}
+
+	if viper.GetBool(common.CfgRPCEnabled) {
+		if n.RPC == nil {
+			n.RPC = n.newRPCServer()
+			n.RPC.Start(n.runCtx)
+		}
+		n.RPC.SetMaxBatchSize(viper.GetInt(common.CfgRPCMaxBatchSize))
+	} else if n.RPC != nil {
+		n.RPC.Stop()
+		n.RPC.Wait()
+		n.RPC = nil
+	}
+
+	log.WithFields(log.Fields{"changed": changed}).Info("Reloaded config")
+	return changed, nil
+}
+
+func containsAny(keys []string, candidates []string) bool {
+	for _, key := range keys {
+		for _, candidate := range candidates {
+			if key == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/notify"
+	"github.com/thetatoken/theta/p2p"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+const (
+	minWatchdogCheckInterval = 1 * time.Second
+	webhookTimeout           = 10 * time.Second
+)
+
+// ChainState provides the consensus state watched by the watchdog.
+type ChainState interface {
+	GetLastFinalizedBlock() *core.ExtendedBlock
+	GetTip(includePendingBlockingLeaf bool) *core.ExtendedBlock
+	GetEpoch() uint64
+	GetEpochVotes() (*core.VoteSet, error)
+}
+
+// Resyncer requests the blocks missing from the local chain from the peers.
+type Resyncer interface {
+	Resync()
+}
+
+// VoteSummary is a vote of the current epoch, as reported in a StallReport.
+type VoteSummary struct {
+	ID     string `json:"id"`
+	Block  string `json:"block"`
+	Height uint64 `json:"height"`
+	Epoch  uint64 `json:"epoch"`
+}
+
+// StallReport describes the state of the node when the finalized height stops advancing. It is
+// logged and posted to the webhook.
+type StallReport struct {
+	NodeID          string              `json:"node_id"`
+	StalledFor      string              `json:"stalled_for"`
+	FinalizedHeight uint64              `json:"finalized_height"`
+	FinalizedBlock  string              `json:"finalized_block"`
+	TipHeight       uint64              `json:"tip_height"`
+	Tip             string              `json:"tip"`
+	Epoch           uint64              `json:"epoch"`
+	Votes           []VoteSummary       `json:"votes"`
+	Peers           []p2ptypes.PeerInfo `json:"peers"`
+}
+
+//
+// Watchdog detects when the finalized height stops advancing. It then logs the consensus state
+// and the peers for diagnosis, tries to recover by reconnecting to the seed peers and requesting
+// the missing blocks, and posts an alert to the webhook, if any. The recovery is attempted again
+// each time the stall timeout elapses without progress.
+//
+type Watchdog struct {
+	logger *log.Entry
+
+	state        ChainState
+	syncer       Resyncer
+	network      p2p.Network
+	stallTimeout time.Duration
+	webhookURL   string
+	client       *http.Client
+
+	lastHeight   uint64
+	lastProgress time.Time
+	stalled      bool
+
+	now func() time.Time
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// NewWatchdog creates an instance of Watchdog.
+func NewWatchdog(state ChainState, syncer Resyncer, network p2p.Network) *Watchdog {
+	return &Watchdog{
+		logger:       util.GetLoggerForModule("watchdog"),
+		state:        state,
+		syncer:       syncer,
+		network:      network,
+		stallTimeout: time.Duration(viper.GetInt(common.CfgWatchdogStallTimeout)) * time.Second,
+		webhookURL:   viper.GetString(common.CfgWatchdogWebhookURL),
+		client:       &http.Client{Timeout: webhookTimeout},
+		now:          time.Now,
+		wg:           &sync.WaitGroup{},
+	}
+}
+
+// Start starts the watching loop.
+func (w *Watchdog) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	w.ctx = c
+	w.cancel = cancel
+
+	w.lastHeight = w.state.GetLastFinalizedBlock().Height
+	w.lastProgress = w.now()
+
+	w.wg.Add(1)
+	go w.mainLoop()
+}
+
+// Stop notifies the watching loop to stop.
+func (w *Watchdog) Stop() {
+	w.cancel()
+}
+
+// Wait blocks until the watching loop stops.
+func (w *Watchdog) Wait() {
+	w.wg.Wait()
+}
+
+func (w *Watchdog) mainLoop() {
+	defer w.wg.Done()
+
+	interval := w.stallTimeout / 10
+	if interval < minWatchdogCheckInterval {
+		interval = minWatchdogCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			w.stopped = true
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check returns the stall report if the finalized height has not advanced for the stall timeout,
+// after trying to recover.
+func (w *Watchdog) check() *StallReport {
+	height := w.state.GetLastFinalizedBlock().Height
+	now := w.now()
+	if height != w.lastHeight {
+		if w.stalled {
+			w.logger.WithFields(log.Fields{
+				"finalizedHeight": height,
+			}).Info("Finalized height is advancing again")
+		}
+		w.lastHeight = height
+		w.lastProgress = now
+		w.stalled = false
+		return nil
+	}
+
+	stalledFor := now.Sub(w.lastProgress)
+	if stalledFor < w.stallTimeout {
+		return nil
+	}
+	w.stalled = true
+	w.lastProgress = now // Try again if there is still no progress after another timeout
+
+	report := w.report(stalledFor)
+	w.logger.WithFields(log.Fields{
+		"stalledFor":      report.StalledFor,
+		"finalizedHeight": report.FinalizedHeight,
+		"finalizedBlock":  report.FinalizedBlock,
+		"tipHeight":       report.TipHeight,
+		"tip":             report.Tip,
+		"epoch":           report.Epoch,
+		"votes":           report.Votes,
+		"peers":           report.Peers,
+	}).Warn("Finalized height has not advanced, trying to recover")
+
+	w.tryToRecover()
+	notify.Notify(notify.Event{
+		Type:     notify.EventChainStalled,
+		Severity: notify.SeverityCritical,
+		Summary:  fmt.Sprintf("Finalized height %v has not advanced for %v", report.FinalizedHeight, report.StalledFor),
+		Details: map[string]interface{}{
+			"finalizedHeight": report.FinalizedHeight,
+			"tipHeight":       report.TipHeight,
+			"epoch":           report.Epoch,
+			"peers":           len(report.Peers),
+		},
+	})
+	if err := w.alert(report); err != nil {
+		w.logger.WithFields(log.Fields{"error": err}).Warn("Failed to post the stall alert")
+	}
+	return report
+}
+
+func (w *Watchdog) report(stalledFor time.Duration) *StallReport {
+	lfb := w.state.GetLastFinalizedBlock()
+	tip := w.state.GetTip(true)
+	report := &StallReport{
+		NodeID:          w.network.ID(),
+		StalledFor:      stalledFor.String(),
+		FinalizedHeight: lfb.Height,
+		FinalizedBlock:  lfb.Hash().Hex(),
+		TipHeight:       tip.Height,
+		Tip:             tip.Hash().Hex(),
+		Epoch:           w.state.GetEpoch(),
+		Votes:           []VoteSummary{},
+		Peers:           []p2ptypes.PeerInfo{},
+	}
+	if votes, err := w.state.GetEpochVotes(); err == nil {
+		for _, vote := range votes.Votes() {
+			report.Votes = append(report.Votes, VoteSummary{
+				ID:     vote.ID.Hex(),
+				Block:  vote.Block.Hex(),
+				Height: vote.Height,
+				Epoch:  vote.Epoch,
+			})
+		}
+	}
+	if admin, ok := w.network.(p2p.PeerAdmin); ok {
+		report.Peers = admin.PeerInfos()
+	}
+	return report
+}
+
+// tryToRecover reconnects to the seed peers, in case the node is partitioned from the network,
+// and requests the missing blocks from the peers.
+func (w *Watchdog) tryToRecover() {
+	if reconnector, ok := w.network.(p2p.Reconnector); ok {
+		reconnector.ReconnectSeedPeers()
+	}
+	w.syncer.Resync()
+}
+
+// alert posts the report to the webhook, if any.
+func (w *Watchdog) alert(report *StallReport) error {
+	if w.webhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded with status %v", resp.Status)
+	}
+	return nil
+}
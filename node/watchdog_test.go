@@ -0,0 +1,105 @@
+package node
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/p2p"
+)
+
+type watchdogTestState struct {
+	height uint64
+	votes  *core.VoteSet
+}
+
+func (s *watchdogTestState) block() *core.ExtendedBlock {
+	block := core.NewBlock()
+	block.Height = s.height
+	return &core.ExtendedBlock{Block: block}
+}
+
+func (s *watchdogTestState) GetLastFinalizedBlock() *core.ExtendedBlock { return s.block() }
+
+func (s *watchdogTestState) GetTip(bool) *core.ExtendedBlock { return s.block() }
+
+func (s *watchdogTestState) GetEpoch() uint64 { return 7 }
+
+func (s *watchdogTestState) GetEpochVotes() (*core.VoteSet, error) { return s.votes, nil }
+
+type watchdogTestNetwork struct {
+	p2p.Network
+	reconnects int
+	resyncs    int
+}
+
+func (n *watchdogTestNetwork) ID() string { return "node" }
+
+func (n *watchdogTestNetwork) ReconnectSeedPeers() { n.reconnects++ }
+
+func (n *watchdogTestNetwork) Resync() { n.resyncs++ }
+
+func TestWatchdog(t *testing.T) {
+	assert := assert.New(t)
+
+	alerts := make(chan StallReport, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report StallReport
+		json.NewDecoder(r.Body).Decode(&report)
+		alerts <- report
+	}))
+	defer server.Close()
+
+	viper.Set(common.CfgWatchdogStallTimeout, 60)
+	viper.Set(common.CfgWatchdogWebhookURL, server.URL)
+	defer viper.Set(common.CfgWatchdogWebhookURL, "")
+
+	votes := core.NewVoteSet()
+	votes.AddVote(core.Vote{ID: common.HexToAddress("0x1"), Height: 10, Epoch: 7})
+	state := &watchdogTestState{height: 10, votes: votes}
+	network := &watchdogTestNetwork{}
+
+	now := time.Now()
+	w := NewWatchdog(state, network, network)
+	w.now = func() time.Time { return now }
+	w.lastHeight = state.height
+	w.lastProgress = now
+
+	now = now.Add(45 * time.Second)
+	assert.Nil(w.check())
+
+	// The finalized height advances, which resets the stall timeout
+	state.height = 11
+	assert.Nil(w.check())
+	now = now.Add(45 * time.Second)
+	assert.Nil(w.check())
+
+	now = now.Add(30 * time.Second)
+	report := w.check()
+	assert.NotNil(report)
+	assert.Equal(uint64(11), report.FinalizedHeight)
+	assert.Equal(uint64(7), report.Epoch)
+	assert.Equal(1, len(report.Votes))
+	assert.Equal("1m15s", report.StalledFor)
+	assert.Equal(1, network.reconnects)
+	assert.Equal(1, network.resyncs)
+
+	alert := <-alerts
+	assert.Equal("node", alert.NodeID)
+	assert.Equal(uint64(11), alert.FinalizedHeight)
+
+	// The recovery is attempted again only after another stall timeout
+	now = now.Add(30 * time.Second)
+	assert.Nil(w.check())
+	now = now.Add(30 * time.Second)
+	assert.NotNil(w.check())
+	assert.Equal(2, network.resyncs)
+	<-alerts
+}
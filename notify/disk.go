@@ -0,0 +1,14 @@
+// +build !windows
+
+package notify
+
+import "syscall"
+
+// freeDiskSpace returns the disk space in bytes available to the node at the path.
+func freeDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
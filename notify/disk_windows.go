@@ -0,0 +1,8 @@
+package notify
+
+import "errors"
+
+// freeDiskSpace is not supported on Windows, the disk space is not checked.
+func freeDiskSpace(path string) (uint64, error) {
+	return 0, errors.New("Free disk space check is not supported on Windows")
+}
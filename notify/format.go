@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+	Ts     int64        `json:"ts"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component"`
+	Class         string                 `json:"class"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+var slackColors = map[Severity]string{
+	SeverityInfo:     "good",
+	SeverityWarning:  "warning",
+	SeverityCritical: "danger",
+}
+
+// formatEvent returns the body posted to the webhook for the event.
+func formatEvent(webhook Webhook, event Event) (interface{}, error) {
+	switch webhook.Format {
+	case FormatJSON, "":
+		return event, nil
+	case FormatSlack:
+		return formatSlack(event), nil
+	case FormatPagerDuty:
+		return formatPagerDuty(webhook.RoutingKey, event), nil
+	default:
+		return nil, fmt.Errorf("Unknown webhook format: %v", webhook.Format)
+	}
+}
+
+func formatSlack(event Event) slackMessage {
+	keys := make([]string, 0, len(event.Details))
+	for key := range event.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := []slackField{{Title: "source", Value: event.Source, Short: true}}
+	for _, key := range keys {
+		fields = append(fields, slackField{
+			Title: key,
+			Value: fmt.Sprintf("%v", event.Details[key]),
+			Short: true,
+		})
+	}
+	return slackMessage{
+		Text: fmt.Sprintf("[%v] %v", event.Severity, event.Summary),
+		Attachments: []slackAttachment{{
+			Color:  slackColors[event.Severity],
+			Fields: fields,
+			Ts:     event.Time.Unix(),
+		}},
+	}
+}
+
+func formatPagerDuty(routingKey string, event Event) pagerDutyEvent {
+	return pagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: "trigger",
+		DedupKey:    fmt.Sprintf("%v/%v", event.Source, event.key()),
+		Payload: pagerDutyPayload{
+			Summary:       event.Summary,
+			Source:        event.Source,
+			Severity:      string(event.Severity), // PagerDuty accepts the same severities
+			Timestamp:     event.Time.UTC().Format(time.RFC3339),
+			Component:     "theta",
+			Class:         string(event.Type),
+			CustomDetails: event.Details,
+		},
+	}
+}
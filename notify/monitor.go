@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+// PeerSource provides the connected peers.
+type PeerSource interface {
+	PeerInfos() []p2ptypes.PeerInfo
+}
+
+// Monitor periodically checks the peer count and the free disk space of the data directory, and
+// notifies the operator when they fall below the configured thresholds.
+type Monitor struct {
+	logger *log.Entry
+
+	notifier    *Notifier
+	peers       PeerSource // nil to skip the peer count check
+	dataPath    string     // empty to skip the disk space check
+	interval    time.Duration
+	minPeers    int
+	minFreeDisk uint64 // In bytes
+
+	freeDiskSpace func(path string) (uint64, error)
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// NewMonitor creates a monitor notifying through the notifier.
+func NewMonitor(notifier *Notifier, peers PeerSource, dataPath string) *Monitor {
+	return &Monitor{
+		logger:        util.GetLoggerForModule("notify"),
+		notifier:      notifier,
+		peers:         peers,
+		dataPath:      dataPath,
+		interval:      time.Duration(viper.GetInt(common.CfgNotifyCheckInterval)) * time.Second,
+		minPeers:      viper.GetInt(common.CfgNotifyMinPeers),
+		minFreeDisk:   uint64(viper.GetInt(common.CfgNotifyMinFreeDiskSpace)) * 1024 * 1024,
+		freeDiskSpace: freeDiskSpace,
+		wg:            &sync.WaitGroup{},
+	}
+}
+
+// Start starts the checking loop.
+func (m *Monitor) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	m.ctx = c
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go m.mainLoop()
+}
+
+// Stop notifies the checking loop to stop without blocking.
+func (m *Monitor) Stop() {
+	m.cancel()
+}
+
+// Wait blocks until the checking loop stops.
+func (m *Monitor) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Monitor) mainLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			m.stopped = true
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+func (m *Monitor) check() {
+	if m.peers != nil {
+		if numPeers := len(m.peers.PeerInfos()); numPeers < m.minPeers {
+			m.notifier.Notify(Event{
+				Type:     EventLowPeerCount,
+				Severity: SeverityWarning,
+				Summary:  fmt.Sprintf("Node has %v peers, below the minimum of %v", numPeers, m.minPeers),
+				Details: map[string]interface{}{
+					"peers":    numPeers,
+					"minPeers": m.minPeers,
+				},
+			})
+		}
+	}
+
+	if m.dataPath != "" {
+		free, err := m.freeDiskSpace(m.dataPath)
+		if err != nil {
+			m.logger.WithFields(log.Fields{"path": m.dataPath, "error": err}).Debug("Failed to check the free disk space")
+			return
+		}
+		if free < m.minFreeDisk {
+			m.notifier.Notify(Event{
+				Type:     EventDiskPressure,
+				Severity: SeverityCritical,
+				Summary:  fmt.Sprintf("Only %v MB of disk space left for %v", free/1024/1024, m.dataPath),
+				Details: map[string]interface{}{
+					"path":      m.dataPath,
+					"freeMB":    free / 1024 / 1024,
+					"minFreeMB": m.minFreeDisk / 1024 / 1024,
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+)
+
+// EventType is the type of the events the operator is notified of.
+type EventType string
+
+const (
+	// EventMissedProposal is raised when the local validator fails to propose in its epoch
+	EventMissedProposal EventType = "missed_proposal"
+	// EventSlashing is raised when a slash transaction involves the local validator
+	EventSlashing EventType = "slashing"
+	// EventLowPeerCount is raised when the node has fewer peers than configured
+	EventLowPeerCount EventType = "low_peer_count"
+	// EventDiskPressure is raised when the free disk space runs low
+	EventDiskPressure EventType = "disk_pressure"
+	// EventChainStalled is raised when the finalized height stops advancing
+	EventChainStalled EventType = "chain_stalled"
+)
+
+// Severity is the severity of an event.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+const (
+	// FormatJSON posts the event as is
+	FormatJSON = "json"
+	// FormatSlack posts the event as a Slack message
+	FormatSlack = "slack"
+	// FormatPagerDuty posts the event to the PagerDuty Events API v2
+	FormatPagerDuty = "pagerduty"
+
+	maxQueuedEvents = 64
+	postTimeout     = 10 * time.Second
+)
+
+// Event is an event the node operator is notified of.
+type Event struct {
+	Type     EventType              `json:"type"`
+	Severity Severity               `json:"severity"`
+	Summary  string                 `json:"summary"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Source   string                 `json:"source"`
+	Time     time.Time              `json:"time"`
+
+	// Key identifies the repeats of the event, which are not notified again until the cooldown
+	// elapses. Defaults to the event type.
+	Key string `json:"-"`
+}
+
+func (e *Event) key() string {
+	if e.Key != "" {
+		return e.Key
+	}
+	return string(e.Type)
+}
+
+// Webhook is an endpoint the events are posted to.
+type Webhook struct {
+	URL        string
+	Format     string
+	RoutingKey string // The PagerDuty integration key
+}
+
+//
+// Notifier posts the events to the webhooks. The events are posted in the background, and the
+// repeats of an event are dropped until the cooldown elapses, so that e.g. a node low on peers
+// does not flood the operator with notifications.
+//
+type Notifier struct {
+	logger *log.Entry
+
+	webhooks []Webhook
+	source   string
+	cooldown time.Duration
+	client   *http.Client
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+	queue    chan Event
+
+	now func() time.Time
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// NewNotifier creates a notifier posting the events of the source, e.g. the node ID, to the
+// webhooks.
+func NewNotifier(webhooks []Webhook, source string, cooldown time.Duration) *Notifier {
+	return &Notifier{
+		logger:   util.GetLoggerForModule("notify"),
+		webhooks: webhooks,
+		source:   source,
+		cooldown: cooldown,
+		client:   &http.Client{Timeout: postTimeout},
+		lastSent: make(map[string]time.Time),
+		queue:    make(chan Event, maxQueuedEvents),
+		now:      time.Now,
+		wg:       &sync.WaitGroup{},
+	}
+}
+
+// NewNotifierFromConfig creates the notifier of the webhooks configured in the notify section.
+// It returns nil if no webhook is configured.
+func NewNotifierFromConfig(source string) *Notifier {
+	webhooks := []Webhook{}
+	if url := viper.GetString(common.CfgNotifyWebhookURL); url != "" {
+		webhooks = append(webhooks, Webhook{URL: url, Format: FormatJSON})
+	}
+	if url := viper.GetString(common.CfgNotifySlackWebhookURL); url != "" {
+		webhooks = append(webhooks, Webhook{URL: url, Format: FormatSlack})
+	}
+	if key := viper.GetString(common.CfgNotifyPagerDutyRoutingKey); key != "" {
+		webhooks = append(webhooks, Webhook{
+			URL:        viper.GetString(common.CfgNotifyPagerDutyURL),
+			Format:     FormatPagerDuty,
+			RoutingKey: key,
+		})
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	cooldown := time.Duration(viper.GetInt(common.CfgNotifyCooldown)) * time.Second
+	return NewNotifier(webhooks, source, cooldown)
+}
+
+// Start starts posting the events.
+func (n *Notifier) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	n.ctx = c
+	n.cancel = cancel
+
+	n.wg.Add(1)
+	go n.mainLoop()
+}
+
+// Stop notifies the posting loop to stop without blocking.
+func (n *Notifier) Stop() {
+	n.cancel()
+}
+
+// Wait blocks until the posting loop stops.
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+func (n *Notifier) mainLoop() {
+	defer n.wg.Done()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			n.stopped = true
+			return
+		case event := <-n.queue:
+			n.post(event)
+		}
+	}
+}
+
+// Notify queues the event to be posted. It returns false if the event is dropped, because it
+// repeats an event notified within the cooldown or because too many events are queued.
+func (n *Notifier) Notify(event Event) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := n.now()
+	key := event.key()
+	if last, ok := n.lastSent[key]; ok && now.Sub(last) < n.cooldown {
+		return false
+	}
+	if event.Source == "" {
+		event.Source = n.source
+	}
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+
+	select {
+	case n.queue <- event:
+		n.lastSent[key] = now
+		return true
+	default:
+		n.logger.WithFields(log.Fields{"event": event.Type}).Warn("Too many notifications queued, dropping event")
+		return false
+	}
+}
+
+func (n *Notifier) post(event Event) {
+	for _, webhook := range n.webhooks {
+		if err := n.postTo(webhook, event); err != nil {
+			n.logger.WithFields(log.Fields{
+				"event":  event.Type,
+				"format": webhook.Format,
+				"error":  err,
+			}).Warn("Failed to post notification")
+		}
+	}
+}
+
+func (n *Notifier) postTo(webhook Webhook, event Event) error {
+	payload, err := formatEvent(webhook, event)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded with status %v", resp.Status)
+	}
+	return nil
+}
+
+var (
+	defaultMu       sync.Mutex
+	defaultNotifier *Notifier
+)
+
+// SetDefault sets the notifier of the events raised through Notify, nil to disable the
+// notifications.
+func SetDefault(n *Notifier) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultNotifier = n
+}
+
+// Notify queues the event to be posted by the default notifier. It is a no-op if no notifier is
+// set.
+func Notify(event Event) {
+	defaultMu.Lock()
+	n := defaultNotifier
+	defaultMu.Unlock()
+
+	if n != nil {
+		n.Notify(event)
+	}
+}
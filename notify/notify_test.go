@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+func newTestServer() (*httptest.Server, chan map[string]interface{}) {
+	bodies := make(chan map[string]interface{}, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := ioutil.ReadAll(r.Body)
+		body := make(map[string]interface{})
+		json.Unmarshal(raw, &body)
+		bodies <- body
+	}))
+	return server, bodies
+}
+
+func TestNotifierFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	server, bodies := newTestServer()
+	defer server.Close()
+
+	n := NewNotifier([]Webhook{
+		{URL: server.URL, Format: FormatJSON},
+		{URL: server.URL, Format: FormatSlack},
+		{URL: server.URL, Format: FormatPagerDuty, RoutingKey: "key"},
+	}, "node", time.Minute)
+	n.Start(context.Background())
+	defer n.Stop()
+
+	assert.True(n.Notify(Event{
+		Type:     EventLowPeerCount,
+		Severity: SeverityWarning,
+		Summary:  "Node has 1 peers",
+		Details:  map[string]interface{}{"peers": 1},
+	}))
+
+	body := <-bodies
+	assert.Equal("low_peer_count", body["type"])
+	assert.Equal("node", body["source"])
+
+	body = <-bodies
+	assert.Equal("[warning] Node has 1 peers", body["text"])
+	attachment := body["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal("warning", attachment["color"])
+	assert.Equal(2, len(attachment["fields"].([]interface{})))
+
+	body = <-bodies
+	assert.Equal("key", body["routing_key"])
+	assert.Equal("trigger", body["event_action"])
+	assert.Equal("node/low_peer_count", body["dedup_key"])
+	payload := body["payload"].(map[string]interface{})
+	assert.Equal("warning", payload["severity"])
+	assert.Equal("Node has 1 peers", payload["summary"])
+}
+
+func TestNotifierCooldown(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	n := NewNotifier([]Webhook{}, "node", time.Minute)
+	n.now = func() time.Time { return now }
+
+	assert.True(n.Notify(Event{Type: EventLowPeerCount}))
+	assert.False(n.Notify(Event{Type: EventLowPeerCount}))
+	assert.True(n.Notify(Event{Type: EventDiskPressure}))
+	assert.True(n.Notify(Event{Type: EventSlashing, Key: "slashing/1"}))
+	assert.True(n.Notify(Event{Type: EventSlashing, Key: "slashing/2"}))
+
+	now = now.Add(time.Minute)
+	assert.True(n.Notify(Event{Type: EventLowPeerCount}))
+}
+
+type testPeerSource struct {
+	numPeers int
+}
+
+func (s *testPeerSource) PeerInfos() []p2ptypes.PeerInfo {
+	return make([]p2ptypes.PeerInfo, s.numPeers)
+}
+
+func TestMonitor(t *testing.T) {
+	assert := assert.New(t)
+
+	n := NewNotifier([]Webhook{}, "node", time.Minute)
+	peers := &testPeerSource{numPeers: 5}
+	m := NewMonitor(n, peers, "/data")
+	m.minPeers = 3
+	m.minFreeDisk = 1024 * 1024 * 1024
+	free := uint64(2 * 1024 * 1024 * 1024)
+	m.freeDiskSpace = func(path string) (uint64, error) { return free, nil }
+
+	m.check()
+	assert.Equal(0, len(n.queue))
+
+	peers.numPeers = 2
+	free = 512 * 1024 * 1024
+	m.check()
+	assert.Equal(2, len(n.queue))
+
+	event := <-n.queue
+	assert.Equal(EventLowPeerCount, event.Type)
+	event = <-n.queue
+	assert.Equal(EventDiskPressure, event.Type)
+	assert.Equal(SeverityCritical, event.Severity)
+	assert.Equal(uint64(512), event.Details["freeMB"])
+}
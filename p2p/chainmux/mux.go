@@ -0,0 +1,257 @@
+package chainmux
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/p2p"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+var logger *log.Entry = util.GetLoggerForModule("chainmux")
+
+//
+// Envelope carries a message of an additional chain over ChannelIDChainMux. The payload is the
+// message encoded by the handler of its channel on that chain.
+//
+type Envelope struct {
+	ChainID   string
+	ChannelID common.ChannelIDEnum
+	Payload   common.Bytes
+}
+
+// received is the content of a message received over ChannelIDChainMux, parsed by the handler of
+// its chain. The handler is nil if the node does not host the chain.
+type received struct {
+	chainID string
+	handler p2p.MessageHandler
+	message p2ptypes.Message
+}
+
+//
+// Mux shares the p2p network of the node between the chains it hosts, e.g. a subchain along
+// with the mainnet. The main chain uses the network as is, so that the node stays compatible
+// with the peers which only host the main chain. The messages of the other chains are sent over
+// ChannelIDChainMux, to the peers with CapabilityChainMux, and dispatched to the handlers of
+// their chain by the receiver. The messages of the chains the receiver does not host are dropped.
+//
+type Mux struct {
+	network     p2p.Network
+	mainChainID string
+
+	mu     *sync.RWMutex
+	chains map[string]*ChainNetwork
+}
+
+var _ p2p.MessageHandler = (*Mux)(nil)
+
+// NewMux creates a Mux over the network, and registers it as the handler of ChannelIDChainMux.
+func NewMux(network p2p.Network, mainChainID string) *Mux {
+	mux := &Mux{
+		network:     network,
+		mainChainID: mainChainID,
+		mu:          &sync.RWMutex{},
+		chains:      make(map[string]*ChainNetwork),
+	}
+	network.RegisterMessageHandler(mux)
+	return mux
+}
+
+// Network returns the network of the chain, i.e. the shared network for the main chain and a
+// network namespaced by the chain ID for the other chains.
+func (mux *Mux) Network(chainID string) p2p.Network {
+	if chainID == mux.mainChainID {
+		return mux.network
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	chain, ok := mux.chains[chainID]
+	if !ok {
+		chain = &ChainNetwork{
+			mux:      mux,
+			chainID:  chainID,
+			mu:       &sync.RWMutex{},
+			handlers: make(map[common.ChannelIDEnum]p2p.MessageHandler),
+		}
+		mux.chains[chainID] = chain
+	}
+	return chain
+}
+
+// ChainIDs returns the IDs of the chains other than the main chain.
+func (mux *Mux) ChainIDs() []string {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+	chainIDs := make([]string, 0, len(mux.chains))
+	for chainID := range mux.chains {
+		chainIDs = append(chainIDs, chainID)
+	}
+	return chainIDs
+}
+
+// GetChannelIDs implements the p2p.MessageHandler interface.
+func (mux *Mux) GetChannelIDs() []common.ChannelIDEnum {
+	return []common.ChannelIDEnum{
+		common.ChannelIDChainMux,
+	}
+}
+
+// ParseMessage implements the p2p.MessageHandler interface. The payload is parsed by the handler
+// of its chain and channel.
+func (mux *Mux) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
+	message := p2ptypes.Message{
+		PeerID:    peerID,
+		ChannelID: channelID,
+	}
+	envelope := Envelope{}
+	if err := rlp.DecodeBytes(rawMessageBytes, &envelope); err != nil {
+		return message, err
+	}
+
+	content := received{chainID: envelope.ChainID}
+	mux.mu.RLock()
+	chain := mux.chains[envelope.ChainID]
+	mux.mu.RUnlock()
+	if chain != nil {
+		content.handler = chain.handler(envelope.ChannelID)
+	}
+	if content.handler != nil {
+		inner, err := content.handler.ParseMessage(peerID, envelope.ChannelID, envelope.Payload)
+		if err != nil {
+			return message, fmt.Errorf("Failed to parse message of chain %v on channel %v: %v", envelope.ChainID, envelope.ChannelID, err)
+		}
+		content.message = inner
+	}
+	message.Content = content
+	return message, nil
+}
+
+// EncodeMessage implements the p2p.MessageHandler interface.
+func (mux *Mux) EncodeMessage(message interface{}) (common.Bytes, error) {
+	return rlp.EncodeToBytes(message)
+}
+
+// HandleMessage implements the p2p.MessageHandler interface. It hands the message over to the
+// handler of its chain and channel.
+func (mux *Mux) HandleMessage(message p2ptypes.Message) error {
+	content, ok := message.Content.(received)
+	if !ok {
+		return fmt.Errorf("Unexpected message content: %v", message.Content)
+	}
+	if content.handler == nil {
+		logger.WithFields(log.Fields{
+			"peer":  message.PeerID,
+			"chain": content.chainID,
+		}).Debug("Dropped message of a chain not hosted by the node")
+		return nil
+	}
+	inner := content.message
+	inner.ReceivedAt = message.ReceivedAt
+	return content.handler.HandleMessage(inner)
+}
+
+//
+// ChainNetwork is the network of a chain other than the main chain, sharing the network of the
+// Mux. Its channels are namespaced by the chain ID. The shared network is started and stopped
+// along with the main chain, not by the chains sharing it.
+//
+type ChainNetwork struct {
+	mux     *Mux
+	chainID string
+
+	mu       *sync.RWMutex
+	handlers map[common.ChannelIDEnum]p2p.MessageHandler
+}
+
+var _ p2p.Network = (*ChainNetwork)(nil)
+
+// Start implements the p2p.Network interface.
+func (cn *ChainNetwork) Start(ctx context.Context) error {
+	return nil
+}
+
+// Wait implements the p2p.Network interface.
+func (cn *ChainNetwork) Wait() {
+}
+
+// Stop implements the p2p.Network interface.
+func (cn *ChainNetwork) Stop() {
+}
+
+// Broadcast implements the p2p.Network interface.
+func (cn *ChainNetwork) Broadcast(message p2ptypes.Message) chan bool {
+	wrapped, err := cn.wrap(message)
+	if err != nil {
+		logger.WithFields(log.Fields{"chain": cn.chainID, "channel": message.ChannelID, "error": err}).Warn("Failed to broadcast message")
+		return make(chan bool)
+	}
+	return cn.mux.network.Broadcast(wrapped)
+}
+
+// Send implements the p2p.Network interface.
+func (cn *ChainNetwork) Send(peerID string, message p2ptypes.Message) bool {
+	wrapped, err := cn.wrap(message)
+	if err != nil {
+		logger.WithFields(log.Fields{"chain": cn.chainID, "channel": message.ChannelID, "error": err}).Warn("Failed to send message")
+		return false
+	}
+	return cn.mux.network.Send(peerID, wrapped)
+}
+
+// RegisterMessageHandler implements the p2p.Network interface.
+func (cn *ChainNetwork) RegisterMessageHandler(messageHandler p2p.MessageHandler) {
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+	for _, channelID := range messageHandler.GetChannelIDs() {
+		if cn.handlers[channelID] != nil {
+			logger.Errorf("Message handler is already added for chain %v, channelID: %v", cn.chainID, channelID)
+			return
+		}
+		cn.handlers[channelID] = messageHandler
+	}
+}
+
+// ID implements the p2p.Network interface.
+func (cn *ChainNetwork) ID() string {
+	return cn.mux.network.ID()
+}
+
+// ChainID returns the ID of the chain of the network.
+func (cn *ChainNetwork) ChainID() string {
+	return cn.chainID
+}
+
+func (cn *ChainNetwork) handler(channelID common.ChannelIDEnum) p2p.MessageHandler {
+	cn.mu.RLock()
+	defer cn.mu.RUnlock()
+	return cn.handlers[channelID]
+}
+
+// wrap encodes the message with the handler of its channel into an envelope sent over
+// ChannelIDChainMux.
+func (cn *ChainNetwork) wrap(message p2ptypes.Message) (p2ptypes.Message, error) {
+	handler := cn.handler(message.ChannelID)
+	if handler == nil {
+		return p2ptypes.Message{}, fmt.Errorf("No message handler for channelID %v", message.ChannelID)
+	}
+	payload, err := handler.EncodeMessage(message.Content)
+	if err != nil {
+		return p2ptypes.Message{}, err
+	}
+	return p2ptypes.Message{
+		PeerID:    message.PeerID,
+		ChannelID: common.ChannelIDChainMux,
+		Content: Envelope{
+			ChainID:   cn.chainID,
+			ChannelID: message.ChannelID,
+			Payload:   payload,
+		},
+	}, nil
+}
@@ -0,0 +1,113 @@
+package chainmux
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p/simulation"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// stringHandler decodes the messages as strings and records their content.
+type stringHandler struct {
+	lock     *sync.Mutex
+	received []string
+}
+
+func newStringHandler() *stringHandler {
+	return &stringHandler{lock: &sync.Mutex{}}
+}
+
+func (sh *stringHandler) GetChannelIDs() []common.ChannelIDEnum {
+	return []common.ChannelIDEnum{common.ChannelIDBlock}
+}
+
+func (sh *stringHandler) EncodeMessage(message interface{}) (common.Bytes, error) {
+	return rlp.EncodeToBytes(message)
+}
+
+func (sh *stringHandler) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
+	var content string
+	err := rlp.DecodeBytes(rawMessageBytes, &content)
+	return p2ptypes.Message{
+		PeerID:    peerID,
+		ChannelID: channelID,
+		Content:   content,
+	}, err
+}
+
+func (sh *stringHandler) HandleMessage(message p2ptypes.Message) error {
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	sh.received = append(sh.received, message.PeerID+": "+message.Content.(string))
+	return nil
+}
+
+func (sh *stringHandler) contents() []string {
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	return append([]string{}, sh.received...)
+}
+
+func TestMuxIsolatesChains(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := simulation.NewMemNetwork(1)
+	endpointA := mn.AddEndpoint("a")
+	endpointB := mn.AddEndpoint("b")
+	muxA := NewMux(endpointA, "main")
+	muxB := NewMux(endpointB, "main")
+	endpointA.Start(ctx)
+	endpointB.Start(ctx)
+
+	// Node a hosts the main chain and the subchain, node b also hosts another subchain
+	handlers := map[string]*stringHandler{}
+	register := func(name string, mux *Mux, chainID string) {
+		handler := newStringHandler()
+		mux.Network(chainID).RegisterMessageHandler(handler)
+		handlers[name] = handler
+	}
+	register("a/main", muxA, "main")
+	register("a/sub", muxA, "sub")
+	register("b/main", muxB, "main")
+	register("b/sub", muxB, "sub")
+	register("b/other", muxB, "other")
+
+	// The main chain uses the shared network as is
+	assert.Equal(endpointA, muxA.Network("main"))
+	assert.Equal(muxA.Network("sub"), muxA.Network("sub"))
+	assert.Equal("a", muxA.Network("sub").ID())
+	assert.Equal([]string{"sub"}, muxA.ChainIDs())
+
+	send := func(mux *Mux, chainID string, content string) {
+		assert.True(mux.Network(chainID).Send("b", p2ptypes.Message{ChannelID: common.ChannelIDBlock, Content: content}))
+	}
+	send(muxA, "main", "main block")
+	send(muxA, "sub", "sub block")
+	send(muxA, "sub", "sub block 2")
+
+	// The messages of a chain the receiver does not host are dropped
+	muxA.Network("unknown").RegisterMessageHandler(newStringHandler())
+	send(muxA, "unknown", "unknown block")
+
+	assert.Eventually(func() bool {
+		return len(handlers["b/main"].contents()) == 1 && len(handlers["b/sub"].contents()) == 2
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal([]string{"a: main block"}, handlers["b/main"].contents())
+	assert.Equal([]string{"a: sub block", "a: sub block 2"}, handlers["b/sub"].contents())
+	assert.Empty(handlers["b/other"].contents())
+	assert.Empty(handlers["a/main"].contents())
+	assert.Empty(handlers["a/sub"].contents())
+
+	// No handler for the channel of the chain
+	assert.False(muxA.Network("sub").Send("b", p2ptypes.Message{ChannelID: common.ChannelIDVote, Content: "vote"}))
+}
@@ -2,6 +2,7 @@ package connection
 
 import (
 	"io"
+	"sync/atomic"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/rlp"
@@ -17,6 +18,14 @@ type Channel struct {
 	recvBuf RecvBuffer
 
 	config ChannelConfig
+
+	recentlySent int64 // bytes recently sent, decayed by the ChannelGroup. Only accessed by the send routine
+
+	// Flow control. The channel sends at most sendWindow more bytes until the peer grants more,
+	// and grants the peer the bytes it has processed once recvCredits reaches the threshold.
+	flowControlled int32
+	sendWindow     int64
+	recvCredits    int64
 }
 
 //
@@ -26,6 +35,27 @@ type ChannelConfig struct {
 	priority uint
 }
 
+// channelPriorities gives the consensus messages a larger share of the connection than the
+// bulk transfers, so that a large sync transfer can not delay the votes.
+var channelPriorities = map[common.ChannelIDEnum]uint{
+	common.ChannelIDCheckpoint:    2,
+	common.ChannelIDHeader:        2,
+	common.ChannelIDBlock:         1,
+	common.ChannelIDProposal:      10,
+	common.ChannelIDCC:            10,
+	common.ChannelIDVote:          10,
+	common.ChannelIDTransaction:   3,
+	common.ChannelIDPeerDiscovery: 3,
+	common.ChannelIDPing:          10,
+	common.ChannelIDState:         1,
+	common.ChannelIDPEX:           3,
+	common.ChannelIDGuardian:      10,
+	common.ChannelIDTxGossip:      3,
+	common.ChannelIDTxReconcile:   3,
+	common.ChannelIDBlockBody:     1,
+	common.ChannelIDChainMux:      3,
+}
+
 // createDefaultChannel creates a channel with default configs
 func createDefaultChannel(channelID common.ChannelIDEnum) Channel {
 	chCfg := getDefaultChannelConfig()
@@ -36,6 +66,19 @@ func createDefaultChannel(channelID common.ChannelIDEnum) Channel {
 	return channel
 }
 
+// createPrioritizedChannel creates a channel with the priority assigned to the channelID
+func createPrioritizedChannel(channelID common.ChannelIDEnum) Channel {
+	chCfg := getDefaultChannelConfig()
+	if priority, ok := channelPriorities[channelID]; ok {
+		chCfg.priority = priority
+	}
+	sbCfg := getDefaultSendBufferConfig()
+	rbCfg := getDefaultRecvBufferConfig()
+
+	channel := createChannel(channelID, chCfg, sbCfg, rbCfg)
+	return channel
+}
+
 // createChannel creates a channel for the given configs
 func createChannel(channelID common.ChannelIDEnum, channelConf ChannelConfig, sbConf SendBufferConfig, rbConf RecvBufferConfig) Channel {
 	sendBuf := createSendBuffer(sbConf)
@@ -84,6 +127,9 @@ func (ch *Channel) sendPacketTo(writer io.Writer) (nonemptyPacket bool, numBytes
 	if packet.isEmpty() {
 		return false, int(0), nil
 	}
+	if atomic.LoadInt32(&ch.flowControlled) == 1 {
+		atomic.AddInt64(&ch.sendWindow, -int64(len(packet.Bytes)))
+	}
 
 	// TODO: shall we use rlp.Encode() instead? But that won't return the num of bytes encoded
 	packetBytes, err := rlp.EncodeToBytes(packet)
@@ -92,6 +138,7 @@ func (ch *Channel) sendPacketTo(writer io.Writer) (nonemptyPacket bool, numBytes
 	}
 
 	numBytes, err = writer.Write(packetBytes)
+	ch.recentlySent += int64(numBytes)
 	return true, numBytes, err
 }
 
@@ -105,3 +152,49 @@ func (ch *Channel) hasPacketToSend() bool {
 	hasPacket := !ch.sendBuf.isEmpty()
 	return hasPacket
 }
+
+// canSendPacket returns whether there are pending data in the sendBuffer and the peer has
+// granted enough window to send the next packet
+func (ch *Channel) canSendPacket() bool {
+	if !ch.hasPacketToSend() {
+		return false
+	}
+	if atomic.LoadInt32(&ch.flowControlled) == 0 {
+		return true
+	}
+	return atomic.LoadInt64(&ch.sendWindow) >= maxPayloadSize
+}
+
+// enableFlowControl limits the bytes sent to the peer to the given window, replenished
+// by the window updates of the peer
+func (ch *Channel) enableFlowControl(window int64) {
+	atomic.StoreInt64(&ch.sendWindow, window)
+	atomic.StoreInt32(&ch.flowControlled, 1)
+}
+
+// disableFlowControl lifts the limit on the bytes sent to the peer
+func (ch *Channel) disableFlowControl() {
+	atomic.StoreInt32(&ch.flowControlled, 0)
+}
+
+// growSendWindow grants the channel the given bytes sent by the peer
+func (ch *Channel) growSendWindow(numBytes int64) {
+	atomic.AddInt64(&ch.sendWindow, numBytes)
+}
+
+// creditReceived records the bytes of a processed packet, and returns the total bytes to grant
+// to the peer since the last window update
+func (ch *Channel) creditReceived(numBytes int64) int64 {
+	return atomic.AddInt64(&ch.recvCredits, numBytes)
+}
+
+// takeRecvCredits returns the bytes to grant to the peer if they reach the given threshold,
+// and resets them
+func (ch *Channel) takeRecvCredits(threshold int64) int64 {
+	credits := atomic.LoadInt64(&ch.recvCredits)
+	if credits < threshold || credits == 0 {
+		return 0
+	}
+	atomic.AddInt64(&ch.recvCredits, -credits)
+	return credits
+}
@@ -8,8 +8,11 @@ import (
 
 const (
 	channelSelectionRoundRobinStrategy = 1
+	channelSelectionPriorityStrategy   = 2
 )
 
+const priorityDecayPeriod = 100 // number of selections between two decays of the recently sent bytes
+
 //
 // ChannelGroup contains multiple channels to facilitate fair scheduling
 //
@@ -42,6 +45,8 @@ func createChannelGroup(cgConfig ChannelGroupConfig, channels []*Channel) (bool,
 	var channelSelector ChannelSelector
 	if cgConfig.selectionStrategy == channelSelectionRoundRobinStrategy {
 		channelSelector = createRoundRobinChannelSelector()
+	} else if cgConfig.selectionStrategy == channelSelectionPriorityStrategy {
+		channelSelector = createPriorityChannelSelector()
 	} else {
 		logger.Errorf("Invalid channel selection strategy")
 		return false, ChannelGroup{}
@@ -67,6 +72,12 @@ func getDefaultChannelGroupConfig() ChannelGroupConfig {
 	}
 }
 
+func getPriorityChannelGroupConfig() ChannelGroupConfig {
+	return ChannelGroupConfig{
+		selectionStrategy: channelSelectionPriorityStrategy,
+	}
+}
+
 func (cg *ChannelGroup) addChannel(channel *Channel) bool {
 	cg.mutex.Lock()
 	defer cg.mutex.Unlock()
@@ -140,11 +151,14 @@ func (cg *ChannelGroup) nextChannelToSendPacket() (sucess bool, channel *Channel
 		if !success {
 			return false, nil
 		}
-		if selectedChannelIndex > len(*channels) {
+		if selectedChannelIndex < 0 {
+			return true, nil // no channel can send a packet
+		}
+		if selectedChannelIndex >= len(*channels) {
 			return false, nil
 		}
 		selectedChannel := (*channels)[selectedChannelIndex]
-		if !selectedChannel.hasPacketToSend() {
+		if !selectedChannel.canSendPacket() {
 			continue
 		}
 		return true, selectedChannel
@@ -179,3 +193,50 @@ func (rrcs *RoundRobinChannelSelector) nextSelectedChannelIndex(cg *ChannelGroup
 	}
 	return true, rrcs.lastUsedChannelIndex
 }
+
+//
+// PriorityChannelSelector implments the ChannelSelector interface. It selects the
+// channel that has sent the least bytes recently relative to its priority, among the
+// channels that can send a packet. Hence a busy low priority channel can not starve
+// the high priority ones, but still gets its share of the connection.
+//
+type PriorityChannelSelector struct {
+	numSelections uint
+}
+
+func createPriorityChannelSelector() ChannelSelector {
+	return &PriorityChannelSelector{}
+}
+
+func (pcs *PriorityChannelSelector) nextSelectedChannelIndex(cg *ChannelGroup) (success bool, index int) {
+	channels := *(cg.getAllChannels())
+	if len(channels) == 0 {
+		logger.Errorf("The channel group contains no channel")
+		return false, -1
+	}
+
+	pcs.numSelections++
+	if pcs.numSelections%priorityDecayPeriod == 0 {
+		for _, channel := range channels {
+			channel.recentlySent = channel.recentlySent * 4 / 5
+		}
+	}
+
+	selectedIndex := -1
+	var leastRatio float64
+	for idx, channel := range channels {
+		if !channel.canSendPacket() {
+			continue
+		}
+		priority := channel.config.priority
+		if priority == 0 {
+			priority = 1
+		}
+		ratio := float64(channel.recentlySent) / float64(priority)
+		if selectedIndex < 0 || ratio < leastRatio {
+			selectedIndex = idx
+			leastRatio = ratio
+		}
+	}
+	return true, selectedIndex
+}
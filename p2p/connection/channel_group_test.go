@@ -249,6 +249,48 @@ func TestRoundRobinChannelSelector2(t *testing.T) {
 	assert.Equal(&ch5, ch)
 }
 
+func TestPriorityChannelSelector(t *testing.T) {
+	assert := assert.New(t)
+
+	success, cg := createChannelGroup(getPriorityChannelGroupConfig(), []*Channel{})
+	assert.True(success)
+	strBuf := bytes.NewBufferString("")
+
+	chBlock := createPrioritizedChannel(common.ChannelIDBlock)
+	chVote := createPrioritizedChannel(common.ChannelIDVote)
+	assert.True(cg.addChannel(&chBlock))
+	assert.True(cg.addChannel(&chVote))
+
+	// Both channels have a long message to send
+	assert.True(chBlock.enqueueMessage(bytes.Repeat([]byte("b"), 100*maxPayloadSize)))
+	assert.True(chVote.enqueueMessage(bytes.Repeat([]byte("v"), 100*maxPayloadSize)))
+
+	numPackets := make(map[common.ChannelIDEnum]int)
+	for i := 0; i < 55; i++ {
+		success, ch := cg.nextChannelToSendPacket()
+		assert.True(success)
+		assert.NotNil(ch)
+		nonempty, _, err := ch.sendPacketTo(strBuf)
+		assert.True(nonempty)
+		assert.Nil(err)
+		numPackets[ch.getID()]++
+	}
+
+	// The vote channel gets most of the bandwidth, but the block channel is not starved
+	assert.True(numPackets[common.ChannelIDBlock] >= 3)
+	assert.True(numPackets[common.ChannelIDVote] >= 8*numPackets[common.ChannelIDBlock])
+
+	// Nothing to send once the channels are drained
+	success, cg = createChannelGroup(getPriorityChannelGroupConfig(), []*Channel{&chBlock})
+	assert.True(success)
+	for chBlock.hasPacketToSend() {
+		chBlock.sendPacketTo(strBuf)
+	}
+	success, ch := cg.nextChannelToSendPacket()
+	assert.True(success)
+	assert.Nil(ch)
+}
+
 // --------------- Test Utilities --------------- //
 
 func newTestEmptyChannelGroup() ChannelGroup {
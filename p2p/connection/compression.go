@@ -0,0 +1,119 @@
+package connection
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+
+	"github.com/thetatoken/theta/common"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+// Compression algorithms of the channel messages
+const (
+	CompressionNone   = byte(0x00)
+	CompressionSnappy = byte(0x01)
+)
+
+const (
+	minCompressedMessageSize   = 256              // smaller messages are sent uncompressed
+	maxDecompressedMessageSize = 64 * 1024 * 1024 // 64MB
+)
+
+// compressibleChannels are the channels carrying messages large enough to benefit from compression
+var compressibleChannels = []common.ChannelIDEnum{
+	common.ChannelIDCheckpoint,
+	common.ChannelIDHeader,
+	common.ChannelIDBlock,
+	common.ChannelIDProposal,
+	common.ChannelIDCC,
+	common.ChannelIDVote,
+	common.ChannelIDTransaction,
+	common.ChannelIDState,
+	common.ChannelIDGuardian,
+	common.ChannelIDTxGossip,
+	common.ChannelIDBlockBody,
+	common.ChannelIDChainMux,
+}
+
+// SupportedCompression returns the compression algorithms supported for each channel, in the
+// order of preference, to be advertised during the handshake.
+func SupportedCompression() []p2ptypes.ChannelCompression {
+	supported := []p2ptypes.ChannelCompression{}
+	for _, channelID := range compressibleChannels {
+		supported = append(supported, p2ptypes.ChannelCompression{
+			ChannelID:  channelID,
+			Algorithms: []byte{CompressionSnappy},
+		})
+	}
+	return supported
+}
+
+// NegotiateCompression enables the compression of the channels for which both sides support a
+// common algorithm, picking the one preferred locally. Both sides arrive at the same algorithm
+// as long as they advertise the same order of preference. It must be called before the
+// connection starts.
+func (conn *Connection) NegotiateCompression(local, remote []p2ptypes.ChannelCompression) {
+	remoteAlgorithms := make(map[common.ChannelIDEnum]map[byte]bool)
+	for _, cc := range remote {
+		remoteAlgorithms[cc.ChannelID] = make(map[byte]bool)
+		for _, algorithm := range cc.Algorithms {
+			remoteAlgorithms[cc.ChannelID][algorithm] = true
+		}
+	}
+
+	conn.compression = make(map[common.ChannelIDEnum]byte)
+	for _, cc := range local {
+		for _, algorithm := range cc.Algorithms {
+			if algorithm != CompressionNone && remoteAlgorithms[cc.ChannelID][algorithm] {
+				conn.compression[cc.ChannelID] = algorithm
+				break
+			}
+		}
+	}
+}
+
+// compressMessage compresses the message if compression is enabled for the channel. On such
+// channels, each message is prefixed with the algorithm it is compressed with.
+func (conn *Connection) compressMessage(channelID common.ChannelIDEnum, msgBytes common.Bytes) common.Bytes {
+	algorithm, ok := conn.compression[channelID]
+	if !ok {
+		return msgBytes
+	}
+	if len(msgBytes) < minCompressedMessageSize {
+		return append([]byte{CompressionNone}, msgBytes...)
+	}
+	switch algorithm {
+	case CompressionSnappy:
+		return append([]byte{CompressionSnappy}, snappy.Encode(nil, msgBytes)...)
+	default:
+		return append([]byte{CompressionNone}, msgBytes...)
+	}
+}
+
+// decompressMessage reverts compressMessage.
+func (conn *Connection) decompressMessage(channelID common.ChannelIDEnum, msgBytes common.Bytes) (common.Bytes, error) {
+	if _, ok := conn.compression[channelID]; !ok {
+		return msgBytes, nil
+	}
+	if len(msgBytes) == 0 {
+		return nil, fmt.Errorf("Missing compression header on channel %v", channelID)
+	}
+
+	payload := msgBytes[1:]
+	switch msgBytes[0] {
+	case CompressionNone:
+		return payload, nil
+	case CompressionSnappy:
+		decodedLen, err := snappy.DecodedLen(payload)
+		if err != nil {
+			return nil, err
+		}
+		if decodedLen > maxDecompressedMessageSize {
+			return nil, fmt.Errorf("Decompressed message too large: %v bytes", decodedLen)
+		}
+		return snappy.Decode(nil, payload)
+	default:
+		return nil, fmt.Errorf("Unknown compression algorithm: %v", msgBytes[0])
+	}
+}
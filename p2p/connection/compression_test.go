@@ -0,0 +1,60 @@
+package connection
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+func TestNegotiateCompression(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{}
+	remote := []p2ptypes.ChannelCompression{
+		{ChannelID: common.ChannelIDBlock, Algorithms: []byte{0x7f, CompressionSnappy}},
+		{ChannelID: common.ChannelIDVote, Algorithms: []byte{0x7f}},
+	}
+	conn.NegotiateCompression(SupportedCompression(), remote)
+	assert.Equal(1, len(conn.compression))
+	assert.Equal(CompressionSnappy, conn.compression[common.ChannelIDBlock])
+
+	// No compression with peers not advertising any
+	conn.NegotiateCompression(SupportedCompression(), nil)
+	assert.Equal(0, len(conn.compression))
+}
+
+func TestCompressMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{}
+	conn.NegotiateCompression(SupportedCompression(), SupportedCompression())
+
+	// Compressed on negotiated channels
+	large := common.Bytes(bytes.Repeat([]byte("theta block "), 1000))
+	compressed := conn.compressMessage(common.ChannelIDBlock, large)
+	assert.Equal(CompressionSnappy, compressed[0])
+	assert.True(len(compressed) < len(large)/3)
+	decompressed, err := conn.decompressMessage(common.ChannelIDBlock, compressed)
+	assert.Nil(err)
+	assert.Equal(large, decompressed)
+
+	// Small messages are sent uncompressed
+	small := common.Bytes("vote")
+	compressed = conn.compressMessage(common.ChannelIDVote, small)
+	assert.Equal(CompressionNone, compressed[0])
+	decompressed, err = conn.decompressMessage(common.ChannelIDVote, compressed)
+	assert.Nil(err)
+	assert.Equal(small, decompressed)
+
+	// Other channels are not touched
+	assert.Equal(large, conn.compressMessage(common.ChannelIDPeerDiscovery, large))
+
+	// Invalid messages are rejected
+	_, err = conn.decompressMessage(common.ChannelIDBlock, common.Bytes{})
+	assert.NotNil(err)
+	_, err = conn.decompressMessage(common.ChannelIDBlock, common.Bytes{0x7f, 0x01})
+	assert.NotNil(err)
+}
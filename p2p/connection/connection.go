@@ -11,15 +11,17 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/timer"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/p2p/connection/flowrate"
 	"github.com/thetatoken/theta/p2p/types"
 	p2ptypes "github.com/thetatoken/theta/p2p/types"
 	"github.com/thetatoken/theta/rlp"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "p2p"})
+var logger *log.Entry = util.GetLoggerForModule("p2p")
 
 //
 // Connection models the connection between the current node and a peer node.
@@ -41,9 +43,13 @@ type Connection struct {
 	onError      ErrorHandler
 	errored      uint32
 
-	sendPulse chan bool
-	pongPulse chan bool
-	quitPulse chan bool
+	compression map[common.ChannelIDEnum]byte // channelID |-> negotiated compression algorithm
+	recvWindow  int64                         // local flow-control window per channel, 0 if disabled
+
+	sendPulse         chan bool
+	pongPulse         chan bool
+	quitPulse         chan bool
+	windowUpdatePulse chan bool
 
 	flushTimer *timer.ThrottleTimer // flush writes as necessary but throttled
 	pingTimer  *timer.RepeatTimer   // send pings periodically
@@ -92,14 +98,21 @@ type ErrorHandler func(interface{})
 
 // CreateConnection creates a Connection instance
 func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
-	channelCheckpoint := createDefaultChannel(common.ChannelIDCheckpoint)
-	channelHeader := createDefaultChannel(common.ChannelIDHeader)
-	channelBlock := createDefaultChannel(common.ChannelIDBlock)
-	channelProposal := createDefaultChannel(common.ChannelIDProposal)
-	channelVote := createDefaultChannel(common.ChannelIDVote)
-	channelTransaction := createDefaultChannel(common.ChannelIDTransaction)
-	channelPeerDiscover := createDefaultChannel(common.ChannelIDPeerDiscovery)
-	channelPing := createDefaultChannel(common.ChannelIDPing)
+	channelCheckpoint := createPrioritizedChannel(common.ChannelIDCheckpoint)
+	channelHeader := createPrioritizedChannel(common.ChannelIDHeader)
+	channelBlock := createPrioritizedChannel(common.ChannelIDBlock)
+	channelProposal := createPrioritizedChannel(common.ChannelIDProposal)
+	channelVote := createPrioritizedChannel(common.ChannelIDVote)
+	channelTransaction := createPrioritizedChannel(common.ChannelIDTransaction)
+	channelPeerDiscover := createPrioritizedChannel(common.ChannelIDPeerDiscovery)
+	channelPing := createPrioritizedChannel(common.ChannelIDPing)
+	channelState := createPrioritizedChannel(common.ChannelIDState)
+	channelPEX := createPrioritizedChannel(common.ChannelIDPEX)
+	channelGuardian := createPrioritizedChannel(common.ChannelIDGuardian)
+	channelTxGossip := createPrioritizedChannel(common.ChannelIDTxGossip)
+	channelTxReconcile := createPrioritizedChannel(common.ChannelIDTxReconcile)
+	channelBlockBody := createPrioritizedChannel(common.ChannelIDBlockBody)
+	channelChainMux := createPrioritizedChannel(common.ChannelIDChainMux)
 	channels := []*Channel{
 		&channelCheckpoint,
 		&channelHeader,
@@ -109,27 +122,36 @@ func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
 		&channelTransaction,
 		&channelPeerDiscover,
 		&channelPing,
+		&channelState,
+		&channelPEX,
+		&channelGuardian,
+		&channelTxGossip,
+		&channelTxReconcile,
+		&channelBlockBody,
+		&channelChainMux,
 	}
 
-	success, channelGroup := createChannelGroup(getDefaultChannelGroupConfig(), channels)
+	success, channelGroup := createChannelGroup(getPriorityChannelGroupConfig(), channels)
 	if !success {
 		return nil
 	}
 
 	return &Connection{
-		netconn:      netconn,
-		bufWriter:    bufio.NewWriterSize(netconn, config.MinWriteBufferSize),
-		sendMonitor:  flowrate.New(0, 0),
-		bufReader:    bufio.NewReaderSize(netconn, config.MinReadBufferSize),
-		recvMonitor:  flowrate.New(0, 0),
-		channelGroup: channelGroup,
-		sendPulse:    make(chan bool, 1),
-		pongPulse:    make(chan bool, 1),
-		quitPulse:    make(chan bool, 1),
-		flushTimer:   timer.NewThrottleTimer("flush", config.FlushThrottle),
-		pingTimer:    timer.NewRepeatTimer("ping", config.PingTimeout),
-		config:       config,
-		wg:           &sync.WaitGroup{},
+		netconn:           netconn,
+		bufWriter:         bufio.NewWriterSize(netconn, config.MinWriteBufferSize),
+		sendMonitor:       flowrate.New(0, 0),
+		bufReader:         bufio.NewReaderSize(netconn, config.MinReadBufferSize),
+		recvMonitor:       flowrate.New(0, 0),
+		channelGroup:      channelGroup,
+		compression:       make(map[common.ChannelIDEnum]byte),
+		sendPulse:         make(chan bool, 1),
+		pongPulse:         make(chan bool, 1),
+		quitPulse:         make(chan bool, 1),
+		windowUpdatePulse: make(chan bool, 1),
+		flushTimer:        timer.NewThrottleTimer("flush", config.FlushThrottle),
+		pingTimer:         timer.NewRepeatTimer("ping", config.PingTimeout),
+		config:            config,
+		wg:                &sync.WaitGroup{},
 
 		onEncode: defaultMessageEncoder,
 	}
@@ -138,8 +160,8 @@ func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
 // GetDefaultConnectionConfig returns the default ConnectionConfig
 func GetDefaultConnectionConfig() ConnectionConfig {
 	return ConnectionConfig{
-		SendRate:        int64(512000), // 500KB/s
-		RecvRate:        int64(512000), // 500KB/s
+		SendRate:        viper.GetInt64(common.CfgP2PSendRate),
+		RecvRate:        viper.GetInt64(common.CfgP2PRecvRate),
 		PacketBatchSize: int64(10),
 		FlushThrottle:   100 * time.Millisecond,
 		PingTimeout:     40 * time.Second,
@@ -147,6 +169,12 @@ func GetDefaultConnectionConfig() ConnectionConfig {
 	}
 }
 
+// SetRateLimits changes the send and receive rates of the connection in bytes per second.
+func (conn *Connection) SetRateLimits(sendRate, recvRate int64) {
+	atomic.StoreInt64(&conn.config.SendRate, sendRate)
+	atomic.StoreInt64(&conn.config.RecvRate, recvRate)
+}
+
 // SetPingTimer for testing purpose
 func (conn *Connection) SetPingTimer(seconds time.Duration) {
 	conn.pingTimer = timer.NewRepeatTimer("ping", seconds*time.Second)
@@ -218,6 +246,7 @@ func (conn *Connection) EnqueueMessage(channelID common.ChannelIDEnum, message i
 		logger.Errorf("Failed to encode message to bytes: %v, err: %v", message, err)
 		return false
 	}
+	msgBytes = conn.compressMessage(channelID, msgBytes)
 	success := channel.enqueueMessage(msgBytes)
 	if success {
 		conn.scheduleSendPulse()
@@ -240,6 +269,7 @@ func (conn *Connection) AttemptToEnqueueMessage(channelID common.ChannelIDEnum,
 		logger.Errorf("Failed to encode message to bytes: %v, error: %v", message, err)
 		return false
 	}
+	msgBytes = conn.compressMessage(channelID, msgBytes)
 	success := channel.attemptToEnqueueMessage(msgBytes)
 	if success {
 		conn.scheduleSendPulse()
@@ -277,6 +307,8 @@ func (conn *Connection) sendRoutine() {
 			err = conn.sendPingSignal()
 		case <-conn.pongPulse:
 			err = conn.sendPongSignal()
+		case <-conn.windowUpdatePulse:
+			err = conn.sendWindowUpdates()
 		case <-conn.sendPulse:
 			conn.sendPacketBatchAndScheduleSendPulse()
 		case <-conn.quitPulse:
@@ -350,8 +382,7 @@ func (conn *Connection) recvRoutine() {
 		// Block until recvMonitor allows reading
 		conn.recvMonitor.Limit(maxPacketTotalSize, atomic.LoadInt64(&conn.config.RecvRate), true)
 
-		var packet Packet
-		err := rlp.Decode(conn.bufReader, &packet)
+		packet, err := readPacket(conn.bufReader)
 		if err != nil {
 			logger.Errorf("recvRoutine: failed to decode packet: %v, error: %v", packet, err)
 			return
@@ -360,6 +391,10 @@ func (conn *Connection) recvRoutine() {
 		switch packet.ChannelID {
 		case common.ChannelIDPing:
 			conn.handlePingPong(&packet)
+		case common.ChannelIDFlowControl:
+			if err := conn.handleWindowUpdate(&packet); err != nil {
+				logger.Errorf("recvRoutine: invalid window update: %v, error: %v", packet, err)
+			}
 		default:
 			conn.handleReceivedPacket(&packet)
 		}
@@ -399,6 +434,7 @@ func (conn *Connection) handleReceivedPacket(packet *Packet) (success bool) {
 	if channel == nil {
 		return false
 	}
+	defer conn.creditReceivedPacket(channel, packet)
 
 	aggregatedBytes, success := channel.receivePacket(packet)
 	if !success {
@@ -409,6 +445,12 @@ func (conn *Connection) handleReceivedPacket(packet *Packet) (success bool) {
 		return true
 	}
 
+	aggregatedBytes, err := conn.decompressMessage(channelID, aggregatedBytes)
+	if err != nil {
+		logger.Errorf("Error decompressing message on channel %v, err: %v", channelID, err)
+		return false
+	}
+
 	message, err := conn.onParse(packet.ChannelID, aggregatedBytes)
 	if err != nil {
 		logger.Errorf("Error parsing packet: %v, err: %v", packet, err)
@@ -482,6 +524,14 @@ func (conn *Connection) GetNetconn() net.Conn {
 	return conn.netconn
 }
 
+// SetNetconn replaces the attached network connection, e.g. with the encrypted connection
+// established by the handshake. It must be called before the connection starts.
+func (conn *Connection) SetNetconn(netconn net.Conn) {
+	conn.netconn = netconn
+	conn.bufWriter = bufio.NewWriterSize(netconn, conn.config.MinWriteBufferSize)
+	conn.bufReader = bufio.NewReaderSize(netconn, conn.config.MinReadBufferSize)
+}
+
 func (conn *Connection) stopForError(r interface{}) {
 	logger.Errorf("Connection error: %v", r)
 	if atomic.CompareAndSwapUint32(&conn.errored, 0, 1) {
@@ -516,6 +566,13 @@ func (conn *Connection) schedulePongPulse() {
 	}
 }
 
+func (conn *Connection) scheduleWindowUpdatePulse() {
+	select {
+	case conn.windowUpdatePulse <- true:
+	default:
+	}
+}
+
 func (conn *Connection) scheduleQuitPulse() {
 	select {
 	case conn.quitPulse <- true:
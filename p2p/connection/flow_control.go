@@ -0,0 +1,102 @@
+package connection
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+//
+// windowUpdate grants the peer the bytes processed on a channel, sent over ChannelIDFlowControl
+//
+type windowUpdate struct {
+	ChannelID common.ChannelIDEnum
+	NumBytes  uint64
+}
+
+// NegotiateFlowControl enables the per-channel flow control if both sides advertise a receive
+// window. The node then sends at most the remote window on each channel until the peer grants
+// more, and grants the peer the bytes it has processed once they reach half of the local window.
+// It must be called before the connection starts.
+func (conn *Connection) NegotiateFlowControl(localWindow, remoteWindow uint32) {
+	enabled := localWindow >= 2*maxPayloadSize && remoteWindow >= 2*maxPayloadSize
+	if enabled {
+		atomic.StoreInt64(&conn.recvWindow, int64(localWindow))
+	} else {
+		atomic.StoreInt64(&conn.recvWindow, 0)
+	}
+
+	for _, channel := range *(conn.channelGroup.getAllChannels()) {
+		if !enabled || channel.getID() == common.ChannelIDPing {
+			channel.disableFlowControl()
+			continue
+		}
+		channel.enableFlowControl(int64(remoteWindow))
+	}
+}
+
+// creditReceivedPacket records the bytes of a processed packet, and schedules a window
+// update if enough bytes can be granted to the peer.
+func (conn *Connection) creditReceivedPacket(channel *Channel, packet *Packet) {
+	recvWindow := atomic.LoadInt64(&conn.recvWindow)
+	if recvWindow == 0 {
+		return
+	}
+	if channel.creditReceived(int64(len(packet.Bytes))) >= recvWindow/2 {
+		conn.scheduleWindowUpdatePulse()
+	}
+}
+
+// sendWindowUpdates grants the peer the bytes processed on each channel, if they reach
+// half of the local window.
+func (conn *Connection) sendWindowUpdates() error {
+	recvWindow := atomic.LoadInt64(&conn.recvWindow)
+	if recvWindow == 0 {
+		return nil
+	}
+
+	for _, channel := range *(conn.channelGroup.getAllChannels()) {
+		credits := channel.takeRecvCredits(recvWindow / 2)
+		if credits == 0 {
+			continue
+		}
+		update := windowUpdate{
+			ChannelID: channel.getID(),
+			NumBytes:  uint64(credits),
+		}
+		updateBytes, err := rlp.EncodeToBytes(update)
+		if err != nil {
+			return err
+		}
+		updatePacket := Packet{
+			ChannelID: common.ChannelIDFlowControl,
+			Bytes:     updateBytes,
+			IsEOF:     byte(0x01),
+		}
+		err = rlp.Encode(conn.bufWriter, updatePacket)
+		if err != nil {
+			return err
+		}
+		conn.sendMonitor.Update(len(updateBytes))
+	}
+	conn.flush()
+	return nil
+}
+
+// handleWindowUpdate grows the send window of the channel by the bytes granted by the peer.
+func (conn *Connection) handleWindowUpdate(packet *Packet) error {
+	var update windowUpdate
+	err := rlp.DecodeBytes(packet.Bytes, &update)
+	if err != nil {
+		return err
+	}
+	channel := conn.channelGroup.getChannel(update.ChannelID)
+	if channel == nil {
+		return fmt.Errorf("Window update for unknown channel %v", update.ChannelID)
+	}
+	channel.growSendWindow(int64(update.NumBytes))
+	conn.scheduleSendPulse()
+	return nil
+}
@@ -0,0 +1,82 @@
+package connection
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func TestNegotiateFlowControl(t *testing.T) {
+	assert := assert.New(t)
+
+	netconn, _ := net.Pipe()
+	conn := CreateConnection(netconn, GetDefaultConnectionConfig())
+	chBlock := conn.channelGroup.getChannel(common.ChannelIDBlock)
+	assert.True(chBlock.enqueueMessage(bytes.Repeat([]byte("b"), 10*maxPayloadSize)))
+
+	// No flow control with peers not advertising a window
+	conn.NegotiateFlowControl(64*1024, 0)
+	assert.Equal(int64(0), conn.recvWindow)
+	assert.True(chBlock.canSendPacket())
+
+	conn.NegotiateFlowControl(64*1024, 4*maxPayloadSize)
+	assert.Equal(int64(64*1024), conn.recvWindow)
+
+	strBuf := bytes.NewBufferString("")
+	for i := 0; i < 4; i++ {
+		assert.True(chBlock.canSendPacket())
+		nonempty, _, err := chBlock.sendPacketTo(strBuf)
+		assert.True(nonempty)
+		assert.Nil(err)
+	}
+
+	// The window is exhausted, the block channel waits while the others can still send
+	assert.True(chBlock.hasPacketToSend())
+	assert.False(chBlock.canSendPacket())
+	chVote := conn.channelGroup.getChannel(common.ChannelIDVote)
+	assert.True(chVote.enqueueMessage([]byte("vote")))
+	success, ch := conn.channelGroup.nextChannelToSendPacket()
+	assert.True(success)
+	assert.Equal(chVote, ch)
+
+	// The window update of the peer resumes the block channel
+	updateBytes, err := rlp.EncodeToBytes(windowUpdate{ChannelID: common.ChannelIDBlock, NumBytes: 2 * maxPayloadSize})
+	assert.Nil(err)
+	assert.Nil(conn.handleWindowUpdate(&Packet{ChannelID: common.ChannelIDFlowControl, Bytes: updateBytes, IsEOF: byte(0x01)}))
+	assert.True(chBlock.canSendPacket())
+}
+
+func TestWindowUpdateRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	recvNetconn, sendNetconn := net.Pipe()
+	receiver := CreateConnection(recvNetconn, GetDefaultConnectionConfig())
+	sender := CreateConnection(sendNetconn, GetDefaultConnectionConfig())
+	receiver.NegotiateFlowControl(4*maxPayloadSize, 4*maxPayloadSize)
+	sender.NegotiateFlowControl(4*maxPayloadSize, 4*maxPayloadSize)
+
+	// No window update until half of the window is processed
+	chState := receiver.channelGroup.getChannel(common.ChannelIDState)
+	packet := &Packet{ChannelID: common.ChannelIDState, Bytes: bytes.Repeat([]byte("s"), maxPayloadSize)}
+	receiver.creditReceivedPacket(chState, packet)
+	assert.Equal(0, len(receiver.windowUpdatePulse))
+	receiver.creditReceivedPacket(chState, packet)
+	assert.Equal(1, len(receiver.windowUpdatePulse))
+
+	go func() {
+		assert.Nil(receiver.sendWindowUpdates())
+	}()
+
+	var update Packet
+	assert.Nil(rlp.Decode(sendNetconn, &update))
+	assert.Equal(common.ChannelIDFlowControl, update.ChannelID)
+	assert.Nil(sender.handleWindowUpdate(&update))
+
+	senderChState := sender.channelGroup.getChannel(common.ChannelIDState)
+	assert.Equal(int64(6*maxPayloadSize), senderChState.sendWindow)
+	assert.Equal(int64(0), chState.recvCredits)
+}
@@ -0,0 +1,58 @@
+// +build gofuzz
+
+package connection
+
+import (
+	"bytes"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// FuzzPackets is the entry point for the go-fuzz tool on the decoding of the packets received
+// from the peers, e.g.
+//
+//	go-fuzz-build -func FuzzPackets github.com/thetatoken/theta/p2p/connection
+//	go-fuzz -bin connection-fuzz.zip -workdir fuzz/packets
+//
+// The input is read as a stream of packets, whose payloads are assembled into messages and
+// decompressed the way the connection does. It returns 1 if at least one packet decodes, and 0
+// otherwise.
+func FuzzPackets(data []byte) int {
+	conn := &Connection{
+		compression: map[common.ChannelIDEnum]byte{common.ChannelIDBlock: CompressionSnappy},
+	}
+	recvBufs := make(map[common.ChannelIDEnum]*RecvBuffer)
+
+	reader := bytes.NewReader(data)
+	numPackets := 0
+	for {
+		packet, err := readPacket(reader)
+		if err != nil {
+			break
+		}
+		numPackets++
+
+		if packet.ChannelID == common.ChannelIDFlowControl {
+			var update windowUpdate
+			rlp.DecodeBytes(packet.Bytes, &update)
+			continue
+		}
+		recvBuf, ok := recvBufs[packet.ChannelID]
+		if !ok {
+			buf := createRecvBuffer(getDefaultRecvBufferConfig())
+			recvBuf = &buf
+			recvBufs[packet.ChannelID] = recvBuf
+		}
+		msgBytes, success := recvBuf.receivePacket(&packet)
+		if !success || msgBytes == nil {
+			continue
+		}
+		conn.decompressMessage(packet.ChannelID, msgBytes)
+	}
+
+	if numPackets == 0 {
+		return 0
+	}
+	return 1
+}
@@ -2,14 +2,17 @@ package connection
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
 )
 
 const (
 	maxPayloadSize        = 1024 // 1k bytes
 	maxAdditionalDataSize = 10
 	maxPacketTotalSize    = maxPayloadSize + maxAdditionalDataSize
+	maxEncodedPacketSize  = maxPacketTotalSize + 32 // payload and the RLP headers of the fields
 	packetTypePing        = byte(0x01)
 	packetTypePong        = byte(0x02)
 	packetTypeMsg         = byte(0x03)
@@ -29,3 +32,18 @@ func (p *Packet) isEmpty() bool {
 func (p Packet) String() string {
 	return fmt.Sprintf("Packet{%X:%X T:%X}", p.ChannelID, p.Bytes, p.IsEOF)
 }
+
+// readPacket decodes the next packet from the reader. A packet announcing more bytes than a
+// packet can hold is rejected before its bytes are read, rather than allocating the size it
+// announces.
+func readPacket(reader io.Reader) (Packet, error) {
+	var packet Packet
+	err := rlp.NewStream(reader, maxEncodedPacketSize).Decode(&packet)
+	if err != nil {
+		return packet, err
+	}
+	if len(packet.Bytes) > maxPayloadSize {
+		return packet, fmt.Errorf("Packet payload too large: %v bytes", len(packet.Bytes))
+	}
+	return packet, nil
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/rlp"
 )
@@ -80,3 +81,37 @@ func TestPacketRLPEncoding2(t *testing.T) {
 	assert.Equal(msgBytes, decodedPacket.Bytes)
 	assert.Equal(byte(0x01), decodedPacket.IsEOF)
 }
+
+func TestReadPacket(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// The packets are read one after the other from the stream
+	var buf bytes.Buffer
+	packets := []Packet{
+		{ChannelID: common.ChannelIDBlock, Bytes: make([]byte, maxPayloadSize), SeqID: 1<<64 - 1},
+		{ChannelID: common.ChannelIDVote, Bytes: []byte("hello"), IsEOF: byte(0x01)},
+	}
+	for _, packet := range packets {
+		require.Nil(rlp.Encode(&buf, packet))
+	}
+	for _, packet := range packets {
+		decoded, err := readPacket(&buf)
+		require.Nil(err)
+		assert.Equal(packet, decoded)
+	}
+
+	// A packet announcing a huge payload is rejected before reading it
+	oversized, err := rlp.EncodeToBytes(Packet{ChannelID: common.ChannelIDBlock, Bytes: make([]byte, 2*maxPayloadSize)})
+	require.Nil(err)
+	_, err = readPacket(bytes.NewBuffer(oversized))
+	assert.NotNil(err)
+	_, err = readPacket(bytes.NewBuffer([]byte{0xfb, 0xff, 0xff, 0xff, 0xff, 0xff}))
+	assert.NotNil(err)
+
+	// The payload is bounded even if the encoding fits
+	tooLong, err := rlp.EncodeToBytes(Packet{ChannelID: common.ChannelIDBlock, Bytes: make([]byte, maxPayloadSize+10)})
+	require.Nil(err)
+	_, err = readPacket(bytes.NewBuffer(tooLong))
+	assert.NotNil(err)
+}
@@ -1,5 +1,9 @@
 package connection
 
+// maxMessageSize bounds the messages assembled from the packets, so that a peer cannot exhaust
+// the memory with a message which never ends.
+const maxMessageSize = maxDecompressedMessageSize
+
 type RecvBuffer struct {
 	workspace []byte
 
@@ -29,10 +33,12 @@ func getDefaultRecvBufferConfig() RecvBufferConfig {
 // receivePacket handles incoming msgPackets. It returns a msg bytes if msg is
 // complete (i.e. ends with EOF). It is not goroutine safe
 func (rb *RecvBuffer) receivePacket(packet *Packet) ([]byte, bool) {
-	// Here we disable the check below to allow arbitrarily long message
-	// if len(rb.workspace)+len(packet.Bytes) > rb.config.workspaceCapacity {
-	// 	return nil, false
-	// }
+	// The workspace grows beyond its capacity for the long messages, up to maxMessageSize
+	if len(rb.workspace)+len(packet.Bytes) > maxMessageSize {
+		rb.workspace = make([]byte, 0, rb.config.workspaceCapacity) // releases the memory
+		rb.chanSeq = 0
+		return nil, false
+	}
 
 	// Note: We do NOT need to worry about the order of the packets.
 	//       TCP guarantees that if bytes arrive, they will be in the
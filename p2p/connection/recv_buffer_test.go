@@ -145,6 +145,41 @@ func TestRecvExtraLongMessage(t *testing.T) {
 	assert.True(sameBytes)
 }
 
+func TestRecvOversizedMessage(t *testing.T) {
+	assert := assert.New(t)
+	drb := newTestDefaultRecvBuffer()
+
+	packet := &Packet{
+		ChannelID: common.ChannelIDTransaction,
+		Bytes:     make([]byte, maxPayloadSize),
+		IsEOF:     byte(0x00),
+	}
+	var i uint
+	for ; i < maxMessageSize/maxPayloadSize; i++ {
+		packet.SeqID = i
+		recvBytes, success := drb.receivePacket(packet)
+		assert.True(success)
+		assert.Nil(recvBytes)
+	}
+
+	// The message is discarded once it exceeds the max size
+	packet.SeqID = i
+	recvBytes, success := drb.receivePacket(packet)
+	assert.False(success)
+	assert.Nil(recvBytes)
+	assert.Equal(0, len(drb.workspace))
+
+	// The next message is received
+	endPacket := &Packet{
+		ChannelID: common.ChannelIDTransaction,
+		Bytes:     []byte("hello"),
+		IsEOF:     byte(0x01),
+	}
+	recvBytes, success = drb.receivePacket(endPacket)
+	assert.True(success)
+	assert.Equal([]byte("hello"), recvBytes)
+}
+
 // --------------- Test Utilities --------------- //
 
 func newTestDefaultRecvBuffer() RecvBuffer {
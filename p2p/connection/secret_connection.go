@@ -0,0 +1,313 @@
+package connection
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	cmn "github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+const (
+	secretConnKeyInfo             = "THETA_SECRET_CONNECTION_KEY_AND_CHALLENGE"
+	secretConnMaxFrameSize        = 16 * 1024 // max plaintext bytes per frame
+	secretConnFrameLenSize        = 4
+	secretConnMaxHandshakeMsgSize = 1024
+	secretConnKeySize             = 32
+	secretConnChallengeSize       = 32
+)
+
+//
+// SecretConnection wraps a network connection, encrypting and authenticating all the traffic.
+// The handshake is station-to-station like: the two sides exchange ephemeral ECDH keys to
+// derive the symmetric keys and a challenge, and then prove the ownership of their node keys
+// by signing the challenge over the encrypted channel. Since the challenge depends on both
+// ephemeral keys, a man in the middle cannot relay the signatures.
+//
+type SecretConnection struct {
+	conn         net.Conn
+	remotePubKey *crypto.PublicKey
+
+	recvMtx    sync.Mutex
+	recvAEAD   cipher.AEAD
+	recvNonce  uint64
+	recvBuffer []byte
+
+	sendMtx   sync.Mutex
+	sendAEAD  cipher.AEAD
+	sendNonce uint64
+}
+
+// authSigMessage proves the ownership of the node key
+type authSigMessage struct {
+	PubKey cmn.Bytes
+	Sig    cmn.Bytes
+}
+
+// MakeSecretConnection performs the handshake over the given connection, and returns the
+// encrypted connection once the remote node proved the ownership of its node key.
+func MakeSecretConnection(conn net.Conn, privKey *crypto.PrivateKey) (*SecretConnection, error) {
+	// Exchange the ephemeral keys
+	ephPriv, ephX, ephY, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	localEphPub := elliptic.Marshal(elliptic.P256(), ephX, ephY)
+	remoteEphPub, err := exchange(conn, localEphPub)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to exchange ephemeral keys: %v", err)
+	}
+	remoteX, remoteY := elliptic.Unmarshal(elliptic.P256(), remoteEphPub)
+	if remoteX == nil {
+		return nil, errors.New("Invalid remote ephemeral key")
+	}
+	if bytes.Equal(localEphPub, remoteEphPub) {
+		return nil, errors.New("Remote ephemeral key is the same as the local one")
+	}
+
+	// Derive the keys of both directions and the challenge
+	sharedX, _ := elliptic.P256().ScalarMult(remoteX, remoteY, ephPriv)
+	loEphPub, hiEphPub := localEphPub, remoteEphPub
+	localIsLo := bytes.Compare(localEphPub, remoteEphPub) < 0
+	if !localIsLo {
+		loEphPub, hiEphPub = remoteEphPub, localEphPub
+	}
+	info := append(append([]byte(secretConnKeyInfo), loEphPub...), hiEphPub...)
+	okm := hkdfSha256(padTo32(sharedX), info, 2*secretConnKeySize+secretConnChallengeSize)
+	loKey := okm[:secretConnKeySize]
+	hiKey := okm[secretConnKeySize : 2*secretConnKeySize]
+	challenge := okm[2*secretConnKeySize:]
+
+	sendKey, recvKey := loKey, hiKey
+	if !localIsLo {
+		sendKey, recvKey = hiKey, loKey
+	}
+	sc := &SecretConnection{conn: conn}
+	if sc.sendAEAD, err = newAEAD(sendKey); err != nil {
+		return nil, err
+	}
+	if sc.recvAEAD, err = newAEAD(recvKey); err != nil {
+		return nil, err
+	}
+
+	// Authenticate the node keys over the encrypted channel
+	sig, err := privKey.Sign(challenge)
+	if err != nil {
+		return nil, err
+	}
+	localAuth, err := rlp.EncodeToBytes(authSigMessage{
+		PubKey: privKey.PublicKey().ToBytes(),
+		Sig:    sig.ToBytes(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	remoteAuthBytes, err := exchange(sc, localAuth)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to exchange auth signatures: %v", err)
+	}
+	remoteAuth := authSigMessage{}
+	if err := rlp.DecodeBytes(remoteAuthBytes, &remoteAuth); err != nil {
+		return nil, fmt.Errorf("Failed to decode auth signature: %v", err)
+	}
+	remotePubKey, err := crypto.PublicKeyFromBytes(remoteAuth.PubKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid remote node key: %v", err)
+	}
+	remoteSig, err := crypto.SignatureFromBytes(remoteAuth.Sig)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid remote auth signature: %v", err)
+	}
+	if !remotePubKey.VerifySignature(challenge, remoteSig) {
+		return nil, errors.New("Remote auth signature verification failed")
+	}
+	sc.remotePubKey = remotePubKey
+
+	return sc, nil
+}
+
+// RemotePubKey returns the authenticated node key of the remote peer
+func (sc *SecretConnection) RemotePubKey() *crypto.PublicKey {
+	return sc.remotePubKey
+}
+
+// Write encrypts the data and writes it to the underlying connection, in frames of at most
+// secretConnMaxFrameSize plaintext bytes.
+func (sc *SecretConnection) Write(data []byte) (n int, err error) {
+	sc.sendMtx.Lock()
+	defer sc.sendMtx.Unlock()
+
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > secretConnMaxFrameSize {
+			chunk = chunk[:secretConnMaxFrameSize]
+		}
+		sealed := sc.sendAEAD.Seal(nil, nonceBytes(sc.sendNonce), chunk, nil)
+		sc.sendNonce++
+
+		frame := make([]byte, secretConnFrameLenSize+len(sealed))
+		binary.BigEndian.PutUint32(frame, uint32(len(sealed)))
+		copy(frame[secretConnFrameLenSize:], sealed)
+		if _, err = sc.conn.Write(frame); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		data = data[len(chunk):]
+	}
+	return n, nil
+}
+
+// Read reads and decrypts data from the underlying connection
+func (sc *SecretConnection) Read(data []byte) (n int, err error) {
+	sc.recvMtx.Lock()
+	defer sc.recvMtx.Unlock()
+
+	if len(sc.recvBuffer) > 0 {
+		n = copy(data, sc.recvBuffer)
+		sc.recvBuffer = sc.recvBuffer[n:]
+		return n, nil
+	}
+
+	lenBytes := make([]byte, secretConnFrameLenSize)
+	if _, err = io.ReadFull(sc.conn, lenBytes); err != nil {
+		return 0, err
+	}
+	sealedLen := int(binary.BigEndian.Uint32(lenBytes))
+	if sealedLen > secretConnMaxFrameSize+sc.recvAEAD.Overhead() {
+		return 0, fmt.Errorf("Frame too large: %v", sealedLen)
+	}
+	sealed := make([]byte, sealedLen)
+	if _, err = io.ReadFull(sc.conn, sealed); err != nil {
+		return 0, err
+	}
+	plaintext, err := sc.recvAEAD.Open(nil, nonceBytes(sc.recvNonce), sealed, nil)
+	if err != nil {
+		return 0, errors.New("Failed to decrypt frame")
+	}
+	sc.recvNonce++
+
+	n = copy(data, plaintext)
+	sc.recvBuffer = plaintext[n:]
+	return n, nil
+}
+
+// ReadByte implements the io.ByteReader interface, so that the RLP decoder reads from the
+// connection without buffering bytes beyond the decoded value.
+func (sc *SecretConnection) ReadByte() (byte, error) {
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(sc, buf); err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// Close closes the underlying connection
+func (sc *SecretConnection) Close() error { return sc.conn.Close() }
+
+// LocalAddr returns the local address of the underlying connection
+func (sc *SecretConnection) LocalAddr() net.Addr { return sc.conn.LocalAddr() }
+
+// RemoteAddr returns the remote address of the underlying connection
+func (sc *SecretConnection) RemoteAddr() net.Addr { return sc.conn.RemoteAddr() }
+
+// SetDeadline sets the deadline of the underlying connection
+func (sc *SecretConnection) SetDeadline(t time.Time) error { return sc.conn.SetDeadline(t) }
+
+// SetReadDeadline sets the read deadline of the underlying connection
+func (sc *SecretConnection) SetReadDeadline(t time.Time) error { return sc.conn.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the write deadline of the underlying connection
+func (sc *SecretConnection) SetWriteDeadline(t time.Time) error {
+	return sc.conn.SetWriteDeadline(t)
+}
+
+// exchange sends the local bytes and receives the remote bytes concurrently. The messages are
+// length prefixed, so that no bytes beyond the remote message are consumed from the connection.
+func exchange(conn io.ReadWriter, local []byte) (remote []byte, err error) {
+	var sendErr, recvErr error
+	cmn.Parallel(
+		func() {
+			msg := make([]byte, secretConnFrameLenSize+len(local))
+			binary.BigEndian.PutUint32(msg, uint32(len(local)))
+			copy(msg[secretConnFrameLenSize:], local)
+			_, sendErr = conn.Write(msg)
+		},
+		func() {
+			lenBytes := make([]byte, secretConnFrameLenSize)
+			if _, recvErr = io.ReadFull(conn, lenBytes); recvErr != nil {
+				return
+			}
+			msgLen := binary.BigEndian.Uint32(lenBytes)
+			if msgLen > secretConnMaxHandshakeMsgSize {
+				recvErr = fmt.Errorf("Handshake message too large: %v", msgLen)
+				return
+			}
+			remote = make([]byte, msgLen)
+			_, recvErr = io.ReadFull(conn, remote)
+		},
+	)
+	if sendErr != nil {
+		return nil, sendErr
+	}
+	if recvErr != nil {
+		return nil, recvErr
+	}
+	return remote, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonceBytes converts the frame counter into a GCM nonce. Each direction uses its own key,
+// so the counters never repeat a nonce for the same key.
+func nonceBytes(counter uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], counter)
+	return nonce
+}
+
+// hkdfSha256 implements the HKDF key derivation function (RFC 5869) with SHA256 and an
+// empty salt.
+func hkdfSha256(secret, info []byte, length int) []byte {
+	extractor := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extractor.Write(secret)
+	prk := extractor.Sum(nil)
+
+	okm := []byte{}
+	prev := []byte{}
+	for counter := byte(1); len(okm) < length; counter++ {
+		expander := hmac.New(sha256.New, prk)
+		expander.Write(prev)
+		expander.Write(info)
+		expander.Write([]byte{counter})
+		prev = expander.Sum(nil)
+		okm = append(okm, prev...)
+	}
+	return okm[:length]
+}
+
+func padTo32(x *big.Int) []byte {
+	buf := make([]byte, 32)
+	b := x.Bytes()
+	copy(buf[32-len(b):], b)
+	return buf
+}
@@ -0,0 +1,101 @@
+package connection
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+func makeSecretConnectionPair(t *testing.T, privKeyA, privKeyB *crypto.PrivateKey, connA, connB net.Conn) (*SecretConnection, *SecretConnection, error, error) {
+	var scA, scB *SecretConnection
+	var errA, errB error
+	// Close the connection on failure, so that the other side does not block
+	common.Parallel(
+		func() {
+			if scA, errA = MakeSecretConnection(connA, privKeyA); errA != nil {
+				connA.Close()
+			}
+		},
+		func() {
+			if scB, errB = MakeSecretConnection(connB, privKeyB); errB != nil {
+				connB.Close()
+			}
+		},
+	)
+	return scA, scB, errA, errB
+}
+
+func TestSecretConnectionHandshakeAndTransfer(t *testing.T) {
+	assert := assert.New(t)
+
+	privKeyA := p2ptypes.GetTestRandPrivKey()
+	privKeyB := p2ptypes.GetTestRandPrivKey()
+	connA, connB := net.Pipe()
+	scA, scB, errA, errB := makeSecretConnectionPair(t, privKeyA, privKeyB, connA, connB)
+	assert.Nil(errA)
+	assert.Nil(errB)
+
+	// The remote node keys are authenticated
+	assert.Equal(privKeyB.PublicKey().Address(), scA.RemotePubKey().Address())
+	assert.Equal(privKeyA.PublicKey().Address(), scB.RemotePubKey().Address())
+
+	// Data larger than a frame is split and reassembled
+	msg := bytes.Repeat([]byte("theta"), secretConnMaxFrameSize)
+	go func() {
+		n, err := scA.Write(msg)
+		assert.Nil(err)
+		assert.Equal(len(msg), n)
+	}()
+	received := make([]byte, len(msg))
+	_, err := io.ReadFull(scB, received)
+	assert.Nil(err)
+	assert.Equal(msg, received)
+}
+
+// tamperingConn flips a bit in every write once enabled
+type tamperingConn struct {
+	net.Conn
+	enabled bool
+}
+
+func (tc *tamperingConn) Write(data []byte) (int, error) {
+	if tc.enabled {
+		tampered := append([]byte{}, data...)
+		tampered[len(tampered)-1] ^= 0x01
+		return tc.Conn.Write(tampered)
+	}
+	return tc.Conn.Write(data)
+}
+
+func TestSecretConnectionRejectsTamperedFrame(t *testing.T) {
+	assert := assert.New(t)
+
+	connA, connB := net.Pipe()
+	tcA := &tamperingConn{Conn: connA}
+	scA, scB, errA, errB := makeSecretConnectionPair(t,
+		p2ptypes.GetTestRandPrivKey(), p2ptypes.GetTestRandPrivKey(), tcA, connB)
+	assert.Nil(errA)
+	assert.Nil(errB)
+
+	tcA.enabled = true
+	go scA.Write([]byte("hello"))
+	buf := make([]byte, 5)
+	_, err := scB.Read(buf)
+	assert.NotNil(err)
+}
+
+func TestSecretConnectionRejectsTamperedHandshake(t *testing.T) {
+	assert := assert.New(t)
+
+	connA, connB := net.Pipe()
+	tcA := &tamperingConn{Conn: connA, enabled: true}
+	_, _, errA, errB := makeSecretConnectionPair(t,
+		p2ptypes.GetTestRandPrivKey(), p2ptypes.GetTestRandPrivKey(), tcA, connB)
+	assert.True(errA != nil || errB != nil)
+}
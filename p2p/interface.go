@@ -2,8 +2,10 @@ package p2p
 
 import (
 	"context"
+	"time"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/event"
 	"github.com/thetatoken/theta/p2p/types"
 )
 
@@ -25,6 +27,17 @@ type MessageHandler interface {
 	HandleMessage(message types.Message) error
 }
 
+//
+// MessageDowngrader is implemented by the message handlers of the channels requiring a
+// capability, which can convert their messages to the format of the peers lacking it
+//
+type MessageDowngrader interface {
+
+	// DowngradeMessage converts the message for a peer lacking the capability of its channel. It
+	// returns no message if the message cannot be conveyed to such a peer.
+	DowngradeMessage(message types.Message) []types.Message
+}
+
 //
 // Network is a handle to the P2P network
 //
@@ -51,3 +64,49 @@ type Network interface {
 	// ID returns the ID of the network peer
 	ID() string
 }
+
+//
+// PeerAdmin is implemented by the networks that let the node operator inspect and ban peers
+//
+type PeerAdmin interface {
+
+	// PeerInfos returns the information of the connected peers
+	PeerInfos() []types.PeerInfo
+
+	// BanPeer disconnects the peer and rejects its connections until the ban expires
+	BanPeer(peerID string, duration time.Duration) error
+
+	// UnbanPeer lifts the ban of the peer
+	UnbanPeer(peerID string) bool
+
+	// BannedPeers returns the banned peers and the time their ban expires
+	BannedPeers() map[string]time.Time
+}
+
+//
+// RateLimiter is implemented by the networks whose bandwidth limits can be changed at runtime
+//
+type RateLimiter interface {
+
+	// SetRateLimits changes the send and receive rates of each peer in bytes per second
+	SetRateLimits(sendRate, recvRate int64)
+}
+
+//
+// Reconnector is implemented by the networks which can reconnect to their seed peers, e.g. when
+// the node stops making progress after losing its connections
+//
+type Reconnector interface {
+
+	// ReconnectSeedPeers connects to the seed peers which are not connected
+	ReconnectSeedPeers()
+}
+
+//
+// EventPublisher is implemented by the networks which publish the connected peers to an event bus
+//
+type EventPublisher interface {
+
+	// SetEventBus sets the bus the connected peers are published to
+	SetEventBus(eventBus *event.Bus)
+}
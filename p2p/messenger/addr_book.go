@@ -15,6 +15,7 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	mm "github.com/thetatoken/theta/common/math"
 	"github.com/thetatoken/theta/crypto"
 	nu "github.com/thetatoken/theta/p2p/netutil"
+	pr "github.com/thetatoken/theta/p2p/peer"
 )
 
 const (
@@ -74,6 +76,13 @@ const (
 	// max addresses returned by GetSelection
 	// NOTE: this must match "maxPexMessageSize"
 	maxGetSelection = 250
+
+	// version of the address book file format. The files written before the format was
+	// versioned have version 0, and are migrated when loaded.
+	addrBookFileVersion = 1
+
+	// weight of the latest measurement in the smoothed dial latency, in %.
+	latencySmoothingPercent = 25
 )
 
 const (
@@ -257,6 +266,28 @@ func (a *AddrBook) MarkAttempt(addr *nu.NetAddress) {
 	ka.markAttempt()
 }
 
+// MarkFailed records a failed attempt to connect to the address.
+func (a *AddrBook) MarkFailed(addr *nu.NetAddress) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	ka := a.addrLookup[addr.String()]
+	if ka == nil {
+		return
+	}
+	ka.NumFailures++
+}
+
+// MarkLatency records the time it took to connect to the address, smoothed over the connections.
+func (a *AddrBook) MarkLatency(addr *nu.NetAddress, latency time.Duration) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	ka := a.addrLookup[addr.String()]
+	if ka == nil {
+		return
+	}
+	ka.markLatency(latency)
+}
+
 // MarkBad currently just ejects the address. In the future, consider
 // blacklisting.
 func (a *AddrBook) MarkBad(addr *nu.NetAddress) {
@@ -310,11 +341,203 @@ func (a *AddrBook) GetSelection() []*nu.NetAddress {
 	return allAddr[:numAddresses]
 }
 
+// GetSelectionWithLiveness randomly selects some addresses like GetSelection, together with
+// their liveness metadata. Addresses that were never reached after numRetries attempts are
+// not shared.
+func (a *AddrBook) GetSelectionWithLiveness() []PEXAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	allAddr := []*knownAddress{}
+	for _, ka := range a.addrLookup {
+		if ka.LastSuccess.IsZero() && ka.Attempts >= numRetries {
+			continue
+		}
+		allAddr = append(allAddr, ka)
+	}
+	if len(allAddr) == 0 {
+		return nil
+	}
+
+	numAddresses := mm.MaxInt(
+		mm.MinInt(minGetSelection, len(allAddr)),
+		len(allAddr)*getSelectionPercent/100)
+	numAddresses = mm.MinInt(maxGetSelection, numAddresses)
+
+	for i := 0; i < numAddresses; i++ {
+		j := a.rand.Intn(len(allAddr)-i) + i
+		allAddr[i], allAddr[j] = allAddr[j], allAddr[i]
+	}
+
+	selection := make([]PEXAddress, numAddresses)
+	for i, ka := range allAddr[:numAddresses] {
+		selection[i] = newPEXAddress(ka)
+	}
+	return selection
+}
+
+// AddPEXAddresses adds the addresses received from the src peer to the new buckets, and
+// returns the number of addresses accepted. The liveness metadata reported by the peer is
+// only used to skip the stale addresses, since it cannot be verified. Whether an address
+// moves to the old buckets depends on our own connection attempts.
+func (a *AddrBook) AddPEXAddresses(pexAddrs []PEXAddress, src *nu.NetAddress) int {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := time.Now()
+	numAdded := 0
+	for _, pexAddr := range pexAddrs {
+		if pexAddr.Addr == nil || !pexAddr.Addr.Valid() || pexAddr.isStale(now) {
+			continue
+		}
+		a.addAddress(pexAddr.Addr, src)
+		numAdded++
+	}
+	return numAdded
+}
+
+/* Crawling */
+
+// GetAddressesToCrawl returns up to maxNum addresses not crawled within the recrawl interval,
+// the ones never crawled or crawled the longest ago first.
+func (a *AddrBook) GetAddressesToCrawl(recrawlInterval time.Duration, maxNum int) []*nu.NetAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := time.Now()
+	candidates := []*knownAddress{}
+	for _, ka := range a.addrLookup {
+		if !ka.LastCrawled.IsZero() && now.Sub(ka.LastCrawled) < recrawlInterval {
+			continue
+		}
+		candidates = append(candidates, ka)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].LastCrawled.Before(candidates[j].LastCrawled)
+	})
+
+	numAddresses := mm.MinInt(maxNum, len(candidates))
+	addrs := make([]*nu.NetAddress, numAddresses)
+	for i := 0; i < numAddresses; i++ {
+		addrs[i] = candidates[i].Addr
+	}
+	return addrs
+}
+
+// MarkCrawlAttempt records an attempt to crawl the address. Addresses that failed maxFailures
+// times without ever being reached are removed.
+func (a *AddrBook) MarkCrawlAttempt(addr *nu.NetAddress) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	ka := a.addrLookup[addr.String()]
+	if ka == nil {
+		return
+	}
+	ka.markAttempt()
+	ka.LastCrawled = ka.LastAttempt
+	if ka.LastSuccess.IsZero() && ka.Attempts >= maxFailures {
+		logger.Infof("Remove unreachable address from book, addr: %v", addr)
+		a.removeFromAllBuckets(ka)
+	}
+}
+
+// MarkCrawled records the peer reached at the address by the crawler, with its software version
+// and the latency of establishing the connection.
+func (a *AddrBook) MarkCrawled(addr *nu.NetAddress, peerID string, version string, latency time.Duration) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	ka := a.addrLookup[addr.String()]
+	if ka == nil {
+		return
+	}
+	ka.markGood()
+	ka.LastCrawled = ka.LastSuccess
+	ka.ID = peerID
+	ka.Version = version
+	ka.markLatency(latency)
+	if ka.isNew() {
+		a.moveToOld(ka)
+	}
+}
+
+// GetReachableSelection randomly selects some of the peers the crawler reached within maxAge,
+// like GetSelection. Suitable for serving bootstrapping nodes.
+func (a *AddrBook) GetReachableSelection(maxAge time.Duration) []pr.PeerIDAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := time.Now()
+	reachable := []*knownAddress{}
+	for _, ka := range a.addrLookup {
+		if ka.ID == "" || ka.LastSuccess.IsZero() || now.Sub(ka.LastSuccess) > maxAge {
+			continue
+		}
+		reachable = append(reachable, ka)
+	}
+	if len(reachable) == 0 {
+		return nil
+	}
+
+	numAddresses := mm.MaxInt(
+		mm.MinInt(minGetSelection, len(reachable)),
+		len(reachable)*getSelectionPercent/100)
+	numAddresses = mm.MinInt(maxGetSelection, numAddresses)
+
+	for i := 0; i < numAddresses; i++ {
+		j := a.rand.Intn(len(reachable)-i) + i
+		reachable[i], reachable[j] = reachable[j], reachable[i]
+	}
+
+	selection := make([]pr.PeerIDAddress, numAddresses)
+	for i, ka := range reachable[:numAddresses] {
+		selection[i] = pr.PeerIDAddress{
+			ID:   ka.ID,
+			Addr: ka.Addr,
+		}
+	}
+	return selection
+}
+
+// GetReliableAddresses returns up to maxNum of the addresses connected to within numMissingDays,
+// the ones with the highest success rate first, then the most recently seen ones, then the ones
+// with the lowest latency. Suitable for reconnecting to the network on startup.
+func (a *AddrBook) GetReliableAddresses(maxNum int) []*nu.NetAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	minLastSeen := time.Now().Add(-numMissingDays * 24 * time.Hour)
+	candidates := []*knownAddress{}
+	for _, ka := range a.addrLookup {
+		if ka.NumSuccesses == 0 || ka.LastSeen.Before(minLastSeen) {
+			continue
+		}
+		candidates = append(candidates, ka)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ki, kj := candidates[i], candidates[j]
+		if ri, rj := ki.successRate(), kj.successRate(); ri != rj {
+			return ri > rj
+		}
+		if !ki.LastSeen.Equal(kj.LastSeen) {
+			return ki.LastSeen.After(kj.LastSeen)
+		}
+		return ki.Latency < kj.Latency
+	})
+
+	numAddresses := mm.MinInt(maxNum, len(candidates))
+	addrs := make([]*nu.NetAddress, numAddresses)
+	for i := 0; i < numAddresses; i++ {
+		addrs[i] = candidates[i].Addr
+	}
+	return addrs
+}
+
 /* Loading & Saving */
 
 type addrBookJSON struct {
-	Key   string
-	Addrs []*knownAddress
+	Version int
+	Key     string
+	Addrs   []*knownAddress
 }
 
 func (a *AddrBook) saveToFile(filePath string) {
@@ -329,8 +552,9 @@ func (a *AddrBook) saveToFile(filePath string) {
 	}
 
 	aJSON := &addrBookJSON{
-		Key:   a.key,
-		Addrs: addrs,
+		Version: addrBookFileVersion,
+		Key:     a.key,
+		Addrs:   addrs,
 	}
 
 	jsonBytes, err := json.MarshalIndent(aJSON, "", "\t")
@@ -365,6 +589,16 @@ func (a *AddrBook) loadFromFile(filePath string) bool {
 	if err != nil {
 		panic(fmt.Sprintf("Error reading file %s: %v", filePath, err))
 	}
+	if aJSON.Version > addrBookFileVersion {
+		logger.Warnf("Ignoring the address book file %s of the newer version %v", filePath, aJSON.Version)
+		return false
+	}
+	if aJSON.Version == 0 {
+		logger.Infof("Migrating the address book file %s to version %v", filePath, addrBookFileVersion)
+		for _, ka := range aJSON.Addrs {
+			ka.migrateFromVersion0()
+		}
+	}
 
 	// Restore all the fields...
 	// Restore the key
@@ -385,6 +619,13 @@ func (a *AddrBook) loadFromFile(filePath string) bool {
 	return true
 }
 
+// Load loads the book from the file, returns false if the file does not exist.
+func (a *AddrBook) Load() bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.loadFromFile(a.filePath)
+}
+
 // Save saves the book.
 func (a *AddrBook) Save() {
 	logger.Infof("Saving AddrBook to file, size: %v", a.Size())
@@ -689,17 +930,17 @@ func (a *AddrBook) groupKey(na *nu.NetAddress) string {
 	}
 
 	if ipv4 := na.IP.To4(); ipv4 != nil {
-		return (&net.IPNet{IP: na.IP, Mask: net.CIDRMask(16, 32)}).String()
+		return groupNet(ipv4, 16, 32)
 	}
 	if na.RFC6145() || na.RFC6052() {
 		// last four bytes are the ip address
 		ip := net.IP(na.IP[12:16])
-		return (&net.IPNet{IP: ip, Mask: net.CIDRMask(16, 32)}).String()
+		return groupNet(ip, 16, 32)
 	}
 
 	if na.RFC3964() {
-		ip := net.IP(na.IP[2:7])
-		return (&net.IPNet{IP: ip, Mask: net.CIDRMask(16, 32)}).String()
+		ip := net.IP(na.IP[2:6])
+		return groupNet(ip, 16, 32)
 
 	}
 	if na.RFC4380() {
@@ -709,7 +950,7 @@ func (a *AddrBook) groupKey(na *nu.NetAddress) string {
 		for i, byte := range na.IP[12:16] {
 			ip[i] = byte ^ 0xff
 		}
-		return (&net.IPNet{IP: ip, Mask: net.CIDRMask(16, 32)}).String()
+		return groupNet(ip, 16, 32)
 	}
 
 	// OK, so now we know ourselves to be a IPv6 address.
@@ -722,7 +963,13 @@ func (a *AddrBook) groupKey(na *nu.NetAddress) string {
 		bits = 36
 	}
 
-	return (&net.IPNet{IP: na.IP, Mask: net.CIDRMask(bits, 128)}).String()
+	return groupNet(na.IP, bits, 128)
+}
+
+// groupNet returns the network of the given size the IP belongs to.
+func groupNet(ip net.IP, ones, bits int) string {
+	mask := net.CIDRMask(ones, bits)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
 }
 
 func (a *AddrBook) generateAddrBookKey() string {
@@ -751,6 +998,17 @@ type knownAddress struct {
 	LastSuccess time.Time
 	BucketType  byte
 	Buckets     []int
+
+	// Connection quality, kept across the restarts to dial the reliable peers first
+	LastSeen     time.Time     // last successful connection
+	NumSuccesses uint32        // successful connections
+	NumFailures  uint32        // failed connection attempts
+	Latency      time.Duration // smoothed time to connect
+
+	// Recorded by the crawler
+	LastCrawled time.Time
+	ID          string
+	Version     string
 }
 
 func newKnownAddress(addr *nu.NetAddress, src *nu.NetAddress) *knownAddress {
@@ -783,6 +1041,35 @@ func (ka *knownAddress) markGood() {
 	ka.LastAttempt = now
 	ka.Attempts = 0
 	ka.LastSuccess = now
+	ka.LastSeen = now
+	ka.NumSuccesses++
+}
+
+func (ka *knownAddress) markLatency(latency time.Duration) {
+	if ka.Latency == 0 {
+		ka.Latency = latency
+		return
+	}
+	ka.Latency = (ka.Latency*(100-latencySmoothingPercent) + latency*latencySmoothingPercent) / 100
+}
+
+// successRate returns the ratio of the successful connections to the connection attempts
+func (ka *knownAddress) successRate() float64 {
+	total := ka.NumSuccesses + ka.NumFailures
+	if total == 0 {
+		return 0
+	}
+	return float64(ka.NumSuccesses) / float64(total)
+}
+
+// migrateFromVersion0 derives the connection quality of an address loaded from a file written
+// before the quality was recorded
+func (ka *knownAddress) migrateFromVersion0() {
+	if ka.LastSuccess.IsZero() {
+		return
+	}
+	ka.LastSeen = ka.LastSuccess
+	ka.NumSuccesses = 1
 }
 
 func (ka *knownAddress) addBucketRef(bucketIdx int) int {
@@ -3,10 +3,12 @@
 package messenger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/thetatoken/theta/p2p/netutil"
@@ -167,3 +169,165 @@ func TestAddrBookRemoveAddress(t *testing.T) {
 	book.RemoveAddress(nonExistingAddr)
 	assert.Equal(t, 0, book.Size())
 }
+
+func TestAddrBookCrawl(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+
+	book := NewAddrBook(fname, true)
+	randAddrs := randNetAddressPairs(t, 10)
+	for _, addrSrc := range randAddrs {
+		book.AddAddress(addrSrc.addr, addrSrc.src)
+	}
+
+	// All the addresses are due for a crawl
+	toCrawl := book.GetAddressesToCrawl(time.Hour, 4)
+	assert.Equal(4, len(toCrawl))
+	assert.Equal(10, len(book.GetAddressesToCrawl(time.Hour, 100)))
+	assert.Nil(book.GetReachableSelection(time.Hour))
+
+	reached := randAddrs[0].addr
+	book.MarkCrawled(reached, "peer0", "v1.0.0", 50*time.Millisecond)
+	unreached := randAddrs[1].addr
+	book.MarkCrawlAttempt(unreached)
+
+	// Recently crawled addresses are skipped
+	assert.Equal(8, len(book.GetAddressesToCrawl(time.Hour, 100)))
+	assert.Equal(10, len(book.GetAddressesToCrawl(0, 100)))
+
+	selection := book.GetReachableSelection(time.Hour)
+	assert.Equal(1, len(selection))
+	assert.Equal("peer0", selection[0].ID)
+	assert.Equal(reached.String(), selection[0].Addr.String())
+
+	ka := book.addrLookup[reached.String()]
+	assert.True(ka.isOld())
+	assert.Equal("v1.0.0", ka.Version)
+	assert.Equal(50*time.Millisecond, ka.Latency)
+
+	// The crawl results are persisted
+	book.saveToFile(fname)
+	book = NewAddrBook(fname, true)
+	book.loadFromFile(fname)
+	assert.Equal(1, len(book.GetReachableSelection(time.Hour)))
+
+	// Addresses never reached are eventually removed
+	for i := 0; i < maxFailures; i++ {
+		book.MarkCrawlAttempt(unreached)
+	}
+	assert.Equal(9, book.Size())
+}
+
+func TestAddrBookReliableAddresses(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+
+	book := NewAddrBook(fname, true)
+	randAddrs := randNetAddressPairs(t, 4)
+	for _, addrSrc := range randAddrs {
+		book.AddAddress(addrSrc.addr, addrSrc.src)
+	}
+	a0, a1, a2 := randAddrs[0].addr, randAddrs[1].addr, randAddrs[2].addr
+	assert.Equal(0, len(book.GetReliableAddresses(10)))
+
+	// a0 always succeeded, a1 failed once, a2 succeeded as often but is slower to connect
+	for i := 0; i < 3; i++ {
+		book.MarkGood(a0)
+		book.MarkGood(a1)
+	}
+	book.MarkFailed(a1)
+	book.MarkGood(a2)
+	book.MarkGood(a2)
+	book.MarkLatency(a0, 300*time.Millisecond)
+	book.MarkLatency(a2, 100*time.Millisecond)
+	book.MarkLatency(a2, 500*time.Millisecond)
+	assert.Equal(200*time.Millisecond, book.addrLookup[a2.String()].Latency)
+
+	reliable := book.GetReliableAddresses(10)
+	assert.Equal(3, len(reliable))
+	assert.Equal(a1.String(), reliable[2].String())
+	assert.Equal(1, len(book.GetReliableAddresses(1)))
+
+	// The quality is persisted in the versioned file format
+	book.saveToFile(fname)
+	book = NewAddrBook(fname, true)
+	assert.True(book.loadFromFile(fname))
+	ka := book.addrLookup[a1.String()]
+	assert.Equal(uint32(3), ka.NumSuccesses)
+	assert.Equal(uint32(1), ka.NumFailures)
+	assert.False(ka.LastSeen.IsZero())
+	assert.Equal(3, len(book.GetReliableAddresses(10)))
+}
+
+func TestAddrBookLoadVersion0(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+
+	randAddrs := randNetAddressPairs(t, 2)
+	lastSuccess := time.Now().Add(-time.Hour)
+	legacy := struct {
+		Key   string
+		Addrs []*knownAddress
+	}{
+		Key: "abc",
+		Addrs: []*knownAddress{
+			{Addr: randAddrs[0].addr, Src: randAddrs[0].src, BucketType: bucketTypeNew, Buckets: []int{1}, LastSuccess: lastSuccess},
+			{Addr: randAddrs[1].addr, Src: randAddrs[1].src, BucketType: bucketTypeNew, Buckets: []int{2}},
+		},
+	}
+	legacyJSON, err := json.Marshal(legacy)
+	assert.Nil(err)
+	assert.Nil(ioutil.WriteFile(fname, legacyJSON, 0644))
+
+	// The addresses reached before the migration count as reliable
+	book := NewAddrBook(fname, true)
+	assert.True(book.loadFromFile(fname))
+	assert.Equal(2, book.Size())
+	reliable := book.GetReliableAddresses(10)
+	assert.Equal(1, len(reliable))
+	assert.Equal(randAddrs[0].addr.String(), reliable[0].String())
+	assert.True(lastSuccess.Equal(book.addrLookup[randAddrs[0].addr.String()].LastSeen))
+
+	// The files of a newer format are ignored
+	assert.Nil(ioutil.WriteFile(fname, []byte(`{"Version": 100, "Key": "abc", "Addrs": []}`), 0644))
+	book = NewAddrBook(fname, true)
+	assert.False(book.loadFromFile(fname))
+}
+
+func TestAddrBookIPv6(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+
+	book := NewAddrBook(fname, true)
+	src, _ := netutil.NewNetAddressString("[2001:4860::1]:50001")
+	addrStrs := []string{
+		"[2001:4860::8888]:50001",
+		"[2a00:1450::1]:50001",
+		"[2002:c000:204::1]:50001", // 6to4
+		"8.8.8.8:50001",
+	}
+	for _, addrStr := range addrStrs {
+		addr, err := netutil.NewNetAddressString(addrStr)
+		assert.Nil(err)
+		book.AddAddress(addr, src)
+	}
+	assert.Equal(len(addrStrs), book.Size())
+
+	// Not routable
+	linkLocal, _ := netutil.NewNetAddressString("[fe80::1]:50001")
+	book.AddAddress(linkLocal, src)
+	assert.Equal(len(addrStrs), book.Size())
+
+	// The 6to4 addresses are grouped by their embedded IPv4 address
+	sixToFour, _ := netutil.NewNetAddressString("[2002:c000:204::1]:50001")
+	assert.Equal("192.0.0.0/16", book.groupKey(sixToFour))
+
+	book.saveToFile(fname)
+	book = NewAddrBook(fname, true)
+	book.loadFromFile(fname)
+	assert.Equal(len(addrStrs), book.Size())
+	for _, addrStr := range addrStrs {
+		_, exists := book.addrLookup[addrStr]
+		assert.True(exists, addrStr)
+	}
+}
@@ -0,0 +1,74 @@
+package messenger
+
+import (
+	"sync"
+	"time"
+)
+
+//
+// banList holds the peers banned by the node operator, until their ban expires
+//
+type banList struct {
+	mutex  *sync.Mutex
+	expiry map[string]time.Time // map: peerID |-> time the ban expires
+}
+
+func newBanList() *banList {
+	return &banList{
+		mutex:  &sync.Mutex{},
+		expiry: make(map[string]time.Time),
+	}
+}
+
+// Ban bans the peer for the given duration
+func (bl *banList) Ban(peerID string, duration time.Duration) time.Time {
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+
+	until := time.Now().Add(duration)
+	bl.expiry[peerID] = until
+	return until
+}
+
+// Unban lifts the ban of the peer
+func (bl *banList) Unban(peerID string) bool {
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+
+	_, ok := bl.expiry[peerID]
+	delete(bl.expiry, peerID)
+	return ok
+}
+
+// IsBanned indicates whether the peer is currently banned
+func (bl *banList) IsBanned(peerID string) bool {
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+
+	until, ok := bl.expiry[peerID]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(bl.expiry, peerID)
+		return false
+	}
+	return true
+}
+
+// BannedPeers returns the peers currently banned and the time their ban expires
+func (bl *banList) BannedPeers() map[string]time.Time {
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+
+	now := time.Now()
+	banned := make(map[string]time.Time)
+	for peerID, until := range bl.expiry {
+		if now.After(until) {
+			delete(bl.expiry, peerID)
+			continue
+		}
+		banned[peerID] = until
+	}
+	return banned
+}
@@ -0,0 +1,29 @@
+package messenger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBanList(t *testing.T) {
+	assert := assert.New(t)
+
+	bl := newBanList()
+	assert.False(bl.IsBanned("peer1"))
+
+	bl.Ban("peer1", time.Hour)
+	bl.Ban("peer2", -time.Second) // already expired
+	assert.True(bl.IsBanned("peer1"))
+	assert.False(bl.IsBanned("peer2"))
+
+	banned := bl.BannedPeers()
+	assert.Equal(1, len(banned))
+	_, ok := banned["peer1"]
+	assert.True(ok)
+
+	assert.True(bl.Unban("peer1"))
+	assert.False(bl.Unban("peer1"))
+	assert.False(bl.IsBanned("peer1"))
+}
@@ -0,0 +1,186 @@
+package messenger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p/netutil"
+	pr "github.com/thetatoken/theta/p2p/peer"
+)
+
+const (
+	maxCrawlsPerRound  = 16               // max addresses crawled per round
+	recrawlInterval    = 10 * time.Minute // min interval between two crawls of the same address
+	crawledPeerTimeout = 2 * time.Minute  // time a crawled or bootstrapping peer stays connected
+	maxCrawledPeerAge  = time.Hour        // peers reached longer ago are not served
+)
+
+//
+// PeerCrawler continuously walks the network when the node runs in crawler mode. It connects
+// to the addresses in the address book, records the reachable peers with their versions and
+// latencies, and asks them for more addresses. Instead of keeping the connections, it
+// disconnects the peers after a while, so that a dedicated seed node can serve the reachable
+// peers to many bootstrapping nodes.
+//
+type PeerCrawler struct {
+	discMgr  *PeerDiscoveryManager
+	enabled  bool
+	interval time.Duration
+
+	mu          *sync.Mutex
+	connectedAt map[string]time.Time // peerID |-> time the peer connected
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	quit    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// createPeerCrawler creates an instance of PeerCrawler
+func createPeerCrawler(discMgr *PeerDiscoveryManager) PeerCrawler {
+	return PeerCrawler{
+		discMgr:     discMgr,
+		enabled:     viper.GetBool(common.CfgP2PCrawlerMode),
+		interval:    time.Duration(viper.GetInt(common.CfgP2PCrawlInterval)) * time.Second,
+		mu:          &sync.Mutex{},
+		connectedAt: make(map[string]time.Time),
+		wg:          &sync.WaitGroup{},
+	}
+}
+
+// Start is called when the PeerCrawler starts
+func (pc *PeerCrawler) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	pc.ctx = c
+	pc.cancel = cancel
+
+	if pc.enabled {
+		logger.Infof("Running in crawler mode")
+		pc.wg.Add(1)
+		go pc.crawlRoutine()
+	}
+
+	return nil
+}
+
+// Stop is called when the PeerCrawler stops
+func (pc *PeerCrawler) Stop() {
+	pc.cancel()
+}
+
+// Wait suspends the caller goroutine
+func (pc *PeerCrawler) Wait() {
+	pc.wg.Wait()
+}
+
+// IsEnabled returns whether the node runs in crawler mode
+func (pc *PeerCrawler) IsEnabled() bool {
+	return pc.enabled
+}
+
+// trackPeer records the time the peer connected, to disconnect it after crawledPeerTimeout
+func (pc *PeerCrawler) trackPeer(peer *pr.Peer) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.connectedAt[peer.ID()] = time.Now()
+}
+
+func (pc *PeerCrawler) crawlRoutine() {
+	defer pc.wg.Done()
+
+	ticker := time.NewTicker(pc.interval)
+	defer ticker.Stop()
+
+	pc.crawl()
+	for {
+		select {
+		case <-pc.ctx.Done():
+			pc.stopped = true
+			return
+		case <-ticker.C:
+			pc.disconnectExpiredPeers()
+			pc.crawl()
+		}
+	}
+}
+
+// crawl connects to the addresses due for a crawl in parallel, and waits for the attempts
+// to complete.
+func (pc *PeerCrawler) crawl() {
+	connected := make(map[string]bool)
+	for _, peer := range *(pc.discMgr.peerTable.GetAllPeers()) {
+		if addr := peer.NetAddress(); addr != nil {
+			connected[addr.String()] = true
+		}
+	}
+
+	addrs := pc.discMgr.addrBook.GetAddressesToCrawl(recrawlInterval, maxCrawlsPerRound)
+	wg := &sync.WaitGroup{}
+	for _, addr := range addrs {
+		if connected[addr.String()] {
+			continue
+		}
+		wg.Add(1)
+		go func(addr *netutil.NetAddress) {
+			defer wg.Done()
+			pc.crawlAddress(addr)
+		}(addr)
+	}
+	wg.Wait()
+
+	pc.discMgr.addrBook.Save()
+	logger.Infof("Crawled %v addresses, address book size: %v", len(addrs), pc.discMgr.addrBook.Size())
+}
+
+func (pc *PeerCrawler) crawlAddress(addr *netutil.NetAddress) {
+	if pc.ctx.Err() != nil {
+		return
+	}
+
+	addrBook := pc.discMgr.addrBook
+	addrBook.MarkCrawlAttempt(addr)
+
+	start := time.Now()
+	peer, err := pc.discMgr.connectToOutboundPeer(addr, false)
+	if err != nil {
+		logger.Debugf("Failed to crawl %v: %v", addr.String(), err)
+		return
+	}
+	latency := time.Since(start)
+
+	addrBook.MarkCrawled(addr, peer.ID(), peer.NodeVersion(), latency)
+	pc.trackPeer(peer)
+	pc.discMgr.peerDiscMsgHandler.requestAddresses(peer)
+
+	logger.Debugf("Crawled peer %v at %v, version: %v, latency: %v", peer.ID(), addr.String(), peer.NodeVersion(), latency)
+}
+
+// disconnectExpiredPeers disconnects the peers connected for longer than crawledPeerTimeout.
+// The persistent peers, e.g. the seed peers, are kept.
+func (pc *PeerCrawler) disconnectExpiredPeers() {
+	now := time.Now()
+	expired := []string{}
+	pc.mu.Lock()
+	for peerID, connectedAt := range pc.connectedAt {
+		if now.Sub(connectedAt) >= crawledPeerTimeout {
+			expired = append(expired, peerID)
+			delete(pc.connectedAt, peerID)
+		}
+	}
+	pc.mu.Unlock()
+
+	for _, peerID := range expired {
+		peer := pc.discMgr.peerTable.GetPeer(peerID)
+		if peer == nil || peer.IsPersistent() {
+			continue
+		}
+		pc.discMgr.peerTable.DeletePeer(peerID)
+		peer.Stop()
+	}
+}
@@ -183,7 +183,7 @@ func getExternalNetAddress(localAddrIP string, localAddrPort int, listenerPort i
 	var externalAddr *netutil.NetAddress
 	if !skipUPNP {
 		// If the lAddrIP is INADDR_ANY, try UPNP
-		if localAddrIP == "" || localAddrIP == "0.0.0.0" {
+		if localAddrIP == "" || localAddrIP == "0.0.0.0" || localAddrIP == "::" {
 			externalAddr = getUPNPExternalAddress(localAddrPort, listenerPort)
 		}
 	}
@@ -226,22 +226,26 @@ func getUPNPExternalAddress(externalPort, internalPort int) *netutil.NetAddress
 	return netutil.NewNetAddressIPPort(ext, uint16(externalPort))
 }
 
+// getNaiveExternalAddress returns the address of the first non-loopback IPv4 interface, or
+// of the first global IPv6 interface on the IPv6-only hosts.
 func getNaiveExternalAddress(port int) *netutil.NetAddress {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
 		panic(fmt.Sprintf("Could not fetch interface addresses: %v", err))
 	}
 
+	var ipv6Addr *netutil.NetAddress
 	for _, a := range addrs {
 		ipnet, ok := a.(*net.IPNet)
-		if !ok {
+		if !ok || ipnet.IP.IsLoopback() {
 			continue
 		}
-		v4 := ipnet.IP.To4()
-		if v4 == nil || v4[0] == 127 {
-			continue
-		} // loopback
-		return netutil.NewNetAddressIPPort(ipnet.IP, uint16(port))
+		if ipnet.IP.To4() != nil {
+			return netutil.NewNetAddressIPPort(ipnet.IP, uint16(port))
+		}
+		if ipv6Addr == nil && ipnet.IP.IsGlobalUnicast() {
+			ipv6Addr = netutil.NewNetAddressIPPort(ipnet.IP, uint16(port))
+		}
 	}
-	return nil
+	return ipv6Addr
 }
@@ -146,6 +146,10 @@ func (pdmh *PeerDiscoveryMessageHandler) HandleMessage(msg types.Message) error
 		return errors.New(errMsg)
 	}
 
+	if pdmh.discMgr.privatePeering {
+		return nil // a private node neither shares nor collects peer addresses
+	}
+
 	discMsg := (msg.Content).(PeerDiscoveryMessage)
 	switch discMsg.Type {
 	case peerAddressesRequestType:
@@ -162,11 +166,28 @@ func (pdmh *PeerDiscoveryMessageHandler) HandleMessage(msg types.Message) error
 }
 
 func (pdmh *PeerDiscoveryMessageHandler) handlePeerAddressRequest(peer *pr.Peer, message PeerDiscoveryMessage) {
-	peerIDAddrs := pdmh.discMgr.peerTable.GetSelection()
+	var peerIDAddrs []pr.PeerIDAddress
+	if pdmh.discMgr.crawler.IsEnabled() {
+		// A crawler keeps few connections, and serves the peers it recently reached instead
+		peerIDAddrs = pdmh.discMgr.addrBook.GetReachableSelection(maxCrawledPeerAge)
+	}
+	if len(peerIDAddrs) == 0 {
+		peerIDAddrs = pdmh.discMgr.peerTable.GetSelection()
+	}
 	pdmh.sendAddresses(peer, peerIDAddrs)
 }
 
 func (pdmh *PeerDiscoveryMessageHandler) handlePeerAddressReply(peer *pr.Peer, message PeerDiscoveryMessage) {
+	if pdmh.discMgr.crawler.IsEnabled() {
+		// The crawler connects to the addresses in its own rounds
+		for _, idAddr := range message.Addresses {
+			if idAddr.Addr != nil && idAddr.Addr.Valid() && pdmh.discMgr.messenger.ID() != idAddr.ID {
+				pdmh.discMgr.addrBook.AddAddress(idAddr.Addr, peer.NetAddress())
+			}
+		}
+		return
+	}
+
 	validAddressMap := make(map[*netutil.NetAddress]bool)
 	for _, idAddr := range message.Addresses {
 		isNotASeedPeer := !pdmh.discMgr.seedPeerConnector.isASeedPeer(idAddr.Addr)
@@ -198,6 +219,9 @@ func (pdmh *PeerDiscoveryMessageHandler) SetDiscoveryCallback(disccb InboundCall
 func (pdmh *PeerDiscoveryMessageHandler) connectToOutboundPeers(addresses []*netutil.NetAddress) {
 	numPeers := int(pdmh.discMgr.peerTable.GetTotalNumPeers())
 	numNeeded := int(GetDefaultPeerDiscoveryManagerConfig().MaxNumPeers) - numPeers
+	if numSlots := pdmh.discMgr.numOutboundSlots(); numNeeded > numSlots {
+		numNeeded = numSlots
+	}
 	if numNeeded > 0 {
 		numToAdd := len(addresses) * peersAddressesSubSamplingPercent / 100
 		if numToAdd < 1 {
@@ -241,6 +265,9 @@ func (pdmh *PeerDiscoveryMessageHandler) maintainSufficientConnectivityRoutine()
 // of connections by dialing peers when the number of connected peers are lower than the
 // required threshold
 func (pdmh *PeerDiscoveryMessageHandler) maintainSufficientConnectivity() {
+	if pdmh.discMgr.privatePeering {
+		return // the persistent peer connector keeps the only peers connected
+	}
 	numPeers := pdmh.discMgr.peerTable.GetTotalNumPeers()
 	if numPeers > 0 {
 		if numPeers < GetDefaultPeerDiscoveryManagerConfig().SufficientNumPeers {
@@ -277,6 +304,10 @@ func (pdmh *PeerDiscoveryMessageHandler) sendAddresses(peer *pr.Peer, peerIDAddr
 }
 
 func decodePeerDiscoveryMessage(msgBytes common.Bytes) (message PeerDiscoveryMessage, err error) {
+	if len(msgBytes) > maxPeerDiscoveryMessageSize {
+		err = fmt.Errorf("PeerDiscoveryMessage too large: %v bytes", len(msgBytes))
+		return
+	}
 	err = rlp.DecodeBytes(msgBytes, &message)
 	return
 }
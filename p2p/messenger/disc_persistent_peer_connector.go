@@ -0,0 +1,151 @@
+package messenger
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/p2p/netutil"
+)
+
+const (
+	persistentPeerCheckInterval = 5 * time.Second // interval between two checks of a connected persistent peer
+	persistentPeerMinBackoff    = 1 * time.Second
+	persistentPeerMaxBackoff    = 5 * time.Minute
+)
+
+//
+// PersistentPeerConnector keeps the node connected to the persistent peers, e.g. the validator
+// behind a sentry node. It redials a disconnected persistent peer with an exponential backoff,
+// and the persistent peers are never evicted nor count against the peer limits.
+//
+type PersistentPeerConnector struct {
+	discMgr *PeerDiscoveryManager
+
+	selfNetAddress             netutil.NetAddress
+	persistentPeerNetAddresses []netutil.NetAddress
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	quit    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// createPersistentPeerConnector creates an instance of the PersistentPeerConnector
+func createPersistentPeerConnector(discMgr *PeerDiscoveryManager,
+	selfNetAddressStr string) (PersistentPeerConnector, error) {
+	ppc := PersistentPeerConnector{
+		discMgr: discMgr,
+		wg:      &sync.WaitGroup{},
+	}
+
+	selfNetAddress, err := netutil.NewNetAddressString(selfNetAddressStr)
+	if err != nil {
+		logger.Errorf("Failed to parse the self network address: %v", selfNetAddressStr)
+		return ppc, err
+	}
+	ppc.selfNetAddress = *selfNetAddress
+
+	return ppc, nil
+}
+
+// SetPersistentPeers sets the addresses of the persistent peers. It must be called before the
+// PersistentPeerConnector starts.
+func (ppc *PersistentPeerConnector) SetPersistentPeers(persistentPeerNetAddressStrs []string) error {
+	ppc.persistentPeerNetAddresses = nil
+	for _, persistentPeerNetAddressStr := range persistentPeerNetAddressStrs {
+		netAddress, err := netutil.NewNetAddressString(persistentPeerNetAddressStr)
+		if err != nil {
+			logger.Errorf("Failed to parse the persistent peer network address: %v", persistentPeerNetAddressStr)
+			return err
+		}
+		if netAddress.Equals(&ppc.selfNetAddress) {
+			continue
+		}
+		ppc.persistentPeerNetAddresses = append(ppc.persistentPeerNetAddresses, *netAddress)
+	}
+	return nil
+}
+
+// Start is called when the PersistentPeerConnector starts
+func (ppc *PersistentPeerConnector) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	ppc.ctx = c
+	ppc.cancel = cancel
+
+	for i := range ppc.persistentPeerNetAddresses {
+		ppc.wg.Add(1)
+		go ppc.maintainConnection(ppc.persistentPeerNetAddresses[i])
+	}
+	return nil
+}
+
+// Stop is called when the PersistentPeerConnector stops
+func (ppc *PersistentPeerConnector) Stop() {
+	ppc.cancel()
+}
+
+// Wait suspends the caller goroutine
+func (ppc *PersistentPeerConnector) Wait() {
+	ppc.wg.Wait()
+}
+
+func (ppc *PersistentPeerConnector) isAPersistentPeer(netAddr *netutil.NetAddress) bool {
+	if netAddr == nil {
+		return false
+	}
+	for _, persistentAddr := range ppc.persistentPeerNetAddresses {
+		if netAddr.Equals(&persistentAddr) {
+			return true
+		}
+	}
+	return false
+}
+
+// maintainConnection dials the persistent peer whenever it is not connected, until the
+// connector stops. The delay between two failed dials doubles up to persistentPeerMaxBackoff,
+// and is reset once the peer is connected.
+func (ppc *PersistentPeerConnector) maintainConnection(peerNetAddress netutil.NetAddress) {
+	defer ppc.wg.Done()
+
+	backoff := persistentPeerMinBackoff
+	for {
+		delay := persistentPeerCheckInterval
+		if !ppc.discMgr.isConnected(&peerNetAddress) {
+			_, err := ppc.discMgr.connectToOutboundPeer(&peerNetAddress, true)
+			if err != nil {
+				delay = persistentPeerBackoffWithJitter(backoff)
+				logger.Warnf("Failed to connect to persistent peer %v, retrying in %v: %v",
+					peerNetAddress.String(), delay, err)
+				backoff = nextPersistentPeerBackoff(backoff)
+			} else {
+				logger.Infof("Successfully connected to persistent peer %v", peerNetAddress.String())
+				backoff = persistentPeerMinBackoff
+			}
+		}
+
+		select {
+		case <-ppc.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// nextPersistentPeerBackoff returns the delay before the dial following a failed one
+func nextPersistentPeerBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > persistentPeerMaxBackoff {
+		backoff = persistentPeerMaxBackoff
+	}
+	return backoff
+}
+
+// persistentPeerBackoffWithJitter randomizes the backoff by up to a quarter, so that the nodes
+// restarted together do not redial the peer in lockstep
+func persistentPeerBackoffWithJitter(backoff time.Duration) time.Duration {
+	return backoff + time.Duration(rand.Int63n(int64(backoff)/4+1))
+}
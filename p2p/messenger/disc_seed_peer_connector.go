@@ -17,6 +17,7 @@ type SeedPeerConnector struct {
 
 	selfNetAddress       netutil.NetAddress
 	seedPeerNetAddresses []netutil.NetAddress
+	dnsSeedResolver      *dnsSeedResolver // resolves the seed peers listed by the DNS seeds, nil if none
 
 	Connected chan bool
 
@@ -60,6 +61,15 @@ func createSeedPeerConnector(discMgr *PeerDiscoveryManager,
 	return spc, nil
 }
 
+// SetDNSSeeds sets the DNS seeds, resolved in addition to the static seed peers each time
+// the node connects to the seed peers. It must be called before the SeedPeerConnector starts.
+func (spc *SeedPeerConnector) SetDNSSeeds(dnsSeeds []string, defaultPort uint16) {
+	spc.dnsSeedResolver = createDNSSeedResolver(dnsSeeds, defaultPort)
+	if spc.dnsSeedResolver != nil {
+		spc.Connected = make(chan bool, len(spc.seedPeerNetAddresses)+maxDNSSeedPeers)
+	}
+}
+
 // Start is called when the SeedPeerConnector starts
 func (spc *SeedPeerConnector) Start(ctx context.Context) error {
 	c, cancel := context.WithCancel(ctx)
@@ -81,6 +91,38 @@ func (spc *SeedPeerConnector) Wait() {
 }
 
 func (spc *SeedPeerConnector) isASeedPeer(netAddr *netutil.NetAddress) bool {
+	if spc.isAStaticSeedPeer(netAddr) {
+		return true
+	}
+	if spc.dnsSeedResolver != nil {
+		for _, seedAddr := range spc.dnsSeedResolver.cachedAddresses() {
+			if netAddr.Equals(seedAddr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// seedPeers returns the static seed peers together with the next batch of the seed peers
+// resolved from the DNS seeds
+func (spc *SeedPeerConnector) seedPeers() []netutil.NetAddress {
+	seedPeers := spc.seedPeerNetAddresses
+	if spc.dnsSeedResolver == nil {
+		return seedPeers
+	}
+
+	seedPeers = append([]netutil.NetAddress{}, seedPeers...)
+	for _, addr := range spc.dnsSeedResolver.resolve() {
+		if addr.Equals(&spc.selfNetAddress) || spc.isAStaticSeedPeer(addr) {
+			continue
+		}
+		seedPeers = append(seedPeers, *addr)
+	}
+	return seedPeers
+}
+
+func (spc *SeedPeerConnector) isAStaticSeedPeer(netAddr *netutil.NetAddress) bool {
 	for _, seedAddr := range spc.seedPeerNetAddresses {
 		if netAddr.Equals(&seedAddr) {
 			return true
@@ -91,7 +133,24 @@ func (spc *SeedPeerConnector) isASeedPeer(netAddr *netutil.NetAddress) bool {
 
 func (spc *SeedPeerConnector) connectToSeedPeers() {
 	logger.Infof("Connecting to seed peers...")
-	perm := rand.Perm(len(spc.seedPeerNetAddresses))
+	spc.connectToPeers(spc.seedPeers())
+}
+
+// reconnectToSeedPeers connects to the seed peers which are not connected, e.g. after the
+// connections to them were lost.
+func (spc *SeedPeerConnector) reconnectToSeedPeers() {
+	disconnected := []netutil.NetAddress{}
+	for _, seedAddr := range spc.seedPeers() {
+		if !spc.discMgr.isConnected(&seedAddr) {
+			disconnected = append(disconnected, seedAddr)
+		}
+	}
+	logger.Infof("Reconnecting to %v seed peers...", len(disconnected))
+	spc.connectToPeers(disconnected)
+}
+
+func (spc *SeedPeerConnector) connectToPeers(seedPeers []netutil.NetAddress) {
+	perm := rand.Perm(len(seedPeers))
 	for i := 0; i < len(perm); i++ { // create outbound peers in a random order
 		spc.wg.Add(1)
 		go func(i int) {
@@ -99,15 +158,24 @@ func (spc *SeedPeerConnector) connectToSeedPeers() {
 
 			time.Sleep(time.Duration(rand.Int63n(3000)) * time.Millisecond)
 			j := perm[i]
-			peerNetAddress := spc.seedPeerNetAddresses[j]
+			peerNetAddress := seedPeers[j]
 			_, err := spc.discMgr.connectToOutboundPeer(&peerNetAddress, true)
 			if err != nil {
-				spc.Connected <- false
+				spc.notifyConnected(false)
 				logger.Warnf("Failed to connect to seed peer %v: %v", peerNetAddress.String(), err)
 			} else {
-				spc.Connected <- true
+				spc.notifyConnected(true)
 				logger.Infof("Successfully connected to seed peer %v", peerNetAddress.String())
 			}
 		}(i)
 	}
 }
+
+// notifyConnected reports the result of a connection attempt, without blocking once the
+// results of the earlier attempts fill up the channel.
+func (spc *SeedPeerConnector) notifyConnected(connected bool) {
+	select {
+	case spc.Connected <- connected:
+	default:
+	}
+}
@@ -3,16 +3,24 @@ package messenger
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"time"
 
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/event"
 	cn "github.com/thetatoken/theta/p2p/connection"
 	"github.com/thetatoken/theta/p2p/netutil"
 	pr "github.com/thetatoken/theta/p2p/peer"
 	p2ptypes "github.com/thetatoken/theta/p2p/types"
 )
 
+// maxReliablePeerDials is the max number of the reliable address book peers dialed on startup
+const maxReliablePeerDials = 16
+
 //
 // PeerDiscoveryManager manages the peer discovery process
 //
@@ -22,11 +30,25 @@ type PeerDiscoveryManager struct {
 	addrBook  *AddrBook
 	peerTable *pr.PeerTable
 	nodeInfo  *p2ptypes.NodeInfo
+	privKey   *crypto.PrivateKey // node key authenticating the peer connections
+	banList   *banList
+
+	// Mechanisms for peer discovery
+	seedPeerConnector       SeedPeerConnector           // pro-actively connect to seed peers
+	persistentPeerConnector PersistentPeerConnector     // keep the persistent peers connected
+	peerDiscMsgHandler      PeerDiscoveryMessageHandler // pro-actively connect to peer candidates obtained from connected peers
+	pexMsgHandler           PEXMessageHandler           // exchange address books with peers, and connect to peers in the address book
+	inboundPeerListener     InboundPeerListener         // listen to incoming peering requests
+
+	crawler PeerCrawler // walk the network in crawler mode
 
-	// Three mechanisms for peer discovery
-	seedPeerConnector   SeedPeerConnector           // pro-actively connect to seed peers
-	peerDiscMsgHandler  PeerDiscoveryMessageHandler // pro-actively connect to peer candidates obtained from connected peers
-	inboundPeerListener InboundPeerListener         // listen to incoming peering requests
+	// Peer limits, 0 means no limit
+	maxNumInboundPeers  uint
+	maxNumOutboundPeers uint
+	peerSlotMu          *sync.Mutex // serializes the peer limit checks with the peer table updates
+
+	// Only peer with the persistent peers, and neither discover nor share addresses
+	privatePeering bool
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -45,16 +67,19 @@ type PeerDiscoveryManagerConfig struct {
 }
 
 // CreatePeerDiscoveryManager creates an instance of the PeerDiscoveryManager
-func CreatePeerDiscoveryManager(msgr *Messenger, nodeInfo *p2ptypes.NodeInfo, addrBookFilePath string,
-	routabilityRestrict bool, seedPeerNetAddresses []string,
+func CreatePeerDiscoveryManager(msgr *Messenger, nodeInfo *p2ptypes.NodeInfo, privKey *crypto.PrivateKey,
+	addrBookFilePath string, routabilityRestrict bool, seedPeerNetAddresses []string,
 	networkProtocol string, localNetworkAddr string, skipUPNP bool, peerTable *pr.PeerTable,
 	config PeerDiscoveryManagerConfig) (*PeerDiscoveryManager, error) {
 
 	discMgr := &PeerDiscoveryManager{
-		messenger: msgr,
-		nodeInfo:  nodeInfo,
-		peerTable: peerTable,
-		wg:        &sync.WaitGroup{},
+		messenger:  msgr,
+		nodeInfo:   nodeInfo,
+		privKey:    privKey,
+		peerTable:  peerTable,
+		banList:    newBanList(),
+		peerSlotMu: &sync.Mutex{},
+		wg:         &sync.WaitGroup{},
 	}
 
 	discMgr.addrBook = NewAddrBook(addrBookFilePath, routabilityRestrict)
@@ -65,11 +90,19 @@ func CreatePeerDiscoveryManager(msgr *Messenger, nodeInfo *p2ptypes.NodeInfo, ad
 		return discMgr, err
 	}
 
+	discMgr.persistentPeerConnector, err = createPersistentPeerConnector(discMgr, localNetworkAddr)
+	if err != nil {
+		return discMgr, err
+	}
+
 	discMgr.peerDiscMsgHandler, err = createPeerDiscoveryMessageHandler(discMgr, localNetworkAddr)
 	if err != nil {
 		return discMgr, err
 	}
 
+	discMgr.pexMsgHandler = createPEXMessageHandler(discMgr)
+	discMgr.crawler = createPeerCrawler(discMgr)
+
 	inlConfig := GetDefaultInboundPeerListenerConfig()
 	discMgr.inboundPeerListener, err = createInboundPeerListener(discMgr, networkProtocol, localNetworkAddr, skipUPNP, inlConfig)
 	if err != nil {
@@ -78,6 +111,9 @@ func CreatePeerDiscoveryManager(msgr *Messenger, nodeInfo *p2ptypes.NodeInfo, ad
 	discMgr.inboundPeerListener.SetInboundCallback(func(peer *pr.Peer, err error) {
 		if err == nil {
 			logger.Infof("Inbound peer connected, ID: %v, from: %v", peer.ID(), peer.GetConnection().GetNetconn().RemoteAddr())
+			if discMgr.crawler.IsEnabled() {
+				discMgr.crawler.trackPeer(peer) // bootstrapping nodes are served and then disconnected
+			}
 		} else {
 			logger.Errorf("Inbound peer listener error: %v", err)
 		}
@@ -86,11 +122,13 @@ func CreatePeerDiscoveryManager(msgr *Messenger, nodeInfo *p2ptypes.NodeInfo, ad
 	return discMgr, nil
 }
 
-// GetDefaultPeerDiscoveryManagerConfig returns the default config for the PeerDiscoveryManager
+// GetDefaultPeerDiscoveryManagerConfig returns the default config for the PeerDiscoveryManager.
+// The peer limits are read from the node config each time, so that reloading the config changes
+// them without restarting the discovery.
 func GetDefaultPeerDiscoveryManagerConfig() PeerDiscoveryManagerConfig {
 	return PeerDiscoveryManagerConfig{
-		MaxNumPeers:        128,
-		SufficientNumPeers: 32,
+		MaxNumPeers:        uint(viper.GetInt(common.CfgP2PMaxNumPeers)),
+		SufficientNumPeers: uint(viper.GetInt(common.CfgP2PSufficientNumPeers)),
 	}
 }
 
@@ -105,12 +143,19 @@ func (discMgr *PeerDiscoveryManager) Start(ctx context.Context) error {
 	discMgr.ctx = c
 	discMgr.cancel = cancel
 
+	discMgr.addrBook.Load()
+
 	var err error
 	err = discMgr.seedPeerConnector.Start(c)
 	if err != nil {
 		return err
 	}
 
+	err = discMgr.persistentPeerConnector.Start(c)
+	if err != nil {
+		return err
+	}
+
 	err = discMgr.inboundPeerListener.Start(c)
 	if err != nil {
 		return err
@@ -121,9 +166,69 @@ func (discMgr *PeerDiscoveryManager) Start(ctx context.Context) error {
 		return err
 	}
 
+	err = discMgr.pexMsgHandler.Start(c)
+	if err != nil {
+		return err
+	}
+
+	err = discMgr.crawler.Start(c)
+	if err != nil {
+		return err
+	}
+
+	discMgr.wg.Add(1)
+	go discMgr.dialReliablePeers()
+
 	return nil
 }
 
+// dialReliablePeers connects on startup to the address book peers which were the most reliable
+// in the past, so that the node rejoins the network without waiting for the address exchanges.
+func (discMgr *PeerDiscoveryManager) dialReliablePeers() {
+	defer discMgr.wg.Done()
+
+	if discMgr.crawler.IsEnabled() {
+		return // the crawler dials the address book peers on its own
+	}
+	if discMgr.privatePeering {
+		return // only the persistent peers are dialed
+	}
+
+	connected := make(map[string]bool)
+	for _, peer := range *(discMgr.peerTable.GetAllPeers()) {
+		if addr := peer.NetAddress(); addr != nil {
+			connected[addr.String()] = true
+		}
+	}
+
+	numDialed := 0
+	for _, addr := range discMgr.addrBook.GetReliableAddresses(maxReliablePeerDials) {
+		if discMgr.ctx.Err() != nil {
+			return
+		}
+		if int(discMgr.peerTable.GetTotalNumPeers()) >= int(GetDefaultPeerDiscoveryManagerConfig().SufficientNumPeers) ||
+			discMgr.numOutboundSlots() <= 0 {
+			break
+		}
+		if connected[addr.String()] {
+			continue
+		}
+		if seedPeerOnlyOutbound() && !discMgr.seedPeerConnector.isASeedPeer(addr) {
+			continue
+		}
+
+		discMgr.addrBook.MarkAttempt(addr)
+		if _, err := discMgr.connectToOutboundPeer(addr, false); err != nil {
+			logger.Debugf("Failed to connect to reliable peer %v: %v", addr.String(), err)
+			continue
+		}
+		numDialed++
+	}
+	if numDialed > 0 {
+		logger.Infof("Connected to %v reliable peers of the address book", numDialed)
+	}
+}
+
 // Stop is called when the PeerDiscoveryManager stops
 func (discMgr *PeerDiscoveryManager) Stop() {
 	discMgr.cancel()
@@ -132,11 +237,24 @@ func (discMgr *PeerDiscoveryManager) Stop() {
 // Wait suspends the caller goroutine
 func (discMgr *PeerDiscoveryManager) Wait() {
 	discMgr.seedPeerConnector.wg.Wait()
+	discMgr.persistentPeerConnector.wg.Wait()
 	discMgr.inboundPeerListener.wg.Wait()
 	discMgr.peerDiscMsgHandler.wg.Wait()
+	discMgr.pexMsgHandler.wg.Wait()
+	discMgr.crawler.wg.Wait()
 	discMgr.wg.Wait()
 }
 
+// isConnected returns whether a peer at the address is in the peer table
+func (discMgr *PeerDiscoveryManager) isConnected(netAddr *netutil.NetAddress) bool {
+	for _, peer := range *discMgr.peerTable.GetAllPeers() {
+		if addr := peer.NetAddress(); addr != nil && addr.Equals(netAddr) {
+			return true
+		}
+	}
+	return false
+}
+
 // HandlePeerWithErrors handles peers that are in the error state.
 // If the peer is persistent, it will attempt to reconnect to the
 // peer. Otherwise, it disconnects from that peer
@@ -186,13 +304,18 @@ func (discMgr *PeerDiscoveryManager) connectToOutboundPeer(peerNetAddress *netut
 	logger.Infof("Connecting to outbound peer: %v...", peerNetAddress)
 	peerConfig := pr.GetDefaultPeerConfig()
 	connConfig := cn.GetDefaultConnectionConfig()
+	start := time.Now()
 	peer, err := pr.CreateOutboundPeer(peerNetAddress, peerConfig, connConfig)
 	if err != nil {
 		logger.Warnf("Failed to create outbound peer: %v", peerNetAddress)
+		discMgr.addrBook.MarkFailed(peerNetAddress)
 		return nil, err
 	}
 	peer.SetPersistency(persistent)
 	err = discMgr.handshakeAndAddPeer(peer)
+	if err == nil {
+		discMgr.addrBook.MarkLatency(peerNetAddress, time.Since(start))
+	}
 	return peer, err
 }
 
@@ -213,8 +336,50 @@ func (discMgr *PeerDiscoveryManager) connectWithInboundPeer(netconn net.Conn, pe
 // handshakeAndAddPeer performs handshake with a peer. Upon successful handshake,
 // it save the peer to the peer table
 func (discMgr *PeerDiscoveryManager) handshakeAndAddPeer(peer *pr.Peer) error {
-	if err := peer.Handshake(discMgr.nodeInfo); err != nil {
+	if err := peer.Handshake(discMgr.nodeInfo, discMgr.privKey); err != nil {
 		logger.Errorf("Failed to handshake with peer, error: %v", err)
+		if peer.IsOutbound() {
+			discMgr.addrBook.MarkFailed(peer.NetAddress())
+		}
+		return err
+	}
+
+	if discMgr.persistentPeerConnector.isAPersistentPeer(peer.NetAddress()) {
+		peer.SetPersistency(true) // also when the persistent peer dialed the node
+	}
+
+	if discMgr.banList.IsBanned(peer.ID()) {
+		peer.GetConnection().GetNetconn().Close() // the peer is not started yet
+		errMsg := fmt.Sprintf("Peer %v is banned", peer.ID())
+		logger.Info(errMsg)
+		return errors.New(errMsg)
+	}
+
+	if err := discMgr.startAndAddPeer(peer); err != nil {
+		return err
+	}
+
+	if peer.IsPrivate() {
+		return nil // the address of a private peer is not shared, so it is not kept either
+	}
+	discMgr.addrBook.AddAddress(peer.NetAddress(), peer.NetAddress())
+	if peer.IsOutbound() {
+		// Only the outbound peers are known to be reachable at their addresses
+		discMgr.addrBook.MarkGood(peer.NetAddress())
+	}
+	discMgr.addrBook.Save()
+
+	return nil
+}
+
+// startAndAddPeer starts the peer and adds it to the peer table if the peer limits allow
+func (discMgr *PeerDiscoveryManager) startAndAddPeer(peer *pr.Peer) error {
+	discMgr.peerSlotMu.Lock()
+	defer discMgr.peerSlotMu.Unlock()
+
+	if err := discMgr.reservePeerSlot(peer); err != nil {
+		peer.GetConnection().GetNetconn().Close() // the peer is not started yet
+		logger.Infof("Reject peer %v: %v", peer.ID(), err)
 		return err
 	}
 
@@ -236,8 +401,28 @@ func (discMgr *PeerDiscoveryManager) handshakeAndAddPeer(peer *pr.Peer) error {
 		return errors.New(errMsg)
 	}
 
-	discMgr.addrBook.AddAddress(peer.NetAddress(), peer.NetAddress())
-	discMgr.addrBook.Save()
+	if discMgr.messenger != nil {
+		discMgr.messenger.eventBus.Publish(event.PeerConnected{
+			PeerID:   peer.ID(),
+			Address:  peer.NetAddress().String(),
+			Outbound: peer.IsOutbound(),
+		})
+	}
 
 	return nil
 }
+
+// BanPeer disconnects the peer and rejects its connections until the ban expires
+func (discMgr *PeerDiscoveryManager) BanPeer(peerID string, duration time.Duration) {
+	discMgr.banList.Ban(peerID, duration)
+
+	peer := discMgr.peerTable.GetPeer(peerID)
+	if peer == nil {
+		return
+	}
+	discMgr.peerTable.DeletePeer(peerID)
+	peer.SetPersistency(false)
+	peer.Stop()
+	discMgr.addrBook.RemoveAddress(peer.NetAddress())
+	discMgr.addrBook.Save()
+}
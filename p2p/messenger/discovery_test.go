@@ -71,6 +71,56 @@ func TestSeedPeerConnector(t *testing.T) {
 	}
 }
 
+func TestPersistentPeerConnector(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	peerANetAddr := "127.0.0.1:24561"
+	peerBNetAddr := "127.0.0.1:24562"
+
+	// PeerB (i.e. us) keeps PeerA connected, and dials it before it is up
+	discMgr := newTestPeerDiscoveryManager([]string{}, peerBNetAddr)
+	assert.NotNil(discMgr.persistentPeerConnector.SetPersistentPeers([]string{"invalid"}))
+	assert.Nil(discMgr.persistentPeerConnector.SetPersistentPeers([]string{peerANetAddr, peerBNetAddr}))
+	assert.Equal(1, len(discMgr.persistentPeerConnector.persistentPeerNetAddresses))
+	discMgr.Start(ctx)
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(uint(0), discMgr.peerTable.GetTotalNumPeers())
+
+	// PeerA comes up later, and is connected once the backoff expires
+	discMgrA := newTestPeerDiscoveryManager([]string{}, peerANetAddr)
+	discMgrA.Start(ctx)
+	peerAID := discMgrA.nodeInfo.PubKey.Address().Hex()
+
+	for i := 0; i < 50 && discMgr.peerTable.GetPeer(peerAID) == nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	peerA := discMgr.peerTable.GetPeer(peerAID)
+	if assert.NotNil(peerA) {
+		assert.True(peerA.IsOutbound())
+		assert.True(peerA.IsPersistent())
+	}
+}
+
+func TestPersistentPeerBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	backoff := persistentPeerMinBackoff
+	for i := 0; i < 3; i++ {
+		backoff = nextPersistentPeerBackoff(backoff)
+	}
+	assert.Equal(8*persistentPeerMinBackoff, backoff)
+	for i := 0; i < 20; i++ {
+		backoff = nextPersistentPeerBackoff(backoff)
+	}
+	assert.Equal(persistentPeerMaxBackoff, backoff)
+
+	for i := 0; i < 10; i++ {
+		delay := persistentPeerBackoffWithJitter(4 * time.Second)
+		assert.True(delay >= 4*time.Second && delay <= 5*time.Second)
+	}
+}
+
 func TestInboundPeerListener(t *testing.T) {
 	assert := assert.New(t)
 	ctx := context.Background()
@@ -352,17 +402,17 @@ func TestPeerDiscoveryMessageHandler(t *testing.T) {
 
 func newTestPeerDiscoveryManager(seedPeerNetAddressStrs []string, localNetworkAddress string) *PeerDiscoveryManager {
 	messenger := (*Messenger)(nil) // not important for the test
-	peerPubKey := p2ptypes.GetTestRandPubKey()
+	peerPrivKey := p2ptypes.GetTestRandPrivKey()
 	_, portStr, _ := net.SplitHostPort(localNetworkAddress)
 	port, _ := strconv.ParseUint(portStr, 16, 16)
-	peerNodeInfo := p2ptypes.CreateNodeInfo(peerPubKey, uint16(port))
+	peerNodeInfo := p2ptypes.CreateNodeInfo(peerPrivKey.PublicKey(), uint16(port))
 	addrbookPath := "./.addrbooks/addrbook_" + localNetworkAddress + ".json"
 	routabilityRestrict := false
 	networkProtocol := "tcp"
 	skipUPNP := true
 	peerTable := pr.CreatePeerTable()
 	config := GetDefaultPeerDiscoveryManagerConfig()
-	discMgr, err := CreatePeerDiscoveryManager(messenger, &peerNodeInfo, addrbookPath, routabilityRestrict,
+	discMgr, err := CreatePeerDiscoveryManager(messenger, &peerNodeInfo, peerPrivKey, addrbookPath, routabilityRestrict,
 		seedPeerNetAddressStrs, networkProtocol, localNetworkAddress,
 		skipUPNP, &peerTable, config)
 	if err != nil {
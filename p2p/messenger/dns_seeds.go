@@ -0,0 +1,337 @@
+package messenger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/thetatoken/theta/p2p/netutil"
+)
+
+const (
+	defaultDNSSeedTTL = time.Hour      // cache duration when the resolver does not report the TTL
+	minDNSSeedTTL     = time.Minute    // the records are not re-resolved more often
+	maxDNSSeedTTL     = 24 * time.Hour // the records are re-resolved at least once a day
+	maxDNSSeedPeers   = 16             // max addresses from the DNS seeds dialed per bootstrap attempt
+	dnsQueryTimeout   = 5 * time.Second
+	resolvConfPath    = "/etc/resolv.conf"
+)
+
+//
+// dnsSeed is a domain name listing seed peers. Its TXT records contain seed addresses in the
+// "host:port" form, separated by spaces or commas, and its A/AAAA records the IPs of seed peers
+// listening on the given port.
+//
+type dnsSeed struct {
+	domain string
+	port   uint16
+}
+
+// parseDNSSeed parses a DNS seed of the form "domain[:port]". The default port applies to the
+// A/AAAA records if the port is omitted.
+func parseDNSSeed(seedStr string, defaultPort uint16) (dnsSeed, error) {
+	seedStr = strings.TrimSpace(seedStr)
+	domain, port := seedStr, defaultPort
+	if host, portStr, err := net.SplitHostPort(seedStr); err == nil {
+		parsedPort, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return dnsSeed{}, fmt.Errorf("Invalid port in DNS seed %v: %v", seedStr, err)
+		}
+		domain, port = host, uint16(parsedPort)
+	}
+	if len(domain) == 0 || net.ParseIP(domain) != nil {
+		return dnsSeed{}, fmt.Errorf("Invalid DNS seed domain: %v", seedStr)
+	}
+	return dnsSeed{domain: strings.TrimSuffix(domain, "."), port: port}, nil
+}
+
+// dnsSeedRecords are the records resolved for a DNS seed
+type dnsSeedRecords struct {
+	txts []string
+	ips  []net.IP
+	ttl  time.Duration
+}
+
+// dnsLookupFunc resolves the TXT and A/AAAA records of a domain
+type dnsLookupFunc func(domain string) (dnsSeedRecords, error)
+
+type dnsSeedCacheEntry struct {
+	addrs  []*netutil.NetAddress
+	expiry time.Time
+}
+
+//
+// dnsSeedResolver resolves the seed peers from the DNS seeds. The resolved addresses are
+// cached as long as the TTL of the records, and each bootstrap attempt takes the next batch
+// of addresses, so that the load spreads over all the seed peers listed.
+//
+type dnsSeedResolver struct {
+	seeds  []dnsSeed
+	lookup dnsLookupFunc
+
+	mu       *sync.Mutex
+	cache    map[string]dnsSeedCacheEntry // domain |-> resolved addresses
+	rotation int
+}
+
+// createDNSSeedResolver creates a resolver for the given DNS seeds. It returns nil if there
+// are no valid DNS seeds.
+func createDNSSeedResolver(seedStrs []string, defaultPort uint16) *dnsSeedResolver {
+	seeds := []dnsSeed{}
+	for _, seedStr := range seedStrs {
+		seed, err := parseDNSSeed(seedStr, defaultPort)
+		if err != nil {
+			logger.Errorf("Failed to parse the DNS seed: %v", err)
+			continue
+		}
+		seeds = append(seeds, seed)
+	}
+	if len(seeds) == 0 {
+		return nil
+	}
+
+	return &dnsSeedResolver{
+		seeds:  seeds,
+		lookup: lookupDNSSeedRecords,
+		mu:     &sync.Mutex{},
+		cache:  make(map[string]dnsSeedCacheEntry),
+	}
+}
+
+// resolve returns the next batch of up to maxDNSSeedPeers seed addresses. The domains are
+// re-resolved once their records expire. If a domain fails to resolve, its expired addresses
+// are used until the next successful resolution.
+func (dsr *dnsSeedResolver) resolve() []*netutil.NetAddress {
+	dsr.mu.Lock()
+	defer dsr.mu.Unlock()
+
+	now := time.Now()
+	for _, seed := range dsr.seeds {
+		entry, cached := dsr.cache[seed.domain]
+		if cached && now.Before(entry.expiry) {
+			continue
+		}
+		records, err := dsr.lookup(seed.domain)
+		if err != nil {
+			logger.Warnf("Failed to resolve DNS seed %v: %v", seed.domain, err)
+			continue
+		}
+		dsr.cache[seed.domain] = dnsSeedCacheEntry{
+			addrs:  seed.addresses(records),
+			expiry: now.Add(clampDNSSeedTTL(records.ttl)),
+		}
+	}
+
+	all := dsr.cachedAddressesUnsafe()
+	if len(all) <= maxDNSSeedPeers {
+		return all
+	}
+
+	start := dsr.rotation % len(all)
+	dsr.rotation = start + maxDNSSeedPeers
+	batch := make([]*netutil.NetAddress, maxDNSSeedPeers)
+	for i := range batch {
+		batch[i] = all[(start+i)%len(all)]
+	}
+	return batch
+}
+
+// cachedAddresses returns the addresses resolved so far, without resolving the domains
+func (dsr *dnsSeedResolver) cachedAddresses() []*netutil.NetAddress {
+	dsr.mu.Lock()
+	defer dsr.mu.Unlock()
+
+	return dsr.cachedAddressesUnsafe()
+}
+
+// cachedAddressesUnsafe returns the deduplicated cached addresses in a deterministic order,
+// so that the rotation walks over all of them.
+func (dsr *dnsSeedResolver) cachedAddressesUnsafe() []*netutil.NetAddress {
+	seen := make(map[string]bool)
+	all := []*netutil.NetAddress{}
+	for _, entry := range dsr.cache {
+		for _, addr := range entry.addrs {
+			if seen[addr.String()] {
+				continue
+			}
+			seen[addr.String()] = true
+			all = append(all, addr)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].String() < all[j].String()
+	})
+	return all
+}
+
+// addresses converts the records of the DNS seed to the seed addresses
+func (seed dnsSeed) addresses(records dnsSeedRecords) []*netutil.NetAddress {
+	addrs := []*netutil.NetAddress{}
+	for _, txt := range records.txts {
+		for _, addrStr := range strings.FieldsFunc(txt, func(c rune) bool { return c == ' ' || c == ',' }) {
+			addr, err := netutil.NewNetAddressString(addrStr)
+			if err != nil || !addr.Valid() {
+				logger.Debugf("Ignore invalid address %v in the TXT record of %v", addrStr, seed.domain)
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	for _, ip := range records.ips {
+		addr := netutil.NewNetAddressIPPort(ip, seed.port)
+		if !addr.Valid() {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+func clampDNSSeedTTL(ttl time.Duration) time.Duration {
+	if ttl < minDNSSeedTTL {
+		return minDNSSeedTTL
+	}
+	if ttl > maxDNSSeedTTL {
+		return maxDNSSeedTTL
+	}
+	return ttl
+}
+
+// lookupDNSSeedRecords queries the name servers of the system for the TXT, A and AAAA records
+// of the domain, to get their TTL. It falls back to the resolver of the Go runtime, which does
+// not report the TTL, if the name servers cannot be queried.
+func lookupDNSSeedRecords(domain string) (dnsSeedRecords, error) {
+	servers := systemNameServers()
+	for _, server := range servers {
+		records, err := queryDNSSeedRecords(server, domain)
+		if err == nil {
+			return records, nil
+		}
+		logger.Debugf("Failed to query name server %v for %v: %v", server, domain, err)
+	}
+
+	records := dnsSeedRecords{ttl: defaultDNSSeedTTL}
+	txts, txtErr := net.LookupTXT(domain)
+	ips, ipErr := net.LookupIP(domain)
+	if txtErr != nil && ipErr != nil {
+		return records, txtErr
+	}
+	records.txts = txts
+	records.ips = ips
+	return records, nil
+}
+
+// systemNameServers returns the name servers listed in resolv.conf
+func systemNameServers() []string {
+	file, err := os.Open(resolvConfPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	servers := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if ip := net.ParseIP(fields[1]); ip != nil {
+			servers = append(servers, net.JoinHostPort(ip.String(), "53"))
+		}
+	}
+	return servers
+}
+
+// queryDNSSeedRecords queries the name server for the records of the domain. The TTL of the
+// records is the smallest TTL of the answers.
+func queryDNSSeedRecords(server string, domain string) (dnsSeedRecords, error) {
+	records := dnsSeedRecords{}
+	name, err := dnsmessage.NewName(domain + ".")
+	if err != nil {
+		return records, err
+	}
+
+	var minTTL uint32
+	hasTTL := false
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeTXT, dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		answers, err := exchangeDNSQuery(server, name, qtype)
+		if err != nil {
+			return records, err
+		}
+		for _, answer := range answers {
+			switch body := answer.Body.(type) {
+			case *dnsmessage.TXTResource:
+				records.txts = append(records.txts, strings.Join(body.TXT, ""))
+			case *dnsmessage.AResource:
+				records.ips = append(records.ips, net.IP(body.A[:]))
+			case *dnsmessage.AAAAResource:
+				records.ips = append(records.ips, net.IP(body.AAAA[:]))
+			default:
+				continue // e.g. CNAME
+			}
+			if !hasTTL || answer.Header.TTL < minTTL {
+				minTTL = answer.Header.TTL
+				hasTTL = true
+			}
+		}
+	}
+
+	if !hasTTL {
+		return records, fmt.Errorf("No seed records for %v", domain)
+	}
+	records.ttl = time.Duration(minTTL) * time.Second
+	return records, nil
+}
+
+func exchangeDNSQuery(server string, name dnsmessage.Name, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("udp", server, dnsQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsQueryTimeout))
+
+	if _, err = conn.Write(packed); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var response dnsmessage.Message
+	if err = response.Unpack(buf[:n]); err != nil {
+		return nil, err
+	}
+	if response.Header.ID != id || !response.Header.Response {
+		return nil, errors.New("Mismatched DNS response")
+	}
+	if response.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("DNS query failed: %v", response.Header.RCode)
+	}
+	return response.Answers, nil
+}
@@ -0,0 +1,101 @@
+package messenger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDNSSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	seed, err := parseDNSSeed("seeds.example.org", 50001)
+	assert.Nil(err)
+	assert.Equal("seeds.example.org", seed.domain)
+	assert.Equal(uint16(50001), seed.port)
+
+	seed, err = parseDNSSeed(" seeds.example.org.:30001 ", 50001)
+	assert.Nil(err)
+	assert.Equal("seeds.example.org", seed.domain)
+	assert.Equal(uint16(30001), seed.port)
+
+	_, err = parseDNSSeed("seeds.example.org:abc", 50001)
+	assert.NotNil(err)
+	_, err = parseDNSSeed("8.8.8.8:50001", 50001)
+	assert.NotNil(err)
+	_, err = parseDNSSeed("", 50001)
+	assert.NotNil(err)
+
+	assert.Nil(createDNSSeedResolver([]string{"1.2.3.4"}, 50001))
+}
+
+func TestDNSSeedResolverTTL(t *testing.T) {
+	assert := assert.New(t)
+
+	dsr := createDNSSeedResolver([]string{"seeds.example.org:30001"}, 50001)
+	numLookups := 0
+	failLookup := false
+	dsr.lookup = func(domain string) (dnsSeedRecords, error) {
+		numLookups++
+		if failLookup {
+			return dnsSeedRecords{}, errors.New("lookup failed")
+		}
+		return dnsSeedRecords{
+			txts: []string{"1.2.3.4:40001, 1.2.3.5:40001", "invalid"},
+			ips:  []net.IP{net.ParseIP("1.2.3.6"), net.ParseIP("1.2.3.4")},
+			ttl:  10 * time.Minute,
+		}, nil
+	}
+
+	addrs := dsr.resolve()
+	assert.Equal(1, numLookups)
+	assert.Equal(4, len(addrs))
+	assert.Equal("1.2.3.4:30001", addrs[0].String())
+	assert.Equal("1.2.3.4:40001", addrs[1].String())
+	assert.Equal("1.2.3.5:40001", addrs[2].String())
+	assert.Equal("1.2.3.6:30001", addrs[3].String())
+
+	// Cached until the TTL expires
+	assert.Equal(4, len(dsr.resolve()))
+	assert.Equal(1, numLookups)
+
+	// The expired addresses are kept if the domain fails to resolve
+	entry := dsr.cache["seeds.example.org"]
+	entry.expiry = time.Now().Add(-time.Second)
+	dsr.cache["seeds.example.org"] = entry
+	failLookup = true
+	assert.Equal(4, len(dsr.resolve()))
+	assert.Equal(2, numLookups)
+	assert.Equal(4, len(dsr.cachedAddresses()))
+
+	// The TTL is clamped
+	assert.Equal(minDNSSeedTTL, clampDNSSeedTTL(time.Second))
+	assert.Equal(maxDNSSeedTTL, clampDNSSeedTTL(30*24*time.Hour))
+	assert.Equal(time.Hour, clampDNSSeedTTL(time.Hour))
+}
+
+func TestDNSSeedResolverRotation(t *testing.T) {
+	assert := assert.New(t)
+
+	dsr := createDNSSeedResolver([]string{"seeds.example.org"}, 50001)
+	dsr.lookup = func(domain string) (dnsSeedRecords, error) {
+		records := dnsSeedRecords{ttl: time.Hour}
+		for i := 0; i < maxDNSSeedPeers+4; i++ {
+			records.ips = append(records.ips, net.ParseIP(fmt.Sprintf("1.2.3.%v", 10+i)))
+		}
+		return records, nil
+	}
+
+	// Each bootstrap attempt takes the next batch of the seed peers
+	first := dsr.resolve()
+	assert.Equal(maxDNSSeedPeers, len(first))
+	assert.Equal("1.2.3.10:50001", first[0].String())
+	second := dsr.resolve()
+	assert.Equal(maxDNSSeedPeers, len(second))
+	assert.Equal(fmt.Sprintf("1.2.3.%v:50001", 10+maxDNSSeedPeers), second[0].String())
+	assert.Equal("1.2.3.10:50001", second[4].String())
+}
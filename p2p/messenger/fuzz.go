@@ -0,0 +1,47 @@
+// +build gofuzz
+
+package messenger
+
+import (
+	"time"
+)
+
+// The entry points for the go-fuzz tool on the decoding of the peer discovery messages, e.g.
+//
+//	go-fuzz-build -func FuzzPEXMessage github.com/thetatoken/theta/p2p/messenger
+//	go-fuzz -bin messenger-fuzz.zip -workdir fuzz/pex
+//
+// They return 1 for the inputs which decode, and 0 otherwise. The addresses of the decoded
+// messages are checked the way the handlers check them.
+
+// FuzzPeerDiscoveryMessage fuzzes the decoding of the peer discovery messages.
+func FuzzPeerDiscoveryMessage(data []byte) int {
+	message, err := decodePeerDiscoveryMessage(data)
+	if err != nil {
+		return 0
+	}
+	for _, idAddr := range message.Addresses {
+		if idAddr.Addr != nil {
+			idAddr.Addr.Valid()
+			idAddr.Addr.String()
+		}
+	}
+	return 1
+}
+
+// FuzzPEXMessage fuzzes the decoding of the peer exchange messages.
+func FuzzPEXMessage(data []byte) int {
+	message, err := decodePEXMessage(data)
+	if err != nil {
+		return 0
+	}
+	now := time.Now()
+	for _, pexAddr := range message.Addresses {
+		pexAddr.isStale(now)
+		if pexAddr.Addr != nil {
+			pexAddr.Addr.Valid()
+			pexAddr.Addr.String()
+		}
+	}
+	return 1
+}
@@ -2,24 +2,32 @@ package messenger
 
 import (
 	"context"
+	"errors"
+	"net"
 	"strconv"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/event"
 	"github.com/thetatoken/theta/p2p"
+	cn "github.com/thetatoken/theta/p2p/connection"
 	pr "github.com/thetatoken/theta/p2p/peer"
 	p2ptypes "github.com/thetatoken/theta/p2p/types"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "p2p"})
+var logger *log.Entry = util.GetLoggerForModule("p2p")
 
 //
 // Messenger implements the Network interface
 //
 var _ p2p.Network = (*Messenger)(nil)
+var _ p2p.PeerAdmin = (*Messenger)(nil)
 
 type Messenger struct {
 	discMgr       *PeerDiscoveryManager
@@ -30,6 +38,8 @@ type Messenger struct {
 
 	config MessengerConfig
 
+	eventBus *event.Bus
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	quit    chan struct{}
@@ -46,23 +56,45 @@ type MessengerConfig struct {
 	routabilityRestrict bool
 	skipUPNP            bool
 	networkProtocol     string
+	nodeVersion         string
+	dnsSeeds            []string
+	persistentPeers     []string
+	privatePeering      bool
+	maxNumInboundPeers  uint
+	maxNumOutboundPeers uint
 }
 
 // CreateMessenger creates an instance of Messenger
-func CreateMessenger(pubKey *crypto.PublicKey, seedPeerNetAddresses []string,
+func CreateMessenger(privKey *crypto.PrivateKey, seedPeerNetAddresses []string,
 	port int, msgrConfig MessengerConfig) (*Messenger, error) {
 
 	messenger := &Messenger{
 		msgHandlerMap: make(map[common.ChannelIDEnum](p2p.MessageHandler)),
 		peerTable:     pr.CreatePeerTable(),
-		nodeInfo:      p2ptypes.CreateNodeInfo(pubKey, uint16(port)),
+		nodeInfo:      p2ptypes.CreateNodeInfo(privKey.PublicKey(), uint16(port)),
 		config:        msgrConfig,
 		wg:            &sync.WaitGroup{},
 	}
+	if viper.GetBool(common.CfgP2PCompressionEnabled) {
+		messenger.nodeInfo.Compression = cn.SupportedCompression()
+	}
+	messenger.nodeInfo.RecvWindow = uint32(viper.GetInt(common.CfgP2PFlowControlWindow))
+	messenger.nodeInfo.Version = msgrConfig.nodeVersion
+	if msgrConfig.privatePeering {
+		if len(msgrConfig.persistentPeers) == 0 {
+			return messenger, errors.New("Private peering requires persistent peers")
+		}
+		if len(seedPeerNetAddresses) > 0 || len(msgrConfig.dnsSeeds) > 0 {
+			logger.Warnf("Private peering enabled, the seed peers are ignored")
+		}
+		seedPeerNetAddresses = nil
+		msgrConfig.dnsSeeds = nil
+		messenger.nodeInfo.Private = true
+	}
 
-	localNetAddress := "0.0.0.0:" + strconv.Itoa(port)
+	localNetAddress := net.JoinHostPort(viper.GetString(common.CfgP2PListenAddress), strconv.Itoa(port))
 	discMgrConfig := GetDefaultPeerDiscoveryManagerConfig()
-	discMgr, err := CreatePeerDiscoveryManager(messenger, &(messenger.nodeInfo),
+	discMgr, err := CreatePeerDiscoveryManager(messenger, &(messenger.nodeInfo), privKey,
 		msgrConfig.addrBookFilePath, msgrConfig.routabilityRestrict,
 		seedPeerNetAddresses, msgrConfig.networkProtocol,
 		localNetAddress, msgrConfig.skipUPNP, &messenger.peerTable, discMgrConfig)
@@ -71,9 +103,16 @@ func CreateMessenger(pubKey *crypto.PublicKey, seedPeerNetAddresses []string,
 		return messenger, err
 	}
 
+	discMgr.seedPeerConnector.SetDNSSeeds(msgrConfig.dnsSeeds, uint16(port))
+	if err := discMgr.persistentPeerConnector.SetPersistentPeers(msgrConfig.persistentPeers); err != nil {
+		return messenger, err
+	}
+	discMgr.SetPeerLimits(msgrConfig.maxNumInboundPeers, msgrConfig.maxNumOutboundPeers)
+	discMgr.SetPrivatePeering(msgrConfig.privatePeering)
 	discMgr.SetMessenger(messenger)
 	messenger.SetPeerDiscoveryManager(discMgr)
 	messenger.RegisterMessageHandler(&discMgr.peerDiscMsgHandler)
+	messenger.RegisterMessageHandler(&discMgr.pexMsgHandler)
 
 	return messenger, nil
 }
@@ -85,6 +124,9 @@ func GetDefaultMessengerConfig() MessengerConfig {
 		routabilityRestrict: false,
 		skipUPNP:            false,
 		networkProtocol:     "tcp",
+		maxNumInboundPeers:  uint(viper.GetInt(common.CfgP2PMaxNumInboundPeers)),
+		maxNumOutboundPeers: uint(viper.GetInt(common.CfgP2PMaxNumOutboundPeers)),
+		privatePeering:      viper.GetBool(common.CfgP2PPrivatePeering),
 	}
 }
 
@@ -136,11 +178,35 @@ func (msgr *Messenger) Send(peerID string, message p2ptypes.Message) bool {
 		return false
 	}
 
+	if required := p2ptypes.RequiredCapability(message.ChannelID); !peer.HasCapability(required) {
+		return msgr.sendDowngraded(peer, message)
+	}
+
 	success := peer.Send(message.ChannelID, message.Content)
 
 	return success
 }
 
+// sendDowngraded sends the message to a peer lacking the capability of its channel, converted by
+// the message handler of the channel to the format of the peer if it can
+func (msgr *Messenger) sendDowngraded(peer *pr.Peer, message p2ptypes.Message) bool {
+	downgrader, ok := msgr.msgHandlerMap[message.ChannelID].(p2p.MessageDowngrader)
+	if !ok {
+		return false
+	}
+	messages := downgrader.DowngradeMessage(message)
+	if len(messages) == 0 {
+		return false
+	}
+	success := true
+	for _, msg := range messages {
+		if !peer.Send(msg.ChannelID, msg.Content) {
+			success = false
+		}
+	}
+	return success
+}
+
 // RegisterMessageHandler registers the message handler
 func (msgr *Messenger) RegisterMessageHandler(msgHandler p2p.MessageHandler) {
 	channelIDs := msgHandler.GetChannelIDs()
@@ -158,15 +224,83 @@ func (msgr *Messenger) ID() string {
 	return msgr.nodeInfo.PubKey.Address().Hex()
 }
 
+// PeerInfos returns the information of the connected peers
+func (msgr *Messenger) PeerInfos() []p2ptypes.PeerInfo {
+	peers := *msgr.peerTable.GetAllPeers()
+	infos := make([]p2ptypes.PeerInfo, 0, len(peers))
+	for _, peer := range peers {
+		info := p2ptypes.PeerInfo{
+			ID:         peer.ID(),
+			IsOutbound: peer.IsOutbound(),
+			Persistent: peer.IsPersistent(),
+			Private:    peer.IsPrivate(),
+
+			ProtocolVersion: peer.ProtocolVersion(),
+			Capabilities:    peer.Capabilities(),
+		}
+		if addr := peer.NetAddress(); addr != nil {
+			info.Address = addr.String()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// ReconnectSeedPeers connects to the seed peers which are not connected
+func (msgr *Messenger) ReconnectSeedPeers() {
+	msgr.discMgr.seedPeerConnector.reconnectToSeedPeers()
+}
+
+// BanPeer disconnects the peer and rejects its connections until the ban expires
+func (msgr *Messenger) BanPeer(peerID string, duration time.Duration) error {
+	if peerID == msgr.ID() {
+		return errors.New("Cannot ban the node itself")
+	}
+	if duration <= 0 {
+		return errors.New("Ban duration must be positive")
+	}
+	msgr.discMgr.BanPeer(peerID, duration)
+	logger.Infof("Banned peer %v for %v", peerID, duration)
+	return nil
+}
+
+// UnbanPeer lifts the ban of the peer
+func (msgr *Messenger) UnbanPeer(peerID string) bool {
+	return msgr.discMgr.banList.Unban(peerID)
+}
+
+// BannedPeers returns the banned peers and the time their ban expires
+func (msgr *Messenger) BannedPeers() map[string]time.Time {
+	return msgr.discMgr.banList.BannedPeers()
+}
+
+// SetRateLimits changes the send and receive rates of the connected peers. The peers connected
+// later get the rates of the node config.
+func (msgr *Messenger) SetRateLimits(sendRate, recvRate int64) {
+	for _, peer := range *msgr.peerTable.GetAllPeers() {
+		peer.GetConnection().SetRateLimits(sendRate, recvRate)
+	}
+}
+
+// SetEventBus sets the bus the connected peers are published to
+func (msgr *Messenger) SetEventBus(eventBus *event.Bus) {
+	msgr.eventBus = eventBus
+}
+
 // AttachMessageHandlersToPeer attaches the registerred message handlers to the given peer
 func (msgr *Messenger) AttachMessageHandlersToPeer(peer *pr.Peer) {
 	messageParser := func(channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
+		receivedAt := time.Now()
 		peerID := peer.ID()
 		msgHandler := msgr.msgHandlerMap[channelID]
 		if msgHandler == nil {
 			logger.Errorf("Failed to setup message parser for channelID %v", channelID)
 		}
 		message, err := msgHandler.ParseMessage(peerID, channelID, rawMessageBytes)
+		if err != nil {
+			peer.AdjustScore(peerScoreMessageFailed)
+		}
+		message.ReceivedAt = receivedAt
 		return message, err
 	}
 	peer.GetConnection().SetMessageParser(messageParser)
@@ -184,6 +318,11 @@ func (msgr *Messenger) AttachMessageHandlersToPeer(peer *pr.Peer) {
 			logger.Errorf("Failed to setup message handler for peer %v on channelID %v", message.PeerID, channelID)
 		}
 		err := msgHandler.HandleMessage(message)
+		if err != nil {
+			peer.AdjustScore(peerScoreMessageFailed)
+		} else {
+			peer.AdjustScore(peerScoreMessageHandled)
+		}
 		return err
 	}
 	peer.GetConnection().SetReceiveHandler(receiveHandler)
@@ -198,3 +337,24 @@ func (msgr *Messenger) AttachMessageHandlersToPeer(peer *pr.Peer) {
 func (msgrConfig *MessengerConfig) SetAddressBookFilePath(filePath string) {
 	msgrConfig.addrBookFilePath = filePath
 }
+
+// SetDNSSeeds sets the domain names listing the seed peers, in the "domain[:port]" form
+func (msgrConfig *MessengerConfig) SetDNSSeeds(dnsSeeds []string) {
+	msgrConfig.dnsSeeds = dnsSeeds
+}
+
+// SetPersistentPeers sets the addresses of the peers the node always keeps connected
+func (msgrConfig *MessengerConfig) SetPersistentPeers(persistentPeers []string) {
+	msgrConfig.persistentPeers = persistentPeers
+}
+
+// SetNodeVersion sets the software version reported to the peers
+func (msgrConfig *MessengerConfig) SetNodeVersion(nodeVersion string) {
+	msgrConfig.nodeVersion = nodeVersion
+}
+
+// SetPeerLimits sets the max numbers of inbound and outbound peers. 0 means no limit.
+func (msgrConfig *MessengerConfig) SetPeerLimits(maxNumInboundPeers, maxNumOutboundPeers uint) {
+	msgrConfig.maxNumInboundPeers = maxNumInboundPeers
+	msgrConfig.maxNumOutboundPeers = maxNumOutboundPeers
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -109,6 +110,60 @@ func TestMessengerBroadcastMessages(t *testing.T) {
 	}
 }
 
+func TestMessengerPrivatePeering(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	validatorPort := 24621
+	sentryPort := 24622
+	otherPort := 24623
+	validatorNetAddr := "127.0.0.1:" + strconv.Itoa(validatorPort)
+	sentryNetAddr := "127.0.0.1:" + strconv.Itoa(sentryPort)
+
+	// Private peering requires the persistent peers
+	_, err := CreateMessenger(p2ptypes.GetTestRandPrivKey(), []string{}, validatorPort, MessengerConfig{
+		skipUPNP:        true,
+		networkProtocol: "tcp",
+		privatePeering:  true,
+	})
+	assert.NotNil(err)
+
+	sentry := newTestMessenger([]string{}, sentryPort)
+	sentry.Start(ctx)
+
+	validatorConfig := MessengerConfig{
+		addrBookFilePath: "./.addrbooks/addrbook_" + validatorNetAddr + ".json",
+		skipUPNP:         true,
+		networkProtocol:  "tcp",
+		persistentPeers:  []string{sentryNetAddr},
+		privatePeering:   true,
+	}
+	validator, err := CreateMessenger(p2ptypes.GetTestRandPrivKey(), []string{sentryNetAddr}, validatorPort, validatorConfig)
+	assert.Nil(err)
+	assert.True(validator.nodeInfo.Private)
+	assert.Equal(0, len(validator.discMgr.seedPeerConnector.seedPeers()))
+	validator.Start(ctx)
+
+	for i := 0; i < 50 && sentry.peerTable.GetPeer(validator.ID()) == nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// The sentry keeps the address of the validator to itself
+	validatorPeer := sentry.peerTable.GetPeer(validator.ID())
+	if assert.NotNil(validatorPeer) {
+		assert.True(validatorPeer.IsPrivate())
+	}
+	assert.Equal(0, len(sentry.peerTable.GetSelection()))
+	assert.Equal(0, sentry.discMgr.addrBook.Size())
+
+	// The validator rejects the other peers
+	other := newTestMessenger([]string{validatorNetAddr}, otherPort)
+	other.Start(ctx)
+	<-other.discMgr.seedPeerConnector.Connected
+	assert.Nil(validator.peerTable.GetPeer(other.ID()))
+	assert.Equal(uint(1), validator.peerTable.GetTotalNumPeers())
+}
+
 // --------------- Test Utilities --------------- //
 
 // TestMessageHandler implements the MessageHandler interface
@@ -161,7 +216,7 @@ func (thm *TestMessageHandler) HandleMessage(message p2ptypes.Message) error {
 }
 
 func newTestMessenger(seedPeerNetAddressStrs []string, port int) *Messenger {
-	peerPubKey := p2ptypes.GetTestRandPubKey()
+	peerPrivKey := p2ptypes.GetTestRandPrivKey()
 	localNetworkAddress := "127.0.0.1:" + strconv.Itoa(port)
 	testMsgrConfig := MessengerConfig{
 		addrBookFilePath:    "./.addrbooks/addrbook_" + localNetworkAddress + ".json",
@@ -169,7 +224,7 @@ func newTestMessenger(seedPeerNetAddressStrs []string, port int) *Messenger {
 		skipUPNP:            true,
 		networkProtocol:     "tcp",
 	}
-	messenger, err := CreateMessenger(peerPubKey, seedPeerNetAddressStrs, port, testMsgrConfig)
+	messenger, err := CreateMessenger(peerPrivKey, seedPeerNetAddressStrs, port, testMsgrConfig)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create Messenger instance: %v", err))
 	}
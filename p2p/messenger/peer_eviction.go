@@ -0,0 +1,188 @@
+package messenger
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	pr "github.com/thetatoken/theta/p2p/peer"
+)
+
+const (
+	numProtectedByGroup  = 4 // peers from the rarest network groups kept regardless of the other criteria
+	numProtectedByScore  = 8 // highest scored peers kept
+	numProtectedByUptime = 8 // longest connected peers kept
+
+	peerScoreMessageHandled = 1   // score earned by a peer for each message handled
+	peerScoreMessageFailed  = -10 // score lost by a peer for each message failing to parse or handle
+)
+
+//
+// evictionCandidate is an inbound peer which may be disconnected to make room for a new one
+//
+type evictionCandidate struct {
+	id          string
+	group       string // network group of the peer address, see AddrBook.groupKey()
+	score       int64
+	connectedAt time.Time
+}
+
+// selectPeerToEvict selects the inbound peer to disconnect when the inbound peer limit is
+// reached. Peers from rare network groups, with the highest scores, and connected for the
+// longest time are protected in turn. Among the remaining peers, the most recently connected
+// peer of the most populated network group is evicted, so that the inbound peers stay diverse.
+// Ties are broken by the peer IDs, so the selection only depends on the candidates. It returns
+// an empty string if all the candidates are protected.
+func selectPeerToEvict(candidates []evictionCandidate) string {
+	remaining := append([]evictionCandidate{}, candidates...)
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i].id < remaining[j].id
+	})
+
+	groupSizes := make(map[string]int)
+	for _, c := range remaining {
+		groupSizes[c.group]++
+	}
+
+	remaining = protectCandidates(remaining, numProtectedByGroup, func(a, b evictionCandidate) bool {
+		if groupSizes[a.group] != groupSizes[b.group] {
+			return groupSizes[a.group] < groupSizes[b.group]
+		}
+		return a.connectedAt.Before(b.connectedAt)
+	})
+	remaining = protectCandidates(remaining, numProtectedByScore, func(a, b evictionCandidate) bool {
+		return a.score > b.score
+	})
+	remaining = protectCandidates(remaining, numProtectedByUptime, func(a, b evictionCandidate) bool {
+		return a.connectedAt.Before(b.connectedAt)
+	})
+	if len(remaining) == 0 {
+		return ""
+	}
+
+	groups := make(map[string][]evictionCandidate)
+	for _, c := range remaining {
+		groups[c.group] = append(groups[c.group], c)
+	}
+	evictedGroup := ""
+	maxGroupSize := math.MinInt32
+	for group, members := range groups {
+		if len(members) > maxGroupSize || (len(members) == maxGroupSize && group < evictedGroup) {
+			evictedGroup = group
+			maxGroupSize = len(members)
+		}
+	}
+
+	youngest := groups[evictedGroup][0]
+	for _, c := range groups[evictedGroup][1:] {
+		if c.connectedAt.After(youngest.connectedAt) {
+			youngest = c
+		}
+	}
+	return youngest.id
+}
+
+// protectCandidates removes the first numProtected candidates in the given order. The sort is
+// stable, so equal candidates keep the order of their IDs.
+func protectCandidates(candidates []evictionCandidate, numProtected int,
+	less func(a, b evictionCandidate) bool) []evictionCandidate {
+	if len(candidates) <= numProtected {
+		return nil
+	}
+	sorted := append([]evictionCandidate{}, candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted[numProtected:]
+}
+
+// SetPeerLimits sets the max numbers of inbound and outbound peers. 0 means no limit.
+func (discMgr *PeerDiscoveryManager) SetPeerLimits(maxNumInboundPeers, maxNumOutboundPeers uint) {
+	discMgr.maxNumInboundPeers = maxNumInboundPeers
+	discMgr.maxNumOutboundPeers = maxNumOutboundPeers
+}
+
+// SetPrivatePeering sets whether the node only peers with its persistent peers
+func (discMgr *PeerDiscoveryManager) SetPrivatePeering(privatePeering bool) {
+	discMgr.privatePeering = privatePeering
+}
+
+// numOutboundSlots returns the number of outbound peers the node can still connect to
+func (discMgr *PeerDiscoveryManager) numOutboundSlots() int {
+	if discMgr.maxNumOutboundPeers == 0 {
+		return math.MaxInt32
+	}
+	numSlots := int(discMgr.maxNumOutboundPeers) - int(discMgr.peerTable.GetNumOutboundPeers())
+	if numSlots < 0 {
+		return 0
+	}
+	return numSlots
+}
+
+// reservePeerSlot checks the peer limits before the peer is added to the peer table. When the
+// inbound peer limit is reached, it evicts an inbound peer for the new one, or rejects the new
+// peer if all the inbound peers are protected. The seed peers, the persistent peers, the
+// reconnecting peers, and the peers crawled in crawler mode do not count against the limits.
+// With private peering, all the other peers are rejected.
+func (discMgr *PeerDiscoveryManager) reservePeerSlot(peer *pr.Peer) error {
+	if discMgr.peerTable.PeerExists(peer.ID()) {
+		return nil
+	}
+
+	if discMgr.persistentPeerConnector.isAPersistentPeer(peer.NetAddress()) {
+		return nil
+	}
+	if discMgr.privatePeering {
+		return errors.New("Only the persistent peers are accepted with private peering")
+	}
+
+	if peer.IsOutbound() {
+		if discMgr.crawler.IsEnabled() || discMgr.seedPeerConnector.isASeedPeer(peer.NetAddress()) {
+			return nil
+		}
+		if discMgr.numOutboundSlots() == 0 {
+			return fmt.Errorf("Max number of outbound peers reached: %v", discMgr.maxNumOutboundPeers)
+		}
+		return nil
+	}
+
+	if discMgr.maxNumInboundPeers == 0 ||
+		discMgr.peerTable.GetNumInboundPeers() < discMgr.maxNumInboundPeers {
+		return nil
+	}
+
+	candidates := []evictionCandidate{}
+	for _, p := range *(discMgr.peerTable.GetAllPeers()) {
+		if p.IsOutbound() || p.IsPersistent() {
+			continue
+		}
+		if addr := p.NetAddress(); addr != nil && discMgr.seedPeerConnector.isASeedPeer(addr) {
+			continue
+		}
+		candidate := evictionCandidate{
+			id:          p.ID(),
+			score:       p.Score(),
+			connectedAt: p.ConnectedAt(),
+		}
+		if addr := p.NetAddress(); addr != nil {
+			candidate.group = discMgr.addrBook.groupKey(addr)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	evictedPeerID := selectPeerToEvict(candidates)
+	if evictedPeerID == "" {
+		return errors.New("Max number of inbound peers reached, and no inbound peer can be evicted")
+	}
+	evictedPeer := discMgr.peerTable.GetPeer(evictedPeerID)
+	if evictedPeer == nil {
+		return nil
+	}
+	logger.Infof("Evicting inbound peer %v for peer %v", evictedPeerID, peer.ID())
+	discMgr.peerTable.DeletePeer(evictedPeerID)
+	evictedPeer.SetPersistency(false)
+	evictedPeer.Stop()
+	return nil
+}
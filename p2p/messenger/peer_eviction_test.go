@@ -0,0 +1,49 @@
+package messenger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectPeerToEvict(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Now()
+	candidates := []evictionCandidate{}
+	// Peers from the same network group, connected one after another
+	for i := 0; i < 24; i++ {
+		candidates = append(candidates, evictionCandidate{
+			id:          fmt.Sprintf("peer%02d", i),
+			group:       "1.2.0.0/16",
+			connectedAt: start.Add(time.Duration(i) * time.Second),
+		})
+	}
+	// A lonely peer from a rare network group, connected last
+	candidates = append(candidates, evictionCandidate{
+		id:          "lonely",
+		group:       "5.6.0.0/16",
+		connectedAt: start.Add(time.Hour),
+	})
+	// A high score peer, also connected late
+	candidates[22].score = 100
+
+	// The youngest unprotected peer of the largest group is evicted
+	assert.Equal("peer23", selectPeerToEvict(candidates))
+
+	candidates[23].score = 50
+	assert.Equal("peer21", selectPeerToEvict(candidates))
+
+	// The selection does not depend on the order of the candidates
+	reversed := []evictionCandidate{}
+	for i := len(candidates) - 1; i >= 0; i-- {
+		reversed = append(reversed, candidates[i])
+	}
+	assert.Equal("peer21", selectPeerToEvict(reversed))
+
+	// No peer is evicted if all of them are protected
+	assert.Equal("", selectPeerToEvict(candidates[:numProtectedByGroup+numProtectedByScore+numProtectedByUptime]))
+	assert.Equal("", selectPeerToEvict(nil))
+}
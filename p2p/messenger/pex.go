@@ -0,0 +1,357 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p/netutil"
+	pr "github.com/thetatoken/theta/p2p/peer"
+	"github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// PEXMessageType defines the types of peer exchange message
+type PEXMessageType byte
+
+const (
+	pexRequestType   PEXMessageType = 0x01
+	pexAddressesType PEXMessageType = 0x02
+)
+
+const (
+	minPEXRequestInterval = 10 * time.Second // min interval between two requests served for the same peer
+	maxPEXClockDrift      = 10 * time.Minute // max tolerated drift of the reported connection times
+	maxPEXDialsPerRound   = 8                // max address book peers dialed per exchange round
+	pexDialNewBias        = 30               // % bias towards the addresses we have never connected to
+	maxPEXMessageSize     = 1048576          // 1MB
+)
+
+// PEXAddress is an address book entry exchanged between peers, together with the liveness
+// metadata observed by the peer sharing it.
+type PEXAddress struct {
+	Addr        *netutil.NetAddress
+	LastAttempt uint64 // Unix time of the last connection attempt
+	LastSuccess uint64 // Unix time of the last successful connection, 0 if never connected
+	Attempts    uint64 // Failed connection attempts since the last success
+}
+
+func newPEXAddress(ka *knownAddress) PEXAddress {
+	pexAddr := PEXAddress{
+		Addr:        ka.Addr,
+		LastAttempt: uint64(ka.LastAttempt.Unix()),
+		Attempts:    uint64(ka.Attempts),
+	}
+	if !ka.LastSuccess.IsZero() {
+		pexAddr.LastSuccess = uint64(ka.LastSuccess.Unix())
+	}
+	return pexAddr
+}
+
+// isStale returns true if the address was never reached after numRetries attempts, or was
+// last reached more than numMissingDays ago (or claims to be reached in the future).
+func (pexAddr PEXAddress) isStale(now time.Time) bool {
+	if pexAddr.LastSuccess == 0 {
+		return pexAddr.Attempts >= numRetries
+	}
+	lastSuccess := time.Unix(int64(pexAddr.LastSuccess), 0)
+	if lastSuccess.After(now.Add(maxPEXClockDrift)) {
+		return true
+	}
+	return lastSuccess.Before(now.Add(-numMissingDays * 24 * time.Hour))
+}
+
+// PEXMessage defines the structure of the peer exchange message. Both the request and the
+// reply carry a random subset of the sender's address book.
+type PEXMessage struct {
+	Type      PEXMessageType
+	Addresses []PEXAddress
+}
+
+//
+// PEXMessageHandler implements the MessageHandler interface. Peers periodically exchange
+// subsets of their address books, so that a node can find peers to connect to from its own
+// address book even when the seed peers are down.
+//
+type PEXMessageHandler struct {
+	discMgr  *PeerDiscoveryManager
+	enabled  bool
+	interval time.Duration
+
+	mu         *sync.Mutex
+	lastServed map[string]time.Time // peerID |-> time the last request was served
+	requested  map[string]bool      // peerIDs we are expecting addresses from
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	quit    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// createPEXMessageHandler creates an instance of PEXMessageHandler
+func createPEXMessageHandler(discMgr *PeerDiscoveryManager) PEXMessageHandler {
+	return PEXMessageHandler{
+		discMgr:    discMgr,
+		enabled:    viper.GetBool(common.CfgP2PPexEnabled),
+		interval:   time.Duration(viper.GetInt(common.CfgP2PPexInterval)) * time.Second,
+		mu:         &sync.Mutex{},
+		lastServed: make(map[string]time.Time),
+		requested:  make(map[string]bool),
+		wg:         &sync.WaitGroup{},
+	}
+}
+
+// Start is called when the message handler starts. The requests from other peers are served
+// even if the periodic exchange is disabled.
+func (pexmh *PEXMessageHandler) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	pexmh.ctx = c
+	pexmh.cancel = cancel
+
+	if pexmh.enabled && !pexmh.discMgr.privatePeering {
+		pexmh.wg.Add(1)
+		go pexmh.exchangeAddressesRoutine()
+	}
+
+	return nil
+}
+
+// Stop is called when the message handler stops
+func (pexmh *PEXMessageHandler) Stop() {
+	pexmh.cancel()
+}
+
+// Wait suspends the caller goroutine
+func (pexmh *PEXMessageHandler) Wait() {
+	pexmh.wg.Wait()
+}
+
+// GetChannelIDs implements the p2p.MessageHandler interface
+func (pexmh *PEXMessageHandler) GetChannelIDs() []common.ChannelIDEnum {
+	return []common.ChannelIDEnum{
+		common.ChannelIDPEX,
+	}
+}
+
+// EncodeMessage implements the p2p.MessageHandler interface
+func (pexmh *PEXMessageHandler) EncodeMessage(message interface{}) (common.Bytes, error) {
+	return rlp.EncodeToBytes(message)
+}
+
+// ParseMessage implements the p2p.MessageHandler interface
+func (pexmh *PEXMessageHandler) ParseMessage(peerID string,
+	channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (types.Message, error) {
+	pexMsg, err := decodePEXMessage(rawMessageBytes)
+	message := types.Message{
+		PeerID:    peerID,
+		ChannelID: channelID,
+		Content:   pexMsg,
+	}
+	if err != nil {
+		logger.Errorf("Error decoding PEXMessage: %v", err)
+		return message, err
+	}
+
+	return message, nil
+}
+
+func decodePEXMessage(msgBytes common.Bytes) (message PEXMessage, err error) {
+	if len(msgBytes) > maxPEXMessageSize {
+		err = fmt.Errorf("PEXMessage too large: %v bytes", len(msgBytes))
+		return
+	}
+	err = rlp.DecodeBytes(msgBytes, &message)
+	return
+}
+
+// HandleMessage implements the p2p.MessageHandler interface
+func (pexmh *PEXMessageHandler) HandleMessage(msg types.Message) error {
+	if msg.ChannelID != common.ChannelIDPEX {
+		errMsg := fmt.Sprintf("Invalid channelID for the PEXMessageHandler: %v", msg.ChannelID)
+		logger.Error(errMsg)
+		return errors.New(errMsg)
+	}
+
+	peer := pexmh.discMgr.peerTable.GetPeer(msg.PeerID)
+	if peer == nil {
+		errMsg := fmt.Sprintf("Cannot find peer %v in the peer table", msg.PeerID)
+		logger.Error(errMsg)
+		return errors.New(errMsg)
+	}
+
+	if pexmh.discMgr.privatePeering {
+		return nil // a private node neither shares nor collects peer addresses
+	}
+
+	pexMsg := (msg.Content).(PEXMessage)
+	if len(pexMsg.Addresses) > maxGetSelection {
+		errMsg := fmt.Sprintf("Too many addresses in PEXMessage from peer %v: %v", msg.PeerID, len(pexMsg.Addresses))
+		logger.Warn(errMsg)
+		return errors.New(errMsg)
+	}
+
+	switch pexMsg.Type {
+	case pexRequestType:
+		pexmh.handleRequest(peer, pexMsg)
+	case pexAddressesType:
+		pexmh.handleAddresses(peer, pexMsg)
+	default:
+		errMsg := "Invalid PEXMessageType"
+		logger.Error(errMsg)
+		return errors.New(errMsg)
+	}
+
+	return nil
+}
+
+func (pexmh *PEXMessageHandler) handleRequest(peer *pr.Peer, message PEXMessage) {
+	pexmh.mu.Lock()
+	now := time.Now()
+	if lastServed, ok := pexmh.lastServed[peer.ID()]; ok && now.Sub(lastServed) < minPEXRequestInterval {
+		pexmh.mu.Unlock()
+		logger.Debugf("Ignore PEX request from peer %v, requested too frequently", peer.ID())
+		return
+	}
+	pexmh.lastServed[peer.ID()] = now
+	pexmh.mu.Unlock()
+
+	pexmh.addAddresses(peer, message.Addresses)
+	pexmh.sendAddresses(peer, pexAddressesType)
+}
+
+func (pexmh *PEXMessageHandler) handleAddresses(peer *pr.Peer, message PEXMessage) {
+	pexmh.mu.Lock()
+	requested := pexmh.requested[peer.ID()]
+	delete(pexmh.requested, peer.ID())
+	pexmh.mu.Unlock()
+
+	if !requested {
+		logger.Debugf("Ignore unsolicited PEX addresses from peer %v", peer.ID())
+		return
+	}
+	pexmh.addAddresses(peer, message.Addresses)
+}
+
+func (pexmh *PEXMessageHandler) addAddresses(peer *pr.Peer, pexAddrs []PEXAddress) {
+	if len(pexAddrs) == 0 {
+		return
+	}
+	addrBook := pexmh.discMgr.addrBook
+	numAdded := addrBook.AddPEXAddresses(pexAddrs, peer.NetAddress())
+	logger.Debugf("Received %v PEX addresses from peer %v, accepted %v", len(pexAddrs), peer.ID(), numAdded)
+	if numAdded > 0 {
+		addrBook.Save()
+	}
+}
+
+func (pexmh *PEXMessageHandler) sendAddresses(peer *pr.Peer, msgType PEXMessageType) bool {
+	message := PEXMessage{
+		Type:      msgType,
+		Addresses: pexmh.discMgr.addrBook.GetSelectionWithLiveness(),
+	}
+	return peer.Send(common.ChannelIDPEX, message)
+}
+
+func (pexmh *PEXMessageHandler) exchangeAddressesRoutine() {
+	defer pexmh.wg.Done()
+
+	ticker := time.NewTicker(pexmh.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pexmh.ctx.Done():
+			pexmh.stopped = true
+			return
+		case <-ticker.C:
+			pexmh.exchangeAddresses()
+			pexmh.dialAddressBookPeers()
+		}
+	}
+}
+
+// exchangeAddresses sends our address book selection to a random connected peer, which
+// replies with its own selection.
+func (pexmh *PEXMessageHandler) exchangeAddresses() {
+	pexmh.mu.Lock()
+	now := time.Now()
+	for peerID, lastServed := range pexmh.lastServed {
+		if now.Sub(lastServed) >= minPEXRequestInterval {
+			delete(pexmh.lastServed, peerID)
+		}
+	}
+	// Requests not replied since the last round are dropped.
+	pexmh.requested = make(map[string]bool)
+	pexmh.mu.Unlock()
+
+	peers := *(pexmh.discMgr.peerTable.GetAllPeers())
+	if len(peers) == 0 || !pexmh.discMgr.addrBook.NeedMoreAddrs() {
+		return
+	}
+	peer := peers[rand.Intn(len(peers))]
+
+	pexmh.mu.Lock()
+	pexmh.requested[peer.ID()] = true
+	pexmh.mu.Unlock()
+
+	if !pexmh.sendAddresses(peer, pexRequestType) {
+		logger.Debugf("Failed to send PEX request to peer %v", peer.ID())
+	}
+}
+
+// dialAddressBookPeers connects to peers picked from the address book when the number of
+// connected peers is below the sufficient threshold, without relying on the seed peers.
+func (pexmh *PEXMessageHandler) dialAddressBookPeers() {
+	if pexmh.discMgr.crawler.IsEnabled() {
+		return // the crawler dials the address book peers on its own
+	}
+	numPeers := int(pexmh.discMgr.peerTable.GetTotalNumPeers())
+	numNeeded := int(GetDefaultPeerDiscoveryManagerConfig().SufficientNumPeers) - numPeers
+	if numNeeded <= 0 {
+		return
+	}
+	if numNeeded > maxPEXDialsPerRound {
+		numNeeded = maxPEXDialsPerRound
+	}
+	if numSlots := pexmh.discMgr.numOutboundSlots(); numNeeded > numSlots {
+		numNeeded = numSlots
+	}
+
+	skipped := make(map[string]bool)
+	for _, peer := range *(pexmh.discMgr.peerTable.GetAllPeers()) {
+		skipped[peer.NetAddress().String()] = true
+	}
+
+	addrBook := pexmh.discMgr.addrBook
+	for i := 0; i < numNeeded; i++ {
+		if pexmh.ctx.Err() != nil {
+			return
+		}
+		addr := addrBook.PickAddress(pexDialNewBias)
+		if addr == nil {
+			return
+		}
+		if skipped[addr.String()] {
+			continue
+		}
+		skipped[addr.String()] = true
+		if seedPeerOnlyOutbound() && !pexmh.discMgr.seedPeerConnector.isASeedPeer(addr) {
+			continue
+		}
+
+		addrBook.MarkAttempt(addr)
+		if _, err := pexmh.discMgr.connectToOutboundPeer(addr, false); err != nil {
+			logger.Debugf("Failed to connect to address book peer %v: %v", addr.String(), err)
+			continue
+		}
+		logger.Infof("Successfully connected to address book peer %v", addr.String())
+	}
+}
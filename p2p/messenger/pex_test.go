@@ -0,0 +1,66 @@
+package messenger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func TestPEXAddressIsStale(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	addr := randNetAddressPairs(t, 1)[0].addr
+
+	assert.False(PEXAddress{Addr: addr}.isStale(now))
+	assert.True(PEXAddress{Addr: addr, Attempts: numRetries}.isStale(now))
+
+	recent := uint64(now.Add(-time.Hour).Unix())
+	assert.False(PEXAddress{Addr: addr, LastSuccess: recent, Attempts: numRetries}.isStale(now))
+
+	old := uint64(now.Add(-(numMissingDays + 1) * 24 * time.Hour).Unix())
+	assert.True(PEXAddress{Addr: addr, LastSuccess: old}.isStale(now))
+
+	future := uint64(now.Add(time.Hour).Unix())
+	assert.True(PEXAddress{Addr: addr, LastSuccess: future}.isStale(now))
+}
+
+func TestAddrBookPEXExchange(t *testing.T) {
+	assert := assert.New(t)
+
+	book := NewAddrBook(createTempFileName("addrbook_test"), true)
+	randAddrs := randNetAddressPairs(t, 100)
+	for _, addrSrc := range randAddrs {
+		book.AddAddress(addrSrc.addr, addrSrc.src)
+	}
+	// Never reached addresses are not shared
+	unreachable := randAddrs[0].addr
+	for i := 0; i < numRetries; i++ {
+		book.MarkAttempt(unreachable)
+	}
+	book.MarkGood(randAddrs[1].addr)
+
+	selection := book.GetSelectionWithLiveness()
+	assert.Equal(minGetSelection, len(selection))
+	for _, pexAddr := range selection {
+		assert.NotEqual(unreachable.String(), pexAddr.Addr.String())
+	}
+
+	// The selection survives the wire encoding
+	raw, err := rlp.EncodeToBytes(PEXMessage{Type: pexAddressesType, Addresses: selection})
+	assert.Nil(err)
+	decoded := PEXMessage{}
+	assert.Nil(rlp.DecodeBytes(raw, &decoded))
+	assert.Equal(pexAddressesType, decoded.Type)
+	assert.Equal(len(selection), len(decoded.Addresses))
+
+	// Stale addresses are skipped by the receiver
+	other := NewAddrBook(createTempFileName("addrbook_test"), true)
+	stale := PEXAddress{Addr: randNetAddressPairs(t, 1)[0].addr, Attempts: numRetries}
+	src := randNetAddressPairs(t, 1)[0].addr
+	numAdded := other.AddPEXAddresses(append(decoded.Addresses, stale), src)
+	assert.Equal(len(decoded.Addresses), numAdded)
+	assert.Equal(len(decoded.Addresses), other.Size())
+}
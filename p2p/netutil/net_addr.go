@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -51,8 +52,9 @@ func NewNetAddressWithEnforcedPort(addr net.Addr, enforcedPort int) *NetAddress
 }
 
 // NewNetAddressString returns a new NetAddress using the provided
-// address in the form of "IP:Port". Also resolves the host if host
-// is not an IP.
+// address in the form of "IP:Port", or "[IP]:Port" for IPv6. Also
+// resolves the host if host is not an IP. An empty host stands for
+// all the interfaces, as in the address of a dual-stack listener.
 func NewNetAddressString(addr string) (*NetAddress, error) {
 
 	host, portStr, err := net.SplitHostPort(addr)
@@ -60,15 +62,21 @@ func NewNetAddressString(addr string) (*NetAddress, error) {
 		return nil, err
 	}
 
-	ip := net.ParseIP(host)
-	if ip == nil {
-		if len(host) > 0 {
-			ips, err := net.LookupIP(host)
-			if err != nil {
-				return nil, err
-			}
-			ip = ips[0]
+	var ip net.IP
+	if len(host) == 0 {
+		ip = net.IPv6unspecified
+	} else if ip = net.ParseIP(host); ip == nil {
+		if strings.Contains(host, ":") {
+			return nil, fmt.Errorf("Invalid IPv6 address: %v", host)
 		}
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("No IP address found for host: %v", host)
+		}
+		ip = ips[0]
 	}
 
 	port, err := strconv.ParseUint(portStr, 10, 16)
@@ -95,8 +103,13 @@ func NewNetAddressStrings(addrs []string) ([]*NetAddress, error) {
 }
 
 // NewNetAddressIPPort returns a new NetAddress using the provided IP
-// and port number.
+// and port number. IPv4-mapped IPv6 addresses, e.g. the addresses of
+// the IPv4 peers connected to a dual-stack listener, are converted to
+// IPv4 addresses.
 func NewNetAddressIPPort(ip net.IP, port uint16) *NetAddress {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
 	na := &NetAddress{
 		IP:   ip,
 		Port: port,
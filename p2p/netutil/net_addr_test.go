@@ -32,6 +32,8 @@ func TestNewNetAddressString(t *testing.T) {
 		correct bool
 	}{
 		{"127.0.0.1:8080", true},
+		{"[2001:4860::8888]:8080", true},
+		{"[::1]:8080", true},
 		// {"127.0.0:8080", false},
 		{"a", false},
 		{"127.0.0.1:a", false},
@@ -52,6 +54,34 @@ func TestNewNetAddressString(t *testing.T) {
 	}
 }
 
+func TestNewNetAddressStringIPv6(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	// IPv6 literals must be bracketed
+	_, err := NewNetAddressString("2001:4860::8888:8080")
+	assert.NotNil(err)
+	_, err = NewNetAddressString("[2001:4860::8888%eth0]:8080")
+	assert.NotNil(err)
+
+	// IPv4-mapped addresses are converted to IPv4
+	addr, err := NewNetAddressString("[::ffff:8.8.8.8]:8080")
+	require.Nil(err)
+	assert.Equal("8.8.8.8:8080", addr.String())
+	assert.True(addr.Equals(NewNetAddressIPPort(net.ParseIP("8.8.8.8"), 8080)))
+	assert.True(addr.Routable())
+
+	// An empty host stands for all the interfaces
+	addr, err = NewNetAddressString(":8080")
+	require.Nil(err)
+	assert.Equal("[::]:8080", addr.String())
+	assert.False(addr.Valid())
+
+	addr, err = NewNetAddressString("[2001:4860::8888]:8080")
+	require.Nil(err)
+	assert.True(addr.Routable())
+	assert.Equal(16, len(addr.IP))
+}
+
 func TestNewNetAddressStrings(t *testing.T) {
 	assert, require := assert.New(t), require.New(t)
 	addrs, err := NewNetAddressStrings([]string{"127.0.0.1:8080", "127.0.0.2:8080"})
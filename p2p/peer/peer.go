@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	cmn "github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/crypto"
 	cn "github.com/thetatoken/theta/p2p/connection"
 	nu "github.com/thetatoken/theta/p2p/netutil"
@@ -17,7 +20,7 @@ import (
 	"github.com/thetatoken/theta/rlp"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "p2p"})
+var logger *log.Entry = util.GetLoggerForModule("p2p")
 
 //
 // Peer models a peer node in a network
@@ -31,6 +34,12 @@ type Peer struct {
 
 	nodeInfo p2ptypes.NodeInfo // information of the blockchain node of the peer
 
+	protocolVersion uint32              // protocol version negotiated in the handshake
+	capabilities    p2ptypes.Capability // capabilities both sides support
+
+	connectedAt time.Time
+	score       int64 // accessed atomically
+
 	config PeerConfig
 
 	// Life cycle
@@ -86,6 +95,7 @@ func (peer *Peer) Start(ctx context.Context) bool {
 	c, cancel := context.WithCancel(ctx)
 	peer.ctx = c
 	peer.cancel = cancel
+	peer.connectedAt = time.Now()
 
 	success := peer.connection.Start(c)
 	return success
@@ -106,20 +116,30 @@ func (peer *Peer) Stop() {
 	peer.connection.Stop()
 }
 
-// Handshake handles the initial signaling between two peers
+// Handshake handles the initial signaling between two peers. It first establishes an encrypted
+// connection authenticated with the node keys, and then exchanges the node info, whose public
+// key must match the authenticated one. The peer ID is thus bound to the remote node key.
 // NOTE: need to call peer.Handshake() before peer.Start()
-func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
+func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo, privKey *crypto.PrivateKey) error {
 	remoteAddr := peer.connection.GetNetconn().RemoteAddr()
 	logger.Infof("Handshake with %v...", remoteAddr)
 
 	timeout := peer.config.HandshakeTimeout
 	peer.connection.GetNetconn().SetDeadline(time.Now().Add(timeout))
+
+	secretConn, err := cn.MakeSecretConnection(peer.connection.GetNetconn(), privKey)
+	if err != nil {
+		logger.Errorf("Error during handshake/secret connection: %v", err)
+		return err
+	}
+	peer.connection.SetNetconn(secretConn)
+
 	var sendError error
 	var recvError error
 	targetPeerNodeInfo := p2ptypes.NodeInfo{}
 	cmn.Parallel(
-		func() { sendError = rlp.Encode(peer.connection.GetNetconn(), sourceNodeInfo) },
-		func() { recvError = rlp.Decode(peer.connection.GetNetconn(), &targetPeerNodeInfo) },
+		func() { sendError = rlp.Encode(secretConn, sourceNodeInfo) },
+		func() { recvError = rlp.Decode(secretConn, &targetPeerNodeInfo) },
 	)
 	if sendError != nil {
 		logger.Errorf("Error during handshake/send: %v", sendError)
@@ -136,15 +156,30 @@ func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
 		logger.Errorf("Error during handshake/recv: %v", err)
 		return err
 	}
+	if targetNodePubKey.Address() != secretConn.RemotePubKey().Address() {
+		errMsg := fmt.Sprintf("Node info public key %v does not match the authenticated key %v",
+			targetNodePubKey.Address().Hex(), secretConn.RemotePubKey().Address().Hex())
+		logger.Errorf("Error during handshake/recv: %v", errMsg)
+		return errors.New(errMsg)
+	}
+	version, capabilities, err := p2ptypes.NegotiateProtocol(sourceNodeInfo, &targetPeerNodeInfo)
+	if err != nil {
+		logger.Errorf("Error during handshake/recv: %v", err)
+		return err
+	}
 	targetPeerNodeInfo.PubKey = targetNodePubKey
 	peer.nodeInfo = targetPeerNodeInfo
+	peer.protocolVersion = version
+	peer.capabilities = capabilities
+	peer.connection.NegotiateCompression(sourceNodeInfo.Compression, targetPeerNodeInfo.Compression)
+	peer.connection.NegotiateFlowControl(sourceNodeInfo.RecvWindow, targetPeerNodeInfo.RecvWindow)
 
 	if !peer.isOutbound {
 		peer.SetNetAddress(nu.NewNetAddressWithEnforcedPort(netconn.RemoteAddr(), int(peer.nodeInfo.Port)))
 	}
 
-	logger.Infof("Handshake completed, target address: %v, target public key: %v",
-		remoteAddr, hex.EncodeToString(targetNodePubKey.ToBytes()))
+	logger.Infof("Handshake completed, target address: %v, target public key: %v, protocol version: %v, capabilities: %v",
+		remoteAddr, hex.EncodeToString(targetNodePubKey.ToBytes()), version, capabilities)
 
 	return nil
 }
@@ -202,6 +237,47 @@ func (peer *Peer) NetAddress() *nu.NetAddress {
 	return peer.netAddress
 }
 
+// NodeVersion returns the software version the peer reported in the handshake
+func (peer *Peer) NodeVersion() string {
+	return peer.nodeInfo.Version
+}
+
+// IsPrivate returns whether the peer asked not to share its address, e.g. a validator behind
+// sentry nodes
+func (peer *Peer) IsPrivate() bool {
+	return peer.nodeInfo.Private
+}
+
+// ProtocolVersion returns the protocol version negotiated with the peer
+func (peer *Peer) ProtocolVersion() uint32 {
+	return peer.protocolVersion
+}
+
+// Capabilities returns the capabilities both the node and the peer support
+func (peer *Peer) Capabilities() p2ptypes.Capability {
+	return peer.capabilities
+}
+
+// HasCapability returns whether both the node and the peer support the capability
+func (peer *Peer) HasCapability(capability p2ptypes.Capability) bool {
+	return peer.capabilities.Has(capability)
+}
+
+// ConnectedAt returns the time the peer started
+func (peer *Peer) ConnectedAt() time.Time {
+	return peer.connectedAt
+}
+
+// AdjustScore adds the delta to the score of the peer, which rates how useful the peer is
+func (peer *Peer) AdjustScore(delta int64) {
+	atomic.AddInt64(&peer.score, delta)
+}
+
+// Score returns the score of the peer
+func (peer *Peer) Score() int64 {
+	return atomic.LoadInt64(&peer.score)
+}
+
 // ID returns the unique idenitifier of the peer in the P2P network
 func (peer *Peer) ID() string {
 	peerID := peer.nodeInfo.PubKey.Address() // use the blockchain address as the peer ID
@@ -111,18 +111,22 @@ func (pt *PeerTable) GetAllPeers() *([]*Peer) {
 	return &pt.peers
 }
 
-// GetSelection randomly selects some peers. Suitable for peer-exchange protocols.
+// GetSelection randomly selects some peers. Suitable for peer-exchange protocols. The private
+// peers are never selected.
 func (pt *PeerTable) GetSelection() (peerIDAddrs []PeerIDAddress) {
 	pt.mutex.Lock()
 	defer pt.mutex.Unlock()
 
-	if len(pt.peers) == 0 {
+	peers := make([]*Peer, 0, len(pt.peers))
+	for _, peer := range pt.peers {
+		if !peer.IsPrivate() {
+			peers = append(peers, peer)
+		}
+	}
+	if len(peers) == 0 {
 		return nil
 	}
 
-	peers := make([]*Peer, len(pt.peers))
-	copy(peers, pt.peers)
-
 	numPeers := mm.MaxInt(
 		mm.MinInt(minGetSelection, len(peers)),
 		len(peers)*getSelectionPercent/100)
@@ -155,3 +159,31 @@ func (pt *PeerTable) GetTotalNumPeers() uint {
 
 	return uint(len(pt.peers))
 }
+
+// GetNumInboundPeers returns the number of inbound peers in the PeerTable
+func (pt *PeerTable) GetNumInboundPeers() uint {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	numInbound := uint(0)
+	for _, peer := range pt.peers {
+		if !peer.IsOutbound() {
+			numInbound++
+		}
+	}
+	return numInbound
+}
+
+// GetNumOutboundPeers returns the number of outbound peers in the PeerTable
+func (pt *PeerTable) GetNumOutboundPeers() uint {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	numOutbound := uint(0)
+	for _, peer := range pt.peers {
+		if peer.IsOutbound() {
+			numOutbound++
+		}
+	}
+	return numOutbound
+}
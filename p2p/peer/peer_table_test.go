@@ -138,6 +138,26 @@ func TestDefaultPeerIterationOrder(t *testing.T) {
 
 // --------------- Test Utilities --------------- //
 
+func TestPeerTableGetSelectionSkipsPrivatePeers(t *testing.T) {
+	assert := assert.New(t)
+
+	port := 37859
+	netconn := newIncomingNetconn(port)
+
+	pt := newTestEmptyPeerTable()
+	privatePeer := newSimulatedInboundPeer(netconn, p2ptypes.GetTestRandPubKey())
+	privatePeer.nodeInfo.Private = true
+	pt.AddPeer(privatePeer)
+	assert.True(privatePeer.IsPrivate())
+	assert.Equal(0, len(pt.GetSelection()))
+
+	publicPeer := newSimulatedInboundPeer(netconn, p2ptypes.GetTestRandPubKey())
+	pt.AddPeer(publicPeer)
+	selection := pt.GetSelection()
+	assert.Equal(1, len(selection))
+	assert.Equal(publicPeer.ID(), selection[0].ID)
+}
+
 func newTestEmptyPeerTable() PeerTable {
 	pt := CreatePeerTable()
 	return pt
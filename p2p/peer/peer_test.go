@@ -39,9 +39,9 @@ func TestPeerHandshakeAndCommunication(t *testing.T) {
 
 	go func() {
 		outboundPeer := newOutboundPeer("127.0.0.1:" + strconv.Itoa(port))
-		randPeerPubKey := p2ptypes.GetTestRandPubKey()
-		peerANodeInfo := p2ptypes.CreateNodeInfo(randPeerPubKey, uint16(port))
-		err := outboundPeer.Handshake(&peerANodeInfo) // send out PeerA's node info
+		randPeerPrivKey := p2ptypes.GetTestRandPrivKey()
+		peerANodeInfo := p2ptypes.CreateNodeInfo(randPeerPrivKey.PublicKey(), uint16(port))
+		err := outboundPeer.Handshake(&peerANodeInfo, randPeerPrivKey) // send out PeerA's node info
 		assert.Nil(err)
 		assert.True(outboundPeer.IsOutbound())
 
@@ -74,9 +74,9 @@ func TestPeerHandshakeAndCommunication(t *testing.T) {
 
 	// Handshake checks
 	inboundPeer := newInboundPeer(netconn)
-	peerBPubKey := p2ptypes.GetTestRandPubKey()
-	peerBNodeInfo := p2ptypes.CreateNodeInfo(peerBPubKey, uint16(port))
-	err = inboundPeer.Handshake(&peerBNodeInfo) // send out PeerB's node info
+	peerBPrivKey := p2ptypes.GetTestRandPrivKey()
+	peerBNodeInfo := p2ptypes.CreateNodeInfo(peerBPrivKey.PublicKey(), uint16(port))
+	err = inboundPeer.Handshake(&peerBNodeInfo, peerBPrivKey) // send out PeerB's node info
 	assert.Nil(err)
 	assert.False(inboundPeer.IsOutbound())
 
@@ -0,0 +1,401 @@
+package simulation
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/p2p"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+var memnetLogger = util.GetLoggerForModule("memnet")
+
+// LinkConfig describes the conditions of the link from one endpoint to another.
+type LinkConfig struct {
+	Latency  time.Duration // One-way delay of the messages
+	Jitter   time.Duration // Maximum random delay added to the latency
+	DropRate float64       // Probability that a message is lost, between 0 and 1
+}
+
+// MemNetworkStats counts the messages sent through a MemNetwork.
+type MemNetworkStats struct {
+	Delivered uint64
+	Dropped   uint64 // Lost to the drop rate or to a partition while in flight
+}
+
+//
+// MemNetwork is an in-memory network connecting the endpoints added to it, so that several
+// node.Node instances can be run in one process, e.g. to test sync and consensus. Unlike the
+// Simnet, the messages go through the encoding and parsing of the message handlers of their
+// channels, and are delivered after the latency of their link, in order per link. The links may
+// lose messages, and the network may be split into partitions. The random delays and drops are
+// drawn from a seeded source, for reproducible simulations.
+//
+type MemNetwork struct {
+	mu          *sync.Mutex
+	rand        *rand.Rand
+	endpoints   map[string]*MemEndpoint
+	defaultLink LinkConfig
+	links       map[memLinkKey]LinkConfig
+	partitions  map[string]int // Partition of the endpoints, nil if the network is whole
+	stats       MemNetworkStats
+}
+
+type memLinkKey struct {
+	from string
+	to   string
+}
+
+// NewMemNetwork creates an instance of MemNetwork. The links have no latency and lose no messages
+// unless configured otherwise.
+func NewMemNetwork(seed int64) *MemNetwork {
+	return &MemNetwork{
+		mu:        &sync.Mutex{},
+		rand:      rand.New(rand.NewSource(seed)),
+		endpoints: make(map[string]*MemEndpoint),
+		links:     make(map[memLinkKey]LinkConfig),
+	}
+}
+
+// AddEndpoint adds an endpoint with the given ID to the network. It is connected to all the other
+// endpoints.
+func (mn *MemNetwork) AddEndpoint(id string) *MemEndpoint {
+	endpoint := &MemEndpoint{
+		id:       id,
+		network:  mn,
+		handlers: make(map[common.ChannelIDEnum]p2p.MessageHandler),
+		links:    make(map[string]*memLink),
+		mu:       &sync.Mutex{},
+		wg:       &sync.WaitGroup{},
+	}
+
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.endpoints[id] = endpoint
+	return endpoint
+}
+
+// SetDefaultLink sets the conditions of the links without their own configuration.
+func (mn *MemNetwork) SetDefaultLink(config LinkConfig) {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.defaultLink = config
+}
+
+// SetLink sets the conditions of the link from one endpoint to another. The link in the opposite
+// direction is not affected.
+func (mn *MemNetwork) SetLink(from, to string, config LinkConfig) {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.links[memLinkKey{from: from, to: to}] = config
+}
+
+// Partition splits the network into the given groups of endpoints. The endpoints can only reach
+// the endpoints of their group, and the endpoints in no group are isolated. The messages in flight
+// between the groups are lost.
+func (mn *MemNetwork) Partition(groups ...[]string) {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.partitions = make(map[string]int)
+	for i, group := range groups {
+		for _, id := range group {
+			mn.partitions[id] = i
+		}
+	}
+}
+
+// Heal removes the partitions of the network.
+func (mn *MemNetwork) Heal() {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	mn.partitions = nil
+}
+
+// Stats returns the number of messages delivered and dropped so far.
+func (mn *MemNetwork) Stats() MemNetworkStats {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	return mn.stats
+}
+
+// connected returns whether the endpoint from can reach the endpoint to. The caller must hold
+// the lock.
+func (mn *MemNetwork) connected(from, to string) bool {
+	if from == to || mn.endpoints[to] == nil {
+		return false
+	}
+	if mn.partitions == nil {
+		return true
+	}
+	fromGroup, ok := mn.partitions[from]
+	if !ok {
+		return false
+	}
+	toGroup, ok := mn.partitions[to]
+	return ok && fromGroup == toGroup
+}
+
+// peers returns the IDs of the endpoints the given endpoint can reach, sorted for reproducibility.
+func (mn *MemNetwork) peers(id string) []string {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	peers := []string{}
+	for peerID := range mn.endpoints {
+		if mn.connected(id, peerID) {
+			peers = append(peers, peerID)
+		}
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// route draws the delay of a message sent from one endpoint to another, and whether it is lost. It
+// returns false if the destination cannot be reached.
+func (mn *MemNetwork) route(from, to string) (delay time.Duration, drop bool, ok bool) {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+
+	if !mn.connected(from, to) {
+		return 0, false, false
+	}
+	config, exists := mn.links[memLinkKey{from: from, to: to}]
+	if !exists {
+		config = mn.defaultLink
+	}
+	if config.DropRate > 0 && mn.rand.Float64() < config.DropRate {
+		mn.stats.Dropped++
+		return 0, true, true
+	}
+	delay = config.Latency
+	if config.Jitter > 0 {
+		delay += time.Duration(mn.rand.Int63n(int64(config.Jitter) + 1))
+	}
+	return delay, false, true
+}
+
+// arrive returns the destination endpoint of a message leaving its link, nil if the message is lost
+// since the endpoints have been partitioned while it was in flight.
+func (mn *MemNetwork) arrive(from, to string) *MemEndpoint {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+
+	if !mn.connected(from, to) {
+		mn.stats.Dropped++
+		return nil
+	}
+	mn.stats.Delivered++
+	return mn.endpoints[to]
+}
+
+//
+// MemEndpoint is the implementation of the Network interface for MemNetwork.
+//
+type MemEndpoint struct {
+	id       string
+	network  *MemNetwork
+	handlers map[common.ChannelIDEnum]p2p.MessageHandler
+
+	mu    *sync.Mutex
+	links map[string]*memLink // Outgoing links by the ID of their destination
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+var _ p2p.Network = (*MemEndpoint)(nil)
+
+// memLink delivers the messages sent to one endpoint in order.
+type memLink struct {
+	to            string
+	queue         chan memPacket
+	lastDeliverAt time.Time
+}
+
+type memPacket struct {
+	channelID common.ChannelIDEnum
+	bytes     common.Bytes
+	deliverAt time.Time
+}
+
+// Start implements the Network interface.
+func (me *MemEndpoint) Start(ctx context.Context) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	me.ctx, me.cancel = context.WithCancel(ctx)
+	return nil
+}
+
+// Stop implements the Network interface. The messages in flight from and to the endpoint are lost.
+func (me *MemEndpoint) Stop() {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	if me.cancel != nil {
+		me.cancel()
+	}
+	me.stopped = true
+}
+
+// Wait implements the Network interface.
+func (me *MemEndpoint) Wait() {
+	me.wg.Wait()
+}
+
+// ID implements the Network interface.
+func (me *MemEndpoint) ID() string {
+	return me.id
+}
+
+// RegisterMessageHandler implements the Network interface.
+func (me *MemEndpoint) RegisterMessageHandler(messageHandler p2p.MessageHandler) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	for _, channelID := range messageHandler.GetChannelIDs() {
+		if me.handlers[channelID] != nil {
+			memnetLogger.Errorf("Message handler is already added for channelID: %v", channelID)
+			continue
+		}
+		me.handlers[channelID] = messageHandler
+	}
+}
+
+// PeerInfos returns the endpoints the endpoint can reach.
+func (me *MemEndpoint) PeerInfos() []p2ptypes.PeerInfo {
+	peers := me.network.peers(me.id)
+	infos := make([]p2ptypes.PeerInfo, 0, len(peers))
+	for _, peerID := range peers {
+		infos = append(infos, p2ptypes.PeerInfo{ID: peerID})
+	}
+	return infos
+}
+
+// Broadcast implements the Network interface.
+func (me *MemEndpoint) Broadcast(message p2ptypes.Message) (successes chan bool) {
+	peers := me.network.peers(me.id)
+	successes = make(chan bool, len(peers))
+	for _, peerID := range peers {
+		go func(peerID string) {
+			successes <- me.Send(peerID, message)
+		}(peerID)
+	}
+	return successes
+}
+
+// Send implements the Network interface. It returns false if the peer cannot be reached or its link
+// is congested. A message lost to the drop rate of the link counts as sent.
+func (me *MemEndpoint) Send(peerID string, message p2ptypes.Message) bool {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	if me.ctx == nil || me.stopped {
+		return false
+	}
+	handler := me.handlers[message.ChannelID]
+	if handler == nil {
+		memnetLogger.Errorf("No message handler for channelID %v", message.ChannelID)
+		return false
+	}
+	bytes, err := handler.EncodeMessage(message.Content)
+	if err != nil {
+		memnetLogger.WithFields(log.Fields{"channel": message.ChannelID, "error": err}).Warn("Failed to encode message")
+		return false
+	}
+
+	delay, drop, ok := me.network.route(me.id, peerID)
+	if !ok {
+		return false
+	}
+	if drop {
+		return true
+	}
+
+	link := me.links[peerID]
+	if link == nil {
+		link = &memLink{
+			to:    peerID,
+			queue: make(chan memPacket, viper.GetInt(common.CfgP2PMessageQueueSize)),
+		}
+		me.links[peerID] = link
+		me.wg.Add(1)
+		go me.deliverLoop(link)
+	}
+
+	// The messages of a link are delivered in order, like over a connection
+	deliverAt := time.Now().Add(delay)
+	if deliverAt.Before(link.lastDeliverAt) {
+		deliverAt = link.lastDeliverAt
+	}
+	select {
+	case link.queue <- memPacket{channelID: message.ChannelID, bytes: bytes, deliverAt: deliverAt}:
+		link.lastDeliverAt = deliverAt
+		return true
+	default:
+		return false
+	}
+}
+
+func (me *MemEndpoint) deliverLoop(link *memLink) {
+	defer me.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-me.ctx.Done():
+			return
+		case packet := <-link.queue:
+			if wait := time.Until(packet.deliverAt); wait > 0 {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(wait)
+				select {
+				case <-me.ctx.Done():
+					return
+				case <-timer.C:
+				}
+			}
+			if to := me.network.arrive(me.id, link.to); to != nil {
+				to.receive(me.id, packet)
+			}
+		}
+	}
+}
+
+// receive parses the message with the handler of its channel and hands it over. The message is
+// lost if the endpoint is not running.
+func (me *MemEndpoint) receive(from string, packet memPacket) {
+	me.mu.Lock()
+	running := me.ctx != nil && !me.stopped
+	handler := me.handlers[packet.channelID]
+	me.mu.Unlock()
+
+	if !running {
+		return
+	}
+	if handler == nil {
+		memnetLogger.Errorf("No message handler for channelID %v", packet.channelID)
+		return
+	}
+	receivedAt := time.Now()
+	message, err := handler.ParseMessage(from, packet.channelID, packet.bytes)
+	if err != nil {
+		memnetLogger.WithFields(log.Fields{"peer": from, "channel": packet.channelID, "error": err}).Warn("Failed to parse message")
+		return
+	}
+	message.ReceivedAt = receivedAt
+	handler.HandleMessage(message)
+}
@@ -0,0 +1,207 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// recordingHandler decodes the messages as strings and records them in order.
+type recordingHandler struct {
+	channelIDs []common.ChannelIDEnum
+
+	lock     *sync.Mutex
+	received []p2ptypes.Message
+}
+
+func newRecordingHandler(channelIDs ...common.ChannelIDEnum) *recordingHandler {
+	return &recordingHandler{
+		channelIDs: channelIDs,
+		lock:       &sync.Mutex{},
+	}
+}
+
+func (rh *recordingHandler) GetChannelIDs() []common.ChannelIDEnum {
+	return rh.channelIDs
+}
+
+func (rh *recordingHandler) EncodeMessage(message interface{}) (common.Bytes, error) {
+	return rlp.EncodeToBytes(message)
+}
+
+func (rh *recordingHandler) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
+	var content string
+	err := rlp.DecodeBytes(rawMessageBytes, &content)
+	return p2ptypes.Message{
+		PeerID:    peerID,
+		ChannelID: channelID,
+		Content:   content,
+	}, err
+}
+
+func (rh *recordingHandler) HandleMessage(message p2ptypes.Message) error {
+	rh.lock.Lock()
+	defer rh.lock.Unlock()
+	rh.received = append(rh.received, message)
+	return nil
+}
+
+func (rh *recordingHandler) contents() []string {
+	rh.lock.Lock()
+	defer rh.lock.Unlock()
+	contents := []string{}
+	for _, message := range rh.received {
+		contents = append(contents, fmt.Sprintf("%v: %v", message.PeerID, message.Content))
+	}
+	return contents
+}
+
+func (rh *recordingHandler) count() int {
+	rh.lock.Lock()
+	defer rh.lock.Unlock()
+	return len(rh.received)
+}
+
+func startMemNetwork(ctx context.Context, mn *MemNetwork, ids ...string) (map[string]*MemEndpoint, map[string]*recordingHandler) {
+	endpoints := make(map[string]*MemEndpoint)
+	handlers := make(map[string]*recordingHandler)
+	for _, id := range ids {
+		endpoint := mn.AddEndpoint(id)
+		handler := newRecordingHandler(common.ChannelIDBlock)
+		endpoint.RegisterMessageHandler(handler)
+		endpoint.Start(ctx)
+		endpoints[id] = endpoint
+		handlers[id] = handler
+	}
+	return endpoints, handlers
+}
+
+func blockMessage(content string) p2ptypes.Message {
+	return p2ptypes.Message{
+		ChannelID: common.ChannelIDBlock,
+		Content:   content,
+	}
+}
+
+func TestMemNetworkLatency(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn := NewMemNetwork(1)
+	mn.SetDefaultLink(LinkConfig{Latency: 100 * time.Millisecond, Jitter: 50 * time.Millisecond})
+	endpoints, handlers := startMemNetwork(ctx, mn, "e1", "e2", "e3")
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		require.True(endpoints["e1"].Send("e2", blockMessage(fmt.Sprintf("m%d", i))))
+	}
+	require.Eventually(func() bool { return handlers["e2"].count() == 10 }, 2*time.Second, 10*time.Millisecond)
+	assert.True(time.Since(start) >= 100*time.Millisecond)
+
+	// The messages of a link arrive in order despite the jitter
+	for i, content := range handlers["e2"].contents() {
+		assert.Equal(fmt.Sprintf("e1: m%d", i), content)
+	}
+	assert.Equal(0, handlers["e1"].count())
+	assert.Equal(0, handlers["e3"].count())
+	for _, message := range handlers["e2"].received {
+		assert.False(message.ReceivedAt.IsZero())
+	}
+
+	// Broadcasts reach every other endpoint
+	successes := endpoints["e3"].Broadcast(blockMessage("hello"))
+	for i := 0; i < 2; i++ {
+		assert.True(<-successes)
+	}
+	require.Eventually(func() bool { return handlers["e1"].count() == 1 && handlers["e2"].count() == 11 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal([]string{"e3: hello"}, handlers["e1"].contents())
+	assert.Equal(0, handlers["e3"].count())
+
+	assert.False(endpoints["e1"].Send("e4", blockMessage("unknown")))
+	assert.False(endpoints["e1"].Send("e2", p2ptypes.Message{ChannelID: common.ChannelIDVote, Content: "unhandled"}))
+}
+
+func TestMemNetworkDrops(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn := NewMemNetwork(1)
+	endpoints, handlers := startMemNetwork(ctx, mn, "e1", "e2")
+	mn.SetLink("e1", "e2", LinkConfig{DropRate: 0.5})
+
+	for i := 0; i < 200; i++ {
+		assert.True(endpoints["e1"].Send("e2", blockMessage("lossy")))
+		assert.True(endpoints["e2"].Send("e1", blockMessage("reliable")))
+	}
+	require.Eventually(func() bool {
+		stats := mn.Stats()
+		return stats.Delivered+stats.Dropped == 400
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(200, handlers["e1"].count())
+	received := handlers["e2"].count()
+	assert.True(received > 50 && received < 150, "received %v of the 200 messages", received)
+	assert.Equal(uint64(200-received), mn.Stats().Dropped)
+}
+
+func TestMemNetworkPartition(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn := NewMemNetwork(1)
+	endpoints, handlers := startMemNetwork(ctx, mn, "e1", "e2", "e3", "e4")
+
+	mn.Partition([]string{"e1", "e2"}, []string{"e3"})
+	assert.Equal([]p2ptypes.PeerInfo{{ID: "e2"}}, endpoints["e1"].PeerInfos())
+	assert.Empty(endpoints["e4"].PeerInfos())
+	assert.False(endpoints["e1"].Send("e3", blockMessage("cut")))
+	assert.False(endpoints["e4"].Send("e1", blockMessage("isolated")))
+
+	successes := endpoints["e1"].Broadcast(blockMessage("group"))
+	assert.True(<-successes)
+	require.Eventually(func() bool { return handlers["e2"].count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(0, handlers["e3"].count())
+	assert.Equal(0, handlers["e4"].count())
+
+	// The messages in flight across the new partition are lost
+	mn.Heal()
+	mn.SetLink("e1", "e3", LinkConfig{Latency: 200 * time.Millisecond})
+	assert.True(endpoints["e1"].Send("e3", blockMessage("in flight")))
+	mn.Partition([]string{"e1"}, []string{"e3"})
+	require.Eventually(func() bool { return mn.Stats().Dropped == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(0, handlers["e3"].count())
+
+	mn.Heal()
+	assert.Len(endpoints["e1"].PeerInfos(), 3)
+	assert.True(endpoints["e1"].Send("e3", blockMessage("healed")))
+	require.Eventually(func() bool { return handlers["e3"].count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal([]string{"e1: healed"}, handlers["e3"].contents())
+}
+
+func TestMemNetworkStop(t *testing.T) {
+	assert := assert.New(t)
+
+	mn := NewMemNetwork(1)
+	endpoints, handlers := startMemNetwork(context.Background(), mn, "e1", "e2")
+	mn.SetDefaultLink(LinkConfig{Latency: time.Second})
+
+	assert.True(endpoints["e1"].Send("e2", blockMessage("lost")))
+	endpoints["e1"].Stop()
+	endpoints["e1"].Wait()
+	assert.False(endpoints["e1"].Send("e2", blockMessage("stopped")))
+	assert.Equal(0, handlers["e2"].count())
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+)
+
+const (
+	// ProtocolVersion is the version of the p2p protocol implemented by the node. It is bumped when
+	// the format of an existing message changes, while new messages are introduced as capabilities.
+	ProtocolVersion = uint32(1)
+
+	// MinProtocolVersion is the oldest protocol version of the peers the node connects to. Version
+	// 0 is the protocol of the nodes which do not advertise a version.
+	MinProtocolVersion = uint32(0)
+)
+
+// Capability is a set of flags of the optional protocol features. A feature is only used on a
+// connection if both peers advertise it in the handshake, so that the nodes supporting it can roll
+// it out without disconnecting from the nodes which do not.
+type Capability uint64
+
+const (
+	// CapabilityTxGossip announces the new transactions by their hashes over ChannelIDTxGossip,
+	// instead of pushing the full transactions over ChannelIDTransaction
+	CapabilityTxGossip Capability = 1 << iota
+	// CapabilityStateSync serves the state trie chunks over ChannelIDState
+	CapabilityStateSync
+	// CapabilityTxReconcile periodically exchanges the sketches of the mempool transaction hashes
+	// over ChannelIDTxReconcile, to recover the transactions missed by the gossip
+	CapabilityTxReconcile
+	// CapabilityBlockParts serves the block headers over ChannelIDHeader and the block bodies over
+	// ChannelIDBlockBody, for the light clients and the peers syncing header first. The node itself
+	// downloads the full blocks
+	CapabilityBlockParts
+	// CapabilityChainMux carries the messages of the additional chains hosted by the node, e.g. a
+	// subchain along with the mainnet, over ChannelIDChainMux
+	CapabilityChainMux
+)
+
+// SupportedCapabilities are the capabilities implemented by the node.
+const SupportedCapabilities = CapabilityTxGossip | CapabilityStateSync | CapabilityTxReconcile | CapabilityBlockParts | CapabilityChainMux
+
+// channelCapabilities lists the channels whose messages only the peers with the capability parse
+var channelCapabilities = map[common.ChannelIDEnum]Capability{
+	common.ChannelIDTxGossip:    CapabilityTxGossip,
+	common.ChannelIDState:       CapabilityStateSync,
+	common.ChannelIDTxReconcile: CapabilityTxReconcile,
+	common.ChannelIDHeader:      CapabilityBlockParts,
+	common.ChannelIDBlockBody:   CapabilityBlockParts,
+	common.ChannelIDChainMux:    CapabilityChainMux,
+}
+
+// Has returns whether all the capabilities of c are in the set.
+func (s Capability) Has(c Capability) bool {
+	return s&c == c
+}
+
+func (s Capability) String() string {
+	return fmt.Sprintf("%#x", uint64(s))
+}
+
+// RequiredCapability returns the capability a peer needs to receive the messages of the channel,
+// 0 if the channel is part of the base protocol.
+func RequiredCapability(channelID common.ChannelIDEnum) Capability {
+	return channelCapabilities[channelID]
+}
+
+// NegotiateProtocol returns the protocol version and the capabilities used on the connection
+// between the nodes, i.e. the lower of their versions and the capabilities both of them advertise.
+// It returns an error if the remote version is older than the minimum version supported locally.
+func NegotiateProtocol(local, remote *NodeInfo) (uint32, Capability, error) {
+	if remote.ProtocolVersion < MinProtocolVersion {
+		return 0, 0, fmt.Errorf("Peer protocol version %v is older than the min version %v",
+			remote.ProtocolVersion, MinProtocolVersion)
+	}
+	version := local.ProtocolVersion
+	if remote.ProtocolVersion < version {
+		version = remote.ProtocolVersion
+	}
+	return version, local.Capabilities & remote.Capabilities, nil
+}
@@ -39,6 +39,15 @@ func GetTestListener(port int) net.Listener {
 	return listener
 }
 
+// GetTestRandPrivKey returns a randomly generated private key
+func GetTestRandPrivKey() *crypto.PrivateKey {
+	randPrivKey, _, err := crypto.GenerateKeyPair()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate a random private key: %v", err))
+	}
+	return randPrivKey
+}
+
 // GetTestRandPubKey returns a randomly generated public key
 func GetTestRandPubKey() *crypto.PublicKey {
 	_, randPubKey, err := crypto.GenerateKeyPair()
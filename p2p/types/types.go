@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/crypto"
@@ -11,9 +12,10 @@ import (
 // Message models the message sent/received through the P2P network
 //
 type Message struct {
-	PeerID    string
-	ChannelID common.ChannelIDEnum
-	Content   interface{}
+	PeerID     string
+	ChannelID  common.ChannelIDEnum
+	Content    interface{}
+	ReceivedAt time.Time // Time the raw message was received from the peer, zero for the sent messages
 }
 
 //
@@ -23,6 +25,21 @@ type NodeInfo struct {
 	PubKey      *crypto.PublicKey `rlp:"-"`
 	PubKeyBytes common.Bytes      // needed for RLP serialization
 	Port        uint16
+	Compression []ChannelCompression // compression algorithms supported for each channel
+	RecvWindow  uint32               // flow-control window of each channel in bytes, 0 if not supported
+	Version     string               // software version of the node
+
+	ProtocolVersion uint32     `rlp:"optional"` // p2p protocol version, 0 if not advertised
+	Capabilities    Capability `rlp:"optional"` // optional protocol features supported
+	Private         bool       `rlp:"optional"` // whether the peers should keep the address of the node to themselves
+}
+
+//
+// ChannelCompression lists the compression algorithms supported for a channel, in the order of preference
+//
+type ChannelCompression struct {
+	ChannelID  common.ChannelIDEnum
+	Algorithms []byte
 }
 
 // CreateNodeInfo creates an instance of NodeInfo
@@ -31,10 +48,27 @@ func CreateNodeInfo(pubKey *crypto.PublicKey, port uint16) NodeInfo {
 		PubKey:      pubKey,
 		PubKeyBytes: pubKey.ToBytes(),
 		Port:        port,
+
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    SupportedCapabilities,
 	}
 	return nodeInfo
 }
 
+//
+// PeerInfo describes a connected peer to the node operator
+//
+type PeerInfo struct {
+	ID         string `json:"id"`
+	Address    string `json:"address"`
+	IsOutbound bool   `json:"is_outbound"`
+	Persistent bool   `json:"persistent"`
+	Private    bool   `json:"private"`
+
+	ProtocolVersion uint32     `json:"protocol_version"`
+	Capabilities    Capability `json:"capabilities"`
+}
+
 const (
 	// PingSignal represents a ping signal to a peer
 	PingSignal = byte(0x0)
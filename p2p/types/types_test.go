@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/rlp"
 )
@@ -55,3 +56,37 @@ func TestNodeInfoRLPEncoding2(t *testing.T) {
 
 	assert.Equal(nodeInfo.PubKey.Address(), decodedNodeInfo.PubKey.Address())
 }
+
+func TestNegotiateProtocol(t *testing.T) {
+	assert := assert.New(t)
+
+	_, pubKey, _ := crypto.GenerateKeyPair()
+	local := CreateNodeInfo(pubKey, 1234)
+	remote := CreateNodeInfo(pubKey, 1234)
+	remote.Capabilities = CapabilityTxGossip | Capability(1<<10)
+
+	version, capabilities, err := NegotiateProtocol(&local, &remote)
+	assert.Nil(err)
+	assert.Equal(ProtocolVersion, version)
+	assert.True(capabilities.Has(CapabilityTxGossip))
+	assert.False(capabilities.Has(CapabilityStateSync))
+	assert.Equal(CapabilityTxGossip, RequiredCapability(common.ChannelIDTxGossip))
+	assert.Equal(Capability(0), RequiredCapability(common.ChannelIDBlock))
+
+	// The node info of the nodes predating the versioning decodes as version 0 without capabilities
+	legacy := struct {
+		PubKeyBytes common.Bytes
+		Port        uint16
+		Compression []ChannelCompression
+		RecvWindow  uint32
+		Version     string
+	}{local.PubKeyBytes, 1234, nil, 0, "legacy"}
+	raw, err := rlp.EncodeToBytes(legacy)
+	assert.Nil(err)
+	decoded := NodeInfo{}
+	assert.Nil(rlp.DecodeBytes(raw, &decoded))
+	version, capabilities, err = NegotiateProtocol(&local, &decoded)
+	assert.Nil(err)
+	assert.Equal(uint32(0), version)
+	assert.Equal(Capability(0), capabilities)
+}
@@ -0,0 +1,257 @@
+package reindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store"
+	"github.com/thetatoken/theta/store/database"
+)
+
+var logger *log.Entry = util.GetLoggerForModule("reindex")
+
+// Names of the derived indexes that can be rebuilt.
+const (
+	IndexTx      = "tx"      // Transaction hash to block position
+	IndexLogs    = "logs"    // Smart contract receipts and logs, rebuilt by replaying the blocks
+	IndexAddress = "address" // Transactions involving each address
+
+	// IndexState is the ledger state together with the block receipts, rebuilt by applying the
+	// blocks from the root block. It is not a derived index, and is only rebuilt on request.
+	IndexState = "state"
+)
+
+// AllIndexes lists the derived indexes that can be rebuilt.
+var AllIndexes = []string{IndexTx, IndexLogs, IndexAddress}
+
+// ReplayIndexes lists what is rebuilt when the chain is replayed: the state and the receipts,
+// and the indexes derived from the blocks. The logs come with the receipts of the state replay.
+var ReplayIndexes = []string{IndexState, IndexTx, IndexAddress}
+
+// progressReportInterval is the number of blocks between two progress reports.
+const progressReportInterval = 1000
+
+// Reindexer rebuilds the chosen derived indexes from the stored blocks. The progress of each
+// index is persisted after every block, so that an interrupted run resumes where it stopped.
+type Reindexer struct {
+	logger *log.Entry
+
+	store   store.Store
+	chain   *blockchain.Chain
+	ledger  *ledger.Ledger
+	indexes []string
+}
+
+// NewReindexer creates an instance of Reindexer. The ledger is only used to replay blocks for
+// the logs and state indexes, and must not be shared with a running node.
+func NewReindexer(db database.Database, store store.Store, chain *blockchain.Chain, indexes []string) (*Reindexer, error) {
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("No index specified")
+	}
+	for _, index := range indexes {
+		if !isValidIndex(index) {
+			return nil, fmt.Errorf("Unknown index: %v, supported indexes: %v", index, append(AllIndexes, IndexState))
+		}
+	}
+	if containsIndex(indexes, IndexState) && containsIndex(indexes, IndexLogs) {
+		return nil, fmt.Errorf("The %v index is rebuilt with the %v index", IndexLogs, IndexState)
+	}
+
+	logger = util.GetLoggerForModule("reindex")
+
+	replayLedger := ledger.NewLedger(chain.ChainID, db, nil, nil, nil)
+	if containsIndex(indexes, IndexState) {
+		// The transactions were checked when the blocks were validated, and the sanity checks
+		// need a running consensus engine.
+		replayLedger.SetSkipSanityCheck(true)
+	}
+
+	return &Reindexer{
+		logger:  logger,
+		store:   store,
+		chain:   chain,
+		ledger:  replayLedger,
+		indexes: indexes,
+	}, nil
+}
+
+func isValidIndex(index string) bool {
+	return index == IndexState || containsIndex(AllIndexes, index)
+}
+
+func containsIndex(indexes []string, index string) bool {
+	for _, idx := range indexes {
+		if idx == index {
+			return true
+		}
+	}
+	return false
+}
+
+// Run rebuilds the indexes up to the given height. If restart is true, the persisted progress
+// is discarded and the indexes are rebuilt from the root block.
+func (r *Reindexer) Run(ctx context.Context, toHeight uint64, restart bool) error {
+	fromHeight := toHeight + 1
+	progress := make(map[string]uint64)
+	for _, index := range r.indexes {
+		done := r.chain.Root().Height
+		if !restart {
+			if height, ok := r.getProgress(index); ok && height > done {
+				done = height
+			}
+		}
+		progress[index] = done
+		if done+1 < fromHeight {
+			fromHeight = done + 1
+		}
+		r.logger.WithFields(log.Fields{"index": index, "resumeFrom": done + 1}).Info("Reindexing")
+	}
+
+	start := time.Now()
+	for height := fromHeight; height <= toHeight; height++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		block := r.findFinalizedBlock(height)
+		if block == nil {
+			return fmt.Errorf("Finalized block not found at height %v", height)
+		}
+		for _, index := range r.indexes {
+			if progress[index] >= height {
+				continue
+			}
+			if err := r.reindexBlock(index, block); err != nil {
+				return fmt.Errorf("Failed to rebuild %v index at height %v: %v", index, height, err)
+			}
+			progress[index] = height
+			r.setProgress(index, height)
+		}
+
+		if (height-fromHeight+1)%progressReportInterval == 0 || height == toHeight {
+			r.reportProgress(fromHeight, height, toHeight, start)
+		}
+	}
+
+	r.logger.WithFields(log.Fields{"indexes": r.indexes, "toHeight": toHeight}).Info("Reindexing completed")
+	return nil
+}
+
+func (r *Reindexer) reindexBlock(index string, block *core.ExtendedBlock) error {
+	switch index {
+	case IndexTx:
+		r.chain.AddTxsToIndex(block, true)
+	case IndexAddress:
+		r.chain.AddTxsToAddressIndex(block)
+	case IndexLogs:
+		parent, err := r.chain.FindBlockHeader(block.Parent)
+		if err != nil {
+			return fmt.Errorf("Failed to load parent block: %v", err)
+		}
+		receipts, res := r.ledger.ReplayBlockTxs(parent.Height, parent.StateHash, block.Txs, block.Timestamp, block.StateHash)
+		if res.IsError() {
+			return fmt.Errorf("Failed to replay block: %v", res.Message)
+		}
+		// Blocks produced before log blooms were introduced carry an empty bloom.
+		if bloom := types.CreateBloom(receipts); block.Bloom != (core.Bloom{}) && bloom != block.Bloom {
+			return fmt.Errorf("Log bloom mismatch for block %v", block.Hash().Hex())
+		}
+		r.chain.AddBlockReceipts(block.Hash(), receipts)
+	case IndexState:
+		return r.applyBlock(block)
+	}
+	return nil
+}
+
+// applyBlock applies the transactions of the block on top of the state of its parent, which
+// is the state rebuilt for the previous block, and commits the resulting state. The receipts
+// are saved as well.
+func (r *Reindexer) applyBlock(block *core.ExtendedBlock) error {
+	parent, err := r.chain.FindBlockHeader(block.Parent)
+	if err != nil {
+		return fmt.Errorf("Failed to load parent block: %v", err)
+	}
+	if res := r.ledger.ResetState(parent.Height, parent.StateHash); res.IsError() {
+		return fmt.Errorf("Failed to load the parent state: %v", res.Message)
+	}
+	res := r.ledger.ApplyBlockTxs(block.Txs, block.Timestamp, block.StateHash)
+	if res.IsError() {
+		return fmt.Errorf("Failed to apply block: %v", res.Message)
+	}
+
+	receipts := []*types.Receipt{}
+	if rs, ok := res.Info["receipts"]; ok {
+		receipts = rs.([]*types.Receipt)
+	}
+	// Blocks produced before log blooms were introduced carry an empty bloom.
+	if bloom := types.CreateBloom(receipts); block.Bloom != (core.Bloom{}) && bloom != block.Bloom {
+		return fmt.Errorf("Log bloom mismatch for block %v", block.Hash().Hex())
+	}
+	r.chain.AddBlockReceipts(block.Hash(), receipts)
+	return nil
+}
+
+// CheckRootState returns an error if the state of the root block is missing, in which case it
+// has to be imported from the snapshot before the state is rebuilt.
+func (r *Reindexer) CheckRootState() error {
+	root := r.chain.Root()
+	if res := r.ledger.ResetState(root.Height, root.StateHash); res.IsError() {
+		return fmt.Errorf("Root state not found: %v", res.Message)
+	}
+	return nil
+}
+
+func (r *Reindexer) reportProgress(fromHeight, height, toHeight uint64, start time.Time) {
+	processed := height - fromHeight + 1
+	total := toHeight - fromHeight + 1
+	elapsed := time.Since(start)
+	rate := float64(processed) / elapsed.Seconds()
+	remaining := time.Duration(0)
+	if rate > 0 {
+		remaining = time.Duration(float64(toHeight-height)/rate) * time.Second
+	}
+	r.logger.WithFields(log.Fields{
+		"height":    height,
+		"toHeight":  toHeight,
+		"progress":  fmt.Sprintf("%.2f%%", 100*float64(processed)/float64(total)),
+		"rate":      fmt.Sprintf("%.1f blocks/s", rate),
+		"remaining": remaining.String(),
+	}).Info("Reindexing progress")
+}
+
+func (r *Reindexer) findFinalizedBlock(height uint64) *core.ExtendedBlock {
+	for _, block := range r.chain.FindBlocksByHeight(height) {
+		if block.Status.IsFinalized() {
+			return block
+		}
+	}
+	return nil
+}
+
+func progressKey(index string) common.Bytes {
+	return common.Bytes("reindex/" + index)
+}
+
+func (r *Reindexer) getProgress(index string) (uint64, bool) {
+	var height uint64
+	if err := r.store.Get(progressKey(index), &height); err != nil {
+		return 0, false
+	}
+	return height, true
+}
+
+func (r *Reindexer) setProgress(index string, height uint64) {
+	if err := r.store.Put(progressKey(index), height); err != nil {
+		r.logger.WithFields(log.Fields{"index": index, "err": err}).Error("Failed to save reindexing progress")
+	}
+}
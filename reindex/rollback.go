@@ -0,0 +1,107 @@
+package reindex
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+)
+
+// Rollback rolls the node back numBlocks blocks below the last finalized block, e.g. to recover
+// from a consensus bug or a corrupted state without downloading the whole chain again. The state
+// of the new last finalized block is rebuilt if it is missing, and the blocks above it are
+// removed from the chain, to be downloaded and validated again once the node restarts. The node
+// must be stopped during the rollback.
+func (r *Reindexer) Rollback(ctx context.Context, state *consensus.State, numBlocks uint64) (*core.ExtendedBlock, error) {
+	if numBlocks == 0 {
+		return nil, fmt.Errorf("The number of blocks to roll back must be positive")
+	}
+	lfb := state.GetLastFinalizedBlock()
+	root := r.chain.Root()
+	if lfb.Height < root.Height+numBlocks {
+		return nil, fmt.Errorf("Cannot roll back %v blocks from height %v past the root block at height %v",
+			numBlocks, lfb.Height, root.Height)
+	}
+	target := r.findFinalizedBlock(lfb.Height - numBlocks)
+	if target == nil {
+		return nil, fmt.Errorf("Finalized block not found at height %v", lfb.Height-numBlocks)
+	}
+
+	// The state is rebuilt first, since removing the blocks cannot be undone
+	if err := r.rebuildState(ctx, target); err != nil {
+		return nil, err
+	}
+
+	removed, err := r.chain.RemoveDescendants(target.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to remove the blocks above height %v: %v", target.Height, err)
+	}
+	if err := state.SetLastFinalizedBlock(target); err != nil {
+		return nil, err
+	}
+	if err := state.SetHighestCCBlock(target); err != nil {
+		return nil, err
+	}
+	for _, index := range append(AllIndexes, IndexState) {
+		if height, ok := r.getProgress(index); ok && height > target.Height {
+			r.setProgress(index, target.Height)
+		}
+	}
+
+	r.logger.WithFields(log.Fields{
+		"fromHeight":    lfb.Height,
+		"toHeight":      target.Height,
+		"block":         target.Hash().Hex(),
+		"removedBlocks": removed,
+	}).Info("Rolled back")
+	return target, nil
+}
+
+// rebuildState rebuilds the state of the block if it is missing, by replaying the finalized
+// blocks from the nearest ancestor whose state is available. The state of the root block must be
+// imported from the snapshot beforehand if it is missing, see CheckRootState.
+func (r *Reindexer) rebuildState(ctx context.Context, target *core.ExtendedBlock) error {
+	base := target
+	root := r.chain.Root()
+	for base.Height > root.Height {
+		if res := r.ledger.ResetState(base.Height, base.StateHash); res.IsOK() {
+			break
+		}
+		parent, err := r.chain.FindBlockHeader(base.Parent)
+		if err != nil {
+			return fmt.Errorf("Failed to load parent block: %v", err)
+		}
+		base = parent
+	}
+	if base.Height == target.Height {
+		return nil
+	}
+	if base.Height == root.Height {
+		if err := r.CheckRootState(); err != nil {
+			return err
+		}
+	}
+
+	r.logger.WithFields(log.Fields{
+		"fromHeight": base.Height,
+		"toHeight":   target.Height,
+	}).Info("Rebuilding the state")
+	for height := base.Height + 1; height <= target.Height; height++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		block := r.findFinalizedBlock(height)
+		if block == nil {
+			return fmt.Errorf("Finalized block not found at height %v", height)
+		}
+		if err := r.applyBlock(block); err != nil {
+			return fmt.Errorf("Failed to rebuild the state at height %v: %v", height, err)
+		}
+	}
+	return nil
+}
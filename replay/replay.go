@@ -0,0 +1,232 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+var logger *log.Entry = util.GetLoggerForModule("replay")
+
+// progressReportInterval is the number of blocks between two progress reports.
+const progressReportInterval = 1000
+
+// Divergence describes the first recorded block the replay does not agree with.
+type Divergence struct {
+	Height            uint64
+	Block             common.Hash
+	Reason            string
+	ExpectedStateRoot common.Hash // state root recorded in the block
+	StateRoot         common.Hash // state root computed by the replay, empty if not computed
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("Divergence{height: %v, block: %v, reason: %v, expected state root: %v, state root: %v}",
+		d.Height, d.Block.Hex(), d.Reason, d.ExpectedStateRoot.Hex(), d.StateRoot.Hex())
+}
+
+// Report summarizes a replay.
+type Report struct {
+	FromHeight uint64
+	ToHeight   uint64 // height of the last block replayed successfully
+	NumBlocks  int
+	NumTxs     int
+	Elapsed    time.Duration
+	Divergence *Divergence // nil if all the blocks were replayed successfully
+}
+
+//
+// Replayer validates and applies recorded blocks, e.g. exported from a mainnet node, on top of the
+// state of the root block, and compares the results with the ones recorded in the blocks. The
+// blocks go through the consensus checks that do not depend on the live state of the engine, i.e.
+// all but the proposer of the epoch, and through the ledger execution. It is meant to detect the
+// regressions of a candidate build before it is released.
+//
+type Replayer struct {
+	logger *log.Entry
+
+	store  store.Store
+	chain  *blockchain.Chain
+	ledger *ledger.Ledger
+}
+
+// NewReplayer creates an instance of Replayer. The database must hold the state of the root block,
+// e.g. imported from a snapshot, and is written to by the replay. It must not be the database of a
+// node.
+func NewReplayer(db database.Database, root *core.BlockHeader) *Replayer {
+	store := kvstore.NewKVStore(database.BlockDatabase(db))
+	chain := blockchain.NewChain(root.ChainID, store, &core.Block{BlockHeader: root})
+
+	replayLedger := ledger.NewLedger(root.ChainID, db, nil, nil, nil)
+	// The sanity checks of the transactions need a running consensus engine.
+	replayLedger.SetSkipSanityCheck(true)
+
+	return &Replayer{
+		logger: logger,
+		store:  store,
+		chain:  chain,
+		ledger: replayLedger,
+	}
+}
+
+// Root returns the root block the blocks are replayed from.
+func (r *Replayer) Root() *core.ExtendedBlock {
+	return r.chain.Root()
+}
+
+// Run replays the given blocks in increasing height order, and stops at the first divergence.
+// The blocks at or below the root height are skipped. An error is returned if the replay could
+// not be carried out, e.g. when the blocks are not contiguous.
+func (r *Replayer) Run(ctx context.Context, blocks *core.BackupBlock) (*Report, error) {
+	root := r.chain.Root()
+	if res := r.ledger.ResetState(root.Height, root.StateHash); res.IsError() {
+		return nil, fmt.Errorf("Root state not found: %v", res.Message)
+	}
+
+	report := &Report{FromHeight: root.Height + 1, ToHeight: root.Height}
+	start := time.Now()
+	defer func() {
+		report.Elapsed = time.Since(start)
+	}()
+
+	for backupBlock := blocks; backupBlock != nil; backupBlock = backupBlock.Next {
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		default:
+		}
+
+		block := backupBlock.Block
+		if block.Height <= root.Height {
+			continue
+		}
+		if block.Height != report.ToHeight+1 {
+			return report, fmt.Errorf("Missing blocks between height %v and %v", report.ToHeight, block.Height)
+		}
+
+		if divergence := r.replayBlock(backupBlock); divergence != nil {
+			report.Divergence = divergence
+			return report, nil
+		}
+		report.ToHeight = block.Height
+		report.NumBlocks++
+		report.NumTxs += len(block.Txs)
+
+		if report.NumBlocks%progressReportInterval == 0 {
+			r.logger.WithFields(log.Fields{
+				"height": block.Height,
+				"rate":   fmt.Sprintf("%.1f blocks/s", float64(report.NumBlocks)/time.Since(start).Seconds()),
+			}).Info("Replay progress")
+		}
+	}
+	return report, nil
+}
+
+// replayBlock validates and applies the block the way the consensus engine does, and returns
+// the divergence from the recorded block if any.
+func (r *Replayer) replayBlock(backupBlock *core.BackupBlock) *Divergence {
+	recorded := backupBlock.Block
+	hash := recorded.Hash()
+	diverge := func(format string, args ...interface{}) *Divergence {
+		return &Divergence{
+			Height:            recorded.Height,
+			Block:             hash,
+			Reason:            fmt.Sprintf(format, args...),
+			ExpectedStateRoot: recorded.StateHash,
+		}
+	}
+
+	parent, err := r.chain.FindBlockHeader(recorded.Parent)
+	if err != nil {
+		return diverge("Parent block %v not found: %v", recorded.Parent.Hex(), err)
+	}
+	if _, err := r.chain.AddBlock(recorded.Block); err != nil {
+		return diverge("Failed to add block: %v", err)
+	}
+	vcp, err := r.ledger.GetFinalizedValidatorCandidatePool(hash, false)
+	if err != nil || vcp == nil {
+		return diverge("Failed to get the validator candidate pool: %v", err)
+	}
+	validators := consensus.SelectTopStakeHoldersAsValidators(vcp)
+	if err := consensus.ValidateBlock(r.chain, validators, recorded.Block, parent); err != nil {
+		return diverge("Invalid block: %v", err)
+	}
+	if err := consensus.ValidateVRFProof(r.ledger, recorded.Block, parent); err != nil {
+		return diverge("Invalid VRF proof: %v", err)
+	}
+
+	if res := r.ledger.ResetState(parent.Height, parent.StateHash); res.IsError() {
+		return diverge("Failed to load the parent state: %v", res.Message)
+	}
+	res := r.ledger.ApplyBlockTxs(recorded.Txs, recorded.Timestamp, recorded.StateHash)
+	if res.IsError() {
+		divergence := diverge("Failed to apply block: %v", res.Message)
+		if stateRoot, ok := res.Info["stateRoot"]; ok {
+			divergence.StateRoot = stateRoot.(common.Hash)
+		}
+		return divergence
+	}
+	if err := consensus.ValidateAppliedBlock(recorded.Block, res); err != nil {
+		divergence := diverge("%v", err)
+		divergence.StateRoot = recorded.StateHash
+		return divergence
+	}
+
+	hasValidatorUpdate := resultInfo(res, "hasValidatorUpdate", false).(bool)
+	if hasValidatorUpdate != recorded.HasValidatorUpdate {
+		divergence := diverge("Validator update mismatch, recorded: %v, replayed: %v", recorded.HasValidatorUpdate, hasValidatorUpdate)
+		divergence.StateRoot = recorded.StateHash
+		return divergence
+	}
+	receipts := resultInfo(res, "receipts", []*types.Receipt{}).([]*types.Receipt)
+	r.chain.MarkBlockValidWithReceipts(hash, hasValidatorUpdate, receipts)
+
+	// The recorded votes finalize the block, and its ancestors
+	if backupBlock.Votes != nil && backupBlock.Votes.Size() > 0 {
+		if err := validateVotes(validators, hash, backupBlock.Votes); err != nil {
+			divergence := diverge("Invalid votes: %v", err)
+			divergence.StateRoot = recorded.StateHash
+			return divergence
+		}
+		r.chain.FinalizePreviousBlocks(hash)
+	}
+	return nil
+}
+
+// validateVotes checks that the votes are valid votes for the block, cast by a majority of the
+// validators.
+func validateVotes(validators *core.ValidatorSet, block common.Hash, voteSet *core.VoteSet) error {
+	votes := voteSet.Votes()
+	for i, res := range core.ValidateVotes(votes) {
+		if res.IsError() {
+			return fmt.Errorf("Vote %v is invalid: %v", votes[i], res.Message)
+		}
+		if votes[i].Block != block {
+			return fmt.Errorf("Vote %v is not for the block", votes[i])
+		}
+	}
+	if !validators.HasMajorityVotes(votes) {
+		return fmt.Errorf("Votes do not have the majority of the validators")
+	}
+	return nil
+}
+
+func resultInfo(res result.Result, key string, defaultValue interface{}) interface{} {
+	if value, ok := res.Info[key]; ok {
+		return value
+	}
+	return defaultValue
+}
@@ -81,12 +81,19 @@ type Decoder interface {
 // error if there are too few or too many elements.
 //
 // The decoding of struct fields honours certain struct tags, "tail",
-// "nil" and "-".
+// "nil", "optional" and "-".
 //
 // The "-" tag ignores fields.
 //
 // For an explanation of "tail", see the example.
 //
+// The "optional" tag allows the input list to end before the field. The
+// missing fields are set to their zero values. All the fields after an
+// optional field must also be optional. When encoding, the trailing
+// optional fields with zero values are omitted, so that new fields can
+// be appended to a struct without changing the encoding of the existing
+// values.
+//
 // The "nil" tag applies to pointer-typed fields and changes the decoding
 // rules for the field such that input values of size zero decode as a nil
 // pointer. This tag can be useful when decoding recursive types.
@@ -438,9 +445,17 @@ func makeStructDecoder(typ reflect.Type) (decoder, error) {
 		if _, err := s.List(); err != nil {
 			return wrapStreamError(err, typ)
 		}
-		for _, f := range fields {
+		for i, f := range fields {
 			err := f.info.decoder(s, val.Field(f.index))
 			if err == EOL {
+				if f.optional {
+					// The remaining fields are optional too, and set to their zero values
+					for _, fi := range fields[i:] {
+						fv := val.Field(fi.index)
+						fv.Set(reflect.Zero(fv.Type()))
+					}
+					break
+				}
 				return &decodeError{msg: "too few elements", typ: typ}
 			} else if err != nil {
 				return addErrorContext(err, "."+typ.Field(f.index).Name)
@@ -623,7 +638,7 @@ type listpos struct{ pos, size uint64 }
 // the remaining input length will return ErrValueTooLarge. The limit
 // can be set by passing a non-zero value for inputLimit.
 //
-// If r is a bytes.Reader or strings.Reader, the input limit is set to
+// If r is a bytes.Reader, bytes.Buffer or strings.Reader, the input limit is set to
 // the length of r's underlying data unless an explicit limit is
 // provided.
 func NewStream(r io.Reader, inputLimit uint64) *Stream {
@@ -832,6 +847,9 @@ func (s *Stream) Reset(r io.Reader, inputLimit uint64) {
 		case *bytes.Reader:
 			s.remaining = uint64(br.Len())
 			s.limited = true
+		case *bytes.Buffer:
+			s.remaining = uint64(br.Len())
+			s.limited = true
 		case *strings.Reader:
 			s.remaining = uint64(br.Len())
 			s.limited = true
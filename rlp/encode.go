@@ -530,8 +530,13 @@ func makeStructWriter(typ reflect.Type) (writer, error) {
 		return nil, err
 	}
 	writer := func(val reflect.Value, w *encbuf) error {
+		// The trailing optional fields with zero values are omitted
+		numFields := len(fields)
+		for numFields > 0 && fields[numFields-1].optional && val.Field(fields[numFields-1].index).IsZero() {
+			numFields--
+		}
 		lh := w.list()
-		for _, f := range fields {
+		for _, f := range fields[:numFields] {
 			if err := f.info.writer(val.Field(f.index), w); err != nil {
 				return err
 			}
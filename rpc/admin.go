@@ -0,0 +1,446 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/rpc"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/backup"
+	"github.com/thetatoken/theta/checkpoint"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/mempool"
+	"github.com/thetatoken/theta/p2p"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+	"github.com/thetatoken/theta/store/archive"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/maintenance"
+)
+
+// ThetaAdminService serves the node administration calls of theta admin. Unlike the
+// ThetaRPCService, it changes the node at runtime and is only meant to be reachable from the
+// local host.
+type ThetaAdminService struct {
+	mempool      *mempool.Mempool
+	consensus    *consensus.ConsensusEngine
+	peers        p2p.PeerAdmin // nil if the network does not support peer administration
+	checkpointer *checkpoint.Checkpointer
+	reloader     ConfigReloader // nil if the node does not support config reload
+	db           database.Database
+	blockArchive *archive.Archive
+	backupMu     *sync.Mutex             // Runs one backup at a time
+	maintainer   *maintenance.Maintainer // nil if the database does not support compaction
+}
+
+// ConfigReloader reloads the settings of the node that can be changed at runtime.
+type ConfigReloader interface {
+	// ReloadConfig re-reads the config file, and returns the reloadable settings that changed.
+	ReloadConfig() ([]string, error)
+}
+
+// ThetaAdminServer is an instance of the admin RPC service.
+type ThetaAdminServer struct {
+	*ThetaAdminService
+
+	server  *http.Server
+	address string
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewThetaAdminServer creates a new instance of ThetaAdminServer.
+func NewThetaAdminServer(mempool *mempool.Mempool, consensus *consensus.ConsensusEngine,
+	network p2p.Network, checkpointer *checkpoint.Checkpointer) *ThetaAdminServer {
+	t := &ThetaAdminServer{
+		ThetaAdminService: &ThetaAdminService{
+			mempool:      mempool,
+			consensus:    consensus,
+			checkpointer: checkpointer,
+			backupMu:     &sync.Mutex{},
+		},
+		address: viper.GetString(common.CfgAdminListenAddress),
+		wg:      &sync.WaitGroup{},
+	}
+	if peers, ok := network.(p2p.PeerAdmin); ok {
+		t.peers = peers
+	}
+
+	s := rpc.NewServer()
+	s.RegisterName("admin", t.ThetaAdminService)
+
+	router := mux.NewRouter()
+	router.Handle("/rpc", jsonrpc2.HTTPHandler(s))
+	t.server = &http.Server{Handler: router}
+
+	logger = util.GetLoggerForModule("rpc")
+
+	return t
+}
+
+// SetConfigReloader sets the handler of the ReloadConfig calls.
+func (t *ThetaAdminServer) SetConfigReloader(reloader ConfigReloader) {
+	t.reloader = reloader
+}
+
+// SetDatabase sets the database and the block archive, which may be nil, backed up by the
+// BackupDatabase calls.
+func (t *ThetaAdminServer) SetDatabase(db database.Database, blockArchive *archive.Archive) {
+	t.db = db
+	t.blockArchive = blockArchive
+}
+
+// SetMaintainer sets the maintainer of the database, which may be nil.
+func (t *ThetaAdminServer) SetMaintainer(maintainer *maintenance.Maintainer) {
+	t.maintainer = maintainer
+}
+
+// Start creates the main goroutine.
+func (t *ThetaAdminServer) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	t.ctx = c
+	t.cancel = cancel
+
+	l, err := net.Listen("tcp", t.address)
+	if err != nil {
+		logger.WithFields(log.Fields{"error": err, "address": t.address}).Fatal("Failed to create admin listener")
+	}
+	logger.WithFields(log.Fields{"address": t.address}).Info("Admin RPC server started")
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		if err := t.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			logger.WithFields(log.Fields{"error": err}).Error("Admin RPC server stopped")
+		}
+	}()
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		<-t.ctx.Done()
+		t.server.Shutdown(context.Background())
+	}()
+}
+
+// Stop notifies all goroutines to stop without blocking.
+func (t *ThetaAdminServer) Stop() {
+	t.cancel()
+}
+
+// Wait blocks until all goroutines stop.
+func (t *ThetaAdminServer) Wait() {
+	t.wg.Wait()
+}
+
+// ------------------------------- ListPeers -----------------------------------
+
+type ListPeersArgs struct{}
+
+type BannedPeer struct {
+	ID    string    `json:"id"`
+	Until time.Time `json:"until"`
+}
+
+type ListPeersResult struct {
+	Peers  []p2ptypes.PeerInfo `json:"peers"`
+	Banned []BannedPeer        `json:"banned"`
+}
+
+func (t *ThetaAdminService) ListPeers(args *ListPeersArgs, result *ListPeersResult) (err error) {
+	if t.peers == nil {
+		return errors.New("Peer administration is not supported by the network")
+	}
+	result.Peers = t.peers.PeerInfos()
+	result.Banned = []BannedPeer{}
+	for id, until := range t.peers.BannedPeers() {
+		result.Banned = append(result.Banned, BannedPeer{ID: id, Until: until})
+	}
+	sort.Slice(result.Banned, func(i, j int) bool { return result.Banned[i].ID < result.Banned[j].ID })
+	return nil
+}
+
+// ------------------------------- BanPeer -----------------------------------
+
+type BanPeerArgs struct {
+	ID       string `json:"id"`
+	Duration int64  `json:"duration"` // in seconds
+}
+
+type BanPeerResult struct {
+	Until time.Time `json:"until"`
+}
+
+func (t *ThetaAdminService) BanPeer(args *BanPeerArgs, result *BanPeerResult) (err error) {
+	if t.peers == nil {
+		return errors.New("Peer administration is not supported by the network")
+	}
+	if args.ID == "" {
+		return errors.New("Peer ID must be specified")
+	}
+	duration := time.Duration(args.Duration) * time.Second
+	if err := t.peers.BanPeer(args.ID, duration); err != nil {
+		return err
+	}
+	result.Until = time.Now().Add(duration)
+	return nil
+}
+
+// ------------------------------- UnbanPeer -----------------------------------
+
+type UnbanPeerArgs struct {
+	ID string `json:"id"`
+}
+
+type UnbanPeerResult struct {
+	Unbanned bool `json:"unbanned"`
+}
+
+func (t *ThetaAdminService) UnbanPeer(args *UnbanPeerArgs, result *UnbanPeerResult) (err error) {
+	if t.peers == nil {
+		return errors.New("Peer administration is not supported by the network")
+	}
+	result.Unbanned = t.peers.UnbanPeer(args.ID)
+	return nil
+}
+
+// ------------------------------- InspectMempool -----------------------------------
+
+type InspectMempoolArgs struct {
+	Limit int `json:"limit"` // max number of transactions to list, 0 for none
+}
+
+type MempoolTx struct {
+	Hash common.Hash `json:"hash"`
+	Size int         `json:"size"`
+}
+
+type InspectMempoolResult struct {
+	Size         int         `json:"size"`
+	NumFutureTxs int         `json:"num_future_txs"`
+	Txs          []MempoolTx `json:"txs"`
+}
+
+func (t *ThetaAdminService) InspectMempool(args *InspectMempoolArgs, result *InspectMempoolResult) (err error) {
+	result.Size = t.mempool.Size()
+	result.NumFutureTxs = t.mempool.NumFutureTxs()
+	result.Txs = []MempoolTx{}
+	if args.Limit <= 0 {
+		return nil
+	}
+	height := t.consensus.GetLastFinalizedBlock().Height + 1
+	for _, rawTx := range t.mempool.GetCandidateTxs(args.Limit) {
+		result.Txs = append(result.Txs, MempoolTx{
+			Hash: crypto.HashAtHeight(height, rawTx),
+			Size: len(rawTx),
+		})
+	}
+	return nil
+}
+
+// ------------------------------- FlushMempool -----------------------------------
+
+type FlushMempoolArgs struct{}
+
+type FlushMempoolResult struct {
+	NumFlushedTxs int `json:"num_flushed_txs"`
+}
+
+func (t *ThetaAdminService) FlushMempool(args *FlushMempoolArgs, result *FlushMempoolResult) (err error) {
+	result.NumFlushedTxs = t.mempool.Size() + t.mempool.NumFutureTxs()
+	t.mempool.Flush()
+	logger.WithFields(log.Fields{"numFlushedTxs": result.NumFlushedTxs}).Info("Flushed mempool")
+	return nil
+}
+
+// ------------------------------- ExportCheckpoint -----------------------------------
+
+type ExportCheckpointArgs struct{}
+
+type ExportCheckpointResult struct {
+	Sequence      common.JSONUint64 `json:"sequence"`
+	Timestamp     common.JSONUint64 `json:"timestamp"`
+	LastVote      core.Vote         `json:"last_vote"`
+	NumMempoolTxs int               `json:"num_mempool_txs"`
+	MempoolDigest common.Hash       `json:"mempool_digest"`
+	SyncTip       common.Hash       `json:"sync_tip"`
+	SyncTipHeight common.JSONUint64 `json:"sync_tip_height"`
+	LastFinalized common.Hash       `json:"last_finalized"`
+}
+
+func (t *ThetaAdminService) ExportCheckpoint(args *ExportCheckpointArgs, result *ExportCheckpointResult) (err error) {
+	if t.checkpointer == nil {
+		return errors.New("Checkpointing is disabled")
+	}
+	if err := t.checkpointer.Checkpoint(); err != nil {
+		return err
+	}
+	record, err := t.checkpointer.Load()
+	if err != nil {
+		return err
+	}
+	result.Sequence = common.JSONUint64(record.Sequence)
+	result.Timestamp = common.JSONUint64(record.Timestamp)
+	result.LastVote = record.LastVote
+	result.NumMempoolTxs = len(record.MempoolTxs)
+	result.MempoolDigest = record.MempoolDigest
+	result.SyncTip = record.SyncTip
+	result.SyncTipHeight = common.JSONUint64(record.SyncTipHeight)
+	result.LastFinalized = record.LastFinalized
+	return nil
+}
+
+// ------------------------------- SetLogLevel -----------------------------------
+
+type SetLogLevelArgs struct {
+	Module string `json:"module"` // "*" for the modules without a level of their own
+	Level  string `json:"level"`
+}
+
+type SetLogLevelResult struct {
+	Levels map[string]string `json:"levels"`
+}
+
+func (t *ThetaAdminService) SetLogLevel(args *SetLogLevelArgs, result *SetLogLevelResult) (err error) {
+	if args.Module == "" {
+		return errors.New("Module must be specified")
+	}
+	if err := util.SetLogLevel(args.Module, args.Level); err != nil {
+		return err
+	}
+	result.Levels = util.GetLogLevels()
+	return nil
+}
+
+// ------------------------------- ReloadConfig -----------------------------------
+
+type ReloadConfigArgs struct{}
+
+type ReloadConfigResult struct {
+	Changed []string `json:"changed"`
+}
+
+func (t *ThetaAdminService) ReloadConfig(args *ReloadConfigArgs, result *ReloadConfigResult) (err error) {
+	if t.reloader == nil {
+		return errors.New("Config reload is not supported")
+	}
+	result.Changed, err = t.reloader.ReloadConfig()
+	return err
+}
+
+// ------------------------------- DumpConsensusState -----------------------------------
+
+type DumpConsensusStateArgs struct{}
+
+type DumpConsensusStateResult struct {
+	State          *consensus.StateStub `json:"state"`
+	Tip            common.Hash          `json:"tip"`
+	TipHeight      common.JSONUint64    `json:"tip_height"`
+	EpochVotes     []core.Vote          `json:"epoch_votes"`
+	EpochTimeoutMs int64                `json:"epoch_timeout_ms"`
+	MissedEpochs   uint                 `json:"missed_epochs"`
+}
+
+func (t *ThetaAdminService) DumpConsensusState(args *DumpConsensusStateArgs, result *DumpConsensusStateResult) (err error) {
+	result.State = t.consensus.GetSummary()
+	tip := t.consensus.GetTipToVote()
+	result.Tip = tip.Hash()
+	result.TipHeight = common.JSONUint64(tip.Height)
+	result.EpochVotes = []core.Vote{}
+	if votes, err := t.consensus.GetEpochVotes(); err == nil {
+		result.EpochVotes = votes.Votes()
+	}
+	timeout := t.consensus.GetEpochTimeout()
+	result.EpochTimeoutMs = int64(timeout.Timeout() / time.Millisecond)
+	result.MissedEpochs = timeout.MissedEpochs()
+	return nil
+}
+
+// ------------------------------- BackupDatabase -----------------------------------
+
+type BackupDatabaseArgs struct {
+	Dir string `json:"dir"` // must not exist, relative to the working directory of the node
+}
+
+type BackupDatabaseResult struct {
+	Dir        string            `json:"dir"`
+	HeadHash   common.Hash       `json:"head_hash"`
+	HeadHeight common.JSONUint64 `json:"head_height"`
+}
+
+func (t *ThetaAdminService) BackupDatabase(args *BackupDatabaseArgs, result *BackupDatabaseResult) (err error) {
+	if t.db == nil {
+		return errors.New("Database backup is not supported")
+	}
+	if args.Dir == "" {
+		return errors.New("Backup directory must be specified")
+	}
+	t.backupMu.Lock()
+	defer t.backupMu.Unlock()
+
+	head := t.consensus.GetLastFinalizedBlock()
+	manifest, err := backup.Create(t.db, t.blockArchive, head, viper.GetString(common.CfgStorageBackend), args.Dir)
+	if err != nil {
+		return err
+	}
+	result.Dir = args.Dir
+	result.HeadHash = manifest.HeadHash
+	result.HeadHeight = manifest.HeadHeight
+	return nil
+}
+
+// ------------------------------- GetDiskUsage -----------------------------------
+
+type GetDiskUsageArgs struct{}
+
+type GetDiskUsageResult struct {
+	Columns        map[string]common.JSONUint64 `json:"columns"` // in bytes
+	Total          common.JSONUint64            `json:"total"`
+	LastCompaction *time.Time                   `json:"last_compaction"` // nil if not compacted since the node started
+	Compacting     bool                         `json:"compacting"`
+}
+
+func (t *ThetaAdminService) GetDiskUsage(args *GetDiskUsageArgs, result *GetDiskUsageResult) (err error) {
+	if t.maintainer == nil {
+		return errors.New("Disk usage reporting is not supported by the database backend")
+	}
+	status := t.maintainer.GetStatus()
+	result.Columns = make(map[string]common.JSONUint64)
+	for column, size := range status.DiskUsage {
+		result.Columns[column] = common.JSONUint64(size)
+		result.Total += common.JSONUint64(size)
+	}
+	if !status.LastCompaction.IsZero() {
+		result.LastCompaction = &status.LastCompaction
+	}
+	result.Compacting = status.Compacting
+	return nil
+}
+
+// ------------------------------- CompactDatabase -----------------------------------
+
+type CompactDatabaseArgs struct{}
+
+type CompactDatabaseResult struct{}
+
+func (t *ThetaAdminService) CompactDatabase(args *CompactDatabaseArgs, result *CompactDatabaseResult) (err error) {
+	if t.maintainer == nil {
+		return errors.New("Compaction is not supported by the database backend")
+	}
+	t.maintainer.TriggerCompaction()
+	return nil
+}
@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+type testPeerAdmin struct {
+	peers  []p2ptypes.PeerInfo
+	banned map[string]time.Time
+}
+
+func (pa *testPeerAdmin) PeerInfos() []p2ptypes.PeerInfo {
+	return pa.peers
+}
+
+func (pa *testPeerAdmin) BanPeer(peerID string, duration time.Duration) error {
+	if duration <= 0 {
+		return errors.New("Ban duration must be positive")
+	}
+	for i, peer := range pa.peers {
+		if peer.ID == peerID {
+			pa.peers = append(pa.peers[:i], pa.peers[i+1:]...)
+			break
+		}
+	}
+	pa.banned[peerID] = time.Now().Add(duration)
+	return nil
+}
+
+func (pa *testPeerAdmin) UnbanPeer(peerID string) bool {
+	_, ok := pa.banned[peerID]
+	delete(pa.banned, peerID)
+	return ok
+}
+
+func (pa *testPeerAdmin) BannedPeers() map[string]time.Time {
+	return pa.banned
+}
+
+func TestAdminPeers(t *testing.T) {
+	assert := assert.New(t)
+
+	peers := &testPeerAdmin{
+		peers:  []p2ptypes.PeerInfo{{ID: "peer1"}, {ID: "peer2"}},
+		banned: make(map[string]time.Time),
+	}
+	service := &ThetaAdminService{peers: peers}
+
+	assert.Nil(service.BanPeer(&BanPeerArgs{ID: "peer1", Duration: 60}, &BanPeerResult{}))
+	assert.NotNil(service.BanPeer(&BanPeerArgs{ID: "peer2"}, &BanPeerResult{}))
+	assert.NotNil(service.BanPeer(&BanPeerArgs{Duration: 60}, &BanPeerResult{}))
+
+	listResult := &ListPeersResult{}
+	assert.Nil(service.ListPeers(&ListPeersArgs{}, listResult))
+	assert.Equal(1, len(listResult.Peers))
+	assert.Equal("peer2", listResult.Peers[0].ID)
+	assert.Equal(1, len(listResult.Banned))
+	assert.Equal("peer1", listResult.Banned[0].ID)
+
+	unbanResult := &UnbanPeerResult{}
+	assert.Nil(service.UnbanPeer(&UnbanPeerArgs{ID: "peer1"}, unbanResult))
+	assert.True(unbanResult.Unbanned)
+
+	// Peer administration is not available on all networks
+	service = &ThetaAdminService{}
+	assert.NotNil(service.ListPeers(&ListPeersArgs{}, &ListPeersResult{}))
+}
+
+func TestAdminSetLogLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	service := &ThetaAdminService{}
+	result := &SetLogLevelResult{}
+	assert.Nil(service.SetLogLevel(&SetLogLevelArgs{Module: "consensus", Level: "info"}, result))
+	assert.Equal("info", result.Levels["consensus"])
+	assert.NotNil(service.SetLogLevel(&SetLogLevelArgs{Module: "consensus", Level: "loud"}, result))
+	assert.NotNil(service.SetLogLevel(&SetLogLevelArgs{Level: "info"}, result))
+}
+
+type testConfigReloader struct {
+	changed []string
+	err     error
+}
+
+func (r *testConfigReloader) ReloadConfig() ([]string, error) {
+	return r.changed, r.err
+}
+
+func TestAdminReloadConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	service := &ThetaAdminService{}
+	assert.NotNil(service.ReloadConfig(&ReloadConfigArgs{}, &ReloadConfigResult{}))
+
+	service.reloader = &testConfigReloader{changed: []string{"log.levels"}}
+	result := &ReloadConfigResult{}
+	assert.Nil(service.ReloadConfig(&ReloadConfigArgs{}, result))
+	assert.Equal([]string{"log.levels"}, result.Changed)
+
+	service.reloader = &testConfigReloader{err: errors.New("Invalid log level: verbose")}
+	assert.NotNil(service.ReloadConfig(&ReloadConfigArgs{}, &ReloadConfigResult{}))
+}
@@ -0,0 +1,239 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"strings"
+)
+
+// rpcRole is the access level of an RPC request.
+type rpcRole int
+
+const (
+	roleNone  rpcRole = iota // unauthenticated, allowed the public methods unless authentication is required
+	roleUser                 // authenticated with a user token, allowed the public methods
+	roleAdmin                // allowed all the methods
+)
+
+//
+// rpcAuth authenticates the RPC requests, and controls the access to the methods. The admin
+// methods, e.g. the ones writing files on the node, are only allowed to the requests with an
+// admin token, with a client certificate verified against the client CA, or from the local host
+// if allowed. The other methods are public, unless authentication is required.
+//
+type rpcAuth struct {
+	userTokens   []string
+	adminTokens  []string
+	adminMethods map[string]bool
+
+	requireAuth        bool
+	adminFromLocalhost bool
+}
+
+func newRPCAuth(userTokens []string, adminTokens []string, adminMethods []string, requireAuth bool, adminFromLocalhost bool) *rpcAuth {
+	a := &rpcAuth{
+		userTokens:         nonEmpty(userTokens),
+		adminTokens:        nonEmpty(adminTokens),
+		adminMethods:       make(map[string]bool),
+		requireAuth:        requireAuth,
+		adminFromLocalhost: adminFromLocalhost,
+	}
+	for _, method := range adminMethods {
+		a.adminMethods[strings.TrimSpace(method)] = true
+	}
+	return a
+}
+
+func nonEmpty(values []string) []string {
+	ret := []string{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			ret = append(ret, value)
+		}
+	}
+	return ret
+}
+
+// role returns the access level of the request.
+func (a *rpcAuth) role(req *http.Request) rpcRole {
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+		return roleAdmin
+	}
+	if token := bearerToken(req); token != "" {
+		if containsToken(a.adminTokens, token) {
+			return roleAdmin
+		}
+		if containsToken(a.userTokens, token) {
+			return roleUser
+		}
+	}
+	if a.adminFromLocalhost && isLoopback(req.RemoteAddr) {
+		return roleAdmin
+	}
+	return roleNone
+}
+
+// allows returns nil if the role is allowed to call the method.
+func (a *rpcAuth) allows(role rpcRole, method string) error {
+	if a.adminMethods[method] {
+		if role < roleAdmin {
+			return fmt.Errorf("method %v is restricted to the admin", method)
+		}
+		return nil
+	}
+	if a.requireAuth && role < roleUser {
+		return fmt.Errorf("authentication required")
+	}
+	return nil
+}
+
+func bearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
+
+// containsToken compares the token against each of the tokens in constant time.
+func containsToken(tokens []string, token string) bool {
+	found := false
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			found = true
+		}
+	}
+	return found
+}
+
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// handler wraps the handler of the JSON-RPC requests over HTTP, rejecting the requests, or the
+// batches, with a call the request is not allowed to make.
+func (a *rpcAuth) handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		role := a.role(req)
+		for _, method := range requestMethods(body) {
+			if err := a.allows(role, method); err != nil {
+				writeUnauthorized(w, role, err.Error())
+				return
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// requestMethods returns the methods called by the JSON-RPC request or batch. A body that cannot
+// be parsed returns no method, and is left to the codec to report the parse error.
+func requestMethods(body []byte) []string {
+	type call struct {
+		Method string `json:"method"`
+	}
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var calls []call
+		if err := json.Unmarshal(trimmed, &calls); err != nil {
+			return nil
+		}
+		methods := []string{}
+		for _, c := range calls {
+			methods = append(methods, c.Method)
+		}
+		return methods
+	}
+	var c call
+	if err := json.Unmarshal(trimmed, &c); err != nil {
+		return nil
+	}
+	return []string{c.Method}
+}
+
+func writeUnauthorized(w http.ResponseWriter, role rpcRole, message string) {
+	status := http.StatusForbidden
+	if role == roleNone {
+		status = http.StatusUnauthorized
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      nil,
+		"error": map[string]interface{}{
+			"code":    -32001,
+			"message": "unauthorized: " + message,
+		},
+	})
+}
+
+//
+// aclServerCodec enforces the access control on the calls read from a long-lived connection, e.g.
+// a websocket, whose role is determined once at the handshake. A call the connection is not
+// allowed to make is redirected to a method that does not exist, so the server replies with an
+// error without executing it.
+//
+type aclServerCodec struct {
+	rpc.ServerCodec
+	auth *rpcAuth
+	role rpcRole
+}
+
+func (c *aclServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	err := c.ServerCodec.ReadRequestHeader(r)
+	if err != nil {
+		return err
+	}
+	if c.auth.allows(c.role, r.ServiceMethod) != nil {
+		r.ServiceMethod = "unauthorized." + r.ServiceMethod
+	}
+	return nil
+}
+
+// loadClientCAs loads the CA certificates the client certificates are verified against.
+func loadClientCAs(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("No CA certificate found in %v", caFile)
+	}
+	return pool, nil
+}
+
+// clientAuthTLSConfig returns the TLS config requesting a client certificate, which authenticates
+// the client as admin if verified against the client CAs. Clients without a certificate can still
+// connect with the role of their token, if any.
+func clientAuthTLSConfig(clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		ClientCAs:  clientCAs,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}
+}
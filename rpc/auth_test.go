@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+)
+
+func postWithToken(t *testing.T, url string, token string, body string) (int, []map[string]interface{}) {
+	req, err := http.NewRequest("POST", url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, decodeReplies(t, resp)
+}
+
+func newAuthTestServer(auth *rpcAuth) *httptest.Server {
+	s := rpc.NewServer()
+	s.RegisterName("test", TestArith{})
+	s.RegisterName("admin", TestArith{})
+	return httptest.NewServer(auth.handler(newBatchLimitHandler(jsonrpc2.HTTPHandler(s), 10)))
+}
+
+const (
+	publicCall = `{"jsonrpc":"2.0","method":"test.Add","params":{"A":1,"B":2},"id":1}`
+	adminCall  = `{"jsonrpc":"2.0","method":"admin.Add","params":{"A":1,"B":2},"id":2}`
+)
+
+func TestRPCAuthAdminMethods(t *testing.T) {
+	assert := assert.New(t)
+
+	// The test server is on the local host, which is not trusted here
+	auth := newRPCAuth([]string{"user"}, []string{"secret"}, []string{"admin.Add"}, false, false)
+	server := newAuthTestServer(auth)
+	defer server.Close()
+
+	// The public methods are open
+	status, replies := postWithToken(t, server.URL, "", publicCall)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(float64(3), replies[0]["result"])
+
+	// The admin methods require an admin token
+	status, replies = postWithToken(t, server.URL, "", adminCall)
+	assert.Equal(http.StatusUnauthorized, status)
+	assert.NotNil(replies[0]["error"])
+	status, _ = postWithToken(t, server.URL, "user", adminCall)
+	assert.Equal(http.StatusForbidden, status)
+	status, _ = postWithToken(t, server.URL, "wrong", adminCall)
+	assert.Equal(http.StatusUnauthorized, status)
+	status, replies = postWithToken(t, server.URL, "secret", adminCall)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(float64(3), replies[0]["result"])
+
+	// A batch with an admin call is rejected as a whole
+	status, _ = postWithToken(t, server.URL, "user", "["+publicCall+","+adminCall+"]")
+	assert.Equal(http.StatusForbidden, status)
+	status, replies = postWithToken(t, server.URL, "secret", "["+publicCall+","+adminCall+"]")
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(2, len(replies))
+
+	// The local host is trusted if allowed
+	auth.adminFromLocalhost = true
+	status, _ = postWithToken(t, server.URL, "", adminCall)
+	assert.Equal(http.StatusOK, status)
+}
+
+func TestRPCAuthRequired(t *testing.T) {
+	assert := assert.New(t)
+
+	auth := newRPCAuth([]string{"user"}, []string{"secret"}, []string{"admin.Add"}, true, true)
+	server := newAuthTestServer(auth)
+	defer server.Close()
+
+	// The local host is allowed the admin methods only, the public methods require a token
+	status, _ := postWithToken(t, server.URL, "", publicCall)
+	assert.Equal(http.StatusOK, status)
+
+	auth.adminFromLocalhost = false
+	status, replies := postWithToken(t, server.URL, "", publicCall)
+	assert.Equal(http.StatusUnauthorized, status)
+	assert.NotNil(replies[0]["error"])
+	status, _ = postWithToken(t, server.URL, "user", publicCall)
+	assert.Equal(http.StatusOK, status)
+	status, _ = postWithToken(t, server.URL, "secret", publicCall)
+	assert.Equal(http.StatusOK, status)
+}
+
+func TestRPCAuthClientCertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	auth := newRPCAuth([]string{}, []string{}, []string{"admin.Add"}, false, false)
+	req := httptest.NewRequest("POST", "/rpc", nil)
+	assert.Equal(roleNone, auth.role(req))
+
+	// A connection with an unverified certificate has no verified chain
+	req.TLS = &tls.ConnectionState{}
+	assert.Equal(roleNone, auth.role(req))
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{&x509.Certificate{}}}
+	assert.Equal(roleAdmin, auth.role(req))
+	assert.Nil(auth.allows(roleAdmin, "admin.Add"))
+	assert.NotNil(auth.allows(roleUser, "admin.Add"))
+}
@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+)
+
+// batchLimitHandler rejects the JSON-RPC 2.0 batch requests that are empty or contain more than
+// maxBatchSize calls. Other requests are passed through to the underlying handler, which executes
+// the calls of a batch concurrently.
+type batchLimitHandler struct {
+	handler      http.Handler
+	maxBatchSize int64 // Accessed atomically, since it can be changed while serving requests
+}
+
+func newBatchLimitHandler(handler http.Handler, maxBatchSize int) *batchLimitHandler {
+	return &batchLimitHandler{
+		handler:      handler,
+		maxBatchSize: int64(maxBatchSize),
+	}
+}
+
+// SetMaxBatchSize changes the max number of calls in a batch, 0 for no limit.
+func (h *batchLimitHandler) SetMaxBatchSize(maxBatchSize int) {
+	atomic.StoreInt64(&h.maxBatchSize, int64(maxBatchSize))
+}
+
+func (h *batchLimitHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		h.handler.ServeHTTP(w, req)
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		h.handler.ServeHTTP(w, req)
+		return
+	}
+
+	var calls []json.RawMessage
+	if err := json.Unmarshal(trimmed, &calls); err != nil {
+		h.handler.ServeHTTP(w, req) // Let the codec report the parse error
+		return
+	}
+	if len(calls) == 0 {
+		writeInvalidRequest(w, "empty batch")
+		return
+	}
+	if maxBatchSize := int(atomic.LoadInt64(&h.maxBatchSize)); maxBatchSize > 0 && len(calls) > maxBatchSize {
+		writeInvalidRequest(w, fmt.Sprintf("batch size %v exceeds the limit %v", len(calls), maxBatchSize))
+		return
+	}
+
+	h.handler.ServeHTTP(w, req)
+}
+
+func writeInvalidRequest(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      nil,
+		"error": map[string]interface{}{
+			"code":    -32600,
+			"message": "invalid request: " + message,
+		},
+	})
+}
@@ -0,0 +1,87 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+)
+
+type TestArith struct{}
+
+type TestAddArgs struct {
+	A, B int
+}
+
+func (TestArith) Add(args TestAddArgs, reply *int) error {
+	*reply = args.A + args.B
+	return nil
+}
+
+func postBatch(t *testing.T, url string, body string) []map[string]interface{} {
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	return decodeReplies(t, resp)
+}
+
+func decodeReplies(t *testing.T, resp *http.Response) []map[string]interface{} {
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.Fatal(err)
+	}
+	replies := []map[string]interface{}{}
+	if raw[0] == '[' {
+		json.Unmarshal(raw, &replies)
+	} else {
+		reply := map[string]interface{}{}
+		json.Unmarshal(raw, &reply)
+		replies = append(replies, reply)
+	}
+	return replies
+}
+
+func TestBatchRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	s := rpc.NewServer()
+	s.RegisterName("test", TestArith{})
+	server := httptest.NewServer(newBatchLimitHandler(jsonrpc2.HTTPHandler(s), 3))
+	defer server.Close()
+
+	replies := postBatch(t, server.URL, `[
+		{"jsonrpc":"2.0","method":"test.Add","params":{"A":1,"B":2},"id":1},
+		{"jsonrpc":"2.0","method":"test.Add","params":{"A":3,"B":4},"id":2}
+	]`)
+	assert.Equal(2, len(replies))
+	results := map[float64]float64{}
+	for _, reply := range replies {
+		results[reply["id"].(float64)] = reply["result"].(float64)
+	}
+	assert.Equal(map[float64]float64{1: 3, 2: 7}, results)
+
+	replies = postBatch(t, server.URL, `[]`)
+	assert.Equal(1, len(replies))
+	assert.NotNil(replies[0]["error"])
+
+	replies = postBatch(t, server.URL, `[
+		{"jsonrpc":"2.0","method":"test.Add","params":{"A":1,"B":2},"id":1},
+		{"jsonrpc":"2.0","method":"test.Add","params":{"A":1,"B":2},"id":2},
+		{"jsonrpc":"2.0","method":"test.Add","params":{"A":1,"B":2},"id":3},
+		{"jsonrpc":"2.0","method":"test.Add","params":{"A":1,"B":2},"id":4}
+	]`)
+	assert.Equal(1, len(replies))
+	assert.NotNil(replies[0]["error"])
+
+	// Single requests are not affected
+	replies = postBatch(t, server.URL, `{"jsonrpc":"2.0","method":"test.Add","params":{"A":5,"B":6},"id":1}`)
+	assert.Equal(1, len(replies))
+	assert.Equal(float64(11), replies[0]["result"])
+}
@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// bridgeMessagesAt returns the outbound bridge messages of the finalized block at the height.
+func (t *ThetaRPCService) bridgeMessagesAt(height uint64) (*core.ExtendedBlock, *types.BridgeMessageList, error) {
+	block := t.findFinalizedBlockByHeight(height)
+	if block == nil {
+		return nil, nil, fmt.Errorf("No finalized block at height %v", height)
+	}
+	deliveredView, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	blockStoreView := state.NewStoreView(height, block.StateHash, deliveredView.GetDB())
+	return block, blockStoreView.GetBridgeOutboundMessages(height), nil
+}
+
+// ------------------------------- GetBridgeBatch -----------------------------------
+
+type GetBridgeBatchArgs struct {
+	Height common.JSONUint64 `json:"height"`
+}
+
+type GetBridgeBatchResult struct {
+	Batch     *core.BridgeBatch     `json:"batch"`
+	Messages  []types.BridgeMessage `json:"messages"`
+	Signature *core.BridgeSignature `json:"signature"` // co-signature of the node, nil if the node is not a validator of the block
+}
+
+// GetBridgeBatch returns the batch of the outbound bridge messages of the finalized block at the
+// height. A validator node also co-signs the batch, so that a relayer can collect the
+// co-signatures of the validator supermajority from the validator nodes.
+func (t *ThetaRPCService) GetBridgeBatch(args *GetBridgeBatchArgs, result *GetBridgeBatchResult) (err error) {
+	height := uint64(args.Height)
+	block, messages, err := t.bridgeMessagesAt(height)
+	if err != nil {
+		return err
+	}
+	result.Batch = types.NewBridgeBatch(t.chain.ChainID, height, messages)
+	result.Messages = messages.Messages
+	if result.Messages == nil {
+		result.Messages = []types.BridgeMessage{}
+	}
+
+	signer := t.consensus.Signer()
+	address := signer.PublicKey().Address()
+	validators := t.consensus.GetValidatorManager().GetValidatorSet(block.Hash())
+	if _, err := validators.GetValidator(address); err != nil {
+		return nil
+	}
+	sig, err := signer.SignBridgeBatch(result.Batch)
+	if err != nil {
+		return err
+	}
+	result.Signature = &core.BridgeSignature{
+		Address:   address,
+		Signature: sig,
+	}
+	return nil
+}
+
+// ------------------------------- GetBridgeMessageProof -----------------------------------
+
+type GetBridgeMessageProofArgs struct {
+	Height common.JSONUint64 `json:"height"`
+	Index  common.JSONUint64 `json:"index"` // index of the message in the batch of the block
+}
+
+type GetBridgeMessageProofResult struct {
+	Message types.BridgeMessage `json:"message"`
+	Batch   *core.BridgeBatch   `json:"batch"`
+	Index   common.JSONUint64   `json:"index"`
+	Proof   []common.Hash       `json:"proof"` // Merkle proof of the message in the batch
+}
+
+// GetBridgeMessageProof returns the Merkle proof of an outbound bridge message in the batch of
+// the finalized block at the height.
+func (t *ThetaRPCService) GetBridgeMessageProof(args *GetBridgeMessageProofArgs, result *GetBridgeMessageProofResult) (err error) {
+	height := uint64(args.Height)
+	_, messages, err := t.bridgeMessagesAt(height)
+	if err != nil {
+		return err
+	}
+	index := uint64(args.Index)
+	proof, err := core.BridgeMerkleProof(messages.Hashes(), index)
+	if err != nil {
+		return err
+	}
+	result.Message = messages.Messages[index]
+	result.Batch = types.NewBridgeBatch(t.chain.ChainID, height, messages)
+	result.Index = args.Index
+	result.Proof = proof
+	return nil
+}
+
+// ------------------------------- GetBridgeValidators -----------------------------------
+
+type GetBridgeValidatorsArgs struct {
+	ChainID string `json:"chain_id"`
+}
+
+type GetBridgeValidatorsResult struct {
+	ChainID    string      `json:"chain_id"`
+	Validators []Validator `json:"validators"`
+}
+
+// GetBridgeValidators returns the registered validator set of the counterpart chain, which
+// co-signs the batches of the messages inbound from the chain.
+func (t *ThetaRPCService) GetBridgeValidators(args *GetBridgeValidatorsArgs, result *GetBridgeValidatorsResult) (err error) {
+	ledgerState, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+	bridgeValidators := ledgerState.GetBridgeValidators(args.ChainID)
+	if bridgeValidators == nil {
+		return fmt.Errorf("Chain %v is not a counterpart of the bridge", args.ChainID)
+	}
+	result.ChainID = bridgeValidators.ChainID
+	result.Validators = []Validator{}
+	for _, v := range bridgeValidators.Validators {
+		result.Validators = append(result.Validators, Validator{
+			Address: v.Address,
+			Stake:   (*common.JSONBig)(v.Stake),
+		})
+	}
+	return nil
+}
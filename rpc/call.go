@@ -53,3 +53,73 @@ func (t *ThetaRPCService) CallSmartContract(args *CallSmartContractArgs, result
 
 	return nil
 }
+
+// ------------------------------- CallTx -----------------------------------
+
+type CallTxArgs struct {
+	TxBytes         string            `json:"tx_bytes"`
+	Height          common.JSONUint64 `json:"height"`            // the state of the finalized block at the height, 0 for the latest state
+	SkipSanityCheck bool              `json:"skip_sanity_check"` // skip the signature, sequence and balance checks, e.g. for unsigned transactions
+}
+
+type CallTxResult struct {
+	GasUsed         common.JSONUint64 `json:"gas_used"`
+	VmReturn        string            `json:"vm_return"`
+	ContractAddress common.Address    `json:"contract_address"`
+	VmError         string            `json:"vm_error"`
+	Logs            []*types.Log      `json:"logs"`
+}
+
+// CallTx executes the transaction against the latest state, or the state at the given height,
+// without committing it. The transaction can be of any type other than the ones added by the
+// block proposers. The VM fields of the result are only set for smart contract transactions.
+func (t *ThetaRPCService) CallTx(args *CallTxArgs, result *CallTxResult) (err error) {
+	txBytes, err := hex.DecodeString(args.TxBytes)
+	if err != nil {
+		return err
+	}
+
+	dryRun, res := t.ledger.DryRunTx(txBytes, uint64(args.Height), args.SkipSanityCheck)
+	if res.IsError() {
+		return toRPCError(res.ToError())
+	}
+
+	result.GasUsed = common.JSONUint64(dryRun.GasUsed)
+	result.Logs = []*types.Log{}
+	if receipt := dryRun.Receipt; receipt != nil {
+		result.VmReturn = hex.EncodeToString(receipt.EvmRet)
+		result.ContractAddress = receipt.ContractAddress
+		result.VmError = receipt.EvmErr
+		if receipt.Logs != nil {
+			result.Logs = receipt.Logs
+		}
+	}
+	return nil
+}
+
+// ------------------------------- EstimateGas -----------------------------------
+
+type EstimateGasArgs struct {
+	TxBytes string            `json:"tx_bytes"`
+	Height  common.JSONUint64 `json:"height"` // the state of the finalized block at the height, 0 for the latest state
+}
+
+type EstimateGasResult struct {
+	GasLimit common.JSONUint64 `json:"gas_limit"`
+}
+
+// EstimateGas returns the lowest gas limit with which the transaction executes successfully.
+// The gas limit of the given transaction is ignored, and the transaction need not be signed.
+func (t *ThetaRPCService) EstimateGas(args *EstimateGasArgs, result *EstimateGasResult) (err error) {
+	txBytes, err := hex.DecodeString(args.TxBytes)
+	if err != nil {
+		return err
+	}
+
+	gasLimit, res := t.ledger.EstimateGas(txBytes, uint64(args.Height))
+	if res.IsError() {
+		return toRPCError(res.ToError())
+	}
+	result.GasLimit = common.JSONUint64(gasLimit)
+	return nil
+}
@@ -0,0 +1,108 @@
+package rpc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+// ------------------------------- GetConsensusState -----------------------------------
+
+type GetConsensusStateArgs struct{}
+
+type ConsensusBlock struct {
+	Hash               common.Hash       `json:"hash"`
+	Height             common.JSONUint64 `json:"height"`
+	Epoch              common.JSONUint64 `json:"epoch"`
+	Status             core.BlockStatus  `json:"status"`
+	HasValidatorUpdate bool              `json:"has_validator_update"`
+}
+
+type ProposalStatus struct {
+	Proposer          common.Address    `json:"proposer"`            // proposer of the current epoch
+	ShouldPropose     bool              `json:"should_propose"`      // whether the local node is due to propose
+	Proposed          bool              `json:"proposed"`            // whether the local node proposed in the current epoch
+	LastProposalBlock common.Hash       `json:"last_proposal_block"` // last block proposed by the local node
+	LastProposalEpoch common.JSONUint64 `json:"last_proposal_epoch"`
+}
+
+type PendingVoteSet struct {
+	Block  common.Hash       `json:"block"`
+	Height common.JSONUint64 `json:"height"`
+	Votes  []core.Vote       `json:"votes"`
+}
+
+type GetConsensusStateResult struct {
+	Epoch              common.JSONUint64 `json:"epoch"`
+	EpochTimeoutMs     int64             `json:"epoch_timeout_ms"`
+	MissedEpochs       uint              `json:"missed_epochs"`
+	HighestCCBlock     ConsensusBlock    `json:"highest_cc_block"`
+	LastFinalizedBlock ConsensusBlock    `json:"last_finalized_block"`
+	LastVote           core.Vote         `json:"last_vote"`
+	TipCandidates      []ConsensusBlock  `json:"tip_candidates"`
+	Proposal           ProposalStatus    `json:"proposal"`
+	PendingVoteSets    []PendingVoteSet  `json:"pending_vote_sets"` // votes on the blocks above the highest CC block
+	EpochVotes         []core.Vote       `json:"epoch_votes"`
+}
+
+// GetConsensusState dumps the state of the consensus engine, for diagnosing the stalls of a node
+// remotely. It is an admin method by default.
+func (t *ThetaRPCService) GetConsensusState(args *GetConsensusStateArgs, result *GetConsensusStateResult) (err error) {
+	s := t.consensus.GetDebugState()
+
+	result.Epoch = common.JSONUint64(s.Epoch)
+	timeout := t.consensus.GetEpochTimeout()
+	result.EpochTimeoutMs = int64(timeout.Timeout() / time.Millisecond)
+	result.MissedEpochs = timeout.MissedEpochs()
+	result.HighestCCBlock = newConsensusBlock(s.HighestCCBlock)
+	result.LastFinalizedBlock = newConsensusBlock(s.LastFinalizedBlock)
+	result.LastVote = s.LastVote
+
+	result.TipCandidates = []ConsensusBlock{}
+	for _, block := range s.TipCandidates {
+		result.TipCandidates = append(result.TipCandidates, newConsensusBlock(block))
+	}
+	sort.Slice(result.TipCandidates, func(i, j int) bool {
+		return result.TipCandidates[i].Height > result.TipCandidates[j].Height
+	})
+
+	result.Proposal = ProposalStatus{
+		Proposer:      s.Proposer.ID(),
+		ShouldPropose: s.ShouldPropose,
+	}
+	if block := s.LastProposal.Block; block != nil {
+		result.Proposal.Proposed = block.Epoch == s.Epoch
+		result.Proposal.LastProposalBlock = block.Hash()
+		result.Proposal.LastProposalEpoch = common.JSONUint64(block.Epoch)
+	}
+
+	result.PendingVoteSets = []PendingVoteSet{}
+	for hash, votes := range s.PendingVotes {
+		pending := PendingVoteSet{
+			Block: hash,
+			Votes: votes.Votes(),
+		}
+		if block, err := t.chain.FindBlockHeader(hash); err == nil {
+			pending.Height = common.JSONUint64(block.Height)
+		}
+		result.PendingVoteSets = append(result.PendingVoteSets, pending)
+	}
+	sort.Slice(result.PendingVoteSets, func(i, j int) bool {
+		return result.PendingVoteSets[i].Height < result.PendingVoteSets[j].Height
+	})
+
+	result.EpochVotes = s.EpochVotes.Votes()
+	return nil
+}
+
+func newConsensusBlock(block *core.ExtendedBlock) ConsensusBlock {
+	return ConsensusBlock{
+		Hash:               block.Hash(),
+		Height:             common.JSONUint64(block.Height),
+		Epoch:              common.JSONUint64(block.Epoch),
+		Status:             block.Status,
+		HasValidatorUpdate: block.HasValidatorUpdate,
+	}
+}
@@ -0,0 +1,26 @@
+package rpc
+
+import (
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+)
+
+// codedError is implemented by the errors that carry a ledger or mempool error code.
+type codedError interface {
+	error
+	ErrorCode() result.ErrorCode
+}
+
+// toRPCError converts the errors carrying an error code into JSON-RPC errors, so that clients
+// receive the numeric code in the "code" field and its stable name in the "data" field. Other
+// errors are returned unchanged.
+func toRPCError(err error) error {
+	ce, ok := err.(codedError)
+	if !ok {
+		return err
+	}
+	code := ce.ErrorCode()
+	rpcErr := jsonrpc2.NewError(int(code), ce.Error())
+	rpcErr.Data = map[string]string{"name": code.String()}
+	return rpcErr
+}
@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+)
+
+func TestToRPCError(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(toRPCError(nil))
+
+	plain := errors.New("some error")
+	assert.Equal(plain, toRPCError(plain))
+
+	res := result.Error("Got 3, expected 2").WithErrorCode(result.CodeInvalidSequence)
+	err := toRPCError(res.ToError())
+
+	rpcErr := &jsonrpc2.Error{}
+	assert.Nil(json.Unmarshal([]byte(err.Error()), rpcErr))
+	assert.Equal(int(result.CodeInvalidSequence), rpcErr.Code)
+	assert.Equal("Got 3, expected 2", rpcErr.Message)
+	assert.Equal(map[string]interface{}{"name": "InvalidSequence"}, rpcErr.Data)
+
+	assert.Nil(result.OK.ToError())
+	assert.Equal("ErrorCode(999)", result.ErrorCode(999).String())
+}
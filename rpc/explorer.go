@@ -0,0 +1,302 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+const (
+	// DefaultExplorerPageSize is the number of items an explorer query returns if no limit is given
+	DefaultExplorerPageSize = 20
+
+	// MaxExplorerPageSize is the maximum number of items an explorer query returns at a time
+	MaxExplorerPageSize = 100
+)
+
+// explorerPageSize returns the page size for the requested limit, capped at MaxExplorerPageSize.
+func explorerPageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultExplorerPageSize
+	}
+	if limit > MaxExplorerPageSize {
+		return MaxExplorerPageSize
+	}
+	return limit
+}
+
+// encodeCursor encodes the position of the next item of a paginated query. The cursors are
+// opaque to the clients, which only pass them back to fetch the next page.
+func encodeCursor(position uint64) string {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, position)
+	return hex.EncodeToString(buf)
+}
+
+// decodeCursor decodes a cursor returned by encodeCursor. The second return value is false if
+// the cursor is empty, i.e. the query starts from the first page.
+func decodeCursor(cursor string) (uint64, bool, error) {
+	if cursor == "" {
+		return 0, false, nil
+	}
+	buf, err := hex.DecodeString(cursor)
+	if err != nil || len(buf) != 8 {
+		return 0, false, fmt.Errorf("Invalid cursor: %v", cursor)
+	}
+	return binary.BigEndian.Uint64(buf), true, nil
+}
+
+// ------------------------------- GetBlocksByRange -----------------------------------
+
+type GetBlocksByRangeArgs struct {
+	FromHeight common.JSONUint64 `json:"from_height"`
+	ToHeight   common.JSONUint64 `json:"to_height"` // Defaults to the latest finalized block
+	Cursor     string            `json:"cursor"`    // next_cursor of the previous page, empty for the first page
+	Limit      int               `json:"limit"`     // Defaults to DefaultExplorerPageSize, capped at MaxExplorerPageSize
+}
+
+// ExplorerBlock summarizes a finalized block, without its transactions
+type ExplorerBlock struct {
+	Hash      common.Hash       `json:"hash"`
+	ChainID   string            `json:"chain_id"`
+	Epoch     common.JSONUint64 `json:"epoch"`
+	Height    common.JSONUint64 `json:"height"`
+	Parent    common.Hash       `json:"parent"`
+	TxHash    common.Hash       `json:"transactions_hash"`
+	StateHash common.Hash       `json:"state_hash"`
+	Timestamp *common.JSONBig   `json:"timestamp"`
+	BaseFee   *common.JSONBig   `json:"base_fee"`
+	Proposer  common.Address    `json:"proposer"`
+	NumTxs    int               `json:"num_txs"`
+}
+
+type GetBlocksByRangeResult struct {
+	Blocks     []ExplorerBlock `json:"blocks"`
+	NextCursor string          `json:"next_cursor"` // empty if there are no more blocks in the range
+}
+
+// GetBlocksByRange returns the finalized blocks in the height range, in increasing height order.
+func (t *ThetaRPCService) GetBlocksByRange(args *GetBlocksByRangeArgs, result *GetBlocksByRangeResult) (err error) {
+	lfbHeight := t.consensus.GetLastFinalizedBlock().Height
+	from := uint64(args.FromHeight)
+	to := uint64(args.ToHeight)
+	if to == 0 || to > lfbHeight {
+		to = lfbHeight
+	}
+	position, ok, err := decodeCursor(args.Cursor)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if position < from {
+			return fmt.Errorf("Invalid cursor: %v", args.Cursor)
+		}
+		from = position
+	}
+
+	result.Blocks = []ExplorerBlock{}
+	pageSize := explorerPageSize(args.Limit)
+	height := from
+	for ; height <= to && len(result.Blocks) < pageSize; height++ {
+		block := t.findFinalizedBlockByHeight(height)
+		if block == nil {
+			continue
+		}
+		result.Blocks = append(result.Blocks, ExplorerBlock{
+			Hash:      block.Hash(),
+			ChainID:   block.ChainID,
+			Epoch:     common.JSONUint64(block.Epoch),
+			Height:    common.JSONUint64(block.Height),
+			Parent:    block.Parent,
+			TxHash:    block.TxHash,
+			StateHash: block.StateHash,
+			Timestamp: (*common.JSONBig)(block.Timestamp),
+			BaseFee:   (*common.JSONBig)(block.BaseFee),
+			Proposer:  block.Proposer,
+			NumTxs:    len(block.Txs),
+		})
+	}
+	if height <= to {
+		result.NextCursor = encodeCursor(height)
+	}
+	return nil
+}
+
+// ------------------------------- GetTxsInBlock -----------------------------------
+
+type GetTxsInBlockArgs struct {
+	Hash   common.Hash       `json:"hash"`
+	Height common.JSONUint64 `json:"height"` // the finalized block at the height, if the hash is not given
+	Cursor string            `json:"cursor"` // next_cursor of the previous page, empty for the first page
+	Limit  int               `json:"limit"`  // Defaults to DefaultExplorerPageSize, capped at MaxExplorerPageSize
+}
+
+// ExplorerTx is a transaction at the given index of a block
+type ExplorerTx struct {
+	Tx
+	Index common.JSONUint64 `json:"index"`
+}
+
+type GetTxsInBlockResult struct {
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	NumTxs      int               `json:"num_txs"`
+	Txs         []ExplorerTx      `json:"transactions"`
+	NextCursor  string            `json:"next_cursor"` // empty if there are no more transactions in the block
+}
+
+// GetTxsInBlock returns the transactions of the block, in the order they were executed.
+func (t *ThetaRPCService) GetTxsInBlock(args *GetTxsInBlockArgs, result *GetTxsInBlockResult) (err error) {
+	var block *core.ExtendedBlock
+	if !args.Hash.IsEmpty() {
+		block, err = t.chain.FindBlock(args.Hash)
+		if err != nil {
+			return err
+		}
+	} else if args.Height != 0 {
+		block = t.findFinalizedBlockByHeight(uint64(args.Height))
+		if block == nil {
+			return fmt.Errorf("No finalized block at height %v", uint64(args.Height))
+		}
+	} else {
+		return errors.New("Block hash or height must be specified")
+	}
+
+	start, _, err := decodeCursor(args.Cursor)
+	if err != nil {
+		return err
+	}
+
+	result.BlockHash = block.Hash()
+	result.BlockHeight = common.JSONUint64(block.Height)
+	result.NumTxs = len(block.Txs)
+	result.Txs = []ExplorerTx{}
+	end := start + uint64(explorerPageSize(args.Limit))
+	if end > uint64(len(block.Txs)) {
+		end = uint64(len(block.Txs))
+	}
+	for idx := start; idx < end; idx++ {
+		rawTx := block.Txs[idx]
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			return err
+		}
+		result.Txs = append(result.Txs, ExplorerTx{
+			Tx: Tx{
+				Tx:   tx,
+				Type: getTxType(tx),
+				Hash: crypto.HashAtHeight(block.Height, rawTx),
+			},
+			Index: common.JSONUint64(idx),
+		})
+	}
+	if end < uint64(len(block.Txs)) {
+		result.NextCursor = encodeCursor(end)
+	}
+	return nil
+}
+
+// ------------------------------- GetAccountHistory -----------------------------------
+
+type GetAccountHistoryArgs struct {
+	Address string `json:"address"`
+	Reverse bool   `json:"reverse"` // newest transactions first
+	Cursor  string `json:"cursor"`  // next_cursor of the previous page, empty for the first page
+	Limit   int    `json:"limit"`   // Defaults to DefaultExplorerPageSize, capped at MaxExplorerPageSize
+}
+
+// AccountHistoryTx is a finalized transaction involving the account
+type AccountHistoryTx struct {
+	Tx
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	Index       common.JSONUint64 `json:"index"`
+}
+
+type GetAccountHistoryResult struct {
+	NumTxs     common.JSONUint64  `json:"num_txs"`
+	Txs        []AccountHistoryTx `json:"transactions"`
+	NextCursor string             `json:"next_cursor"` // empty if there are no more transactions
+}
+
+// GetAccountHistory returns the finalized transactions involving the address, in the order they
+// were finalized, or in the reverse order. It requires the address index to be enabled. The index of the transactions of an address is append
+// only, so the pages are stable as new blocks get finalized.
+func (t *ThetaRPCService) GetAccountHistory(args *GetAccountHistoryArgs, result *GetAccountHistoryResult) (err error) {
+	if !viper.GetBool(common.CfgIndexAddress) {
+		return errors.New("The address index is not enabled, set index.address to enable it")
+	}
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	address := common.HexToAddress(args.Address)
+	count := t.chain.GetAddressTxCount(address)
+	position, ok, err := decodeCursor(args.Cursor)
+	if err != nil {
+		return err
+	}
+
+	pageSize := uint64(explorerPageSize(args.Limit))
+	var entries []blockchain.AddressTxEntry
+	if args.Reverse {
+		// The position is the one past the next entry, so that the first page starts at count
+		if !ok {
+			position = count
+		}
+		if position > count {
+			return fmt.Errorf("Invalid cursor: %v", args.Cursor)
+		}
+		start := uint64(0)
+		if position > pageSize {
+			start = position - pageSize
+		}
+		entries = t.chain.FindTxsByAddress(address, start, position-start)
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+		if start > 0 {
+			result.NextCursor = encodeCursor(start)
+		}
+	} else {
+		entries = t.chain.FindTxsByAddress(address, position, pageSize)
+		if next := position + uint64(len(entries)); next < count {
+			result.NextCursor = encodeCursor(next)
+		}
+	}
+
+	result.NumTxs = common.JSONUint64(count)
+	result.Txs = []AccountHistoryTx{}
+	for _, entry := range entries {
+		block, err := t.chain.FindBlock(entry.BlockHash)
+		if err != nil {
+			return err
+		}
+		if entry.Index >= uint64(len(block.Txs)) {
+			return fmt.Errorf("Transaction %v not found in block %v", entry.TxHash.Hex(), entry.BlockHash.Hex())
+		}
+		tx, err := types.TxFromBytes(block.Txs[entry.Index])
+		if err != nil {
+			return err
+		}
+		result.Txs = append(result.Txs, AccountHistoryTx{
+			Tx: Tx{
+				Tx:   tx,
+				Type: getTxType(tx),
+				Hash: entry.TxHash,
+			},
+			BlockHash:   entry.BlockHash,
+			BlockHeight: common.JSONUint64(entry.BlockHeight),
+			Index:       common.JSONUint64(entry.Index),
+		})
+	}
+	return nil
+}
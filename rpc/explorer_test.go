@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplorerCursor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, position := range []uint64{0, 1, 12345, ^uint64(0)} {
+		decoded, ok, err := decodeCursor(encodeCursor(position))
+		assert.Nil(err)
+		assert.True(ok)
+		assert.Equal(position, decoded)
+	}
+
+	_, ok, err := decodeCursor("")
+	assert.Nil(err)
+	assert.False(ok)
+
+	_, _, err = decodeCursor("xyz")
+	assert.NotNil(err)
+	_, _, err = decodeCursor("0102")
+	assert.NotNil(err)
+}
+
+func TestExplorerPageSize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(DefaultExplorerPageSize, explorerPageSize(0))
+	assert.Equal(DefaultExplorerPageSize, explorerPageSize(-1))
+	assert.Equal(5, explorerPageSize(5))
+	assert.Equal(MaxExplorerPageSize, explorerPageSize(MaxExplorerPageSize+1))
+}
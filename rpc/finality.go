@@ -0,0 +1,38 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+// ------------------------------- GetFinalityCertificate -----------------------------------
+
+type GetFinalityCertificateArgs struct {
+	Hash   common.Hash       `json:"hash"`
+	Height common.JSONUint64 `json:"height"` // the finalized block at the height, if the hash is not given
+}
+
+type GetFinalityCertificateResult struct {
+	*core.FinalityCertificate
+}
+
+// GetFinalityCertificate exports a certificate proving the finality of the block, which can be
+// verified offline with core.FinalityCertificate.Verify.
+func (t *ThetaRPCService) GetFinalityCertificate(args *GetFinalityCertificateArgs, result *GetFinalityCertificateResult) (err error) {
+	hash := args.Hash
+	if hash.IsEmpty() {
+		if args.Height == 0 {
+			return errors.New("Block hash or height must be specified")
+		}
+		block := t.findFinalizedBlockByHeight(uint64(args.Height))
+		if block == nil {
+			return fmt.Errorf("No finalized block at height %v", uint64(args.Height))
+		}
+		hash = block.Hash()
+	}
+	result.FinalityCertificate, err = t.consensus.GetFinalityCertificate(hash)
+	return err
+}
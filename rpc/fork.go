@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+)
+
+const (
+	defaultForkTreeDepth = 100
+	maxForkTreeDepth     = 1000
+)
+
+// ------------------------------- GetForkTree -----------------------------------
+
+type GetForkTreeArgs struct {
+	From     common.Hash       `json:"from"`      // Root of the tree, defaults to the last finalized block
+	MaxDepth common.JSONUint64 `json:"max_depth"` // Number of levels of the tree, defaults to 100
+}
+
+type GetForkTreeResult struct {
+	Tree     *blockchain.ForkBlock `json:"tree"`
+	Branches []blockchain.Branch   `json:"branches"`
+}
+
+func (t *ThetaRPCService) GetForkTree(args *GetForkTreeArgs, result *GetForkTreeResult) (err error) {
+	from := args.From
+	if from.IsEmpty() {
+		from = t.consensus.GetLastFinalizedBlock().Hash()
+	}
+	maxDepth := int(args.MaxDepth)
+	if maxDepth == 0 {
+		maxDepth = defaultForkTreeDepth
+	}
+	if maxDepth > maxForkTreeDepth {
+		maxDepth = maxForkTreeDepth
+	}
+
+	valMgr := t.consensus.GetValidatorManager()
+	result.Tree, err = t.chain.GetForkTree(from, maxDepth, valMgr)
+	if err != nil {
+		return err
+	}
+	result.Branches = result.Tree.Branches()
+	return nil
+}
@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// GovernanceProposal is a governance proposal, with the tally of its votes weighted by the
+// current stakes of the voters
+type GovernanceProposal struct {
+	ID               common.JSONUint64      `json:"id"`
+	Proposer         common.Address         `json:"proposer"`
+	Params           types.ChainParams      `json:"params"`
+	SubmitHeight     common.JSONUint64      `json:"submit_height"`
+	VotingEndHeight  common.JSONUint64      `json:"voting_end_height"`
+	ActivationHeight common.JSONUint64      `json:"activation_height"`
+	Status           string                 `json:"status"`
+	Votes            []types.GovernanceVote `json:"votes"`
+	Tally            GovernanceTally        `json:"tally"`
+}
+
+type GovernanceTally struct {
+	ApprovedStake *common.JSONBig `json:"approved_stake"`
+	RejectedStake *common.JSONBig `json:"rejected_stake"`
+	TotalStake    *common.JSONBig `json:"total_stake"`
+	Approved      bool            `json:"approved"` // more than 2/3 of the total stake approved
+}
+
+func newGovernanceProposal(view *state.StoreView, proposal *types.GovernanceProposal) *GovernanceProposal {
+	tally := types.TallyGovernanceProposal(proposal, view.GetValidatorCandidatePool())
+	votes := proposal.Votes
+	if votes == nil {
+		votes = []types.GovernanceVote{}
+	}
+	return &GovernanceProposal{
+		ID:               common.JSONUint64(proposal.ID),
+		Proposer:         proposal.Proposer,
+		Params:           proposal.Params,
+		SubmitHeight:     common.JSONUint64(proposal.SubmitHeight),
+		VotingEndHeight:  common.JSONUint64(proposal.VotingEndHeight),
+		ActivationHeight: common.JSONUint64(proposal.ActivationHeight),
+		Status:           proposal.Status.String(),
+		Votes:            votes,
+		Tally: GovernanceTally{
+			ApprovedStake: (*common.JSONBig)(tally.ApprovedStake),
+			RejectedStake: (*common.JSONBig)(tally.RejectedStake),
+			TotalStake:    (*common.JSONBig)(tally.TotalStake),
+			Approved:      tally.Approved(),
+		},
+	}
+}
+
+// ------------------------------- GetGovernanceProposal -----------------------------------
+
+type GetGovernanceProposalArgs struct {
+	ID common.JSONUint64 `json:"id"`
+}
+
+type GetGovernanceProposalResult struct {
+	*GovernanceProposal
+}
+
+func (t *ThetaRPCService) GetGovernanceProposal(args *GetGovernanceProposalArgs, result *GetGovernanceProposalResult) (err error) {
+	ledgerState, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+	proposal := ledgerState.GetGovernanceProposal(uint64(args.ID))
+	if proposal == nil {
+		return fmt.Errorf("Governance proposal %v not found", uint64(args.ID))
+	}
+	result.GovernanceProposal = newGovernanceProposal(ledgerState, proposal)
+	return nil
+}
+
+// ------------------------------- GetGovernanceProposals -----------------------------------
+
+type GetGovernanceProposalsArgs struct {
+	Status string `json:"status"` // voting, passed, rejected or activated, all the proposals if empty
+}
+
+type GetGovernanceProposalsResult struct {
+	Proposals []*GovernanceProposal `json:"proposals"`
+}
+
+// GetGovernanceProposals returns the governance proposals in the order they were submitted.
+func (t *ThetaRPCService) GetGovernanceProposals(args *GetGovernanceProposalsArgs, result *GetGovernanceProposalsResult) (err error) {
+	filter := args.Status != ""
+	var status types.GovernanceProposalStatus
+	if filter {
+		status, err = types.ParseGovernanceProposalStatus(args.Status)
+		if err != nil {
+			return err
+		}
+	}
+
+	ledgerState, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+	result.Proposals = []*GovernanceProposal{}
+	nextID := ledgerState.GetNextGovernanceProposalID()
+	for id := uint64(1); id < nextID; id++ {
+		proposal := ledgerState.GetGovernanceProposal(id)
+		if proposal == nil || (filter && proposal.Status != status) {
+			continue
+		}
+		result.Proposals = append(result.Proposals, newGovernanceProposal(ledgerState, proposal))
+	}
+	return nil
+}
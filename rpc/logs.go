@@ -0,0 +1,139 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// MaxGetLogsBlockRange is the maximum number of blocks a single GetLogs query can scan.
+const MaxGetLogsBlockRange = 5000
+
+// ------------------------------- GetLogs -----------------------------------
+
+// GetLogsArgs specifies the log filter. A log matches if it was emitted by any of the
+// Addresses (or any contract if empty), and for each position i, its i-th topic is any of
+// Topics[i] (or any topic if Topics[i] is empty).
+type GetLogsArgs struct {
+	FromBlock common.JSONUint64 `json:"from_block"`
+	ToBlock   common.JSONUint64 `json:"to_block"` // Defaults to the latest finalized block
+	Addresses []common.Address  `json:"addresses"`
+	Topics    [][]common.Hash   `json:"topics"`
+}
+
+type GetLogsResult struct {
+	Logs []*types.Log `json:"logs"`
+}
+
+func (t *ThetaRPCService) GetLogs(args *GetLogsArgs, result *GetLogsResult) (err error) {
+	from := uint64(args.FromBlock)
+	to := uint64(args.ToBlock)
+	if to == 0 {
+		lfb, err := t.chain.FindBlockHeader(t.consensus.GetSummary().LastFinalizedBlock)
+		if err != nil {
+			return err
+		}
+		to = lfb.Height
+	}
+	if from > to {
+		return errors.New("from_block must not be greater than to_block")
+	}
+	if to-from >= MaxGetLogsBlockRange {
+		return fmt.Errorf("Block range too large, at most %v blocks can be queried at a time", MaxGetLogsBlockRange)
+	}
+
+	result.Logs = []*types.Log{}
+	for height := from; height <= to; height++ {
+		block := t.findFinalizedBlockByHeight(height)
+		if block == nil || !bloomMatches(block.Bloom, args.Addresses, args.Topics) {
+			continue
+		}
+		for _, receipt := range t.chain.FindBlockReceipts(block.Hash()) {
+			for _, l := range receipt.Logs {
+				if logMatches(l, args.Addresses, args.Topics) {
+					result.Logs = append(result.Logs, l)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (t *ThetaRPCService) findFinalizedBlockByHeight(height uint64) *core.ExtendedBlock {
+	for _, b := range t.chain.FindBlocksByHeight(height) {
+		if b.Status.IsFinalized() {
+			return b
+		}
+	}
+	return nil
+}
+
+// bloomMatches returns false if the bloom filter rules out any log of the block matching the filter.
+func bloomMatches(bloom core.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		included := false
+		for _, addr := range addresses {
+			if core.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		if len(sub) == 0 {
+			continue
+		}
+		included := false
+		for _, topic := range sub {
+			if core.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// logMatches returns whether the log matches the filter.
+func logMatches(l *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		included := false
+		for _, addr := range addresses {
+			if l.Address == addr {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(topics) > len(l.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue
+		}
+		included := false
+		for _, topic := range sub {
+			if l.Topics[i] == topic {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
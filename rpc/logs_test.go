@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func TestLogFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	contract := common.HexToAddress("0x1234")
+	other := common.HexToAddress("0x5678")
+	transfer := common.HexToHash("0xaa")
+	approval := common.HexToHash("0xbb")
+	sender := common.HexToHash("0x01")
+
+	l := &types.Log{Address: contract, Topics: []common.Hash{transfer, sender}}
+	bloom := types.CreateBloom([]*types.Receipt{{Logs: []*types.Log{l}}})
+
+	filters := []struct {
+		addresses []common.Address
+		topics    [][]common.Hash
+		match     bool
+	}{
+		{nil, nil, true},
+		{[]common.Address{contract}, nil, true},
+		{[]common.Address{other, contract}, nil, true},
+		{[]common.Address{other}, nil, false},
+		{nil, [][]common.Hash{{transfer}}, true},
+		{nil, [][]common.Hash{{approval}}, false},
+		{nil, [][]common.Hash{{approval, transfer}}, true},
+		{nil, [][]common.Hash{{}, {sender}}, true},
+		{[]common.Address{contract}, [][]common.Hash{{transfer}, {sender}}, true},
+		{[]common.Address{contract}, [][]common.Hash{{transfer}, {approval}}, false},
+	}
+	for i, f := range filters {
+		assert.Equal(f.match, logMatches(l, f.addresses, f.topics), "filter %v", i)
+		if f.match {
+			// The bloom filter never rules out a matching block.
+			assert.True(bloomMatches(bloom, f.addresses, f.topics), "filter %v", i)
+		}
+	}
+
+	// Topic position beyond the log's topics.
+	assert.False(logMatches(l, nil, [][]common.Hash{{}, {}, {sender}}))
+
+	assert.False(bloomMatches(types.CreateBloom(nil), []common.Address{contract}, nil))
+}
@@ -0,0 +1,171 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//
+// corsHandler serves the CORS headers, so browser dapps served from the allowed origins can call
+// the RPC server. An origin "*" allows all the origins. Without allowed origins, no CORS header
+// is served and browsers only allow the same origin.
+//
+type corsHandler struct {
+	handler        http.Handler
+	allowedOrigins map[string]bool
+	allowAll       bool
+}
+
+func newCORSHandler(handler http.Handler, allowedOrigins []string) *corsHandler {
+	h := &corsHandler{
+		handler:        handler,
+		allowedOrigins: make(map[string]bool),
+	}
+	for _, origin := range nonEmpty(allowedOrigins) {
+		if origin == "*" {
+			h.allowAll = true
+		}
+		h.allowedOrigins[strings.TrimRight(origin, "/")] = true
+	}
+	return h
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	if origin == "" || !(h.allowAll || h.allowedOrigins[origin]) {
+		h.handler.ServeHTTP(w, req)
+		return
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if h.allowAll {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	// Preflight request
+	if req.Method == "OPTIONS" && req.Header.Get("Access-Control-Request-Method") != "" {
+		header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		header.Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		header.Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.handler.ServeHTTP(w, req)
+}
+
+// maxBodySizeHandler rejects the requests whose body is larger than maxBodySize bytes, 0 for
+// no limit. A body of unknown length fails to read beyond the limit.
+func maxBodySizeHandler(handler http.Handler, maxBodySize int64) http.Handler {
+	if maxBodySize <= 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.ContentLength > maxBodySize {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		req.Body = http.MaxBytesReader(w, req.Body, maxBodySize)
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// requestTimeoutHandler replies with an error to the requests not handled within the timeout, 0
+// for no timeout. It does not apply to the websocket connections, which are long-lived.
+func requestTimeoutHandler(handler http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return handler
+	}
+	message := fmt.Sprintf(`{"jsonrpc":"2.0","id":null,"error":{"code":-32603,"message":"request timed out after %v"}}`, timeout)
+	return http.TimeoutHandler(handler, timeout, message)
+}
+
+//
+// proxyHandler resolves the address of the clients connecting through the trusted reverse
+// proxies, e.g. load balancers, from the X-Forwarded-For header. The client address is the last
+// address in the header not of a trusted proxy, and replaces the remote address of the request,
+// so that, e.g., a request forwarded by a proxy on the local host is not mistaken for a local one.
+// The header is ignored on the requests not from a trusted proxy, since it can be forged.
+//
+type proxyHandler struct {
+	handler        http.Handler
+	trustedProxies []*net.IPNet
+}
+
+func newProxyHandler(handler http.Handler, trustedProxies []string) (*proxyHandler, error) {
+	h := &proxyHandler{
+		handler: handler,
+	}
+	for _, proxy := range nonEmpty(trustedProxies) {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid trusted proxy address: %v", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			h.trustedProxies = append(h.trustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid trusted proxy range: %v", proxy)
+		}
+		h.trustedProxies = append(h.trustedProxies, ipNet)
+	}
+	return h, nil
+}
+
+func (h *proxyHandler) isTrusted(ip net.IP) bool {
+	for _, ipNet := range h.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP address of the client the request is from.
+func (h *proxyHandler) clientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !h.isTrusted(ip) {
+		return ip
+	}
+
+	forwarded := []string{}
+	for _, header := range req.Header["X-Forwarded-For"] {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		forwardedIP := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if forwardedIP == nil {
+			break // The addresses before an invalid one cannot be trusted
+		}
+		ip = forwardedIP
+		if !h.isTrusted(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+func (h *proxyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if len(h.trustedProxies) > 0 {
+		if ip := h.clientIP(req); ip != nil {
+			req.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+		}
+	}
+	h.handler.ServeHTTP(w, req)
+}
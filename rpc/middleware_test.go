@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	h := newCORSHandler(ok, []string{"https://dapp.example.com/"})
+
+	req := httptest.NewRequest("POST", "/rpc", nil)
+	req.Header.Set("Origin", "https://dapp.example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal("https://dapp.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	req = httptest.NewRequest("OPTIONS", "/rpc", nil)
+	req.Header.Set("Origin", "https://dapp.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Contains(w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+
+	// Other origins get no CORS header
+	req = httptest.NewRequest("POST", "/rpc", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal("", w.Header().Get("Access-Control-Allow-Origin"))
+
+	h = newCORSHandler(ok, []string{"*"})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal("*", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestMaxBodySizeHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	h := maxBodySizeHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		buf := new(bytes.Buffer)
+		if _, err := buf.ReadFrom(req.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}), 16)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/rpc", strings.NewReader("small")))
+	assert.Equal(http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/rpc", strings.NewReader(strings.Repeat("x", 17))))
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+
+	// A body of unknown length fails to read beyond the limit
+	req := httptest.NewRequest("POST", "/rpc", strings.NewReader(strings.Repeat("x", 17)))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestRequestTimeoutHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	w := httptest.NewRecorder()
+	requestTimeoutHandler(slow, 10*time.Millisecond).ServeHTTP(w, httptest.NewRequest("POST", "/rpc", nil))
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+	assert.Contains(w.Body.String(), "timed out")
+}
+
+func TestProxyHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	remoteAddr := ""
+	h, err := newProxyHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		remoteAddr = req.RemoteAddr
+	}), []string{"127.0.0.1", "10.0.0.0/8"})
+	assert.Nil(err)
+
+	serve := func(remote string, forwardedFor string) string {
+		req := httptest.NewRequest("POST", "/rpc", nil)
+		req.RemoteAddr = remote
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		return remoteAddr
+	}
+
+	// The last address not of a trusted proxy is the client
+	assert.Equal("1.2.3.4:0", serve("127.0.0.1:5000", "1.2.3.4"))
+	assert.Equal("1.2.3.4:0", serve("127.0.0.1:5000", "9.9.9.9, 1.2.3.4, 10.1.2.3"))
+
+	// The header from an untrusted client is ignored
+	assert.Equal("5.6.7.8:0", serve("5.6.7.8:5000", "127.0.0.1"))
+	assert.False(isLoopback(serve("5.6.7.8:5000", "127.0.0.1")))
+
+	_, err = newProxyHandler(http.NotFoundHandler(), []string{"not-an-ip"})
+	assert.NotNil(err)
+}
@@ -1,24 +1,28 @@
 package rpc
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
 	"time"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/hexutil"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/trie"
 )
 
 // ------------------------------- GetAccount -----------------------------------
 
 type GetAccountArgs struct {
-	Name    string `json:"name"`
-	Address string `json:"address"`
-	Preview bool   `json:"preview"` // preview the account balance from the ScreenedView
+	Name    string            `json:"name"`
+	Address string            `json:"address"`
+	Preview bool              `json:"preview"` // preview the account balance from the ScreenedView
+	Height  common.JSONUint64 `json:"height"`  // the account as of the finalized block at the height, 0 for the last finalized block
 }
 
 type GetAccountResult struct {
@@ -33,6 +37,14 @@ func (t *ThetaRPCService) GetAccount(args *GetAccountArgs, result *GetAccountRes
 	address := common.HexToAddress(args.Address)
 	result.Address = args.Address
 
+	if args.Height != 0 {
+		if args.Preview {
+			return errors.New("Preview and height cannot be both specified")
+		}
+		result.Account, err = t.ledger.GetAccount(address, uint64(args.Height))
+		return err
+	}
+
 	var ledgerState *state.StoreView
 	if args.Preview {
 		ledgerState, err = t.ledger.GetScreenedSnapshot()
@@ -50,6 +62,218 @@ func (t *ThetaRPCService) GetAccount(args *GetAccountArgs, result *GetAccountRes
 	return nil
 }
 
+// ------------------------------- GetStake -----------------------------------
+
+type GetStakeArgs struct {
+	Address string            `json:"address"`
+	Height  common.JSONUint64 `json:"height"` // 0 for the last finalized block
+}
+
+type DepositedStake struct {
+	Holder       common.Address    `json:"holder"`
+	Purpose      uint8             `json:"purpose"`
+	Amount       *common.JSONBig   `json:"amount"`
+	Withdrawn    bool              `json:"withdrawn"`
+	ReturnHeight common.JSONUint64 `json:"return_height"`
+}
+
+type GetStakeResult struct {
+	Address string           `json:"address"`
+	Stakes  []DepositedStake `json:"stakes"`
+}
+
+func (t *ThetaRPCService) GetStake(args *GetStakeArgs, result *GetStakeResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	stakes, err := t.ledger.GetStake(common.HexToAddress(args.Address), uint64(args.Height))
+	if err != nil {
+		return err
+	}
+	result.Address = args.Address
+	result.Stakes = []DepositedStake{}
+	for _, stake := range stakes {
+		result.Stakes = append(result.Stakes, DepositedStake{
+			Holder:       stake.Holder,
+			Purpose:      stake.Purpose,
+			Amount:       (*common.JSONBig)(stake.Amount),
+			Withdrawn:    stake.Withdrawn,
+			ReturnHeight: common.JSONUint64(stake.ReturnHeight),
+		})
+	}
+	return nil
+}
+
+// ------------------------------- GetDelegations -----------------------------------
+
+type GetDelegationsArgs struct {
+	Address string            `json:"address"`
+	Height  common.JSONUint64 `json:"height"` // 0 for the last finalized block
+}
+
+type Delegation struct {
+	Validator    common.Address    `json:"validator"`
+	Amount       *common.JSONBig   `json:"amount"`
+	Undelegated  bool              `json:"undelegated"`
+	ReturnHeight common.JSONUint64 `json:"return_height"`
+}
+
+type GetDelegationsResult struct {
+	Address     string       `json:"address"`
+	Delegations []Delegation `json:"delegations"`
+}
+
+func (t *ThetaRPCService) GetDelegations(args *GetDelegationsArgs, result *GetDelegationsResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	delegations, err := t.ledger.GetDelegations(common.HexToAddress(args.Address), uint64(args.Height))
+	if err != nil {
+		return err
+	}
+	result.Address = args.Address
+	result.Delegations = []Delegation{}
+	for _, delegation := range delegations {
+		result.Delegations = append(result.Delegations, Delegation{
+			Validator:    delegation.Validator,
+			Amount:       (*common.JSONBig)(delegation.Amount),
+			Undelegated:  delegation.Withdrawn,
+			ReturnHeight: common.JSONUint64(delegation.ReturnHeight),
+		})
+	}
+	return nil
+}
+
+// ------------------------------- GetPendingUnbondings -----------------------------------
+
+type GetPendingUnbondingsArgs struct {
+	Address string            `json:"address"`
+	Height  common.JSONUint64 `json:"height"` // 0 for the last finalized block
+}
+
+type PendingUnbonding struct {
+	Source       common.Address    `json:"source"`
+	Holder       common.Address    `json:"holder"`
+	Purpose      uint8             `json:"purpose"`
+	Delegated    bool              `json:"delegated"`
+	Amount       *common.JSONBig   `json:"amount"`
+	StartHeight  common.JSONUint64 `json:"start_height"`
+	ReturnHeight common.JSONUint64 `json:"return_height"`
+}
+
+type GetPendingUnbondingsResult struct {
+	Address    string             `json:"address"`
+	Unbondings []PendingUnbonding `json:"unbondings"`
+}
+
+// GetPendingUnbondings returns the stakes waiting in the unbonding queue that were withdrawn or
+// undelegated by the address, or that were withdrawn or undelegated from the address as holder.
+func (t *ThetaRPCService) GetPendingUnbondings(args *GetPendingUnbondingsArgs, result *GetPendingUnbondingsResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	entries, err := t.ledger.GetUnbondings(common.HexToAddress(args.Address), uint64(args.Height))
+	if err != nil {
+		return err
+	}
+	result.Address = args.Address
+	result.Unbondings = []PendingUnbonding{}
+	for _, entry := range entries {
+		result.Unbondings = append(result.Unbondings, PendingUnbonding{
+			Source:       entry.Source,
+			Holder:       entry.Holder,
+			Purpose:      entry.Purpose,
+			Delegated:    entry.Delegated,
+			Amount:       (*common.JSONBig)(entry.Amount),
+			StartHeight:  common.JSONUint64(entry.StartHeight),
+			ReturnHeight: common.JSONUint64(entry.ReturnHeight),
+		})
+	}
+	return nil
+}
+
+// ------------------------------- GetValidatorSet -----------------------------------
+
+type GetValidatorSetArgs struct {
+	Height common.JSONUint64 `json:"height"` // 0 for the last finalized block
+}
+
+type Validator struct {
+	Address common.Address  `json:"address"`
+	Stake   *common.JSONBig `json:"stake"`
+}
+
+type GetValidatorSetResult struct {
+	Validators []Validator `json:"validators"`
+}
+
+func (t *ThetaRPCService) GetValidatorSet(args *GetValidatorSetArgs, result *GetValidatorSetResult) (err error) {
+	valSet, err := t.ledger.GetValidatorSet(uint64(args.Height))
+	if err != nil {
+		return err
+	}
+	result.Validators = []Validator{}
+	for _, v := range valSet.Validators() {
+		result.Validators = append(result.Validators, Validator{
+			Address: v.Address,
+			Stake:   (*common.JSONBig)(v.Stake),
+		})
+	}
+	return nil
+}
+
+// ------------------------------- GetSequence -----------------------------------
+
+type SequenceMode string
+
+const (
+	SequenceModeCommitted SequenceMode = "committed" // only consider finalized transactions
+	SequenceModePending   SequenceMode = "pending"   // also consider transactions pending in the mempool
+)
+
+type GetSequenceArgs struct {
+	Address string       `json:"address"`
+	Mode    SequenceMode `json:"mode"`
+}
+
+type GetSequenceResult struct {
+	Address      string            `json:"address"`
+	Sequence     common.JSONUint64 `json:"sequence"`
+	NextSequence common.JSONUint64 `json:"next_sequence"` // sequence to use for the next transaction
+}
+
+func (t *ThetaRPCService) GetSequence(args *GetSequenceArgs, result *GetSequenceResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	mode := args.Mode
+	if mode == "" {
+		mode = SequenceModeCommitted
+	}
+	if mode != SequenceModeCommitted && mode != SequenceModePending {
+		return fmt.Errorf("Invalid sequence mode: %v", mode)
+	}
+	address := common.HexToAddress(args.Address)
+	result.Address = args.Address
+
+	ledgerState, err := t.ledger.GetFinalizedSnapshot()
+	if err != nil {
+		return err
+	}
+	var sequence uint64
+	if account := ledgerState.GetAccount(address); account != nil {
+		sequence = account.Sequence
+	}
+	if mode == SequenceModePending {
+		if pendingSequence, ok := t.mempool.GetPendingSequence(address); ok && pendingSequence > sequence {
+			sequence = pendingSequence
+		}
+	}
+	result.Sequence = common.JSONUint64(sequence)
+	result.NextSequence = common.JSONUint64(sequence + 1)
+	return nil
+}
+
 // ------------------------------- GetSplitRule -----------------------------------
 
 type GetSplitRuleArgs struct {
@@ -73,6 +297,144 @@ func (t *ThetaRPCService) GetSplitRule(args *GetSplitRuleArgs, result *GetSplitR
 	return nil
 }
 
+// ------------------------------- GetChainParams -----------------------------------
+
+type GetChainParamsArgs struct{}
+
+type GetChainParamsResult struct {
+	*types.ChainParams
+}
+
+func (t *ThetaRPCService) GetChainParams(args *GetChainParamsArgs, result *GetChainParamsResult) (err error) {
+	ledgerState, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+	result.ChainParams = ledgerState.GetChainParams()
+	return nil
+}
+
+// ------------------------------- GetBaseFee -----------------------------------
+
+type GetBaseFeeArgs struct{}
+
+type GetBaseFeeResult struct {
+	BaseFee               *common.JSONBig `json:"base_fee"`
+	MinimumGasPrice       *common.JSONBig `json:"minimum_gas_price"`
+	MinimumTransactionFee *common.JSONBig `json:"minimum_transaction_fee_tfuel_wei"`
+}
+
+// GetBaseFee returns the base fee of the next block, and the resulting fee minimums that the
+// transactions submitted now need to meet.
+func (t *ThetaRPCService) GetBaseFee(args *GetBaseFeeArgs, result *GetBaseFeeResult) (err error) {
+	ledgerState, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+	params := ledgerState.GetChainParams()
+	baseFee := ledgerState.GetBaseFee()
+	result.BaseFee = (*common.JSONBig)(baseFee)
+	result.MinimumGasPrice = (*common.JSONBig)(types.MinimumGasPriceWithBaseFee(params, baseFee))
+	result.MinimumTransactionFee = (*common.JSONBig)(types.MinimumTransactionFeeWithBaseFee(params, baseFee))
+	return nil
+}
+
+// ------------------------------- GetAccountProof -----------------------------------
+
+type GetAccountProofArgs struct {
+	Address string            `json:"address"`
+	Height  common.JSONUint64 `json:"height"` // 0 for the last finalized block
+}
+
+type GetAccountProofResult struct {
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	StateRoot   common.Hash       `json:"state_root"`
+	Address     common.Address    `json:"address"`
+	Account     *types.Account    `json:"account"` // null if the proof proves the absence of the account
+	Proof       []hexutil.Bytes   `json:"proof"`
+}
+
+func (t *ThetaRPCService) GetAccountProof(args *GetAccountProofArgs, result *GetAccountProofResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	proof, err := t.ledger.GetAccountProof(common.HexToAddress(args.Address), uint64(args.Height))
+	if err != nil {
+		return err
+	}
+	result.setAccountProof(proof)
+	return nil
+}
+
+func (result *GetAccountProofResult) setAccountProof(proof *state.AccountProof) {
+	result.BlockHash = proof.BlockHash
+	result.BlockHeight = common.JSONUint64(proof.BlockHeight)
+	result.StateRoot = proof.StateRoot
+	result.Address = proof.Address
+	result.Account = proof.Account
+	result.Proof = toHexNodes(proof.Proof)
+}
+
+// ------------------------------- GetStorageProof -----------------------------------
+
+type GetStorageProofArgs struct {
+	Address string            `json:"address"`
+	Key     string            `json:"key"`
+	Height  common.JSONUint64 `json:"height"` // 0 for the last finalized block
+}
+
+type GetStorageProofResult struct {
+	GetAccountProofResult
+	Key          common.Hash     `json:"key"`
+	Value        common.Hash     `json:"value"`
+	StorageProof []hexutil.Bytes `json:"storage_proof"`
+}
+
+func (t *ThetaRPCService) GetStorageProof(args *GetStorageProofArgs, result *GetStorageProofResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	if args.Key == "" {
+		return errors.New("Key must be specified")
+	}
+	proof, err := t.ledger.GetStorageProof(common.HexToAddress(args.Address), common.HexToHash(args.Key), uint64(args.Height))
+	if err != nil {
+		return err
+	}
+	result.setAccountProof(&proof.AccountProof)
+	result.Key = proof.Key
+	result.Value = proof.Value
+	result.StorageProof = toHexNodes(proof.StorageProof)
+	return nil
+}
+
+// ------------------------------- GetGuardianInfo -----------------------------------
+
+type GetGuardianInfoArgs struct{}
+
+type GetGuardianInfoResult struct {
+	Holder                  common.Address `json:"holder"`
+	BlsPubkey               string         `json:"bls_pubkey"`
+	BlsPop                  string         `json:"bls_pop"`
+	Summary                 string         `json:"summary"` // pass to "thetacli tx deposit --guardian_key" to register the guardian
+	LastFinalizedCheckpoint common.Hash    `json:"last_finalized_checkpoint"`
+}
+
+func (t *ThetaRPCService) GetGuardianInfo(args *GetGuardianInfoArgs, result *GetGuardianInfoResult) (err error) {
+	guardian := t.consensus.GetGuardianEngine()
+	info, err := guardian.GetKeyInfo()
+	if err != nil {
+		return err
+	}
+	result.Holder = info.Holder
+	result.BlsPubkey = hex.EncodeToString(info.BlsPubkey)
+	result.BlsPop = hex.EncodeToString(info.BlsPop)
+	result.Summary = hex.EncodeToString(info.Summary())
+	result.LastFinalizedCheckpoint = guardian.GetLastFinalizedCheckpoint()
+	return nil
+}
+
 // ------------------------------ GetTransaction -----------------------------------
 
 type GetTransactionArgs struct {
@@ -86,6 +448,7 @@ type GetTransactionResult struct {
 	TxHash      common.Hash       `json:"hash"`
 	Type        byte              `json:"type"`
 	Tx          types.Tx          `json:"transaction"`
+	Receipt     *types.Receipt    `json:"receipt"`
 }
 
 type TxStatus string
@@ -123,6 +486,13 @@ func (t *ThetaRPCService) GetTransaction(args *GetTransactionArgs, result *GetTr
 	result.Tx = tx
 	result.Type = getTxType(tx)
 
+	switch tx.(type) {
+	case *types.SmartContractTx, *types.SponsoredTx: // a sponsored smart contract call has a receipt too
+		if receipt, found := t.chain.FindTxReceipt(block.Hash(), hash); found {
+			result.Receipt = receipt
+		}
+	}
+
 	return nil
 }
 
@@ -151,6 +521,10 @@ type GetBlockResultInner struct {
 	StateHash common.Hash       `json:"state_hash"`
 	Timestamp *common.JSONBig   `json:"timestamp"`
 	Proposer  common.Address    `json:"proposer"`
+	BaseFee   *common.JSONBig   `json:"base_fee"`
+
+	NextValidatorSetHash common.Hash  `json:"next_validator_set_hash"` // empty unless the block updates the validator set
+	VRFProof             common.Bytes `json:"vrf_proof"`               // empty before the VRF proposer selection
 
 	Children []common.Hash    `json:"children"`
 	Status   core.BlockStatus `json:"status"`
@@ -172,6 +546,20 @@ const (
 	TxTypeSmartContract
 	TxTypeDepositStake
 	TxTypeWithdrawStake
+	TxTypeGovernance
+	TxTypeDepositStakeV2
+	TxTypeGovernanceProposal
+	TxTypeGovernanceVote
+	TxTypeGovernanceTally
+	TxTypeBridgeLock
+	TxTypeBridgeMint
+	TxTypeBridgeValidators
+	TxTypeSponsored
+	TxTypeTimeLock
+	TxTypeTimeLockClaim
+	TxTypeTimeLockRevoke
+	TxTypeDelegate
+	TxTypeUndelegate
 )
 
 func (t *ThetaRPCService) GetBlock(args *GetBlockArgs, result *GetBlockResult) (err error) {
@@ -192,6 +580,9 @@ func (t *ThetaRPCService) GetBlock(args *GetBlockArgs, result *GetBlockResult) (
 	result.TxHash = block.TxHash
 	result.StateHash = block.StateHash
 	result.Timestamp = (*common.JSONBig)(block.Timestamp)
+	result.BaseFee = (*common.JSONBig)(block.BaseFee)
+	result.NextValidatorSetHash = block.NextValidatorSetHash
+	result.VRFProof = block.VRFProof
 	result.Proposer = block.Proposer
 	result.Children = block.Children
 	result.Status = block.Status
@@ -205,7 +596,7 @@ func (t *ThetaRPCService) GetBlock(args *GetBlockArgs, result *GetBlockResult) (
 		if err != nil {
 			return
 		}
-		hash := crypto.Keccak256Hash(txBytes)
+		hash := crypto.HashAtHeight(block.Height, txBytes)
 
 		t := getTxType(tx)
 		txw := Tx{
@@ -251,6 +642,9 @@ func (t *ThetaRPCService) GetBlockByHeight(args *GetBlockByHeightArgs, result *G
 	result.TxHash = block.TxHash
 	result.StateHash = block.StateHash
 	result.Timestamp = (*common.JSONBig)(block.Timestamp)
+	result.BaseFee = (*common.JSONBig)(block.BaseFee)
+	result.NextValidatorSetHash = block.NextValidatorSetHash
+	result.VRFProof = block.VRFProof
 	result.Proposer = block.Proposer
 	result.Children = block.Children
 	result.Status = block.Status
@@ -264,7 +658,7 @@ func (t *ThetaRPCService) GetBlockByHeight(args *GetBlockByHeightArgs, result *G
 		if err != nil {
 			return
 		}
-		hash := crypto.Keccak256Hash(txBytes)
+		hash := crypto.HashAtHeight(block.Height, txBytes)
 
 		t := getTxType(tx)
 		txw := Tx{
@@ -288,6 +682,8 @@ type GetStatusResult struct {
 	LatestFinalizedBlockEpoch  common.JSONUint64 `json:"latest_finalized_block_epoch"`
 	CurrentEpoch               common.JSONUint64 `json:"current_epoch"`
 	CurrentTime                *common.JSONBig   `json:"current_time"`
+	ChainID                    string            `json:"chain_id"`
+	SignatureDomain            string            `json:"signature_domain"` // to sign the transactions with in place of the chain ID
 }
 
 func (t *ThetaRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResult) (err error) {
@@ -295,7 +691,7 @@ func (t *ThetaRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResult
 	latestFinalizedHash := s.LastFinalizedBlock
 	if !latestFinalizedHash.IsEmpty() {
 		result.LatestFinalizedBlockHash = latestFinalizedHash
-		block, err := t.chain.FindBlock(latestFinalizedHash)
+		block, err := t.chain.FindBlockHeader(latestFinalizedHash)
 		if err != nil {
 			return err
 		}
@@ -305,6 +701,8 @@ func (t *ThetaRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResult
 	}
 	result.CurrentEpoch = common.JSONUint64(s.Epoch)
 	result.CurrentTime = (*common.JSONBig)(big.NewInt(time.Now().Unix()))
+	result.ChainID = t.chain.ChainID
+	result.SignatureDomain = core.SignatureDomain(t.chain.ChainID, uint64(result.LatestFinalizedBlockHeight)+1)
 	return
 }
 
@@ -333,7 +731,7 @@ func (t *ThetaRPCService) GetVcpByHeight(args *GetVcpByHeightArgs, result *GetVc
 	height := uint64(args.Height)
 
 	blockHashVcpPairs := []BlockHashVcpPair{}
-	blocks := t.chain.FindBlocksByHeight(height)
+	blocks := t.chain.FindBlockHeadersByHeight(height)
 	for _, b := range blocks {
 		blockHash := b.Hash()
 		stateRoot := b.StateHash
@@ -353,6 +751,14 @@ func (t *ThetaRPCService) GetVcpByHeight(args *GetVcpByHeightArgs, result *GetVc
 
 // ------------------------------ Utils ------------------------------
 
+func toHexNodes(proof trie.ProofList) []hexutil.Bytes {
+	nodes := make([]hexutil.Bytes, len(proof))
+	for i, node := range proof {
+		nodes[i] = hexutil.Bytes(node)
+	}
+	return nodes
+}
+
 func getTxType(tx types.Tx) byte {
 	t := byte(0x0)
 	switch tx.(type) {
@@ -376,6 +782,34 @@ func getTxType(tx types.Tx) byte {
 		t = TxTypeDepositStake
 	case *types.WithdrawStakeTx:
 		t = TxTypeWithdrawStake
+	case *types.GovernanceTx:
+		t = TxTypeGovernance
+	case *types.DepositStakeTxV2:
+		t = TxTypeDepositStakeV2
+	case *types.GovernanceProposalTx:
+		t = TxTypeGovernanceProposal
+	case *types.GovernanceVoteTx:
+		t = TxTypeGovernanceVote
+	case *types.GovernanceTallyTx:
+		t = TxTypeGovernanceTally
+	case *types.BridgeLockTx:
+		t = TxTypeBridgeLock
+	case *types.BridgeMintTx:
+		t = TxTypeBridgeMint
+	case *types.BridgeValidatorsTx:
+		t = TxTypeBridgeValidators
+	case *types.SponsoredTx:
+		t = TxTypeSponsored
+	case *types.TimeLockTx:
+		t = TxTypeTimeLock
+	case *types.TimeLockClaimTx:
+		t = TxTypeTimeLockClaim
+	case *types.TimeLockRevokeTx:
+		t = TxTypeTimeLockRevoke
+	case *types.DelegateTx:
+		t = TxTypeDelegate
+	case *types.UndelegateTx:
+		t = TxTypeUndelegate
 	}
 
 	return t
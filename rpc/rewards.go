@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// ------------------------------- GetBlockRewards -----------------------------------
+
+type GetBlockRewardsArgs struct {
+	Height common.JSONUint64 `json:"height"` // 0 for the last finalized block
+}
+
+type BlockReward struct {
+	Address common.Address  `json:"address"`
+	Holder  common.Address  `json:"holder"` // the validator or the guardian staked to
+	Role    string          `json:"role"`   // proposer, validator, guardian or delegator
+	Amount  *common.JSONBig `json:"amount"`
+}
+
+type GetBlockRewardsResult struct {
+	BlockHash   common.Hash       `json:"block_hash"`
+	Height      common.JSONUint64 `json:"height"`
+	Proposer    common.Address    `json:"proposer"`
+	BlockReward *common.JSONBig   `json:"block_reward"`
+	Fees        *common.JSONBig   `json:"fees"`
+	Rewards     []BlockReward     `json:"rewards"`
+}
+
+// GetBlockRewards returns the rewards paid by the finalized block at the given height, from the
+// reward distribution upgrade on.
+func (t *ThetaRPCService) GetBlockRewards(args *GetBlockRewardsArgs, result *GetBlockRewardsResult) (err error) {
+	block, rewards, err := t.ledger.GetBlockRewards(uint64(args.Height))
+	if err != nil {
+		return err
+	}
+
+	result.BlockHash = block.Hash()
+	result.Height = common.JSONUint64(block.Height)
+	result.Proposer = rewards.Proposer
+	result.BlockReward = (*common.JSONBig)(rewards.BlockReward)
+	result.Fees = (*common.JSONBig)(rewards.Fees)
+	result.Rewards = []BlockReward{}
+	for _, reward := range rewards.Rewards {
+		result.Rewards = append(result.Rewards, BlockReward{
+			Address: reward.Address,
+			Holder:  reward.Holder,
+			Role:    getRewardRole(reward.Role),
+			Amount:  (*common.JSONBig)(reward.Amount),
+		})
+	}
+	return nil
+}
+
+func getRewardRole(role uint8) string {
+	switch role {
+	case types.RewardToProposer:
+		return "proposer"
+	case types.RewardToValidator:
+		return "validator"
+	case types.RewardToGuardian:
+		return "guardian"
+	case types.RewardToDelegator:
+		return "delegator"
+	default:
+		return "unknown"
+	}
+}
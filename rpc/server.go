@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"time"
 
 	"net/rpc"
 
@@ -19,6 +20,9 @@ import (
 	"github.com/thetatoken/theta/ledger"
 	"github.com/thetatoken/theta/mempool"
 	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+	"github.com/thetatoken/theta/stats"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"golang.org/x/net/netutil"
 	"golang.org/x/net/websocket"
 )
@@ -30,6 +34,9 @@ type ThetaRPCService struct {
 	ledger    *ledger.Ledger
 	chain     *blockchain.Chain
 	consensus *consensus.ConsensusEngine
+	stats     *stats.Collector
+
+	servedSnapshot *servedSnapshot
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -42,17 +49,21 @@ type ThetaRPCService struct {
 type ThetaRPCServer struct {
 	*ThetaRPCService
 
-	server   *http.Server
-	handler  *rpc.Server
-	router   *mux.Router
-	listener net.Listener
+	server     *http.Server
+	handler    *rpc.Server
+	router     *mux.Router
+	batchLimit *batchLimitHandler
+	auth       *rpcAuth
+	listener   net.Listener
 }
 
 // NewThetaRPCServer creates a new instance of ThetaRPCServer.
-func NewThetaRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, chain *blockchain.Chain, consensus *consensus.ConsensusEngine) *ThetaRPCServer {
+func NewThetaRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, chain *blockchain.Chain,
+	consensus *consensus.ConsensusEngine, stats *stats.Collector) *ThetaRPCServer {
 	t := &ThetaRPCServer{
 		ThetaRPCService: &ThetaRPCService{
-			wg: &sync.WaitGroup{},
+			servedSnapshot: &servedSnapshot{},
+			wg:             &sync.WaitGroup{},
 		},
 	}
 
@@ -60,20 +71,58 @@ func NewThetaRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, chain *b
 	t.ledger = ledger
 	t.chain = chain
 	t.consensus = consensus
+	t.stats = stats
 
 	s := rpc.NewServer()
 	s.RegisterName("theta", t.ThetaRPCService)
 
 	t.handler = s
 
+	t.auth = newRPCAuth(viper.GetStringSlice(common.CfgRPCAuthTokens), viper.GetStringSlice(common.CfgRPCAdminTokens),
+		viper.GetStringSlice(common.CfgRPCAdminMethods), viper.GetBool(common.CfgRPCRequireAuth),
+		viper.GetBool(common.CfgRPCAdminFromLocalhost))
+
 	t.router = mux.NewRouter()
-	t.router.Handle("/rpc", jsonrpc2.HTTPHandler(s))
+	t.batchLimit = newBatchLimitHandler(jsonrpc2.HTTPHandler(s), viper.GetInt(common.CfgRPCMaxBatchSize))
+	maxBodySize := viper.GetInt64(common.CfgRPCMaxRequestBodySize)
+	requestTimeout := time.Duration(viper.GetInt(common.CfgRPCRequestTimeout)) * time.Second
+	t.router.Handle("/rpc", requestTimeoutHandler(maxBodySizeHandler(t.auth.handler(t.batchLimit), maxBodySize), requestTimeout))
 	t.router.Handle("/ws", websocket.Handler(func(ws *websocket.Conn) {
-		s.ServeCodec(jsonrpc2.NewServerCodec(ws, s))
+		// The role of a websocket connection is determined at the handshake
+		role := t.auth.role(ws.Request())
+		s.ServeCodec(&aclServerCodec{
+			ServerCodec: jsonrpc2.NewServerCodec(ws, s),
+			auth:        t.auth,
+			role:        role,
+		})
 	}))
 
+	// The client addresses are resolved before the authentication, which trusts the local clients
+	handler, err := newProxyHandler(newCORSHandler(t.router, viper.GetStringSlice(common.CfgRPCCORSAllowedOrigins)),
+		viper.GetStringSlice(common.CfgRPCTrustedProxies))
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("Failed to configure the RPC trusted proxies")
+	}
+
+	// Clients can reuse connections, either with HTTP/1.1 keep-alive or HTTP/2. Without TLS,
+	// HTTP/2 is served over cleartext (h2c).
+	idleTimeout := time.Duration(viper.GetInt(common.CfgRPCIdleTimeout)) * time.Second
+	h2s := &http2.Server{
+		IdleTimeout: idleTimeout,
+	}
 	t.server = &http.Server{
-		Handler: t.router,
+		Handler:     h2c.NewHandler(handler, h2s),
+		IdleTimeout: idleTimeout,
+	}
+	if caFile := viper.GetString(common.CfgRPCTLSClientCAFile); caFile != "" {
+		clientCAs, err := loadClientCAs(caFile)
+		if err != nil {
+			log.WithFields(log.Fields{"error": err}).Fatal("Failed to load the RPC client CA certificates")
+		}
+		t.server.TLSConfig = clientAuthTLSConfig(clientCAs)
+	}
+	if err := http2.ConfigureServer(t.server, h2s); err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("Failed to configure HTTP/2")
 	}
 
 	logger = util.GetLoggerForModule("rpc")
@@ -117,7 +166,23 @@ func (t *ThetaRPCServer) serve() {
 	ll := netutil.LimitListener(l, viper.GetInt(common.CfgRPCMaxConnections))
 	t.listener = ll
 
-	logger.Fatal(t.server.Serve(ll))
+	certFile := viper.GetString(common.CfgRPCTLSCertFile)
+	keyFile := viper.GetString(common.CfgRPCTLSKeyFile)
+	if certFile != "" {
+		err = t.server.ServeTLS(ll, certFile, keyFile)
+	} else {
+		err = t.server.Serve(ll)
+	}
+	// The server can be stopped at runtime when the RPC service is disabled by a config reload.
+	if err != http.ErrServerClosed {
+		logger.Fatal(err)
+	}
+	logger.Info("RPC server stopped")
+}
+
+// SetMaxBatchSize changes the max number of calls in a JSON-RPC batch request.
+func (t *ThetaRPCServer) SetMaxBatchSize(maxBatchSize int) {
+	t.batchLimit.SetMaxBatchSize(maxBatchSize)
 }
 
 // Stop notifies all goroutines to stop without blocking.
@@ -1,9 +1,14 @@
 package rpc
 
 import (
+	"errors"
 	"os"
 	"path"
+	"sync"
+	"time"
 
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/snapshot"
 )
 
@@ -30,3 +35,85 @@ func (t *ThetaRPCService) GenSnapshot(args *GenSnapshotArgs, result *GenSnapshot
 
 	return err
 }
+
+// ------------------------------- GetSnapshotManifest -----------------------------------
+
+type GetSnapshotManifestArgs struct {
+}
+
+type GetSnapshotManifestResult struct {
+	*snapshot.Manifest
+}
+
+func (t *ThetaRPCService) GetSnapshotManifest(args *GetSnapshotManifestArgs, result *GetSnapshotManifestResult) error {
+	manifest, _, err := t.getServedSnapshot()
+	if err != nil {
+		return err
+	}
+	result.Manifest = manifest
+	return nil
+}
+
+// ------------------------------- GetSnapshotChunk -----------------------------------
+
+type GetSnapshotChunkArgs struct {
+	Index common.JSONUint64 `json:"index"`
+}
+
+type GetSnapshotChunkResult struct {
+	Chunk common.Bytes `json:"chunk"`
+}
+
+func (t *ThetaRPCService) GetSnapshotChunk(args *GetSnapshotChunkArgs, result *GetSnapshotChunkResult) error {
+	manifest, filePath, err := t.getServedSnapshot()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	chunk, err := snapshot.ReadChunk(file, manifest, uint64(args.Index))
+	if err != nil {
+		return err
+	}
+	result.Chunk = chunk
+	return nil
+}
+
+// servedSnapshot caches the manifest of the snapshot served to peers bootstrapping from this
+// node. The chunks are read from the snapshot file on demand.
+type servedSnapshot struct {
+	mu       sync.Mutex
+	filePath string
+	modTime  time.Time
+	manifest *snapshot.Manifest
+}
+
+func (t *ThetaRPCService) getServedSnapshot() (*snapshot.Manifest, string, error) {
+	filePath := viper.GetString(common.CfgSnapshotServePath)
+	if filePath == "" {
+		return nil, "", errors.New("Snapshot serving is disabled")
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s := t.servedSnapshot
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.manifest == nil || s.filePath != filePath || !s.modTime.Equal(info.ModTime()) {
+		manifest, err := snapshot.BuildManifest(filePath, snapshot.DefaultChunkSize)
+		if err != nil {
+			return nil, "", err
+		}
+		s.filePath = filePath
+		s.modTime = info.ModTime()
+		s.manifest = manifest
+	}
+	return s.manifest, s.filePath, nil
+}
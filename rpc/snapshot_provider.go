@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/snapshot"
+	"github.com/ybbus/jsonrpc"
+)
+
+var _ snapshot.Provider = (*SnapshotProvider)(nil)
+
+// SnapshotProvider downloads snapshot from a remote node through its RPC endpoint.
+type SnapshotProvider struct {
+	url    string
+	client *jsonrpc.RPCClient
+}
+
+// NewSnapshotProvider creates an instance of SnapshotProvider.
+func NewSnapshotProvider(url string) *SnapshotProvider {
+	return &SnapshotProvider{
+		url:    url,
+		client: jsonrpc.NewRPCClient(url),
+	}
+}
+
+// ID implements the snapshot.Provider interface.
+func (p *SnapshotProvider) ID() string {
+	return p.url
+}
+
+// GetManifest implements the snapshot.Provider interface.
+func (p *SnapshotProvider) GetManifest() (*snapshot.Manifest, error) {
+	res, err := p.client.Call("theta.GetSnapshotManifest", GetSnapshotManifestArgs{})
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	result := &GetSnapshotManifestResult{}
+	if err := res.GetObject(result); err != nil {
+		return nil, err
+	}
+	if result.Manifest == nil {
+		return nil, errors.New("Empty snapshot manifest")
+	}
+	return result.Manifest, nil
+}
+
+// GetChunk implements the snapshot.Provider interface.
+func (p *SnapshotProvider) GetChunk(index uint64) (common.Bytes, error) {
+	res, err := p.client.Call("theta.GetSnapshotChunk", GetSnapshotChunkArgs{Index: common.JSONUint64(index)})
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	result := &GetSnapshotChunkResult{}
+	if err := res.GetObject(result); err != nil {
+		return nil, err
+	}
+	return result.Chunk, nil
+}
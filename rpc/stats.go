@@ -0,0 +1,37 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/stats"
+)
+
+// ------------------------------- GetChainStats -----------------------------------
+
+type GetChainStatsArgs struct {
+	Window common.JSONUint64 `json:"window"` // Number of latest finalized blocks, defaults to 100
+	Days   common.JSONUint64 `json:"days"`   // Number of days of active addresses, defaults to 7
+}
+
+type GetChainStatsResult struct {
+	*stats.Summary
+	DailyActiveAddresses []stats.DailyActiveCount `json:"daily_active_addresses"`
+}
+
+func (t *ThetaRPCService) GetChainStats(args *GetChainStatsArgs, result *GetChainStatsResult) (err error) {
+	if t.stats == nil {
+		return errors.New("Chain statistics are not enabled")
+	}
+	window := uint64(args.Window)
+	if window == 0 {
+		window = 100
+	}
+	days := int(args.Days)
+	if days == 0 {
+		days = 7
+	}
+	result.Summary = t.stats.GetSummary(window)
+	result.DailyActiveAddresses = t.stats.GetDailyActiveAddresses(days)
+	return nil
+}
@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+)
+
+// ------------------------------- GetTFuelSupply -----------------------------------
+
+type GetTFuelSupplyArgs struct {
+	Height common.JSONUint64 `json:"height"` // 0 for the last finalized block
+}
+
+type GetTFuelSupplyResult struct {
+	BlockHash         common.Hash       `json:"block_hash"`
+	Height            common.JSONUint64 `json:"height"`
+	TotalSupply       *common.JSONBig   `json:"total_supply"`
+	CirculatingSupply *common.JSONBig   `json:"circulating_supply"` // the total supply minus the coins held by the escrows
+	EscrowedSupply    *common.JSONBig   `json:"escrowed_supply"`
+	Minted            *common.JSONBig   `json:"minted"` // since the supply accounting upgrade
+	Burned            *common.JSONBig   `json:"burned"` // since the supply accounting upgrade
+	BlockMinted       *common.JSONBig   `json:"block_minted"`
+	BlockBurned       *common.JSONBig   `json:"block_burned"`
+}
+
+// GetTFuelSupply returns the TFuel supply after the finalized block at the given height, and the
+// TFuel minted and burned by the block. The supply is accounted from the supply accounting
+// upgrade on.
+func (t *ThetaRPCService) GetTFuelSupply(args *GetTFuelSupplyArgs, result *GetTFuelSupplyResult) (err error) {
+	block, supply, escrowed, err := t.ledger.GetTFuelSupply(uint64(args.Height))
+	if err != nil {
+		return err
+	}
+
+	result.BlockHash = block.Hash()
+	result.Height = common.JSONUint64(block.Height)
+	result.TotalSupply = (*common.JSONBig)(supply.Total)
+	result.CirculatingSupply = (*common.JSONBig)(supply.Circulating(escrowed))
+	result.EscrowedSupply = (*common.JSONBig)(escrowed)
+	result.Minted = (*common.JSONBig)(supply.Minted)
+	result.Burned = (*common.JSONBig)(supply.Burned)
+	result.BlockMinted = (*common.JSONBig)(supply.BlockMinted)
+	result.BlockBurned = (*common.JSONBig)(supply.BlockBurned)
+	return nil
+}
+
+// ------------------------------- GetTFuelInflation -----------------------------------
+
+type GetTFuelInflationArgs struct {
+	FromHeight common.JSONUint64 `json:"from_height"`
+	ToHeight   common.JSONUint64 `json:"to_height"` // 0 for the last finalized block
+}
+
+type GetTFuelInflationResult struct {
+	FromHeight    common.JSONUint64 `json:"from_height"`
+	ToHeight      common.JSONUint64 `json:"to_height"`
+	Minted        *common.JSONBig   `json:"minted"`
+	Burned        *common.JSONBig   `json:"burned"`
+	NetIssuance   *common.JSONBig   `json:"net_issuance"`   // minted minus burned, negative if more TFuel was burned
+	InflationRate float64           `json:"inflation_rate"` // net issuance relative to the total supply at the from height
+}
+
+// GetTFuelInflation returns the TFuel minted and burned by the finalized blocks after the from
+// height up to the to height. The states of both heights need to be available.
+func (t *ThetaRPCService) GetTFuelInflation(args *GetTFuelInflationArgs, result *GetTFuelInflationResult) (err error) {
+	toBlock, to, _, err := t.ledger.GetTFuelSupply(uint64(args.ToHeight))
+	if err != nil {
+		return err
+	}
+	if uint64(args.FromHeight) == 0 || uint64(args.FromHeight) >= toBlock.Height {
+		return fmt.Errorf("The from height %v needs to be positive and below the to height %v", uint64(args.FromHeight), toBlock.Height)
+	}
+	fromBlock, from, _, err := t.ledger.GetTFuelSupply(uint64(args.FromHeight))
+	if err != nil {
+		return err
+	}
+
+	minted := new(big.Int).Sub(to.Minted, from.Minted)
+	burned := new(big.Int).Sub(to.Burned, from.Burned)
+	netIssuance := new(big.Int).Sub(minted, burned)
+
+	result.FromHeight = common.JSONUint64(fromBlock.Height)
+	result.ToHeight = common.JSONUint64(toBlock.Height)
+	result.Minted = (*common.JSONBig)(minted)
+	result.Burned = (*common.JSONBig)(burned)
+	result.NetIssuance = (*common.JSONBig)(netIssuance)
+	if from.Total.Sign() > 0 {
+		result.InflationRate, _ = new(big.Float).Quo(new(big.Float).SetInt(netIssuance),
+			new(big.Float).SetInt(from.Total)).Float64()
+	}
+	return nil
+}
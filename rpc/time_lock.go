@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// ------------------------------- GetTimeLock -----------------------------------
+
+type GetTimeLockArgs struct {
+	ID common.JSONUint64 `json:"id"`
+}
+
+type GetTimeLockResult struct {
+	ID            common.JSONUint64 `json:"id"`
+	Source        common.Address    `json:"source"`
+	Recipient     common.Address    `json:"recipient"`
+	Revoker       common.Address    `json:"revoker"`
+	Coins         types.Coins       `json:"coins"`
+	LockHeight    common.JSONUint64 `json:"lock_height"`
+	ReleaseHeight common.JSONUint64 `json:"release_height"`
+	ReleaseTime   common.JSONUint64 `json:"release_time"`
+	Released      bool              `json:"released"` // whether the recipient can claim the coins in the next block
+}
+
+// GetTimeLock returns the time lock with the given ID. The claimed and revoked time locks are
+// deleted, so they are not found.
+func (t *ThetaRPCService) GetTimeLock(args *GetTimeLockArgs, result *GetTimeLockResult) (err error) {
+	ledgerState, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+	timeLock := ledgerState.GetTimeLock(uint64(args.ID))
+	if timeLock == nil {
+		return fmt.Errorf("Time lock %v not found", uint64(args.ID))
+	}
+
+	result.ID = common.JSONUint64(timeLock.ID)
+	result.Source = timeLock.Source
+	result.Recipient = timeLock.Recipient
+	result.Revoker = timeLock.Revoker
+	result.Coins = timeLock.Coins
+	result.LockHeight = common.JSONUint64(timeLock.LockHeight)
+	result.ReleaseHeight = common.JSONUint64(timeLock.ReleaseHeight)
+	result.ReleaseTime = common.JSONUint64(timeLock.ReleaseTime)
+	result.Released = timeLock.IsReleased(ledgerState.Height()+1, ledgerState.GetChainTime())
+	return nil
+}
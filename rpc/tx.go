@@ -85,7 +85,7 @@ func (t *ThetaRPCService) txCallback() {
 			return
 		case block := <-t.consensus.FinalizedBlocks():
 			for _, tx := range block.Txs {
-				txHash := crypto.Keccak256Hash(tx)
+				txHash := crypto.HashAtHeight(block.Height, tx)
 				cb, ok := txCallbackManager.RemoveCallback(txHash)
 				if ok {
 					cb.Callback(block)
@@ -97,6 +97,13 @@ func (t *ThetaRPCService) txCallback() {
 	}
 }
 
+// pendingTxHash returns the hash of a transaction yet to be included, assuming it gets into
+// the block following the last finalized block.
+func (t *ThetaRPCService) pendingTxHash(txBytes common.Bytes) common.Hash {
+	height := t.consensus.GetLastFinalizedBlock().Height + 1
+	return crypto.HashAtHeight(height, txBytes)
+}
+
 // ------------------------------- BroadcastRawTransaction -----------------------------------
 
 type BroadcastRawTransactionArgs struct {
@@ -115,14 +122,14 @@ func (t *ThetaRPCService) BroadcastRawTransaction(
 		return err
 	}
 
-	hash := crypto.Keccak256Hash(txBytes)
+	hash := t.pendingTxHash(txBytes)
 	result.TxHash = hash.Hex()
 
 	logger.Infof("[rpc] broadcast raw transaction: %v", hex.EncodeToString(txBytes))
 
 	err = t.mempool.InsertTransaction(txBytes)
 	if err != nil {
-		return err
+		return toRPCError(err)
 	}
 
 	finalized := make(chan *core.Block)
@@ -159,10 +166,10 @@ func (t *ThetaRPCService) BroadcastRawTransactionAsync(
 		return err
 	}
 
-	hash := crypto.Keccak256Hash(txBytes)
+	hash := t.pendingTxHash(txBytes)
 	result.TxHash = hash.Hex()
 
 	logger.Infof("[rpc] broadcast raw transaction: %v", hex.EncodeToString(txBytes))
 
-	return t.mempool.InsertTransaction(txBytes)
+	return toRPCError(t.mempool.InsertTransaction(txBytes))
 }
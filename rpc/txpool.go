@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/mempool"
+)
+
+// TxPoolTx is a transaction pending or queued in the mempool
+type TxPoolTx struct {
+	Tx
+	Sequence common.JSONUint64 `json:"sequence"`
+	Fee      *common.JSONBig   `json:"fee"`
+	Gas      common.JSONUint64 `json:"gas"`
+	Size     int               `json:"size"`
+}
+
+// ------------------------------- GetTxPoolContent -----------------------------------
+
+type GetTxPoolContentArgs struct {
+	Sender string `json:"sender"` // only the transactions of the sender if specified
+}
+
+type GetTxPoolContentResult struct {
+	Pending map[common.Address][]TxPoolTx `json:"pending"` // executable transactions, by sender
+	Queued  map[common.Address][]TxPoolTx `json:"queued"`  // future sequence transactions, by sender
+}
+
+// GetTxPoolContent lists the transactions in the mempool by sender, sorted by sequence. The
+// pending transactions can be included in the next block, while the queued ones wait for the
+// transactions filling their sequence gap.
+func (t *ThetaRPCService) GetTxPoolContent(args *GetTxPoolContentArgs, result *GetTxPoolContentResult) (err error) {
+	pending, queued := t.mempool.GetPoolTxs(txPoolSender(args.Sender))
+	height := t.consensus.GetLastFinalizedBlock().Height + 1
+	if result.Pending, err = toTxPoolTxs(height, pending); err != nil {
+		return err
+	}
+	if result.Queued, err = toTxPoolTxs(height, queued); err != nil {
+		return err
+	}
+	return nil
+}
+
+func toTxPoolTxs(height uint64, poolTxs map[common.Address][]mempool.PoolTx) (map[common.Address][]TxPoolTx, error) {
+	txs := make(map[common.Address][]TxPoolTx)
+	for sender, senderTxs := range poolTxs {
+		for _, poolTx := range senderTxs {
+			tx, err := types.TxFromBytes(poolTx.RawTx)
+			if err != nil {
+				return nil, err
+			}
+			txs[sender] = append(txs[sender], TxPoolTx{
+				Tx: Tx{
+					Tx:   tx,
+					Type: getTxType(tx),
+					Hash: crypto.HashAtHeight(height, poolTx.RawTx),
+				},
+				Sequence: common.JSONUint64(poolTx.TxInfo.Sequence),
+				Fee:      (*common.JSONBig)(poolTx.TxInfo.Fee),
+				Gas:      common.JSONUint64(poolTx.TxInfo.Gas),
+				Size:     len(poolTx.RawTx),
+			})
+		}
+	}
+	return txs, nil
+}
+
+// ------------------------------- GetTxPoolStatus -----------------------------------
+
+type GetTxPoolStatusArgs struct {
+	Sender string `json:"sender"` // only the transactions of the sender if specified
+}
+
+type TxPoolSummary struct {
+	NumTxs     int               `json:"num_txs"`
+	NumSenders int               `json:"num_senders"`
+	TotalFee   *common.JSONBig   `json:"total_fee"`
+	TotalGas   common.JSONUint64 `json:"total_gas"`
+	TotalSize  int               `json:"total_size"`
+}
+
+type GetTxPoolStatusResult struct {
+	Pending TxPoolSummary `json:"pending"`
+	Queued  TxPoolSummary `json:"queued"`
+}
+
+// GetTxPoolStatus summarizes the pending and the queued transactions in the mempool.
+func (t *ThetaRPCService) GetTxPoolStatus(args *GetTxPoolStatusArgs, result *GetTxPoolStatusResult) (err error) {
+	pending, queued := t.mempool.GetPoolTxs(txPoolSender(args.Sender))
+	result.Pending = summarizeTxPoolTxs(pending)
+	result.Queued = summarizeTxPoolTxs(queued)
+	return nil
+}
+
+func summarizeTxPoolTxs(poolTxs map[common.Address][]mempool.PoolTx) TxPoolSummary {
+	totalFee := big.NewInt(0)
+	summary := TxPoolSummary{
+		NumSenders: len(poolTxs),
+	}
+	for _, senderTxs := range poolTxs {
+		for _, poolTx := range senderTxs {
+			summary.NumTxs++
+			if poolTx.TxInfo.Fee != nil {
+				totalFee.Add(totalFee, poolTx.TxInfo.Fee)
+			}
+			summary.TotalGas += common.JSONUint64(poolTx.TxInfo.Gas)
+			summary.TotalSize += len(poolTx.RawTx)
+		}
+	}
+	summary.TotalFee = (*common.JSONBig)(totalFee)
+	return summary
+}
+
+func txPoolSender(sender string) *common.Address {
+	if sender == "" {
+		return nil
+	}
+	address := common.HexToAddress(sender)
+	return &address
+}
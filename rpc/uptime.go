@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"errors"
+	"math"
+
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/common"
+)
+
+// ------------------------------- GetValidatorUptime -----------------------------------
+
+type GetValidatorUptimeArgs struct {
+	Address   string            `json:"address"`
+	FromEpoch common.JSONUint64 `json:"from_epoch"`
+	ToEpoch   common.JSONUint64 `json:"to_epoch"` // Defaults to the latest epoch
+}
+
+type ValidatorEpochUptime struct {
+	Epoch    common.JSONUint64 `json:"epoch"`
+	Proposed common.JSONUint64 `json:"proposed"`
+	Voted    common.JSONUint64 `json:"voted"`
+	Missed   common.JSONUint64 `json:"missed"`
+}
+
+type GetValidatorUptimeResult struct {
+	Address     string                 `json:"address"`
+	IndexHeight common.JSONUint64      `json:"index_height"` // height of the last finalized block recorded
+	Proposed    common.JSONUint64      `json:"proposed"`
+	Voted       common.JSONUint64      `json:"voted"`
+	Missed      common.JSONUint64      `json:"missed"`
+	Uptime      float64                `json:"uptime"` // voted / (voted + missed), 0 if there are no blocks to vote on
+	Epochs      []ValidatorEpochUptime `json:"epochs"`
+}
+
+// GetValidatorUptime returns the proposal and vote participation of the validator in the
+// finalized blocks of the epochs in the given range. It requires the validator uptime index to
+// be enabled. Only the epochs with finalized blocks the validator proposed or was expected to
+// vote on are listed.
+func (t *ThetaRPCService) GetValidatorUptime(args *GetValidatorUptimeArgs, result *GetValidatorUptimeResult) (err error) {
+	if !viper.GetBool(common.CfgIndexValidatorUptime) {
+		return errors.New("The validator uptime index is not enabled, set index.validatorUptime to enable it")
+	}
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	toEpoch := uint64(args.ToEpoch)
+	if toEpoch == 0 {
+		toEpoch = math.MaxUint64
+	}
+	if uint64(args.FromEpoch) > toEpoch {
+		return errors.New("from_epoch must not be greater than to_epoch")
+	}
+
+	address := common.HexToAddress(args.Address)
+	var proposed, voted, missed uint64
+	result.Epochs = []ValidatorEpochUptime{}
+	for _, stats := range t.chain.GetValidatorUptime(address, uint64(args.FromEpoch), toEpoch) {
+		proposed += stats.Proposed
+		voted += stats.Voted
+		missed += stats.Missed
+		result.Epochs = append(result.Epochs, ValidatorEpochUptime{
+			Epoch:    common.JSONUint64(stats.Epoch),
+			Proposed: common.JSONUint64(stats.Proposed),
+			Voted:    common.JSONUint64(stats.Voted),
+			Missed:   common.JSONUint64(stats.Missed),
+		})
+	}
+
+	result.Address = address.Hex()
+	result.IndexHeight = common.JSONUint64(t.chain.GetUptimeIndexHeight())
+	result.Proposed = common.JSONUint64(proposed)
+	result.Voted = common.JSONUint64(voted)
+	result.Missed = common.JSONUint64(missed)
+	if voted+missed > 0 {
+		result.Uptime = float64(voted) / float64(voted+missed)
+	}
+	return nil
+}
+
+// ------------------------------- GetMissedBlocks -----------------------------------
+
+type GetMissedBlocksArgs struct {
+	Address string `json:"address"`
+	Cursor  string `json:"cursor"` // next_cursor of the previous page, empty for the first page
+	Limit   int    `json:"limit"`  // Defaults to DefaultExplorerPageSize, capped at MaxExplorerPageSize
+}
+
+type MissedBlock struct {
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	Epoch       common.JSONUint64 `json:"epoch"`
+}
+
+type GetMissedBlocksResult struct {
+	NumMissedBlocks common.JSONUint64 `json:"num_missed_blocks"`
+	Blocks          []MissedBlock     `json:"blocks"`
+	NextCursor      string            `json:"next_cursor"` // empty if there are no more blocks
+}
+
+// GetMissedBlocks returns the finalized blocks the validator was expected to vote on but did
+// not, in increasing height order. It requires the validator uptime index to be enabled.
+func (t *ThetaRPCService) GetMissedBlocks(args *GetMissedBlocksArgs, result *GetMissedBlocksResult) (err error) {
+	if !viper.GetBool(common.CfgIndexValidatorUptime) {
+		return errors.New("The validator uptime index is not enabled, set index.validatorUptime to enable it")
+	}
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	address := common.HexToAddress(args.Address)
+	position, _, err := decodeCursor(args.Cursor)
+	if err != nil {
+		return err
+	}
+
+	count := t.chain.GetMissedBlockCount(address)
+	entries := t.chain.FindMissedBlocks(address, position, uint64(explorerPageSize(args.Limit)))
+	if next := position + uint64(len(entries)); next < count {
+		result.NextCursor = encodeCursor(next)
+	}
+
+	result.NumMissedBlocks = common.JSONUint64(count)
+	result.Blocks = []MissedBlock{}
+	for _, entry := range entries {
+		result.Blocks = append(result.Blocks, MissedBlock{
+			BlockHash:   entry.BlockHash,
+			BlockHeight: common.JSONUint64(entry.BlockHeight),
+			Epoch:       common.JSONUint64(entry.Epoch),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+)
+
+// signState records the last vote and block signed by the signing service.
+type signState struct {
+	VoteHeight uint64      `json:"vote_height"`
+	VoteBlock  common.Hash `json:"vote_block"`
+	BlockEpoch uint64      `json:"block_epoch"`
+	BlockHash  common.Hash `json:"block_hash"` // Hash of the sign bytes of the last signed block
+}
+
+//
+// signGuard protects the validator from double signing. Votes need to be monotonic in
+// height, and blocks in epoch. At the same height (or epoch) only the same vote (or block)
+// can be signed again. The state is persisted before a signature is released, so that the
+// protection holds across restarts of the signing service.
+//
+type signGuard struct {
+	mu       *sync.Mutex
+	filePath string
+	state    signState
+}
+
+func loadSignGuard(filePath string) (*signGuard, error) {
+	guard := &signGuard{
+		mu:       &sync.Mutex{},
+		filePath: filePath,
+	}
+	raw, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return guard, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &guard.state); err != nil {
+		return nil, fmt.Errorf("Failed to parse sign state %v: %v", filePath, err)
+	}
+	return guard, nil
+}
+
+func (g *signGuard) checkVote(vote core.Vote) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if vote.Height < g.state.VoteHeight {
+		return fmt.Errorf("Vote height %v is lower than the last signed vote height %v", vote.Height, g.state.VoteHeight)
+	}
+	if vote.Height == g.state.VoteHeight && !g.state.VoteBlock.IsEmpty() && vote.Block != g.state.VoteBlock {
+		return fmt.Errorf("Conflicting vote at height %v: already signed block %v", vote.Height, g.state.VoteBlock.Hex())
+	}
+
+	newState := g.state
+	newState.VoteHeight = vote.Height
+	newState.VoteBlock = vote.Block
+	return g.save(newState)
+}
+
+func (g *signGuard) checkBlock(header *core.BlockHeader) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	hash := crypto.Keccak256Hash(header.SignBytes())
+	if header.Epoch < g.state.BlockEpoch {
+		return fmt.Errorf("Block epoch %v is lower than the last signed block epoch %v", header.Epoch, g.state.BlockEpoch)
+	}
+	if header.Epoch == g.state.BlockEpoch && !g.state.BlockHash.IsEmpty() && hash != g.state.BlockHash {
+		return fmt.Errorf("Conflicting block proposal in epoch %v", header.Epoch)
+	}
+
+	newState := g.state
+	newState.BlockEpoch = header.Epoch
+	newState.BlockHash = hash
+	return g.save(newState)
+}
+
+func (g *signGuard) save(newState signState) error {
+	if newState == g.state {
+		return nil
+	}
+	raw, err := json.MarshalIndent(newState, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := g.filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, raw, 0600); err != nil {
+		return fmt.Errorf("Failed to save sign state: %v", err)
+	}
+	if err := os.Rename(tmpPath, g.filePath); err != nil {
+		return fmt.Errorf("Failed to save sign state: %v", err)
+	}
+	g.state = newState
+	return nil
+}
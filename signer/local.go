@@ -0,0 +1,49 @@
+package signer
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ core.Signer = (*LocalSigner)(nil)
+
+// LocalSigner signs with the validator key held by the node itself.
+type LocalSigner struct {
+	privKey *crypto.PrivateKey
+}
+
+// NewLocalSigner creates an instance of LocalSigner
+func NewLocalSigner(privKey *crypto.PrivateKey) *LocalSigner {
+	return &LocalSigner{privKey: privKey}
+}
+
+// PublicKey implements the core.Signer interface
+func (ls *LocalSigner) PublicKey() *crypto.PublicKey {
+	return ls.privKey.PublicKey()
+}
+
+// SignVote implements the core.Signer interface
+func (ls *LocalSigner) SignVote(vote core.Vote) (*crypto.Signature, error) {
+	return ls.privKey.Sign(vote.SignBytes())
+}
+
+// SignBlock implements the core.Signer interface
+func (ls *LocalSigner) SignBlock(header *core.BlockHeader) (*crypto.Signature, error) {
+	return ls.privKey.Sign(header.SignBytes())
+}
+
+// SignTx implements the core.Signer interface
+func (ls *LocalSigner) SignTx(chainID string, rawTx common.Bytes) (*crypto.Signature, error) {
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	return ls.privKey.Sign(tx.SignBytes(chainID))
+}
+
+// SignBridgeBatch implements the core.Signer interface
+func (ls *LocalSigner) SignBridgeBatch(batch *core.BridgeBatch) (*crypto.Signature, error) {
+	return ls.privKey.Sign(batch.SignBytes())
+}
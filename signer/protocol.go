@@ -0,0 +1,44 @@
+package signer
+
+import (
+	"io"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// SignRequestType defines the types of sign request
+type SignRequestType byte
+
+const (
+	SignRequestVote        SignRequestType = 0x01
+	SignRequestBlock       SignRequestType = 0x02
+	SignRequestTx          SignRequestType = 0x03
+	SignRequestBridgeBatch SignRequestType = 0x04
+)
+
+const maxSignMessageSize = 4 * 1024 * 1024
+
+// SignRequest is sent by the node to the signing service. The payload is the RLP encoded vote,
+// block header, transaction or bridge batch depending on the request type.
+type SignRequest struct {
+	Type    SignRequestType
+	ChainID string
+	Payload common.Bytes
+}
+
+// SignResponse carries either the signature or the reason the request was refused.
+type SignResponse struct {
+	Signature common.Bytes
+	Error     string
+}
+
+func writeMessage(w io.Writer, msg interface{}) error {
+	return rlp.Encode(w, msg)
+}
+
+// readMessage decodes one message from the connection. The connection needs to implement
+// io.ByteReader, so that no bytes beyond the message are consumed.
+func readMessage(r io.Reader, msg interface{}) error {
+	return rlp.NewStream(r, maxSignMessageSize).Decode(msg)
+}
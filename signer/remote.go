@@ -0,0 +1,186 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/p2p/connection"
+	"github.com/thetatoken/theta/rlp"
+)
+
+var _ core.Signer = (*RemoteSigner)(nil)
+
+//
+// RemoteSigner requests the signatures from a signing service over an encrypted connection,
+// so that the validator key never needs to be present on the node. The node authenticates
+// with its node key, and the service with the validator key.
+//
+type RemoteSigner struct {
+	endpoint string
+	nodeKey  *crypto.PrivateKey
+	address  common.Address
+	timeout  time.Duration
+
+	mu     *sync.Mutex
+	conn   *connection.SecretConnection
+	pubKey *crypto.PublicKey
+}
+
+// NewRemoteSigner creates an instance of RemoteSigner and connects to the signing service at
+// endpoint, which needs to prove the ownership of the key of the given validator address.
+func NewRemoteSigner(endpoint string, nodeKey *crypto.PrivateKey, address common.Address, timeout time.Duration) (*RemoteSigner, error) {
+	rs := &RemoteSigner{
+		endpoint: endpoint,
+		nodeKey:  nodeKey,
+		address:  address,
+		timeout:  timeout,
+		mu:       &sync.Mutex{},
+	}
+	if err := rs.connect(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// PublicKey implements the core.Signer interface
+func (rs *RemoteSigner) PublicKey() *crypto.PublicKey {
+	return rs.pubKey
+}
+
+// SignVote implements the core.Signer interface
+func (rs *RemoteSigner) SignVote(vote core.Vote) (*crypto.Signature, error) {
+	payload, err := rlp.EncodeToBytes(vote)
+	if err != nil {
+		return nil, err
+	}
+	req := SignRequest{Type: SignRequestVote, Payload: payload}
+	return rs.request(req, vote.SignBytes())
+}
+
+// SignBlock implements the core.Signer interface
+func (rs *RemoteSigner) SignBlock(header *core.BlockHeader) (*crypto.Signature, error) {
+	payload, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	req := SignRequest{Type: SignRequestBlock, Payload: payload}
+	return rs.request(req, header.SignBytes())
+}
+
+// SignTx implements the core.Signer interface
+func (rs *RemoteSigner) SignTx(chainID string, rawTx common.Bytes) (*crypto.Signature, error) {
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	req := SignRequest{Type: SignRequestTx, ChainID: chainID, Payload: rawTx}
+	return rs.request(req, tx.SignBytes(chainID))
+}
+
+// SignBridgeBatch implements the core.Signer interface
+func (rs *RemoteSigner) SignBridgeBatch(batch *core.BridgeBatch) (*crypto.Signature, error) {
+	payload, err := rlp.EncodeToBytes(batch)
+	if err != nil {
+		return nil, err
+	}
+	req := SignRequest{Type: SignRequestBridgeBatch, ChainID: batch.SourceChainID, Payload: payload}
+	return rs.request(req, batch.SignBytes())
+}
+
+// Close closes the connection to the signing service
+func (rs *RemoteSigner) Close() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.disconnect()
+}
+
+func (rs *RemoteSigner) connect() error {
+	conn, err := net.DialTimeout("tcp", rs.endpoint, rs.timeout)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to the signing service %v: %v", rs.endpoint, err)
+	}
+	conn.SetDeadline(time.Now().Add(rs.timeout))
+	sc, err := connection.MakeSecretConnection(conn, rs.nodeKey)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Handshake with the signing service %v failed: %v", rs.endpoint, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	pubKey := sc.RemotePubKey()
+	if pubKey.Address() != rs.address {
+		conn.Close()
+		return fmt.Errorf("Signing service %v authenticated as %v, expected validator %v",
+			rs.endpoint, pubKey.Address().Hex(), rs.address.Hex())
+	}
+	rs.conn = sc
+	rs.pubKey = pubKey
+
+	logger.Infof("Connected to the signing service %v for validator %v", rs.endpoint, rs.address.Hex())
+	return nil
+}
+
+func (rs *RemoteSigner) disconnect() {
+	if rs.conn != nil {
+		rs.conn.Close()
+		rs.conn = nil
+	}
+}
+
+// request sends the request to the signing service, and verifies the returned signature
+// against signBytes. A broken connection is re-established once.
+func (rs *RemoteSigner) request(req SignRequest, signBytes common.Bytes) (*crypto.Signature, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	var resp SignResponse
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if rs.conn == nil {
+			if err = rs.connect(); err != nil {
+				continue
+			}
+		}
+		if resp, err = rs.roundTrip(req); err == nil {
+			break
+		}
+		logger.Warnf("Request to the signing service %v failed: %v", rs.endpoint, err)
+		rs.disconnect()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, fmt.Errorf("Signing service refused to sign: %v", resp.Error)
+	}
+	sig, err := crypto.SignatureFromBytes(resp.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if !rs.pubKey.VerifySignature(signBytes, sig) {
+		return nil, errors.New("Invalid signature returned by the signing service")
+	}
+	return sig, nil
+}
+
+func (rs *RemoteSigner) roundTrip(req SignRequest) (SignResponse, error) {
+	resp := SignResponse{}
+	rs.conn.SetDeadline(time.Now().Add(rs.timeout))
+	defer rs.conn.SetDeadline(time.Time{})
+
+	if err := writeMessage(rs.conn, req); err != nil {
+		return resp, err
+	}
+	if err := readMessage(rs.conn, &resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,234 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/p2p/connection"
+	"github.com/thetatoken/theta/rlp"
+)
+
+var logger *log.Entry = util.GetLoggerForModule("signer")
+
+const handshakeTimeout = 10 * time.Second
+
+//
+// Server is the signing service holding the validator key. It accepts connections from the
+// allowed nodes only, authenticated by their node keys, and signs their requests after
+// checking them against the double signing protection.
+//
+type Server struct {
+	privKey      *crypto.PrivateKey
+	address      common.Address
+	listenAddr   string
+	allowedNodes map[common.Address]bool
+	guard        *signGuard
+
+	listener net.Listener
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// NewServer creates an instance of Server. The sign state is persisted in stateFilePath.
+func NewServer(privKey *crypto.PrivateKey, listenAddr string, allowedNodes []common.Address, stateFilePath string) (*Server, error) {
+	guard, err := loadSignGuard(stateFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[common.Address]bool)
+	for _, addr := range allowedNodes {
+		allowed[addr] = true
+	}
+
+	logger = util.GetLoggerForModule("signer")
+
+	return &Server{
+		privKey:      privKey,
+		address:      privKey.PublicKey().Address(),
+		listenAddr:   listenAddr,
+		allowedNodes: allowed,
+		guard:        guard,
+		wg:           &sync.WaitGroup{},
+	}, nil
+}
+
+// Start starts listening for the node connections
+func (s *Server) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	s.ctx = c
+	s.cancel = cancel
+
+	listener, err := net.Listen("tcp", s.listenAddr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	logger.WithFields(log.Fields{
+		"address": s.address.Hex(),
+		"listen":  listener.Addr().String(),
+	}).Info("Signing service started")
+
+	s.wg.Add(1)
+	go s.acceptRoutine()
+
+	return nil
+}
+
+// Stop notifies all goroutines to stop without blocking.
+func (s *Server) Stop() {
+	s.cancel()
+	s.listener.Close()
+}
+
+// Wait blocks until all goroutines stop.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) acceptRoutine() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				s.stopped = true
+				return
+			}
+			logger.Warnf("Failed to accept connection: %v", err)
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	sc, err := connection.MakeSecretConnection(conn, s.privKey)
+	if err != nil {
+		logger.Warnf("Handshake with %v failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	nodeAddr := sc.RemotePubKey().Address()
+	if !s.allowedNodes[nodeAddr] {
+		logger.Warnf("Rejected connection from node %v at %v", nodeAddr.Hex(), conn.RemoteAddr())
+		return
+	}
+	logger.Infof("Accepted connection from node %v at %v", nodeAddr.Hex(), conn.RemoteAddr())
+
+	for {
+		req := SignRequest{}
+		if err := readMessage(sc, &req); err != nil {
+			if s.ctx.Err() == nil {
+				logger.Infof("Connection from node %v closed: %v", nodeAddr.Hex(), err)
+			}
+			return
+		}
+
+		resp := SignResponse{}
+		sig, err := s.sign(req)
+		if err != nil {
+			logger.WithFields(log.Fields{"node": nodeAddr.Hex(), "type": req.Type, "error": err}).Warn("Refused to sign")
+			resp.Error = err.Error()
+		} else {
+			resp.Signature = sig.ToBytes()
+		}
+		if err := writeMessage(sc, resp); err != nil {
+			logger.Infof("Failed to reply to node %v: %v", nodeAddr.Hex(), err)
+			return
+		}
+	}
+}
+
+func (s *Server) sign(req SignRequest) (*crypto.Signature, error) {
+	switch req.Type {
+	case SignRequestVote:
+		vote := core.Vote{}
+		if err := rlp.DecodeBytes(req.Payload, &vote); err != nil {
+			return nil, fmt.Errorf("Failed to decode vote: %v", err)
+		}
+		if vote.ID != s.address {
+			return nil, fmt.Errorf("Vote is not cast by validator %v", s.address.Hex())
+		}
+		if err := s.guard.checkVote(vote); err != nil {
+			return nil, err
+		}
+		return s.privKey.Sign(vote.SignBytes())
+	case SignRequestBlock:
+		header := &core.BlockHeader{}
+		if err := rlp.DecodeBytes(req.Payload, header); err != nil {
+			return nil, fmt.Errorf("Failed to decode block header: %v", err)
+		}
+		if header.Proposer != s.address {
+			return nil, fmt.Errorf("Block is not proposed by validator %v", s.address.Hex())
+		}
+		if err := s.guard.checkBlock(header); err != nil {
+			return nil, err
+		}
+		return s.privKey.Sign(header.SignBytes())
+	case SignRequestTx:
+		tx, err := types.TxFromBytes(req.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode transaction: %v", err)
+		}
+		var proposer common.Address
+		switch tx := tx.(type) {
+		case *types.CoinbaseTx:
+			proposer = tx.Proposer.Address
+		case *types.SlashTx:
+			proposer = tx.Proposer.Address
+		default:
+			return nil, errors.New("Only coinbase and slash transactions can be signed")
+		}
+		if proposer != s.address {
+			return nil, fmt.Errorf("Transaction is not proposed by validator %v", s.address.Hex())
+		}
+		return s.privKey.Sign(tx.SignBytes(req.ChainID))
+	case SignRequestBridgeBatch:
+		batch := &core.BridgeBatch{}
+		if err := rlp.DecodeBytes(req.Payload, batch); err != nil {
+			return nil, fmt.Errorf("Failed to decode bridge batch: %v", err)
+		}
+		return s.privKey.Sign(batch.SignBytes())
+	default:
+		return nil, fmt.Errorf("Invalid sign request type: %v", req.Type)
+	}
+}
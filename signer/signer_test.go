@@ -0,0 +1,183 @@
+package signer
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func newTestServer(t *testing.T, valKey *crypto.PrivateKey, stateDir string, allowed ...common.Address) *Server {
+	server, err := NewServer(valKey, "127.0.0.1:0", allowed, path.Join(stateDir, "signer_state.json"))
+	assert.Nil(t, err)
+	assert.Nil(t, server.Start(context.Background()))
+	return server
+}
+
+func TestRemoteSignerVote(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "signer")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	valKey, _, _ := crypto.GenerateKeyPair()
+	nodeKey, _, _ := crypto.GenerateKeyPair()
+	valAddr := valKey.PublicKey().Address()
+	server := newTestServer(t, valKey, dir, nodeKey.PublicKey().Address())
+	defer func() {
+		server.Stop()
+		server.Wait()
+	}()
+
+	rs, err := NewRemoteSigner(server.Addr().String(), nodeKey, valAddr, 3*time.Second)
+	assert.Nil(err)
+	defer rs.Close()
+	assert.Equal(valAddr, rs.PublicKey().Address())
+
+	vote := core.Vote{Block: common.BytesToHash([]byte("b1")), Height: 10, Epoch: 20, ID: valAddr}
+	sig, err := rs.SignVote(vote)
+	assert.Nil(err)
+	vote.SetSignature(sig)
+	assert.True(vote.Validate().IsOK())
+
+	// Repeating the vote in a later epoch is allowed
+	vote.Epoch = 21
+	_, err = rs.SignVote(vote)
+	assert.Nil(err)
+
+	// Conflicting vote at the same height
+	conflicting := core.Vote{Block: common.BytesToHash([]byte("b2")), Height: 10, Epoch: 21, ID: valAddr}
+	_, err = rs.SignVote(conflicting)
+	assert.NotNil(err)
+
+	// Lower height
+	lower := core.Vote{Block: common.BytesToHash([]byte("b0")), Height: 9, Epoch: 22, ID: valAddr}
+	_, err = rs.SignVote(lower)
+	assert.NotNil(err)
+
+	// Vote of another validator
+	other := core.Vote{Block: common.BytesToHash([]byte("b3")), Height: 11, Epoch: 22, ID: nodeKey.PublicKey().Address()}
+	_, err = rs.SignVote(other)
+	assert.NotNil(err)
+}
+
+func TestRemoteSignerBlockAndTx(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "signer")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	valKey, _, _ := crypto.GenerateKeyPair()
+	nodeKey, _, _ := crypto.GenerateKeyPair()
+	valAddr := valKey.PublicKey().Address()
+	server := newTestServer(t, valKey, dir, nodeKey.PublicKey().Address())
+	defer func() {
+		server.Stop()
+		server.Wait()
+	}()
+
+	rs, err := NewRemoteSigner(server.Addr().String(), nodeKey, valAddr, 3*time.Second)
+	assert.Nil(err)
+	defer rs.Close()
+
+	block := core.NewBlock()
+	block.ChainID = "testchain"
+	block.Epoch = 5
+	block.Height = 3
+	block.Proposer = valAddr
+	block.Timestamp = big.NewInt(1)
+	sig, err := rs.SignBlock(block.BlockHeader)
+	assert.Nil(err)
+	assert.True(sig.Verify(block.SignBytes(), valAddr))
+
+	// Conflicting proposal in the same epoch
+	block2 := core.NewBlock()
+	block2.ChainID = "testchain"
+	block2.Epoch = 5
+	block2.Height = 4
+	block2.Proposer = valAddr
+	block2.Timestamp = big.NewInt(2)
+	_, err = rs.SignBlock(block2.BlockHeader)
+	assert.NotNil(err)
+
+	coinbaseTx := &types.CoinbaseTx{
+		Proposer:    types.TxInput{Address: valAddr},
+		BlockHeight: 3,
+	}
+	rawTx, err := types.TxToBytes(coinbaseTx)
+	assert.Nil(err)
+	sig, err = rs.SignTx("testchain", rawTx)
+	assert.Nil(err)
+	assert.True(valKey.PublicKey().VerifySignature(coinbaseTx.SignBytes("testchain"), sig))
+
+	sendTx := &types.SendTx{
+		Fee:    types.NewCoins(0, 1),
+		Inputs: []types.TxInput{{Address: valAddr}},
+	}
+	rawTx, err = types.TxToBytes(sendTx)
+	assert.Nil(err)
+	_, err = rs.SignTx("testchain", rawTx)
+	assert.NotNil(err)
+}
+
+func TestRemoteSignerAuthentication(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "signer")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	valKey, _, _ := crypto.GenerateKeyPair()
+	nodeKey, _, _ := crypto.GenerateKeyPair()
+	otherKey, _, _ := crypto.GenerateKeyPair()
+	valAddr := valKey.PublicKey().Address()
+	server := newTestServer(t, valKey, dir, nodeKey.PublicKey().Address())
+	defer func() {
+		server.Stop()
+		server.Wait()
+	}()
+
+	// The signing service does not hold the expected validator key
+	_, err = NewRemoteSigner(server.Addr().String(), nodeKey, otherKey.PublicKey().Address(), 3*time.Second)
+	assert.NotNil(err)
+
+	// The node is not allowed to connect
+	rs, err := NewRemoteSigner(server.Addr().String(), otherKey, valAddr, 3*time.Second)
+	if err == nil {
+		defer rs.Close()
+		vote := core.Vote{Block: common.BytesToHash([]byte("b1")), Height: 1, Epoch: 1, ID: valAddr}
+		_, err = rs.SignVote(vote)
+	}
+	assert.NotNil(err)
+}
+
+func TestSignGuardPersistence(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "signer")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "signer_state.json")
+
+	guard, err := loadSignGuard(filePath)
+	assert.Nil(err)
+	vote := core.Vote{Block: common.BytesToHash([]byte("b1")), Height: 10}
+	assert.Nil(guard.checkVote(vote))
+
+	guard, err = loadSignGuard(filePath)
+	assert.Nil(err)
+	assert.Nil(guard.checkVote(vote))
+	assert.NotNil(guard.checkVote(core.Vote{Block: common.BytesToHash([]byte("b2")), Height: 10}))
+	assert.Nil(guard.checkVote(core.Vote{Block: common.BytesToHash([]byte("b2")), Height: 11}))
+}
@@ -24,7 +24,7 @@ func ExportSnapshot(db database.Database, consensus *cns.ConsensusEngine, chain
 	metadata := &core.SnapshotMetadata{}
 
 	stub := consensus.GetSummary()
-	lastFinalizedBlock, err := chain.FindBlock(stub.LastFinalizedBlock)
+	lastFinalizedBlock, err := chain.FindBlockHeader(stub.LastFinalizedBlock)
 	if err != nil {
 		logger.Errorf("Failed to get block %v, %v", stub.LastFinalizedBlock, err)
 		return "", err
@@ -33,7 +33,7 @@ func ExportSnapshot(db database.Database, consensus *cns.ConsensusEngine, chain
 	sv := state.NewStoreView(lastFinalizedBlock.Height, lastFinalizedBlock.BlockHeader.StateHash, db)
 
 	var genesisBlockHeader *core.BlockHeader
-	kvStore := kvstore.NewKVStore(db)
+	kvStore := kvstore.NewKVStore(database.BlockDatabase(db))
 	hl := sv.GetStakeTransactionHeightList().Heights
 	for _, height := range hl {
 		// check kvstore first
@@ -49,7 +49,7 @@ func ExportSnapshot(db database.Database, consensus *cns.ConsensusEngine, chain
 		}
 
 		if height == core.GenesisBlockHeight {
-			blocks := chain.FindBlocksByHeight(core.GenesisBlockHeight)
+			blocks := chain.FindBlockHeadersByHeight(core.GenesisBlockHeight)
 			genesisBlock := blocks[0]
 			genesisBlockHeader = genesisBlock.BlockHeader
 			metadata.ProofTrios = append(metadata.ProofTrios,
@@ -59,7 +59,7 @@ func ExportSnapshot(db database.Database, consensus *cns.ConsensusEngine, chain
 					Third:  core.SnapshotThirdBlock{},
 				})
 		} else {
-			blocks := chain.FindBlocksByHeight(height)
+			blocks := chain.FindBlockHeadersByHeight(height)
 			foundDirectlyFinalizedBlock := false
 			for _, block := range blocks {
 				if block.Status.IsDirectlyFinalized() {
@@ -90,6 +90,11 @@ func ExportSnapshot(db database.Database, consensus *cns.ConsensusEngine, chain
 					if child.HCC.BlockHash != block.Hash() || grandChild.HCC.BlockHash != child.Hash() {
 						return "", fmt.Errorf("Invalid block HCC link for validator set changes")
 					}
+					// The voters of a compact HCC are relative to the validator set updated by the block
+					sv := state.NewStoreView(block.Height, block.StateHash, db)
+					if err := grandChild.HCC.ResolveVotes(getValidatorSetFromSV(sv)); err != nil {
+						return "", fmt.Errorf("Invalid block HCC voters for validator set changes: %v", err)
+					}
 					if grandChild.HCC.Votes.IsEmpty() {
 						return "", fmt.Errorf("Missing block HCC votes for validator set changes")
 					}
@@ -119,7 +124,7 @@ func ExportSnapshot(db database.Database, consensus *cns.ConsensusEngine, chain
 		}
 	}
 
-	parentBlock, err := chain.FindBlock(lastFinalizedBlock.Parent)
+	parentBlock, err := chain.FindBlockHeader(lastFinalizedBlock.Parent)
 	if err != nil {
 		return "", fmt.Errorf("Failed to find last finalized block's parent, %v", err)
 	}
@@ -181,7 +186,7 @@ func proveVCP(block *core.ExtendedBlock, db database.Database) (*core.VCPProof,
 
 func getFinalizedChild(block *core.ExtendedBlock, chain *blockchain.Chain) (*core.ExtendedBlock, error) {
 	for _, h := range block.Children {
-		b, err := chain.FindBlock(h)
+		b, err := chain.FindBlockHeader(h)
 		if err != nil {
 			logger.Errorf("Failed to get block %v", err)
 			return nil, err
@@ -195,7 +200,7 @@ func getFinalizedChild(block *core.ExtendedBlock, chain *blockchain.Chain) (*cor
 
 func getAtLeastCommittedChild(block *core.ExtendedBlock, chain *blockchain.Chain) (*core.ExtendedBlock, error) {
 	for _, h := range block.Children {
-		b, err := chain.FindBlock(h)
+		b, err := chain.FindBlockHeader(h)
 		if err != nil {
 			logger.Errorf("Failed to get block %v", err)
 			return nil, err
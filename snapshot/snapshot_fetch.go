@@ -0,0 +1,296 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+)
+
+const (
+	// DefaultChunkSize is the size of the chunks a snapshot file is split into for download.
+	DefaultChunkSize = 1024 * 1024
+
+	// MaxChunkSize is the largest chunk size accepted from a provider, since a chunk is held in
+	// memory while it is downloaded.
+	MaxChunkSize = 64 * 1024 * 1024
+
+	// MaxNumChunks is the largest number of chunks accepted from a provider.
+	MaxNumChunks = 1024 * 1024
+
+	// MaxFileSize is the largest snapshot file accepted from a provider.
+	MaxFileSize = 1024 * 1024 * 1024 * 1024
+)
+
+// Manifest describes a snapshot file served by a provider.
+type Manifest struct {
+	Height      uint64        `json:"height"`
+	StateRoot   common.Hash   `json:"state_root"`
+	FileSize    uint64        `json:"file_size"`
+	ChunkSize   uint64        `json:"chunk_size"`
+	ChunkHashes []common.Hash `json:"chunk_hashes"`
+}
+
+// NumChunks returns the number of chunks in the snapshot.
+func (m *Manifest) NumChunks() uint64 {
+	return uint64(len(m.ChunkHashes))
+}
+
+// Equal returns whether two manifests describe the same snapshot.
+func (m *Manifest) Equal(other *Manifest) bool {
+	if m.Height != other.Height || m.StateRoot != other.StateRoot ||
+		m.FileSize != other.FileSize || m.ChunkSize != other.ChunkSize ||
+		len(m.ChunkHashes) != len(other.ChunkHashes) {
+		return false
+	}
+	for i := range m.ChunkHashes {
+		if m.ChunkHashes[i] != other.ChunkHashes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Manifest) String() string {
+	return fmt.Sprintf("Manifest{Height: %v, StateRoot: %v, FileSize: %v, NumChunks: %v}",
+		m.Height, m.StateRoot.Hex(), m.FileSize, m.NumChunks())
+}
+
+// Validate checks the manifest received from a provider is within the limits, and that its
+// chunks add up to the file size, before anything is allocated for the download.
+func (m *Manifest) Validate() error {
+	if m.ChunkSize == 0 || m.ChunkSize > MaxChunkSize {
+		return fmt.Errorf("Invalid snapshot chunk size: %v", m.ChunkSize)
+	}
+	if m.FileSize > MaxFileSize {
+		return fmt.Errorf("Snapshot file too large: %v", m.FileSize)
+	}
+	if m.NumChunks() > MaxNumChunks {
+		return fmt.Errorf("Too many snapshot chunks: %v", m.NumChunks())
+	}
+	if expected := (m.FileSize + m.ChunkSize - 1) / m.ChunkSize; m.NumChunks() != expected {
+		return fmt.Errorf("Snapshot of %v bytes has %v chunks, expected %v", m.FileSize, m.NumChunks(), expected)
+	}
+	return nil
+}
+
+// NewManifest creates the manifest of the given snapshot content.
+func NewManifest(height uint64, stateRoot common.Hash, content []byte, chunkSize uint64) *Manifest {
+	m, err := newManifest(height, stateRoot, bytes.NewReader(content), chunkSize)
+	if err != nil {
+		panic(err) // reading from memory does not fail
+	}
+	return m
+}
+
+// newManifest creates the manifest of the snapshot content read from r, hashing one chunk at a
+// time so that the content is never held in memory as a whole.
+func newManifest(height uint64, stateRoot common.Hash, r io.Reader, chunkSize uint64) (*Manifest, error) {
+	m := &Manifest{
+		Height:    height,
+		StateRoot: stateRoot,
+		ChunkSize: chunkSize,
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			m.FileSize += uint64(n)
+			m.ChunkHashes = append(m.ChunkHashes, crypto.Keccak256Hash(buf[:n]))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// BuildManifest creates the manifest of the snapshot file at the given path.
+func BuildManifest(filePath string, chunkSize uint64) (*Manifest, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	metadata := core.SnapshotMetadata{}
+	err = core.ReadRecord(file, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load snapshot metadata, %v", err)
+	}
+	header := metadata.TailTrio.Second.Header
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return newManifest(header.Height, header.StateHash, file, chunkSize)
+}
+
+// ReadChunk reads the chunk with the given index of the snapshot described by the manifest.
+func ReadChunk(r io.ReaderAt, manifest *Manifest, index uint64) (common.Bytes, error) {
+	if index >= manifest.NumChunks() {
+		return nil, fmt.Errorf("Chunk index out of range: %v", index)
+	}
+	start := index * manifest.ChunkSize
+	size := manifest.ChunkSize
+	if start+size > manifest.FileSize {
+		size = manifest.FileSize - start
+	}
+	chunk := make(common.Bytes, size)
+	n, err := r.ReadAt(chunk, int64(start))
+	if n < len(chunk) {
+		return nil, fmt.Errorf("Failed to read snapshot chunk %v: %v", index, err)
+	}
+	return chunk, nil
+}
+
+// Provider is a source a snapshot can be downloaded from, e.g. a peer's RPC endpoint.
+type Provider interface {
+	ID() string
+	GetManifest() (*Manifest, error)
+	GetChunk(index uint64) (common.Bytes, error)
+}
+
+// ConflictError is returned when providers disagree on the snapshot to serve.
+type ConflictError struct {
+	Manifests map[string]*Manifest
+}
+
+func (e *ConflictError) Error() string {
+	ids := []string{}
+	for id := range e.Manifests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	lines := []string{"Snapshot providers disagree on the snapshot to serve:"}
+	for _, id := range ids {
+		m := e.Manifests[id]
+		lines = append(lines, fmt.Sprintf("  %v: height %v, state root %v", id, m.Height, m.StateRoot.Hex()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FetchManifest queries the manifest from all providers and makes sure at least minProviders
+// distinct providers agree on it. Returns the agreed manifest and the providers serving it.
+func FetchManifest(providers []Provider, minProviders int) (*Manifest, []Provider, error) {
+	manifests := make(map[string]*Manifest)
+	var agreed *Manifest
+	agreedProviders := []Provider{}
+	conflicted := false
+	for _, p := range providers {
+		if _, ok := manifests[p.ID()]; ok {
+			continue // Duplicate provider.
+		}
+		m, err := p.GetManifest()
+		if err == nil {
+			err = m.Validate()
+		}
+		if err != nil {
+			logger.WithFields(log.Fields{"provider": p.ID(), "err": err}).Warn("Failed to get snapshot manifest")
+			continue
+		}
+		manifests[p.ID()] = m
+		if agreed == nil {
+			agreed = m
+		}
+		if !agreed.Equal(m) {
+			conflicted = true
+			continue
+		}
+		agreedProviders = append(agreedProviders, p)
+	}
+
+	if conflicted {
+		return nil, nil, &ConflictError{Manifests: manifests}
+	}
+	if len(agreedProviders) < minProviders {
+		return nil, nil, fmt.Errorf("Only %v snapshot providers responded, at least %v required", len(agreedProviders), minProviders)
+	}
+	return agreed, agreedProviders, nil
+}
+
+// Download fetches the snapshot from the given providers and writes it to filePath. The
+// manifest is cross-verified among at least minProviders providers, and chunk downloads are
+// interleaved across them so that no single provider serves the whole snapshot. The chunks are
+// written to a temporary file as they are downloaded, which is moved to filePath once complete.
+func Download(providers []Provider, minProviders int, filePath string) (*Manifest, error) {
+	manifest, agreedProviders, err := FetchManifest(providers, minProviders)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(log.Fields{
+		"manifest":  manifest,
+		"providers": len(agreedProviders),
+	}).Info("Downloading snapshot")
+
+	tmpPath := filePath + ".download"
+	if err := downloadChunks(agreedProviders, manifest, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// downloadChunks downloads the chunks of the snapshot in order and appends them to the file.
+func downloadChunks(providers []Provider, manifest *Manifest, filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	written := uint64(0)
+	for i := uint64(0); i < manifest.NumChunks(); i++ {
+		chunk, err := fetchChunk(providers, manifest, i)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(chunk); err != nil {
+			return err
+		}
+		written += uint64(len(chunk))
+	}
+
+	if written != manifest.FileSize {
+		return fmt.Errorf("Snapshot size mismatch: %v vs %v", written, manifest.FileSize)
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// fetchChunk downloads a chunk starting from the provider assigned by round-robin, falling back
+// to the other providers if the download fails or the chunk doesn't match the manifest.
+func fetchChunk(providers []Provider, manifest *Manifest, index uint64) (common.Bytes, error) {
+	numProviders := uint64(len(providers))
+	for attempt := uint64(0); attempt < numProviders; attempt++ {
+		p := providers[(index+attempt)%numProviders]
+		chunk, err := p.GetChunk(index)
+		if err != nil {
+			logger.WithFields(log.Fields{"provider": p.ID(), "chunk": index, "err": err}).Warn("Failed to download snapshot chunk")
+			continue
+		}
+		if crypto.Keccak256Hash(chunk) != manifest.ChunkHashes[index] {
+			logger.WithFields(log.Fields{"provider": p.ID(), "chunk": index}).Warn("Snapshot chunk hash mismatch")
+			continue
+		}
+		return chunk, nil
+	}
+	return nil, fmt.Errorf("Failed to download snapshot chunk %v from any provider", index)
+}
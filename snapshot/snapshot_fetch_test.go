@@ -0,0 +1,155 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+)
+
+type testProvider struct {
+	id       string
+	manifest *Manifest
+	content  []byte
+	corrupt  bool
+	requests []uint64
+}
+
+func (p *testProvider) ID() string {
+	return p.id
+}
+
+func (p *testProvider) GetManifest() (*Manifest, error) {
+	if p.manifest == nil {
+		return nil, errors.New("unavailable")
+	}
+	return p.manifest, nil
+}
+
+func (p *testProvider) GetChunk(index uint64) (common.Bytes, error) {
+	p.requests = append(p.requests, index)
+	chunk, err := ReadChunk(bytes.NewReader(p.content), p.manifest, index)
+	if err != nil {
+		return nil, err
+	}
+	if p.corrupt {
+		return common.Bytes("corrupted"), nil
+	}
+	return chunk, nil
+}
+
+func newTestProvider(id string, height uint64, content []byte) *testProvider {
+	return &testProvider{
+		id:       id,
+		manifest: NewManifest(height, common.HexToHash("a1"), content, 4),
+		content:  content,
+	}
+}
+
+func TestSnapshotDownload(t *testing.T) {
+	assert := assert.New(t)
+
+	content := []byte("0123456789abcdefghij")
+	p1 := newTestProvider("p1", 10, content)
+	p2 := newTestProvider("p2", 10, content)
+	p3 := newTestProvider("p3", 10, content)
+	p3.corrupt = true
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "snapshot")
+
+	manifest, err := Download([]Provider{p1, p2, p3}, 3, filePath)
+	assert.Nil(err)
+	assert.Equal(uint64(5), manifest.NumChunks())
+
+	downloaded, err := ioutil.ReadFile(filePath)
+	assert.Nil(err)
+	assert.Equal(content, downloaded)
+	_, err = os.Stat(filePath + ".download")
+	assert.True(os.IsNotExist(err))
+
+	// Chunk downloads are interleaved across providers.
+	assert.True(len(p1.requests) > 0)
+	assert.True(len(p2.requests) > 0)
+	assert.True(len(p3.requests) > 0)
+}
+
+func TestSnapshotDownloadConflict(t *testing.T) {
+	assert := assert.New(t)
+
+	content := []byte("0123456789abcdefghij")
+	p1 := newTestProvider("p1", 10, content)
+	p2 := newTestProvider("p2", 11, content)
+
+	_, err := Download([]Provider{p1, p2}, 2, path.Join(os.TempDir(), "snapshot_conflict"))
+	assert.NotNil(err)
+	conflict, ok := err.(*ConflictError)
+	assert.True(ok)
+	assert.Equal(2, len(conflict.Manifests))
+	assert.Contains(err.Error(), "p1: height 10")
+	assert.Contains(err.Error(), "p2: height 11")
+}
+
+func TestSnapshotDownloadNotEnoughProviders(t *testing.T) {
+	assert := assert.New(t)
+
+	content := []byte("0123456789abcdefghij")
+	p1 := newTestProvider("p1", 10, content)
+	p2 := newTestProvider("p2", 10, content)
+	p2.manifest = nil
+
+	// Duplicate providers are only counted once.
+	_, err := Download([]Provider{p1, p1, p2}, 2, path.Join(os.TempDir(), "snapshot_insufficient"))
+	assert.NotNil(err)
+	_, ok := err.(*ConflictError)
+	assert.False(ok)
+}
+
+func TestSnapshotManifestLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	content := []byte("0123456789abcdefghij")
+	manifest := NewManifest(10, common.HexToHash("a1"), content, 4)
+	assert.Nil(manifest.Validate())
+	assert.Equal(uint64(len(content)), manifest.FileSize)
+
+	// A provider cannot make the node allocate more than the manifest backs with chunks
+	inflated := *manifest
+	inflated.FileSize = 1 << 50
+	assert.NotNil(inflated.Validate())
+	inflated.FileSize = 1 << 30
+	assert.NotNil(inflated.Validate())
+
+	oversized := *manifest
+	oversized.ChunkSize = MaxChunkSize + 1
+	assert.NotNil(oversized.Validate())
+
+	// The providers serving an invalid manifest are skipped
+	p1 := newTestProvider("p1", 10, content)
+	p2 := newTestProvider("p2", 10, content)
+	p2.manifest = &inflated
+	_, providers, err := FetchManifest([]Provider{p1, p2}, 1)
+	assert.Nil(err)
+	assert.Equal([]Provider{p1}, providers)
+	_, _, err = FetchManifest([]Provider{p1, p2}, 2)
+	assert.NotNil(err)
+
+	// The chunks are read from the file at their offset
+	for i := uint64(0); i < manifest.NumChunks(); i++ {
+		chunk, err := ReadChunk(bytes.NewReader(content), manifest, i)
+		assert.Nil(err)
+		assert.Equal(crypto.Keccak256Hash(chunk), manifest.ChunkHashes[i])
+	}
+	_, err = ReadChunk(bytes.NewReader(content), manifest, manifest.NumChunks())
+	assert.NotNil(err)
+	_, err = ReadChunk(bytes.NewReader(content[:10]), manifest, 3)
+	assert.NotNil(err)
+}
@@ -12,6 +12,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/ledger/state"
@@ -24,7 +25,7 @@ import (
 	"github.com/thetatoken/theta/store/trie"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "snapshot"})
+var logger *log.Entry = util.GetLoggerForModule("snapshot")
 
 type SVStack []*state.StoreView
 
@@ -112,7 +113,7 @@ func loadSnapshot(filePath string, db database.Database) (*core.BlockHeader, err
 
 	// --------------------- Save Proofs and Tail Blocks  --------------------- //
 
-	kvstore := kvstore.NewKVStore(db)
+	kvstore := kvstore.NewKVStore(database.BlockDatabase(db))
 
 	for _, blockTrio := range metadata.ProofTrios {
 		blockTrioKey := []byte(core.BlockTrioStoreKeyPrefix + strconv.FormatUint(blockTrio.First.Header.Height, 10))
@@ -238,14 +239,21 @@ func checkProofTrios(proofTrios []core.SnapshotBlockTrio, db database.Database)
 					second.Header.Hash(), third.Header.HCC.BlockHash)
 			}
 
-			// third.Header.HCC.Votes contains the votes for the second block in the trio
-			if err := validateVotes(provenValSet, &second.Header, third.Header.HCC.Votes); err != nil {
-				return nil, fmt.Errorf("Failed to validate voteSet, %v", err)
-			}
-			provenValSet, err = getValidatorSetFromVCPProof(first.Header.StateHash, &first.Proof)
+			updatedValSet, err := getValidatorSetFromVCPProof(first.Header.StateHash, &first.Proof)
 			if err != nil {
 				return nil, fmt.Errorf("Failed to retrieve validator set from VCP proof: %v", err)
 			}
+
+			// third.Header.HCC.Votes contains the votes for the second block in the trio. The voters
+			// of a compact HCC are relative to the validator set of the third block, i.e. the
+			// validator set updated by the first block.
+			if err := third.Header.HCC.ResolveVotes(updatedValSet); err != nil {
+				return nil, fmt.Errorf("Failed to resolve HCC voters, %v", err)
+			}
+			if err := validateVotes(provenValSet, &second.Header, third.Header.HCC.Votes); err != nil {
+				return nil, fmt.Errorf("Failed to validate voteSet, %v", err)
+			}
+			provenValSet = updatedValSet
 		}
 	}
 
@@ -0,0 +1,170 @@
+package statesync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/trie"
+)
+
+// ChunkRequest asks a peer for the entries of a trie within the key range [Start, End).
+type ChunkRequest struct {
+	ID         uint64
+	Height     uint64      // Height of the state, which decides the trie hash algorithm
+	Root       common.Hash // Root of the trie to stream
+	Start      common.Bytes
+	End        common.Bytes // Exclusive, empty for no upper bound
+	MaxEntries uint64
+}
+
+// ChunkResponse carries consecutive trie entries along with the trie nodes proving them.
+type ChunkResponse struct {
+	ID     uint64
+	Root   common.Hash
+	Keys   []common.Bytes
+	Values []common.Bytes
+	Proof  []common.Bytes // Encoded trie nodes on the paths from the root to the entries
+	Next   common.Bytes   // Start of the next chunk, empty if the range is exhausted
+}
+
+// proofSet is an in-memory set of trie nodes keyed by their hashes.
+type proofSet struct {
+	nodes map[string]common.Bytes
+}
+
+func newProofSet() *proofSet {
+	return &proofSet{nodes: make(map[string]common.Bytes)}
+}
+
+func (ps *proofSet) Put(key []byte, value []byte) error {
+	ps.nodes[string(key)] = common.CopyBytes(value)
+	return nil
+}
+
+func (ps *proofSet) Get(key []byte) ([]byte, error) {
+	value, ok := ps.nodes[string(key)]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return value, nil
+}
+
+func (ps *proofSet) Has(key []byte) (bool, error) {
+	_, ok := ps.nodes[string(key)]
+	return ok, nil
+}
+
+// list returns the encoded nodes ordered by hash.
+func (ps *proofSet) list() []common.Bytes {
+	keys := make([]string, 0, len(ps.nodes))
+	for key := range ps.nodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ret := make([]common.Bytes, 0, len(keys))
+	for _, key := range keys {
+		ret = append(ret, ps.nodes[key])
+	}
+	return ret
+}
+
+// ServeChunk collects the entries requested from the trie stored in db. At most maxEntries
+// entries are returned regardless of the request.
+func ServeChunk(db database.Database, req *ChunkRequest, maxEntries uint64) (*ChunkResponse, error) {
+	algorithm := crypto.HasherAtHeight(req.Height).Algorithm()
+	tr, err := trie.NewWithHashAlgorithm(req.Root, trie.NewDatabase(db), algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := maxEntries
+	if req.MaxEntries > 0 && req.MaxEntries < limit {
+		limit = req.MaxEntries
+	}
+
+	resp := &ChunkResponse{
+		ID:   req.ID,
+		Root: req.Root,
+	}
+	proof := newProofSet()
+	it := trie.NewIterator(tr.NodeIterator(req.Start))
+	for it.Next() {
+		if bytes.Compare(it.Key, req.Start) < 0 {
+			continue
+		}
+		if len(req.End) > 0 && bytes.Compare(it.Key, req.End) >= 0 {
+			break
+		}
+		if uint64(len(resp.Keys)) >= limit {
+			resp.Next = common.CopyBytes(it.Key)
+			break
+		}
+		key := common.CopyBytes(it.Key)
+		resp.Keys = append(resp.Keys, key)
+		resp.Values = append(resp.Values, common.CopyBytes(it.Value))
+		if err := tr.Prove(key, 0, proof); err != nil {
+			return nil, err
+		}
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	resp.Proof = proof.list()
+	return resp, nil
+}
+
+// VerifyChunk checks that every entry in the response is proven to be in the requested trie
+// and within the requested range.
+func VerifyChunk(req *ChunkRequest, resp *ChunkResponse) error {
+	if resp.ID != req.ID || resp.Root != req.Root {
+		return fmt.Errorf("Chunk response %v/%v does not match request %v/%v",
+			resp.ID, resp.Root.Hex(), req.ID, req.Root.Hex())
+	}
+	if len(resp.Keys) != len(resp.Values) {
+		return fmt.Errorf("Chunk has %v keys but %v values", len(resp.Keys), len(resp.Values))
+	}
+	if req.MaxEntries > 0 && uint64(len(resp.Keys)) > req.MaxEntries {
+		return fmt.Errorf("Chunk has %v entries, at most %v requested", len(resp.Keys), req.MaxEntries)
+	}
+
+	hasher := crypto.HasherAtHeight(req.Height)
+	proof := newProofSet()
+	for _, enc := range resp.Proof {
+		hash := hasher.Hash(enc)
+		proof.Put(hash[:], enc)
+	}
+
+	prev := req.Start
+	for i, key := range resp.Keys {
+		if (i == 0 && bytes.Compare(key, prev) < 0) || (i > 0 && bytes.Compare(key, prev) <= 0) {
+			return fmt.Errorf("Chunk key %x out of order", key)
+		}
+		if len(req.End) > 0 && bytes.Compare(key, req.End) >= 0 {
+			return fmt.Errorf("Chunk key %x out of range", key)
+		}
+		value, _, err := trie.VerifyProof(req.Root, key, proof)
+		if err != nil {
+			return fmt.Errorf("Invalid proof for chunk key %x: %v", key, err)
+		}
+		if !bytes.Equal(value, resp.Values[i]) {
+			return fmt.Errorf("Chunk value for key %x does not match proof", key)
+		}
+		prev = key
+	}
+
+	if len(resp.Next) > 0 {
+		if bytes.Compare(resp.Next, prev) <= 0 {
+			return fmt.Errorf("Chunk next key %x out of order", resp.Next)
+		}
+		if len(req.End) > 0 && bytes.Compare(resp.Next, req.End) >= 0 {
+			return fmt.Errorf("Chunk next key %x out of range", resp.Next)
+		}
+	}
+	return nil
+}
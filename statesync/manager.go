@@ -0,0 +1,252 @@
+package statesync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/p2p"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rlp"
+	"github.com/thetatoken/theta/store/database"
+)
+
+var logger *log.Entry = util.GetLoggerForModule("statesync")
+
+var ErrSyncInProgress = errors.New("State sync already in progress")
+
+type MessageIDEnum uint8
+
+const (
+	MessageIDChunkRequest MessageIDEnum = iota
+	MessageIDChunkResponse
+)
+
+var _ p2p.MessageHandler = (*StateSyncManager)(nil)
+var _ Transport = (*StateSyncManager)(nil)
+
+// StateSyncManager serves state trie chunks to peers on the state channel, and drives the
+// Syncer when the local node reconstructs the state from its peers.
+type StateSyncManager struct {
+	db        database.Database
+	network   p2p.Network
+	chunkSize uint64
+	timeout   time.Duration
+
+	mu     sync.Mutex
+	syncer *Syncer
+
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+
+	incoming chan p2ptypes.Message
+
+	logger *log.Entry
+}
+
+// NewStateSyncManager creates an instance of StateSyncManager serving the state stored in db.
+func NewStateSyncManager(db database.Database, network p2p.Network) *StateSyncManager {
+	m := &StateSyncManager{
+		db:        db,
+		network:   network,
+		chunkSize: uint64(viper.GetInt(common.CfgStateSyncChunkSize)),
+		timeout:   time.Duration(viper.GetInt(common.CfgStateSyncRequestTimeout)) * time.Second,
+
+		wg:       &sync.WaitGroup{},
+		incoming: make(chan p2ptypes.Message, viper.GetInt(common.CfgSyncMessageQueueSize)),
+	}
+	network.RegisterMessageHandler(m)
+
+	logger = util.GetLoggerForModule("statesync")
+	m.logger = logger
+
+	return m
+}
+
+func (m *StateSyncManager) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	m.ctx = c
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go m.mainLoop()
+}
+
+func (m *StateSyncManager) Stop() {
+	m.cancel()
+}
+
+func (m *StateSyncManager) Wait() {
+	m.wg.Wait()
+}
+
+func (m *StateSyncManager) mainLoop() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			m.stopped = true
+			return
+		case msg := <-m.incoming:
+			m.processMessage(msg)
+		}
+	}
+}
+
+// SyncState reconstructs the state with the given root at the given height from the given
+// peers. It blocks until the state is fully downloaded and verified.
+func (m *StateSyncManager) SyncState(ctx context.Context, height uint64, root common.Hash, peerIDs []string) error {
+	m.mu.Lock()
+	if m.syncer != nil {
+		m.mu.Unlock()
+		return ErrSyncInProgress
+	}
+	syncer := NewSyncer(m.db, m, height, root, peerIDs, m.chunkSize, m.timeout)
+	m.syncer = syncer
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		m.syncer = nil
+		m.mu.Unlock()
+	}()
+
+	m.logger.WithFields(log.Fields{
+		"height": height,
+		"root":   root.Hex(),
+		"peers":  peerIDs,
+	}).Info("Starting state sync")
+
+	return syncer.Sync(ctx)
+}
+
+// SendChunkRequest implements the Transport interface.
+func (m *StateSyncManager) SendChunkRequest(peerID string, req *ChunkRequest) {
+	message := p2ptypes.Message{
+		ChannelID: common.ChannelIDState,
+		Content:   *req,
+	}
+	go m.network.Send(peerID, message)
+}
+
+// GetChannelIDs implements the p2p.MessageHandler interface.
+func (m *StateSyncManager) GetChannelIDs() []common.ChannelIDEnum {
+	return []common.ChannelIDEnum{
+		common.ChannelIDState,
+	}
+}
+
+// ParseMessage implements p2p.MessageHandler interface.
+func (m *StateSyncManager) ParseMessage(peerID string, channelID common.ChannelIDEnum,
+	rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
+	message := p2ptypes.Message{
+		PeerID:    peerID,
+		ChannelID: channelID,
+	}
+	data, err := decodeMessage(rawMessageBytes)
+	message.Content = data
+	return message, err
+}
+
+// EncodeMessage implements p2p.MessageHandler interface.
+func (m *StateSyncManager) EncodeMessage(message interface{}) (common.Bytes, error) {
+	return encodeMessage(message)
+}
+
+// HandleMessage implements p2p.MessageHandler interface.
+func (m *StateSyncManager) HandleMessage(msg p2ptypes.Message) (err error) {
+	m.incoming <- msg
+	return
+}
+
+func (m *StateSyncManager) processMessage(message p2ptypes.Message) {
+	switch content := message.Content.(type) {
+	case ChunkRequest:
+		m.handleChunkRequest(message.PeerID, &content)
+	case ChunkResponse:
+		m.handleChunkResponse(message.PeerID, &content)
+	default:
+		m.logger.WithFields(log.Fields{
+			"message": message,
+		}).Error("Received unknown message")
+	}
+}
+
+func (m *StateSyncManager) handleChunkRequest(peerID string, req *ChunkRequest) {
+	resp, err := ServeChunk(m.db, req, m.chunkSize)
+	if err != nil {
+		m.logger.WithFields(log.Fields{
+			"peer": peerID,
+			"root": req.Root.Hex(),
+			"err":  err,
+		}).Debug("Failed to serve state chunk")
+		return
+	}
+	message := p2ptypes.Message{
+		ChannelID: common.ChannelIDState,
+		Content:   *resp,
+	}
+	go m.network.Send(peerID, message)
+}
+
+func (m *StateSyncManager) handleChunkResponse(peerID string, resp *ChunkResponse) {
+	m.mu.Lock()
+	syncer := m.syncer
+	m.mu.Unlock()
+
+	if syncer == nil {
+		return
+	}
+	syncer.HandleResponse(peerID, resp)
+}
+
+func encodeMessage(message interface{}) (common.Bytes, error) {
+	var buf bytes.Buffer
+	var msgID MessageIDEnum
+	switch message.(type) {
+	case ChunkRequest:
+		msgID = MessageIDChunkRequest
+	case ChunkResponse:
+		msgID = MessageIDChunkResponse
+	default:
+		return nil, errors.New("Unsupported message type")
+	}
+	err := rlp.Encode(&buf, msgID)
+	if err != nil {
+		return nil, err
+	}
+	err = rlp.Encode(&buf, message)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeMessage(raw common.Bytes) (interface{}, error) {
+	var msgID MessageIDEnum
+	err := rlp.DecodeBytes(raw[:1], &msgID)
+	if err != nil {
+		return nil, err
+	}
+	if msgID == MessageIDChunkRequest {
+		data := ChunkRequest{}
+		err = rlp.DecodeBytes(raw[1:], &data)
+		return data, err
+	} else if msgID == MessageIDChunkResponse {
+		data := ChunkResponse{}
+		err = rlp.DecodeBytes(raw[1:], &data)
+		return data, err
+	} else {
+		return nil, fmt.Errorf("Unknown message ID: %v", msgID)
+	}
+}
@@ -0,0 +1,122 @@
+package statesync
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+// loopbackTransport serves chunk requests from a local database.
+type loopbackTransport struct {
+	db       database.Database
+	syncer   *Syncer
+	badPeers map[string]bool
+}
+
+func (lt *loopbackTransport) SendChunkRequest(peerID string, req *ChunkRequest) {
+	resp, err := ServeChunk(lt.db, req, 100)
+	if err != nil {
+		return
+	}
+	if lt.badPeers[peerID] && len(resp.Values) > 0 {
+		resp.Values[0] = common.Bytes("tampered")
+	}
+	go lt.syncer.HandleResponse(peerID, resp)
+}
+
+func newTestState(numAccounts int) (database.Database, uint64, common.Hash) {
+	db := backend.NewMemDatabase()
+	height := uint64(10)
+	sv := state.NewStoreView(height, common.Hash{}, db)
+	for i := 0; i < numAccounts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		acc := sv.GetOrCreateAccount(addr)
+		acc.Balance.ThetaWei = big.NewInt(int64(i * 100))
+		sv.SetAccount(addr, acc)
+	}
+	contract := common.BigToAddress(big.NewInt(1))
+	for i := 0; i < 20; i++ {
+		sv.SetState(contract, common.BigToHash(big.NewInt(int64(i))), common.BigToHash(big.NewInt(int64(i+1))))
+	}
+	sv.Set(common.Bytes("other/key"), common.Bytes("other value"))
+	return db, height, sv.Save()
+}
+
+func TestServeAndVerifyChunk(t *testing.T) {
+	assert := assert.New(t)
+
+	db, height, root := newTestState(30)
+
+	req := &ChunkRequest{ID: 1, Height: height, Root: root, MaxEntries: 8}
+	resp, err := ServeChunk(db, req, 100)
+	assert.Nil(err)
+	assert.Equal(8, len(resp.Keys))
+	assert.NotEmpty(resp.Next)
+	assert.Nil(VerifyChunk(req, resp))
+
+	// The next chunk continues where the previous one stopped.
+	req2 := &ChunkRequest{ID: 2, Height: height, Root: root, Start: resp.Next, MaxEntries: 8}
+	resp2, err := ServeChunk(db, req2, 100)
+	assert.Nil(err)
+	assert.Nil(VerifyChunk(req2, resp2))
+	assert.Equal(resp.Next, resp2.Keys[0])
+
+	// Tampered values are detected.
+	resp.Values[3] = common.Bytes("tampered")
+	assert.NotNil(VerifyChunk(req, resp))
+
+	// Entries outside of the requested range are detected.
+	req3 := &ChunkRequest{ID: 3, Height: height, Root: root, End: resp2.Keys[0], MaxEntries: 8}
+	assert.NotNil(VerifyChunk(req3, resp2))
+}
+
+func TestSyncState(t *testing.T) {
+	assert := assert.New(t)
+
+	srcDB, height, root := newTestState(50)
+	dstDB := backend.NewMemDatabase()
+
+	transport := &loopbackTransport{db: srcDB, badPeers: map[string]bool{"peer2": true}}
+	peers := []string{}
+	for i := 0; i < 4; i++ {
+		peers = append(peers, "peer"+strconv.Itoa(i))
+	}
+	syncer := NewSyncer(dstDB, transport, height, root, peers, 5, time.Second)
+	transport.syncer = syncer
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.Nil(syncer.Sync(ctx))
+
+	sv := state.NewStoreView(height, root, dstDB)
+	assert.NotNil(sv)
+	for i := 0; i < 50; i++ {
+		acc := sv.GetAccount(common.BigToAddress(big.NewInt(int64(i + 1))))
+		assert.NotNil(acc)
+		assert.Equal(int64(i*100), acc.Balance.ThetaWei.Int64())
+	}
+	contract := common.BigToAddress(big.NewInt(1))
+	for i := 0; i < 20; i++ {
+		assert.Equal(common.BigToHash(big.NewInt(int64(i+1))), sv.GetState(contract, common.BigToHash(big.NewInt(int64(i)))))
+	}
+	assert.Equal(common.Bytes("other value"), sv.Get(common.Bytes("other/key")))
+}
+
+func TestSyncStateNoPeer(t *testing.T) {
+	assert := assert.New(t)
+
+	srcDB, height, root := newTestState(10)
+	transport := &loopbackTransport{db: srcDB, badPeers: map[string]bool{"peer0": true}}
+	syncer := NewSyncer(backend.NewMemDatabase(), transport, height, root, []string{"peer0"}, 5, time.Second)
+	transport.syncer = syncer
+
+	assert.Equal(ErrNoPeerAvailable, syncer.Sync(context.Background()))
+}
@@ -0,0 +1,267 @@
+package statesync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/treestore"
+)
+
+// maxPartitions is the max number of key ranges the state trie is split into up front so
+// that chunks can be downloaded from multiple peers in parallel.
+const maxPartitions = 16
+
+var ErrNoPeerAvailable = errors.New("No peer available for state sync")
+
+// Transport sends chunk requests to peers.
+type Transport interface {
+	SendChunkRequest(peerID string, req *ChunkRequest)
+}
+
+type peerResponse struct {
+	peerID string
+	resp   *ChunkResponse
+}
+
+// task is a key range of a trie yet to be downloaded.
+type task struct {
+	root   common.Hash
+	start  common.Bytes
+	end    common.Bytes
+	req    *ChunkRequest
+	peerID string
+	sentAt time.Time
+}
+
+// Syncer reconstructs the state at a given height and root from chunks streamed by peers.
+// Tries are split into key ranges which are downloaded from multiple peers in parallel.
+// Each chunk is verified against the trie root on arrival, and the root of every
+// reconstructed trie is checked once all its chunks have been received.
+type Syncer struct {
+	logger *log.Entry
+
+	db        database.Database
+	transport Transport
+	height    uint64
+	root      common.Hash
+	algorithm crypto.HashAlgorithm
+	chunkSize uint64
+	timeout   time.Duration
+
+	peers     []string
+	busyPeers map[string]bool
+	badPeers  map[string]bool
+
+	nextID   uint64
+	pending  []*task
+	inflight map[uint64]*task
+	tries    map[common.Hash]*treestore.TreeStore
+
+	responses chan peerResponse
+}
+
+// NewSyncer creates an instance of Syncer. The reconstructed state is written to db.
+func NewSyncer(db database.Database, transport Transport, height uint64, root common.Hash,
+	peers []string, chunkSize uint64, timeout time.Duration) *Syncer {
+	s := &Syncer{
+		logger:    logger,
+		db:        db,
+		transport: transport,
+		height:    height,
+		root:      root,
+		algorithm: crypto.HasherAtHeight(height).Algorithm(),
+		chunkSize: chunkSize,
+		timeout:   timeout,
+		peers:     peers,
+		busyPeers: make(map[string]bool),
+		badPeers:  make(map[string]bool),
+		inflight:  make(map[uint64]*task),
+		tries:     make(map[common.Hash]*treestore.TreeStore),
+		responses: make(chan peerResponse, len(peers)+1),
+	}
+
+	numPartitions := len(peers)
+	if numPartitions > maxPartitions {
+		numPartitions = maxPartitions
+	}
+	if numPartitions < 1 {
+		numPartitions = 1
+	}
+	s.addTrie(root, numPartitions)
+
+	return s
+}
+
+// addTrie schedules the download of the trie with the given root, split into the given
+// number of key ranges by the first key byte.
+func (s *Syncer) addTrie(root common.Hash, numPartitions int) {
+	if root.IsEmpty() {
+		return
+	}
+	if _, ok := s.tries[root]; ok {
+		return // Tries with the same root only need to be downloaded once.
+	}
+	s.tries[root] = treestore.NewTreeStoreWithHashAlgorithm(common.Hash{}, s.db, s.algorithm)
+
+	var start common.Bytes
+	for i := 1; i <= numPartitions; i++ {
+		var end common.Bytes
+		if i < numPartitions {
+			end = common.Bytes{byte(i * 256 / numPartitions)}
+		}
+		s.pending = append(s.pending, &task{root: root, start: start, end: end})
+		start = end
+	}
+}
+
+// HandleResponse passes a chunk response received from a peer to the syncer. Responses that
+// cannot be queued are dropped, and the corresponding requests are retried after timeout.
+func (s *Syncer) HandleResponse(peerID string, resp *ChunkResponse) {
+	select {
+	case s.responses <- peerResponse{peerID: peerID, resp: resp}:
+	default:
+		s.logger.WithFields(log.Fields{"peer": peerID, "id": resp.ID}).Debug("Dropping state chunk")
+	}
+}
+
+// Sync downloads the state and blocks until all the tries are reconstructed and verified.
+func (s *Syncer) Sync(ctx context.Context) error {
+	ticker := time.NewTicker(s.timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		if len(s.pending) == 0 && len(s.inflight) == 0 {
+			return s.commit()
+		}
+		if err := s.assignTasks(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case pr := <-s.responses:
+			s.processResponse(pr.peerID, pr.resp)
+		case <-ticker.C:
+			s.checkTimeouts()
+		}
+	}
+}
+
+// assignTasks sends pending tasks to idle peers, one request in flight per peer.
+func (s *Syncer) assignTasks() error {
+	available := 0
+	for _, peerID := range s.peers {
+		if s.badPeers[peerID] {
+			continue
+		}
+		available++
+		if s.busyPeers[peerID] || len(s.pending) == 0 {
+			continue
+		}
+		t := s.pending[0]
+		s.pending = s.pending[1:]
+
+		s.nextID++
+		t.req = &ChunkRequest{
+			ID:         s.nextID,
+			Height:     s.height,
+			Root:       t.root,
+			Start:      t.start,
+			End:        t.end,
+			MaxEntries: s.chunkSize,
+		}
+		t.peerID = peerID
+		t.sentAt = time.Now()
+		s.inflight[t.req.ID] = t
+		s.busyPeers[peerID] = true
+		s.transport.SendChunkRequest(peerID, t.req)
+	}
+	if available == 0 {
+		return ErrNoPeerAvailable
+	}
+	return nil
+}
+
+func (s *Syncer) processResponse(peerID string, resp *ChunkResponse) {
+	t, ok := s.inflight[resp.ID]
+	if !ok || t.peerID != peerID {
+		s.logger.WithFields(log.Fields{"peer": peerID, "id": resp.ID}).Debug("Ignoring unexpected state chunk")
+		return
+	}
+	delete(s.inflight, resp.ID)
+	s.busyPeers[peerID] = false
+
+	if err := VerifyChunk(t.req, resp); err != nil {
+		s.logger.WithFields(log.Fields{"peer": peerID, "err": err}).Warn("Received invalid state chunk, dropping peer")
+		s.badPeers[peerID] = true
+		s.pending = append(s.pending, t)
+		return
+	}
+
+	tr := s.tries[t.root]
+	for i, key := range resp.Keys {
+		tr.Update(key, resp.Values[i])
+		if t.root == s.root {
+			s.addStorageTrie(key, resp.Values[i])
+		}
+	}
+	if len(resp.Next) > 0 {
+		s.pending = append(s.pending, &task{root: t.root, start: resp.Next, end: t.end})
+	}
+}
+
+// addStorageTrie schedules the download of the storage trie of a smart contract account.
+func (s *Syncer) addStorageTrie(key common.Bytes, value common.Bytes) {
+	if !bytes.HasPrefix(key, state.AccountKeyPrefix()) {
+		return
+	}
+	acc := &types.Account{}
+	if err := types.FromBytes(value, acc); err != nil {
+		s.logger.WithFields(log.Fields{"key": key, "err": err}).Warn("Failed to decode account")
+		return
+	}
+	s.addTrie(acc.Root, 1)
+}
+
+// checkTimeouts reschedules the requests that peers failed to respond in time.
+func (s *Syncer) checkTimeouts() {
+	now := time.Now()
+	for id, t := range s.inflight {
+		if now.Sub(t.sentAt) < s.timeout {
+			continue
+		}
+		s.logger.WithFields(log.Fields{"peer": t.peerID, "id": id}).Debug("State chunk request timed out")
+		delete(s.inflight, id)
+		s.busyPeers[t.peerID] = false
+		s.pending = append(s.pending, t)
+	}
+}
+
+// commit writes the reconstructed tries to the database and checks their roots.
+func (s *Syncer) commit() error {
+	for root, tr := range s.tries {
+		h, err := tr.Commit()
+		if err != nil {
+			return err
+		}
+		if h != root {
+			return fmt.Errorf("Reconstructed trie root mismatch: %v vs %v", h.Hex(), root.Hex())
+		}
+	}
+	s.logger.WithFields(log.Fields{
+		"height": s.height,
+		"root":   s.root.Hex(),
+		"tries":  len(s.tries),
+	}).Info("State sync completed")
+	return nil
+}
@@ -0,0 +1,267 @@
+package stats
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/store"
+)
+
+var logger *log.Entry = util.GetLoggerForModule("stats")
+
+const (
+	// MaxWindow is the max number of blocks a summary can be computed over.
+	MaxWindow = 100000
+
+	// MaxDays is the max number of days active addresses can be queried for.
+	MaxDays = 90
+)
+
+// FinalizedBlockSource provides the last finalized block.
+type FinalizedBlockSource interface {
+	GetLastFinalizedBlock() *core.ExtendedBlock
+}
+
+// Collector incrementally computes statistics of the finalized blocks and persists them,
+// so that queries only read the per-block summaries instead of re-scanning the chain.
+type Collector struct {
+	logger *log.Entry
+
+	mu        sync.Mutex
+	db        store.Store
+	chain     *blockchain.Chain
+	finalized FinalizedBlockSource
+	interval  time.Duration
+	backfill  uint64
+
+	cursor      uint64 // Height of the last processed block
+	hasCursor   bool
+	currentDay  *DailyActives
+	activeToday map[common.Address]bool
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// NewCollector creates an instance of Collector.
+func NewCollector(db store.Store, chain *blockchain.Chain, finalized FinalizedBlockSource) *Collector {
+	c := &Collector{
+		db:        db,
+		chain:     chain,
+		finalized: finalized,
+		interval:  time.Duration(viper.GetInt(common.CfgStatsInterval)) * time.Millisecond,
+		backfill:  uint64(viper.GetInt(common.CfgStatsBackfillBlocks)),
+		wg:        &sync.WaitGroup{},
+	}
+
+	logger = util.GetLoggerForModule("stats")
+	c.logger = logger
+
+	var cursor uint64
+	if err := c.db.Get(cursorKey(), &cursor); err == nil {
+		c.cursor = cursor
+		c.hasCursor = true
+	}
+
+	return c
+}
+
+// Start starts the collecting loop.
+func (c *Collector) Start(ctx context.Context) {
+	cc, cancel := context.WithCancel(ctx)
+	c.ctx = cc
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.mainLoop()
+}
+
+// Stop notifies the collecting loop to stop without blocking.
+func (c *Collector) Stop() {
+	c.cancel()
+}
+
+// Wait blocks until the collecting loop stops.
+func (c *Collector) Wait() {
+	c.wg.Wait()
+}
+
+func (c *Collector) mainLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			c.stopped = true
+			return
+		case <-ticker.C:
+			c.Update()
+		}
+	}
+}
+
+// Update processes the blocks finalized since the last update.
+func (c *Collector) Update() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lfb := c.finalized.GetLastFinalizedBlock()
+	if lfb == nil {
+		return
+	}
+	if !c.hasCursor {
+		// Only backfill the recent history on first start.
+		if lfb.Height > c.backfill {
+			c.cursor = lfb.Height - c.backfill
+		}
+		c.hasCursor = true
+	}
+
+	for height := c.cursor + 1; height <= lfb.Height; height++ {
+		if c.ctx != nil && c.ctx.Err() != nil {
+			return
+		}
+		block := c.findFinalizedBlock(height)
+		if block == nil {
+			c.logger.WithFields(log.Fields{"height": height}).Debug("Finalized block not found")
+		} else if err := c.processBlock(block); err != nil {
+			c.logger.WithFields(log.Fields{"height": height, "err": err}).Error("Failed to process block")
+			return
+		}
+		c.cursor = height
+		if err := c.db.Put(cursorKey(), c.cursor); err != nil {
+			c.logger.WithFields(log.Fields{"err": err}).Error("Failed to save stats cursor")
+			return
+		}
+	}
+}
+
+func (c *Collector) findFinalizedBlock(height uint64) *core.ExtendedBlock {
+	for _, b := range c.chain.FindBlocksByHeight(height) {
+		if b.Status.IsFinalized() {
+			return b
+		}
+	}
+	return nil
+}
+
+func (c *Collector) processBlock(block *core.ExtendedBlock) error {
+	receipts := c.chain.FindBlockReceipts(block.Hash())
+	bs, addresses := CalculateBlockStats(block.Block, receipts)
+	if err := c.db.Put(blockStatsKey(bs.Height), bs); err != nil {
+		return err
+	}
+	return c.addActiveAddresses(dayOf(bs.Timestamp), addresses)
+}
+
+func (c *Collector) addActiveAddresses(day uint64, addresses map[common.Address]bool) error {
+	if c.currentDay == nil || c.currentDay.Day != day {
+		c.currentDay = c.loadDailyActives(day)
+		c.activeToday = make(map[common.Address]bool)
+		for _, addr := range c.currentDay.Addresses {
+			c.activeToday[addr] = true
+		}
+	}
+	updated := false
+	for addr := range addresses {
+		if !c.activeToday[addr] {
+			c.activeToday[addr] = true
+			c.currentDay.Addresses = append(c.currentDay.Addresses, addr)
+			updated = true
+		}
+	}
+	if !updated {
+		return nil
+	}
+	return c.db.Put(dailyActivesKey(day), c.currentDay)
+}
+
+func (c *Collector) loadDailyActives(day uint64) *DailyActives {
+	da := &DailyActives{}
+	if err := c.db.Get(dailyActivesKey(day), da); err != nil {
+		return &DailyActives{Day: day, Addresses: []common.Address{}}
+	}
+	return da
+}
+
+// GetSummary returns the rolling metrics over the last window finalized blocks processed.
+func (c *Collector) GetSummary(window uint64) *Summary {
+	if window > MaxWindow {
+		window = MaxWindow
+	}
+
+	c.mu.Lock()
+	to := c.cursor
+	c.mu.Unlock()
+
+	from := uint64(1)
+	if to > window {
+		from = to - window + 1
+	}
+	blocks := []*BlockStats{}
+	for height := from; height <= to; height++ {
+		bs := &BlockStats{}
+		if err := c.db.Get(blockStatsKey(height), bs); err != nil {
+			continue
+		}
+		blocks = append(blocks, bs)
+	}
+	return Summarize(blocks)
+}
+
+// GetDailyActiveAddresses returns the number of active addresses on each of the last days,
+// the latest day first.
+func (c *Collector) GetDailyActiveAddresses(numDays int) []DailyActiveCount {
+	if numDays > MaxDays {
+		numDays = MaxDays
+	}
+
+	c.mu.Lock()
+	var today uint64
+	if c.currentDay != nil {
+		today = c.currentDay.Day
+	} else {
+		today = dayOf(uint64(time.Now().Unix()))
+	}
+	c.mu.Unlock()
+
+	ret := []DailyActiveCount{}
+	for i := 0; i < numDays && uint64(i) <= today; i++ {
+		day := today - uint64(i)
+		ret = append(ret, DailyActiveCount{
+			Day:   time.Unix(int64(day*secondsPerDay), 0).UTC().Format("2006-01-02"),
+			Count: len(c.loadDailyActives(day).Addresses),
+		})
+	}
+	return ret
+}
+
+func cursorKey() common.Bytes {
+	return common.Bytes("stats/cursor")
+}
+
+func blockStatsKey(height uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, height)
+	return append(common.Bytes("stats/block/"), buf...)
+}
+
+func dailyActivesKey(day uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, day)
+	return append(common.Bytes("stats/daily/"), buf...)
+}
@@ -0,0 +1,196 @@
+package stats
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// secondsPerDay is the length of the days active addresses are counted over (UTC).
+const secondsPerDay = 24 * 3600
+
+// BlockStats is the per-block summary persisted for each finalized block, from which the
+// rolling metrics are computed without re-parsing the transactions.
+type BlockStats struct {
+	Height       uint64
+	Timestamp    uint64
+	NumTxs       uint64     // Regular transactions, i.e. excluding coinbase and slash
+	Fees         []*big.Int // Fee in TFuelWei of each regular transaction, sorted
+	GasUsed      uint64
+	NumAddresses uint64 // Distinct addresses involved in the block
+}
+
+// DailyActives is the set of addresses active on a day.
+type DailyActives struct {
+	Day       uint64 // Days since the Unix epoch
+	Addresses []common.Address
+}
+
+// Summary contains the rolling metrics over a window of finalized blocks.
+type Summary struct {
+	FromHeight       common.JSONUint64 `json:"from_height"`
+	ToHeight         common.JSONUint64 `json:"to_height"`
+	NumBlocks        common.JSONUint64 `json:"num_blocks"`
+	NumTxs           common.JSONUint64 `json:"num_txs"`
+	TPS              float64           `json:"tps"`
+	AvgFee           *common.JSONBig   `json:"avg_fee"`
+	MedianFee        *common.JSONBig   `json:"median_fee"`
+	GasUsed          common.JSONUint64 `json:"gas_used"`
+	AvgGasPerBlock   common.JSONUint64 `json:"avg_gas_per_block"`
+	BlockUtilization float64           `json:"block_utilization"` // Fraction of regular tx slots used
+}
+
+// DailyActiveCount is the number of addresses active on a day.
+type DailyActiveCount struct {
+	Day   string `json:"day"` // YYYY-MM-DD in UTC
+	Count int    `json:"count"`
+}
+
+// dayOf returns the day since the Unix epoch the given timestamp falls on.
+func dayOf(timestamp uint64) uint64 {
+	return timestamp / secondsPerDay
+}
+
+// CalculateBlockStats extracts the statistics of the given block. Receipts are used for the
+// actual gas consumed by smart contract transactions.
+func CalculateBlockStats(block *core.Block, receipts []*types.Receipt) (*BlockStats, map[common.Address]bool) {
+	gasUsed := make(map[common.Hash]uint64)
+	for _, receipt := range receipts {
+		gasUsed[receipt.TxHash] = receipt.GasUsed
+	}
+
+	bs := &BlockStats{
+		Height: block.Height,
+		Fees:   []*big.Int{},
+	}
+	if block.Timestamp != nil {
+		bs.Timestamp = block.Timestamp.Uint64()
+	}
+	addresses := make(map[common.Address]bool)
+	for _, rawTx := range block.Txs {
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			continue
+		}
+		switch tx.(type) {
+		case *types.CoinbaseTx, *types.SlashTx:
+			continue
+		}
+		bs.NumTxs++
+		for _, addr := range types.GetTxAddresses(tx) {
+			if !addr.IsEmpty() {
+				addresses[addr] = true
+			}
+		}
+		// The fee of a sponsored transaction is the fee of the inner transaction
+		if stx, ok := tx.(*types.SponsoredTx); ok {
+			if inner, err := stx.InnerTx(); err == nil {
+				tx = inner
+			}
+		}
+
+		fee := big.NewInt(0)
+		if sctx, ok := tx.(*types.SmartContractTx); ok {
+			gas := gasUsed[crypto.HashAtHeight(block.Height, rawTx)]
+			bs.GasUsed += gas
+			if sctx.GasPrice != nil {
+				fee = new(big.Int).Mul(sctx.GasPrice, new(big.Int).SetUint64(gas))
+			}
+		} else if coins, ok := txFee(tx); ok && coins.TFuelWei != nil {
+			fee = new(big.Int).Set(coins.TFuelWei)
+		}
+		bs.Fees = append(bs.Fees, fee)
+	}
+	sort.Slice(bs.Fees, func(i, j int) bool { return bs.Fees[i].Cmp(bs.Fees[j]) < 0 })
+	bs.NumAddresses = uint64(len(addresses))
+	return bs, addresses
+}
+
+// Summarize computes the rolling metrics over the given block stats, ordered by height.
+func Summarize(blocks []*BlockStats) *Summary {
+	s := &Summary{
+		AvgFee:    (*common.JSONBig)(big.NewInt(0)),
+		MedianFee: (*common.JSONBig)(big.NewInt(0)),
+	}
+	if len(blocks) == 0 {
+		return s
+	}
+	first, last := blocks[0], blocks[len(blocks)-1]
+	s.FromHeight = common.JSONUint64(first.Height)
+	s.ToHeight = common.JSONUint64(last.Height)
+	s.NumBlocks = common.JSONUint64(len(blocks))
+
+	fees := []*big.Int{}
+	totalFee := big.NewInt(0)
+	for _, b := range blocks {
+		s.NumTxs += common.JSONUint64(b.NumTxs)
+		s.GasUsed += common.JSONUint64(b.GasUsed)
+		for _, fee := range b.Fees {
+			fees = append(fees, fee)
+			totalFee.Add(totalFee, fee)
+		}
+	}
+	if last.Timestamp > first.Timestamp {
+		// The txs in the first block were collected before its timestamp.
+		s.TPS = float64(s.NumTxs-common.JSONUint64(first.NumTxs)) / float64(last.Timestamp-first.Timestamp)
+	}
+	if len(fees) > 0 {
+		sort.Slice(fees, func(i, j int) bool { return fees[i].Cmp(fees[j]) < 0 })
+		s.AvgFee = (*common.JSONBig)(totalFee.Div(totalFee, big.NewInt(int64(len(fees)))))
+		s.MedianFee = (*common.JSONBig)(new(big.Int).Set(fees[len(fees)/2]))
+	}
+	s.AvgGasPerBlock = s.GasUsed / s.NumBlocks
+	s.BlockUtilization = float64(s.NumTxs) / float64(uint64(s.NumBlocks)*uint64(core.MaxNumRegularTxsPerBlock))
+	return s
+}
+
+// txFee returns the fee of a non smart contract transaction.
+func txFee(tx types.Tx) (types.Coins, bool) {
+	switch tx := tx.(type) {
+	case *types.SendTx:
+		return tx.Fee, true
+	case *types.ReserveFundTx:
+		return tx.Fee, true
+	case *types.ReleaseFundTx:
+		return tx.Fee, true
+	case *types.ServicePaymentTx:
+		return tx.Fee, true
+	case *types.SplitRuleTx:
+		return tx.Fee, true
+	case *types.DepositStakeTx:
+		return tx.Fee, true
+	case *types.WithdrawStakeTx:
+		return tx.Fee, true
+	case *types.GovernanceTx:
+		return tx.Fee, true
+	case *types.DepositStakeTxV2:
+		return tx.Fee, true
+	case *types.GovernanceProposalTx:
+		return tx.Fee, true
+	case *types.GovernanceVoteTx:
+		return tx.Fee, true
+	case *types.GovernanceTallyTx:
+		return tx.Fee, true
+	case *types.BridgeLockTx:
+		return tx.Fee, true
+	case *types.BridgeMintTx:
+		return tx.Fee, true
+	case *types.BridgeValidatorsTx:
+		return tx.Fee, true
+	case *types.TimeLockTx:
+		return tx.Fee, true
+	case *types.TimeLockClaimTx:
+		return tx.Fee, true
+	case *types.TimeLockRevokeTx:
+		return tx.Fee, true
+	case *types.DelegateTx:
+		return tx.Fee, true
+	case *types.UndelegateTx:
+		return tx.Fee, true
+	}
+	return types.Coins{}, false
+}
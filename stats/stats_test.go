@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func newSendTxBytes(from, to common.Address, fee int64) common.Bytes {
+	tx := &types.SendTx{
+		Fee: types.NewCoins(0, fee),
+		Inputs: []types.TxInput{
+			{Address: from, Coins: types.NewCoins(10, fee), Sequence: 1},
+		},
+		Outputs: []types.TxOutput{
+			{Address: to, Coins: types.NewCoins(10, 0)},
+		},
+	}
+	raw, _ := types.TxToBytes(tx)
+	return raw
+}
+
+func newTestBlock(height uint64, timestamp int64, txs ...common.Bytes) *core.Block {
+	block := core.NewBlock()
+	block.Height = height
+	block.Timestamp = big.NewInt(timestamp)
+	block.AddTxs(txs)
+	return block
+}
+
+func TestCalculateBlockStats(t *testing.T) {
+	assert := assert.New(t)
+
+	alice := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	bob := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	carol := common.HexToAddress("0x3333333333333333333333333333333333333333")
+
+	block := newTestBlock(5, 1000,
+		newSendTxBytes(alice, bob, 30),
+		newSendTxBytes(bob, carol, 10),
+		newSendTxBytes(alice, carol, 20),
+	)
+	bs, addresses := CalculateBlockStats(block, nil)
+	assert.Equal(uint64(5), bs.Height)
+	assert.Equal(uint64(1000), bs.Timestamp)
+	assert.Equal(uint64(3), bs.NumTxs)
+	assert.Equal(uint64(3), bs.NumAddresses)
+	assert.Equal(3, len(addresses))
+	assert.Equal(int64(10), bs.Fees[0].Int64())
+	assert.Equal(int64(20), bs.Fees[1].Int64())
+	assert.Equal(int64(30), bs.Fees[2].Int64())
+}
+
+func TestSummarize(t *testing.T) {
+	assert := assert.New(t)
+
+	s := Summarize([]*BlockStats{})
+	assert.Equal(common.JSONUint64(0), s.NumBlocks)
+	assert.Equal(int64(0), (*big.Int)(s.MedianFee).Int64())
+
+	blocks := []*BlockStats{
+		{Height: 1, Timestamp: 100, NumTxs: 2, Fees: []*big.Int{big.NewInt(10), big.NewInt(20)}, GasUsed: 100},
+		{Height: 2, Timestamp: 110, NumTxs: 1, Fees: []*big.Int{big.NewInt(60)}, GasUsed: 0},
+		{Height: 3, Timestamp: 120, NumTxs: 3, Fees: []*big.Int{big.NewInt(30), big.NewInt(30), big.NewInt(90)}, GasUsed: 200},
+	}
+	s = Summarize(blocks)
+	assert.Equal(common.JSONUint64(1), s.FromHeight)
+	assert.Equal(common.JSONUint64(3), s.ToHeight)
+	assert.Equal(common.JSONUint64(3), s.NumBlocks)
+	assert.Equal(common.JSONUint64(6), s.NumTxs)
+	assert.Equal(0.2, s.TPS) // 4 txs over 20 seconds
+	assert.Equal(int64(40), (*big.Int)(s.AvgFee).Int64())
+	assert.Equal(int64(30), (*big.Int)(s.MedianFee).Int64())
+	assert.Equal(common.JSONUint64(300), s.GasUsed)
+	assert.Equal(common.JSONUint64(100), s.AvgGasPerBlock)
+	assert.Equal(float64(6)/float64(3*core.MaxNumRegularTxsPerBlock), s.BlockUtilization)
+}
@@ -0,0 +1,432 @@
+package archive
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/thetatoken/theta/common/util"
+)
+
+var logger = util.GetLoggerForModule("archive")
+
+const (
+	dataFileName  = "bodies.dat"
+	indexFileName = "bodies.idx"
+
+	indexHeaderSize = 8 // base height
+	indexEntrySize  = 8 // end offset of the record in the data file
+
+	// The files are remapped once the part appended since the last mapping exceeds the threshold,
+	// the records appended in between are read from the files directly.
+	remapThreshold = 64 * 1024 * 1024
+)
+
+// ErrNotFound is returned for the heights without an archived record.
+var ErrNotFound = errors.New("Record not found in archive")
+
+//
+// Archive stores immutable records, e.g. the bodies of the finalized blocks, by height in
+// append-only flat files. The files are memory-mapped, so that the records are read from the
+// page cache without the copies of the KV store read path, which speeds up the sequential scans
+// of the explorers and the reindexing.
+//
+// The data file holds the records back to back. The index file starts with the base height, i.e.
+// the height of the first record, followed by the end offset of the record of each height. The
+// heights without a record have an empty range.
+//
+type Archive struct {
+	mu *sync.RWMutex
+
+	data  *mappedFile
+	index *mappedFile
+
+	baseHeight uint64
+	numEntries uint64
+	dataEnd    uint64 // end offset of the last record
+}
+
+// Open opens the archive in the directory, creating it if needed. The records partially
+// written before a crash are discarded.
+func Open(dir string) (*Archive, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	data, err := openMappedFile(filepath.Join(dir, dataFileName))
+	if err != nil {
+		return nil, err
+	}
+	index, err := openMappedFile(filepath.Join(dir, indexFileName))
+	if err != nil {
+		data.close()
+		return nil, err
+	}
+
+	a := &Archive{
+		mu:    &sync.RWMutex{},
+		data:  data,
+		index: index,
+	}
+	err = a.recover()
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// recover loads the base height and the number of records, and truncates the files to the last
+// complete record.
+func (a *Archive) recover() error {
+	if a.index.size < indexHeaderSize {
+		err := a.index.truncate(0)
+		if err != nil {
+			return err
+		}
+		return a.data.truncate(0)
+	}
+	header, err := a.index.read(0, indexHeaderSize)
+	if err != nil {
+		return err
+	}
+	a.baseHeight = binary.BigEndian.Uint64(header)
+
+	numEntries := uint64(a.index.size-indexHeaderSize) / indexEntrySize
+	for ; numEntries > 0; numEntries-- {
+		end, err := a.entryEnd(numEntries - 1)
+		if err != nil {
+			return err
+		}
+		if end <= uint64(a.data.size) {
+			a.numEntries = numEntries
+			a.dataEnd = end
+			break
+		}
+	}
+	if a.numEntries != uint64(a.index.size-indexHeaderSize)/indexEntrySize ||
+		a.dataEnd != uint64(a.data.size) {
+		logger.Warnf("Discarding the incomplete records of the archive after height %v", a.nextHeightUnsafe())
+	}
+	err = a.index.truncate(indexHeaderSize + int64(a.numEntries)*indexEntrySize)
+	if err != nil {
+		return err
+	}
+	return a.data.truncate(int64(a.dataEnd))
+}
+
+func (a *Archive) entryEnd(i uint64) (uint64, error) {
+	b, err := a.index.read(indexHeaderSize+int64(i)*indexEntrySize, indexEntrySize)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// NextHeight returns the height following the last archived record, 0 if the archive is empty.
+func (a *Archive) NextHeight() uint64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.nextHeightUnsafe()
+}
+
+func (a *Archive) nextHeightUnsafe() uint64 {
+	if a.index.size == 0 {
+		return 0
+	}
+	return a.baseHeight + a.numEntries
+}
+
+// Append archives the record of the height. The height needs to be above the archived heights,
+// the heights skipped are left without a record. The record is synced to the disk on return.
+func (a *Archive) Append(height uint64, record []byte) error {
+	if len(record) == 0 {
+		return errors.New("Cannot archive an empty record")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.index.size == 0 {
+		header := make([]byte, indexHeaderSize)
+		binary.BigEndian.PutUint64(header, height)
+		err := a.index.append(header)
+		if err != nil {
+			return err
+		}
+		a.baseHeight = height
+	}
+	if height < a.nextHeightUnsafe() {
+		return fmt.Errorf("Height %v is already archived, next height: %v", height, a.nextHeightUnsafe())
+	}
+
+	// The record is synced before the index entries, so that the index never points past the data
+	err := a.data.truncate(int64(a.dataEnd)) // discards the record of a failed append
+	if err != nil {
+		return err
+	}
+	err = a.data.append(record)
+	if err != nil {
+		return err
+	}
+	err = a.data.sync()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]byte, (height-a.nextHeightUnsafe()+1)*indexEntrySize)
+	for i := 0; i < len(entries)-indexEntrySize; i += indexEntrySize {
+		binary.BigEndian.PutUint64(entries[i:], a.dataEnd)
+	}
+	dataEnd := a.dataEnd + uint64(len(record))
+	binary.BigEndian.PutUint64(entries[len(entries)-indexEntrySize:], dataEnd)
+	err = a.index.append(entries)
+	if err != nil {
+		return err
+	}
+	err = a.index.sync()
+	if err != nil {
+		return err
+	}
+	a.numEntries += uint64(len(entries) / indexEntrySize)
+	a.dataEnd = dataEnd
+	return nil
+}
+
+// recordRange returns the range of the record of the height in the data file
+func (a *Archive) recordRange(height uint64) (start uint64, end uint64, err error) {
+	if a.index.size == 0 || height < a.baseHeight || height >= a.nextHeightUnsafe() {
+		return 0, 0, ErrNotFound
+	}
+	i := height - a.baseHeight
+	end, err = a.entryEnd(i)
+	if err != nil {
+		return 0, 0, err
+	}
+	if i > 0 {
+		start, err = a.entryEnd(i - 1)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if start == end {
+		return 0, 0, ErrNotFound
+	}
+	return start, end, nil
+}
+
+// Has returns whether the record of the height is archived.
+func (a *Archive) Has(height uint64) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	_, _, err := a.recordRange(height)
+	return err == nil
+}
+
+// View calls fn with the record of the height. The record may be backed by the memory mapping of
+// the archive and is only valid until fn returns, fn needs to copy the bytes it keeps.
+func (a *Archive) View(height uint64, fn func(record []byte) error) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	start, end, err := a.recordRange(height)
+	if err != nil {
+		return err
+	}
+	return a.data.view(int64(start), int64(end-start), fn)
+}
+
+// Truncate removes the records of the height and above, e.g. when the chain is rolled back.
+func (a *Archive) Truncate(height uint64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.index.size == 0 || height >= a.nextHeightUnsafe() {
+		return nil
+	}
+	if height <= a.baseHeight {
+		a.numEntries = 0
+		a.dataEnd = 0
+		err := a.index.truncate(0)
+		if err != nil {
+			return err
+		}
+		return a.data.truncate(0)
+	}
+
+	numEntries := height - a.baseHeight
+	dataEnd, err := a.entryEnd(numEntries - 1)
+	if err != nil {
+		return err
+	}
+	err = a.index.truncate(indexHeaderSize + int64(numEntries)*indexEntrySize)
+	if err != nil {
+		return err
+	}
+	a.numEntries = numEntries
+	a.dataEnd = dataEnd
+	return a.data.truncate(int64(dataEnd))
+}
+
+// Backup copies the archived records to the directory, which is opened as an archive on restore.
+// The records appended during the backup are not copied.
+func (a *Archive) Backup(dir string) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	indexSize := int64(0)
+	if a.index.size > 0 {
+		indexSize = indexHeaderSize + int64(a.numEntries)*indexEntrySize
+	}
+	err = a.index.copyTo(filepath.Join(dir, indexFileName), indexSize)
+	if err != nil {
+		return err
+	}
+	return a.data.copyTo(filepath.Join(dir, dataFileName), int64(a.dataEnd))
+}
+
+// Close unmaps and closes the files of the archive.
+func (a *Archive) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.data.close()
+	a.index.close()
+}
+
+//
+// mappedFile is an append-only file read through a memory mapping. The part appended since the
+// last mapping is read from the file.
+//
+type mappedFile struct {
+	file    *os.File
+	mapping []byte
+	size    int64
+}
+
+func openMappedFile(path string) (*mappedFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	mf := &mappedFile{
+		file: file,
+		size: info.Size(),
+	}
+	err = mf.remap()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return mf, nil
+}
+
+func (mf *mappedFile) remap() error {
+	if mf.mapping != nil {
+		err := munmap(mf.mapping)
+		if err != nil {
+			return err
+		}
+		mf.mapping = nil
+	}
+	if mf.size == 0 {
+		return nil
+	}
+	mapping, err := mmap(mf.file, mf.size)
+	if err != nil {
+		return err
+	}
+	mf.mapping = mapping
+	return nil
+}
+
+func (mf *mappedFile) append(b []byte) error {
+	_, err := mf.file.WriteAt(b, mf.size)
+	if err != nil {
+		return err
+	}
+	mf.size += int64(len(b))
+	if mf.size-int64(len(mf.mapping)) > remapThreshold {
+		return mf.remap()
+	}
+	return nil
+}
+
+func (mf *mappedFile) view(off int64, n int64, fn func([]byte) error) error {
+	if off+n <= int64(len(mf.mapping)) {
+		return fn(mf.mapping[off : off+n])
+	}
+	b, err := mf.read(off, n)
+	if err != nil {
+		return err
+	}
+	return fn(b)
+}
+
+func (mf *mappedFile) read(off int64, n int64) ([]byte, error) {
+	b := make([]byte, n)
+	if off+n <= int64(len(mf.mapping)) {
+		copy(b, mf.mapping[off:off+n])
+		return b, nil
+	}
+	_, err := mf.file.ReadAt(b, off)
+	return b, err
+}
+
+func (mf *mappedFile) truncate(size int64) error {
+	if size == mf.size {
+		return nil
+	}
+	if mf.mapping != nil {
+		err := munmap(mf.mapping)
+		if err != nil {
+			return err
+		}
+		mf.mapping = nil
+	}
+	err := mf.file.Truncate(size)
+	if err != nil {
+		return err
+	}
+	mf.size = size
+	return mf.remap()
+}
+
+// copyTo copies the first n bytes of the file to a new file
+func (mf *mappedFile) copyTo(path string, n int64) error {
+	target, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	_, err = io.Copy(target, io.NewSectionReader(mf.file, 0, n))
+	if err != nil {
+		return err
+	}
+	return target.Sync()
+}
+
+func (mf *mappedFile) sync() error {
+	return mf.file.Sync()
+}
+
+func (mf *mappedFile) close() {
+	if mf.mapping != nil {
+		munmap(mf.mapping)
+		mf.mapping = nil
+	}
+	mf.file.Close()
+}
@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readRecord(a *Archive, height uint64) ([]byte, error) {
+	var ret []byte
+	err := a.View(height, func(record []byte) error {
+		ret = append([]byte{}, record...)
+		return nil
+	})
+	return ret, err
+}
+
+func TestArchiveAppendAndView(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "archive")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	a, err := Open(dir)
+	require.Nil(err)
+	defer a.Close()
+	assert.Equal(uint64(0), a.NextHeight())
+
+	require.Nil(a.Append(10, []byte("block10")))
+	require.Nil(a.Append(11, []byte("block11")))
+	require.Nil(a.Append(14, []byte("block14"))) // heights 12 and 13 have no record
+	assert.Equal(uint64(15), a.NextHeight())
+	assert.NotNil(a.Append(13, []byte("block13")))
+
+	for height, expected := range map[uint64]string{10: "block10", 11: "block11", 14: "block14"} {
+		assert.True(a.Has(height))
+		record, err := readRecord(a, height)
+		assert.Nil(err)
+		assert.Equal(expected, string(record))
+	}
+	for _, height := range []uint64{9, 12, 13, 15} {
+		assert.False(a.Has(height))
+		_, err := readRecord(a, height)
+		assert.Equal(ErrNotFound, err)
+	}
+}
+
+func TestArchiveReopen(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "archive")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	a, err := Open(dir)
+	require.Nil(err)
+	require.Nil(a.Append(5, []byte("block5")))
+	require.Nil(a.Append(6, []byte("block6")))
+	a.Close()
+
+	// Simulate a crash in the middle of the append of height 7
+	f, err := os.OpenFile(filepath.Join(dir, dataFileName), os.O_WRONLY|os.O_APPEND, 0600)
+	require.Nil(err)
+	_, err = f.Write([]byte("partial"))
+	require.Nil(err)
+	f.Close()
+
+	a, err = Open(dir)
+	require.Nil(err)
+	defer a.Close()
+	assert.Equal(uint64(7), a.NextHeight())
+	record, err := readRecord(a, 6)
+	assert.Nil(err)
+	assert.Equal("block6", string(record))
+
+	require.Nil(a.Append(7, []byte("block7")))
+	record, err = readRecord(a, 7)
+	assert.Nil(err)
+	assert.Equal("block7", string(record))
+}
+
+func TestArchiveTruncate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "archive")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	a, err := Open(dir)
+	require.Nil(err)
+	defer a.Close()
+	for height := uint64(1); height <= 4; height++ {
+		require.Nil(a.Append(height, []byte{byte(height)}))
+	}
+
+	require.Nil(a.Truncate(3))
+	assert.Equal(uint64(3), a.NextHeight())
+	assert.True(a.Has(2))
+	assert.False(a.Has(3))
+
+	// The truncated heights can be archived again
+	require.Nil(a.Append(3, []byte("new3")))
+	record, err := readRecord(a, 3)
+	assert.Nil(err)
+	assert.Equal("new3", string(record))
+
+	require.Nil(a.Truncate(0))
+	assert.Equal(uint64(0), a.NextHeight())
+	assert.False(a.Has(1))
+}
+
+func TestArchiveBackup(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "archive")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	a, err := Open(filepath.Join(dir, "archive"))
+	require.Nil(err)
+	defer a.Close()
+	require.Nil(a.Append(3, []byte("block3")))
+	require.Nil(a.Append(5, []byte("block5")))
+
+	backupDir := filepath.Join(dir, "backup")
+	require.Nil(a.Backup(backupDir))
+	require.Nil(a.Append(6, []byte("block6")))
+	assert.NotNil(a.Backup(backupDir)) // The files of a backup are never overwritten
+
+	b, err := Open(backupDir)
+	require.Nil(err)
+	defer b.Close()
+	assert.Equal(uint64(6), b.NextHeight())
+	record, err := readRecord(b, 5)
+	assert.Nil(err)
+	assert.Equal("block5", string(record))
+	assert.False(b.Has(4))
+}
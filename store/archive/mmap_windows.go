@@ -0,0 +1,12 @@
+package archive
+
+import "os"
+
+// mmap is not supported on Windows, the records are read from the files directly.
+func mmap(file *os.File, size int64) ([]byte, error) {
+	return nil, nil
+}
+
+func munmap(mapping []byte) error {
+	return nil
+}
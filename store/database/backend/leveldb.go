@@ -19,6 +19,8 @@ package backend
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -32,11 +34,12 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/thetatoken/theta/common/metrics"
+	cutil "github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/store"
 	"github.com/thetatoken/theta/store/database"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "store"})
+var logger *log.Entry = cutil.GetLoggerForModule("store")
 
 const (
 	writePauseWarningThrottler = 1 * time.Minute
@@ -46,6 +49,7 @@ var OpenFileLimit = 64
 
 type LDBDatabase struct {
 	fn    string      // filename for reporting
+	refFn string      // filename of the reference database
 	db    *leveldb.DB // LevelDB instance
 	refdb *leveldb.DB // LevelDB instance for references
 
@@ -59,8 +63,21 @@ type LDBDatabase struct {
 
 	quitLock sync.Mutex      // Mutex protecting the quit channel access
 	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
+
+	// Held for reading by the writes, and for writing while the snapshots of a backup are taken,
+	// so that the reference counts of the backup match its content
+	backupLock sync.RWMutex
 }
 
+var _ database.Backupable = (*LDBDatabase)(nil)
+var _ database.Compactable = (*LDBDatabase)(nil)
+
+// Columns of the LevelDB database, i.e. the main and the reference databases
+const (
+	ldbColumnMain = "main"
+	ldbColumnRef  = "ref"
+)
+
 // NewLDBDatabase returns a LevelDB wrapped object.
 func NewLDBDatabase(file string, reffile string, cache int, handles int) (*LDBDatabase, error) {
 	// Ensure we have some minimal caching and file guarantees
@@ -104,6 +121,7 @@ func NewLDBDatabase(file string, reffile string, cache int, handles int) (*LDBDa
 
 	return &LDBDatabase{
 		fn:    file,
+		refFn: reffile,
 		db:    db,
 		refdb: refdb,
 	}, nil
@@ -116,6 +134,8 @@ func (db *LDBDatabase) Path() string {
 
 // Put puts the given key / value to the queue
 func (db *LDBDatabase) Put(key []byte, value []byte) error {
+	db.backupLock.RLock()
+	defer db.backupLock.RUnlock()
 	return db.db.Put(key, value, nil)
 }
 
@@ -137,6 +157,8 @@ func (db *LDBDatabase) Get(key []byte) ([]byte, error) {
 
 // Delete deletes the key from the queue and database
 func (db *LDBDatabase) Delete(key []byte) error {
+	db.backupLock.RLock()
+	defer db.backupLock.RUnlock()
 	db.refdb.Delete(key, nil)
 	err := db.db.Delete(key, nil)
 	if err != nil && err == leveldb.ErrNotFound {
@@ -146,6 +168,9 @@ func (db *LDBDatabase) Delete(key []byte) error {
 }
 
 func (db *LDBDatabase) Reference(key []byte) error {
+	db.backupLock.RLock()
+	defer db.backupLock.RUnlock()
+
 	// check if k/v exists
 	value, err := db.Get(key)
 	if err != nil {
@@ -176,6 +201,9 @@ func (db *LDBDatabase) Reference(key []byte) error {
 }
 
 func (db *LDBDatabase) Dereference(key []byte) error {
+	db.backupLock.RLock()
+	defer db.backupLock.RUnlock()
+
 	// check if k/v exists
 	value, err := db.Get(key)
 	if err != nil {
@@ -236,6 +264,108 @@ func (db *LDBDatabase) CountReference(key []byte) (int, error) {
 	return ref, nil
 }
 
+// Backup writes a copy of the database and of the reference database to the main and ref
+// directories under dir. The copies are read from snapshots of the databases, which are taken
+// together while the writes are held, so the node keeps running during the backup.
+func (db *LDBDatabase) Backup(dir string) error {
+	db.backupLock.Lock()
+	snapshot, err := db.db.GetSnapshot()
+	if err != nil {
+		db.backupLock.Unlock()
+		return err
+	}
+	defer snapshot.Release()
+	refSnapshot, err := db.refdb.GetSnapshot()
+	db.backupLock.Unlock()
+	if err != nil {
+		return err
+	}
+	defer refSnapshot.Release()
+
+	err = copyLDBSnapshot(snapshot, filepath.Join(dir, ldbColumnMain))
+	if err != nil {
+		return err
+	}
+	return copyLDBSnapshot(refSnapshot, filepath.Join(dir, ldbColumnRef))
+}
+
+// copyLDBSnapshot writes the content of the snapshot to a new database in the directory
+func copyLDBSnapshot(snapshot *leveldb.Snapshot, dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("Backup directory %v already exists", dir)
+	}
+	target, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	it := snapshot.NewIterator(nil, nil)
+	defer it.Release()
+	batch := new(leveldb.Batch)
+	size := 0
+	for it.Next() {
+		batch.Put(it.Key(), it.Value())
+		size += len(it.Key()) + len(it.Value())
+		if size >= database.IdealBatchSize {
+			if err := target.Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+			size = 0
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return target.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// Columns returns the main and the reference databases.
+func (db *LDBDatabase) Columns() []string {
+	return []string{ldbColumnMain, ldbColumnRef}
+}
+
+// Compact compacts the main or the reference database. The reads and the writes are served
+// during the compaction.
+func (db *LDBDatabase) Compact(column string) error {
+	switch column {
+	case ldbColumnMain:
+		return db.db.CompactRange(util.Range{})
+	case ldbColumnRef:
+		return db.refdb.CompactRange(util.Range{})
+	default:
+		return fmt.Errorf("Unknown column: %v", column)
+	}
+}
+
+// DiskUsage returns the size of the files of the main and the reference databases.
+func (db *LDBDatabase) DiskUsage() (map[string]uint64, error) {
+	usage := make(map[string]uint64)
+	for column, dir := range map[string]string{ldbColumnMain: db.fn, ldbColumnRef: db.refFn} {
+		size, err := dirSize(dir)
+		if err != nil {
+			return nil, err
+		}
+		usage[column] = size
+	}
+	return usage, nil
+}
+
+func dirSize(dir string) (uint64, error) {
+	size := uint64(0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size, err
+}
+
 func (db *LDBDatabase) NewIterator() iterator.Iterator {
 	return db.db.NewIterator(nil, nil)
 }
@@ -468,12 +598,13 @@ func (db *LDBDatabase) meter(refresh time.Duration) {
 }
 
 func (db *LDBDatabase) NewBatch() database.Batch {
-	return &ldbBatch{db: db.db, refdb: db.refdb, b: new(leveldb.Batch), references: make(map[string]int)}
+	return &ldbBatch{db: db.db, refdb: db.refdb, backupLock: &db.backupLock, b: new(leveldb.Batch), references: make(map[string]int)}
 }
 
 type ldbBatch struct {
 	db         *leveldb.DB
 	refdb      *leveldb.DB
+	backupLock *sync.RWMutex
 	b          *leveldb.Batch
 	references map[string]int
 	size       int
@@ -505,6 +636,9 @@ func (b *ldbBatch) Dereference(key []byte) error {
 }
 
 func (b *ldbBatch) Write() error {
+	b.backupLock.RLock()
+	defer b.backupLock.RUnlock()
+
 	err := b.db.Write(b.b, nil)
 	if err != nil {
 		return err
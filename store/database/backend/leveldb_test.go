@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
@@ -479,3 +480,68 @@ func testParallelPutGet(db database.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestLDB_Backup(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+
+	db.Put([]byte("k1"), []byte("v1"))
+	db.Put([]byte("k2"), []byte("v2"))
+	db.Reference([]byte("k1"))
+
+	dir, err := ioutil.TempDir(os.TempDir(), "ethdb_backup_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := db.Backup(dir); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	db.Put([]byte("k3"), []byte("v3")) // written after the backup
+	if err := db.Backup(dir); err == nil {
+		t.Fatal("backup overwrote an existing backup")
+	}
+
+	restored, err := NewLDBDatabase(filepath.Join(dir, "main"), filepath.Join(dir, "ref"), 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer restored.Close()
+	if data, err := restored.Get([]byte("k2")); err != nil || !bytes.Equal(data, []byte("v2")) {
+		t.Fatalf("get returned wrong result, got %q expected %q", string(data), "v2")
+	}
+	if ref, err := restored.CountReference([]byte("k1")); err != nil || ref != 1 {
+		t.Fatalf("reference count returned wrong result, got %v expected 1", ref)
+	}
+	if _, err := restored.Get([]byte("k3")); err != store.ErrKeyNotFound {
+		t.Fatalf("key written after the backup is in the backup")
+	}
+}
+
+func TestLDB_CompactAndDiskUsage(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+
+	for i := 0; i < 1000; i++ {
+		db.Put([]byte(strconv.Itoa(i)), bytes.Repeat([]byte("v"), 100))
+	}
+	for i := 0; i < 1000; i++ {
+		db.Delete([]byte(strconv.Itoa(i)))
+	}
+	for _, column := range db.Columns() {
+		if err := db.Compact(column); err != nil {
+			t.Fatalf("compaction of %v failed: %v", column, err)
+		}
+	}
+	if err := db.Compact("unknown"); err == nil {
+		t.Fatal("compaction of an unknown column succeeded")
+	}
+
+	usage, err := db.DiskUsage()
+	if err != nil {
+		t.Fatalf("disk usage failed: %v", err)
+	}
+	if len(usage) != 2 || usage["main"] == 0 || usage["ref"] == 0 {
+		t.Fatalf("disk usage returned wrong result: %v", usage)
+	}
+}
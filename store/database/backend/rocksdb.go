@@ -0,0 +1,413 @@
+// +build rocksdb
+
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tecbot/gorocksdb"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/store"
+	"github.com/thetatoken/theta/store/database"
+)
+
+// Column families of the RocksDB database. RocksDB requires the default column family to
+// exist, it is left empty.
+const (
+	cfDefault = iota
+	cfBlocks
+	cfState
+	cfIndexes
+	cfRefs
+)
+
+var columnFamilies = []string{"default", "blocks", "state", "indexes", "refs"}
+
+// indexKeyPrefixes are the prefixes of the keys stored in the indexes column family, whichever
+// table they are written to.
+var indexKeyPrefixes = [][]byte{
+	[]byte("bh/"),    // block by height
+	[]byte("tx/"),    // transaction by hash
+	[]byte("addr/"),  // transactions by address
+	[]byte("vt/"),    // votes by block
+	[]byte("stats/"), // chain statistics
+}
+
+var _ database.Database = (*RocksDatabase)(nil)
+var _ database.Partitioned = (*RocksDatabase)(nil)
+var _ database.Backupable = (*RocksDatabase)(nil)
+var _ database.Compactable = (*RocksDatabase)(nil)
+
+// RocksDatabase is a RocksDB wrapped object. The blocks, the state and the indexes are kept in
+// separate column families, so that each of them is flushed and compacted on its own. The
+// database itself reads and writes the state, the blocks are accessed through Blocks().
+type RocksDatabase struct {
+	*rocksTable
+
+	fn      string
+	db      *gorocksdb.DB
+	handles []*gorocksdb.ColumnFamilyHandle
+	opts    *gorocksdb.Options
+	ro      *gorocksdb.ReadOptions
+	wo      *gorocksdb.WriteOptions
+	blocks  *rocksTable
+}
+
+// NewRocksDatabase returns a RocksDB wrapped object.
+func NewRocksDatabase(dir string, options RocksDBOptions) (database.Database, error) {
+	compression, err := rocksCompression(options.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	// The block cache is shared by all the column families
+	bbto := gorocksdb.NewDefaultBlockBasedTableOptions()
+	bbto.SetBlockCache(gorocksdb.NewLRUCache(uint64(options.BlockCacheSize) * 1024 * 1024))
+	bbto.SetFilterPolicy(gorocksdb.NewBloomFilter(10))
+
+	opts := gorocksdb.NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+	opts.SetCreateIfMissingColumnFamilies(true)
+	opts.SetBlockBasedTableFactory(bbto)
+	opts.SetWriteBufferSize(options.WriteBufferSize * 1024 * 1024)
+	opts.SetMaxOpenFiles(options.MaxOpenFiles)
+	opts.IncreaseParallelism(options.MaxBackgroundJobs)
+	opts.SetCompression(compression)
+	// Size the levels from the last one up, which bounds the space and write amplification
+	opts.SetLevelCompactionDynamicLevelBytes(true)
+
+	cfOpts := make([]*gorocksdb.Options, len(columnFamilies))
+	for i := range cfOpts {
+		cfOpts[i] = opts
+	}
+	db, handles, err := gorocksdb.OpenDbColumnFamilies(opts, dir, columnFamilies, cfOpts)
+	if err != nil {
+		opts.Destroy()
+		return nil, err
+	}
+	logger.Infof("Opened RocksDB, dir: %v, block cache: %vMB, write buffer: %vMB, compression: %v",
+		dir, options.BlockCacheSize, options.WriteBufferSize, options.Compression)
+
+	rdb := &RocksDatabase{
+		fn:      dir,
+		db:      db,
+		handles: handles,
+		opts:    opts,
+		ro:      gorocksdb.NewDefaultReadOptions(),
+		wo:      gorocksdb.NewDefaultWriteOptions(),
+	}
+	rdb.rocksTable = &rocksTable{host: rdb, cf: cfState}
+	rdb.blocks = &rocksTable{host: rdb, cf: cfBlocks}
+	return rdb, nil
+}
+
+func rocksCompression(name string) (gorocksdb.CompressionType, error) {
+	switch strings.ToLower(name) {
+	case "none":
+		return gorocksdb.NoCompression, nil
+	case "snappy":
+		return gorocksdb.SnappyCompression, nil
+	case "lz4":
+		return gorocksdb.LZ4Compression, nil
+	case "zstd":
+		return gorocksdb.ZSTDCompression, nil
+	default:
+		return gorocksdb.NoCompression, fmt.Errorf("Unknown RocksDB compression: %v", name)
+	}
+}
+
+// Path returns the path to the database directory.
+func (db *RocksDatabase) Path() string {
+	return db.fn
+}
+
+// Blocks returns the table storing into the blocks column family.
+func (db *RocksDatabase) Blocks() database.Database {
+	return db.blocks
+}
+
+// Backup writes a checkpoint of the database to the rocksdb directory under dir. The table files
+// of the checkpoint are hard links to the files of the database when both are on the same file
+// system, so the backup is fast and takes little space until the database compacts them away.
+func (db *RocksDatabase) Backup(dir string) error {
+	checkpoint, err := db.db.NewCheckpoint()
+	if err != nil {
+		return err
+	}
+	defer checkpoint.Destroy()
+	return checkpoint.CreateCheckpoint(filepath.Join(dir, "rocksdb"), 0)
+}
+
+// Columns returns the column families of the database.
+func (db *RocksDatabase) Columns() []string {
+	return columnFamilies
+}
+
+// Compact compacts the column family. The reads and the writes are served during the compaction.
+func (db *RocksDatabase) Compact(column string) error {
+	for cf, name := range columnFamilies {
+		if name == column {
+			db.db.CompactRangeCF(db.handles[cf], gorocksdb.Range{})
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown column family: %v", column)
+}
+
+// DiskUsage returns the size of the table files of each column family. The write-ahead log,
+// shared by the column families, is not included.
+func (db *RocksDatabase) DiskUsage() (map[string]uint64, error) {
+	usage := make(map[string]uint64)
+	for cf, name := range columnFamilies {
+		property := db.db.GetPropertyCF("rocksdb.total-sst-files-size", db.handles[cf])
+		size, err := strconv.ParseUint(property, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse the size of column family %v: %v", name, err)
+		}
+		usage[name] = size
+	}
+	return usage, nil
+}
+
+func (db *RocksDatabase) Close() {
+	for _, handle := range db.handles {
+		handle.Destroy()
+	}
+	db.db.Close()
+	db.ro.Destroy()
+	db.wo.Destroy()
+	db.opts.Destroy()
+	logger.Infof("Database closed")
+}
+
+func (db *RocksDatabase) get(cf int, key []byte) ([]byte, error) {
+	slice, err := db.db.GetCF(db.ro, db.handles[cf], key)
+	if err != nil {
+		return nil, err
+	}
+	defer slice.Free()
+	if !slice.Exists() {
+		return nil, store.ErrKeyNotFound
+	}
+	return common.CopyBytes(slice.Data()), nil
+}
+
+func (db *RocksDatabase) getRef(cf int, key []byte) (int, error) {
+	dat, err := db.get(cfRefs, refKey(cf, key))
+	if err == store.ErrKeyNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(dat))
+}
+
+func (db *RocksDatabase) putRef(cf int, key []byte, ref int) error {
+	return db.db.PutCF(db.wo, db.handles[cfRefs], refKey(cf, key), []byte(strconv.Itoa(ref)))
+}
+
+// refKey returns the key of the reference count in the refs column family.
+func refKey(cf int, key []byte) []byte {
+	return append([]byte{byte(cf)}, key...)
+}
+
+// rocksTable stores into a column family of the host database, except for the index keys which
+// always go to the indexes column family.
+type rocksTable struct {
+	host *RocksDatabase
+	cf   int
+}
+
+func (t *rocksTable) columnFamily(key []byte) int {
+	for _, prefix := range indexKeyPrefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return cfIndexes
+		}
+	}
+	return t.cf
+}
+
+// Put puts the given key / value to the database
+func (t *rocksTable) Put(key []byte, value []byte) error {
+	return t.host.db.PutCF(t.host.wo, t.host.handles[t.columnFamily(key)], key, value)
+}
+
+func (t *rocksTable) Has(key []byte) (bool, error) {
+	_, err := t.Get(key)
+	if err == store.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Get returns the given key if it's present.
+func (t *rocksTable) Get(key []byte) ([]byte, error) {
+	return t.host.get(t.columnFamily(key), key)
+}
+
+// Delete deletes the key and its reference count from the database
+func (t *rocksTable) Delete(key []byte) error {
+	cf := t.columnFamily(key)
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	wb.DeleteCF(t.host.handles[cfRefs], refKey(cf, key))
+	wb.DeleteCF(t.host.handles[cf], key)
+	return t.host.db.Write(t.host.wo, wb)
+}
+
+func (t *rocksTable) Reference(key []byte) error {
+	// check if k/v exists
+	if _, err := t.Get(key); err != nil {
+		return err
+	}
+
+	cf := t.columnFamily(key)
+	ref, err := t.host.getRef(cf, key)
+	if err != nil {
+		return err
+	}
+	return t.host.putRef(cf, key, ref+1)
+}
+
+func (t *rocksTable) Dereference(key []byte) error {
+	// check if k/v exists
+	if _, err := t.Get(key); err != nil {
+		return err
+	}
+
+	cf := t.columnFamily(key)
+	ref, err := t.host.getRef(cf, key)
+	if err != nil {
+		return err
+	}
+	if ref > 0 {
+		return t.host.putRef(cf, key, ref-1)
+	}
+	return nil
+}
+
+func (t *rocksTable) CountReference(key []byte) (int, error) {
+	// check if k/v exists
+	if _, err := t.Get(key); err != nil {
+		return 0, err
+	}
+	return t.host.getRef(t.columnFamily(key), key)
+}
+
+func (t *rocksTable) Close() {
+	// Do nothing; the table is closed with the host database.
+}
+
+func (t *rocksTable) NewBatch() database.Batch {
+	return &rocksBatch{table: t, references: make(map[string]int)}
+}
+
+// rocksBatchOp is a write of the batch. The operations are only handed to RocksDB on Write, so
+// that a batch which is dropped without being written does not leak native memory.
+type rocksBatchOp struct {
+	cf     int
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+type rocksBatch struct {
+	table      *rocksTable
+	ops        []rocksBatchOp
+	references map[string]int
+	size       int
+}
+
+func (b *rocksBatch) Put(key, value []byte) error {
+	b.ops = append(b.ops, rocksBatchOp{cf: b.table.columnFamily(key), key: common.CopyBytes(key), value: common.CopyBytes(value)})
+	b.size += len(value)
+	return nil
+}
+
+func (b *rocksBatch) Delete(key []byte) error {
+	delete(b.references, string(key))
+	b.ops = append(b.ops, rocksBatchOp{cf: b.table.columnFamily(key), key: common.CopyBytes(key), delete: true})
+	b.size += 1
+	return nil
+}
+
+func (b *rocksBatch) Reference(key []byte) error {
+	b.references[string(key)]++
+	b.size++
+	return nil
+}
+
+func (b *rocksBatch) Dereference(key []byte) error {
+	b.references[string(key)]--
+	b.size++
+	return nil
+}
+
+func (b *rocksBatch) Write() error {
+	host := b.table.host
+
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for _, op := range b.ops {
+		if op.delete {
+			wb.DeleteCF(host.handles[cfRefs], refKey(op.cf, op.key))
+			wb.DeleteCF(host.handles[op.cf], op.key)
+		} else {
+			wb.PutCF(host.handles[op.cf], op.key, op.value)
+		}
+	}
+	if err := host.db.Write(host.wo, wb); err != nil {
+		return err
+	}
+
+	for k, v := range b.references {
+		if v == 0 {
+			// refs and derefs canceled out
+			continue
+		}
+
+		key := []byte(k)
+		has, err := b.table.Has(key)
+		if err != nil {
+			return err
+		}
+		if !has {
+			continue
+		}
+
+		cf := b.table.columnFamily(key)
+		ref, err := host.getRef(cf, key)
+		if err != nil {
+			return err
+		}
+		if ref <= 0 && v < 0 {
+			continue
+		}
+		ref = ref + v
+		if ref < 0 {
+			ref = 0
+		}
+		if err := host.putRef(cf, key, ref); err != nil {
+			return err
+		}
+	}
+
+	b.Reset()
+
+	return nil
+}
+
+func (b *rocksBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *rocksBatch) Reset() {
+	b.ops = nil
+	b.references = make(map[string]int)
+	b.size = 0
+}
@@ -0,0 +1,15 @@
+// +build !rocksdb
+
+package backend
+
+import (
+	"errors"
+
+	"github.com/thetatoken/theta/store/database"
+)
+
+// NewRocksDatabase fails since the binary was built without RocksDB. Build with the rocksdb
+// tag to enable it.
+func NewRocksDatabase(dir string, options RocksDBOptions) (database.Database, error) {
+	return nil, errors.New("RocksDB backend is not enabled, rebuild with -tags rocksdb")
+}
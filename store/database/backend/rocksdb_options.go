@@ -0,0 +1,21 @@
+package backend
+
+// RocksDBOptions tunes the RocksDB backend.
+type RocksDBOptions struct {
+	BlockCacheSize    int    // size of the block cache shared by the column families, in MB
+	WriteBufferSize   int    // size of the memtable of each column family, in MB
+	MaxOpenFiles      int    // -1 keeps all the files open
+	MaxBackgroundJobs int    // max number of concurrent flushes and compactions
+	Compression       string // none, snappy, lz4 or zstd
+}
+
+// DefaultRocksDBOptions returns the options used when not overridden by the config.
+func DefaultRocksDBOptions() RocksDBOptions {
+	return RocksDBOptions{
+		BlockCacheSize:    512,
+		WriteBufferSize:   64,
+		MaxOpenFiles:      1024,
+		MaxBackgroundJobs: 4,
+		Compression:       "lz4",
+	}
+}
@@ -0,0 +1,85 @@
+// +build rocksdb
+
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/store"
+	"github.com/thetatoken/theta/store/database"
+)
+
+func newTestRocksDB() (*RocksDatabase, func()) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "rocksdb_test_")
+	if err != nil {
+		panic("failed to create test file: " + err.Error())
+	}
+
+	db, err := NewRocksDatabase(dirname, DefaultRocksDBOptions())
+	if err != nil {
+		panic("failed to create test database: " + err.Error())
+	}
+
+	return db.(*RocksDatabase), func() {
+		db.Close()
+		os.RemoveAll(dirname)
+	}
+}
+
+func TestRocksDB_PutGet(t *testing.T) {
+	db, remove := newTestRocksDB()
+	defer remove()
+	testPutGet(db, db.NewBatch(), t)
+}
+
+func TestRocksDB_BlocksPutGet(t *testing.T) {
+	db, remove := newTestRocksDB()
+	defer remove()
+	blocks := database.BlockDatabase(db)
+	testPutGet(blocks, blocks.NewBatch(), t)
+}
+
+func TestRocksDB_ColumnFamilies(t *testing.T) {
+	assert := assert.New(t)
+
+	db, remove := newTestRocksDB()
+	defer remove()
+	blocks := database.BlockDatabase(db)
+
+	// The blocks and the state are kept apart
+	key := []byte("hash")
+	assert.Nil(db.Put(key, []byte("state")))
+	assert.Nil(blocks.Put(key, []byte("block")))
+	value, err := db.Get(key)
+	assert.Nil(err)
+	assert.Equal([]byte("state"), value)
+	value, err = blocks.Get(key)
+	assert.Nil(err)
+	assert.Equal([]byte("block"), value)
+
+	// So are their reference counts
+	assert.Nil(db.Reference(key))
+	ref, err := blocks.CountReference(key)
+	assert.Nil(err)
+	assert.Equal(0, ref)
+
+	assert.Nil(blocks.Delete(key))
+	_, err = blocks.Get(key)
+	assert.Equal(store.ErrKeyNotFound, err)
+	ref, err = db.CountReference(key)
+	assert.Nil(err)
+	assert.Equal(1, ref)
+
+	// The indexes are shared by both tables
+	index := []byte("tx/hash")
+	assert.Nil(blocks.Put(index, []byte("index")))
+	value, err = db.Get(index)
+	assert.Nil(err)
+	assert.Equal([]byte("index"), value)
+	value, err = db.get(cfIndexes, index)
+	assert.Nil(err)
+	assert.Equal([]byte("index"), value)
+}
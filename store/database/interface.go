@@ -66,3 +66,37 @@ type Batch interface {
 	// Reset resets the batch for reuse
 	Reset()
 }
+
+// Partitioned is implemented by the databases which keep the blocks apart from the state, e.g.
+// in different column families. The database itself holds the state.
+type Partitioned interface {
+	Blocks() Database
+}
+
+// BlockDatabase returns the database to store the blocks and the chain indexes in.
+func BlockDatabase(db Database) Database {
+	if p, ok := db.(Partitioned); ok {
+		return p.Blocks()
+	}
+	return db
+}
+
+// Backupable is implemented by the databases which can copy their content while they are in use,
+// e.g. from a snapshot or a checkpoint of the backend. The copy is a consistent view of the
+// database, as if the node had been stopped when it was taken.
+type Backupable interface {
+	// Backup writes the copy under the directory, laid out as the database directory of the node.
+	Backup(dir string) error
+}
+
+// Compactable is implemented by the databases whose backend can be compacted while in use. The
+// columns are the column families or the tables the backend stores the data in.
+type Compactable interface {
+	// Columns returns the names of the columns of the database.
+	Columns() []string
+	// Compact compacts the whole key range of the column, which reclaims the space of the
+	// deleted and overwritten keys. It blocks until the compaction completes.
+	Compact(column string) error
+	// DiskUsage returns the size on disk in bytes of each column.
+	DiskUsage() (map[string]uint64, error)
+}
@@ -4,9 +4,24 @@ import (
 	"github.com/thetatoken/theta/common"
 )
 
-// Store is the interface for key/value storages.
-type Store interface {
+// Writer wraps the write operations supported by both stores and batches.
+type Writer interface {
 	Put(key common.Bytes, value interface{}) error
 	Delete(key common.Bytes) error
+}
+
+// Store is the interface for key/value storages.
+type Store interface {
+	Writer
 	Get(key common.Bytes, value interface{}) error
+	NewBatch() Batch
+}
+
+// Batch is a write-only store whose changes are committed to the host store atomically when
+// Write is called. Batch cannot be used concurrently.
+type Batch interface {
+	Writer
+	Write() error
+	// Reset resets the batch for reuse
+	Reset()
 }
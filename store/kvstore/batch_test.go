@@ -0,0 +1,42 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/store"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+func TestKVBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	kvstore := NewKVStore(backend.NewMemDatabase())
+	assert.Nil(kvstore.Put(common.Bytes("deleted"), "value"))
+
+	batch := kvstore.NewBatch()
+	assert.Nil(batch.Put(common.Bytes("key1"), "value1"))
+	assert.Nil(batch.Put(common.Bytes("key2"), uint64(2)))
+	assert.Nil(batch.Delete(common.Bytes("deleted")))
+
+	// Nothing is visible before the batch is written
+	var str string
+	assert.Equal(store.ErrKeyNotFound, kvstore.Get(common.Bytes("key1"), &str))
+	assert.Nil(kvstore.Get(common.Bytes("deleted"), &str))
+
+	assert.Nil(batch.Write())
+	assert.Nil(kvstore.Get(common.Bytes("key1"), &str))
+	assert.Equal("value1", str)
+	var num uint64
+	assert.Nil(kvstore.Get(common.Bytes("key2"), &num))
+	assert.Equal(uint64(2), num)
+	assert.Equal(store.ErrKeyNotFound, kvstore.Get(common.Bytes("deleted"), &str))
+
+	// Reset discards the pending writes
+	batch.Reset()
+	assert.Nil(batch.Put(common.Bytes("key3"), "value3"))
+	batch.Reset()
+	assert.Nil(batch.Write())
+	assert.Equal(store.ErrKeyNotFound, kvstore.Get(common.Bytes("key3"), &str))
+}
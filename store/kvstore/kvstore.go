@@ -39,3 +39,37 @@ func (store *KVStore) Get(key common.Bytes, value interface{}) error {
 	}
 	return rlp.DecodeBytes(encodedValue, value)
 }
+
+// NewBatch returns a batch whose writes are committed atomically on Write.
+func (store *KVStore) NewBatch() store.Batch {
+	return &KVBatch{store.db.NewBatch()}
+}
+
+// KVBatch a Batch wrapped object.
+type KVBatch struct {
+	batch database.Batch
+}
+
+// Put upserts key/value into the batch
+func (batch *KVBatch) Put(key common.Bytes, value interface{}) error {
+	encodedValue, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		return err
+	}
+	return batch.batch.Put(key, encodedValue)
+}
+
+// Delete deletes key entry in the batch
+func (batch *KVBatch) Delete(key common.Bytes) error {
+	return batch.batch.Delete(key)
+}
+
+// Write commits the batch to DB
+func (batch *KVBatch) Write() error {
+	return batch.batch.Write()
+}
+
+// Reset discards the writes of the batch
+func (batch *KVBatch) Reset() {
+	batch.batch.Reset()
+}
@@ -0,0 +1,287 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/metrics"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/store/database"
+)
+
+const maintenanceCheckInterval = 1 * time.Minute
+
+// PendingTxCounter provides the number of pending transactions, which measures the activity of
+// the node.
+type PendingTxCounter interface {
+	Size() int
+}
+
+// Status is the disk usage of the database and the state of its compactions.
+type Status struct {
+	DiskUsage      map[string]uint64
+	LastCompaction time.Time // zero if the database has not been compacted since the node started
+	Compacting     bool
+}
+
+//
+// Maintainer compacts the database while the node is in use, so that the space of the pruned
+// states and of the overwritten keys is reclaimed without downtime. A compaction is run once per
+// compaction interval, when the node has few pending transactions and, if a window is configured,
+// during the window of the day, e.g. at night. The Maintainer also reports the disk usage of the
+// columns of the database as metrics.
+//
+type Maintainer struct {
+	logger *log.Entry
+
+	db                 database.Compactable
+	txs                PendingTxCounter
+	compactionInterval time.Duration // 0 disables the scheduled compactions
+	window             *compactionWindow
+	maxPendingTxs      int
+
+	mu             *sync.Mutex
+	usage          map[string]uint64
+	lastCompaction time.Time
+	lastScheduled  time.Time
+	compacting     bool
+	triggered      bool
+
+	now func() time.Time
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// NewMaintainer creates an instance of Maintainer. It returns an error if the compaction window
+// is invalid.
+func NewMaintainer(db database.Compactable, txs PendingTxCounter) (*Maintainer, error) {
+	window, err := parseCompactionWindow(viper.GetString(common.CfgStorageCompactionWindow))
+	if err != nil {
+		return nil, err
+	}
+	return &Maintainer{
+		logger:             util.GetLoggerForModule("maintenance"),
+		db:                 db,
+		txs:                txs,
+		compactionInterval: time.Duration(viper.GetInt(common.CfgStorageCompactionInterval)) * time.Hour,
+		window:             window,
+		maxPendingTxs:      viper.GetInt(common.CfgStorageCompactionMaxPendingTxs),
+		mu:                 &sync.Mutex{},
+		usage:              make(map[string]uint64),
+		now:                time.Now,
+		wg:                 &sync.WaitGroup{},
+	}, nil
+}
+
+// Start starts the maintenance loop.
+func (m *Maintainer) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	m.ctx = c
+	m.cancel = cancel
+
+	// The first compaction is scheduled one interval after the start, rather than while the node
+	// catches up with the chain
+	m.lastScheduled = m.now()
+
+	m.wg.Add(1)
+	go m.mainLoop()
+}
+
+// Stop notifies the maintenance loop to stop. A compaction in progress stops after the column
+// being compacted.
+func (m *Maintainer) Stop() {
+	m.cancel()
+}
+
+// Wait blocks until the maintenance loop stops.
+func (m *Maintainer) Wait() {
+	m.wg.Wait()
+}
+
+// TriggerCompaction requests a compaction regardless of the schedule, the window and the activity
+// of the node. It starts within a minute.
+func (m *Maintainer) TriggerCompaction() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.triggered = true
+}
+
+// GetStatus returns the last reported disk usage and the state of the compactions.
+func (m *Maintainer) GetStatus() *Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &Status{
+		DiskUsage:      make(map[string]uint64),
+		LastCompaction: m.lastCompaction,
+		Compacting:     m.compacting,
+	}
+	for column, size := range m.usage {
+		status.DiskUsage[column] = size
+	}
+	return status
+}
+
+func (m *Maintainer) mainLoop() {
+	defer m.wg.Done()
+
+	m.reportDiskUsage()
+
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			m.stopped = true
+			return
+		case <-ticker.C:
+			m.reportDiskUsage()
+			if m.shouldCompact() {
+				m.compact()
+			}
+		}
+	}
+}
+
+// reportDiskUsage updates the disk usage of the columns and their metrics.
+func (m *Maintainer) reportDiskUsage() map[string]uint64 {
+	usage, err := m.db.DiskUsage()
+	if err != nil {
+		m.logger.WithFields(log.Fields{"error": err}).Warn("Failed to get the disk usage of the database")
+		return nil
+	}
+	for column, size := range usage {
+		metrics.GetOrRegisterGauge("store/disk/"+column, nil).Update(int64(size))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = usage
+	return usage
+}
+
+// shouldCompact returns whether a compaction is due and the node is idle enough to run it.
+func (m *Maintainer) shouldCompact() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.triggered {
+		return true
+	}
+	if m.compactionInterval == 0 {
+		return false
+	}
+	now := m.now()
+	if now.Sub(m.lastScheduled) < m.compactionInterval {
+		return false
+	}
+	if !m.window.contains(now) {
+		return false
+	}
+	return m.txs.Size() <= m.maxPendingTxs
+}
+
+// compact compacts the columns of the database one after the other.
+func (m *Maintainer) compact() {
+	m.mu.Lock()
+	m.compacting = true
+	m.triggered = false
+	m.mu.Unlock()
+
+	start := m.now()
+	before := m.reportDiskUsage()
+	m.logger.Info("Compacting the database")
+	completed := true
+	for _, column := range m.db.Columns() {
+		if m.ctx.Err() != nil {
+			completed = false
+			break
+		}
+		columnStart := m.now()
+		if err := m.db.Compact(column); err != nil {
+			m.logger.WithFields(log.Fields{"column": column, "error": err}).Warn("Failed to compact the database column")
+			completed = false
+			continue
+		}
+		m.logger.WithFields(log.Fields{
+			"column":  column,
+			"elapsed": m.now().Sub(columnStart).String(),
+		}).Debug("Compacted the database column")
+	}
+	after := m.reportDiskUsage()
+
+	m.mu.Lock()
+	m.compacting = false
+	m.lastScheduled = start
+	if completed {
+		m.lastCompaction = m.now()
+	}
+	m.mu.Unlock()
+
+	m.logger.WithFields(log.Fields{
+		"completed": completed,
+		"elapsed":   m.now().Sub(start).String(),
+		"reclaimed": int64(totalSize(before)) - int64(totalSize(after)),
+	}).Info("Database compaction finished")
+}
+
+func totalSize(usage map[string]uint64) uint64 {
+	total := uint64(0)
+	for _, size := range usage {
+		total += size
+	}
+	return total
+}
+
+// compactionWindow is a range of the local time of day, which may wrap around midnight. The nil
+// window contains any time.
+type compactionWindow struct {
+	start time.Duration // offset from midnight
+	end   time.Duration
+}
+
+// parseCompactionWindow parses a window in the HH:MM-HH:MM format. The empty string is the nil
+// window.
+func parseCompactionWindow(s string) (*compactionWindow, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid compaction window %v, expected HH:MM-HH:MM", s)
+	}
+	window := &compactionWindow{}
+	for i, offset := range []*time.Duration{&window.start, &window.end} {
+		t, err := time.Parse("15:04", strings.TrimSpace(parts[i]))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid compaction window %v, expected HH:MM-HH:MM", s)
+		}
+		*offset = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if window.start == window.end {
+		return nil, fmt.Errorf("Empty compaction window %v", s)
+	}
+	return window, nil
+}
+
+func (w *compactionWindow) contains(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if w.start < w.end {
+		return offset >= w.start && offset < w.end
+	}
+	return offset >= w.start || offset < w.end
+}
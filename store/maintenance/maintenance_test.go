@@ -0,0 +1,131 @@
+package maintenance
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common/util"
+)
+
+type testDB struct {
+	usage     map[string]uint64
+	compacted []string
+}
+
+func (db *testDB) Columns() []string {
+	return []string{"main", "ref"}
+}
+
+func (db *testDB) Compact(column string) error {
+	db.compacted = append(db.compacted, column)
+	db.usage[column] /= 2
+	return nil
+}
+
+func (db *testDB) DiskUsage() (map[string]uint64, error) {
+	usage := make(map[string]uint64)
+	for column, size := range db.usage {
+		usage[column] = size
+	}
+	return usage, nil
+}
+
+type testTxCounter struct {
+	size int
+}
+
+func (c *testTxCounter) Size() int {
+	return c.size
+}
+
+func newTestMaintainer(db *testDB, txs *testTxCounter, window string, now *time.Time) *Maintainer {
+	w, err := parseCompactionWindow(window)
+	if err != nil {
+		panic(err)
+	}
+	m := &Maintainer{
+		logger:             util.GetLoggerForModule("maintenance"),
+		db:                 db,
+		txs:                txs,
+		compactionInterval: 24 * time.Hour,
+		window:             w,
+		maxPendingTxs:      10,
+		mu:                 &sync.Mutex{},
+		usage:              make(map[string]uint64),
+		now:                func() time.Time { return *now },
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.lastScheduled = *now
+	return m
+}
+
+func TestCompactionWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2020, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	window, err := parseCompactionWindow("")
+	assert.Nil(err)
+	assert.True(window.contains(at(12, 0)))
+
+	window, err = parseCompactionWindow("01:00-05:30")
+	assert.Nil(err)
+	assert.False(window.contains(at(0, 59)))
+	assert.True(window.contains(at(1, 0)))
+	assert.True(window.contains(at(5, 29)))
+	assert.False(window.contains(at(5, 30)))
+
+	// Window wrapping around midnight
+	window, err = parseCompactionWindow("22:00-02:00")
+	assert.Nil(err)
+	assert.True(window.contains(at(23, 0)))
+	assert.True(window.contains(at(1, 0)))
+	assert.False(window.contains(at(12, 0)))
+
+	for _, invalid := range []string{"01:00", "1-2", "25:00-02:00", "01:00-01:00"} {
+		_, err = parseCompactionWindow(invalid)
+		assert.NotNil(err, invalid)
+	}
+}
+
+func TestMaintainerSchedule(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db := &testDB{usage: map[string]uint64{"main": 1000, "ref": 100}}
+	txs := &testTxCounter{}
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)
+	m := newTestMaintainer(db, txs, "01:00-05:00", &now)
+
+	// Not due yet
+	assert.False(m.shouldCompact())
+
+	// Due, but outside of the window
+	now = now.Add(24 * time.Hour)
+	assert.False(m.shouldCompact())
+
+	// Due and in the window, but the node is busy
+	now = time.Date(2020, 1, 3, 2, 0, 0, 0, time.Local)
+	txs.size = 11
+	assert.False(m.shouldCompact())
+	txs.size = 10
+	require.True(m.shouldCompact())
+
+	m.compact()
+	assert.Equal([]string{"main", "ref"}, db.compacted)
+	status := m.GetStatus()
+	assert.Equal(map[string]uint64{"main": 500, "ref": 50}, status.DiskUsage)
+	assert.Equal(now, status.LastCompaction)
+	assert.False(status.Compacting)
+
+	// The next compaction is scheduled one interval later, unless triggered
+	assert.False(m.shouldCompact())
+	m.TriggerCompaction()
+	assert.True(m.shouldCompact())
+}
@@ -0,0 +1,235 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/util"
+	"github.com/thetatoken/theta/store"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+const (
+	// BaseSchemaVersion is the schema version of the databases written before the schema was
+	// versioned, and of the new databases before their first migration.
+	BaseSchemaVersion = uint64(1)
+
+	// DBSchemaVersionKey is the key of the schema version of the database.
+	DBSchemaVersionKey = "schema/version"
+	// DBMigrationProgressKeyPrefix is the namespace of the progress of the interrupted migrations.
+	DBMigrationProgressKeyPrefix = "schema/progress/"
+
+	progressReportInterval = 10 * time.Second
+)
+
+//
+// Migration migrates the data of the database from the previous schema version to its version,
+// e.g. when the key layout of an index changes. A migration processes the data in chunks, and
+// records its cursor after each chunk through the Progress, so that it resumes from the last
+// chunk when the node is restarted after an interruption. The chunks need to be safe to process
+// again, since the cursor may lag behind the data written.
+//
+type Migration struct {
+	Version     uint64
+	Description string
+	Migrate     func(ctx context.Context, db database.Database, progress *Progress) error
+}
+
+//
+// Progress records the cursor of a migration in the database, and reports how far it is.
+//
+type Progress struct {
+	logger *log.Entry
+
+	store   store.Store
+	version uint64
+	record  progressRecord
+
+	lastReport time.Time
+}
+
+type progressRecord struct {
+	Cursor common.Bytes
+	Done   uint64
+	Total  uint64
+}
+
+func progressKey(version uint64) common.Bytes {
+	return common.Bytes(fmt.Sprintf("%s%d", DBMigrationProgressKeyPrefix, version))
+}
+
+func loadProgress(logger *log.Entry, s store.Store, version uint64) (*Progress, error) {
+	p := &Progress{
+		logger:     logger,
+		store:      s,
+		version:    version,
+		lastReport: time.Now(),
+	}
+	err := s.Get(progressKey(version), &p.record)
+	if err != nil && err != store.ErrKeyNotFound {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Cursor returns the cursor saved by the interrupted run of the migration, nil if it starts from
+// the beginning.
+func (p *Progress) Cursor() []byte {
+	return p.record.Cursor
+}
+
+// SetTotal sets the amount of work of the migration, e.g. the number of heights to process, for
+// the progress reports. It is optional.
+func (p *Progress) SetTotal(total uint64) {
+	p.record.Total = total
+}
+
+// Advance records that a chunk of the given amount of work has been processed, and the cursor
+// the migration resumes from.
+func (p *Progress) Advance(cursor []byte, done uint64) error {
+	p.record.Cursor = cursor
+	p.record.Done += done
+	err := p.store.Put(progressKey(p.version), p.record)
+	if err != nil {
+		return err
+	}
+	if time.Since(p.lastReport) >= progressReportInterval {
+		p.report()
+		p.lastReport = time.Now()
+	}
+	return nil
+}
+
+func (p *Progress) report() {
+	fields := log.Fields{
+		"version": p.version,
+		"done":    p.record.Done,
+	}
+	if p.record.Total > 0 {
+		fields["total"] = p.record.Total
+		fields["progress"] = fmt.Sprintf("%.2f%%", 100*float64(p.record.Done)/float64(p.record.Total))
+	}
+	p.logger.WithFields(fields).Info("Migration progress")
+}
+
+// GetSchemaVersion returns the schema version of the database.
+func GetSchemaVersion(db database.Database) (uint64, error) {
+	var version uint64
+	err := kvstore.NewKVStore(database.BlockDatabase(db)).Get(common.Bytes(DBSchemaVersionKey), &version)
+	if err == store.ErrKeyNotFound {
+		return BaseSchemaVersion, nil
+	}
+	return version, err
+}
+
+//
+// Runner brings the database to the latest schema version on startup, by running the migrations
+// above the version of the database in order.
+//
+type Runner struct {
+	logger *log.Entry
+
+	db         database.Database
+	store      store.Store
+	migrations []*Migration
+}
+
+// NewRunner creates an instance of Runner. The migrations need to be sorted by increasing version.
+func NewRunner(db database.Database, migrations []*Migration) *Runner {
+	return &Runner{
+		logger:     util.GetLoggerForModule("migration"),
+		db:         db,
+		store:      kvstore.NewKVStore(database.BlockDatabase(db)),
+		migrations: migrations,
+	}
+}
+
+// LatestVersion returns the schema version the runner migrates the database to.
+func (r *Runner) LatestVersion() uint64 {
+	if len(r.migrations) == 0 {
+		return BaseSchemaVersion
+	}
+	return r.migrations[len(r.migrations)-1].Version
+}
+
+// Run runs the pending migrations. It returns the error of the context if it is cancelled, and the
+// interrupted migration resumes on the next run. It fails if the database has been migrated by a
+// newer version of the node.
+func (r *Runner) Run(ctx context.Context) error {
+	version, err := GetSchemaVersion(r.db)
+	if err != nil {
+		return err
+	}
+	latest := r.LatestVersion()
+	if version > latest {
+		return fmt.Errorf("Database schema version %v is newer than the latest supported version %v, "+
+			"the node needs to be upgraded", version, latest)
+	}
+
+	prev := BaseSchemaVersion
+	for _, m := range r.migrations {
+		if m.Version <= prev {
+			return fmt.Errorf("Migration to schema version %v is out of order", m.Version)
+		}
+		prev = m.Version
+	}
+
+	for _, m := range r.migrations {
+		if m.Version <= version {
+			continue
+		}
+		err := r.migrate(ctx, m)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) migrate(ctx context.Context, m *Migration) error {
+	progress, err := loadProgress(r.logger, r.store, m.Version)
+	if err != nil {
+		return err
+	}
+	logger := r.logger.WithFields(log.Fields{
+		"version":     m.Version,
+		"description": m.Description,
+	})
+	if progress.Cursor() != nil {
+		logger.Info("Resuming database migration")
+	} else {
+		logger.Info("Migrating database")
+	}
+
+	start := time.Now()
+	err = m.Migrate(ctx, r.db, progress)
+	if err == nil {
+		err = ctx.Err() // Never records a migration stopped early as done
+	}
+	if err != nil {
+		if err == context.Canceled {
+			logger.Info("Database migration interrupted, it resumes on the next start")
+		}
+		return err
+	}
+
+	batch := r.store.NewBatch()
+	err = batch.Put(common.Bytes(DBSchemaVersionKey), m.Version)
+	if err != nil {
+		return err
+	}
+	err = batch.Delete(progressKey(m.Version))
+	if err != nil {
+		return err
+	}
+	err = batch.Write()
+	if err != nil {
+		return err
+	}
+	logger.WithFields(log.Fields{"elapsed": time.Since(start).String()}).Info("Database migrated")
+	return nil
+}
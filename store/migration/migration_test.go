@@ -0,0 +1,122 @@
+package migration
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/store/database"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+// newRenameMigration creates a migration which moves the keys old/<i> to new/<i>, one key per
+// chunk. It cancels the context after stopAfter keys if positive.
+func newRenameMigration(version uint64, numKeys uint64, stopAfter uint64, cancel context.CancelFunc) *Migration {
+	return &Migration{
+		Version:     version,
+		Description: "Rename the test keys",
+		Migrate: func(ctx context.Context, db database.Database, progress *Progress) error {
+			progress.SetTotal(numKeys)
+			next := uint64(0)
+			if cursor := progress.Cursor(); cursor != nil {
+				next = binary.BigEndian.Uint64(cursor)
+			}
+			for i := next; i < numKeys; i++ {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				value, err := db.Get([]byte(fmt.Sprintf("old/%d", i)))
+				if err != nil {
+					return err
+				}
+				batch := db.NewBatch()
+				batch.Put([]byte(fmt.Sprintf("new/%d", i)), value)
+				batch.Delete([]byte(fmt.Sprintf("old/%d", i)))
+				if err := batch.Write(); err != nil {
+					return err
+				}
+
+				cursor := make([]byte, 8)
+				binary.BigEndian.PutUint64(cursor, i+1)
+				if err := progress.Advance(cursor, 1); err != nil {
+					return err
+				}
+				if stopAfter > 0 && i+1 == stopAfter {
+					cancel()
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func TestMigrationRunner(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db := backend.NewMemDatabase()
+	for i := 0; i < 10; i++ {
+		db.Put([]byte(fmt.Sprintf("old/%d", i)), []byte{byte(i)})
+	}
+	version, err := GetSchemaVersion(db)
+	require.Nil(err)
+	assert.Equal(BaseSchemaVersion, version)
+
+	// The first run is interrupted after 4 keys
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := NewRunner(db, []*Migration{newRenameMigration(2, 10, 4, cancel)})
+	assert.Equal(context.Canceled, runner.Run(ctx))
+	version, err = GetSchemaVersion(db)
+	require.Nil(err)
+	assert.Equal(BaseSchemaVersion, version)
+
+	// The second run resumes from the fifth key
+	migrated := 0
+	m := newRenameMigration(2, 10, 0, nil)
+	migrate := m.Migrate
+	m.Migrate = func(ctx context.Context, db database.Database, progress *Progress) error {
+		assert.NotNil(progress.Cursor())
+		migrated++
+		return migrate(ctx, db, progress)
+	}
+	runner = NewRunner(db, []*Migration{m})
+	require.Nil(runner.Run(context.Background()))
+	assert.Equal(1, migrated)
+	version, err = GetSchemaVersion(db)
+	require.Nil(err)
+	assert.Equal(uint64(2), version)
+	for i := 0; i < 10; i++ {
+		value, err := db.Get([]byte(fmt.Sprintf("new/%d", i)))
+		assert.Nil(err)
+		assert.Equal([]byte{byte(i)}, value)
+		has, _ := db.Has([]byte(fmt.Sprintf("old/%d", i)))
+		assert.False(has)
+	}
+	has, _ := db.Has(progressKey(2))
+	assert.False(has)
+
+	// The applied migrations are not run again
+	require.Nil(runner.Run(context.Background()))
+	assert.Equal(1, migrated)
+}
+
+func TestMigrationRunnerVersionChecks(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db := backend.NewMemDatabase()
+	require.Nil(NewRunner(db, []*Migration{newRenameMigration(2, 0, 0, nil)}).Run(context.Background()))
+
+	// The database was migrated by a newer node
+	assert.NotNil(NewRunner(db, []*Migration{}).Run(context.Background()))
+
+	// The migrations are out of order
+	runner := NewRunner(backend.NewMemDatabase(), []*Migration{
+		newRenameMigration(3, 0, 0, nil),
+		newRenameMigration(2, 0, 0, nil),
+	})
+	assert.NotNil(runner.Run(context.Background()))
+}
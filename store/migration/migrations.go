@@ -0,0 +1,5 @@
+package migration
+
+// Migrations lists the migrations of the database schema by increasing version. A change of the
+// key layout of the stored data appends the migration of the existing databases here.
+var Migrations = []*Migration{}
@@ -6,15 +6,22 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/store/database"
 	"github.com/thetatoken/theta/store/trie"
 )
 
 // NewTreeStore create a new instance of TreeStore.
 func NewTreeStore(root common.Hash, db database.Database) *TreeStore {
+	return NewTreeStoreWithHashAlgorithm(root, db, crypto.HashAlgorithmKeccak256)
+}
+
+// NewTreeStoreWithHashAlgorithm create a new instance of TreeStore whose trie nodes are hashed
+// with the given algorithm.
+func NewTreeStoreWithHashAlgorithm(root common.Hash, db database.Database, algorithm crypto.HashAlgorithm) *TreeStore {
 	var tr *trie.Trie
 	var err error
-	tr, err = trie.New(root, trie.NewDatabase(db))
+	tr, err = trie.NewWithHashAlgorithm(root, trie.NewDatabase(db), algorithm)
 	if err != nil {
 		log.Errorf("Failed to create tree store for: %v: %v", root.Hex(), err)
 		return nil
@@ -52,7 +59,7 @@ func (store *TreeStore) Commit() (common.Hash, error) {
 // otherwise the function will return an error.
 func (store *TreeStore) Revert(root common.Hash) (*TreeStore, error) {
 	trieDB := store.Trie.GetDB()
-	revertedTrie, err := trie.New(root, trieDB)
+	revertedTrie, err := trie.NewWithHashAlgorithm(root, trieDB, store.Trie.HashAlgorithm())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,165 @@
+package trie
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/metrics"
+	"github.com/thetatoken/theta/store/database"
+)
+
+var (
+	nodeCacheHitMeter   = metrics.NewRegisteredMeter("trie/nodecache/hit", nil)
+	nodeCacheMissMeter  = metrics.NewRegisteredMeter("trie/nodecache/miss", nil)
+	nodeCacheEvictMeter = metrics.NewRegisteredMeter("trie/nodecache/evict", nil)
+)
+
+// nodeCacheEntryOverhead approximates the memory used by a cache entry besides the node blob:
+// the hash, the list element and the map entry.
+const nodeCacheEntryOverhead = 128
+
+// nodeCacher is implemented by the disk databases caching the trie nodes.
+type nodeCacher interface {
+	getNode(hash common.Hash) ([]byte, error)
+}
+
+var _ database.Database = (*CachedDatabase)(nil)
+var _ database.Partitioned = (*CachedDatabase)(nil)
+
+// CachedDatabase wraps a disk database with an LRU cache of the trie nodes read through it.
+// The trie databases are created per store view and only hold the nodes not yet committed, so
+// the cache is kept here to be shared by all the views. Trie nodes are keyed by their hash and
+// never modified, a cached node only becomes stale when deleted, which goes through the wrapper.
+// The other reads and writes are passed through.
+type CachedDatabase struct {
+	database.Database
+
+	mu      *sync.Mutex
+	budget  int
+	size    int
+	lru     *list.List // front is the most recently used
+	entries map[common.Hash]*list.Element
+	hits    uint64
+	misses  uint64
+}
+
+type nodeCacheEntry struct {
+	hash common.Hash
+	blob []byte
+}
+
+// NewCachedDatabase returns a database caching the trie nodes within the memory budget in bytes.
+func NewCachedDatabase(db database.Database, budget int) *CachedDatabase {
+	return &CachedDatabase{
+		Database: db,
+		mu:       &sync.Mutex{},
+		budget:   budget,
+		lru:      list.New(),
+		entries:  make(map[common.Hash]*list.Element),
+	}
+}
+
+// Blocks returns the database storing the blocks, which are not cached.
+func (db *CachedDatabase) Blocks() database.Database {
+	return database.BlockDatabase(db.Database)
+}
+
+// Delete deletes the key from the cache and the database.
+func (db *CachedDatabase) Delete(key []byte) error {
+	db.remove(key)
+	return db.Database.Delete(key)
+}
+
+// NewBatch returns a batch whose deletes also evict the cached nodes.
+func (db *CachedDatabase) NewBatch() database.Batch {
+	return &cachedBatch{Batch: db.Database.NewBatch(), db: db}
+}
+
+// HitRate returns the ratio of the node reads served from the cache.
+func (db *CachedDatabase) HitRate() float64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.hits+db.misses == 0 {
+		return 0
+	}
+	return float64(db.hits) / float64(db.hits+db.misses)
+}
+
+// Size returns the memory used by the cache in bytes.
+func (db *CachedDatabase) Size() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.size
+}
+
+func (db *CachedDatabase) getNode(hash common.Hash) ([]byte, error) {
+	db.mu.Lock()
+	if elem, ok := db.entries[hash]; ok {
+		db.lru.MoveToFront(elem)
+		db.hits++
+		db.mu.Unlock()
+		nodeCacheHitMeter.Mark(1)
+		return elem.Value.(*nodeCacheEntry).blob, nil
+	}
+	db.misses++
+	db.mu.Unlock()
+	nodeCacheMissMeter.Mark(1)
+
+	blob, err := db.Database.Get(hash[:])
+	if err != nil || blob == nil {
+		return blob, err
+	}
+	db.add(hash, blob)
+	return blob, nil
+}
+
+func (db *CachedDatabase) add(hash common.Hash, blob []byte) {
+	entrySize := len(blob) + nodeCacheEntryOverhead
+	if entrySize > db.budget {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.entries[hash]; ok {
+		return
+	}
+	db.entries[hash] = db.lru.PushFront(&nodeCacheEntry{hash: hash, blob: blob})
+	db.size += entrySize
+
+	for db.size > db.budget {
+		db.removeElement(db.lru.Back())
+		nodeCacheEvictMeter.Mark(1)
+	}
+}
+
+func (db *CachedDatabase) remove(key []byte) {
+	if len(key) != common.HashLength {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if elem, ok := db.entries[common.BytesToHash(key)]; ok {
+		db.removeElement(elem)
+	}
+}
+
+func (db *CachedDatabase) removeElement(elem *list.Element) {
+	entry := db.lru.Remove(elem).(*nodeCacheEntry)
+	delete(db.entries, entry.hash)
+	db.size -= len(entry.blob) + nodeCacheEntryOverhead
+}
+
+type cachedBatch struct {
+	database.Batch
+	db *CachedDatabase
+}
+
+func (b *cachedBatch) Delete(key []byte) error {
+	b.db.remove(key)
+	return b.Batch.Delete(key)
+}
@@ -0,0 +1,80 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/store/database"
+	dbbackend "github.com/thetatoken/theta/store/database/backend"
+)
+
+func TestCachedDatabase(t *testing.T) {
+	assert := assert.New(t)
+
+	counting := &countingDB{Database: dbbackend.NewMemDatabase(), gets: make(map[string]int)}
+	db := NewCachedDatabase(counting, 1024*1024)
+
+	trie, _ := New(common.Hash{}, NewDatabase(db))
+	updateString(trie, "120000", "qwerqwerqwerqwerqwerqwerqwerqwer")
+	updateString(trie, "123456", "asdfasdfasdfasdfasdfasdfasdfasdf")
+	root, _ := trie.Commit(nil)
+	assert.Nil(trie.db.Commit(root, true))
+
+	// The first view reads the nodes from the disk, the next ones from the cache
+	for i := 0; i < 3; i++ {
+		trie, _ = New(root, NewDatabase(db))
+		assert.Equal("qwerqwerqwerqwerqwerqwerqwerqwer", string(getString(trie, "120000")))
+	}
+	reads := 0
+	for _, count := range counting.gets {
+		assert.Equal(1, count)
+		reads += count
+	}
+	assert.True(reads > 0)
+	assert.True(db.HitRate() > 0.5)
+	assert.True(db.Size() > 0)
+
+	// Deleted nodes are evicted
+	batch := db.NewBatch()
+	assert.Nil(batch.Delete(root[:]))
+	assert.Nil(batch.Write())
+	_, err := New(root, NewDatabase(db))
+	assert.NotNil(err)
+
+	// Blocks are not cached
+	assert.Equal(database.Database(counting), database.BlockDatabase(db))
+}
+
+func TestCachedDatabaseBudget(t *testing.T) {
+	assert := assert.New(t)
+
+	blob := make([]byte, 100)
+	entrySize := len(blob) + nodeCacheEntryOverhead
+	db := NewCachedDatabase(dbbackend.NewMemDatabase(), 2*entrySize)
+
+	hashes := []common.Hash{common.HexToHash("01"), common.HexToHash("02"), common.HexToHash("03")}
+	for _, hash := range hashes {
+		assert.Nil(db.Put(hash[:], blob))
+	}
+
+	// Reading the third node evicts the least recently used one
+	db.getNode(hashes[0])
+	db.getNode(hashes[1])
+	db.getNode(hashes[0])
+	db.getNode(hashes[2])
+	assert.Equal(2*entrySize, db.Size())
+	_, ok := db.entries[hashes[1]]
+	assert.False(ok)
+	_, ok = db.entries[hashes[0]]
+	assert.True(ok)
+
+	// Nodes larger than the budget are not cached
+	large := common.HexToHash("04")
+	assert.Nil(db.Put(large[:], make([]byte, 2*entrySize)))
+	value, err := db.getNode(large)
+	assert.Nil(err)
+	assert.Equal(2*entrySize, len(value))
+	_, ok = db.entries[large]
+	assert.False(ok)
+}
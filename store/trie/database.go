@@ -26,11 +26,12 @@ import (
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/metrics"
+	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store/database"
 )
 
-var logger *log.Entry = log.WithFields(log.Fields{"prefix": "store"})
+var logger *log.Entry = util.GetLoggerForModule("store")
 
 var (
 	memcacheFlushTimeTimer  = metrics.NewRegisteredResettingTimer("trie/memcache/flush/time", nil)
@@ -345,7 +346,7 @@ func (db *Database) node(hash common.Hash, cachegen uint16) node {
 		return node.obj(hash, cachegen)
 	}
 	// Content unavailable in memory, attempt to retrieve from disk
-	enc, err := db.diskdb.Get(hash[:])
+	enc, err := db.diskNode(hash)
 	if err != nil || enc == nil {
 		return nil
 	}
@@ -364,6 +365,15 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 		return node.rlp(), nil
 	}
 	// Content unavailable in memory, attempt to retrieve from disk
+	return db.diskNode(hash)
+}
+
+// diskNode retrieves an encoded trie node from the persistent database, through its node cache
+// if it has one.
+func (db *Database) diskNode(hash common.Hash) ([]byte, error) {
+	if cacher, ok := db.diskdb.(nodeCacher); ok {
+		return cacher.getNode(hash)
+	}
 	return db.diskdb.Get(hash[:])
 }
 
@@ -620,14 +630,9 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 			db.lock.RUnlock()
 			return err
 		}
-		if batch.ValueSize() > database.IdealBatchSize {
-			if err := batch.Write(); err != nil {
-				return err
-			}
-			batch.Reset()
-		}
 	}
-	// Move the trie itself into the batch, flushing if enough data is accumulated
+	// Move the trie itself into the batch. The batch is written at once, so that a crash
+	// cannot leave a partially persisted state trie.
 	nodes, storage := len(db.nodes), db.nodesSize
 	if err := db.commit(node, batch); err != nil {
 		logger.Error("Failed to commit trie from trie database", "err", err)
@@ -683,14 +688,6 @@ func (db *Database) commit(hash common.Hash, batch database.Batch) error {
 	if err := batch.Put(hash[:], node.rlp()); err != nil {
 		return err
 	}
-
-	// If we've reached an optimal batch size, commit and start over
-	if batch.ValueSize() >= database.IdealBatchSize {
-		if err := batch.Write(); err != nil {
-			return err
-		}
-		batch.Reset()
-	}
 	return nil
 }
 
@@ -22,13 +22,13 @@ import (
 	"sync"
 
 	"github.com/thetatoken/theta/common"
-	"github.com/thetatoken/theta/crypto/sha3"
+	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/rlp"
 )
 
 type hasher struct {
 	tmp        sliceBuffer
-	sha        keccakState
+	sha        hash.Hash
 	cachegen   uint16
 	cachelimit uint16
 	onleaf     LeafCallback
@@ -53,24 +53,38 @@ func (b *sliceBuffer) Reset() {
 	*b = (*b)[:0]
 }
 
-// hashers live in a global db.
-var hasherPool = sync.Pool{
-	New: func() interface{} {
-		return &hasher{
-			tmp: make(sliceBuffer, 0, 550), // cap is as large as a full fullNode.
-			sha: sha3.NewKeccak256().(keccakState),
-		}
-	},
+// hashers live in a global db, one pool per hash algorithm. The pools are looked up on every
+// hash, so they are kept in a sync.Map rather than behind a mutex.
+var hasherPools sync.Map // crypto.HashAlgorithm -> *sync.Pool
+
+func getHasherPool(algorithm crypto.HashAlgorithm) *sync.Pool {
+	if pool, ok := hasherPools.Load(algorithm); ok {
+		return pool.(*sync.Pool)
+	}
+
+	hf, err := crypto.GetHasher(algorithm)
+	if err != nil {
+		panic(err)
+	}
+	pool, _ := hasherPools.LoadOrStore(algorithm, &sync.Pool{
+		New: func() interface{} {
+			return &hasher{
+				tmp: make(sliceBuffer, 0, 550), // cap is as large as a full fullNode.
+				sha: hf.New(),
+			}
+		},
+	})
+	return pool.(*sync.Pool)
 }
 
-func newHasher(cachegen, cachelimit uint16, onleaf LeafCallback) *hasher {
-	h := hasherPool.Get().(*hasher)
+func newHasher(algorithm crypto.HashAlgorithm, cachegen, cachelimit uint16, onleaf LeafCallback) *hasher {
+	h := getHasherPool(algorithm).Get().(*hasher)
 	h.cachegen, h.cachelimit, h.onleaf = cachegen, cachelimit, onleaf
 	return h
 }
 
-func returnHasherToPool(h *hasher) {
-	hasherPool.Put(h)
+func returnHasherToPool(algorithm crypto.HashAlgorithm, h *hasher) {
+	getHasherPool(algorithm).Put(h)
 }
 
 // hash collapses a node down into a hash node, also returning a copy of the
@@ -214,6 +228,10 @@ func (h *hasher) makeHashNode(data []byte) hashNode {
 	n := make(hashNode, h.sha.Size())
 	h.sha.Reset()
 	h.sha.Write(data)
-	h.sha.Read(n)
+	if ks, ok := h.sha.(keccakState); ok {
+		ks.Read(n)
+	} else {
+		h.sha.Sum(n[:0])
+	}
 	return n
 }
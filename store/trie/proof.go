@@ -65,7 +65,8 @@ func (t *Trie) Prove(key []byte, fromLevel uint, proofDb database.Putter) error
 			panic(fmt.Sprintf("%T: invalid node: %v", tn, tn))
 		}
 	}
-	hasher := newHasher(0, 0, nil)
+	hasher := newHasher(t.hashAlgorithm, 0, 0, nil)
+	defer returnHasherToPool(t.hashAlgorithm, hasher)
 	for i, n := range nodes {
 		// Don't bother checking for errors here since hasher panics
 		// if encoding doesn't work and we're not writing to any database.
@@ -79,7 +80,7 @@ func (t *Trie) Prove(key []byte, fromLevel uint, proofDb database.Putter) error
 			} else {
 				enc, _ := rlp.EncodeToBytes(n)
 				if !ok {
-					hash = crypto.Keccak256(enc)
+					hash = hasher.makeHashNode(enc)
 				}
 				proofDb.Put(hash, enc)
 			}
@@ -128,6 +129,52 @@ func VerifyProof(rootHash common.Hash, key []byte, proofDb DatabaseReader) (valu
 	}
 }
 
+// ProofList is a merkle proof as the list of the encoded nodes on the path to the key, root
+// first. Unlike a proof database keyed by the node hashes, it can be handed to a client which
+// recomputes the hashes itself.
+type ProofList []common.Bytes
+
+// Put appends the node to the proof, so that ProofList can be passed to Prove.
+func (p *ProofList) Put(key []byte, value []byte) error {
+	*p = append(*p, common.CopyBytes(value))
+	return nil
+}
+
+// VerifyProofList checks a proof list against the root hash of a trie hashed with the given
+// algorithm. It returns the value for key, or nil if the proof proves its absence.
+func VerifyProofList(rootHash common.Hash, key []byte, proof ProofList, algorithm crypto.HashAlgorithm) ([]byte, error) {
+	if len(proof) == 0 && (rootHash == common.Hash{} || rootHash == emptyRoot) {
+		// The trie is empty
+		return nil, nil
+	}
+	hasher, err := crypto.GetHasher(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	proofDb := make(proofNodeSet)
+	for _, node := range proof {
+		proofDb[hasher.Hash(node)] = node
+	}
+	value, _, err := VerifyProof(rootHash, key, proofDb)
+	return value, err
+}
+
+// proofNodeSet is a proof database keyed by the node hashes computed by the verifier.
+type proofNodeSet map[common.Hash][]byte
+
+func (s proofNodeSet) Get(key []byte) ([]byte, error) {
+	node, ok := s[common.BytesToHash(key)]
+	if !ok {
+		return nil, fmt.Errorf("proof node %x missing", key)
+	}
+	return node, nil
+}
+
+func (s proofNodeSet) Has(key []byte) (bool, error) {
+	_, ok := s[common.BytesToHash(key)]
+	return ok, nil
+}
+
 func get(tn node, key []byte) ([]byte, node) {
 	for {
 		switch n := tn.(type) {
@@ -23,8 +23,6 @@ import (
 	"fmt"
 	"sync"
 
-	"github.com/thetatoken/theta/ledger/types"
-	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store"
 	"github.com/thetatoken/theta/store/database"
 
@@ -46,6 +44,12 @@ var (
 	cacheUnloadCounter = metrics.NewRegisteredCounter("trie/cacheunload", nil)
 )
 
+// FormatValue formats the values of the value nodes printed by FmtNode. The trie does not know
+// the types of the values it stores, so the tools printing the tries decode the values instead.
+var FormatValue = func(value []byte) string {
+	return fmt.Sprintf("%v", value)
+}
+
 // CacheMisses retrieves a global counter measuring the number of cache misses
 // the trie had since process startup. This isn't useful for anything apart from
 // trie debugging purposes.
@@ -71,9 +75,10 @@ type LeafCallback func(leaf []byte, parent common.Hash) error
 //
 // Trie is not safe for concurrent use.
 type Trie struct {
-	db           *Database
-	root         node
-	originalRoot common.Hash
+	db            *Database
+	root          node
+	originalRoot  common.Hash
+	hashAlgorithm crypto.HashAlgorithm
 
 	// Cache generation values.
 	// cachegen increases by one with each commit operation.
@@ -107,13 +112,19 @@ func (t *Trie) newFlag() nodeFlag {
 // New will panic if db is nil and returns a MissingNodeError if root does
 // not exist in the database. Accessing the trie loads nodes from db on demand.
 func New(root common.Hash, db *Database) (*Trie, error) {
+	return NewWithHashAlgorithm(root, db, crypto.HashAlgorithmKeccak256)
+}
+
+// NewWithHashAlgorithm creates a trie whose nodes are hashed with the given algorithm.
+func NewWithHashAlgorithm(root common.Hash, db *Database, algorithm crypto.HashAlgorithm) (*Trie, error) {
 	if db == nil {
 		panic("trie.New called without a database")
 	}
 	trie := &Trie{
-		db:           db,
-		originalRoot: root,
-		mu:           &sync.RWMutex{},
+		db:            db,
+		originalRoot:  root,
+		hashAlgorithm: algorithm,
+		mu:            &sync.RWMutex{},
 	}
 	if root != (common.Hash{}) && root != emptyRoot {
 		rootnode, err := trie.resolveHash(root[:], nil)
@@ -128,10 +139,15 @@ func New(root common.Hash, db *Database) (*Trie, error) {
 // Copy creates a copy of the trie
 func (t *Trie) Copy() (*Trie, error) {
 	rootHash := t.Hash()
-	copiedTrie, err := New(rootHash, t.db)
+	copiedTrie, err := NewWithHashAlgorithm(rootHash, t.db, t.hashAlgorithm)
 	return copiedTrie, err
 }
 
+// HashAlgorithm returns the algorithm the trie nodes are hashed with.
+func (t *Trie) HashAlgorithm() crypto.HashAlgorithm {
+	return t.hashAlgorithm
+}
+
 // NodeIterator returns an iterator that returns nodes of the trie. Iteration starts at
 // the key after the given start key.
 func (t *Trie) NodeIterator(start []byte) NodeIterator {
@@ -494,8 +510,8 @@ func (t *Trie) hashRoot(db *Database, onleaf LeafCallback) (node, node, error) {
 	if t.root == nil {
 		return hashNode(emptyRoot.Bytes()), nil, nil
 	}
-	h := newHasher(t.cachegen, t.cachelimit, onleaf)
-	defer returnHasherToPool(h)
+	h := newHasher(t.hashAlgorithm, t.cachegen, t.cachelimit, onleaf)
+	defer returnHasherToPool(t.hashAlgorithm, h)
 	return h.hash(t.root, db, true)
 }
 
@@ -672,29 +688,5 @@ func fmtHashNode(n hashNode, ind string, level int, db database.Database) string
 }
 
 func fmtValueNode(n valueNode, ind string) string {
-	account := types.Account{}
-	err := rlp.DecodeBytes([]byte(n), &account)
-	if err == nil {
-		return fmt.Sprintf("%v", account)
-	}
-
-	splitRule := types.SplitRule{}
-	err = rlp.DecodeBytes([]byte(n), &splitRule)
-	if err == nil {
-		return fmt.Sprintf("%v", splitRule)
-	}
-
-	// vcp := core.ValidatorCandidatePool{}
-	// err = rlp.DecodeBytes([]byte(n), &vcp)
-	// if err == nil {
-	// 	return fmt.Sprintf("%v", vcp)
-	// }
-
-	hl := types.HeightList{}
-	err = rlp.DecodeBytes([]byte(n), &hl)
-	if err == nil {
-		return fmt.Sprintf("%v", hl)
-	}
-
-	return fmt.Sprintf("%v", []byte(n))
+	return FormatValue([]byte(n))
 }
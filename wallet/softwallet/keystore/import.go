@@ -0,0 +1,65 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pborman/uuid"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/crypto"
+)
+
+// ImportEthereumKeyJSON decrypts a key from a keystore file generated by Ethereum tools (e.g.
+// geth, MyEtherWallet). Both the scrypt and the pbkdf2 variants of the version 3 format are
+// supported. If the file declares an address, it must match the one derived from the key.
+func ImportEthereumKeyJSON(keyjson []byte, auth string) (*Key, error) {
+	keyJs := new(encryptedKeyJSON)
+	if err := json.Unmarshal(keyjson, keyJs); err != nil {
+		return nil, fmt.Errorf("Invalid keystore file: %v", err)
+	}
+
+	key, err := decryptKey(keyjson, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyJs.Address != "" {
+		declared := common.HexToAddress(keyJs.Address)
+		if declared != key.Address {
+			return nil, fmt.Errorf("Key content mismatch: file declares address %v, key derives %v",
+				declared.Hex(), key.Address.Hex())
+		}
+	}
+
+	// Ethereum tools do not always produce valid UUIDs
+	if key.Id == nil {
+		key.Id = uuid.NewRandom()
+	}
+
+	return key, nil
+}
+
+// ImportHexKey parses a raw hex encoded private key, with or without the 0x prefix.
+func ImportHexKey(hexKey string) (*Key, error) {
+	hexKey = strings.TrimSpace(hexKey)
+	if strings.HasPrefix(hexKey, "0x") || strings.HasPrefix(hexKey, "0X") {
+		hexKey = hexKey[2:]
+	}
+	skBytes, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid hex private key: %v", err)
+	}
+	if len(skBytes) != 32 {
+		return nil, fmt.Errorf("Invalid private key length: expected 32 bytes, got %v", len(skBytes))
+	}
+
+	privKey, err := crypto.PrivateKeyFromBytes(skBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid private key: %v", err)
+	}
+
+	return NewKey(privKey), nil
+}
@@ -0,0 +1,62 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/thetatoken/theta/common"
+)
+
+func TestImportEthereumKeyJSON(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := common.HexToAddress("45dea0fb0bba44f4fcf290bba71fd57d7117cbb8")
+
+	if _, err := ImportEthereumKeyJSON(keyjson, "bad"); err == nil {
+		t.Error("json key imported with bad password")
+	}
+	key, err := ImportEthereumKeyJSON(keyjson, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Address != address {
+		t.Errorf("key address mismatch: have %x, want %x", key.Address, address)
+	}
+
+	// The declared address must match the key
+	tampered := make(map[string]interface{})
+	if err := json.Unmarshal(keyjson, &tampered); err != nil {
+		t.Fatal(err)
+	}
+	tampered["address"] = "26d813157f7503a9057fb2db6eb2f83a35c4fdd7"
+	tamperedjson, _ := json.Marshal(tampered)
+	if _, err := ImportEthereumKeyJSON(tamperedjson, ""); err == nil {
+		t.Error("json key imported with mismatched address")
+	}
+}
+
+func TestImportHexKey(t *testing.T) {
+	tests := loadKeyStoreTest("testdata/test_vector.json", t)
+	test := tests["wikipage_test_vector_pbkdf2"]
+
+	for _, hexKey := range []string{test.Priv, "0x" + test.Priv, " " + test.Priv + "\n"} {
+		key, err := ImportHexKey(hexKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privHex := hex.EncodeToString(key.PrivateKey.ToBytes())
+		if privHex != test.Priv {
+			t.Errorf("private key mismatch: have %v, want %v", privHex, test.Priv)
+		}
+	}
+
+	for _, hexKey := range []string{"", "xyz", test.Priv[:62], test.Priv + "00"} {
+		if _, err := ImportHexKey(hexKey); err == nil {
+			t.Errorf("invalid hex key %q imported", hexKey)
+		}
+	}
+}
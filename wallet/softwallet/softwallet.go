@@ -3,6 +3,7 @@ package softwallet
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/crypto"
@@ -31,7 +32,7 @@ type SoftWallet struct {
 
 type UnlockedKey struct {
 	*ks.Key
-	abort chan struct{}
+	abort chan struct{} // Closed to cancel the automatic re-lock, nil if the key has no timeout
 }
 
 func NewSoftWallet(keysDirPath string, kstype KeystoreType) (*SoftWallet, error) {
@@ -98,8 +99,38 @@ func (w *SoftWallet) NewKey(password string) (common.Address, error) {
 	return address, nil
 }
 
+// ImportKey stores a key imported from elsewhere, encrypted with the given password
+func (w *SoftWallet) ImportKey(key *ks.Key, password string) (common.Address, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	addresses, err := w.keystore.ListKeyAddresses()
+	if err != nil {
+		return common.Address{}, err
+	}
+	for _, address := range addresses {
+		if address == key.Address {
+			return common.Address{}, fmt.Errorf("Key for address %v already exists", key.Address.Hex())
+		}
+	}
+
+	err = w.keystore.StoreKey(key, password)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return key.Address, nil
+}
+
 // Unlock unlocks a key if the password is correct
 func (w *SoftWallet) Unlock(address common.Address, password string) error {
+	return w.UnlockWithTimeout(address, password, 0)
+}
+
+// UnlockWithTimeout unlocks a key if the password is correct, and locks it again when the
+// timeout expires. A zero timeout keeps the key unlocked until Lock is called. Unlocking an
+// unlocked key replaces its timeout.
+func (w *SoftWallet) UnlockWithTimeout(address common.Address, password string, timeout time.Duration) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -108,9 +139,17 @@ func (w *SoftWallet) Unlock(address common.Address, password string) error {
 		return err
 	}
 
+	if previous, exists := w.unlockedKeyMap[address]; exists {
+		w.lockKey(previous)
+	}
+
 	unlockedKey := &UnlockedKey{
 		Key: key,
 	}
+	if timeout > 0 {
+		unlockedKey.abort = make(chan struct{})
+		go w.expire(address, unlockedKey, timeout)
+	}
 	w.unlockedKeyMap[address] = unlockedKey
 
 	return nil
@@ -127,12 +166,39 @@ func (w *SoftWallet) Lock(address common.Address) error {
 	}
 
 	delete(w.unlockedKeyMap, address)
+	w.lockKey(unlockedKey)
 
-	if unlockedKey != nil {
-		w.zeroKey(unlockedKey)
+	return nil
+}
+
+// IsUnlocked returns whether the key of the address is unlocked
+func (w *SoftWallet) IsUnlocked(address common.Address) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, exists := w.unlockedKeyMap[address]
+	return exists
+}
+
+// expire locks the unlocked key when the timeout expires, unless the key has been locked or
+// unlocked again in the meantime.
+func (w *SoftWallet) expire(address common.Address, unlockedKey *UnlockedKey, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-unlockedKey.abort:
+		return
+	case <-timer.C:
 	}
 
-	return nil
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.unlockedKeyMap[address] == unlockedKey {
+		delete(w.unlockedKeyMap, address)
+		w.zeroKey(unlockedKey)
+	}
 }
 
 // Delete deletes a key from disk permanently
@@ -143,9 +209,7 @@ func (w *SoftWallet) Delete(address common.Address, password string) error {
 	unlockedKey, exists := w.unlockedKeyMap[address]
 	if exists {
 		delete(w.unlockedKeyMap, address)
-		if unlockedKey != nil {
-			w.zeroKey(unlockedKey)
-		}
+		w.lockKey(unlockedKey)
 	}
 
 	err := w.keystore.DeleteKey(address, password)
@@ -199,6 +263,18 @@ func (w *SoftWallet) Sign(address common.Address, txrlp common.Bytes) (*crypto.S
 	return signature, err
 }
 
+// lockKey cancels the automatic re-lock of the unlocked key and zeroes it. The key must have
+// been removed from the unlocked keys or be about to be replaced.
+func (w *SoftWallet) lockKey(unlockedKey *UnlockedKey) {
+	if unlockedKey == nil {
+		return
+	}
+	if unlockedKey.abort != nil {
+		close(unlockedKey.abort)
+	}
+	w.zeroKey(unlockedKey)
+}
+
 // zeroKey zeroes a private key in memory
 func (w *SoftWallet) zeroKey(unlockedKey *UnlockedKey) {
 	if unlockedKey == nil {
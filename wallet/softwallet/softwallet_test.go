@@ -5,6 +5,7 @@ import (
 	"os"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/thetatoken/theta/common"
@@ -26,6 +27,41 @@ func TestEncryptedSoftWalletMultipleKeys(t *testing.T) {
 	testSoftWalletMultipleKeys(t, KeystoreTypeEncrypted)
 }
 
+func TestSoftWalletUnlockWithTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir := createTempDir()
+	defer os.RemoveAll(tmpdir)
+
+	wallet, err := NewSoftWallet(tmpdir, KeystoreTypePlain)
+	assert.Nil(err)
+	password := "abcd"
+	addr, err := wallet.NewKey(password)
+	assert.Nil(err)
+	assert.Nil(wallet.Lock(addr))
+	assert.False(wallet.IsUnlocked(addr))
+
+	// The key is locked again once the timeout expires.
+	assert.Nil(wallet.UnlockWithTimeout(addr, password, 100*time.Millisecond))
+	assert.True(wallet.IsUnlocked(addr))
+	time.Sleep(300 * time.Millisecond)
+	assert.False(wallet.IsUnlocked(addr))
+	_, err = wallet.Sign(addr, common.Bytes("hello world"))
+	assert.NotNil(err)
+
+	// Unlocking again replaces the timeout.
+	assert.Nil(wallet.UnlockWithTimeout(addr, password, 100*time.Millisecond))
+	assert.Nil(wallet.Unlock(addr, password))
+	time.Sleep(300 * time.Millisecond)
+	assert.True(wallet.IsUnlocked(addr))
+
+	// Locking cancels the timeout.
+	assert.Nil(wallet.UnlockWithTimeout(addr, password, 100*time.Millisecond))
+	assert.Nil(wallet.Lock(addr))
+	time.Sleep(300 * time.Millisecond)
+	assert.False(wallet.IsUnlocked(addr))
+}
+
 // ---------------- Test Utilities ---------------- //
 
 func testSoftWalletBasics(t *testing.T, ksType KeystoreType) {